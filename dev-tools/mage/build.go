@@ -242,11 +242,32 @@ func Build(params BuildArgs) error {
 	return sh.RunWith(env, "go", args...)
 }
 
+// windowsManifest is the application manifest embedded in the Windows exe. It
+// marks the exe as long path aware so paths longer than MAX_PATH can be used
+// when long path support is enabled on the system.
+const windowsManifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <trustInfo xmlns="urn:schemas-microsoft-com:asm.v3">
+    <security>
+      <requestedPrivileges>
+        <requestedExecutionLevel level="asInvoker" uiAccess="false"/>
+      </requestedPrivileges>
+    </security>
+  </trustInfo>
+  <application xmlns="urn:schemas-microsoft-com:asm.v3">
+    <windowsSettings xmlns:ws2="http://schemas.microsoft.com/SMI/2016/WindowsSettings">
+      <ws2:longPathAware>true</ws2:longPathAware>
+    </windowsSettings>
+  </application>
+</assembly>
+`
+
 // MakeWindowsSysoFile generates a .syso file containing metadata about the
 // executable file like vendor, version, copyright. The linker automatically
 // discovers the .syso file and incorporates it into the Windows exe. This
 // allows users to view metadata about the exe in the Details tab of the file
-// properties viewer.
+// properties viewer. The .syso also contains the application manifest that
+// enables long path support.
 func MakeWindowsSysoFile() (string, error) {
 	version, err := BeatQualifiedVersion()
 	if err != nil {
@@ -282,6 +303,13 @@ func MakeWindowsSysoFile() (string, error) {
 		},
 	}
 
+	manifestFile := BeatName + ".exe.manifest"
+	if err = os.WriteFile(manifestFile, []byte(windowsManifest), 0644); err != nil {
+		return "", fmt.Errorf("failed to write Windows application manifest: %w", err)
+	}
+	defer os.Remove(manifestFile)
+	vi.ManifestPath = manifestFile
+
 	vi.Build()
 	vi.Walk()
 	sysoFile := BeatName + "_windows_" + GOARCH + ".syso"
@@ -25,6 +25,7 @@ import (
 	"github.com/magefile/mage/sh"
 
 	"github.com/elastic/elastic-agent/dev-tools/mage/gotool"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	v1 "github.com/elastic/elastic-agent/pkg/api/v1"
 )
 
@@ -387,6 +388,7 @@ func GeneratePackageManifest(beatName, packageVersion string, snapshot bool, ful
 	m.Package.PathMappings[0][versionedHomePath] = fmt.Sprintf("data/%s-%s%s-%s", beatName, m.Package.Version, GenerateSnapshotSuffix(snapshot), shortHash)
 	m.Package.PathMappings[0][v1.ManifestFileName] = fmt.Sprintf("data/%s-%s%s-%s/%s", beatName, m.Package.Version, GenerateSnapshotSuffix(snapshot), shortHash, v1.ManifestFileName)
 	m.Package.Flavors = flavorsRegistry
+	m.Package.StateStoreVersion = store.Version
 	yamlBytes, err := yaml.Marshal(m)
 	if err != nil {
 		return "", fmt.Errorf("marshaling manifest: %w", err)
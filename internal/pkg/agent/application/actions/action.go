@@ -17,6 +17,13 @@ type Handler interface {
 	Handle(ctx context.Context, a fleetapi.Action, acker acker.Acker) error
 }
 
+// AsyncHandler handles actions that complete after the handler returns. The dispatcher keeps the action pending
+// until done is called, so an action interrupted by the agent stopping is known after a restart. done is only
+// called when HandleAsync returns no error.
+type AsyncHandler interface {
+	HandleAsync(ctx context.Context, a fleetapi.Action, acker acker.Acker, done func(executed bool)) error
+}
+
 // ClientSetter sets the client for communication.
 type ClientSetter interface {
 	SetClient(client.Sender)
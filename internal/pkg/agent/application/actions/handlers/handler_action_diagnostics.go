@@ -46,7 +46,7 @@ type Uploader interface {
 type diagnosticsProvider interface {
 	DiagnosticHooks() diagnostics.Hooks
 	PerformDiagnostics(ctx context.Context, req ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
-	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
 }
 
 // abstractLogger represents a logger implementation
@@ -161,7 +161,7 @@ func (h *Diagnostics) collectDiag(ctx context.Context, action *fleetapi.ActionDi
 				h.log.Warn(str)
 			}
 		}()
-		err := diagnostics.ZipArchive(&wBuf, &b, h.topPath, aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog)
+		err := diagnostics.ZipArchive(&wBuf, &b, h.topPath, aDiag, uDiag, cDiag, action.Data.ExcludeEventsLog, diagnostics.DefaultRedactor())
 		if err != nil {
 			h.log.Errorw(
 				"diagnostics action handler failed generate zip archive",
@@ -301,7 +301,7 @@ func (h *Diagnostics) diagComponents(ctx context.Context, action *fleetapi.Actio
 			additionalMetrics = append(additionalMetrics, cproto.AdditionalDiagnosticRequest_CPU)
 		}
 	}
-	rr, err := h.diagProvider.PerformComponentDiagnostics(ctx, additionalMetrics)
+	rr, err := h.diagProvider.PerformComponentDiagnostics(ctx, additionalMetrics, 0)
 	if err != nil {
 		h.log.Errorf("Error fetching component-level diagnostics: %w", err)
 	}
@@ -350,7 +350,7 @@ func (h *Diagnostics) diagFile(
 			h.log.Warn(str)
 		}
 	}()
-	if err := diagnostics.ZipArchive(&wBuf, f, h.topPath, aDiag, uDiag, cDiag, excludeEvents); err != nil {
+	if err := diagnostics.ZipArchive(&wBuf, f, h.topPath, aDiag, uDiag, cDiag, excludeEvents, diagnostics.DefaultRedactor()); err != nil {
 		os.Remove(name)
 		return nil, 0, err
 	}
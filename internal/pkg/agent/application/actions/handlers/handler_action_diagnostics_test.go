@@ -85,7 +85,7 @@ func TestDiagnosticHandlerHappyPathWithLogs(t *testing.T) {
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{hook1})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{mockUnitDiagnostic})
-	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
 
 	mockAcker := mockackers.NewAcker(t)
 	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
@@ -166,7 +166,7 @@ func TestDiagnosticHandlerUploaderErrorWithLogs(t *testing.T) {
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
-	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
 
 	// this error will be returbned by the uploader
 	uploaderError := errors.New("upload went wrong!")
@@ -207,7 +207,7 @@ func TestDiagnosticHandlerZipArchiveErrorWithLogs(t *testing.T) {
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
-	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
 
 	mockAcker := mockackers.NewAcker(t)
 	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
@@ -243,7 +243,7 @@ func TestDiagnosticHandlerAckErrorWithLogs(t *testing.T) {
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
-	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
 
 	mockAcker := mockackers.NewAcker(t)
 	ackError := errors.New("acking went wrong")
@@ -282,7 +282,7 @@ func TestDiagnosticHandlerCommitErrorWithLogs(t *testing.T) {
 
 	mockDiagProvider.EXPECT().DiagnosticHooks().Return([]diagnostics.Hook{})
 	mockDiagProvider.EXPECT().PerformDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentUnitDiagnostic{})
-	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
+	mockDiagProvider.EXPECT().PerformComponentDiagnostics(mock.Anything, mock.Anything, mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
 
 	mockAcker := mockackers.NewAcker(t)
 	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
@@ -375,7 +375,7 @@ func TestDiagnosticHandlerWithCPUProfile(t *testing.T) {
 			}
 		}
 		return false
-	})).Return([]runtime.ComponentDiagnostic{}, nil)
+	}), mock.Anything).Return([]runtime.ComponentDiagnostic{}, nil)
 
 	mockAcker := mockackers.NewAcker(t)
 	mockAcker.EXPECT().Ack(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, a fleetapi.Action) error {
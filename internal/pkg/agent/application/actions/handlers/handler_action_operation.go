@@ -0,0 +1,266 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const (
+	defaultOperationTimeout = 5 * time.Minute
+	maxOperationTimeout     = time.Hour
+	maxOperationOutput      = 64 * 1024
+)
+
+var (
+	// ErrOperationNotAllowed is returned when the requested operation is not in the local allowlist.
+	ErrOperationNotAllowed = errors.New("operation is not allowed")
+	// ErrOperationNotSigned is returned when the operation payload is not signed or no key exists to validate it.
+	ErrOperationNotSigned = errors.New("operation payload must be signed")
+)
+
+// OperationSpec is an operation of the local allowlist.
+type OperationSpec struct {
+	Name    string        `yaml:"name"`
+	Command string        `yaml:"command"`
+	Args    []string      `yaml:"args"`
+	Timeout time.Duration `yaml:"timeout"`
+	// AllowArgs allows the action to append arguments to Args.
+	AllowArgs bool `yaml:"allow_args"`
+}
+
+type operationsSpec struct {
+	Operations []OperationSpec `yaml:"operations"`
+}
+
+// LoadOperations loads the allowlist of operations from the file at path, a missing file allows no operation.
+//
+// The allowlist decides which commands run with the privileges of the Elastic Agent, it is refused when it could
+// be modified by a less privileged user.
+func LoadOperations(path string) (map[string]OperationSpec, error) {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return map[string]OperationSpec{}, nil
+	}
+	if err := utils.CheckTrustedFile(path); err != nil {
+		return nil, fmt.Errorf("refusing operations allowlist: %w", err)
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operations allowlist %s: %w", path, err)
+	}
+	var spec operationsSpec
+	if err := yaml.Unmarshal(contents, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse operations allowlist %s: %w", path, err)
+	}
+	operations := make(map[string]OperationSpec, len(spec.Operations))
+	for _, op := range spec.Operations {
+		if op.Name == "" || op.Command == "" {
+			return nil, fmt.Errorf("operation in allowlist %s is missing a name or a command", path)
+		}
+		if _, ok := operations[op.Name]; ok {
+			return nil, fmt.Errorf("operation %q is defined more than once in allowlist %s", op.Name, path)
+		}
+		if op.Timeout <= 0 {
+			op.Timeout = defaultOperationTimeout
+		}
+		if op.Timeout > maxOperationTimeout {
+			op.Timeout = maxOperationTimeout
+		}
+		operations[op.Name] = op
+	}
+	return operations, nil
+}
+
+type operationCoordinator interface {
+	Protection() protection.Config
+}
+
+// Operation handles the signed operational actions of the local allowlist.
+type Operation struct {
+	log           *logger.Logger
+	agentInfo     info.Agent
+	coord         operationCoordinator
+	allowlistPath string
+}
+
+// NewOperation creates a new Operation handler, the allowlist is read from allowlistPath on every action so
+// operations can be added without restarting the Elastic Agent.
+func NewOperation(
+	log *logger.Logger,
+	agentInfo info.Agent,
+	coord operationCoordinator,
+	allowlistPath string,
+) *Operation {
+	return &Operation{
+		log:           log,
+		agentInfo:     agentInfo,
+		coord:         coord,
+		allowlistPath: allowlistPath,
+	}
+}
+
+// Handle handles OPERATION action asynchronously, an operation can run for up to an hour and would otherwise
+// block the dispatch of the other actions. The action is acked once the operation completed.
+func (h *Operation) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker) error {
+	return h.HandleAsync(ctx, a, ack, func(bool) {})
+}
+
+// HandleAsync handles OPERATION action asynchronously like Handle, done is called once the action is acked. An
+// operation interrupted by the agent stopping is not done: it stays pending in the ledger of the dispatcher and
+// is reported as interrupted after the restart, it is not run again.
+func (h *Operation) HandleAsync(ctx context.Context, a fleetapi.Action, ack acker.Acker, done func(executed bool)) error {
+	h.log.Debugf("handlerOperation: action '%+v' received", a)
+
+	action, ok := a.(*fleetapi.ActionOperation)
+	if !ok {
+		return fmt.Errorf("invalid type, expected ActionOperation and received %T", a)
+	}
+	go func() {
+		h.run(ctx, action, ack)
+		if ctx.Err() != nil {
+			return
+		}
+		done(true)
+	}()
+	return nil
+}
+
+// run runs the operation of the action, then acks and commits the action with its result.
+func (h *Operation) run(ctx context.Context, action *fleetapi.ActionOperation, ack acker.Acker) {
+	action.Result = &fleetapi.ActionOperationResult{Name: action.Data.Name, ExitCode: -1}
+	if err := h.handle(ctx, action); err != nil {
+		action.Err = err
+	}
+	h.audit(action)
+	if err := ack.Ack(ctx, action); err != nil {
+		h.log.Errorw("failed to ack operation action",
+			"error.message", err,
+			"action", action)
+	}
+	if err := ack.Commit(ctx); err != nil {
+		h.log.Errorw("failed to commit operation action",
+			"error.message", err,
+			"action", action)
+	}
+}
+
+func (h *Operation) handle(ctx context.Context, action *fleetapi.ActionOperation) error {
+	// only vendor-signed payloads are executed, there is no fallback to unsigned data
+	signatureValidationKey := h.coord.Protection().SignatureValidationKey
+	if len(signatureValidationKey) == 0 {
+		return fmt.Errorf("%w: no signature validation key", ErrOperationNotSigned)
+	}
+	signedData, err := protection.ValidateAction(action, signatureValidationKey, h.agentInfo.AgentID())
+	if errors.Is(err, protection.ErrNotSigned) {
+		return ErrOperationNotSigned
+	}
+	if err != nil {
+		return fmt.Errorf("operation failed validation: %w", err)
+	}
+	sum := sha256.Sum256(signedData)
+	action.Result.SHA256 = hex.EncodeToString(sum[:])
+	action.Data = fleetapi.ActionOperationData{}
+	if err := json.Unmarshal(signedData, &action.Data); err != nil {
+		return fmt.Errorf("failed to convert signed data to action data: %w", err)
+	}
+	action.Result.Name = action.Data.Name
+
+	operations, err := LoadOperations(h.allowlistPath)
+	if err != nil {
+		return err
+	}
+	op, ok := operations[action.Data.Name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrOperationNotAllowed, action.Data.Name)
+	}
+	if len(action.Data.Args) > 0 && !op.AllowArgs {
+		return fmt.Errorf("%w: %q does not accept arguments", ErrOperationNotAllowed, op.Name)
+	}
+	args := append(append([]string{}, op.Args...), action.Data.Args...)
+	action.Result.Command = op.Command
+	action.Result.Args = args
+
+	return runOperation(ctx, op, args, action.Result)
+}
+
+func runOperation(ctx context.Context, op OperationSpec, args []string, result *fleetapi.ActionOperationResult) error {
+	ctx, cancel := context.WithTimeout(ctx, op.Timeout)
+	defer cancel()
+
+	output := &limitedBuffer{limit: maxOperationOutput}
+	cmd := exec.CommandContext(ctx, op.Command, args...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	result.StartedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	err := cmd.Run()
+	result.CompletedAt = time.Now().UTC().Format(time.RFC3339Nano)
+	result.Output = output.String()
+	result.Truncated = output.truncated
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("operation %q did not complete within %s: %w", op.Name, op.Timeout, ctx.Err())
+	}
+	if err != nil {
+		return fmt.Errorf("operation %q failed: %w", op.Name, err)
+	}
+	return nil
+}
+
+func (h *Operation) audit(action *fleetapi.ActionOperation) {
+	fields := []interface{}{
+		"audit", true,
+		"action_id", action.ActionID,
+		"operation.name", action.Result.Name,
+		"operation.command", action.Result.Command,
+		"operation.args", action.Result.Args,
+		"operation.payload_sha256", action.Result.SHA256,
+		"operation.exit_code", action.Result.ExitCode,
+	}
+	if action.Err != nil {
+		h.log.Errorw("operation action failed", append(fields, "error.message", action.Err)...)
+		return
+	}
+	h.log.Infow("operation action completed", fields...)
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.Buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
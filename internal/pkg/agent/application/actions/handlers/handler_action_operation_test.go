@@ -0,0 +1,279 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	mockinfo "github.com/elastic/elastic-agent/testing/mocks/internal_/pkg/agent/application/info"
+)
+
+func TestLoadOperations(t *testing.T) {
+	dir := t.TempDir()
+
+	operations, err := LoadOperations(filepath.Join(dir, "missing.yml"))
+	require.NoError(t, err)
+	assert.Empty(t, operations)
+
+	path := filepath.Join(dir, "operations.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+operations:
+  - name: flush-registry
+    command: /usr/bin/true
+  - name: rotate-certs
+    command: /usr/bin/rotate
+    args: [--all]
+    timeout: 2h
+    allow_args: true
+`), 0o600))
+	testutils.TrustFile(t, path)
+	operations, err = LoadOperations(path)
+	require.NoError(t, err)
+	require.Len(t, operations, 2)
+	assert.Equal(t, defaultOperationTimeout, operations["flush-registry"].Timeout)
+	assert.Equal(t, maxOperationTimeout, operations["rotate-certs"].Timeout)
+	assert.True(t, operations["rotate-certs"].AllowArgs)
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+operations:
+  - name: flush-registry
+    command: /usr/bin/true
+  - name: flush-registry
+    command: /usr/bin/false
+`), 0o600))
+	_, err = LoadOperations(path)
+	assert.ErrorContains(t, err, "defined more than once")
+}
+
+func TestLoadOperationsPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the file modes only apply on unix, the ACLs are covered by the tests of utils.CheckTrustedFile")
+	}
+	path := filepath.Join(t.TempDir(), "operations.yml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+operations:
+  - name: flush-registry
+    command: /usr/bin/true
+`), 0o600))
+
+	for _, mode := range []os.FileMode{0o620, 0o602, 0o666} {
+		require.NoError(t, os.Chmod(path, mode))
+		_, err := LoadOperations(path)
+		assert.ErrorContainsf(t, err, "must not be writable by its group or others", "mode %v", mode)
+	}
+
+	require.NoError(t, os.Chmod(path, 0o644))
+	operations, err := LoadOperations(path)
+	require.NoError(t, err)
+	assert.Len(t, operations, 1)
+
+	dir := filepath.Join(t.TempDir(), "operations.yml")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	_, err = LoadOperations(dir)
+	assert.ErrorContains(t, err, "is not a regular file")
+}
+
+func TestActionOperationHandler(t *testing.T) {
+	log, _ := loggertest.New("")
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("echo is required to run operations")
+	}
+
+	allowlist := filepath.Join(t.TempDir(), "operations.yml")
+	require.NoError(t, os.WriteFile(allowlist, []byte(`
+operations:
+  - name: flush-registry
+    command: `+echo+`
+    args: [flushed]
+  - name: rotate-certs
+    command: `+echo+`
+    allow_args: true
+`), 0o600))
+	testutils.TrustFile(t, allowlist)
+
+	private, signatureValidationKey, err := genKeys()
+	require.NoError(t, err)
+
+	newAction := func(t *testing.T, agentID string, data fleetapi.ActionOperationData) *fleetapi.ActionOperation {
+		raw, err := json.Marshal(data)
+		require.NoError(t, err)
+		signed, err := json.Marshal(map[string]interface{}{
+			"action_id": "123",
+			"type":      fleetapi.ActionTypeOperation,
+			"agents":    []string{agentID},
+			"data":      json.RawMessage(raw),
+		})
+		require.NoError(t, err)
+		signature, err := sign(signed, private)
+		require.NoError(t, err)
+		return &fleetapi.ActionOperation{
+			ActionID:   "123",
+			ActionType: fleetapi.ActionTypeOperation,
+			Data:       data,
+			Signature: &fleetapi.Signed{
+				Data:      base64.StdEncoding.EncodeToString(signed),
+				Signature: base64.StdEncoding.EncodeToString(signature),
+			},
+		}
+	}
+
+	cases := map[string]struct {
+		key           []byte
+		action        func(t *testing.T) *fleetapi.ActionOperation
+		expectedError error
+		expectedOut   string
+	}{
+		"no signature validation key": {
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				return newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "flush-registry"})
+			},
+			expectedError: ErrOperationNotSigned,
+		},
+		"not signed": {
+			key: signatureValidationKey,
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				action := newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "flush-registry"})
+				action.Signature = nil
+				return action
+			},
+			expectedError: ErrOperationNotSigned,
+		},
+		"signed for another agent": {
+			key: signatureValidationKey,
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				return newAction(t, "other-agent", fleetapi.ActionOperationData{Name: "flush-registry"})
+			},
+			expectedError: protection.ErrNonMatchingAgentID,
+		},
+		"not in allowlist": {
+			key: signatureValidationKey,
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				return newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "rm-rf"})
+			},
+			expectedError: ErrOperationNotAllowed,
+		},
+		"arguments not allowed": {
+			key: signatureValidationKey,
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				return newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "flush-registry", Args: []string{"--force"}})
+			},
+			expectedError: ErrOperationNotAllowed,
+		},
+		"unsigned data is ignored": {
+			key: signatureValidationKey,
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				action := newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "flush-registry"})
+				action.Data.Args = []string{"--force"}
+				return action
+			},
+			expectedOut: "flushed\n",
+		},
+		"arguments allowed": {
+			key: signatureValidationKey,
+			action: func(t *testing.T) *fleetapi.ActionOperation {
+				return newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "rotate-certs", Args: []string{"rotated"}})
+			},
+			expectedOut: "rotated\n",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mockAgentInfo := mockinfo.NewAgent(t)
+			mockAgentInfo.On("AgentID").Return("agent-id").Maybe()
+
+			coord := &fakeMigrateCoordinator{}
+			coord.On("Protection").Return(protection.Config{SignatureValidationKey: tc.key})
+
+			action := tc.action(t)
+			ack := &fakeAcker{}
+			ack.On("Ack", t.Context(), action).Return(nil)
+			ack.On("Commit", t.Context()).Return(nil)
+
+			h := NewOperation(log, mockAgentInfo, coord, allowlist)
+			done := make(chan bool, 1)
+			require.NoError(t, h.HandleAsync(t.Context(), action, ack, func(executed bool) { done <- executed }), "the operation should run asynchronously")
+			select {
+			case executed := <-done:
+				assert.True(t, executed)
+			case <-time.After(10 * time.Second):
+				require.Fail(t, "the operation action was not done")
+			}
+			ack.AssertNumberOfCalls(t, "Ack", 1)
+			ack.AssertNumberOfCalls(t, "Commit", 1)
+			err := action.Err
+
+			event := action.AckEvent()
+			var result fleetapi.ActionOperationResult
+			require.NoError(t, json.Unmarshal(event.Data, &result))
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+				assert.NotEmpty(t, event.Error)
+				assert.Equal(t, -1, result.ExitCode)
+				return
+			}
+			require.NoError(t, err)
+			assert.Empty(t, event.Error)
+			assert.Equal(t, 0, result.ExitCode)
+			assert.Equal(t, echo, result.Command)
+			assert.Equal(t, tc.expectedOut, result.Output)
+			assert.NotEmpty(t, result.SHA256)
+			assert.NotEmpty(t, result.StartedAt)
+			assert.NotEmpty(t, result.CompletedAt)
+		})
+	}
+
+	t.Run("interrupted by the agent stopping", func(t *testing.T) {
+		mockAgentInfo := mockinfo.NewAgent(t)
+		mockAgentInfo.On("AgentID").Return("agent-id").Maybe()
+		coord := &fakeMigrateCoordinator{}
+		coord.On("Protection").Return(protection.Config{SignatureValidationKey: signatureValidationKey})
+
+		ctx, cancel := context.WithCancel(t.Context())
+		cancel()
+		action := newAction(t, "agent-id", fleetapi.ActionOperationData{Name: "flush-registry"})
+		ack := &fakeAcker{}
+		ack.On("Ack", ctx, action).Return(nil)
+		committed := make(chan struct{})
+		ack.On("Commit", ctx).Return(nil).Run(func(mock.Arguments) { close(committed) })
+
+		h := NewOperation(log, mockAgentInfo, coord, allowlist)
+		done := make(chan bool, 1)
+		require.NoError(t, h.HandleAsync(ctx, action, ack, func(executed bool) { done <- executed }))
+		select {
+		case <-committed:
+		case <-time.After(10 * time.Second):
+			require.Fail(t, "the operation action was not acked")
+		}
+		select {
+		case <-done:
+			assert.Fail(t, "an interrupted operation should stay pending")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("wrong action type", func(t *testing.T) {
+		h := NewOperation(log, mockinfo.NewAgent(t), &fakeMigrateCoordinator{}, allowlist)
+		ack := &fakeAcker{}
+		require.Error(t, h.Handle(t.Context(), &fleetapi.ActionSettings{}, ack))
+		ack.AssertNotCalled(t, "Ack", mock.Anything, mock.Anything)
+	})
+}
@@ -7,10 +7,13 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	goerrors "errors"
 	"fmt"
 	"io"
+	"slices"
 	"sort"
+	"sync"
 
 	"gopkg.in/yaml.v2"
 
@@ -18,11 +21,14 @@ import (
 	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
 	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/clientcert"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
@@ -41,6 +47,16 @@ type PolicyChangeHandler struct {
 	setters              []actions.ClientSetter
 	policyLogLevelSetter logLevelSetter
 	coordinator          *coordinator.Coordinator
+
+	// configMx protects the Fleet client configuration, changed by the policies and when the client
+	// certificate is renewed.
+	configMx sync.Mutex
+
+	policyMx sync.Mutex
+	// policy is the current policy, the policy deltas sent by Fleet are applied to it.
+	policy     *transpiler.AST
+	policyHash string
+
 	// Disabled for 8.8.0 release in order to limit the surface
 	// https://github.com/elastic/security-team/issues/6501
 	// // Last known valid signature validation key
@@ -100,6 +116,10 @@ func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, ack
 	// // Cache signature validation key for the next policy handling
 	// h.signatureValidationKey = signatureValidationKey
 
+	if err := h.resolvePolicy(action); err != nil {
+		return err
+	}
+
 	c, err := config.NewConfigFrom(action.Data.Policy)
 	if err != nil {
 		return errors.New(err, "could not parse the configuration from the policy", errors.TypeConfig)
@@ -115,6 +135,92 @@ func (h *PolicyChangeHandler) Handle(ctx context.Context, a fleetapi.Action, ack
 	return nil
 }
 
+// PolicyHash returns the hash of the current policy, reported on checkin so that Fleet sends the next policy
+// as a delta of it. It is empty when there is no policy the deltas can be applied to.
+func (h *PolicyChangeHandler) PolicyHash() string {
+	h.policyMx.Lock()
+	defer h.policyMx.Unlock()
+	return h.policyHash
+}
+
+// resolvePolicy applies the policy delta of the action to the current policy. The action is changed to hold the
+// full policy, so that the action stored once acknowledged can be replayed on its own.
+func (h *PolicyChangeHandler) resolvePolicy(action *fleetapi.ActionPolicyChange) error {
+	h.policyMx.Lock()
+	defer h.policyMx.Unlock()
+
+	delta := action.Data.PolicyDelta
+	if delta == nil {
+		policy, err := transpiler.NewAST(action.Data.Policy)
+		if err != nil {
+			h.log.Warnf("Policy deltas disabled, failed to parse the policy: %v", err)
+			h.policy, h.policyHash = nil, ""
+			return nil
+		}
+		hash, err := fleetapi.PolicyHash(action.Data.Policy)
+		if err != nil {
+			h.log.Warnf("Policy deltas disabled, failed to hash the policy: %v", err)
+			h.policy, h.policyHash = nil, ""
+			return nil
+		}
+		h.policy, h.policyHash = policy, hash
+		return nil
+	}
+
+	policy, m, err := h.patchPolicy(delta)
+	if err != nil {
+		// the next checkin reports no policy hash, Fleet sends the full policy
+		h.policy, h.policyHash = nil, ""
+		return errors.New(err, "could not apply the policy delta, requesting the full policy", errors.TypeConfig)
+	}
+	h.log.Debugf("handlerPolicyChange: applied policy delta of %d patches", len(delta.Patches))
+	action.Data.Policy = m
+	action.Data.PolicyDelta = nil
+	h.policy, h.policyHash = policy, delta.Hash
+	return nil
+}
+
+// patchPolicy returns the current policy with the patches of the delta applied, as an AST and as a map. It fails
+// when the delta is not based on the current policy or doesn't result in the new policy.
+func (h *PolicyChangeHandler) patchPolicy(delta *fleetapi.PolicyDelta) (*transpiler.AST, map[string]interface{}, error) {
+	if h.policy == nil || delta.BaseHash != h.policyHash {
+		return nil, nil, fmt.Errorf("policy delta based on policy %q, the current policy is %q", delta.BaseHash, h.policyHash)
+	}
+	patches, err := decodePatches(delta.Patches)
+	if err != nil {
+		return nil, nil, err
+	}
+	policy, err := h.policy.Patch(patches)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := policy.Map()
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, err := fleetapi.PolicyHash(m)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hash != delta.Hash {
+		return nil, nil, fmt.Errorf("patched policy %q does not match the policy %q of the delta", hash, delta.Hash)
+	}
+	return policy, m, nil
+}
+
+// decodePatches decodes the patches of a policy delta sent by Fleet.
+func decodePatches(raw []map[string]interface{}) ([]transpiler.Patch, error) {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the patches of the policy delta: %w", err)
+	}
+	var patches []transpiler.Patch
+	if err := json.Unmarshal(b, &patches); err != nil {
+		return nil, fmt.Errorf("failed to decode the patches of the policy delta: %w", err)
+	}
+	return patches, nil
+}
+
 // Watch returns the channel for configuration change notifications.
 func (h *PolicyChangeHandler) Watch() <-chan coordinator.ConfigChange {
 	return h.ch
@@ -144,6 +250,10 @@ func (h *PolicyChangeHandler) validateFleetServerHosts(ctx context.Context, cfg
 	// make a copy the current client config and apply the changes on this copy
 	newFleetClientConfig := h.config.Fleet.Client
 	updateFleetConfig(h.log, parsedConfig.Fleet.Client, &newFleetClientConfig)
+	if h.config.Fleet.IssuedClientCertificate() && h.config.Fleet.Client.Transport.TLS != nil {
+		// the client certificate issued by Fleet is renewed by the agent, it's kept over the policy
+		newFleetClientConfig.Transport.TLS.Certificate = h.config.Fleet.Client.Transport.TLS.Certificate
+	}
 
 	// Test new config
 	err = testFleetConfig(ctx, h.log, newFleetClientConfig, h.config.Fleet.AccessAPIKey)
@@ -207,6 +317,13 @@ func updateFleetConfig(log *logger.Logger, policyConfig remote.Config, agentConf
 		agentConfig.Transport.Proxy.URL = &urlCopy
 	}
 
+	// Same as the proxy, empty failover paths from fleet are ignored.
+	if policyConfig.ProxyPaths.Enabled() {
+		log.Debugw("received proxy failover paths from fleet, applying them", "proxy_failover", policyConfig.ProxyPaths.Failover)
+		agentConfig.ProxyPaths = policyConfig.ProxyPaths
+		agentConfig.ProxyPaths.Failover = slices.Clone(policyConfig.ProxyPaths.Failover)
+	}
+
 	if policyConfig.Transport.TLS != nil {
 
 		tlsCopy := tlscommon.Config{}
@@ -239,6 +356,9 @@ func emptyCertificateConfig() tlscommon.CertificateConfig {
 }
 
 func (h *PolicyChangeHandler) handlePolicyChange(ctx context.Context, c *config.Config) (err error) {
+	h.configMx.Lock()
+	defer h.configMx.Unlock()
+
 	var validationErr error
 
 	// validate Fleet connectivity with the new configuration
@@ -367,6 +487,27 @@ func (h *PolicyChangeHandler) applyFleetClientConfig(validatedConfig *remote.Con
 	return nil
 }
 
+// SetClientCertificate applies the renewed client certificate issued by Fleet to the Fleet client
+// configuration and to the current clients. The certificate is stored in the vault, the stored Fleet
+// configuration doesn't change.
+func (h *PolicyChangeHandler) SetClientCertificate(cert secret.ClientCertificate) error {
+	h.configMx.Lock()
+	defer h.configMx.Unlock()
+
+	clientConfig := h.config.Fleet.Client
+	clientcert.Apply(&clientConfig, cert)
+	fleetClient, err := client.NewAuthWithConfig(
+		h.log, h.config.Fleet.AccessAPIKey, clientConfig)
+	if err != nil {
+		return fmt.Errorf("creating new fleet client with the renewed certificate: %w", err)
+	}
+	h.config.Fleet.Client = clientConfig
+	for _, setter := range h.setters {
+		setter.SetClient(fleetClient)
+	}
+	return nil
+}
+
 func (h *PolicyChangeHandler) applyLoggingConfig(ctx context.Context, loggingConfig *logger.Config) error {
 
 	var policyLogLevel *logger.Level
@@ -449,7 +590,7 @@ func clientEqual(k1 remote.Config, k2 remote.Config) bool {
 
 func fleetToReader(agentID string, headers map[string]string, cfg *configuration.Configuration) (io.ReadSeeker, error) {
 	configToStore := map[string]interface{}{
-		"fleet": cfg.Fleet,
+		"fleet": clientcert.Without(cfg.Fleet),
 		"agent": map[string]interface{}{ // Add event logging configuration here!
 			"id":                           agentID,
 			"headers":                      headers,
@@ -520,6 +661,14 @@ func (l *policyChange) Ack() error {
 	return nil
 }
 
+func (l *policyChange) Origin() coordinator.ConfigChangeOrigin {
+	origin := coordinator.ConfigChangeOrigin{Source: "fleet"}
+	if l.action != nil {
+		origin.ActionID = l.action.ID()
+	}
+	return origin
+}
+
 // WaitAck waits for policy change to be acked.
 // Policy change ack is awaitable only in case commit flag was set.
 // Caller is responsible to use any reasonable deadline otherwise
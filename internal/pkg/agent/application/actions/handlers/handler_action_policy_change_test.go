@@ -8,6 +8,7 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -29,8 +30,10 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	noopacker "github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
@@ -99,6 +102,73 @@ func TestPolicyChange(t *testing.T) {
 	})
 }
 
+func TestPolicyChangeDelta(t *testing.T) {
+	log, _ := logger.New("", false)
+	ack := noopacker.New()
+	ch := make(chan coordinator.ConfigChange, 1)
+	var nilLogLevel *logger.Level
+	logLevelSetter := mockhandlers.NewLogLevelSetter(t)
+	logLevelSetter.EXPECT().SetLogLevel(mock.Anything, nilLogLevel).Return(nil).Twice()
+	handler := NewPolicyChangeHandler(log, &info.AgentInfo{}, configuration.DefaultConfiguration(), &storage.NullStore{}, ch, logLevelSetter, &coordinator.Coordinator{})
+
+	// the policies are decoded from JSON, like the patches
+	v1 := map[string]interface{}{
+		"revision": float64(1),
+		"inputs":   []interface{}{map[string]interface{}{"id": "logs", "type": "filestream"}},
+	}
+	v2 := map[string]interface{}{
+		"revision": float64(2),
+		"inputs": []interface{}{
+			map[string]interface{}{"id": "logs", "type": "filestream"},
+			map[string]interface{}{"id": "metrics", "type": "system/metrics"},
+		},
+	}
+	v1AST, err := transpiler.NewAST(v1)
+	require.NoError(t, err)
+	v2AST, err := transpiler.NewAST(v2)
+	require.NoError(t, err)
+	v1Hash, err := fleetapi.PolicyHash(v1)
+	require.NoError(t, err)
+	v2Hash, err := fleetapi.PolicyHash(v2)
+	require.NoError(t, err)
+	// the patches are received from Fleet as JSON
+	b, err := json.Marshal(transpiler.Diff(v1AST, v2AST))
+	require.NoError(t, err)
+	var patches []map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &patches))
+	delta := &fleetapi.PolicyDelta{BaseHash: v1Hash, Hash: v2Hash, Patches: patches}
+
+	assert.Empty(t, handler.PolicyHash(), "no policy hash should be reported before the first policy")
+	require.NoError(t, handler.Handle(context.Background(), &fleetapi.ActionPolicyChange{
+		ActionID:   "full",
+		ActionType: fleetapi.ActionTypePolicyChange,
+		Data:       fleetapi.ActionPolicyChangeData{Policy: v1},
+	}, ack))
+	<-ch
+	assert.Equal(t, v1Hash, handler.PolicyHash())
+
+	action := &fleetapi.ActionPolicyChange{
+		ActionID:   "delta",
+		ActionType: fleetapi.ActionTypePolicyChange,
+		Data:       fleetapi.ActionPolicyChangeData{PolicyDelta: delta},
+	}
+	require.NoError(t, handler.Handle(context.Background(), action, ack))
+	change := <-ch
+	assert.Equal(t, config.MustNewConfigFrom(v2), change.Config())
+	assert.Equal(t, v2, action.Data.Policy, "the action should hold the full policy to be stored")
+	assert.Nil(t, action.Data.PolicyDelta)
+	assert.Equal(t, v2Hash, handler.PolicyHash())
+
+	// the same delta doesn't apply to the new policy, the full policy is requested
+	err = handler.Handle(context.Background(), &fleetapi.ActionPolicyChange{
+		ActionID:   "stale-delta",
+		ActionType: fleetapi.ActionTypePolicyChange,
+		Data:       fleetapi.ActionPolicyChangeData{PolicyDelta: delta},
+	}, ack)
+	assert.ErrorContains(t, err, "could not apply the policy delta")
+	assert.Empty(t, handler.PolicyHash())
+}
+
 func TestPolicyAcked(t *testing.T) {
 	log, _ := logger.New("", false)
 
@@ -1065,3 +1135,51 @@ func nilLogLevelSet(t *testing.T) *mockhandlers.LogLevelSetter {
 	logLevelSetter.EXPECT().SetLogLevel(mock.Anything, nilLogLevel).Return(nil).Once()
 	return logLevelSetter
 }
+
+func TestPolicyChangeHandler_SetClientCertificate(t *testing.T) {
+	log, _ := loggertest.New("TestPolicyChangeHandler")
+	fleetRootPair, agentChildPair, err := certutil.NewRootAndChildCerts()
+	require.NoError(t, err)
+	cert := secret.ClientCertificate{Certificate: string(agentChildPair.Cert), Key: string(agentChildPair.Key)}
+
+	var setterCalledCount int
+	setter := testSetter{SetClientFn: func(c client.Sender) {
+		setterCalledCount++
+	}}
+	sharedTLS := &tlscommon.Config{CAs: []string{string(fleetRootPair.Cert)}}
+	cfg := &configuration.Configuration{
+		Fleet: &configuration.FleetAgentConfig{
+			AccessAPIKey:      "api-key",
+			ClientCertificate: &configuration.FleetClientCertificateConfig{Enabled: true},
+			Client: remote.Config{
+				Hosts:     []string{"https://fleet-server:8220"},
+				Transport: httpcommon.HTTPTransportSettings{TLS: sharedTLS},
+			},
+		},
+		Settings: configuration.DefaultSettingsConfig(),
+	}
+	h := PolicyChangeHandler{
+		agentInfo: &info.AgentInfo{},
+		config:    cfg,
+		store:     &storage.NullStore{},
+		setters:   []actions.ClientSetter{&setter},
+		log:       log,
+	}
+
+	require.NoError(t, h.SetClientCertificate(cert))
+	assert.Equal(t, 1, setterCalledCount)
+	assert.Equal(t, cert.Key, h.config.Fleet.Client.Transport.TLS.Certificate.Key)
+	assert.Equal(t, sharedTLS.CAs, h.config.Fleet.Client.Transport.TLS.CAs)
+	assert.Empty(t, sharedTLS.Certificate.Key, "the previous TLS configuration should not be changed")
+
+	// the certificate issued by Fleet is only stored in the vault
+	reader, err := fleetToReader("agent-id", nil, h.config)
+	require.NoError(t, err)
+	stored, err := config.NewConfigFrom(reader)
+	require.NoError(t, err)
+	storedCfg, err := configuration.NewFromConfig(stored)
+	require.NoError(t, err)
+	assert.True(t, storedCfg.Fleet.IssuedClientCertificate())
+	assert.Empty(t, storedCfg.Fleet.Client.Transport.TLS.Certificate.Key)
+	assert.Equal(t, sharedTLS.CAs, storedCfg.Fleet.Client.Transport.TLS.CAs)
+}
@@ -9,9 +9,11 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -27,16 +29,20 @@ type Upgrade struct {
 	bkgActions []*fleetapi.ActionUpgrade
 	bkgCancel  context.CancelFunc
 	bkgMutex   sync.Mutex
+	window     *configuration.UpgradeWindowConfig
 
+	nowFn                        func() time.Time                                                                                                                       // allows to inject the time for tests, defaults to time.Now
 	tamperProtectionFn           func() bool                                                                                                                            // allows to inject the flag for tests, defaults to features.TamperProtection
 	notifyUnitsOfProxiedActionFn func(ctx context.Context, log *logp.Logger, action dispatchableAction, ucs []unitWithComponent, performAction performActionFunc) error // allows to inject the function for tests, defaults to notifyUnitsOfProxiedAction
 }
 
-// NewUpgrade creates a new Upgrade handler.
-func NewUpgrade(log *logger.Logger, coord upgradeCoordinator) *Upgrade {
+// NewUpgrade creates a new Upgrade handler. Upgrades are deferred to the next maintenance window when window is set.
+func NewUpgrade(log *logger.Logger, coord upgradeCoordinator, window *configuration.UpgradeWindowConfig) *Upgrade {
 	return &Upgrade{
 		log:                          log,
 		coord:                        coord,
+		window:                       window,
+		nowFn:                        time.Now,
 		tamperProtectionFn:           features.TamperProtection,
 		notifyUnitsOfProxiedActionFn: notifyUnitsOfProxiedAction,
 	}
@@ -54,6 +60,10 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 		return fmt.Errorf("invalid type, expected ActionUpgrade and received %T", a)
 	}
 
+	if err := h.checkWindow(action); err != nil {
+		return err
+	}
+
 	asyncCtx, runAsync := h.getAsyncContext(ctx, action, ack)
 	if !runAsync {
 		return nil
@@ -84,7 +94,8 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 
 	go func() {
 		h.log.Infof("starting upgrade to version %s in background", action.Data.Version)
-		if err := h.coord.Upgrade(asyncCtx, action.Data.Version, action.Data.SourceURI, action, uOpts...); err != nil {
+		err := h.coord.Upgrade(asyncCtx, action.Data.Version, action.Data.SourceURI, action, uOpts...)
+		if err != nil {
 			h.log.Errorf("upgrade to version %s failed: %v", action.Data.Version, err)
 			// If context is cancelled in getAsyncContext, the actions are acked there
 			if !errors.Is(asyncCtx.Err(), context.Canceled) {
@@ -97,6 +108,30 @@ func (h *Upgrade) Handle(ctx context.Context, a fleetapi.Action, ack acker.Acker
 	return nil
 }
 
+// checkWindow returns a fleetapi.DeferredError, so the dispatcher queues the action again, when the upgrade is
+// requested outside of the maintenance windows.
+func (h *Upgrade) checkWindow(action *fleetapi.ActionUpgrade) error {
+	if h.window == nil {
+		return nil
+	}
+	now := h.nowFn()
+	next, err := h.window.Next(now)
+	if err != nil {
+		return fmt.Errorf("failed to check the upgrade windows: %w", err)
+	}
+	if next.After(now) {
+		h.log.Infof("upgrade to version %s requested outside of the upgrade windows, deferring it to %s", action.Data.Version, next)
+		return &fleetapi.DeferredError{Until: next, Reason: "outside of the upgrade windows"}
+	}
+
+	// the upgrade is no longer deferred, a failure must not be acked as a deferral
+	var deferred *fleetapi.DeferredError
+	if errors.As(action.GetError(), &deferred) {
+		action.SetError(nil)
+	}
+	return nil
+}
+
 // ackActions Acks all the actions in bkgActions, and deletes entries from bkgActions.
 // User is responsible for obtaining and releasing bkgMutex lock
 func (h *Upgrade) ackActions(ctx context.Context, ack acker.Acker) {
@@ -119,7 +119,7 @@ func TestUpgradeHandler(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a := fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{
 		Version: "8.3.0", SourceURI: "http://localhost"}}
 	ack := noopacker.New()
@@ -178,7 +178,7 @@ func TestUpgradeHandlerSameVersion(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a := fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{
 		Version: "8.3.0", SourceURI: "http://localhost"}}
 	ack := noopacker.New()
@@ -237,7 +237,7 @@ func TestDuplicateActionsHandled(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a1 := fleetapi.ActionUpgrade{
 		ActionID: "action-8.5-1",
 		Data: fleetapi.ActionUpgradeData{
@@ -331,7 +331,7 @@ func TestUpgradeHandlerNewVersion(t *testing.T) {
 	//nolint:errcheck // We don't need the termination state of the Coordinator
 	go c.Run(ctx)
 
-	u := NewUpgrade(log, c)
+	u := NewUpgrade(log, c, nil)
 	a1 := fleetapi.ActionUpgrade{
 		ActionID: "action-8.2",
 		Data: fleetapi.ActionUpgradeData{
@@ -460,7 +460,7 @@ func TestEndpointPreUpgradeCallback(t *testing.T) {
 				})
 
 			log, _ := logger.New("", false)
-			u := NewUpgrade(log, mockCoordinator)
+			u := NewUpgrade(log, mockCoordinator, nil)
 			u.tamperProtectionFn = func() bool { return tc.shouldProxyToEndpoint }
 
 			notifyUnitsCalled := atomic.Bool{}
@@ -508,6 +508,58 @@ func TestEndpointPreUpgradeCallback(t *testing.T) {
 	}
 }
 
+func TestUpgradeHandlerWindow(t *testing.T) {
+	window := &configuration.UpgradeWindowConfig{
+		Timezone: "UTC",
+		Ranges:   []string{"Sat,Sun 00:00-24:00"},
+	}
+	friday := time.Date(2024, time.January, 5, 12, 0, 0, 0, time.UTC)
+	saturday := time.Date(2024, time.January, 6, 0, 0, 0, 0, time.UTC)
+
+	t.Run("deferred outside of the windows", func(t *testing.T) {
+		mockCoordinator := mockhandlers.NewUpgradeCoordinator(t)
+		log, _ := logger.New("", false)
+		u := NewUpgrade(log, mockCoordinator, window)
+		u.nowFn = func() time.Time { return friday }
+
+		action := &fleetapi.ActionUpgrade{Data: fleetapi.ActionUpgradeData{Version: "255.0.0"}}
+		err := u.Handle(context.Background(), action, mockAcker.NewAcker(t))
+		var deferred *fleetapi.DeferredError
+		require.ErrorAs(t, err, &deferred)
+		assert.Equal(t, saturday, deferred.Until)
+		assert.Empty(t, u.bkgActions, "a deferred upgrade must not be started")
+	})
+
+	t.Run("upgraded within the windows", func(t *testing.T) {
+		mockCoordinator := mockhandlers.NewUpgradeCoordinator(t)
+		upgradeCalledChan := make(chan struct{})
+		mockCoordinator.EXPECT().Upgrade(mock.Anything, "255.0.0", "", mock.Anything).
+			RunAndReturn(func(ctx context.Context, s string, s2 string, actionUpgrade *fleetapi.ActionUpgrade, opt ...coordinator.UpgradeOpt) error {
+				upgradeCalledChan <- struct{}{}
+				return nil
+			})
+
+		log, _ := logger.New("", false)
+		u := NewUpgrade(log, mockCoordinator, window)
+		u.nowFn = func() time.Time { return saturday }
+		u.tamperProtectionFn = func() bool { return false }
+
+		action := &fleetapi.ActionUpgrade{
+			Data: fleetapi.ActionUpgradeData{Version: "255.0.0"},
+			Err:  &fleetapi.DeferredError{Until: saturday, Reason: "outside of the upgrade windows"},
+		}
+		err := u.Handle(context.Background(), action, mockAcker.NewAcker(t))
+		require.NoError(t, err)
+		assert.NoError(t, action.GetError(), "the deferral must be cleared once the upgrade starts")
+
+		select {
+		case <-upgradeCalledChan:
+		case <-time.After(10 * time.Second):
+			t.Fatal("mockCoordinator.Upgrade was not called in time")
+		}
+	})
+}
+
 type fakeAcker struct {
 	mock.Mock
 }
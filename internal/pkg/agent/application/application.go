@@ -15,10 +15,13 @@ import (
 
 	"github.com/elastic/elastic-agent-libs/logp"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/clientcert"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/dispatcher"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/keyrotation"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/outputcheck"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
@@ -26,6 +29,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	stateStore "github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/composable"
 	"github.com/elastic/elastic-agent/internal/pkg/composable/providers/kubernetes"
@@ -43,6 +47,7 @@ import (
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/features"
 	"github.com/elastic/elastic-agent/pkg/limits"
+	"github.com/elastic/elastic-agent/pkg/utils"
 	"github.com/elastic/elastic-agent/version"
 )
 
@@ -78,10 +83,14 @@ func New(
 	}
 	log.Info("Gathered system information")
 
-	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
+	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform, component.WithUserSpecs(paths.UserComponents()))
 	if err != nil {
 		return nil, nil, nil, fmt.Errorf("failed to detect inputs and outputs: %w", err)
 	}
+	for path, err := range specs.UserSpecErrors() {
+		// non-fatal error, the custom component is not available until its specification is fixed
+		log.With("error.message", err).Warnf("Skipped the custom component specification %s", path)
+	}
 	log.With("inputs", specs.Inputs()).Info("Detected available inputs and outputs")
 
 	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), log)
@@ -144,7 +153,8 @@ func New(
 
 	var configMgr coordinator.ConfigManager
 	var managed *managedConfigManager
-	var compModifiers = []coordinator.ComponentsModifier{InjectAPMConfig}
+	var migrationStateResetter coordinator.MigrationStateResetter
+	var compModifiers = []coordinator.ComponentsModifier{InjectAPMConfig, InjectEventAnnotations, InjectLocationMetadata}
 	var composableManaged bool
 	var isManaged bool
 	var actionAcker acker.Acker
@@ -156,19 +166,23 @@ func New(
 	} else if configuration.IsStandalone(cfg.Fleet) {
 		log.Info("Parsed configuration and determined agent is managed locally")
 
-		loader := config.NewLoader(log, paths.ExternalInputs())
+		loader := config.NewLoader(log, paths.ExternalInputs(), paths.ExternalOutputs())
 		rawCfgMap, err := rawConfig.ToMapStr()
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("failed to transform agent configuration into a map: %w", err)
 		}
-		discover := config.Discoverer(pathConfigFile, cfg.Settings.Path, paths.ExternalInputs(),
-			kubernetes.GetHintsInputConfigPath(log, rawCfgMap))
+		patterns := []string{pathConfigFile, cfg.Settings.Path, paths.ExternalInputs(), paths.ExternalOutputs(),
+			kubernetes.GetHintsInputConfigPath(log, rawCfgMap)}
+		discover := config.Discoverer(patterns...)
 		if !cfg.Settings.Reload.Enabled {
 			log.Debug("Reloading of configuration is off")
 			configMgr = newOnce(log, discover, loader)
+		} else if cfg.Settings.Reload.WatchFiles() {
+			log.Debug("Reloading of configuration is on, watching the configuration files for changes")
+			configMgr = newWatched(log, patternDirs(patterns), cfg.Settings.Reload.Period, discover, loader, validateLocalConfig)
 		} else {
 			log.Debugf("Reloading of configuration is on, frequency is set to %s", cfg.Settings.Reload.Period)
-			configMgr = newPeriodic(log, cfg.Settings.Reload.Period, discover, loader)
+			configMgr = newPeriodic(log, cfg.Settings.Reload.Period, discover, loader, validateLocalConfig)
 		}
 	} else {
 		isManaged = true
@@ -194,6 +208,15 @@ func New(
 				InjectProxyEndpointModifier(),
 			)
 
+			// the client certificate issued by Fleet is only stored in the vault
+			hasRoot, err := utils.HasRoot()
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("checking if running with root/Administrator privileges: %w", err)
+			}
+			if err := clientcert.Load(ctx, cfg.Fleet, vault.WithUnprivileged(!hasRoot)); err != nil {
+				return nil, nil, nil, errors.New(err, "fail to load the Fleet client certificate", errors.TypeSecurity)
+			}
+
 			client, err := fleetclient.NewAuthWithConfig(log, cfg.Fleet.AccessAPIKey, cfg.Fleet.Client)
 			if err != nil {
 				return nil, nil, nil, errors.New(err,
@@ -205,6 +228,7 @@ func New(
 			if err != nil {
 				return nil, nil, nil, errors.New(err, fmt.Sprintf("fail to read state store '%s'", paths.AgentStateStoreFile()))
 			}
+			migrationStateResetter = stateStorage
 
 			fleetAcker, err := fleet.NewAcker(log, agentInfo, client)
 			if err != nil {
@@ -245,10 +269,25 @@ func New(
 		return nil, nil, nil, fmt.Errorf("failed to create otel manager: %w", err)
 	}
 	coord := coordinator.New(log, cfg, logLevel, agentInfo, specs, reexec, upgrader, runtime, configMgr, varsManager, caps, monitor, isManaged, otelManager, actionAcker, initialUpgradeDetails, compModifiers...)
+	if !testingMode {
+		// re-render the component model when components are added, updated or removed without a restart
+		coord.RegisterSpecsWatcher(coordinator.NewComponentsDirWatcher(log, paths.Components(), paths.UserComponents(), platform))
+	}
+	if migrationStateResetter != nil {
+		coord.RegisterMigrationStateResetter(migrationStateResetter)
+	}
+	coord.RegisterOutputChecker(outputcheck.New(log.Named("output_check")))
 	if managed != nil {
 		// the coordinator requires the config manager as well as in managed-mode the config manager requires the
 		// coordinator, so it must be set here once the coordinator is created
 		managed.coord = coord
+
+		outputKeysStore, err := storage.NewEncryptedDiskStore(ctx, paths.AgentOutputKeysFile())
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create the output keys store: %w", err)
+		}
+		managed.outputKeys = keyrotation.New(log.Named("output_key_rotation"), coord, agentInfo, outputKeysStore)
+		coord.RegisterOutputKeyRotation(managed.outputKeys)
 	}
 
 	// every time we change the limits we'll see the log message
@@ -0,0 +1,266 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package autocapture captures diagnostics bundles automatically when the agent or one of its components stays
+// failed, so that the data needed to investigate an incident exists even once it is over.
+//
+// A bundle is captured once the agent or a component has been failed for longer than the configured duration,
+// and at most once per failure: it must recover before another of its failures is captured. The bundles are
+// written to the diagnostics directory of the data path and only the most recent ones are kept.
+package autocapture
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// agentID identifies the failures of the agent itself.
+const agentID = "elastic-agent"
+
+// Config is the configuration of the automatic capture, read from agent.diagnostics.auto_capture.
+type Config struct {
+	// Enabled turns the automatic capture on, it is off by default.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// FailedFor is how long the agent or a component must stay failed for a bundle to be captured.
+	FailedFor time.Duration `config:"failed_for" yaml:"failed_for"`
+	// Interval is the minimum time between two captures, a failure lasting past it is captured once it elapsed.
+	Interval time.Duration `config:"interval" yaml:"interval"`
+	// MaxBundles is the number of bundles kept, the oldest ones are removed.
+	MaxBundles int `config:"max_bundles" yaml:"max_bundles"`
+}
+
+// DefaultConfig returns the default configuration of the automatic capture.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:    false,
+		FailedFor:  30 * time.Second,
+		Interval:   10 * time.Minute,
+		MaxBundles: 5,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.FailedFor < 0 {
+		return fmt.Errorf("invalid diagnostics auto capture failed_for %s, must not be negative", c.FailedFor)
+	}
+	if c.Interval < 0 {
+		return fmt.Errorf("invalid diagnostics auto capture interval %s, must not be negative", c.Interval)
+	}
+	if c.MaxBundles < 1 {
+		return fmt.Errorf("invalid diagnostics auto capture max_bundles %d, must be at least 1", c.MaxBundles)
+	}
+	return nil
+}
+
+// Coordinator is the part of the Coordinator the automatic capture observes and collects diagnostics from.
+type Coordinator interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+	DiagnosticHooks() diagnostics.Hooks
+	PerformDiagnostics(ctx context.Context, req ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+}
+
+// failure is an ongoing failure of the agent or of a component.
+type failure struct {
+	ID       string    `yaml:"id"`
+	Since    time.Time `yaml:"since"`
+	Message  string    `yaml:"message"`
+	captured bool
+}
+
+// Capturer captures diagnostics bundles when the agent or its components stay failed.
+type Capturer struct {
+	log   *logger.Logger
+	coord Coordinator
+	// topPath is the top path of the agent, the logs of the bundles are collected from it.
+	topPath string
+	// bundlesDir is the directory the bundles are written to.
+	bundlesDir string
+
+	mx  sync.Mutex
+	cfg Config
+	// reloadCh signals a new configuration to the Run goroutine.
+	reloadCh chan struct{}
+
+	now func() time.Time
+
+	// the following are only accessed by the Run goroutine
+	failures    map[string]*failure
+	lastCapture time.Time
+}
+
+// New creates a Capturer, disabled until a configuration enabling it is reloaded. The bundles are written to
+// the diagnostics directory of the data path under topPath.
+func New(log *logger.Logger, coord Coordinator, topPath string) *Capturer {
+	return &Capturer{
+		log:        log,
+		coord:      coord,
+		topPath:    topPath,
+		bundlesDir: filepath.Join(paths.DataFrom(topPath), "diagnostics"),
+		cfg:        DefaultConfig(),
+		reloadCh:   make(chan struct{}, 1),
+		now:        time.Now,
+		failures:   make(map[string]*failure),
+	}
+}
+
+// Reload reads the automatic capture settings from the agent configuration.
+func (c *Capturer) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		AutoCapture Config `config:"agent.diagnostics.auto_capture"`
+	}{
+		AutoCapture: DefaultConfig(),
+	}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack diagnostics auto capture config: %w", err)
+	}
+
+	c.mx.Lock()
+	c.cfg = cfg.AutoCapture
+	c.mx.Unlock()
+
+	select {
+	case c.reloadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (c *Capturer) config() Config {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.cfg
+}
+
+// Run observes the state of the agent and captures the bundles until the context is done.
+func (c *Capturer) Run(ctx context.Context) {
+	stateCh := c.coord.StateSubscribe(ctx, 32)
+	check := time.NewTimer(time.Hour)
+	check.Stop()
+	defer check.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.reloadCh:
+		case state, ok := <-stateCh:
+			if !ok {
+				return
+			}
+			c.observe(state)
+		case <-check.C:
+			c.capture(ctx)
+		}
+
+		if next, ok := c.nextCapture(); ok {
+			check.Reset(max(next.Sub(c.now()), 0))
+		} else {
+			check.Stop()
+		}
+	}
+}
+
+// observe tracks the failures of the agent and of the components, a failure is forgotten once recovered.
+func (c *Capturer) observe(state coordinator.State) {
+	now := c.now()
+	failed := make(map[string]string)
+	if state.State == agentclient.Failed {
+		failed[agentID] = state.Message
+	}
+	for _, comp := range state.Components {
+		if comp.State.State == client.UnitStateFailed {
+			failed[comp.Component.ID] = comp.State.Message
+		}
+	}
+
+	for id := range c.failures {
+		if _, ok := failed[id]; !ok {
+			delete(c.failures, id)
+		}
+	}
+	for id, message := range failed {
+		f, ok := c.failures[id]
+		if !ok {
+			f = &failure{ID: id, Since: now}
+			c.failures[id] = f
+		}
+		f.Message = message
+	}
+}
+
+// nextCapture returns when the next bundle must be captured, if a failure is not captured yet.
+func (c *Capturer) nextCapture() (time.Time, bool) {
+	cfg := c.config()
+	if !cfg.Enabled {
+		return time.Time{}, false
+	}
+	var next time.Time
+	for _, f := range c.failures {
+		if f.captured {
+			continue
+		}
+		if due := f.Since.Add(cfg.FailedFor); next.IsZero() || due.Before(next) {
+			next = due
+		}
+	}
+	if next.IsZero() {
+		return next, false
+	}
+	if !c.lastCapture.IsZero() {
+		if earliest := c.lastCapture.Add(cfg.Interval); next.Before(earliest) {
+			next = earliest
+		}
+	}
+	return next, true
+}
+
+// capture writes a bundle for the failures lasting longer than the configured duration.
+func (c *Capturer) capture(ctx context.Context) {
+	cfg := c.config()
+	now := c.now()
+	if !cfg.Enabled || (!c.lastCapture.IsZero() && now.Before(c.lastCapture.Add(cfg.Interval))) {
+		return
+	}
+	var due []*failure
+	for _, f := range c.failures {
+		if !f.captured && now.Sub(f.Since) >= cfg.FailedFor {
+			due = append(due, f)
+		}
+	}
+	if len(due) == 0 {
+		return
+	}
+	slices.SortFunc(due, func(a, b *failure) int { return a.Since.Compare(b.Since) })
+
+	ids := make([]string, 0, len(due))
+	for _, f := range due {
+		f.captured = true
+		ids = append(ids, f.ID)
+	}
+	c.lastCapture = now
+
+	path, err := c.captureDiagnostics(ctx, cfg, due, now)
+	if err != nil {
+		c.log.Errorf("failed to capture the diagnostics of the failure of %v: %v", ids, err)
+		return
+	}
+	c.log.Infof("captured the diagnostics of the failure of %v in %s", ids, path)
+}
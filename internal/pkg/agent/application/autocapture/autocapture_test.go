@@ -0,0 +1,194 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package autocapture
+
+import (
+	"archive/zip"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeCoordinator struct {
+	captures int
+}
+
+func (c *fakeCoordinator) StateSubscribe(context.Context, int) chan coordinator.State {
+	return make(chan coordinator.State)
+}
+
+func (c *fakeCoordinator) DiagnosticHooks() diagnostics.Hooks {
+	return diagnostics.Hooks{{
+		Name:        "state",
+		Filename:    "state.yaml",
+		ContentType: "application/yaml",
+		Hook:        func(context.Context) []byte { return []byte("state: FAILED\n") },
+	}}
+}
+
+func (c *fakeCoordinator) PerformDiagnostics(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic {
+	return nil
+}
+
+func (c *fakeCoordinator) PerformComponentDiagnostics(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+	c.captures++
+	return nil, nil
+}
+
+func newTestCapturer(t *testing.T, cfg map[string]interface{}) (*Capturer, *fakeCoordinator, *time.Time) {
+	log, _ := loggertest.New("autocapture")
+	coord := &fakeCoordinator{}
+	c := New(log, coord, t.TempDir())
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	require.NoError(t, c.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.diagnostics.auto_capture": cfg,
+	})))
+	return c, coord, &now
+}
+
+func agentState(state agentclient.State, components ...runtime.ComponentComponentState) coordinator.State {
+	return coordinator.State{State: state, Message: string(state.String()), Components: components}
+}
+
+func componentState(id string, state client.UnitState) runtime.ComponentComponentState {
+	return runtime.ComponentComponentState{
+		Component: component.Component{ID: id},
+		State:     runtime.ComponentState{State: state, Message: "crashed"},
+	}
+}
+
+func bundles(t *testing.T, c *Capturer) []string {
+	bundles, err := filepath.Glob(filepath.Join(c.bundlesDir, bundlePrefix+"*.zip"))
+	require.NoError(t, err)
+	return bundles
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate())
+
+	for name, modify := range map[string]func(c *Config){
+		"negative failed_for": func(c *Config) { c.FailedFor = -time.Second },
+		"negative interval":   func(c *Config) { c.Interval = -time.Second },
+		"zero max_bundles":    func(c *Config) { c.MaxBundles = 0 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			modify(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestCapturerDisabled(t *testing.T) {
+	c, _, _ := newTestCapturer(t, map[string]interface{}{})
+	c.observe(agentState(agentclient.Failed))
+	_, ok := c.nextCapture()
+	assert.False(t, ok, "nothing should be captured while disabled")
+}
+
+func TestCapturerCapturesLastingFailures(t *testing.T) {
+	c, coord, now := newTestCapturer(t, map[string]interface{}{
+		"enabled":    true,
+		"failed_for": "30s",
+		"interval":   "0s",
+	})
+
+	// a transient failure is not captured
+	c.observe(agentState(agentclient.Healthy, componentState("filestream-default", client.UnitStateFailed)))
+	next, ok := c.nextCapture()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(30*time.Second), next)
+	*now = now.Add(10 * time.Second)
+	c.observe(agentState(agentclient.Healthy, componentState("filestream-default", client.UnitStateHealthy)))
+	_, ok = c.nextCapture()
+	assert.False(t, ok, "a recovered failure should not be captured")
+
+	// a lasting failure is captured once
+	c.observe(agentState(agentclient.Failed, componentState("filestream-default", client.UnitStateFailed)))
+	*now = now.Add(30 * time.Second)
+	c.capture(context.Background())
+	assert.Equal(t, 1, coord.captures)
+	require.Len(t, bundles(t, c), 1)
+	_, ok = c.nextCapture()
+	assert.False(t, ok, "a captured failure should not be captured again")
+
+	zr, err := zip.OpenReader(bundles(t, c)[0])
+	require.NoError(t, err)
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "auto_capture.yaml")
+	assert.Contains(t, names, "state.yaml")
+
+	// it is captured again once it recovered and failed again
+	c.observe(agentState(agentclient.Healthy))
+	c.observe(agentState(agentclient.Healthy, componentState("filestream-default", client.UnitStateFailed)))
+	*now = now.Add(time.Minute)
+	c.capture(context.Background())
+	assert.Equal(t, 2, coord.captures)
+}
+
+func TestCapturerInterval(t *testing.T) {
+	c, coord, now := newTestCapturer(t, map[string]interface{}{
+		"enabled":    true,
+		"failed_for": "0s",
+		"interval":   "10m",
+	})
+
+	c.observe(agentState(agentclient.Healthy, componentState("filestream-default", client.UnitStateFailed)))
+	c.capture(context.Background())
+	assert.Equal(t, 1, coord.captures)
+
+	// a failure within the interval is captured once it elapsed
+	*now = now.Add(time.Minute)
+	c.observe(agentState(agentclient.Healthy,
+		componentState("filestream-default", client.UnitStateFailed),
+		componentState("system-default", client.UnitStateFailed)))
+	next, ok := c.nextCapture()
+	require.True(t, ok)
+	assert.Equal(t, now.Add(9*time.Minute), next)
+	c.capture(context.Background())
+	assert.Equal(t, 1, coord.captures)
+
+	*now = next
+	c.capture(context.Background())
+	assert.Equal(t, 2, coord.captures)
+}
+
+func TestCapturerMaxBundles(t *testing.T) {
+	c, _, now := newTestCapturer(t, map[string]interface{}{
+		"enabled":     true,
+		"failed_for":  "0s",
+		"interval":    "0s",
+		"max_bundles": 2,
+	})
+
+	for range 3 {
+		c.observe(agentState(agentclient.Failed))
+		c.capture(context.Background())
+		c.observe(agentState(agentclient.Healthy))
+		*now = now.Add(time.Minute)
+	}
+	assert.Len(t, bundles(t, c), 2, "only the most recent bundles should be kept")
+}
@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package autocapture
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+const (
+	// diagnosticsTimeout is the time given to the agent and the components to answer the diagnostics requests.
+	diagnosticsTimeout = time.Minute
+
+	bundlePrefix = "auto-diagnostics-"
+)
+
+// captureDiagnostics writes a diagnostics bundle of the agent and of all its components, with the logs of the
+// agent and the failures that triggered it, and returns its path.
+func (c *Capturer) captureDiagnostics(ctx context.Context, cfg Config, failures []*failure, now time.Time) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsTimeout)
+	defer cancel()
+
+	trigger, err := yaml.Marshal(struct {
+		FailedFor time.Duration `yaml:"failed_for"`
+		Failures  []*failure    `yaml:"failures"`
+	}{
+		FailedFor: cfg.FailedFor,
+		Failures:  failures,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the failures: %w", err)
+	}
+	agentDiag := []client.DiagnosticFileResult{{
+		Name:        "auto_capture",
+		Filename:    "auto_capture.yaml",
+		Description: "Failures that triggered the automatic capture of the diagnostics",
+		ContentType: "application/yaml",
+		Content:     trigger,
+		Generated:   now,
+	}}
+	for _, hook := range append(c.coord.DiagnosticHooks(), diagnostics.GlobalHooks()...) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		agentDiag = append(agentDiag, client.DiagnosticFileResult{
+			Name:        hook.Name,
+			Filename:    hook.Filename,
+			Description: hook.Description,
+			ContentType: hook.ContentType,
+			Content:     hook.Hook(ctx),
+			Generated:   time.Now().UTC(),
+		})
+	}
+
+	return diagnostics.Bundle{
+		Dir:     c.bundlesDir,
+		Prefix:  bundlePrefix,
+		Keep:    cfg.MaxBundles,
+		TopPath: c.topPath,
+		Agent:   agentDiag,
+	}.Write(ctx, c.log, c.coord, now)
+}
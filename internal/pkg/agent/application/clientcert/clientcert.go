@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package clientcert handles the client certificate of the mutual TLS with Fleet Server issued by Fleet.
+//
+// The Elastic Agent generates its private key and sends a certificate signing request when enrolling,
+// Fleet answers with the issued certificate. The certificate and its key are stored in the vault and
+// passed to the Fleet client configuration when the agent starts. The certificate is renewed with a new
+// key and request before it expires, authenticated by the current certificate.
+package clientcert
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+)
+
+// errNoCertificate is returned when Fleet didn't issue a certificate for the request.
+var errNoCertificate = errors.New("fleet did not issue a client certificate, check that the certificate authority of the agents is configured in Fleet")
+
+// NewRequest generates a private key and a certificate signing request for it, both PEM encoded.
+func NewRequest(commonName string) (key string, csr string, err error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate the private key: %w", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode the private key: %w", err)
+	}
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: commonName},
+	}, privateKey)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create the certificate signing request: %w", err)
+	}
+	key = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	csr = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER}))
+	return key, csr, nil
+}
+
+// Issued returns the certificate issued by Fleet for the request of the private key, checking that it
+// matches the key.
+func Issued(key string, certificate string) (secret.ClientCertificate, *x509.Certificate, error) {
+	cert := secret.ClientCertificate{Certificate: certificate, Key: key}
+	if certificate == "" {
+		return cert, nil, errNoCertificate
+	}
+	leaf, err := Verify(cert)
+	return cert, leaf, err
+}
+
+// Verify checks that the certificate matches its private key and returns the parsed certificate.
+func Verify(cert secret.ClientCertificate) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair([]byte(cert.Certificate), []byte(cert.Key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %w", err)
+	}
+	return leaf, nil
+}
+
+// Apply sets the certificate as the client certificate of the Fleet client configuration, the TLS
+// configuration is copied as it can be shared with other copies of the configuration.
+func Apply(cfg *remote.Config, cert secret.ClientCertificate) {
+	tlsCfg := tlscommon.Config{}
+	if cfg.Transport.TLS != nil {
+		tlsCfg = *cfg.Transport.TLS
+	}
+	tlsCfg.Certificate = tlscommon.CertificateConfig{
+		Certificate: cert.Certificate,
+		Key:         cert.Key,
+	}
+	cfg.Transport.TLS = &tlsCfg
+}
+
+// Without returns a copy of the Fleet configuration without the certificate issued by Fleet, so it's
+// only stored in the vault.
+func Without(cfg *configuration.FleetAgentConfig) *configuration.FleetAgentConfig {
+	if !cfg.IssuedClientCertificate() || cfg.Client.Transport.TLS == nil {
+		return cfg
+	}
+	c := *cfg
+	tlsCfg := *cfg.Client.Transport.TLS
+	tlsCfg.Certificate = tlscommon.CertificateConfig{}
+	c.Client.Transport.TLS = &tlsCfg
+	return &c
+}
+
+// Load reads the certificate issued by Fleet from the vault and sets it in the Fleet client configuration,
+// nothing is done when the certificate is not issued by Fleet.
+func Load(ctx context.Context, cfg *configuration.FleetAgentConfig, opts ...vault.OptionFunc) error {
+	if !cfg.IssuedClientCertificate() {
+		return nil
+	}
+	cert, err := secret.GetClientCertificate(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to read the client certificate from the vault: %w", err)
+	}
+	if _, err := Verify(cert); err != nil {
+		return err
+	}
+	Apply(&cfg.Client, cert)
+	return nil
+}
@@ -0,0 +1,184 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package clientcert
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+// testCA issues the certificates of the certificate signing requests.
+type testCA struct {
+	t        *testing.T
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	validity time.Duration
+	serial   int64
+}
+
+func newTestCA(t *testing.T, validity time.Duration) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fleet agents CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{t: t, cert: cert, key: key, validity: validity, serial: 1}
+}
+
+func (ca *testCA) issue(csrPEM string) string {
+	block, _ := pem.Decode([]byte(csrPEM))
+	require.NotNil(ca.t, block)
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	require.NoError(ca.t, err)
+	require.NoError(ca.t, csr.CheckSignature())
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      csr.Subject,
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(ca.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, csr.PublicKey, ca.key)
+	require.NoError(ca.t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+// Send answers the certificate renewal requests.
+func (ca *testCA) Send(_ context.Context, _ string, _ string, _ url.Values, _ http.Header, body io.Reader) (*http.Response, error) {
+	req := &fleetapi.RenewCertificateRequest{}
+	require.NoError(ca.t, json.NewDecoder(body).Decode(req))
+	b, err := json.Marshal(fleetapi.RenewCertificateResponse{Certificate: ca.issue(req.CSR)})
+	require.NoError(ca.t, err)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(bytes.NewReader(b))}, nil
+}
+
+func (ca *testCA) URI() string {
+	return "https://localhost:8220"
+}
+
+type agentInfo struct{}
+
+func (agentInfo) AgentID() string {
+	return "agent-id"
+}
+
+func testVaultOptions(t *testing.T) []vault.OptionFunc {
+	return []vault.OptionFunc{
+		vault.WithVaultPath(filepath.Join(t.TempDir(), "vault")),
+		vault.WithUnprivileged(true),
+	}
+}
+
+func TestIssued(t *testing.T) {
+	ca := newTestCA(t, time.Hour)
+	key, csr, err := NewRequest("agent-id")
+	require.NoError(t, err)
+
+	cert, leaf, err := Issued(key, ca.issue(csr))
+	require.NoError(t, err)
+	assert.Equal(t, "agent-id", leaf.Subject.CommonName)
+	assert.Equal(t, key, cert.Key)
+
+	otherKey, _, err := NewRequest("agent-id")
+	require.NoError(t, err)
+	_, _, err = Issued(otherKey, cert.Certificate)
+	assert.Error(t, err, "a certificate not matching the key should be rejected")
+
+	_, _, err = Issued(key, "")
+	assert.ErrorIs(t, err, errNoCertificate)
+}
+
+func TestLoadAndWithout(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "secret storage does not use NewGCMWithRandomNonce.")
+	opts := testVaultOptions(t)
+	ctx := context.Background()
+	ca := newTestCA(t, time.Hour)
+	key, csr, err := NewRequest("agent-id")
+	require.NoError(t, err)
+	cert, _, err := Issued(key, ca.issue(csr))
+	require.NoError(t, err)
+	require.NoError(t, secret.SetClientCertificate(ctx, cert, opts...))
+
+	cfg := configuration.DefaultFleetAgentConfig()
+	require.NoError(t, Load(ctx, cfg, opts...))
+	assert.Nil(t, cfg.Client.Transport.TLS, "the certificate should only be loaded when issued by Fleet")
+
+	cfg.ClientCertificate = &configuration.FleetClientCertificateConfig{Enabled: true}
+	require.NoError(t, Load(ctx, cfg, opts...))
+	require.NotNil(t, cfg.Client.Transport.TLS)
+	assert.Equal(t, cert.Certificate, cfg.Client.Transport.TLS.Certificate.Certificate)
+	assert.Equal(t, cert.Key, cfg.Client.Transport.TLS.Certificate.Key)
+
+	stored := Without(cfg)
+	assert.Empty(t, stored.Client.Transport.TLS.Certificate.Key)
+	assert.Equal(t, cert.Key, cfg.Client.Transport.TLS.Certificate.Key, "the configuration in use should not be changed")
+}
+
+func TestRenew(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "secret storage does not use NewGCMWithRandomNonce.")
+	opts := testVaultOptions(t)
+	ctx := context.Background()
+	ca := newTestCA(t, time.Hour)
+	log, _ := loggertest.New("clientcert")
+
+	var renewed []secret.ClientCertificate
+	r := NewRenewer(log, agentInfo{}, 0, func(cert secret.ClientCertificate) error {
+		renewed = append(renewed, cert)
+		return nil
+	}, opts...)
+
+	_, err := r.renew(ctx)
+	assert.Error(t, err, "the certificate cannot be renewed without a client")
+
+	r.SetClient(ca)
+	leaf, err := r.renew(ctx)
+	require.NoError(t, err)
+	require.Len(t, renewed, 1)
+	stored, err := secret.GetClientCertificate(ctx, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, renewed[0], stored)
+
+	// the certificate lives less than the default renew_before, it's renewed a third of its lifetime before
+	// it expires
+	assert.Equal(t, leaf.NotAfter.Add(-leaf.NotAfter.Sub(leaf.NotBefore)/3), r.renewAt(leaf))
+	r.renewBefore = 10 * time.Minute
+	assert.Equal(t, leaf.NotAfter.Add(-10*time.Minute), r.renewAt(leaf))
+}
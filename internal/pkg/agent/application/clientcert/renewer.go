@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package clientcert
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// DefaultRenewBefore is how long before its expiration the certificate is renewed by default.
+	DefaultRenewBefore = 7 * 24 * time.Hour
+	// retryInterval is the time waited before retrying a failed renewal.
+	retryInterval = 10 * time.Minute
+	// requestTimeout is the time given to Fleet to issue a certificate.
+	requestTimeout = time.Minute
+)
+
+// Renewer renews the client certificate issued by Fleet before it expires.
+type Renewer struct {
+	log         *logger.Logger
+	info        fleetapi.AgentInfo
+	renewBefore time.Duration
+	vaultOpts   []vault.OptionFunc
+	// onRenew is called with the renewed certificate, once stored in the vault, to use it for the next
+	// connections to Fleet Server.
+	onRenew func(secret.ClientCertificate) error
+
+	mx     sync.Mutex
+	client fleetclient.Sender
+
+	now func() time.Time
+}
+
+// NewRenewer creates the renewal of the client certificate issued by Fleet, onRenew is called with each
+// renewed certificate.
+func NewRenewer(log *logger.Logger, info fleetapi.AgentInfo, renewBefore time.Duration, onRenew func(secret.ClientCertificate) error, vaultOpts ...vault.OptionFunc) *Renewer {
+	if renewBefore <= 0 {
+		renewBefore = DefaultRenewBefore
+	}
+	return &Renewer{
+		log:         log,
+		info:        info,
+		renewBefore: renewBefore,
+		vaultOpts:   vaultOpts,
+		onRenew:     onRenew,
+		now:         time.Now,
+	}
+}
+
+// SetClient sets the client used to request the certificates.
+func (r *Renewer) SetClient(c fleetclient.Sender) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.client = c
+}
+
+func (r *Renewer) sender() fleetclient.Sender {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.client
+}
+
+// Run renews the certificate before it expires until the context is done.
+func (r *Renewer) Run(ctx context.Context) error {
+	cert, err := secret.GetClientCertificate(ctx, r.vaultOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to read the client certificate from the vault: %w", err)
+	}
+	leaf, err := Verify(cert)
+	if err != nil {
+		return err
+	}
+
+	next := r.renewAt(leaf)
+	for {
+		r.log.Debugf("renewing the client certificate expiring at %s at %s", leaf.NotAfter.Format(time.RFC3339), next.Format(time.RFC3339))
+		t := time.NewTimer(next.Sub(r.now()))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+
+		renewed, err := r.renew(ctx)
+		if err != nil {
+			r.log.Errorf("failed to renew the client certificate expiring at %s, retrying in %s: %v", leaf.NotAfter.Format(time.RFC3339), retryInterval, err)
+			next = r.now().Add(retryInterval)
+			continue
+		}
+		leaf = renewed
+		next = r.renewAt(leaf)
+		r.log.Infof("renewed the client certificate, it expires at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+}
+
+// renewAt returns when the certificate must be renewed, a third of its lifetime before it expires when
+// its lifetime is shorter than renewBefore.
+func (r *Renewer) renewAt(leaf *x509.Certificate) time.Time {
+	before := r.renewBefore
+	if lifetime := leaf.NotAfter.Sub(leaf.NotBefore); before >= lifetime {
+		before = lifetime / 3
+	}
+	return leaf.NotAfter.Add(-before)
+}
+
+// renew requests a certificate for a new private key, stores it in the vault and passes it to onRenew.
+func (r *Renewer) renew(ctx context.Context) (*x509.Certificate, error) {
+	c := r.sender()
+	if c == nil {
+		return nil, errors.New("no fleet client")
+	}
+	key, csr, err := NewRequest(r.info.AgentID())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	resp, err := fleetapi.NewRenewCertificateCmd(r.info, c).Execute(ctx, &fleetapi.RenewCertificateRequest{CSR: csr})
+	if err != nil {
+		return nil, err
+	}
+	cert, leaf, err := Issued(key, resp.Certificate)
+	if err != nil {
+		return nil, err
+	}
+	if err := secret.SetClientCertificate(ctx, cert, r.vaultOpts...); err != nil {
+		return nil, fmt.Errorf("failed to store the client certificate in the vault: %w", err)
+	}
+	if err := r.onRenew(cert); err != nil {
+		// the certificate is stored, it's used after a restart
+		r.log.Errorf("failed to use the renewed client certificate: %v", err)
+	}
+	return leaf, nil
+}
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -38,10 +39,12 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	fleetapiClient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -145,8 +148,9 @@ type RuntimeManager interface {
 	PerformDiagnostics(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
 
 	// PerformComponentDiagnostics executes the diagnostic action for the provided components. If no components are provided,
-	// then it performs the diagnostics for all current units.
-	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+	// then it performs the diagnostics for all current units. The cpuProfile duration is forwarded to the components when
+	// CPU is requested, 0 uses their default duration.
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
 }
 
 // OTelManager provides an interface to run components and plain otel configurations in an otel collector.
@@ -172,7 +176,7 @@ type OTelManager interface {
 
 	// PerformComponentDiagnostics executes the diagnostic action for the provided components. If no components are provided,
 	// then it performs the diagnostics for all current units.
-	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
 }
 
 // ConfigChange provides an interface for receiving a new configuration.
@@ -189,6 +193,26 @@ type ConfigChange interface {
 	Fail(err error)
 }
 
+// ConfigChangeOrigin describes where a configuration change comes from, it is recorded in the policy history.
+type ConfigChangeOrigin struct {
+	// Source is fleet for the policies delivered by Fleet and local for the configuration files.
+	Source string
+	// ActionID is the ID of the Fleet action delivering the policy.
+	ActionID string
+	// User is the user that changed the local configuration.
+	User string
+}
+
+// OriginConfigChange is implemented by the configuration changes that know where they come from.
+type OriginConfigChange interface {
+	Origin() ConfigChangeOrigin
+}
+
+// PolicyRecorder records the policies applied by the Coordinator.
+type PolicyRecorder interface {
+	Record(policy map[string]interface{}, origin ConfigChangeOrigin) error
+}
+
 // ErrorReporter provides an interface for any manager that is handled by the coordinator to report errors.
 type ErrorReporter interface{}
 
@@ -227,6 +251,10 @@ type ComponentsModifier func(comps []component.Component, cfg map[string]interfa
 // to receive termination states from its managers.
 const managerShutdownTimeout = time.Second * 5
 
+// timeConditionsInterval is how often the conditions calling a time function
+// are rendered again, the time functions of EQL have a resolution of a minute.
+var timeConditionsInterval = time.Minute
+
 type configReloader interface {
 	Reload(*config.Config) error
 }
@@ -248,6 +276,22 @@ type Coordinator struct {
 	monitorMgr MonitorManager
 
 	monitoringServerReloader configReloader
+	otlpExporterReloader     configReloader
+	faultHandlerReloader     configReloader
+	outputKeysReloader       configReloader
+	autoCaptureReloader      configReloader
+	controlAuthzReloader     configReloader
+	stopProtectionReloader   configReloader
+	eventLogReloader         configReloader
+	policyGuardrailReloader  configReloader
+	policyRecorder           PolicyRecorder
+	policyRollbackStore      storage.Storage
+
+	specsWatcher SpecsWatcher
+
+	migrationStateResetter MigrationStateResetter
+
+	outputChecker OutputChecker
 
 	runtimeMgr RuntimeManager
 	configMgr  ConfigManager
@@ -291,6 +335,66 @@ type Coordinator struct {
 	// SetUpgradeDetails helper to the Coordinator goroutine.
 	upgradeDetailsChan chan *details.Details
 
+	// fleetServerStateChan forwards hosted Fleet Server states from the publicly
+	// accessible SetFleetServerState helper to the Coordinator goroutine.
+	fleetServerStateChan chan *FleetServerState
+
+	// fleetHostsChan forwards the health of the Fleet Server hosts from the
+	// publicly accessible SetFleetHosts helper to the Coordinator goroutine.
+	fleetHostsChan chan []remote.HostState
+
+	// outputCheckResults are the errors of the outputs that failed their
+	// validation by output name, outputCheckCancel cancels the validation in
+	// progress.
+	outputCheckResults map[string]error
+	outputCheckCancel  context.CancelFunc
+
+	// outputsChecked are the outputs of the validation in progress or done
+	// by output name, an output is only validated again when it changes.
+	// outputsChecking are the names of the outputs of the validation in
+	// progress.
+	outputsChecked  map[string]interface{}
+	outputsChecking []string
+
+	// outputCheckChan forwards the results of the output validation running
+	// in the background to the Coordinator goroutine.
+	outputCheckChan chan outputCheckResults
+
+	// memoryPressure is set while the host or the cgroup of the agent is low on
+	// memory. Re-rendering the policy on variable and PID changes is deferred
+	// until the pressure recovers, refreshDeferred records that one is pending.
+	memoryPressure  bool
+	refreshDeferred bool
+
+	// healthGateAcked is set once the upgrade deferred until the upgraded agent passed
+	// the health gate is acked.
+	healthGateAcked bool
+
+	// memoryPressureChan forwards memory pressure changes from the publicly
+	// accessible SetMemoryPressure helper to the Coordinator goroutine.
+	memoryPressureChan chan bool
+
+	// quarantinedChan forwards the components held out by the fault handling from
+	// the publicly accessible SetQuarantinedComponents helper to the Coordinator goroutine.
+	quarantinedChan chan []QuarantinedComponent
+
+	// policyHealthyChan and policyRollbackChan forward the health of the applied policy from the publicly
+	// accessible MarkPolicyHealthy and RollbackPolicy helpers to the Coordinator goroutine.
+	policyHealthyChan  chan struct{}
+	policyRollbackChan chan string
+
+	// appliedPolicy is the policy applied by the last configuration change, healthyPolicy is the last
+	// policy marked healthy, the one the policy is rolled back to.
+	appliedPolicy appliedPolicy
+	healthyPolicy appliedPolicy
+
+	// outputKeysChan forwards the API keys of the outputs set by the key rotation from
+	// the publicly accessible SetOutputKeys helper to the Coordinator goroutine.
+	outputKeysChan chan outputKeysUpdate
+
+	// outputKeys are the API keys replacing the ones of the outputs of the policy, by output name.
+	outputKeys map[string]string
+
 	// loglevelCh forwards log level changes from the public API (SetLogLevel)
 	// to the run loop in Coordinator's main goroutine.
 	logLevelCh chan logp.Level
@@ -335,12 +439,18 @@ type Coordinator struct {
 	// The current variables
 	vars []*transpiler.Vars
 
+	// Copy of the current variables that is safe to read from external
+	// goroutines. Always updated together with vars via setVars.
+	varsSnapshot atomic.Pointer[[]*transpiler.Vars]
+
 	// The policy after spec and variable substitution
 	derivedConfig map[string]interface{}
 
 	// The final component model generated from ast and vars (this is the same
 	// value that is sent to the runtime manager).
 	componentModel []component.Component
+	// componentModelSnapshot is the last componentModel, for the readers outside the run loop.
+	componentModelSnapshot atomic.Pointer[[]component.Component]
 
 	// Protection section
 	protection protection.Config
@@ -355,9 +465,16 @@ type Coordinator struct {
 	// however, if endpoint is in some kind of restart loop,
 	// we could DOS the config system. Instead,
 	// run a ticker that checks to see if we have a new PID.
+	// Only the PID of the components running as a service is tracked, the
+	// processes spawned by the agent are monitored without their PID.
 	componentPIDTicker         *time.Ticker
 	componentPidRequiresUpdate *atomic.Bool
 
+	// the result of the conditions calling a time function of EQL, e.g. hour(),
+	// changes over time so the component model is rendered again on each tick,
+	// the ticker is only set while the AST has such conditions.
+	timeConditionsTicker *time.Ticker
+
 	// Abstraction for diagnostics AddSecretMarkers function for testability
 	secretMarkerFunc func(*logger.Logger, *config.Config) error
 }
@@ -382,6 +499,8 @@ type managerChans struct {
 	otelManagerError           <-chan error
 
 	upgradeMarkerUpdate <-chan upgrade.UpdateMarker
+
+	specsUpdate <-chan component.RuntimeSpecs
 }
 
 // diffCheck is a container used by checkAndLogUpdate()
@@ -477,6 +596,14 @@ func New(
 		logLevelCh:                 make(chan logp.Level),
 		overrideStateChan:          make(chan *coordinatorOverrideState),
 		upgradeDetailsChan:         make(chan *details.Details),
+		memoryPressureChan:         make(chan bool, 1),
+		quarantinedChan:            make(chan []QuarantinedComponent),
+		outputKeysChan:             make(chan outputKeysUpdate),
+		policyHealthyChan:          make(chan struct{}),
+		policyRollbackChan:         make(chan string),
+		fleetServerStateChan:       make(chan *FleetServerState),
+		fleetHostsChan:             make(chan []remote.HostState),
+		outputCheckChan:            make(chan outputCheckResults),
 		heartbeatChan:              make(chan struct{}),
 		componentPIDTicker:         time.NewTicker(time.Second * 30),
 		componentPidRequiresUpdate: &atomic.Bool{},
@@ -550,6 +677,77 @@ func (c *Coordinator) RegisterMonitoringServer(s configReloader) {
 	c.monitoringServerReloader = s
 }
 
+// RegisterOTLPExporter registers the exporter of the self-monitoring metrics with OTLP, reloaded with
+// each policy. Must be called before Run.
+func (c *Coordinator) RegisterOTLPExporter(e configReloader) {
+	c.otlpExporterReloader = e
+}
+
+// RegisterFaultHandler registers the handling of the faulty components, reloaded with each policy.
+// Must be called before Run.
+func (c *Coordinator) RegisterFaultHandler(h configReloader) {
+	c.faultHandlerReloader = h
+}
+
+// RegisterOutputKeyRotation registers the rotation of the API keys of the outputs, reloaded with each
+// policy. Must be called before Run.
+func (c *Coordinator) RegisterOutputKeyRotation(r configReloader) {
+	c.outputKeysReloader = r
+}
+
+// RegisterDiagnosticsAutoCapture registers the automatic capture of diagnostics bundles on failures, reloaded
+// with each policy. Must be called before Run.
+func (c *Coordinator) RegisterDiagnosticsAutoCapture(a configReloader) {
+	c.autoCaptureReloader = a
+}
+
+// RegisterControlAuthorization registers the authorization of the control protocol, reloaded with each
+// policy. Must be called before Run.
+func (c *Coordinator) RegisterControlAuthorization(a configReloader) {
+	c.controlAuthzReloader = a
+}
+
+// RegisterServiceStopProtection registers the protection of the installed service against manual stops,
+// reloaded with each policy. Must be called before Run.
+func (c *Coordinator) RegisterServiceStopProtection(p configReloader) {
+	c.stopProtectionReloader = p
+}
+
+// RegisterEventLog registers the sink of the lifecycle events to the Windows Event Log, reloaded with each
+// policy. Must be called before Run.
+func (c *Coordinator) RegisterEventLog(e configReloader) {
+	c.eventLogReloader = e
+}
+
+// RegisterPolicyGuardrail registers the rollback of the policy on a failure storm of the components,
+// reloaded with each policy. Must be called before Run.
+func (c *Coordinator) RegisterPolicyGuardrail(g configReloader) {
+	c.policyGuardrailReloader = g
+}
+
+// RegisterPolicyRecorder registers the recorder of the applied policies. Must be called before Run.
+func (c *Coordinator) RegisterPolicyRecorder(r PolicyRecorder) {
+	c.policyRecorder = r
+}
+
+// MigrationStateResetter resets the local state bound to the Fleet cluster the agent migrates away from.
+type MigrationStateResetter interface {
+	ResetForMigration() error
+}
+
+// RegisterMigrationStateResetter registers the state reset once the agent is enrolled into the target
+// cluster of a migration. Must be called before Run.
+func (c *Coordinator) RegisterMigrationStateResetter(r MigrationStateResetter) {
+	c.migrationStateResetter = r
+}
+
+// RegisterSpecsWatcher registers the watcher that reports changes to the component specifications,
+// the component model is regenerated with the new specifications. Must be called before Run.
+func (c *Coordinator) RegisterSpecsWatcher(w SpecsWatcher) {
+	c.specsWatcher = w
+	c.managerChans.specsUpdate = w.Watch()
+}
+
 // StateSubscribe returns a channel that reports changes in Coordinator state.
 //
 // bufferLen specifies how many state changes should be queued in addition to
@@ -571,6 +769,32 @@ func (c *Coordinator) StateSubscribe(ctx context.Context, bufferLen int) chan St
 	return c.stateBroadcaster.Subscribe(ctx, bufferLen)
 }
 
+// Vars returns the default provider and the current variables resolved from the providers.
+// Returns nil variables when no variables have been resolved yet.
+// Called by external goroutines.
+func (c *Coordinator) Vars() (string, []*transpiler.Vars) {
+	var defaultProvider string
+	if c.varsMgr != nil {
+		defaultProvider = c.varsMgr.DefaultProvider()
+	}
+	vars := c.varsSnapshot.Load()
+	if vars == nil {
+		return defaultProvider, nil
+	}
+	return defaultProvider, *vars
+}
+
+// ComponentModel returns the component model computed from the policy, as sent to the runtime manager.
+// Returns nil when no component model has been computed yet.
+// Called by external goroutines.
+func (c *Coordinator) ComponentModel() []component.Component {
+	comps := c.componentModelSnapshot.Load()
+	if comps == nil {
+		return nil
+	}
+	return *comps
+}
+
 // Disabled for 8.8.0 release in order to limit the surface
 // https://github.com/elastic/security-team/issues/6501
 
@@ -595,6 +819,7 @@ func (c *Coordinator) ReExec(callback reexec.ShutdownCallbackFn, argOverrides ..
 
 // Migrate migrates agent to a new cluster and ACKs success to the old one.
 // In case of failure no ack is performed and error is returned.
+// A migration without action ID is initiated locally and is not acked.
 func (c *Coordinator) Migrate(ctx context.Context, action *fleetapi.ActionMigrate, backoffFactory func(done <-chan struct{}) backoff.Backoff) error {
 	if !c.isManaged {
 		return ErrNotManaged
@@ -666,8 +891,18 @@ func (c *Coordinator) Migrate(ctx context.Context, action *fleetapi.ActionMigrat
 	}
 
 	// ACK success to source fleet server
-	if err := c.ackMigration(ctx, action, c.fleetAcker); err != nil {
-		c.logger.Warnf("failed to ACK success: %v", err)
+	if action.ActionID != "" {
+		if err := c.ackMigration(ctx, action, c.fleetAcker); err != nil {
+			c.logger.Warnf("failed to ACK success: %v", err)
+		}
+	}
+
+	// the ack token and the scheduled actions of the source cluster mean nothing to the target one,
+	// the current policy is kept to keep data flowing until the target cluster sends its own
+	if c.migrationStateResetter != nil {
+		if err := c.migrationStateResetter.ResetForMigration(); err != nil {
+			c.logger.Warnf("failed to reset the state of the source cluster: %v", err)
+		}
 	}
 
 	if err := cleanBackupConfig(); err != nil {
@@ -843,14 +1078,14 @@ func (c *Coordinator) PerformDiagnostics(ctx context.Context, req ...runtime.Com
 }
 
 // PerformComponentDiagnostics executes the diagnostic action for the provided components.
-func (c *Coordinator) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+func (c *Coordinator) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
 	var diags []runtime.ComponentDiagnostic
-	runtimeDiags, runtimeErr := c.runtimeMgr.PerformComponentDiagnostics(ctx, additionalMetrics, req...)
+	runtimeDiags, runtimeErr := c.runtimeMgr.PerformComponentDiagnostics(ctx, additionalMetrics, cpuProfile, req...)
 	if runtimeErr != nil {
 		runtimeErr = fmt.Errorf("runtime diagnostics failed: %w", runtimeErr)
 	}
 	diags = append(diags, runtimeDiags...)
-	otelDiags, otelErr := c.otelMgr.PerformComponentDiagnostics(ctx, additionalMetrics, req...)
+	otelDiags, otelErr := c.otelMgr.PerformComponentDiagnostics(ctx, additionalMetrics, cpuProfile, req...)
 	if otelErr != nil {
 		otelErr = fmt.Errorf("otel diagnostics failed: %w", otelErr)
 	}
@@ -1227,6 +1462,8 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 					Components     []StateComponentOutput `yaml:"components"`
 					Collector      *StateCollectorStatus  `yaml:"collector,omitempty"`
 					UpgradeDetails *details.Details       `yaml:"upgrade_details,omitempty"`
+					Blocked        []BlockedComponent     `yaml:"blocked_by_capabilities,omitempty"`
+					OutputKeys     []OutputKeyState       `yaml:"output_keys,omitempty"`
 				}
 
 				var toCollectorStatus func(status *status.AggregateStatus) *StateCollectorStatus
@@ -1270,6 +1507,8 @@ func (c *Coordinator) DiagnosticHooks() diagnostics.Hooks {
 					Components:     compStates,
 					Collector:      collectorStatus,
 					UpgradeDetails: s.UpgradeDetails,
+					Blocked:        s.BlockedComponents,
+					OutputKeys:     s.OutputKeys,
 				}
 				o, err := yaml.Marshal(output)
 				if err != nil {
@@ -1324,6 +1563,7 @@ func (c *Coordinator) runner(ctx context.Context) error {
 	defer cancel()
 
 	defer c.componentPIDTicker.Stop()
+	defer c.setTimeConditions(false)
 
 	// We run nil checks before starting the various managers so that unit tests
 	// only have to initialize / mock the specific components they're testing.
@@ -1387,6 +1627,16 @@ func (c *Coordinator) runner(ctx context.Context) error {
 		upgradeMarkerWatcherErrCh <- nil
 	}
 
+	if c.specsWatcher != nil {
+		go func() {
+			err := c.specsWatcher.Run(ctx)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				// not fatal, changes to the components directory are only noticed on restart
+				c.logger.Errorf("components directory watcher stopped: %s", err)
+			}
+		}()
+	}
+
 	// Keep looping until the context ends.
 	for ctx.Err() == nil {
 		c.runLoopIteration(ctx)
@@ -1405,6 +1655,11 @@ func (c *Coordinator) runner(ctx context.Context) error {
 // runLoopIteration runs one iteration of the Coorinator's internal run
 // loop in a standalone helper function to enable testing.
 func (c *Coordinator) runLoopIteration(ctx context.Context) {
+	var timeConditionsTick <-chan time.Time
+	if c.timeConditionsTicker != nil {
+		timeConditionsTick = c.timeConditionsTicker.C
+	}
+
 	select {
 	case <-ctx.Done():
 		return
@@ -1449,11 +1704,41 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	case upgradeDetails := <-c.upgradeDetailsChan:
 		c.setUpgradeDetails(upgradeDetails)
 
+	case pressure := <-c.memoryPressureChan:
+		c.setMemoryPressure(ctx, pressure)
+
+	case quarantined := <-c.quarantinedChan:
+		c.setQuarantinedComponents(ctx, quarantined)
+
+	case <-c.policyHealthyChan:
+		c.markPolicyHealthy()
+
+	case reason := <-c.policyRollbackChan:
+		if ctx.Err() == nil {
+			c.rollbackPolicy(ctx, reason)
+		}
+
+	case update := <-c.outputKeysChan:
+		c.setOutputKeys(ctx, update)
+
+	case fleetServerState := <-c.fleetServerStateChan:
+		c.setFleetServerState(fleetServerState)
+
+	case fleetHosts := <-c.fleetHostsChan:
+		c.setFleetHosts(fleetHosts)
+
+	case outputCheck := <-c.outputCheckChan:
+		c.setOutputCheckResults(outputCheck)
+
 	case c.heartbeatChan <- struct{}{}:
 
 	case <-c.componentPIDTicker.C:
 		// if we hit the ticker and we've got a new PID,
 		// reload the component model
+		if c.memoryPressure {
+			// the update stays pending until the pressure recovers
+			break
+		}
 		if c.componentPidRequiresUpdate.Swap(false) {
 			err := c.refreshComponentModel(ctx)
 			if err != nil {
@@ -1463,6 +1748,16 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 			}
 		}
 
+	case <-timeConditionsTick:
+		// render the conditions calling a time function again
+		if c.memoryPressure {
+			c.refreshDeferred = true
+			break
+		}
+		if err := c.refreshComponentModel(ctx); err != nil {
+			c.logger.Errorf("updating component model for the time conditions: %s", err.Error())
+		}
+
 	case componentState := <-c.managerChans.runtimeManagerUpdate:
 		// New component change reported by the runtime manager via
 		// Coordinator.watchRuntimeComponents(), merge it with the
@@ -1470,10 +1765,15 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 		c.applyComponentState(componentState)
 
 	case change := <-c.managerChans.configManagerUpdate:
-		if err := c.processConfig(ctx, change.Config()); err != nil {
+		if err := c.processConfigChange(ctx, change); err != nil {
 			c.logger.Errorf("applying new policy: %s", err.Error())
 			change.Fail(err)
 		} else {
+			origin := ConfigChangeOrigin{Source: "local"}
+			if o, ok := change.(OriginConfigChange); ok {
+				origin = o.Origin()
+			}
+			c.recordPolicy(change.Config(), origin)
 			if err := change.Ack(); err != nil {
 				err = fmt.Errorf("failed to ack configuration change: %w", err)
 				// Workaround: setConfigManagerError is usually used by the config
@@ -1510,6 +1810,12 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	case upgradeMarker := <-c.managerChans.upgradeMarkerUpdate:
 		if ctx.Err() == nil {
 			c.setUpgradeDetails(upgradeMarker.Details)
+			c.ackHealthGatePassed(ctx, upgradeMarker)
+		}
+
+	case specs := <-c.managerChans.specsUpdate:
+		if ctx.Err() == nil {
+			c.processSpecs(ctx, specs)
 		}
 	}
 
@@ -1520,6 +1826,37 @@ func (c *Coordinator) runLoopIteration(ctx context.Context) {
 	}
 }
 
+// ackHealthGatePassed acks the upgrade once the upgraded agent passed the health gate, the ack is deferred
+// on startup until then.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) ackHealthGatePassed(ctx context.Context, marker upgrade.UpdateMarker) {
+	if c.upgradeMgr == nil || c.healthGateAcked || marker.Acked || marker.Details == nil ||
+		marker.Details.Metadata.HealthGate != details.HealthGatePassed {
+		return
+	}
+	c.healthGateAcked = true
+	go func() {
+		if err := c.upgradeMgr.Ack(ctx, c.fleetAcker); err != nil {
+			c.logger.Warnf("Failed to ack the upgrade that passed the health gate: %v", err)
+		}
+	}()
+}
+
+// recordPolicy records the applied policy in the policy history, a failure is only logged as the policy is
+// already applied.
+func (c *Coordinator) recordPolicy(cfg *config.Config, origin ConfigChangeOrigin) {
+	if c.policyRecorder == nil {
+		return
+	}
+	policy, err := cfg.ToMapStr()
+	if err == nil {
+		err = c.policyRecorder.Record(policy, origin)
+	}
+	if err != nil {
+		c.logger.Warnf("Failed to record the policy in the policy history: %v", err)
+	}
+}
+
 // Always called on the main Coordinator goroutine.
 func (c *Coordinator) processConfig(ctx context.Context, cfg *config.Config) (err error) {
 	if c.otelMgr != nil {
@@ -1589,6 +1926,9 @@ func (c *Coordinator) generateAST(cfg *config.Config, m map[string]interface{})
 	if err != nil {
 		return fmt.Errorf("could not create the AST from the configuration: %w", err)
 	}
+	if err := transpiler.ExpandPresets(rawAst); err != nil {
+		return fmt.Errorf("expanding presets failed: %w", err)
+	}
 
 	// applying updated agent process limits
 	if err := limits.Apply(cfg); err != nil {
@@ -1621,10 +1961,81 @@ func (c *Coordinator) generateAST(cfg *config.Config, m map[string]interface{})
 		}
 	}
 
+	if c.otlpExporterReloader != nil {
+		if err := c.otlpExporterReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload OTLP exporter configuration: %w", err)
+		}
+	}
+
+	if c.faultHandlerReloader != nil {
+		if err := c.faultHandlerReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload fault handling configuration: %w", err)
+		}
+	}
+
+	if c.outputKeysReloader != nil {
+		if err := c.outputKeysReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload output key rotation configuration: %w", err)
+		}
+	}
+
+	if c.autoCaptureReloader != nil {
+		if err := c.autoCaptureReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload diagnostics auto capture configuration: %w", err)
+		}
+	}
+
+	if c.controlAuthzReloader != nil {
+		if err := c.controlAuthzReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload control authorization configuration: %w", err)
+		}
+	}
+
+	if c.stopProtectionReloader != nil {
+		if err := c.stopProtectionReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload service stop protection configuration: %w", err)
+		}
+	}
+
+	if c.eventLogReloader != nil {
+		if err := c.eventLogReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload event log configuration: %w", err)
+		}
+	}
+
+	if c.policyGuardrailReloader != nil {
+		if err := c.policyGuardrailReloader.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload policy guardrail configuration: %w", err)
+		}
+	}
+
+	if c.outputChecker != nil {
+		if err := c.outputChecker.Reload(cfg); err != nil {
+			return fmt.Errorf("failed to reload output checker configuration: %w", err)
+		}
+	}
+
 	c.ast = rawAst
+	c.setTimeConditions(rawAst.UsesTime())
 	return nil
 }
 
+// setTimeConditions starts the ticker rendering the component model again when the AST has conditions
+// calling a time function of EQL, e.g. hour(), as their result changes over time. The ticker is stopped
+// once the AST has no such conditions.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setTimeConditions(usesTime bool) {
+	if usesTime == (c.timeConditionsTicker != nil) {
+		return
+	}
+	if usesTime {
+		c.timeConditionsTicker = time.NewTicker(timeConditionsInterval)
+		return
+	}
+	c.timeConditionsTicker.Stop()
+	c.timeConditionsTicker = nil
+}
+
 // observeASTVars identifies the variables that are referenced in the computed AST and passed to
 // the varsMgr so it knows what providers are being referenced. If a providers is not being
 // referenced then the provider does not need to be running.
@@ -1639,10 +2050,18 @@ func (c *Coordinator) observeASTVars(ctx context.Context) error {
 		if ok {
 			vars = inputs.Vars(vars, c.varsMgr.DefaultProvider())
 		}
+		templates, ok := transpiler.Lookup(c.ast, "input_templates")
+		if ok {
+			vars = transpiler.InputTemplatesVars(templates, vars, c.varsMgr.DefaultProvider())
+		}
 		outputs, ok := transpiler.Lookup(c.ast, "outputs")
 		if ok {
 			vars = outputs.Vars(vars, c.varsMgr.DefaultProvider())
 		}
+		components, ok := transpiler.Lookup(c.ast, "agent.components")
+		if ok {
+			vars = components.Vars(vars, c.varsMgr.DefaultProvider())
+		}
 	}
 	updated, err := c.varsMgr.Observe(ctx, vars)
 	if err != nil {
@@ -1651,7 +2070,7 @@ func (c *Coordinator) observeASTVars(ctx context.Context) error {
 	}
 	if updated != nil {
 		// provided an updated set of vars (observed changed)
-		c.vars = updated
+		c.setVars(updated)
 	}
 	return nil
 }
@@ -1659,13 +2078,53 @@ func (c *Coordinator) observeASTVars(ctx context.Context) error {
 // processVars updates the transpiler vars in the Coordinator.
 // Called on the main Coordinator goroutine.
 func (c *Coordinator) processVars(ctx context.Context, vars []*transpiler.Vars) {
-	c.vars = vars
+	c.setVars(vars)
+	if c.memoryPressure {
+		c.logger.Debug("Deferring the update of the component model for new variables under memory pressure")
+		c.refreshDeferred = true
+		return
+	}
 	err := c.refreshComponentModel(ctx)
 	if err != nil {
 		c.logger.Errorf("updating Coordinator variables: %s", err.Error())
 	}
 }
 
+// setVars sets the current variables of the Coordinator.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setVars(vars []*transpiler.Vars) {
+	c.vars = vars
+	c.varsSnapshot.Store(&vars)
+}
+
+// setMemoryPressure enters or leaves memory pressure, the component model is
+// refreshed once the pressure recovers if an update was deferred.
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) setMemoryPressure(ctx context.Context, pressure bool) {
+	if c.memoryPressure == pressure {
+		return
+	}
+	c.memoryPressure = pressure
+	c.stateNeedsRefresh = true
+	if pressure || !c.refreshDeferred {
+		return
+	}
+	c.refreshDeferred = false
+	c.logger.Info("Applying the component model updates deferred under memory pressure")
+	if err := c.refreshComponentModel(ctx); err != nil {
+		c.logger.Errorf("updating component model deferred under memory pressure: %s", err.Error())
+	}
+}
+
+// Called on the main Coordinator goroutine.
+func (c *Coordinator) processSpecs(ctx context.Context, specs component.RuntimeSpecs) {
+	c.specs = specs
+	err := c.refreshComponentModel(ctx)
+	if err != nil {
+		c.logger.Errorf("updating component specifications: %s", err.Error())
+	}
+}
+
 // Called on the main Coordinator goroutine.
 func (c *Coordinator) processLogLevel(ctx context.Context, ll logp.Level) {
 	c.setLogLevel(ll)
@@ -1691,11 +2150,17 @@ func (c *Coordinator) refreshComponentModel(ctx context.Context) (err error) {
 	}()
 
 	// regenerate the component model
+	renderStart := time.Now()
 	err = c.generateComponentModel()
+	agentmetrics.ObservePolicyRender(time.Since(renderStart), err)
 	if err != nil {
 		return fmt.Errorf("generating component model: %w", err)
 	}
 
+	// validate the outputs in the background, the output units of the
+	// components are degraded if their output fails its validation
+	c.checkOutputs(ctx)
+
 	signed, err := component.SignedFromPolicy(c.derivedConfig)
 	if err != nil {
 		if !errors.Is(err, component.ErrNotFound) {
@@ -1803,13 +2268,12 @@ func (c *Coordinator) generateComponentModel() (err error) {
 
 	ast := c.ast.ShallowClone()
 
-	// perform variable substitution for inputs
-	inputs, ok := transpiler.Lookup(ast, "inputs")
+	// perform variable substitution for inputs and input templates
+	renderedInputs, ok, err := transpiler.RenderAllInputs(ast, c.vars)
+	if err != nil {
+		return fmt.Errorf("rendering inputs failed: %w", err)
+	}
 	if ok {
-		renderedInputs, err := transpiler.RenderInputs(inputs, c.vars)
-		if err != nil {
-			return fmt.Errorf("rendering inputs failed: %w", err)
-		}
 		err = transpiler.Insert(ast, renderedInputs, "inputs")
 		if err != nil {
 			return fmt.Errorf("inserting rendered inputs failed: %w", err)
@@ -1830,10 +2294,20 @@ func (c *Coordinator) generateComponentModel() (err error) {
 		}
 	}
 
+	// perform variable substitution for the environment, working directory and arguments of the components
+	// like the outputs they only support the context variables
+	if err := transpiler.RenderComponents(ast, c.vars); err != nil {
+		return fmt.Errorf("rendering components failed: %w", err)
+	}
+
 	cfg, err := ast.Map()
 	if err != nil {
 		return fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
 	}
+	applyOutputKeys(cfg, c.outputKeys)
+	// Strip the policy parts denied by the selector rules of the capabilities config
+	c.filterPolicyByCapabilities(cfg)
+
 	var configInjector component.GenerateMonitoringCfgFn
 	if c.monitorMgr != nil && c.monitorMgr.Enabled() {
 		configInjector = c.monitorMgr.MonitoringConfig
@@ -1857,6 +2331,8 @@ func (c *Coordinator) generateComponentModel() (err error) {
 
 	// Filter any disallowed inputs/outputs from the components
 	comps = c.filterByCapabilities(comps)
+	// Filter the components stopped by the fault handling
+	comps = c.filterQuarantined(comps)
 
 	for _, modifier := range c.modifiers {
 		comps, err = modifier(comps, cfg)
@@ -1871,6 +2347,7 @@ func (c *Coordinator) generateComponentModel() (err error) {
 
 	lastComponentModel := c.componentModel
 	c.componentModel = comps
+	c.componentModelSnapshot.Store(&comps)
 
 	c.checkAndLogUpdate(lastComponentModel)
 
@@ -2011,28 +2488,60 @@ func (c *Coordinator) checkAndLogUpdate(lastComponentModel []component.Component
 	c.logger.Infow("component model updated", "changes", logStruct)
 }
 
+// Strip the parts of the rendered policy denied by the selector rules of
+// the capabilities config before the component model is generated from it.
+func (c *Coordinator) filterPolicyByCapabilities(cfg map[string]interface{}) {
+	if c.caps == nil {
+		return
+	}
+	for _, denial := range c.caps.FilterPolicy(cfg) {
+		c.logger.Infof("Policy part '%s' filtered by capabilities.yml: %s", denial.Path, denial.Rule)
+	}
+}
+
 // Filter any inputs and outputs in the generated component model
-// based on whether they're excluded by the capabilities config
+// based on whether they're excluded by the capabilities config, the
+// removed components are reported in the state with the matching rule.
 func (c *Coordinator) filterByCapabilities(comps []component.Component) []component.Component {
 	if c.caps == nil {
 		// No active filters, return unchanged
+		c.setBlockedComponents(nil)
 		return comps
 	}
 	result := []component.Component{}
+	var blocked []BlockedComponent
 	for _, component := range comps {
-		if component.InputSpec != nil && !c.caps.AllowInput(component.InputType) {
-			c.logger.Infof("Component '%v' with input type '%v' filtered by capabilities.yml", component.ID, component.InputType)
-			continue
+		if component.InputSpec != nil {
+			if rule, denied := c.caps.InputDeniedBy(component.InputType); denied {
+				c.logger.Infof("Component '%v' with input type '%v' filtered by capabilities.yml: %s", component.ID, component.InputType, rule)
+				blocked = append(blocked, newBlockedComponent(component, "input type denied by "+rule))
+				continue
+			}
 		}
-		if !c.caps.AllowOutput(component.OutputType) {
-			c.logger.Infof("Component '%v' with output type '%v' filtered by capabilities.yml", component.ID, component.OutputType)
+		if rule, denied := c.caps.OutputDeniedBy(component.OutputType); denied {
+			c.logger.Infof("Component '%v' with output type '%v' filtered by capabilities.yml: %s", component.ID, component.OutputType, rule)
+			blocked = append(blocked, newBlockedComponent(component, "output type denied by "+rule))
 			continue
 		}
 		result = append(result, component)
 	}
+	c.setBlockedComponents(blocked)
 	return result
 }
 
+// filterQuarantined removes the components held out by the fault handling from the
+// generated component model.
+func (c *Coordinator) filterQuarantined(comps []component.Component) []component.Component {
+	if len(c.state.QuarantinedComponents) == 0 {
+		return comps
+	}
+	return slices.DeleteFunc(comps, func(comp component.Component) bool {
+		return slices.ContainsFunc(c.state.QuarantinedComponents, func(q QuarantinedComponent) bool {
+			return q.ID == comp.ID
+		})
+	})
+}
+
 // helpers for checkAndLogUpdate
 
 func convertUnitListToMap(unitList []component.Unit) map[string]component.Unit {
@@ -5,7 +5,11 @@
 package coordinator
 
 import (
+	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"time"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
 	"go.opentelemetry.io/collector/component/componentstatus"
@@ -15,6 +19,8 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/otel/otelhelpers"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 )
@@ -39,8 +45,105 @@ type State struct {
 	Collector *status.AggregateStatus
 
 	UpgradeDetails *details.Details `yaml:"upgrade_details,omitempty"`
+
+	// The state of the Fleet Server hosted by this Elastic Agent, nil when it doesn't run one.
+	FleetServer *FleetServerState `yaml:"fleet_server,omitempty"`
+
+	// The health of the Fleet Server hosts the Elastic Agent checks in with.
+	FleetHosts []remote.HostState `yaml:"fleet_hosts,omitempty"`
+
+	// The components of the policy removed from the component model by the capabilities rules.
+	BlockedComponents []BlockedComponent `yaml:"blocked_components,omitempty"`
+
+	// The components of the policy held out of the component model by the fault handling.
+	QuarantinedComponents []QuarantinedComponent `yaml:"quarantined_components,omitempty"`
+
+	// The rotation of the API keys of the outputs, empty when they are not rotated.
+	OutputKeys []OutputKeyState `yaml:"output_keys,omitempty"`
+
+	// The rollback of the policy to the last healthy revision, nil when the applied revision was not rolled back.
+	PolicyRollback *PolicyRollback `yaml:"policy_rollback,omitempty"`
+}
+
+// BlockedComponent is a component of the policy that is not run because a
+// rule of the capabilities file denies its input or output type.
+type BlockedComponent struct {
+	ID         string `yaml:"id"`
+	InputType  string `yaml:"input_type"`
+	OutputType string `yaml:"output_type"`
+	// Reason names the capabilities rule that blocked the component.
+	Reason string `yaml:"reason"`
+}
+
+func newBlockedComponent(comp component.Component, reason string) BlockedComponent {
+	return BlockedComponent{
+		ID:         comp.ID,
+		InputType:  comp.InputType,
+		OutputType: comp.OutputType,
+		Reason:     reason,
+	}
+}
+
+// QuarantinedComponent is a component of the policy that is not run because the fault handling
+// stopped it after repeated failures. It is held until Until, when it is restarted, or until the
+// next policy when Until is zero.
+type QuarantinedComponent struct {
+	ID         string `yaml:"id"`
+	InputType  string `yaml:"input_type"`
+	OutputType string `yaml:"output_type"`
+	// Reason describes the failures that caused the component to be stopped.
+	Reason string    `yaml:"reason"`
+	Since  time.Time `yaml:"since"`
+	Until  time.Time `yaml:"until,omitempty"`
+}
+
+const (
+	// OutputKeyRotating is the state of an output whose new API key is used by the components, the
+	// previous key is retired once they are healthy with it.
+	OutputKeyRotating = "rotating"
+	// OutputKeyRotated is the state of an output whose API key was rotated.
+	OutputKeyRotated = "rotated"
+	// OutputKeyFailed is the state of an output whose API key failed to rotate, the previous key is
+	// still used and the rotation is retried later.
+	OutputKeyFailed = "failed"
+)
+
+// OutputKeyState is the state of the rotation of the API key of an output.
+type OutputKeyState struct {
+	Output  string `yaml:"output"`
+	State   string `yaml:"state"`
+	Message string `yaml:"message,omitempty"`
+	// Expiration is the expiration of the API key in use.
+	Expiration time.Time `yaml:"expiration,omitempty"`
+	// Since is the time of the last state change.
+	Since time.Time `yaml:"since"`
+}
+
+// outputKeysUpdate is the update of the API keys of the outputs sent by SetOutputKeys.
+type outputKeysUpdate struct {
+	keys   map[string]string
+	states []OutputKeyState
 }
 
+// outputKeyExpirationKey is the key of an output of the policy with the expiration of its API key, it
+// is only read by the key rotation and not passed to the components.
+const outputKeyExpirationKey = "api_key_expiration"
+
+// FleetServerState is the state of the Fleet Server hosted by the Elastic Agent. It is healthy
+// once the Fleet Server API answers, the component being healthy is not enough for enrollments
+// and check-ins to succeed.
+type FleetServerState struct {
+	State   agentclient.State `yaml:"state"`
+	Message string            `yaml:"message"`
+	// URL is the local address of the Fleet Server API.
+	URL string `yaml:"url"`
+	// Since is the time of the last state change.
+	Since time.Time `yaml:"since"`
+}
+
+// memoryPressureMessage is the reason the Coordinator is degraded while under memory pressure.
+const memoryPressureMessage = "Memory pressure: policy updates from variable changes are deferred until memory is available"
+
 type coordinatorOverrideState struct {
 	state   agentclient.State
 	message string
@@ -63,6 +166,50 @@ func (c *Coordinator) ClearOverrideState() {
 	c.overrideStateChan <- nil
 }
 
+// SetMemoryPressure reports whether the agent is under memory pressure. Under
+// pressure the Coordinator reports itself degraded and defers the re-rendering
+// of the policy on variable changes until the pressure recovers. It never blocks
+// the caller, a change not yet processed is replaced by the new one.
+func (c *Coordinator) SetMemoryPressure(pressure bool) {
+	for {
+		select {
+		case c.memoryPressureChan <- pressure:
+			return
+		default:
+		}
+		// drop the pending change, only the last one matters
+		select {
+		case <-c.memoryPressureChan:
+		default:
+		}
+	}
+}
+
+// SetQuarantinedComponents sets the components held out of the component model by the fault
+// handling, replacing the previous ones. The component model is regenerated so the components
+// are stopped, or started again once they are no longer held.
+func (c *Coordinator) SetQuarantinedComponents(quarantined []QuarantinedComponent) {
+	c.quarantinedChan <- quarantined
+}
+
+// SetOutputKeys sets the API keys replacing the ones of the outputs of the policy, by output name, and the
+// state of their rotation. The component model is regenerated when the keys changed so the components
+// use the new keys.
+func (c *Coordinator) SetOutputKeys(keys map[string]string, states []OutputKeyState) {
+	c.outputKeysChan <- outputKeysUpdate{keys: keys, states: states}
+}
+
+// SetFleetServerState sets the state of the Fleet Server hosted by the Elastic Agent.
+// While it is set and not healthy, the Coordinator doesn't report itself healthy.
+func (c *Coordinator) SetFleetServerState(state *FleetServerState) {
+	c.fleetServerStateChan <- state
+}
+
+// SetFleetHosts sets the health of the Fleet Server hosts the Elastic Agent checks in with.
+func (c *Coordinator) SetFleetHosts(hosts []remote.HostState) {
+	c.fleetHostsChan <- hosts
+}
+
 // SetUpgradeDetails sets upgrade details. This is used during upgrades.
 func (c *Coordinator) SetUpgradeDetails(upgradeDetails *details.Details) {
 	c.upgradeDetailsChan <- upgradeDetails
@@ -136,6 +283,81 @@ func (c *Coordinator) setUpgradeDetails(upgradeDetails *details.Details) {
 	c.logUpgradeDetails(upgradeDetails)
 }
 
+// setBlockedComponents is the internal helper to set the components blocked by the
+// capabilities and set stateNeedsRefresh.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setBlockedComponents(blocked []BlockedComponent) {
+	if len(blocked) == 0 && len(c.state.BlockedComponents) == 0 {
+		return
+	}
+	c.state.BlockedComponents = blocked
+	c.stateNeedsRefresh = true
+}
+
+// setQuarantinedComponents is the internal helper to set the components held out by the fault
+// handling, regenerate the component model and set stateNeedsRefresh.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setQuarantinedComponents(ctx context.Context, quarantined []QuarantinedComponent) {
+	if len(quarantined) == 0 && len(c.state.QuarantinedComponents) == 0 {
+		return
+	}
+	c.state.QuarantinedComponents = quarantined
+	c.stateNeedsRefresh = true
+	if err := c.refreshComponentModel(ctx); err != nil {
+		c.logger.Errorf("updating component model for quarantined components: %s", err.Error())
+	}
+}
+
+// setOutputKeys is the internal helper to set the API keys of the outputs and the state of their rotation,
+// regenerate the component model when the keys changed and set stateNeedsRefresh.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setOutputKeys(ctx context.Context, update outputKeysUpdate) {
+	c.state.OutputKeys = update.states
+	c.stateNeedsRefresh = true
+	if maps.Equal(c.outputKeys, update.keys) {
+		return
+	}
+	c.outputKeys = update.keys
+	if err := c.refreshComponentModel(ctx); err != nil {
+		c.logger.Errorf("updating component model for rotated output keys: %s", err.Error())
+	}
+}
+
+// applyOutputKeys replaces the API keys of the outputs of the rendered policy with the rotated ones and
+// removes the expiration of the keys that is not a setting of the outputs.
+func applyOutputKeys(cfg map[string]interface{}, keys map[string]string) {
+	outputs, ok := cfg["outputs"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, raw := range outputs {
+		output, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(output, outputKeyExpirationKey)
+		if key, ok := keys[name]; ok {
+			if _, hasKey := output["api_key"]; hasKey {
+				output["api_key"] = key
+			}
+		}
+	}
+}
+
+// setFleetServerState is the internal helper to set the hosted Fleet Server state and set stateNeedsRefresh.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setFleetServerState(state *FleetServerState) {
+	c.state.FleetServer = state
+	c.stateNeedsRefresh = true
+}
+
+// setFleetHosts is the internal helper to set the health of the Fleet Server hosts and set stateNeedsRefresh.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setFleetHosts(hosts []remote.HostState) {
+	c.state.FleetHosts = hosts
+	c.stateNeedsRefresh = true
+}
+
 // Forward the current state to the broadcaster and clear the stateNeedsRefresh
 // flag. Must be called on the main Coordinator goroutine.
 func (c *Coordinator) refreshState() {
@@ -147,11 +369,13 @@ func (c *Coordinator) refreshState() {
 // Coordinator state and sets stateNeedsRefresh.
 // Must be called on the main Coordinator goroutine.
 func (c *Coordinator) applyComponentState(state runtime.ComponentComponentState) {
-	// check for any component updates to the known PID, so we can update the component monitoring
+	// check for any component updates to the known PID, so we can update the component monitoring,
+	// only the monitoring of the components running as a service uses their PID
+	service := state.Component.InputSpec != nil && state.Component.InputSpec.Spec.Service != nil
 	found := false
 	for i, other := range c.state.Components {
 		if other.Component.ID == state.Component.ID {
-			if other.State.Pid != state.State.Pid {
+			if service && other.State.Pid != state.State.Pid {
 				c.componentPidRequiresUpdate.Store(true)
 			}
 			c.state.Components[i] = state
@@ -161,7 +385,7 @@ func (c *Coordinator) applyComponentState(state runtime.ComponentComponentState)
 	}
 	if !found {
 		c.state.Components = append(c.state.Components, state)
-		if state.State.Pid != 0 {
+		if service && state.State.Pid != 0 {
 			c.componentPidRequiresUpdate.Store(true)
 		}
 	}
@@ -193,8 +417,21 @@ func (c *Coordinator) generateReportableState() (s State) {
 	s.FleetMessage = c.state.FleetMessage
 	s.LogLevel = c.state.LogLevel
 	s.UpgradeDetails = c.state.UpgradeDetails
+	if c.state.FleetServer != nil {
+		fleetServer := *c.state.FleetServer
+		s.FleetServer = &fleetServer
+	}
+	s.FleetHosts = slices.Clone(c.state.FleetHosts)
+	s.BlockedComponents = slices.Clone(c.state.BlockedComponents)
+	s.QuarantinedComponents = slices.Clone(c.state.QuarantinedComponents)
+	s.OutputKeys = slices.Clone(c.state.OutputKeys)
+	if c.state.PolicyRollback != nil {
+		rollback := *c.state.PolicyRollback
+		s.PolicyRollback = &rollback
+	}
 	s.Components = make([]runtime.ComponentComponentState, len(c.state.Components))
 	copy(s.Components, c.state.Components)
+	applyOutputCheckResults(s.Components, c.outputCheckResults)
 	if c.state.Collector != nil {
 		// copy the contents
 		s.Collector = copyOTelStatus(c.state.Collector)
@@ -204,7 +441,11 @@ func (c *Coordinator) generateReportableState() (s State) {
 	// - Override state, if present
 	// - Errors applying the configured policy (report Failed)
 	// - Errors reported by managers (report Failed)
+	// - Policy rolled back to the last healthy revision (report Degraded)
+	// - Components stopped by the fault handling (report Degraded)
+	// - API keys of the outputs failing to rotate (report Degraded)
 	// - Errors in component/unit state (report Degraded)
+	// - Hosted Fleet Server API not answering (report its state)
 	if c.overrideState != nil {
 		// state has been overridden by an upgrade in progress
 		s.State = c.overrideState.state
@@ -230,12 +471,27 @@ func (c *Coordinator) generateReportableState() (s State) {
 	} else if c.varsMgrErr != nil {
 		s.State = agentclient.Failed
 		s.Message = fmt.Sprintf("Vars manager: %s", c.varsMgrErr.Error())
+	} else if c.memoryPressure {
+		s.State = agentclient.Degraded
+		s.Message = memoryPressureMessage
+	} else if s.PolicyRollback != nil {
+		s.State = agentclient.Degraded
+		s.Message = "Policy rolled back to the last healthy revision: " + s.PolicyRollback.Reason
+	} else if len(s.QuarantinedComponents) > 0 {
+		s.State = agentclient.Degraded
+		s.Message = "1 or more components stopped by the fault handling"
+	} else if slices.ContainsFunc(s.OutputKeys, func(k OutputKeyState) bool { return k.State == OutputKeyFailed }) {
+		s.State = agentclient.Degraded
+		s.Message = "1 or more output API keys failed to rotate"
 	} else if hasState(s.Components, client.UnitStateFailed) || otelhelpers.HasStatus(s.Collector, componentstatus.StatusFatalError) || otelhelpers.HasStatus(s.Collector, componentstatus.StatusPermanentError) {
 		s.State = agentclient.Degraded
 		s.Message = "1 or more components/units in a failed state"
 	} else if hasState(s.Components, client.UnitStateDegraded) || otelhelpers.HasStatus(s.Collector, componentstatus.StatusRecoverableError) {
 		s.State = agentclient.Degraded
 		s.Message = "1 or more components/units in a degraded state"
+	} else if s.CoordinatorState == agentclient.Healthy && s.FleetServer != nil && s.FleetServer.State != agentclient.Healthy {
+		s.State = s.FleetServer.State
+		s.Message = "Fleet Server: " + s.FleetServer.Message
 	} else {
 		// If no error conditions apply, the global state inherits the current
 		// Coordinator state.
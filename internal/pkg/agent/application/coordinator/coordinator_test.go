@@ -410,6 +410,24 @@ func TestCoordinator_VarsMgr_Observe(t *testing.T) {
 	assert.Equal(t, []string{"env.filestream_path", "env.log_path", "host.platform"}, observed)
 }
 
+func TestCoordinator_Vars(t *testing.T) {
+	coord := &Coordinator{}
+
+	defaultProvider, vars := coord.Vars()
+	assert.Empty(t, defaultProvider)
+	assert.Nil(t, vars, "no variables should be returned before any are set")
+
+	expected, err := transpiler.NewVars("id", map[string]interface{}{
+		"host": map[string]interface{}{"name": "example"},
+	}, nil, "")
+	require.NoError(t, err)
+	coord.setVars([]*transpiler.Vars{expected})
+
+	_, vars = coord.Vars()
+	require.Len(t, vars, 1)
+	assert.Same(t, expected, vars[0])
+}
+
 func TestCoordinator_State_Starting(t *testing.T) {
 	coordCh := make(chan error)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1387,7 +1405,7 @@ type fakeOTelManager struct {
 	updateCollectorCallback             func(*confmap.Conf) error
 	updateComponentCallback             func([]component.Component) error
 	performDiagnosticsCallback          func(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
-	performComponentDiagnosticsCallback func(context.Context, []cproto.AdditionalDiagnosticRequest, ...component.Component) ([]runtime.ComponentDiagnostic, error)
+	performComponentDiagnosticsCallback func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) ([]runtime.ComponentDiagnostic, error)
 	errChan                             chan error
 	collectorStatusChan                 chan *status.AggregateStatus
 	componentStateChan                  chan []runtime.ComponentComponentState
@@ -1437,9 +1455,9 @@ func (f *fakeOTelManager) PerformDiagnostics(ctx context.Context, reqs ...runtim
 	return nil
 }
 
-func (f *fakeOTelManager) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+func (f *fakeOTelManager) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
 	if f.performComponentDiagnosticsCallback != nil {
-		return f.performComponentDiagnosticsCallback(ctx, additionalMetrics, req...)
+		return f.performComponentDiagnosticsCallback(ctx, additionalMetrics, cpuProfile, req...)
 	}
 	return nil, nil
 }
@@ -1449,7 +1467,7 @@ type fakeRuntimeManager struct {
 	state                               []runtime.ComponentComponentState
 	updateCallback                      func([]component.Component) error
 	performDiagnosticsCallback          func(context.Context, ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
-	performComponentDiagnosticsCallback func(context.Context, []cproto.AdditionalDiagnosticRequest, ...component.Component) ([]runtime.ComponentDiagnostic, error)
+	performComponentDiagnosticsCallback func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) ([]runtime.ComponentDiagnostic, error)
 	result                              error
 	errChan                             chan error
 }
@@ -1497,9 +1515,9 @@ func (r *fakeRuntimeManager) PerformDiagnostics(ctx context.Context, req ...runt
 }
 
 // PerformComponentDiagnostics  executes the diagnostic action for the provided components.
-func (r *fakeRuntimeManager) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+func (r *fakeRuntimeManager) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
 	if r.performComponentDiagnosticsCallback != nil {
-		return r.performComponentDiagnosticsCallback(ctx, additionalMetrics, req...)
+		return r.performComponentDiagnosticsCallback(ctx, additionalMetrics, cpuProfile, req...)
 	}
 	return nil, nil
 }
@@ -15,12 +15,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"maps"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -49,10 +52,12 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/capabilities"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
@@ -955,6 +960,75 @@ service:
 	assert.Nil(t, otelConfig, "empty policy should cause otel manager to get nil config")
 }
 
+func TestCoordinatorTimeConditionsRenderedAgain(t *testing.T) {
+	// A policy with a condition calling a time function is rendered again on
+	// each tick, until a policy without such a condition is applied.
+	prevInterval := timeConditionsInterval
+	timeConditionsInterval = 10 * time.Millisecond
+	defer func() { timeConditionsInterval = prevInterval }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	logger := logp.NewLogger("testing")
+
+	configChan := make(chan ConfigChange, 1)
+	var updated bool // Set by runtime manager callback
+	runtimeManager := &fakeRuntimeManager{
+		updateCallback: func(comp []component.Component) error {
+			updated = true
+			return nil
+		},
+	}
+	coord := &Coordinator{
+		logger:           logger,
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+		},
+		runtimeMgr:         runtimeManager,
+		otelMgr:            &fakeOTelManager{},
+		vars:               emptyVars(t),
+		componentPIDTicker: time.NewTicker(time.Second * 30),
+		secretMarkerFunc:   testSecretMarkerFunc,
+	}
+	defer coord.setTimeConditions(false)
+
+	cfgChange := &configChange{cfg: config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: test-input
+    type: filestream
+    use_output: default
+    condition: "hour('UTC') >= 0"
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	require.True(t, cfgChange.acked, "Coordinator should ACK a successful policy change")
+	require.NotNil(t, coord.timeConditionsTicker, "time conditions should start the ticker")
+
+	updated = false
+	coord.runLoopIteration(ctx)
+	require.NoError(t, ctx.Err())
+	assert.True(t, updated, "Runtime manager should be updated on the tick of the time conditions")
+
+	cfgChange = &configChange{cfg: config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: test-input
+    type: filestream
+    use_output: default
+`)}
+	configChan <- cfgChange
+	coord.runLoopIteration(ctx)
+	require.True(t, cfgChange.acked, "Coordinator should ACK a successful policy change")
+	assert.Nil(t, coord.timeConditionsTicker, "the ticker should be stopped without time conditions")
+}
+
 func TestCoordinatorPolicyChangeUpdatesRuntimeAndOTelManagerWithOtelComponents(t *testing.T) {
 	// Send a test policy to the Coordinator as a Config Manager update,
 	// verify it generates the right component model and sends components
@@ -1311,6 +1385,104 @@ inputs:
 	assert.Equal(t, "changed-input-id", components[0].Units[0].Config.Id)
 }
 
+func TestCoordinatorDefersVarsUpdatesUnderMemoryPressure(t *testing.T) {
+	// Make sure:
+	// - A vars update under memory pressure doesn't update the component model
+	// - The Coordinator reports itself degraded while under memory pressure
+	// - The deferred update is applied once the pressure recovers
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	logger := logp.NewLogger("testing")
+
+	configChan := make(chan ConfigChange, 1)
+	varsChan := make(chan []*transpiler.Vars, 1)
+	memoryPressureChan := make(chan bool, 1)
+	stateChan := make(chan State, 10)
+
+	var updated bool
+	var components []component.Component
+	runtimeManager := &fakeRuntimeManager{
+		updateCallback: func(comp []component.Component) error {
+			updated = true
+			components = comp
+			return nil
+		},
+	}
+
+	coord := &Coordinator{
+		logger:    logger,
+		agentInfo: &info.AgentInfo{},
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+			varsManagerUpdate:   varsChan,
+		},
+		memoryPressureChan: memoryPressureChan,
+		runtimeMgr:         runtimeManager,
+		otelMgr:            &fakeOTelManager{},
+		vars:               emptyVars(t),
+		componentPIDTicker: time.NewTicker(time.Second * 30),
+		secretMarkerFunc:   testSecretMarkerFunc,
+	}
+	lastState := func() State {
+		var state State
+		for {
+			select {
+			case state = <-stateChan:
+			default:
+				return state
+			}
+		}
+	}
+
+	cfg := config.MustNewConfigFrom(`
+outputs:
+  default:
+    type: elasticsearch
+inputs:
+  - id: ${TEST_VAR}
+    type: filestream
+    use_output: default
+`)
+	configChan <- &configChange{cfg: cfg}
+	coord.runLoopIteration(ctx)
+	require.True(t, updated, "Runtime manager should receive a component model update")
+	require.Empty(t, components, "Input with missing variable shouldn't create a component")
+
+	// the monitor is never blocked, a pending change is replaced by the last one
+	coord.SetMemoryPressure(false)
+	coord.SetMemoryPressure(true)
+	coord.runLoopIteration(ctx)
+	state := lastState()
+	assert.Equal(t, agentclient.Degraded, state.State, "Coordinator should be degraded under memory pressure")
+	assert.Equal(t, memoryPressureMessage, state.Message)
+
+	// the vars update is deferred
+	updated = false
+	vars, err := transpiler.NewVars("", map[string]interface{}{
+		"TEST_VAR": "input-id",
+	}, nil, "")
+	require.NoError(t, err, "Vars creation must succeed")
+	varsChan <- []*transpiler.Vars{vars}
+	coord.runLoopIteration(ctx)
+	assert.False(t, updated, "Runtime manager shouldn't be updated under memory pressure")
+
+	// and applied once the pressure recovers
+	coord.SetMemoryPressure(false)
+	coord.runLoopIteration(ctx)
+	assert.True(t, updated, "Runtime manager should receive the deferred component model update")
+	require.Len(t, components, 1, "Input with valid variable should create a component")
+	assert.Equal(t, "input-id", components[0].Units[0].Config.Id)
+	state = lastState()
+	assert.Equal(t, agentclient.Healthy, state.State, "Coordinator should recover once the memory pressure recovers")
+}
+
 func TestCoordinatorReportsOverrideState(t *testing.T) {
 	// Set a one-second timeout -- nothing here should block, but if it
 	// does let's report a failure instead of timing out the test runner.
@@ -1362,6 +1534,363 @@ func TestCoordinatorReportsOverrideState(t *testing.T) {
 	}
 }
 
+func TestCoordinatorReportsFleetServerState(t *testing.T) {
+	// Make sure the Coordinator doesn't report itself healthy while the API of
+	// the hosted Fleet Server doesn't answer, and exposes its state.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	stateChan := make(chan State, 1)
+	fleetServerStateChan := make(chan *FleetServerState, 1)
+	coord := &Coordinator{
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		fleetServerStateChan: fleetServerStateChan,
+		componentPIDTicker:   time.NewTicker(time.Second * 30),
+	}
+
+	fleetServerStateChan <- &FleetServerState{
+		State:   agentclient.Starting,
+		Message: "Waiting for the Fleet Server API",
+		URL:     "https://localhost:8221",
+	}
+	coord.runLoopIteration(ctx)
+
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Starting, state.State, "Coordinator shouldn't be healthy until the Fleet Server API answers")
+		assert.Equal(t, "Fleet Server: Waiting for the Fleet Server API", state.Message)
+		require.NotNil(t, state.FleetServer, "state should include the Fleet Server state")
+		assert.Equal(t, "https://localhost:8221", state.FleetServer.URL)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+
+	fleetServerStateChan <- &FleetServerState{
+		State:   agentclient.Healthy,
+		Message: "Fleet Server API is ready",
+		URL:     "https://localhost:8221",
+	}
+	coord.runLoopIteration(ctx)
+
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, agentclient.Healthy, state.State, "Coordinator should be healthy once the Fleet Server API answers")
+		assert.Equal(t, "Running", state.Message)
+		require.NotNil(t, state.FleetServer, "state should include the Fleet Server state")
+		assert.Equal(t, agentclient.Healthy, state.FleetServer.State)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+}
+
+func TestCoordinatorReportsFleetHosts(t *testing.T) {
+	// Make sure the health of the Fleet Server hosts reported by the gateway
+	// is exposed in the state.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	stateChan := make(chan State, 1)
+	fleetHostsChan := make(chan []remote.HostState, 1)
+	coord := &Coordinator{
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		fleetHostsChan:     fleetHostsChan,
+		componentPIDTicker: time.NewTicker(time.Second * 30),
+	}
+
+	hosts := []remote.HostState{
+		{Host: "https://fleet-a:8220", Healthy: true, Current: true},
+		{Host: "https://fleet-b:8220", Failures: 2, Error: "connection refused"},
+	}
+	fleetHostsChan <- hosts
+	coord.runLoopIteration(ctx)
+
+	select {
+	case state := <-stateChan:
+		assert.Equal(t, hosts, state.FleetHosts)
+	default:
+		assert.Fail(t, "Coordinator's state didn't change")
+	}
+}
+
+func TestCoordinatorReportsComponentsBlockedByCapabilities(t *testing.T) {
+	// Make sure the components removed by the capabilities are reported in the
+	// state with the rule that removed them.
+	caps, err := capabilities.Load(strings.NewReader(`
+capabilities:
+- rule: deny
+  input: system/*
+- rule: deny
+  output: kafka
+`), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err)
+
+	coord := &Coordinator{
+		logger: logp.NewLogger("testing"),
+		caps:   caps,
+	}
+	comps := []component.Component{
+		{ID: "filestream-default", InputType: "filestream", OutputType: "elasticsearch", InputSpec: &component.InputRuntimeSpec{}},
+		{ID: "system/metrics-default", InputType: "system/metrics", OutputType: "elasticsearch", InputSpec: &component.InputRuntimeSpec{}},
+		{ID: "filestream-kafka", InputType: "filestream", OutputType: "kafka", InputSpec: &component.InputRuntimeSpec{}},
+	}
+
+	allowed := coord.filterByCapabilities(comps)
+	require.Len(t, allowed, 1)
+	assert.Equal(t, "filestream-default", allowed[0].ID)
+	assert.True(t, coord.stateNeedsRefresh, "blocked components should refresh the state")
+	assert.Equal(t, []BlockedComponent{
+		{
+			ID:         "system/metrics-default",
+			InputType:  "system/metrics",
+			OutputType: "elasticsearch",
+			Reason:     `input type denied by capability #1 {rule: deny, input: "system/*"}`,
+		},
+		{
+			ID:         "filestream-kafka",
+			InputType:  "filestream",
+			OutputType: "kafka",
+			Reason:     `output type denied by capability #2 {rule: deny, output: "kafka"}`,
+		},
+	}, coord.generateReportableState().BlockedComponents)
+
+	coord.stateNeedsRefresh = false
+	coord.filterByCapabilities(comps[:1])
+	assert.True(t, coord.stateNeedsRefresh, "unblocked components should refresh the state")
+	assert.Empty(t, coord.generateReportableState().BlockedComponents)
+}
+
+func TestCoordinatorFiltersQuarantinedComponents(t *testing.T) {
+	// Make sure the components held out by the fault handling are removed from
+	// the component model and degrade the state.
+	coord := &Coordinator{
+		logger: logp.NewLogger("testing"),
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+	}
+	comps := []component.Component{
+		{ID: "filestream-default", InputType: "filestream", OutputType: "elasticsearch"},
+		{ID: "system/metrics-default", InputType: "system/metrics", OutputType: "elasticsearch"},
+	}
+
+	assert.Len(t, coord.filterQuarantined(slices.Clone(comps)), 2, "no component should be removed without quarantine")
+	assert.Equal(t, agentclient.Healthy, coord.generateReportableState().State)
+
+	coord.state.QuarantinedComponents = []QuarantinedComponent{
+		{ID: "system/metrics-default", InputType: "system/metrics", OutputType: "elasticsearch", Reason: "3 failures within 10m0s"},
+	}
+	remaining := coord.filterQuarantined(slices.Clone(comps))
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "filestream-default", remaining[0].ID)
+
+	state := coord.generateReportableState()
+	assert.Equal(t, agentclient.Degraded, state.State)
+	assert.Equal(t, "1 or more components stopped by the fault handling", state.Message)
+	assert.Equal(t, coord.state.QuarantinedComponents, state.QuarantinedComponents)
+}
+
+func TestCoordinatorAppliesOutputKeys(t *testing.T) {
+	// Make sure the rotated API keys replace the ones of the policy, the expiration of
+	// the keys is not passed to the components and failed rotations degrade the state.
+	cfg := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"type":                 "elasticsearch",
+				"api_key":              "policy:secret",
+				outputKeyExpirationKey: "2025-10-02T00:00:00Z",
+			},
+			"logstash": map[string]interface{}{
+				"type":  "logstash",
+				"hosts": []interface{}{"localhost:5044"},
+			},
+		},
+	}
+	applyOutputKeys(cfg, map[string]string{"default": "rotated:secret", "logstash": "rotated:secret"})
+	outputs := cfg["outputs"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "elasticsearch", "api_key": "rotated:secret"}, outputs["default"])
+	assert.NotContains(t, outputs["logstash"], "api_key", "outputs without an API key should not get one")
+
+	coord := &Coordinator{
+		logger: logp.NewLogger("testing"),
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+		},
+	}
+	coord.state.OutputKeys = []OutputKeyState{{Output: "default", State: OutputKeyRotated}}
+	assert.Equal(t, agentclient.Healthy, coord.generateReportableState().State)
+
+	coord.state.OutputKeys = []OutputKeyState{{Output: "default", State: OutputKeyFailed, Message: "rolled back"}}
+	state := coord.generateReportableState()
+	assert.Equal(t, agentclient.Degraded, state.State)
+	assert.Equal(t, "1 or more output API keys failed to rotate", state.Message)
+	assert.Equal(t, coord.state.OutputKeys, state.OutputKeys)
+}
+
+type fakeOutputChecker struct {
+	results map[string]error
+	checks  atomic.Int32
+	checked atomic.Value
+}
+
+func (f *fakeOutputChecker) Reload(*config.Config) error { return nil }
+
+func (f *fakeOutputChecker) Enabled() bool { return true }
+
+func (f *fakeOutputChecker) Check(_ context.Context, outputs map[string]interface{}) map[string]error {
+	f.checks.Add(1)
+	f.checked.Store(slices.Sorted(maps.Keys(outputs)))
+	return f.results
+}
+
+func TestCoordinatorChecksOutputsOnlyWhenChanged(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	outputs := func(esHost string) map[string]interface{} {
+		return map[string]interface{}{
+			"outputs": map[string]interface{}{
+				"es":    map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{esHost}},
+				"other": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"https://other:9200"}},
+			},
+		}
+	}
+	checker := &fakeOutputChecker{results: map[string]error{
+		"other": errors.New("the API key of the Elasticsearch output was rejected"),
+	}}
+	coord := &Coordinator{
+		logger:             logp.NewLogger("testing"),
+		stateBroadcaster:   broadcaster.New(State{}, 0, 0),
+		derivedConfig:      outputs("https://es:9200"),
+		outputChecker:      checker,
+		outputCheckChan:    make(chan outputCheckResults),
+		componentPIDTicker: time.NewTicker(time.Second * 30),
+	}
+
+	coord.checkOutputs(ctx)
+	coord.runLoopIteration(ctx)
+	require.NoError(t, ctx.Err())
+	assert.Equal(t, int32(1), checker.checks.Load())
+	assert.Equal(t, []string{"es", "other"}, checker.checked.Load())
+	assert.Contains(t, coord.outputCheckResults, "other")
+
+	// the component model is regenerated with the same outputs
+	coord.derivedConfig = outputs("https://es:9200")
+	coord.checkOutputs(ctx)
+	select {
+	case <-coord.outputCheckChan:
+		require.Fail(t, "unchanged outputs should not be validated again")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, int32(1), checker.checks.Load())
+
+	// one output changes, only that output is validated again
+	checker.results = map[string]error{}
+	coord.derivedConfig = outputs("https://es-2:9200")
+	coord.checkOutputs(ctx)
+	coord.runLoopIteration(ctx)
+	require.NoError(t, ctx.Err())
+	assert.Equal(t, int32(2), checker.checks.Load())
+	assert.Equal(t, []string{"es"}, checker.checked.Load())
+	assert.Contains(t, coord.outputCheckResults, "other", "the results of the unchanged outputs should be kept")
+
+	// the failed output is removed from the policy
+	coord.derivedConfig = map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"es": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"https://es-2:9200"}},
+		},
+	}
+	coord.checkOutputs(ctx)
+	assert.Empty(t, coord.outputCheckResults, "the results of a removed output should be dropped")
+	assert.True(t, coord.stateNeedsRefresh)
+	assert.Equal(t, int32(2), checker.checks.Load())
+}
+
+func TestCoordinatorReportsOutputCheckResults(t *testing.T) {
+	// Make sure the output units of the components using an output that
+	// failed its validation are reported degraded with the reason.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	outputUnit := func(id string, state client.UnitState) map[runtime.ComponentUnitKey]runtime.ComponentUnitState {
+		return map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+			{UnitType: client.UnitTypeInput, UnitID: id + "-unit"}: {State: client.UnitStateHealthy},
+			{UnitType: client.UnitTypeOutput, UnitID: id}:          {State: state},
+		}
+	}
+	components := []runtime.ComponentComponentState{
+		{
+			Component: component.Component{ID: "filestream-es-eu", InputType: "filestream"},
+			State:     runtime.ComponentState{State: client.UnitStateHealthy, Units: outputUnit("filestream-es-eu", client.UnitStateHealthy)},
+		},
+		{
+			Component: component.Component{ID: "system/metrics-es", InputType: "system/metrics"},
+			State:     runtime.ComponentState{State: client.UnitStateHealthy, Units: outputUnit("system/metrics-es", client.UnitStateHealthy)},
+		},
+		{
+			Component: component.Component{ID: "log-es-eu", InputType: "log"},
+			State:     runtime.ComponentState{State: client.UnitStateFailed, Units: outputUnit("log-es-eu", client.UnitStateFailed)},
+		},
+	}
+
+	stateChan := make(chan State, 1)
+	coord := &Coordinator{
+		logger: logp.NewLogger("testing"),
+		state: State{
+			CoordinatorState:   agentclient.Healthy,
+			CoordinatorMessage: "Running",
+			Components:         components,
+		},
+		stateBroadcaster: &broadcaster.Broadcaster[State]{
+			InputChan: stateChan,
+		},
+		derivedConfig: map[string]interface{}{
+			"outputs": map[string]interface{}{
+				"es":    map[string]interface{}{"type": "elasticsearch"},
+				"es-eu": map[string]interface{}{"type": "elasticsearch"},
+			},
+		},
+		outputChecker: &fakeOutputChecker{results: map[string]error{
+			"es-eu": errors.New("the API key of the Elasticsearch output is missing privileges: logs-*-*:create_doc"),
+		}},
+		outputCheckChan:    make(chan outputCheckResults),
+		componentPIDTicker: time.NewTicker(time.Second * 30),
+	}
+
+	coord.checkOutputs(ctx)
+	coord.runLoopIteration(ctx)
+
+	var state State
+	select {
+	case state = <-stateChan:
+	default:
+		require.Fail(t, "Coordinator's state didn't change")
+	}
+	assert.Equal(t, agentclient.Degraded, state.State)
+
+	outputKey := func(id string) runtime.ComponentUnitKey {
+		return runtime.ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: id}
+	}
+	unit := state.Components[0].State.Units[outputKey("filestream-es-eu")]
+	assert.Equal(t, client.UnitStateDegraded, unit.State, "the output unit of the failed output should be degraded")
+	assert.Equal(t, "Output validation failed: the API key of the Elasticsearch output is missing privileges: logs-*-*:create_doc", unit.Message)
+	assert.Equal(t, client.UnitStateHealthy, state.Components[1].State.Units[outputKey("system/metrics-es")].State,
+		"the output unit of a valid output should be left unchanged")
+	assert.Equal(t, client.UnitStateFailed, state.Components[2].State.Units[outputKey("log-es-eu")].State,
+		"a failed output unit should be left unchanged")
+	assert.Equal(t, client.UnitStateHealthy, coord.state.Components[0].State.Units[outputKey("filestream-es-eu")].State,
+		"the internal state of the components should be left unchanged")
+}
+
 func TestCoordinatorTranslatesOtelStatusToComponentState(t *testing.T) {
 	// Send an otel status to the coordinator, verify that it is correctly reflected in the component state
 
@@ -1977,3 +2506,123 @@ func TestHasEndpoint(t *testing.T) {
 		})
 	}
 }
+
+func TestCoordinatorRollsBackPolicy(t *testing.T) {
+	// Make sure a revision rolled back after a failure storm is replaced by the last
+	// healthy revision, reported in the state and refused until another one is applied.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	configChan := make(chan ConfigChange, 1)
+	coord := &Coordinator{
+		logger:           logp.NewLogger("testing"),
+		agentInfo:        &info.AgentInfo{},
+		stateBroadcaster: broadcaster.New(State{}, 0, 0),
+		managerChans: managerChans{
+			configManagerUpdate: configChan,
+		},
+		runtimeMgr:         &fakeRuntimeManager{},
+		otelMgr:            &fakeOTelManager{},
+		vars:               emptyVars(t),
+		componentPIDTicker: time.NewTicker(time.Second * 30),
+		secretMarkerFunc:   testSecretMarkerFunc,
+	}
+	apply := func(revision int) *configChange {
+		change := &configChange{cfg: config.MustNewConfigFrom(map[string]interface{}{"revision": revision})}
+		configChan <- change
+		coord.runLoopIteration(ctx)
+		return change
+	}
+
+	require.True(t, apply(1).acked)
+	healthyHash := coord.appliedPolicy.hash
+	coord.rollbackPolicy(ctx, "storm")
+	assert.Nil(t, coord.state.PolicyRollback, "nothing to roll back to before a revision is healthy")
+
+	coord.markPolicyHealthy()
+	require.True(t, apply(2).acked)
+	badHash := coord.appliedPolicy.hash
+
+	coord.rollbackPolicy(ctx, "4 component failures")
+	assert.Equal(t, healthyHash, coord.appliedPolicy.hash, "the healthy revision is applied again")
+	state := coord.generateReportableState()
+	require.NotNil(t, state.PolicyRollback)
+	assert.Equal(t, badHash, state.PolicyRollback.RevisionHash)
+	assert.Equal(t, agentclient.Degraded, state.State)
+	assert.Equal(t, "Policy rolled back to the last healthy revision: 4 component failures", state.Message)
+
+	refused := apply(2)
+	assert.ErrorIs(t, refused.err, errRolledBackPolicy, "the rolled back revision is refused")
+	assert.Equal(t, healthyHash, coord.appliedPolicy.hash)
+
+	require.True(t, apply(3).acked)
+	assert.Nil(t, coord.state.PolicyRollback, "a new revision clears the rollback")
+}
+
+func TestCoordinatorPersistsPolicyRollback(t *testing.T) {
+	// Make sure the rollback survives a restart of the Elastic Agent: the revision stored with the last
+	// policy change is the rolled back one, the healthy revision is applied instead.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	store, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "policy_rollback.enc"))
+	require.NoError(t, err)
+	newCoordinator := func() (*Coordinator, func(revision int) *configChange) {
+		configChan := make(chan ConfigChange, 1)
+		coord := &Coordinator{
+			logger:           logp.NewLogger("testing"),
+			agentInfo:        &info.AgentInfo{},
+			stateBroadcaster: broadcaster.New(State{}, 0, 0),
+			managerChans: managerChans{
+				configManagerUpdate: configChan,
+			},
+			runtimeMgr:         &fakeRuntimeManager{},
+			otelMgr:            &fakeOTelManager{},
+			vars:               emptyVars(t),
+			componentPIDTicker: time.NewTicker(time.Second * 30),
+			secretMarkerFunc:   testSecretMarkerFunc,
+		}
+		coord.RegisterPolicyRollbackStore(store)
+		return coord, func(revision int) *configChange {
+			change := &configChange{cfg: config.MustNewConfigFrom(map[string]interface{}{"revision": revision})}
+			configChan <- change
+			coord.runLoopIteration(ctx)
+			return change
+		}
+	}
+
+	coord, apply := newCoordinator()
+	require.True(t, apply(1).acked)
+	healthyHash := coord.appliedPolicy.hash
+	coord.markPolicyHealthy()
+	require.True(t, apply(2).acked)
+	badHash := coord.appliedPolicy.hash
+	coord.rollbackPolicy(ctx, "4 component failures")
+
+	restarted, apply := newCoordinator()
+	require.NotNil(t, restarted.state.PolicyRollback)
+	assert.Equal(t, badHash, restarted.state.PolicyRollback.RevisionHash)
+	assert.Equal(t, "4 component failures", restarted.state.PolicyRollback.Reason)
+	refused := apply(2)
+	assert.ErrorIs(t, refused.err, errRolledBackPolicy, "the rolled back revision is refused after a restart")
+	assert.Equal(t, healthyHash, restarted.appliedPolicy.hash, "the healthy revision is applied instead")
+	rev, err := restarted.appliedPolicy.cfg.ToMapStr()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, rev["revision"])
+
+	require.True(t, apply(3).acked)
+	restarted, _ = newCoordinator()
+	assert.Nil(t, restarted.state.PolicyRollback, "a new revision clears the persisted rollback")
+}
+
+func TestCoordinatorPolicyGuardrailSendersHonorContext(t *testing.T) {
+	// The Coordinator is not running, the senders of the guardrail must not block once their context is done.
+	coord := &Coordinator{
+		policyHealthyChan:  make(chan struct{}),
+		policyRollbackChan: make(chan string),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	coord.MarkPolicyHealthy(ctx)
+	coord.RollbackPolicy(ctx, "storm")
+}
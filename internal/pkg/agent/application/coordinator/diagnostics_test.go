@@ -91,12 +91,16 @@ func TestDiagnosticLocalConfig(t *testing.T) {
 	// The YAML we expect to see from the preceding config
 	expectedCfg := `
 agent:
+  checkin: null
   download: null
   grpc: null
   id: ""
+  memory_pressure: null
   path: ""
+  peers: null
   process: null
   reload: null
+  run_cleanup: null
   upgrade: null
   v1_monitoring_enabled: false
   monitoring:
@@ -921,12 +925,13 @@ func TestCoordinatorPerformComponentDiagnostics(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create mock managers with callbacks
 			mockRuntimeMgr := &fakeRuntimeManager{
-				performComponentDiagnosticsCallback: func(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, comps ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+				performComponentDiagnosticsCallback: func(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, comps ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+					assert.Equal(t, time.Minute, cpuProfile, "the CPU profile duration should be forwarded")
 					return tt.runtimeDiags, tt.runtimeErr
 				},
 			}
 			mockOtelMgr := &fakeOTelManager{
-				performComponentDiagnosticsCallback: func(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, comps ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+				performComponentDiagnosticsCallback: func(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, comps ...component.Component) ([]runtime.ComponentDiagnostic, error) {
 					return tt.otelDiags, tt.otelErr
 				},
 			}
@@ -947,7 +952,7 @@ func TestCoordinatorPerformComponentDiagnostics(t *testing.T) {
 
 			// Execute PerformComponentDiagnostics
 			ctx := context.Background()
-			result, err := coord.PerformComponentDiagnostics(ctx, additionalMetrics, comp1, comp2)
+			result, err := coord.PerformComponentDiagnostics(ctx, additionalMetrics, time.Minute, comp1, comp2)
 
 			// Verify error handling
 			if tt.otelErr != nil {
@@ -0,0 +1,198 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+// OutputChecker validates the outputs of the policy before the components use them.
+type OutputChecker interface {
+	// Reload reads the output validation settings from the policy.
+	Reload(cfg *config.Config) error
+	// Enabled returns true when the outputs must be validated.
+	Enabled() bool
+	// Check validates the outputs and returns the errors of the outputs that failed by output name.
+	Check(ctx context.Context, outputs map[string]interface{}) map[string]error
+}
+
+// outputCheckResults are the results of the validation of the checked outputs, they are discarded when
+// ctx is cancelled by a newer validation.
+type outputCheckResults struct {
+	ctx     context.Context
+	checked []string
+	results map[string]error
+}
+
+// RegisterOutputChecker registers the validation of the outputs, run each time the outputs of the
+// component model change. Must be called before Run.
+func (c *Coordinator) RegisterOutputChecker(checker OutputChecker) {
+	c.outputChecker = checker
+}
+
+// checkOutputs starts the validation of the outputs of the current policy that are new or changed in the
+// background, the results of the other outputs are kept. A validation in progress is cancelled and its
+// outputs are validated again with the changed ones. The results are merged into the state once the
+// checks end.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) checkOutputs(ctx context.Context) {
+	if c.outputChecker == nil {
+		return
+	}
+	if !c.outputChecker.Enabled() {
+		if c.outputCheckCancel != nil {
+			c.outputCheckCancel()
+			c.outputCheckCancel = nil
+		}
+		c.outputsChecked = nil
+		c.outputsChecking = nil
+		if c.outputCheckResults != nil {
+			c.outputCheckResults = nil
+			c.stateNeedsRefresh = true
+		}
+		return
+	}
+
+	outputs, _ := c.derivedConfig["outputs"].(map[string]interface{})
+	for name := range c.outputsChecked {
+		if _, ok := outputs[name]; !ok {
+			c.forgetOutputCheck(name)
+		}
+	}
+	changed := make(map[string]interface{})
+	for name, output := range outputs {
+		if checked, ok := c.outputsChecked[name]; !ok || !reflect.DeepEqual(output, checked) {
+			changed[name] = output
+		}
+	}
+	if len(changed) == 0 {
+		// the outputs are unchanged, keep the validation in progress or its results
+		return
+	}
+	if c.outputCheckCancel != nil {
+		c.outputCheckCancel()
+		c.outputCheckCancel = nil
+		for _, name := range c.outputsChecking {
+			if output, ok := outputs[name]; ok {
+				changed[name] = output
+			}
+		}
+	}
+
+	if c.outputsChecked == nil {
+		c.outputsChecked = make(map[string]interface{}, len(changed))
+	}
+	checked := make([]string, 0, len(changed))
+	for name, output := range changed {
+		c.forgetOutputCheck(name)
+		c.outputsChecked[name] = output
+		checked = append(checked, name)
+	}
+	c.outputsChecking = checked
+
+	checkCtx, cancel := context.WithCancel(ctx)
+	c.outputCheckCancel = cancel
+	go func() {
+		results := c.outputChecker.Check(checkCtx, changed)
+		select {
+		case c.outputCheckChan <- outputCheckResults{ctx: checkCtx, checked: checked, results: results}:
+		case <-checkCtx.Done():
+		}
+	}()
+}
+
+// forgetOutputCheck drops the validation of the output, setting stateNeedsRefresh when it had failed.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) forgetOutputCheck(name string) {
+	delete(c.outputsChecked, name)
+	if _, ok := c.outputCheckResults[name]; ok {
+		delete(c.outputCheckResults, name)
+		c.stateNeedsRefresh = true
+	}
+}
+
+// setOutputCheckResults stores the errors of the checked outputs that failed their validation and sets
+// stateNeedsRefresh. Results of a cancelled validation, or of outputs removed since, are discarded.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) setOutputCheckResults(update outputCheckResults) {
+	if update.ctx.Err() != nil {
+		return
+	}
+	c.outputCheckCancel()
+	c.outputCheckCancel = nil
+	c.outputsChecking = nil
+
+	for _, name := range update.checked {
+		if _, ok := c.outputsChecked[name]; !ok {
+			continue
+		}
+		err, failed := update.results[name]
+		if !failed {
+			delete(c.outputCheckResults, name)
+			continue
+		}
+		c.logger.Warnf("Output %q failed its validation: %v", name, err)
+		if c.outputCheckResults == nil {
+			c.outputCheckResults = make(map[string]error)
+		}
+		c.outputCheckResults[name] = err
+	}
+	c.stateNeedsRefresh = true
+}
+
+// applyOutputCheckResults marks the output units of the components using an output that failed its
+// validation as degraded with the reason, unless they already report a failure.
+func applyOutputCheckResults(components []runtime.ComponentComponentState, results map[string]error) {
+	if len(results) == 0 {
+		return
+	}
+	for i, comp := range components {
+		name, ok := componentOutputName(comp.Component, results)
+		if !ok {
+			continue
+		}
+		key := runtime.ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: comp.Component.ID}
+		unit, ok := comp.State.Units[key]
+		if !ok {
+			continue
+		}
+		switch unit.State {
+		case client.UnitStateFailed, client.UnitStateStopping, client.UnitStateStopped:
+			continue
+		}
+		unit.State = client.UnitStateDegraded
+		unit.Message = "Output validation failed: " + results[name].Error()
+
+		// the units are shared with the internal state, copy them before the change
+		units := maps.Clone(comp.State.Units)
+		units[key] = unit
+		components[i].State.Units = units
+	}
+}
+
+// componentOutputName returns the name of the output used by the component among the given outputs. The
+// ID of a component is its input type followed by its output name and, for some inputs, the input ID.
+func componentOutputName(comp component.Component, outputs map[string]error) (string, bool) {
+	rest, ok := strings.CutPrefix(comp.ID, comp.InputType+"-")
+	if !ok {
+		return "", false
+	}
+	var found string
+	for name := range outputs {
+		if (rest == name || strings.HasPrefix(rest, name+"-")) && len(name) > len(found) {
+			found = name
+		}
+	}
+	return found, found != ""
+}
@@ -0,0 +1,223 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+// errRolledBackPolicy is returned when a configuration change delivers the revision of the policy that was
+// rolled back.
+var errRolledBackPolicy = errors.New("the policy revision was rolled back after a failure storm of the components and is not applied again")
+
+// PolicyRollback is the rollback of the applied policy to the last healthy revision, after applying it caused
+// a failure storm of the components.
+type PolicyRollback struct {
+	// Reason describes the failures that caused the rollback.
+	Reason string `yaml:"reason"`
+	// RevisionHash is the hash of the rolled back revision, it is refused until another revision is applied.
+	RevisionHash string    `yaml:"revision_hash"`
+	Since        time.Time `yaml:"since"`
+}
+
+// appliedPolicy is a policy applied by the Coordinator.
+type appliedPolicy struct {
+	cfg  *config.Config
+	hash string
+}
+
+// persistedPolicyRollback is the rollback of the policy kept across the restarts of the Elastic Agent: the policy
+// stored with the last policy change is the rolled back revision.
+type persistedPolicyRollback struct {
+	Reason        string                 `json:"reason"`
+	RevisionHash  string                 `json:"revision_hash"`
+	Since         time.Time              `json:"since"`
+	HealthyHash   string                 `json:"healthy_hash"`
+	HealthyPolicy map[string]interface{} `json:"healthy_policy"`
+}
+
+// MarkPolicyHealthy marks the applied policy as the healthy revision the policy is rolled back to.
+// Called from external goroutines.
+func (c *Coordinator) MarkPolicyHealthy(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+	case c.policyHealthyChan <- struct{}{}:
+	}
+}
+
+// RollbackPolicy rolls the applied policy back to the last healthy revision, the applied revision is refused
+// until another revision is applied. Nothing is done when the applied revision is the healthy one.
+// Called from external goroutines.
+func (c *Coordinator) RollbackPolicy(ctx context.Context, reason string) {
+	select {
+	case <-ctx.Done():
+	case c.policyRollbackChan <- reason:
+	}
+}
+
+// RegisterPolicyRollbackStore registers the store the rollback of the policy is persisted in, the persisted
+// rollback is loaded. Must be called before Run.
+func (c *Coordinator) RegisterPolicyRollbackStore(store storage.Storage) {
+	c.policyRollbackStore = store
+	if err := c.loadPolicyRollback(); err != nil {
+		c.logger.Errorf("Failed to load the rollback of the policy: %v", err)
+	}
+}
+
+// loadPolicyRollback reads the rollback of the policy and the healthy revision from the store.
+func (c *Coordinator) loadPolicyRollback() error {
+	if exists, err := c.policyRollbackStore.Exists(); err != nil || !exists {
+		return err
+	}
+	reader, err := c.policyRollbackStore.Load()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	var persisted persistedPolicyRollback
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return fmt.Errorf("failed to decode the rollback of the policy: %w", err)
+	}
+	healthy, err := config.NewConfigFrom(persisted.HealthyPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to decode the healthy revision of the policy: %w", err)
+	}
+	c.healthyPolicy = appliedPolicy{cfg: healthy, hash: persisted.HealthyHash}
+	c.state.PolicyRollback = &PolicyRollback{
+		Reason:       persisted.Reason,
+		RevisionHash: persisted.RevisionHash,
+		Since:        persisted.Since,
+	}
+	return nil
+}
+
+// persistPolicyRollback writes the rollback of the policy and the healthy revision to the store, the store is
+// emptied once the rollback is cleared.
+func (c *Coordinator) persistPolicyRollback() {
+	if c.policyRollbackStore == nil {
+		return
+	}
+	var data []byte
+	err := func() error {
+		if c.state.PolicyRollback == nil {
+			return nil
+		}
+		healthy, err := c.healthyPolicy.cfg.ToMapStr()
+		if err != nil {
+			return err
+		}
+		data, err = json.Marshal(persistedPolicyRollback{
+			Reason:        c.state.PolicyRollback.Reason,
+			RevisionHash:  c.state.PolicyRollback.RevisionHash,
+			Since:         c.state.PolicyRollback.Since,
+			HealthyHash:   c.healthyPolicy.hash,
+			HealthyPolicy: healthy,
+		})
+		return err
+	}()
+	if err == nil {
+		err = c.policyRollbackStore.Save(bytes.NewReader(data))
+	}
+	if err != nil {
+		c.logger.Errorf("Failed to persist the rollback of the policy: %v", err)
+	}
+}
+
+// hashConfig hashes a configuration as it is received, before it is processed.
+func hashConfig(cfg *config.Config) (string, error) {
+	m, err := cfg.ToMapStr()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// processConfigChange applies a configuration change unless it is the rolled back revision of the policy.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) processConfigChange(ctx context.Context, change ConfigChange) error {
+	hash, err := hashConfig(change.Config())
+	if err != nil {
+		return fmt.Errorf("could not hash the configuration: %w", err)
+	}
+	if c.state.PolicyRollback != nil && c.state.PolicyRollback.RevisionHash == hash {
+		if c.appliedPolicy.cfg == nil && c.healthyPolicy.cfg != nil {
+			// the Elastic Agent restarted with the rolled back revision as its last policy
+			c.logger.Warnf("Applying the last healthy policy revision %s instead of the rolled back revision %s", c.healthyPolicy.hash, hash)
+			if err := c.processConfig(ctx, c.healthyPolicy.cfg); err != nil {
+				return err
+			}
+			c.appliedPolicy = c.healthyPolicy
+		}
+		return errRolledBackPolicy
+	}
+	if err := c.processConfig(ctx, change.Config()); err != nil {
+		return err
+	}
+	if c.state.PolicyRollback != nil {
+		c.logger.Infof("Applied a new policy revision, clearing the rollback of revision %s", c.state.PolicyRollback.RevisionHash)
+		c.state.PolicyRollback = nil
+		c.stateNeedsRefresh = true
+		c.persistPolicyRollback()
+	}
+	c.appliedPolicy = appliedPolicy{cfg: change.Config(), hash: hash}
+	return nil
+}
+
+// markPolicyHealthy is the internal helper to mark the applied policy as healthy.
+// Must be called on the main Coordinator goroutine.
+func (c *Coordinator) markPolicyHealthy() {
+	if c.appliedPolicy.cfg == nil || c.healthyPolicy.hash == c.appliedPolicy.hash {
+		return
+	}
+	c.logger.Infof("Policy revision %s is healthy", c.appliedPolicy.hash)
+	c.healthyPolicy = c.appliedPolicy
+}
+
+// rollbackPolicy is the internal helper to roll the applied policy back to the last healthy revision and set
+// stateNeedsRefresh. Must be called on the main Coordinator goroutine.
+func (c *Coordinator) rollbackPolicy(ctx context.Context, reason string) {
+	if c.healthyPolicy.cfg == nil || c.healthyPolicy.hash == c.appliedPolicy.hash {
+		c.logger.Warnf("No healthy policy revision to roll back to after: %s", reason)
+		return
+	}
+	bad := c.appliedPolicy.hash
+	c.logger.Warnf("Rolling back policy revision %s to the last healthy revision %s: %s", bad, c.healthyPolicy.hash, reason)
+	if err := c.processConfig(ctx, c.healthyPolicy.cfg); err != nil {
+		c.logger.Errorf("Failed to roll back to the last healthy policy revision: %v", err)
+		return
+	}
+	c.appliedPolicy = c.healthyPolicy
+	c.state.PolicyRollback = &PolicyRollback{
+		Reason:       reason,
+		RevisionHash: bad,
+		Since:        time.Now().UTC(),
+	}
+	c.stateNeedsRefresh = true
+	c.persistPolicyRollback()
+	c.recordPolicy(c.healthyPolicy.cfg, ConfigChangeOrigin{Source: "rollback"})
+}
@@ -0,0 +1,143 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// specsWatcherDebounce is how long the watcher waits for the components directory to settle
+	// before reloading the specifications, a component update touches multiple files.
+	specsWatcherDebounce = time.Second
+
+	specFileSuffix = ".spec.yml"
+)
+
+// SpecsWatcher reports new runtime specifications when the components directory changes.
+type SpecsWatcher interface {
+	// Watch returns the channel the reloaded runtime specifications are sent to.
+	Watch() <-chan component.RuntimeSpecs
+	// Run watches the components directory until the context is cancelled.
+	Run(ctx context.Context) error
+}
+
+// ComponentsDirWatcher watches the components directory, and the user specs directory of the custom components,
+// for added, updated and removed spec files and component binaries, and reloads the runtime specifications when
+// they change.
+type ComponentsDirWatcher struct {
+	logger   *logger.Logger
+	dir      string
+	userDir  string
+	platform component.PlatformDetail
+	debounce time.Duration
+	updateCh chan component.RuntimeSpecs
+}
+
+// NewComponentsDirWatcher creates a watcher for the components directory and the user specs directory, an empty
+// userDir only watches the components directory.
+func NewComponentsDirWatcher(log *logger.Logger, dir string, userDir string, platform component.PlatformDetail) *ComponentsDirWatcher {
+	return &ComponentsDirWatcher{
+		logger:   log.Named("specs_watcher"),
+		dir:      dir,
+		userDir:  userDir,
+		platform: platform,
+		debounce: specsWatcherDebounce,
+		updateCh: make(chan component.RuntimeSpecs),
+	}
+}
+
+// Watch returns the channel the reloaded runtime specifications are sent to.
+func (w *ComponentsDirWatcher) Watch() <-chan component.RuntimeSpecs {
+	return w.updateCh
+}
+
+// Run watches the components directory until the context is cancelled.
+func (w *ComponentsDirWatcher) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create components directory watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(w.dir); err != nil {
+		return fmt.Errorf("failed to set watch on components directory [%s]: %w", w.dir, err)
+	}
+	if w.userDir != "" {
+		// the user specs directory is optional, the custom components are only reloaded when it exists at start
+		if err := watcher.Add(w.userDir); err != nil {
+			w.logger.Debugf("not watching user specs directory [%s]: %s", w.userDir, err)
+		}
+	}
+
+	// the timer is only armed once a relevant change has been seen
+	t := time.NewTimer(w.debounce)
+	t.Stop()
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.logger.Errorf("components directory watch returned error: %s", err)
+		case e, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if e.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 || !isComponentFile(e.Name) {
+				continue
+			}
+			w.logger.Debugf("component file %s changed (%s)", e.Name, e.Op)
+			t.Reset(w.debounce)
+		case <-t.C:
+			specs, err := component.LoadRuntimeSpecs(w.dir, w.platform, w.loadOpts()...)
+			if err != nil {
+				// can happen in the middle of a drop-in, when the spec is written before the binary;
+				// the current specifications are kept until the next change
+				w.logger.Warnf("failed to reload component specifications, keeping the current ones: %s", err)
+				continue
+			}
+			for path, err := range specs.UserSpecErrors() {
+				w.logger.With("error.message", err).Warnf("Skipped the custom component specification %s", path)
+			}
+			w.logger.With("inputs", specs.Inputs()).Info("Component specifications changed, reloaded available inputs and outputs")
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case w.updateCh <- specs:
+			}
+		}
+	}
+}
+
+func (w *ComponentsDirWatcher) loadOpts() []component.LoadRuntimeOption {
+	if w.userDir == "" {
+		return nil
+	}
+	return []component.LoadRuntimeOption{component.WithUserSpecs(w.userDir)}
+}
+
+// isComponentFile returns true when the path is a spec file or the binary of a component with a spec file.
+func isComponentFile(path string) bool {
+	if strings.HasSuffix(path, specFileSuffix) {
+		return true
+	}
+	specPath := strings.TrimSuffix(path, ".exe") + specFileSuffix
+	_, err := os.Stat(specPath)
+	return err == nil
+}
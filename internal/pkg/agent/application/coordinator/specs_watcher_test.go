@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package coordinator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+const watcherTestSpec = `
+version: 2
+inputs:
+  - name: foobar
+    description: "Foo input"
+    platforms:
+      - container/amd64
+      - container/arm64
+      - linux/amd64
+      - linux/arm64
+      - darwin/amd64
+      - darwin/arm64
+      - windows/amd64
+    outputs:
+      - elasticsearch
+    command: {}
+`
+
+func TestComponentsDirWatcher(t *testing.T) {
+	dir := t.TempDir()
+	log, _ := loggertest.New("specs_watcher")
+	platform, err := component.LoadPlatformDetail()
+	require.NoError(t, err)
+
+	w := NewComponentsDirWatcher(log, dir, "", platform)
+	w.debounce = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- w.Run(ctx)
+	}()
+
+	// unrelated files are ignored, the watch is set asynchronously so write the file
+	// until the watcher had time to start
+	require.Eventually(t, func() bool {
+		return os.WriteFile(filepath.Join(dir, "README.md"), []byte("readme"), 0o644) == nil
+	}, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	// a spec without its binary cannot be loaded, no update is sent until the binary is added
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foobar.spec.yml"), []byte(watcherTestSpec), 0o644))
+	select {
+	case <-w.Watch():
+		t.Fatal("specifications should not be reloaded without the binary")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	binaryName := "foobar"
+	if runtime.GOOS == "windows" {
+		binaryName += ".exe"
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, binaryName), []byte("binary"), 0o755))
+	select {
+	case specs := <-w.Watch():
+		assert.Equal(t, []string{"foobar"}, specs.Inputs())
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the specifications to be reloaded")
+	}
+
+	cancel()
+	assert.ErrorIs(t, <-errCh, context.Canceled)
+}
+
+func TestIsComponentFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foobar.spec.yml"), []byte(watcherTestSpec), 0o644))
+
+	assert.True(t, isComponentFile(filepath.Join(dir, "foobar.spec.yml")))
+	assert.True(t, isComponentFile(filepath.Join(dir, "foobar")))
+	assert.True(t, isComponentFile(filepath.Join(dir, "foobar.exe")))
+	assert.True(t, isComponentFile(filepath.Join(dir, "other.spec.yml")))
+	assert.False(t, isComponentFile(filepath.Join(dir, "other")))
+	assert.False(t, isComponentFile(filepath.Join(dir, "foobar.yml")))
+}
@@ -33,6 +33,15 @@ type priorityQueue interface {
 	Save() error
 }
 
+// ledger records the actions delivered by Fleet until they complete, so they are neither lost nor
+// executed twice across restarts.
+type ledger interface {
+	AddPendingActions(actions ...fleetapi.Action)
+	StartPendingAction(action fleetapi.Action)
+	CompletePendingAction(actionID string, executed bool)
+	Save() error
+}
+
 // Dispatcher processes actions coming from fleet api.
 type Dispatcher interface {
 	Dispatch(context.Context, details.Observer, acker.Acker, ...fleetapi.Action)
@@ -45,6 +54,7 @@ type ActionDispatcher struct {
 	handlers actionHandlers
 	def      actions.Handler
 	queue    priorityQueue
+	ledger   ledger
 	rt       *retryConfig
 	errCh    chan error
 	topPath  string
@@ -83,6 +93,11 @@ func (ad *ActionDispatcher) Errors() <-chan error {
 	return ad.errCh
 }
 
+// SetLedger sets the ledger recording the progress of the dispatched actions.
+func (ad *ActionDispatcher) SetLedger(l ledger) {
+	ad.ledger = l
+}
+
 // Register registers a new handler for action.
 func (ad *ActionDispatcher) Register(a fleetapi.Action, handler actions.Handler) error {
 	k := ad.key(a)
@@ -141,6 +156,8 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 		ad.updateUpgradeDetails(now, detailsSetter)
 	}
 
+	// actions taken from the queue are pending until they complete, they are persisted with the queue
+	ad.addPending(actions)
 	if err := ad.queue.Save(); err != nil {
 		ad.log.Errorf("failed to persist action_queue: %v", err)
 	}
@@ -163,17 +180,32 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 			return
 		}
 
-		if err := ad.dispatchAction(ctx, action, acker); err != nil {
+		ad.startPending(action)
+		async, err := ad.dispatchAction(ctx, action, acker)
+		if err != nil {
 			rAction, ok := action.(fleetapi.RetryableAction)
+			var deferred *fleetapi.DeferredError
+			if ok && errors.As(err, &deferred) {
+				ad.scheduleDeferred(ctx, rAction, deferred, acker, &upgradeDetailsNeedUpdate)
+				// the queue holds the deferred action until it runs, it's pending again once dequeued
+				ad.completePending(action, false)
+				continue
+			}
 			if ok {
 				rAction.SetError(err) // set the retryable action error to what the dispatcher returned
 				ad.scheduleRetry(ctx, rAction, acker, &upgradeDetailsNeedUpdate)
+				// the queue holds the action until its retry, the action is not queued again without retries left
+				ad.completePending(action, false)
 				continue
 			}
 			ad.log.Errorf("Failed to dispatch action id %q of type %q, error: %+v", action.ID(), action.Type(), err)
+			ad.completePending(action, false)
 			reportedErr = err
 			continue
 		}
+		if !async {
+			ad.completePending(action, true)
+		}
 		ad.log.Debugf("Successfully dispatched action: '%+v'", action)
 	}
 
@@ -190,13 +222,20 @@ func (ad *ActionDispatcher) Dispatch(ctx context.Context, detailsSetter details.
 	}
 }
 
-func (ad *ActionDispatcher) dispatchAction(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
+// dispatchAction calls the handler of the action. It returns true when the handler completes the action in the
+// background, the action is then completed in the ledger once the handler is done with it.
+func (ad *ActionDispatcher) dispatchAction(ctx context.Context, a fleetapi.Action, acker acker.Acker) (bool, error) {
 	handler, found := ad.handlers[ad.key(a)]
 	if !found {
-		return ad.def.Handle(ctx, a, acker)
+		handler = ad.def
 	}
 
-	return handler.Handle(ctx, a, acker)
+	if asyncHandler, ok := handler.(actions.AsyncHandler); ok {
+		return true, asyncHandler.HandleAsync(ctx, a, acker, func(executed bool) {
+			ad.completePending(a, executed)
+		})
+	}
+	return false, handler.Handle(ctx, a, acker)
 }
 
 func detectTypes(actions []fleetapi.Action) []string {
@@ -270,9 +309,14 @@ func (ad *ActionDispatcher) dispatchCancelActions(ctx context.Context, actions [
 		}
 
 		actions = append(actions[:i], actions[i+1:]...)
-		if err := ad.dispatchAction(ctx, action, acker); err != nil {
+		ad.startPending(action)
+		async, err := ad.dispatchAction(ctx, action, acker)
+		if err != nil {
 			ad.log.Errorf("Unable to dispatch cancel action id %s: %v", action.ID(), err)
 		}
+		if err != nil || !async {
+			ad.completePending(action, err == nil)
+		}
 
 		if _, exists := queuedUpgradeActions[cancelAction.Data.TargetID]; exists {
 			*upgradeDetailsNeedUpdate = true
@@ -358,6 +402,7 @@ func (ad *ActionDispatcher) compactAndRemoveQueuedUpgrades(input []fleetapi.Acti
 				upgradeAction = action
 			} else {
 				ad.log.Warnf("Found extra upgrade action in fleetgateway actions [id = %s]", action.ID())
+				ad.completePending(action, false)
 				continue
 			}
 			if n := ad.queue.CancelType(fleetapi.ActionTypeUpgrade); n > 0 {
@@ -388,6 +433,37 @@ func (ad *ActionDispatcher) updateUpgradeDetails(ts time.Time, detailsSetter det
 	detailsSetter(upgradeDetails)
 }
 
+// addPending records the actions as pending in the ledger, it's persisted by the next save of the queue.
+func (ad *ActionDispatcher) addPending(actions []fleetapi.Action) {
+	if ad.ledger == nil || len(actions) == 0 {
+		return
+	}
+	ad.ledger.AddPendingActions(actions...)
+}
+
+// startPending records in the ledger that the handler of the action is called. Once started, the action is not
+// dispatched again after a restart, as it may have been partially executed.
+func (ad *ActionDispatcher) startPending(action fleetapi.Action) {
+	if ad.ledger == nil {
+		return
+	}
+	ad.ledger.StartPendingAction(action)
+	if err := ad.ledger.Save(); err != nil {
+		ad.log.Errorf("failed to persist start of action id %s: %v", action.ID(), err)
+	}
+}
+
+// completePending removes the action from the pending actions of the ledger.
+func (ad *ActionDispatcher) completePending(action fleetapi.Action, executed bool) {
+	if ad.ledger == nil {
+		return
+	}
+	ad.ledger.CompletePendingAction(action.ID(), executed)
+	if err := ad.ledger.Save(); err != nil {
+		ad.log.Errorf("failed to persist completion of action id %s: %v", action.ID(), err)
+	}
+}
+
 // scheduleRetry will schedule a retry for the passed action. Note that this adjusts the start time of the action
 // but doesn't affect expiration time. If the action is scheduled to be retried and it is an upgrade action,
 // upgradeDetailsNeedUpdate will be set to true.
@@ -431,6 +507,32 @@ func (ad *ActionDispatcher) scheduleRetry(ctx context.Context, action fleetapi.R
 	}
 }
 
+// scheduleDeferred will queue the passed action again to start when its deferral ends. Unlike a retry, a deferral
+// doesn't count as an attempt, the action can be deferred until it expires. If the action is an upgrade action,
+// upgradeDetailsNeedUpdate will be set to true.
+func (ad *ActionDispatcher) scheduleDeferred(ctx context.Context, action fleetapi.RetryableAction, deferred *fleetapi.DeferredError, acker acker.Acker, upgradeDetailsNeedUpdate *bool) {
+	ad.log.Infof("Deferring action id %q of type %q until %s: %s", action.ID(), action.Type(), deferred.Until, deferred.Reason)
+	action.SetError(deferred)
+	action.SetStartTime(deferred.Until)
+	ad.log.Debugf("Adding action id: %s to queue.", action.ID())
+	ad.queue.Add(action, deferred.Until.Unix())
+	if err := ad.queue.Save(); err != nil {
+		ad.log.Errorf("deferred action id %s failed to persist action_queue: %v", action.ID(), err)
+	}
+
+	if action.Type() == fleetapi.ActionTypeUpgrade {
+		*upgradeDetailsNeedUpdate = true
+	}
+
+	if err := acker.Ack(ctx, action); err != nil {
+		ad.log.Errorf("Unable to ack action deferral (id %s) to fleet-server: %v", action.ID(), err)
+		return
+	}
+	if err := acker.Commit(ctx); err != nil {
+		ad.log.Errorf("Unable to commit action deferral (id %s) to fleet-server: %v", action.ID(), err)
+	}
+}
+
 // GetScheduledUpgradeDetails returns the upgrade details of the next scheduled upgrade action, if any. It also adjusts
 // accordingly the upgrade details if the action has expired.
 func GetScheduledUpgradeDetails(log *logger.Logger, actions []fleetapi.ScheduledAction, ts time.Time) *details.Details {
@@ -490,8 +592,9 @@ func GetScheduledUpgradeDetails(log *logger.Logger, actions []fleetapi.Scheduled
 		details.StateScheduled,
 		nextUpgradeActionID)
 	upgradeDetails.Metadata.ScheduledAt = &nextUpgradeStartTime
-	// scheduled upgrade actions can have errors if retried
-	if err := nextUpgradeAction.GetError(); err != nil {
+	// scheduled upgrade actions can have errors if retried, a deferral is not a failure
+	var deferred *fleetapi.DeferredError
+	if err := nextUpgradeAction.GetError(); err != nil && !errors.As(err, &deferred) {
 		upgradeDetails.Metadata.ErrorMsg = fmt.Sprintf("A prior dispatch attempt failed with: %v", err)
 	}
 	return upgradeDetails
@@ -8,6 +8,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -19,6 +20,8 @@ import (
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/noop"
@@ -35,6 +38,21 @@ func (h *mockHandler) Handle(ctx context.Context, a fleetapi.Action, acker acker
 	return args.Error(0)
 }
 
+// mockAsyncHandler completes the actions once done is called by the test.
+type mockAsyncHandler struct {
+	mockHandler
+	done chan func(executed bool)
+}
+
+func (h *mockAsyncHandler) HandleAsync(ctx context.Context, a fleetapi.Action, acker acker.Acker, done func(executed bool)) error {
+	args := h.Called(ctx, a, acker)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	h.done <- done
+	return nil
+}
+
 // need various action structs as the dispather uses type reflection for routing, not action.Type()
 type mockAction struct {
 	mock.Mock
@@ -103,6 +121,24 @@ func (m *mockSaver) Save() error {
 	return args.Error(0)
 }
 
+type mockLedger struct {
+	mock.Mock
+}
+
+func (m *mockLedger) AddPendingActions(actions ...fleetapi.Action) {
+	m.Called(actions)
+}
+func (m *mockLedger) StartPendingAction(action fleetapi.Action) {
+	m.Called(action)
+}
+func (m *mockLedger) CompletePendingAction(actionID string, executed bool) {
+	m.Called(actionID, executed)
+}
+func (m *mockLedger) Save() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
 func TestActionDispatcher(t *testing.T) {
 	detailsSetter := func(upgradeDetails *details.Details) {}
 	ack := noop.New()
@@ -396,6 +432,131 @@ func TestActionDispatcher(t *testing.T) {
 		saver.AssertExpectations(t)
 	})
 
+	t.Run("Dispatch of a deferred upgrade action queues it until the deferral ends", func(t *testing.T) {
+		until := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).
+			Return(&fleetapi.DeferredError{Until: until, Reason: "outside of the upgrade windows"}).Once()
+
+		saver := &mockSaver{}
+		saver.On("Save").Return(nil).Times(2)
+		saver.On("SetQueue", mock.Anything).Times(2)
+		actionQueue, err := queue.NewActionQueue([]fleetapi.ScheduledAction{}, saver)
+		require.NoError(t, err)
+
+		d, err := New(nil, t.TempDir(), def, actionQueue)
+		require.NoError(t, err)
+
+		var gotDetails *details.Details
+		detailsSetter := func(upgradeDetails *details.Details) {
+			gotDetails = upgradeDetails
+		}
+
+		action := &fleetapi.ActionUpgrade{
+			ActionID:   "id",
+			ActionType: fleetapi.ActionTypeUpgrade,
+			Data: fleetapi.ActionUpgradeData{
+				Version: "9.3.0",
+			},
+		}
+		go d.Dispatch(context.Background(), detailsSetter, ack, action)
+		if err := <-d.Errors(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		queued := actionQueue.Actions()
+		require.Len(t, queued, 1)
+		startTime, err := queued[0].StartTime()
+		require.NoError(t, err)
+		assert.Equal(t, until, startTime)
+		assert.Zero(t, action.RetryAttempt(), "a deferral must not count as a retry")
+
+		require.NotNil(t, gotDetails, "upgrade details should have been set")
+		assert.Equal(t, details.StateScheduled, gotDetails.State)
+		require.NotNil(t, gotDetails.Metadata.ScheduledAt)
+		assert.Equal(t, until, *gotDetails.Metadata.ScheduledAt)
+		assert.Empty(t, gotDetails.Metadata.ErrorMsg)
+		def.AssertExpectations(t)
+		saver.AssertExpectations(t)
+	})
+
+	t.Run("Deferred actions stay queued and are not interrupted after a restart", func(t *testing.T) {
+		log, _ := loggertest.New(t.Name())
+		statePath := filepath.Join(t.TempDir(), "state.enc")
+		diskStore, err := storage.NewDiskStore(statePath)
+		require.NoError(t, err)
+		stateStore, err := store.NewStateStore(log, diskStore)
+		require.NoError(t, err)
+		actionQueue, err := queue.NewActionQueue(stateStore.Queue(), stateStore)
+		require.NoError(t, err)
+
+		until := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).
+			Return(&fleetapi.DeferredError{Until: until, Reason: "outside of the upgrade windows"}).Once()
+
+		d, err := New(log, t.TempDir(), def, actionQueue)
+		require.NoError(t, err)
+		d.SetLedger(stateStore)
+
+		action := &fleetapi.ActionUpgrade{
+			ActionID:   "id",
+			ActionType: fleetapi.ActionTypeUpgrade,
+			Data: fleetapi.ActionUpgradeData{
+				Version: "9.3.0",
+			},
+		}
+		go d.Dispatch(context.Background(), detailsSetter, ack, action)
+		if err := <-d.Errors(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		def.AssertExpectations(t)
+
+		// the state is loaded again as on a restart of the agent
+		diskStore, err = storage.NewDiskStore(statePath)
+		require.NoError(t, err)
+		restarted, err := store.NewStateStore(log, diskStore)
+		require.NoError(t, err)
+		pending, interrupted := restarted.PendingActions()
+		assert.Empty(t, pending)
+		assert.Empty(t, interrupted, "a deferred action is still queued, it was not interrupted")
+		require.Len(t, restarted.Queue(), 1)
+		assert.Equal(t, "id", restarted.Queue()[0].ID())
+		assert.False(t, restarted.ActionExecuted("id"))
+	})
+
+	t.Run("Deferred actions are completed in the ledger once queued", func(t *testing.T) {
+		until := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).
+			Return(&fleetapi.DeferredError{Until: until, Reason: "outside of the upgrade windows"}).Once()
+
+		saver := &mockSaver{}
+		saver.On("Save").Return(nil).Times(2)
+		saver.On("SetQueue", mock.Anything).Times(2)
+		actionQueue, err := queue.NewActionQueue([]fleetapi.ScheduledAction{}, saver)
+		require.NoError(t, err)
+
+		action := &fleetapi.ActionUpgrade{ActionID: "id", ActionType: fleetapi.ActionTypeUpgrade}
+		l := &mockLedger{}
+		l.On("AddPendingActions", []fleetapi.Action{action}).Once()
+		l.On("StartPendingAction", action).Once()
+		l.On("CompletePendingAction", "id", false).Once()
+		l.On("Save").Return(nil).Times(2)
+
+		d, err := New(nil, t.TempDir(), def, actionQueue)
+		require.NoError(t, err)
+		d.SetLedger(l)
+
+		go d.Dispatch(context.Background(), detailsSetter, ack, action)
+		if err := <-d.Errors(); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		assert.Len(t, actionQueue.Actions(), 1)
+		def.AssertExpectations(t)
+		l.AssertExpectations(t)
+	})
+
 	t.Run("Dispatch multiple events returns one error", func(t *testing.T) {
 		saver := &mockSaver{}
 		saver.On("Save").Return(nil).Once()
@@ -446,6 +607,95 @@ func TestActionDispatcher(t *testing.T) {
 		def.AssertExpectations(t)
 	})
 
+	t.Run("Dispatched actions are recorded in the ledger", func(t *testing.T) {
+		saver := &mockSaver{}
+		saver.On("Save").Return(nil).Once()
+		saver.On("SetQueue", mock.Anything).Once()
+		actionQueue, err := queue.NewActionQueue([]fleetapi.ScheduledAction{}, saver)
+		require.NoError(t, err)
+
+		action1 := &mockAction{}
+		action1.On("Type").Return("action")
+		action1.On("ID").Return("id1")
+		action2 := &mockAction{}
+		action2.On("Type").Return("action")
+		action2.On("ID").Return("id2")
+
+		def := &mockHandler{}
+		def.On("Handle", mock.Anything, action1, ack).Return(nil).Once()
+		def.On("Handle", mock.Anything, action2, ack).Return(errors.New("failed")).Once()
+
+		l := &mockLedger{}
+		l.On("AddPendingActions", []fleetapi.Action{action1, action2}).Once()
+		l.On("StartPendingAction", action1).Once()
+		l.On("CompletePendingAction", "id1", true).Once()
+		l.On("StartPendingAction", action2).Once()
+		l.On("CompletePendingAction", "id2", false).Once()
+		l.On("Save").Return(nil).Times(4)
+
+		d, err := New(nil, t.TempDir(), def, actionQueue)
+		require.NoError(t, err)
+		d.SetLedger(l)
+
+		dispatchCompleted := make(chan struct{})
+		go func() {
+			d.Dispatch(context.Background(), detailsSetter, ack, action1, action2)
+			close(dispatchCompleted)
+		}()
+		select {
+		case err := <-d.Errors():
+			assert.EqualError(t, err, "failed")
+		case <-time.After(time.Second):
+			t.Fatal("Expected error")
+		}
+		<-dispatchCompleted
+
+		def.AssertExpectations(t)
+		l.AssertExpectations(t)
+	})
+
+	t.Run("Actions of an async handler are pending until done", func(t *testing.T) {
+		saver := &mockSaver{}
+		saver.On("Save").Return(nil).Once()
+		saver.On("SetQueue", mock.Anything).Once()
+		actionQueue, err := queue.NewActionQueue([]fleetapi.ScheduledAction{}, saver)
+		require.NoError(t, err)
+
+		action := &mockAction{}
+		action.On("Type").Return("action")
+		action.On("ID").Return("id1")
+
+		def := &mockHandler{}
+		handler := &mockAsyncHandler{done: make(chan func(executed bool), 1)}
+		handler.On("HandleAsync", mock.Anything, action, ack).Return(nil).Once()
+
+		l := &mockLedger{}
+		l.On("AddPendingActions", []fleetapi.Action{action}).Once()
+		l.On("StartPendingAction", action).Once()
+		l.On("Save").Return(nil)
+
+		d, err := New(nil, t.TempDir(), def, actionQueue)
+		require.NoError(t, err)
+		require.NoError(t, d.Register(action, handler))
+		d.SetLedger(l)
+
+		go d.Dispatch(context.Background(), detailsSetter, ack, action)
+		select {
+		case err := <-d.Errors():
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Expected the dispatch to complete")
+		}
+		l.AssertNotCalled(t, "CompletePendingAction", mock.Anything, mock.Anything)
+
+		l.On("CompletePendingAction", "id1", true).Once()
+		done := <-handler.done
+		done(true)
+
+		handler.AssertExpectations(t)
+		l.AssertExpectations(t)
+	})
+
 	t.Run("Dispatch multiples events in separate batch returns one error second one resets it", func(t *testing.T) {
 		def := &mockHandler{}
 		def.On("Handle", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("test error")).Once()
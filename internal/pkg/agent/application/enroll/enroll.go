@@ -18,11 +18,14 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/clientcert"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
 	monitoringConfig "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
@@ -32,6 +35,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
 const (
@@ -125,6 +129,16 @@ func enroll(
 	options EnrollOptions,
 	configStore saver,
 ) error {
+	// the enrollment token is obtained from the cloud identity of the instance on every attempt, it can be
+	// short-lived
+	if options.EnrollAPIKey == "" && options.IdentityProvider != "" {
+		token, err := exchangeIdentity(ctx, client, options.IdentityProvider, options.URL)
+		if err != nil {
+			return err
+		}
+		options.EnrollAPIKey = token
+	}
+
 	cmd := fleetapi.NewEnrollCmd(client)
 
 	metadata, err := info.Metadata(ctx, log)
@@ -150,6 +164,19 @@ func enroll(
 		},
 	}
 
+	// a new key is generated on every attempt, it's only stored once Fleet issued its certificate
+	var clientKey string
+	if options.CertificateRequest {
+		commonName := options.ID
+		if commonName == "" && metadata.Host != nil {
+			commonName = metadata.Host.Hostname
+		}
+		clientKey, r.CSR, err = clientcert.NewRequest(commonName)
+		if err != nil {
+			return err
+		}
+	}
+
 	resp, err := cmd.Execute(ctx, r)
 	if err != nil {
 		return errors.New(err,
@@ -169,6 +196,13 @@ func enroll(
 		return err
 	}
 
+	if options.CertificateRequest {
+		if err := storeClientCertificate(ctx, clientKey, resp.Item.ClientCertificate, options.FixPermissions); err != nil {
+			return err
+		}
+		fleetConfig.ClientCertificate = &configuration.FleetClientCertificateConfig{Enabled: true}
+	}
+
 	agentConfig := CreateAgentConfig(resp.Item.ID, persistentConfig, options.FleetServer.Headers, options.Staging)
 
 	localFleetServer := options.FleetServer.ConnStr != ""
@@ -481,6 +515,27 @@ func createFleetConfigFromEnroll(accessAPIKey string, enrollmentToken string, re
 	return cfg, nil
 }
 
+// storeClientCertificate stores the client certificate issued by Fleet in the vault, the certificate is not
+// part of the stored Fleet configuration.
+func storeClientCertificate(ctx context.Context, key string, certificate string, owner *utils.FileOwner) error {
+	cert, _, err := clientcert.Issued(key, certificate)
+	if err != nil {
+		return err
+	}
+	hasRoot, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("checking if running with root/Administrator privileges: %w", err)
+	}
+	opts := []vault.OptionFunc{vault.WithUnprivileged(!hasRoot)}
+	if owner != nil {
+		opts = append(opts, vault.WithVaultOwnership(*owner))
+	}
+	if err := secret.SetClientCertificate(ctx, cert, opts...); err != nil {
+		return fmt.Errorf("failed to store the client certificate: %w", err)
+	}
+	return nil
+}
+
 func fleetHashToken(token string) (string, error) {
 	enrollmentHashBytes, err := crypto.GeneratePBKDF2FromPassword([]byte(token))
 	if err != nil {
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package enroll
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+const (
+	// IdentityProviderAWS proves the identity of an EC2 instance with its signed instance identity document.
+	IdentityProviderAWS = "aws"
+	// IdentityProviderGCP proves the identity of a Compute Engine instance with the identity token of its
+	// service account, the audience of the token is the Fleet Server URL.
+	IdentityProviderGCP = "gcp"
+	// IdentityProviderAzure proves the identity of an Azure virtual machine with its attested document.
+	IdentityProviderAzure = "azure"
+
+	identityTimeout = 10 * time.Second
+)
+
+// IdentityProviders are the supported cloud identity providers.
+var IdentityProviders = []string{IdentityProviderAWS, IdentityProviderGCP, IdentityProviderAzure}
+
+// the metadata services of the providers, variables for testability
+var (
+	awsMetadataURL   = "http://169.254.169.254"
+	gcpMetadataURL   = "http://metadata.google.internal"
+	azureMetadataURL = "http://169.254.169.254"
+)
+
+// ValidateIdentityProvider returns an error when the provider is not one of the IdentityProviders.
+func ValidateIdentityProvider(provider string) error {
+	if !slices.Contains(IdentityProviders, provider) {
+		return fmt.Errorf("unknown identity provider %q, must be one of [%s]", provider, strings.Join(IdentityProviders, ", "))
+	}
+	return nil
+}
+
+// exchangeIdentity exchanges the identity of the instance from the metadata service of the provider for an
+// enrollment token from Fleet Server.
+func exchangeIdentity(ctx context.Context, client fleetclient.Sender, provider string, fleetURL string) (string, error) {
+	req, err := instanceIdentity(ctx, provider, fleetURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the %s instance identity: %w", provider, err)
+	}
+
+	resp, err := fleetapi.NewIdentityTokenCmd(client).Execute(ctx, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to exchange the %s instance identity for an enrollment token: %w", provider, err)
+	}
+	return resp.EnrollmentToken, nil
+}
+
+func instanceIdentity(ctx context.Context, provider string, fleetURL string) (*fleetapi.IdentityTokenRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, identityTimeout)
+	defer cancel()
+
+	req := &fleetapi.IdentityTokenRequest{Provider: provider}
+	switch provider {
+	case IdentityProviderAWS:
+		// IMDSv2, the session token is required before reading the metadata
+		token, err := metadataRequest(ctx, http.MethodPut, awsMetadataURL+"/latest/api/token",
+			map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "60"})
+		if err != nil {
+			return nil, err
+		}
+		headers := map[string]string{"X-aws-ec2-metadata-token": token}
+		req.Document, err = metadataRequest(ctx, http.MethodGet, awsMetadataURL+"/latest/dynamic/instance-identity/document", headers)
+		if err != nil {
+			return nil, err
+		}
+		req.Signature, err = metadataRequest(ctx, http.MethodGet, awsMetadataURL+"/latest/dynamic/instance-identity/pkcs7", headers)
+		if err != nil {
+			return nil, err
+		}
+	case IdentityProviderGCP:
+		var err error
+		req.Document, err = metadataRequest(ctx, http.MethodGet,
+			gcpMetadataURL+"/computeMetadata/v1/instance/service-accounts/default/identity?format=full&audience="+url.QueryEscape(fleetURL),
+			map[string]string{"Metadata-Flavor": "Google"})
+		if err != nil {
+			return nil, err
+		}
+	case IdentityProviderAzure:
+		doc, err := metadataRequest(ctx, http.MethodGet, azureMetadataURL+"/metadata/attested/document?api-version=2020-09-01",
+			map[string]string{"Metadata": "true"})
+		if err != nil {
+			return nil, err
+		}
+		var attested struct {
+			Signature string `json:"signature"`
+		}
+		if err := json.Unmarshal([]byte(doc), &attested); err != nil {
+			return nil, fmt.Errorf("failed to decode the attested document: %w", err)
+		}
+		req.Document = attested.Signature
+	default:
+		return nil, ValidateIdentityProvider(provider)
+	}
+	return req, nil
+}
+
+func metadataRequest(ctx context.Context, method string, u string, headers map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	// the metadata services are link-local, they must not go through a proxy
+	client := &http.Client{Transport: &http.Transport{Proxy: nil}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: status code %d: %s", method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package enroll
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestExchangeIdentity(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		assert.NotEmpty(t, r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+		_, _ = w.Write([]byte("imds-token"))
+	})
+	mux.HandleFunc("GET /latest/dynamic/instance-identity/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "imds-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/latest/dynamic/instance-identity/pkcs7" {
+			_, _ = w.Write([]byte("aws-signature\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"instanceId": "i-1234567890abcdef0"}`))
+	})
+	mux.HandleFunc("GET /computeMetadata/v1/instance/service-accounts/default/identity", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Google", r.Header.Get("Metadata-Flavor"))
+		assert.Equal(t, "https://fleet.example.com:8220", r.URL.Query().Get("audience"))
+		_, _ = w.Write([]byte("gcp-jwt"))
+	})
+	mux.HandleFunc("GET /metadata/attested/document", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Metadata"))
+		_, _ = w.Write([]byte(`{"encoding": "pkcs7", "signature": "azure-signature"}`))
+	})
+	metadata := httptest.NewServer(mux)
+	defer metadata.Close()
+	awsMetadataURL, gcpMetadataURL, azureMetadataURL = metadata.URL, metadata.URL, metadata.URL
+	defer func() {
+		awsMetadataURL, gcpMetadataURL, azureMetadataURL = "http://169.254.169.254", "http://metadata.google.internal", "http://169.254.169.254"
+	}()
+
+	expected := map[string]fleetapi.IdentityTokenRequest{
+		IdentityProviderAWS:   {Provider: IdentityProviderAWS, Document: `{"instanceId": "i-1234567890abcdef0"}`, Signature: "aws-signature"},
+		IdentityProviderGCP:   {Provider: IdentityProviderGCP, Document: "gcp-jwt"},
+		IdentityProviderAzure: {Provider: IdentityProviderAzure, Document: "azure-signature"},
+	}
+	fleet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/fleet/agents/identity_token", r.URL.Path)
+		var req fleetapi.IdentityTokenRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, expected[req.Provider], req)
+		_ = json.NewEncoder(w).Encode(fleetapi.IdentityTokenResponse{EnrollmentToken: req.Provider + "-enrollment-token"})
+	}))
+	defer fleet.Close()
+	log, _ := loggertest.New(t.Name())
+	client, err := fleetclient.NewWithConfig(log, remote.Config{Host: fleet.URL})
+	require.NoError(t, err)
+
+	for _, provider := range IdentityProviders {
+		t.Run(provider, func(t *testing.T) {
+			token, err := exchangeIdentity(t.Context(), client, provider, "https://fleet.example.com:8220")
+			require.NoError(t, err)
+			assert.Equal(t, provider+"-enrollment-token", token)
+		})
+	}
+
+	t.Run("unknown provider", func(t *testing.T) {
+		_, err := exchangeIdentity(t.Context(), client, "oci", "https://fleet.example.com:8220")
+		assert.ErrorContains(t, err, "unknown identity provider")
+	})
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
@@ -31,6 +32,8 @@ type EnrollOptions struct {
 	ID                   string                     `yaml:"id,omitempty" json:"id,omitempty"`
 	ReplaceToken         string                     `yaml:"replace_token,omitempty" json:"replace_token,omitempty"`
 	EnrollAPIKey         string                     `yaml:"enrollment_key,omitempty" json:"enrollment_key,omitempty"`
+	IdentityProvider     string                     `yaml:"identity_provider,omitempty" json:"identity_provider,omitempty"`
+	CertificateRequest   bool                       `yaml:"certificate_request,omitempty" json:"certificate_request,omitempty"`
 	Staging              string                     `yaml:"staging,omitempty" json:"staging,omitempty"`
 	Headers              map[string]string          `yaml:"headers,omitempty"`
 	ProxyURL             string                     `yaml:"proxy_url,omitempty" json:"proxy_url,omitempty"`
@@ -40,9 +43,11 @@ type EnrollOptions struct {
 	UserProvidedMetadata map[string]interface{}     `yaml:"-" json:"-"`
 	FixPermissions       *utils.FileOwner           `yaml:"-" json:"-"`
 	DelayEnroll          bool                       `yaml:"-" json:"-"`
+	Staged               bool                       `yaml:"-" json:"-"`
 	FleetServer          EnrollCmdFleetServerOption `yaml:"-" json:"-"`
 	SkipCreateSecret     bool                       `yaml:"-" json:"-"`
 	SkipDaemonRestart    bool                       `yaml:"-" json:"-"`
+	UninstallToken       string                     `yaml:"-" json:"-"`
 	Tags                 []string                   `yaml:"tags,omitempty" json:"tags,omitempty"`
 }
 
@@ -63,6 +68,8 @@ type EnrollCmdFleetServerOption struct {
 	Cert                  string
 	CertKey               string
 	CertKeyPassphrasePath string
+	CertSANs              []string
+	CertValidity          time.Duration
 	ClientAuth            string
 	Insecure              bool
 	SpawnAgent            bool
@@ -120,6 +127,29 @@ func (e *EnrollOptions) RemoteConfig(failOnInsecureMismatch bool) (remote.Config
 	return cfg, nil
 }
 
+// ValidateStaged validates the options of an enrollment staged to complete on the first start of the Elastic
+// Agent, without contacting Fleet.
+func (e *EnrollOptions) ValidateStaged(log *logger.Logger) error {
+	if e.URL == "" {
+		return fmt.Errorf("missing URL of Fleet Server to enroll into")
+	}
+	if e.EnrollAPIKey == "" && e.IdentityProvider == "" {
+		return fmt.Errorf("missing enrollment token or identity provider")
+	}
+	if e.FleetServer.ConnStr != "" {
+		return fmt.Errorf("the enrollment of a Fleet Server cannot be staged")
+	}
+
+	cfg, err := e.RemoteConfig(true)
+	if err != nil {
+		return err
+	}
+	if _, err := tlscommon.LoadTLSConfig(cfg.Transport.TLS, log); err != nil {
+		return fmt.Errorf("invalid TLS configuration: %w", err)
+	}
+	return nil
+}
+
 func MergeOptionsWithMigrateAction(action *fleetapi.ActionMigrate, options EnrollOptions) (EnrollOptions, error) {
 	// there is place to make this much more performant but as this is far away from hot path
 	// i'm keeping it this way (michal)
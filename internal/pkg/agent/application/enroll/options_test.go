@@ -6,6 +6,7 @@ package enroll
 
 import (
 	"encoding/json"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -15,6 +16,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
 )
 
 func TestRemoteConfig(t *testing.T) {
@@ -444,3 +446,48 @@ func TestFromFleetConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateStaged(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	cases := map[string]struct {
+		options       EnrollOptions
+		expectedError string
+	}{
+		"valid": {
+			options: EnrollOptions{URL: "https://fleet.example.com:8220", EnrollAPIKey: "token"},
+		},
+		"identity provider": {
+			options: EnrollOptions{URL: "https://fleet.example.com:8220", IdentityProvider: IdentityProviderAWS},
+		},
+		"missing URL": {
+			options:       EnrollOptions{EnrollAPIKey: "token"},
+			expectedError: "missing URL",
+		},
+		"missing token": {
+			options:       EnrollOptions{URL: "https://fleet.example.com:8220"},
+			expectedError: "missing enrollment token or identity provider",
+		},
+		"insecure": {
+			options:       EnrollOptions{URL: "http://fleet.example.com:8220", EnrollAPIKey: "token"},
+			expectedError: "insecure",
+		},
+		"fleet server": {
+			options:       EnrollOptions{URL: "https://fleet.example.com:8220", EnrollAPIKey: "token", FleetServer: EnrollCmdFleetServerOption{ConnStr: "https://localhost:9200"}},
+			expectedError: "Fleet Server cannot be staged",
+		},
+		"missing certificate authority": {
+			options:       EnrollOptions{URL: "https://fleet.example.com:8220", EnrollAPIKey: "token", CAs: []string{filepath.Join(t.TempDir(), "ca.pem")}},
+			expectedError: "invalid TLS configuration",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.options.ValidateStaged(log)
+			if tc.expectedError == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// eventAnnotationsTarget is the field the annotations are added under in every event.
+const eventAnnotationsTarget = "elastic_agent"
+
+// eventAnnotationsConfig is the configuration under `agent.event_annotations` of the policy.
+type eventAnnotationsConfig struct {
+	// Enabled turns on the annotation of events, disabled by default.
+	Enabled bool `config:"enabled"`
+	// Tags are added to every event along with the policy ID and name.
+	Tags []string `config:"tags"`
+}
+
+// InjectEventAnnotations is a modifier passed to coordinator in order to annotate every event with the ID and
+// name of the policy (and the configured tags) when `agent.event_annotations.enabled` is set in the policy.
+// The annotation is added as an `add_fields` processor at the end of the processors of each input unit.
+func InjectEventAnnotations(comps []component.Component, cfg map[string]interface{}) ([]component.Component, error) {
+	annotationsCfg, err := getEventAnnotationsConfig(cfg)
+	if err != nil {
+		return comps, fmt.Errorf("error retrieving event annotations config: %w", err)
+	}
+
+	if annotationsCfg == nil || !annotationsCfg.Enabled {
+		// nothing to do
+		return comps, nil
+	}
+
+	fields := map[string]interface{}{}
+	policy := map[string]interface{}{}
+	if id, ok := cfg["id"].(string); ok && id != "" {
+		policy["id"] = id
+	}
+	if name, ok := cfg["name"].(string); ok && name != "" {
+		policy["name"] = name
+	}
+	if len(policy) > 0 {
+		fields["policy"] = policy
+	}
+	if len(annotationsCfg.Tags) > 0 {
+		tags := make([]interface{}, 0, len(annotationsCfg.Tags))
+		for _, tag := range annotationsCfg.Tags {
+			tags = append(tags, tag)
+		}
+		fields["tags"] = tags
+	}
+	if len(fields) == 0 {
+		// nothing to annotate with
+		return comps, nil
+	}
+
+	return appendInputProcessor(comps, map[string]interface{}{
+		"add_fields": map[string]interface{}{
+			"target": eventAnnotationsTarget,
+			"fields": fields,
+		},
+	})
+}
+
+// appendInputProcessor appends the processor at the end of the processors of each input unit, the endpoint
+// units are skipped.
+func appendInputProcessor(comps []component.Component, processor map[string]interface{}) ([]component.Component, error) {
+	for i, comp := range comps {
+		if comp.InputSpec == nil || comp.InputSpec.InputType == endpoint {
+			// endpoint does not support processors in its configuration
+			continue
+		}
+		for j, unit := range comp.Units {
+			if unit.Type != client.UnitTypeInput || unit.Config == nil || unit.Config.Source == nil {
+				continue
+			}
+			unitCfgMap := unit.Config.Source.AsMap()
+			processors, _ := unitCfgMap["processors"].([]interface{})
+			unitCfgMap["processors"] = append(processors, processor)
+			unitCfg, err := component.ExpectedConfig(unitCfgMap)
+			if err != nil {
+				return nil, fmt.Errorf("error adding processor to unit %s: %w", unit.ID, err)
+			}
+			unit.Config = unitCfg
+			comp.Units[j] = unit
+		}
+		comps[i] = comp
+	}
+
+	return comps, nil
+}
+
+func getEventAnnotationsConfig(cfg map[string]interface{}) (*eventAnnotationsConfig, error) {
+	nestedValue, err := utils.GetNestedMap(cfg, "agent", "event_annotations")
+	if errors.Is(err, utils.ErrKeyNotFound) {
+		// No event annotations config found, nothing to do
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error traversing config: %w", err)
+	}
+
+	rawAnnotationsConfig, ok := nestedValue.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the retrieved event annotations config is not a map: %T", nestedValue)
+	}
+
+	newConfigFrom, err := config.NewConfigFrom(rawAnnotationsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing event annotations config: %w", err)
+	}
+
+	annotationsConfig := new(eventAnnotationsConfig)
+	err = newConfigFrom.UnpackTo(annotationsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error unpacking event annotations config: %w", err)
+	}
+	return annotationsConfig, nil
+}
@@ -0,0 +1,107 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func annotationTestComponents(t *testing.T) []component.Component {
+	inputCfg, err := component.ExpectedConfig(map[string]interface{}{
+		"type": "filestream",
+		"processors": []interface{}{
+			map[string]interface{}{"drop_event": map[string]interface{}{}},
+		},
+	})
+	require.NoError(t, err)
+	outputCfg, err := component.ExpectedConfig(map[string]interface{}{
+		"type": "elasticsearch",
+	})
+	require.NoError(t, err)
+	endpointCfg, err := component.ExpectedConfig(map[string]interface{}{
+		"type": "endpoint",
+	})
+	require.NoError(t, err)
+
+	return []component.Component{
+		{
+			ID:        "filestream-default",
+			InputSpec: &component.InputRuntimeSpec{InputType: "filestream"},
+			Units: []component.Unit{
+				{ID: "filestream-default", Type: client.UnitTypeOutput, Config: outputCfg},
+				{ID: "filestream-default-input", Type: client.UnitTypeInput, Config: inputCfg},
+			},
+		},
+		{
+			ID:        "endpoint-default",
+			InputSpec: &component.InputRuntimeSpec{InputType: endpoint},
+			Units: []component.Unit{
+				{ID: "endpoint-default-input", Type: client.UnitTypeInput, Config: endpointCfg},
+			},
+		},
+	}
+}
+
+func TestInjectEventAnnotations(t *testing.T) {
+	t.Run("disabled by default", func(t *testing.T) {
+		comps, err := InjectEventAnnotations(annotationTestComponents(t), map[string]interface{}{
+			"id":   "policy-id",
+			"name": "policy-name",
+		})
+		require.NoError(t, err)
+		assert.Len(t, comps[0].Units[1].Config.Source.AsMap()["processors"], 1)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := InjectEventAnnotations(annotationTestComponents(t), map[string]interface{}{
+			"agent": map[string]interface{}{
+				"event_annotations": "enabled",
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		comps, err := InjectEventAnnotations(annotationTestComponents(t), map[string]interface{}{
+			"id":   "policy-id",
+			"name": "policy-name",
+			"agent": map[string]interface{}{
+				"event_annotations": map[string]interface{}{
+					"enabled": true,
+					"tags":    []interface{}{"team-a", "billing"},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		// input units get the annotation appended to their processors
+		processors := comps[0].Units[1].Config.Source.AsMap()["processors"]
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"drop_event": map[string]interface{}{}},
+			map[string]interface{}{
+				"add_fields": map[string]interface{}{
+					"target": "elastic_agent",
+					"fields": map[string]interface{}{
+						"policy": map[string]interface{}{
+							"id":   "policy-id",
+							"name": "policy-name",
+						},
+						"tags": []interface{}{"team-a", "billing"},
+					},
+				},
+			},
+		}, processors)
+
+		// output units and endpoint are left untouched
+		assert.NotContains(t, comps[0].Units[0].Config.Source.AsMap(), "processors")
+		assert.NotContains(t, comps[1].Units[0].Config.Source.AsMap(), "processors")
+	})
+}
@@ -0,0 +1,248 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package eventlog mirrors the lifecycle events of the agent to the Windows Application Event Log, so Windows
+// administrators can alert on them with native tooling.
+//
+// The events are written with the source of the installed service and a fixed event ID per kind of event. The
+// IDs stay within the range of the EventCreate message file the source is registered with on install.
+package eventlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// The event IDs of the lifecycle events.
+const (
+	EventAgentStarted       uint32 = 100
+	EventAgentStopped       uint32 = 101
+	EventPolicyChanged      uint32 = 102
+	EventUpgradeStarted     uint32 = 110
+	EventUpgradeWatching    uint32 = 111
+	EventUpgradeCompleted   uint32 = 112
+	EventUpgradeFailed      uint32 = 113
+	EventUpgradeRolledBack  uint32 = 114
+	EventComponentFailed    uint32 = 120
+	EventComponentRecovered uint32 = 121
+)
+
+// errUnsupported is returned when opening the event log on a platform other than Windows.
+var errUnsupported = errors.New("the event log is only supported on Windows")
+
+// Config is the configuration of the event log sink, read from agent.logging.event_log.
+type Config struct {
+	// Enabled mirrors the lifecycle events to the event log, it is off by default.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+}
+
+// writer writes to the event log, implemented by *eventlog.Log of golang.org/x/sys.
+type writer interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+// Coordinator is the part of the Coordinator the sink observes.
+type Coordinator interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+}
+
+// policy identifies a revision of the policy.
+type policy struct {
+	ID       string `config:"id"`
+	Revision int    `config:"revision"`
+}
+
+// Sink writes the lifecycle events of the agent to the event log.
+type Sink struct {
+	log   *logger.Logger
+	coord Coordinator
+	// allow to inject the event log for tests, defaults to the event log of the platform
+	open func() (writer, error)
+
+	mx sync.Mutex
+	// w is the open event log, nil when the sink is disabled
+	w        writer
+	policy   policy
+	reloaded bool
+
+	// the following are only accessed by the Run goroutine
+	failed  map[string]bool
+	upgrade upgradeState
+}
+
+// upgradeState is the target version and the state of the upgrade, the events are written on its transitions.
+type upgradeState struct {
+	target string
+	state  details.State
+}
+
+// New creates a Sink, disabled until a configuration enabling it is reloaded.
+func New(log *logger.Logger, coord Coordinator) *Sink {
+	return &Sink{
+		log:    log,
+		coord:  coord,
+		open:   openEventLog,
+		failed: make(map[string]bool),
+	}
+}
+
+// Reload reads the event log settings from the agent configuration, a new revision of the policy is written as
+// an event.
+func (s *Sink) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		EventLog Config `config:"agent.logging.event_log"`
+		Policy   policy `config:",inline"`
+	}{}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack event log config: %w", err)
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	switch {
+	case cfg.EventLog.Enabled && s.w == nil:
+		w, err := s.open()
+		if err != nil {
+			// not fatal to the policy, the events are still in the logs of the agent
+			s.log.Warnw("Failed to open the event log, lifecycle events are not mirrored to it", "error.message", err)
+			break
+		}
+		s.w = w
+		s.writeLocked(w.Info, EventAgentStarted, fmt.Sprintf("Elastic Agent %s started", release.VersionWithSnapshot()))
+	case !cfg.EventLog.Enabled && s.w != nil:
+		_ = s.w.Close()
+		s.w = nil
+	}
+
+	// a standalone policy has no revision, each of its reloads is a change
+	if s.reloaded && (cfg.Policy != s.policy || cfg.Policy.ID == "") && s.w != nil {
+		s.writeLocked(s.w.Info, EventPolicyChanged, policyMessage(cfg.Policy))
+	}
+	s.policy = cfg.Policy
+	s.reloaded = true
+	return nil
+}
+
+// Run observes the state of the agent and writes its events until the context is done, the stop of the agent is
+// then written.
+func (s *Sink) Run(ctx context.Context) {
+	stateCh := s.coord.StateSubscribe(ctx, 32)
+	defer s.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case state, ok := <-stateCh:
+			if !ok {
+				return
+			}
+			s.observe(state)
+		}
+	}
+}
+
+// observe writes the upgrade and component events of the state transitions.
+func (s *Sink) observe(state coordinator.State) {
+	s.observeUpgrade(state.UpgradeDetails)
+
+	failed := make(map[string]bool)
+	for _, comp := range state.Components {
+		id := comp.Component.ID
+		if comp.State.State != client.UnitStateFailed {
+			if s.failed[id] {
+				s.write(EventComponentRecovered, fmt.Sprintf("Component %s recovered, it is %s", id, comp.State.State))
+			}
+			continue
+		}
+		failed[id] = true
+		if !s.failed[id] {
+			s.write(EventComponentFailed, fmt.Sprintf("Component %s failed: %s", id, comp.State.Message))
+		}
+	}
+	s.failed = failed
+}
+
+// observeUpgrade writes an event when the upgrade starts and on the states it is watched, completed, failed or
+// rolled back in.
+func (s *Sink) observeUpgrade(d *details.Details) {
+	if d == nil {
+		s.upgrade = upgradeState{}
+		return
+	}
+	previous := s.upgrade
+	s.upgrade = upgradeState{target: d.TargetVersion, state: d.State}
+	if previous == s.upgrade {
+		return
+	}
+
+	switch d.State {
+	case details.StateWatching:
+		s.write(EventUpgradeWatching, fmt.Sprintf("Elastic Agent upgraded to %s, the upgrade is being watched", d.TargetVersion))
+	case details.StateCompleted:
+		s.write(EventUpgradeCompleted, fmt.Sprintf("Elastic Agent upgrade to %s completed", d.TargetVersion))
+	case details.StateFailed:
+		s.write(EventUpgradeFailed, fmt.Sprintf("Elastic Agent upgrade to %s failed in state %s: %s", d.TargetVersion, d.Metadata.FailedState, d.Metadata.ErrorMsg))
+	case details.StateRollback:
+		s.write(EventUpgradeRolledBack, fmt.Sprintf("Elastic Agent upgrade to %s rolled back: %s", d.TargetVersion, d.Metadata.Reason))
+	default:
+		if previous.target != d.TargetVersion {
+			s.write(EventUpgradeStarted, fmt.Sprintf("Elastic Agent upgrade to %s started", d.TargetVersion))
+		}
+	}
+}
+
+// write writes the event with the severity of its ID.
+func (s *Sink) write(eid uint32, msg string) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.w == nil {
+		return
+	}
+	severity := s.w.Info
+	switch eid {
+	case EventUpgradeFailed, EventComponentFailed:
+		severity = s.w.Error
+	case EventUpgradeRolledBack:
+		severity = s.w.Warning
+	}
+	s.writeLocked(severity, eid, msg)
+}
+
+func (s *Sink) writeLocked(severity func(uint32, string) error, eid uint32, msg string) {
+	if err := severity(eid, msg); err != nil {
+		s.log.Errorw("Failed to write to the event log", "event.code", eid, "error.message", err)
+	}
+}
+
+// Close writes the stop of the agent and closes the event log, it does nothing once closed.
+func (s *Sink) Close() {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.w == nil {
+		return
+	}
+	s.writeLocked(s.w.Info, EventAgentStopped, "Elastic Agent stopped")
+	_ = s.w.Close()
+	s.w = nil
+}
+
+func policyMessage(p policy) string {
+	if p.ID == "" {
+		return "Elastic Agent policy changed"
+	}
+	return fmt.Sprintf("Elastic Agent policy %s changed to revision %d", p.ID, p.Revision)
+}
@@ -0,0 +1,197 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eventlog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type event struct {
+	severity string
+	id       uint32
+	msg      string
+}
+
+type fakeWriter struct {
+	events []event
+	closed bool
+}
+
+func (w *fakeWriter) Info(eid uint32, msg string) error {
+	w.events = append(w.events, event{"info", eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Warning(eid uint32, msg string) error {
+	w.events = append(w.events, event{"warning", eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Error(eid uint32, msg string) error {
+	w.events = append(w.events, event{"error", eid, msg})
+	return nil
+}
+
+func (w *fakeWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+// ids returns the IDs of the events written since the last call.
+func (w *fakeWriter) ids() []uint32 {
+	ids := make([]uint32, 0, len(w.events))
+	for _, e := range w.events {
+		ids = append(ids, e.id)
+	}
+	w.events = nil
+	return ids
+}
+
+type fakeCoordinator struct {
+	stateCh chan coordinator.State
+}
+
+func (c *fakeCoordinator) StateSubscribe(context.Context, int) chan coordinator.State {
+	return c.stateCh
+}
+
+func newTestSink(t *testing.T) (*Sink, *fakeWriter) {
+	log, _ := loggertest.New("eventlog")
+	w := &fakeWriter{}
+	s := New(log, &fakeCoordinator{stateCh: make(chan coordinator.State)})
+	s.open = func() (writer, error) { return w, nil }
+	return s, w
+}
+
+func reload(t *testing.T, s *Sink, cfg map[string]interface{}) {
+	require.NoError(t, s.Reload(config.MustNewConfigFrom(cfg)))
+}
+
+func componentState(id string, state client.UnitState) runtime.ComponentComponentState {
+	return runtime.ComponentComponentState{
+		Component: component.Component{ID: id},
+		State:     runtime.ComponentState{State: state, Message: "crashed"},
+	}
+}
+
+func TestSinkDisabled(t *testing.T) {
+	s, w := newTestSink(t)
+	reload(t, s, map[string]interface{}{})
+	s.observe(coordinator.State{Components: []runtime.ComponentComponentState{componentState("filestream-default", client.UnitStateFailed)}})
+	s.Close()
+	assert.Empty(t, w.ids())
+}
+
+func TestSinkOpenFailure(t *testing.T) {
+	s, _ := newTestSink(t)
+	s.open = func() (writer, error) { return nil, errors.New("unsupported") }
+	// a failure to open the event log does not fail the policy
+	reload(t, s, map[string]interface{}{"agent.logging.event_log.enabled": true})
+	assert.Nil(t, s.w)
+}
+
+func TestSinkLifecycle(t *testing.T) {
+	s, w := newTestSink(t)
+	enabled := map[string]interface{}{"agent.logging.event_log.enabled": true, "id": "policy-1", "revision": 1}
+	reload(t, s, enabled)
+	assert.Equal(t, []uint32{EventAgentStarted}, w.ids())
+
+	// the same revision is not a change
+	reload(t, s, enabled)
+	assert.Empty(t, w.ids())
+
+	enabled["revision"] = 2
+	reload(t, s, enabled)
+	require.Len(t, w.events, 1)
+	assert.Equal(t, event{"info", EventPolicyChanged, "Elastic Agent policy policy-1 changed to revision 2"}, w.events[0])
+	w.ids()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return once the context is done")
+	}
+	assert.Equal(t, []uint32{EventAgentStopped}, w.ids())
+	assert.True(t, w.closed)
+
+	// closing again does nothing
+	s.Close()
+	assert.Empty(t, w.ids())
+}
+
+func TestSinkComponents(t *testing.T) {
+	s, w := newTestSink(t)
+	reload(t, s, map[string]interface{}{"agent.logging.event_log.enabled": true})
+	w.ids()
+
+	state := func(states ...runtime.ComponentComponentState) coordinator.State {
+		return coordinator.State{Components: states}
+	}
+	s.observe(state(componentState("filestream-default", client.UnitStateHealthy)))
+	assert.Empty(t, w.ids())
+
+	s.observe(state(componentState("filestream-default", client.UnitStateFailed)))
+	require.Len(t, w.events, 1)
+	assert.Equal(t, event{"error", EventComponentFailed, "Component filestream-default failed: crashed"}, w.events[0])
+	w.ids()
+
+	// a lasting failure is written once
+	s.observe(state(componentState("filestream-default", client.UnitStateFailed)))
+	assert.Empty(t, w.ids())
+
+	s.observe(state(componentState("filestream-default", client.UnitStateHealthy)))
+	assert.Equal(t, []uint32{EventComponentRecovered}, w.ids())
+}
+
+func TestSinkUpgrade(t *testing.T) {
+	s, w := newTestSink(t)
+	reload(t, s, map[string]interface{}{"agent.logging.event_log.enabled": true})
+	w.ids()
+
+	upgrade := func(state details.State) coordinator.State {
+		d := details.NewDetails("9.2.0", state, "action-1")
+		if state == details.StateFailed {
+			d.Metadata.FailedState = details.StateDownloading
+			d.Metadata.ErrorMsg = "network unreachable"
+		}
+		return coordinator.State{UpgradeDetails: d}
+	}
+
+	s.observe(upgrade(details.StateRequested))
+	s.observe(upgrade(details.StateDownloading))
+	assert.Equal(t, []uint32{EventUpgradeStarted}, w.ids(), "the upgrade starts once")
+
+	s.observe(upgrade(details.StateFailed))
+	require.Len(t, w.events, 1)
+	assert.Equal(t, event{"error", EventUpgradeFailed, "Elastic Agent upgrade to 9.2.0 failed in state UPG_DOWNLOADING: network unreachable"}, w.events[0])
+	w.ids()
+
+	s.observe(coordinator.State{})
+	s.observe(upgrade(details.StateWatching))
+	s.observe(upgrade(details.StateRollback))
+	assert.Equal(t, []uint32{EventUpgradeWatching, EventUpgradeRolledBack}, w.ids())
+}
@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package eventlog
+
+import (
+	"golang.org/x/sys/windows/svc/eventlog"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// openEventLog opens the Application Event Log with the source registered for the service on install.
+func openEventLog() (writer, error) {
+	return eventlog.Open(paths.ServiceName())
+}
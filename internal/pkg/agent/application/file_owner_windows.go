@@ -0,0 +1,28 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package application
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// fileOwner returns the account owning the file as DOMAIN\name, the SID when it has no account.
+func fileOwner(path string) (string, error) {
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION)
+	if err != nil {
+		return "", err
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return "", err
+	}
+	account, domain, _, err := owner.LookupAccount("")
+	if err != nil {
+		return owner.String(), nil
+	}
+	return domain + `\` + account, nil
+}
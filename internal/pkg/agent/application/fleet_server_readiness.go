@@ -0,0 +1,151 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+const (
+	// fleetServerInternalHost and fleetServerInternalPort are the address Fleet Server binds its
+	// internal listener to, used by the Elastic Agent hosting it.
+	fleetServerInternalHost = "localhost"
+	fleetServerInternalPort = 8221
+
+	fleetServerStatusPath = "/api/status"
+
+	fleetServerProbeTimeout = 5 * time.Second
+	// fleetServerReadyInterval is the interval between probes while waiting for the Fleet Server API.
+	fleetServerReadyInterval = time.Second
+	// fleetServerMonitorInterval is the interval between probes once the Fleet Server API answered.
+	fleetServerMonitorInterval = 30 * time.Second
+
+	fleetServerWaitingMessage = "Waiting for the Fleet Server API"
+	fleetServerReadyMessage   = "Fleet Server API is ready"
+)
+
+// fleetServerProbe returns nil when the API of the Fleet Server hosted by the Elastic Agent answers.
+type fleetServerProbe func(ctx context.Context) error
+
+// fleetServerURL returns the address of the internal listener of the hosted Fleet Server.
+func fleetServerURL(cfg *configuration.FleetServerConfig) string {
+	port := int(cfg.InternalPort)
+	if port == 0 {
+		port = fleetServerInternalPort
+	}
+	scheme := "http"
+	if cfg.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(fleetServerInternalHost, strconv.Itoa(port))
+}
+
+// newFleetServerProbe returns a probe of the status API of the hosted Fleet Server. The API answers
+// with 200 OK once Fleet Server is connected to Elasticsearch and can serve enrollments and check-ins.
+func newFleetServerProbe(baseURL string) fleetServerProbe {
+	client := &http.Client{
+		Timeout: fleetServerProbeTimeout,
+		Transport: &http.Transport{
+			// the certificate is issued for the host name, not for the loopback address of the
+			// internal listener, and the probe sends no credentials
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // loopback probe, see above
+		},
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+fleetServerStatusPath, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s answered %s", fleetServerStatusPath, resp.Status)
+		}
+		return nil
+	}
+}
+
+// waitForFleetServerAPI probes the hosted Fleet Server until its API answers, reporting the
+// Fleet Server as starting in the meantime.
+func (m *managedConfigManager) waitForFleetServerAPI(ctx context.Context) error {
+	m.log.Debugf("waiting for the Fleet Server API at %s", m.fleetServerURL)
+	m.setFleetServerState(agentclient.Starting, fleetServerWaitingMessage)
+
+	t := time.NewTicker(m.fleetServerReadyInterval)
+	defer t.Stop()
+	for {
+		err := m.fleetServerProbe(ctx)
+		if err == nil {
+			m.log.Info("Fleet Server API is ready")
+			m.setFleetServerState(agentclient.Healthy, fleetServerReadyMessage)
+			return nil
+		}
+		m.log.Debugf("Fleet Server API is not ready: %s", err)
+		m.setFleetServerState(agentclient.Starting, fmt.Sprintf("%s: %s", fleetServerWaitingMessage, err))
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout while waiting for the Fleet Server API: %w", ctx.Err())
+		case <-t.C:
+		}
+	}
+}
+
+// monitorFleetServerAPI keeps probing the hosted Fleet Server once ready, reporting it degraded
+// while its API doesn't answer.
+func (m *managedConfigManager) monitorFleetServerAPI(ctx context.Context) error {
+	t := time.NewTicker(m.fleetServerMonitorInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+
+		if err := m.fleetServerProbe(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			m.log.Warnf("Fleet Server API is not answering: %s", err)
+			m.setFleetServerState(agentclient.Degraded, fmt.Sprintf("Fleet Server API is not answering: %s", err))
+			continue
+		}
+		m.setFleetServerState(agentclient.Healthy, fleetServerReadyMessage)
+	}
+}
+
+// setFleetServerState reports the state of the hosted Fleet Server to the Coordinator when it changes.
+func (m *managedConfigManager) setFleetServerState(state agentclient.State, message string) {
+	if m.fleetServerState != nil && m.fleetServerState.State == state && m.fleetServerState.Message == message {
+		return
+	}
+	since := time.Now().UTC()
+	if m.fleetServerState != nil && m.fleetServerState.State == state {
+		since = m.fleetServerState.Since
+	}
+	m.fleetServerState = &coordinator.FleetServerState{
+		State:   state,
+		Message: message,
+		URL:     m.fleetServerURL,
+		Since:   since,
+	}
+	m.reportFleetServerState(m.fleetServerState)
+}
@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestFleetServerURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:8221", fleetServerURL(&configuration.FleetServerConfig{}))
+	assert.Equal(t, "https://localhost:8222", fleetServerURL(&configuration.FleetServerConfig{
+		InternalPort: 8222,
+		TLS:          &tlscommon.ServerConfig{},
+	}))
+}
+
+func TestFleetServerProbe(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, fleetServerStatusPath, r.URL.Path)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	probe := newFleetServerProbe(srv.URL)
+	require.ErrorContains(t, probe(context.Background()), "503 Service Unavailable")
+	healthy.Store(true)
+	require.NoError(t, probe(context.Background()))
+}
+
+func TestWaitForFleetServerAPI(t *testing.T) {
+	var probes atomic.Int32
+	var states []coordinator.FleetServerState
+	log, _ := loggertest.New(t.Name())
+	m := &managedConfigManager{
+		log:            log,
+		fleetServerURL: "https://localhost:8221",
+		fleetServerProbe: func(ctx context.Context) error {
+			if probes.Add(1) < 3 {
+				return assert.AnError
+			}
+			return nil
+		},
+		fleetServerReadyInterval: time.Millisecond,
+		reportFleetServerState: func(state *coordinator.FleetServerState) {
+			states = append(states, *state)
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, m.waitForFleetServerAPI(ctx))
+	assert.EqualValues(t, 3, probes.Load())
+
+	// the repeated probe failure is only reported once
+	require.Len(t, states, 3)
+	assert.Equal(t, agentclient.Starting, states[0].State)
+	assert.Equal(t, fleetServerWaitingMessage, states[0].Message)
+	assert.Equal(t, agentclient.Starting, states[1].State)
+	assert.Equal(t, fleetServerWaitingMessage+": "+assert.AnError.Error(), states[1].Message)
+	assert.Equal(t, states[0].Since, states[1].Since, "since only changes with the state")
+	assert.Equal(t, agentclient.Healthy, states[2].State)
+	assert.Equal(t, "https://localhost:8221", states[2].URL)
+}
+
+func TestWaitForFleetServerAPITimeout(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	m := &managedConfigManager{
+		log:                      log,
+		fleetServerProbe:         func(ctx context.Context) error { return assert.AnError },
+		fleetServerReadyInterval: time.Millisecond,
+		reportFleetServerState:   func(*coordinator.FleetServerState) {},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, m.waitForFleetServerAPI(ctx), context.DeadlineExceeded)
+	assert.Equal(t, agentclient.Starting, m.fleetServerState.State, "Fleet Server must not be reported healthy")
+}
@@ -15,12 +15,15 @@ import (
 	eaclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
 	"github.com/elastic/elastic-agent/internal/pkg/otel/otelhelpers"
+	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/internal/pkg/scheduler"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -67,9 +70,16 @@ type agentInfo interface {
 	AgentID() string
 }
 
+// hostStater is implemented by the clients reporting the health of their hosts.
+type hostStater interface {
+	HostStates() []remote.HostState
+}
+
 type stateStore interface {
 	AckToken() string
 	SetAckToken(ackToken string)
+	AddPendingActions(actions ...fleetapi.Action)
+	ActionExecuted(actionID string) bool
 	Save() error
 }
 
@@ -84,6 +94,10 @@ type FleetGateway struct {
 	checkinFailCounter int
 	stateFetcher       func() coordinator.State
 	stateStore         stateStore
+	peerHealth         func() *fleetapi.PeerHealth
+	policyHash         func() string
+	fleetHosts         func([]remote.HostState)
+	checkinOpts        []fleetapi.CheckinOption
 	errCh              chan error
 	actionCh           chan []fleetapi.Action
 }
@@ -134,6 +148,32 @@ func newFleetGatewayWithScheduler(
 	}, nil
 }
 
+// SetPeerHealth sets the function returning the health of the peers of the agent reported on checkin.
+func (f *FleetGateway) SetPeerHealth(peerHealth func() *fleetapi.PeerHealth) {
+	f.peerHealth = peerHealth
+}
+
+// SetPolicyHash sets the function returning the hash of the current policy reported on checkin.
+func (f *FleetGateway) SetPolicyHash(policyHash func() string) {
+	f.policyHash = policyHash
+}
+
+// SetCheckinConfig sets the compression and the payload budget of the checkin requests.
+func (f *FleetGateway) SetCheckinConfig(cfg *configuration.CheckinConfig) {
+	if cfg == nil {
+		return
+	}
+	f.checkinOpts = []fleetapi.CheckinOption{
+		fleetapi.WithCompression(cfg.Compression),
+		fleetapi.WithPayloadBudget(cfg.MaxBodySize, cfg.MaxUnitMessageSize),
+	}
+}
+
+// SetFleetHosts sets the function the health of the Fleet Server hosts is reported to after each checkin.
+func (f *FleetGateway) SetFleetHosts(fleetHosts func([]remote.HostState)) {
+	f.fleetHosts = fleetHosts
+}
+
 func (f *FleetGateway) Actions() <-chan []fleetapi.Action {
 	return f.actionCh
 }
@@ -169,8 +209,7 @@ func (f *FleetGateway) Run(ctx context.Context) error {
 				continue
 			}
 
-			actions := make([]fleetapi.Action, len(resp.Actions))
-			copy(actions, resp.Actions)
+			actions := f.notExecuted(ctx, resp.Actions)
 			if len(actions) > 0 {
 				f.actionCh <- actions
 			}
@@ -178,6 +217,32 @@ func (f *FleetGateway) Run(ctx context.Context) error {
 	}
 }
 
+// notExecuted returns the actions that were not executed yet. Fleet delivers an action again
+// when its ack was not committed, e.g. when the agent stopped right after executing it, these
+// actions are acknowledged again without being executed twice.
+func (f *FleetGateway) notExecuted(ctx context.Context, actions []fleetapi.Action) []fleetapi.Action {
+	notExecuted := make([]fleetapi.Action, 0, len(actions))
+	acked := false
+	for _, a := range actions {
+		if !f.stateStore.ActionExecuted(a.ID()) {
+			notExecuted = append(notExecuted, a)
+			continue
+		}
+		f.log.Infof("Action id %q of type %q was already executed, acknowledging it again", a.ID(), a.Type())
+		if err := f.acker.Ack(ctx, a); err != nil {
+			f.log.Errorf("failed to acknowledge action id %q again: %v", a.ID(), err)
+			continue
+		}
+		acked = true
+	}
+	if acked {
+		if err := f.acker.Commit(ctx); err != nil {
+			f.log.Errorf("failed to commit acknowledgement of already executed actions: %v", err)
+		}
+	}
+	return notExecuted
+}
+
 // Errors returns the channel to watch for reported errors.
 func (f *FleetGateway) Errors() <-chan error {
 	return f.errCh
@@ -191,6 +256,7 @@ func (f *FleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 	for ctx.Err() == nil {
 		f.log.Debugf("Checking started")
 		resp, took, err := f.execute(ctx)
+		agentmetrics.ObserveCheckin(took, err)
 		if err != nil {
 			f.checkinFailCounter++
 
@@ -248,8 +314,8 @@ func (f *FleetGateway) doExecute(ctx context.Context, bo backoff.Backoff) (*flee
 	return nil, ctx.Err()
 }
 
-func (f *FleetGateway) convertToCheckinComponents(components []runtime.ComponentComponentState, collector *status.AggregateStatus) []fleetapi.CheckinComponent {
-	if components == nil {
+func (f *FleetGateway) convertToCheckinComponents(components []runtime.ComponentComponentState, collector *status.AggregateStatus, quarantined []coordinator.QuarantinedComponent) []fleetapi.CheckinComponent {
+	if components == nil && quarantined == nil {
 		return nil
 	}
 	stateString := func(s eaclient.UnitState) string {
@@ -266,7 +332,7 @@ func (f *FleetGateway) convertToCheckinComponents(components []runtime.Component
 		return ""
 	}
 
-	size := len(components)
+	size := len(components) + len(quarantined)
 	if collector != nil {
 		size += len(collector.ComponentStatusMap)
 	}
@@ -300,6 +366,21 @@ func (f *FleetGateway) convertToCheckinComponents(components []runtime.Component
 		checkinComponents = append(checkinComponents, checkinComponent)
 	}
 
+	// Components stopped by the fault handling are no longer in the component model, they are
+	// reported failed while quarantined and stopped while waiting to be restarted.
+	for _, item := range quarantined {
+		status := eaclient.UnitStateFailed
+		if !item.Until.IsZero() {
+			status = eaclient.UnitStateStopped
+		}
+		checkinComponents = append(checkinComponents, fleetapi.CheckinComponent{
+			ID:      item.ID,
+			Type:    item.InputType,
+			Status:  stateString(status),
+			Message: item.Reason,
+		})
+	}
+
 	// OTel status is placed as a component for each top-level component in OTel
 	// and each subcomponent is a unit.
 	if collector != nil {
@@ -350,14 +431,14 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 	state := f.stateFetcher()
 
 	// convert components into checkin components structure
-	components := f.convertToCheckinComponents(state.Components, state.Collector)
+	components := f.convertToCheckinComponents(state.Components, state.Collector, state.QuarantinedComponents)
 
 	f.log.Debugf("correcting agent loglevel from %s to %s using coordinator state", ecsMeta.Elastic.Agent.LogLevel, state.LogLevel.String())
 	// Fix loglevel with the current log level used by coordinator
 	ecsMeta.Elastic.Agent.LogLevel = state.LogLevel.String()
 
 	// checkin
-	cmd := fleetapi.NewCheckinCmd(f.agentInfo, f.client)
+	cmd := fleetapi.NewCheckinCmd(f.agentInfo, f.client, f.checkinOpts...)
 	req := &fleetapi.CheckinRequest{
 		AckToken:       ackToken,
 		Metadata:       ecsMeta,
@@ -366,8 +447,17 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 		Components:     components,
 		UpgradeDetails: state.UpgradeDetails,
 	}
+	if f.peerHealth != nil {
+		req.PeerHealth = f.peerHealth()
+	}
+	if f.policyHash != nil {
+		req.PolicyHash = f.policyHash()
+	}
 
 	resp, took, err := cmd.Execute(ctx, req)
+	if hs, ok := f.client.(hostStater); ok && f.fleetHosts != nil {
+		f.fleetHosts(hs.HostStates())
+	}
 	if isUnauth(err) {
 		f.unauthCounter++
 		if f.shouldUseLongSched() {
@@ -386,9 +476,14 @@ func (f *FleetGateway) execute(ctx context.Context) (*fleetapi.CheckinResponse,
 		return nil, took, err
 	}
 
-	// Save the latest ackToken
-	if resp.AckToken != "" {
-		f.stateStore.SetAckToken(resp.AckToken)
+	// Save the latest ackToken together with the delivered actions, Fleet does not deliver
+	// them again once the ackToken moved forward, they are dispatched again after a restart
+	// until they complete.
+	if resp.AckToken != "" || len(resp.Actions) > 0 {
+		f.stateStore.AddPendingActions(resp.Actions...)
+		if resp.AckToken != "" {
+			f.stateStore.SetAckToken(resp.AckToken)
+		}
 		serr := f.stateStore.Save()
 		if serr != nil {
 			f.log.Errorf("failed to save the ack token, err: %v", serr)
@@ -210,6 +210,138 @@ func TestFleetGateway(t *testing.T) {
 		}
 	}))
 
+	t.Run("Persists delivered actions and acknowledges again the executed ones", func(t *testing.T) {
+		scheduler := scheduler.NewStepper()
+		client := newTestingClient()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		log, _ := logger.New("tst", false)
+		stateStore := newStateStore(t, log)
+		stateStore.CompletePendingAction("id1", true)
+		acker := &recordingAcker{}
+
+		gateway, err := newFleetGatewayWithScheduler(
+			log,
+			settings,
+			agentInfo,
+			client,
+			scheduler,
+			acker,
+			emptyStateFetcher,
+			stateStore,
+		)
+		require.NoError(t, err)
+
+		waitFn := ackSeq(
+			client.Answer(func(headers http.Header, body io.Reader) (*http.Response, error) {
+				resp := wrapStrToResp(http.StatusOK, `
+	{
+		"ack_token": "token",
+		"actions": [
+			{"type": "SETTINGS", "id": "id1"},
+			{"type": "SETTINGS", "id": "id2"}
+		]
+	}
+	`)
+				return resp, nil
+			}),
+		)
+
+		errCh := runFleetGateway(ctx, gateway)
+
+		scheduler.Next()
+		waitFn()
+
+		var actions []fleetapi.Action
+		select {
+		case actions = <-gateway.Actions():
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected to receive actions")
+		}
+		cancel()
+		require.NoError(t, <-errCh)
+
+		require.Len(t, actions, 1)
+		assert.Equal(t, "id2", actions[0].ID())
+		assert.Equal(t, []string{"id1"}, acker.acked)
+		assert.Equal(t, 1, acker.commits)
+
+		pending, _ := stateStore.PendingActions()
+		require.Len(t, pending, 1)
+		assert.Equal(t, "id2", pending[0].ID())
+		assert.Equal(t, "token", stateStore.AckToken())
+	})
+
+	t.Run("Sends the health of the peers", withGateway(agentInfo, settings, func(
+		t *testing.T,
+		gateway coordinator.FleetGateway,
+		client *testingClient,
+		scheduler *scheduler.Stepper,
+	) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		gateway.(*FleetGateway).SetPeerHealth(func() *fleetapi.PeerHealth {
+			return &fleetapi.PeerHealth{Site: "dc1", Peers: 2, Reachable: 1, Unreachable: []string{"peer-2"}}
+		})
+
+		var req fleetapi.CheckinRequest
+		waitFn := ackSeq(
+			client.Answer(func(headers http.Header, body io.Reader) (*http.Response, error) {
+				if err := json.NewDecoder(body).Decode(&req); err != nil {
+					return nil, err
+				}
+				return wrapStrToResp(http.StatusOK, `{ "actions": [] }`), nil
+			}),
+		)
+
+		errCh := runFleetGateway(ctx, gateway)
+
+		scheduler.Next()
+		waitFn()
+
+		cancel()
+		err := <-errCh
+		require.NoError(t, err)
+		require.NotNil(t, req.PeerHealth)
+		assert.Equal(t, "dc1", req.PeerHealth.Site)
+		assert.Equal(t, []string{"peer-2"}, req.PeerHealth.Unreachable)
+	}))
+
+	t.Run("Sends the hash of the policy", withGateway(agentInfo, settings, func(
+		t *testing.T,
+		gateway coordinator.FleetGateway,
+		client *testingClient,
+		scheduler *scheduler.Stepper,
+	) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		gateway.(*FleetGateway).SetPolicyHash(func() string { return "policy-hash" })
+
+		var req fleetapi.CheckinRequest
+		waitFn := ackSeq(
+			client.Answer(func(headers http.Header, body io.Reader) (*http.Response, error) {
+				if err := json.NewDecoder(body).Decode(&req); err != nil {
+					return nil, err
+				}
+				return wrapStrToResp(http.StatusOK, `{ "actions": [] }`), nil
+			}),
+		)
+
+		errCh := runFleetGateway(ctx, gateway)
+
+		scheduler.Next()
+		waitFn()
+
+		cancel()
+		err := <-errCh
+		require.NoError(t, err)
+		assert.Equal(t, "policy-hash", req.PolicyHash)
+	}))
+
 	// Test the normal time based execution.
 	t.Run("Periodically communicates with Fleet", func(t *testing.T) {
 		scheduler := scheduler.NewPeriodic(150 * time.Millisecond)
@@ -377,6 +509,69 @@ func TestFleetGateway(t *testing.T) {
 		default:
 		}
 	})
+
+	t.Run("Sends quarantined components", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		scheduler := scheduler.NewStepper()
+		client := newTestingClient()
+
+		log, _ := loggertest.New("fleet_gateway")
+
+		stateStore := newStateStore(t, log)
+
+		stateFetcher := func() coordinator.State {
+			return coordinator.State{
+				QuarantinedComponents: []coordinator.QuarantinedComponent{
+					{ID: "system/metrics-default", InputType: "system/metrics", OutputType: "elasticsearch", Reason: "quarantined after 3 failures"},
+					{ID: "filestream-default", InputType: "filestream", OutputType: "elasticsearch", Reason: "restarting after 3 failures", Until: time.Now().Add(time.Minute)},
+				},
+			}
+		}
+
+		gateway, err := newFleetGatewayWithScheduler(
+			log,
+			settings,
+			agentInfo,
+			client,
+			scheduler,
+			noop.New(),
+			stateFetcher,
+			stateStore,
+		)
+
+		require.NoError(t, err)
+
+		waitFn := ackSeq(
+			client.Answer(func(headers http.Header, body io.Reader) (*http.Response, error) {
+				data, err := io.ReadAll(body)
+				require.NoError(t, err)
+
+				var checkinRequest fleetapi.CheckinRequest
+				err = json.Unmarshal(data, &checkinRequest)
+				require.NoError(t, err)
+
+				require.Equal(t, []fleetapi.CheckinComponent{
+					{ID: "system/metrics-default", Type: "system/metrics", Status: "FAILED", Message: "quarantined after 3 failures"},
+					{ID: "filestream-default", Type: "filestream", Status: "STOPPED", Message: "restarting after 3 failures"},
+				}, checkinRequest.Components)
+
+				resp := wrapStrToResp(http.StatusOK, `{ "actions": [] }`)
+				return resp, nil
+			}),
+		)
+
+		errCh := runFleetGateway(ctx, gateway)
+
+		// Synchronize scheduler and acking of calls from the worker go routine.
+		scheduler.Next()
+		waitFn()
+
+		cancel()
+		err = <-errCh
+		require.NoError(t, err)
+	})
 }
 
 func TestRetriesOnFailures(t *testing.T) {
@@ -497,6 +692,21 @@ func runFleetGateway(ctx context.Context, g coordinator.FleetGateway) <-chan err
 	return errCh
 }
 
+type recordingAcker struct {
+	acked   []string
+	commits int
+}
+
+func (a *recordingAcker) Ack(_ context.Context, action fleetapi.Action) error {
+	a.acked = append(a.acked, action.ID())
+	return nil
+}
+
+func (a *recordingAcker) Commit(_ context.Context) error {
+	a.commits++
+	return nil
+}
+
 func newStateStore(t *testing.T, log *logger.Logger) *store.StateStore {
 	dir := t.TempDir()
 
@@ -0,0 +1,214 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package guardrail rolls the policy back to the last healthy revision when applying a new revision causes a
+// failure storm of the components.
+//
+// The failures of the components are counted for a window after each revision of the policy is applied. A
+// revision causing more failures than allowed within the window is rolled back by the Coordinator, which
+// refuses it until another revision is applied and reports the rollback to Fleet. A revision that stays within
+// the allowed failures for the whole window becomes the healthy revision to roll back to.
+package guardrail
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Config is the configuration of the policy guardrail, read from agent.policy_guardrail.
+type Config struct {
+	// Enabled turns the guardrail on, it is off by default.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// MaxFailures is the number of component failures allowed within Window, the policy is rolled back
+	// once more components fail.
+	MaxFailures int `config:"max_failures" yaml:"max_failures"`
+	// Window is the period after applying a revision over which the component failures are counted.
+	Window time.Duration `config:"window" yaml:"window"`
+}
+
+// DefaultConfig returns the default configuration of the policy guardrail.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:     false,
+		MaxFailures: 3,
+		Window:      5 * time.Minute,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.MaxFailures < 0 {
+		return fmt.Errorf("invalid policy guardrail max failures %d, must not be negative", c.MaxFailures)
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("invalid policy guardrail window %s, must be greater than zero", c.Window)
+	}
+	return nil
+}
+
+// Coordinator is the part of the Coordinator the guardrail observes and acts on.
+type Coordinator interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+	MarkPolicyHealthy(ctx context.Context)
+	RollbackPolicy(ctx context.Context, reason string)
+}
+
+// Guardrail counts the failures of the components after each revision of the policy.
+type Guardrail struct {
+	log   *logger.Logger
+	coord Coordinator
+
+	mx  sync.Mutex
+	cfg Config
+	// reloadCh signals a new revision of the policy to the Run goroutine.
+	reloadCh chan struct{}
+
+	now func() time.Time
+
+	// the following are only accessed by the Run goroutine
+	states map[string]client.UnitState
+	// watching is true while the failures of the applied revision are counted
+	watching  bool
+	appliedAt time.Time
+	failures  int
+}
+
+// New creates a guardrail, disabled until a configuration enabling it is reloaded.
+func New(log *logger.Logger, coord Coordinator) *Guardrail {
+	return &Guardrail{
+		log:      log,
+		coord:    coord,
+		cfg:      DefaultConfig(),
+		reloadCh: make(chan struct{}, 1),
+		now:      time.Now,
+		states:   make(map[string]client.UnitState),
+	}
+}
+
+// Reload reads the guardrail settings from the agent configuration, the failures of the components are
+// counted from now for the new revision.
+func (g *Guardrail) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		PolicyGuardrail Config `config:"agent.policy_guardrail"`
+	}{
+		PolicyGuardrail: DefaultConfig(),
+	}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack policy guardrail config: %w", err)
+	}
+
+	g.mx.Lock()
+	g.cfg = cfg.PolicyGuardrail
+	g.mx.Unlock()
+
+	// Reload is called on the Coordinator goroutine, the Coordinator cannot be called back from it
+	select {
+	case g.reloadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (g *Guardrail) config() Config {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	return g.cfg
+}
+
+// Run observes the state of the components until the context is done.
+func (g *Guardrail) Run(ctx context.Context) {
+	stateCh := g.coord.StateSubscribe(ctx, 32)
+	healthy := time.NewTimer(time.Hour)
+	healthy.Stop()
+	defer healthy.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.reloadCh:
+			if g.watch() {
+				healthy.Reset(g.config().Window)
+			} else {
+				healthy.Stop()
+			}
+		case state, ok := <-stateCh:
+			if !ok {
+				return
+			}
+			if g.observe(ctx, state.Components) {
+				healthy.Stop()
+			}
+		case <-healthy.C:
+			g.healthy(ctx)
+		}
+	}
+}
+
+// watch starts counting the failures of a new revision, it returns false when the guardrail is disabled.
+func (g *Guardrail) watch() bool {
+	cfg := g.config()
+	g.watching = cfg.Enabled
+	g.appliedAt = g.now()
+	g.failures = 0
+	return g.watching
+}
+
+// observe counts the components entering a failed state, it returns true when the revision is rolled back.
+func (g *Guardrail) observe(ctx context.Context, components []runtime.ComponentComponentState) bool {
+	cfg := g.config()
+	seen := make(map[string]bool, len(components))
+	var last runtime.ComponentComponentState
+	failed := 0
+	for _, comp := range components {
+		id := comp.Component.ID
+		seen[id] = true
+		state := comp.State.State
+		previous, known := g.states[id]
+		g.states[id] = state
+		if state != client.UnitStateFailed || (known && previous == client.UnitStateFailed) {
+			continue
+		}
+		failed++
+		last = comp
+	}
+	for id := range g.states {
+		if !seen[id] {
+			delete(g.states, id)
+		}
+	}
+
+	if !g.watching || failed == 0 {
+		return false
+	}
+	g.failures += failed
+	g.log.Debugf("%d component failures since the policy was applied %s ago", g.failures, g.now().Sub(g.appliedAt))
+	if g.failures <= cfg.MaxFailures {
+		return false
+	}
+
+	g.watching = false
+	reason := fmt.Sprintf("%d component failures within %s of applying the policy, last: %s: %s", g.failures, g.now().Sub(g.appliedAt).Round(time.Second), last.Component.ID, last.State.Message)
+	g.log.Warnf("rolling back the policy: %s", reason)
+	g.coord.RollbackPolicy(ctx, reason)
+	return true
+}
+
+// healthy marks the revision healthy once its window elapsed within the allowed failures.
+func (g *Guardrail) healthy(ctx context.Context) {
+	if !g.watching {
+		return
+	}
+	g.watching = false
+	g.log.Debugf("%d component failures within %s of applying the policy, the revision is healthy", g.failures, g.config().Window)
+	g.coord.MarkPolicyHealthy(ctx)
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package guardrail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeCoordinator struct {
+	healthy   int
+	rollbacks []string
+}
+
+func (c *fakeCoordinator) StateSubscribe(context.Context, int) chan coordinator.State {
+	return make(chan coordinator.State)
+}
+
+func (c *fakeCoordinator) MarkPolicyHealthy(context.Context) {
+	c.healthy++
+}
+
+func (c *fakeCoordinator) RollbackPolicy(_ context.Context, reason string) {
+	c.rollbacks = append(c.rollbacks, reason)
+}
+
+func newTestGuardrail(t *testing.T, cfg map[string]interface{}) (*Guardrail, *fakeCoordinator, *time.Time) {
+	log, _ := loggertest.New("policy_guardrail")
+	coord := &fakeCoordinator{}
+	g := New(log, coord)
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return now }
+	require.NoError(t, g.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.policy_guardrail": cfg,
+	})))
+	g.watch()
+	return g, coord, &now
+}
+
+func componentState(id string, state client.UnitState) runtime.ComponentComponentState {
+	return runtime.ComponentComponentState{
+		Component: component.Component{ID: id},
+		State:     runtime.ComponentState{State: state, Message: "crashed"},
+	}
+}
+
+func states(state client.UnitState, ids ...string) []runtime.ComponentComponentState {
+	var result []runtime.ComponentComponentState
+	for _, id := range ids {
+		result = append(result, componentState(id, state))
+	}
+	return result
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate())
+	assert.False(t, cfg.Enabled, "the guardrail should be disabled by default")
+
+	for name, modify := range map[string]func(c *Config){
+		"negative max failures": func(c *Config) { c.MaxFailures = -1 },
+		"zero window":           func(c *Config) { c.Window = 0 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			modify(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestGuardrailDisabled(t *testing.T) {
+	g, coord, _ := newTestGuardrail(t, map[string]interface{}{})
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateFailed, "a", "b", "c", "d", "e")))
+	g.healthy(context.Background())
+	assert.Empty(t, coord.rollbacks)
+	assert.Zero(t, coord.healthy, "no revision should be marked healthy while disabled")
+}
+
+func TestGuardrailRollsBack(t *testing.T) {
+	g, coord, now := newTestGuardrail(t, map[string]interface{}{
+		"enabled":      true,
+		"max_failures": 2,
+	})
+
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateHealthy, "a", "b", "c")))
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateFailed, "a", "b")))
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateFailed, "a", "b")), "components still failed are not counted again")
+	assert.Empty(t, coord.rollbacks)
+
+	*now = now.Add(time.Minute)
+	assert.True(t, g.observe(context.Background(), states(client.UnitStateFailed, "a", "b", "c")))
+	require.Len(t, coord.rollbacks, 1)
+	assert.Equal(t, "3 component failures within 1m0s of applying the policy, last: c: crashed", coord.rollbacks[0])
+
+	// the rolled back revision is not watched anymore
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateHealthy, "a", "b", "c")))
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateFailed, "a", "b", "c")))
+	g.healthy(context.Background())
+	assert.Len(t, coord.rollbacks, 1)
+	assert.Zero(t, coord.healthy)
+}
+
+func TestGuardrailMarksHealthy(t *testing.T) {
+	g, coord, _ := newTestGuardrail(t, map[string]interface{}{
+		"enabled":      true,
+		"max_failures": 2,
+	})
+
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateFailed, "a")))
+	g.healthy(context.Background())
+	assert.Equal(t, 1, coord.healthy)
+	assert.Empty(t, coord.rollbacks)
+
+	// failures counted for the previous revision do not count for the next one
+	g.watch()
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateHealthy, "a")))
+	assert.False(t, g.observe(context.Background(), states(client.UnitStateFailed, "a", "b")))
+	assert.Empty(t, coord.rollbacks)
+}
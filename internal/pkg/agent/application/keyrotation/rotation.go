@@ -0,0 +1,549 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package keyrotation rotates the API keys of the outputs before they expire.
+//
+// Fleet sets the expiration of the API key of an output in its api_key_expiration setting. Before the
+// key expires a new one is requested from Fleet and the Coordinator passes it to the components in place
+// of the key of the policy. Once all the components using the output are healthy with the new key for
+// the confirmation period the new key is persisted and the previous key is retired, otherwise the previous
+// key is restored, the new one is retired and the rotation is retried later. The rotated keys are persisted,
+// so they are used after a restart until the policy provides another key.
+package keyrotation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	fleetclient "github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// checkInterval is the interval the expiration of the keys and the rotations are checked at.
+	checkInterval = 10 * time.Second
+	// requestTimeout is the time given to Fleet to create or retire a key.
+	requestTimeout = 30 * time.Second
+)
+
+// Config is the configuration of the rotation of the API keys of the outputs, read from
+// agent.output_key_rotation.
+type Config struct {
+	// Enabled turns the rotation on, the keys without an expiration are never rotated.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// RenewBefore is how long before its expiration a key is rotated.
+	RenewBefore time.Duration `config:"renew_before" yaml:"renew_before"`
+	// ConfirmPeriod is how long the components must be healthy with a new key before the previous one
+	// is retired.
+	ConfirmPeriod time.Duration `config:"confirm_period" yaml:"confirm_period"`
+	// ConfirmTimeout is how long the components have to become healthy with a new key before the
+	// rotation is rolled back.
+	ConfirmTimeout time.Duration `config:"confirm_timeout" yaml:"confirm_timeout"`
+	// RetryInterval is the time waited before retrying a failed rotation.
+	RetryInterval time.Duration `config:"retry_interval" yaml:"retry_interval"`
+}
+
+// DefaultConfig returns the default configuration of the rotation of the API keys of the outputs.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:        true,
+		RenewBefore:    24 * time.Hour,
+		ConfirmPeriod:  time.Minute,
+		ConfirmTimeout: 10 * time.Minute,
+		RetryInterval:  time.Hour,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.RenewBefore <= 0 {
+		return fmt.Errorf("invalid output key rotation renew_before %s, must be greater than zero", c.RenewBefore)
+	}
+	if c.ConfirmPeriod < 0 {
+		return fmt.Errorf("invalid output key rotation confirm_period %s, must not be negative", c.ConfirmPeriod)
+	}
+	if c.ConfirmTimeout <= c.ConfirmPeriod {
+		return fmt.Errorf("invalid output key rotation confirm_timeout %s, must be greater than the confirm_period %s", c.ConfirmTimeout, c.ConfirmPeriod)
+	}
+	if c.RetryInterval <= 0 {
+		return fmt.Errorf("invalid output key rotation retry_interval %s, must be greater than zero", c.RetryInterval)
+	}
+	return nil
+}
+
+// Coordinator is the part of the Coordinator the rotation observes and acts on.
+type Coordinator interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+	SetOutputKeys(keys map[string]string, states []coordinator.OutputKeyState)
+}
+
+// policyOutput is the API key of an output of the policy.
+type policyOutput struct {
+	key        string
+	expiration time.Time
+}
+
+// rotatedKey is a rotated API key of an output, it replaces the key of the policy as long as the policy
+// doesn't provide another key.
+type rotatedKey struct {
+	// PolicyKeyID is the ID of the key of the policy the rotated key replaces.
+	PolicyKeyID string    `json:"policy_key_id"`
+	ID          string    `json:"id"`
+	APIKey      string    `json:"api_key"`
+	Expiration  time.Time `json:"expiration,omitempty"`
+}
+
+// rotation is a rotation in progress, the components use the new key and the previous one is still valid.
+type rotation struct {
+	previous     string
+	new          fleetapi.OutputKeyResponse
+	started      time.Time
+	healthySince time.Time
+}
+
+// failure is a failed rotation, retried after retryAt.
+type failure struct {
+	message string
+	since   time.Time
+	retryAt time.Time
+}
+
+// Rotator rotates the API keys of the outputs before they expire.
+type Rotator struct {
+	log   *logger.Logger
+	coord Coordinator
+	info  fleetapi.AgentInfo
+	store storage.Storage
+
+	mx      sync.Mutex
+	cfg     Config
+	outputs map[string]policyOutput
+	client  fleetclient.Sender
+	// reloadCh signals a new policy to the Run goroutine.
+	reloadCh chan struct{}
+
+	now func() time.Time
+
+	// the following are only accessed by the Run goroutine
+	rotated    map[string]rotatedKey
+	rotations  map[string]*rotation
+	failures   map[string]failure
+	components []runtime.ComponentComponentState
+}
+
+// New creates the rotation of the API keys of the outputs, the rotated keys are persisted in store.
+func New(log *logger.Logger, coord Coordinator, info fleetapi.AgentInfo, store storage.Storage) *Rotator {
+	return &Rotator{
+		log:       log,
+		coord:     coord,
+		info:      info,
+		store:     store,
+		cfg:       DefaultConfig(),
+		reloadCh:  make(chan struct{}, 1),
+		now:       time.Now,
+		rotated:   make(map[string]rotatedKey),
+		rotations: make(map[string]*rotation),
+		failures:  make(map[string]failure),
+	}
+}
+
+// SetClient sets the client used to request and retire the keys.
+func (r *Rotator) SetClient(c fleetclient.Sender) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.client = c
+}
+
+// Reload reads the rotation settings and the API keys of the outputs from the policy.
+func (r *Rotator) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		Rotation Config `config:"agent.output_key_rotation"`
+		Outputs  map[string]struct {
+			APIKey     string `config:"api_key"`
+			Expiration string `config:"api_key_expiration"`
+		} `config:"outputs"`
+	}{
+		Rotation: DefaultConfig(),
+	}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack output key rotation config: %w", err)
+	}
+
+	outputs := make(map[string]policyOutput, len(cfg.Outputs))
+	for name, output := range cfg.Outputs {
+		if output.APIKey == "" {
+			continue
+		}
+		o := policyOutput{key: output.APIKey}
+		if output.Expiration != "" {
+			expiration, err := time.Parse(time.RFC3339, output.Expiration)
+			if err != nil {
+				r.log.Warnf("ignoring invalid API key expiration %q of output %s: %v", output.Expiration, name, err)
+			} else {
+				o.expiration = expiration
+			}
+		}
+		outputs[name] = o
+	}
+
+	r.mx.Lock()
+	r.cfg = cfg.Rotation
+	r.outputs = outputs
+	r.mx.Unlock()
+
+	// Reload is called on the Coordinator goroutine, the keys are updated by the Run goroutine as the
+	// Coordinator cannot be updated from its own goroutine.
+	select {
+	case r.reloadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *Rotator) config() (Config, map[string]policyOutput, fleetclient.Sender) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.cfg, r.outputs, r.client
+}
+
+// Run rotates the API keys of the outputs until the context is done.
+func (r *Rotator) Run(ctx context.Context) {
+	if err := r.load(); err != nil {
+		r.log.Errorf("failed to load the rotated output API keys: %v", err)
+	}
+	if len(r.rotated) > 0 {
+		// use the rotated keys as soon as possible, they are checked against the policy once it's loaded
+		r.publish()
+	}
+
+	stateCh := r.coord.StateSubscribe(ctx, 32)
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.reloadCh:
+			r.reconcile(ctx)
+		case state, ok := <-stateCh:
+			if !ok {
+				return
+			}
+			r.components = state.Components
+			r.evaluate(ctx)
+		case <-ticker.C:
+			r.evaluate(ctx)
+			r.check(ctx)
+		}
+	}
+}
+
+// reconcile drops the rotated keys and the rotations of the outputs whose key changed in the policy.
+func (r *Rotator) reconcile(ctx context.Context) {
+	_, outputs, _ := r.config()
+	changed := false
+	for name, key := range r.rotated {
+		if output, ok := outputs[name]; !ok || keyID(output.key) != key.PolicyKeyID {
+			r.log.Infof("the policy replaced the API key of output %s, dropping the rotated key", name)
+			delete(r.rotated, name)
+			changed = true
+		}
+	}
+	if changed {
+		if err := r.persist(); err != nil {
+			r.log.Errorf("failed to persist the rotated output API keys: %v", err)
+		}
+	}
+	for name, rot := range r.rotations {
+		if output, ok := outputs[name]; !ok || (output.key != rot.previous && !r.replaces(name, rot.previous, output)) {
+			r.log.Infof("the policy replaced the API key of output %s, stopping its rotation", name)
+			r.retire(ctx, rot.new.ID)
+			delete(r.rotations, name)
+		}
+	}
+	for name := range r.failures {
+		if _, ok := outputs[name]; !ok {
+			delete(r.failures, name)
+		}
+	}
+	r.publish()
+	r.check(ctx)
+}
+
+// replaces returns true when key is the rotated key of the output replacing the key of the policy.
+func (r *Rotator) replaces(name string, key string, output policyOutput) bool {
+	rotated, ok := r.rotated[name]
+	return ok && rotated.APIKey == key && rotated.PolicyKeyID == keyID(output.key)
+}
+
+// current returns the API key used by an output outside a rotation and its expiration.
+func (r *Rotator) current(name string, output policyOutput) (string, time.Time) {
+	if r.replaces(name, r.rotated[name].APIKey, output) {
+		return r.rotated[name].APIKey, r.rotated[name].Expiration
+	}
+	return output.key, output.expiration
+}
+
+// check starts the rotation of the keys about to expire.
+func (r *Rotator) check(ctx context.Context) {
+	cfg, outputs, c := r.config()
+	if !cfg.Enabled || c == nil {
+		return
+	}
+	now := r.now()
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if _, ok := r.rotations[name]; ok {
+			continue
+		}
+		if f, ok := r.failures[name]; ok && now.Before(f.retryAt) {
+			continue
+		}
+		key, expiration := r.current(name, outputs[name])
+		if expiration.IsZero() || now.Before(expiration.Add(-cfg.RenewBefore)) {
+			continue
+		}
+		r.start(ctx, c, name, key, expiration)
+	}
+}
+
+// start requests a new key for the output and passes it to the components.
+func (r *Rotator) start(ctx context.Context, c fleetclient.Sender, name string, previous string, expiration time.Time) {
+	r.log.Infof("rotating the API key of output %s expiring at %s", name, expiration.Format(time.RFC3339))
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	resp, err := fleetapi.NewOutputKeyCmd(r.info, c).Execute(ctx, &fleetapi.OutputKeyRequest{OutputID: name})
+	if err != nil {
+		r.fail(name, fmt.Sprintf("failed to request a new API key: %v", err))
+		r.publish()
+		return
+	}
+	r.rotations[name] = &rotation{
+		previous: previous,
+		new:      *resp,
+		started:  r.now(),
+	}
+	r.publish()
+}
+
+// evaluate confirms or rolls back the rotations depending on the health of the components using the new keys.
+func (r *Rotator) evaluate(ctx context.Context) {
+	if len(r.rotations) == 0 {
+		return
+	}
+	cfg, _, _ := r.config()
+	now := r.now()
+	for name, rot := range r.rotations {
+		using, usingPrevious, failed, healthy := r.units(rot)
+		switch {
+		case failed != "":
+			r.rollback(ctx, name, rot, fmt.Sprintf("rolled back, %s failed with the new API key", failed))
+		case using == 0 && usingPrevious == 0:
+			// no component uses the output, the new key can't be verified any further
+			r.commit(ctx, name, rot)
+		case usingPrevious == 0 && healthy == using:
+			if rot.healthySince.IsZero() {
+				rot.healthySince = now
+			}
+			if now.Sub(rot.healthySince) >= cfg.ConfirmPeriod {
+				r.commit(ctx, name, rot)
+			}
+		case now.Sub(rot.started) >= cfg.ConfirmTimeout:
+			r.rollback(ctx, name, rot, fmt.Sprintf("rolled back, the components were not healthy with the new API key within %s", cfg.ConfirmTimeout))
+		default:
+			rot.healthySince = time.Time{}
+		}
+	}
+}
+
+// units returns the number of output units using the new key of a rotation, the number still using
+// the previous key, the first unit failed with the new key and the number healthy with the new key.
+func (r *Rotator) units(rot *rotation) (using int, usingPrevious int, failed string, healthy int) {
+	for _, comp := range r.components {
+		for _, unit := range comp.Component.Units {
+			if unit.Type != client.UnitTypeOutput || unit.Config == nil {
+				continue
+			}
+			switch unit.Config.GetSource().GetFields()["api_key"].GetStringValue() {
+			case rot.new.APIKey:
+				using++
+				state := comp.State.Units[runtime.ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: unit.ID}].State
+				switch state {
+				case client.UnitStateHealthy:
+					healthy++
+				case client.UnitStateFailed:
+					if failed == "" {
+						failed = comp.Component.ID
+					}
+				}
+			case rot.previous:
+				usingPrevious++
+			}
+		}
+	}
+	return using, usingPrevious, failed, healthy
+}
+
+// commit persists the new key of a confirmed rotation and retires the previous key. The previous key is
+// only retired once the new one is persisted, otherwise the agent would use a retired key after a restart,
+// the commit is retried on the next evaluation.
+func (r *Rotator) commit(ctx context.Context, name string, rot *rotation) {
+	_, outputs, _ := r.config()
+	previous, hadPrevious := r.rotated[name]
+	r.rotated[name] = rotatedKey{
+		PolicyKeyID: keyID(outputs[name].key),
+		ID:          rot.new.ID,
+		APIKey:      rot.new.APIKey,
+		Expiration:  rot.new.Expiration,
+	}
+	if err := r.persist(); err != nil {
+		if hadPrevious {
+			r.rotated[name] = previous
+		} else {
+			delete(r.rotated, name)
+		}
+		r.log.Errorf("failed to persist the new API key of output %s, keeping the previous key: %v", name, err)
+		return
+	}
+	delete(r.rotations, name)
+	delete(r.failures, name)
+	r.retire(ctx, keyID(rot.previous))
+	r.log.Infof("rotated the API key of output %s", name)
+	r.publish()
+}
+
+// rollback restores the previous key of a rotation and retires the new one.
+func (r *Rotator) rollback(ctx context.Context, name string, rot *rotation, message string) {
+	delete(r.rotations, name)
+	r.retire(ctx, rot.new.ID)
+	r.fail(name, message)
+	r.publish()
+}
+
+func (r *Rotator) fail(name string, message string) {
+	cfg, _, _ := r.config()
+	now := r.now()
+	r.log.Errorf("failed to rotate the API key of output %s, retrying in %s: %s", name, cfg.RetryInterval, message)
+	r.failures[name] = failure{
+		message: message,
+		since:   now,
+		retryAt: now.Add(cfg.RetryInterval),
+	}
+}
+
+// retire retires a key in Fleet, a key that cannot be retired still expires.
+func (r *Rotator) retire(ctx context.Context, id string) {
+	_, _, c := r.config()
+	if c == nil || id == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	if err := fleetapi.NewRetireOutputKeyCmd(r.info, c).Execute(ctx, id); err != nil {
+		r.log.Warnf("failed to retire output API key %s, it stays valid until it expires: %v", id, err)
+	}
+}
+
+// publish passes the keys replacing the ones of the policy and the state of the rotations to the Coordinator.
+func (r *Rotator) publish() {
+	_, outputs, _ := r.config()
+	keys := make(map[string]string)
+	var states []coordinator.OutputKeyState
+	for name, key := range r.rotated {
+		if output, ok := outputs[name]; outputs == nil || (ok && r.replaces(name, key.APIKey, output)) {
+			keys[name] = key.APIKey
+			states = append(states, coordinator.OutputKeyState{
+				Output:     name,
+				State:      coordinator.OutputKeyRotated,
+				Expiration: key.Expiration,
+			})
+		}
+	}
+	for name, rot := range r.rotations {
+		keys[name] = rot.new.APIKey
+		states = slices.DeleteFunc(states, func(s coordinator.OutputKeyState) bool { return s.Output == name })
+		states = append(states, coordinator.OutputKeyState{
+			Output:     name,
+			State:      coordinator.OutputKeyRotating,
+			Expiration: rot.new.Expiration,
+			Since:      rot.started,
+		})
+	}
+	for name, f := range r.failures {
+		if _, ok := r.rotations[name]; ok {
+			continue
+		}
+		_, expiration := r.current(name, outputs[name])
+		states = slices.DeleteFunc(states, func(s coordinator.OutputKeyState) bool { return s.Output == name })
+		states = append(states, coordinator.OutputKeyState{
+			Output:     name,
+			State:      coordinator.OutputKeyFailed,
+			Message:    f.message,
+			Expiration: expiration,
+			Since:      f.since,
+		})
+	}
+	slices.SortFunc(states, func(a, b coordinator.OutputKeyState) int {
+		return strings.Compare(a.Output, b.Output)
+	})
+	r.coord.SetOutputKeys(keys, states)
+}
+
+// load reads the rotated keys from the store.
+func (r *Rotator) load() error {
+	if exists, err := r.store.Exists(); err != nil || !exists {
+		return err
+	}
+	reader, err := r.store.Load()
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	rotated := make(map[string]rotatedKey)
+	if err := json.Unmarshal(data, &rotated); err != nil {
+		return fmt.Errorf("failed to decode the rotated output API keys: %w", err)
+	}
+	r.rotated = rotated
+	return nil
+}
+
+// persist writes the rotated keys to the store.
+func (r *Rotator) persist() error {
+	data, err := json.Marshal(r.rotated)
+	if err != nil {
+		return err
+	}
+	return r.store.Save(bytes.NewReader(data))
+}
+
+// keyID returns the ID of an API key in the id:key format.
+func keyID(key string) string {
+	id, _, _ := strings.Cut(key, ":")
+	return id
+}
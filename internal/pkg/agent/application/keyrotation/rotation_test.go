@@ -0,0 +1,295 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package keyrotation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeCoordinator struct {
+	keys   []map[string]string
+	states [][]coordinator.OutputKeyState
+}
+
+func (c *fakeCoordinator) StateSubscribe(context.Context, int) chan coordinator.State {
+	return make(chan coordinator.State)
+}
+
+func (c *fakeCoordinator) SetOutputKeys(keys map[string]string, states []coordinator.OutputKeyState) {
+	c.keys = append(c.keys, keys)
+	c.states = append(c.states, states)
+}
+
+// last returns the keys and the states of the last update.
+func (c *fakeCoordinator) last() (map[string]string, []coordinator.OutputKeyState) {
+	if len(c.keys) == 0 {
+		return nil, nil
+	}
+	return c.keys[len(c.keys)-1], c.states[len(c.states)-1]
+}
+
+// fakeFleet answers the output key requests, creating the keys key-1, key-2, ...
+type fakeFleet struct {
+	created int
+	retired []string
+	fail    bool
+}
+
+func (f *fakeFleet) Send(_ context.Context, method string, path string, _ url.Values, _ http.Header, _ io.Reader) (*http.Response, error) {
+	if f.fail {
+		return response(http.StatusInternalServerError, `{"statusCode": 500, "error": "Internal Server Error"}`), nil
+	}
+	switch method {
+	case http.MethodPost:
+		f.created++
+		id := fmt.Sprintf("key-%d", f.created)
+		body, _ := json.Marshal(map[string]string{
+			"id":         id,
+			"api_key":    id + ":secret",
+			"expiration": "2025-11-01T00:00:00Z",
+		})
+		return response(http.StatusOK, string(body)), nil
+	case http.MethodDelete:
+		f.retired = append(f.retired, path[strings.LastIndex(path, "/")+1:])
+		return response(http.StatusNoContent, ""), nil
+	}
+	return response(http.StatusMethodNotAllowed, ""), nil
+}
+
+func (f *fakeFleet) URI() string {
+	return "http://localhost"
+}
+
+func response(status int, body string) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(body))}
+}
+
+// failingStore is a store failing to save while fail is set.
+type failingStore struct {
+	storage.Storage
+	fail bool
+}
+
+func (s *failingStore) Save(in io.Reader) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return s.Storage.Save(in)
+}
+
+type agentInfo struct{}
+
+func (agentInfo) AgentID() string {
+	return "agent-id"
+}
+
+func newTestRotator(t *testing.T, storePath string, outputs map[string]interface{}) (*Rotator, *fakeCoordinator, *fakeFleet, *time.Time) {
+	log, _ := loggertest.New("keyrotation")
+	coord := &fakeCoordinator{}
+	store, err := storage.NewDiskStore(storePath)
+	require.NoError(t, err)
+	r := New(log, coord, agentInfo{}, store)
+	fleet := &fakeFleet{}
+	r.SetClient(fleet)
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	require.NoError(t, r.load())
+	require.NoError(t, r.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.output_key_rotation.confirm_period": "1m",
+		"outputs": outputs,
+	})))
+	return r, coord, fleet, &now
+}
+
+// componentUsing returns a component with an output unit using the key in the given state.
+func componentUsing(t *testing.T, id string, key string, state client.UnitState) runtime.ComponentComponentState {
+	source, err := structpb.NewStruct(map[string]interface{}{"type": "elasticsearch", "api_key": key})
+	require.NoError(t, err)
+	return runtime.ComponentComponentState{
+		Component: component.Component{
+			ID: id,
+			Units: []component.Unit{{
+				ID:     id,
+				Type:   client.UnitTypeOutput,
+				Config: &proto.UnitExpectedConfig{Source: source},
+			}},
+		},
+		State: runtime.ComponentState{
+			Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+				{UnitType: client.UnitTypeOutput, UnitID: id}: {State: state},
+			},
+		},
+	}
+}
+
+func TestRotation(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "output_keys")
+	outputs := map[string]interface{}{
+		"default": map[string]interface{}{
+			"type":               "elasticsearch",
+			"api_key":            "policy:secret",
+			"api_key_expiration": "2025-10-02T00:00:00Z",
+		},
+		"other": map[string]interface{}{
+			"type":    "elasticsearch",
+			"api_key": "other:secret",
+		},
+	}
+	r, coord, fleet, now := newTestRotator(t, storePath, outputs)
+	ctx := t.Context()
+
+	// the key of default expires within renew_before, a new one is requested
+	r.reconcile(ctx)
+	keys, states := coord.last()
+	assert.Equal(t, map[string]string{"default": "key-1:secret"}, keys)
+	require.Len(t, states, 1)
+	assert.Equal(t, coordinator.OutputKeyRotating, states[0].State)
+
+	// the previous key is kept until the components are healthy with the new one for confirm_period
+	r.components = []runtime.ComponentComponentState{componentUsing(t, "filestream-default", "policy:secret", client.UnitStateHealthy)}
+	r.evaluate(ctx)
+	r.components = []runtime.ComponentComponentState{componentUsing(t, "filestream-default", "key-1:secret", client.UnitStateHealthy)}
+	r.evaluate(ctx)
+	assert.Empty(t, fleet.retired)
+	*now = now.Add(time.Minute)
+	r.evaluate(ctx)
+	assert.Equal(t, []string{"policy"}, fleet.retired)
+	keys, states = coord.last()
+	assert.Equal(t, map[string]string{"default": "key-1:secret"}, keys)
+	require.Len(t, states, 1)
+	assert.Equal(t, coordinator.OutputKeyRotated, states[0].State)
+	assert.Equal(t, time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC), states[0].Expiration)
+
+	// the rotated key is persisted and used until the policy provides another key
+	r, coord, _, _ = newTestRotator(t, storePath, outputs)
+	r.reconcile(ctx)
+	keys, _ = coord.last()
+	assert.Equal(t, map[string]string{"default": "key-1:secret"}, keys)
+
+	outputs["default"] = map[string]interface{}{"type": "elasticsearch", "api_key": "new:secret"}
+	r, coord, _, _ = newTestRotator(t, storePath, outputs)
+	r.reconcile(ctx)
+	keys, states = coord.last()
+	assert.Empty(t, keys)
+	assert.Empty(t, states)
+}
+
+func TestRotationRollback(t *testing.T) {
+	r, coord, fleet, now := newTestRotator(t, filepath.Join(t.TempDir(), "output_keys"), map[string]interface{}{
+		"default": map[string]interface{}{
+			"type":               "elasticsearch",
+			"api_key":            "policy:secret",
+			"api_key_expiration": "2025-10-02T00:00:00Z",
+		},
+	})
+	ctx := t.Context()
+
+	r.reconcile(ctx)
+	r.components = []runtime.ComponentComponentState{componentUsing(t, "filestream-default", "key-1:secret", client.UnitStateFailed)}
+	r.evaluate(ctx)
+	assert.Equal(t, []string{"key-1"}, fleet.retired)
+	keys, states := coord.last()
+	assert.Empty(t, keys)
+	require.Len(t, states, 1)
+	assert.Equal(t, coordinator.OutputKeyFailed, states[0].State)
+	assert.Contains(t, states[0].Message, "filestream-default failed")
+
+	// retried after retry_interval, rolled back when the components are not healthy within confirm_timeout
+	r.check(ctx)
+	assert.Equal(t, 1, fleet.created)
+	*now = now.Add(time.Hour)
+	r.check(ctx)
+	keys, _ = coord.last()
+	assert.Equal(t, map[string]string{"default": "key-2:secret"}, keys)
+	r.components = []runtime.ComponentComponentState{componentUsing(t, "filestream-default", "key-2:secret", client.UnitStateDegraded)}
+	*now = now.Add(10 * time.Minute)
+	r.evaluate(ctx)
+	assert.Equal(t, []string{"key-1", "key-2"}, fleet.retired)
+	_, states = coord.last()
+	require.Len(t, states, 1)
+	assert.Equal(t, coordinator.OutputKeyFailed, states[0].State)
+
+	// failing to request a key is reported
+	fleet.fail = true
+	*now = now.Add(time.Hour)
+	r.check(ctx)
+	_, states = coord.last()
+	require.Len(t, states, 1)
+	assert.Equal(t, coordinator.OutputKeyFailed, states[0].State)
+	assert.Contains(t, states[0].Message, "failed to request a new API key")
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate())
+
+	cfg.ConfirmTimeout = cfg.ConfirmPeriod
+	assert.Error(t, cfg.Validate())
+
+	cfg = DefaultConfig()
+	cfg.RenewBefore = 0
+	assert.Error(t, cfg.Validate())
+}
+
+func TestRotationCommitPersistsBeforeRetiring(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "output_keys")
+	outputs := map[string]interface{}{
+		"default": map[string]interface{}{
+			"type":               "elasticsearch",
+			"api_key":            "policy:secret",
+			"api_key_expiration": "2025-10-02T00:00:00Z",
+		},
+	}
+	r, coord, fleet, _ := newTestRotator(t, storePath, outputs)
+	store := &failingStore{Storage: r.store, fail: true}
+	r.store = store
+	ctx := t.Context()
+
+	r.reconcile(ctx)
+	r.components = []runtime.ComponentComponentState{componentUsing(t, "filestream-default", "key-1:secret", client.UnitStateHealthy)}
+	r.cfg.ConfirmPeriod = 0
+	r.evaluate(ctx)
+
+	// the new key cannot be persisted, the previous key is not retired and the rotation is kept
+	assert.Empty(t, fleet.retired)
+	assert.Empty(t, r.rotated)
+	require.Contains(t, r.rotations, "default")
+	_, states := coord.last()
+	require.Len(t, states, 1)
+	assert.Equal(t, coordinator.OutputKeyRotating, states[0].State)
+
+	// once the new key is persisted the previous key is retired
+	store.fail = false
+	r.evaluate(ctx)
+	assert.Equal(t, []string{"policy"}, fleet.retired)
+	r, coord, _, _ = newTestRotator(t, storePath, outputs)
+	r.reconcile(ctx)
+	keys, _ := coord.last()
+	assert.Equal(t, map[string]string{"default": "key-1:secret"}, keys)
+}
@@ -0,0 +1,91 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// locationMetadataConfig is the configuration under `agent.metadata.location` of the policy.
+type locationMetadataConfig struct {
+	// Site is the site or data center of the host.
+	Site string `config:"site"`
+	// Rack is the rack of the host in the site.
+	Rack string `config:"rack"`
+	// Region is the region of the site.
+	Region string `config:"region"`
+}
+
+// InjectLocationMetadata is a modifier passed to coordinator in order to add the location of the host configured
+// under `agent.metadata.location` to every event, including the events of the monitoring components, so all the
+// data from a host carries the same locality. The location is added as an `add_fields` processor at the end of the
+// processors of each input unit, under `elastic_agent.location`.
+func InjectLocationMetadata(comps []component.Component, cfg map[string]interface{}) ([]component.Component, error) {
+	locationCfg, err := getLocationMetadataConfig(cfg)
+	if err != nil {
+		return comps, fmt.Errorf("error retrieving location metadata config: %w", err)
+	}
+	if locationCfg == nil {
+		// nothing to do
+		return comps, nil
+	}
+
+	location := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"site":   locationCfg.Site,
+		"rack":   locationCfg.Rack,
+		"region": locationCfg.Region,
+	} {
+		if value != "" {
+			location[key] = value
+		}
+	}
+	if len(location) == 0 {
+		// nothing to add
+		return comps, nil
+	}
+
+	return appendInputProcessor(comps, map[string]interface{}{
+		"add_fields": map[string]interface{}{
+			"target": eventAnnotationsTarget,
+			"fields": map[string]interface{}{
+				"location": location,
+			},
+		},
+	})
+}
+
+func getLocationMetadataConfig(cfg map[string]interface{}) (*locationMetadataConfig, error) {
+	nestedValue, err := utils.GetNestedMap(cfg, "agent", "metadata", "location")
+	if errors.Is(err, utils.ErrKeyNotFound) {
+		// No location metadata config found, nothing to do
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error traversing config: %w", err)
+	}
+
+	rawLocationConfig, ok := nestedValue.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the retrieved location metadata config is not a map: %T", nestedValue)
+	}
+
+	newConfigFrom, err := config.NewConfigFrom(rawLocationConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing location metadata config: %w", err)
+	}
+
+	locationConfig := new(locationMetadataConfig)
+	err = newConfigFrom.UnpackTo(locationConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error unpacking location metadata config: %w", err)
+	}
+	return locationConfig, nil
+}
@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectLocationMetadata(t *testing.T) {
+	t.Run("not configured", func(t *testing.T) {
+		comps, err := InjectLocationMetadata(annotationTestComponents(t), map[string]interface{}{
+			"agent": map[string]interface{}{
+				"metadata": map[string]interface{}{},
+			},
+		})
+		require.NoError(t, err)
+		assert.Len(t, comps[0].Units[1].Config.Source.AsMap()["processors"], 1)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, err := InjectLocationMetadata(annotationTestComponents(t), map[string]interface{}{
+			"agent": map[string]interface{}{
+				"metadata": map[string]interface{}{"location": "dc-1"},
+			},
+		})
+		require.Error(t, err)
+	})
+
+	t.Run("configured", func(t *testing.T) {
+		comps, err := InjectLocationMetadata(annotationTestComponents(t), map[string]interface{}{
+			"agent": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"location": map[string]interface{}{
+						"site":   "dc-1",
+						"region": "eu-west",
+					},
+				},
+			},
+		})
+		require.NoError(t, err)
+
+		// input units get the location appended to their processors
+		processors := comps[0].Units[1].Config.Source.AsMap()["processors"]
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"drop_event": map[string]interface{}{}},
+			map[string]interface{}{
+				"add_fields": map[string]interface{}{
+					"target": "elastic_agent",
+					"fields": map[string]interface{}{
+						"location": map[string]interface{}{
+							"site":   "dc-1",
+							"region": "eu-west",
+						},
+					},
+				},
+			},
+		}, processors)
+
+		// output units and endpoint are left untouched
+		assert.NotContains(t, comps[0].Units[0].Config.Source.AsMap(), "processors")
+		assert.NotContains(t, comps[1].Units[0].Config.Source.AsMap(), "processors")
+	})
+}
@@ -7,21 +7,26 @@ package application
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/actions/handlers"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/clientcert"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/dispatcher"
 	fleetgateway "github.com/elastic/elastic-agent/internal/pkg/agent/application/gateway/fleet"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/keyrotation"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/peers"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker/fleet"
@@ -31,7 +36,9 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 	"github.com/elastic/elastic-agent/internal/pkg/runner"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
 // dispatchFlushInterval is the max time between calls to dispatcher.Dispatch
@@ -54,6 +61,17 @@ type managedConfigManager struct {
 	actionAcker          acker.Acker
 	retrier              *retrier.Retrier
 
+	// outputKeys rotates the API keys of the outputs, set by the application once the coordinator is created.
+	outputKeys *keyrotation.Rotator
+
+	// fleetServerProbe checks the API of the hosted Fleet Server, only set when running one.
+	fleetServerProbe           fleetServerProbe
+	fleetServerURL             string
+	fleetServerReadyInterval   time.Duration
+	fleetServerMonitorInterval time.Duration
+	fleetServerState           *coordinator.FleetServerState
+	reportFleetServerState     func(*coordinator.FleetServerState)
+
 	ch    chan coordinator.ConfigChange
 	errCh chan error
 }
@@ -79,8 +97,9 @@ func newManagedConfigManager(
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize action dispatcher: %w", err)
 	}
+	actionDispatcher.SetLedger(stateStore)
 
-	return &managedConfigManager{
+	m := &managedConfigManager{
 		log:                  log,
 		agentInfo:            agentInfo,
 		cfg:                  cfg,
@@ -97,7 +116,14 @@ func newManagedConfigManager(
 		fleetAcker:           fleetAcker,
 		actionAcker:          actionAcker,
 		retrier:              retrier,
-	}, nil
+	}
+	if cfg.Fleet.Server != nil {
+		m.fleetServerURL = fleetServerURL(cfg.Fleet.Server)
+		m.fleetServerProbe = newFleetServerProbe(m.fleetServerURL)
+		m.fleetServerReadyInterval = fleetServerReadyInterval
+		m.fleetServerMonitorInterval = fleetServerMonitorInterval
+	}
+	return m, nil
 }
 
 func (m *managedConfigManager) Run(ctx context.Context) error {
@@ -105,6 +131,9 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 	if m.coord == nil {
 		return errors.New("coord must be set before calling Run")
 	}
+	if m.reportFleetServerState == nil {
+		m.reportFleetServerState = m.coord.SetFleetServerState
+	}
 
 	// Un-enrolled so we will not do anything.
 	if m.wasUnenrolled() {
@@ -151,6 +180,9 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		m.dispatcher.Dispatch(ctx, m.coord.SetUpgradeDetails, m.actionAcker, action)
 		stateRestored = true
 	}
+	if !m.wasUnenrolled() {
+		m.dispatchPendingActions(ctx)
+	}
 
 	// In the case this Elastic Agent is running a Fleet Server; we need to ensure that
 	// the Fleet Server is running before the Fleet gateway is started.
@@ -180,6 +212,22 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		return err
 	}
 
+	if peersCfg := m.cfg.Settings.Peers; peersCfg != nil && peersCfg.Enabled {
+		peersMgr, err := peers.New(m.log.Named("peers"), peersCfg, m.agentInfo.AgentID(), func() bool {
+			return m.coord.State().State == agentclient.Healthy
+		})
+		if err != nil {
+			return fmt.Errorf("failed to initialize peer health beacons: %w", err)
+		}
+		gateway.SetPeerHealth(peersMgr.Health)
+		peersRunner := runner.Start(gatewayCtx, peersMgr.Run)
+		defer peersRunner.Stop()
+	}
+
+	gateway.SetPolicyHash(policyChanger.PolicyHash)
+	gateway.SetFleetHosts(m.coord.SetFleetHosts)
+	gateway.SetCheckinConfig(m.cfg.Settings.Checkin)
+
 	// Not running a Fleet Server so the gateway and acker can be changed based on the configuration change.
 	if m.cfg.Fleet.Server == nil {
 		policyChanger.AddSetter(gateway)
@@ -196,6 +244,40 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		}
 	}
 
+	if m.outputKeys != nil {
+		if m.cfg.Fleet.Server == nil {
+			policyChanger.AddSetter(m.outputKeys)
+		} else {
+			m.outputKeys.SetClient(m.client)
+		}
+		outputKeysRunner := runner.Start(gatewayCtx, func(ctx context.Context) error {
+			m.outputKeys.Run(ctx)
+			return nil
+		})
+		defer outputKeysRunner.Stop()
+	}
+
+	if m.cfg.Fleet.IssuedClientCertificate() {
+		hasRoot, err := utils.HasRoot()
+		if err != nil {
+			return fmt.Errorf("checking if running with root/Administrator privileges: %w", err)
+		}
+		certRenewer := clientcert.NewRenewer(m.log.Named("client_certificate"), m.agentInfo, m.cfg.Fleet.ClientCertificate.RenewBefore,
+			policyChanger.SetClientCertificate, vault.WithUnprivileged(!hasRoot))
+		if m.cfg.Fleet.Server == nil {
+			policyChanger.AddSetter(certRenewer)
+		} else {
+			certRenewer.SetClient(m.client)
+		}
+		certRenewerRunner := runner.Start(gatewayCtx, func(ctx context.Context) error {
+			if err := certRenewer.Run(ctx); err != nil {
+				m.log.Errorf("client certificate will not be renewed: %v", err)
+			}
+			return nil
+		})
+		defer certRenewerRunner.Stop()
+	}
+
 	// Proxy errors from the gateway to our own channel.
 	gatewayErrorsRunner := runner.Start(context.Background(), func(ctx context.Context) error {
 		for {
@@ -208,6 +290,12 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 		}
 	})
 
+	// Keep the state of the locally hosted Fleet Server up to date.
+	if m.cfg.Fleet.Server != nil {
+		fleetServerRunner := runner.Start(gatewayCtx, m.monitorFleetServerAPI)
+		defer fleetServerRunner.Stop()
+	}
+
 	// Run the gateway.
 	gatewayRunner := runner.Start(gatewayCtx, func(ctx context.Context) error {
 		defer gatewayErrorsRunner.Stop()
@@ -220,6 +308,43 @@ func (m *managedConfigManager) Run(ctx context.Context) error {
 	return gatewayRunner.Err()
 }
 
+// idempotentActionTypes are the types of the actions that can be executed again without side effects, they are
+// dispatched again when they were interrupted by the agent stopping.
+var idempotentActionTypes = []string{
+	fleetapi.ActionTypePolicyChange,
+	fleetapi.ActionTypePolicyReassign,
+	fleetapi.ActionTypeSettings,
+	fleetapi.ActionTypeUnenroll,
+}
+
+// dispatchPendingActions dispatches the actions delivered by Fleet before the agent stopped that did not
+// complete, Fleet does not deliver them again. The idempotent actions that started are dispatched again, the
+// other ones are not as they may have been partially executed.
+func (m *managedConfigManager) dispatchPendingActions(ctx context.Context) {
+	pending, interrupted := m.stateStore.PendingActions()
+	dropped := 0
+	for _, action := range interrupted {
+		if slices.Contains(idempotentActionTypes, action.Type()) {
+			m.log.Infof("Action id %q of type %q was interrupted by the agent stopping, it is executed again", action.ID(), action.Type())
+			pending = append(pending, action)
+			continue
+		}
+		m.log.Warnf("Action id %q of type %q was interrupted by the agent stopping, it is not executed again", action.ID(), action.Type())
+		m.stateStore.CompletePendingAction(action.ID(), true)
+		dropped++
+	}
+	if dropped > 0 {
+		if err := m.stateStore.Save(); err != nil {
+			m.log.Errorf("failed to persist interrupted actions: %v", err)
+		}
+	}
+	if len(pending) == 0 {
+		return
+	}
+	m.log.Infof("Dispatching %d actions delivered before the agent stopped", len(pending))
+	m.dispatcher.Dispatch(ctx, m.coord.SetUpgradeDetails, m.actionAcker, pending...)
+}
+
 // runDispatcher passes actions collected from gateway to dispatcher or calls Dispatch with no actions every flushInterval.
 func runDispatcher(ctx context.Context, actionDispatcher dispatcher.Dispatcher, fleetGateway coordinator.FleetGateway, detailsSetter details.Observer, actionAcker acker.Acker, flushInterval time.Duration) {
 	t := time.NewTimer(flushInterval)
@@ -269,13 +394,21 @@ func (m *managedConfigManager) initFleetServer(ctx context.Context, cfg *configu
 	select {
 	case <-ctx.Done():
 		return fmt.Errorf("timeout while waiting for fleet server start: %w", ctx.Err())
-	case m.ch <- &localConfigChange{injectFleetServerInput}:
+	case m.ch <- &localConfigChange{cfg: injectFleetServerInput}:
 	}
 
 	return m.waitForFleetServer(ctx)
 }
 
 func (m *managedConfigManager) waitForFleetServer(ctx context.Context) error {
+	if err := m.waitForFleetServerComponent(ctx); err != nil {
+		return err
+	}
+	// the component reports healthy before its API serves enrollments and check-ins
+	return m.waitForFleetServerAPI(ctx)
+}
+
+func (m *managedConfigManager) waitForFleetServerComponent(ctx context.Context) error {
 	m.log.Debugf("watching Fleet Server component state")
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -352,7 +485,7 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionUpgrade{},
-		handlers.NewUpgrade(m.log, m.coord),
+		handlers.NewUpgrade(m.log, m.coord, m.cfg.Settings.Upgrade.Window),
 	)
 
 	m.dispatcher.MustRegister(
@@ -389,6 +522,11 @@ func (m *managedConfigManager) initDispatcher(canceller context.CancelFunc) *han
 		handlers.NewMigrate(m.log, m.agentInfo, m.coord),
 	)
 
+	m.dispatcher.MustRegister(
+		&fleetapi.ActionOperation{},
+		handlers.NewOperation(m.log, m.agentInfo, m.coord, paths.AgentOperationsPath()),
+	)
+
 	m.dispatcher.MustRegister(
 		&fleetapi.ActionUnknown{},
 		handlers.NewUnknown(m.log),
@@ -6,17 +6,25 @@ package application
 
 import (
 	"context"
+	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/dispatcher"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/acker"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+	"github.com/elastic/elastic-agent/internal/pkg/queue"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type mockDispatcher struct {
@@ -145,3 +153,58 @@ func Test_runDispatcher(t *testing.T) {
 		})
 	}
 }
+
+func Test_dispatchPendingActions(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+	diskStore, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "state.enc"))
+	require.NoError(t, err)
+	stateStore, err := store.NewStateStore(log, diskStore)
+	require.NoError(t, err)
+
+	pending := &fleetapi.ActionSettings{ActionID: "pending", ActionType: fleetapi.ActionTypeSettings}
+	policyChange := &fleetapi.ActionPolicyChange{ActionID: "policy-change", ActionType: fleetapi.ActionTypePolicyChange}
+	upgrade := &fleetapi.ActionUpgrade{ActionID: "upgrade", ActionType: fleetapi.ActionTypeUpgrade}
+	stateStore.AddPendingActions(pending, policyChange, upgrade)
+	stateStore.StartPendingAction(policyChange)
+	stateStore.StartPendingAction(upgrade)
+
+	actionQueue, err := queue.NewActionQueue(stateStore.Queue(), stateStore)
+	require.NoError(t, err)
+	handler := &mockHandler{}
+	handler.On("Handle", mock.Anything, pending, mock.Anything).Return(nil).Once()
+	handler.On("Handle", mock.Anything, policyChange, mock.Anything).Return(nil).Once()
+	actionDispatcher, err := dispatcher.New(log, t.TempDir(), handler, actionQueue)
+	require.NoError(t, err)
+	actionDispatcher.SetLedger(stateStore)
+	actionAcker := &mockAcker{}
+	actionAcker.On("Commit", mock.Anything).Return(nil)
+
+	m := &managedConfigManager{
+		log:         log,
+		coord:       &coordinator.Coordinator{},
+		stateStore:  stateStore,
+		dispatcher:  actionDispatcher,
+		actionAcker: actionAcker,
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- <-actionDispatcher.Errors() }()
+	m.dispatchPendingActions(t.Context())
+	require.NoError(t, <-errCh)
+	handler.AssertExpectations(t)
+
+	// the interrupted policy change is idempotent, it's executed again; the upgrade is not
+	pendingActions, interrupted := stateStore.PendingActions()
+	assert.Empty(t, pendingActions)
+	assert.Empty(t, interrupted)
+	assert.True(t, stateStore.ActionExecuted(policyChange.ID()))
+	assert.True(t, stateStore.ActionExecuted(upgrade.ID()), "the interrupted upgrade must not be executed again")
+}
+
+type mockHandler struct {
+	mock.Mock
+}
+
+func (h *mockHandler) Handle(ctx context.Context, a fleetapi.Action, acker acker.Acker) error {
+	args := h.Called(ctx, a, acker)
+	return args.Error(0)
+}
@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package memorypressure
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupRoot = "/sys/fs/cgroup"
+	procCgroup = "/proc/self/cgroup"
+
+	// unlimitedV1 is the lowest limit reported by cgroups v1 for a cgroup without memory limit.
+	unlimitedV1 = 1 << 62
+)
+
+func readCgroupUsage() (Usage, bool, error) {
+	return cgroupReader{root: cgroupRoot, procCgroup: procCgroup}.read()
+}
+
+type cgroupReader struct {
+	root       string
+	procCgroup string
+}
+
+// read reads the usage of the cgroup of the agent, the usage excludes the inactive page cache that is
+// reclaimed before the cgroup runs out of memory. It returns false when the cgroup has no memory limit.
+func (r cgroupReader) read() (Usage, bool, error) {
+	content, err := os.ReadFile(r.procCgroup)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Usage{}, false, nil
+		}
+		return Usage{}, false, fmt.Errorf("failed to read %s: %w", r.procCgroup, err)
+	}
+
+	var v2Path, v1Path string
+	var v1 bool
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			v2Path = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			if controller == "memory" {
+				v1, v1Path = true, parts[2]
+			}
+		}
+	}
+
+	if v1 {
+		return r.readV1(v1Path)
+	}
+	if v2Path != "" {
+		return r.readV2(v2Path)
+	}
+	return Usage{}, false, nil
+}
+
+func (r cgroupReader) readV2(path string) (Usage, bool, error) {
+	dir := r.dir(r.root, path)
+	limit, err := readValue(filepath.Join(dir, "memory.max"))
+	if err != nil || limit == 0 {
+		// no memory controller, or no limit
+		return Usage{}, false, nil
+	}
+	current, err := readValue(filepath.Join(dir, "memory.current"))
+	if err != nil {
+		return Usage{}, false, err
+	}
+	inactive, _ := readStat(filepath.Join(dir, "memory.stat"), "inactive_file")
+	return Usage{Used: workingSet(current, inactive), Limit: limit, Source: SourceCgroup}, true, nil
+}
+
+func (r cgroupReader) readV1(path string) (Usage, bool, error) {
+	dir := r.dir(filepath.Join(r.root, "memory"), path)
+	limit, err := readValue(filepath.Join(dir, "memory.limit_in_bytes"))
+	if err != nil || limit == 0 || limit >= unlimitedV1 {
+		return Usage{}, false, nil
+	}
+	current, err := readValue(filepath.Join(dir, "memory.usage_in_bytes"))
+	if err != nil {
+		return Usage{}, false, err
+	}
+	inactive, _ := readStat(filepath.Join(dir, "memory.stat"), "total_inactive_file")
+	return Usage{Used: workingSet(current, inactive), Limit: limit, Source: SourceCgroup}, true, nil
+}
+
+// dir returns the directory of the cgroup path under the hierarchy root. Within a container the path is often
+// the path of the container on the host, the cgroup of the container is then mounted at the root.
+func (r cgroupReader) dir(root, path string) string {
+	dir := filepath.Join(root, path)
+	if _, err := os.Stat(dir); err != nil {
+		return root
+	}
+	return dir
+}
+
+func workingSet(current, inactive uint64) uint64 {
+	if inactive > current {
+		return 0
+	}
+	return current - inactive
+}
+
+// readValue reads a single value file, "max" is returned as 0.
+func readValue(file string) (uint64, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(content))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// readStat reads the value of the key in a flat keyed file.
+func readStat(file, key string) (uint64, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, file)
+}
@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package memorypressure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
+
+func TestCgroupReader(t *testing.T) {
+	t.Run("v2 with limit", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"proc": "0::/system.slice/elastic-agent.service\n",
+			"system.slice/elastic-agent.service/memory.max":     "1000\n",
+			"system.slice/elastic-agent.service/memory.current": "900\n",
+			"system.slice/elastic-agent.service/memory.stat":    "anon 500\ninactive_file 200\n",
+		})
+		usage, ok, err := cgroupReader{root: dir, procCgroup: filepath.Join(dir, "proc")}.read()
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, Usage{Used: 700, Limit: 1000, Source: SourceCgroup}, usage)
+	})
+
+	t.Run("v2 without limit", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"proc":           "0::/\n",
+			"memory.max":     "max\n",
+			"memory.current": "900\n",
+		})
+		_, ok, err := cgroupReader{root: dir, procCgroup: filepath.Join(dir, "proc")}.read()
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("v1 within a container", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"proc":                         "12:cpu,cpuacct:/docker/abc\n4:memory:/docker/abc\n",
+			"memory/memory.limit_in_bytes": "2000\n",
+			"memory/memory.usage_in_bytes": "1500\n",
+			"memory/memory.stat":           "cache 800\ntotal_inactive_file 500\n",
+		})
+		usage, ok, err := cgroupReader{root: dir, procCgroup: filepath.Join(dir, "proc")}.read()
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.Equal(t, Usage{Used: 1000, Limit: 2000, Source: SourceCgroup}, usage)
+	})
+
+	t.Run("v1 without limit", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"proc":                         "4:memory:/\n",
+			"memory/memory.limit_in_bytes": "9223372036854771712\n",
+			"memory/memory.usage_in_bytes": "1500\n",
+		})
+		_, ok, err := cgroupReader{root: dir, procCgroup: filepath.Join(dir, "proc")}.read()
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,12 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux
+
+package memorypressure
+
+// readCgroupUsage returns false, cgroups only exist on Linux.
+func readCgroupUsage() (Usage, bool, error) {
+	return Usage{}, false, nil
+}
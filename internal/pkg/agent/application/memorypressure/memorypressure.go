@@ -0,0 +1,159 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package memorypressure detects when the agent is about to run out of memory.
+//
+// The memory used is periodically compared to the memory limit of the cgroup of the agent, or to the memory
+// of the host when the agent has no limit. The agent is under pressure once the ratio reaches the threshold and
+// recovers once it falls below the recovery threshold. Under pressure the memory of the agent itself is capped
+// to its footprint when the pressure was detected: the garbage collector runs as often as needed to stay below
+// it instead of growing the heap, and the freed memory is returned to the OS at every check.
+package memorypressure
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	"github.com/elastic/go-sysinfo"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// SourceCgroup is the source of the usage read from the cgroup of the agent.
+	SourceCgroup = "cgroup"
+	// SourceHost is the source of the usage read from the host.
+	SourceHost = "host"
+)
+
+// Usage is the memory used against the memory limit.
+type Usage struct {
+	Used   uint64
+	Limit  uint64
+	Source string
+}
+
+// Ratio returns the ratio of the limit that is used.
+func (u Usage) Ratio() float64 {
+	if u.Limit == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Limit)
+}
+
+// String returns a readable description of the usage.
+func (u Usage) String() string {
+	return fmt.Sprintf("%.0f%% of the %s memory limit used", u.Ratio()*100, u.Source)
+}
+
+// Monitor checks the memory usage and reports the changes of pressure.
+type Monitor struct {
+	log *logger.Logger
+	cfg *configuration.MemoryPressureConfig
+	// onChange is called when the agent enters or leaves memory pressure.
+	onChange func(pressure bool, usage Usage)
+
+	read     func() (Usage, error)
+	pressure bool
+	// previousLimit is the memory limit of the Go runtime restored once the pressure recovers.
+	previousLimit int64
+}
+
+// New creates a monitor calling onChange when the agent enters or leaves memory pressure.
+func New(log *logger.Logger, cfg *configuration.MemoryPressureConfig, onChange func(pressure bool, usage Usage)) (*Monitor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("invalid memory pressure interval %s", cfg.Interval)
+	}
+	return &Monitor{
+		log:      log,
+		cfg:      cfg,
+		onChange: onChange,
+		read:     readUsage,
+	}, nil
+}
+
+// Run checks the memory usage until the context is done.
+func (m *Monitor) Run(ctx context.Context) {
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	for {
+		m.check()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Monitor) check() {
+	usage, err := m.read()
+	if err != nil {
+		m.log.Debugf("failed to read memory usage: %v", err)
+		return
+	}
+
+	ratio := usage.Ratio()
+	switch {
+	case !m.pressure && ratio >= m.cfg.Threshold:
+		m.pressure = true
+		m.log.Warnf("memory pressure detected, %s", usage)
+		m.limitMemory()
+		m.onChange(true, usage)
+	case m.pressure && ratio < m.cfg.RecoveryThreshold:
+		m.pressure = false
+		m.log.Infof("memory pressure recovered, %s", usage)
+		debug.SetMemoryLimit(m.previousLimit)
+		m.onChange(false, usage)
+	}
+	if m.pressure {
+		// return the memory freed by the garbage collector to the OS, leaving room to the components
+		debug.FreeOSMemory()
+	}
+}
+
+// limitMemory caps the memory of the Go runtime to its current footprint.
+func (m *Monitor) limitMemory() {
+	sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		m.previousLimit = debug.SetMemoryLimit(-1)
+		return
+	}
+	footprint := sample[0].Value.Uint64()
+	m.previousLimit = debug.SetMemoryLimit(int64(footprint)) //nolint:gosec // the footprint fits in an int64
+	m.log.Infof("limiting the memory of the agent to %d bytes until the pressure recovers", footprint)
+}
+
+// readUsage reads the usage of the cgroup of the agent, or of the host when the agent has no memory limit.
+func readUsage() (Usage, error) {
+	usage, ok, err := readCgroupUsage()
+	if err != nil {
+		return Usage{}, err
+	}
+	if ok {
+		return usage, nil
+	}
+
+	host, err := sysinfo.Host()
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to get host information: %w", err)
+	}
+	mem, err := host.Memory()
+	if err != nil {
+		return Usage{}, fmt.Errorf("failed to get host memory: %w", err)
+	}
+	used := mem.Total - mem.Available
+	if mem.Available > mem.Total {
+		used = 0
+	}
+	return Usage{Used: used, Limit: mem.Total, Source: SourceHost}, nil
+}
@@ -0,0 +1,84 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package memorypressure
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestMonitor(t *testing.T) {
+	log, _ := loggertest.New("memory_pressure")
+
+	var changes []bool
+	m, err := New(log, configuration.DefaultMemoryPressureConfig(), func(pressure bool, _ Usage) {
+		changes = append(changes, pressure)
+	})
+	require.NoError(t, err)
+
+	var used uint64
+	m.read = func() (Usage, error) {
+		return Usage{Used: used, Limit: 100, Source: SourceCgroup}, nil
+	}
+
+	// pressure is entered at the threshold and left below the recovery threshold
+	for _, u := range []uint64{50, 89, 90, 95, 85, 80, 79, 85, 92} {
+		used = u
+		m.check()
+	}
+	assert.Equal(t, []bool{true, false, true}, changes)
+
+	used = 10
+	m.check()
+}
+
+func TestMonitorLimitsMemory(t *testing.T) {
+	log, _ := loggertest.New("memory_pressure")
+	m, err := New(log, configuration.DefaultMemoryPressureConfig(), func(bool, Usage) {})
+	require.NoError(t, err)
+
+	var used uint64
+	m.read = func() (Usage, error) {
+		return Usage{Used: used, Limit: 100, Source: SourceCgroup}, nil
+	}
+
+	previous := debug.SetMemoryLimit(-1)
+	used = 95
+	m.check()
+	assert.Less(t, debug.SetMemoryLimit(-1), previous, "the memory of the agent is limited under pressure")
+
+	used = 50
+	m.check()
+	assert.Equal(t, previous, debug.SetMemoryLimit(-1), "the memory limit is restored once the pressure recovers")
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	log, _ := loggertest.New("memory_pressure")
+	for name, mutate := range map[string]func(*configuration.MemoryPressureConfig){
+		"threshold above 1":                  func(cfg *configuration.MemoryPressureConfig) { cfg.Threshold = 1.5 },
+		"recovery threshold above threshold": func(cfg *configuration.MemoryPressureConfig) { cfg.RecoveryThreshold = 0.95 },
+		"no interval":                        func(cfg *configuration.MemoryPressureConfig) { cfg.Interval = 0 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := configuration.DefaultMemoryPressureConfig()
+			mutate(cfg)
+			_, err := New(log, cfg, func(bool, Usage) {})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	usage := Usage{Used: 75, Limit: 100, Source: SourceHost}
+	assert.InDelta(t, 0.75, usage.Ratio(), 0.001)
+	assert.Equal(t, "75% of the host memory limit used", usage.String())
+	assert.Zero(t, Usage{Used: 10}.Ratio())
+}
@@ -0,0 +1,307 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	aConfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
+	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/version"
+)
+
+const (
+	otlpScopeName = "github.com/elastic/elastic-agent/monitoring"
+	// otlpAgentMetricsPrefix prefixes the metrics of the agent, the metrics of the components are
+	// prefixed with their binary name.
+	otlpAgentMetricsPrefix = "elastic_agent"
+	// otlpComponentScrapeUpMetric reports if the stats of a component were successfully fetched.
+	otlpComponentScrapeUpMetric = "elastic_agent.component.scrape_up"
+	// otlpComponentRestartsMetric counts the unexpected exits of the process of a component.
+	otlpComponentRestartsMetric = "elastic_agent.component.restarts_total"
+	otlpHTTPMetricsPath         = "/v1/metrics"
+)
+
+// OTLPExporter periodically exports the metrics of the agent and of its components to an
+// OpenTelemetry collector, alongside or instead of the metrics collected by the monitoring
+// components. It is configured under agent.monitoring.otlp and follows the policy changes.
+type OTLPExporter struct {
+	log      *logger.Logger
+	ns       *monitoring.Namespace
+	coord    CoordinatorState
+	fetch    componentStatsFetcher
+	restarts func() map[string]uint64
+	resource *resource.Resource
+
+	newExporter func(ctx context.Context, cfg monitoringCfg.OTLPConfig) (sdkmetric.Exporter, error)
+
+	mx      sync.Mutex
+	cfg     monitoringCfg.OTLPConfig
+	running bool
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewOTLPExporter creates an exporter of the metrics of the stats namespace of the agent and of the
+// components reported by the coordinator.
+func NewOTLPExporter(log *logger.Logger, ns *monitoring.Namespace, coord CoordinatorState, agentID string, cfg *monitoringCfg.MonitoringConfig) *OTLPExporter {
+	e := &OTLPExporter{
+		log:      log,
+		ns:       ns,
+		coord:    coord,
+		fetch:    fetchComponentStats,
+		restarts: agentmetrics.ComponentRestarts,
+		resource: resource.NewSchemaless(
+			attribute.String("service.name", agentName),
+			attribute.String("service.version", version.GetDefaultVersion()),
+			attribute.String("service.instance.id", agentID),
+		),
+		newExporter: newOTLPMetricExporter,
+	}
+	if cfg != nil && cfg.Enabled {
+		e.cfg = cfg.OTLP
+	}
+	return e
+}
+
+// Start starts the export when enabled by the initial configuration.
+func (e *OTLPExporter) Start() {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.startLocked()
+}
+
+// Stop stops the export.
+func (e *OTLPExporter) Stop() {
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	e.stopLocked()
+}
+
+// Reload applies the OTLP export settings of the policy, the export is restarted when they change.
+func (e *OTLPExporter) Reload(rawConfig *aConfig.Config) error {
+	newConfig := configuration.DefaultConfiguration()
+	if err := rawConfig.UnpackTo(&newConfig); err != nil {
+		return errors.New(err, "failed to unpack monitoring config during reload")
+	}
+	var cfg monitoringCfg.OTLPConfig
+	if mcfg := newConfig.Settings.MonitoringConfig; mcfg != nil && mcfg.Enabled {
+		cfg = mcfg.OTLP
+	}
+
+	e.mx.Lock()
+	defer e.mx.Unlock()
+	if reflect.DeepEqual(cfg, e.cfg) {
+		return nil
+	}
+	e.stopLocked()
+	e.cfg = cfg
+	e.startLocked()
+	return nil
+}
+
+func (e *OTLPExporter) startLocked() {
+	if e.running || !e.cfg.Enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done = make(chan struct{})
+	e.running = true
+	e.log.Infof("exporting monitoring metrics with OTLP to %s using %s every %s", e.cfg.Endpoint, e.cfg.Protocol, e.cfg.Interval)
+	go e.run(ctx, e.cfg, e.done)
+}
+
+func (e *OTLPExporter) stopLocked() {
+	if !e.running {
+		return
+	}
+	e.cancel()
+	<-e.done
+	e.running = false
+}
+
+func (e *OTLPExporter) run(ctx context.Context, cfg monitoringCfg.OTLPConfig, done chan struct{}) {
+	defer close(done)
+
+	exporter, err := e.newExporter(ctx, cfg)
+	if err != nil {
+		e.log.Errorf("failed to create the OTLP metrics exporter: %v", err)
+		return
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		defer cancel()
+		if err := exporter.Shutdown(shutdownCtx); err != nil {
+			e.log.Debugf("failed to shut down the OTLP metrics exporter: %v", err)
+		}
+	}()
+
+	t := time.NewTicker(cfg.Interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		metrics := e.collect(ctx)
+		exportCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		err := exporter.Export(exportCtx, metrics)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			e.log.Warnf("failed to export monitoring metrics with OTLP: %v", err)
+		}
+	}
+}
+
+// collect gathers the metrics of the agent and of its Beats components as gauges. The metrics of the
+// components are attributed with the ID and the binary of the component.
+func (e *OTLPExporter) collect(ctx context.Context) *metricdata.ResourceMetrics {
+	now := time.Now()
+	gauges := make(map[string][]metricdata.DataPoint[float64])
+	emit := func(attrs attribute.Set) func(name string, value float64) {
+		return func(name string, value float64) {
+			gauges[name] = append(gauges[name], metricdata.DataPoint[float64]{Attributes: attrs, Time: now, Value: value})
+		}
+	}
+	join := func(prefix, key string) string {
+		return prefix + "." + key
+	}
+
+	if e.ns != nil {
+		snapshot := monitoring.CollectStructSnapshot(e.ns.GetRegistry(), monitoring.Full, false)
+		walkStats(otlpAgentMetricsPrefix, snapshot, join, emit(*attribute.EmptySet()))
+	}
+
+	for id, restarts := range e.restarts() {
+		attrs := attribute.NewSet(attribute.String("component.id", id))
+		emit(attrs)(otlpComponentRestartsMetric, float64(restarts))
+	}
+
+	if e.coord != nil {
+		state := e.coord.State()
+		for iter := range state.Components {
+			comp := &state.Components[iter].Component
+			binaryName := comp.BinaryName()
+			if !isSupportedBeatsBinary(binaryName) {
+				continue
+			}
+			attrs := attribute.NewSet(
+				attribute.String("component.id", comp.ID),
+				attribute.String("component.binary", binaryName),
+			)
+			up := 1.0
+			data, err := e.fetch(ctx, comp.ID)
+			if err == nil {
+				err = walkComponentStats(binaryName, data, join, emit(attrs))
+			}
+			if err != nil {
+				e.log.Debugf("failed to fetch the stats of component %s: %v", comp.ID, err)
+				up = 0
+			}
+			emit(attrs)(otlpComponentScrapeUpMetric, up)
+		}
+	}
+
+	names := make([]string, 0, len(gauges))
+	for name := range gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	metrics := make([]metricdata.Metrics, 0, len(names))
+	for _, name := range names {
+		metrics = append(metrics, metricdata.Metrics{
+			Name: name,
+			Data: metricdata.Gauge[float64]{DataPoints: gauges[name]},
+		})
+	}
+
+	return &metricdata.ResourceMetrics{
+		Resource: e.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope:   instrumentation.Scope{Name: otlpScopeName, Version: version.GetDefaultVersion()},
+			Metrics: metrics,
+		}},
+	}
+}
+
+func walkComponentStats(prefix string, data []byte, join func(prefix, key string) string, emit func(name string, value float64)) error {
+	var stats map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&stats); err != nil {
+		return fmt.Errorf("failed to parse component stats: %w", err)
+	}
+	walkStats(prefix, stats, join, emit)
+	return nil
+}
+
+// newOTLPMetricExporter creates the OTLP exporter of the configured protocol. The endpoint is either
+// host:port or a URL, the path of an HTTP endpoint defaults to /v1/metrics.
+func newOTLPMetricExporter(ctx context.Context, cfg monitoringCfg.OTLPConfig) (sdkmetric.Exporter, error) {
+	isURL := strings.Contains(cfg.Endpoint, "://")
+	switch cfg.Protocol {
+	case monitoringCfg.OTLPProtocolHTTP:
+		opts := []otlpmetrichttp.Option{otlpmetrichttp.WithTimeout(cfg.Timeout)}
+		if isURL {
+			u, err := url.Parse(cfg.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid OTLP endpoint %q: %w", cfg.Endpoint, err)
+			}
+			opts = append(opts, otlpmetrichttp.WithEndpointURL(cfg.Endpoint))
+			if u.Path == "" || u.Path == "/" {
+				opts = append(opts, otlpmetrichttp.WithURLPath(otlpHTTPMetricsPath))
+			}
+		} else {
+			opts = append(opts, otlpmetrichttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetrichttp.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetrichttp.WithHeaders(cfg.Headers))
+		}
+		return otlpmetrichttp.New(ctx, opts...)
+	default:
+		opts := []otlpmetricgrpc.Option{otlpmetricgrpc.WithTimeout(cfg.Timeout)}
+		if isURL {
+			opts = append(opts, otlpmetricgrpc.WithEndpointURL(cfg.Endpoint))
+		} else {
+			opts = append(opts, otlpmetricgrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlpmetricgrpc.WithInsecure())
+		}
+		if len(cfg.Headers) > 0 {
+			opts = append(opts, otlpmetricgrpc.WithHeaders(cfg.Headers))
+		}
+		return otlpmetricgrpc.New(ctx, opts...)
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestOTLPExporterCollect(t *testing.T) {
+	ns := monitoring.GetNamespace("otlp_test_stats")
+	monitoring.NewInt(ns.GetRegistry(), "system.load").Set(3)
+
+	coord := mockCoordinator{
+		isUp: true,
+		state: coordinator.State{
+			Components: []runtime.ComponentComponentState{
+				{Component: component.Component{ID: "filestream-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "filebeat"}}},
+				{Component: component.Component{ID: "system/metrics-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "metricbeat"}}},
+				{Component: component.Component{ID: "endpoint-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "endpoint-security"}}},
+			},
+		},
+	}
+	log, _ := loggertest.New("otlp")
+	e := NewOTLPExporter(log, ns, coord, "agent-id", nil)
+	e.restarts = func() map[string]uint64 {
+		return map[string]uint64{"filestream-default": 2}
+	}
+	e.fetch = func(_ context.Context, componentID string) ([]byte, error) {
+		switch componentID {
+		case "filestream-default":
+			return []byte(`{"libbeat":{"output":{"events":{"acked":10}}},"beat":{"info":{"name":"filebeat"}}}`), nil
+		case "system/metrics-default":
+			return nil, errors.New("connection refused")
+		}
+		t.Fatalf("unexpected fetch for component %s", componentID)
+		return nil, nil
+	}
+
+	rm := e.collect(context.Background())
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	serviceName, ok := rm.Resource.Set().Value("service.name")
+	require.True(t, ok)
+	assert.Equal(t, "elastic-agent", serviceName.AsString())
+	instanceID, ok := rm.Resource.Set().Value("service.instance.id")
+	require.True(t, ok)
+	assert.Equal(t, "agent-id", instanceID.AsString())
+
+	type point struct {
+		component string
+		value     float64
+	}
+	got := make(map[string][]point)
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		gauge, ok := m.Data.(metricdata.Gauge[float64])
+		require.True(t, ok, "metric %s should be a gauge", m.Name)
+		for _, dp := range gauge.DataPoints {
+			id, _ := dp.Attributes.Value(attribute.Key("component.id"))
+			got[m.Name] = append(got[m.Name], point{component: id.AsString(), value: dp.Value})
+		}
+	}
+	assert.Equal(t, map[string][]point{
+		"elastic_agent.system.load":              {{value: 3}},
+		"elastic_agent.component.scrape_up":      {{component: "filestream-default", value: 1}, {component: "system/metrics-default", value: 0}},
+		"elastic_agent.component.restarts_total": {{component: "filestream-default", value: 2}},
+		"filebeat.libbeat.output.events.acked":   {{component: "filestream-default", value: 10}},
+	}, got)
+}
+
+func TestOTLPExporterReload(t *testing.T) {
+	requests := make(chan *http.Request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requests <- r:
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ns := monitoring.GetNamespace("otlp_test_reload")
+	monitoring.NewInt(ns.GetRegistry(), "events").Set(1)
+	log, _ := loggertest.New("otlp")
+	e := NewOTLPExporter(log, ns, mockCoordinator{}, "agent-id", nil)
+	e.Start()
+	defer e.Stop()
+
+	require.NoError(t, e.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.monitoring": map[string]interface{}{
+			"enabled": true,
+			"otlp": map[string]interface{}{
+				"enabled":  true,
+				"endpoint": srv.URL,
+				"protocol": "http",
+				"headers":  map[string]interface{}{"Authorization": "Bearer token"},
+				"interval": "50ms",
+			},
+		},
+	})))
+
+	select {
+	case r := <-requests:
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the metrics were not exported")
+	}
+
+	// the export stops once disabled by the policy
+	require.NoError(t, e.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.monitoring.enabled": true,
+	})))
+	for len(requests) > 0 {
+		<-requests
+	}
+	select {
+	case <-requests:
+		t.Fatal("the metrics should not be exported once disabled")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	assert.Error(t, e.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.monitoring.otlp": map[string]interface{}{"enabled": true, "protocol": "udp", "endpoint": "localhost:4317"},
+	})), "an unknown protocol should be rejected")
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -135,7 +136,29 @@ func redirectToPath(w http.ResponseWriter, r *http.Request, id, path string) err
 }
 
 func GetProcessMetrics(ctx context.Context, endpoint, path string) ([]byte, int, error) {
-	hostData, err := parseURL(endpoint, "http", "", "", path, "")
+	return getProcessPath(ctx, endpoint, path, "", timeout)
+}
+
+// GetProcessProfile returns the named pprof profile, e.g. heap or goroutine, of the process serving the
+// monitoring endpoint. A CPU profile is collected over the given duration when name is "profile". The
+// process must have pprof enabled on its monitoring endpoint.
+func GetProcessProfile(ctx context.Context, endpoint, name string, duration time.Duration) ([]byte, error) {
+	query := ""
+	if duration > 0 {
+		query = "seconds=" + strconv.Itoa(int(duration.Seconds()))
+	}
+	profile, statusCode, err := getProcessPath(ctx, endpoint, "debug/pprof/"+name, query, timeout+duration)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, errorWithStatus(statusCode, fmt.Errorf("fetching %s profile failed with status %d: %s", name, statusCode, strings.TrimSpace(string(profile))))
+	}
+	return profile, nil
+}
+
+func getProcessPath(ctx context.Context, endpoint, path, query string, timeout time.Duration) ([]byte, int, error) {
+	hostData, err := parseURL(endpoint, "http", "", "", path, query)
 	if err != nil {
 		return nil, 0, errorWithStatus(http.StatusInternalServerError, err)
 	}
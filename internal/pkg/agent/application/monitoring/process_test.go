@@ -0,0 +1,44 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetProcessProfile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/debug/pprof/heap":
+			_, _ = w.Write([]byte("heap"))
+		case "/debug/pprof/profile":
+			_, _ = w.Write([]byte("cpu " + r.URL.Query().Get("seconds")))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	profile, err := GetProcessProfile(context.Background(), srv.URL, "heap", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "heap", string(profile))
+
+	profile, err = GetProcessProfile(context.Background(), srv.URL, "profile", 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "cpu 5", string(profile))
+
+	// pprof is not enabled on the endpoint
+	_, err = GetProcessProfile(context.Background(), srv.URL, "goroutine", 0)
+	var statusErr *statusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.Status())
+}
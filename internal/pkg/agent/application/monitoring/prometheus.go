@@ -0,0 +1,217 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+const (
+	prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+	// componentScrapeUpMetric reports if the stats of a component were successfully fetched.
+	componentScrapeUpMetric = "elastic_agent_component_scrape_up"
+	// componentRestartsMetric counts the unexpected exits of the process of a component.
+	componentRestartsMetric = "elastic_agent_component_restarts_total"
+	// agentMetricsPrefix prefixes the metrics of the stats namespace of the agent.
+	agentMetricsPrefix = "elastic_agent"
+)
+
+// componentStatsFetcher fetches the JSON stats document of a component.
+type componentStatsFetcher func(ctx context.Context, componentID string) ([]byte, error)
+
+// fetchComponentStats fetches the stats of a component from its monitoring endpoint.
+func fetchComponentStats(ctx context.Context, componentID string) ([]byte, error) {
+	endpoint := PrefixedEndpoint(BeatsMonitoringEndpoint(componentID))
+	metricsBytes, statusCode, err := GetProcessMetrics(ctx, endpoint, "stats")
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching stats for component %s returned status %d", componentID, statusCode)
+	}
+	return metricsBytes, nil
+}
+
+// componentsMetricsHandler merges the stats of every running Beats component into a single Prometheus
+// exposition. Metrics are prefixed with the binary name of the component and labeled with its ID.
+func componentsMetricsHandler(coord CoordinatorState, fetch componentStatsFetcher) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", prometheusContentType)
+
+		var families promFamilies
+		if coord != nil {
+			state := coord.State()
+			for iter := range state.Components {
+				comp := &state.Components[iter].Component
+				binaryName := comp.BinaryName()
+				if !isSupportedBeatsBinary(binaryName) {
+					continue
+				}
+				labels := promLabels{{"component_id", comp.ID}, {"component_binary", binaryName}}
+				up := 1.0
+				data, err := fetch(r.Context(), comp.ID)
+				if err == nil {
+					err = families.addStats(promMetricName(binaryName), labels, data)
+				}
+				if err != nil {
+					up = 0
+				}
+				families.add(componentScrapeUpMetric, labels, up)
+			}
+		}
+
+		_, err := w.Write(families.render())
+		return err
+	}
+}
+
+// agentMetricsHandler exposes the metrics of the agent in the Prometheus format: the metrics of its stats
+// namespace, prefixed with elastic_agent, and the restarts of the components labeled with their ID.
+func agentMetricsHandler(ns *monitoring.Namespace, componentRestarts func() map[string]uint64) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", prometheusContentType)
+
+		var families promFamilies
+		snapshot := monitoring.CollectStructSnapshot(ns.GetRegistry(), monitoring.Full, false)
+		families.addValue(agentMetricsPrefix, nil, snapshot)
+
+		restarts := componentRestarts()
+		ids := make([]string, 0, len(restarts))
+		for id := range restarts {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			families.add(componentRestartsMetric, promLabels{{"component_id", id}}, float64(restarts[id]))
+		}
+
+		_, err := w.Write(families.render())
+		return err
+	}
+}
+
+type promLabel struct {
+	name  string
+	value string
+}
+
+type promLabels []promLabel
+
+func (l promLabels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(l))
+	for _, label := range l {
+		parts = append(parts, fmt.Sprintf("%s=%q", label.name, label.value))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+type promSample struct {
+	labels promLabels
+	value  float64
+}
+
+// promFamilies groups samples by metric name, as the exposition format requires all samples of
+// a metric to be written together.
+type promFamilies map[string][]promSample
+
+func (f *promFamilies) add(name string, labels promLabels, value float64) {
+	if *f == nil {
+		*f = promFamilies{}
+	}
+	(*f)[name] = append((*f)[name], promSample{labels: labels, value: value})
+}
+
+// addStats flattens the JSON stats document into samples. Numeric and boolean leaves become
+// samples, all other values are ignored.
+func (f *promFamilies) addStats(prefix string, labels promLabels, data []byte) error {
+	var stats map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&stats); err != nil {
+		return fmt.Errorf("failed to parse component stats: %w", err)
+	}
+	f.addValue(prefix, labels, stats)
+	return nil
+}
+
+func (f *promFamilies) addValue(name string, labels promLabels, value interface{}) {
+	walkStats(name, value, func(prefix, key string) string {
+		return prefix + "_" + promMetricName(key)
+	}, func(name string, value float64) {
+		f.add(name, labels, value)
+	})
+}
+
+// walkStats calls emit for every numeric and boolean leaf of a stats document, booleans are 1 or 0.
+// The name of a leaf joins the names of its parents, all other values are ignored.
+func walkStats(name string, value interface{}, join func(prefix, key string) string, emit func(name string, value float64)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			walkStats(join(name, key), child, join, emit)
+		}
+	case json.Number:
+		n, err := strconv.ParseFloat(v.String(), 64)
+		if err == nil {
+			emit(name, n)
+		}
+	case float64:
+		emit(name, v)
+	case int64:
+		emit(name, float64(v))
+	case uint64:
+		emit(name, float64(v))
+	case int:
+		emit(name, float64(v))
+	case bool:
+		if v {
+			emit(name, 1)
+		} else {
+			emit(name, 0)
+		}
+	}
+}
+
+func (f promFamilies) render() []byte {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "# TYPE %s untyped\n", name)
+		for _, sample := range f[name] {
+			fmt.Fprintf(&buf, "%s%s %s\n", name, sample.labels, strconv.FormatFloat(sample.value, 'g', -1, 64))
+		}
+	}
+	return buf.Bytes()
+}
+
+// promMetricName replaces all characters that are not valid in a Prometheus metric name.
+func promMetricName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == ':':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
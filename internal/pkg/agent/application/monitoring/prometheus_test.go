@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+func TestComponentsMetricsHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	coord := mockCoordinator{
+		isUp: true,
+		state: coordinator.State{
+			Components: []runtime.ComponentComponentState{
+				{Component: component.Component{ID: "filestream-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "filebeat"}}},
+				{Component: component.Component{ID: "system/metrics-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "metricbeat"}}},
+				{Component: component.Component{ID: "endpoint-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "endpoint-security"}}},
+			},
+		},
+	}
+	fetch := func(_ context.Context, componentID string) ([]byte, error) {
+		switch componentID {
+		case "filestream-default":
+			return []byte(`{"libbeat":{"output":{"events":{"acked":10}},"config":{"running":true}},"beat":{"info":{"name":"filebeat"}}}`), nil
+		case "system/metrics-default":
+			return nil, errors.New("connection refused")
+		}
+		t.Fatalf("unexpected fetch for component %s", componentID)
+		return nil, nil
+	}
+
+	testSrv := httptest.NewServer(createHandler(componentsMetricsHandler(coord, fetch)))
+	defer testSrv.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, testSrv.URL, nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, prometheusContentType, res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	expected := `# TYPE elastic_agent_component_scrape_up untyped
+elastic_agent_component_scrape_up{component_id="filestream-default",component_binary="filebeat"} 1
+elastic_agent_component_scrape_up{component_id="system/metrics-default",component_binary="metricbeat"} 0
+# TYPE filebeat_libbeat_config_running untyped
+filebeat_libbeat_config_running{component_id="filestream-default",component_binary="filebeat"} 1
+# TYPE filebeat_libbeat_output_events_acked untyped
+filebeat_libbeat_output_events_acked{component_id="filestream-default",component_binary="filebeat"} 10
+`
+	assert.Equal(t, expected, string(body))
+}
+
+func TestAgentMetricsHandler(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	ns := monitoring.GetNamespace("prometheus_test_agent")
+	ns.SetRegistry(reg)
+	m := agentmetrics.New(reg)
+	m.ObserveCheckin(1500*time.Millisecond, nil)
+	m.ObservePolicyRender(20*time.Millisecond, errors.New("rendering inputs failed"))
+	monitoring.NewString(reg, "beat.info.name").Set("elastic-agent")
+	restarts := func() map[string]uint64 {
+		return map[string]uint64{"system/metrics-default": 1, "filestream-default": 3}
+	}
+
+	testSrv := httptest.NewServer(createHandler(agentMetricsHandler(ns, restarts)))
+	defer testSrv.Close()
+
+	res, err := http.Get(testSrv.URL) //nolint:noctx // test request
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, prometheusContentType, res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	expected := `# TYPE elastic_agent_component_restarts_total untyped
+elastic_agent_component_restarts_total{component_id="filestream-default"} 3
+elastic_agent_component_restarts_total{component_id="system/metrics-default"} 1
+# TYPE elastic_agent_download_bytes_total untyped
+elastic_agent_download_bytes_total 0
+# TYPE elastic_agent_download_failures_total untyped
+elastic_agent_download_failures_total 0
+# TYPE elastic_agent_download_last_duration_seconds untyped
+elastic_agent_download_last_duration_seconds 0
+# TYPE elastic_agent_download_last_status_code untyped
+elastic_agent_download_last_status_code 0
+# TYPE elastic_agent_download_requests_total untyped
+elastic_agent_download_requests_total 0
+# TYPE elastic_agent_download_retryable_failures_total untyped
+elastic_agent_download_retryable_failures_total 0
+# TYPE elastic_agent_fleet_checkin_duration_seconds_total untyped
+elastic_agent_fleet_checkin_duration_seconds_total 1.5
+# TYPE elastic_agent_fleet_checkin_failures_total untyped
+elastic_agent_fleet_checkin_failures_total 0
+# TYPE elastic_agent_fleet_checkin_last_duration_seconds untyped
+elastic_agent_fleet_checkin_last_duration_seconds 1.5
+# TYPE elastic_agent_fleet_checkin_requests_total untyped
+elastic_agent_fleet_checkin_requests_total 1
+# TYPE elastic_agent_policy_render_duration_seconds_total untyped
+elastic_agent_policy_render_duration_seconds_total 0.02
+# TYPE elastic_agent_policy_render_failures_total untyped
+elastic_agent_policy_render_failures_total 1
+# TYPE elastic_agent_policy_render_last_duration_seconds untyped
+elastic_agent_policy_render_last_duration_seconds 0.02
+# TYPE elastic_agent_policy_render_total untyped
+elastic_agent_policy_render_total 1
+# TYPE elastic_agent_queue_ack_retries untyped
+elastic_agent_queue_ack_retries 0
+# TYPE elastic_agent_queue_actions untyped
+elastic_agent_queue_actions 0
+`
+	assert.Equal(t, expected, string(body))
+}
+
+func TestPromMetricName(t *testing.T) {
+	assert.Equal(t, "apm_server_stats_cpu_total_ms", promMetricName("apm-server_stats.cpu.total/ms"))
+}
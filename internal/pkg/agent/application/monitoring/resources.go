@@ -0,0 +1,116 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// handleCounter returns the number of open file descriptors, or handles on Windows, of the process.
+type handleCounter func(pid int) (int, error)
+
+// diskUsageReader returns the usage of the filesystem holding the path.
+type diskUsageReader func(path string) (diskUsage, error)
+
+type resourceComponent struct {
+	ID     string `json:"id"`
+	Binary string `json:"binary"`
+}
+
+type resourceProcess struct {
+	PID int `json:"pid"`
+}
+
+type resourceHandles struct {
+	Open int `json:"open"`
+}
+
+type diskUsage struct {
+	Path    string  `json:"path"`
+	Total   uint64  `json:"total"`
+	Free    uint64  `json:"free"`
+	Used    uint64  `json:"used"`
+	UsedPct float64 `json:"used_pct"`
+}
+
+// resourceUsage is the resource usage of the agent or one of its components, the disk usage is only
+// reported for the agent as it is the usage of the data directory. A component without a process has
+// no usage and the reason in Error instead.
+type resourceUsage struct {
+	Component resourceComponent `json:"component"`
+	Process   *resourceProcess  `json:"process,omitempty"`
+	Handles   *resourceHandles  `json:"handles,omitempty"`
+	Disk      *diskUsage        `json:"disk,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// resourcesHandler reports the disk usage of the data directory and the open handles of the agent
+// and of each of its components, as an array with one entry per process. The components not reporting
+// their process are listed with an error, so they are not mistaken for components using no resources.
+func resourcesHandler(coord CoordinatorState, dataPath string, handles handleCounter, disk diskUsageReader) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		agent := resourceUsage{
+			Component: resourceComponent{ID: agentName, Binary: agentName},
+			Process:   &resourceProcess{PID: os.Getpid()},
+		}
+		if open, err := handles(agent.Process.PID); err == nil {
+			agent.Handles = &resourceHandles{Open: open}
+		}
+		if usage, err := disk(dataPath); err == nil {
+			agent.Disk = &usage
+		}
+		resources := []resourceUsage{agent}
+
+		if coord != nil {
+			state := coord.State()
+			for _, c := range state.Components {
+				if c.Component.InputSpec == nil {
+					continue
+				}
+				usage := resourceUsage{
+					Component: resourceComponent{ID: c.Component.ID, Binary: c.Component.BinaryName()},
+				}
+				if c.State.Pid == 0 {
+					usage.Error = "the component has not reported its process"
+					resources = append(resources, usage)
+					continue
+				}
+				usage.Process = &resourceProcess{PID: int(c.State.Pid)} //nolint:gosec // pids fit in an int
+				if open, err := handles(usage.Process.PID); err == nil {
+					usage.Handles = &resourceHandles{Open: open}
+				}
+				resources = append(resources, usage)
+			}
+		}
+
+		bytes, err := json.Marshal(resources)
+		var content string
+		if err != nil {
+			content = fmt.Sprintf("Not valid json: %v", err)
+		} else {
+			content = string(bytes)
+		}
+		fmt.Fprint(w, content)
+
+		return nil
+	}
+}
+
+// newDiskUsage computes the used space and its percentage from the total and free space of the filesystem.
+func newDiskUsage(path string, total, free uint64) diskUsage {
+	usage := diskUsage{Path: path, Total: total, Free: free}
+	if total > free {
+		usage.Used = total - free
+	}
+	if total > 0 {
+		usage.UsedPct = float64(usage.Used) / float64(total)
+	}
+	return usage
+}
@@ -0,0 +1,16 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux && !windows
+
+package monitoring
+
+import (
+	"errors"
+)
+
+// OpenHandles is not supported on this platform, the handles are not reported.
+func OpenHandles(_ int) (int, error) {
+	return 0, errors.ErrUnsupported
+}
@@ -0,0 +1,92 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+)
+
+func TestResourcesHandler(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	filestream := runtime.ComponentComponentState{Component: component.Component{ID: "filestream-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "filebeat"}}}
+	filestream.State.Pid = 1234
+	endpoint := runtime.ComponentComponentState{Component: component.Component{ID: "endpoint-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "endpoint-security"}}}
+	endpoint.State.Pid = 5678
+	stopped := runtime.ComponentComponentState{Component: component.Component{ID: "system/metrics-default", InputSpec: &component.InputRuntimeSpec{BinaryName: "metricbeat"}}}
+	coord := mockCoordinator{
+		isUp: true,
+		state: coordinator.State{
+			Components: []runtime.ComponentComponentState{filestream, endpoint, stopped},
+		},
+	}
+	handles := func(pid int) (int, error) {
+		switch pid {
+		case os.Getpid():
+			return 42, nil
+		case 1234:
+			return 17, nil
+		}
+		return 0, errors.New("access denied")
+	}
+	disk := func(path string) (diskUsage, error) {
+		return newDiskUsage(path, 1000, 250), nil
+	}
+
+	testSrv := httptest.NewServer(createHandler(resourcesHandler(coord, "/opt/elastic-agent/data", handles, disk)))
+	defer testSrv.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, testSrv.URL, nil)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	expected := fmt.Sprintf(`[
+		{"component":{"id":"elastic-agent","binary":"elastic-agent"},"process":{"pid":%d},"handles":{"open":42},
+		 "disk":{"path":"/opt/elastic-agent/data","total":1000,"free":250,"used":750,"used_pct":0.75}},
+		{"component":{"id":"filestream-default","binary":"filebeat"},"process":{"pid":1234},"handles":{"open":17}},
+		{"component":{"id":"endpoint-default","binary":"endpoint-security"},"process":{"pid":5678}},
+		{"component":{"id":"system/metrics-default","binary":"metricbeat"},"error":"the component has not reported its process"}
+	]`, os.Getpid())
+	assert.JSONEq(t, expected, string(body))
+}
+
+func TestOpenHandles(t *testing.T) {
+	open, err := OpenHandles(os.Getpid())
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skip("open handles are not reported on this platform")
+	}
+	require.NoError(t, err)
+	assert.Positive(t, open)
+}
+
+func TestReadDiskUsage(t *testing.T) {
+	usage, err := readDiskUsage(t.TempDir())
+	require.NoError(t, err)
+	assert.Positive(t, usage.Total)
+	assert.LessOrEqual(t, usage.Free, usage.Total)
+	assert.Equal(t, usage.Total-usage.Free, usage.Used)
+}
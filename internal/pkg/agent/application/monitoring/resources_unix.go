@@ -0,0 +1,22 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package monitoring
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// readDiskUsage returns the usage of the filesystem holding the path, the free space is the space
+// available to unprivileged users.
+func readDiskUsage(path string) (diskUsage, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return diskUsage{}, err
+	}
+	//nolint:unconvert // the types of the fields differ between platforms
+	return newDiskUsage(path, uint64(stat.Blocks)*uint64(stat.Bsize), uint64(stat.Bavail)*uint64(stat.Bsize)), nil
+}
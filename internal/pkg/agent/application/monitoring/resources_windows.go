@@ -0,0 +1,47 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package monitoring
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetProcessHandleCount = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetProcessHandleCount")
+
+// OpenHandles returns the number of handles opened by the process.
+func OpenHandles(pid int) (int, error) {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid)) //nolint:gosec // pids fit in an uint32
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = windows.CloseHandle(h)
+	}()
+
+	var count uint32
+	r, _, err := procGetProcessHandleCount.Call(uintptr(h), uintptr(unsafe.Pointer(&count)))
+	if r == 0 {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// readDiskUsage returns the usage of the volume holding the path, the free space is the space
+// available to the agent.
+func readDiskUsage(path string) (diskUsage, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return diskUsage{}, err
+	}
+	var available, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(pathPtr, &available, &total, &totalFree); err != nil {
+		return diskUsage{}, err
+	}
+	return newDiskUsage(path, total, available), nil
+}
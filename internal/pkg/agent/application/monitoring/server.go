@@ -21,7 +21,9 @@ import (
 	"github.com/elastic/elastic-agent-libs/api"
 	"github.com/elastic/elastic-agent-libs/monitoring"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/reload"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -49,8 +51,10 @@ func NewServer(
 
 		statsHandler := statsHandler(statNs)
 		r.Handle("/stats", createHandler(statsHandler))
+		r.Handle("/metrics", createHandler(agentMetricsHandler(statNs, agentmetrics.ComponentRestarts)))
 		r.Handle("/readiness", createHandler(readinessHandler(coord)))
 		r.Handle("/liveness", createHandler(livenessHandler(coord)))
+		r.Handle("/resources", createHandler(resourcesHandler(coord, paths.Data(), OpenHandles, readDiskUsage)))
 
 		if isProcessStatsEnabled(cfg) {
 			log.Infof("process monitoring is enabled, creating monitoring endpoints")
@@ -58,6 +62,7 @@ func NewServer(
 			r.Handle("/processes/{componentID}", createHandler(processHandler(coord, statsHandler)))
 			r.Handle("/processes/{componentID}/", createHandler(processHandler(coord, statsHandler)))
 			r.Handle("/processes/{componentID}/{metricsPath}", createHandler(processHandler(coord, statsHandler)))
+			r.Handle("/metrics/components", createHandler(componentsMetricsHandler(coord, fetchComponentStats)))
 		}
 
 		if isPprofEnabled(cfg) {
@@ -53,7 +53,10 @@ const (
 	agentKey                   = "agent"
 	monitoringKey              = "monitoring"
 	useOutputKey               = "use_output"
+	outputKey                  = "output"
+	typeKey                    = "type"
 	monitoringMetricsPeriodKey = "metrics_period"
+	monitoringMetricsetsKey    = "metricsets"
 	failureThresholdKey        = "failure_threshold"
 	monitoringOutput           = "monitoring"
 	defaultMonitoringNamespace = "default"
@@ -159,8 +162,10 @@ func (b *BeatsMonitor) MonitoringConfig(
 	cfg := make(map[string]interface{})
 
 	monitoringOutputName := defaultOutputName
+	var inlineOutput map[string]interface{}
 	metricsCollectionIntervalString := b.config.C.MetricsPeriod
 	failureThreshold := b.config.C.FailureThreshold
+	metricsets := b.config.C.Metricsets
 	if agentCfg, found := policy[agentKey]; found {
 		// The agent section is required for feature flags
 		cfg[agentKey] = agentCfg
@@ -176,12 +181,32 @@ func (b *BeatsMonitor) MonitoringConfig(
 						}
 					}
 
+					if output, found := monitoringMap[outputKey]; found && output != nil {
+						outputMap, ok := output.(map[string]interface{})
+						if !ok {
+							return nil, fmt.Errorf("invalid 'agent.monitoring.output', expected a map not a %T", output)
+						}
+						inlineOutput = outputMap
+					}
+
 					if metricsPeriod, found := monitoringMap[monitoringMetricsPeriodKey]; found {
 						if metricsPeriodStr, ok := metricsPeriod.(string); ok {
 							metricsCollectionIntervalString = metricsPeriodStr
 						}
 					}
 
+					if policyMetricsets, found := monitoringMap[monitoringMetricsetsKey]; found && policyMetricsets != nil {
+						metricsetsCfg, err := config.NewConfigFrom(policyMetricsets)
+						if err != nil {
+							return nil, fmt.Errorf("invalid 'agent.monitoring.metricsets': %w", err)
+						}
+						// the filter of the policy replaces the filter of the configuration
+						metricsets.Include, metricsets.Exclude = nil, nil
+						if err := metricsetsCfg.UnpackTo(&metricsets); err != nil {
+							return nil, fmt.Errorf("invalid 'agent.monitoring.metricsets': %w", err)
+						}
+					}
+
 					if policyFailureThresholdRaw, found := monitoringMap[failureThresholdKey]; found {
 						switch policyValue := policyFailureThresholdRaw.(type) {
 						case uint:
@@ -220,7 +245,12 @@ func (b *BeatsMonitor) MonitoringConfig(
 
 	componentInfos := b.getComponentInfos(components, componentIDPidMap)
 
-	if err := b.injectMonitoringOutput(policy, cfg, monitoringOutputName); err != nil && !errors.Is(err, errNoOutputPresent) {
+	if inlineOutput != nil {
+		// the output defined inline takes precedence over the output of the policy referenced by use_output
+		if err := injectInlineMonitoringOutput(cfg, inlineOutput); err != nil {
+			return nil, errors.New(err, "failed to inject monitoring output")
+		}
+	} else if err := b.injectMonitoringOutput(policy, cfg, monitoringOutputName); err != nil && !errors.Is(err, errNoOutputPresent) {
 		return nil, errors.New(err, "failed to inject monitoring output")
 	} else if errors.Is(err, errNoOutputPresent) {
 		// nothing to inject, no monitoring output
@@ -237,7 +267,10 @@ func (b *BeatsMonitor) MonitoringConfig(
 	}
 
 	if b.config.C.MonitorMetrics {
-		if err := b.injectMetricsInput(cfg, componentInfos, metricsCollectionIntervalString, failureThreshold); err != nil {
+		metricsComponentInfos := slices.DeleteFunc(slices.Clone(componentInfos), func(compInfo componentInfo) bool {
+			return !b.config.C.MonitorComponentMetrics(compInfo.ID, compInfo.BinaryName)
+		})
+		if err := b.injectMetricsInput(cfg, metricsComponentInfos, metricsCollectionIntervalString, failureThreshold, metricsets); err != nil {
 			return nil, errors.New(err, "failed to inject monitoring output")
 		}
 	}
@@ -398,6 +431,20 @@ func (b *BeatsMonitor) injectMonitoringOutput(source, dest map[string]interface{
 	return nil
 }
 
+// injectInlineMonitoringOutput injects the output defined under agent.monitoring.output, it has its own
+// hosts, credentials and TLS settings so the monitoring data keeps flowing to the same cluster whatever
+// the outputs of the policy are.
+func injectInlineMonitoringOutput(dest map[string]interface{}, output map[string]interface{}) error {
+	if outputType, ok := output[typeKey].(string); !ok || outputType == "" {
+		return fmt.Errorf("output defined in 'agent.monitoring.output' has no type")
+	}
+
+	dest[outputsKey] = map[string]interface{}{
+		monitoringOutput: output,
+	}
+	return nil
+}
+
 // getComponentInfos returns a slice of componentInfo structs based on the provided components. This slice contains
 // all the information needed to generate the monitoring configuration for these components, as well as configuration
 // for new components which are going to be doing the monitoring.
@@ -446,9 +493,20 @@ func (b *BeatsMonitor) getComponentInfos(components []component.Component, compo
 func (b *BeatsMonitor) injectLogsInput(cfg map[string]interface{}, componentInfos []componentInfo, monitoringOutput string) error {
 	logsDrop := filepath.Dir(loggingPath("unit", b.operatingSystem))
 
-	streams := []any{b.getAgentFilestreamStream(logsDrop)}
+	// components with logs disabled are dropped from the agent logs and their own log files are not collected
+	var excludedComponentIDs []string
+	logsComponentInfos := make([]componentInfo, 0, len(componentInfos))
+	for _, compInfo := range componentInfos {
+		if !b.config.C.MonitorComponentLogs(compInfo.ID, compInfo.BinaryName) {
+			excludedComponentIDs = append(excludedComponentIDs, compInfo.ID)
+			continue
+		}
+		logsComponentInfos = append(logsComponentInfos, compInfo)
+	}
 
-	streams = append(streams, b.getServiceComponentFilestreamStreams(componentInfos)...)
+	streams := []any{b.getAgentFilestreamStream(logsDrop, excludedComponentIDs, logsComponentInfos)}
+
+	streams = append(streams, b.getServiceComponentFilestreamStreams(logsComponentInfos)...)
 
 	input := map[string]interface{}{
 		idKey:        fmt.Sprintf("%s-agent", monitoringFilesUnitsID),
@@ -492,6 +550,7 @@ func (b *BeatsMonitor) injectMetricsInput(
 	componentInfos []componentInfo,
 	metricsCollectionIntervalString string,
 	failureThreshold *uint,
+	metricsets monitoringCfg.MetricsetsFilter,
 ) error {
 	if metricsCollectionIntervalString == "" {
 		metricsCollectionIntervalString = defaultMetricsCollectionInterval.String()
@@ -541,6 +600,15 @@ func (b *BeatsMonitor) injectMetricsInput(
 	inputs = append(inputs, b.getServiceComponentProcessMetricInputs(
 		componentInfos, metricsCollectionIntervalString)...)
 
+	// drop the streams removed by the metricsets filter, and the inputs left without streams
+	inputs = slices.DeleteFunc(inputs, func(input any) bool {
+		inputMap := input.(map[string]interface{})
+		module, _, _ := strings.Cut(inputMap["type"].(string), "/")
+		streams := filterMetricsStreams(metricsets, module, inputMap["streams"].([]any))
+		inputMap["streams"] = streams
+		return len(streams) == 0
+	})
+
 	inputsNode, found := cfg[inputsKey]
 	if !found {
 		return fmt.Errorf("no inputs in config")
@@ -556,8 +624,30 @@ func (b *BeatsMonitor) injectMetricsInput(
 	return nil
 }
 
+// filterMetricsStreams returns the metrics streams of the module allowed by the metricsets filter.
+func filterMetricsStreams(metricsets monitoringCfg.MetricsetsFilter, module string, streams []any) []any {
+	return slices.DeleteFunc(streams, func(stream any) bool {
+		streamMap, ok := stream.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		var names []string
+		if streamMetricsets, ok := streamMap["metricsets"].([]interface{}); ok {
+			for _, metricset := range streamMetricsets {
+				names = append(names, fmt.Sprintf("%s.%v", module, metricset))
+			}
+		}
+		if dataStream, ok := streamMap["data_stream"].(map[string]interface{}); ok {
+			if dataset, ok := dataStream["dataset"].(string); ok {
+				names = append(names, dataset)
+			}
+		}
+		return !metricsets.Allows(names...)
+	})
+}
+
 // getAgentFilestreamStream returns the filestream stream definition for collecting agent logs.
-func (b *BeatsMonitor) getAgentFilestreamStream(logsDrop string) any {
+func (b *BeatsMonitor) getAgentFilestreamStream(logsDrop string, excludedComponentIDs []string, componentInfos []componentInfo) any {
 	monitoringNamespace := b.monitoringNamespace()
 	return map[string]any{
 		idKey:  fmt.Sprintf("%s-agent", monitoringFilesUnitsID),
@@ -586,10 +676,39 @@ func (b *BeatsMonitor) getAgentFilestreamStream(logsDrop string) any {
 				},
 			},
 		},
-		"processors": processorsForAgentFilestream(),
+		"processors": processorsForAgentFilestream(excludedComponentIDs, b.componentLogsProcessors(componentInfos)),
 	}
 }
 
+// componentLogsProcessors returns the processors applying the log level and namespace overrides to the
+// logs of the components collected from the agent logs.
+func (b *BeatsMonitor) componentLogsProcessors(componentInfos []componentInfo) []any {
+	monitoringNamespace := b.monitoringNamespace()
+	var processors []any
+	for _, compInfo := range componentInfos {
+		componentCondition := map[string]interface{}{
+			"equals": map[string]interface{}{
+				"component.id": compInfo.ID,
+			},
+		}
+		if levels := b.config.C.DroppedLogLevels(compInfo.ID, compInfo.BinaryName); len(levels) > 0 {
+			processors = append(processors, dropLogLevelsProcessor(levels, componentCondition))
+		}
+		if namespace := b.config.C.ComponentLogsNamespace(compInfo.ID, compInfo.BinaryName); namespace != "" && namespace != monitoringNamespace {
+			processors = append(processors, map[string]interface{}{
+				"add_fields": map[string]interface{}{
+					"when":   componentCondition,
+					"target": "data_stream",
+					"fields": map[string]interface{}{
+						"namespace": namespace,
+					},
+				},
+			})
+		}
+	}
+	return processors
+}
+
 // getServiceComponentFilestreamStreams returns filestream stream definitions for collecting logs of components running as
 // services.
 func (b *BeatsMonitor) getServiceComponentFilestreamStreams(componentInfos []componentInfo) []any {
@@ -603,6 +722,14 @@ func (b *BeatsMonitor) getServiceComponentFilestreamStreams(componentInfos []com
 		}
 		sanitizedBinaryName := sanitizeName(compInfo.BinaryName) // conform with index naming policy
 		dataset := fmt.Sprintf("elastic_agent.%s", sanitizedBinaryName)
+		namespace := monitoringNamespace
+		if override := b.config.C.ComponentLogsNamespace(compInfo.ID, compInfo.BinaryName); override != "" {
+			namespace = override
+		}
+		processors := processorsForServiceComponentFilestream(compInfo, dataset)
+		if levels := b.config.C.DroppedLogLevels(compInfo.ID, compInfo.BinaryName); len(levels) > 0 {
+			processors = append(processors, dropLogLevelsProcessor(levels, nil))
+		}
 		streams = append(streams, map[string]interface{}{
 			idKey:  fmt.Sprintf("%s-%s", monitoringFilesUnitsID, compInfo.ID),
 			"type": "filestream",
@@ -612,7 +739,7 @@ func (b *BeatsMonitor) getServiceComponentFilestreamStreams(componentInfos []com
 			"data_stream": map[string]interface{}{
 				"type":      "logs",
 				"dataset":   dataset,
-				"namespace": monitoringNamespace,
+				"namespace": namespace,
 			},
 			"close": map[string]interface{}{
 				"on_state_change": map[string]interface{}{
@@ -629,7 +756,7 @@ func (b *BeatsMonitor) getServiceComponentFilestreamStreams(componentInfos []com
 					},
 				},
 			},
-			"processors": processorsForServiceComponentFilestream(compInfo, dataset),
+			"processors": processors,
 		})
 	}
 	return streams
@@ -668,6 +795,30 @@ func (b *BeatsMonitor) getHttpStreams(
 	}
 	httpStreams = append(httpStreams, agentStream)
 
+	// disk usage of the data directory and open handles of the agent and of each component
+	resourcesDataStreamName := "agent_resources"
+	resourcesDataset := fmt.Sprintf("elastic_agent.%s", resourcesDataStreamName)
+	resourcesStream := map[string]any{
+		idKey: fmt.Sprintf("%s-agent-resources", monitoringMetricsUnitID),
+		"data_stream": map[string]interface{}{
+			"type":      "metrics",
+			"dataset":   resourcesDataset,
+			"namespace": monitoringNamespace,
+		},
+		"metricsets":    []interface{}{"json"},
+		"path":          "/resources",
+		"hosts":         []interface{}{HttpPlusAgentMonitoringEndpoint(b.config.C)},
+		"namespace":     resourcesDataStreamName,
+		"json.is_array": true,
+		"period":        metricsCollectionIntervalString,
+		"index":         fmt.Sprintf("metrics-elastic_agent.%s-%s", resourcesDataStreamName, monitoringNamespace),
+		"processors":    processorsForAgentResourcesStream(monitoringNamespace, resourcesDataset, b.agentInfo),
+	}
+	if failureThreshold != nil {
+		resourcesStream[failureThresholdKey] = *failureThreshold
+	}
+	httpStreams = append(httpStreams, resourcesStream)
+
 	for _, compInfo := range componentInfos {
 		binaryName := compInfo.BinaryName
 		if !isSupportedMetricsBinary(binaryName) {
@@ -821,21 +972,29 @@ func (b *BeatsMonitor) getServiceComponentProcessMetricInputs(
 	return inputs
 }
 
-// processorsForAgentFilestream returns processors used for agent logs in a filestream input.
-func processorsForAgentFilestream() []any {
+// processorsForAgentFilestream returns processors used for agent logs in a filestream input, the logs of the
+// excluded components are dropped.
+func processorsForAgentFilestream(excludedComponentIDs []string, componentProcessors []any) []any {
 	processors := []any{
 		// drop all events from monitoring components (do it early)
 		// without dropping these events the filestream gets stuck in an infinite loop
 		// if filestream hits an issue publishing the events it logs an error which then filestream monitor
 		// will read from the logs and try to also publish that new log message (thus the infinite loop).
 		dropEventsFromMonitoringComponentsProcessor(),
+	}
+	for _, componentID := range excludedComponentIDs {
+		processors = append(processors, dropEventsFromComponentProcessor(componentID))
+	}
+	processors = append(processors,
 		// drop periodic metrics logs (those are useful mostly in diagnostic dumps where we collect log files)
 		dropPeriodicMetricsLogsProcessor(),
 		// drop event logs
 		dropEventLogs(),
-	}
+	)
 	// if the event is from a component, use the component's dataset
 	processors = append(processors, useComponentDatasetProcessors()...)
+	// log levels and namespaces overridden per component
+	processors = append(processors, componentProcessors...)
 	processors = append(processors,
 		// coming from logger, added by agent (drop)
 		dropEcsVersionFieldProcessor(),
@@ -945,6 +1104,40 @@ func processorsForAgentHttpStream(namespace, dataset string, agentInfo info.Agen
 	}
 }
 
+// processorsForAgentResourcesStream returns the processors used for the agent resources stream, each event
+// carries the component it reports on and the error of a component without a process.
+func processorsForAgentResourcesStream(namespace, dataset string, agentInfo info.Agent) []any {
+	return []interface{}{
+		addDataStreamFieldsProcessor(dataset, namespace),
+		addEventFieldsProcessor(dataset),
+		addElasticAgentFieldsProcessor(agentName, agentInfo),
+		addAgentFieldsProcessor(agentInfo.AgentID()),
+		addCopyFieldsProcessor([]any{
+			map[string]interface{}{
+				"from": "http.agent_resources.component",
+				"to":   "component",
+			},
+			map[string]interface{}{
+				"from": "http.agent_resources.process.pid",
+				"to":   "process.pid",
+			},
+			map[string]interface{}{
+				"from": "http.agent_resources.handles",
+				"to":   "system.process.fd",
+			},
+			map[string]interface{}{
+				"from": "http.agent_resources.disk",
+				"to":   "elastic_agent.data.disk",
+			},
+			map[string]interface{}{
+				"from": "http.agent_resources.error",
+				"to":   "error.message",
+			},
+		}, true, false),
+		dropFieldsProcessor([]any{"http"}, true),
+	}
+}
+
 // addElasticAgentFieldsProcessor returns a processor definition that adds agent information in an `elastic_agent` field.
 func addElasticAgentFieldsProcessor(binaryName string, agentInfo info.Agent) map[string]any {
 	return map[string]any{
@@ -1062,6 +1255,45 @@ func dropEventsFromMonitoringComponentsProcessor() map[string]any {
 	}
 }
 
+// dropEventsFromComponentProcessor returns a processor which drops the logs of a component.
+func dropEventsFromComponentProcessor(componentID string) map[string]any {
+	return map[string]interface{}{
+		"drop_event": map[string]interface{}{
+			"when": map[string]interface{}{
+				"equals": map[string]interface{}{
+					"component.id": componentID,
+				},
+			},
+		},
+	}
+}
+
+// dropLogLevelsProcessor returns a processor dropping the logs of the levels, only for the events matching
+// the condition when set.
+func dropLogLevelsProcessor(levels []string, condition map[string]interface{}) map[string]any {
+	levelConditions := make([]interface{}, 0, len(levels))
+	for _, level := range levels {
+		levelConditions = append(levelConditions, map[string]interface{}{
+			"equals": map[string]interface{}{
+				"log.level": level,
+			},
+		})
+	}
+	when := map[string]interface{}{
+		"or": levelConditions,
+	}
+	if condition != nil {
+		when = map[string]interface{}{
+			"and": []interface{}{condition, when},
+		}
+	}
+	return map[string]interface{}{
+		"drop_event": map[string]interface{}{
+			"when": when,
+		},
+	}
+}
+
 // dropPeriodicMetricsLogsProcessor returns a processor which drops logs about periodic metrics. This is done by
 // matching on the start of the log message.
 func dropPeriodicMetricsLogsProcessor() map[string]any {
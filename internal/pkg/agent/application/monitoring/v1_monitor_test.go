@@ -996,6 +996,369 @@ func TestMonitoringWithOtelRuntime(t *testing.T) {
 	}
 }
 
+func TestMonitoringConfigComponentOverrides(t *testing.T) {
+	agentInfo, err := info.NewAgentInfo(context.Background(), false)
+	require.NoError(t, err, "Error creating agent info")
+
+	disabled := false
+	cfg := &monitoringConfig{
+		C: &monitoringcfg.MonitoringConfig{
+			Enabled:        true,
+			MonitorLogs:    true,
+			MonitorMetrics: true,
+			Namespace:      "test",
+			HTTP: &monitoringcfg.MonitoringHTTPConfig{
+				Enabled: false,
+			},
+			Components: map[string]monitoringcfg.ComponentMonitoringConfig{
+				"filestream-default":     {Logs: &disabled},
+				"system/metrics-default": {Metrics: &disabled},
+			},
+		},
+	}
+
+	policy := map[string]any{
+		"outputs": map[string]any{
+			"default": map[string]any{},
+		},
+	}
+
+	b := &BeatsMonitor{
+		enabled:   true,
+		config:    cfg,
+		agentInfo: agentInfo,
+	}
+
+	components := []component.Component{
+		{
+			ID: "filestream-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "filebeat",
+			},
+		},
+		{
+			ID: "system/metrics-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "metricbeat",
+			},
+		},
+	}
+	monitoringCfgMap, err := b.MonitoringConfig(policy, components, map[string]uint64{})
+	require.NoError(t, err)
+
+	inputsByID := map[string]string{}
+	for _, input := range monitoringCfgMap["inputs"].([]any) {
+		inputMap := input.(map[string]any)
+		raw, err := json.Marshal(inputMap)
+		require.NoError(t, err)
+		inputsByID[inputMap["id"].(string)] = string(raw)
+	}
+
+	// metrics are collected for filestream-default only
+	metrics := inputsByID["metrics-monitoring-beats"] + inputsByID["metrics-monitoring-agent"]
+	assert.Contains(t, metrics, `"id":"filestream-default"`)
+	assert.NotContains(t, metrics, `"id":"system/metrics-default"`)
+
+	// the logs of filestream-default are dropped from the agent logs
+	logs := inputsByID["filestream-monitoring-agent"]
+	assert.Contains(t, logs, `{"drop_event":{"when":{"equals":{"component.id":"filestream-default"}}}}`)
+	assert.NotContains(t, logs, `"component.id":"system/metrics-default"`)
+}
+
+func TestMonitoringConfigComponentLogsOverrides(t *testing.T) {
+	agentInfo, err := info.NewAgentInfo(context.Background(), false)
+	require.NoError(t, err, "Error creating agent info")
+
+	b := &BeatsMonitor{
+		enabled: true,
+		config: &monitoringConfig{
+			C: &monitoringcfg.MonitoringConfig{
+				Enabled:     true,
+				MonitorLogs: true,
+				Namespace:   "test",
+				HTTP: &monitoringcfg.MonitoringHTTPConfig{
+					Enabled: false,
+				},
+				Components: map[string]monitoringcfg.ComponentMonitoringConfig{
+					"filestream-default": {Namespace: "filestream"},
+					"endpoint-security":  {Namespace: "security"},
+				},
+				LogLevelOverrides: map[string]string{
+					"filebeat":         "warning",
+					"endpoint-default": "error",
+				},
+			},
+		},
+		agentInfo: agentInfo,
+	}
+	policy := map[string]any{
+		"outputs": map[string]any{
+			"default": map[string]any{},
+		},
+	}
+	components := []component.Component{
+		{
+			ID: "filestream-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "filebeat",
+			},
+		},
+		{
+			ID: "system/metrics-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "metricbeat",
+			},
+		},
+		{
+			ID: "endpoint-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "endpoint-security",
+				Spec: component.InputSpec{
+					Service: &component.ServiceSpec{
+						Log: &component.ServiceLogSpec{
+							Path: "/var/log/endpoint.log",
+						},
+					},
+				},
+			},
+		},
+	}
+	monitoringCfgMap, err := b.MonitoringConfig(policy, components, map[string]uint64{})
+	require.NoError(t, err)
+
+	streamsByID := map[string]map[string]any{}
+	for _, input := range monitoringCfgMap["inputs"].([]any) {
+		for _, stream := range input.(map[string]any)["streams"].([]any) {
+			streamMap := stream.(map[string]any)
+			streamsByID[streamMap["id"].(string)] = streamMap
+		}
+	}
+
+	// the overrides of the components collected from the agent logs are applied with processors
+	agentStream := streamsByID["filestream-monitoring-agent"]
+	require.NotNil(t, agentStream)
+	raw, err := json.Marshal(agentStream["processors"])
+	require.NoError(t, err)
+	processors := string(raw)
+	assert.Contains(t, processors, `{"drop_event":{"when":{"and":[{"equals":{"component.id":"filestream-default"}},{"or":[{"equals":{"log.level":"debug"}},{"equals":{"log.level":"info"}}]}]}}}`)
+	assert.Contains(t, processors, `{"add_fields":{"fields":{"namespace":"filestream"},"target":"data_stream","when":{"equals":{"component.id":"filestream-default"}}}}`)
+	assert.NotContains(t, processors, `"component.id":"system/metrics-default"`)
+	assert.Equal(t, "test", agentStream["data_stream"].(map[string]any)["namespace"])
+
+	// the service component stream is routed to its namespace and drops its logs below the level
+	endpointStream := streamsByID["filestream-monitoring-endpoint-default"]
+	require.NotNil(t, endpointStream)
+	assert.Equal(t, "security", endpointStream["data_stream"].(map[string]any)["namespace"])
+	raw, err = json.Marshal(endpointStream["processors"])
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `{"drop_event":{"when":{"or":[{"equals":{"log.level":"debug"}},{"equals":{"log.level":"info"}},{"equals":{"log.level":"warning"}},{"equals":{"log.level":"warn"}}]}}}`)
+}
+
+func TestMonitoringConfigMetricsets(t *testing.T) {
+	agentInfo, err := info.NewAgentInfo(context.Background(), false)
+	require.NoError(t, err, "Error creating agent info")
+
+	components := []component.Component{
+		{
+			ID: "filestream-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "filebeat",
+			},
+		},
+		{
+			ID: "endpoint-default",
+			InputSpec: &component.InputRuntimeSpec{
+				BinaryName: "endpoint-security",
+				Spec: component.InputSpec{
+					Service: &component.ServiceSpec{},
+				},
+			},
+		},
+	}
+	pids := map[string]uint64{"endpoint-default": 1234}
+
+	tcs := []struct {
+		name            string
+		filter          monitoringcfg.MetricsetsFilter
+		policyFilter    map[string]any
+		expectedStreams []string
+	}{
+		{
+			name: "no filter",
+			expectedStreams: []string{
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-filebeat",
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-agent",
+				"metrics-monitoring-agent-resources",
+				"metrics-monitoring-metricbeat-1",
+				"metrics-monitoring-filebeat-1",
+				"metrics-monitoring-filebeat-1",
+				"metrics-monitoring-metricbeat-1",
+				"metrics-monitoring-endpoint_security",
+			},
+		},
+		{
+			name:   "exclude a dataset",
+			filter: monitoringcfg.MetricsetsFilter{Exclude: []string{"elastic_agent.filebeat_input"}},
+			expectedStreams: []string{
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-filebeat",
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-agent",
+				"metrics-monitoring-agent-resources",
+				"metrics-monitoring-metricbeat-1",
+				"metrics-monitoring-filebeat-1",
+				"metrics-monitoring-metricbeat-1",
+				"metrics-monitoring-endpoint_security",
+			},
+		},
+		{
+			name:   "include a metricset",
+			filter: monitoringcfg.MetricsetsFilter{Include: []string{"beat.stats"}},
+			expectedStreams: []string{
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-filebeat",
+				"metrics-monitoring-metricbeat",
+			},
+		},
+		{
+			name:         "policy replaces the filter",
+			filter:       monitoringcfg.MetricsetsFilter{Include: []string{"beat.stats"}},
+			policyFilter: map[string]any{"exclude": []any{"http.json"}},
+			expectedStreams: []string{
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-filebeat",
+				"metrics-monitoring-metricbeat",
+				"metrics-monitoring-endpoint_security",
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := map[string]any{
+				"outputs": map[string]any{
+					"default": map[string]any{},
+				},
+			}
+			if tc.policyFilter != nil {
+				policy["agent"] = map[string]any{
+					"monitoring": map[string]any{
+						"metricsets": tc.policyFilter,
+					},
+				}
+			}
+			b := &BeatsMonitor{
+				enabled: true,
+				config: &monitoringConfig{
+					C: &monitoringcfg.MonitoringConfig{
+						Enabled:        true,
+						MonitorMetrics: true,
+						HTTP: &monitoringcfg.MonitoringHTTPConfig{
+							Enabled: false,
+						},
+						Metricsets: tc.filter,
+					},
+				},
+				agentInfo: agentInfo,
+			}
+			got, err := b.MonitoringConfig(policy, components, pids)
+			require.NoError(t, err)
+
+			var streamIDs []string
+			for _, input := range got["inputs"].([]any) {
+				streams := input.(map[string]any)["streams"].([]any)
+				require.NotEmpty(t, streams, "inputs without streams should be removed")
+				for _, stream := range streams {
+					streamIDs = append(streamIDs, stream.(map[string]any)["id"].(string))
+				}
+			}
+			assert.Equal(t, tc.expectedStreams, streamIDs)
+		})
+	}
+}
+
+func TestMonitoringConfigInlineOutput(t *testing.T) {
+	agentInfo, err := info.NewAgentInfo(context.Background(), false)
+	require.NoError(t, err, "Error creating agent info")
+
+	inlineOutput := map[string]any{
+		"type":    "elasticsearch",
+		"hosts":   []any{"https://monitoring.example.com:9200"},
+		"api_key": "monitoring:key",
+		"ssl": map[string]any{
+			"certificate_authorities": []any{"/etc/monitoring-ca.crt"},
+		},
+	}
+
+	testCases := map[string]struct {
+		monitoring map[string]any
+		outputs    map[string]any
+		expected   any
+		wantErr    string
+	}{
+		"inline output takes precedence over use_output": {
+			monitoring: map[string]any{"use_output": "default", "output": inlineOutput},
+			outputs:    map[string]any{"default": map[string]any{"type": "logstash"}},
+			expected:   inlineOutput,
+		},
+		"inline output without policy outputs": {
+			monitoring: map[string]any{"output": inlineOutput},
+			expected:   inlineOutput,
+		},
+		"use_output without inline output": {
+			monitoring: map[string]any{"use_output": "default"},
+			outputs:    map[string]any{"default": map[string]any{"type": "logstash"}},
+			expected:   map[string]any{"type": "logstash"},
+		},
+		"inline output without type": {
+			monitoring: map[string]any{"output": map[string]any{"hosts": []any{"localhost:9200"}}},
+			wantErr:    "has no type",
+		},
+		"inline output not a map": {
+			monitoring: map[string]any{"output": "monitoring-cluster"},
+			wantErr:    "expected a map",
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b := &BeatsMonitor{
+				enabled: true,
+				config: &monitoringConfig{
+					C: &monitoringcfg.MonitoringConfig{
+						Enabled:        true,
+						MonitorLogs:    true,
+						MonitorMetrics: true,
+						Namespace:      "test",
+						HTTP: &monitoringcfg.MonitoringHTTPConfig{
+							Enabled: false,
+						},
+					},
+				},
+				agentInfo: agentInfo,
+			}
+			policy := map[string]any{
+				"agent": map[string]any{
+					"monitoring": tc.monitoring,
+				},
+			}
+			if tc.outputs != nil {
+				policy["outputs"] = tc.outputs
+			}
+
+			monitoringCfgMap, err := b.MonitoringConfig(policy, nil, map[string]uint64{})
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, map[string]any{"monitoring": tc.expected}, monitoringCfgMap["outputs"])
+		})
+	}
+}
+
 func TestEnrichArgs(t *testing.T) {
 	unitID := "test"
 	tests := []struct {
@@ -41,7 +41,7 @@ func (o *once) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	o.ch <- &localConfigChange{cfg}
+	o.ch <- &localConfigChange{cfg: cfg}
 	<-ctx.Done()
 	return ctx.Err()
 }
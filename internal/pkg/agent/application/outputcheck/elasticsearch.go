@@ -0,0 +1,191 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package outputcheck
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/config"
+	"github.com/elastic/elastic-agent-libs/kibana"
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	elasticsearchDefaultPort = 9200
+	hasPrivilegesPath        = "/_security/user/_has_privileges"
+)
+
+// requiredClusterPrivileges and requiredIndexPrivileges are the privileges the components need to
+// write their data, they match the privileges Fleet grants to the API keys of the outputs.
+var (
+	requiredClusterPrivileges = []string{"monitor"}
+	requiredIndexPrivileges   = []indexPrivileges{{
+		Names:      []string{"logs-*-*", "metrics-*-*", "traces-*-*"},
+		Privileges: []string{"auto_configure", "create_doc"},
+	}}
+)
+
+type elasticsearchConfig struct {
+	Hosts    []string          `config:"hosts"`
+	Protocol string            `config:"protocol"`
+	Path     string            `config:"path"`
+	APIKey   string            `config:"api_key"`
+	Username string            `config:"username"`
+	Password string            `config:"password"`
+	Headers  map[string]string `config:"headers"`
+}
+
+type indexPrivileges struct {
+	Names      []string `json:"names"`
+	Privileges []string `json:"privileges"`
+}
+
+type hasPrivilegesRequest struct {
+	Cluster []string          `json:"cluster"`
+	Index   []indexPrivileges `json:"index"`
+}
+
+type hasPrivilegesResponse struct {
+	HasAllRequested bool                       `json:"has_all_requested"`
+	Cluster         map[string]bool            `json:"cluster"`
+	Index           map[string]map[string]bool `json:"index"`
+}
+
+// checkElasticsearch checks that the credentials of the output are accepted by Elasticsearch and
+// grant the privileges to write the data of the components. Outputs without credentials are not
+// checked, and a host that cannot be reached is skipped as the check is inconclusive.
+func checkElasticsearch(ctx context.Context, log *logger.Logger, output *config.C) error {
+	var cfg elasticsearchConfig
+	if err := output.Unpack(&cfg); err != nil {
+		return fmt.Errorf("invalid Elasticsearch output configuration: %w", err)
+	}
+	if cfg.APIKey == "" && cfg.Username == "" {
+		return nil
+	}
+
+	transport := httpcommon.DefaultHTTPTransportSettings()
+	if err := output.Unpack(&transport); err != nil {
+		return fmt.Errorf("invalid Elasticsearch output transport configuration: %w", err)
+	}
+	client, err := transport.Client()
+	if err != nil {
+		return fmt.Errorf("invalid Elasticsearch output transport configuration: %w", err)
+	}
+	defer client.CloseIdleConnections()
+
+	scheme := cfg.Protocol
+	if scheme == "" && transport.TLS != nil && transport.TLS.IsEnabled() {
+		scheme = "https"
+	}
+
+	body, err := json.Marshal(hasPrivilegesRequest{
+		Cluster: requiredClusterPrivileges,
+		Index:   requiredIndexPrivileges,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, host := range cfg.Hosts {
+		hostURL, err := kibana.MakeURL(scheme, cfg.Path, host, elasticsearchDefaultPort)
+		if err != nil {
+			return fmt.Errorf("invalid Elasticsearch output host %q: %w", host, err)
+		}
+		resp, err := hasPrivileges(ctx, client, cfg, strings.TrimSuffix(hostURL, "/")+hasPrivilegesPath, body)
+		if err != nil {
+			log.Debugf("skipping the validation of Elasticsearch output host %s: %v", hostURL, err)
+			continue
+		}
+		return resp.check(hostURL, cfg)
+	}
+	return nil
+}
+
+// privilegesResult is the conclusive answer of Elasticsearch to the privileges check.
+type privilegesResult struct {
+	statusCode int
+	privileges hasPrivilegesResponse
+}
+
+func (r *privilegesResult) check(hostURL string, cfg elasticsearchConfig) error {
+	credentials := "API key"
+	if cfg.APIKey == "" {
+		credentials = fmt.Sprintf("user %q", cfg.Username)
+	}
+
+	switch r.statusCode {
+	case http.StatusUnauthorized:
+		return fmt.Errorf("the %s of the Elasticsearch output was rejected by %s: invalid or expired credentials", credentials, hostURL)
+	case http.StatusForbidden:
+		return fmt.Errorf("the %s of the Elasticsearch output is not allowed to check its privileges on %s", credentials, hostURL)
+	}
+
+	if r.privileges.HasAllRequested {
+		return nil
+	}
+	var missing []string
+	for privilege, granted := range r.privileges.Cluster {
+		if !granted {
+			missing = append(missing, "cluster:"+privilege)
+		}
+	}
+	for index, privileges := range r.privileges.Index {
+		for privilege, granted := range privileges {
+			if !granted {
+				missing = append(missing, index+":"+privilege)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("the %s of the Elasticsearch output is missing privileges: %s", credentials, strings.Join(missing, ", "))
+}
+
+// hasPrivileges calls the has privileges API of Elasticsearch with the credentials of the output. It
+// returns an error when the answer is inconclusive, the host being unreachable or failing.
+func hasPrivileges(ctx context.Context, client *http.Client, cfg elasticsearchConfig, url string, body []byte) (*privilegesResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+base64.StdEncoding.EncodeToString([]byte(cfg.APIKey)))
+	} else {
+		req.SetBasicAuth(cfg.Username, cfg.Password)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		result := &privilegesResult{statusCode: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&result.privileges); err != nil {
+			return nil, fmt.Errorf("failed to decode the privileges check response: %w", err)
+		}
+		return result, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return &privilegesResult{statusCode: resp.StatusCode}, nil
+	default:
+		return nil, fmt.Errorf("privileges check returned unexpected status code: %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package outputcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/elastic/elastic-agent-libs/config"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const logstashDefaultPort = "5044"
+
+type logstashConfig struct {
+	Hosts []string `config:"hosts"`
+}
+
+// checkLogstash checks that at least one of the hosts of the output accepts connections.
+func checkLogstash(ctx context.Context, log *logger.Logger, output *config.C) error {
+	var cfg logstashConfig
+	if err := output.Unpack(&cfg); err != nil {
+		return fmt.Errorf("invalid Logstash output configuration: %w", err)
+	}
+	if len(cfg.Hosts) == 0 {
+		return nil
+	}
+
+	var dialer net.Dialer
+	var errs []string
+	for _, host := range cfg.Hosts {
+		addr := host
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			addr = net.JoinHostPort(host, logstashDefaultPort)
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		if errors.Is(ctx.Err(), context.Canceled) {
+			// interrupted, the check is inconclusive
+			return nil
+		}
+		log.Debugf("Logstash output host %s is not reachable: %v", addr, err)
+		errs = append(errs, err.Error())
+	}
+	return fmt.Errorf("no Logstash host reachable: %s", strings.Join(errs, "; "))
+}
@@ -0,0 +1,154 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package outputcheck validates the outputs of the policy before the components use them.
+//
+// The credentials of the Elasticsearch outputs are checked against the privileges the components need
+// to write their data and the Logstash outputs are checked to be reachable, so a misconfigured output
+// is reported with a precise reason instead of failing the bulk requests of every component using it.
+package outputcheck
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/config"
+
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	outputTypeElasticsearch = "elasticsearch"
+	outputTypeLogstash      = "logstash"
+
+	// DefaultTimeout is the default time given to the check of a single output.
+	DefaultTimeout = 10 * time.Second
+)
+
+// Config is the configuration of the output validation, read from agent.output_validation.
+type Config struct {
+	// Enabled turns the validation of the outputs on, it is off by default.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// Timeout is the time given to the check of a single output.
+	Timeout time.Duration `config:"timeout" yaml:"timeout"`
+}
+
+// DefaultConfig returns the default configuration of the output validation.
+func DefaultConfig() Config {
+	return Config{
+		Enabled: false,
+		Timeout: DefaultTimeout,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.Timeout <= 0 {
+		return fmt.Errorf("invalid output validation timeout %s, must be greater than zero", c.Timeout)
+	}
+	return nil
+}
+
+// checkFunc checks a single output, it returns an error describing why the output cannot be used.
+type checkFunc func(ctx context.Context, log *logger.Logger, output *config.C) error
+
+// Checker validates the outputs of the policy.
+type Checker struct {
+	log *logger.Logger
+
+	mx  sync.RWMutex
+	cfg Config
+
+	// checks by output type, outputs of other types are not checked
+	checks map[string]checkFunc
+}
+
+// New creates a checker, disabled until a configuration enabling it is reloaded.
+func New(log *logger.Logger) *Checker {
+	return &Checker{
+		log: log,
+		cfg: DefaultConfig(),
+		checks: map[string]checkFunc{
+			outputTypeElasticsearch: checkElasticsearch,
+			outputTypeLogstash:      checkLogstash,
+		},
+	}
+}
+
+// Reload reads the output validation settings from the agent configuration.
+func (c *Checker) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		OutputValidation Config `config:"agent.output_validation"`
+	}{
+		OutputValidation: DefaultConfig(),
+	}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack output validation config: %w", err)
+	}
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.cfg = cfg.OutputValidation
+	return nil
+}
+
+// Enabled returns true when the outputs must be validated.
+func (c *Checker) Enabled() bool {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+	return c.cfg.Enabled
+}
+
+// Check validates the given outputs of the policy concurrently. It returns the errors of the outputs
+// that failed their check by output name, outputs that passed or that are not checked are omitted.
+func (c *Checker) Check(ctx context.Context, outputs map[string]interface{}) map[string]error {
+	c.mx.RLock()
+	timeout := c.cfg.Timeout
+	c.mx.RUnlock()
+
+	var wg sync.WaitGroup
+	var resultsMx sync.Mutex
+	results := make(map[string]error)
+	for name, raw := range outputs {
+		output, err := config.NewConfigFrom(raw)
+		if err != nil {
+			c.log.Debugf("skipping the validation of output %q: %v", name, err)
+			continue
+		}
+		var meta struct {
+			Type    string `config:"type"`
+			Enabled *bool  `config:"enabled"`
+		}
+		if err := output.Unpack(&meta); err != nil {
+			c.log.Debugf("skipping the validation of output %q: %v", name, err)
+			continue
+		}
+		check, ok := c.checks[meta.Type]
+		if !ok || (meta.Enabled != nil && !*meta.Enabled) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, output *config.C) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			if err := check(checkCtx, c.log, output); err != nil {
+				resultsMx.Lock()
+				results[name] = err
+				resultsMx.Unlock()
+			}
+		}(name, output)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		// checks interrupted by a newer policy are inconclusive
+		return nil
+	}
+	return results
+}
@@ -0,0 +1,193 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package outputcheck
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestCheckerReload(t *testing.T) {
+	log, _ := loggertest.New("outputcheck")
+	c := New(log)
+	assert.False(t, c.Enabled(), "output validation should be disabled by default")
+
+	require.NoError(t, c.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.output_validation": map[string]interface{}{
+			"enabled": true,
+			"timeout": "5s",
+		},
+	})))
+	assert.True(t, c.Enabled())
+	assert.Equal(t, 5*time.Second, c.cfg.Timeout)
+
+	require.NoError(t, c.Reload(config.MustNewConfigFrom(map[string]interface{}{})))
+	assert.False(t, c.Enabled(), "output validation should be disabled once removed from the policy")
+	assert.Equal(t, DefaultTimeout, c.cfg.Timeout)
+
+	assert.Error(t, c.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.output_validation.timeout": "0s",
+	})), "a zero timeout should be rejected")
+}
+
+func TestCheckElasticsearch(t *testing.T) {
+	var response func(w http.ResponseWriter)
+	var authorization string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != hasPrivilegesPath || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		response(w)
+	}))
+	defer srv.Close()
+
+	privileges := func(resp hasPrivilegesResponse) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			_ = json.NewEncoder(w).Encode(resp)
+		}
+	}
+	status := func(code int) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.WriteHeader(code)
+		}
+	}
+	apiKeyOutput := map[string]interface{}{
+		"type":    "elasticsearch",
+		"hosts":   []interface{}{srv.URL},
+		"api_key": "id:secret",
+	}
+
+	apiKeyAuthorization := "ApiKey " + base64.StdEncoding.EncodeToString([]byte("id:secret"))
+
+	testCases := map[string]struct {
+		output            map[string]interface{}
+		response          func(w http.ResponseWriter)
+		wantErr           string
+		wantAuthorization string
+	}{
+		"all privileges": {
+			output:            apiKeyOutput,
+			response:          privileges(hasPrivilegesResponse{HasAllRequested: true}),
+			wantAuthorization: apiKeyAuthorization,
+		},
+		"missing privileges": {
+			output: apiKeyOutput,
+			response: privileges(hasPrivilegesResponse{
+				Cluster: map[string]bool{"monitor": false},
+				Index: map[string]map[string]bool{
+					"logs-*-*":    {"auto_configure": true, "create_doc": false},
+					"metrics-*-*": {"auto_configure": true, "create_doc": true},
+				},
+			}),
+			wantErr:           "the API key of the Elasticsearch output is missing privileges: cluster:monitor, logs-*-*:create_doc",
+			wantAuthorization: apiKeyAuthorization,
+		},
+		"invalid API key": {
+			output:            apiKeyOutput,
+			response:          status(http.StatusUnauthorized),
+			wantErr:           "the API key of the Elasticsearch output was rejected by " + srv.URL + ": invalid or expired credentials",
+			wantAuthorization: apiKeyAuthorization,
+		},
+		"invalid user": {
+			output: map[string]interface{}{
+				"type":     "elasticsearch",
+				"hosts":    []interface{}{srv.URL},
+				"username": "elastic",
+				"password": "changeme",
+			},
+			response:          status(http.StatusUnauthorized),
+			wantErr:           `the user "elastic" of the Elasticsearch output was rejected`,
+			wantAuthorization: "Basic " + base64.StdEncoding.EncodeToString([]byte("elastic:changeme")),
+		},
+		"inconclusive server error": {
+			output:            apiKeyOutput,
+			response:          status(http.StatusServiceUnavailable),
+			wantAuthorization: apiKeyAuthorization,
+		},
+		"no credentials": {
+			output: map[string]interface{}{
+				"type":  "elasticsearch",
+				"hosts": []interface{}{srv.URL},
+			},
+			response: status(http.StatusUnauthorized),
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			log, _ := loggertest.New("outputcheck")
+			c := New(log)
+			response = tc.response
+			authorization = ""
+			results := c.Check(context.Background(), map[string]interface{}{"default": tc.output})
+			assert.Equal(t, tc.wantAuthorization, authorization,
+				"the credentials should be sent as in the Elasticsearch output")
+			if tc.wantErr == "" {
+				assert.Empty(t, results)
+				return
+			}
+			require.Contains(t, results, "default")
+			assert.ErrorContains(t, results["default"], tc.wantErr)
+		})
+	}
+}
+
+func TestCheckLogstash(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	// reserve a port nothing listens on
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedAddr := closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	log, _ := loggertest.New("outputcheck")
+	c := New(log)
+	results := c.Check(context.Background(), map[string]interface{}{
+		"reachable": map[string]interface{}{
+			"type":  "logstash",
+			"hosts": []interface{}{closedAddr, l.Addr().String()},
+		},
+		"unreachable": map[string]interface{}{
+			"type":  "logstash",
+			"hosts": []interface{}{closedAddr},
+		},
+		"disabled": map[string]interface{}{
+			"type":    "logstash",
+			"enabled": false,
+			"hosts":   []interface{}{closedAddr},
+		},
+		"kafka": map[string]interface{}{
+			"type":  "kafka",
+			"hosts": []interface{}{closedAddr},
+		},
+	})
+	require.Len(t, results, 1, "only the unreachable output should fail")
+	assert.ErrorContains(t, results["unreachable"], "no Logstash host reachable")
+}
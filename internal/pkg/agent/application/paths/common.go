@@ -8,6 +8,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -51,6 +52,9 @@ const (
 // ExternalInputsPattern is a glob that matches the paths of external configuration files.
 var ExternalInputsPattern = filepath.Join("inputs.d", "*.yml")
 
+// ExternalOutputsPattern is a glob that matches the paths of external output configuration files.
+var ExternalOutputsPattern = filepath.Join("outputs.d", "*.yml")
+
 var (
 	topPath           string
 	configPath        string
@@ -181,6 +185,16 @@ func ExternalInputs() string {
 	return filepath.Join(Config(), ExternalInputsPattern)
 }
 
+// ExternalOutputs returns the path to load external outputs from.
+func ExternalOutputs() string {
+	return filepath.Join(Config(), ExternalOutputsPattern)
+}
+
+// UserComponents returns the path to load the specifications of the custom components from.
+func UserComponents() string {
+	return filepath.Join(Config(), "components.d")
+}
+
 // Data returns the data directory for Agent
 func Data() string {
 	return DataFrom(Top())
@@ -195,6 +209,24 @@ func DataFrom(topDirPath string) string {
 	return filepath.Join(topDirPath, "data")
 }
 
+// RelocatedDataFrom returns the directory the data directory of the install at topDirPath was relocated to, the data
+// directory is then a symlink to it. Returns false when the data directory is not relocated.
+func RelocatedDataFrom(topDirPath string) (string, bool) {
+	dataPath := DataFrom(topDirPath)
+	info, err := os.Lstat(dataPath)
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		return "", false
+	}
+	target, err := os.Readlink(dataPath)
+	if err != nil {
+		return "", false
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(topDirPath, target)
+	}
+	return filepath.Clean(target), true
+}
+
 // Run returns the run directory for Agent
 func Run() string {
 	return filepath.Join(Home(), "run")
@@ -205,6 +237,13 @@ func Components() string {
 	return componentsPath
 }
 
+// SetComponents overrides the path to the components directory.
+//
+// Used when the agent is embedded in another process that ships the components in its own directory.
+func SetComponents(path string) {
+	componentsPath = path
+}
+
 // Logs returns the log directory for Agent
 func Logs() string {
 	return logsPath
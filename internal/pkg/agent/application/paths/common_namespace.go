@@ -7,6 +7,8 @@ package paths
 import (
 	"fmt"
 	"path/filepath"
+	"regexp"
+	"runtime"
 	"strings"
 )
 
@@ -24,8 +26,15 @@ const (
 	// Service display names. Must be different from the ServiceName() on Windows.
 	serviceDisplayName             = "Elastic Agent"
 	serviceDisplayNameNamespaceFmt = "Elastic Agent - %s"
+
+	// windowsControlSocketInstalledPathNamespaceFmt is the control socket path used when installed on Windows
+	// in an installation namespace.
+	windowsControlSocketInstalledPathNamespaceFmt = WindowsControlSocketInstalledPath + "-%s"
 )
 
+// validInstallNamespace matches the namespaces that are valid in a directory, a service and a named pipe name.
+var validInstallNamespace = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
 // installNamespace is the name of the agent's current installation namepsace.
 var installNamespace string
 
@@ -49,6 +58,7 @@ func InstallNamespace() string {
 		// Parse the namespace from the directory once to ensure deterministic behavior from startup.
 		namespace := parseNamespaceFromDir(filepath.Base(Top()))
 		installNamespace = namespace
+		return namespace
 	}
 
 	return ""
@@ -65,6 +75,14 @@ func parseNamespaceFromDir(dir string) string {
 	return parts[1]
 }
 
+// ValidateInstallNamespace returns an error when the namespace cannot be used as an installation namespace.
+func ValidateInstallNamespace(namespace string) error {
+	if !validInstallNamespace.MatchString(namespace) {
+		return fmt.Errorf("invalid installation namespace %q, it can only contain letters, digits, '-' and '_'", namespace)
+	}
+	return nil
+}
+
 // InInstallNamespace returns true if the agent is being installed in an installation namespace.
 func InInstallNamespace() bool {
 	return InstallNamespace() != ""
@@ -126,3 +144,24 @@ func ControlSocketRunSymlink(namespace string) string {
 
 	return controlSocketRunSymlinkForNamespace(namespace)
 }
+
+// WindowsControlSocketInstalledPathForNamespace returns the control socket path used when installed on Windows
+// accounting for the namespace, so agents installed side by side do not share a named pipe. The provided
+// namespace is always lowercased for consistency.
+func WindowsControlSocketInstalledPathForNamespace(namespace string) string {
+	if namespace == "" {
+		return WindowsControlSocketInstalledPath
+	}
+
+	return fmt.Sprintf(windowsControlSocketInstalledPathNamespaceFmt, strings.ToLower(namespace))
+}
+
+// InstalledControlSocket returns the control socket of the Elastic Agent installed at topPath in the namespace.
+// Used to reach the installed Elastic Agent from a process that is not running installed, like the installer.
+func InstalledControlSocket(topPath string, namespace string) string {
+	if runtime.GOOS == "windows" {
+		return WindowsControlSocketInstalledPathForNamespace(namespace)
+	}
+
+	return ControlSocketFromPath(runtime.GOOS, topPath)
+}
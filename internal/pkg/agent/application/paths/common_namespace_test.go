@@ -7,6 +7,7 @@ package paths
 import (
 	"fmt"
 	"path/filepath"
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -26,6 +27,7 @@ func TestInstallNamespace(t *testing.T) {
 	assert.Equal(t, fmt.Sprintf(serviceDisplayNameNamespaceFmt, namespace), ServiceDisplayName())
 	assert.Equal(t, ShellWrapperPathForNamespace(namespace), ShellWrapperPath())
 	assert.Equal(t, controlSocketRunSymlinkForNamespace(namespace), ControlSocketRunSymlink(namespace))
+	assert.Equal(t, WindowsControlSocketInstalledPath+"-testing", WindowsControlSocketInstalledPathForNamespace(namespace))
 }
 
 func TestInstallNoNamespace(t *testing.T) {
@@ -40,6 +42,33 @@ func TestInstallNoNamespace(t *testing.T) {
 	assert.Equal(t, serviceDisplayName, ServiceDisplayName())
 	assert.Equal(t, shellWrapperPath, ShellWrapperPath())
 	assert.Equal(t, controlSocketRunSymlink, ControlSocketRunSymlink(namespace))
+	assert.Equal(t, WindowsControlSocketInstalledPath, WindowsControlSocketInstalledPathForNamespace(namespace))
+}
+
+func TestWindowsControlSocketInstalledPathForNamespace(t *testing.T) {
+	// agents installed side by side must not share a named pipe, regardless of the case of the namespace
+	assert.Equal(t, "npipe:///elastic-agent-system-prod", WindowsControlSocketInstalledPathForNamespace("Prod"))
+	assert.NotEqual(t,
+		WindowsControlSocketInstalledPathForNamespace("prod"),
+		WindowsControlSocketInstalledPathForNamespace("test"))
+}
+
+func TestInstalledControlSocket(t *testing.T) {
+	topPath := filepath.Join("base", "path", "Elastic", "Agent-test")
+	want := ControlSocketFromPath(runtime.GOOS, topPath)
+	if runtime.GOOS == "windows" {
+		want = "npipe:///elastic-agent-system-test"
+	}
+	assert.Equal(t, want, InstalledControlSocket(topPath, "test"))
+}
+
+func TestValidateInstallNamespace(t *testing.T) {
+	for _, namespace := range []string{"prod", "Test_1", "with-dashes"} {
+		assert.NoErrorf(t, ValidateInstallNamespace(namespace), "namespace %q", namespace)
+	}
+	for _, namespace := range []string{"", "with spaces", "with/slash", `with\backslash`, "%s"} {
+		assert.Errorf(t, ValidateInstallNamespace(namespace), "namespace %q", namespace)
+	}
 }
 
 func TestParseNamespaceFromDirName(t *testing.T) {
@@ -6,6 +6,7 @@ package paths
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -156,3 +157,28 @@ func TestPathSplitWindows(t *testing.T) {
 		})
 	}
 }
+
+func TestRelocatedDataFrom(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires Administrator rights on Windows")
+	}
+	topPath := t.TempDir()
+	if err := os.MkdirAll(DataFrom(topPath), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := RelocatedDataFrom(topPath); ok {
+		t.Error("data directory reported as relocated")
+	}
+
+	relocated := t.TempDir()
+	if err := os.Remove(DataFrom(topPath)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(relocated, DataFrom(topPath)); err != nil {
+		t.Fatal(err)
+	}
+	target, ok := RelocatedDataFrom(topPath)
+	if !ok || target != relocated {
+		t.Errorf("expected data directory relocated to %q, got %q (relocated %t)", relocated, target, ok)
+	}
+}
@@ -14,6 +14,9 @@ import (
 // defaultAgentCapabilitiesFile is a name of file used to store agent capabilities
 const defaultAgentCapabilitiesFile = "capabilities.yml"
 
+// defaultAgentOperationsFile is a name of file used to allowlist the operations Fleet can request
+const defaultAgentOperationsFile = "operations.yml"
+
 // defaultAgentFleetYmlFile is a name of file used to store agent information
 const defaultAgentFleetYmlFile = "fleet.yml"
 
@@ -39,6 +42,17 @@ const defaultAgentStateStoreYmlFile = "state.yml"
 // store.
 const defaultAgentStateStoreFile = "state.enc"
 
+// defaultAgentOutputKeysFile is the file that contains the encrypted rotated API
+// keys of the outputs.
+const defaultAgentOutputKeysFile = "output_keys.enc"
+
+// defaultAgentPolicyHistoryFile is the file that contains the audit trail of the applied policies.
+const defaultAgentPolicyHistoryFile = "policy_history.ndjson"
+
+// defaultAgentPolicyRollbackFile is the file that contains the encrypted rollback of the policy and its
+// healthy revision.
+const defaultAgentPolicyRollbackFile = "policy_rollback.enc"
+
 // AgentConfigYmlFile is a name of file used to store agent information
 func AgentConfigYmlFile() string {
 	return filepath.Join(Config(), defaultAgentFleetYmlFile)
@@ -67,6 +81,11 @@ func AgentCapabilitiesPath() string {
 	return filepath.Join(Config(), defaultAgentCapabilitiesFile)
 }
 
+// AgentOperationsPath is a name of file used to allowlist the operations Fleet can request
+func AgentOperationsPath() string {
+	return filepath.Join(Config(), defaultAgentOperationsFile)
+}
+
 // AgentActionStoreFile is the file that contains the action that can be replayed after restart.
 func AgentActionStoreFile() string {
 	return filepath.Join(Home(), defaultAgentActionStoreFile)
@@ -81,3 +100,18 @@ func AgentStateStoreYmlFile() string {
 func AgentStateStoreFile() string {
 	return filepath.Join(Home(), defaultAgentStateStoreFile)
 }
+
+// AgentOutputKeysFile is the file that contains the rotated API keys of the outputs encrypted.
+func AgentOutputKeysFile() string {
+	return filepath.Join(Config(), defaultAgentOutputKeysFile)
+}
+
+// AgentPolicyHistoryFile is the file that contains the audit trail of the applied policies.
+func AgentPolicyHistoryFile() string {
+	return filepath.Join(Config(), defaultAgentPolicyHistoryFile)
+}
+
+// AgentPolicyRollbackFile is the file that contains the rollback of the policy and its healthy revision encrypted.
+func AgentPolicyRollbackFile() string {
+	return filepath.Join(Config(), defaultAgentPolicyRollbackFile)
+}
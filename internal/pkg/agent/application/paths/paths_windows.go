@@ -52,9 +52,9 @@ func ArePathsEqual(expected, actual string) bool {
 }
 
 func initialControlSocketPath(topPath string) string {
-	// when installed the control address is fixed
+	// when installed the control address is fixed for the installation namespace
 	if RunningInstalled() {
-		return WindowsControlSocketInstalledPath
+		return WindowsControlSocketInstalledPathForNamespace(InstallNamespace())
 	}
 	return ControlSocketFromPath(runtime.GOOS, topPath)
 }
@@ -69,7 +69,7 @@ func ResolveControlSocket(runningInstalled bool) {
 	if currentPath == ControlSocketFromPath(runtime.GOOS, topPath) && runningInstalled {
 		// path is not correct being that it's installed
 		// reset the control socket path to be the installed path
-		SetControlSocket(WindowsControlSocketInstalledPath)
+		SetControlSocket(WindowsControlSocketInstalledPathForNamespace(InstallNamespace()))
 	}
 }
 
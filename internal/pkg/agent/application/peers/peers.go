@@ -0,0 +1,274 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package peers exchanges lightweight health beacons between the agents of the same site.
+//
+// Every agent periodically sends a beacon over UDP to the broadcast address of the configured subnets and to
+// the configured hosts, and keeps track of the beacons it receives from the other agents of its site. The
+// resulting health is reported on checkin and tells whether an agent failing to reach Fleet is alone or
+// whether the whole site lost its network.
+package peers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// maxBeaconSize is the maximum size of a beacon, larger datagrams are dropped.
+	maxBeaconSize = 1024
+
+	// peerExpiry is the time after which a peer that sent no beacon is forgotten, it is no longer reported as
+	// unreachable.
+	peerExpiry = 24 * time.Hour
+)
+
+// beacon is the message exchanged between peers. It carries no sensitive information.
+type beacon struct {
+	AgentID string `json:"agent_id"`
+	Site    string `json:"site"`
+	Healthy bool   `json:"healthy"`
+}
+
+type peer struct {
+	healthy  bool
+	lastSeen time.Time
+}
+
+// Manager sends beacons to the peers of the agent and tracks the beacons received from them.
+type Manager struct {
+	log     *logger.Logger
+	cfg     *configuration.PeersConfig
+	agentID string
+	healthy func() bool
+
+	subnets []*net.IPNet
+	targets []*net.UDPAddr
+	// hosts are the IPs of the configured hosts, mapped to their configured address, a host is reported by
+	// address until a beacon is received from it.
+	hosts map[string]string
+	now   func() time.Time
+
+	mx    sync.Mutex
+	peers map[string]*peer
+	// hostPeers are the IDs of the agents of the configured hosts a beacon was received from.
+	hostPeers map[string]string
+}
+
+// New creates a manager for the agent with the given ID. The healthy function reports the health of the agent
+// sent in its beacons.
+func New(log *logger.Logger, cfg *configuration.PeersConfig, agentID string, healthy func() bool) (*Manager, error) {
+	if cfg.Port <= 0 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("invalid peers port %d", cfg.Port)
+	}
+	if cfg.Interval <= 0 {
+		return nil, fmt.Errorf("invalid peers interval %s", cfg.Interval)
+	}
+	if cfg.Timeout < cfg.Interval {
+		return nil, fmt.Errorf("peers timeout %s must not be lower than the interval %s", cfg.Timeout, cfg.Interval)
+	}
+
+	m := &Manager{
+		log:       log,
+		cfg:       cfg,
+		agentID:   agentID,
+		healthy:   healthy,
+		hosts:     make(map[string]string),
+		now:       time.Now,
+		peers:     make(map[string]*peer),
+		hostPeers: make(map[string]string),
+	}
+	for _, subnet := range cfg.Subnets {
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peers subnet %q: %w", subnet, err)
+		}
+		ip4 := ipNet.IP.To4()
+		if ip4 == nil {
+			return nil, fmt.Errorf("invalid peers subnet %q: only IPv4 subnets support broadcast", subnet)
+		}
+		m.subnets = append(m.subnets, ipNet)
+		broadcast := make(net.IP, len(ip4))
+		for i := range ip4 {
+			broadcast[i] = ip4[i] | ^ipNet.Mask[i]
+		}
+		m.targets = append(m.targets, &net.UDPAddr{IP: broadcast, Port: cfg.Port})
+	}
+	for _, host := range cfg.Hosts {
+		addr, err := resolveHost(host, cfg.Port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peers host %q: %w", host, err)
+		}
+		m.targets = append(m.targets, addr)
+		m.hosts[addr.IP.String()] = host
+	}
+	if len(m.targets) == 0 {
+		return nil, errors.New("peers require at least one subnet or host")
+	}
+	return m, nil
+}
+
+// resolveHost resolves a host, with an optional port, to an UDP address.
+func resolveHost(host string, defaultPort int) (*net.UDPAddr, error) {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, strconv.Itoa(defaultPort))
+	}
+	return net.ResolveUDPAddr("udp", host)
+}
+
+// Run sends and receives beacons until the context is done.
+func (m *Manager) Run(ctx context.Context) error {
+	conn, err := net.ListenPacket("udp", net.JoinHostPort("", strconv.Itoa(m.cfg.Port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen for peer beacons: %w", err)
+	}
+	return m.run(ctx, conn)
+}
+
+func (m *Manager) run(ctx context.Context, conn net.PacketConn) error {
+	go func() {
+		<-ctx.Done()
+		_ = conn.Close()
+	}()
+
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		m.receive(conn)
+	}()
+
+	t := time.NewTicker(m.cfg.Interval)
+	defer t.Stop()
+	for {
+		m.send(conn)
+		select {
+		case <-ctx.Done():
+			<-received
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Manager) send(conn net.PacketConn) {
+	msg, err := json.Marshal(beacon{AgentID: m.agentID, Site: m.cfg.Site, Healthy: m.healthy()})
+	if err != nil {
+		m.log.Errorf("failed to encode peer beacon: %v", err)
+		return
+	}
+	for _, target := range m.targets {
+		if _, err := conn.WriteTo(msg, target); err != nil {
+			m.log.Debugf("failed to send peer beacon to %s: %v", target, err)
+		}
+	}
+}
+
+func (m *Manager) receive(conn net.PacketConn) {
+	buf := make([]byte, maxBeaconSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			m.log.Debugf("failed to receive peer beacon: %v", err)
+			continue
+		}
+		udpAddr, ok := addr.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		var b beacon
+		if err := json.Unmarshal(buf[:n], &b); err != nil {
+			m.log.Debugf("dropping invalid peer beacon from %s: %v", addr, err)
+			continue
+		}
+		m.handle(udpAddr.IP, b)
+	}
+}
+
+// handle records a beacon received from the IP, beacons of the agent itself, of other sites or from
+// addresses that are neither in the subnets nor configured hosts are ignored.
+func (m *Manager) handle(ip net.IP, b beacon) {
+	if b.AgentID == "" || b.AgentID == m.agentID || b.Site != m.cfg.Site {
+		return
+	}
+	_, isHost := m.hosts[ip.String()]
+	if !isHost && !m.inSubnets(ip) {
+		return
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if isHost {
+		m.hostPeers[ip.String()] = b.AgentID
+	}
+	m.peers[b.AgentID] = &peer{healthy: b.Healthy, lastSeen: m.now()}
+}
+
+func (m *Manager) inSubnets(ip net.IP) bool {
+	for _, subnet := range m.subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Health returns the health of the peers of the agent.
+func (m *Manager) Health() *fleetapi.PeerHealth {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+
+	now := m.now()
+	health := &fleetapi.PeerHealth{Site: m.cfg.Site}
+	for id, p := range m.peers {
+		since := now.Sub(p.lastSeen)
+		if since > peerExpiry {
+			delete(m.peers, id)
+			continue
+		}
+		health.Peers++
+		if since > m.cfg.Timeout {
+			health.Unreachable = append(health.Unreachable, id)
+			continue
+		}
+		health.Reachable++
+		if !p.healthy {
+			health.Unhealthy = append(health.Unhealthy, id)
+		}
+	}
+	// configured hosts not heard of for too long are unreachable, they are only known by their address
+	for ip, host := range m.hosts {
+		if _, ok := m.peers[m.hostPeers[ip]]; !ok {
+			health.Peers++
+			health.Unreachable = append(health.Unreachable, host)
+		}
+	}
+	sort.Strings(health.Unreachable)
+	sort.Strings(health.Unhealthy)
+
+	switch {
+	case health.Peers == 0:
+		health.Message = "no peers discovered"
+	case health.Reachable == 0:
+		health.Message = fmt.Sprintf("none of the %d peers are reachable, the site network may be down", health.Peers)
+	case len(health.Unreachable) > 0:
+		health.Message = fmt.Sprintf("%d of %d peers are unreachable", len(health.Unreachable), health.Peers)
+	default:
+		health.Message = fmt.Sprintf("all %d peers are reachable", health.Peers)
+	}
+	return health
+}
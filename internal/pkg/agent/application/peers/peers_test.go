@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peers
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func newTestConfig() *configuration.PeersConfig {
+	cfg := configuration.DefaultPeersConfig()
+	cfg.Enabled = true
+	cfg.Site = "dc1"
+	return cfg
+}
+
+func TestNew(t *testing.T) {
+	log, _ := loggertest.New("peers")
+	healthy := func() bool { return true }
+
+	t.Run("broadcast address of the subnets", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Subnets = []string{"10.1.2.0/24", "192.168.0.0/16"}
+		m, err := New(log, cfg, "agent", healthy)
+		require.NoError(t, err)
+		require.Len(t, m.targets, 2)
+		assert.Equal(t, "10.1.2.255:6795", m.targets[0].String())
+		assert.Equal(t, "192.168.255.255:6795", m.targets[1].String())
+	})
+
+	t.Run("hosts with and without port", func(t *testing.T) {
+		cfg := newTestConfig()
+		cfg.Hosts = []string{"10.0.0.1", "10.0.0.2:7000"}
+		m, err := New(log, cfg, "agent", healthy)
+		require.NoError(t, err)
+		require.Len(t, m.targets, 2)
+		assert.Equal(t, "10.0.0.1:6795", m.targets[0].String())
+		assert.Equal(t, "10.0.0.2:7000", m.targets[1].String())
+	})
+
+	t.Run("invalid configurations", func(t *testing.T) {
+		for name, mutate := range map[string]func(*configuration.PeersConfig){
+			"no targets":     func(*configuration.PeersConfig) {},
+			"invalid subnet": func(cfg *configuration.PeersConfig) { cfg.Subnets = []string{"10.0.0.0"} },
+			"ipv6 subnet":    func(cfg *configuration.PeersConfig) { cfg.Subnets = []string{"fd00::/64"} },
+			"invalid port": func(cfg *configuration.PeersConfig) {
+				cfg.Hosts = []string{"10.0.0.1"}
+				cfg.Port = 0
+			},
+			"timeout lower than interval": func(cfg *configuration.PeersConfig) {
+				cfg.Hosts = []string{"10.0.0.1"}
+				cfg.Timeout = time.Second
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				cfg := newTestConfig()
+				mutate(cfg)
+				_, err := New(log, cfg, "agent", healthy)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestHealth(t *testing.T) {
+	log, _ := loggertest.New("peers")
+	cfg := newTestConfig()
+	cfg.Subnets = []string{"10.1.2.0/24"}
+	cfg.Hosts = []string{"10.9.0.1"}
+	m, err := New(log, cfg, "agent", func() bool { return true })
+	require.NoError(t, err)
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	health := m.Health()
+	assert.Equal(t, 1, health.Peers)
+	assert.Equal(t, []string{"10.9.0.1"}, health.Unreachable)
+	assert.Equal(t, "none of the 1 peers are reachable, the site network may be down", health.Message)
+
+	subnetIP := net.ParseIP("10.1.2.3")
+	m.handle(subnetIP, beacon{AgentID: "peer-1", Site: "dc1", Healthy: true})
+	m.handle(subnetIP, beacon{AgentID: "peer-2", Site: "dc1", Healthy: false})
+	m.handle(net.ParseIP("10.9.0.1"), beacon{AgentID: "peer-3", Site: "dc1", Healthy: true})
+	// ignored: the agent itself, another site and an address outside of the subnets and hosts
+	m.handle(subnetIP, beacon{AgentID: "agent", Site: "dc1", Healthy: true})
+	m.handle(subnetIP, beacon{AgentID: "peer-4", Site: "dc2", Healthy: true})
+	m.handle(net.ParseIP("10.1.3.1"), beacon{AgentID: "peer-5", Site: "dc1", Healthy: true})
+
+	health = m.Health()
+	assert.Equal(t, "dc1", health.Site)
+	assert.Equal(t, 3, health.Peers)
+	assert.Equal(t, 3, health.Reachable)
+	assert.Empty(t, health.Unreachable)
+	assert.Equal(t, []string{"peer-2"}, health.Unhealthy)
+	assert.Equal(t, "all 3 peers are reachable", health.Message)
+
+	now = now.Add(cfg.Timeout + time.Second)
+	m.handle(subnetIP, beacon{AgentID: "peer-1", Site: "dc1", Healthy: true})
+	health = m.Health()
+	assert.Equal(t, 3, health.Peers)
+	assert.Equal(t, 1, health.Reachable)
+	assert.Equal(t, []string{"peer-2", "peer-3"}, health.Unreachable)
+	assert.Empty(t, health.Unhealthy)
+	assert.Equal(t, "2 of 3 peers are unreachable", health.Message)
+
+	// expired peers are forgotten, except the configured hosts
+	now = now.Add(peerExpiry + time.Second)
+	health = m.Health()
+	assert.Equal(t, 1, health.Peers)
+	assert.Equal(t, []string{"10.9.0.1"}, health.Unreachable)
+
+	cfg.Hosts = nil
+	m, err = New(log, cfg, "agent", func() bool { return true })
+	require.NoError(t, err)
+	assert.Equal(t, "no peers discovered", m.Health().Message)
+}
+
+func TestRun(t *testing.T) {
+	log, _ := loggertest.New("peers")
+
+	conn1, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	conn2, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	newManager := func(agentID string, peer net.Addr) *Manager {
+		cfg := newTestConfig()
+		cfg.Interval = 10 * time.Millisecond
+		cfg.Hosts = []string{peer.String()}
+		m, err := New(log, cfg, agentID, func() bool { return true })
+		require.NoError(t, err)
+		return m
+	}
+	m1 := newManager("agent-1", conn2.LocalAddr())
+	m2 := newManager("agent-2", conn1.LocalAddr())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 2)
+	go func() { done <- m1.run(ctx, conn1) }()
+	go func() { done <- m2.run(ctx, conn2) }()
+
+	assert.Eventually(t, func() bool {
+		h1, h2 := m1.Health(), m2.Health()
+		return h1.Reachable == 1 && h2.Reachable == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.NoError(t, <-done)
+}
@@ -6,6 +6,7 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -23,8 +24,15 @@ type periodic struct {
 	watcher  *filewatcher.Watch
 	loader   *config.Loader
 	discover config.DiscoverFunc
+	// validate gates the reloaded configuration, an invalid one is reported and the previous one is kept
+	validate func(*config.Config) error
 	ch       chan coordinator.ConfigChange
 	errCh    chan error
+
+	// applied is true once a configuration has been sent
+	applied bool
+	// invalid is true while the last reloaded configuration is reported as invalid
+	invalid bool
 }
 
 func (p *periodic) Run(ctx context.Context) error {
@@ -104,18 +112,32 @@ func (p *periodic) work(ctx context.Context) error {
 		}
 
 		cfg, err := readfiles(files, p.loader)
+		if err == nil {
+			err = p.validate(cfg)
+		}
 		if err != nil {
-			// assume something when really wrong and invalidate any cache
-			// so we get a full new config on next tick.
-			p.watcher.Invalidate()
-			return err
+			if !p.applied {
+				// assume something when really wrong and invalidate any cache
+				// so we get a full new config on next tick.
+				p.watcher.Invalidate()
+				return err
+			}
+			// the files are only reloaded again once they change
+			p.log.Errorf("Invalid configuration, keeping the previous configuration: %s", err)
+			p.invalid = true
+			return p.reportError(ctx, fmt.Errorf("invalid configuration, keeping the previous configuration: %w", err))
 		}
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case p.ch <- &localConfigChange{cfg}:
+		case p.ch <- &localConfigChange{cfg: cfg, user: changedBy(s.Updated)}:
 		}
+		p.applied = true
 
+		if p.invalid {
+			p.invalid = false
+			return p.reportError(ctx, nil)
+		}
 		return nil
 	}
 
@@ -123,11 +145,22 @@ func (p *periodic) work(ctx context.Context) error {
 	return nil
 }
 
+// reportError reports the error to the Coordinator, a nil error clears the reported one.
+func (p *periodic) reportError(ctx context.Context, err error) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case p.errCh <- err:
+	}
+	return nil
+}
+
 func newPeriodic(
 	log *logger.Logger,
 	period time.Duration,
 	discover config.DiscoverFunc,
 	loader *config.Loader,
+	validate func(*config.Config) error,
 ) *periodic {
 	w, err := filewatcher.New(log, filewatcher.DefaultComparer)
 
@@ -142,6 +175,7 @@ func newPeriodic(
 		watcher:  w,
 		discover: discover,
 		loader:   loader,
+		validate: validate,
 		ch:       make(chan coordinator.ConfigChange),
 		errCh:    make(chan error),
 	}
@@ -149,6 +183,8 @@ func newPeriodic(
 
 type localConfigChange struct {
 	cfg *config.Config
+	// user is the owner of the changed configuration files, recorded in the policy history
+	user string
 }
 
 func (l *localConfigChange) Config() *config.Config {
@@ -163,3 +199,19 @@ func (l *localConfigChange) Ack() error {
 func (l *localConfigChange) Fail(_ error) {
 	// do nothing
 }
+
+func (l *localConfigChange) Origin() coordinator.ConfigChangeOrigin {
+	return coordinator.ConfigChangeOrigin{Source: "local", User: l.user}
+}
+
+// changedBy returns the owner of the first changed configuration file, empty when it cannot be resolved.
+func changedBy(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	owner, err := fileOwner(files[0])
+	if err != nil {
+		return ""
+	}
+	return owner
+}
@@ -0,0 +1,374 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package policyhistory keeps a local audit trail of the policy revisions applied by the agent, to troubleshoot
+// what changed and when without access to Fleet.
+//
+// Each applied revision is appended as a JSON line to the history file with the hash of the policy, where the
+// change came from, a summary of the changed sections and the policy itself with the secret values redacted. The
+// file is compacted to the most recent revisions once it holds too many of them.
+package policyhistory
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// DefaultMaxRevisions is the number of revisions kept in the history file.
+const DefaultMaxRevisions = 100
+
+// ErrRevisionNotFound is returned when the requested revision is not in the history.
+var ErrRevisionNotFound = errors.New("revision not found in the policy history")
+
+// Entry is an applied revision of the policy.
+type Entry struct {
+	// Revision is the local revision number, incremented with each applied policy.
+	Revision int `json:"revision" yaml:"revision"`
+	// Time is when the policy was applied.
+	Time time.Time `json:"time" yaml:"time"`
+	// Hash is the SHA-256 of the policy before the secret values are redacted.
+	Hash string `json:"hash" yaml:"hash"`
+	// Source is where the policy comes from, fleet or local.
+	Source string `json:"source" yaml:"source"`
+	// ActionID is the ID of the Fleet action delivering the policy.
+	ActionID string `json:"action_id,omitempty" yaml:"action_id,omitempty"`
+	// User is the user that changed the local configuration.
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+	// PolicyID and PolicyRevision identify the policy in Fleet.
+	PolicyID       string `json:"policy_id,omitempty" yaml:"policy_id,omitempty"`
+	PolicyRevision int    `json:"policy_revision,omitempty" yaml:"policy_revision,omitempty"`
+	// Changes summarizes the sections changed from the previous revision.
+	Changes Changes `json:"changes" yaml:"changes"`
+	// Policy is the applied policy with the secret values redacted.
+	Policy map[string]interface{} `json:"policy,omitempty" yaml:"policy,omitempty"`
+}
+
+// Changes lists the sections of the policy added, removed and changed by a revision. The sections are the inputs
+// by ID, the outputs by name and the other keys of the agent and top level sections.
+type Changes struct {
+	Added   []string `json:"added,omitempty" yaml:"added,omitempty"`
+	Removed []string `json:"removed,omitempty" yaml:"removed,omitempty"`
+	Changed []string `json:"changed,omitempty" yaml:"changed,omitempty"`
+}
+
+// Recorder appends the applied policies to the history file.
+type Recorder struct {
+	log          *logger.Logger
+	path         string
+	maxRevisions int
+	now          func() time.Time
+
+	mx sync.Mutex
+	// last is the most recent entry, loaded from the file on the first record
+	last   *Entry
+	loaded bool
+}
+
+// NewRecorder creates a recorder appending to the history file at path.
+func NewRecorder(log *logger.Logger, path string) *Recorder {
+	return &Recorder{
+		log:          log,
+		path:         path,
+		maxRevisions: DefaultMaxRevisions,
+		now:          time.Now,
+	}
+}
+
+// Record appends the policy to the history unless it is the same as the most recent revision.
+func (r *Recorder) Record(policy map[string]interface{}, origin coordinator.ConfigChangeOrigin) error {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if !r.loaded {
+		entries, err := Read(r.path)
+		if err != nil {
+			return err
+		}
+		if len(entries) > 0 {
+			r.last = &entries[len(entries)-1]
+		}
+		r.loaded = true
+	}
+
+	hash, err := hashPolicy(policy)
+	if err != nil {
+		return err
+	}
+	if r.last != nil && r.last.Hash == hash {
+		// re-applied without change, e.g. on restart
+		return nil
+	}
+
+	redacted, err := r.redact(policy)
+	if err != nil {
+		return err
+	}
+	entry := Entry{
+		Revision: 1,
+		Time:     r.now().UTC(),
+		Hash:     hash,
+		Source:   origin.Source,
+		ActionID: origin.ActionID,
+		User:     origin.User,
+		Policy:   redacted,
+	}
+	entry.PolicyID, entry.PolicyRevision = fleetRevision(redacted)
+	if r.last != nil {
+		entry.Revision = r.last.Revision + 1
+		entry.Changes = diff(r.last.Policy, redacted)
+	} else {
+		entry.Changes = diff(nil, redacted)
+	}
+
+	if err := r.append(entry); err != nil {
+		return err
+	}
+	r.last = &entry
+	r.log.Infof("Recorded revision %d of the policy from %s in the policy history", entry.Revision, entry.Source)
+	return nil
+}
+
+// redact returns the policy with the secret values redacted, including the values listed in the
+// agent.secret_paths of the policy.
+func (r *Recorder) redact(policy map[string]interface{}) (map[string]interface{}, error) {
+	cfg, err := config.NewConfigFrom(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy: %w", err)
+	}
+	if err := diagnostics.AddSecretMarkers(r.log, cfg); err != nil {
+		// the secret paths found are still marked
+		r.log.Warnf("Failed to mark the secret paths of the policy for redaction: %v", err)
+	}
+	marked, err := cfg.ToMapStr()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy: %w", err)
+	}
+	// the numbers are decoded as float64 from the file, compare with the policy as it is stored
+	return normalize(diagnostics.Redact(marked, io.Discard))
+}
+
+func (r *Recorder) append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the policy history entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0750); err != nil {
+		return fmt.Errorf("failed to create the policy history directory: %w", err)
+	}
+	// the policy is redacted but still describes the whole deployment, only the agent reads it
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the policy history: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to append to the policy history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close the policy history: %w", err)
+	}
+
+	if entry.Revision%r.maxRevisions == 0 {
+		return r.compact()
+	}
+	return nil
+}
+
+// compact rewrites the history file with the most recent revisions only.
+func (r *Recorder) compact() error {
+	entries, err := Read(r.path)
+	if err != nil {
+		return err
+	}
+	if len(entries) <= r.maxRevisions {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries[len(entries)-r.maxRevisions:] {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal the policy history entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to compact the policy history: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("failed to compact the policy history: %w", err)
+	}
+	return nil
+}
+
+// Read returns the revisions of the history file at path, oldest first. A missing file is an empty history, a
+// truncated last line left by a crash is skipped.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the policy history: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 && err == nil {
+			var entry Entry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return nil, fmt.Errorf("failed to read the policy history entry %d: %w", len(entries)+1, err)
+			}
+			entries = append(entries, entry)
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the policy history: %w", err)
+		}
+	}
+}
+
+// Find returns the given revision of the history file at path.
+func Find(path string, revision int) (Entry, error) {
+	entries, err := Read(path)
+	if err != nil {
+		return Entry{}, err
+	}
+	for _, entry := range entries {
+		if entry.Revision == revision {
+			return entry, nil
+		}
+	}
+	return Entry{}, fmt.Errorf("%w: %d", ErrRevisionNotFound, revision)
+}
+
+// hashPolicy hashes the JSON encoding of the policy, the keys of the maps are sorted by the encoding.
+func hashPolicy(policy map[string]interface{}) (string, error) {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash the policy: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalize returns the policy as it is decoded from the history file.
+func normalize(policy map[string]interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the policy: %w", err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the policy: %w", err)
+	}
+	return result, nil
+}
+
+// fleetRevision returns the ID and the revision of a policy from Fleet.
+func fleetRevision(policy map[string]interface{}) (string, int) {
+	id, _ := policy["id"].(string)
+	switch revision := policy["revision"].(type) {
+	case int:
+		return id, revision
+	case int64:
+		return id, int(revision)
+	case uint64:
+		return id, int(revision)
+	case float64:
+		return id, int(revision)
+	}
+	return id, 0
+}
+
+// diff summarizes the sections changed between two policies.
+func diff(prev map[string]interface{}, next map[string]interface{}) Changes {
+	prevSections := sections(prev)
+	nextSections := sections(next)
+
+	var changes Changes
+	for name, value := range nextSections {
+		prevValue, ok := prevSections[name]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, name)
+		case !reflect.DeepEqual(prevValue, value):
+			changes.Changed = append(changes.Changed, name)
+		}
+	}
+	for name := range prevSections {
+		if _, ok := nextSections[name]; !ok {
+			changes.Removed = append(changes.Removed, name)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Changed)
+	return changes
+}
+
+// sections splits a policy into the sections compared by diff.
+func sections(policy map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range policy {
+		switch key {
+		case "inputs":
+			inputs, ok := value.([]interface{})
+			if !ok {
+				result[key] = value
+				continue
+			}
+			for i, input := range inputs {
+				name := "inputs." + strconv.Itoa(i)
+				if m, ok := input.(map[string]interface{}); ok {
+					if id, ok := m["id"].(string); ok && id != "" {
+						name = "inputs." + id
+					}
+				}
+				result[name] = input
+			}
+		case "outputs", "agent":
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				result[key] = value
+				continue
+			}
+			for name, section := range m {
+				result[key+"."+name] = section
+			}
+		case "revision":
+			// tracked by the entry, changes with every policy from Fleet
+		default:
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package policyhistory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func testPolicy(revision int, inputs ...string) map[string]interface{} {
+	policy := map[string]interface{}{
+		"id":       "policy-1",
+		"revision": revision,
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"type":    "elasticsearch",
+				"api_key": "id:secret",
+			},
+		},
+		"agent": map[string]interface{}{
+			"logging": map[string]interface{}{"level": "info"},
+		},
+	}
+	var list []interface{}
+	for _, id := range inputs {
+		list = append(list, map[string]interface{}{"id": id, "type": "filestream", "paths": []interface{}{"/var/log/" + id}})
+	}
+	policy["inputs"] = list
+	return policy
+}
+
+func TestRecorder(t *testing.T) {
+	log, _ := loggertest.New("policy_history")
+	path := filepath.Join(t.TempDir(), "policy_history.ndjson")
+	now := time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+	r := NewRecorder(log, path)
+	r.now = func() time.Time { return now }
+
+	require.NoError(t, r.Record(testPolicy(1, "logs-1", "logs-2"), coordinator.ConfigChangeOrigin{Source: "fleet", ActionID: "action-1"}))
+	require.NoError(t, r.Record(testPolicy(1, "logs-1", "logs-2"), coordinator.ConfigChangeOrigin{Source: "fleet"}), "the same policy is not recorded again")
+
+	second := testPolicy(2, "logs-1", "logs-3")
+	second["agent"].(map[string]interface{})["logging"] = map[string]interface{}{"level": "debug"}
+	require.NoError(t, r.Record(second, coordinator.ConfigChangeOrigin{Source: "fleet", ActionID: "action-2"}))
+
+	entries, err := Read(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, 1, entries[0].Revision)
+	assert.Equal(t, now, entries[0].Time)
+	assert.Equal(t, "action-1", entries[0].ActionID)
+	assert.Equal(t, "policy-1", entries[0].PolicyID)
+	assert.Equal(t, 1, entries[0].PolicyRevision)
+	assert.Equal(t, []string{"agent.logging", "id", "inputs.logs-1", "inputs.logs-2", "outputs.default"}, entries[0].Changes.Added)
+
+	assert.Equal(t, 2, entries[1].Revision)
+	assert.Equal(t, "action-2", entries[1].ActionID)
+	assert.Equal(t, 2, entries[1].PolicyRevision)
+	assert.Equal(t, Changes{
+		Added:   []string{"inputs.logs-3"},
+		Removed: []string{"inputs.logs-2"},
+		Changed: []string{"agent.logging"},
+	}, entries[1].Changes)
+	assert.NotEqual(t, entries[0].Hash, entries[1].Hash)
+	assert.NotContains(t, entries[1].Policy["outputs"].(map[string]interface{})["default"].(map[string]interface{})["api_key"], "secret", "the secrets are redacted")
+
+	// a new recorder continues the history of the file
+	r = NewRecorder(log, path)
+	require.NoError(t, r.Record(testPolicy(2, "logs-1"), coordinator.ConfigChangeOrigin{Source: "local", User: "root"}))
+	entry, err := Find(path, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "root", entry.User)
+	assert.Equal(t, Changes{
+		Removed: []string{"inputs.logs-3"},
+		Changed: []string{"agent.logging"},
+	}, entry.Changes, "the numbers read from the file are not reported as changed")
+
+	_, err = Find(path, 4)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestRecorderRedactsSecretPaths(t *testing.T) {
+	log, _ := loggertest.New("policy_history")
+	path := filepath.Join(t.TempDir(), "policy_history.ndjson")
+	r := NewRecorder(log, path)
+
+	policy := testPolicy(1, "logs-1")
+	policy["inputs"].([]interface{})[0].(map[string]interface{})["custom"] = "cleartext"
+	policy["secret_paths"] = []interface{}{"inputs.0.custom"}
+	require.NoError(t, r.Record(policy, coordinator.ConfigChangeOrigin{Source: "fleet"}))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "cleartext", "the values of the secret paths are redacted")
+	assert.NotContains(t, string(raw), "__mark_redact_", "the redaction markers are not recorded")
+
+	entries, err := Read(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	input := entries[0].Policy["inputs"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "<REDACTED>", input["custom"])
+	assert.Equal(t, "logs-1", input["id"])
+}
+
+func TestRecorderCompacts(t *testing.T) {
+	log, _ := loggertest.New("policy_history")
+	path := filepath.Join(t.TempDir(), "policy_history.ndjson")
+	r := NewRecorder(log, path)
+	r.maxRevisions = 3
+
+	for i := 1; i <= 7; i++ {
+		require.NoError(t, r.Record(testPolicy(i), coordinator.ConfigChangeOrigin{Source: "fleet"}))
+	}
+
+	entries, err := Read(path)
+	require.NoError(t, err)
+	var revisions []int
+	for _, entry := range entries {
+		revisions = append(revisions, entry.Revision)
+	}
+	assert.Equal(t, []int{4, 5, 6, 7}, revisions)
+}
+
+func TestReadSkipsTruncatedLine(t *testing.T) {
+	log, _ := loggertest.New("policy_history")
+	path := filepath.Join(t.TempDir(), "policy_history.ndjson")
+	r := NewRecorder(log, path)
+	require.NoError(t, r.Record(testPolicy(1), coordinator.ConfigChangeOrigin{Source: "fleet"}))
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"revision":2,"ti`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	entries, err := Read(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
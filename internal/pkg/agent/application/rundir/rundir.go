@@ -0,0 +1,238 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package rundir removes the run directories no component uses anymore.
+//
+// Every component keeps its state in <home>/run/<component-id>. The directory outlives the component once it is
+// removed from the policy, and the run directory of a versioned home is left behind when an upgrade fails before
+// the new agent is installed. Both are removed once they have not been modified for the retention period.
+package rundir
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// startDelay is the delay before the first cleanup, giving the coordinator the time to compute the components of
+// the policy.
+const startDelay = 5 * time.Minute
+
+// versionedHomePrefix is the prefix of the versioned homes in the data directory.
+const versionedHomePrefix = "elastic-agent-"
+
+// Collector removes the orphaned run directories.
+type Collector struct {
+	log *logger.Logger
+	cfg *configuration.RunCleanupConfig
+
+	// top is the top directory of the installation, home the versioned home of the running agent.
+	top  string
+	home string
+	// components returns the IDs of the components of the current model, none when the model is not known.
+	components func() []string
+
+	now        func() time.Time
+	startDelay time.Duration
+}
+
+// New creates a collector of the run directories of the running agent, keeping the directories of the given
+// components.
+func New(log *logger.Logger, cfg *configuration.RunCleanupConfig, components func() []string) *Collector {
+	return &Collector{
+		log:        log,
+		cfg:        cfg,
+		top:        paths.Top(),
+		home:       paths.Home(),
+		components: components,
+		now:        time.Now,
+		startDelay: startDelay,
+	}
+}
+
+// Run collects the orphaned run directories shortly after startup and then periodically, until the context is
+// done. Without a positive interval they are only collected once.
+func (c *Collector) Run(ctx context.Context) {
+	t := time.NewTimer(c.startDelay)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		c.Collect()
+		if c.cfg.Interval <= 0 {
+			return
+		}
+		t.Reset(c.cfg.Interval)
+	}
+}
+
+// Collect removes the orphaned run directories and returns the number of bytes reclaimed.
+func (c *Collector) Collect() int64 {
+	var reclaimed int64
+	keep := make(map[string]struct{})
+	for _, id := range c.components() {
+		keep[filepath.ToSlash(id)] = struct{}{}
+	}
+	if len(keep) > 0 {
+		reclaimed += c.collectComponents(filepath.Join(c.home, "run"), "", keep)
+	} else {
+		// an empty model is not computed yet or failed, the run directories of every component would be removed
+		c.log.Debug("not collecting the run directories of the components, the component model is empty")
+	}
+
+	for _, home := range c.failedHomes() {
+		reclaimed += c.remove(filepath.Join(home, "run"), "left by a failed upgrade")
+	}
+	if reclaimed > 0 {
+		c.log.Infof("reclaimed %d bytes of orphaned run directories", reclaimed)
+	}
+	return reclaimed
+}
+
+// collectComponents removes the directories of dir that belong to none of the components. Component IDs can
+// contain slashes, the directories that are a prefix of the ID of a component are walked into.
+func (c *Collector) collectComponents(dir, prefix string, keep map[string]struct{}) int64 {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.log.Warnf("failed to list run directory %s: %v", dir, err)
+		}
+		return 0
+	}
+
+	var reclaimed int64
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := prefix + entry.Name()
+		if _, ok := keep[id]; ok {
+			continue
+		}
+		fullPath := filepath.Join(dir, entry.Name())
+		if hasPrefix(keep, id+"/") {
+			reclaimed += c.collectComponents(fullPath, id+"/", keep)
+			continue
+		}
+		reclaimed += c.remove(fullPath, "of removed component "+id)
+	}
+	return reclaimed
+}
+
+func hasPrefix(keep map[string]struct{}, prefix string) bool {
+	for id := range keep {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// failedHomes returns the versioned homes, other than the running one, that have no agent binary and are not
+// referenced by the upgrade marker.
+func (c *Collector) failedHomes() []string {
+	dataDir := filepath.Dir(c.home)
+	if filepath.Clean(dataDir) != filepath.Clean(paths.DataFrom(c.top)) {
+		// unversioned home, e.g. a container, there are no other homes
+		return nil
+	}
+
+	referenced := map[string]struct{}{filepath.Clean(c.home): {}}
+	marker, err := upgrade.LoadMarker(dataDir)
+	if err != nil {
+		c.log.Warnf("not collecting the run directories of failed upgrades, failed to load the upgrade marker: %v", err)
+		return nil
+	}
+	if marker != nil {
+		homes := []string{marker.VersionedHome, marker.PrevVersionedHome}
+		for _, rollback := range marker.RollbacksAvailable {
+			homes = append(homes, rollback.Home)
+		}
+		for _, home := range homes {
+			if home == "" {
+				continue
+			}
+			if !filepath.IsAbs(home) {
+				home = filepath.Join(c.top, home)
+			}
+			referenced[filepath.Clean(home)] = struct{}{}
+		}
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		c.log.Warnf("failed to list data directory %s: %v", dataDir, err)
+		return nil
+	}
+	var homes []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), versionedHomePrefix) {
+			continue
+		}
+		home := filepath.Join(dataDir, entry.Name())
+		if _, ok := referenced[home]; ok {
+			continue
+		}
+		if _, err := os.Stat(paths.BinaryPath(home, paths.BinaryName)); err == nil {
+			continue
+		}
+		homes = append(homes, home)
+	}
+	return homes
+}
+
+// remove removes the directory when it has not been modified for the retention period and returns its size.
+func (c *Collector) remove(dir, reason string) int64 {
+	size, modTime, err := usage(dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			c.log.Warnf("failed to compute the usage of run directory %s: %v", dir, err)
+		}
+		return 0
+	}
+	if c.now().Sub(modTime) < c.cfg.Retention {
+		c.log.Debugf("keeping run directory %s %s until its retention expires", dir, reason)
+		return 0
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		c.log.Warnf("failed to remove run directory %s %s: %v", dir, reason, err)
+		return 0
+	}
+	c.log.Infof("removed run directory %s %s, reclaimed %d bytes", dir, reason, size)
+	return size
+}
+
+// usage returns the size of the directory and the last time anything in it was modified.
+func usage(dir string) (int64, time.Time, error) {
+	var size int64
+	var modTime time.Time
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			size += info.Size()
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		return nil
+	})
+	return size, modTime, err
+}
@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package rundir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func writeFile(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0o600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+	require.NoError(t, os.Chtimes(filepath.Dir(path), modTime, modTime))
+}
+
+func TestCollect(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-10 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	top := t.TempDir()
+	dataDir := paths.DataFrom(top)
+	home := filepath.Join(dataDir, "elastic-agent-9.0.0-abcdef")
+	run := filepath.Join(home, "run")
+
+	// components of the model, one of them with a slash in its ID
+	writeFile(t, filepath.Join(run, "filestream-default", "registry"), 10, old)
+	writeFile(t, filepath.Join(run, "http", "metrics-monitoring", "state"), 10, old)
+	// removed components, the recent one is kept until its retention expires
+	writeFile(t, filepath.Join(run, "system-metrics-default", "state"), 100, old)
+	writeFile(t, filepath.Join(run, "http", "removed-monitoring", "state"), 20, old)
+	writeFile(t, filepath.Join(run, "winlog-default", "state"), 30, recent)
+
+	// homes of the marker and of an installed agent are kept
+	writeFile(t, filepath.Join(dataDir, "elastic-agent-8.19.0-prev", "run", "state"), 40, old)
+	writeFile(t, filepath.Join(dataDir, "elastic-agent-8.18.0-bin", "run", "state"), 40, old)
+	writeFile(t, paths.BinaryPath(filepath.Join(dataDir, "elastic-agent-8.18.0-bin"), paths.BinaryName), 1, old)
+	// home of a failed upgrade
+	writeFile(t, filepath.Join(dataDir, "elastic-agent-9.1.0-failed", "run", "state"), 200, old)
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, ".update-marker"), []byte(
+		"versioned_home: data/elastic-agent-9.0.0-abcdef\nprev_versioned_home: data/elastic-agent-8.19.0-prev\n"), 0o600))
+
+	log, _ := loggertest.New("rundir")
+	c := New(log, configuration.DefaultRunCleanupConfig(), func() []string {
+		return []string{"filestream-default", "http/metrics-monitoring"}
+	})
+	c.top = top
+	c.home = home
+	c.now = func() time.Time { return now }
+
+	assert.Equal(t, int64(320), c.Collect())
+
+	assert.DirExists(t, filepath.Join(run, "filestream-default"))
+	assert.DirExists(t, filepath.Join(run, "http", "metrics-monitoring"))
+	assert.NoDirExists(t, filepath.Join(run, "http", "removed-monitoring"))
+	assert.NoDirExists(t, filepath.Join(run, "system-metrics-default"))
+	assert.DirExists(t, filepath.Join(run, "winlog-default"))
+	assert.DirExists(t, filepath.Join(dataDir, "elastic-agent-8.19.0-prev", "run"))
+	assert.DirExists(t, filepath.Join(dataDir, "elastic-agent-8.18.0-bin", "run"))
+	assert.NoDirExists(t, filepath.Join(dataDir, "elastic-agent-9.1.0-failed", "run"))
+	assert.DirExists(t, filepath.Join(dataDir, "elastic-agent-9.1.0-failed"))
+
+	// nothing left to reclaim until the retention of the recent directory expires
+	assert.Zero(t, c.Collect())
+	now = now.Add(8 * 24 * time.Hour)
+	assert.Equal(t, int64(30), c.Collect())
+	assert.NoDirExists(t, filepath.Join(run, "winlog-default"))
+}
+
+func TestCollectEmptyModel(t *testing.T) {
+	now := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-10 * 24 * time.Hour)
+
+	top := t.TempDir()
+	home := filepath.Join(paths.DataFrom(top), "elastic-agent-9.0.0-abcdef")
+	run := filepath.Join(home, "run")
+	writeFile(t, filepath.Join(run, "filestream-default", "registry"), 10, old)
+
+	log, _ := loggertest.New("rundir")
+	c := New(log, configuration.DefaultRunCleanupConfig(), func() []string {
+		// not computed yet or failed
+		return nil
+	})
+	c.top = top
+	c.home = home
+	c.now = func() time.Time { return now }
+
+	assert.Zero(t, c.Collect())
+	assert.DirExists(t, filepath.Join(run, "filestream-default"))
+}
@@ -0,0 +1,49 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+)
+
+// clientCertificateKey is the vault key of the client certificate issued by Fleet for the mutual TLS with
+// Fleet Server.
+const clientCertificateKey = "fleet.client_certificate"
+
+// ClientCertificate is the client certificate of the Elastic Agent and its private key, PEM encoded.
+type ClientCertificate struct {
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+}
+
+// SetClientCertificate stores the client certificate issued by Fleet.
+func SetClientCertificate(ctx context.Context, cert ClientCertificate, opts ...vault.OptionFunc) error {
+	b, err := json.Marshal(cert)
+	if err != nil {
+		return fmt.Errorf("could not marshal client certificate: %w", err)
+	}
+	return Set(ctx, clientCertificateKey, Secret{
+		Value:     b,
+		CreatedOn: time.Now().UTC(),
+	}, opts...)
+}
+
+// GetClientCertificate reads the client certificate issued by Fleet.
+func GetClientCertificate(ctx context.Context, opts ...vault.OptionFunc) (ClientCertificate, error) {
+	var cert ClientCertificate
+	secret, err := Get(ctx, clientCertificateKey, opts...)
+	if err != nil {
+		return cert, err
+	}
+	if err := json.Unmarshal(secret.Value, &cert); err != nil {
+		return cert, fmt.Errorf("could not unmarshal client certificate: %w", err)
+	}
+	return cert, nil
+}
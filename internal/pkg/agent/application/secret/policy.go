@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+)
+
+// policySecretPrefix prefixes the vault keys of the secrets referenced in the policies with ${secret.<name>}, so
+// they cannot collide with the secrets of the Elastic Agent.
+const policySecretPrefix = "policy.secret."
+
+var policySecretNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidatePolicySecretName returns an error when the name cannot be referenced in a policy.
+func ValidatePolicySecretName(name string) error {
+	if !policySecretNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q, it must start with a letter or a digit and only contain letters, digits, '_', '-' and '.'", name)
+	}
+	return nil
+}
+
+// SetPolicySecret stores the value of the secret referenced in the policies with ${secret.<name>}.
+func SetPolicySecret(ctx context.Context, name string, value []byte, opts ...vault.OptionFunc) error {
+	if err := ValidatePolicySecretName(name); err != nil {
+		return err
+	}
+	return Set(ctx, policySecretPrefix+name, Secret{
+		Value:     value,
+		CreatedOn: time.Now().UTC(),
+	}, opts...)
+}
+
+// GetPolicySecret reads the value of the secret referenced in the policies with ${secret.<name>}.
+func GetPolicySecret(ctx context.Context, name string, opts ...vault.OptionFunc) ([]byte, error) {
+	if err := ValidatePolicySecretName(name); err != nil {
+		return nil, err
+	}
+	secret, err := Get(ctx, policySecretPrefix+name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return secret.Value, nil
+}
+
+// RemovePolicySecret removes the secret referenced in the policies with ${secret.<name>}.
+func RemovePolicySecret(ctx context.Context, name string, opts ...vault.OptionFunc) error {
+	if err := ValidatePolicySecretName(name); err != nil {
+		return err
+	}
+	return Remove(ctx, policySecretPrefix+name, opts...)
+}
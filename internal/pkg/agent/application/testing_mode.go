@@ -54,7 +54,7 @@ func (t *testingModeConfigManager) SetConfig(ctx context.Context, cfg string) er
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case t.ch <- &localConfigChange{rawConfig}:
+	case t.ch <- &localConfigChange{cfg: rawConfig}:
 	}
 	return nil
 }
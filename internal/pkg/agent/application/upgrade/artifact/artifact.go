@@ -7,6 +7,7 @@ package artifact
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	agtversion "github.com/elastic/elastic-agent/pkg/version"
@@ -31,6 +32,18 @@ type Artifact struct {
 	Artifact string
 }
 
+// PackageSuffixes returns the suffixes of the package names of the supported platforms, sorted.
+func PackageSuffixes() []string {
+	suffixes := make([]string, 0, len(packageArchMap))
+	for _, suffix := range packageArchMap {
+		if !slices.Contains(suffixes, suffix) {
+			suffixes = append(suffixes, suffix)
+		}
+	}
+	slices.Sort(suffixes)
+	return suffixes
+}
+
 // GetArtifactName constructs a path to a downloaded artifact
 func GetArtifactName(a Artifact, version agtversion.ParsedSemVer, operatingSystem, arch string) (string, error) {
 	key := fmt.Sprintf("%s-binary-%s", operatingSystem, arch)
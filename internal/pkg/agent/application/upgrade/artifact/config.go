@@ -5,14 +5,26 @@
 package artifact
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
 	"reflect"
 	"runtime"
 	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+
 	c "github.com/elastic/elastic-agent-libs/config"
 	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/proxy"
+	"github.com/elastic/elastic-agent/internal/pkg/remote/proxypath"
+	"github.com/elastic/elastic-agent/internal/pkg/remote/revocation"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 const (
@@ -58,6 +70,46 @@ type configWithoutHTTPTransportSettings struct {
 	// will increase for subsequent retry attempts in a randomized exponential backoff manner.
 	// This key is, for some reason, problematic
 	RetrySleepInitDuration time.Duration `yaml:"retry_sleep_init_duration" config:"retry_sleep_init_duration"`
+
+	// Retry: budget of the retries of a failed download.
+	Retry RetryConfig `yaml:"retry" config:"retry"`
+
+	// SnapshotPGP: ASCII armored PGP public key used to verify snapshot builds instead of the
+	// embedded Elastic key.
+	SnapshotPGP string `yaml:"snapshot_pgp" config:"snapshot_pgp"`
+
+	// Snapshot: configuration of resolving snapshot builds.
+	Snapshot SnapshotConfig `yaml:"snapshot" config:"snapshot"`
+
+	// Segments: configuration of downloading an artifact in parallel ranged segments.
+	Segments SegmentsConfig `yaml:"segments" config:"segments"`
+
+	// RateLimit: maximum bandwidth used by downloads per second, e.g. 10MB, unlimited when empty.
+	RateLimit string `yaml:"rate_limit" config:"rate_limit"`
+
+	// Window: time of the day downloads are allowed in, downloads are allowed at any time when empty.
+	Window WindowConfig `yaml:"window" config:"window"`
+
+	// ProxyAutoDetect: resolve the proxy from the proxy settings of the operating system (Windows and
+	// macOS), including their PAC file, before falling back to the explicit proxy settings.
+	ProxyAutoDetect bool `yaml:"proxy_auto_detect" config:"proxy_auto_detect"`
+
+	// ProxyPACURL: URL or path of a proxy auto-config (PAC) file used to resolve the proxy, it takes
+	// precedence over the PAC file of the operating system.
+	ProxyPACURL string `yaml:"proxy_pac_url" config:"proxy_pac_url"`
+
+	// ProxyPaths: proxies or direct connection used in order when the proxy is not reachable, unused
+	// when the proxy is auto-detected or resolved with a PAC file.
+	ProxyPaths proxypath.Config `yaml:",inline" config:",inline"`
+
+	// PeerCache: configuration of the cache of artifacts shared between the agents of a site.
+	PeerCache PeerCacheConfig `yaml:"peer_cache" config:"peer_cache"`
+
+	// Verification: configuration of the verification of the signature of the downloaded artifacts.
+	Verification VerificationConfig `yaml:"verification" config:"verification"`
+
+	// Revocation: configuration of the revocation checks of the certificates of the download servers.
+	Revocation revocation.Config `yaml:"revocation" config:"revocation"`
 }
 
 // Config is a configuration used for verifier and downloader
@@ -88,9 +140,221 @@ type Config struct {
 	// will increase for subsequent retry attempts in a randomized exponential backoff manner.
 	RetrySleepInitDuration time.Duration `yaml:"retry_sleep_init_duration" config:"retry_sleep_init_duration"`
 
+	// Retry: budget of the retries of a failed download.
+	Retry RetryConfig `yaml:"retry" config:"retry"`
+
+	// SnapshotPGP: ASCII armored PGP public key used to verify snapshot builds instead of the
+	// embedded Elastic key.
+	SnapshotPGP string `yaml:"snapshot_pgp" config:"snapshot_pgp"`
+
+	// Snapshot: configuration of resolving snapshot builds.
+	Snapshot SnapshotConfig `yaml:"snapshot" config:"snapshot"`
+
+	// Segments: configuration of downloading an artifact in parallel ranged segments.
+	Segments SegmentsConfig `yaml:"segments" config:"segments"`
+
+	// RateLimit: maximum bandwidth used by downloads per second, e.g. 10MB, unlimited when empty.
+	RateLimit string `yaml:"rate_limit" config:"rate_limit"`
+
+	// Window: time of the day downloads are allowed in, downloads are allowed at any time when empty.
+	Window WindowConfig `yaml:"window" config:"window"`
+
+	// ProxyAutoDetect: resolve the proxy from the proxy settings of the operating system (Windows and
+	// macOS), including their PAC file, before falling back to the explicit proxy settings.
+	ProxyAutoDetect bool `yaml:"proxy_auto_detect" config:"proxy_auto_detect"`
+
+	// ProxyPACURL: URL or path of a proxy auto-config (PAC) file used to resolve the proxy, it takes
+	// precedence over the PAC file of the operating system.
+	ProxyPACURL string `yaml:"proxy_pac_url" config:"proxy_pac_url"`
+
+	// ProxyPaths: proxies or direct connection used in order when the proxy is not reachable, unused
+	// when the proxy is auto-detected or resolved with a PAC file.
+	ProxyPaths proxypath.Config `yaml:",inline" config:",inline"`
+
+	// PeerCache: configuration of the cache of artifacts shared between the agents of a site.
+	PeerCache PeerCacheConfig `yaml:"peer_cache" config:"peer_cache"`
+
+	// Verification: configuration of the verification of the signature of the downloaded artifacts.
+	Verification VerificationConfig `yaml:"verification" config:"verification"`
+
+	// Revocation: configuration of the revocation checks of the certificates of the download servers.
+	Revocation revocation.Config `yaml:"revocation" config:"revocation"`
+
 	httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"` // Note: use anonymous struct for json inline
 }
 
+// RetryConfig is the budget of the retries of a failed download. Retries are delayed with an exponential
+// backoff starting at RetrySleepInitDuration and stop at the first exhausted limit. Downloads failing with a
+// fatal error, e.g. a missing artifact or a full disk, are not retried.
+type RetryConfig struct {
+	// MaxElapsedTime: time after which a failed download is not retried anymore, the download timeout when 0.
+	MaxElapsedTime time.Duration `yaml:"max_elapsed_time" config:"max_elapsed_time"`
+
+	// MaxAttempts: maximum number of download attempts, unlimited when 0.
+	MaxAttempts int `yaml:"max_attempts" config:"max_attempts"`
+
+	// Jitter: randomization factor of the delay between attempts, between 0 and 1. A delay d is picked
+	// at random in [d - jitter * d, d + jitter * d] so agents retrying at the same time spread their requests.
+	Jitter float64 `yaml:"jitter" config:"jitter"`
+}
+
+// Validate validates the retry configuration.
+func (r *RetryConfig) Validate() error {
+	if r.MaxElapsedTime < 0 {
+		return fmt.Errorf("invalid retry max_elapsed_time %s, it must not be negative", r.MaxElapsedTime)
+	}
+	if r.MaxAttempts < 0 {
+		return fmt.Errorf("invalid retry max_attempts %d, it must not be negative", r.MaxAttempts)
+	}
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return fmt.Errorf("invalid retry jitter %v, it must be between 0 and 1", r.Jitter)
+	}
+	return nil
+}
+
+// BackOff returns the backoff of the retries, starting at initialInterval. Without MaxElapsedTime the
+// retries are only bounded by the deadline of the download.
+func (r *RetryConfig) BackOff(initialInterval time.Duration) backoff.BackOff {
+	expBo := backoff.NewExponentialBackOff()
+	expBo.InitialInterval = initialInterval
+	expBo.RandomizationFactor = r.Jitter
+	expBo.MaxElapsedTime = r.MaxElapsedTime
+	expBo.Reset()
+	if r.MaxAttempts > 0 {
+		return backoff.WithMaxRetries(expBo, uint64(r.MaxAttempts-1))
+	}
+	return expBo
+}
+
+// SnapshotConfig is the configuration of resolving snapshot builds.
+type SnapshotConfig struct {
+	// Build: ID of the snapshot build upgrades are pinned to, either the build hash or the build ID
+	// of the artifacts API (<version>-<hash>). The latest build is used when empty.
+	Build string `yaml:"build" config:"build"`
+}
+
+// SegmentsConfig is the configuration of downloading an artifact in parallel ranged segments.
+type SegmentsConfig struct {
+	// Count: number of segments the artifact is split into, 0 or 1 downloads the artifact in a single request.
+	Count int `yaml:"count" config:"count"`
+
+	// Concurrency: maximum number of segments downloaded at the same time, defaults to Count when 0.
+	Concurrency int `yaml:"concurrency" config:"concurrency"`
+
+	// MinSize: artifacts smaller than this size in bytes are downloaded in a single request.
+	MinSize int64 `yaml:"min_size" config:"min_size"`
+}
+
+// WindowConfig is the time of the day downloads are allowed in, in the local time of the host. A window
+// that ends before it starts spans midnight.
+type WindowConfig struct {
+	// Start: time the window opens, in the 15:04 format.
+	Start string `yaml:"start" config:"start"`
+
+	// End: time the window closes, in the 15:04 format.
+	End string `yaml:"end" config:"end"`
+}
+
+// PeerCacheConfig is the configuration of the cache of artifacts shared between the agents of a site. Agents
+// download artifacts from the peer caches before the source URI, the artifacts are verified the same way
+// whatever their source.
+type PeerCacheConfig struct {
+	// Sources: https URLs of the peer caches artifacts are downloaded from before the source URI, in order.
+	Sources []string `yaml:"sources" config:"sources"`
+
+	// Token: shared secret authenticating the agents to the peer caches.
+	Token string `yaml:"token" config:"token"`
+
+	// Serve: configuration of serving the artifacts of the agent to its peers.
+	Serve PeerCacheServeConfig `yaml:"serve" config:"serve"`
+}
+
+// PeerCacheServeConfig is the configuration of serving the artifacts of the agent to its peers.
+type PeerCacheServeConfig struct {
+	// Enabled: serve the artifacts of the target directory to the peers.
+	Enabled bool `yaml:"enabled" config:"enabled"`
+
+	// Address: address the artifacts are served on.
+	Address string `yaml:"address" config:"address"`
+
+	// PullThrough: download the artifacts requested by the peers and missing from the target directory
+	// from the source URI, for the agent to act as a proxy of the site.
+	PullThrough bool `yaml:"pull_through" config:"pull_through"`
+
+	// SSL: TLS configuration of the endpoint, required as the peers send the token with every request.
+	SSL *tlscommon.ServerConfig `yaml:"ssl" config:"ssl"`
+}
+
+// VerificationConfig is the configuration of the verification of the signature of the downloaded artifacts.
+type VerificationConfig struct {
+	// Mode: signatures verified, either the PGP signatures (pgp) or the sigstore bundles (sigstore).
+	Mode string `yaml:"mode" config:"mode"`
+
+	// Sigstore: configuration of the verification of the sigstore bundles.
+	Sigstore SigstoreConfig `yaml:"sigstore" config:"sigstore"`
+}
+
+// SigstoreConfig is the configuration of the verification of the sigstore bundles published next to the
+// artifacts (<artifact>.sigstore.json). The certificate of the bundle must match the identity and its issuer.
+type SigstoreConfig struct {
+	// Cosign: absolute path of the cosign binary verifying the bundles. It must be owned by root or the user
+	// running the Elastic Agent and not be writable by others.
+	Cosign string `yaml:"cosign" config:"cosign"`
+
+	// TrustedRoot: path of the trusted root (trusted_root.json) of the sigstore instance, the trusted root of
+	// the public good instance is retrieved with TUF when empty.
+	TrustedRoot string `yaml:"trusted_root" config:"trusted_root"`
+
+	// Identity: subject alternative name of the signing certificate, e.g. the email or the workflow URI of the signer.
+	Identity string `yaml:"identity" config:"identity"`
+
+	// IdentityRegexp: regular expression the subject alternative name of the signing certificate must match.
+	IdentityRegexp string `yaml:"identity_regexp" config:"identity_regexp"`
+
+	// Issuer: OIDC issuer of the signing certificate, e.g. https://token.actions.githubusercontent.com.
+	Issuer string `yaml:"issuer" config:"issuer"`
+
+	// IssuerRegexp: regular expression the OIDC issuer of the signing certificate must match.
+	IssuerRegexp string `yaml:"issuer_regexp" config:"issuer_regexp"`
+}
+
+const (
+	// VerificationModePGP verifies the PGP signatures of the artifacts.
+	VerificationModePGP = "pgp"
+	// VerificationModeSigstore verifies the sigstore bundles of the artifacts.
+	VerificationModeSigstore = "sigstore"
+)
+
+// Validate validates the verification configuration.
+func (v *VerificationConfig) Validate() error {
+	switch v.Mode {
+	case "", VerificationModePGP:
+		return nil
+	case VerificationModeSigstore:
+		if !filepath.IsAbs(v.Sigstore.Cosign) {
+			return fmt.Errorf("sigstore verification requires the absolute path of cosign, got %q", v.Sigstore.Cosign)
+		}
+		if (v.Sigstore.Identity == "") == (v.Sigstore.IdentityRegexp == "") {
+			return errors.New("sigstore verification requires either an identity or an identity_regexp")
+		}
+		if (v.Sigstore.Issuer == "") == (v.Sigstore.IssuerRegexp == "") {
+			return errors.New("sigstore verification requires either an issuer or an issuer_regexp")
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid verification mode %q, expected %q or %q", v.Mode, VerificationModePGP, VerificationModeSigstore)
+	}
+}
+
+// DefaultPeerCacheAddress is the default address artifacts are served to the peers on.
+const DefaultPeerCacheAddress = ":6796"
+
+// DefaultRetryJitter is the default randomization factor of the delay between download attempts.
+const DefaultRetryJitter = 0.5
+
+// DefaultSegmentsMinSize is the default minimum size of an artifact to be downloaded in segments.
+const DefaultSegmentsMinSize = 32 * 1024 * 1024
+
 // DefaultConfig creates a config with pre-set default values.
 func DefaultConfig() *Config {
 	transport := httpcommon.DefaultHTTPTransportSettings()
@@ -105,7 +369,21 @@ func DefaultConfig() *Config {
 		TargetDirectory:        paths.Downloads(),
 		InstallPath:            paths.Install(),
 		RetrySleepInitDuration: 30 * time.Second,
-		HTTPTransportSettings:  transport,
+		Retry: RetryConfig{
+			Jitter: DefaultRetryJitter,
+		},
+		Segments: SegmentsConfig{
+			MinSize: DefaultSegmentsMinSize,
+		},
+		Verification: VerificationConfig{
+			Mode: VerificationModePGP,
+		},
+		PeerCache: PeerCacheConfig{
+			Serve: PeerCacheServeConfig{
+				Address: DefaultPeerCacheAddress,
+			},
+		},
+		HTTPTransportSettings: transport,
 	}
 }
 
@@ -144,6 +422,45 @@ func (c *Config) Arch() string {
 	return c.Architecture
 }
 
+// Client creates an HTTP client out of the transport settings. When proxy auto-detection or a PAC file
+// is configured the proxy of each request is resolved from them, falling back to the explicit proxy settings.
+// Otherwise, when failover paths are configured, the requests fail over to them while the proxy is not reachable.
+// The revocation status of the certificates of the servers is checked when revocation checks are enabled.
+func (c *Config) Client(opts ...httpcommon.TransportOption) (*http.Client, error) {
+	if c.Revocation.Enabled() {
+		revocationOpt, err := revocation.New(logger.NewWithoutConfig("download.revocation"), c.Revocation).TransportOption(&c.HTTPTransportSettings)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, revocationOpt)
+	}
+
+	if !c.Proxy.Disable && (c.ProxyAutoDetect || c.ProxyPACURL != "") {
+		resolver := proxy.NewResolver(logger.NewWithoutConfig("download.proxy"), c.ProxyAutoDetect, c.ProxyPACURL, c.Proxy.ProxyFunc())
+		opts = append(opts, httpcommon.WithTransportFunc(func(t *http.Transport) {
+			t.Proxy = resolver.Proxy
+		}))
+		return c.HTTPTransportSettings.Client(opts...)
+	}
+
+	if c.Proxy.Disable || !c.ProxyPaths.Enabled() {
+		return c.HTTPTransportSettings.Client(opts...)
+	}
+	failover, err := proxypath.New(logger.NewWithoutConfig("download.proxy"), "artifacts", (*url.URL)(c.Proxy.URL), c.ProxyPaths)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, httpcommon.WithTransportFunc(func(t *http.Transport) {
+		t.Proxy = failover.Proxy
+	}))
+	client, err := c.HTTPTransportSettings.Client(opts...)
+	if err != nil {
+		return nil, err
+	}
+	client.Transport = failover.RoundTripper(client.Transport)
+	return client, nil
+}
+
 // Unpack reads a config object into the settings.
 func (c *Config) Unpack(cfg *c.C) error {
 	// c.HTTPTransportSettings need to be unpacked separately (see
@@ -5,10 +5,15 @@
 package artifact
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -74,3 +79,172 @@ func TestConfig_Unpack(t *testing.T) {
 	require.NoError(t, err, "UnpackTo failed")
 	assert.Equal(t, DefaultConfig(), defaultcfg)
 }
+
+func TestConfig_UnpackVerification(t *testing.T) {
+	testcases := map[string]struct {
+		cfg string
+		err string
+	}{
+		"pgp": {
+			cfg: `verification.mode: pgp`,
+		},
+		"sigstore": {
+			cfg: `
+verification:
+  mode: sigstore
+  sigstore:
+    cosign: {cosign}
+    identity_regexp: ^https://github.com/elastic/
+    issuer: https://token.actions.githubusercontent.com`,
+		},
+		"sigstore without cosign": {
+			cfg: `
+verification:
+  mode: sigstore
+  sigstore:
+    identity: release@example.com
+    issuer: https://token.actions.githubusercontent.com`,
+			err: "requires the absolute path of cosign",
+		},
+		"sigstore with cosign in the PATH": {
+			cfg: `
+verification:
+  mode: sigstore
+  sigstore:
+    cosign: cosign
+    identity: release@example.com
+    issuer: https://token.actions.githubusercontent.com`,
+			err: "requires the absolute path of cosign",
+		},
+		"sigstore with a missing cosign": {
+			// checked when the verifier of an upgrade is created
+			cfg: `
+verification:
+  mode: sigstore
+  sigstore:
+    cosign: {missing}
+    identity: release@example.com
+    issuer: https://token.actions.githubusercontent.com`,
+		},
+		"sigstore without identity": {
+			cfg: `
+verification:
+  mode: sigstore
+  sigstore:
+    cosign: {cosign}
+    issuer: https://token.actions.githubusercontent.com`,
+			err: "requires either an identity",
+		},
+		"sigstore with both issuers": {
+			cfg: `
+verification:
+  mode: sigstore
+  sigstore:
+    cosign: {cosign}
+    identity: release@example.com
+    issuer: https://token.actions.githubusercontent.com
+    issuer_regexp: ^https://`,
+			err: "requires either an issuer or an issuer_regexp",
+		},
+		"unknown mode": {
+			cfg: `verification.mode: x509`,
+			err: "invalid verification mode",
+		},
+	}
+	// cosign itself never runs in the tests
+	dir := t.TempDir()
+	cosign := filepath.Join(dir, "cosign")
+	require.NoError(t, os.WriteFile(cosign, []byte("#!/bin/sh\n"), 0o700))
+	paths := strings.NewReplacer("{cosign}", cosign, "{missing}", filepath.Join(dir, "missing"))
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			rawCfg, err := agentlibsconfig.NewConfigFrom(paths.Replace(tc.cfg))
+			require.NoError(t, err)
+
+			cfg := DefaultConfig()
+			err = cfg.Unpack(rawCfg)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestConfig_UnpackRetry(t *testing.T) {
+	testcases := map[string]struct {
+		cfg      string
+		expected RetryConfig
+		err      string
+	}{
+		"defaults": {
+			cfg:      `retry_sleep_init_duration: 1s`,
+			expected: RetryConfig{Jitter: DefaultRetryJitter},
+		},
+		"budget": {
+			cfg: `
+retry:
+  max_elapsed_time: 30m
+  max_attempts: 5
+  jitter: 0.2`,
+			expected: RetryConfig{MaxElapsedTime: 30 * time.Minute, MaxAttempts: 5, Jitter: 0.2},
+		},
+		"negative attempts": {
+			cfg: `retry.max_attempts: -1`,
+			err: "invalid retry max_attempts",
+		},
+		"jitter out of range": {
+			cfg: `retry.jitter: 1.5`,
+			err: "invalid retry jitter",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			rawCfg, err := agentlibsconfig.NewConfigFrom(tc.cfg)
+			require.NoError(t, err)
+
+			cfg := DefaultConfig()
+			err = cfg.Unpack(rawCfg)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, cfg.Retry)
+		})
+	}
+}
+
+func TestConfig_UnpackProxyPaths(t *testing.T) {
+	rawCfg, err := agentlibsconfig.NewConfigFrom(`
+proxy_url: http://proxy-1:3128
+proxy_failover: [http://proxy-2:3128, direct]
+proxy_probe_interval: 1m`)
+	require.NoError(t, err)
+
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Unpack(rawCfg))
+	assert.Equal(t, "http://proxy-1:3128", cfg.Proxy.URL.String())
+	assert.Equal(t, []string{"http://proxy-2:3128", "direct"}, cfg.ProxyPaths.Failover)
+	assert.Equal(t, time.Minute, cfg.ProxyPaths.ProbeInterval)
+
+	rawCfg, err = agentlibsconfig.NewConfigFrom(`proxy_failover: [proxy-2]`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, DefaultConfig().Unpack(rawCfg), "invalid proxy_failover entry")
+}
+
+func TestRetryConfig_BackOff(t *testing.T) {
+	r := RetryConfig{MaxAttempts: 3}
+	bo := r.BackOff(time.Second)
+	assert.Equal(t, time.Second, bo.NextBackOff())
+	assert.Equal(t, 1500*time.Millisecond, bo.NextBackOff(), "no jitter, the delay grows exponentially")
+	assert.Equal(t, backoff.Stop, bo.NextBackOff(), "the budget allows 3 attempts")
+
+	r = RetryConfig{Jitter: 0.5}
+	bo = r.BackOff(time.Second)
+	for i := 0; i < 10; i++ {
+		d := bo.NextBackOff()
+		assert.NotEqual(t, backoff.Stop, d, "attempts are unlimited")
+	}
+}
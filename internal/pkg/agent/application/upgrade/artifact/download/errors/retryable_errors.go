@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// HTTPStatusError is returned when the server answers a download request with an unsuccessful status code.
+type HTTPStatusError struct {
+	URI        string
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", e.URI, e.StatusCode)
+}
+
+// Retryable returns true when the request may succeed if retried.
+func (e *HTTPStatusError) Retryable() bool {
+	return IsRetryableStatus(e.StatusCode)
+}
+
+// IsRetryableStatus returns true for the HTTP status codes of transient failures: request timeouts,
+// rate limiting and server errors. Other client errors, e.g. a missing artifact, are fatal.
+func IsRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return statusCode >= http.StatusInternalServerError
+}
+
+// IsRetryable returns false when retrying the download cannot succeed, because the disk is full or
+// the server answered with a fatal status code.
+func IsRetryable(err error) bool {
+	if err == nil || IsDiskSpaceError(err) || errors.Is(err, ErrInsufficientDiskSpace) {
+		return false
+	}
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+	return true
+}
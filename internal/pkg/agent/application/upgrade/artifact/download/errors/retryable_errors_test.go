@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package errors
+
+import (
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	agentErrors "github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+func TestIsRetryable(t *testing.T) {
+	statusErr := func(code int) error {
+		return &HTTPStatusError{URI: "https://artifacts.elastic.co/downloads/agent.tar.gz", StatusCode: code}
+	}
+
+	testCases := map[string]struct {
+		err  error
+		want bool
+	}{
+		"nil":                  {err: nil, want: false},
+		"network error":        {err: goerrors.New("connection reset by peer"), want: true},
+		"disk space":           {err: fmt.Errorf("copy: %w", OS_DiskSpaceErrors[0]), want: false},
+		"insufficient space":   {err: ErrInsufficientDiskSpace, want: false},
+		"not found":            {err: statusErr(http.StatusNotFound), want: false},
+		"forbidden":            {err: statusErr(http.StatusForbidden), want: false},
+		"not implemented":      {err: statusErr(http.StatusNotImplemented), want: false},
+		"request timeout":      {err: statusErr(http.StatusRequestTimeout), want: true},
+		"too many requests":    {err: statusErr(http.StatusTooManyRequests), want: true},
+		"bad gateway":          {err: statusErr(http.StatusBadGateway), want: true},
+		"service unavailable":  {err: statusErr(http.StatusServiceUnavailable), want: true},
+		"wrapped agent error":  {err: agentErrors.New(statusErr(http.StatusNotFound), agentErrors.TypeNetwork), want: false},
+		"joined with fallback": {err: goerrors.Join(goerrors.New("not found in drop path"), statusErr(http.StatusGone)), want: false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsRetryable(tc.err))
+		})
+	}
+}
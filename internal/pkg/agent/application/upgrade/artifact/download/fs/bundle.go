@@ -0,0 +1,261 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fs
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	libsfile "github.com/elastic/elastic-agent-libs/file"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// AvailableArtifactsFile is the file of the drop path recording the artifacts imported from bundles.
+	AvailableArtifactsFile = "artifacts.yml"
+
+	hashSuffix = ".sha512"
+)
+
+// ImportedArtifact is an artifact imported from a bundle into the drop path.
+type ImportedArtifact struct {
+	Name       string    `yaml:"name"`
+	SHA512     string    `yaml:"sha512"`
+	Bundle     string    `yaml:"bundle"`
+	ImportedAt time.Time `yaml:"imported_at"`
+}
+
+type availableArtifacts struct {
+	Artifacts []ImportedArtifact `yaml:"artifacts"`
+}
+
+// ImportBundle unpacks the bundle, a tar archive (optionally gzipped) of packages each accompanied by its
+// .sha512 and .asc files, into dropPath. Every package is verified against its checksum and its signature
+// using pgpKeys before any file is moved to dropPath, so a bundle is either fully imported or not at all.
+// Each file is renamed into place and a package only after its .sha512 and .asc files, so readers of
+// dropPath never see a partially written file. The imported packages are recorded in the
+// AvailableArtifactsFile of dropPath, also replaced atomically.
+func ImportBundle(log *logger.Logger, bundlePath, dropPath string, pgpKeys [][]byte) ([]ImportedArtifact, error) {
+	if len(pgpKeys) == 0 {
+		return nil, errors.New("expecting PGP key but received none")
+	}
+	if err := os.MkdirAll(dropPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create drop path %q: %w", dropPath, err)
+	}
+	// stage in the drop path so moving the verified files is a rename on the same filesystem
+	stagingDir, err := os.MkdirTemp(dropPath, ".import-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	files, err := extractBundle(bundlePath, stagingDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract bundle %q: %w", bundlePath, err)
+	}
+
+	now := time.Now().UTC()
+	var imported []ImportedArtifact
+	for name, sum := range files {
+		if isSidecar(name) {
+			if _, ok := files[strings.TrimSuffix(strings.TrimSuffix(name, hashSuffix), ascSuffix)]; !ok {
+				return nil, fmt.Errorf("bundle contains %q without its package", name)
+			}
+			continue
+		}
+		if err := verifyBundledPackage(log, filepath.Join(stagingDir, name), pgpKeys); err != nil {
+			return nil, err
+		}
+		imported = append(imported, ImportedArtifact{
+			Name:       name,
+			SHA512:     sum,
+			Bundle:     filepath.Base(bundlePath),
+			ImportedAt: now,
+		})
+	}
+	if len(imported) == 0 {
+		return nil, fmt.Errorf("bundle %q contains no package", bundlePath)
+	}
+	sort.Slice(imported, func(i, j int) bool { return imported[i].Name < imported[j].Name })
+
+	// the .sha512 and .asc files are moved first, a package only shows up in the drop path along with them
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		iSidecar, jSidecar := isSidecar(names[i]), isSidecar(names[j])
+		if iSidecar != jSidecar {
+			return iSidecar
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		if err := libsfile.SafeFileRotate(filepath.Join(dropPath, name), filepath.Join(stagingDir, name)); err != nil {
+			return nil, fmt.Errorf("failed to move %q to the drop path: %w", name, err)
+		}
+	}
+	if err := recordArtifacts(dropPath, imported); err != nil {
+		return nil, err
+	}
+	return imported, nil
+}
+
+// isSidecar returns true for the .sha512 and .asc files accompanying a package.
+func isSidecar(name string) bool {
+	return strings.HasSuffix(name, hashSuffix) || strings.HasSuffix(name, ascSuffix)
+}
+
+// verifyBundledPackage checks the package against its .sha512 and .asc files.
+func verifyBundledPackage(log *logger.Logger, packagePath string, pgpKeys [][]byte) error {
+	if err := download.VerifySHA512Hash(packagePath); err != nil {
+		return fmt.Errorf("failed to verify SHA512 hash of %q: %w", filepath.Base(packagePath), err)
+	}
+	asc, err := os.ReadFile(packagePath + ascSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to read signature of %q: %w", filepath.Base(packagePath), err)
+	}
+	return download.VerifyPGPSignatureWithKeys(log, packagePath, asc, pgpKeys)
+}
+
+// extractBundle writes the regular files of the bundle in dir and returns their SHA512 checksums by name.
+// Entries are expected at the root of the archive, directories are ignored.
+func extractBundle(bundlePath, dir string) (map[string]string, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, err := r.(*bufio.Reader).Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			continue
+		case tar.TypeReg:
+		default:
+			return nil, fmt.Errorf("entry %q is not a regular file", hdr.Name)
+		}
+		name := path.Clean(hdr.Name)
+		if name != path.Base(name) || name == ".." || name == "." {
+			return nil, fmt.Errorf("entry %q is not at the root of the bundle", hdr.Name)
+		}
+		if _, ok := files[name]; ok {
+			return nil, fmt.Errorf("entry %q is duplicated", hdr.Name)
+		}
+
+		sum, err := extractFile(tr, filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %q: %w", hdr.Name, err)
+		}
+		files[name] = sum
+	}
+	return files, nil
+}
+
+func extractFile(r io.Reader, dst string) (string, error) {
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, packagePermissions)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hasher := sha512.New()
+	if _, err := io.Copy(io.MultiWriter(f, hasher), r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), f.Close()
+}
+
+// recordArtifacts adds the imported artifacts to the AvailableArtifactsFile of dropPath, replacing the
+// records of artifacts with the same name.
+func recordArtifacts(dropPath string, imported []ImportedArtifact) error {
+	recordPath := filepath.Join(dropPath, AvailableArtifactsFile)
+	var available availableArtifacts
+	data, err := os.ReadFile(recordPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %q: %w", recordPath, err)
+	}
+	if err := yaml.Unmarshal(data, &available); err != nil {
+		return fmt.Errorf("failed to parse %q: %w", recordPath, err)
+	}
+
+	names := make(map[string]struct{}, len(imported))
+	for _, a := range imported {
+		names[a.Name] = struct{}{}
+	}
+	kept := available.Artifacts[:0]
+	for _, a := range available.Artifacts {
+		if _, ok := names[a.Name]; !ok {
+			kept = append(kept, a)
+		}
+	}
+	available.Artifacts = append(kept, imported...)
+	sort.Slice(available.Artifacts, func(i, j int) bool { return available.Artifacts[i].Name < available.Artifacts[j].Name })
+
+	data, err = yaml.Marshal(available)
+	if err != nil {
+		return fmt.Errorf("failed to marshal available artifacts: %w", err)
+	}
+	// write a temporary file rotated into place, the record is never seen partially written
+	tmp, err := os.CreateTemp(dropPath, AvailableArtifactsFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %q: %w", recordPath, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %q: %w", tmp.Name(), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync %q: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close %q: %w", tmp.Name(), err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to set permissions of %q: %w", tmp.Name(), err)
+	}
+	if err := libsfile.SafeFileRotate(recordPath, tmp.Name()); err != nil {
+		return fmt.Errorf("failed to write %q: %w", recordPath, err)
+	}
+	return nil
+}
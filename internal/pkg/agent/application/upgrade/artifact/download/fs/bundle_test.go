@@ -0,0 +1,146 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fs
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestImportBundle(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "the test packages are signed with an OpenPGP key which results in a SHA-1 violation.")
+
+	const packageName = "elastic-agent-8.0.0-darwin-x86_64.tar.gz"
+	srcDir := filepath.Join("testdata", "drop")
+	pgp, err := os.ReadFile(filepath.Join(srcDir, "public-key.pgp"))
+	require.NoError(t, err)
+
+	readFile := func(name string) []byte {
+		data, err := os.ReadFile(filepath.Join(srcDir, name))
+		require.NoError(t, err)
+		return data
+	}
+	validEntries := func() map[string][]byte {
+		return map[string][]byte{
+			packageName:             readFile(packageName),
+			packageName + ".sha512": readFile(packageName + ".sha512"),
+			packageName + ".asc":    readFile(packageName + ".asc"),
+		}
+	}
+
+	testcases := map[string]struct {
+		entries func() map[string][]byte
+		err     string
+	}{
+		"valid bundle": {
+			entries: validEntries,
+		},
+		"tampered package": {
+			entries: func() map[string][]byte {
+				entries := validEntries()
+				entries[packageName] = append(entries[packageName], 0)
+				return entries
+			},
+			err: "failed to verify SHA512 hash",
+		},
+		"missing signature": {
+			entries: func() map[string][]byte {
+				entries := validEntries()
+				delete(entries, packageName+".asc")
+				return entries
+			},
+			err: "failed to read signature",
+		},
+		"sidecar without package": {
+			entries: func() map[string][]byte {
+				entries := validEntries()
+				entries["other.tar.gz.sha512"] = []byte("0 other.tar.gz")
+				return entries
+			},
+			err: `bundle contains "other.tar.gz.sha512" without its package`,
+		},
+		"entry outside of the root": {
+			entries: func() map[string][]byte {
+				return map[string][]byte{"../" + packageName: readFile(packageName)}
+			},
+			err: "is not at the root of the bundle",
+		},
+		"empty bundle": {
+			entries: func() map[string][]byte { return nil },
+			err:     "contains no package",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			log, _ := loggertest.New(t.Name())
+			bundlePath := writeBundle(t, tc.entries())
+			dropPath := filepath.Join(t.TempDir(), "drop")
+
+			imported, err := ImportBundle(log, bundlePath, dropPath, [][]byte{pgp})
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				dirEntries, err := os.ReadDir(dropPath)
+				require.NoError(t, err)
+				assert.Empty(t, dirEntries, "nothing must be imported from an invalid bundle")
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, imported, 1)
+			assert.Equal(t, packageName, imported[0].Name)
+			assert.Equal(t, "bundle.tar", imported[0].Bundle)
+
+			// only the imported files and their record are left, no staging or temporary files
+			dirEntries, err := os.ReadDir(dropPath)
+			require.NoError(t, err)
+			var names []string
+			for _, e := range dirEntries {
+				names = append(names, e.Name())
+			}
+			assert.ElementsMatch(t, []string{packageName, packageName + ".sha512", packageName + ".asc", AvailableArtifactsFile}, names)
+
+			data, err := os.ReadFile(filepath.Join(dropPath, AvailableArtifactsFile))
+			require.NoError(t, err)
+			var available availableArtifacts
+			require.NoError(t, yaml.Unmarshal(data, &available))
+			require.Len(t, available.Artifacts, 1)
+			assert.Equal(t, imported[0].SHA512, available.Artifacts[0].SHA512)
+
+			// importing again replaces the record
+			_, err = ImportBundle(log, bundlePath, dropPath, [][]byte{pgp})
+			require.NoError(t, err)
+			data, err = os.ReadFile(filepath.Join(dropPath, AvailableArtifactsFile))
+			require.NoError(t, err)
+			require.NoError(t, yaml.Unmarshal(data, &available))
+			assert.Len(t, available.Artifacts, 1)
+		})
+	}
+}
+
+func writeBundle(t *testing.T, entries map[string][]byte) string {
+	t.Helper()
+	bundlePath := filepath.Join(t.TempDir(), "bundle.tar")
+	f, err := os.Create(bundlePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	for name, data := range entries {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return bundlePath
+}
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package http
+
+import (
+	goerrors "errors"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+
+	downloadErrors "github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
+)
+
+// requestAttempt holds the metrics of a single request of a download, they are logged and recorded in the
+// download metrics of the agent when the request ends so the failures of a flaky mirror can be diagnosed.
+type requestAttempt struct {
+	uri        string
+	byteRange  string
+	attempt    int
+	started    time.Time
+	statusCode int
+	bytes      int64
+}
+
+func newRequestAttempt(uri string, attempt int) *requestAttempt {
+	return &requestAttempt{uri: uri, attempt: attempt, started: time.Now()}
+}
+
+// reportAttempt logs the outcome of the request with its metrics and records them in the download metrics.
+func (e *Downloader) reportAttempt(a *requestAttempt, err error) {
+	took := time.Since(a.started)
+	var permanent *backoff.PermanentError
+	retryable := err != nil && downloadErrors.IsRetryable(err) && !goerrors.As(err, &permanent)
+	agentmetrics.ObserveDownloadRequest(took, a.bytes, a.statusCode, err, retryable)
+
+	fields := []interface{}{
+		"url.full", a.uri,
+		"download.attempt", a.attempt,
+		"download.bytes", a.bytes,
+		"event.duration", took,
+	}
+	if a.byteRange != "" {
+		fields = append(fields, "http.request.range", a.byteRange)
+	}
+	if a.statusCode != 0 {
+		fields = append(fields, "http.response.status_code", a.statusCode)
+	}
+	if err == nil {
+		e.log.Infow("download request completed", fields...)
+		return
+	}
+	fields = append(fields, "error.message", err.Error(), "download.retryable", retryable)
+	e.log.Warnw("download request failed", fields...)
+}
@@ -112,6 +112,10 @@ func (e *Downloader) Download(ctx context.Context, a artifact.Artifact, version
 	defer func() {
 		if err != nil {
 			for _, path := range downloadedFiles {
+				if path == "" {
+					// nothing to clean up (or kept to resume the download)
+					continue
+				}
 				if err := os.Remove(path); err != nil {
 					e.log.Warnf("failed to cleanup %s: %v", path, err)
 				}
@@ -179,7 +183,7 @@ func (e *Downloader) downloadHash(ctx context.Context, remoteArtifact string, op
 	return e.downloadFile(ctx, remoteArtifact, filename, fullPath)
 }
 
-func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, fullPath string) (string, error) {
+func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, fullPath string) (_ string, err error) {
 	sourceURI, err := e.composeURI(artifactName, filename)
 	if err != nil {
 		return "", err
@@ -190,28 +194,93 @@ func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, f
 		return "", errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
 	}
 
+	throttle, err := newDownloadThrottle(e.log, e.config)
+	if err != nil {
+		return "", errors.New(err, "invalid download throttling", errors.TypeConfig)
+	}
+	if err := throttle.waitWindow(ctx, sourceURI); err != nil {
+		return "", errors.New(err, "waiting for download window failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+
 	if destinationDir := filepath.Dir(fullPath); destinationDir != "" && destinationDir != "." {
 		if err := e.mkdirAll(destinationDir, 0o755); err != nil {
 			return "", err
 		}
 	}
 
-	destinationFile, err := e.openFile(fullPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, packagePermissions)
+	// continue an interrupted download from the last downloaded byte
+	state, offset := loadResumeState(fullPath, sourceURI)
+	openFlags := os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if state != nil {
+		openFlags = os.O_CREATE | os.O_WRONLY
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", state.validator())
+	}
+
+	destinationFile, err := e.openFile(fullPath, openFlags, packagePermissions)
 	if err != nil {
 		return "", goerrors.Join(errors.New("creating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath)), err)
 	}
 	defer destinationFile.Close()
 
+	// large artifacts can be downloaded in parallel segments when the server supports ranges
+	if state == nil && e.config.Segments.Count > 1 {
+		if info := e.segmentedInfo(ctx, sourceURI); info != nil {
+			if err := e.downloadSegmented(ctx, sourceURI, info, destinationFile, throttle); err != nil {
+				// return path, file already exists and needs to be cleaned up
+				return fullPath, err
+			}
+			return fullPath, nil
+		}
+	}
+
+	attempt := newRequestAttempt(sourceURI, 1)
+	if state != nil {
+		attempt.byteRange = req.Header.Get("Range")
+	}
+	defer func() {
+		e.reportAttempt(attempt, err)
+	}()
+
 	resp, err := e.client.Do(req.WithContext(ctx))
 	if err != nil {
+		if state != nil {
+			// keep the partial file to resume on the next attempt
+			return "", errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		}
 		// return path, file already exists and needs to be cleaned up
 		return fullPath, errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
 	}
 	defer resp.Body.Close()
+	attempt.statusCode = resp.StatusCode
 
-	if resp.StatusCode != 200 {
+	switch {
+	case state != nil && resp.StatusCode == http.StatusPartialContent:
+		start, err := contentRangeStart(resp)
+		if err == nil && start != offset {
+			err = fmt.Errorf("expected partial content from byte %d, got byte %d", offset, start)
+		}
+		if err == nil {
+			_, err = destinationFile.Seek(offset, io.SeekStart)
+		}
+		if err != nil {
+			_ = removeResumeState(fullPath)
+			// return path, file already exists and needs to be cleaned up
+			return fullPath, errors.New(err, "resuming package download failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		}
+		e.log.Infof("resuming download from %s at byte %d", sourceURI, offset)
+	case resp.StatusCode == http.StatusOK:
+		if state != nil {
+			// remote file changed or range is not supported, download from the start
+			if err := destinationFile.Truncate(0); err != nil {
+				return fullPath, goerrors.Join(errors.New("truncating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath)), err)
+			}
+		}
+		state = resumeStateFromResponse(sourceURI, resp)
+	default:
+		_ = removeResumeState(fullPath)
 		// return path, file already exists and needs to be cleaned up
-		return fullPath, errors.New(fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", sourceURI, resp.StatusCode), errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+		return fullPath, errors.New(&downloadErrors.HTTPStatusError{URI: sourceURI, StatusCode: resp.StatusCode}, errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
 	}
 
 	fileSize := -1
@@ -226,7 +295,7 @@ func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, f
 	dp := newDownloadProgressReporter(sourceURI, e.config.Timeout, fileSize, loggingObserver, detailsObserver)
 	dp.Report(ctx)
 
-	_, err = e.copy(destinationFile, io.TeeReader(resp.Body, dp))
+	attempt.bytes, err = e.copy(destinationFile, io.TeeReader(throttle.reader(ctx, sourceURI, resp.Body), dp))
 	if err != nil {
 		// checking for disk space error here before passing it into the reporter
 		// so the details observer sets the state with clean error message
@@ -235,10 +304,24 @@ func (e *Downloader) downloadFile(ctx context.Context, artifactName, filename, f
 			reportedErr = downloadErrors.ErrInsufficientDiskSpace
 		}
 		dp.ReportFailed(reportedErr)
+		copyErr := goerrors.Join(errors.New("copying fetched package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI)), err)
+		if state != nil && !e.isDiskSpaceErrorFunc(err) {
+			saveErr := saveResumeState(fullPath, state)
+			if saveErr == nil {
+				// keep the partial file to resume on the next attempt
+				return "", copyErr
+			}
+			e.log.Warnf("failed to save download state of %s: %v", fullPath, saveErr)
+		}
+		_ = removeResumeState(fullPath)
 		// return path, file already exists and needs to be cleaned up
-		return fullPath, goerrors.Join(errors.New("copying fetched package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI)), err)
+		return fullPath, copyErr
 	}
 	dp.ReportComplete()
 
+	if err := removeResumeState(fullPath); err != nil {
+		e.log.Warnf("failed to remove download state of %s: %v", fullPath, err)
+	}
+
 	return fullPath, nil
 }
@@ -15,7 +15,10 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -134,6 +137,252 @@ func TestDownloadBodyError(t *testing.T) {
 	assert.True(t, containsMessage(warnLogs, expectedMsg))
 }
 
+func TestDownloadResume(t *testing.T) {
+	// This tests the scenario where the download is interrupted part way
+	// through and the next download continues from the last downloaded byte.
+
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+
+	type connKey struct{}
+	var mx sync.Mutex
+	var ranges []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha512") {
+			_, _ = w.Write([]byte("checksum"))
+			return
+		}
+
+		mx.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		attempt := len(ranges)
+		mx.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if attempt == 1 {
+			// interrupt the first download half way
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			conn, ok := r.Context().Value(connKey{}).(net.Conn)
+			if ok {
+				_ = conn.Close()
+			}
+			return
+		}
+		http.ServeContent(w, r, "package", time.Time{}, bytes.NewReader(content))
+	}))
+	srv.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return context.WithValue(ctx, connKey{}, c)
+	}
+	srv.Start()
+	defer srv.Close()
+	client := srv.Client()
+
+	config := &artifact.Config{
+		SourceURI:       srv.URL,
+		TargetDirectory: t.TempDir(),
+		OperatingSystem: "linux",
+		Architecture:    "64",
+	}
+
+	log, _ := loggertest.New("downloader")
+	upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+	testClient := NewDownloaderWithClient(log, config, *client, upgradeDetails)
+
+	_, err := testClient.Download(context.Background(), beatSpec, version)
+	require.Error(t, err, "expected the first download to be interrupted")
+
+	packagePath, err := artifact.GetArtifactPath(beatSpec, *version, config.OperatingSystem, config.Arch(), config.TargetDirectory)
+	require.NoError(t, err)
+	require.FileExists(t, packagePath, "partial package must be kept to resume the download")
+	require.FileExists(t, packagePath+resumeSuffix)
+
+	artifactPath, err := testClient.Download(context.Background(), beatSpec, version)
+	require.NoError(t, err)
+	require.Equal(t, packagePath, artifactPath)
+
+	downloaded, err := os.ReadFile(artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.NoFileExists(t, packagePath+resumeSuffix)
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.Len(t, ranges, 2)
+	assert.Empty(t, ranges[0])
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(content)/2), ranges[1])
+}
+
+func TestDownloadSegmented(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+
+	type connKey struct{}
+	var mx sync.Mutex
+	var ranges []string
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha512") {
+			_, _ = w.Write([]byte("checksum"))
+			return
+		}
+
+		rangeHeader := r.Header.Get("Range")
+		mx.Lock()
+		failed := slices.Contains(ranges, rangeHeader)
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, rangeHeader)
+		}
+		mx.Unlock()
+
+		w.Header().Set("ETag", `"v1"`)
+		if r.Method == http.MethodGet && rangeHeader == "bytes=0-2559" && !failed {
+			// interrupt the first request of the first segment
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-2559/%d", len(content)))
+			w.Header().Set("Content-Length", "2560")
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(content[:1000])
+			w.(http.Flusher).Flush()
+			conn, ok := r.Context().Value(connKey{}).(net.Conn)
+			if ok {
+				_ = conn.Close()
+			}
+			return
+		}
+		http.ServeContent(w, r, "package", time.Time{}, bytes.NewReader(content))
+	}))
+	srv.Config.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+		return context.WithValue(ctx, connKey{}, c)
+	}
+	srv.Start()
+	defer srv.Close()
+	client := srv.Client()
+
+	config := &artifact.Config{
+		SourceURI:       srv.URL,
+		TargetDirectory: t.TempDir(),
+		OperatingSystem: "linux",
+		Architecture:    "64",
+		Segments: artifact.SegmentsConfig{
+			Count:       4,
+			Concurrency: 2,
+			MinSize:     1024,
+		},
+	}
+
+	log, _ := loggertest.New("downloader")
+	upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+	testClient := NewDownloaderWithClient(log, config, *client, upgradeDetails)
+
+	artifactPath, err := testClient.Download(context.Background(), beatSpec, version)
+	require.NoError(t, err)
+
+	downloaded, err := os.ReadFile(artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	mx.Lock()
+	defer mx.Unlock()
+	assert.ElementsMatch(t, []string{
+		"bytes=0-2559",
+		"bytes=1000-2559",
+		"bytes=2560-5119",
+		"bytes=5120-7679",
+		"bytes=7680-10239",
+	}, ranges, "the interrupted segment must continue from the last written byte")
+}
+
+func TestDownloadSegmentedRetryableStatus(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1024)
+
+	var mx sync.Mutex
+	statuses := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha512") {
+			_, _ = w.Write([]byte("checksum"))
+			return
+		}
+		rangeHeader := r.Header.Get("Range")
+		mx.Lock()
+		failed := statuses[rangeHeader] != 0
+		if r.Method == http.MethodGet && rangeHeader == "bytes=2560-5119" && !failed {
+			// the mirror is overloaded on the first request of the second segment
+			statuses[rangeHeader] = http.StatusServiceUnavailable
+			mx.Unlock()
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mx.Unlock()
+		http.ServeContent(w, r, "package", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	config := &artifact.Config{
+		SourceURI:       srv.URL,
+		TargetDirectory: t.TempDir(),
+		OperatingSystem: "linux",
+		Architecture:    "64",
+		Retry:           artifact.RetryConfig{MaxAttempts: 2},
+		Segments: artifact.SegmentsConfig{
+			Count:   4,
+			MinSize: 1024,
+		},
+	}
+
+	log, obs := loggertest.New("downloader")
+	upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+	testClient := NewDownloaderWithClient(log, config, *srv.Client(), upgradeDetails)
+
+	artifactPath, err := testClient.Download(context.Background(), beatSpec, version)
+	require.NoError(t, err)
+	downloaded, err := os.ReadFile(artifactPath)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+
+	failures := obs.FilterMessage("download request failed").TakeAll()
+	require.Len(t, failures, 1)
+	fields := failures[0].ContextMap()
+	assert.EqualValues(t, http.StatusServiceUnavailable, fields["http.response.status_code"])
+	assert.Equal(t, "bytes=2560-5119", fields["http.request.range"])
+	assert.EqualValues(t, 1, fields["download.attempt"])
+	assert.Equal(t, true, fields["download.retryable"])
+	assert.Len(t, obs.FilterMessage("download request completed").FilterField(zapcore.Field{Key: "http.request.range", Type: zapcore.StringType, String: "bytes=2560-5119"}).TakeAll(), 1)
+}
+
+func TestDownloadFatalStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	config := &artifact.Config{
+		SourceURI:       srv.URL,
+		TargetDirectory: t.TempDir(),
+		OperatingSystem: "linux",
+		Architecture:    "64",
+	}
+
+	log, obs := loggertest.New("downloader")
+	upgradeDetails := details.NewDetails("8.12.0", details.StateRequested, "")
+	testClient := NewDownloaderWithClient(log, config, *srv.Client(), upgradeDetails)
+
+	_, err := testClient.Download(context.Background(), beatSpec, version)
+	var statusErr *downloadErrors.HTTPStatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.False(t, downloadErrors.IsRetryable(err))
+
+	failures := obs.FilterMessage("download request failed").TakeAll()
+	require.Len(t, failures, 1)
+	assert.EqualValues(t, http.StatusNotFound, failures[0].ContextMap()["http.response.status_code"])
+	assert.Equal(t, false, failures[0].ContextMap()["download.retryable"])
+}
+
+func TestSplitSegments(t *testing.T) {
+	assert.Equal(t, []segment{{0, 2}, {3, 5}, {6, 10}}, splitSegments(11, 3))
+	assert.Equal(t, []segment{{0, 0}, {1, 1}}, splitSegments(2, 4))
+}
+
 func TestDownloadLogProgressWithLength(t *testing.T) {
 	fileSize := 100 * units.MB
 	chunks := 100
@@ -114,14 +114,15 @@ func (dpObs *detailsProgressObserver) Report(sourceURI string, timePast time.Dur
 	dpObs.mu.Lock()
 	defer dpObs.mu.Unlock()
 
-	dpObs.upgradeDetails.SetDownloadProgress(percentComplete, downloadRateBytesPerSecond)
+	dpObs.upgradeDetails.SetDownloadProgress(percentComplete, downloadRateBytesPerSecond,
+		details.EstimateRemaining(downloadedBytes, totalBytes, downloadRateBytesPerSecond))
 }
 
 func (dpObs *detailsProgressObserver) ReportCompleted(sourceURI string, timePast time.Duration, downloadRateBytesPerSecond float64) {
 	dpObs.mu.Lock()
 	defer dpObs.mu.Unlock()
 
-	dpObs.upgradeDetails.SetDownloadProgress(1, downloadRateBytesPerSecond)
+	dpObs.upgradeDetails.SetDownloadProgress(1, downloadRateBytesPerSecond, 0)
 }
 
 func (dpObs *detailsProgressObserver) ReportFailed(sourceURI string, timePast time.Duration, downloadedBytes, totalBytes, percentComplete, downloadRateBytesPerSecond float64, err error) {
@@ -0,0 +1,99 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resumeSuffix is the suffix of the sidecar file that keeps the state of an interrupted download
+// next to the partially downloaded file.
+const resumeSuffix = ".resume"
+
+// resumeState is the state of an interrupted download. The validator of the remote file is sent
+// with If-Range, so a remote file that changed since the interruption is downloaded again.
+type resumeState struct {
+	URI          string `json:"uri"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func (s *resumeState) validator() string {
+	if s.ETag != "" {
+		return s.ETag
+	}
+	return s.LastModified
+}
+
+// resumeStateFromResponse returns the state to resume the download of the response body, or nil
+// when the server does not support range requests or does not provide a validator for the file.
+func resumeStateFromResponse(sourceURI string, resp *http.Response) *resumeState {
+	if !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") {
+		return nil
+	}
+	state := &resumeState{
+		URI:          sourceURI,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	if state.validator() == "" {
+		return nil
+	}
+	return state
+}
+
+// loadResumeState returns the state of an interrupted download of sourceURI into fullPath along
+// with the number of bytes already downloaded. Returns nil when there is nothing to resume.
+func loadResumeState(fullPath, sourceURI string) (*resumeState, int64) {
+	data, err := os.ReadFile(fullPath + resumeSuffix)
+	if err != nil {
+		return nil, 0
+	}
+	var state resumeState
+	if err := json.Unmarshal(data, &state); err != nil || state.URI != sourceURI || state.validator() == "" {
+		return nil, 0
+	}
+	info, err := os.Stat(fullPath)
+	if err != nil || info.Size() == 0 {
+		return nil, 0
+	}
+	return &state, info.Size()
+}
+
+func saveResumeState(fullPath string, state *resumeState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fullPath+resumeSuffix, data, packagePermissions)
+}
+
+func removeResumeState(fullPath string) error {
+	err := os.Remove(fullPath + resumeSuffix)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// contentRangeStart returns the first byte position of the Content-Range header of a partial
+// response.
+func contentRangeStart(resp *http.Response) (int64, error) {
+	contentRange := resp.Header.Get("Content-Range")
+	rest, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, fmt.Errorf("invalid Content-Range %q", contentRange)
+	}
+	return strconv.ParseInt(start, 10, 64)
+}
@@ -0,0 +1,194 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package http
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"golang.org/x/sync/errgroup"
+
+	downloadErrors "github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+// segmentMaxAttempts is the number of attempts of a failed segment before the download fails, when the
+// retry budget doesn't limit the number of attempts.
+const segmentMaxAttempts = 6
+
+// segment is a byte range of the remote file, end is inclusive.
+type segment struct {
+	start int64
+	end   int64
+}
+
+// splitSegments splits a file of the size into count segments of about the same size.
+func splitSegments(size int64, count int) []segment {
+	if int64(count) > size {
+		count = int(size)
+	}
+	segments := make([]segment, 0, count)
+	segmentSize := size / int64(count)
+	var start int64
+	for i := 0; i < count; i++ {
+		end := start + segmentSize - 1
+		if i == count-1 {
+			end = size - 1
+		}
+		segments = append(segments, segment{start: start, end: end})
+		start = end + 1
+	}
+	return segments
+}
+
+// remoteFileInfo is the information about the remote file needed to download it in segments.
+type remoteFileInfo struct {
+	size      int64
+	validator string
+}
+
+// segmentedInfo requests the headers of the remote file and returns nil when the file cannot be
+// downloaded in segments, because the server does not support ranges or the file is too small.
+func (e *Downloader) segmentedInfo(ctx context.Context, sourceURI string) *remoteFileInfo {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, sourceURI, nil)
+	if err != nil {
+		return nil
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.log.Debugf("segmented download of %s not possible, HEAD request failed: %v", sourceURI, err)
+		return nil
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes") {
+		return nil
+	}
+	minSize := e.config.Segments.MinSize
+	if resp.ContentLength <= 0 || resp.ContentLength < minSize {
+		return nil
+	}
+	validator := resp.Header.Get("ETag")
+	if validator == "" {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	return &remoteFileInfo{size: resp.ContentLength, validator: validator}
+}
+
+// downloadSegmented downloads the remote file into the destination file in parallel ranged segments.
+// Every segment is retried within the retry budget, continuing from the last byte written for the
+// segment, so the pace adapts to a degraded connection instead of failing the whole download.
+func (e *Downloader) downloadSegmented(ctx context.Context, sourceURI string, info *remoteFileInfo, destinationFile *os.File, throttle *downloadThrottle) error {
+	if err := destinationFile.Truncate(info.size); err != nil {
+		return goerrors.Join(errors.New("allocating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, destinationFile.Name())), err)
+	}
+
+	segments := splitSegments(info.size, e.config.Segments.Count)
+	concurrency := e.config.Segments.Concurrency
+	if concurrency <= 0 || concurrency > len(segments) {
+		concurrency = len(segments)
+	}
+	e.log.Infof("downloading %s in %d segments with %d concurrent requests", sourceURI, len(segments), concurrency)
+
+	loggingObserver := newLoggingProgressObserver(e.log, e.config.Timeout)
+	detailsObserver := newDetailsProgressObserver(e.upgradeDetails)
+	dp := newDownloadProgressReporter(sourceURI, e.config.Timeout, int(info.size), loggingObserver, detailsObserver)
+	dp.Report(ctx)
+
+	g, gCtx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+	for _, s := range segments {
+		g.Go(func() error {
+			return e.downloadSegment(gCtx, sourceURI, info.validator, s, destinationFile, dp, throttle)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		reportedErr := err
+		if e.isDiskSpaceErrorFunc(err) {
+			reportedErr = downloadErrors.ErrInsufficientDiskSpace
+		}
+		dp.ReportFailed(reportedErr)
+		return goerrors.Join(errors.New("copying fetched package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI)), err)
+	}
+	dp.ReportComplete()
+	return nil
+}
+
+func (e *Downloader) downloadSegment(ctx context.Context, sourceURI, validator string, s segment, destinationFile *os.File, dp io.Writer, throttle *downloadThrottle) error {
+	retry := e.config.Retry
+	if retry.MaxAttempts == 0 {
+		retry.MaxAttempts = segmentMaxAttempts
+	}
+	boCtx := backoff.WithContext(retry.BackOff(time.Second), ctx)
+
+	offset := s.start
+	var attempts int
+	opFn := func() (err error) {
+		attempts++
+		attempt := newRequestAttempt(sourceURI, attempts)
+		attempt.byteRange = fmt.Sprintf("bytes=%d-%d", offset, s.end)
+		defer func() {
+			e.reportAttempt(attempt, err)
+		}()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURI, nil)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		req.Header.Set("Range", attempt.byteRange)
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+
+		resp, err := e.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		attempt.statusCode = resp.StatusCode
+
+		if resp.StatusCode != http.StatusPartialContent {
+			statusErr := fmt.Errorf("bytes %d-%d: %w", offset, s.end, &downloadErrors.HTTPStatusError{URI: sourceURI, StatusCode: resp.StatusCode})
+			if downloadErrors.IsRetryableStatus(resp.StatusCode) {
+				return statusErr
+			}
+			// remote file changed or the range is not satisfiable, retrying does not help
+			return backoff.Permanent(statusErr)
+		}
+		start, err := contentRangeStart(resp)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+		if start != offset {
+			return backoff.Permanent(fmt.Errorf("expected partial content from byte %d, got byte %d", offset, start))
+		}
+
+		w := io.NewOffsetWriter(destinationFile, offset)
+		n, err := e.copy(w, io.TeeReader(throttle.reader(ctx, sourceURI, io.LimitReader(resp.Body, s.end-offset+1)), dp))
+		attempt.bytes = n
+		offset += n
+		if err != nil {
+			if e.isDiskSpaceErrorFunc(err) {
+				return backoff.Permanent(err)
+			}
+			return err
+		}
+		if offset <= s.end {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	notify := func(err error, retryAfter time.Duration) {
+		e.log.Warnf("download of bytes %d-%d from %s failed: %v, retrying in %s", offset, s.end, sourceURI, err, retryAfter)
+	}
+	return backoff.RetryNotify(opFn, boCtx, notify)
+}
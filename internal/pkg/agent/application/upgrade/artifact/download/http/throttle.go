@@ -0,0 +1,155 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/docker/go-units"
+	"golang.org/x/time/rate"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// windowTimeFormat is the format of the start and end of the download window.
+const windowTimeFormat = "15:04"
+
+// downloadWindow is the time of the day downloads are allowed in, as minutes since midnight.
+type downloadWindow struct {
+	start int
+	end   int
+}
+
+func parseDownloadWindow(cfg artifact.WindowConfig) (*downloadWindow, error) {
+	if cfg.Start == "" && cfg.End == "" {
+		return nil, nil
+	}
+	start, err := time.Parse(windowTimeFormat, cfg.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download window start %q: %w", cfg.Start, err)
+	}
+	end, err := time.Parse(windowTimeFormat, cfg.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid download window end %q: %w", cfg.End, err)
+	}
+	return &downloadWindow{
+		start: start.Hour()*60 + start.Minute(),
+		end:   end.Hour()*60 + end.Minute(),
+	}, nil
+}
+
+// untilOpen returns how long until the window opens, zero when the window is open.
+func (w *downloadWindow) untilOpen(now time.Time) time.Duration {
+	minute := now.Hour()*60 + now.Minute()
+	var open bool
+	switch {
+	case w.start == w.end:
+		open = true
+	case w.start < w.end:
+		open = minute >= w.start && minute < w.end
+	default:
+		// window spans midnight
+		open = minute >= w.start || minute < w.end
+	}
+	if open {
+		return 0
+	}
+	startOfMinute := now.Truncate(time.Minute)
+	wait := w.start - minute
+	if wait < 0 {
+		wait += 24 * 60
+	}
+	return startOfMinute.Add(time.Duration(wait) * time.Minute).Sub(now)
+}
+
+// downloadThrottle limits the bandwidth used by downloads with a token bucket and holds downloads
+// while outside the allowed time window.
+type downloadThrottle struct {
+	log     *logger.Logger
+	limiter *rate.Limiter
+	window  *downloadWindow
+	now     func() time.Time
+}
+
+func newDownloadThrottle(log *logger.Logger, config *artifact.Config) (*downloadThrottle, error) {
+	t := &downloadThrottle{
+		log: log,
+		now: time.Now,
+	}
+	if config.RateLimit != "" {
+		limit, err := units.RAMInBytes(config.RateLimit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid download rate limit %q: %w", config.RateLimit, err)
+		}
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid download rate limit %q: must be positive", config.RateLimit)
+		}
+		t.limiter = rate.NewLimiter(rate.Limit(limit), int(limit))
+	}
+	window, err := parseDownloadWindow(config.Window)
+	if err != nil {
+		return nil, err
+	}
+	t.window = window
+	return t, nil
+}
+
+// waitWindow blocks until the download window is open.
+func (t *downloadThrottle) waitWindow(ctx context.Context, sourceURI string) error {
+	if t.window == nil {
+		return nil
+	}
+	for {
+		wait := t.window.untilOpen(t.now())
+		if wait <= 0 {
+			return nil
+		}
+		t.log.Infof("download from %s is outside of the download window, waiting %s", sourceURI, wait)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reader wraps the reader so reading is limited to the rate limit and holds while outside of the
+// download window.
+func (t *downloadThrottle) reader(ctx context.Context, sourceURI string, r io.Reader) io.Reader {
+	if t.limiter == nil && t.window == nil {
+		return r
+	}
+	return &throttledReader{ctx: ctx, sourceURI: sourceURI, throttle: t, r: r}
+}
+
+type throttledReader struct {
+	ctx       context.Context
+	sourceURI string
+	throttle  *downloadThrottle
+	r         io.Reader
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	if err := tr.throttle.waitWindow(tr.ctx, tr.sourceURI); err != nil {
+		return 0, err
+	}
+	limiter := tr.throttle.limiter
+	if limiter != nil && len(p) > limiter.Burst() {
+		p = p[:limiter.Burst()]
+	}
+	n, err := tr.r.Read(p)
+	if limiter != nil && n > 0 {
+		if waitErr := limiter.WaitN(tr.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package http
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestDownloadWindowUntilOpen(t *testing.T) {
+	at := func(hour, minute, second int) time.Time {
+		return time.Date(2024, 1, 1, hour, minute, second, 0, time.Local)
+	}
+
+	testcases := map[string]struct {
+		window   artifact.WindowConfig
+		now      time.Time
+		expected time.Duration
+	}{
+		"inside window": {
+			window:   artifact.WindowConfig{Start: "09:00", End: "17:00"},
+			now:      at(12, 0, 0),
+			expected: 0,
+		},
+		"before window": {
+			window:   artifact.WindowConfig{Start: "09:00", End: "17:00"},
+			now:      at(8, 30, 30),
+			expected: 29*time.Minute + 30*time.Second,
+		},
+		"after window": {
+			window:   artifact.WindowConfig{Start: "09:00", End: "17:00"},
+			now:      at(17, 0, 0),
+			expected: 16 * time.Hour,
+		},
+		"inside window spanning midnight": {
+			window:   artifact.WindowConfig{Start: "22:00", End: "06:00"},
+			now:      at(1, 0, 0),
+			expected: 0,
+		},
+		"outside window spanning midnight": {
+			window:   artifact.WindowConfig{Start: "22:00", End: "06:00"},
+			now:      at(12, 0, 0),
+			expected: 10 * time.Hour,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			window, err := parseDownloadWindow(tc.window)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, window.untilOpen(tc.now))
+		})
+	}
+}
+
+func TestNewDownloadThrottleInvalid(t *testing.T) {
+	log, _ := loggertest.New("throttle")
+
+	_, err := newDownloadThrottle(log, &artifact.Config{RateLimit: "fast"})
+	assert.Error(t, err)
+
+	_, err = newDownloadThrottle(log, &artifact.Config{Window: artifact.WindowConfig{Start: "9am", End: "17:00"}})
+	assert.Error(t, err)
+}
+
+func TestDownloadThrottleRateLimit(t *testing.T) {
+	log, _ := loggertest.New("throttle")
+	throttle, err := newDownloadThrottle(log, &artifact.Config{RateLimit: "10KB"})
+	require.NoError(t, err)
+
+	content := bytes.Repeat([]byte("a"), 20*1024)
+	started := time.Now()
+	read, err := io.ReadAll(throttle.reader(context.Background(), "test", bytes.NewReader(content)))
+	require.NoError(t, err)
+	assert.Equal(t, content, read)
+	// the first 10KB are available right away from the bucket, the next 10KB take a second
+	assert.GreaterOrEqual(t, time.Since(started), 900*time.Millisecond)
+}
+
+func TestDownloadThrottleWaitWindow(t *testing.T) {
+	log, _ := loggertest.New("throttle")
+	throttle, err := newDownloadThrottle(log, &artifact.Config{Window: artifact.WindowConfig{Start: "09:00", End: "17:00"}})
+	require.NoError(t, err)
+	throttle.now = func() time.Time {
+		return time.Date(2024, 1, 1, 20, 0, 0, 0, time.Local)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err = throttle.waitWindow(ctx, "test")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -48,7 +48,7 @@ func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (*Veri
 		return nil, errors.New("expecting PGP key received none", errors.TypeSecurity)
 	}
 
-	client, err := config.HTTPTransportSettings.Client(
+	client, err := config.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
 		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
 			return download.WithHeaders(rt, download.Headers)
@@ -61,19 +61,22 @@ func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (*Veri
 		return nil, err
 	}
 
-	v := &Verifier{
+	return NewVerifierWithClient(log, config, *client, pgp), nil
+}
+
+// NewVerifierWithClient creates a verifier that uses the specific client to fetch the ASC files.
+func NewVerifierWithClient(log *logger.Logger, config *artifact.Config, client http.Client, pgp []byte) *Verifier {
+	return &Verifier{
 		config:     config,
-		client:     *client,
+		client:     client,
 		defaultKey: pgp,
 		log:        log,
 	}
-
-	return v, nil
 }
 
 func (v *Verifier) Reload(c *artifact.Config) error {
 	// reload client
-	client, err := c.HTTPTransportSettings.Client(
+	client, err := c.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
 		httpcommon.WithModRoundtripper(func(rt http.RoundTripper) http.RoundTripper {
 			return download.WithHeaders(rt, download.Headers)
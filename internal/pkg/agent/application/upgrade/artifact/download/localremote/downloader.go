@@ -10,6 +10,8 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/composed"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/fs"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/objectstore"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/peercache"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/snapshot"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -19,7 +21,7 @@ import (
 // NewDownloader creates a downloader which first checks local directory
 // and then fallbacks to remote if configured.
 func NewDownloader(log *logger.Logger, config *artifact.Config, upgradeDetails *details.Details) (download.Downloader, error) {
-	downloaders := make([]download.Downloader, 0, 3)
+	downloaders := make([]download.Downloader, 0, 4)
 	downloaders = append(downloaders, fs.NewDownloader(config))
 
 	// If the current build is a snapshot we use this downloader to update
@@ -35,11 +37,37 @@ func NewDownloader(log *logger.Logger, config *artifact.Config, upgradeDetails *
 		}
 	}
 
-	httpDownloader, err := http.NewDownloader(log, config, upgradeDetails)
+	// try the peer caches of the site before the remote source
+	if len(config.PeerCache.Sources) > 0 {
+		peerDownloader, err := peercache.NewDownloader(log, config, upgradeDetails)
+		if err != nil {
+			return nil, err
+		}
+		downloaders = append(downloaders, peerDownloader)
+	}
+
+	remoteDownloader, err := NewRemoteDownloader(log, config, upgradeDetails)
 	if err != nil {
 		return nil, err
 	}
 
-	downloaders = append(downloaders, httpDownloader)
+	downloaders = append(downloaders, remoteDownloader)
 	return composed.NewDownloader(downloaders...), nil
 }
+
+// NewRemoteDownloader creates the downloader of the remote source URI of the configuration, either an object
+// storage bucket (s3://, gs:// or azblob://) or an HTTP server.
+func NewRemoteDownloader(log *logger.Logger, config *artifact.Config, upgradeDetails *details.Details) (download.Downloader, error) {
+	if objectstore.IsSourceURI(config.SourceURI) {
+		d, err := objectstore.NewDownloader(log, config, upgradeDetails)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	d, err := http.NewDownloader(log, config, upgradeDetails)
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/composed"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/fs"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/objectstore"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/peercache"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/snapshot"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -18,7 +20,7 @@ import (
 // NewVerifier creates a downloader which first checks local directory
 // and then fallbacks to remote if configured.
 func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (download.Verifier, error) {
-	verifiers := make([]download.Verifier, 0, 3)
+	verifiers := make([]download.Verifier, 0, 4)
 
 	fsVer, err := fs.NewVerifier(log, config, pgp)
 	if err != nil {
@@ -38,11 +40,38 @@ func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (downl
 		}
 	}
 
-	remoteVer, err := http.NewVerifier(log, config, pgp)
+	remoteVer, err := NewRemoteVerifier(log, config, pgp)
 	if err != nil {
 		return nil, err
 	}
 	verifiers = append(verifiers, remoteVer)
 
+	// the signatures served by the peer caches are checked against the same key, they are used when the
+	// remote source is not reachable
+	if len(config.PeerCache.Sources) > 0 {
+		peerVer, err := peercache.NewVerifier(log, config, pgp)
+		if err != nil {
+			return nil, err
+		}
+		verifiers = append(verifiers, peerVer)
+	}
+
 	return composed.NewVerifier(log, verifiers...), nil
 }
+
+// NewRemoteVerifier creates the verifier of the remote source URI of the configuration, either an object
+// storage bucket (s3://, gs:// or azblob://) or an HTTP server.
+func NewRemoteVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (download.Verifier, error) {
+	if objectstore.IsSourceURI(config.SourceURI) {
+		v, err := objectstore.NewVerifier(log, config, pgp)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+	v, err := http.NewVerifier(log, config, pgp)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
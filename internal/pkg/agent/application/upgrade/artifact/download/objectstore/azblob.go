@@ -0,0 +1,58 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+type azureBucket struct {
+	client    *azblob.Client
+	container string
+}
+
+// newAzureBucket creates the bucket with the default credential chain of Azure (environment, workload
+// identity, managed identity and Azure CLI). The endpoint query parameter selects the blob service of
+// sovereign clouds or of an emulator, it defaults to https://<account>.blob.core.windows.net/.
+func newAzureBucket(_ context.Context, source *sourceURI) (bucket, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credentials: %w", err)
+	}
+	serviceURL := source.query.Get("endpoint")
+	if serviceURL == "" {
+		serviceURL = fmt.Sprintf("https://%s.blob.core.windows.net/", source.bucket)
+	}
+	client, err := azblob.NewClient(serviceURL, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure Blob Storage client: %w", err)
+	}
+	return &azureBucket{client: client, container: source.container}, nil
+}
+
+func (b *azureBucket) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	resp, err := b.client.DownloadStream(ctx, b.container, key, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+			return nil, 0, errObjectNotFound
+		}
+		return nil, 0, err
+	}
+	size := int64(-1)
+	if resp.ContentLength != nil {
+		size = *resp.ContentLength
+	}
+	return resp.Body, size, nil
+}
+
+func (b *azureBucket) Close() error {
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package objectstore downloads artifacts hosted in object storage: Amazon S3 (s3://), Google Cloud
+// Storage (gs://) and Azure Blob Storage (azblob://). Credentials are resolved with the standard
+// credential chain of each provider.
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// errObjectNotFound is returned by buckets when the object does not exist.
+var errObjectNotFound = errors.New("object not found")
+
+// bucket reads objects of an object storage bucket.
+type bucket interface {
+	// Open opens the object, size is -1 when unknown.
+	Open(ctx context.Context, key string) (body io.ReadCloser, size int64, err error)
+	// Close releases the client of the bucket.
+	Close() error
+}
+
+// bucketFactory creates the bucket of a source URI.
+type bucketFactory func(ctx context.Context, source *sourceURI) (bucket, error)
+
+// backends are the supported object storage, by the scheme of their source URIs.
+var backends = map[string]bucketFactory{
+	"s3":     newS3Bucket,
+	"gs":     newGCSBucket,
+	"azblob": newAzureBucket,
+}
+
+// IsSourceURI returns true when the source URI is an object storage URI.
+func IsSourceURI(uri string) bool {
+	scheme, _, found := strings.Cut(uri, "://")
+	if !found {
+		return false
+	}
+	_, ok := backends[strings.ToLower(scheme)]
+	return ok
+}
+
+// sourceURI is a parsed object storage source URI:
+//   - s3://<bucket>/<prefix>[?region=<region>&endpoint=<endpoint>]
+//   - gs://<bucket>/<prefix>
+//   - azblob://<account>/<container>/<prefix>[?endpoint=<endpoint>]
+type sourceURI struct {
+	scheme string
+	// bucket is the bucket, the storage account for Azure
+	bucket string
+	// container is the container of Azure, empty for the other backends
+	container string
+	prefix    string
+	query     url.Values
+}
+
+func parseSourceURI(uri string) (*sourceURI, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid source URI %q: %w", uri, err)
+	}
+	s := &sourceURI{
+		scheme: strings.ToLower(u.Scheme),
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+		query:  u.Query(),
+	}
+	if _, ok := backends[s.scheme]; !ok {
+		return nil, fmt.Errorf("unsupported object storage source URI %q", uri)
+	}
+	if s.bucket == "" {
+		return nil, fmt.Errorf("source URI %q is missing the bucket", uri)
+	}
+	if s.scheme == "azblob" {
+		s.container, s.prefix, _ = strings.Cut(s.prefix, "/")
+		if s.container == "" {
+			return nil, fmt.Errorf("source URI %q is missing the container", uri)
+		}
+	}
+	return s, nil
+}
+
+// key returns the key of the file of the artifact, following the same layout as the artifacts on HTTP
+// servers: <prefix>/<artifact>/<filename>.
+func (s *sourceURI) key(artifactName, filename string) string {
+	return strings.TrimPrefix(path.Join(s.prefix, artifactName, filename), "/")
+}
+
+// String returns the URI of the bucket, without the query.
+func (s *sourceURI) String() string {
+	parts := []string{s.bucket}
+	if s.container != "" {
+		parts = append(parts, s.container)
+	}
+	if s.prefix != "" {
+		parts = append(parts, s.prefix)
+	}
+	return s.scheme + "://" + strings.Join(parts, "/")
+}
+
+// objectURI returns the URI of the object with the key, used in logs and errors.
+func (s *sourceURI) objectURI(key string) string {
+	bucketURI := s.scheme + "://" + s.bucket
+	if s.container != "" {
+		bucketURI += "/" + s.container
+	}
+	return bucketURI + "/" + key
+}
+
+func newBucket(ctx context.Context, source *sourceURI) (bucket, error) {
+	return backends[source.scheme](ctx, source)
+}
@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package objectstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsSourceURI(t *testing.T) {
+	assert.True(t, IsSourceURI("s3://artifacts/downloads"))
+	assert.True(t, IsSourceURI("GS://artifacts"))
+	assert.True(t, IsSourceURI("azblob://account/container"))
+	assert.False(t, IsSourceURI("https://artifacts.elastic.co/downloads"))
+	assert.False(t, IsSourceURI("/var/artifacts"))
+	assert.False(t, IsSourceURI("artifacts.elastic.co/downloads"))
+}
+
+func TestParseSourceURI(t *testing.T) {
+	testcases := map[string]struct {
+		uri       string
+		bucket    string
+		container string
+		prefix    string
+		key       string
+		err       string
+	}{
+		"s3": {
+			uri:    "s3://artifacts/elastic/downloads/?region=eu-west-1",
+			bucket: "artifacts",
+			prefix: "elastic/downloads",
+			key:    "elastic/downloads/beats/elastic-agent/elastic-agent-9.0.0-linux-x86_64.tar.gz",
+		},
+		"gs without prefix": {
+			uri:    "gs://artifacts",
+			bucket: "artifacts",
+			key:    "beats/elastic-agent/elastic-agent-9.0.0-linux-x86_64.tar.gz",
+		},
+		"azblob": {
+			uri:       "azblob://account/container/downloads",
+			bucket:    "account",
+			container: "container",
+			prefix:    "downloads",
+			key:       "downloads/beats/elastic-agent/elastic-agent-9.0.0-linux-x86_64.tar.gz",
+		},
+		"azblob without container": {
+			uri: "azblob://account",
+			err: "missing the container",
+		},
+		"missing bucket": {
+			uri: "s3:///downloads",
+			err: "missing the bucket",
+		},
+		"unsupported": {
+			uri: "https://artifacts.elastic.co/downloads",
+			err: "unsupported object storage source URI",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			s, err := parseSourceURI(tc.uri)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.bucket, s.bucket)
+			assert.Equal(t, tc.container, s.container)
+			assert.Equal(t, tc.prefix, s.prefix)
+			assert.Equal(t, tc.key, s.key("beats/elastic-agent", "elastic-agent-9.0.0-linux-x86_64.tar.gz"))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package objectstore
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	packagePermissions = 0o660
+
+	// progressInterval is the minimum interval between two updates of the download progress.
+	progressInterval = time.Second
+)
+
+// Downloader downloads artifacts from an object storage bucket.
+type Downloader struct {
+	log            *logger.Logger
+	config         *artifact.Config
+	upgradeDetails *details.Details
+	newBucket      bucketFactory
+}
+
+// NewDownloader creates a downloader for the object storage source URI of the configuration.
+func NewDownloader(log *logger.Logger, config *artifact.Config, upgradeDetails *details.Details) (*Downloader, error) {
+	if _, err := parseSourceURI(config.SourceURI); err != nil {
+		return nil, errors.New(err, "invalid object storage source URI", errors.TypeConfig, errors.M(errors.MetaKeyURI, config.SourceURI))
+	}
+	return &Downloader{
+		log:            log,
+		config:         config,
+		upgradeDetails: upgradeDetails,
+		newBucket:      newBucket,
+	}, nil
+}
+
+// Reload reloads the configuration of the downloader.
+func (d *Downloader) Reload(c *artifact.Config) error {
+	if _, err := parseSourceURI(c.SourceURI); err != nil {
+		return errors.New(err, "objectstore.downloader: invalid object storage source URI", errors.TypeConfig, errors.M(errors.MetaKeyURI, c.SourceURI))
+	}
+	d.config = c
+	return nil
+}
+
+// Download fetches the package and its hash from the bucket.
+// Returns absolute path to downloaded package and an error.
+func (d *Downloader) Download(ctx context.Context, a artifact.Artifact, version *agtversion.ParsedSemVer) (_ string, err error) {
+	source, err := parseSourceURI(d.config.SourceURI)
+	if err != nil {
+		return "", errors.New(err, "invalid object storage source URI", errors.TypeConfig, errors.M(errors.MetaKeyURI, d.config.SourceURI))
+	}
+	b, err := d.newBucket(ctx, source)
+	if err != nil {
+		return "", errors.New(err, "failed to access the object storage", errors.TypeNetwork, errors.M(errors.MetaKeyURI, source.String()))
+	}
+	defer b.Close()
+
+	filename, err := artifact.GetArtifactName(a, *version, d.config.OS(), d.config.Arch())
+	if err != nil {
+		return "", errors.New(err, "generating package name failed")
+	}
+	fullPath, err := artifact.GetArtifactPath(a, *version, d.config.OS(), d.config.Arch(), d.config.TargetDirectory)
+	if err != nil {
+		return "", errors.New(err, "generating package path failed")
+	}
+
+	downloadedFiles := make([]string, 0, 2)
+	defer func() {
+		if err != nil {
+			for _, path := range downloadedFiles {
+				if err := os.Remove(path); err != nil && !goerrors.Is(err, os.ErrNotExist) {
+					d.log.Warnf("failed to cleanup %s: %v", path, err)
+				}
+			}
+		}
+	}()
+
+	downloadedFiles = append(downloadedFiles, fullPath)
+	if err := d.downloadFile(ctx, b, source, source.key(a.Artifact, filename), fullPath, true); err != nil {
+		return "", err
+	}
+	downloadedFiles = append(downloadedFiles, fullPath+".sha512")
+	if err := d.downloadFile(ctx, b, source, source.key(a.Artifact, filename+".sha512"), fullPath+".sha512", false); err != nil {
+		return "", err
+	}
+	return fullPath, nil
+}
+
+func (d *Downloader) downloadFile(ctx context.Context, b bucket, source *sourceURI, key string, fullPath string, reportProgress bool) error {
+	objectURI := source.objectURI(key)
+	body, size, err := b.Open(ctx, key)
+	if err != nil {
+		return errors.New(err, fmt.Sprintf("fetching %s failed", objectURI), errors.TypeNetwork, errors.M(errors.MetaKeyURI, objectURI))
+	}
+	defer body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return errors.New(err, "creating package directory failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+	}
+	f, err := os.OpenFile(fullPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, packagePermissions)
+	if err != nil {
+		return errors.New(err, "creating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if reportProgress && d.upgradeDetails != nil {
+		w = io.MultiWriter(f, newProgressWriter(d.upgradeDetails, size))
+	}
+	d.log.Infof("downloading %s to %s", objectURI, fullPath)
+	if _, err := io.Copy(w, body); err != nil {
+		return errors.New(err, fmt.Sprintf("copying %s failed", objectURI), errors.TypeNetwork, errors.M(errors.MetaKeyURI, objectURI))
+	}
+	return f.Close()
+}
+
+// progressWriter reports the progress of a download to the upgrade details.
+type progressWriter struct {
+	details    *details.Details
+	size       int64
+	written    int64
+	started    time.Time
+	lastReport time.Time
+}
+
+func newProgressWriter(d *details.Details, size int64) *progressWriter {
+	return &progressWriter{details: d, size: size, started: time.Now()}
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	now := time.Now()
+	if p.size <= 0 || (now.Sub(p.lastReport) < progressInterval && p.written < p.size) {
+		return len(b), nil
+	}
+	p.lastReport = now
+	rate := 0.0
+	if elapsed := now.Sub(p.started).Seconds(); elapsed > 0 {
+		rate = float64(p.written) / elapsed
+	}
+	p.details.SetDownloadProgress(float64(p.written)/float64(p.size), rate,
+		details.EstimateRemaining(float64(p.written), float64(p.size), rate))
+	return len(b), nil
+}
@@ -0,0 +1,155 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+	"github.com/elastic/elastic-agent/testing/pgptest"
+)
+
+var testVersion = agtversion.NewParsedSemVer(9, 1, 0, "", "")
+
+var agentSpec = artifact.Artifact{
+	Name:     "Elastic Agent",
+	Cmd:      "elastic-agent",
+	Artifact: "beats/elastic-agent",
+}
+
+// memBucket is a bucket holding its objects in memory.
+type memBucket struct {
+	objects map[string][]byte
+	closed  bool
+}
+
+func (b *memBucket) Open(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	data, ok := b.objects[key]
+	if !ok {
+		return nil, 0, errObjectNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), int64(len(data)), nil
+}
+
+func (b *memBucket) Close() error {
+	b.closed = true
+	return nil
+}
+
+func newTestConfig(t *testing.T, sourceURI string) *artifact.Config {
+	return &artifact.Config{
+		SourceURI:             sourceURI,
+		TargetDirectory:       t.TempDir(),
+		OperatingSystem:       "linux",
+		Architecture:          "64",
+		HTTPTransportSettings: artifact.DefaultConfig().HTTPTransportSettings,
+	}
+}
+
+// newTestBucket returns a bucket with the package, its hash and its signature under the prefix, and the
+// public key of the signature.
+func newTestBucket(t *testing.T, cfg *artifact.Config, prefix string) (*memBucket, []byte, []byte) {
+	filename, err := artifact.GetArtifactName(agentSpec, *testVersion, cfg.OS(), cfg.Arch())
+	require.NoError(t, err)
+
+	content := []byte("sample content")
+	hash := sha512.Sum512(content)
+	pub, sig := pgptest.Sign(t, bytes.NewReader(content))
+	key := prefix + "/" + agentSpec.Artifact + "/" + filename
+	return &memBucket{objects: map[string][]byte{
+		key:             content,
+		key + ".sha512": []byte(fmt.Sprintf("%x %s", hash, filename)),
+		key + ".asc":    sig,
+	}}, content, pub
+}
+
+func TestDownloader(t *testing.T) {
+	log, _ := loggertest.New("objectstore")
+	cfg := newTestConfig(t, "s3://artifacts/elastic?region=eu-west-1")
+	mem, content, _ := newTestBucket(t, cfg, "elastic")
+
+	upgradeDetails := details.NewDetails(testVersion.String(), details.StateDownloading, "")
+	d, err := NewDownloader(log, cfg, upgradeDetails)
+	require.NoError(t, err)
+	d.newBucket = func(_ context.Context, source *sourceURI) (bucket, error) {
+		assert.Equal(t, "artifacts", source.bucket)
+		assert.Equal(t, "eu-west-1", source.query.Get("region"))
+		return mem, nil
+	}
+
+	path, err := d.Download(context.Background(), agentSpec, testVersion)
+	require.NoError(t, err)
+	assert.True(t, mem.closed, "bucket must be closed after the download")
+
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, downloaded)
+	assert.FileExists(t, path+".sha512")
+	assert.Equal(t, 1.0, upgradeDetails.Metadata.DownloadPercent)
+}
+
+func TestDownloaderMissingHash(t *testing.T) {
+	log, _ := loggertest.New("objectstore")
+	cfg := newTestConfig(t, "gs://artifacts/elastic")
+	mem, _, _ := newTestBucket(t, cfg, "elastic")
+	for key := range mem.objects {
+		if filepath.Ext(key) == ".sha512" {
+			delete(mem.objects, key)
+		}
+	}
+
+	d, err := NewDownloader(log, cfg, nil)
+	require.NoError(t, err)
+	d.newBucket = func(context.Context, *sourceURI) (bucket, error) {
+		return mem, nil
+	}
+
+	_, err = d.Download(context.Background(), agentSpec, testVersion)
+	require.ErrorIs(t, err, errObjectNotFound)
+
+	entries, err := os.ReadDir(cfg.TargetDirectory)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "downloaded files must be removed on failure")
+}
+
+func TestVerifier(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "verifier being tested uses an OpenPGP key which results in a SHA-1 violation.")
+
+	log, _ := loggertest.New("objectstore")
+	cfg := newTestConfig(t, "azblob://account/container/elastic")
+	cfg.Timeout = 30 * time.Second
+	mem, _, pub := newTestBucket(t, cfg, "elastic")
+
+	d, err := NewDownloader(log, cfg, nil)
+	require.NoError(t, err)
+	d.newBucket = func(context.Context, *sourceURI) (bucket, error) {
+		return mem, nil
+	}
+	_, err = d.Download(context.Background(), agentSpec, testVersion)
+	require.NoError(t, err)
+
+	v, err := NewVerifier(log, cfg, pub)
+	require.NoError(t, err)
+	v.newBucket = func(_ context.Context, source *sourceURI) (bucket, error) {
+		assert.Equal(t, "container", source.container)
+		return mem, nil
+	}
+	require.NoError(t, v.Verify(context.Background(), agentSpec, *testVersion, false))
+}
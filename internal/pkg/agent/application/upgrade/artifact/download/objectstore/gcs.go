@@ -0,0 +1,43 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package objectstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+type gcsBucket struct {
+	client *storage.Client
+	name   string
+}
+
+// newGCSBucket creates the bucket with the application default credentials of Google Cloud.
+func newGCSBucket(ctx context.Context, source *sourceURI) (bucket, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google Cloud Storage client: %w", err)
+	}
+	return &gcsBucket{client: client, name: source.bucket}, nil
+}
+
+func (b *gcsBucket) Open(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	r, err := b.client.Bucket(b.name).Object(key).NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotExist) {
+			return nil, 0, errObjectNotFound
+		}
+		return nil, 0, err
+	}
+	return r, r.Attrs.Size, nil
+}
+
+func (b *gcsBucket) Close() error {
+	return b.client.Close()
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package objectstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const ascSuffix = ".asc"
+
+// ascMaxSize is the maximum size of a signature file.
+const ascMaxSize = 64 * 1024
+
+// Verifier verifies a downloaded package with the ASC file stored next to it in the bucket.
+type Verifier struct {
+	log        *logger.Logger
+	config     *artifact.Config
+	defaultKey []byte
+	// client fetches the PGP keys configured as URIs
+	client    http.Client
+	newBucket bucketFactory
+}
+
+func (v *Verifier) Name() string {
+	return "objectstore.verifier"
+}
+
+// NewVerifier creates a verifier for the object storage source URI of the configuration.
+func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (*Verifier, error) {
+	if len(pgp) == 0 {
+		return nil, errors.New("expecting PGP key received none", errors.TypeSecurity)
+	}
+	if _, err := parseSourceURI(config.SourceURI); err != nil {
+		return nil, errors.New(err, "invalid object storage source URI", errors.TypeConfig, errors.M(errors.MetaKeyURI, config.SourceURI))
+	}
+	client, err := config.Client(httpcommon.WithAPMHTTPInstrumentation())
+	if err != nil {
+		return nil, err
+	}
+	return &Verifier{
+		log:        log,
+		config:     config,
+		defaultKey: pgp,
+		client:     *client,
+		newBucket:  newBucket,
+	}, nil
+}
+
+// Reload reloads the configuration of the verifier.
+func (v *Verifier) Reload(c *artifact.Config) error {
+	if _, err := parseSourceURI(c.SourceURI); err != nil {
+		return errors.New(err, "objectstore.verifier: invalid object storage source URI", errors.TypeConfig, errors.M(errors.MetaKeyURI, c.SourceURI))
+	}
+	client, err := c.Client(httpcommon.WithAPMHTTPInstrumentation())
+	if err != nil {
+		return errors.New(err, "objectstore.verifier: failed to generate client out of config")
+	}
+	v.client = *client
+	v.config = c
+	return nil
+}
+
+// Verify checks the hash of the downloaded package and its signature with the ASC file of the bucket.
+func (v *Verifier) Verify(ctx context.Context, a artifact.Artifact, version agtversion.ParsedSemVer, skipDefaultPgp bool, pgpBytes ...string) error {
+	artifactPath, err := artifact.GetArtifactPath(a, version, v.config.OS(), v.config.Arch(), v.config.TargetDirectory)
+	if err != nil {
+		return errors.New(err, "retrieving package path")
+	}
+
+	if err = download.VerifySHA512HashWithCleanup(v.log, artifactPath); err != nil {
+		return fmt.Errorf("failed to verify SHA512 hash: %w", err)
+	}
+
+	if err = v.verifyAsc(ctx, a, version, artifactPath, skipDefaultPgp, pgpBytes...); err != nil {
+		var invalidSignatureErr *download.InvalidSignatureError
+		if errors.As(err, &invalidSignatureErr) {
+			if err := os.Remove(artifactPath); err != nil {
+				v.log.Warnf("failed clean up after signature verification: failed to remove %q: %v", artifactPath, err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (v *Verifier) verifyAsc(ctx context.Context, a artifact.Artifact, version agtversion.ParsedSemVer, artifactPath string, skipDefaultKey bool, pgpSources ...string) error {
+	filename, err := artifact.GetArtifactName(a, version, v.config.OS(), v.config.Arch())
+	if err != nil {
+		return errors.New(err, "retrieving package name")
+	}
+
+	source, err := parseSourceURI(v.config.SourceURI)
+	if err != nil {
+		return errors.New(err, "invalid object storage source URI", errors.TypeConfig, errors.M(errors.MetaKeyURI, v.config.SourceURI))
+	}
+	b, err := v.newBucket(ctx, source)
+	if err != nil {
+		return errors.New(err, "failed to access the object storage", errors.TypeNetwork, errors.M(errors.MetaKeyURI, source.String()))
+	}
+	defer b.Close()
+
+	key := source.key(a.Artifact, filename+ascSuffix)
+	ascURI := source.objectURI(key)
+	body, _, err := b.Open(ctx, key)
+	if err != nil {
+		return errors.New(err, fmt.Sprintf("fetching asc file from %s", ascURI), errors.TypeNetwork, errors.M(errors.MetaKeyURI, ascURI))
+	}
+	defer body.Close()
+	ascBytes, err := io.ReadAll(io.LimitReader(body, ascMaxSize))
+	if err != nil {
+		return errors.New(err, fmt.Sprintf("reading asc file from %s", ascURI), errors.TypeNetwork, errors.M(errors.MetaKeyURI, ascURI))
+	}
+
+	pgpBytes, err := download.FetchPGPKeys(v.log, v.client, v.defaultKey, skipDefaultKey, pgpSources)
+	if err != nil {
+		return fmt.Errorf("could not fetch pgp keys: %w", err)
+	}
+
+	return download.VerifyPGPSignatureWithKeys(v.log, artifactPath, ascBytes, pgpBytes)
+}
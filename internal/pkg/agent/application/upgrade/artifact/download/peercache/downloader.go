@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peercache
+
+import (
+	"context"
+	goerrors "errors"
+	"sync"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// ErrNoSources is returned when no peer cache is configured.
+var ErrNoSources = goerrors.New("no peer cache configured")
+
+// Downloader downloads artifacts from the peer caches, in order.
+type Downloader struct {
+	log            *logger.Logger
+	upgradeDetails *details.Details
+
+	mx sync.Mutex
+	dd []*http.Downloader
+}
+
+// NewDownloader creates a downloader of the peer caches of the configuration.
+func NewDownloader(log *logger.Logger, config *artifact.Config, upgradeDetails *details.Details) (*Downloader, error) {
+	d := &Downloader{
+		log:            log,
+		upgradeDetails: upgradeDetails,
+	}
+	if err := d.Reload(config); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Reload reloads the peer caches from the configuration.
+func (d *Downloader) Reload(c *artifact.Config) error {
+	dd := make([]*http.Downloader, 0, len(c.PeerCache.Sources))
+	for _, source := range c.PeerCache.Sources {
+		cfg, client, err := peerConfig(c, source)
+		if err != nil {
+			return errors.New(err, "peercache.downloader: failed to generate client out of config", errors.M(errors.MetaKeyURI, source))
+		}
+		dd = append(dd, http.NewDownloaderWithClient(d.log, cfg, *client, d.upgradeDetails))
+	}
+
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	d.dd = dd
+	return nil
+}
+
+// Download fetches the package from the first peer cache serving it.
+// Returns absolute path to downloaded package and an error.
+func (d *Downloader) Download(ctx context.Context, a artifact.Artifact, version *agtversion.ParsedSemVer) (string, error) {
+	d.mx.Lock()
+	dd := d.dd
+	d.mx.Unlock()
+	if len(dd) == 0 {
+		return "", ErrNoSources
+	}
+
+	var errs []error
+	for _, downloader := range dd {
+		path, err := downloader.Download(ctx, a, version)
+		if err == nil {
+			return path, nil
+		}
+		d.log.Debugf("failed to download %s from peer cache: %v", a.Artifact, err)
+		errs = append(errs, err)
+	}
+	return "", goerrors.Join(errs...)
+}
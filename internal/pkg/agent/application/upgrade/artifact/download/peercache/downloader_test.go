@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peercache
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+	"github.com/elastic/elastic-agent/testing/pgptest"
+)
+
+var agentSpec = artifact.Artifact{
+	Name:     "Elastic Agent",
+	Cmd:      "elastic-agent",
+	Artifact: "beats/elastic-agent",
+}
+
+func TestDownloaderAndVerifier(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "verifier being tested uses an OpenPGP key which results in a SHA-1 violation.")
+
+	log, _ := loggertest.New("peercache")
+	version := agtversion.NewParsedSemVer(9, 1, 0, "", "")
+
+	// the serving agent already downloaded the package
+	serveCfg := newTestConfig(t)
+	serveCfg.OperatingSystem = "linux"
+	serveCfg.Architecture = "64"
+	filename, err := artifact.GetArtifactName(agentSpec, *version, serveCfg.OS(), serveCfg.Arch())
+	require.NoError(t, err)
+	pub, sig := pgptest.Sign(t, bytes.NewReader(testContent))
+	servePath := filepath.Join(serveCfg.TargetDirectory, filename)
+	require.NoError(t, os.WriteFile(servePath, testContent, 0o600))
+	require.NoError(t, os.WriteFile(servePath+hashSuffix, testHash(filename, testContent), 0o600))
+	require.NoError(t, os.WriteFile(servePath+ascSuffix, sig, 0o600))
+	srv := newTestServer(t, serveCfg)
+
+	cfg := artifact.DefaultConfig()
+	cfg.OperatingSystem = "linux"
+	cfg.Architecture = "64"
+	cfg.TargetDirectory = t.TempDir()
+	cfg.PeerCache.Token = testToken
+	cfg.TLS = serveCfg.TLS
+	// the first peer does not serve the artifacts
+	cfg.PeerCache.Sources = []string{"https://127.0.0.1:1", srv.URL}
+
+	upgradeDetails := details.NewDetails(version.String(), details.StateDownloading, "")
+	d, err := NewDownloader(log, cfg, upgradeDetails)
+	require.NoError(t, err)
+	path, err := d.Download(context.Background(), agentSpec, version)
+	require.NoError(t, err)
+	downloaded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, testContent, downloaded)
+
+	v, err := NewVerifier(log, cfg, pub)
+	require.NoError(t, err)
+	require.NoError(t, v.Verify(context.Background(), agentSpec, *version, false))
+}
+
+func TestDownloaderRequiresHTTPS(t *testing.T) {
+	log, _ := loggertest.New("peercache")
+	cfg := artifact.DefaultConfig()
+	cfg.TargetDirectory = t.TempDir()
+	cfg.PeerCache.Token = testToken
+	cfg.PeerCache.Sources = []string{"http://127.0.0.1:6796"}
+
+	_, err := NewDownloader(log, cfg, nil)
+	assert.Error(t, err, "the token must not be sent in clear text")
+}
+
+func TestNoSources(t *testing.T) {
+	log, _ := loggertest.New("peercache")
+	version := agtversion.NewParsedSemVer(9, 1, 0, "", "")
+	cfg := artifact.DefaultConfig()
+	cfg.TargetDirectory = t.TempDir()
+
+	d, err := NewDownloader(log, cfg, nil)
+	require.NoError(t, err)
+	_, err = d.Download(context.Background(), agentSpec, version)
+	assert.ErrorIs(t, err, ErrNoSources)
+}
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package peercache shares the artifacts downloaded by an agent with the other agents of its site.
+//
+// An agent serving the cache exposes the verified artifacts of its target directory over a TLS endpoint
+// authenticated by a shared token, optionally downloading the missing ones from its source URI. The other agents
+// download from the peer caches before the source URI. Artifacts downloaded from a peer are verified the same way
+// as any other, against the PGP key of the agent, a peer cache cannot serve a tampered artifact.
+package peercache
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+)
+
+// peerConfig returns the configuration and the client downloading from the peer cache at the source.
+func peerConfig(c *artifact.Config, source string) (*artifact.Config, *http.Client, error) {
+	// the token is sent with every request, it is never sent in clear text
+	if u, err := url.Parse(source); err != nil || u.Scheme != "https" {
+		return nil, nil, fmt.Errorf("peer cache %q must be reached over https, the token is sent with every request", source)
+	}
+	cfg := *c
+	cfg.SourceURI = source
+	// peers are on the local network, never reached through a proxy
+	cfg.ProxyAutoDetect = false
+	cfg.ProxyPACURL = ""
+	cfg.Proxy.Disable = true
+
+	client, err := cfg.Client(
+		httpcommon.WithAPMHTTPInstrumentation(),
+		httpcommon.WithKeepaliveSettings{Disable: false, IdleConnTimeout: 30 * time.Second},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	headers := make(map[string]string, len(download.Headers)+1)
+	for k, v := range download.Headers {
+		headers[k] = v
+	}
+	headers[authorizationHeader] = bearerPrefix + c.PeerCache.Token
+	client.Transport = download.WithHeaders(client.Transport, headers)
+	return &cfg, client, nil
+}
@@ -0,0 +1,372 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peercache
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	packagePermissions = 0o660
+
+	hashSuffix   = ".sha512"
+	ascSuffix    = ".asc"
+	bundleSuffix = ".sigstore.json"
+)
+
+// pullThroughArtifacts are the artifacts pulled through from the source URI for the peers, by their path on the
+// source URI, with the commands naming their packages.
+var pullThroughArtifacts = map[string][]string{
+	"beats/elastic-agent": {"elastic-agent", "elastic-agent-fips"},
+}
+
+// errNotVerified is returned when a package of the target directory does not match its hash, e.g. while it is
+// being downloaded.
+var errNotVerified = goerrors.New("package is not verified")
+
+// Server serves the artifacts of the target directory of the agent to its peers. Packages are only served once
+// they match their hash.
+type Server struct {
+	log    *logger.Logger
+	config *artifact.Config
+	client *http.Client
+	tls    *tls.Config
+
+	// pulls deduplicates the downloads from the source URI of the artifacts requested by many peers at once.
+	pulls singleflight.Group
+
+	mx       sync.Mutex
+	verified map[string]fileStamp
+
+	srv *http.Server
+	ln  net.Listener
+}
+
+// fileStamp identifies the content of a verified package, the package is verified again once it changed.
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// NewServer creates the server of the artifacts of the configuration.
+func NewServer(log *logger.Logger, config *artifact.Config) (*Server, error) {
+	if config.PeerCache.Token == "" {
+		return nil, errors.New("serving the peer cache requires a token", errors.TypeConfig)
+	}
+	ssl := config.PeerCache.Serve.SSL
+	if ssl == nil || !ssl.IsEnabled() {
+		return nil, errors.New("serving the peer cache requires TLS, the peers send the token with every request", errors.TypeConfig)
+	}
+
+	client, err := config.Client(httpcommon.WithAPMHTTPInstrumentation())
+	if err != nil {
+		return nil, errors.New(err, "peercache.server: failed to generate client out of config")
+	}
+	client.Transport = download.WithHeaders(client.Transport, download.Headers)
+
+	s := &Server{
+		log:      log,
+		config:   config,
+		client:   client,
+		verified: make(map[string]fileStamp),
+	}
+	tlsCfg, err := tlscommon.LoadTLSServerConfig(ssl, log)
+	if err != nil {
+		return nil, errors.New(err, "peercache.server: failed to load TLS configuration", errors.TypeConfig)
+	}
+	s.tls = tlsCfg.BuildServerConfig("")
+	return s, nil
+}
+
+// Start starts serving the artifacts.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.config.PeerCache.Serve.Address)
+	if err != nil {
+		return errors.New(err, "peercache.server: failed to listen", errors.TypeNetwork, errors.M(errors.MetaKeyURI, s.config.PeerCache.Serve.Address))
+	}
+	ln = tls.NewListener(ln, s.tls)
+	s.ln = ln
+	s.srv = &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !goerrors.Is(err, http.ErrServerClosed) {
+			s.log.Errorf("peer cache server failed: %v", err)
+		}
+	}()
+	s.log.Infof("serving artifacts to peers on %s", ln.Addr())
+	return nil
+}
+
+// Addr returns the address the artifacts are served on, once started.
+func (s *Server) Addr() net.Addr {
+	if s.ln == nil {
+		return nil
+	}
+	return s.ln.Addr()
+}
+
+// Stop stops serving the artifacts.
+func (s *Server) Stop() error {
+	if s.srv == nil {
+		return nil
+	}
+	return s.srv.Close()
+}
+
+// ServeHTTP serves the artifact of the request path, laid out the same as the source URI:
+// /<artifact>/<filename>.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorized(r) {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	artifactName, filename, ok := splitRequestPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	fullPath := filepath.Join(s.config.TargetDirectory, filename)
+	if err := s.ensure(r.Context(), artifactName, filename, fullPath); err != nil {
+		if goerrors.Is(err, os.ErrNotExist) || goerrors.Is(err, errNotVerified) {
+			http.NotFound(w, r)
+			return
+		}
+		s.log.Warnf("failed to serve %s to peer %s: %v", r.URL.Path, r.RemoteAddr, err)
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	f, err := os.Open(fullPath)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, filename, stat.ModTime(), f)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get(authorizationHeader), bearerPrefix)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.PeerCache.Token)) == 1
+}
+
+// splitRequestPath splits the request path into the artifact name and the filename.
+func splitRequestPath(p string) (string, string, bool) {
+	dir, filename := path.Split(path.Clean("/" + p))
+	if filename == "" || filename == "." || filename == ".." || strings.ContainsAny(filename, `\:`) {
+		return "", "", false
+	}
+	return strings.Trim(dir, "/"), filename, true
+}
+
+// ensure ensures the file is in the target directory, pulling it from the source URI when configured, and
+// that packages match their hash.
+func (s *Server) ensure(ctx context.Context, artifactName, filename, fullPath string) error {
+	if isSideFile(filename) {
+		if _, err := os.Stat(fullPath); err == nil || !s.pullThrough(artifactName, filename) {
+			return err
+		}
+		return s.pull(ctx, artifactName, filename)
+	}
+
+	pulled := false
+	stat, err := os.Stat(fullPath)
+	if err != nil {
+		if !goerrors.Is(err, os.ErrNotExist) || !s.pullThrough(artifactName, filename) {
+			return err
+		}
+		if err := s.pull(ctx, artifactName, filename+hashSuffix); err != nil {
+			return err
+		}
+		if err := s.pull(ctx, artifactName, filename); err != nil {
+			return err
+		}
+		if stat, err = os.Stat(fullPath); err != nil {
+			return err
+		}
+		pulled = true
+	}
+
+	stamp := fileStamp{size: stat.Size(), modTime: stat.ModTime()}
+	s.mx.Lock()
+	verified := s.verified[fullPath] == stamp
+	s.mx.Unlock()
+	if verified {
+		return nil
+	}
+	if err := download.VerifySHA512Hash(fullPath); err != nil {
+		if pulled {
+			// a package of the target directory may still be downloading, a pulled one is complete and invalid
+			s.log.Warnf("removing %s pulled for peers: %v", fullPath, err)
+			s.remove(fullPath)
+			return errNotVerified
+		}
+		s.log.Debugf("not serving %s to peers: %v", fullPath, err)
+		return errNotVerified
+	}
+	s.mx.Lock()
+	s.verified[fullPath] = stamp
+	s.mx.Unlock()
+	return nil
+}
+
+// remove removes the package and its hash from the target directory.
+func (s *Server) remove(fullPath string) {
+	for _, p := range []string{fullPath, fullPath + hashSuffix} {
+		if err := os.Remove(p); err != nil && !goerrors.Is(err, os.ErrNotExist) {
+			s.log.Warnf("failed to remove %s: %v", p, err)
+		}
+	}
+}
+
+// isSideFile returns true when the file is the hash or the signature of a package.
+func isSideFile(filename string) bool {
+	return strings.HasSuffix(filename, hashSuffix) || strings.HasSuffix(filename, ascSuffix) || strings.HasSuffix(filename, bundleSuffix)
+}
+
+// pullThrough returns true when the file is pulled through from the source URI, only the packages of the known
+// artifacts, their hashes and their signatures are.
+func (s *Server) pullThrough(artifactName, filename string) bool {
+	if !s.config.PeerCache.Serve.PullThrough {
+		return false
+	}
+	cmds, ok := pullThroughArtifacts[artifactName]
+	if !ok {
+		return false
+	}
+	name := filename
+	for _, suffix := range []string{hashSuffix, ascSuffix, bundleSuffix} {
+		if n, ok := strings.CutSuffix(name, suffix); ok {
+			name = n
+			break
+		}
+	}
+	for _, cmd := range cmds {
+		rest, ok := strings.CutPrefix(name, cmd+"-")
+		if !ok {
+			continue
+		}
+		for _, suffix := range artifact.PackageSuffixes() {
+			if version, ok := strings.CutSuffix(rest, "-"+suffix); ok {
+				if _, err := agtversion.ParseVersion(version); err == nil {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// pull downloads the file from the source URI into the target directory, once for all the peers requesting it
+// at the same time.
+func (s *Server) pull(ctx context.Context, artifactName, filename string) error {
+	_, err, _ := s.pulls.Do(filename, func() (interface{}, error) {
+		fullPath := filepath.Join(s.config.TargetDirectory, filename)
+		if _, err := os.Stat(fullPath); err == nil {
+			return nil, nil
+		}
+		// the download outlives the request of a single peer, the other peers wait for it
+		return nil, s.fetch(context.WithoutCancel(ctx), artifactName, filename, fullPath)
+	})
+	return err
+}
+
+func (s *Server) fetch(ctx context.Context, artifactName, filename, fullPath string) error {
+	upstream := s.config.SourceURI
+	if !strings.HasPrefix(upstream, "http") {
+		return fmt.Errorf("pulling through from %q is not supported", upstream)
+	}
+	uri, err := url.Parse(upstream)
+	if err != nil {
+		return errors.New(err, "invalid upstream URI", errors.TypeNetwork, errors.M(errors.MetaKeyURI, upstream))
+	}
+	uri.Path = path.Join(uri.Path, artifactName, filename)
+	sourceURI := uri.String()
+
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURI, nil)
+	if err != nil {
+		return errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.New(err, "fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return os.ErrNotExist
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", sourceURI, resp.StatusCode), errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+
+	if err := os.MkdirAll(s.config.TargetDirectory, 0o750); err != nil {
+		return errors.New(err, "creating directory", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, s.config.TargetDirectory))
+	}
+	// download next to the file and rename it once complete, peers never see a partial file
+	tmp, err := os.CreateTemp(s.config.TargetDirectory, filename+".*.tmp")
+	if err != nil {
+		return errors.New(err, "creating package file failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.New(err, "copying fetched package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, sourceURI))
+	}
+	if err := os.Chmod(tmp.Name(), packagePermissions); err != nil {
+		return errors.New(err, "setting package permissions failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		return errors.New(err, "renaming fetched package failed", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, fullPath))
+	}
+	s.log.Infof("pulled %s for peers from %s", filename, sourceURI)
+	return nil
+}
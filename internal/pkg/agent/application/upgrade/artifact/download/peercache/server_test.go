@@ -0,0 +1,220 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peercache
+
+import (
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/testing/certutil"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+const (
+	testToken    = "secret"
+	testFilename = "elastic-agent-9.1.0-linux-x86_64.tar.gz"
+)
+
+var testContent = []byte("sample content")
+
+func testHash(filename string, content []byte) []byte {
+	return []byte(fmt.Sprintf("%x %s", sha512.Sum512(content), filename))
+}
+
+// testCA is the certificate authority of the certificates of the test servers, in PEM format.
+var testCA, testCert certutil.Pair
+
+func init() {
+	caKey, caCert, caPair, err := certutil.NewRootCA()
+	if err != nil {
+		panic(err)
+	}
+	_, certPair, err := certutil.GenerateChildCert("localhost", []net.IP{net.ParseIP("127.0.0.1")}, caKey, caCert)
+	if err != nil {
+		panic(err)
+	}
+	testCA, testCert = caPair, certPair
+}
+
+func newTestConfig(t *testing.T) *artifact.Config {
+	cfg := artifact.DefaultConfig()
+	cfg.TargetDirectory = t.TempDir()
+	cfg.PeerCache.Token = testToken
+	cfg.PeerCache.Serve.Enabled = true
+	cfg.PeerCache.Serve.Address = "127.0.0.1:0"
+	cfg.PeerCache.Serve.SSL = &tlscommon.ServerConfig{
+		Certificate: tlscommon.CertificateConfig{Certificate: string(testCert.Cert), Key: string(testCert.Key)},
+	}
+	cfg.TLS = &tlscommon.Config{CAs: []string{string(testCA.Cert)}}
+	return cfg
+}
+
+func newTestServer(t *testing.T, cfg *artifact.Config) *httptest.Server {
+	log, _ := loggertest.New("peercache")
+	s, err := NewServer(log, cfg)
+	require.NoError(t, err)
+	srv := httptest.NewUnstartedServer(s)
+	srv.TLS = s.tls
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, uri, token string) (int, []byte) {
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set(authorizationHeader, bearerPrefix+token)
+	}
+	pool := x509.NewCertPool()
+	require.True(t, pool.AppendCertsFromPEM(testCA.Cert))
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, body
+}
+
+func TestNewServerRequiresToken(t *testing.T) {
+	log, _ := loggertest.New("peercache")
+	cfg := newTestConfig(t)
+	cfg.PeerCache.Token = ""
+	_, err := NewServer(log, cfg)
+	assert.Error(t, err)
+}
+
+func TestNewServerRequiresTLS(t *testing.T) {
+	log, _ := loggertest.New("peercache")
+	cfg := newTestConfig(t)
+	cfg.PeerCache.Serve.SSL = nil
+	_, err := NewServer(log, cfg)
+	assert.Error(t, err)
+}
+
+func TestServer(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.TargetDirectory = filepath.Join(t.TempDir(), "downloads")
+	require.NoError(t, os.MkdirAll(cfg.TargetDirectory, 0o750))
+	fullPath := filepath.Join(cfg.TargetDirectory, testFilename)
+	require.NoError(t, os.WriteFile(fullPath, testContent, 0o600))
+	srv := newTestServer(t, cfg)
+	uri := srv.URL + "/beats/elastic-agent/" + testFilename
+
+	status, _ := get(t, uri, "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+	status, _ = get(t, uri, "invalid")
+	assert.Equal(t, http.StatusUnauthorized, status)
+
+	// not served without a matching hash
+	status, _ = get(t, uri, testToken)
+	assert.Equal(t, http.StatusNotFound, status)
+	require.NoError(t, os.WriteFile(fullPath+hashSuffix, testHash(testFilename, []byte("other")), 0o600))
+	status, _ = get(t, uri, testToken)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	require.NoError(t, os.WriteFile(fullPath+hashSuffix, testHash(testFilename, testContent), 0o600))
+	status, body := get(t, uri, testToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testContent, body)
+
+	status, body = get(t, uri+hashSuffix, testToken)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, testHash(testFilename, testContent), body)
+
+	// only the target directory is served
+	outside := filepath.Join(filepath.Dir(cfg.TargetDirectory), "outside")
+	require.NoError(t, os.WriteFile(outside, testContent, 0o600))
+	require.NoError(t, os.WriteFile(outside+hashSuffix, testHash("outside", testContent), 0o600))
+	status, _ = get(t, srv.URL+"/beats/elastic-agent/..%2F..%2F..%2Foutside", testToken)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestServerPullThrough(t *testing.T) {
+	var requests atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/downloads/beats/elastic-agent/" + testFilename:
+			_, _ = w.Write(testContent)
+		case "/downloads/beats/elastic-agent/" + testFilename + hashSuffix:
+			_, _ = w.Write(testHash(testFilename, testContent))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig(t)
+	cfg.SourceURI = upstream.URL + "/downloads/"
+	cfg.PeerCache.Serve.PullThrough = true
+	srv := newTestServer(t, cfg)
+	uri := srv.URL + "/beats/elastic-agent/" + testFilename
+
+	for i := 0; i < 3; i++ {
+		status, body := get(t, uri, testToken)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, testContent, body)
+	}
+	assert.Equal(t, int32(2), requests.Load(), "the package and its hash must only be pulled once")
+	assert.FileExists(t, filepath.Join(cfg.TargetDirectory, testFilename))
+
+	status, _ := get(t, uri+ascSuffix, testToken)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// only the packages of the known artifacts are pulled through
+	requests.Store(0)
+	for _, p := range []string{
+		"/beats/elastic-agent/elastic-agent.yml",
+		"/beats/elastic-agent/elastic-agent-9.1.0-linux-x86_64.tar.gz.sha512.exe",
+		"/beats/elastic-agent/elastic-agent-latest-linux-x86_64.tar.gz",
+		"/beats/filebeat/filebeat-9.1.0-linux-x86_64.tar.gz",
+		"/other/elastic-agent-9.2.0-linux-x86_64.tar.gz",
+	} {
+		status, _ := get(t, srv.URL+p, testToken)
+		assert.Equal(t, http.StatusNotFound, status, p)
+	}
+	assert.Zero(t, requests.Load(), "the unknown artifacts must not be pulled")
+}
+
+func TestServerPullThroughRemovesUnverified(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/beats/elastic-agent/" + testFilename:
+			_, _ = w.Write([]byte("tampered content"))
+		case "/beats/elastic-agent/" + testFilename + hashSuffix:
+			_, _ = w.Write(testHash(testFilename, testContent))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	cfg := newTestConfig(t)
+	cfg.SourceURI = upstream.URL
+	cfg.PeerCache.Serve.PullThrough = true
+	srv := newTestServer(t, cfg)
+
+	status, _ := get(t, srv.URL+"/beats/elastic-agent/"+testFilename, testToken)
+	assert.Equal(t, http.StatusNotFound, status)
+	assert.NoFileExists(t, filepath.Join(cfg.TargetDirectory, testFilename), "the unverified package must be removed")
+	assert.NoFileExists(t, filepath.Join(cfg.TargetDirectory, testFilename+hashSuffix), "the hash of the unverified package must be removed")
+}
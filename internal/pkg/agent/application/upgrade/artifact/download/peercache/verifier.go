@@ -0,0 +1,83 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package peercache
+
+import (
+	"context"
+	goerrors "errors"
+	"sync"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// Verifier verifies artifacts with the signatures served by the peer caches, in order. The signatures are
+// checked against the PGP key of the agent, the same as the signatures of the source URI.
+type Verifier struct {
+	log *logger.Logger
+	pgp []byte
+
+	mx sync.Mutex
+	vv []*http.Verifier
+}
+
+func (v *Verifier) Name() string {
+	return "peercache.verifier"
+}
+
+// NewVerifier creates a verifier of the peer caches of the configuration.
+func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte) (*Verifier, error) {
+	if len(pgp) == 0 {
+		return nil, errors.New("expecting PGP key received none", errors.TypeSecurity)
+	}
+	v := &Verifier{
+		log: log,
+		pgp: pgp,
+	}
+	if err := v.Reload(config); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Reload reloads the peer caches from the configuration.
+func (v *Verifier) Reload(c *artifact.Config) error {
+	vv := make([]*http.Verifier, 0, len(c.PeerCache.Sources))
+	for _, source := range c.PeerCache.Sources {
+		cfg, client, err := peerConfig(c, source)
+		if err != nil {
+			return errors.New(err, "peercache.verifier: failed to generate client out of config", errors.M(errors.MetaKeyURI, source))
+		}
+		vv = append(vv, http.NewVerifierWithClient(v.log, cfg, *client, v.pgp))
+	}
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.vv = vv
+	return nil
+}
+
+// Verify checks the downloaded package with the signature of the first peer cache serving it.
+func (v *Verifier) Verify(ctx context.Context, a artifact.Artifact, version agtversion.ParsedSemVer, skipDefaultPgp bool, pgpBytes ...string) error {
+	v.mx.Lock()
+	vv := v.vv
+	v.mx.Unlock()
+	if len(vv) == 0 {
+		return ErrNoSources
+	}
+
+	var errs []error
+	for _, verifier := range vv {
+		err := verifier.Verify(ctx, a, version, skipDefaultPgp, pgpBytes...)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+	}
+	return goerrors.Join(errs...)
+}
@@ -0,0 +1,275 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// pacTimeout is how long the evaluation of a PAC file, including its top level code, may take before it
+// is interrupted.
+const pacTimeout = 5 * time.Second
+
+var weekdays = []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}
+
+// pacScript is a compiled proxy auto-config file.
+type pacScript struct {
+	program *goja.Program
+	timeout time.Duration
+	now     func() time.Time
+	lookup  func(host string) ([]string, error)
+}
+
+func newPACScript(name string, src string) (*pacScript, error) {
+	program, err := goja.Compile(name, src, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile PAC file %s: %w", name, err)
+	}
+	return &pacScript{
+		program: program,
+		timeout: pacTimeout,
+		now:     time.Now,
+		lookup:  net.LookupHost,
+	}, nil
+}
+
+// FindProxyForURL evaluates the PAC file for the URL and returns its result, e.g. "PROXY proxy:8080; DIRECT".
+//
+// Every evaluation runs in its own runtime so an interrupted evaluation cannot affect the next one.
+func (p *pacScript) FindProxyForURL(u *url.URL) (string, error) {
+	vm := goja.New()
+	p.register(vm)
+
+	// the timeout covers the top level code of the PAC file as well as the call to FindProxyForURL
+	timer := time.AfterFunc(p.timeout, func() {
+		vm.Interrupt("PAC evaluation timed out")
+	})
+	defer timer.Stop()
+
+	if _, err := vm.RunProgram(p.program); err != nil {
+		return "", fmt.Errorf("failed to run PAC file: %w", err)
+	}
+	fn, ok := goja.AssertFunction(vm.Get("FindProxyForURL"))
+	if !ok {
+		return "", fmt.Errorf("PAC file does not define FindProxyForURL")
+	}
+
+	result, err := fn(goja.Undefined(), vm.ToValue(u.String()), vm.ToValue(u.Hostname()))
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate FindProxyForURL: %w", err)
+	}
+	return result.String(), nil
+}
+
+// register defines the functions available to PAC files.
+func (p *pacScript) register(vm *goja.Runtime) {
+	vm.Set("isPlainHostName", func(host string) bool {
+		return !strings.Contains(host, ".")
+	})
+	vm.Set("dnsDomainIs", func(host, domain string) bool {
+		return strings.HasSuffix(strings.ToLower(host), strings.ToLower(domain))
+	})
+	vm.Set("localHostOrDomainIs", func(host, hostdom string) bool {
+		host, hostdom = strings.ToLower(host), strings.ToLower(hostdom)
+		return host == hostdom || (!strings.Contains(host, ".") && strings.HasPrefix(hostdom, host+"."))
+	})
+	vm.Set("isResolvable", func(host string) bool {
+		return p.resolve(host) != ""
+	})
+	vm.Set("dnsResolve", func(call goja.FunctionCall) goja.Value {
+		ip := p.resolve(call.Argument(0).String())
+		if ip == "" {
+			return goja.Null()
+		}
+		return vm.ToValue(ip)
+	})
+	vm.Set("isInNet", func(host, pattern, mask string) bool {
+		ip := net.ParseIP(host)
+		if ip == nil {
+			ip = net.ParseIP(p.resolve(host))
+		}
+		patternIP, maskIP := net.ParseIP(pattern).To4(), net.ParseIP(mask).To4()
+		if ip == nil || ip.To4() == nil || patternIP == nil || maskIP == nil {
+			return false
+		}
+		m := net.IPMask(maskIP)
+		return ip.To4().Mask(m).Equal(patternIP.Mask(m))
+	})
+	vm.Set("myIpAddress", func() string {
+		return myIPAddress()
+	})
+	vm.Set("dnsDomainLevels", func(host string) int {
+		return strings.Count(host, ".")
+	})
+	vm.Set("shExpMatch", func(str, shexp string) bool {
+		return shExpMatch(str, shexp)
+	})
+	vm.Set("weekdayRange", func(call goja.FunctionCall) goja.Value {
+		return vm.ToValue(p.weekdayRange(call.Arguments))
+	})
+	vm.Set("timeRange", func(call goja.FunctionCall) goja.Value {
+		return vm.ToValue(p.timeRange(call.Arguments))
+	})
+}
+
+// resolve returns the first IPv4 address of the host, empty when the host does not resolve.
+func (p *pacScript) resolve(host string) string {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	addrs, err := p.lookup(host)
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ip := net.ParseIP(addr); ip != nil && ip.To4() != nil {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+// clock returns the current time, in UTC when the last argument is "GMT", and the arguments without it.
+func (p *pacScript) clock(args []goja.Value) (time.Time, []goja.Value) {
+	now := p.now()
+	if len(args) > 0 && strings.EqualFold(args[len(args)-1].String(), "GMT") {
+		return now.UTC(), args[:len(args)-1]
+	}
+	return now, args
+}
+
+// weekdayRange implements weekdayRange(wd1 [, wd2] [, "GMT"]).
+func (p *pacScript) weekdayRange(args []goja.Value) bool {
+	now, args := p.clock(args)
+	if len(args) == 0 {
+		return false
+	}
+	day := func(v goja.Value) int {
+		for i, d := range weekdays {
+			if strings.EqualFold(v.String(), d) {
+				return i
+			}
+		}
+		return -1
+	}
+	start := day(args[0])
+	end := start
+	if len(args) > 1 {
+		end = day(args[1])
+	}
+	if start < 0 || end < 0 {
+		return false
+	}
+	return inRange(int(now.Weekday()), start, end)
+}
+
+// timeRange implements the hour forms of timeRange, timeRange(hour [, "GMT"]) and
+// timeRange(hour1, hour2 [, "GMT"]).
+func (p *pacScript) timeRange(args []goja.Value) bool {
+	now, args := p.clock(args)
+	hours := make([]int, 0, len(args))
+	for _, arg := range args {
+		h, err := strconv.Atoi(arg.String())
+		if err != nil {
+			return false
+		}
+		hours = append(hours, h)
+	}
+	switch len(hours) {
+	case 1:
+		return now.Hour() == hours[0]
+	case 2:
+		// the end hour is exclusive, e.g. timeRange(8, 17) is 8:00 to 16:59
+		if hours[0] == hours[1] {
+			return now.Hour() == hours[0]
+		}
+		return inRange(now.Hour(), hours[0], hours[1]-1)
+	default:
+		return false
+	}
+}
+
+// inRange returns true when v is between start and end inclusive, the range wraps when end is before start.
+func inRange(v, start, end int) bool {
+	if start <= end {
+		return v >= start && v <= end
+	}
+	return v >= start || v <= end
+}
+
+// shExpMatch matches the string against a shell expression, where * matches any sequence of
+// characters and ? matches a single character.
+func shExpMatch(str, shexp string) bool {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range shexp {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	matched, err := regexp.MatchString(b.String(), str)
+	return err == nil && matched
+}
+
+// myIPAddress returns the first non loopback IPv4 address of the host.
+func myIPAddress() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "127.0.0.1"
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return "127.0.0.1"
+}
+
+// parsePACResult returns the proxy of the first supported entry of the result of FindProxyForURL,
+// nil when the first supported entry is DIRECT.
+func parsePACResult(result string) (*url.URL, error) {
+	if strings.TrimSpace(result) == "" {
+		return nil, nil
+	}
+	for _, entry := range strings.Split(result, ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		var scheme string
+		switch strings.ToUpper(fields[0]) {
+		case "DIRECT":
+			return nil, nil
+		case "PROXY", "HTTP":
+			scheme = "http"
+		case "HTTPS":
+			scheme = "https"
+		case "SOCKS", "SOCKS5":
+			scheme = "socks5"
+		default:
+			// unsupported type, e.g. SOCKS4, try the next entry
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("PAC result %q is missing the proxy address", entry)
+		}
+		return url.Parse(scheme + "://" + fields[1])
+	}
+	return nil, fmt.Errorf("PAC result %q has no supported entry", result)
+}
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxy
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPAC = `
+function FindProxyForURL(url, host) {
+	if (isPlainHostName(host) || dnsDomainIs(host, ".internal.example.com")) {
+		return "DIRECT";
+	}
+	if (isInNet(host, "10.0.0.0", "255.0.0.0")) {
+		return "DIRECT";
+	}
+	if (shExpMatch(url, "https://artifacts.elastic.co/*")) {
+		return "PROXY artifacts-proxy.example.com:3128; DIRECT";
+	}
+	if (dnsResolve(host) == "192.0.2.10" && weekdayRange("MON", "FRI") && timeRange(8, 18)) {
+		return "HTTPS office-proxy.example.com:443";
+	}
+	return "PROXY proxy.example.com:8080";
+}
+`
+
+func TestPACScript(t *testing.T) {
+	script, err := newPACScript("test.pac", testPAC)
+	require.NoError(t, err)
+	// a Wednesday at noon
+	script.now = func() time.Time { return time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC) }
+	script.lookup = func(host string) ([]string, error) {
+		if host == "office.example.com" {
+			return []string{"2001:db8::1", "192.0.2.10"}, nil
+		}
+		return nil, errors.New("not found")
+	}
+
+	testcases := map[string]string{
+		"http://intranet/file":                        "DIRECT",
+		"https://repo.internal.example.com/file":      "DIRECT",
+		"http://10.1.2.3/file":                        "DIRECT",
+		"https://artifacts.elastic.co/downloads/file": "PROXY artifacts-proxy.example.com:3128; DIRECT",
+		"https://office.example.com/file":             "HTTPS office-proxy.example.com:443",
+		"https://other.example.com/file":              "PROXY proxy.example.com:8080",
+	}
+	for rawURL, expected := range testcases {
+		t.Run(rawURL, func(t *testing.T) {
+			u, err := url.Parse(rawURL)
+			require.NoError(t, err)
+			result, err := script.FindProxyForURL(u)
+			require.NoError(t, err)
+			assert.Equal(t, expected, result)
+		})
+	}
+}
+
+func TestPACScriptErrors(t *testing.T) {
+	_, err := newPACScript("invalid.pac", "function FindProxyForURL(url, host) {")
+	assert.Error(t, err)
+
+	u, _ := url.Parse("https://artifacts.elastic.co")
+
+	script, err := newPACScript("missing.pac", "var proxy = 'DIRECT';")
+	require.NoError(t, err)
+	_, err = script.FindProxyForURL(u)
+	assert.ErrorContains(t, err, "does not define FindProxyForURL")
+
+	script, err = newPACScript("throws.pac", "function FindProxyForURL(url, host) { throw 'failed'; }")
+	require.NoError(t, err)
+	_, err = script.FindProxyForURL(u)
+	assert.Error(t, err)
+}
+
+func TestPACScriptTimeout(t *testing.T) {
+	u, _ := url.Parse("https://artifacts.elastic.co")
+
+	testcases := map[string]string{
+		"top level": "while (true) {}\nfunction FindProxyForURL(url, host) { return 'DIRECT'; }",
+		"function":  "function FindProxyForURL(url, host) { while (true) {} }",
+	}
+	for name, src := range testcases {
+		t.Run(name, func(t *testing.T) {
+			script, err := newPACScript("loop.pac", src)
+			require.NoError(t, err)
+			script.timeout = 100 * time.Millisecond
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := script.FindProxyForURL(u)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				assert.ErrorContains(t, err, "PAC evaluation timed out")
+			case <-time.After(10 * time.Second):
+				t.Fatal("PAC evaluation was not interrupted")
+			}
+		})
+	}
+}
+
+func TestParsePACResult(t *testing.T) {
+	testcases := map[string]struct {
+		result   string
+		expected string
+		err      bool
+	}{
+		"empty":             {result: "", expected: ""},
+		"direct":            {result: "DIRECT", expected: ""},
+		"proxy":             {result: "PROXY proxy:8080", expected: "http://proxy:8080"},
+		"https":             {result: "HTTPS proxy:443; DIRECT", expected: "https://proxy:443"},
+		"socks":             {result: "SOCKS socks:1080", expected: "socks5://socks:1080"},
+		"unsupported first": {result: "SOCKS4 socks:1080; PROXY proxy:8080", expected: "http://proxy:8080"},
+		"missing address":   {result: "PROXY", err: true},
+		"unsupported":       {result: "SOCKS4 socks:1080", err: true},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			proxy, err := parsePACResult(tc.result)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.expected == "" {
+				assert.Nil(t, proxy)
+				return
+			}
+			assert.Equal(t, tc.expected, proxy.String())
+		})
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package proxy resolves the proxy of the artifact downloads from the proxy settings of the
+// operating system and proxy auto-config (PAC) files.
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// refreshInterval is how long detected system settings and fetched PAC files are used before they
+	// are detected and fetched again.
+	refreshInterval = 5 * time.Minute
+
+	// pacFetchTimeout is the timeout of fetching a PAC file.
+	pacFetchTimeout = 30 * time.Second
+
+	// pacMaxSize is the maximum size of a PAC file.
+	pacMaxSize = 1024 * 1024
+)
+
+// ProxyFunc returns the proxy of a request, the signature of http.Transport.Proxy.
+type ProxyFunc func(*http.Request) (*url.URL, error)
+
+// Resolver resolves the proxy of requests from a PAC file and the proxy settings of the operating system,
+// falling back to the explicit proxy settings when neither provides one.
+//
+// The proxy is resolved in this order:
+//   - the configured PAC file, or the PAC file of the system settings when auto-detection is enabled
+//   - the proxy of the system settings when auto-detection is enabled
+//   - the fallback, the explicit proxy settings
+type Resolver struct {
+	log        *logger.Logger
+	autoDetect bool
+	pacURL     string
+	fallback   ProxyFunc
+
+	// client fetches the PAC file, it never uses a proxy
+	client *http.Client
+	detect func() (*systemSettings, error)
+	now    func() time.Time
+
+	mx             sync.Mutex
+	system         *systemSettings
+	systemDetected time.Time
+	pac            *pacScript
+	pacSource      string
+	pacFetched     time.Time
+}
+
+// NewResolver creates a resolver. The PAC file at pacURL (http, https or file URL, or a path) takes
+// precedence over the PAC file of the system settings. Fallback may be nil, then requests are sent
+// directly when no proxy is resolved.
+func NewResolver(log *logger.Logger, autoDetect bool, pacURL string, fallback ProxyFunc) *Resolver {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	return &Resolver{
+		log:        log,
+		autoDetect: autoDetect,
+		pacURL:     pacURL,
+		fallback:   fallback,
+		client:     &http.Client{Transport: transport, Timeout: pacFetchTimeout},
+		detect:     detectSystemSettings,
+		now:        time.Now,
+	}
+}
+
+// Proxy returns the proxy of the request, nil when the request is sent directly.
+func (r *Resolver) Proxy(req *http.Request) (*url.URL, error) {
+	system := r.systemSettings()
+
+	pacURL := r.pacURL
+	if pacURL == "" && system != nil {
+		pacURL = system.pacURL
+	}
+	if pacURL != "" {
+		proxy, err := r.pacProxy(req.Context(), pacURL, req.URL)
+		if err == nil {
+			return proxy, nil
+		}
+		r.log.Warnf("failed to resolve proxy of %s with PAC file %s, falling back to the proxy settings: %v", req.URL.Redacted(), pacURL, err)
+	}
+
+	if system != nil {
+		if proxy := system.proxyFor(req.URL); proxy != nil {
+			return proxy, nil
+		}
+		if system.bypassed(req.URL.Hostname()) {
+			return nil, nil
+		}
+	}
+
+	if r.fallback == nil {
+		return nil, nil
+	}
+	return r.fallback(req)
+}
+
+// systemSettings returns the proxy settings of the system, nil when auto-detection is disabled or the
+// settings cannot be detected.
+func (r *Resolver) systemSettings() *systemSettings {
+	if !r.autoDetect {
+		return nil
+	}
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if !r.systemDetected.IsZero() && r.now().Sub(r.systemDetected) < refreshInterval {
+		return r.system
+	}
+	system, err := r.detect()
+	if err != nil {
+		r.log.Warnf("failed to detect the system proxy settings: %v", err)
+	}
+	r.system = system
+	r.systemDetected = r.now()
+	return r.system
+}
+
+func (r *Resolver) pacProxy(ctx context.Context, pacURL string, u *url.URL) (*url.URL, error) {
+	script, err := r.pacScript(ctx, pacURL)
+	if err != nil {
+		return nil, err
+	}
+	result, err := script.FindProxyForURL(u)
+	if err != nil {
+		return nil, err
+	}
+	return parsePACResult(result)
+}
+
+// pacScript returns the PAC file, fetched again once the refresh interval passed.
+func (r *Resolver) pacScript(ctx context.Context, pacURL string) (*pacScript, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+
+	if r.pac != nil && r.pacSource == pacURL && r.now().Sub(r.pacFetched) < refreshInterval {
+		return r.pac, nil
+	}
+	src, err := r.fetchPAC(ctx, pacURL)
+	if err != nil {
+		if r.pac != nil && r.pacSource == pacURL {
+			// keep using the last PAC file until it can be fetched again
+			r.log.Warnf("failed to fetch PAC file %s, using the previous one: %v", pacURL, err)
+			return r.pac, nil
+		}
+		return nil, err
+	}
+	script, err := newPACScript(pacURL, src)
+	if err != nil {
+		return nil, err
+	}
+	r.pac = script
+	r.pacSource = pacURL
+	r.pacFetched = r.now()
+	return script, nil
+}
+
+func (r *Resolver) fetchPAC(ctx context.Context, pacURL string) (string, error) {
+	if filepath.IsAbs(pacURL) {
+		// a path, Windows paths are not valid URLs
+		return readPAC(pacURL)
+	}
+	u, err := url.Parse(pacURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid PAC file URL %s: %w", pacURL, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return r.downloadPAC(ctx, pacURL)
+	case "file":
+		return readPAC(u.Path)
+	default:
+		return "", fmt.Errorf("unsupported PAC file URL %s", pacURL)
+	}
+}
+
+func (r *Resolver) downloadPAC(ctx context.Context, pacURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pacURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request for PAC file %s: %w", pacURL, err)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch PAC file %s: %w", pacURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch PAC file %s: unexpected status code %d", pacURL, resp.StatusCode)
+	}
+
+	src, err := io.ReadAll(io.LimitReader(resp.Body, pacMaxSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PAC file %s: %w", pacURL, err)
+	}
+	return string(src), nil
+}
+
+func readPAC(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open PAC file: %w", err)
+	}
+	defer f.Close()
+
+	src, err := io.ReadAll(io.LimitReader(f, pacMaxSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read PAC file %s: %w", path, err)
+	}
+	return string(src), nil
+}
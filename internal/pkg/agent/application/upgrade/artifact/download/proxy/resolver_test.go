@@ -0,0 +1,119 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestResolver(t *testing.T) {
+	log, _ := loggertest.New("proxy")
+	explicit := func(*http.Request) (*url.URL, error) {
+		return url.Parse("http://explicit:3128")
+	}
+	request := func(t *testing.T, rawURL string) *http.Request {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		require.NoError(t, err)
+		return req
+	}
+
+	pacRequests := 0
+	pacServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pacRequests++
+		fmt.Fprint(w, `function FindProxyForURL(url, host) { return host == "direct.example.com" ? "DIRECT" : "PROXY pac:8080"; }`)
+	}))
+	defer pacServer.Close()
+
+	t.Run("configured PAC file", func(t *testing.T) {
+		r := NewResolver(log, false, pacServer.URL, explicit)
+		proxy, err := r.Proxy(request(t, "https://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://pac:8080", proxy.String())
+
+		proxy, err = r.Proxy(request(t, "https://direct.example.com/file"))
+		require.NoError(t, err)
+		assert.Nil(t, proxy)
+		assert.Equal(t, 1, pacRequests, "PAC file should be fetched once")
+	})
+
+	t.Run("PAC file from path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "proxy.pac")
+		require.NoError(t, os.WriteFile(path, []byte(`function FindProxyForURL(url, host) { return "PROXY file:8080"; }`), 0o644))
+		r := NewResolver(log, false, path, explicit)
+		proxy, err := r.Proxy(request(t, "https://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://file:8080", proxy.String())
+	})
+
+	t.Run("system PAC file", func(t *testing.T) {
+		r := NewResolver(log, true, "", explicit)
+		r.detect = func() (*systemSettings, error) {
+			return &systemSettings{pacURL: pacServer.URL}, nil
+		}
+		proxy, err := r.Proxy(request(t, "https://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://pac:8080", proxy.String())
+	})
+
+	t.Run("unavailable PAC file falls back", func(t *testing.T) {
+		r := NewResolver(log, false, pacServer.URL+"/missing", explicit)
+		r.client = &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("unreachable")
+		})}
+		proxy, err := r.Proxy(request(t, "https://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://explicit:3128", proxy.String())
+	})
+
+	t.Run("system proxy", func(t *testing.T) {
+		r := NewResolver(log, true, "", explicit)
+		r.detect = func() (*systemSettings, error) {
+			return &systemSettings{
+				httpsProxy: &url.URL{Scheme: "http", Host: "system:8080"},
+				bypass:     []string{"*.internal.example.com"},
+			}, nil
+		}
+		proxy, err := r.Proxy(request(t, "https://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://system:8080", proxy.String())
+
+		proxy, err = r.Proxy(request(t, "https://repo.internal.example.com/file"))
+		require.NoError(t, err)
+		assert.Nil(t, proxy, "bypassed hosts are connected to directly")
+
+		// no system proxy for http, explicit settings are used
+		proxy, err = r.Proxy(request(t, "http://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://explicit:3128", proxy.String())
+	})
+
+	t.Run("detection failure falls back", func(t *testing.T) {
+		r := NewResolver(log, true, "", explicit)
+		r.detect = func() (*systemSettings, error) {
+			return nil, errors.New("failed")
+		}
+		proxy, err := r.Proxy(request(t, "https://artifacts.elastic.co/file"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://explicit:3128", proxy.String())
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// systemSettings are the proxy settings of the operating system.
+type systemSettings struct {
+	// pacURL is the URL of the proxy auto-config file.
+	pacURL string
+	// httpProxy is the proxy used for http URLs.
+	httpProxy *url.URL
+	// httpsProxy is the proxy used for https URLs.
+	httpsProxy *url.URL
+	// bypass are the hosts that are connected to directly.
+	bypass []string
+	// bypassSimple connects directly to host names without a domain.
+	bypassSimple bool
+}
+
+// proxyFor returns the proxy of the system settings for the URL, nil when the URL is connected to directly.
+func (s *systemSettings) proxyFor(u *url.URL) *url.URL {
+	if s.bypassed(u.Hostname()) {
+		return nil
+	}
+	if u.Scheme == "https" {
+		return s.httpsProxy
+	}
+	return s.httpProxy
+}
+
+func (s *systemSettings) bypassed(host string) bool {
+	host = strings.ToLower(host)
+	if s.bypassSimple && !strings.Contains(host, ".") {
+		return true
+	}
+	for _, pattern := range s.bypass {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "":
+			continue
+		case pattern == "<local>":
+			if !strings.Contains(host, ".") {
+				return true
+			}
+		case strings.Contains(pattern, "/"):
+			if inCIDR(host, pattern) {
+				return true
+			}
+		case shExpMatch(host, pattern):
+			return true
+		case strings.HasPrefix(pattern, ".") && strings.HasSuffix(host, pattern):
+			return true
+		}
+	}
+	return false
+}
+
+// inCIDR returns true when the host is an IP in the network, short networks like 169.254/16 are allowed.
+func inCIDR(host, cidr string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	network, bits, _ := strings.Cut(cidr, "/")
+	if ip.To4() != nil {
+		for strings.Count(network, ".") < 3 {
+			network += ".0"
+		}
+	}
+	_, ipNet, err := net.ParseCIDR(network + "/" + bits)
+	return err == nil && ipNet.Contains(ip)
+}
+
+// proxyURL parses a proxy of the system settings, which may omit the scheme.
+func proxyURL(host string, port string) *url.URL {
+	if host == "" {
+		return nil
+	}
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil
+	}
+	if port != "" && u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return u
+}
+
+// parseScutilProxy parses the output of `scutil --proxy` on macOS.
+func parseScutilProxy(output []byte) *systemSettings {
+	values := map[string]string{}
+	var exceptions []string
+	inExceptions := false
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if inExceptions {
+			if line == "}" {
+				inExceptions = false
+				continue
+			}
+			if _, value, ok := strings.Cut(line, " : "); ok {
+				exceptions = append(exceptions, value)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, " : ")
+		if !ok {
+			continue
+		}
+		if key == "ExceptionsList" {
+			inExceptions = true
+			continue
+		}
+		values[key] = value
+	}
+
+	s := &systemSettings{
+		bypass:       exceptions,
+		bypassSimple: values["ExcludeSimpleHostnames"] == "1",
+	}
+	if values["ProxyAutoConfigEnable"] == "1" {
+		s.pacURL = values["ProxyAutoConfigURLString"]
+	}
+	if values["HTTPEnable"] == "1" {
+		s.httpProxy = proxyURL(values["HTTPProxy"], values["HTTPPort"])
+	}
+	if values["HTTPSEnable"] == "1" {
+		s.httpsProxy = proxyURL(values["HTTPSProxy"], values["HTTPSPort"])
+	}
+	return s
+}
+
+// parseWindowsProxySettings parses the Internet Settings of Windows. The proxy server is either one proxy
+// for all the protocols (host:port) or a proxy per protocol (http=host:port;https=host:port), the
+// override is the semicolon separated list of hosts that are connected to directly.
+func parseWindowsProxySettings(enabled bool, server string, override string, autoConfigURL string) *systemSettings {
+	s := &systemSettings{pacURL: autoConfigURL}
+	if override != "" {
+		s.bypass = strings.Split(override, ";")
+	}
+	if !enabled || server == "" {
+		return s
+	}
+	if !strings.Contains(server, "=") {
+		s.httpProxy = proxyURL(server, "")
+		s.httpsProxy = s.httpProxy
+		return s
+	}
+	for _, entry := range strings.Split(server, ";") {
+		protocol, address, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(protocol)) {
+		case "http":
+			s.httpProxy = proxyURL(strings.TrimSpace(address), "")
+		case "https":
+			s.httpsProxy = proxyURL(strings.TrimSpace(address), "")
+		}
+	}
+	return s
+}
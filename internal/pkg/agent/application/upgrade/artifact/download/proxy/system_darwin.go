@@ -0,0 +1,21 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build darwin
+
+package proxy
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// detectSystemSettings reads the proxy settings of the system configuration.
+func detectSystemSettings() (*systemSettings, error) {
+	output, err := exec.Command("/usr/sbin/scutil", "--proxy").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read system proxy settings: %w", err)
+	}
+	return parseScutilProxy(output), nil
+}
@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows && !darwin
+
+package proxy
+
+// detectSystemSettings returns no settings, the operating system has no system wide proxy settings
+// besides the proxy environment variables that are already used by the explicit settings.
+func detectSystemSettings() (*systemSettings, error) {
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxy
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const scutilOutput = `<dictionary> {
+  ExceptionsList : <array> {
+    0 : *.local
+    1 : 169.254/16
+  }
+  ExcludeSimpleHostnames : 1
+  FTPPassive : 1
+  HTTPEnable : 1
+  HTTPPort : 8080
+  HTTPProxy : proxy.example.com
+  HTTPSEnable : 1
+  HTTPSPort : 8443
+  HTTPSProxy : secure-proxy.example.com
+  ProxyAutoConfigEnable : 1
+  ProxyAutoConfigURLString : http://wpad.example.com/wpad.dat
+}
+`
+
+func TestParseScutilProxy(t *testing.T) {
+	s := parseScutilProxy([]byte(scutilOutput))
+	assert.Equal(t, "http://wpad.example.com/wpad.dat", s.pacURL)
+	assert.Equal(t, "http://proxy.example.com:8080", s.httpProxy.String())
+	assert.Equal(t, "http://secure-proxy.example.com:8443", s.httpsProxy.String())
+	assert.Equal(t, []string{"*.local", "169.254/16"}, s.bypass)
+	assert.True(t, s.bypassSimple)
+
+	disabled := parseScutilProxy([]byte("<dictionary> {\n  HTTPEnable : 0\n  HTTPProxy : proxy.example.com\n}\n"))
+	assert.Nil(t, disabled.httpProxy)
+	assert.Empty(t, disabled.pacURL)
+}
+
+func TestParseWindowsProxySettings(t *testing.T) {
+	s := parseWindowsProxySettings(true, "proxy.example.com:8080", "*.local;<local>", "")
+	assert.Equal(t, "http://proxy.example.com:8080", s.httpProxy.String())
+	assert.Equal(t, "http://proxy.example.com:8080", s.httpsProxy.String())
+	assert.Equal(t, []string{"*.local", "<local>"}, s.bypass)
+
+	s = parseWindowsProxySettings(true, "http=proxy.example.com:8080;https=secure-proxy.example.com:8443;ftp=ftp:21", "", "http://wpad/wpad.dat")
+	assert.Equal(t, "http://proxy.example.com:8080", s.httpProxy.String())
+	assert.Equal(t, "http://secure-proxy.example.com:8443", s.httpsProxy.String())
+	assert.Equal(t, "http://wpad/wpad.dat", s.pacURL)
+
+	s = parseWindowsProxySettings(false, "proxy.example.com:8080", "", "")
+	assert.Nil(t, s.httpProxy)
+	assert.Nil(t, s.httpsProxy)
+}
+
+func TestSystemSettingsBypass(t *testing.T) {
+	s := &systemSettings{
+		httpsProxy: &url.URL{Scheme: "http", Host: "proxy:8080"},
+		bypass:     []string{"*.local", "169.254/16", "<local>", ".corp.example.com", "10.0.0.0/8"},
+	}
+	for host, bypassed := range map[string]bool{
+		"printer.local":         true,
+		"169.254.1.1":           true,
+		"intranet":              true,
+		"repo.corp.example.com": true,
+		"10.1.2.3":              true,
+		"artifacts.elastic.co":  false,
+		"11.1.2.3":              false,
+	} {
+		t.Run(host, func(t *testing.T) {
+			u, err := url.Parse("https://" + host + "/file")
+			require.NoError(t, err)
+			assert.Equal(t, bypassed, s.bypassed(host))
+			if bypassed {
+				assert.Nil(t, s.proxyFor(u))
+			} else {
+				assert.Equal(t, s.httpsProxy, s.proxyFor(u))
+			}
+		})
+	}
+}
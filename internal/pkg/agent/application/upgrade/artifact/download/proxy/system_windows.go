@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package proxy
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	internetSettingsKey       = `SOFTWARE\Microsoft\Windows\CurrentVersion\Internet Settings`
+	internetSettingsPolicyKey = `SOFTWARE\Policies\Microsoft\Windows\CurrentVersion\Internet Settings`
+)
+
+// detectSystemSettings reads the proxy settings of the Internet Settings. The settings of the machine are
+// used when the group policy disables per user settings, otherwise the settings of the user the agent
+// runs as.
+func detectSystemSettings() (*systemSettings, error) {
+	root := registry.CURRENT_USER
+	if perUser, err := readDWORD(registry.LOCAL_MACHINE, internetSettingsPolicyKey, "ProxySettingsPerUser"); err == nil && perUser == 0 {
+		root = registry.LOCAL_MACHINE
+	}
+
+	key, err := registry.OpenKey(root, internetSettingsKey, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open internet settings: %w", err)
+	}
+	defer key.Close()
+
+	enabled, _, err := key.GetIntegerValue("ProxyEnable")
+	if err != nil && !errors.Is(err, registry.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read ProxyEnable: %w", err)
+	}
+	server, err := readString(key, "ProxyServer")
+	if err != nil {
+		return nil, err
+	}
+	override, err := readString(key, "ProxyOverride")
+	if err != nil {
+		return nil, err
+	}
+	autoConfigURL, err := readString(key, "AutoConfigURL")
+	if err != nil {
+		return nil, err
+	}
+	return parseWindowsProxySettings(enabled == 1, server, override, autoConfigURL), nil
+}
+
+func readDWORD(root registry.Key, path string, name string) (uint64, error) {
+	key, err := registry.OpenKey(root, path, registry.QUERY_VALUE)
+	if err != nil {
+		return 0, err
+	}
+	defer key.Close()
+	value, _, err := key.GetIntegerValue(name)
+	return value, err
+}
+
+func readString(key registry.Key, name string) (string, error) {
+	value, _, err := key.GetStringValue(name)
+	if errors.Is(err, registry.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,254 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package sigstore verifies the downloaded artifacts with the sigstore bundles published next to them, for
+// organizations signing their artifacts with sigstore (cosign sign-blob --bundle) instead of PGP. The bundles
+// are verified by cosign (cosign verify-blob --bundle): the protobuf definitions of the bundles conflict with
+// the ones of the components linked into the Elastic Agent.
+package sigstore
+
+import (
+	"bytes"
+	"context"
+	goerrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	// bundleSuffix is the suffix of the sigstore bundle of an artifact.
+	bundleSuffix = ".sigstore.json"
+
+	// maxBundleSize is the maximum size of a sigstore bundle.
+	maxBundleSize = 1024 * 1024
+
+	// cosignTimeout bounds the verification of a bundle, including the retrieval of the trusted root with TUF.
+	cosignTimeout = 5 * time.Minute
+)
+
+// Verifier verifies the artifacts with their sigstore bundle. The bundle is read next to the artifact in the
+// target directory or the drop path, or fetched from the source URI.
+type Verifier struct {
+	log *logger.Logger
+
+	mx     sync.Mutex
+	config *artifact.Config
+	client http.Client
+
+	// runCosign runs cosign with the arguments and the environment, and returns its combined output.
+	runCosign func(ctx context.Context, cosign string, args []string, env []string) ([]byte, error)
+}
+
+func (v *Verifier) Name() string {
+	return "sigstore.verifier"
+}
+
+// NewVerifier creates a verifier of the sigstore bundles of the configuration.
+func NewVerifier(log *logger.Logger, config *artifact.Config) (*Verifier, error) {
+	v := &Verifier{
+		log:       log,
+		runCosign: runCosign,
+	}
+	if err := v.Reload(config); err != nil {
+		return nil, err
+	}
+	// cosign is not shipped with the Elastic Agent, a missing cosign fails the upgrade creating the verifier
+	// and not the loading of the configuration
+	if err := utils.CheckTrustedFile(config.Verification.Sigstore.Cosign); err != nil {
+		return nil, errors.New(fmt.Errorf("sigstore verification requires cosign, it is not shipped with the Elastic Agent and must be installed on the host: %w", err), errors.TypeSecurity, errors.M(errors.MetaKeyPath, config.Verification.Sigstore.Cosign))
+	}
+	return v, nil
+}
+
+// Reload reloads the verifier from the configuration.
+func (v *Verifier) Reload(c *artifact.Config) error {
+	if err := c.Verification.Validate(); err != nil {
+		return errors.New(err, "sigstore.verifier: invalid configuration", errors.TypeConfig)
+	}
+	client, err := c.Client(httpcommon.WithAPMHTTPInstrumentation())
+	if err != nil {
+		return errors.New(err, "sigstore.verifier: failed to generate client out of config")
+	}
+	client.Transport = download.WithHeaders(client.Transport, download.Headers)
+
+	v.mx.Lock()
+	defer v.mx.Unlock()
+	v.config = c
+	v.client = *client
+	return nil
+}
+
+// Verify checks the downloaded package against its hash and its sigstore bundle. The PGP keys are ignored.
+func (v *Verifier) Verify(ctx context.Context, a artifact.Artifact, version agtversion.ParsedSemVer, _ bool, _ ...string) error {
+	v.mx.Lock()
+	config := v.config
+	v.mx.Unlock()
+
+	artifactPath, err := artifact.GetArtifactPath(a, version, config.OS(), config.Arch(), config.TargetDirectory)
+	if err != nil {
+		return errors.New(err, "retrieving package path")
+	}
+
+	if err = download.VerifySHA512HashWithCleanup(v.log, artifactPath); err != nil {
+		return fmt.Errorf("failed to verify SHA512 hash: %w", err)
+	}
+
+	if err = v.verifyBundle(ctx, config, a, version, artifactPath); err != nil {
+		var invalidSignatureErr *download.InvalidSignatureError
+		if goerrors.As(err, &invalidSignatureErr) {
+			if err := os.Remove(artifactPath); err != nil {
+				v.log.Warnf("failed clean up after signature verification: failed to remove %q: %v",
+					artifactPath, err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (v *Verifier) verifyBundle(ctx context.Context, config *artifact.Config, a artifact.Artifact, version agtversion.ParsedSemVer, artifactPath string) error {
+	filename, err := artifact.GetArtifactName(a, version, config.OS(), config.Arch())
+	if err != nil {
+		return errors.New(err, "retrieving package name")
+	}
+	bundlePath, cleanup, err := v.getBundle(ctx, config, a.Artifact, filename, artifactPath)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	sigstoreCfg := config.Verification.Sigstore
+	cosign := sigstoreCfg.Cosign
+	// cosign decides whether the package is installed, it is checked again as it could have been replaced by a
+	// less privileged user since the configuration was loaded
+	if err := utils.CheckTrustedFile(cosign); err != nil {
+		return errors.New(err, "checking cosign", errors.TypeSecurity, errors.M(errors.MetaKeyPath, cosign))
+	}
+	// the trusted root of the public good instance is retrieved with TUF, cached in the data directory
+	env := append(os.Environ(), "TUF_ROOT="+filepath.Join(paths.Data(), "sigstore", "tuf"))
+
+	ctx, cancel := context.WithTimeout(ctx, cosignTimeout)
+	defer cancel()
+	out, err := v.runCosign(ctx, cosign, cosignArgs(sigstoreCfg, bundlePath, artifactPath), env)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !goerrors.As(err, &exitErr) {
+			return errors.New(err, "running cosign to verify the sigstore bundle", errors.TypeSecurity, errors.M(errors.MetaKeyPath, cosign))
+		}
+		return &download.InvalidSignatureError{File: artifactPath, Err: fmt.Errorf("cosign verify-blob: %w: %s", err, strings.TrimSpace(string(out)))}
+	}
+	v.log.Infow("Verified sigstore bundle", "file", artifactPath)
+	return nil
+}
+
+// cosignArgs returns the arguments of cosign verifying the artifact with the bundle. The bundle must be logged
+// in the transparency log, cosign requires it unless told otherwise.
+func cosignArgs(cfg artifact.SigstoreConfig, bundlePath, artifactPath string) []string {
+	args := []string{"verify-blob", "--bundle", bundlePath}
+	if cfg.Identity != "" {
+		args = append(args, "--certificate-identity", cfg.Identity)
+	} else {
+		args = append(args, "--certificate-identity-regexp", cfg.IdentityRegexp)
+	}
+	if cfg.Issuer != "" {
+		args = append(args, "--certificate-oidc-issuer", cfg.Issuer)
+	} else {
+		args = append(args, "--certificate-oidc-issuer-regexp", cfg.IssuerRegexp)
+	}
+	if cfg.TrustedRoot != "" {
+		args = append(args, "--trusted-root", cfg.TrustedRoot)
+	}
+	return append(args, artifactPath)
+}
+
+func runCosign(ctx context.Context, cosign string, args []string, env []string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, cosign, args...) //nolint:gosec // cosign is checked before it runs
+	cmd.Env = env
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.Bytes(), err
+}
+
+// getBundle returns the path of the bundle next to the package in the target directory or the drop path, or
+// fetches it from the source URI into a temporary file removed by cleanup.
+func (v *Verifier) getBundle(ctx context.Context, config *artifact.Config, artifactName, filename, artifactPath string) (string, func(), error) {
+	noCleanup := func() {}
+	candidates := []string{artifactPath + bundleSuffix}
+	if config.DropPath != "" {
+		candidates = append(candidates, filepath.Join(config.DropPath, filename+bundleSuffix))
+	}
+	for _, candidate := range candidates {
+		_, err := os.Stat(candidate)
+		if err == nil {
+			return candidate, noCleanup, nil
+		}
+		if !goerrors.Is(err, os.ErrNotExist) {
+			return "", nil, errors.New(err, "reading sigstore bundle", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, candidate))
+		}
+	}
+
+	upstream := config.SourceURI
+	if !strings.HasPrefix(upstream, "http") {
+		return "", nil, errors.New(fmt.Sprintf("sigstore bundle %s not found", candidates[0]), errors.TypeFilesystem, errors.M(errors.MetaKeyPath, candidates[0]))
+	}
+	uri, err := url.Parse(upstream)
+	if err != nil {
+		return "", nil, errors.New(err, "invalid upstream URI", errors.TypeNetwork, errors.M(errors.MetaKeyURI, upstream))
+	}
+	uri.Path = path.Join(uri.Path, artifactName, filename+bundleSuffix)
+	bundleURI := uri.String()
+
+	fetchCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(fetchCtx, http.MethodGet, bundleURI, nil)
+	if err != nil {
+		return "", nil, errors.New(err, "failed create request for loading sigstore bundle", errors.TypeNetwork, errors.M(errors.MetaKeyURI, bundleURI))
+	}
+	v.mx.Lock()
+	client := v.client
+	v.mx.Unlock()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", nil, errors.New(err, "failed loading sigstore bundle", errors.TypeNetwork, errors.M(errors.MetaKeyURI, bundleURI))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, errors.New(fmt.Sprintf("call to '%s' returned unsuccessful status code: %d", bundleURI, resp.StatusCode), errors.TypeNetwork, errors.M(errors.MetaKeyURI, bundleURI))
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(artifactPath), filename+".*"+bundleSuffix)
+	if err != nil {
+		return "", nil, errors.New(err, "creating sigstore bundle file", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, filepath.Dir(artifactPath)))
+	}
+	cleanup := func() { _ = os.Remove(f.Name()) }
+	_, err = io.Copy(f, io.LimitReader(resp.Body, maxBundleSize))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, errors.New(err, "failed loading sigstore bundle", errors.TypeNetwork, errors.M(errors.MetaKeyURI, bundleURI))
+	}
+	return f.Name(), cleanup, nil
+}
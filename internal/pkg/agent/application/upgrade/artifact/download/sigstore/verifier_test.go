@@ -0,0 +1,257 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package sigstore
+
+import (
+	"context"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+const (
+	testIdentity = "release@example.com"
+	testIssuer   = "https://accounts.example.com"
+	testBundle   = `{"mediaType":"application/vnd.dev.sigstore.bundle.v0.3+json"}`
+)
+
+var (
+	testVersion = agtversion.NewParsedSemVer(9, 1, 0, "", "")
+	agentSpec   = artifact.Artifact{
+		Name:     "Elastic Agent",
+		Cmd:      "elastic-agent",
+		Artifact: "beats/elastic-agent",
+	}
+)
+
+func newTestConfig(t *testing.T) *artifact.Config {
+	cfg := artifact.DefaultConfig()
+	cfg.OperatingSystem = "linux"
+	cfg.Architecture = "64"
+	cfg.TargetDirectory = t.TempDir()
+	cfg.SourceURI = "file:///nowhere"
+	cfg.Verification.Mode = artifact.VerificationModeSigstore
+	cfg.Verification.Sigstore.Cosign = writeCosign(t)
+	cfg.Verification.Sigstore.Identity = testIdentity
+	cfg.Verification.Sigstore.Issuer = testIssuer
+	return cfg
+}
+
+// writeCosign writes the file checked as cosign, cosign itself never runs in the tests.
+func writeCosign(t *testing.T) string {
+	cosign := filepath.Join(t.TempDir(), "cosign")
+	require.NoError(t, os.WriteFile(cosign, []byte("#!/bin/sh\n"), 0o700))
+	testutils.TrustFile(t, cosign)
+	return cosign
+}
+
+// writeArtifact writes the package and its hash to the target directory, and returns the path of the package.
+func writeArtifact(t *testing.T, cfg *artifact.Config, content []byte) string {
+	filename, err := artifact.GetArtifactName(agentSpec, *testVersion, cfg.OS(), cfg.Arch())
+	require.NoError(t, err)
+	fullPath := filepath.Join(cfg.TargetDirectory, filename)
+	require.NoError(t, os.WriteFile(fullPath, content, 0o600))
+	hash := fmt.Sprintf("%x %s", sha512.Sum512(content), filename)
+	require.NoError(t, os.WriteFile(fullPath+".sha512", []byte(hash), 0o600))
+	return fullPath
+}
+
+// fakeCosign records the verification of the bundles instead of running cosign.
+type fakeCosign struct {
+	cosign string
+	args   []string
+	env    []string
+	bundle string
+	err    error
+}
+
+func (f *fakeCosign) run(_ context.Context, cosign string, args []string, env []string) ([]byte, error) {
+	f.cosign, f.args, f.env = cosign, args, env
+	// the bundle is only readable while cosign runs, a fetched bundle is removed afterwards
+	if i := slices.Index(args, "--bundle"); i >= 0 {
+		data, _ := os.ReadFile(args[i+1])
+		f.bundle = string(data)
+	}
+	if f.err != nil {
+		return []byte("Error: none of the expected identities matched what was in the certificate"), f.err
+	}
+	return nil, nil
+}
+
+func newTestVerifier(t *testing.T, cfg *artifact.Config, cosign *fakeCosign) *Verifier {
+	log, _ := loggertest.New("sigstore")
+	v, err := NewVerifier(log, cfg)
+	require.NoError(t, err)
+	v.runCosign = cosign.run
+	return v
+}
+
+// exitError returns the error of a process exiting with a non zero code, as cosign does when the bundle
+// does not match.
+func exitError(t *testing.T) error {
+	err := exec.Command(os.Args[0], "-test.run=^$", "-test.bogus-flag").Run()
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	return err
+}
+
+func TestVerifier(t *testing.T) {
+	content := []byte("sample content")
+
+	t.Run("valid bundle", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		fullPath := writeArtifact(t, cfg, content)
+		require.NoError(t, os.WriteFile(fullPath+bundleSuffix, []byte(testBundle), 0o600))
+
+		cosign := &fakeCosign{}
+		v := newTestVerifier(t, cfg, cosign)
+		require.NoError(t, v.Verify(context.Background(), agentSpec, *testVersion, false))
+		assert.Equal(t, cfg.Verification.Sigstore.Cosign, cosign.cosign)
+		assert.Equal(t, []string{
+			"verify-blob", "--bundle", fullPath + bundleSuffix,
+			"--certificate-identity", testIdentity,
+			"--certificate-oidc-issuer", testIssuer,
+			fullPath,
+		}, cosign.args)
+		assert.Contains(t, cosign.env, "TUF_ROOT="+filepath.Join(paths.Data(), "sigstore", "tuf"))
+	})
+
+	t.Run("regular expressions and trusted root", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		cfg.Verification.Sigstore = artifact.SigstoreConfig{
+			Cosign:         cfg.Verification.Sigstore.Cosign,
+			TrustedRoot:    "/etc/sigstore/trusted_root.json",
+			IdentityRegexp: "@example.com$",
+			IssuerRegexp:   "^https://accounts.example.com",
+		}
+		fullPath := writeArtifact(t, cfg, content)
+		require.NoError(t, os.WriteFile(fullPath+bundleSuffix, []byte(testBundle), 0o600))
+
+		cosign := &fakeCosign{}
+		v := newTestVerifier(t, cfg, cosign)
+		require.NoError(t, v.Verify(context.Background(), agentSpec, *testVersion, false))
+		assert.Equal(t, []string{
+			"verify-blob", "--bundle", fullPath + bundleSuffix,
+			"--certificate-identity-regexp", "@example.com$",
+			"--certificate-oidc-issuer-regexp", "^https://accounts.example.com",
+			"--trusted-root", "/etc/sigstore/trusted_root.json",
+			fullPath,
+		}, cosign.args)
+	})
+
+	t.Run("bundle fetched from the source", func(t *testing.T) {
+		var requested string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requested = r.URL.Path
+			_, _ = w.Write([]byte(testBundle))
+		}))
+		defer srv.Close()
+
+		cfg := newTestConfig(t)
+		cfg.SourceURI = srv.URL + "/downloads/"
+		fullPath := writeArtifact(t, cfg, content)
+
+		cosign := &fakeCosign{}
+		v := newTestVerifier(t, cfg, cosign)
+		require.NoError(t, v.Verify(context.Background(), agentSpec, *testVersion, false))
+		assert.Equal(t, "/downloads/beats/elastic-agent/"+filepath.Base(fullPath)+bundleSuffix, requested)
+		assert.Equal(t, testBundle, cosign.bundle, "the fetched bundle should be verified")
+		matches, err := filepath.Glob(filepath.Join(cfg.TargetDirectory, "*"+bundleSuffix))
+		require.NoError(t, err)
+		assert.Empty(t, matches, "the fetched bundle should be removed once verified")
+	})
+
+	t.Run("bundle not matching", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		fullPath := writeArtifact(t, cfg, content)
+		require.NoError(t, os.WriteFile(fullPath+bundleSuffix, []byte(testBundle), 0o600))
+
+		v := newTestVerifier(t, cfg, &fakeCosign{err: exitError(t)})
+		err := v.Verify(context.Background(), agentSpec, *testVersion, false)
+		var invalidSignatureErr *download.InvalidSignatureError
+		require.ErrorAs(t, err, &invalidSignatureErr)
+		assert.ErrorContains(t, err, "none of the expected identities matched")
+		assert.NoFileExists(t, fullPath, "package with an invalid signature must be removed")
+	})
+
+	t.Run("cosign removed", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		fullPath := writeArtifact(t, cfg, content)
+		require.NoError(t, os.WriteFile(fullPath+bundleSuffix, []byte(testBundle), 0o600))
+
+		cosign := &fakeCosign{}
+		v := newTestVerifier(t, cfg, cosign)
+		require.NoError(t, os.Remove(cfg.Verification.Sigstore.Cosign))
+		err := v.Verify(context.Background(), agentSpec, *testVersion, false)
+		require.ErrorIs(t, err, os.ErrNotExist)
+		assert.Nil(t, cosign.args, "a missing cosign should not run")
+		var invalidSignatureErr *download.InvalidSignatureError
+		assert.False(t, errors.As(err, &invalidSignatureErr), "a missing cosign is not an invalid signature")
+		assert.FileExists(t, fullPath, "package must be kept when cosign cannot run")
+	})
+
+	t.Run("cosign writable by others", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		fullPath := writeArtifact(t, cfg, content)
+		require.NoError(t, os.WriteFile(fullPath+bundleSuffix, []byte(testBundle), 0o600))
+
+		cosign := &fakeCosign{}
+		v := newTestVerifier(t, cfg, cosign)
+		// cosign is checked again before it runs
+		testutils.UntrustFile(t, cfg.Verification.Sigstore.Cosign)
+		err := v.Verify(context.Background(), agentSpec, *testVersion, false)
+		assert.ErrorContains(t, err, "must not be writable by")
+		assert.Nil(t, cosign.args, "a cosign writable by others should not run")
+		assert.FileExists(t, fullPath, "package must be kept when cosign cannot run")
+	})
+
+	t.Run("missing bundle", func(t *testing.T) {
+		cfg := newTestConfig(t)
+		fullPath := writeArtifact(t, cfg, content)
+
+		cosign := &fakeCosign{}
+		v := newTestVerifier(t, cfg, cosign)
+		err := v.Verify(context.Background(), agentSpec, *testVersion, false)
+		require.Error(t, err)
+		assert.Nil(t, cosign.args, "cosign should not run without a bundle")
+		assert.FileExists(t, fullPath, "package must be kept when its bundle is missing")
+	})
+}
+
+func TestNewVerifierInvalidConfig(t *testing.T) {
+	log, _ := loggertest.New("sigstore")
+	cfg := newTestConfig(t)
+	cfg.Verification.Sigstore.Issuer = ""
+	_, err := NewVerifier(log, cfg)
+	assert.Error(t, err)
+
+	cfg = newTestConfig(t)
+	cfg.Verification.Sigstore.Cosign = "cosign"
+	_, err = NewVerifier(log, cfg)
+	assert.ErrorContains(t, err, "absolute path of cosign")
+
+	cfg = newTestConfig(t)
+	cfg.Verification.Sigstore.Cosign = filepath.Join(t.TempDir(), "cosign")
+	_, err = NewVerifier(log, cfg)
+	assert.ErrorContains(t, err, "requires cosign, it is not shipped with the Elastic Agent")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -27,6 +27,7 @@ const snapshotURIFormat = "https://snapshots.elastic.co/%s-%s/downloads/"
 
 type Downloader struct {
 	downloader      download.Downloader
+	verifier        *http.Verifier
 	versionOverride *agtversion.ParsedSemVer
 	client          *gohttp.Client
 }
@@ -37,7 +38,7 @@ type Downloader struct {
 // artifact.Config struct is part of agent configuration and a version
 // override makes no sense there
 func NewDownloader(log *logger.Logger, config *artifact.Config, versionOverride *agtversion.ParsedSemVer, upgradeDetails *details.Details) (download.Downloader, error) {
-	client, err := config.HTTPTransportSettings.Client(
+	client, err := config.Client(
 		httpcommon.WithAPMHTTPInstrumentation(),
 		httpcommon.WithKeepaliveSettings{Disable: false, IdleConnTimeout: 30 * time.Second},
 	)
@@ -57,8 +58,12 @@ func NewDownloaderWithClient(log *logger.Logger, config *artifact.Config, versio
 
 	httpDownloader := http.NewDownloaderWithClient(log, cfg, *client, upgradeDetails)
 
+	// verify against the same resolved build the package is downloaded from
+	httpVerifier := http.NewVerifierWithClient(log, cfg, *client, snapshotPGP(config, release.PGP()))
+
 	return &Downloader{
 		downloader:      httpDownloader,
+		verifier:        httpVerifier,
 		versionOverride: versionOverride,
 		client:          client,
 	}, nil
@@ -76,6 +81,10 @@ func (e *Downloader) Reload(c *artifact.Config) error {
 		return fmt.Errorf("snapshot.downloader: failed to generate snapshot config: %w", err)
 	}
 
+	if err := e.verifier.Reload(cfg); err != nil {
+		return fmt.Errorf("snapshot.downloader: failed to reload verifier: %w", err)
+	}
+
 	return reloader.Reload(cfg)
 }
 
@@ -84,7 +93,18 @@ func (e *Downloader) Reload(c *artifact.Config) error {
 func (e *Downloader) Download(ctx context.Context, a artifact.Artifact, version *agtversion.ParsedSemVer) (string, error) {
 	// remove build metadata to match filename of the package for the specific snapshot build
 	strippedVersion := agtversion.NewParsedSemVer(version.Major(), version.Minor(), version.Patch(), version.Prerelease(), "")
-	return e.downloader.Download(ctx, a, strippedVersion)
+	path, err := e.downloader.Download(ctx, a, strippedVersion)
+	if err != nil {
+		return "", err
+	}
+
+	// the snapshot build is verified with its checksum and signature right after the download, a
+	// package that does not match is removed and fails the download
+	if err := e.verifier.Verify(ctx, a, *strippedVersion, false); err != nil {
+		return "", fmt.Errorf("snapshot.downloader: failed to verify %s: %w", path, err)
+	}
+
+	return path, nil
 }
 
 func snapshotConfig(ctx context.Context, client *gohttp.Client, config *artifact.Config, versionOverride *agtversion.ParsedSemVer) (*artifact.Config, error) {
@@ -100,6 +120,17 @@ func snapshotConfig(ctx context.Context, client *gohttp.Client, config *artifact
 		TargetDirectory: config.TargetDirectory,
 		InstallPath:     config.InstallPath,
 		DropPath:        config.DropPath,
+		SnapshotPGP:     config.SnapshotPGP,
+		Snapshot:        config.Snapshot,
+		Segments:        config.Segments,
+		RateLimit:       config.RateLimit,
+		Window:          config.Window,
+		ProxyAutoDetect: config.ProxyAutoDetect,
+		ProxyPACURL:     config.ProxyPACURL,
+		ProxyPaths:      config.ProxyPaths,
+		PeerCache:       config.PeerCache,
+		Verification:    config.Verification,
+		Revocation:      config.Revocation,
 
 		HTTPTransportSettings: config.HTTPTransportSettings,
 	}, nil
@@ -124,6 +155,15 @@ func snapshotURI(ctx context.Context, client *gohttp.Client, versionOverride *ag
 		version = versionOverride.CoreVersion()
 	}
 
+	// a build pinned in the configuration makes all the agents upgrade to the same snapshot build
+	if config.Snapshot.Build != "" {
+		buildID, err := pinnedSnapshotBuild(config.Snapshot.Build, version)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf(snapshotURIFormat, version, buildID), nil
+	}
+
 	// otherwise, if we don't know the exact build and we're trying to find the latest snapshot build
 	buildID, err := findLatestSnapshot(ctx, client, version)
 	if err != nil {
@@ -171,3 +211,30 @@ func findLatestSnapshot(ctx context.Context, client *gohttp.Client, version stri
 		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, latestSnapshotURI)
 	}
 }
+
+// pinnedSnapshotBuild returns the hash of the pinned build. The build is either the hash alone or the
+// build ID returned by the artifacts API (<version>-<hash>), the version of which must match the version
+// being resolved.
+func pinnedSnapshotBuild(build string, version string) (string, error) {
+	idx := strings.LastIndex(build, "-")
+	if idx < 0 {
+		return build, nil
+	}
+	buildVersion, hash := build[:idx], build[idx+1:]
+	if buildVersion != version {
+		return "", fmt.Errorf("pinned snapshot build %q is a build of version %q, not %q", build, buildVersion, version)
+	}
+	if hash == "" {
+		return "", fmt.Errorf("wrong format for a build ID: %s", build)
+	}
+	return hash, nil
+}
+
+// snapshotPGP returns the PGP key snapshot builds are verified with. A key configured with
+// `snapshot_pgp` overrides the embedded Elastic key.
+func snapshotPGP(config *artifact.Config, pgp []byte) []byte {
+	if config.SnapshotPGP != "" {
+		return []byte(config.SnapshotPGP)
+	}
+	return pgp
+}
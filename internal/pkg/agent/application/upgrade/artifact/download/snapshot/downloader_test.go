@@ -7,6 +7,7 @@ package snapshot
 import (
 	"bytes"
 	"context"
+	"crypto/sha512"
 	"fmt"
 	"io"
 	"net"
@@ -23,6 +24,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
 	agtversion "github.com/elastic/elastic-agent/pkg/version"
+	"github.com/elastic/elastic-agent/testing/pgptest"
 )
 
 func TestNonDefaultSourceURI(t *testing.T) {
@@ -38,6 +40,67 @@ func TestNonDefaultSourceURI(t *testing.T) {
 
 }
 
+func TestSnapshotURIPinnedBuild(t *testing.T) {
+	testcases := map[string]struct {
+		version     string
+		build       string
+		expectedURI string
+		expectedErr string
+	}{
+		"build in version": {
+			version:     "8.12.0-SNAPSHOT+abcdef12",
+			expectedURI: "https://snapshots.elastic.co/8.12.0-abcdef12/downloads/",
+		},
+		"build in version wins over configured build": {
+			version:     "8.12.0-SNAPSHOT+abcdef12",
+			build:       "12345678",
+			expectedURI: "https://snapshots.elastic.co/8.12.0-abcdef12/downloads/",
+		},
+		"configured build hash": {
+			version:     "8.12.0-SNAPSHOT",
+			build:       "12345678",
+			expectedURI: "https://snapshots.elastic.co/8.12.0-12345678/downloads/",
+		},
+		"configured build ID": {
+			version:     "8.12.0-SNAPSHOT",
+			build:       "8.12.0-12345678",
+			expectedURI: "https://snapshots.elastic.co/8.12.0-12345678/downloads/",
+		},
+		"configured build ID of another version": {
+			version:     "8.12.0-SNAPSHOT",
+			build:       "8.13.0-12345678",
+			expectedErr: `pinned snapshot build "8.13.0-12345678" is a build of version "8.13.0", not "8.12.0"`,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			version, err := agtversion.ParseVersion(tc.version)
+			require.NoError(t, err)
+
+			config := artifact.DefaultConfig()
+			config.Snapshot.Build = tc.build
+			// the latest snapshot API must not be called when the build is known
+			client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return nil, fmt.Errorf("unexpected request to %s", r.URL)
+			})}
+			sourceURI, err := snapshotURI(context.TODO(), client, version, config)
+			if tc.expectedErr != "" {
+				assert.EqualError(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedURI, sourceURI)
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
 var agentSpec = artifact.Artifact{
 	Name:     "Elastic Agent",
 	Cmd:      "elastic-agent",
@@ -52,15 +115,31 @@ func readFile(t *testing.T, name string) []byte {
 }
 
 func TestDownloadVersion(t *testing.T) {
+	content := []byte("snapshot package")
+	hash := sha512.Sum512(content)
+	pub, sig := pgptest.Sign(t, bytes.NewReader(content))
+
 	files := map[string][]byte{
 		// links for the latest snapshot
 		"/latest/8.14.0-SNAPSHOT.json": readFile(t, "./testdata/latest-snapshot.json"),
-		"/8.14.0-6d69ee76/downloads/beat/elastic-agent/elastic-agent-8.14.0-SNAPSHOT-linux-x86_64.tar.gz":        {},
-		"/8.14.0-6d69ee76/downloads/beat/elastic-agent/elastic-agent-8.14.0-SNAPSHOT-linux-x86_64.tar.gz.sha512": {},
+		"/8.14.0-6d69ee76/downloads/beat/elastic-agent/elastic-agent-8.14.0-SNAPSHOT-linux-x86_64.tar.gz":        content,
+		"/8.14.0-6d69ee76/downloads/beat/elastic-agent/elastic-agent-8.14.0-SNAPSHOT-linux-x86_64.tar.gz.sha512": []byte(fmt.Sprintf("%x elastic-agent-8.14.0-SNAPSHOT-linux-x86_64.tar.gz", hash)),
+		"/8.14.0-6d69ee76/downloads/beat/elastic-agent/elastic-agent-8.14.0-SNAPSHOT-linux-x86_64.tar.gz.asc":    sig,
 
 		// links for a specific build
-		"/8.13.3-76ce1a63/downloads/beat/elastic-agent/elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz":        {},
-		"/8.13.3-76ce1a63/downloads/beat/elastic-agent/elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz.sha512": {},
+		"/8.13.3-76ce1a63/downloads/beat/elastic-agent/elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz":        content,
+		"/8.13.3-76ce1a63/downloads/beat/elastic-agent/elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz.sha512": []byte(fmt.Sprintf("%x elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz", hash)),
+		"/8.13.3-76ce1a63/downloads/beat/elastic-agent/elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz.asc":    sig,
+
+		// links for a build with a checksum that does not match the package
+		"/8.13.2-aaaaaaaa/downloads/beat/elastic-agent/elastic-agent-8.13.2-SNAPSHOT-linux-x86_64.tar.gz":        content,
+		"/8.13.2-aaaaaaaa/downloads/beat/elastic-agent/elastic-agent-8.13.2-SNAPSHOT-linux-x86_64.tar.gz.sha512": []byte(fmt.Sprintf("%x elastic-agent-8.13.2-SNAPSHOT-linux-x86_64.tar.gz", sha512.Sum512([]byte("other")))),
+		"/8.13.2-aaaaaaaa/downloads/beat/elastic-agent/elastic-agent-8.13.2-SNAPSHOT-linux-x86_64.tar.gz.asc":    sig,
+
+		// links for a build with a signature from another key
+		"/8.13.1-bbbbbbbb/downloads/beat/elastic-agent/elastic-agent-8.13.1-SNAPSHOT-linux-x86_64.tar.gz":        content,
+		"/8.13.1-bbbbbbbb/downloads/beat/elastic-agent/elastic-agent-8.13.1-SNAPSHOT-linux-x86_64.tar.gz.sha512": []byte(fmt.Sprintf("%x elastic-agent-8.13.1-SNAPSHOT-linux-x86_64.tar.gz", hash)),
+		"/8.13.1-bbbbbbbb/downloads/beat/elastic-agent/elastic-agent-8.13.1-SNAPSHOT-linux-x86_64.tar.gz.asc":    otherSig(t, content),
 	}
 	type fields struct {
 		config *artifact.Config
@@ -82,6 +161,7 @@ func TestDownloadVersion(t *testing.T) {
 				config: &artifact.Config{
 					OperatingSystem: "linux",
 					Architecture:    "64",
+					SnapshotPGP:     string(pub),
 				},
 			},
 			args:    args{a: agentSpec, version: agtversion.NewParsedSemVer(8, 14, 0, "SNAPSHOT", "")},
@@ -94,12 +174,39 @@ func TestDownloadVersion(t *testing.T) {
 				config: &artifact.Config{
 					OperatingSystem: "linux",
 					Architecture:    "64",
+					SnapshotPGP:     string(pub),
 				},
 			},
 			args:    args{a: agentSpec, version: agtversion.NewParsedSemVer(8, 13, 3, "SNAPSHOT", "76ce1a63")},
 			want:    "elastic-agent-8.13.3-SNAPSHOT-linux-x86_64.tar.gz",
 			wantErr: assert.NoError,
 		},
+		{
+			name: "checksum mismatch",
+			fields: fields{
+				config: &artifact.Config{
+					OperatingSystem: "linux",
+					Architecture:    "64",
+					SnapshotPGP:     string(pub),
+				},
+			},
+			args:    args{a: agentSpec, version: agtversion.NewParsedSemVer(8, 13, 2, "SNAPSHOT", "aaaaaaaa")},
+			want:    "elastic-agent-8.13.2-SNAPSHOT-linux-x86_64.tar.gz",
+			wantErr: assert.Error,
+		},
+		{
+			name: "signature from unknown key",
+			fields: fields{
+				config: &artifact.Config{
+					OperatingSystem: "linux",
+					Architecture:    "64",
+					SnapshotPGP:     string(pub),
+				},
+			},
+			args:    args{a: agentSpec, version: agtversion.NewParsedSemVer(8, 13, 1, "SNAPSHOT", "bbbbbbbb")},
+			want:    "elastic-agent-8.13.1-SNAPSHOT-linux-x86_64.tar.gz",
+			wantErr: assert.Error,
+		},
 	}
 
 	for _, tt := range tests {
@@ -145,9 +252,20 @@ func TestDownloadVersion(t *testing.T) {
 			if !tt.wantErr(t, err, fmt.Sprintf("Download(%v, %v)", tt.args.a, tt.args.version)) {
 				return
 			}
+			if err != nil {
+				assert.NoFileExists(t, filepath.Join(targetDirPath, tt.want), "package failing verification must be removed")
+				return
+			}
 
 			assert.Equalf(t, filepath.Join(targetDirPath, tt.want), got, "Download(%v, %v)", tt.args.a, tt.args.version)
 		})
 	}
 
 }
+
+// otherSig signs the content with a newly generated key, that is different from the key the
+// package is verified with.
+func otherSig(t *testing.T, content []byte) []byte {
+	_, sig := pgptest.Sign(t, bytes.NewReader(content))
+	return sig
+}
@@ -30,7 +30,7 @@ func (v *Verifier) Name() string {
 // NewVerifier creates a downloader which first checks local directory
 // and then fallbacks to remote if configured.
 func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte, versionOverride *agtversion.ParsedSemVer) (download.Verifier, error) {
-	client, err := config.HTTPTransportSettings.Client(httpcommon.WithAPMHTTPInstrumentation())
+	client, err := config.Client(httpcommon.WithAPMHTTPInstrumentation())
 	if err != nil {
 		return nil, err
 	}
@@ -40,7 +40,7 @@ func NewVerifier(log *logger.Logger, config *artifact.Config, pgp []byte, versio
 	if err != nil {
 		return nil, err
 	}
-	v, err := http.NewVerifier(log, cfg, pgp)
+	v, err := http.NewVerifier(log, cfg, snapshotPGP(config, pgp))
 	if err != nil {
 		return nil, errors.New(err, "failed to create snapshot verifier")
 	}
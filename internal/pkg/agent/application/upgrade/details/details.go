@@ -38,6 +38,10 @@ type Metadata struct {
 	// is progressing.
 	DownloadRate details.DownloadRate `json:"download_rate,omitempty" yaml:"download_rate,omitempty"`
 
+	// DownloadETA is the estimated time at which the download completes, based
+	// on the current download rate. Unset when the artifact size is unknown.
+	DownloadETA *time.Time `json:"download_eta,omitempty" yaml:"download_eta,omitempty"`
+
 	// RetryErrorMsg is any error message that is a result of a retryable upgrade
 	// step, e.g. the download step, being retried.
 	RetryErrorMsg string `json:"retry_error_msg,omitempty" yaml:"retry_error_msg,omitempty"`
@@ -46,6 +50,9 @@ type Metadata struct {
 	// step, will be retried.
 	RetryUntil *time.Time `json:"retry_until,omitempty" yaml:"retry_until"`
 
+	// DownloadAttempt is the number of the current attempt of the download step.
+	DownloadAttempt int `json:"download_attempt,omitempty" yaml:"download_attempt,omitempty"`
+
 	// FailedState is the state an upgrade was in if/when it failed. Use the
 	// Fail() method of UpgradeDetails to correctly record details when
 	// an upgrade fails.
@@ -59,6 +66,14 @@ type Metadata struct {
 	// Reason is a string that may give out more information about transitioning to the current state. It has been
 	// introduced initially to distinguish between manual and automatic rollbacks
 	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+
+	// HealthGate is the state of the health gate the upgraded Agent must pass
+	// before the upgrade is committed. Empty when the health gate is disabled.
+	HealthGate HealthGateState `json:"health_gate,omitempty" yaml:"health_gate,omitempty"`
+
+	// HealthGateUntil is the deadline until when the upgraded Agent must
+	// report healthy to pass the health gate.
+	HealthGateUntil *time.Time `json:"health_gate_until,omitempty" yaml:"health_gate_until,omitempty"`
 }
 
 func NewDetails(targetVersion string, initialState State, actionID string) *Details {
@@ -112,13 +127,20 @@ func (d *Details) SetStateWithReason(s State, reason string) {
 	d.notifyObservers()
 }
 
-// SetDownloadProgress is a convenience method to set the download percent
-// and download rate when the upgrade is in UPG_DOWNLOADING state.
-func (d *Details) SetDownloadProgress(percent, rateBytesPerSecond float64) {
+// SetDownloadProgress is a convenience method to set the download percent,
+// download rate and download ETA when the upgrade is in UPG_DOWNLOADING state.
+// A remaining duration of zero or less clears the ETA.
+func (d *Details) SetDownloadProgress(percent, rateBytesPerSecond float64, remaining time.Duration) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
 	d.Metadata.DownloadPercent = percent
+	if remaining > 0 {
+		eta := time.Now().Add(remaining).UTC()
+		d.Metadata.DownloadETA = &eta
+	} else {
+		d.Metadata.DownloadETA = nil
+	}
 
 	// A download rate of +Inf is possible if the download completes near instantaneously. Since
 	// +Inf (or -Inf or NaN) are not indexable into Elasticsearch or generally informative since the
@@ -133,6 +155,16 @@ func (d *Details) SetDownloadProgress(percent, rateBytesPerSecond float64) {
 	d.notifyObservers()
 }
 
+// EstimateRemaining returns the time left to download the remaining bytes at the
+// given rate, or zero when it can't be estimated.
+func EstimateRemaining(downloadedBytes, totalBytes, bytesPerSecond float64) time.Duration {
+	if totalBytes <= 0 || downloadedBytes >= totalBytes || bytesPerSecond <= 0 ||
+		math.IsInf(bytesPerSecond, 0) || math.IsNaN(bytesPerSecond) {
+		return 0
+	}
+	return time.Duration((totalBytes - downloadedBytes) / bytesPerSecond * float64(time.Second))
+}
+
 // SetRetryableError sets the RetryErrorMsg metadata field.
 func (d *Details) SetRetryableError(retryableError error) {
 	d.mu.Lock()
@@ -146,6 +178,15 @@ func (d *Details) SetRetryableError(retryableError error) {
 	d.notifyObservers()
 }
 
+// SetDownloadAttempt sets the DownloadAttempt metadata field.
+func (d *Details) SetDownloadAttempt(attempt int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Metadata.DownloadAttempt = attempt
+	d.notifyObservers()
+}
+
 // SetRetryUntil sets the RetryUntil metadata field.
 func (d *Details) SetRetryUntil(retryUntil *time.Time) {
 	d.mu.Lock()
@@ -155,6 +196,16 @@ func (d *Details) SetRetryUntil(retryUntil *time.Time) {
 	d.notifyObservers()
 }
 
+// SetHealthGate sets the HealthGate and HealthGateUntil metadata fields.
+func (d *Details) SetHealthGate(state HealthGateState, until *time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.Metadata.HealthGate = state
+	d.Metadata.HealthGateUntil = until
+	d.notifyObservers()
+}
+
 // Fail is a convenience method to set the state of the upgrade
 // to StateFailed, set metadata associated with the failure, and
 // notify all observers.
@@ -230,9 +281,13 @@ func (m Metadata) Equals(otherM Metadata) bool {
 		m.ErrorMsg == otherM.ErrorMsg &&
 		m.DownloadPercent == otherM.DownloadPercent &&
 		m.DownloadRate == otherM.DownloadRate &&
+		equalTimePointers(m.DownloadETA, otherM.DownloadETA) &&
 		equalTimePointers(m.RetryUntil, otherM.RetryUntil) &&
 		m.RetryErrorMsg == otherM.RetryErrorMsg &&
-		m.Reason == otherM.Reason
+		m.DownloadAttempt == otherM.DownloadAttempt &&
+		m.Reason == otherM.Reason &&
+		m.HealthGate == otherM.HealthGate &&
+		equalTimePointers(m.HealthGateUntil, otherM.HealthGateUntil)
 }
 
 func equalTimePointers(t, otherT *time.Time) bool {
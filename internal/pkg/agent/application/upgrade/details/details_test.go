@@ -40,6 +40,23 @@ func TestDetailsSetStateWithReason(t *testing.T) {
 	assert.Equal(t, ReasonWatchFailed, det.Metadata.Reason)
 }
 
+func TestDetailsSetHealthGate(t *testing.T) {
+	det := NewDetails("99.999.9999", StateWatching, "test_action_id")
+
+	var observed *Details
+	det.RegisterObserver(func(d *Details) { observed = d })
+
+	until := time.Now().Add(5 * time.Minute)
+	det.SetHealthGate(HealthGatePending, &until)
+	require.NotNil(t, observed)
+	assert.Equal(t, HealthGatePending, observed.Metadata.HealthGate)
+	assert.Equal(t, &until, observed.Metadata.HealthGateUntil)
+
+	det.SetHealthGate(HealthGatePassed, &until)
+	assert.Equal(t, HealthGatePassed, observed.Metadata.HealthGate)
+	assert.False(t, det.Metadata.Equals(Metadata{}), "metadata with a health gate should not equal empty metadata")
+}
+
 func TestDetailsFail(t *testing.T) {
 	det := NewDetails("99.999.9999", StateRequested, "test_action_id")
 	require.Equal(t, StateRequested, det.State)
@@ -95,7 +112,7 @@ func TestDetailsDownloadRateJSON(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			det.SetDownloadProgress(tc.percent, tc.rate_in)
+			det.SetDownloadProgress(tc.percent, tc.rate_in, 0)
 
 			data, err := json.Marshal(det)
 			require.NoError(t, err)
@@ -109,16 +126,36 @@ func TestDetailsDownloadRateJSON(t *testing.T) {
 	}
 }
 
+func TestDetailsDownloadETA(t *testing.T) {
+	det := NewDetails("99.999.9999", StateDownloading, "test_action_id")
+
+	before := time.Now()
+	det.SetDownloadProgress(0.5, 1024, time.Minute)
+	require.NotNil(t, det.Metadata.DownloadETA)
+	assert.WithinRange(t, *det.Metadata.DownloadETA, before.Add(time.Minute), time.Now().Add(time.Minute))
+
+	det.SetDownloadProgress(1, 1024, 0)
+	assert.Nil(t, det.Metadata.DownloadETA)
+}
+
+func TestEstimateRemaining(t *testing.T) {
+	assert.Equal(t, 2*time.Second, EstimateRemaining(1024, 3072, 1024))
+	assert.Zero(t, EstimateRemaining(1024, 0, 1024), "unknown size")
+	assert.Zero(t, EstimateRemaining(1024, 1024, 1024), "completed")
+	assert.Zero(t, EstimateRemaining(1024, 3072, 0), "no progress")
+	assert.Zero(t, EstimateRemaining(1024, 3072, math.Inf(1)), "infinite rate")
+}
+
 func TestEquals(t *testing.T) {
 	details1 := NewDetails("8.12.0", StateDownloading, "foobar")
-	details1.SetDownloadProgress(0.1234, 34.56)
+	details1.SetDownloadProgress(0.1234, 34.56, 0)
 	details1.SetRetryableError(errors.New("retryable error"))
 	retryUntil1 := time.Date(2023, 11, 29, 11, 00, 32, 0, time.UTC)
 	details1.SetRetryUntil(&retryUntil1)
 	details1.Fail(errors.New("download failed"))
 
 	details2 := NewDetails("8.12.0", StateDownloading, "foobar")
-	details2.SetDownloadProgress(0.1234, 34.56)
+	details2.SetDownloadProgress(0.1234, 34.56, 0)
 	details2.SetRetryableError(errors.New("retryable error"))
 	retryUntil2 := time.Date(2023, 11, 29, 11, 00, 32, 0, time.UTC)
 	details2.SetRetryUntil(&retryUntil2)
@@ -22,5 +22,18 @@ const (
 
 	// List of well-known reasons for state transitions
 	ReasonWatchFailed           = "watch failed"
+	ReasonHealthGateFailed      = "health gate failed"
 	ReasonManualRollbackPattern = "manual rollback requested to version %s"
 )
+
+// HealthGateState is the state of the health gate of an upgrade.
+type HealthGateState string
+
+const (
+	// HealthGatePending is set until the upgraded Agent reports healthy.
+	HealthGatePending HealthGateState = "PENDING"
+	// HealthGatePassed is set once the upgraded Agent reported healthy, the upgrade is committed.
+	HealthGatePassed HealthGateState = "PASSED"
+	// HealthGateFailed is set when the upgraded Agent didn't report healthy in time, the upgrade is rolled back.
+	HealthGateFailed HealthGateState = "FAILED"
+)
@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/go-units"
+
+	upgradeErrors "github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// packageRatio is the ratio between the size of an unpacked agent and the size of its package, the actual ratio
+// is higher, the package size is overestimated.
+const packageRatio = 2
+
+type freeDiskSpaceFunc func(path string) (uint64, error)
+
+// checkDiskSpace checks that the space required to upgrade is available, it is estimated from the size of the
+// running agent: the package is downloaded in the downloads directory, unpacked next to the current home, and
+// the run directory is copied to the new home. The reserve is left free once the upgrade is complete. The size of
+// the reclaimable paths, removed once the new package is verified, is counted as available.
+func checkDiskSpace(log *logger.Logger, free freeDiskSpaceFunc, topDir, home, downloadsDir string, reserve uint64, reclaimable []string) error {
+	runDir := filepath.Join(home, "run")
+	homeSize, err := dirSize(home)
+	if err != nil {
+		return fmt.Errorf("failed to compute the size of %q: %w", home, err)
+	}
+	runSize, err := dirSize(runDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to compute the size of %q: %w", runDir, err)
+	}
+	unpackSize := homeSize - runSize
+	packageSize := unpackSize / packageRatio
+
+	dataDir := filepath.Dir(home)
+	required := map[string]uint64{dataDir: unpackSize + runSize + reserve}
+	if isWithin(topDir, downloadsDir) {
+		required[dataDir] += packageSize
+	} else {
+		required[downloadsDir] = packageSize + reserve
+	}
+
+	reclaimed := make(map[string]uint64)
+	for _, path := range reclaimable {
+		size, err := dirSize(path)
+		if err != nil {
+			log.Debugf("not counting the size of %q as available: %v", path, err)
+			continue
+		}
+		if isWithin(topDir, path) {
+			reclaimed[dataDir] += size
+		} else {
+			reclaimed[downloadsDir] += size
+		}
+	}
+
+	for dir, size := range required {
+		available, err := free(existingParent(dir))
+		if err != nil {
+			return fmt.Errorf("failed to get the free disk space of %q: %w", dir, err)
+		}
+		available += reclaimed[dir]
+		log.Debugf("upgrade requires %s of free disk space in %s, %s available", units.HumanSize(float64(size)), dir, units.HumanSize(float64(available)))
+		if available < size {
+			return fmt.Errorf("%w: upgrading requires %s of free disk space in %s, only %s available",
+				upgradeErrors.ErrInsufficientDiskSpace, units.HumanSize(float64(size)), dir, units.HumanSize(float64(available)))
+		}
+	}
+	return nil
+}
+
+// dirSize returns the size of the files of the directory.
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += uint64(info.Size())
+		return nil
+	})
+	return size, err
+}
+
+// isWithin returns true when the path is the directory or one of its descendants.
+func isWithin(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// existingParent returns the path, or its closest parent that exists.
+func existingParent(path string) string {
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package upgrade
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// freeDiskSpace returns the disk space available to the agent on the filesystem of the path.
+func freeDiskSpace(path string) (uint64, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	//nolint:unconvert // the types of the fields differ between platforms
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
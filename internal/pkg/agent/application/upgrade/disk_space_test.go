@@ -0,0 +1,74 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	upgradeErrors "github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/errors"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	log, _ := loggertest.New("disk_space")
+
+	topDir := t.TempDir()
+	home := filepath.Join(topDir, "data", "elastic-agent-1.2.3-abcdef")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "run", "component"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "elastic-agent"), make([]byte, 1000), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "run", "component", "state"), make([]byte, 100), 0o600))
+
+	freeSpace := func(available map[string]uint64) freeDiskSpaceFunc {
+		return func(path string) (uint64, error) {
+			return available[path], nil
+		}
+	}
+
+	t.Run("downloads within the top directory", func(t *testing.T) {
+		downloads := filepath.Join(home, "downloads")
+		// unpack 1000 + run copy 100 + package 500 + reserve 10
+		dataDir := filepath.Dir(home)
+		assert.NoError(t, checkDiskSpace(log, freeSpace(map[string]uint64{dataDir: 1610}), topDir, home, downloads, 10, nil))
+
+		err := checkDiskSpace(log, freeSpace(map[string]uint64{dataDir: 1609}), topDir, home, downloads, 10, nil)
+		assert.ErrorIs(t, err, upgradeErrors.ErrInsufficientDiskSpace)
+		assert.ErrorContains(t, err, dataDir)
+	})
+
+	t.Run("downloads on another filesystem", func(t *testing.T) {
+		downloads := filepath.Join(t.TempDir(), "downloads")
+		dataDir := filepath.Dir(home)
+		available := map[string]uint64{dataDir: 1110, filepath.Dir(downloads): 510}
+		assert.NoError(t, checkDiskSpace(log, freeSpace(available), topDir, home, downloads, 10, nil))
+
+		available[filepath.Dir(downloads)] = 509
+		err := checkDiskSpace(log, freeSpace(available), topDir, home, downloads, 10, nil)
+		assert.ErrorIs(t, err, upgradeErrors.ErrInsufficientDiskSpace)
+		assert.ErrorContains(t, err, downloads)
+	})
+
+	t.Run("reclaimable versions counted as available", func(t *testing.T) {
+		downloads := filepath.Join(home, "downloads")
+		dataDir := filepath.Dir(home)
+		oldHome := filepath.Join(dataDir, "elastic-agent-1.2.0-old")
+		require.NoError(t, os.MkdirAll(oldHome, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(oldHome, "elastic-agent"), make([]byte, 300), 0o600))
+		assert.NoError(t, checkDiskSpace(log, freeSpace(map[string]uint64{dataDir: 1310}), topDir, home, downloads, 10, []string{oldHome}))
+
+		err := checkDiskSpace(log, freeSpace(map[string]uint64{dataDir: 1309}), topDir, home, downloads, 10, []string{oldHome})
+		assert.ErrorIs(t, err, upgradeErrors.ErrInsufficientDiskSpace)
+	})
+
+	t.Run("free disk space of the filesystem", func(t *testing.T) {
+		available, err := freeDiskSpace(topDir)
+		require.NoError(t, err)
+		assert.NotZero(t, available)
+	})
+}
@@ -0,0 +1,55 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+// ErrIncompatibleDowngrade is returned when the version downgraded to can't read the data of the running version.
+var ErrIncompatibleDowngrade = errors.New("downgraded version cannot read the agent data")
+
+// DowngradeInfo records, in the update marker, an upgrade to a lower version than the running one.
+type DowngradeInfo struct {
+	// StateStoreVersion is the version of the state store format kept across the downgrade.
+	StateStoreVersion string `json:"state_store_version" yaml:"state_store_version"`
+	// Warnings are the data compatibility issues that didn't prevent the downgrade.
+	Warnings []string `json:"warnings,omitempty" yaml:"warnings,omitempty"`
+}
+
+// checkDowngrade returns the record of the downgrade when newVersion is lower than currentVersion, nil otherwise.
+// The state store is kept across upgrades, so the new version must be able to read it. An error wrapping
+// ErrIncompatibleDowngrade is returned when the package declares it reads another state store version, as no
+// migration to older formats exists. A warning is recorded when the package predates the declaration.
+func checkDowngrade(log *logger.Logger, currentVersion, newVersion *agtversion.ParsedSemVer, metadata packageMetadata) (*DowngradeInfo, error) {
+	if currentVersion == nil || newVersion == nil || !newVersion.Less(*currentVersion) {
+		return nil, nil
+	}
+
+	info := &DowngradeInfo{StateStoreVersion: store.Version}
+	var supported string
+	if metadata.manifest != nil {
+		supported = metadata.manifest.Package.StateStoreVersion
+	}
+	switch supported {
+	case store.Version:
+	case "":
+		info.Warnings = append(info.Warnings, fmt.Sprintf(
+			"version %s does not declare the state store version it reads, state store version %s is kept as is",
+			newVersion, store.Version))
+	default:
+		return nil, fmt.Errorf("%w: version %s reads state store version %s, the state store is version %s",
+			ErrIncompatibleDowngrade, newVersion, supported, store.Version)
+	}
+
+	log.Warnw("Downgrading agent", "version", newVersion.String(), "prev_version", currentVersion.String(),
+		"state_store_version", info.StateStoreVersion, "warnings", info.Warnings)
+	return info, nil
+}
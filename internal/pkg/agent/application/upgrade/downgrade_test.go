@@ -0,0 +1,77 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/storage/store"
+	v1 "github.com/elastic/elastic-agent/pkg/api/v1"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	agtversion "github.com/elastic/elastic-agent/pkg/version"
+)
+
+func TestCheckDowngrade(t *testing.T) {
+	current := agtversion.NewParsedSemVer(9, 3, 0, "", "")
+	lower := agtversion.NewParsedSemVer(9, 2, 1, "", "")
+	higher := agtversion.NewParsedSemVer(9, 4, 0, "", "")
+
+	withStateStore := func(version string) packageMetadata {
+		return packageMetadata{manifest: &v1.PackageManifest{Package: v1.PackageDesc{StateStoreVersion: version}}}
+	}
+
+	testcases := map[string]struct {
+		newVersion *agtversion.ParsedSemVer
+		metadata   packageMetadata
+		expected   *DowngradeInfo
+		err        error
+	}{
+		"upgrade": {
+			newVersion: higher,
+			metadata:   withStateStore("0"),
+		},
+		"compatible downgrade": {
+			newVersion: lower,
+			metadata:   withStateStore(store.Version),
+			expected:   &DowngradeInfo{StateStoreVersion: store.Version},
+		},
+		"downgrade to a package not declaring its state store version": {
+			newVersion: lower,
+			metadata:   withStateStore(""),
+			expected: &DowngradeInfo{
+				StateStoreVersion: store.Version,
+				Warnings:          []string{"version 9.2.1 does not declare the state store version it reads, state store version " + store.Version + " is kept as is"},
+			},
+		},
+		"downgrade to a package without manifest": {
+			newVersion: lower,
+			expected: &DowngradeInfo{
+				StateStoreVersion: store.Version,
+				Warnings:          []string{"version 9.2.1 does not declare the state store version it reads, state store version " + store.Version + " is kept as is"},
+			},
+		},
+		"incompatible downgrade": {
+			newVersion: lower,
+			metadata:   withStateStore("0"),
+			err:        ErrIncompatibleDowngrade,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			log, _ := loggertest.New(t.Name())
+			info, err := checkDowngrade(log, current, tc.newVersion, tc.metadata)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, info)
+		})
+	}
+}
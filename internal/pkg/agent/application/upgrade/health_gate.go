@@ -0,0 +1,90 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// healthGateCheckInterval is the period between two checks of the health of the upgraded Agent.
+const healthGateCheckInterval = 5 * time.Second
+
+// ErrHealthGateFailed is returned when the upgraded Agent didn't report healthy before the health gate deadline.
+var ErrHealthGateFailed = errors.New("upgraded agent did not report healthy before the health gate deadline")
+
+// WaitForHealthGate waits until the upgraded Agent, running the commit hash, reports healthy with all its components
+// healthy and, when managed, its last Fleet checkin successful. ErrHealthGateFailed is returned, along with the last
+// reason the Agent was not healthy, if that doesn't happen before until.
+func WaitForHealthGate(ctx context.Context, log *logger.Logger, c client.Client, hash string, until time.Time) error {
+	return waitForHealthGate(ctx, log, c, hash, until, healthGateCheckInterval)
+}
+
+func waitForHealthGate(ctx context.Context, log *logger.Logger, c client.Client, hash string, until time.Time, interval time.Duration) error {
+	gateCtx, cancel := context.WithDeadline(ctx, until)
+	defer cancel()
+
+	log.Infof("Waiting up to %s for the upgraded agent to pass the health gate", time.Until(until).Round(time.Second))
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	var lastErr error
+	for {
+		lastErr = checkHealthGate(gateCtx, c, hash)
+		if lastErr == nil {
+			log.Info("Upgraded agent passed the health gate")
+			return nil
+		}
+		log.Debugf("Upgraded agent not healthy yet: %s", lastErr)
+
+		select {
+		case <-gateCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("%w: %w", ErrHealthGateFailed, lastErr)
+		case <-t.C:
+		}
+	}
+}
+
+func checkHealthGate(ctx context.Context, c client.Client, hash string) error {
+	if err := c.Connect(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrCannotConnect, err)
+	}
+	defer c.Disconnect()
+
+	state, err := c.State(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get agent state: %w", err)
+	}
+	return isHealthy(state, hash)
+}
+
+// isHealthy returns why the state doesn't pass the health gate, nil when it does.
+func isHealthy(state *client.AgentState, hash string) error {
+	if !strings.HasPrefix(state.Info.Commit, hash) {
+		return fmt.Errorf("agent is running commit %q, not the upgraded commit %q", state.Info.Commit, hash)
+	}
+	if state.State != client.Healthy {
+		return fmt.Errorf("agent is %s: %s", state.State, state.Message)
+	}
+	for _, comp := range state.Components {
+		if comp.State != client.Healthy {
+			return fmt.Errorf("component %s is %s: %s", comp.ID, comp.State, comp.Message)
+		}
+	}
+	if state.Info.IsManaged && state.FleetState != client.Healthy {
+		return fmt.Errorf("fleet checkin is %s: %s", state.FleetState, state.FleetMessage)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	mocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func TestIsHealthy(t *testing.T) {
+	healthy := func() *client.AgentState {
+		return &client.AgentState{
+			Info:       client.AgentStateInfo{Commit: "abcdef0123456789", IsManaged: true},
+			State:      client.Healthy,
+			FleetState: client.Healthy,
+			Components: []client.ComponentState{
+				{ID: "filestream-default", State: client.Healthy},
+			},
+		}
+	}
+
+	testcases := map[string]struct {
+		modify  func(state *client.AgentState)
+		healthy bool
+	}{
+		"healthy": {
+			modify:  func(*client.AgentState) {},
+			healthy: true,
+		},
+		"previous version still running": {
+			modify: func(state *client.AgentState) { state.Info.Commit = "0123456789abcdef" },
+		},
+		"agent degraded": {
+			modify: func(state *client.AgentState) { state.State = client.Degraded },
+		},
+		"component starting": {
+			modify: func(state *client.AgentState) { state.Components[0].State = client.Starting },
+		},
+		"fleet checkin failed": {
+			modify: func(state *client.AgentState) { state.FleetState = client.Failed },
+		},
+		"fleet checkin ignored when standalone": {
+			modify: func(state *client.AgentState) {
+				state.Info.IsManaged = false
+				state.FleetState = client.Stopped
+			},
+			healthy: true,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			state := healthy()
+			tc.modify(state)
+			err := isHealthy(state, "abcdef")
+			if tc.healthy {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestWaitForHealthGate(t *testing.T) {
+	log, _ := loggertest.New(t.Name())
+
+	t.Run("passes once healthy", func(t *testing.T) {
+		mockClient := mocks.NewClient(t)
+		mockClient.EXPECT().Connect(mock.Anything).Return(nil)
+		mockClient.EXPECT().Disconnect().Return()
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{
+			Info:  client.AgentStateInfo{Commit: "abcdef0123456789"},
+			State: client.Starting,
+		}, nil).Once()
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{
+			Info:  client.AgentStateInfo{Commit: "abcdef0123456789"},
+			State: client.Healthy,
+		}, nil).Once()
+
+		err := waitForHealthGate(context.Background(), log, mockClient, "abcdef", time.Now().Add(time.Minute), time.Millisecond)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails at the deadline", func(t *testing.T) {
+		mockClient := mocks.NewClient(t)
+		mockClient.EXPECT().Connect(mock.Anything).Return(nil)
+		mockClient.EXPECT().Disconnect().Return()
+		mockClient.EXPECT().State(mock.Anything).Return(&client.AgentState{
+			Info:    client.AgentStateInfo{Commit: "abcdef0123456789"},
+			State:   client.Degraded,
+			Message: "output unavailable",
+		}, nil)
+
+		err := waitForHealthGate(context.Background(), log, mockClient, "abcdef", time.Now().Add(50*time.Millisecond), time.Millisecond)
+		require.ErrorIs(t, err, ErrHealthGateFailed)
+		assert.ErrorContains(t, err, "output unavailable")
+	})
+}
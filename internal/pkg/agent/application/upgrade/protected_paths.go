@@ -0,0 +1,26 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// unlockProtectedPaths unlocks the protected paths of a hardened install so the versioned homes can be removed,
+// the returned function locks them again.
+func unlockProtectedPaths(log *logger.Logger, topDirPath string) func() {
+	if !perms.IsHardened(topDirPath) {
+		return func() {}
+	}
+	if err := perms.Unlock(topDirPath); err != nil {
+		log.Warnw("Failed to unlock the protected paths", "error.message", err)
+	}
+	return func() {
+		if err := perms.Lock(topDirPath); err != nil {
+			log.Errorw("Failed to lock the protected paths", "error.message", err)
+		}
+	}
+}
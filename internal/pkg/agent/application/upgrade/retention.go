@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// versionedHomePrefix is the prefix of the versioned homes in the data directory.
+const versionedHomePrefix = agentName + "-"
+
+// downloadVersionRegexp matches the version in the filename of a downloaded package, its hash or its signature.
+var downloadVersionRegexp = regexp.MustCompile(`-(\d+\.\d+\.\d+(?:-SNAPSHOT)?)-`)
+
+// pruneVersions keeps the given number of versions on disk, the running one included. The most recently
+// modified versioned homes and downloads are kept and the older ones removed, except the versioned homes
+// referenced by the update marker that can still be rolled back to and the downloads of keepVersions.
+func pruneVersions(log *logger.Logger, topDir, home, downloadsDir string, keepVersions []string, versions int) error {
+	homes, downloads, err := versionsBeyondRetention(topDir, home, downloadsDir, keepVersions, versions)
+	var errs []error
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(homes) > 0 {
+		relock := unlockProtectedPaths(log, topDir)
+		defer relock()
+	}
+	for _, oldHome := range homes {
+		log.Infow("Removing versioned home beyond the retained versions", "versioned_home", oldHome, "versions", versions)
+		if err := os.RemoveAll(oldHome); err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove versioned home %q: %w", oldHome, err))
+		}
+	}
+	for _, file := range downloads {
+		log.Infow("Removing download beyond the retained versions", "file", file, "versions", versions)
+		if err := os.Remove(file); err != nil {
+			errs = append(errs, fmt.Errorf("unable to remove file %q: %w", file, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// versionsBeyondRetention returns the versioned homes and the downloads pruneVersions removes.
+func versionsBeyondRetention(topDir, home, downloadsDir string, keepVersions []string, versions int) ([]string, []string, error) {
+	if versions <= 0 {
+		return nil, nil, nil
+	}
+	homes, homesErr := versionedHomesBeyondRetention(topDir, home, versions)
+	downloads, downloadsErr := downloadsBeyondRetention(downloadsDir, keepVersions, versions)
+	return homes, downloads, errors.Join(homesErr, downloadsErr)
+}
+
+func versionedHomesBeyondRetention(topDir, home string, versions int) ([]string, error) {
+	dataDir := paths.DataFrom(topDir)
+	if filepath.Clean(filepath.Dir(home)) != filepath.Clean(dataDir) {
+		// unversioned home, there are no other versions
+		return nil, nil
+	}
+
+	keep := map[string]struct{}{filepath.Base(home): {}}
+	marker, err := LoadMarker(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the update marker: %w", err)
+	}
+	if marker != nil {
+		referenced := []string{marker.VersionedHome, marker.PrevVersionedHome}
+		for _, rollback := range marker.RollbacksAvailable {
+			referenced = append(referenced, rollback.Home)
+		}
+		for _, h := range referenced {
+			if h != "" {
+				keep[filepath.Base(h)] = struct{}{}
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(dataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the data directory %q: %w", dataDir, err)
+	}
+	var homes []os.DirEntry
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), versionedHomePrefix) {
+			continue
+		}
+		if _, ok := keep[entry.Name()]; ok {
+			continue
+		}
+		homes = append(homes, entry)
+	}
+
+	var beyond []string
+	for i, entry := range sortByModTime(homes) {
+		// the running version and the referenced ones are always kept
+		if i+len(keep) < versions {
+			continue
+		}
+		beyond = append(beyond, filepath.Join(dataDir, entry.Name()))
+	}
+	return beyond, nil
+}
+
+func downloadsBeyondRetention(downloadsDir string, keepVersions []string, versions int) ([]string, error) {
+	entries, err := os.ReadDir(downloadsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read directory %q: %w", downloadsDir, err)
+	}
+
+	byVersion := make(map[string][]string)
+	var latest []os.DirEntry
+	for _, entry := range sortByModTime(entries) {
+		if entry.IsDir() {
+			continue
+		}
+		match := downloadVersionRegexp.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		if _, ok := byVersion[match[1]]; !ok {
+			latest = append(latest, entry)
+		}
+		byVersion[match[1]] = append(byVersion[match[1]], entry.Name())
+	}
+
+	keep := make(map[string]struct{}, len(keepVersions))
+	for _, version := range keepVersions {
+		keep[version] = struct{}{}
+	}
+	kept := len(keep)
+	var beyond []string
+	for _, entry := range latest {
+		version := downloadVersionRegexp.FindStringSubmatch(entry.Name())[1]
+		if _, ok := keep[version]; ok {
+			continue
+		}
+		if kept < versions {
+			kept++
+			continue
+		}
+		for _, name := range byVersion[version] {
+			beyond = append(beyond, filepath.Join(downloadsDir, name))
+		}
+	}
+	return beyond, nil
+}
+
+// sortByModTime sorts the entries from the most recently modified to the least, entries that can't be
+// stat-ed are dropped.
+func sortByModTime(entries []os.DirEntry) []os.DirEntry {
+	modTimes := make(map[string]time.Time, len(entries))
+	sorted := make([]os.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		modTimes[entry.Name()] = info.ModTime()
+		sorted = append(sorted, entry)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return modTimes[sorted[i].Name()].After(modTimes[sorted[j].Name()])
+	})
+	return sorted
+}
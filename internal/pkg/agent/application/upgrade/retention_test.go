@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package upgrade
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestPruneVersions(t *testing.T) {
+	log, _ := loggertest.New("retention")
+	now := time.Now()
+
+	topDir := t.TempDir()
+	dataDir := paths.DataFrom(topDir)
+	downloads := filepath.Join(t.TempDir(), "downloads")
+	require.NoError(t, os.MkdirAll(downloads, 0o755))
+
+	// versioned homes and downloads, from the newest to the oldest
+	homes := []string{"elastic-agent-1.2.3-current", "elastic-agent-1.2.2-newest", "elastic-agent-1.2.1-rollback", "elastic-agent-1.2.0-oldest"}
+	for i, home := range homes {
+		dir := filepath.Join(dataDir, home)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		modTime := now.Add(-time.Duration(i) * time.Hour)
+		require.NoError(t, os.Chtimes(dir, modTime, modTime))
+	}
+	for i, version := range []string{"1.2.3", "1.2.2", "1.2.1", "1.2.0"} {
+		for _, suffix := range []string{".tar.gz", ".tar.gz.sha512"} {
+			file := filepath.Join(downloads, "elastic-agent-"+version+"-linux-x86_64"+suffix)
+			require.NoError(t, os.WriteFile(file, []byte(version), 0o600))
+			modTime := now.Add(-time.Duration(i) * time.Hour)
+			require.NoError(t, os.Chtimes(file, modTime, modTime))
+		}
+	}
+	require.NoError(t, os.WriteFile(markerFilePath(dataDir), []byte(`
+version: 1.2.3
+rollbacks_available:
+  - version: 1.2.1
+    home: data/elastic-agent-1.2.1-rollback
+    valid_until: 2999-01-01T00:00:00Z
+`), 0o600))
+
+	current := filepath.Join(dataDir, homes[0])
+	require.NoError(t, pruneVersions(log, topDir, current, downloads, []string{"1.2.3"}, 0))
+	for _, home := range homes {
+		assert.DirExists(t, filepath.Join(dataDir, home))
+	}
+
+	// the download of the version upgraded to is kept on top of the retained versions
+	require.NoError(t, pruneVersions(log, topDir, current, downloads, []string{"1.2.3", "1.2.0"}, 2))
+	assert.DirExists(t, current)
+	assert.DirExists(t, filepath.Join(dataDir, "elastic-agent-1.2.1-rollback"))
+	assert.NoDirExists(t, filepath.Join(dataDir, "elastic-agent-1.2.2-newest"))
+	assert.NoDirExists(t, filepath.Join(dataDir, "elastic-agent-1.2.0-oldest"))
+
+	entries, err := os.ReadDir(downloads)
+	require.NoError(t, err)
+	var remaining []string
+	for _, entry := range entries {
+		remaining = append(remaining, entry.Name())
+	}
+	assert.ElementsMatch(t, []string{
+		"elastic-agent-1.2.3-linux-x86_64.tar.gz",
+		"elastic-agent-1.2.3-linux-x86_64.tar.gz.sha512",
+		"elastic-agent-1.2.0-linux-x86_64.tar.gz",
+		"elastic-agent-1.2.0-linux-x86_64.tar.gz.sha512",
+	}, remaining)
+}
@@ -177,6 +177,9 @@ func cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash s
 		currentDir = fmt.Sprintf("%s-%s", agentName, currentHash)
 	}
 
+	relock := unlockProtectedPaths(log, topDirPath)
+	defer relock()
+
 	var errs []error
 	for _, dir := range subdirs {
 		if dir == currentDir {
@@ -281,7 +284,8 @@ func restartAgent(ctx context.Context, log *logger.Logger, c client.Client) erro
 
 	restartViaServiceFn := func(ctx context.Context) error {
 		topPath := paths.Top()
-		err := install.RestartService(topPath)
+		// the watcher has no uninstall token, it restarts the rolled back agent even when it is tamper protected
+		err := install.RestartServiceAfterRollback(topPath)
 		if err != nil {
 			return fmt.Errorf("failed to restart agent via service: %w", err)
 		}
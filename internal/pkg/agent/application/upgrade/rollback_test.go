@@ -725,6 +725,6 @@ func createUpdateMarker(t *testing.T, log *logger.Logger, topDir, newAgentVersio
 		time.Now(),
 		newAgentInstall,
 		oldAgentInstall,
-		nil, nil, disableRollbackWindow)
+		nil, nil, disableRollbackWindow, nil)
 	require.NoError(t, err, "error writing fake update marker")
 }
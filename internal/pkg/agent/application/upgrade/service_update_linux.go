@@ -56,15 +56,27 @@ func ensureSystemdServiceConfigUpToDate(unitFilePath string) (bool, error) {
 		return false, fmt.Errorf("error opening systemd unit file [%s]: %w", unitFilePath, err)
 	}
 
-	// Check if KillMode= is already present
-	if cfg.Section("Service").HasKey("KillMode") {
+	updated := false
+
+	// If KillMode= is not present, add it and set it to "process"
+	// See https://github.com/elastic/elastic-agent/pull/3220
+	if !cfg.Section("Service").HasKey("KillMode") {
+		cfg.Section("Service").Key("KillMode").SetValue("process")
+		updated = true
+	}
+
+	// If Delegate= is not present, add it so systemd leaves the cgroups of the components with a resource
+	// budget alone
+	if !cfg.Section("Service").HasKey("Delegate") {
+		cfg.Section("Service").Key("Delegate").SetValue("yes")
+		updated = true
+	}
+
+	if !updated {
 		// Nothing more to do
 		return false, nil
 	}
 
-	// If KillMode= is not present, add it and set it to "process"
-	// See https://github.com/elastic/elastic-agent/pull/3220
-	cfg.Section("Service").Key("KillMode").SetValue("process")
 	if err := cfg.SaveTo(unitFilePath); err != nil {
 		return false, fmt.Errorf("error writing updated systemd unit file [%s]: %w", unitFilePath, err)
 	}
@@ -30,6 +30,7 @@ StartLimitBurst=10
 ExecStart=/usr/bin/elastic-agent
 WorkingDirectory=/opt/Elastic/Agent
 KillMode=process
+Delegate=yes
 Restart=always
 RestartSec=120
 EnvironmentFile=-/etc/sysconfig/elastic-agent
@@ -41,11 +42,13 @@ WantedBy=multi-user.target
 		unitFileInitialContents string
 		expectedUpdated         bool
 		expectedKillMode        string
+		expectedDelegate        string
 	}{
 		"killmode_process_exists": {
 			unitFileInitialContents: unitFileExpectedContents,
 			expectedUpdated:         false,
 			expectedKillMode:        "process",
+			expectedDelegate:        "yes",
 		},
 		"killmode_process_missing": {
 			unitFileInitialContents: `
@@ -67,6 +70,7 @@ WantedBy=multi-user.target
 `,
 			expectedUpdated:  true,
 			expectedKillMode: "process",
+			expectedDelegate: "yes",
 		},
 		"killmode_different": {
 			unitFileInitialContents: `
@@ -83,12 +87,37 @@ Restart=always
 RestartSec=120
 EnvironmentFile=-/etc/sysconfig/elastic-agent
 KillMode=control-group
+Delegate=yes
 
 [Install]
 WantedBy=multi-user.target
 `,
 			expectedUpdated:  false,
 			expectedKillMode: "control-group",
+			expectedDelegate: "yes",
+		},
+		"delegate_missing": {
+			unitFileInitialContents: `
+[Unit]
+Description=Elastic Agent is a unified agent to observe, monitor and protect your system.
+ConditionFileIsExecutable=/usr/bin/elastic-agent
+
+[Service]
+StartLimitInterval=5
+StartLimitBurst=10
+ExecStart=/usr/bin/elastic-agent
+WorkingDirectory=/opt/Elastic/Agent
+KillMode=process
+Restart=always
+RestartSec=120
+EnvironmentFile=-/etc/sysconfig/elastic-agent
+
+[Install]
+WantedBy=multi-user.target
+`,
+			expectedUpdated:  true,
+			expectedKillMode: "process",
+			expectedDelegate: "yes",
 		},
 	}
 
@@ -105,6 +134,7 @@ WantedBy=multi-user.target
 			cfg, err := ini.Load(unitFilePath)
 			require.NoError(t, err)
 			require.Equal(t, test.expectedKillMode, cfg.Section("Service").Key("KillMode").Value())
+			require.Equal(t, test.expectedDelegate, cfg.Section("Service").Key("Delegate").Value())
 		})
 	}
 }
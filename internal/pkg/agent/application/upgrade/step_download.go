@@ -22,8 +22,8 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/composed"
 	downloadErrors "github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/fs"
-	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/http"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/localremote"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/sigstore"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/snapshot"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -89,7 +89,11 @@ func (a *artifactDownloader) downloadArtifact(ctx context.Context, parsedVersion
 			}
 
 			// set specific verifier, local file verifies locally only
-			verifier, err = fs.NewVerifier(a.log, &settings, release.PGP())
+			if settings.Verification.Mode == artifact.VerificationModeSigstore {
+				verifier, err = sigstore.NewVerifier(a.log, &settings)
+			} else {
+				verifier, err = fs.NewVerifier(a.log, &settings, release.PGP())
+			}
 			if err != nil {
 				return "", errors.New(err, "initiating verifier")
 			}
@@ -179,15 +183,20 @@ func newDownloader(version *agtversion.ParsedSemVer, log *logger.Logger, setting
 		return nil, err
 	}
 
-	httpDownloader, err := http.NewDownloader(log, settings, upgradeDetails)
+	remoteDownloader, err := localremote.NewRemoteDownloader(log, settings, upgradeDetails)
 	if err != nil {
 		return nil, err
 	}
 
-	return composed.NewDownloader(fs.NewDownloader(settings), snapDownloader, httpDownloader), nil
+	return composed.NewDownloader(fs.NewDownloader(settings), snapDownloader, remoteDownloader), nil
 }
 
 func newVerifier(version *agtversion.ParsedSemVer, log *logger.Logger, settings *artifact.Config) (download.Verifier, error) {
+	// the sigstore bundle is looked up next to the artifact wherever it was downloaded from
+	if settings.Verification.Mode == artifact.VerificationModeSigstore {
+		return sigstore.NewVerifier(log, settings)
+	}
+
 	pgp := release.PGP()
 
 	if !version.IsSnapshot() {
@@ -204,7 +213,7 @@ func newVerifier(version *agtversion.ParsedSemVer, log *logger.Logger, settings
 		return nil, err
 	}
 
-	remoteVerifier, err := http.NewVerifier(log, settings, pgp)
+	remoteVerifier, err := localremote.NewRemoteVerifier(log, settings, pgp)
 	if err != nil {
 		return nil, err
 	}
@@ -246,23 +255,30 @@ func (a *artifactDownloader) downloadWithRetries(
 	cancelCtx, cancel := context.WithDeadline(ctx, cancelDeadline)
 	defer cancel()
 
-	upgradeDetails.SetRetryUntil(&cancelDeadline)
+	// retries stop at the end of the retry budget or at the download timeout, whichever comes first
+	retryUntil := cancelDeadline
+	if maxElapsed := settings.Retry.MaxElapsedTime; maxElapsed > 0 && maxElapsed < settings.Timeout {
+		retryUntil = time.Now().Add(maxElapsed)
+	}
+	upgradeDetails.SetRetryUntil(&retryUntil)
 
-	expBo := backoff.NewExponentialBackOff()
-	expBo.InitialInterval = settings.RetrySleepInitDuration
-	boCtx := backoff.WithContext(expBo, cancelCtx)
+	boCtx := backoff.WithContext(settings.Retry.BackOff(settings.RetrySleepInitDuration), cancelCtx)
 
 	var path string
 	var attempt uint
+	var attemptStarted time.Time
 
 	opFn := func() error {
 		attempt++
+		attemptStarted = time.Now()
+		upgradeDetails.SetDownloadAttempt(int(attempt))
 		a.log.Infof("download attempt %d", attempt)
 		var err error
 		path, err = a.downloadOnce(cancelCtx, factory, version, settings, upgradeDetails)
 		if err != nil {
-			if downloadErrors.IsDiskSpaceError(err) {
-				a.log.Infof("insufficient disk space error detected, stopping retries")
+			if !downloadErrors.IsRetryable(err) {
+				a.log.Errorw(fmt.Sprintf("download attempt %d failed with a fatal error, stopping retries: %s", attempt, err.Error()),
+					"download.attempt", attempt, "event.duration", time.Since(attemptStarted))
 				return backoff.Permanent(err)
 			}
 			return err
@@ -271,8 +287,8 @@ func (a *artifactDownloader) downloadWithRetries(
 	}
 
 	opFailureNotificationFn := func(err error, retryAfter time.Duration) {
-		a.log.Warnf("download attempt %d failed: %s; retrying in %s.",
-			attempt, err.Error(), retryAfter)
+		a.log.Warnw(fmt.Sprintf("download attempt %d failed: %s; retrying in %s.", attempt, err.Error(), retryAfter),
+			"download.attempt", attempt, "event.duration", time.Since(attemptStarted), "download.retry_in", retryAfter)
 		upgradeDetails.SetRetryableError(err)
 	}
 
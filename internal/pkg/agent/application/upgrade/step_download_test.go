@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -301,6 +302,61 @@ func TestDownloadWithRetries(t *testing.T) {
 
 		require.Empty(t, *upgradeDetailsRetryErrorMsg)
 	})
+
+	t.Run("fatal status stops retries", func(t *testing.T) {
+		obs.TakeAll()
+		numberOfAttempts := 0
+		statusErr := &downloadErrors.HTTPStatusError{URI: expectedDownloadPath, StatusCode: http.StatusNotFound}
+		mockDownloaderCtor := func(version *agtversion.ParsedSemVer, log *logger.Logger, settings *artifact.Config, upgradeDetails *details.Details) (download.Downloader, error) {
+			numberOfAttempts++
+			return &mockDownloader{"", statusErr}, nil
+		}
+
+		a := newArtifactDownloader(&settings, testLogger)
+
+		parsedVersion, err := agtversion.ParseVersion("8.9.0")
+		require.NoError(t, err)
+
+		upgradeDetails, _, _, upgradeDetailsRetryErrorMsg := mockUpgradeDetails(parsedVersion)
+
+		_, err = a.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &settings, upgradeDetails)
+		require.ErrorIs(t, err, statusErr)
+		require.Equal(t, 1, numberOfAttempts)
+		require.Empty(t, *upgradeDetailsRetryErrorMsg)
+
+		logs := obs.TakeAll()
+		require.Len(t, logs, 2)
+		require.Contains(t, logs[1].Message, "download attempt 1 failed with a fatal error, stopping retries")
+	})
+
+	t.Run("retry budget limits attempts", func(t *testing.T) {
+		testCaseSettings := settings
+		testCaseSettings.RetrySleepInitDuration = time.Millisecond
+		testCaseSettings.Retry = artifact.RetryConfig{MaxAttempts: 3, Jitter: 0.5}
+
+		numberOfAttempts := 0
+		mockDownloaderCtor := func(version *agtversion.ParsedSemVer, log *logger.Logger, settings *artifact.Config, upgradeDetails *details.Details) (download.Downloader, error) {
+			numberOfAttempts++
+			return &mockDownloader{"", &downloadErrors.HTTPStatusError{URI: expectedDownloadPath, StatusCode: http.StatusServiceUnavailable}}, nil
+		}
+
+		a := newArtifactDownloader(&testCaseSettings, testLogger)
+
+		parsedVersion, err := agtversion.ParseVersion("8.9.0")
+		require.NoError(t, err)
+
+		upgradeDetails, _, _, _ := mockUpgradeDetails(parsedVersion)
+
+		_, err = a.downloadWithRetries(context.Background(), mockDownloaderCtor, parsedVersion, &testCaseSettings, upgradeDetails)
+		require.ErrorContains(t, err, "returned unsuccessful status code: 503")
+		require.Equal(t, 3, numberOfAttempts)
+		require.Equal(t, 3, upgradeDetails.Metadata.DownloadAttempt)
+
+		logs := obs.TakeAll()
+		require.Len(t, logs, 5, "every retried attempt is logged with its failure")
+		require.Contains(t, logs[1].Message, "download attempt 1 failed")
+		require.EqualValues(t, 1, logs[1].ContextMap()["download.attempt"])
+	})
 }
 
 // mockUpgradeDetails returns a *details.Details value that has an observer registered on it for inspecting
@@ -56,6 +56,9 @@ type UpdateMarker struct {
 	Details *details.Details `json:"details,omitempty" yaml:"details,omitempty"`
 
 	RollbacksAvailable []RollbackAvailable `json:"rollbacks_available,omitempty" yaml:"rollbacks_available,omitempty"`
+
+	// Downgrade is set when the agent is upgraded to a lower version.
+	Downgrade *DowngradeInfo `json:"downgrade,omitempty" yaml:"downgrade,omitempty"`
 }
 
 // GetActionID returns the Fleet Action ID associated with the
@@ -113,6 +116,7 @@ type updateMarkerSerializer struct {
 	Action             *MarkerActionUpgrade `yaml:"action"`
 	Details            *details.Details     `yaml:"details"`
 	RollbacksAvailable []RollbackAvailable  `yaml:"rollbacks_available,omitempty"`
+	Downgrade          *DowngradeInfo       `yaml:"downgrade,omitempty"`
 }
 
 func newMarkerSerializer(m *UpdateMarker) *updateMarkerSerializer {
@@ -128,6 +132,7 @@ func newMarkerSerializer(m *UpdateMarker) *updateMarkerSerializer {
 		Action:             convertToMarkerAction(m.Action),
 		Details:            m.Details,
 		RollbacksAvailable: m.RollbacksAvailable,
+		Downgrade:          m.Downgrade,
 	}
 }
 
@@ -142,7 +147,7 @@ type updateActiveCommitFunc func(log *logger.Logger, topDirPath, hash string, wr
 
 // markUpgrade marks update happened so we can handle grace period
 func markUpgradeProvider(updateActiveCommit updateActiveCommitFunc, writeFile writeFileFunc) markUpgradeFunc {
-	return func(log *logger.Logger, dataDirPath string, updatedOn time.Time, agent, previousAgent agentInstall, action *fleetapi.ActionUpgrade, upgradeDetails *details.Details, rollbackWindow time.Duration) error {
+	return func(log *logger.Logger, dataDirPath string, updatedOn time.Time, agent, previousAgent agentInstall, action *fleetapi.ActionUpgrade, upgradeDetails *details.Details, rollbackWindow time.Duration, downgrade *DowngradeInfo) error {
 
 		if len(previousAgent.hash) > hashLen {
 			previousAgent.hash = previousAgent.hash[:hashLen]
@@ -158,6 +163,7 @@ func markUpgradeProvider(updateActiveCommit updateActiveCommitFunc, writeFile wr
 			PrevVersionedHome: previousAgent.versionedHome,
 			Action:            action,
 			Details:           upgradeDetails,
+			Downgrade:         downgrade,
 		}
 
 		if rollbackWindow > disableRollbackWindow && agent.parsedVersion != nil && !agent.parsedVersion.Less(*Version_9_2_0_SNAPSHOT) {
@@ -247,6 +253,7 @@ func loadMarker(markerFile string) (*UpdateMarker, error) {
 		Action:             convertToActionUpgrade(marker.Action),
 		Details:            marker.Details,
 		RollbacksAvailable: marker.RollbacksAvailable,
+		Downgrade:          marker.Downgrade,
 	}, nil
 }
 
@@ -270,6 +277,7 @@ func saveMarkerToPath(marker *UpdateMarker, markerFile string, shouldFsync bool)
 		Action:             convertToMarkerAction(marker.Action),
 		Details:            marker.Details,
 		RollbacksAvailable: marker.RollbacksAvailable,
+		Downgrade:          marker.Downgrade,
 	}
 	markerBytes, err := yaml.Marshal(makerSerializer)
 	if err != nil {
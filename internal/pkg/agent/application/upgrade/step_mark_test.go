@@ -94,6 +94,7 @@ func TestMarkUpgrade(t *testing.T) {
 		action         *fleetapi.ActionUpgrade
 		details        *details.Details
 		rollbackWindow time.Duration
+		downgrade      *DowngradeInfo
 	}
 	type workingDirHook func(t *testing.T, dataDir string)
 
@@ -277,6 +278,38 @@ func TestMarkUpgrade(t *testing.T) {
 				assert.Equal(t, expectedMarker, actualMarker)
 			},
 		},
+		{
+			name: "downgrade is recorded",
+			args: args{
+				updatedOn: updatedOnNow,
+				currentAgent: agentInstall{
+					parsedVersion: parsed123SNAPSHOT,
+					version:       "1.2.3-SNAPSHOT",
+					hash:          "newagt",
+					versionedHome: filepath.Join("data", "elastic-agent-1.2.3-SNAPSHOT-newagt"),
+				},
+				previousAgent: agentInstall{
+					parsedVersion: parsed456SNAPSHOT,
+					version:       "4.5.6-SNAPSHOT",
+					hash:          "prvagt",
+					versionedHome: filepath.Join("data", "elastic-agent-4.5.6-SNAPSHOT-prvagt"),
+				},
+				details: details.NewDetails("1.2.3-SNAPSHOT", details.StateReplacing, ""),
+				downgrade: &DowngradeInfo{
+					StateStoreVersion: "1",
+					Warnings:          []string{"version 1.2.3-SNAPSHOT does not declare the state store version it reads"},
+				},
+			},
+			wantErr: assert.NoError,
+			assertAfterMark: func(t *testing.T, dataDir string) {
+				actualMarker, err := LoadMarker(dataDir)
+				require.NoError(t, err, "error reading actualMarker content after writing")
+				assert.Equal(t, &DowngradeInfo{
+					StateStoreVersion: "1",
+					Warnings:          []string{"version 1.2.3-SNAPSHOT does not declare the state store version it reads"},
+				}, actualMarker.Downgrade)
+			},
+		},
 	}
 
 	// use the regular markUpgrade function, disabling the updateActiveCommitFunction that is bundled together
@@ -295,7 +328,7 @@ func TestMarkUpgrade(t *testing.T) {
 				tc.setupBeforeMark(t, dataDir)
 			}
 
-			err := markUpgrade(log, dataDir, tc.args.updatedOn, tc.args.currentAgent, tc.args.previousAgent, tc.args.action, tc.args.details, tc.args.rollbackWindow)
+			err := markUpgrade(log, dataDir, tc.args.updatedOn, tc.args.currentAgent, tc.args.previousAgent, tc.args.action, tc.args.details, tc.args.rollbackWindow, tc.args.downgrade)
 			tc.wantErr(t, err)
 			if tc.assertAfterMark != nil {
 				tc.assertAfterMark(t, dataDir)
@@ -89,7 +89,7 @@ type unpackHandler interface {
 type copyActionStoreFunc func(log *logger.Logger, newHome string) error
 type copyRunDirectoryFunc func(log *logger.Logger, oldRunPath, newRunPath string) error
 type fileDirCopyFunc func(from, to string, opts ...copy.Options) error
-type markUpgradeFunc func(log *logger.Logger, dataDirPath string, updatedOn time.Time, agent, previousAgent agentInstall, action *fleetapi.ActionUpgrade, upgradeDetails *details.Details, rollbackWindow time.Duration) error
+type markUpgradeFunc func(log *logger.Logger, dataDirPath string, updatedOn time.Time, agent, previousAgent agentInstall, action *fleetapi.ActionUpgrade, upgradeDetails *details.Details, rollbackWindow time.Duration, downgrade *DowngradeInfo) error
 type changeSymlinkFunc func(log *logger.Logger, topDirPath, symlinkPath, newTarget string) error
 type rollbackInstallFunc func(ctx context.Context, log *logger.Logger, topDirPath, versionedHome, oldVersionedHome string) error
 
@@ -130,6 +130,7 @@ type Upgrader struct {
 	artifactDownloader   artifactDownloadHandler
 	unpacker             unpackHandler
 	isDiskSpaceErrorFunc func(err error) bool
+	freeDiskSpace        freeDiskSpaceFunc
 	extractAgentVersion  func(metadata packageMetadata, upgradeVersion string) agentVersion
 	copyActionStore      copyActionStoreFunc
 	copyRunDirectory     copyRunDirectoryFunc
@@ -158,6 +159,7 @@ func NewUpgrader(log *logger.Logger, settings *artifact.Config, upgradeConfig *c
 		artifactDownloader:   newArtifactDownloader(settings, log),
 		unpacker:             newUnpacker(log),
 		isDiskSpaceErrorFunc: upgradeErrors.IsDiskSpaceError,
+		freeDiskSpace:        freeDiskSpace,
 		extractAgentVersion:  extractAgentVersion,
 		copyActionStore:      copyActionStoreProvider(os.ReadFile, os.WriteFile),
 		copyRunDirectory:     copyRunDirectoryProvider(os.MkdirAll, copy.Copy),
@@ -227,6 +229,59 @@ func (u *Upgrader) Upgradeable() bool {
 	return u.upgradeable
 }
 
+// preflight checks that the disk space required to upgrade is available, counting the versions beyond the
+// retained ones as available.
+func (u *Upgrader) preflight() error {
+	if u.upgradeSettings == nil {
+		return nil
+	}
+	downloadsDir := u.downloadsDir()
+
+	var reclaimable []string
+	if retention := u.upgradeSettings.Retention; retention != nil {
+		homes, downloads, err := versionsBeyondRetention(paths.Top(), paths.Home(), downloadsDir, []string{u.agentInfo.Version()}, retention.Versions)
+		if err != nil {
+			u.log.Warnw("Unable to list the versions beyond retention before update", "error.message", err)
+		}
+		reclaimable = append(reclaimable, homes...)
+		reclaimable = append(reclaimable, downloads...)
+	}
+
+	if diskSpace := u.upgradeSettings.DiskSpace; diskSpace != nil && diskSpace.Check {
+		err := checkDiskSpace(u.log, u.freeDiskSpace, paths.Top(), paths.Home(), downloadsDir, diskSpace.Reserve, reclaimable)
+		if goerrors.Is(err, upgradeErrors.ErrInsufficientDiskSpace) {
+			return err
+		}
+		if err != nil {
+			u.log.Warnw("Unable to check the disk space before update", "error.message", err)
+		}
+	}
+	return nil
+}
+
+// pruneBeyondRetention prunes the versions beyond the retained ones, the running version and the version of the
+// verified package at archivePath are kept. It is only called once the package is verified, a failed download
+// does not leave the agent without the versions it can fall back to.
+func (u *Upgrader) pruneBeyondRetention(archivePath string) {
+	if u.upgradeSettings == nil || u.upgradeSettings.Retention == nil {
+		return
+	}
+	keep := []string{u.agentInfo.Version()}
+	if match := downloadVersionRegexp.FindStringSubmatch(filepath.Base(archivePath)); match != nil {
+		keep = append(keep, match[1])
+	}
+	if err := pruneVersions(u.log, paths.Top(), paths.Home(), u.downloadsDir(), keep, u.upgradeSettings.Retention.Versions); err != nil {
+		u.log.Errorw("Unable to prune the versions beyond retention before update", "error.message", err)
+	}
+}
+
+func (u *Upgrader) downloadsDir() string {
+	if u.settings != nil && u.settings.TargetDirectory != "" {
+		return u.settings.TargetDirectory
+	}
+	return paths.Downloads()
+}
+
 type agentVersion struct {
 	version  string
 	snapshot bool
@@ -318,6 +373,10 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, rollback bool, s
 		u.log.Errorw("Unable to clean downloads before update", "error.message", err, "downloads.path", paths.Downloads())
 	}
 
+	if err := u.preflight(); err != nil {
+		return nil, err
+	}
+
 	det.SetState(details.StateDownloading)
 
 	sourceURI = u.sourceURI(sourceURI)
@@ -338,6 +397,8 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, rollback bool, s
 		return nil, err
 	}
 
+	u.pruneBeyondRetention(archivePath)
+
 	det.SetState(details.StateExtracting)
 
 	metadata, err := u.unpacker.getPackageMetadata(archivePath)
@@ -350,6 +411,12 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, rollback bool, s
 		return nil, fmt.Errorf("cannot upgrade the agent: %w", err)
 	}
 
+	previousParsedVersion := currentagtversion.GetParsedAgentPackageVersion()
+	downgrade, err := checkDowngrade(u.log, previousParsedVersion, parsedVersion, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("cannot downgrade the agent: %w", err)
+	}
+
 	u.log.Infow("Unpacking agent package", "version", newVersion)
 
 	// Nice to have: add check that no archive files end up in the current versioned home
@@ -421,7 +488,6 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, rollback bool, s
 		versionedHome: unpackRes.VersionedHome,
 	}
 
-	previousParsedVersion := currentagtversion.GetParsedAgentPackageVersion()
 	previous := agentInstall{
 		parsedVersion: previousParsedVersion,
 		version:       release.VersionWithSnapshot(),
@@ -432,12 +498,18 @@ func (u *Upgrader) Upgrade(ctx context.Context, version string, rollback bool, s
 	if u.upgradeSettings != nil && u.upgradeSettings.Rollback != nil {
 		rollbackWindow = u.upgradeSettings.Rollback.Window
 	}
+	updatedOn := time.Now()
+	if u.upgradeSettings != nil && u.upgradeSettings.HealthGate != nil && u.upgradeSettings.HealthGate.Enabled {
+		// the watcher rolls back the upgrade unless the new agent reports healthy before the deadline
+		healthGateUntil := updatedOn.Add(u.upgradeSettings.HealthGate.Window)
+		det.SetHealthGate(details.HealthGatePending, &healthGateUntil)
+	}
 	if err := u.markUpgrade(u.log,
 		paths.Data(), // data dir to place the marker in
-		time.Now(),
+		updatedOn,
 		current,  // new agent version data
 		previous, // old agent version data
-		action, det, rollbackWindow); err != nil {
+		action, det, rollbackWindow, downgrade); err != nil {
 		u.log.Errorw("Rolling back: marking upgrade failed", "error.message", err)
 		rollbackErr := u.rollbackInstall(ctx, u.log, paths.Top(), hashedDir, currentVersionedHome)
 		return nil, goerrors.Join(err, rollbackErr)
@@ -603,6 +675,12 @@ func (u *Upgrader) Ack(ctx context.Context, acker acker.Acker) error {
 		return nil
 	}
 
+	if marker.Details != nil && marker.Details.Metadata.HealthGate == details.HealthGatePending {
+		// the upgrade is only committed, and acked, once the upgraded agent passed the health gate
+		u.log.Info("Deferring the ack of the upgrade until the upgraded agent passes the health gate")
+		return nil
+	}
+
 	// Action can be nil if the upgrade was called locally.
 	// Should handle gracefully
 	// https://github.com/elastic/elastic-agent/issues/1788
@@ -329,6 +329,41 @@ func TestUpgraderAckAction(t *testing.T) {
 	})
 }
 
+func TestUpgraderAckDeferredUntilHealthGate(t *testing.T) {
+	log, _ := loggertest.New("")
+	u := Upgrader{
+		log:      log,
+		settings: artifact.DefaultConfig(),
+	}
+
+	topDir := t.TempDir()
+	prevTop := paths.Top()
+	paths.SetTop(topDir)
+	t.Cleanup(func() { paths.SetTop(prevTop) })
+	require.NoError(t, os.MkdirAll(paths.Data(), 0o755))
+
+	action := &fleetapi.ActionUpgrade{ActionID: "upgrade", ActionType: fleetapi.ActionTypeUpgrade}
+	until := time.Now().Add(time.Hour)
+	det := details.NewDetails("9.1.0", details.StateWatching, action.ActionID)
+	det.SetHealthGate(details.HealthGatePending, &until)
+	require.NoError(t, SaveMarker(paths.Data(), &UpdateMarker{Action: action, Details: det}, true))
+
+	// no ack while the health gate is pending
+	mockAcker := ackermocks.NewAcker(t)
+	require.NoError(t, u.Ack(t.Context(), mockAcker))
+	marker, err := LoadMarker(paths.Data())
+	require.NoError(t, err)
+	require.NotNil(t, marker)
+	assert.False(t, marker.Acked)
+
+	// acked once it passed
+	det.SetHealthGate(details.HealthGatePassed, &until)
+	require.NoError(t, SaveMarker(paths.Data(), &UpdateMarker{Action: action, Details: det}, true))
+	mockAcker.EXPECT().Ack(mock.Anything, action).Return(nil)
+	mockAcker.EXPECT().Commit(mock.Anything).Return(nil)
+	require.NoError(t, u.Ack(t.Context(), mockAcker))
+}
+
 func prepareTestUpgraderReload() (string, artifact.Config) {
 	cfgyaml := `
 agent.download:
@@ -434,6 +469,20 @@ agent.download:
 		InstallPath:            "/sonic_screwdriver",
 		DropPath:               "/gallifrey",
 		RetrySleepInitDuration: 10 * time.Second,
+		Retry: artifact.RetryConfig{
+			Jitter: artifact.DefaultRetryJitter,
+		},
+		Segments: artifact.SegmentsConfig{
+			MinSize: artifact.DefaultSegmentsMinSize,
+		},
+		PeerCache: artifact.PeerCacheConfig{
+			Serve: artifact.PeerCacheServeConfig{
+				Address: artifact.DefaultPeerCacheAddress,
+			},
+		},
+		Verification: artifact.VerificationConfig{
+			Mode: artifact.VerificationModePGP,
+		},
 
 		HTTPTransportSettings: httpcommon.HTTPTransportSettings{
 			TLS: &tlscommon.Config{
@@ -1520,7 +1569,7 @@ func TestUpgradeErrorHandling(t *testing.T) {
 				upgrader.rollbackInstall = func(ctx context.Context, log *logger.Logger, topDirPath, versionedHome, oldVersionedHome string) error {
 					return nil
 				}
-				upgrader.markUpgrade = func(log *logger.Logger, dataDirPath string, updatedOn time.Time, agent, previousAgent agentInstall, action *fleetapi.ActionUpgrade, upgradeDetails *details.Details, rollbackWindow time.Duration) error {
+				upgrader.markUpgrade = func(log *logger.Logger, dataDirPath string, updatedOn time.Time, agent, previousAgent agentInstall, action *fleetapi.ActionUpgrade, upgradeDetails *details.Details, rollbackWindow time.Duration, downgrade *DowngradeInfo) error {
 					return testError
 				}
 			},
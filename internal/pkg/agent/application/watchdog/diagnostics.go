@@ -0,0 +1,66 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+const (
+	// diagnosticsTimeout is the time given to the components to answer the diagnostics requests.
+	diagnosticsTimeout = time.Minute
+	// maxBundles is the number of diagnostics bundles kept, the oldest ones are removed.
+	maxBundles = 10
+
+	bundlePrefix = "fault-diagnostics-"
+)
+
+// captureDiagnostics writes a diagnostics bundle of a faulty component, with the logs of the agent, and
+// returns its path.
+func (w *Watchdog) captureDiagnostics(ctx context.Context, comp runtime.ComponentComponentState, reason string, now time.Time) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsTimeout)
+	defer cancel()
+
+	fault, err := yaml.Marshal(struct {
+		Component string                 `yaml:"component"`
+		Reason    string                 `yaml:"reason"`
+		State     runtime.ComponentState `yaml:"state"`
+	}{
+		Component: comp.Component.ID,
+		Reason:    reason,
+		State:     comp.State,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal the fault: %w", err)
+	}
+	agentDiag := []client.DiagnosticFileResult{{
+		Name:        "fault",
+		Filename:    "fault.yaml",
+		Description: "Fault detected by the fault handling",
+		ContentType: "application/yaml",
+		Content:     fault,
+		Generated:   now,
+	}}
+
+	return diagnostics.Bundle{
+		Dir:        w.bundlesDir,
+		Prefix:     bundlePrefix,
+		Suffix:     "-" + strings.ReplaceAll(comp.Component.ID, "/", "-"),
+		Keep:       maxBundles,
+		TopPath:    w.topPath,
+		Agent:      agentDiag,
+		Components: []component.Component{comp.Component},
+	}.Write(ctx, w.log, w.coord, now)
+}
@@ -0,0 +1,349 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package watchdog handles the components that keep failing instead of letting them crash-loop.
+//
+// The health transitions of the components are tracked and a component entering a failed or degraded
+// state too often within a window is faulty. Depending on the policy, the diagnostics of a faulty
+// component are captured into a bundle, the component is restarted after a backoff, or it is quarantined
+// until the next policy. Restarted and quarantined components are held out of the component model by the
+// Coordinator and reported to Fleet.
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// ActionRestart stops a faulty component and starts it again after a backoff.
+	ActionRestart = "restart"
+	// ActionDiagnostics captures a diagnostics bundle of a faulty component.
+	ActionDiagnostics = "diagnostics"
+	// ActionQuarantine stops a faulty component until the next policy, once it cannot be restarted anymore.
+	ActionQuarantine = "quarantine"
+)
+
+// Config is the configuration of the fault handling, read from agent.fault_handling.
+type Config struct {
+	// Enabled turns the fault handling on, it is off by default.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// Threshold is the number of times a component must become unhealthy within Window to be faulty.
+	Threshold int `config:"threshold" yaml:"threshold"`
+	// Window is the period over which the unhealthy transitions of a component are counted.
+	Window time.Duration `config:"window" yaml:"window"`
+	// Actions are the actions performed on a faulty component, restart when empty.
+	Actions []string `config:"actions" yaml:"actions"`
+	// RestartBackoff is the time a faulty component is stopped for before being restarted, doubled
+	// after each restart.
+	RestartBackoff BackoffConfig `config:"restart_backoff" yaml:"restart_backoff"`
+	// MaxRestarts is the number of times a component is restarted before being quarantined.
+	MaxRestarts int `config:"max_restarts" yaml:"max_restarts"`
+}
+
+// BackoffConfig is the backoff between the restarts of a faulty component.
+type BackoffConfig struct {
+	Init time.Duration `config:"init" yaml:"init"`
+	Max  time.Duration `config:"max" yaml:"max"`
+}
+
+// DefaultConfig returns the default configuration of the fault handling.
+func DefaultConfig() Config {
+	return Config{
+		Enabled:   false,
+		Threshold: 3,
+		Window:    10 * time.Minute,
+		RestartBackoff: BackoffConfig{
+			Init: 10 * time.Second,
+			Max:  5 * time.Minute,
+		},
+		MaxRestarts: 3,
+	}
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	if c.Threshold < 1 {
+		return fmt.Errorf("invalid fault handling threshold %d, must be at least 1", c.Threshold)
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("invalid fault handling window %s, must be greater than zero", c.Window)
+	}
+	for _, action := range c.Actions {
+		switch action {
+		case ActionRestart, ActionDiagnostics, ActionQuarantine:
+		default:
+			return fmt.Errorf("invalid fault handling action %q, must be one of %s, %s or %s", action, ActionRestart, ActionDiagnostics, ActionQuarantine)
+		}
+	}
+	if c.RestartBackoff.Init <= 0 {
+		return fmt.Errorf("invalid fault handling restart backoff %s, must be greater than zero", c.RestartBackoff.Init)
+	}
+	if c.RestartBackoff.Max < c.RestartBackoff.Init {
+		return fmt.Errorf("invalid fault handling maximum restart backoff %s, must be at least %s", c.RestartBackoff.Max, c.RestartBackoff.Init)
+	}
+	if c.MaxRestarts < 0 {
+		return fmt.Errorf("invalid fault handling max restarts %d, must not be negative", c.MaxRestarts)
+	}
+	return nil
+}
+
+func (c *Config) has(action string) bool {
+	if len(c.Actions) == 0 {
+		return action == ActionRestart
+	}
+	return slices.Contains(c.Actions, action)
+}
+
+// backoff returns the time a component restarted the given number of times is stopped for.
+func (c *Config) backoff(restarts int) time.Duration {
+	d := c.RestartBackoff.Init
+	for i := 0; i < restarts && d < c.RestartBackoff.Max; i++ {
+		d *= 2
+	}
+	return min(d, c.RestartBackoff.Max)
+}
+
+// Coordinator is the part of the Coordinator the watchdog observes and acts on.
+type Coordinator interface {
+	StateSubscribe(ctx context.Context, bufferLen int) chan coordinator.State
+	SetQuarantinedComponents(quarantined []coordinator.QuarantinedComponent)
+	PerformDiagnostics(ctx context.Context, req ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+}
+
+// Watchdog tracks the health of the components and handles the faulty ones.
+type Watchdog struct {
+	log   *logger.Logger
+	coord Coordinator
+	// topPath is the top path of the agent, the logs of the diagnostics bundles are collected from it.
+	topPath string
+	// bundlesDir is the directory the diagnostics bundles of faulty components are written to.
+	bundlesDir string
+
+	mx  sync.Mutex
+	cfg Config
+	// reloadCh signals a new policy to the Run goroutine.
+	reloadCh chan struct{}
+
+	now func() time.Time
+
+	// the following are only accessed by the Run goroutine
+	states   map[string]client.UnitState
+	faults   map[string][]time.Time
+	restarts map[string]int
+	held     []coordinator.QuarantinedComponent
+}
+
+// New creates a watchdog, disabled until a configuration enabling it is reloaded. The diagnostics
+// bundles are written to the fault_handling directory of the data path under topPath.
+func New(log *logger.Logger, coord Coordinator, topPath string) *Watchdog {
+	return &Watchdog{
+		log:        log,
+		coord:      coord,
+		topPath:    topPath,
+		bundlesDir: filepath.Join(paths.DataFrom(topPath), "fault_handling"),
+		cfg:        DefaultConfig(),
+		reloadCh:   make(chan struct{}, 1),
+		now:        time.Now,
+		states:     make(map[string]client.UnitState),
+		faults:     make(map[string][]time.Time),
+		restarts:   make(map[string]int),
+	}
+}
+
+// Reload reads the fault handling settings from the agent configuration. The components held by the
+// previous policy are released.
+func (w *Watchdog) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		FaultHandling Config `config:"agent.fault_handling"`
+	}{
+		FaultHandling: DefaultConfig(),
+	}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack fault handling config: %w", err)
+	}
+
+	w.mx.Lock()
+	w.cfg = cfg.FaultHandling
+	w.mx.Unlock()
+
+	// Reload is called on the Coordinator goroutine, the components are released by the Run goroutine
+	// as the Coordinator cannot be updated from its own goroutine.
+	select {
+	case w.reloadCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (w *Watchdog) config() Config {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.cfg
+}
+
+// Run observes the state of the components until the context is done.
+func (w *Watchdog) Run(ctx context.Context) {
+	stateCh := w.coord.StateSubscribe(ctx, 32)
+	release := time.NewTimer(time.Hour)
+	release.Stop()
+	defer release.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.reloadCh:
+			w.reset()
+		case state, ok := <-stateCh:
+			if !ok {
+				return
+			}
+			w.observe(ctx, state.Components)
+		case <-release.C:
+			w.release()
+		}
+
+		if next, ok := w.nextRelease(); ok {
+			release.Reset(next.Sub(w.now()))
+		} else {
+			release.Stop()
+		}
+	}
+}
+
+// reset forgets the failures of the components and releases the held ones.
+func (w *Watchdog) reset() {
+	clear(w.faults)
+	clear(w.restarts)
+	if len(w.held) == 0 {
+		return
+	}
+	w.log.Infof("releasing %d components held by the fault handling for the new policy", len(w.held))
+	w.held = nil
+	w.coord.SetQuarantinedComponents(nil)
+}
+
+// observe records the unhealthy transitions of the components and handles the ones becoming faulty.
+func (w *Watchdog) observe(ctx context.Context, components []runtime.ComponentComponentState) {
+	cfg := w.config()
+	now := w.now()
+	seen := make(map[string]bool, len(components))
+	for _, comp := range components {
+		id := comp.Component.ID
+		seen[id] = true
+		state := comp.State.State
+		previous, known := w.states[id]
+		w.states[id] = state
+		if !cfg.Enabled || !unhealthy(state) || (known && unhealthy(previous)) {
+			continue
+		}
+
+		faults := append(w.faults[id], now)
+		faults = slices.DeleteFunc(faults, func(t time.Time) bool {
+			return now.Sub(t) > cfg.Window
+		})
+		w.faults[id] = faults
+		w.log.Debugf("component %s is %s (%d times within %s): %s", id, state, len(faults), cfg.Window, comp.State.Message)
+		if len(faults) >= cfg.Threshold {
+			delete(w.faults, id)
+			w.handle(ctx, cfg, comp, now)
+		}
+	}
+	for id := range w.states {
+		if !seen[id] {
+			delete(w.states, id)
+		}
+	}
+}
+
+// handle performs the configured actions on a faulty component.
+func (w *Watchdog) handle(ctx context.Context, cfg Config, comp runtime.ComponentComponentState, now time.Time) {
+	id := comp.Component.ID
+	reason := fmt.Sprintf("unhealthy %d times within %s, last: %s", cfg.Threshold, cfg.Window, comp.State.Message)
+
+	if cfg.has(ActionDiagnostics) {
+		path, err := w.captureDiagnostics(ctx, comp, reason, now)
+		if err != nil {
+			w.log.Errorf("failed to capture the diagnostics of faulty component %s: %v", id, err)
+		} else {
+			w.log.Infof("captured the diagnostics of faulty component %s in %s", id, path)
+		}
+	}
+
+	held := coordinator.QuarantinedComponent{
+		ID:         id,
+		InputType:  comp.Component.InputType,
+		OutputType: comp.Component.OutputType,
+		Since:      now,
+	}
+	restarts := w.restarts[id]
+	switch {
+	case cfg.has(ActionRestart) && restarts < cfg.MaxRestarts:
+		delay := cfg.backoff(restarts)
+		w.restarts[id] = restarts + 1
+		held.Until = now.Add(delay)
+		held.Reason = fmt.Sprintf("restarting in %s (restart %d of %d), %s", delay, restarts+1, cfg.MaxRestarts, reason)
+		w.log.Warnf("stopping faulty component %s, restarting it in %s: %s", id, delay, reason)
+	case cfg.has(ActionQuarantine):
+		held.Reason = "quarantined until the next policy, " + reason
+		w.log.Warnf("quarantining faulty component %s until the next policy: %s", id, reason)
+	default:
+		if cfg.has(ActionRestart) {
+			w.log.Warnf("faulty component %s reached the maximum of %d restarts: %s", id, cfg.MaxRestarts, reason)
+		}
+		return
+	}
+
+	w.held = append(slices.DeleteFunc(w.held, func(q coordinator.QuarantinedComponent) bool {
+		return q.ID == id
+	}), held)
+	w.coord.SetQuarantinedComponents(slices.Clone(w.held))
+}
+
+// release restarts the components whose backoff has elapsed.
+func (w *Watchdog) release() {
+	now := w.now()
+	remaining := slices.DeleteFunc(slices.Clone(w.held), func(q coordinator.QuarantinedComponent) bool {
+		if q.Until.IsZero() || q.Until.After(now) {
+			return false
+		}
+		w.log.Infof("restarting faulty component %s", q.ID)
+		return true
+	})
+	if len(remaining) == len(w.held) {
+		return
+	}
+	w.held = remaining
+	w.coord.SetQuarantinedComponents(slices.Clone(w.held))
+}
+
+// nextRelease returns when the next held component must be restarted.
+func (w *Watchdog) nextRelease() (time.Time, bool) {
+	var next time.Time
+	for _, q := range w.held {
+		if !q.Until.IsZero() && (next.IsZero() || q.Until.Before(next)) {
+			next = q.Until
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func unhealthy(state client.UnitState) bool {
+	return state == client.UnitStateFailed || state == client.UnitStateDegraded
+}
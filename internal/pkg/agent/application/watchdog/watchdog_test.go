@@ -0,0 +1,230 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package watchdog
+
+import (
+	"archive/zip"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeCoordinator struct {
+	quarantined [][]coordinator.QuarantinedComponent
+	diagnosed   []string
+}
+
+func (c *fakeCoordinator) StateSubscribe(context.Context, int) chan coordinator.State {
+	return make(chan coordinator.State)
+}
+
+func (c *fakeCoordinator) SetQuarantinedComponents(quarantined []coordinator.QuarantinedComponent) {
+	c.quarantined = append(c.quarantined, quarantined)
+}
+
+func (c *fakeCoordinator) PerformDiagnostics(_ context.Context, req ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic {
+	var diags []runtime.ComponentUnitDiagnostic
+	for _, r := range req {
+		diags = append(diags, runtime.ComponentUnitDiagnostic{Component: r.Component, Unit: r.Unit})
+	}
+	return diags
+}
+
+func (c *fakeCoordinator) PerformComponentDiagnostics(_ context.Context, _ []cproto.AdditionalDiagnosticRequest, _ time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+	var diags []runtime.ComponentDiagnostic
+	for _, comp := range req {
+		c.diagnosed = append(c.diagnosed, comp.ID)
+		diags = append(diags, runtime.ComponentDiagnostic{Component: comp})
+	}
+	return diags, nil
+}
+
+// last returns the components held by the last update.
+func (c *fakeCoordinator) last() []coordinator.QuarantinedComponent {
+	if len(c.quarantined) == 0 {
+		return nil
+	}
+	return c.quarantined[len(c.quarantined)-1]
+}
+
+func newTestWatchdog(t *testing.T, cfg map[string]interface{}) (*Watchdog, *fakeCoordinator, *time.Time) {
+	log, _ := loggertest.New("watchdog")
+	coord := &fakeCoordinator{}
+	w := New(log, coord, t.TempDir())
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return now }
+	require.NoError(t, w.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.fault_handling": cfg,
+	})))
+	return w, coord, &now
+}
+
+func componentState(id string, state client.UnitState) runtime.ComponentComponentState {
+	return runtime.ComponentComponentState{
+		Component: component.Component{
+			ID:         id,
+			InputType:  "filestream",
+			OutputType: "elasticsearch",
+			Units:      []component.Unit{{ID: id + "-unit", Type: client.UnitTypeInput}},
+		},
+		State: runtime.ComponentState{State: state, Message: "crashed"},
+	}
+}
+
+// crash makes the component fail and recover once.
+func crash(w *Watchdog, id string) {
+	w.observe(context.Background(), []runtime.ComponentComponentState{componentState(id, client.UnitStateFailed)})
+	w.observe(context.Background(), []runtime.ComponentComponentState{componentState(id, client.UnitStateHealthy)})
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	require.NoError(t, cfg.Validate())
+	assert.True(t, cfg.has(ActionRestart), "restart should be the default action")
+	assert.False(t, cfg.has(ActionQuarantine))
+
+	for name, modify := range map[string]func(c *Config){
+		"zero threshold":       func(c *Config) { c.Threshold = 0 },
+		"zero window":          func(c *Config) { c.Window = 0 },
+		"unknown action":       func(c *Config) { c.Actions = []string{"reboot"} },
+		"zero backoff":         func(c *Config) { c.RestartBackoff.Init = 0 },
+		"max below init":       func(c *Config) { c.RestartBackoff.Max = time.Second },
+		"negative max restart": func(c *Config) { c.MaxRestarts = -1 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			modify(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestConfigBackoff(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, 10*time.Second, cfg.backoff(0))
+	assert.Equal(t, 20*time.Second, cfg.backoff(1))
+	assert.Equal(t, 160*time.Second, cfg.backoff(4))
+	assert.Equal(t, 5*time.Minute, cfg.backoff(10), "the backoff should be capped")
+}
+
+func TestWatchdogDisabled(t *testing.T) {
+	w, coord, _ := newTestWatchdog(t, map[string]interface{}{})
+	for range 5 {
+		crash(w, "filestream-default")
+	}
+	assert.Empty(t, coord.quarantined, "no component should be held while disabled")
+}
+
+func TestWatchdogRestartsWithBackoff(t *testing.T) {
+	w, coord, now := newTestWatchdog(t, map[string]interface{}{
+		"enabled":      true,
+		"threshold":    2,
+		"max_restarts": 2,
+		"actions":      []string{ActionRestart, ActionQuarantine},
+	})
+
+	// staying failed counts once
+	w.observe(context.Background(), []runtime.ComponentComponentState{componentState("filestream-default", client.UnitStateFailed)})
+	w.observe(context.Background(), []runtime.ComponentComponentState{componentState("filestream-default", client.UnitStateHealthy)})
+	assert.Empty(t, coord.quarantined)
+
+	crash(w, "filestream-default")
+	require.Len(t, coord.last(), 1)
+	held := coord.last()[0]
+	assert.Equal(t, "filestream-default", held.ID)
+	assert.Equal(t, "filestream", held.InputType)
+	assert.Equal(t, now.Add(10*time.Second), held.Until)
+	assert.Contains(t, held.Reason, "restarting in 10s (restart 1 of 2)")
+
+	next, ok := w.nextRelease()
+	require.True(t, ok)
+	assert.Equal(t, held.Until, next)
+
+	w.release()
+	assert.Len(t, coord.last(), 1, "the component should be held until the backoff elapses")
+	*now = now.Add(10 * time.Second)
+	w.release()
+	assert.Empty(t, coord.last(), "the component should be restarted once the backoff elapses")
+
+	// the backoff doubles on the next restart
+	crash(w, "filestream-default")
+	crash(w, "filestream-default")
+	require.Len(t, coord.last(), 1)
+	assert.Equal(t, now.Add(20*time.Second), coord.last()[0].Until)
+	*now = now.Add(20 * time.Second)
+	w.release()
+
+	// quarantined once the restarts are exhausted
+	crash(w, "filestream-default")
+	crash(w, "filestream-default")
+	require.Len(t, coord.last(), 1)
+	assert.True(t, coord.last()[0].Until.IsZero(), "the component should be quarantined")
+	assert.Contains(t, coord.last()[0].Reason, "quarantined until the next policy")
+	_, ok = w.nextRelease()
+	assert.False(t, ok, "a quarantined component is not restarted")
+
+	// a new policy releases it
+	require.NoError(t, w.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.fault_handling.enabled": true,
+	})))
+	<-w.reloadCh
+	w.reset()
+	assert.Empty(t, coord.last())
+	assert.Empty(t, w.restarts, "the restarts should be forgotten with the new policy")
+}
+
+func TestWatchdogWindow(t *testing.T) {
+	w, coord, now := newTestWatchdog(t, map[string]interface{}{
+		"enabled":   true,
+		"threshold": 2,
+		"window":    "1m",
+	})
+
+	crash(w, "filestream-default")
+	*now = now.Add(2 * time.Minute)
+	crash(w, "filestream-default")
+	assert.Empty(t, coord.quarantined, "failures outside of the window should not count")
+
+	*now = now.Add(30 * time.Second)
+	crash(w, "filestream-default")
+	assert.Len(t, coord.last(), 1)
+}
+
+func TestWatchdogDiagnostics(t *testing.T) {
+	w, coord, _ := newTestWatchdog(t, map[string]interface{}{
+		"enabled":   true,
+		"threshold": 1,
+		"actions":   []string{ActionDiagnostics},
+	})
+
+	crash(w, "filestream-default")
+	assert.Empty(t, coord.quarantined, "the component should only be diagnosed")
+	assert.Equal(t, []string{"filestream-default"}, coord.diagnosed)
+
+	bundles, err := filepath.Glob(filepath.Join(w.bundlesDir, bundlePrefix+"*-filestream-default.zip"))
+	require.NoError(t, err)
+	require.Len(t, bundles, 1)
+	zr, err := zip.OpenReader(bundles[0])
+	require.NoError(t, err)
+	defer zr.Close()
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "fault.yaml")
+}
@@ -0,0 +1,152 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// watchedDebounce is how long the watcher waits for the configuration files to settle before reloading them,
+// editors and configuration management tools write a file in multiple steps.
+const watchedDebounce = 500 * time.Millisecond
+
+// watched reloads the standalone configuration as soon as the files in the watched directories change. The
+// directories are watched instead of the files, the files are replaced by renaming a new file over them.
+type watched struct {
+	*periodic
+	dirs     []string
+	debounce time.Duration
+}
+
+func newWatched(
+	log *logger.Logger,
+	dirs []string,
+	period time.Duration,
+	discover config.DiscoverFunc,
+	loader *config.Loader,
+	validate func(*config.Config) error,
+) *watched {
+	return &watched{
+		periodic: newPeriodic(log, period, discover, loader, validate),
+		dirs:     dirs,
+		debounce: watchedDebounce,
+	}
+}
+
+func (w *watched) Run(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		// e.g. the limit of inotify instances is reached
+		w.log.Warnf("Failed to watch the configuration files, checking them for changes every %s: %s", w.period, err)
+		return w.periodic.Run(ctx)
+	}
+	defer watcher.Close()
+	w.add(watcher)
+
+	if err := w.work(ctx); err != nil {
+		return err
+	}
+
+	// the timer is only armed once a change has been seen
+	t := time.NewTimer(w.debounce)
+	t.Stop()
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			w.log.Errorf("configuration files watch returned error: %s", err)
+		case e, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if e.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) == 0 {
+				continue
+			}
+			w.log.Debugf("configuration file %s changed (%s)", e.Name, e.Op)
+			if e.Op&fsnotify.Create != 0 {
+				// a directory of fragments created after the start
+				w.add(watcher)
+			}
+			t.Reset(w.debounce)
+		case <-t.C:
+			if err := w.work(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// add watches the directories, the ones that do not exist yet are skipped.
+func (w *watched) add(watcher *fsnotify.Watcher) {
+	watching := make(map[string]bool)
+	for _, dir := range watcher.WatchList() {
+		watching[dir] = true
+	}
+	for _, dir := range w.dirs {
+		if watching[dir] {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			w.log.Debugf("not watching configuration directory [%s]: %s", dir, err)
+			continue
+		}
+		watching[dir] = true
+	}
+}
+
+// patternDirs returns the directories of the files matched by the patterns.
+func patternDirs(patterns []string) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		dir := filepath.Dir(pattern)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// validateLocalConfig validates the merged standalone configuration before it is applied: the agent settings
+// must unpack, the configuration must transpile and the variables must be well formed.
+func validateLocalConfig(cfg *config.Config) error {
+	if _, err := configuration.NewFromConfig(cfg); err != nil {
+		return fmt.Errorf("invalid agent settings: %w", err)
+	}
+	m, err := cfg.ToMapStr()
+	if err != nil {
+		return fmt.Errorf("could not create the map from the configuration: %w", err)
+	}
+	ast, err := transpiler.NewAST(m)
+	if err != nil {
+		return fmt.Errorf("could not create the AST from the configuration: %w", err)
+	}
+	if inputs, ok := transpiler.Lookup(ast, "inputs"); ok {
+		if _, ok := inputs.Value().(*transpiler.List); !ok {
+			return fmt.Errorf("inputs must be an array")
+		}
+	}
+	return transpiler.ValidateVars(m)
+}
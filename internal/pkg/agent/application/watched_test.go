@@ -0,0 +1,163 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func writeWatchedConfig(t *testing.T, path string, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	// replaced by renaming a new file over it, as editors do
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0600))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func nextChange(t *testing.T, w *watched) map[string]interface{} {
+	t.Helper()
+	select {
+	case change := <-w.Watch():
+		m, err := change.Config().ToMapStr()
+		require.NoError(t, err)
+		return m
+	case err := <-w.Errors():
+		require.FailNow(t, "expected a configuration change", "got error: %v", err)
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "timed out waiting for a configuration change")
+	}
+	return nil
+}
+
+func nextError(t *testing.T, w *watched) error {
+	t.Helper()
+	select {
+	case change := <-w.Watch():
+		require.FailNow(t, "expected an error", "got configuration change: %v", change.Config())
+	case err := <-w.Errors():
+		return err
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "timed out waiting for an error")
+	}
+	return nil
+}
+
+func TestWatchedReloadsValidConfiguration(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "elastic-agent.yml")
+	inputsPattern := filepath.Join(dir, "inputs.d", "*.yml")
+	writeWatchedConfig(t, cfgPath, `
+outputs:
+  default:
+    type: elasticsearch
+    hosts: [127.0.0.1:9200]
+inputs:
+  - id: logs
+    type: filestream
+    paths: [/var/log/a.log]
+`)
+
+	log, _ := loggertest.New("watched")
+	patterns := []string{cfgPath, inputsPattern}
+	w := newWatched(log, patternDirs(patterns), time.Hour, config.Discoverer(patterns...),
+		config.NewLoader(log, inputsPattern, ""), validateLocalConfig)
+	w.debounce = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		_ = w.Run(ctx)
+	}()
+
+	m := nextChange(t, w)
+	assert.Equal(t, "/var/log/a.log", m["inputs"].([]interface{})[0].(map[string]interface{})["paths"].([]interface{})[0])
+
+	// an invalid variable is reported and the configuration is not applied
+	writeWatchedConfig(t, cfgPath, `
+outputs:
+  default:
+    type: elasticsearch
+    hosts: [127.0.0.1:9200]
+inputs:
+  - id: logs
+    type: filestream
+    paths: ["/var/log/${host.name.log"]
+`)
+	err := nextError(t, w)
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "keeping the previous configuration")
+	assert.ErrorContains(t, err, "inputs.0.paths.0")
+
+	// the fixed configuration is applied and the error cleared
+	writeWatchedConfig(t, cfgPath, `
+outputs:
+  default:
+    type: elasticsearch
+    hosts: [127.0.0.1:9200]
+inputs:
+  - id: logs
+    type: filestream
+    paths: [/var/log/b.log]
+`)
+	m = nextChange(t, w)
+	assert.Equal(t, "/var/log/b.log", m["inputs"].([]interface{})[0].(map[string]interface{})["paths"].([]interface{})[0])
+	assert.NoError(t, nextError(t, w), "the reported error should be cleared")
+
+	// a fragment in the inputs directory created after the start is picked up
+	writeWatchedConfig(t, filepath.Join(dir, "inputs.d", "metrics.yml"), `
+inputs:
+  - id: metrics
+    type: system/metrics
+`)
+	m = nextChange(t, w)
+	assert.Len(t, m["inputs"], 2)
+}
+
+func TestValidateLocalConfig(t *testing.T) {
+	for name, tc := range map[string]struct {
+		cfg map[string]interface{}
+		err string
+	}{
+		"valid": {
+			cfg: map[string]interface{}{
+				"inputs": []interface{}{map[string]interface{}{"type": "filestream", "paths": []interface{}{"/var/log/${host.name}.log", "$${literal}"}}},
+			},
+		},
+		"invalid agent settings": {
+			cfg: map[string]interface{}{"agent.reload": map[string]interface{}{"enabled": true, "period": 0}},
+			err: "invalid agent settings",
+		},
+		"inputs not an array": {
+			cfg: map[string]interface{}{"inputs": map[string]interface{}{"type": "filestream"}},
+			err: "inputs must be an array",
+		},
+		"unterminated variable": {
+			cfg: map[string]interface{}{
+				"inputs": []interface{}{map[string]interface{}{"type": "filestream", "paths": []interface{}{"/var/log/${host.name.log"}}},
+			},
+			err: "invalid variable in 'inputs.0.paths.0'",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := validateLocalConfig(config.MustNewConfigFrom(tc.cfg))
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/agentbench"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func newAgentbenchCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agentbench",
+		Short: "Measure the processing time of synthetic policies",
+		Long: `Generates a synthetic policy of the given size and shape and measures, on this machine, the time the
+Elastic Agent takes to process it:
+
+  startup: load the component specifications, then render and apply the policy, like a starting Elastic Agent
+  render:  compute the configuration of the policy with its variables, evaluating the conditions of the inputs
+  apply:   compute the components model of the computed configuration
+
+Nothing is started, the components model is only computed. The generated policy and its variables can be
+written with --write-dir, to be rendered with 'elastic-agent render --policy policy.yml --vars variables.yaml'.
+`,
+		Args:   cobra.ExactArgs(0),
+		Hidden: true, // internal tooling to track the performance per release
+		Run: func(c *cobra.Command, args []string) {
+			opts, err := agentbenchOptsFromFlags(c)
+			if err == nil {
+				err = runAgentbench(opts, streams)
+			}
+			if err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().Int("inputs", 100, "number of inputs of the policy")
+	cmd.Flags().Int("streams", 1, "number of streams of each input")
+	cmd.Flags().Int("vars", 10, "number of variables referenced by the streams")
+	cmd.Flags().Int("conditions", 0, "number of inputs with a condition on a variable, half of them are false")
+	cmd.Flags().StringSlice("input-types", agentbench.DefaultInputTypes, "types of the inputs, assigned in turn")
+	cmd.Flags().Int("iterations", 10, "number of measurements")
+	cmd.Flags().String("specs", "", "path to the directory of the component specifications (default to the components of this Elastic Agent)")
+	cmd.Flags().String("platform", "", "platform to render for in <os>/<arch> format (default to this platform)")
+	cmd.Flags().Bool("monitoring", true, "include the monitoring components")
+	cmd.Flags().String("write-dir", "", "directory to write the generated policy.yml and variables.yaml to")
+	cmd.Flags().String("output", "human", "output the results in either 'human', 'json' or 'yaml', durations are in nanoseconds in 'json' and 'yaml'")
+
+	return cmd
+}
+
+type agentbenchOpts struct {
+	profile    agentbench.Profile
+	iterations int
+	specsPath  string
+	platform   string
+	monitoring bool
+	writeDir   string
+	output     string
+}
+
+func agentbenchOptsFromFlags(c *cobra.Command) (agentbenchOpts, error) {
+	var opts agentbenchOpts
+	opts.profile.Inputs, _ = c.Flags().GetInt("inputs")
+	opts.profile.StreamsPerInput, _ = c.Flags().GetInt("streams")
+	opts.profile.Variables, _ = c.Flags().GetInt("vars")
+	opts.profile.Conditions, _ = c.Flags().GetInt("conditions")
+	opts.profile.InputTypes, _ = c.Flags().GetStringSlice("input-types")
+	opts.iterations, _ = c.Flags().GetInt("iterations")
+	opts.specsPath, _ = c.Flags().GetString("specs")
+	opts.platform, _ = c.Flags().GetString("platform")
+	opts.monitoring, _ = c.Flags().GetBool("monitoring")
+	opts.writeDir, _ = c.Flags().GetString("write-dir")
+	opts.output, _ = c.Flags().GetString("output")
+	if opts.specsPath == "" {
+		opts.specsPath = paths.Components()
+	}
+	if opts.iterations <= 0 {
+		return opts, errors.New("--iterations must be greater than zero", errors.TypeValidation)
+	}
+	switch opts.output {
+	case "human", "json", "yaml":
+	default:
+		return opts, errors.New(fmt.Sprintf("unsupported output: %s", opts.output), errors.TypeValidation)
+	}
+	return opts, nil
+}
+
+// agentbenchResult is the result of the agentbench command.
+type agentbenchResult struct {
+	Inputs          int                `json:"inputs" yaml:"inputs"`
+	StreamsPerInput int                `json:"streams_per_input" yaml:"streams_per_input"`
+	Variables       int                `json:"variables" yaml:"variables"`
+	Conditions      int                `json:"conditions" yaml:"conditions"`
+	Platform        string             `json:"platform" yaml:"platform"`
+	Components      int                `json:"components" yaml:"components"`
+	Units           int                `json:"units" yaml:"units"`
+	Startup         agentbench.Summary `json:"startup" yaml:"startup"`
+	Render          agentbench.Summary `json:"render" yaml:"render"`
+	Apply           agentbench.Summary `json:"apply" yaml:"apply"`
+}
+
+func runAgentbench(opts agentbenchOpts, streams *cli.IOStreams) error {
+	policy, err := agentbench.Generate(opts.profile)
+	if err != nil {
+		return errors.New(err, "invalid policy profile", errors.TypeValidation)
+	}
+	if opts.writeDir != "" {
+		if err := writeAgentbenchPolicy(opts.writeDir, policy); err != nil {
+			return err
+		}
+	}
+
+	result, err := measureAgentbench(opts, policy)
+	if err != nil {
+		return err
+	}
+	switch opts.output {
+	case "json":
+		return jsonOutput(streams.Out, result)
+	case "yaml":
+		return yamlOutput(streams.Out, result)
+	default:
+		return humanAgentbenchOutput(streams.Out, result)
+	}
+}
+
+// measureAgentbench measures the processing of the policy. Each iteration starts from scratch, like a starting
+// Elastic Agent, and times its steps.
+func measureAgentbench(opts agentbenchOpts, policy *agentbench.Policy) (*agentbenchResult, error) {
+	platform, err := component.LoadPlatformDetail(renderPlatformModifier(opts.platform))
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather system information: %w", err)
+	}
+	if opts.platform != "" && platform.String() != opts.platform {
+		return nil, errors.New(fmt.Sprintf("unsupported platform %q", opts.platform), errors.TypeValidation)
+	}
+	vars, err := transpiler.NewVars("", policy.Vars, nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the variables: %w", err)
+	}
+	agentInfo := &renderAgentInfo{id: renderAgentID, logLevel: logp.InfoLevel.String()}
+
+	result := &agentbenchResult{
+		Inputs:          opts.profile.Inputs,
+		StreamsPerInput: opts.profile.StreamsPerInput,
+		Variables:       opts.profile.Variables,
+		Conditions:      opts.profile.Conditions,
+		Platform:        platform.String(),
+	}
+	startup := make([]time.Duration, 0, opts.iterations)
+	renders := make([]time.Duration, 0, opts.iterations)
+	applies := make([]time.Duration, 0, opts.iterations)
+	for i := 0; i < opts.iterations; i++ {
+		start := time.Now()
+		specs, err := component.LoadRuntimeSpecs(opts.specsPath, platform, component.SkipBinaryCheck())
+		if err != nil {
+			return nil, errors.New(err, "failed to load the component specifications", errors.TypePath, errors.M(errors.MetaKeyPath, opts.specsPath))
+		}
+
+		renderStart := time.Now()
+		computed, err := computeRenderConfig(policy.Config, []*transpiler.Vars{vars})
+		if err != nil {
+			return nil, err
+		}
+
+		applyStart := time.Now()
+		comps, err := renderComponents(specs, computed, logp.InfoLevel, agentInfo, opts.monitoring)
+		if err != nil {
+			return nil, err
+		}
+		end := time.Now()
+
+		startup = append(startup, end.Sub(start))
+		renders = append(renders, applyStart.Sub(renderStart))
+		applies = append(applies, end.Sub(applyStart))
+
+		result.Components = len(comps)
+		result.Units = 0
+		for _, comp := range comps {
+			result.Units += len(comp.Units)
+		}
+	}
+	result.Startup = agentbench.Summarize(startup)
+	result.Render = agentbench.Summarize(renders)
+	result.Apply = agentbench.Summarize(applies)
+	return result, nil
+}
+
+// writeAgentbenchPolicy writes the policy and its variables in the formats read by the render command.
+func writeAgentbenchPolicy(dir string, policy *agentbench.Policy) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return errors.New(err, "failed to create the directory of the policy", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, dir))
+	}
+	files := map[string]interface{}{
+		"policy.yml":     policy.Config,
+		"variables.yaml": renderVarsFile{Variables: []map[string]interface{}{policy.Vars}},
+	}
+	for name, content := range files {
+		data, err := yaml.Marshal(content)
+		if err != nil {
+			return errors.New(err, "could not marshal to YAML")
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return errors.New(err, "failed to write the policy", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, path))
+		}
+	}
+	return nil
+}
+
+func humanAgentbenchOutput(w io.Writer, result *agentbenchResult) error {
+	fmt.Fprintf(w, "Policy: %d inputs, %d streams per input, %d variables, %d conditions\n",
+		result.Inputs, result.StreamsPerInput, result.Variables, result.Conditions)
+	fmt.Fprintf(w, "Components model: %d components, %d units on %s\n\n", result.Components, result.Units, result.Platform)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"STEP", "ITERATIONS", "MIN", "MEAN", "P50", "P95", "MAX"}, "\t"))
+	for _, step := range []struct {
+		name    string
+		summary agentbench.Summary
+	}{
+		{"startup", result.Startup},
+		{"render", result.Render},
+		{"apply", result.Apply},
+	} {
+		s := step.summary
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", step.name, s.Iterations,
+			s.Min.Round(time.Microsecond), s.Mean.Round(time.Microsecond), s.P50.Round(time.Microsecond),
+			s.P95.Round(time.Microsecond), s.Max.Round(time.Microsecond))
+	}
+	return tw.Flush()
+}
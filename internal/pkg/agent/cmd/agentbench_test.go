@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agentbench"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestAgentbench(t *testing.T) {
+	dir := t.TempDir()
+	opts := agentbenchOpts{
+		profile:    agentbench.Profile{Inputs: 6, StreamsPerInput: 2, Variables: 3, Conditions: 2},
+		iterations: 3,
+		specsPath:  filepath.Join("..", "..", "..", "..", "specs"),
+		platform:   "linux/amd64",
+		monitoring: false,
+		writeDir:   dir,
+		output:     "yaml",
+	}
+	out := &bytes.Buffer{}
+	require.NoError(t, runAgentbench(opts, &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}))
+
+	var result agentbenchResult
+	require.NoError(t, yaml.Unmarshal(out.Bytes(), &result))
+	assert.Equal(t, "linux/amd64", result.Platform)
+	// one component per input type, the false condition removes one of the inputs
+	assert.Equal(t, 2, result.Components)
+	assert.Equal(t, 2+5, result.Units, "one output unit per component and one unit per input")
+	for name, s := range map[string]agentbench.Summary{"startup": result.Startup, "render": result.Render, "apply": result.Apply} {
+		assert.Equal(t, 3, s.Iterations, name)
+		assert.Positive(t, s.Max, name)
+	}
+	assert.GreaterOrEqual(t, result.Startup.Min, result.Render.Min+result.Apply.Min)
+
+	// the written policy renders to the same components model
+	rendered, err := renderPolicy(renderOpts{
+		policyPath: filepath.Join(dir, "policy.yml"),
+		varsPath:   filepath.Join(dir, "variables.yaml"),
+		specsPath:  opts.specsPath,
+		now:        time.Now(),
+		platform:   opts.platform,
+		agentID:    renderAgentID,
+	})
+	require.NoError(t, err)
+	assert.Len(t, rendered.Components, result.Components)
+
+	out.Reset()
+	opts.output = "human"
+	opts.writeDir = ""
+	require.NoError(t, runAgentbench(opts, &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}))
+	assert.Contains(t, out.String(), "Policy: 6 inputs, 2 streams per input, 3 variables, 2 conditions")
+	assert.Contains(t, out.String(), "STEP")
+}
+
+func TestAgentbenchInvalidProfile(t *testing.T) {
+	err := runAgentbench(agentbenchOpts{profile: agentbench.Profile{Inputs: 1, Conditions: 1}, iterations: 1}, &cli.IOStreams{Out: &bytes.Buffer{}})
+	assert.ErrorContains(t, err, "conditions require at least one variable")
+}
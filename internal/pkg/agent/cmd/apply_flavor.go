@@ -24,7 +24,7 @@ func newApplyFlavorCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Co
 		Short: "Apply Flavor cleans up unnecessary components from agent installation directory",
 		Run: func(c *cobra.Command, _ []string) {
 			if err := applyCmd(); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				logExternal(fmt.Sprintf("%s apply flavor failed: %s", paths.BinaryName, err))
 				os.Exit(1)
 			}
@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/fs"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func newArtifactsCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "artifacts <subcommand>",
+		Short: "Manage the artifacts available for upgrades",
+		Long:  "Manage the agent and component packages available locally for upgrades without network access",
+	}
+
+	cmd.AddCommand(newArtifactsImportCommandWithArgs(args, streams))
+
+	return cmd
+}
+
+func newArtifactsImportCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import <bundle.tar>",
+		Short: "Import a bundle of signed packages into the drop path",
+		Long: `Import a tar bundle of agent and component packages, each with its .sha512 and .asc files, into the drop path.
+Every package is verified against its checksum and its signature before the bundle is imported.
+Upgrades then use the imported packages when Fleet sets the source URI to file://<drop path>, or
+when the drop path is the default one.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := artifactsImportCmd(streams, c, args[0]); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String("drop-path", paths.Downloads(), "Directory to import the packages into")
+
+	return cmd
+}
+
+func artifactsImportCmd(streams *cli.IOStreams, cmd *cobra.Command, bundlePath string) error {
+	dropPath, _ := cmd.Flags().GetString("drop-path")
+
+	log, err := logger.NewWithLogpLevel("", logp.ErrorLevel, false)
+	if err != nil {
+		return err
+	}
+
+	imported, err := fs.ImportBundle(log, bundlePath, dropPath, [][]byte{release.PGP()})
+	if err != nil {
+		return fmt.Errorf("failed to import bundle: %w", err)
+	}
+
+	for _, a := range imported {
+		fmt.Fprintf(streams.Out, "Imported %s\n", a.Name)
+	}
+	fmt.Fprintf(streams.Out, "%d package(s) available in %s\n", len(imported), dropPath)
+	return nil
+}
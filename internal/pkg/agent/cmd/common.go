@@ -15,6 +15,7 @@ import (
 	// import logp flags
 	_ "github.com/elastic/elastic-agent-libs/logp/configure"
 
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/basecmd"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
@@ -27,6 +28,16 @@ func troubleshootMessage() string {
 	return fmt.Sprintf("For help, please see our troubleshooting guide at https://www.elastic.co/guide/en/fleet/%s/fleet-troubleshooting.html", version)
 }
 
+// printCommandError writes the error of the command to the error stream, in the error format selected
+// with --error-format.
+func printCommandError(cmd *cobra.Command, streams *cli.IOStreams, err error) {
+	if flag := cmd.Flag(cli.ErrorFormatFlag); flag != nil && flag.Value.String() == cli.ErrorFormatJSON {
+		cli.WriteError(streams.Err, cli.ErrorFormatJSON, err)
+		return
+	}
+	fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+}
+
 // NewCommand returns the default command for the agent.
 func NewCommand() *cobra.Command {
 	return NewCommandWithArgs(os.Args, cli.NewIOStreams())
@@ -34,9 +45,13 @@ func NewCommand() *cobra.Command {
 
 // NewCommandWithArgs returns a new agent with the flags and the subcommand.
 func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	errorFormat := cli.ErrorFormatFromArgs(args)
 	cmd := &cobra.Command{
 		Use: "elastic-agent [subcommand]",
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := cli.ValidateErrorFormat(errorFormat); err != nil {
+				return err
+			}
 			if cmd.Name() == "container" {
 				// need to initialize container and try to chown agent-related paths
 				// before tryContainerLoadPaths as this will try to read/write from
@@ -69,6 +84,17 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.PersistentFlags().AddGoFlag(flag.CommandLine.Lookup("path.downloads"))
 	cmd.PersistentFlags().AddGoFlag(flag.CommandLine.Lookup("path.socket"))
 
+	// error output flags
+	cmd.PersistentFlags().String(cli.ErrorFormatFlag, cli.ErrorFormatText, "Format errors are written in, text or json")
+	if errorFormat == cli.ErrorFormatJSON {
+		// errors are written by the caller with WriteError, only in JSON
+		cmd.SilenceErrors = true
+		cmd.SilenceUsage = true
+	}
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return errors.New(err, errors.TypeValidation)
+	})
+
 	// logging flags
 	cmd.PersistentFlags().AddGoFlag(flag.CommandLine.Lookup("v"))
 	cmd.PersistentFlags().AddGoFlag(flag.CommandLine.Lookup("e"))
@@ -83,8 +109,11 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newInstallCommandWithArgs(args, streams))
 	cmd.AddCommand(newUninstallCommandWithArgs(args, streams))
 	cmd.AddCommand(newUpgradeCommandWithArgs(args, streams))
+	cmd.AddCommand(newMigrateCommandWithArgs(args, streams))
 	cmd.AddCommand(newEnrollCommandWithArgs(args, streams))
 	cmd.AddCommand(newInspectCommandWithArgs(args, streams))
+	cmd.AddCommand(newRenderCommandWithArgs(args, streams))
+	cmd.AddCommand(newAgentbenchCommandWithArgs(args, streams))
 	cmd.AddCommand(newPrivilegedCommandWithArgs(args, streams))
 	cmd.AddCommand(newUnprivilegedCommandWithArgs(args, streams))
 	cmd.AddCommand(newWatchCommandWithArgs(args, streams))
@@ -93,8 +122,14 @@ func NewCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
 	cmd.AddCommand(newDiagnosticsCommand(args, streams))
 	cmd.AddCommand(newComponentCommandWithArgs(args, streams))
 	cmd.AddCommand(newLogsCommandWithArgs(args, streams))
+	cmd.AddCommand(newTopCommandWithArgs(args, streams))
 	cmd.AddCommand(newOtelCommandWithArgs(args, streams))
 	cmd.AddCommand(newApplyFlavorCommandWithArgs(args, streams))
+	cmd.AddCommand(newArtifactsCommandWithArgs(args, streams))
+	cmd.AddCommand(newMaintenanceCommandWithArgs(args, streams))
+	cmd.AddCommand(newRelocateDataCommandWithArgs(args, streams))
+	cmd.AddCommand(newSecretsCommandWithArgs(args, streams))
+	cmd.AddCommand(newPolicyCommandWithArgs(args, streams))
 
 	// windows special hidden sub-command (only added on Windows)
 	reexec := newReExecWindowsCommand(args, streams)
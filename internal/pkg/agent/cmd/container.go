@@ -85,6 +85,7 @@ The following actions are possible and grouped based on the actions.
   FLEET_ENROLL - set to 1 for enrollment into Fleet Server. If not set, Elastic Agent is run in standalone mode.
   FLEET_URL - URL of the Fleet Server to enroll into
   FLEET_ENROLLMENT_TOKEN - token to use for enrollment. This is not needed in case FLEET_SERVER_ENABLED and FLEET_ENROLL is set. Then the token is fetched from Kibana.
+  FLEET_IDENTITY_PROVIDER - cloud provider of the instance identity exchanged with Fleet Server for an enrollment token, instead of FLEET_ENROLLMENT_TOKEN. Must be one of [aws, gcp, azure].
   FLEET_ENROLL_TIMEOUT - The timeout duration for the enroll commnd. Defaults to 10m. A negative value disables the timeout.
   FLEET_CA - path to certificate authority to use with communicate with Fleet Server [$KIBANA_CA]
   FLEET_INSECURE - communicate with Fleet with either insecure HTTP or unverified HTTPS
@@ -117,6 +118,8 @@ The following actions are possible and grouped based on the actions.
   FLEET_SERVER_CERT - path to certificate to use for HTTPS endpoint
   FLEET_SERVER_CERT_KEY - path to private key for certificate to use for HTTPS endpoint
   FLEET_SERVER_CERT_KEY_PASSPHRASE - path to private key passphrase file for certificate to use for HTTPS endpoint
+  FLEET_SERVER_CERT_SAN - comma-separated DNS names and IP addresses added to the self-signed certificate generated when FLEET_SERVER_CERT is not set
+  FLEET_SERVER_CERT_VALIDITY - validity of the self-signed certificate generated when FLEET_SERVER_CERT is not set. Default: 10 years.
   FLEET_SERVER_ES_CERT - path to certificate to use for connecting to Elasticsearch
   FLEET_SERVER_ES_CERT_KEY - path to private key for certificate to use for connecting to Elasticsearch
   FLEET_SERVER_CLIENT_AUTH - fleet-server mTLS client authentication for connecting elastic-agents. Must be one of [none, optional, required]. A default of none is used.
@@ -160,7 +163,7 @@ occurs on every start of the container set FLEET_FORCE to 1.
 `,
 		Run: func(c *cobra.Command, args []string) {
 			if err := logContainerCmd(streams); err != nil {
-				logError(streams, err)
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
@@ -191,7 +194,7 @@ func logContainerCmd(streams *cli.IOStreams) error {
 		}
 		defer w.Close()
 		streams.Out = io.MultiWriter(streams.Out, w)
-		streams.Err = io.MultiWriter(streams.Out, w)
+		streams.Err = io.MultiWriter(streams.Err, w)
 	}
 	return containerCmd(streams)
 }
@@ -314,7 +317,7 @@ func runContainerCmd(streams *cli.IOStreams, cfg setupConfig) error {
 
 		var policy *kibanaPolicy
 		token := cfg.Fleet.EnrollmentToken
-		if token == "" && !cfg.FleetServer.Enable {
+		if token == "" && cfg.Fleet.IdentityProvider == "" && !cfg.FleetServer.Enable {
 			client, err := kibanaClient(cfg.Kibana, cfg.Kibana.Headers)
 			if err != nil {
 				return err
@@ -489,6 +492,12 @@ func buildEnrollArgs(cfg setupConfig, token string, policyID string) ([]string,
 		if cfg.FleetServer.PassphrasePath != "" {
 			args = append(args, "--fleet-server-cert-key-passphrase", cfg.FleetServer.PassphrasePath)
 		}
+		for _, san := range cfg.FleetServer.CertSANs {
+			args = append(args, "--fleet-server-cert-san", san)
+		}
+		if cfg.FleetServer.CertValidity != 0 {
+			args = append(args, "--fleet-server-cert-validity", cfg.FleetServer.CertValidity.String())
+		}
 		if cfg.FleetServer.ClientAuth != "" {
 			args = append(args, "--fleet-server-client-auth", cfg.FleetServer.ClientAuth)
 		}
@@ -530,6 +539,8 @@ func buildEnrollArgs(cfg setupConfig, token string, policyID string) ([]string,
 	}
 	if token != "" {
 		args = append(args, "--enrollment-token", token)
+	} else if cfg.Fleet.IdentityProvider != "" {
+		args = append(args, "--identity-provider", cfg.Fleet.IdentityProvider)
 	}
 	if cfg.Fleet.ID != "" {
 		args = append(args, "--id", cfg.Fleet.ID)
@@ -6,9 +6,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
+	"slices"
 	"time"
 
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
@@ -25,19 +27,24 @@ func newDiagnosticsCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "diagnostics",
 		Short: "Gather diagnostics information from the Elastic Agent and write it to a zip archive",
-		Long:  "This command gathers diagnostics information from the Elastic Agent and writes it to a zip archive.",
+		Long: `This command gathers diagnostics information from the Elastic Agent and writes it to a zip archive.
+The passwords, tokens, keys and PEM blocks of the collected configurations and logs are redacted unless --no-redact is used.`,
 		Run: func(c *cobra.Command, args []string) {
 			if err := diagnosticCmd(streams, c); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
 	}
 
 	cmd.Flags().StringP("file", "f", "", "name of the output diagnostics zip archive")
-	cmd.Flags().BoolP("cpu-profile", "p", false, "wait to collect a CPU profile")
+	cmd.Flags().DurationP("cpu-profile", "p", 0, "wait to collect a CPU profile of the given duration, 30s when no duration is given (e.g. --cpu-profile=1m)")
+	cmd.Flags().Lookup("cpu-profile").NoOptDefVal = diagnostics.DiagCPUDuration.String()
+	cmd.Flags().Bool("profiles", false, "collect the heap, goroutine and, with --cpu-profile, CPU profiles of the components from their monitoring endpoints, requires agent.monitoring.pprof.enabled")
 	cmd.Flags().BoolP("skip-conn", "", false, "Skip connection request diagnostics")
 	cmd.Flags().Bool("exclude-events", false, "do not collect events log file")
+	cmd.Flags().Bool("no-redact", false, "do not redact the secrets from the files and logs of the archive")
+	cmd.Flags().StringSlice("redact", nil, "additional selectors of the keys to redact, e.g. *api_key*, a selector prefixed with ! excludes the matching keys")
 
 	return cmd
 }
@@ -54,6 +61,11 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		return fmt.Errorf("cannot get 'exclude-events' flag: %w", err)
 	}
 
+	redactor, err := diagnosticsRedactor(cmd)
+	if err != nil {
+		return err
+	}
+
 	ctx := handleSignal(context.Background())
 
 	// 1st create the file to store the diagnostics, if it fails, anything else
@@ -64,22 +76,33 @@ func diagnosticCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	}
 	defer f.Close()
 
-	cpuProfile, _ := cmd.Flags().GetBool("cpu-profile")
+	cpuProfile, err := cmd.Flags().GetDuration("cpu-profile")
+	if err != nil {
+		return fmt.Errorf("cannot get 'cpu-profile' flag: %w", err)
+	}
+	if cpuProfile < 0 {
+		return fmt.Errorf("the CPU profile duration must be positive: %s", cpuProfile)
+	}
+	profiles, _ := cmd.Flags().GetBool("profiles")
 	connSkip, _ := cmd.Flags().GetBool("skip-conn")
-	agentDiag, unitDiags, compDiags, err := collectDiagnostics(ctx, streams, cpuProfile, connSkip)
+	agentDiag, unitDiags, compDiags, err := collectDiagnostics(ctx, streams, cpuProfile, profiles, connSkip)
 	if err != nil {
 		return fmt.Errorf("failed collecting diagnostics: %w", err)
 	}
 
-	if err := diagnostics.ZipArchive(streams.Err, f, paths.Top(), agentDiag, unitDiags, compDiags, excludeEvents); err != nil {
+	if err := diagnostics.ZipArchive(streams.Err, f, paths.Top(), agentDiag, unitDiags, compDiags, excludeEvents, redactor); err != nil {
 		return fmt.Errorf("unable to create archive %q: %w", filepath, err)
 	}
 	fmt.Fprintf(streams.Out, "Created diagnostics archive %q\n", filepath)
-	fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive may contain plain text credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
+	if redactor == nil {
+		fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive contains plain text credentials.\nEnsure that files in archive are redacted before sharing.\n*******************")
+	} else {
+		fmt.Fprintln(streams.Out, "***** WARNING *****\nCreated archive may still contain credentials that could not be detected.\nEnsure that files in archive are redacted before sharing.\n*******************")
+	}
 	return nil
 }
 
-func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile, connSkip bool) ([]client.DiagnosticFileResult, []client.DiagnosticUnitResult, []client.DiagnosticComponentResult, error) {
+func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile time.Duration, profiles, connSkip bool) ([]client.DiagnosticFileResult, []client.DiagnosticUnitResult, []client.DiagnosticComponentResult, error) {
 	daemon := client.New()
 	err := daemon.Connect(ctx)
 	if err != nil {
@@ -87,17 +110,13 @@ func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile,
 	}
 	defer daemon.Disconnect()
 
-	var additionalDiags []cproto.AdditionalDiagnosticRequest
-	if !connSkip {
-		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_CONN)
-	}
-	if cpuProfile {
+	if cpuProfile > 0 {
 		// console will just hang while we wait for the CPU profile; print something so user doesn't get confused
-		fmt.Fprintf(streams.Out, "Creating diagnostics archive, waiting for CPU profile...\n")
-		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_CPU)
+		fmt.Fprintf(streams.Out, "Creating diagnostics archive, waiting %s for CPU profile...\n", cpuProfile)
 	}
+	additionalDiags := additionalDiagnostics(cpuProfile, profiles, connSkip)
 
-	agentDiag, err := daemon.DiagnosticAgent(ctx, additionalDiags)
+	agentDiag, err := daemon.DiagnosticAgent(ctx, additionalDiags, cpuProfile)
 	if err != nil {
 		fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch agent diagnostics: %s", err)
 	}
@@ -107,7 +126,7 @@ func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile,
 		fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch unit diagnostics: %s", err)
 	}
 
-	compDiags, err := daemon.DiagnosticComponents(ctx, additionalDiags)
+	compDiags, err := daemon.DiagnosticComponents(ctx, additionalDiags, cpuProfile)
 	if err != nil {
 		fmt.Fprintf(streams.Err, "[WARNING]: failed to fetch component diagnostics: %s", err)
 	}
@@ -119,6 +138,41 @@ func collectDiagnostics(ctx context.Context, streams *cli.IOStreams, cpuProfile,
 	return agentDiag, unitDiags, compDiags, nil
 }
 
+// diagnosticsRedactor returns the redactor of the archive for the flags of the command, nil when nothing must be
+// redacted.
+func diagnosticsRedactor(cmd *cobra.Command) (*diagnostics.Redactor, error) {
+	noRedact, err := cmd.Flags().GetBool("no-redact")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get 'no-redact' flag: %w", err)
+	}
+	selectors, err := cmd.Flags().GetStringSlice("redact")
+	if err != nil {
+		return nil, fmt.Errorf("cannot get 'redact' flag: %w", err)
+	}
+	if noRedact {
+		if len(selectors) > 0 {
+			return nil, errors.New("--redact cannot be used with --no-redact")
+		}
+		return nil, nil
+	}
+	return diagnostics.NewRedactor(append(slices.Clone(diagnostics.DefaultRedactSelectors), selectors...)...), nil
+}
+
+// additionalDiagnostics returns the additional diagnostics to request for the flags of the command.
+func additionalDiagnostics(cpuProfile time.Duration, profiles, connSkip bool) []cproto.AdditionalDiagnosticRequest {
+	var additionalDiags []cproto.AdditionalDiagnosticRequest
+	if !connSkip {
+		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_CONN)
+	}
+	if cpuProfile > 0 {
+		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_CPU)
+	}
+	if profiles {
+		additionalDiags = append(additionalDiags, cproto.AdditionalDiagnosticRequest_PROFILES)
+	}
+	return additionalDiags
+}
+
 func createFile(filepath string) (*os.File, error) {
 	// Ensure all the folders on filepath exist as os.Create does not do so.
 	// 0777 is the same permission, before unmask, os.Create uses.
@@ -7,9 +7,17 @@ package cmd
 import (
 	"os"
 	"path"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 )
 
 func Test_createFile(t *testing.T) {
@@ -49,3 +57,60 @@ func Test_createFile(t *testing.T) {
 		})
 	}
 }
+
+func TestDiagnosticsCPUProfileFlag(t *testing.T) {
+	testCases := []struct {
+		args    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{args: "", want: 0},
+		{args: "--cpu-profile", want: diagnostics.DiagCPUDuration},
+		{args: "-p", want: diagnostics.DiagCPUDuration},
+		{args: "--cpu-profile=1m", want: time.Minute},
+		{args: "--cpu-profile=1x", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.args, func(t *testing.T) {
+			cmd := newDiagnosticsCommand(nil, cli.NewIOStreams())
+			err := cmd.ParseFlags(strings.Fields(tc.args))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			got, err := cmd.Flags().GetDuration("cpu-profile")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestAdditionalDiagnostics(t *testing.T) {
+	assert.Equal(t, []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CONN}, additionalDiagnostics(0, false, false))
+	assert.Empty(t, additionalDiagnostics(0, false, true))
+	assert.Equal(t,
+		[]cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CPU, cproto.AdditionalDiagnosticRequest_PROFILES},
+		additionalDiagnostics(time.Minute, true, true))
+}
+
+func TestDiagnosticsRedactor(t *testing.T) {
+	cmd := newDiagnosticsCommand(nil, cli.NewIOStreams())
+	require.NoError(t, cmd.ParseFlags([]string{"--redact", "db.*,!db.name"}))
+	redactor, err := diagnosticsRedactor(cmd)
+	require.NoError(t, err)
+	require.NotNil(t, redactor)
+	assert.Equal(t, diagnostics.NewRedactor(append(slices.Clone(diagnostics.DefaultRedactSelectors), "db.*", "!db.name")...), redactor)
+
+	cmd = newDiagnosticsCommand(nil, cli.NewIOStreams())
+	require.NoError(t, cmd.ParseFlags([]string{"--no-redact"}))
+	redactor, err = diagnosticsRedactor(cmd)
+	require.NoError(t, err)
+	assert.Nil(t, redactor)
+
+	cmd = newDiagnosticsCommand(nil, cli.NewIOStreams())
+	require.NoError(t, cmd.ParseFlags([]string{"--no-redact", "--redact", "db.*"}))
+	_, err = diagnosticsRedactor(cmd)
+	assert.Error(t, err)
+}
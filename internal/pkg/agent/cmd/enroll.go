@@ -45,7 +45,7 @@ func newEnrollCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 		Long:  "This command will enroll the Elastic Agent into Fleet.",
 		Run: func(c *cobra.Command, args []string) {
 			if err := doEnroll(streams, c); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				logExternal(fmt.Sprintf("%s enroll failed: %s", paths.BinaryName, err))
 				os.Exit(1)
 			}
@@ -54,6 +54,7 @@ func newEnrollCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 
 	addEnrollFlags(cmd)
 	cmd.Flags().BoolP("force", "f", false, "Force overwrite the current and do not prompt for confirmation")
+	cmd.Flags().String(flagUninstallToken, "", "Uninstall token required to restart a tamper protected agent once enrolled")
 
 	// used by install command
 	cmd.Flags().BoolP(fromInstallArg, "", false, "Set by install command to signal this was executed from install")
@@ -68,6 +69,7 @@ func newEnrollCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 func addEnrollFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("url", "", "", "URL to enroll Agent into Fleet")
 	cmd.Flags().StringP("enrollment-token", "t", "", "Enrollment token to use to enroll Agent into Fleet")
+	cmd.Flags().StringP("identity-provider", "", "", "Cloud provider of the instance identity exchanged for an enrollment token, instead of --enrollment-token. Must be one of [aws, gcp, azure]")
 	cmd.Flags().StringP("id", "", "", "Agent ID to use for enrollment into Fleet")
 	cmd.Flags().StringP("replace-token", "", "", "Replace token that that allows the Agent to be replace with the provided --id as long as this token matches")
 	cmd.Flags().StringP("fleet-server-es", "", "", "Start and run a Fleet Server alongside this Elastic Agent connecting to the provided Elasticsearch")
@@ -84,6 +86,8 @@ func addEnrollFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("fleet-server-cert", "", "", "Certificate for Fleet Server to use for exposed HTTPS endpoint")
 	cmd.Flags().StringP("fleet-server-cert-key", "", "", "Private key for the certificate used by Fleet Server for exposed HTTPS endpoint")
 	cmd.Flags().StringP("fleet-server-cert-key-passphrase", "", "", "Path for private key passphrase file used to decrypt Fleet Server certificate key")
+	cmd.Flags().StringSliceP("fleet-server-cert-san", "", []string{}, "Additional DNS names and IP addresses of the self-signed certificate generated for Fleet Server when no certificate is provided")
+	cmd.Flags().DurationP("fleet-server-cert-validity", "", 0, "Validity of the self-signed certificate generated for Fleet Server when no certificate is provided (default 10 years)")
 	cmd.Flags().StringP("fleet-server-client-auth", "", "none", "Fleet Server mTLS client authentication for connecting Elastic Agents. Must be one of [none, optional, required]")
 	cmd.Flags().StringSliceP("header", "", []string{}, "Headers used by Agent to communicate with Fleet Server, and when a bootstrapped Fleet Server communicates with Elasticsearch")
 	cmd.Flags().BoolP("fleet-server-insecure-http", "", false, "Expose Fleet Server over HTTP (not recommended; insecure)")
@@ -92,12 +96,14 @@ func addEnrollFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("elastic-agent-cert", "", "", "Elastic Agent client certificate to use with Fleet Server during mTLS authentication")
 	cmd.Flags().StringP("elastic-agent-cert-key", "", "", "Elastic Agent client private key to use with Fleet Server during mTLS authentication")
 	cmd.Flags().StringP("elastic-agent-cert-key-passphrase", "", "", "Path for private key passphrase file used to decrypt Elastic Agent client certificate key")
+	cmd.Flags().BoolP("elastic-agent-cert-csr", "", false, "Request the Elastic Agent client certificate for the mTLS authentication from Fleet with a certificate signing request, the certificate is stored in the vault and renewed before it expires")
 	cmd.Flags().BoolP("insecure", "i", false, "Allow insecure connection made by the Elastic Agent. It's also required to use a Fleet Server on a HTTP endpoint")
 	cmd.Flags().StringP("staging", "", "", "Configures Elastic Agent to download artifacts from a staging build")
 	cmd.Flags().StringP("proxy-url", "", "", "Configures the proxy URL: when bootstrapping Fleet Server, it's the proxy used by Fleet Server to connect to Elasticsearch; when enrolling the Elastic Agent to Fleet Server, it's the proxy used by the Elastic Agent to connect to Fleet Server")
 	cmd.Flags().BoolP("proxy-disabled", "", false, "Disable proxy support including environment variables: when bootstrapping Fleet Server, it's the proxy used by Fleet Server to connect to Elasticsearch; when enrolling the Elastic Agent to Fleet Server, it's the proxy used by the Elastic Agent to connect to Fleet Server")
 	cmd.Flags().StringSliceP("proxy-header", "", []string{}, "Proxy headers used with CONNECT request: when bootstrapping Fleet Server, it's the proxy used by Fleet Server to connect to Elasticsearch; when enrolling the Elastic Agent to Fleet Server, it's the proxy used by the Elastic Agent to connect to Fleet Server")
 	cmd.Flags().BoolP("delay-enroll", "", false, "Delays enrollment to occur on first start of the Elastic Agent service")
+	cmd.Flags().BoolP("staged", "", false, "Validates and stores the enrollment parameters without contacting Fleet, the enrollment completes on first start of the Elastic Agent service. Unlike --delay-enroll, the agent secret is only created on first start, for images cloned to many hosts")
 	cmd.Flags().DurationP("daemon-timeout", "", 0, "Timeout waiting for Elastic Agent daemon")
 	cmd.Flags().DurationP("enroll-timeout", "", 10*time.Minute, "Timeout waiting for Elastic Agent enroll command. A negative value disables the timeout.")
 	cmd.Flags().DurationP("fleet-server-timeout", "", 0, "When bootstrapping Fleet Server, timeout waiting for Fleet Server to be ready to start enrollment")
@@ -130,6 +136,23 @@ func validateEnrollFlags(cmd *cobra.Command) error {
 			return errors.New("--elastic-agent-cert and --elastic-agent-cert-key must be provided when using --elastic-agent-cert-key-passphrase", errors.M("path", keyPassphrase), errors.TypeConfig)
 		}
 	}
+	if csr, _ := cmd.Flags().GetBool("elastic-agent-cert-csr"); csr {
+		if cert != "" || key != "" {
+			return errors.New("--elastic-agent-cert-csr cannot be used with --elastic-agent-cert or --elastic-agent-cert-key", errors.TypeConfig)
+		}
+		if fServer, _ := cmd.Flags().GetString("fleet-server-es"); fServer != "" {
+			return errors.New("--elastic-agent-cert-csr cannot be used with --fleet-server-es", errors.TypeConfig)
+		}
+	}
+	identityProvider, _ := cmd.Flags().GetString("identity-provider")
+	if identityProvider != "" {
+		if err := enroll.ValidateIdentityProvider(identityProvider); err != nil {
+			return errors.New(err, errors.TypeConfig)
+		}
+		if token, _ := cmd.Flags().GetString("enrollment-token"); token != "" {
+			return errors.New("--identity-provider cannot be used with --enrollment-token", errors.TypeConfig)
+		}
+	}
 	esCa, _ := cmd.Flags().GetString("fleet-server-es-ca")
 	if esCa != "" && !filepath.IsAbs(esCa) {
 		return errors.New("--fleet-server-es-ca must be provided as an absolute path", errors.M("path", esCa), errors.TypeConfig)
@@ -162,6 +185,19 @@ func validateEnrollFlags(cmd *cobra.Command) error {
 	if fPassphrase != "" && !filepath.IsAbs(fPassphrase) {
 		return errors.New("--fleet-server-cert-key-passphrase must be provided as an absolute path", errors.M("path", fPassphrase), errors.TypeConfig)
 	}
+	fCertSANs, _ := cmd.Flags().GetStringSlice("fleet-server-cert-san")
+	fCertValidity, _ := cmd.Flags().GetDuration("fleet-server-cert-validity")
+	if fCertValidity < 0 {
+		return errors.New("--fleet-server-cert-validity must be positive", errors.TypeConfig)
+	}
+	if fCert != "" && (len(fCertSANs) > 0 || fCertValidity != 0) {
+		return errors.New("--fleet-server-cert-san and --fleet-server-cert-validity only apply to the generated certificate and cannot be used with --fleet-server-cert", errors.TypeConfig)
+	}
+	if staged, _ := cmd.Flags().GetBool("staged"); staged {
+		if fServer, _ := cmd.Flags().GetString("fleet-server-es"); fServer != "" {
+			return errors.New("--staged cannot be used with --fleet-server-es", errors.TypeConfig)
+		}
+	}
 	fClientAuth, _ := cmd.Flags().GetString("fleet-server-client-auth")
 	switch fClientAuth {
 	case "none", "optional", "required":
@@ -179,6 +215,7 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	if token == "" {
 		token, _ = cmd.Flags().GetString("enrollment-token")
 	}
+	identityProvider, _ := cmd.Flags().GetString("identity-provider")
 	id, _ := cmd.Flags().GetString("id")
 	replaceToken, _ := cmd.Flags().GetString("replace-token")
 	fServer, _ := cmd.Flags().GetString("fleet-server-es")
@@ -195,6 +232,8 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	fCert, _ := cmd.Flags().GetString("fleet-server-cert")
 	fCertKey, _ := cmd.Flags().GetString("fleet-server-cert-key")
 	fPassphrase, _ := cmd.Flags().GetString("fleet-server-cert-key-passphrase")
+	fCertSANs, _ := cmd.Flags().GetStringSlice("fleet-server-cert-san")
+	fCertValidity, _ := cmd.Flags().GetDuration("fleet-server-cert-validity")
 	fClientAuth, _ := cmd.Flags().GetString("fleet-server-client-auth")
 	fHeaders, _ := cmd.Flags().GetStringSlice("header")
 	fInsecure, _ := cmd.Flags().GetBool("fleet-server-insecure-http")
@@ -202,6 +241,7 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	cert, _ := cmd.Flags().GetString("elastic-agent-cert")
 	key, _ := cmd.Flags().GetString("elastic-agent-cert-key")
 	keyPassphrase, _ := cmd.Flags().GetString("elastic-agent-cert-key-passphrase")
+	certCSR, _ := cmd.Flags().GetBool("elastic-agent-cert-csr")
 	sha256, _ := cmd.Flags().GetString("ca-sha256")
 	insecure, _ := cmd.Flags().GetBool("insecure")
 	staging, _ := cmd.Flags().GetString("staging")
@@ -209,6 +249,7 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	fProxyDisabled, _ := cmd.Flags().GetBool("proxy-disabled")
 	fProxyHeaders, _ := cmd.Flags().GetStringSlice("proxy-header")
 	delayEnroll, _ := cmd.Flags().GetBool("delay-enroll")
+	staged, _ := cmd.Flags().GetBool("staged")
 	daemonTimeout, _ := cmd.Flags().GetDuration("daemon-timeout")
 	enrollTimeout, _ := cmd.Flags().GetDuration("enroll-timeout")
 	fTimeout, _ := cmd.Flags().GetDuration("fleet-server-timeout")
@@ -223,6 +264,10 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 		args = append(args, "--enrollment-token")
 		args = append(args, token)
 	}
+	if identityProvider != "" {
+		args = append(args, "--identity-provider")
+		args = append(args, identityProvider)
+	}
 	if id != "" {
 		args = append(args, "--id")
 		args = append(args, id)
@@ -283,6 +328,14 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 		args = append(args, "--fleet-server-cert-key-passphrase")
 		args = append(args, fPassphrase)
 	}
+	for _, san := range fCertSANs {
+		args = append(args, "--fleet-server-cert-san")
+		args = append(args, san)
+	}
+	if fCertValidity != 0 {
+		args = append(args, "--fleet-server-cert-validity")
+		args = append(args, fCertValidity.String())
+	}
 	if fClientAuth != "" {
 		args = append(args, "--fleet-server-client-auth")
 		args = append(args, fClientAuth)
@@ -324,6 +377,9 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 		args = append(args, "--elastic-agent-cert-key-passphrase")
 		args = append(args, keyPassphrase)
 	}
+	if certCSR {
+		args = append(args, "--elastic-agent-cert-csr")
+	}
 	if sha256 != "" {
 		args = append(args, "--ca-sha256")
 		args = append(args, sha256)
@@ -346,12 +402,15 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 	}
 	for k, v := range mapFromEnvList(fProxyHeaders) {
 		args = append(args, "--proxy-header")
-		args = append(args, k+"="+v)
+		args = append(args, sliceFlagValue(k+"="+v))
 	}
 
 	if delayEnroll {
 		args = append(args, "--delay-enroll")
 	}
+	if staged {
+		args = append(args, "--staged")
+	}
 
 	if fElasticSearchInsecure {
 		args = append(args, "--fleet-server-es-insecure")
@@ -361,11 +420,20 @@ func buildEnrollmentFlags(cmd *cobra.Command, url string, token string) []string
 		args = append(args, "--skip-daemon-reload")
 	}
 	for _, v := range fTags {
-		args = append(args, "--tag", v)
+		args = append(args, "--tag", sliceFlagValue(v))
 	}
 	return args
 }
 
+// sliceFlagValue quotes the value of a list flag, the flag parses its values as CSV and would split a value
+// containing a comma.
+func sliceFlagValue(v string) string {
+	if !strings.ContainsAny(v, ",\"\n") {
+		return v
+	}
+	return `"` + strings.ReplaceAll(v, `"`, `""`) + `"`
+}
+
 // getFileOwnFromCmdFunc, getOwnerFromPathFunc and computeFixPermissions are for
 // testability. Instead of directly executing the code block in doEnroll, we
 // are calling computeFixPermissions. computeFixPermissions is tested on its own.
@@ -462,6 +530,7 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 	insecure, _ := cmd.Flags().GetBool("insecure")
 	url, _ := cmd.Flags().GetString("url")
 	enrollmentToken, _ := cmd.Flags().GetString("enrollment-token")
+	identityProvider, _ := cmd.Flags().GetString("identity-provider")
 	id, _ := cmd.Flags().GetString("id")
 	replaceToken, _ := cmd.Flags().GetString("replace-token")
 	fServer, _ := cmd.Flags().GetString("fleet-server-es")
@@ -480,16 +549,20 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 	fCert, _ := cmd.Flags().GetString("fleet-server-cert")
 	fCertKey, _ := cmd.Flags().GetString("fleet-server-cert-key")
 	fPassphrase, _ := cmd.Flags().GetString("fleet-server-cert-key-passphrase")
+	fCertSANs, _ := cmd.Flags().GetStringSlice("fleet-server-cert-san")
+	fCertValidity, _ := cmd.Flags().GetDuration("fleet-server-cert-validity")
 	fClientAuth, _ := cmd.Flags().GetString("fleet-server-client-auth")
 	fInsecure, _ := cmd.Flags().GetBool("fleet-server-insecure-http")
 	proxyURL, _ := cmd.Flags().GetString("proxy-url")
 	proxyDisabled, _ := cmd.Flags().GetBool("proxy-disabled")
 	proxyHeaders, _ := cmd.Flags().GetStringSlice("proxy-header")
 	delayEnroll, _ := cmd.Flags().GetBool("delay-enroll")
+	staged, _ := cmd.Flags().GetBool("staged")
 	daemonTimeout, _ := cmd.Flags().GetDuration("daemon-timeout")
 	enrollTimeout, _ := cmd.Flags().GetDuration("enroll-timeout")
 	fTimeout, _ := cmd.Flags().GetDuration("fleet-server-timeout")
 	skipDaemonReload, _ := cmd.Flags().GetBool("skip-daemon-reload")
+	uninstallToken, _ := cmd.Flags().GetString(flagUninstallToken)
 	tags, _ := cmd.Flags().GetStringSlice("tag")
 
 	caStr, _ := cmd.Flags().GetString("certificate-authorities")
@@ -499,6 +572,7 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 	cert, _ := cmd.Flags().GetString("elastic-agent-cert")
 	key, _ := cmd.Flags().GetString("elastic-agent-cert-key")
 	keyPassphrase, _ := cmd.Flags().GetString("elastic-agent-cert-key-passphrase")
+	certCSR, _ := cmd.Flags().GetBool("elastic-agent-cert-csr")
 
 	ctx := handleSignal(context.Background())
 
@@ -520,6 +594,7 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	options := enroll.EnrollOptions{
 		EnrollAPIKey:         enrollmentToken,
+		IdentityProvider:     identityProvider,
 		ID:                   id,
 		ReplaceToken:         replaceToken,
 		URL:                  url,
@@ -528,6 +603,7 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 		Certificate:          cert,
 		Key:                  key,
 		KeyPassphrasePath:    keyPassphrase,
+		CertificateRequest:   certCSR,
 		Insecure:             insecure,
 		UserProvidedMetadata: make(map[string]interface{}),
 		Staging:              staging,
@@ -536,9 +612,12 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 		ProxyURL:             proxyURL,
 		ProxyDisabled:        proxyDisabled,
 		ProxyHeaders:         mapFromEnvList(proxyHeaders),
-		DelayEnroll:          delayEnroll,
+		DelayEnroll:          delayEnroll || staged,
+		Staged:               staged,
+		SkipCreateSecret:     staged,
 		DaemonTimeout:        daemonTimeout,
 		SkipDaemonRestart:    skipDaemonReload,
+		UninstallToken:       uninstallToken,
 		Tags:                 tags,
 		FleetServer: enroll.EnrollCmdFleetServerOption{
 			ConnStr:               fServer,
@@ -555,6 +634,8 @@ func doEnroll(streams *cli.IOStreams, cmd *cobra.Command) error {
 			Cert:                  fCert,
 			CertKey:               fCertKey,
 			CertKeyPassphrasePath: fPassphrase,
+			CertSANs:              fCertSANs,
+			CertValidity:          fCertValidity,
 			ClientAuth:            fClientAuth,
 			Insecure:              fInsecure,
 			SpawnAgent:            !fromInstall,
@@ -16,6 +16,8 @@ import (
 	"time"
 
 	"go.elastic.co/apm/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/enroll"
@@ -86,7 +88,7 @@ func newEnrollCmd(
 		options:          options,
 		configStore:      store,
 		configPath:       configPath,
-		daemonReloadFunc: daemonReload,
+		daemonReloadFunc: func(ctx context.Context) error { return daemonReload(ctx, options.UninstallToken) },
 		backoffFactory:   backoffFactory,
 	}, nil
 }
@@ -157,6 +159,12 @@ func (c *enrollCmd) Execute(ctx context.Context, streams *cli.IOStreams) error {
 		if c.options.FleetServer.Host != "" {
 			return errors.New("--delay-enroll cannot be used with --fleet-server-es", errors.TypeConfig)
 		}
+		if c.options.Staged {
+			// nothing can be checked against Fleet, at least the options must allow to enroll on first start
+			if err = c.options.ValidateStaged(c.log); err != nil {
+				return errors.New(err, "invalid staged enrollment", errors.TypeConfig)
+			}
+		}
 		err = c.writeDelayEnroll(streams)
 		if err != nil {
 			// context for error already provided in writeDelayEnroll
@@ -228,6 +236,10 @@ func (c *enrollCmd) writeDelayEnroll(streams *cli.IOStreams) error {
 			errors.TypeFilesystem,
 			errors.M("path", enrollPath))
 	}
+	if c.options.Staged {
+		fmt.Fprintf(streams.Out, "Successfully staged the enrollment of the Elastic Agent in %s, it completes on the first start of the Elastic Agent.\n", enrollPath)
+		return nil
+	}
 	fmt.Fprintf(streams.Out, "Successfully wrote %s for delayed enrollment of the Elastic Agent.\n", enrollPath)
 	return nil
 }
@@ -347,7 +359,11 @@ func (c *enrollCmd) prepareFleetTLS() error {
 		if err != nil {
 			return err
 		}
-		pair, err := ca.GeneratePairWithName(hostname)
+		pairOpts := []authority.PairOption{authority.WithSANs(c.options.FleetServer.CertSANs...)}
+		if c.options.FleetServer.CertValidity > 0 {
+			pairOpts = append(pairOpts, authority.WithValidity(c.options.FleetServer.CertValidity))
+		}
+		pair, err := ca.GeneratePairWithName(hostname, pairOpts...)
 		if err != nil {
 			return err
 		}
@@ -396,6 +412,10 @@ func (c *enrollCmd) daemonReloadWithBackoff(ctx context.Context) error {
 			return fmt.Errorf("could not reload daemon after %d retries: %w",
 				attempt, err)
 		}
+		// the tamper protected agent refuses the restart without a valid uninstall token, retrying cannot help
+		if status.Code(err) == codes.PermissionDenied {
+			return fmt.Errorf("could not reload daemon, provide the uninstall token with --%s: %w", flagUninstallToken, err)
+		}
 		lastErr = err
 
 		c.log.Errorf("Restart attempt %d failed: '%s'. Waiting for %s", attempt, err, backExp.NextWait().String())
@@ -408,8 +428,9 @@ func (c *enrollCmd) daemonReloadWithBackoff(ctx context.Context) error {
 	return fmt.Errorf("could not reload agent's daemon, all retries failed. Last error: %w", lastErr)
 }
 
-func daemonReload(ctx context.Context) error {
-	daemon := client.New()
+// daemonReload restarts the running daemon, the restart of a tamper protected agent requires the uninstall token.
+func daemonReload(ctx context.Context, uninstallToken string) error {
+	daemon := client.New(client.WithUninstallToken(uninstallToken))
 	err := daemon.Connect(ctx)
 	if err != nil {
 		return err
@@ -27,9 +27,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/elastic/elastic-agent-libs/testing/certutil"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/enroll"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
@@ -542,6 +545,37 @@ func TestEnroll(t *testing.T) {
 			assert.Equal(t, host, config.Client.Host)
 		},
 	))
+
+	t.Run("staged enrollment is validated and stored without contacting Fleet", func(t *testing.T) {
+		configPath := paths.Config()
+		paths.SetConfig(t.TempDir())
+		defer paths.SetConfig(configPath)
+
+		store := &mockStore{}
+		options := &enroll.EnrollOptions{
+			URL:              "https://fleet.example.com:8220",
+			DelayEnroll:      true,
+			Staged:           true,
+			SkipCreateSecret: true,
+		}
+		cmd, err := newEnrollCmd(log, options, "", store, nil)
+		require.NoError(t, err)
+
+		streams, _, _, _ := cli.NewTestingIOStreams()
+		err = cmd.Execute(t.Context(), streams)
+		assert.ErrorContains(t, err, "missing enrollment token or identity provider")
+		assert.NoFileExists(t, paths.AgentEnrollFile())
+
+		options.EnrollAPIKey = "my-enrollment-token"
+		err = cmd.Execute(t.Context(), streams)
+		require.NoError(t, err)
+		assert.False(t, store.Called, "the configuration should be stored on first start")
+
+		contents, err := os.ReadFile(paths.AgentEnrollFile())
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), "url: https://fleet.example.com:8220")
+		assert.Contains(t, string(contents), "enrollment_key: my-enrollment-token")
+	})
 }
 
 func TestValidateArgs(t *testing.T) {
@@ -654,6 +688,20 @@ func TestValidateArgs(t *testing.T) {
 		require.Contains(t, args, "1m0s")
 	})
 
+	t.Run("fleet-server generated certificate options are passed", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("fleet-server-cert-san", "fleet.example.com,10.0.0.1")
+		require.NoError(t, err)
+		err = cmd.Flags().Set("fleet-server-cert-validity", "8760h")
+		require.NoError(t, err)
+		args := buildEnrollmentFlags(cmd, url, enrolmentToken)
+		require.Contains(t, args, "--fleet-server-cert-san")
+		require.Contains(t, args, "fleet.example.com")
+		require.Contains(t, args, "10.0.0.1")
+		require.Contains(t, args, "--fleet-server-cert-validity")
+		require.Contains(t, args, "8760h0m0s")
+	})
+
 	t.Run("negative enroll-timeout value is passed", func(t *testing.T) {
 		cmd := newEnrollCommandWithArgs([]string{}, streams)
 		err := cmd.Flags().Set("enroll-timeout", "-1s")
@@ -692,6 +740,24 @@ func TestValidateEnrollFlags(t *testing.T) {
 		assert.Equal(t, errors.TypeConfig, agentErr.Type())
 	})
 
+	t.Run("generated certificate options cannot be used with a certificate", func(t *testing.T) {
+		absPath, err := filepath.Abs("/path/to/fleet-server-cert")
+		require.NoError(t, err, "could not get absolute absPath")
+
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err = cmd.Flags().Set("fleet-server-cert", absPath)
+		require.NoError(t, err)
+		err = cmd.Flags().Set("fleet-server-cert-san", "fleet.example.com")
+		require.NoError(t, err)
+
+		err = validateEnrollFlags(cmd)
+		assert.Error(t, err)
+
+		var agentErr errors.Error
+		assert.ErrorAs(t, err, &agentErr)
+		assert.Equal(t, errors.TypeConfig, agentErr.Type())
+	})
+
 	t.Run("elastic-agent-cert-key does not require key-passphrase", func(t *testing.T) {
 		absPath, err := filepath.Abs("/path/to/elastic-agent-cert-key")
 		require.NoError(t, err, "could not get absolute absPath")
@@ -720,6 +786,77 @@ func TestValidateEnrollFlags(t *testing.T) {
 		assert.ErrorAs(t, err, &agentErr)
 		assert.Equal(t, errors.TypeConfig, agentErr.Type())
 	})
+
+	t.Run("staged cannot be used with fleet-server-es", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("staged", "true")
+		require.NoError(t, err)
+		err = cmd.Flags().Set("fleet-server-es", "https://localhost:9200")
+		require.NoError(t, err)
+
+		err = validateEnrollFlags(cmd)
+		assert.Error(t, err)
+		var agentErr errors.Error
+		assert.ErrorAs(t, err, &agentErr)
+		assert.Equal(t, errors.TypeConfig, agentErr.Type())
+	})
+
+	t.Run("identity-provider must be known", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("identity-provider", "aws")
+		require.NoError(t, err)
+		assert.NoError(t, validateEnrollFlags(cmd))
+
+		err = cmd.Flags().Set("identity-provider", "oci")
+		require.NoError(t, err)
+		err = validateEnrollFlags(cmd)
+		assert.ErrorContains(t, err, "unknown identity provider")
+		var agentErr errors.Error
+		assert.ErrorAs(t, err, &agentErr)
+		assert.Equal(t, errors.TypeConfig, agentErr.Type())
+	})
+
+	t.Run("elastic-agent-cert-csr cannot be used with a certificate", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("elastic-agent-cert-csr", "true")
+		require.NoError(t, err)
+		assert.NoError(t, validateEnrollFlags(cmd))
+
+		absPath, err := filepath.Abs("/path/to/elastic-agent-cert")
+		require.NoError(t, err)
+		err = cmd.Flags().Set("elastic-agent-cert", absPath)
+		require.NoError(t, err)
+		err = validateEnrollFlags(cmd)
+		assert.ErrorContains(t, err, "--elastic-agent-cert-csr cannot be used with --elastic-agent-cert")
+		var agentErr errors.Error
+		assert.ErrorAs(t, err, &agentErr)
+		assert.Equal(t, errors.TypeConfig, agentErr.Type())
+	})
+
+	t.Run("elastic-agent-cert-csr cannot be used with fleet-server-es", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("elastic-agent-cert-csr", "true")
+		require.NoError(t, err)
+		err = cmd.Flags().Set("fleet-server-es", "https://localhost:9200")
+		require.NoError(t, err)
+
+		err = validateEnrollFlags(cmd)
+		assert.ErrorContains(t, err, "--elastic-agent-cert-csr cannot be used with --fleet-server-es")
+	})
+
+	t.Run("identity-provider and enrollment-token are mutually exclusive", func(t *testing.T) {
+		cmd := newEnrollCommandWithArgs([]string{}, streams)
+		err := cmd.Flags().Set("identity-provider", "gcp")
+		require.NoError(t, err)
+		err = cmd.Flags().Set("enrollment-token", "token-value")
+		require.NoError(t, err)
+
+		err = validateEnrollFlags(cmd)
+		assert.Error(t, err)
+		var agentErr errors.Error
+		assert.ErrorAs(t, err, &agentErr)
+		assert.Equal(t, errors.TypeConfig, agentErr.Type())
+	})
 }
 
 func TestDaemonReloadWithBackoff(t *testing.T) {
@@ -729,6 +866,9 @@ func TestDaemonReloadWithBackoff(t *testing.T) {
 	// Cancel context
 	cn()
 
+	// the tamper protected agent refuses the restart without a valid uninstall token
+	denied := status.Error(codes.PermissionDenied, "uninstall token is required")
+
 	tests := []struct {
 		name             string
 		daemonReloadFunc func(ctx context.Context) error
@@ -745,6 +885,13 @@ func TestDaemonReloadWithBackoff(t *testing.T) {
 			},
 			wantErr: context.Canceled,
 		},
+		{
+			name: "deniedWithoutUninstallToken",
+			daemonReloadFunc: func(ctx context.Context) error {
+				return denied
+			},
+			wantErr: denied,
+		},
 	}
 
 	for _, tc := range tests {
@@ -17,4 +17,5 @@ import (
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/local"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/localdynamic"
 	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/path"
+	_ "github.com/elastic/elastic-agent/internal/pkg/composable/providers/secret"
 )
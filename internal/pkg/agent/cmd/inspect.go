@@ -30,6 +30,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/config/operations"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
@@ -45,6 +46,17 @@ first set of computed variables are used when only the --variables flag is defin
 dynamic providers (kubernetes, docker, etc.) from providing all the possible variables it could have discovered if given
 more time. The --variables-wait allows an amount of time to be provided for variable discovery, when set it will
 wait that amount of time before using the variables for the configuration.
+
+The --vars-file renders the configuration with the variables of a YAML file instead of the variables of the
+providers, to preview the configuration on a host where the providers would return them. The file is either a
+mapping of variables or a list of mappings, the inputs using the variables of a dynamic provider are rendered once
+for each mapping of the list:
+
+  - host:
+      name: web-1
+    kubernetes:
+      labels:
+        app: nginx
 `,
 		Args: cobra.ExactArgs(0),
 		Run: func(c *cobra.Command, args []string) {
@@ -52,13 +64,14 @@ wait that amount of time before using the variables for the configuration.
 			opts.variables, _ = c.Flags().GetBool("variables")
 			opts.includeMonitoring, _ = c.Flags().GetBool("monitoring")
 			opts.variablesWait, _ = c.Flags().GetDuration("variables-wait")
+			opts.variablesFile, _ = c.Flags().GetString("vars-file")
 
-			opts.variables = opts.variables || c.Flags().Changed("variables-wait")
+			opts.variables = opts.variables || c.Flags().Changed("variables-wait") || opts.variablesFile != ""
 
 			ctx, cancel := context.WithCancel(context.Background())
 			service.HandleSignals(func() {}, cancel)
 			if err := inspectConfig(ctx, paths.ConfigFile(), opts, streams); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
@@ -67,8 +80,12 @@ wait that amount of time before using the variables for the configuration.
 	cmd.Flags().Bool("variables", false, "render configuration with variables substituted")
 	cmd.Flags().Bool("monitoring", false, "includes monitoring configuration (implies --variables)")
 	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution (implies --variables)")
+	cmd.Flags().String("vars-file", "", "render configuration with the variables of this YAML file instead of the providers (implies --variables)")
+	cmd.MarkFlagsMutuallyExclusive("variables-wait", "vars-file")
 
 	cmd.AddCommand(newInspectComponentsCommandWithArgs(s, streams))
+	cmd.AddCommand(newInspectVariablesCommandWithArgs(s, streams))
+	cmd.AddCommand(newInspectCapabilitiesCommandWithArgs(s, streams))
 
 	return cmd
 }
@@ -95,7 +112,12 @@ Variable substitution is always performed when computing the components, and it
 first set of computed variables are used. This can prevent some of the dynamic providers (kubernetes, docker, etc.) from
 providing all the possible variables it could have discovered if given more time. The --variables-wait allows an
 amount of time to be provided for variable discovery, when set it will wait that amount of time before using the
-variables for the configuration.
+variables for the configuration. The --vars-file uses the variables of a YAML file instead of the variables of the
+providers, see the inspect command for its format.
+
+The --show-monitoring returns the components currently run by the Elastic Agent daemon instead of computing them from
+the configuration. These are the final components, with the monitoring components injected, the components blocked by
+capabilities removed and the outputs rewritten by the policy modifiers. Secret values are always redacted.
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(c *cobra.Command, args []string) {
@@ -106,12 +128,27 @@ variables for the configuration.
 			opts.showConfig, _ = c.Flags().GetBool("show-config")
 			opts.showSpec, _ = c.Flags().GetBool("show-spec")
 			opts.variablesWait, _ = c.Flags().GetDuration("variables-wait")
+			opts.variablesFile, _ = c.Flags().GetString("vars-file")
+			showMonitoring, _ := c.Flags().GetBool("show-monitoring")
 
 			ctx, cancel := context.WithCancel(context.Background())
 			service.HandleSignals(func() {}, cancel)
 
-			if err := inspectComponents(ctx, paths.ConfigFile(), opts, streams); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+			var err error
+			if showMonitoring {
+				innerCtx, innerCancel := context.WithTimeout(ctx, 30*time.Second)
+				defer innerCancel()
+				daemon := client.New()
+				err = daemon.Connect(innerCtx)
+				if err == nil {
+					defer daemon.Disconnect()
+					err = inspectRunningComponents(innerCtx, daemon, opts, streams)
+				}
+			} else {
+				err = inspectComponents(ctx, paths.ConfigFile(), opts, streams)
+			}
+			if err != nil {
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
@@ -120,14 +157,188 @@ variables for the configuration.
 	cmd.Flags().Bool("show-config", false, "show the configuration for all units")
 	cmd.Flags().Bool("show-spec", false, "show the runtime specification for a component")
 	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution")
+	cmd.Flags().String("vars-file", "", "perform substitution with the variables of this YAML file instead of the providers")
+	cmd.Flags().Bool("show-monitoring", false, "show the final components run by the Elastic Agent daemon, including the monitoring components")
+	cmd.MarkFlagsMutuallyExclusive("variables-wait", "vars-file")
+	cmd.MarkFlagsMutuallyExclusive("show-monitoring", "variables-wait")
+	cmd.MarkFlagsMutuallyExclusive("show-monitoring", "vars-file")
+
+	return cmd
+}
+
+func newInspectVariablesCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "variables [id]",
+		Short: "Displays the variables currently resolved by the running Elastic Agent",
+		Long: `Displays the variables currently resolved from the providers by the running Elastic Agent daemon.
+
+This is useful to debug why a variable in the policy was not substituted. Every set of variables is returned, one set
+for the context providers and one set for each mapping produced by the dynamic providers (kubernetes, docker, etc.).
+
+A specific set of variables can be selected by the ID of the mapping produced by the dynamic provider.
+
+Secret values are always redacted.
+`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			var opts inspectVariablesOpts
+			if len(args) > 0 {
+				opts.id = args[0]
+			}
+
+			ctx := handleSignal(context.Background())
+			innerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+
+			daemon := client.New()
+			err := daemon.Connect(innerCtx)
+			if err == nil {
+				defer daemon.Disconnect()
+				err = inspectVariables(innerCtx, daemon, opts, streams)
+			}
+			if err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
 
 	return cmd
 }
 
+func newInspectCapabilitiesCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "capabilities",
+		Short: "Displays the parts of the configuration stripped by the capabilities",
+		Long: `Displays the parts of the current configuration stripped by the selector rules of the capabilities, without
+applying them. Each stripped part is returned with its path in the configuration and the rule stripping it.
+
+The --file flag evaluates a capabilities file other than the one of the Elastic Agent, to try rules before deploying
+them. The --show-config flag also returns the configuration once the parts are stripped, secret values are always
+redacted.
+
+Variable substitution is always performed before the rules are evaluated, see the components command for the
+--variables-wait and --vars-file flags.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			var opts inspectCapabilitiesOpts
+			opts.file, _ = c.Flags().GetString("file")
+			opts.showConfig, _ = c.Flags().GetBool("show-config")
+			opts.variablesWait, _ = c.Flags().GetDuration("variables-wait")
+			opts.variablesFile, _ = c.Flags().GetString("vars-file")
+
+			ctx, cancel := context.WithCancel(context.Background())
+			service.HandleSignals(func() {}, cancel)
+			if err := inspectCapabilities(ctx, paths.ConfigFile(), opts, streams); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String("file", paths.AgentCapabilitiesPath(), "capabilities file to evaluate")
+	cmd.Flags().Bool("show-config", false, "show the configuration once the parts are stripped")
+	cmd.Flags().Duration("variables-wait", time.Duration(0), "wait this amount of time for variables before performing substitution")
+	cmd.Flags().String("vars-file", "", "perform substitution with the variables of this YAML file instead of the providers")
+	cmd.MarkFlagsMutuallyExclusive("variables-wait", "vars-file")
+
+	return cmd
+}
+
+type inspectCapabilitiesOpts struct {
+	file          string
+	showConfig    bool
+	variablesWait time.Duration
+	variablesFile string
+}
+
+// inspectCapabilitiesResult is the output of the inspect capabilities command.
+type inspectCapabilitiesResult struct {
+	Stripped []capabilities.Denial  `yaml:"stripped"`
+	Config   map[string]interface{} `yaml:"config,omitempty"`
+}
+
+// inspectCapabilities prints the parts of the configuration stripped by the selector rules of the capabilities.
+func inspectCapabilities(ctx context.Context, cfgPath string, opts inspectCapabilitiesOpts, streams *cli.IOStreams) error {
+	l, err := newErrorLogger()
+	if err != nil {
+		return err
+	}
+
+	caps, err := capabilities.LoadFile(opts.file, l)
+	if err != nil {
+		return fmt.Errorf("failed to load capabilities: %w", err)
+	}
+
+	isAdmin, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("error checking for root/Administrator privileges: %w", err)
+	}
+	cfg, _, err := getConfigWithVariables(ctx, l, cfgPath, variablesOpts{wait: opts.variablesWait, file: opts.variablesFile}, !isAdmin)
+	if err != nil {
+		return fmt.Errorf("error fetching config with variables: %w", err)
+	}
+
+	result := inspectCapabilitiesResult{Stripped: caps.FilterPolicy(cfg)}
+	if result.Stripped == nil {
+		result.Stripped = []capabilities.Denial{}
+	}
+	if opts.showConfig {
+		// Ensure secret markers are injected based on secret_paths before redaction.
+		rawCfg := config.MustNewConfigFrom(cfg)
+		if err := diagnostics.AddSecretMarkers(l, rawCfg); err != nil {
+			fmt.Fprintf(streams.Err, "failed to add secret markers: %v\n", err)
+		}
+		cfg, err = rawCfg.ToMapStr()
+		if err != nil {
+			return fmt.Errorf("failed to convert config with secret markers: %w", err)
+		}
+		result.Config = diagnostics.Redact(cfg, streams.Err)
+	}
+
+	data, err := yaml.Marshal(result)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
+type inspectVariablesOpts struct {
+	id string
+}
+
+func inspectVariables(ctx context.Context, daemon client.Client, opts inspectVariablesOpts, streams *cli.IOStreams) error {
+	vars, err := daemon.Vars(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch variables from Elastic Agent daemon: %w", err)
+	}
+	if opts.id != "" {
+		found := vars.Vars[:0]
+		for _, v := range vars.Vars {
+			if v.ID == opts.id {
+				found = append(found, v)
+			}
+		}
+		if len(found) == 0 {
+			return fmt.Errorf("unable to find variables with ID: %s", opts.id)
+		}
+		vars.Vars = found
+	}
+	data, err := yaml.Marshal(vars)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
 type inspectConfigOpts struct {
 	variables         bool
 	includeMonitoring bool
 	variablesWait     time.Duration
+	variablesFile     string
 }
 
 func inspectConfig(ctx context.Context, cfgPath string, opts inspectConfigOpts, streams *cli.IOStreams) error {
@@ -157,7 +368,7 @@ func inspectConfig(ctx context.Context, cfgPath string, opts inspectConfigOpts,
 		return nil
 	}
 
-	cfg, lvl, err := getConfigWithVariables(ctx, l, cfgPath, opts.variablesWait, !isAdmin)
+	cfg, lvl, err := getConfigWithVariables(ctx, l, cfgPath, variablesOpts{wait: opts.variablesWait, file: opts.variablesFile}, !isAdmin)
 	if err != nil {
 		return fmt.Errorf("error fetching config with variables: %w", err)
 	}
@@ -174,7 +385,7 @@ func inspectConfig(ctx context.Context, cfgPath string, opts inspectConfigOpts,
 		if err != nil {
 			return fmt.Errorf("failed to gather system information: %w", err)
 		}
-		specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
+		specs, err := component.LoadRuntimeSpecs(paths.Components(), platform, component.WithUserSpecs(paths.UserComponents()))
 		if err != nil {
 			return fmt.Errorf("failed to detect inputs and outputs: %w", err)
 		}
@@ -274,11 +485,19 @@ type inspectComponentsOpts struct {
 	showConfig    bool
 	showSpec      bool
 	variablesWait time.Duration
+	variablesFile string
 }
 
-// returns true if the given Capabilities config blocks the given component.
-func blockedByCaps(c component.Component, caps capabilities.Capabilities) bool {
-	return !caps.AllowInput(c.InputType) || !caps.AllowOutput(c.OutputType)
+// returns the rule of the given Capabilities config blocking the given component,
+// the second return value is false when the component is allowed.
+func blockedByCaps(c component.Component, caps capabilities.Capabilities) (string, bool) {
+	if rule, denied := caps.InputDeniedBy(c.InputType); denied {
+		return "input type denied by " + rule, true
+	}
+	if rule, denied := caps.OutputDeniedBy(c.OutputType); denied {
+		return "output type denied by " + rule, true
+	}
+	return "", false
 }
 
 func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponentsOpts, streams *cli.IOStreams) error {
@@ -287,7 +506,7 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 		return err
 	}
 
-	comps, err := getComponentsFromPolicy(ctx, l, cfgPath, opts.variablesWait)
+	comps, err := getComponentsFromPolicy(ctx, l, cfgPath, variablesOpts{wait: opts.variablesWait, file: opts.variablesFile})
 	if err != nil {
 		// error already includes the context
 		return err
@@ -342,7 +561,10 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 	allowed := []component.Component{}
 	blocked := []component.Component{}
 	for _, c := range comps {
-		if blockedByCaps(c, caps) {
+		if reason, ok := blockedByCaps(c, caps); ok {
+			// reported as the error of the component, so it is not mistaken
+			// for a component missing because of a condition or a variable
+			c.Err = fmt.Errorf("blocked by capabilities: %s", reason)
 			blocked = append(blocked, c)
 		} else {
 			allowed = append(allowed, c)
@@ -352,14 +574,82 @@ func inspectComponents(ctx context.Context, cfgPath string, opts inspectComponen
 	return printComponents(allowed, blocked, streams)
 }
 
-func getComponentsFromPolicy(ctx context.Context, l *logger.Logger, cfgPath string, variablesWait time.Duration, platformModifiers ...component.PlatformModifier) ([]component.Component, error) {
+// inspectRunningComponents prints the final components run by the Elastic Agent daemon.
+func inspectRunningComponents(ctx context.Context, daemon client.Client, opts inspectComponentsOpts, streams *cli.IOStreams) error {
+	res, err := daemon.Components(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch components from Elastic Agent daemon: %w", err)
+	}
+	comps := res.Components
+
+	// the components are decoded from YAML, the units are lists of generic mappings
+	units := func(comp map[string]interface{}) []interface{} {
+		u, _ := comp["units"].([]interface{})
+		return u
+	}
+	for _, comp := range comps {
+		if !opts.showConfig {
+			for _, unit := range units(comp) {
+				if u, ok := unit.(map[interface{}]interface{}); ok {
+					delete(u, "config")
+				}
+			}
+		}
+		if !opts.showSpec {
+			delete(comp, "input_spec")
+		}
+	}
+
+	var out interface{} = res
+	if opts.id != "" {
+		// the IDs of the monitoring components and of their units contain a slash, the component is the one
+		// with the ID selected or prefixing the selected ID
+		var selected map[string]interface{}
+		unitID := ""
+		for _, comp := range comps {
+			id, _ := comp["id"].(string)
+			if id == opts.id {
+				selected, unitID = comp, ""
+				break
+			}
+			if after, ok := strings.CutPrefix(opts.id, id+"/"); ok {
+				selected, unitID = comp, after
+			}
+		}
+		if selected == nil {
+			compID, _, _ := strings.Cut(opts.id, "/")
+			return fmt.Errorf("unable to find component with ID: %s", compID)
+		}
+		out = selected
+		if unitID != "" {
+			out = nil
+			for _, unit := range units(selected) {
+				if u, ok := unit.(map[interface{}]interface{}); ok && u["id"] == unitID {
+					out = u
+				}
+			}
+			if out == nil {
+				return fmt.Errorf("unable to find unit with ID: %s", opts.id)
+			}
+		}
+	}
+
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
+func getComponentsFromPolicy(ctx context.Context, l *logger.Logger, cfgPath string, variables variablesOpts, platformModifiers ...component.PlatformModifier) ([]component.Component, error) {
 	// Load the requirements before trying to load the configuration. These should always load
 	// even if the configuration is wrong.
 	platform, err := component.LoadPlatformDetail(platformModifiers...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to gather system information: %w", err)
 	}
-	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform)
+	specs, err := component.LoadRuntimeSpecs(paths.Components(), platform, component.WithUserSpecs(paths.UserComponents()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect inputs and outputs: %w", err)
 	}
@@ -369,11 +659,18 @@ func getComponentsFromPolicy(ctx context.Context, l *logger.Logger, cfgPath stri
 		return nil, fmt.Errorf("error checking for root/Administrator privileges: %w", err)
 	}
 
-	m, lvl, err := getConfigWithVariables(ctx, l, cfgPath, variablesWait, !isAdmin)
+	m, lvl, err := getConfigWithVariables(ctx, l, cfgPath, variables, !isAdmin)
 	if err != nil {
 		return nil, err
 	}
 
+	// Strip the parts of the configuration denied by the capabilities, like the running Elastic Agent.
+	caps, err := capabilities.LoadFile(paths.AgentCapabilitiesPath(), l)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load capabilities: %w", err)
+	}
+	caps.FilterPolicy(m)
+
 	monitorFn, err := getMonitoringFn(ctx, m)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get monitoring: %w", err)
@@ -413,7 +710,15 @@ func getMonitoringFn(ctx context.Context, cfg map[string]interface{}) (component
 	return monitor.MonitoringConfig, nil
 }
 
-func getConfigWithVariables(ctx context.Context, l *logger.Logger, cfgPath string, timeout time.Duration, unprivileged bool) (map[string]interface{}, logp.Level, error) {
+// variablesOpts selects the variables substituted in the configuration.
+type variablesOpts struct {
+	// wait is the time given to the providers to gather the variables.
+	wait time.Duration
+	// file is the path of a YAML file with the variables to use instead of the variables of the providers.
+	file string
+}
+
+func getConfigWithVariables(ctx context.Context, l *logger.Logger, cfgPath string, variables variablesOpts, unprivileged bool) (map[string]interface{}, logp.Level, error) {
 
 	cfg, err := operations.LoadFullAgentConfig(ctx, l, cfgPath, true, unprivileged)
 	if err != nil {
@@ -431,20 +736,30 @@ func getConfigWithVariables(ctx context.Context, l *logger.Logger, cfgPath strin
 	if err != nil {
 		return nil, lvl, fmt.Errorf("could not create the AST from the configuration: %w", err)
 	}
-
-	// Wait for the variables based on the timeout.
-	vars, err := vars.WaitForVariables(ctx, l, cfg, timeout)
-	if err != nil {
-		return nil, lvl, fmt.Errorf("failed to gather variables: %w", err)
+	if err := transpiler.ExpandPresets(ast); err != nil {
+		return nil, lvl, fmt.Errorf("expanding presets failed: %w", err)
 	}
 
-	// Render the inputs using the discovered inputs.
-	inputs, ok := transpiler.Lookup(ast, "inputs")
-	if ok {
-		renderedInputs, err := transpiler.RenderInputs(inputs, vars)
+	var varsSets []*transpiler.Vars
+	if variables.file != "" {
+		varsSets, err = vars.LoadVariablesFile(cfg, variables.file)
 		if err != nil {
-			return nil, lvl, fmt.Errorf("rendering inputs failed: %w", err)
+			return nil, lvl, err
 		}
+	} else {
+		// Wait for the variables based on the timeout.
+		varsSets, err = vars.WaitForVariables(ctx, l, cfg, variables.wait)
+		if err != nil {
+			return nil, lvl, fmt.Errorf("failed to gather variables: %w", err)
+		}
+	}
+
+	// Render the inputs and the input templates using the discovered inputs.
+	renderedInputs, ok, err := transpiler.RenderAllInputs(ast, varsSets)
+	if err != nil {
+		return nil, lvl, fmt.Errorf("rendering inputs failed: %w", err)
+	}
+	if ok {
 		err = transpiler.Insert(ast, renderedInputs, "inputs")
 		if err != nil {
 			return nil, lvl, fmt.Errorf("inserting rendered inputs failed: %w", err)
@@ -0,0 +1,160 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	clientmocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func TestInspectVariables(t *testing.T) {
+	vars := func() *client.AgentVars {
+		return &client.AgentVars{
+			DefaultProvider: "env",
+			Vars: []client.VarsMapping{
+				{
+					Mapping: map[string]interface{}{"host": map[string]interface{}{"name": "example"}},
+				},
+				{
+					ID:      "kubernetes-pod-1",
+					Mapping: map[string]interface{}{"kubernetes": map[string]interface{}{"pod": map[string]interface{}{"name": "pod-1"}}},
+				},
+			},
+		}
+	}
+
+	t.Run("all variables", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Vars(mock.Anything).Return(vars(), nil)
+
+		out := &bytes.Buffer{}
+		streams := &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}
+		err := inspectVariables(context.Background(), daemon, inspectVariablesOpts{}, streams)
+		require.NoError(t, err)
+		assert.Equal(t, `default_provider: env
+vars:
+- mapping:
+    host:
+      name: example
+- id: kubernetes-pod-1
+  mapping:
+    kubernetes:
+      pod:
+        name: pod-1
+`, out.String())
+	})
+
+	t.Run("select by id", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Vars(mock.Anything).Return(vars(), nil)
+
+		out := &bytes.Buffer{}
+		streams := &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}
+		err := inspectVariables(context.Background(), daemon, inspectVariablesOpts{id: "kubernetes-pod-1"}, streams)
+		require.NoError(t, err)
+		assert.NotContains(t, out.String(), "example")
+		assert.Contains(t, out.String(), "pod-1")
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Vars(mock.Anything).Return(vars(), nil)
+
+		streams := &cli.IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}
+		err := inspectVariables(context.Background(), daemon, inspectVariablesOpts{id: "missing"}, streams)
+		assert.ErrorContains(t, err, "unable to find variables with ID: missing")
+	})
+}
+
+func TestInspectRunningComponents(t *testing.T) {
+	components := func() *client.AgentComponents {
+		return &client.AgentComponents{
+			Components: []map[string]interface{}{
+				{
+					"id":         "filestream-default",
+					"input_type": "filestream",
+					"input_spec": map[interface{}]interface{}{"binary_name": "filebeat"},
+					"units": []interface{}{
+						map[interface{}]interface{}{"id": "filestream-default", "config": map[interface{}]interface{}{"type": "elasticsearch"}},
+					},
+				},
+				{
+					"id":         "beat/metrics-monitoring",
+					"input_type": "beat/metrics",
+					"units": []interface{}{
+						map[interface{}]interface{}{"id": "beat/metrics-monitoring", "config": map[interface{}]interface{}{"type": "elasticsearch"}},
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("all components", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Components(mock.Anything).Return(components(), nil)
+
+		out := &bytes.Buffer{}
+		streams := &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}
+		err := inspectRunningComponents(context.Background(), daemon, inspectComponentsOpts{}, streams)
+		require.NoError(t, err)
+		assert.Equal(t, `components:
+- id: filestream-default
+  input_type: filestream
+  units:
+  - id: filestream-default
+- id: beat/metrics-monitoring
+  input_type: beat/metrics
+  units:
+  - id: beat/metrics-monitoring
+`, out.String())
+	})
+
+	t.Run("select monitoring unit", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Components(mock.Anything).Return(components(), nil)
+
+		out := &bytes.Buffer{}
+		streams := &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}
+		err := inspectRunningComponents(context.Background(), daemon, inspectComponentsOpts{
+			id:         "beat/metrics-monitoring/beat/metrics-monitoring",
+			showConfig: true,
+		}, streams)
+		require.NoError(t, err)
+		assert.Equal(t, `config:
+  type: elasticsearch
+id: beat/metrics-monitoring
+`, out.String())
+	})
+
+	t.Run("select component", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Components(mock.Anything).Return(components(), nil)
+
+		out := &bytes.Buffer{}
+		streams := &cli.IOStreams{Out: out, Err: &bytes.Buffer{}}
+		err := inspectRunningComponents(context.Background(), daemon, inspectComponentsOpts{id: "filestream-default", showSpec: true}, streams)
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "binary_name: filebeat")
+		assert.NotContains(t, out.String(), "beat/metrics-monitoring")
+	})
+
+	t.Run("unknown unit", func(t *testing.T) {
+		daemon := clientmocks.NewClient(t)
+		daemon.EXPECT().Components(mock.Anything).Return(components(), nil)
+
+		streams := &cli.IOStreams{Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}
+		err := inspectRunningComponents(context.Background(), daemon, inspectComponentsOpts{id: "filestream-default/missing"}, streams)
+		assert.ErrorContains(t, err, "unable to find unit with ID: filestream-default/missing")
+	})
+}
@@ -19,8 +19,13 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
@@ -32,10 +37,13 @@ const (
 	flagInstallNamespace              = "namespace"
 	flagInstallRunUninstallFromBinary = "run-uninstall-from-binary"
 	flagInstallServers                = "install-servers"
+	flagInstallHarden                 = "harden"
 
 	flagInstallCustomUser  = "user"
 	flagInstallCustomGroup = "group"
 	flagInstallCustomPass  = "password"
+
+	flagInstallVirtualAccount = "virtual-account"
 )
 
 func newInstallCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
@@ -49,7 +57,7 @@ would like the Agent to operate.
 `,
 		Run: func(c *cobra.Command, _ []string) {
 			if err := installCmd(streams, c); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				logExternal(fmt.Sprintf("%s install failed: %s", paths.BinaryName, err))
 				os.Exit(1)
 			}
@@ -61,12 +69,13 @@ would like the Agent to operate.
 	cmd.Flags().String(flagInstallBasePath, paths.DefaultBasePath, "The path where the Elastic Agent will be installed. It must be an absolute path.")
 	cmd.Flags().Bool(flagInstallUnprivileged, false, "Install in unprivileged mode, limiting the access of the Elastic Agent.")
 	cmd.Flags().Bool(flagInstallServers, false, "Install larger version of agent that includes server components")
+	cmd.Flags().String(flagInstallProfile, "", "Path to a YAML profile declaring the install flags and the health assertions checked after the install, the flags given on the command line override it. Implies --non-interactive.")
+	cmd.Flags().Bool(flagInstallHarden, false, "Protect the binaries and the components of the Elastic Agent against accidental or casual modification. It is not a security boundary against root or Administrators, who can remove the protection.")
 
 	cmd.Flags().Bool(flagInstallRunUninstallFromBinary, false, "Run the uninstall command from this binary instead of using the binary found in the system's path.")
 	_ = cmd.Flags().MarkHidden(flagInstallRunUninstallFromBinary) // Advanced option to force a new agent to override an existing installation, it may orphan installed components.
 
-	cmd.Flags().String(flagInstallNamespace, "", "Install into an isolated namespace. Allows multiple Elastic Agents to be installed at once. (experimental)")
-	_ = cmd.Flags().MarkHidden(flagInstallNamespace) // For internal use only.
+	cmd.Flags().String(flagInstallNamespace, "", "Install into an isolated namespace with its own install path, service and control socket. Allows multiple Elastic Agents to be installed at once.")
 
 	cmd.Flags().Bool(flagInstallDevelopment, false, "Install into a standardized development namespace, may enable development specific options. Allows multiple Elastic Agents to be installed at once. (experimental)")
 	_ = cmd.Flags().MarkHidden(flagInstallDevelopment) // For internal use only.
@@ -76,6 +85,7 @@ would like the Agent to operate.
 	cmd.Flags().String(flagInstallCustomGroup, "", "Custom group used to access Elastic Agent files")
 	if runtime.GOOS == "windows" {
 		cmd.Flags().String(flagInstallCustomPass, "", "Password for user used to run Elastic Agent")
+		cmd.Flags().Bool(flagInstallVirtualAccount, false, "Run Elastic Agent as the virtual service account of its service instead of a local user, implies --unprivileged")
 	}
 
 	addEnrollFlags(cmd)
@@ -86,6 +96,18 @@ would like the Agent to operate.
 func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	var err error
 
+	var profile *installProfile
+	if profilePath, _ := cmd.Flags().GetString(flagInstallProfile); profilePath != "" {
+		profile, err = loadInstallProfile(profilePath)
+		if err != nil {
+			return err
+		}
+		if err = applyInstallProfile(cmd, profile); err != nil {
+			return err
+		}
+		fmt.Fprintf(streams.Out, "Using install profile %s\n", profilePath)
+	}
+
 	if installServers, _ := cmd.Flags().GetBool(flagInstallServers); isFleetServerFlagProvided(cmd) && !installServers {
 		_ = cmd.Flags().Lookup(flagInstallServers).Value.Set("true") // this can fail only when parsing bool
 		fmt.Fprintf(streams.Out, "fleet-server installation detected, using --%s flag\n", flagInstallServers)
@@ -110,10 +132,18 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	}
 
 	unprivileged, _ := cmd.Flags().GetBool(flagInstallUnprivileged)
+	if virtualAccount, _ := cmd.Flags().GetBool(flagInstallVirtualAccount); virtualAccount {
+		unprivileged = true
+	}
 	if unprivileged {
 		fmt.Fprintln(streams.Out, "Unprivileged installation mode enabled.")
 	}
 
+	harden, _ := cmd.Flags().GetBool(flagInstallHarden)
+	if harden && unprivileged {
+		return fmt.Errorf("--%s cannot be used with --%s, the protected paths require a privileged Elastic Agent", flagInstallHarden, flagInstallUnprivileged)
+	}
+
 	isDevelopmentMode, _ := cmd.Flags().GetBool(flagInstallDevelopment)
 	if isDevelopmentMode {
 		fmt.Fprintln(streams.Out, "Installing into development namespace; this is an experimental and currently unsupported feature.")
@@ -123,7 +153,10 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	namespace, _ := cmd.Flags().GetString(flagInstallNamespace)
 	if namespace != "" {
-		fmt.Fprintf(streams.Out, "Installing into namespace '%s'; this is an experimental and currently unsupported feature.\n", namespace)
+		if err := paths.ValidateInstallNamespace(namespace); err != nil {
+			return err
+		}
+		fmt.Fprintf(streams.Out, "Installing into namespace '%s'.\n", namespace)
 		// Overrides the development namespace if namespace was specified separately.
 		paths.SetInstallNamespace(namespace)
 	}
@@ -187,8 +220,14 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	askEnroll := true
 	url, _ := cmd.Flags().GetString("url")
 	token, _ := cmd.Flags().GetString("enrollment-token")
+	identityProvider, _ := cmd.Flags().GetString("identity-provider")
 	delayEnroll, _ := cmd.Flags().GetBool("delay-enroll")
-	if url != "" && token != "" {
+	if staged, _ := cmd.Flags().GetBool("staged"); staged {
+		delayEnroll = true
+	}
+	// the enrollment token is obtained with the identity of the instance
+	hasToken := token != "" || identityProvider != ""
+	if url != "" && hasToken {
 		askEnroll = false
 	}
 	fleetServer, _ := cmd.Flags().GetString("fleet-server-es")
@@ -205,7 +244,7 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 			enroll = false
 		}
 	}
-	if !askEnroll && (url == "" || token == "") && fleetServer == "" {
+	if !askEnroll && (url == "" || !hasToken) && fleetServer == "" {
 		// force was performed without required enrollment arguments, all done (standalone mode)
 		enroll = false
 	}
@@ -224,7 +263,7 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 				return nil
 			}
 		}
-		if token == "" {
+		if !hasToken {
 			if nonInteractive {
 				return fmt.Errorf("missing required --enrollment-token argument used to enroll the agent")
 			}
@@ -252,6 +291,21 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 
 	var ownership utils.FileOwner
 	cfgFile := paths.ConfigFile()
+	dataPath, err := configuredDataPath(cfgFile)
+	if err != nil {
+		return err
+	}
+	vaultCfg, err := configuredVault(cfgFile)
+	if err != nil {
+		return err
+	}
+	if !vaultCfg.IsDefault() {
+		progBar.Describe("Checking vault backend")
+		if err := vaultCfg.Check(); err != nil {
+			progBar.Describe("Vault backend check failed")
+			return fmt.Errorf("error checking vault backend: %w", err)
+		}
+	}
 	if status == install.Installed {
 		// Uninstall the agent
 		progBar.Describe(fmt.Sprintf("Uninstalling current %s", paths.ServiceDisplayName()))
@@ -278,6 +332,10 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		if runtime.GOOS == "windows" {
 			customPass, _ = cmd.Flags().GetString(flagInstallCustomPass)
 		}
+		customUser, err = virtualAccountUser(cmd, customUser, customPass)
+		if err != nil {
+			return err
+		}
 
 		flavor := install.DefaultFlavor
 		if installServers, _ := cmd.Flags().GetBool(flagInstallServers); installServers {
@@ -301,6 +359,34 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 			}
 		}()
 
+		if dataPath != "" {
+			progBar.Describe("Relocating data")
+			err = install.RelocateDataDir(topPath, dataPath, ownership, progBar)
+			if err != nil {
+				progBar.Describe("Failed to relocate data")
+				return fmt.Errorf("error relocating data: %w", err)
+			}
+		}
+
+		if !vaultCfg.IsDefault() {
+			progBar.Describe("Saving vault backend")
+			err = install.SaveVaultBackend(topPath, vaultCfg, ownership)
+			if err != nil {
+				progBar.Describe("Failed to save vault backend")
+				return fmt.Errorf("error saving vault backend: %w", err)
+			}
+		}
+
+		if harden {
+			progBar.Describe("Protecting paths")
+			err = perms.Harden(topPath)
+			if err != nil {
+				progBar.Describe("Failed to protect paths")
+				return fmt.Errorf("error protecting paths: %w", err)
+			}
+			progBar.Describe("Paths protected")
+		}
+
 		if !delayEnroll {
 			progBar.Describe("Starting Service")
 			err = install.StartService(topPath)
@@ -356,6 +442,18 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 		progBar.Describe("Enroll Completed")
 	}
 
+	if profile != nil && profile.Health != nil {
+		progBar.Describe("Checking health assertions")
+		// a failed assertion does not uninstall, the Elastic Agent can become healthy once the cause is fixed
+		if healthErr := waitForProfileHealth(cmd.Context(), client.New(client.WithAddress(paths.InstalledControlSocket(topPath, paths.InstallNamespace()))), profile.Health, profileHealthCheckInterval); healthErr != nil {
+			progBar.Describe("Health assertions failed")
+			_ = progBar.Finish()
+			_ = progBar.Exit()
+			return fmt.Errorf("%s is installed but unhealthy: %w", paths.ServiceDisplayName(), healthErr)
+		}
+		progBar.Describe("Health assertions passed")
+	}
+
 	progBar.Describe("Done")
 	_ = progBar.Finish()
 	_ = progBar.Exit()
@@ -363,6 +461,18 @@ func installCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	return nil
 }
 
+// virtualAccountUser returns the virtual service account of the Elastic Agent service when --virtual-account is
+// set, the custom user otherwise.
+func virtualAccountUser(cmd *cobra.Command, customUser, customPass string) (string, error) {
+	if virtualAccount, _ := cmd.Flags().GetBool(flagInstallVirtualAccount); !virtualAccount {
+		return customUser, nil
+	}
+	if customUser != "" || customPass != "" {
+		return "", fmt.Errorf("--%s cannot be used with --%s or --%s", flagInstallVirtualAccount, flagInstallCustomUser, flagInstallCustomPass)
+	}
+	return install.VirtualServiceAccount(), nil
+}
+
 func isFleetServerFlagProvided(cmd *cobra.Command) bool {
 	var fleetServerFlagPresent bool
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
@@ -415,3 +525,19 @@ func execUninstall(streams *cli.IOStreams, topPath string, binName string) error
 	}
 	return nil
 }
+
+// configuredVault returns the backend of the vault set with agent.vault in the configuration file.
+func configuredVault(cfgFile string) (vault.Config, error) {
+	rawConfig, err := config.LoadFile(cfgFile)
+	if err != nil {
+		return vault.Config{}, fmt.Errorf("failed to read configuration %s: %w", cfgFile, err)
+	}
+	cfg, err := configuration.NewFromConfig(rawConfig)
+	if err != nil {
+		return vault.Config{}, fmt.Errorf("failed to parse configuration %s: %w", cfgFile, err)
+	}
+	if cfg.Settings.Vault == nil {
+		return vault.Config{}, nil
+	}
+	return *cfg.Settings.Vault, nil
+}
@@ -0,0 +1,264 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+const (
+	flagInstallProfile = "profile"
+
+	defaultProfileHealthTimeout = 5 * time.Minute
+	profileHealthCheckInterval  = 5 * time.Second
+)
+
+// installProfile is the declarative equivalent of the flags of the install command, so configuration management
+// tools deploy the Elastic Agent from a single file. The flags given on the command line override the profile.
+type installProfile struct {
+	BasePath       string              `yaml:"base_path"`
+	Namespace      string              `yaml:"namespace"`
+	Unprivileged   *bool               `yaml:"unprivileged"`
+	InstallServers *bool               `yaml:"install_servers"`
+	Harden         *bool               `yaml:"harden"`
+	Force          *bool               `yaml:"force"`
+	User           string              `yaml:"user"`
+	Group          string              `yaml:"group"`
+	Tags           []string            `yaml:"tags"`
+	Fleet          installProfileFleet `yaml:"fleet"`
+	Proxy          installProfileProxy `yaml:"proxy"`
+	// Health are the assertions checked once the Elastic Agent is installed, none are checked when unset.
+	Health *installProfileHealth `yaml:"health"`
+}
+
+type installProfileFleet struct {
+	URL string `yaml:"url"`
+	// EnrollmentToken, EnrollmentTokenFile, EnrollmentTokenEnv and IdentityProvider are the sources of the
+	// enrollment token, only one can be set.
+	EnrollmentToken        string   `yaml:"enrollment_token"`
+	EnrollmentTokenFile    string   `yaml:"enrollment_token_file"`
+	EnrollmentTokenEnv     string   `yaml:"enrollment_token_env"`
+	IdentityProvider       string   `yaml:"identity_provider"`
+	CertificateAuthorities []string `yaml:"certificate_authorities"`
+	CASHA256               []string `yaml:"ca_sha256"`
+	Insecure               *bool    `yaml:"insecure"`
+	DelayEnroll            *bool    `yaml:"delay_enroll"`
+}
+
+type installProfileProxy struct {
+	URL      string            `yaml:"url"`
+	Disabled *bool             `yaml:"disabled"`
+	Headers  map[string]string `yaml:"headers"`
+}
+
+type installProfileHealth struct {
+	// Timeout is how long the assertions can take to pass, defaults to 5 minutes.
+	Timeout time.Duration `yaml:"timeout"`
+	// AllowDegraded accepts a degraded Elastic Agent or component as healthy.
+	AllowDegraded bool `yaml:"allow_degraded"`
+	// Components are the IDs of the components that must be running and healthy.
+	Components []string `yaml:"components"`
+}
+
+// loadInstallProfile reads the profile at path, unknown keys are rejected so typos do not go unnoticed.
+func loadInstallProfile(path string) (*installProfile, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read install profile %s: %w", path, err)
+	}
+	var profile installProfile
+	if err := yaml.UnmarshalStrict(contents, &profile); err != nil {
+		return nil, fmt.Errorf("failed to parse install profile %s: %w", path, err)
+	}
+	if err := profile.validate(); err != nil {
+		return nil, fmt.Errorf("invalid install profile %s: %w", path, err)
+	}
+	return &profile, nil
+}
+
+func (p *installProfile) validate() error {
+	sources := 0
+	for _, source := range []string{p.Fleet.EnrollmentToken, p.Fleet.EnrollmentTokenFile, p.Fleet.EnrollmentTokenEnv, p.Fleet.IdentityProvider} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of fleet.enrollment_token, fleet.enrollment_token_file, fleet.enrollment_token_env and fleet.identity_provider can be set")
+	}
+	if p.Health != nil {
+		if p.Health.Timeout < 0 {
+			return errors.New("health.timeout must be positive")
+		}
+		if p.Fleet.DelayEnroll != nil && *p.Fleet.DelayEnroll {
+			return errors.New("health assertions cannot be checked with fleet.delay_enroll, the service is not started")
+		}
+	}
+	return nil
+}
+
+// enrollmentToken resolves the enrollment token from its source.
+func (p *installProfile) enrollmentToken() (string, error) {
+	switch {
+	case p.Fleet.EnrollmentTokenFile != "":
+		contents, err := os.ReadFile(p.Fleet.EnrollmentTokenFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the enrollment token: %w", err)
+		}
+		return strings.TrimSpace(string(contents)), nil
+	case p.Fleet.EnrollmentTokenEnv != "":
+		token, ok := os.LookupEnv(p.Fleet.EnrollmentTokenEnv)
+		if !ok || token == "" {
+			return "", fmt.Errorf("environment variable %s of the enrollment token is not set", p.Fleet.EnrollmentTokenEnv)
+		}
+		return token, nil
+	default:
+		return p.Fleet.EnrollmentToken, nil
+	}
+}
+
+// applyInstallProfile sets the flags of the install command from the profile, the flags given on the command
+// line are kept. A profile always installs non-interactively.
+func applyInstallProfile(cmd *cobra.Command, p *installProfile) error {
+	flags := cmd.Flags()
+	var errs []error
+	set := func(name, value string) {
+		if value == "" || flags.Changed(name) {
+			return
+		}
+		if err := flags.Set(name, value); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set --%s from the install profile: %w", name, err))
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			set(name, strconv.FormatBool(*value))
+		}
+	}
+	// setSlice keeps the values as a list, joining them would split the values containing a comma
+	setSlice := func(name string, values []string) {
+		if len(values) == 0 || flags.Changed(name) {
+			return
+		}
+		flag := flags.Lookup(name)
+		sv, ok := flag.Value.(pflag.SliceValue)
+		if !ok {
+			errs = append(errs, fmt.Errorf("failed to set --%s from the install profile: not a list", name))
+			return
+		}
+		if err := sv.Replace(values); err != nil {
+			errs = append(errs, fmt.Errorf("failed to set --%s from the install profile: %w", name, err))
+			return
+		}
+		flag.Changed = true
+	}
+
+	set("non-interactive", "true")
+	set(flagInstallBasePath, p.BasePath)
+	set(flagInstallNamespace, p.Namespace)
+	setBool(flagInstallUnprivileged, p.Unprivileged)
+	setBool(flagInstallServers, p.InstallServers)
+	setBool(flagInstallHarden, p.Harden)
+	setBool("force", p.Force)
+	set(flagInstallCustomUser, p.User)
+	set(flagInstallCustomGroup, p.Group)
+	setSlice("tag", p.Tags)
+
+	set("url", p.Fleet.URL)
+	if !flags.Changed("enrollment-token") && !flags.Changed("identity-provider") {
+		token, err := p.enrollmentToken()
+		if err != nil {
+			return err
+		}
+		set("enrollment-token", token)
+		set("identity-provider", p.Fleet.IdentityProvider)
+	}
+	set("certificate-authorities", strings.Join(p.Fleet.CertificateAuthorities, ","))
+	set("ca-sha256", strings.Join(p.Fleet.CASHA256, ","))
+	setBool("insecure", p.Fleet.Insecure)
+	setBool("delay-enroll", p.Fleet.DelayEnroll)
+
+	set("proxy-url", p.Proxy.URL)
+	setBool("proxy-disabled", p.Proxy.Disabled)
+	headers := make([]string, 0, len(p.Proxy.Headers))
+	for k, v := range p.Proxy.Headers {
+		headers = append(headers, k+"="+v)
+	}
+	slices.Sort(headers)
+	setSlice("proxy-header", headers)
+
+	return errors.Join(errs...)
+}
+
+// waitForProfileHealth waits until the running Elastic Agent and the components of the health assertions are
+// healthy, it returns the last unmet assertion when the timeout elapses.
+func waitForProfileHealth(ctx context.Context, c client.Client, health *installProfileHealth, interval time.Duration) error {
+	timeout := health.Timeout
+	if timeout == 0 {
+		timeout = defaultProfileHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := c.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to communicate with Elastic Agent daemon: %w", err)
+	}
+	defer c.Disconnect()
+
+	for {
+		// the daemon may still be starting, the errors are retried until the timeout
+		state, unmet := c.State(ctx)
+		if unmet == nil {
+			unmet = checkProfileHealth(state, health)
+			if unmet == nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("health assertions not met within %s: %w", timeout, unmet)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkProfileHealth returns the first health assertion the state does not meet.
+func checkProfileHealth(state *client.AgentState, health *installProfileHealth) error {
+	healthy := func(s client.State) bool {
+		return s == client.Healthy || (health.AllowDegraded && s == client.Degraded)
+	}
+	if !healthy(state.State) {
+		return fmt.Errorf("the Elastic Agent is %s: %s", state.State, state.Message)
+	}
+	for _, id := range health.Components {
+		found := false
+		for _, comp := range state.Components {
+			if comp.ID != id {
+				continue
+			}
+			found = true
+			if !healthy(comp.State) {
+				return fmt.Errorf("component %s is %s: %s", id, comp.State, comp.Message)
+			}
+		}
+		if !found {
+			return fmt.Errorf("component %s is not running", id)
+		}
+	}
+	return nil
+}
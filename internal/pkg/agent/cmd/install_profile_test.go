@@ -0,0 +1,160 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	clientmocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func writeInstallProfile(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "profile.yml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoadInstallProfile(t *testing.T) {
+	profile, err := loadInstallProfile(writeInstallProfile(t, `
+base_path: /opt
+unprivileged: true
+tags: [prod, eu]
+fleet:
+  url: https://fleet.example.com
+  enrollment_token_env: ENROLLMENT_TOKEN
+proxy:
+  url: http://proxy:3128
+health:
+  timeout: 2m
+  components: [filebeat-default]
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "/opt", profile.BasePath)
+	assert.True(t, *profile.Unprivileged)
+	assert.Nil(t, profile.Harden)
+	assert.Equal(t, 2*time.Minute, profile.Health.Timeout)
+	assert.Equal(t, []string{"filebeat-default"}, profile.Health.Components)
+
+	_, err = loadInstallProfile(writeInstallProfile(t, "base_pat: /opt\n"))
+	assert.ErrorContains(t, err, "base_pat")
+
+	_, err = loadInstallProfile(writeInstallProfile(t, `
+fleet:
+  enrollment_token: token
+  enrollment_token_file: /token
+`))
+	assert.ErrorContains(t, err, "only one of")
+
+	_, err = loadInstallProfile(writeInstallProfile(t, `
+fleet:
+  delay_enroll: true
+health: {}
+`))
+	assert.ErrorContains(t, err, "delay_enroll")
+}
+
+func TestApplyInstallProfile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("file-token\n"), 0o600))
+
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	cmd := newInstallCommandWithArgs(nil, streams)
+	// the command line overrides the profile
+	require.NoError(t, cmd.Flags().Set(flagInstallBasePath, "/custom"))
+
+	unprivileged := true
+	require.NoError(t, applyInstallProfile(cmd, &installProfile{
+		BasePath:     "/opt",
+		Namespace:    "test",
+		Unprivileged: &unprivileged,
+		Tags:         []string{"prod", "eu"},
+		Fleet: installProfileFleet{
+			URL:                 "https://fleet.example.com",
+			EnrollmentTokenFile: tokenFile,
+		},
+		Proxy: installProfileProxy{
+			Headers: map[string]string{"Proxy-Authorization": "Basic abc", "X-Forwarded-For": "10.0.0.1, 10.0.0.2"},
+		},
+	}))
+
+	get := func(name string) string {
+		return cmd.Flags().Lookup(name).Value.String()
+	}
+	assert.Equal(t, "/custom", get(flagInstallBasePath))
+	assert.Equal(t, "test", get(flagInstallNamespace))
+	assert.Equal(t, "true", get(flagInstallUnprivileged))
+	assert.Equal(t, "true", get("non-interactive"))
+	assert.Equal(t, "false", get(flagInstallHarden))
+	assert.Equal(t, "https://fleet.example.com", get("url"))
+	assert.Equal(t, "file-token", get("enrollment-token"))
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	assert.Equal(t, []string{"prod", "eu"}, tags)
+	headers, _ := cmd.Flags().GetStringSlice("proxy-header")
+	assert.Equal(t, []string{"Proxy-Authorization=Basic abc", "X-Forwarded-For=10.0.0.1, 10.0.0.2"}, headers)
+
+	// the headers are kept as a list when passed to the enroll command
+	enrollCmd := newEnrollCommandWithArgs(nil, streams)
+	require.NoError(t, enrollCmd.Flags().Parse(buildEnrollmentFlags(cmd, "", "")))
+	headers, _ = enrollCmd.Flags().GetStringSlice("proxy-header")
+	assert.ElementsMatch(t, []string{"Proxy-Authorization=Basic abc", "X-Forwarded-For=10.0.0.1, 10.0.0.2"}, headers)
+
+	cmd = newInstallCommandWithArgs(nil, streams)
+	err := applyInstallProfile(cmd, &installProfile{Fleet: installProfileFleet{EnrollmentTokenEnv: "ELASTIC_AGENT_TEST_UNSET_TOKEN"}})
+	assert.ErrorContains(t, err, "ELASTIC_AGENT_TEST_UNSET_TOKEN")
+}
+
+func TestWaitForProfileHealth(t *testing.T) {
+	ctx := context.Background()
+	healthy := &client.AgentState{
+		State: client.Healthy,
+		Components: []client.ComponentState{
+			{ID: "filebeat-default", State: client.Healthy},
+		},
+	}
+
+	c := clientmocks.NewClient(t)
+	c.EXPECT().Connect(mock.Anything).Return(nil).Once()
+	c.EXPECT().Disconnect().Once()
+	c.EXPECT().State(mock.Anything).Return(nil, errors.New("daemon starting")).Once()
+	c.EXPECT().State(mock.Anything).Return(&client.AgentState{State: client.Starting}, nil).Once()
+	c.EXPECT().State(mock.Anything).Return(healthy, nil).Once()
+	health := &installProfileHealth{Timeout: 5 * time.Second, Components: []string{"filebeat-default"}}
+	require.NoError(t, waitForProfileHealth(ctx, c, health, time.Millisecond))
+
+	c = clientmocks.NewClient(t)
+	c.EXPECT().Connect(mock.Anything).Return(nil).Once()
+	c.EXPECT().Disconnect().Once()
+	c.EXPECT().State(mock.Anything).Return(healthy, nil)
+	health = &installProfileHealth{Timeout: 50 * time.Millisecond, Components: []string{"metricbeat-default"}}
+	err := waitForProfileHealth(ctx, c, health, 10*time.Millisecond)
+	assert.ErrorContains(t, err, "component metricbeat-default is not running")
+}
+
+func TestCheckProfileHealth(t *testing.T) {
+	state := &client.AgentState{
+		State:   client.Degraded,
+		Message: "1 unit degraded",
+		Components: []client.ComponentState{
+			{ID: "filebeat-default", State: client.Degraded, Message: "output unreachable"},
+		},
+	}
+	assert.ErrorContains(t, checkProfileHealth(state, &installProfileHealth{}), "1 unit degraded")
+	assert.NoError(t, checkProfileHealth(state, &installProfileHealth{AllowDegraded: true}))
+
+	state.State = client.Healthy
+	err := checkProfileHealth(state, &installProfileHealth{Components: []string{"filebeat-default"}})
+	assert.ErrorContains(t, err, "output unreachable")
+}
@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newMaintenanceCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance <subcommand>",
+		Short: "Unlock or lock the protected paths of a hardened install",
+		Long: `The binaries and the components of an Elastic Agent installed with --harden are protected against
+modification until they are unlocked. These commands unlock them for a maintenance window and lock them again.`,
+	}
+
+	cmd.AddCommand(newMaintenanceUnlockCommandWithArgs(args, streams))
+	cmd.AddCommand(newMaintenanceLockCommandWithArgs(args, streams))
+
+	return cmd
+}
+
+func newMaintenanceUnlockCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unlock",
+		Short: "Unlock the protected paths for a maintenance window",
+		Long: `This command unlocks the protected paths of the running Elastic Agent. They are locked again once the
+duration elapses, or when the Elastic Agent restarts. The duration is capped to 24 hours.`,
+		Run: func(c *cobra.Command, _ []string) {
+			if err := maintenanceCmd(streams, c, true); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().DurationP("duration", "d", time.Hour, "Duration of the maintenance window")
+	cmd.Flags().String(flagUninstallToken, "", "Uninstall token required to unlock a tamper protected agent")
+
+	return cmd
+}
+
+func newMaintenanceLockCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Short: "Lock the protected paths, ending the maintenance window",
+		Run: func(c *cobra.Command, _ []string) {
+			if err := maintenanceCmd(streams, c, false); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String(flagUninstallToken, "", "Uninstall token required to lock a tamper protected agent")
+
+	return cmd
+}
+
+func maintenanceCmd(streams *cli.IOStreams, cmd *cobra.Command, unlock bool) error {
+	var duration time.Duration
+	if unlock {
+		duration, _ = cmd.Flags().GetDuration("duration")
+		if duration <= 0 {
+			return errors.New("the duration must be positive")
+		}
+	}
+
+	ctx := handleSignal(context.Background())
+	uninstallToken, _ := cmd.Flags().GetString(flagUninstallToken)
+	c := client.New(client.WithUninstallToken(uninstallToken))
+	if err := c.Connect(ctx); err != nil {
+		return errors.New(err, "failed communicating to running daemon", errors.TypeNetwork, errors.M("socket", control.Address()))
+	}
+	defer c.Disconnect()
+
+	return maintenanceCmdWithClient(ctx, streams, c, duration)
+}
+
+// maintenanceCmdWithClient unlocks the protected paths for the duration, or locks them when the duration is zero.
+func maintenanceCmdWithClient(ctx context.Context, streams *cli.IOStreams, c client.Client, duration time.Duration) error {
+	until, err := c.MaintenanceUnlock(ctx, duration)
+	if err != nil {
+		return errors.New(err, "maintenance failed")
+	}
+	if until.IsZero() {
+		fmt.Fprintln(streams.Out, "Protected paths are locked")
+		return nil
+	}
+	fmt.Fprintf(streams.Out, "Protected paths are unlocked until %s\n", until.Local().Format(time.RFC3339))
+	return nil
+}
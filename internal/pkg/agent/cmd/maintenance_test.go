@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	clientmocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func TestMaintenanceCmdWithClient(t *testing.T) {
+	ctx := context.Background()
+	until := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+
+	streams, _, out, _ := cli.NewTestingIOStreams()
+	c := clientmocks.NewClient(t)
+	c.EXPECT().MaintenanceUnlock(mock.Anything, time.Hour).Return(until, nil).Once()
+	require.NoError(t, maintenanceCmdWithClient(ctx, streams, c, time.Hour))
+	assert.Contains(t, out.String(), "unlocked until")
+
+	streams, _, out, _ = cli.NewTestingIOStreams()
+	c = clientmocks.NewClient(t)
+	c.EXPECT().MaintenanceUnlock(mock.Anything, time.Duration(0)).Return(time.Time{}, nil).Once()
+	require.NoError(t, maintenanceCmdWithClient(ctx, streams, c, 0))
+	assert.Contains(t, out.String(), "locked")
+
+	c = clientmocks.NewClient(t)
+	c.EXPECT().MaintenanceUnlock(mock.Anything, mock.Anything).Return(time.Time{}, errors.New("install is not hardened")).Once()
+	err := maintenanceCmdWithClient(ctx, streams, c, time.Hour)
+	assert.ErrorContains(t, err, "install is not hardened")
+}
@@ -0,0 +1,136 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+func newMigrateCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Migrate the Elastic Agent to another Fleet cluster",
+		Long: `This command re-enrolls the running Elastic Agent into another Fleet cluster, the CLI equivalent of
+the migrate action of Fleet. The Elastic Agent keeps running its current policy until the target cluster
+sends it a new one, then it unenrolls from the source cluster.
+
+The enrollment settings not provided are kept from the current enrollment.`,
+		Args: cobra.NoArgs,
+		Run: func(c *cobra.Command, _ []string) {
+			if err := migrateCmd(streams, c); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringP("url", "", "", "URL of the Fleet Server of the target cluster")
+	cmd.Flags().StringP("enrollment-token", "t", "", "Enrollment token of the target cluster")
+	cmd.Flags().StringP("id", "", "", "Agent ID to use in the target cluster")
+	cmd.Flags().StringP("replace-token", "", "", "Replace token to use in the target cluster")
+	cmd.Flags().StringP("certificate-authorities", "a", "", "Comma-separated list of root certificates for server verification")
+	cmd.Flags().StringP("ca-sha256", "p", "", "Comma-separated list of certificate authority hash pins for server verification")
+	cmd.Flags().BoolP("insecure", "i", false, "Allow insecure connection to the Fleet Server of the target cluster")
+	cmd.Flags().StringP("elastic-agent-cert", "", "", "Elastic Agent client certificate to use with the target Fleet Server during mTLS authentication")
+	cmd.Flags().StringP("elastic-agent-cert-key", "", "", "Elastic Agent client private key to use with the target Fleet Server during mTLS authentication")
+	cmd.Flags().StringP("elastic-agent-cert-key-passphrase", "", "", "Path for private key passphrase file used to decrypt Elastic Agent client certificate key")
+	cmd.Flags().StringP("proxy-url", "", "", "Proxy used by the Elastic Agent to connect to the target Fleet Server")
+	cmd.Flags().BoolP("proxy-disabled", "", false, "Disable proxy support including environment variables")
+	cmd.Flags().StringSliceP("proxy-header", "", []string{}, "Proxy headers used with CONNECT request")
+	cmd.Flags().StringSliceP("tag", "", []string{}, "User-set tags")
+	cmd.Flags().DurationP("timeout", "", 10*time.Minute, "Timeout waiting for the migration to complete")
+	cmd.Flags().String(flagUninstallToken, "", "Uninstall token required to migrate a tamper protected agent")
+
+	_ = cmd.MarkFlagRequired("url")
+	_ = cmd.MarkFlagRequired("enrollment-token")
+
+	return cmd
+}
+
+func migrateCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	uninstallToken, _ := cmd.Flags().GetString(flagUninstallToken)
+	c := client.New(client.WithUninstallToken(uninstallToken))
+	if err := c.Connect(ctx); err != nil {
+		return errors.New(err, "failed communicating to running daemon", errors.TypeNetwork, errors.M("socket", control.Address()))
+	}
+	defer c.Disconnect()
+
+	return migrateCmdWithClient(ctx, streams, cmd, c)
+}
+
+func migrateCmdWithClient(ctx context.Context, streams *cli.IOStreams, cmd *cobra.Command, c client.Client) error {
+	url, _ := cmd.Flags().GetString("url")
+	token, _ := cmd.Flags().GetString("enrollment-token")
+	settings, err := migrateSettings(cmd)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(streams.Out, "Migrating to %s, this may take a few minutes\n", url)
+	if err := c.Migrate(ctx, url, token, settings); err != nil {
+		return errors.New(err, "migration failed")
+	}
+	fmt.Fprintln(streams.Out, "Migration succeeded, the Elastic Agent is restarting to connect to the target cluster")
+	return nil
+}
+
+// migrateSettings returns the enrollment settings overriding the current ones as expected by the
+// migrate action, nil when none are set.
+func migrateSettings(cmd *cobra.Command) ([]byte, error) {
+	settings := make(map[string]interface{})
+	flags := cmd.Flags()
+	setString := func(flag, key string) {
+		if v, _ := flags.GetString(flag); flags.Changed(flag) && v != "" {
+			settings[key] = v
+		}
+	}
+	setString("id", "id")
+	setString("replace-token", "replace_token")
+	setString("elastic-agent-cert", "certificate")
+	setString("elastic-agent-cert-key", "key")
+	setString("elastic-agent-cert-key-passphrase", "key_passphrase_path")
+	setString("proxy-url", "proxy_url")
+	if flags.Changed("certificate-authorities") {
+		v, _ := flags.GetString("certificate-authorities")
+		settings["ca"] = cli.StringToSlice(v)
+	}
+	if flags.Changed("ca-sha256") {
+		v, _ := flags.GetString("ca-sha256")
+		settings["ca_sha256"] = cli.StringToSlice(v)
+	}
+	if flags.Changed("insecure") {
+		settings["insecure"], _ = flags.GetBool("insecure")
+	}
+	if flags.Changed("proxy-disabled") {
+		settings["proxy_disabled"], _ = flags.GetBool("proxy-disabled")
+	}
+	if flags.Changed("proxy-header") {
+		v, _ := flags.GetStringSlice("proxy-header")
+		settings["proxy_headers"] = mapFromEnvList(v)
+	}
+	if flags.Changed("tag") {
+		settings["tags"], _ = flags.GetStringSlice("tag")
+	}
+
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(settings)
+}
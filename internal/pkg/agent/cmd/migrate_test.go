@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	clientmocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func TestMigrateSettings(t *testing.T) {
+	testcases := map[string]struct {
+		flags    map[string]string
+		expected string
+	}{
+		"no settings": {
+			flags: map[string]string{"url": "https://fleet.example.com", "enrollment-token": "token"},
+		},
+		"settings": {
+			flags: map[string]string{
+				"certificate-authorities": "/ca1.crt,/ca2.crt",
+				"insecure":                "false",
+				"proxy-url":               "http://proxy:3128",
+				"proxy-header":            "Proxy-Authorization=Basic abc",
+				"tag":                     "migrated,eu",
+				"replace-token":           "",
+			},
+			expected: `{"ca":["/ca1.crt","/ca2.crt"],"insecure":false,"proxy_headers":{"Proxy-Authorization":"Basic abc"},"proxy_url":"http://proxy:3128","tags":["migrated","eu"]}`,
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			streams, _, _, _ := cli.NewTestingIOStreams()
+			cmd := newMigrateCommandWithArgs(nil, streams)
+			for k, v := range tc.flags {
+				require.NoError(t, cmd.Flags().Set(k, v))
+			}
+
+			settings, err := migrateSettings(cmd)
+			require.NoError(t, err)
+			if tc.expected == "" {
+				assert.Nil(t, settings)
+				return
+			}
+			assert.JSONEq(t, tc.expected, string(settings))
+		})
+	}
+}
+
+func TestMigrateCmdWithClient(t *testing.T) {
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	cmd := newMigrateCommandWithArgs(nil, streams)
+	require.NoError(t, cmd.Flags().Set("url", "https://fleet.example.com"))
+	require.NoError(t, cmd.Flags().Set("enrollment-token", "token"))
+	require.NoError(t, cmd.Flags().Set("insecure", "true"))
+
+	ctx := context.Background()
+	c := clientmocks.NewClient(t)
+	c.EXPECT().Migrate(mock.Anything, "https://fleet.example.com", "token", []byte(`{"insecure":true}`)).Return(nil).Once()
+	require.NoError(t, migrateCmdWithClient(ctx, streams, cmd, c))
+
+	c = clientmocks.NewClient(t)
+	c.EXPECT().Migrate(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("target unreachable")).Once()
+	err := migrateCmdWithClient(ctx, streams, cmd, c)
+	assert.ErrorContains(t, err, "target unreachable")
+}
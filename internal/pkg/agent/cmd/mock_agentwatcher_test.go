@@ -29,6 +29,55 @@ func (_m *mockAgentWatcher) EXPECT() *mockAgentWatcher_Expecter {
 	return &mockAgentWatcher_Expecter{mock: &_m.Mock}
 }
 
+// HealthGate provides a mock function with given fields: ctx, until, hash, log
+func (_m *mockAgentWatcher) HealthGate(ctx context.Context, until time.Time, hash string, log *logp.Logger) error {
+	ret := _m.Called(ctx, until, hash, log)
+
+	if len(ret) == 0 {
+		panic("no return value specified for HealthGate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, string, *logp.Logger) error); ok {
+		r0 = rf(ctx, until, hash, log)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// mockAgentWatcher_HealthGate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'HealthGate'
+type mockAgentWatcher_HealthGate_Call struct {
+	*mock.Call
+}
+
+// HealthGate is a helper method to define mock.On call
+//   - ctx context.Context
+//   - until time.Time
+//   - hash string
+//   - log *logp.Logger
+func (_e *mockAgentWatcher_Expecter) HealthGate(ctx interface{}, until interface{}, hash interface{}, log interface{}) *mockAgentWatcher_HealthGate_Call {
+	return &mockAgentWatcher_HealthGate_Call{Call: _e.mock.On("HealthGate", ctx, until, hash, log)}
+}
+
+func (_c *mockAgentWatcher_HealthGate_Call) Run(run func(ctx context.Context, until time.Time, hash string, log *logp.Logger)) *mockAgentWatcher_HealthGate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Time), args[2].(string), args[3].(*logp.Logger))
+	})
+	return _c
+}
+
+func (_c *mockAgentWatcher_HealthGate_Call) Return(_a0 error) *mockAgentWatcher_HealthGate_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *mockAgentWatcher_HealthGate_Call) RunAndReturn(run func(context.Context, time.Time, string, *logp.Logger) error) *mockAgentWatcher_HealthGate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Watch provides a mock function with given fields: ctx, tilGrace, errorCheckInterval, log
 func (_m *mockAgentWatcher) Watch(ctx context.Context, tilGrace time.Duration, errorCheckInterval time.Duration, log *logp.Logger) error {
 	ret := _m.Called(ctx, tilGrace, errorCheckInterval, log)
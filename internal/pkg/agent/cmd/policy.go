@@ -0,0 +1,167 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/policyhistory"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func newPolicyCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "policy <subcommand>",
+		Short: "Inspect the history of the policies applied by the Elastic Agent",
+		Long: `Every policy applied by the Elastic Agent is recorded in a local audit trail with its hash, where it comes
+from (the Fleet action or the user changing the local configuration) and a summary of what changed from the previous
+revision. The secret values of the recorded policies are redacted.`,
+	}
+
+	cmd.AddCommand(newPolicyHistoryCommandWithArgs(args, streams))
+	cmd.AddCommand(newPolicyShowCommandWithArgs(args, streams))
+
+	return cmd
+}
+
+func newPolicyHistoryCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "List the revisions of the policy applied by the Elastic Agent",
+		Args:  cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, _ []string) {
+			number, _ := c.Flags().GetInt("number")
+			if err := policyHistoryCmd(streams.Out, paths.AgentPolicyHistoryFile(), number); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().IntP("number", "n", 20, "Maximum number of the most recent revisions to list, 0 lists them all.")
+
+	return cmd
+}
+
+func newPolicyShowCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show a revision of the policy applied by the Elastic Agent",
+		Long: `This command shows a revision of the policy from the local audit trail, with the secret values redacted.
+The most recent revision is shown unless --revision selects another one.`,
+		Example: `elastic-agent policy show --revision 12`,
+		Args:    cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, _ []string) {
+			revision, _ := c.Flags().GetInt("revision")
+			if err := policyShowCmd(streams.Out, paths.AgentPolicyHistoryFile(), revision); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().Int("revision", 0, "Revision of the policy to show, as listed by the history command.")
+
+	return cmd
+}
+
+func policyHistoryCmd(w io.Writer, path string, number int) error {
+	entries, err := policyhistory.Read(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "No policy recorded in the policy history")
+		return nil
+	}
+	if number > 0 && len(entries) > number {
+		entries = entries[len(entries)-number:]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"REVISION", "TIME", "SOURCE", "BY", "FLEET REVISION", "HASH", "CHANGES"}, "\t"))
+	for _, entry := range entries {
+		by := entry.User
+		if entry.ActionID != "" {
+			by = "action " + entry.ActionID
+		}
+		fleetRevision := ""
+		if entry.PolicyID != "" {
+			fleetRevision = entry.PolicyID + "@" + strconv.Itoa(entry.PolicyRevision)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Revision,
+			entry.Time.Local().Format(time.RFC3339),
+			entry.Source,
+			by,
+			fleetRevision,
+			shortHash(entry.Hash),
+			summarizeChanges(entry.Changes),
+		)
+	}
+	return tw.Flush()
+}
+
+func policyShowCmd(w io.Writer, path string, revision int) error {
+	var entry policyhistory.Entry
+	if revision == 0 {
+		entries, err := policyhistory.Read(path)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return fmt.Errorf("no policy recorded in the policy history")
+		}
+		entry = entries[len(entries)-1]
+	} else {
+		var err error
+		entry, err = policyhistory.Find(path, revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := yaml.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("could not marshal to YAML: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// summarizeChanges lists the changed sections prefixed by + when added, - when removed and ~ when changed.
+func summarizeChanges(changes policyhistory.Changes) string {
+	var parts []string
+	for _, name := range changes.Added {
+		parts = append(parts, "+"+name)
+	}
+	for _, name := range changes.Removed {
+		parts = append(parts, "-"+name)
+	}
+	for _, name := range changes.Changed {
+		parts = append(parts, "~"+name)
+	}
+	if len(parts) == 0 {
+		return "no visible change"
+	}
+	return strings.Join(parts, " ")
+}
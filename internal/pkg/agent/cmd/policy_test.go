@@ -0,0 +1,52 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/policyhistory"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestPolicyHistoryCmd(t *testing.T) {
+	log, _ := loggertest.New("policy_history")
+	path := filepath.Join(t.TempDir(), "policy_history.ndjson")
+	r := policyhistory.NewRecorder(log, path)
+	require.NoError(t, r.Record(map[string]interface{}{
+		"id":       "policy-1",
+		"revision": 4,
+		"inputs":   []interface{}{map[string]interface{}{"id": "logs-1", "type": "filestream"}},
+	}, coordinator.ConfigChangeOrigin{Source: "fleet", ActionID: "action-1"}))
+	require.NoError(t, r.Record(map[string]interface{}{
+		"id":       "policy-1",
+		"revision": 5,
+		"inputs":   []interface{}{map[string]interface{}{"id": "logs-2", "type": "filestream"}},
+	}, coordinator.ConfigChangeOrigin{Source: "fleet", ActionID: "action-2"}))
+
+	var out bytes.Buffer
+	require.NoError(t, policyHistoryCmd(&out, path, 1))
+	assert.Contains(t, out.String(), "action action-2")
+	assert.Contains(t, out.String(), "policy-1@5")
+	assert.Contains(t, out.String(), "+inputs.logs-2 -inputs.logs-1")
+	assert.NotContains(t, out.String(), "action action-1", "only the most recent revision is listed")
+
+	out.Reset()
+	require.NoError(t, policyShowCmd(&out, path, 1))
+	assert.Contains(t, out.String(), "revision: 1")
+	assert.Contains(t, out.String(), "id: logs-1")
+
+	out.Reset()
+	require.NoError(t, policyShowCmd(&out, path, 0))
+	assert.Contains(t, out.String(), "id: logs-2", "the most recent revision is shown by default")
+
+	assert.ErrorIs(t, policyShowCmd(&out, path, 3), policyhistory.ErrRevisionNotFound)
+}
@@ -34,7 +34,7 @@ privileged it will still perform all the same work, including stopping and start
 		Args: cobra.ExactArgs(0),
 		Run: func(c *cobra.Command, args []string) {
 			if err := privilegedCmd(streams, c); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
@@ -0,0 +1,135 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client/wait"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+func newRelocateDataCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "relocate-data <path>",
+		Short: "Relocate the data directory of the installed Elastic Agent",
+		Long: `This command moves the data directory of the installed Elastic Agent, with its state, components and logs,
+to another directory, usually on a separate volume, and replaces it with a symlink to that directory. The directory
+must be empty or not exist.
+
+By default this command will ask for a confirmation before making this change. You can bypass the confirmation request
+using the -f flag. This is not a zero downtime operation and will stop the running Elastic Agent (if running) while the
+data is copied. The Elastic Agent daemon will always be started once the data is relocated.
+
+Setting agent.paths.data in elastic-agent.yml before installing relocates the data directory during the install.
+`,
+		Args: cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := relocateDataCmd(streams, c, args[0]); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolP("force", "f", false, "Do not prompt for confirmation")
+	cmd.Flags().DurationP("daemon-timeout", "", 0, "Timeout waiting for Elastic Agent daemon restart after the change is applied (-1 = no wait)")
+
+	return cmd
+}
+
+func relocateDataCmd(streams *cli.IOStreams, cmd *cobra.Command, target string) error {
+	isAdmin, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("unable to perform relocate-data command while checking for root/Administrator rights: %w", err)
+	}
+	if !isAdmin {
+		return fmt.Errorf("unable to perform relocate-data command, not executed with %s permissions", utils.PermissionUser)
+	}
+	if !paths.RunningInstalled() {
+		return fmt.Errorf("can only relocate the data directory by executing the installed Elastic Agent at: %s", install.ExecutablePath(paths.Top()))
+	}
+	target, err = filepath.Abs(target)
+	if err != nil {
+		return fmt.Errorf("failed to resolve data path: %w", err)
+	}
+
+	topPath := paths.Top()
+	daemonTimeout, _ := cmd.Flags().GetDuration("daemon-timeout")
+	force, _ := cmd.Flags().GetBool("force")
+	if !force {
+		confirm, err := cli.Confirm(fmt.Sprintf("This will stop the running Elastic Agent and relocate its data directory to %s. Do you want to continue?", target), true)
+		if err != nil {
+			return fmt.Errorf("problem reading prompt response")
+		}
+		if !confirm {
+			return fmt.Errorf("relocate-data was cancelled by the user")
+		}
+	}
+
+	// the relocated data keeps the ownership of the install, it differs from root on unprivileged installs
+	ownership, err := getOwnerFromPath(topPath)
+	if err != nil {
+		return fmt.Errorf("failed to get the ownership of %s: %w", topPath, err)
+	}
+
+	pt := install.CreateAndStartNewSpinner(streams.Out, "Relocating Elastic Agent data...")
+	err = install.RelocateData(topPath, target, ownership, pt)
+	if err != nil {
+		// error already adds context
+		return err
+	}
+
+	// wait for the service
+	if daemonTimeout >= 0 {
+		pt.Describe("Waiting for running service")
+		ctx := handleSignal(context.Background()) // allowed to be cancelled
+		err = wait.ForAgent(ctx, daemonTimeout)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				pt.Describe("Cancelled waiting for running service")
+				return nil
+			}
+			pt.Describe("Failed waiting for running service")
+			return err
+		}
+		pt.Describe("Service is up and running")
+	}
+
+	return nil
+}
+
+// isDataRelocatedTo returns true when the data directory of the install at topPath is relocated to dataPath.
+func isDataRelocatedTo(topPath string, dataPath string) bool {
+	relocated, ok := paths.RelocatedDataFrom(topPath)
+	return ok && paths.ArePathsEqual(relocated, filepath.Clean(dataPath))
+}
+
+// configuredDataPath returns agent.paths.data of the configuration file, empty when it is not set.
+func configuredDataPath(cfgFile string) (string, error) {
+	rawConfig, err := config.LoadFile(cfgFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read configuration %s: %w", cfgFile, err)
+	}
+	cfg, err := configuration.NewFromConfig(rawConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse configuration %s: %w", cfgFile, err)
+	}
+	if cfg.Settings.Paths == nil {
+		return "", nil
+	}
+	return cfg.Settings.Paths.Data, nil
+}
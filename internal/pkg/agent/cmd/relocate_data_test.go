@@ -0,0 +1,50 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+func TestConfiguredDataPath(t *testing.T) {
+	dir := t.TempDir()
+	dataPath := filepath.Join(dir, "volume", "data")
+
+	cfgFile := filepath.Join(dir, "elastic-agent.yml")
+	require.NoError(t, os.WriteFile(cfgFile, []byte("agent.paths.data: "+dataPath+"\n"), 0o600))
+	got, err := configuredDataPath(cfgFile)
+	require.NoError(t, err)
+	assert.Equal(t, dataPath, got)
+
+	require.NoError(t, os.WriteFile(cfgFile, []byte("agent.logging.level: info\n"), 0o600))
+	got, err = configuredDataPath(cfgFile)
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	require.NoError(t, os.WriteFile(cfgFile, []byte("agent.paths.data: data\n"), 0o600))
+	_, err = configuredDataPath(cfgFile)
+	assert.ErrorContains(t, err, "must be an absolute path")
+}
+
+func TestIsDataRelocatedTo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires Administrator rights on Windows")
+	}
+	topPath := t.TempDir()
+	relocated := t.TempDir()
+	assert.False(t, isDataRelocatedTo(topPath, relocated))
+
+	require.NoError(t, os.Symlink(relocated, paths.DataFrom(topPath)))
+	assert.True(t, isDataRelocatedTo(topPath, relocated+string(filepath.Separator)))
+	assert.False(t, isDataRelocatedTo(topPath, t.TempDir()))
+}
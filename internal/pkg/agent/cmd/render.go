@@ -0,0 +1,355 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// renderAgentID is the agent ID used when rendering without --agent-id.
+const renderAgentID = "00000000-0000-0000-0000-000000000000"
+
+func newRenderCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render",
+		Short: "Render the components model of a policy without a running Elastic Agent",
+		Long: `Renders the computed configuration and the components model of a policy, the same way the running
+Elastic Agent computes them, without reading any state of the local Elastic Agent.
+
+This is used to reproduce what an Elastic Agent computed from the files of a diagnostics bundle: the policy
+(pre-config.yaml), the variables (variables.yaml) and the component specifications of the same version.
+
+The output is deterministic, rendering the same inputs produces byte-identical output across runs. The time of the
+rendering is included in the output, use --frozen-time to pin it.
+`,
+		Args: cobra.ExactArgs(0),
+		Run: func(c *cobra.Command, args []string) {
+			opts, err := renderOptsFromFlags(c)
+			if err == nil {
+				err = render(opts, streams)
+			}
+			if err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().String("policy", "", "path to the policy to render")
+	cmd.Flags().String("vars", "", "path to the variables, either variables.yaml of a diagnostics bundle or the output of 'inspect variables'")
+	cmd.Flags().String("specs", "", "path to the directory of the component specifications (default to the components of this Elastic Agent)")
+	cmd.Flags().String("frozen-time", "", "time of the rendering in RFC3339 format (default to the current time)")
+	cmd.Flags().String("platform", "", "platform to render for in <os>/<arch> format (default to this platform)")
+	cmd.Flags().String("agent-id", renderAgentID, "ID of the agent the policy is rendered for")
+	cmd.Flags().Bool("monitoring", true, "include the monitoring components")
+	_ = cmd.MarkFlagRequired("policy")
+
+	return cmd
+}
+
+type renderOpts struct {
+	policyPath string
+	varsPath   string
+	specsPath  string
+	now        time.Time
+	platform   string
+	agentID    string
+	monitoring bool
+}
+
+func renderOptsFromFlags(c *cobra.Command) (renderOpts, error) {
+	opts := renderOpts{now: time.Now().UTC()}
+	opts.policyPath, _ = c.Flags().GetString("policy")
+	opts.varsPath, _ = c.Flags().GetString("vars")
+	opts.specsPath, _ = c.Flags().GetString("specs")
+	opts.platform, _ = c.Flags().GetString("platform")
+	opts.agentID, _ = c.Flags().GetString("agent-id")
+	opts.monitoring, _ = c.Flags().GetBool("monitoring")
+	if opts.specsPath == "" {
+		opts.specsPath = paths.Components()
+	}
+
+	frozenTime, _ := c.Flags().GetString("frozen-time")
+	if frozenTime != "" {
+		t, err := time.Parse(time.RFC3339, frozenTime)
+		if err != nil {
+			return opts, errors.New(err, fmt.Sprintf("invalid --frozen-time %q", frozenTime), errors.TypeValidation)
+		}
+		opts.now = t.UTC()
+	}
+	return opts, nil
+}
+
+// renderOutput is the output of the render command. Maps are marshalled with sorted keys, and components and
+// units are sorted by ID, so the output only depends on the inputs.
+type renderOutput struct {
+	RenderedAt     time.Time              `yaml:"rendered_at"`
+	Platform       string                 `yaml:"platform"`
+	ComputedConfig map[string]interface{} `yaml:"computed_config"`
+	Components     []component.Component  `yaml:"components"`
+}
+
+func render(opts renderOpts, streams *cli.IOStreams) error {
+	out, err := renderPolicy(opts)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return errors.New(err, "could not marshal to YAML")
+	}
+	_, err = streams.Out.Write(data)
+	return err
+}
+
+func renderPolicy(opts renderOpts) (*renderOutput, error) {
+	platform, err := component.LoadPlatformDetail(renderPlatformModifier(opts.platform))
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather system information: %w", err)
+	}
+	if opts.platform != "" && platform.String() != opts.platform {
+		return nil, errors.New(fmt.Sprintf("unsupported platform %q", opts.platform), errors.TypeValidation)
+	}
+	// the binaries are not needed to render, a diagnostics bundle only includes the specifications
+	specs, err := component.LoadRuntimeSpecs(opts.specsPath, platform, component.SkipBinaryCheck())
+	if err != nil {
+		return nil, errors.New(err, "failed to load the component specifications", errors.TypePath, errors.M(errors.MetaKeyPath, opts.specsPath))
+	}
+
+	rawCfg, err := config.LoadFile(opts.policyPath)
+	if err != nil {
+		return nil, errors.New(err, "failed to load the policy", errors.TypeConfig, errors.M(errors.MetaKeyPath, opts.policyPath))
+	}
+	lvl, err := getLogLevel(rawCfg, opts.policyPath)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := rawCfg.ToMapStr()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the policy: %w", err)
+	}
+
+	vars, err := loadRenderVars(opts.varsPath)
+	if err != nil {
+		return nil, err
+	}
+	computed, err := computeRenderConfig(policy, vars)
+	if err != nil {
+		return nil, err
+	}
+	agentInfo := &renderAgentInfo{id: opts.agentID, logLevel: lvl.String()}
+	comps, err := renderComponents(specs, computed, lvl, agentInfo, opts.monitoring)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, comp := range comps {
+		// the specifications are an input of the rendering
+		comp.InputSpec = nil
+		sort.Slice(comp.Units, func(i, j int) bool {
+			return comp.Units[i].ID < comp.Units[j].ID
+		})
+		comps[i] = comp
+	}
+	sort.Slice(comps, func(i, j int) bool {
+		return comps[i].ID < comps[j].ID
+	})
+
+	return &renderOutput{
+		RenderedAt:     opts.now,
+		Platform:       platform.String(),
+		ComputedConfig: computed,
+		Components:     comps,
+	}, nil
+}
+
+// computeRenderConfig computes the configuration of the policy with the variables, the same way the coordinator
+// does: presets are expanded and the inputs are rendered with the variables, evaluating their conditions.
+func computeRenderConfig(policy map[string]interface{}, vars []*transpiler.Vars) (map[string]interface{}, error) {
+	ast, err := transpiler.NewAST(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not create the AST from the policy: %w", err)
+	}
+	if err := transpiler.ExpandPresets(ast); err != nil {
+		return nil, fmt.Errorf("expanding presets failed: %w", err)
+	}
+	renderedInputs, ok, err := transpiler.RenderAllInputs(ast, vars)
+	if err != nil {
+		return nil, fmt.Errorf("rendering inputs failed: %w", err)
+	}
+	if ok {
+		if err := transpiler.Insert(ast, renderedInputs, "inputs"); err != nil {
+			return nil, fmt.Errorf("inserting rendered inputs failed: %w", err)
+		}
+	}
+	computed, err := ast.Map()
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert ast to map[string]interface{}: %w", err)
+	}
+	return computed, nil
+}
+
+// renderComponents computes the components model of the computed configuration, with the monitoring components
+// when withMonitoring is set.
+func renderComponents(specs component.RuntimeSpecs, computed map[string]interface{}, lvl logp.Level, agentInfo info.Agent, withMonitoring bool) ([]component.Component, error) {
+	var monitorFn component.GenerateMonitoringCfgFn
+	if withMonitoring {
+		var err error
+		monitorFn, err = renderMonitoringFn(computed, agentInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get monitoring: %w", err)
+		}
+	}
+	comps, err := specs.ToComponents(computed, monitorFn, lvl, agentInfo, map[string]uint64{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render components: %w", err)
+	}
+	return comps, nil
+}
+
+// renderPlatformModifier renders for the platform instead of this platform, when set.
+func renderPlatformModifier(platform string) component.PlatformModifier {
+	return func(detail component.PlatformDetail) component.PlatformDetail {
+		for _, p := range component.GlobalPlatforms {
+			if p.String() == platform {
+				detail.Platform = p
+				detail.NativeArch = p.Arch
+			}
+		}
+		return detail
+	}
+}
+
+func renderMonitoringFn(cfg map[string]interface{}, agentInfo info.Agent) (component.GenerateMonitoringCfgFn, error) {
+	rawCfg, err := config.NewConfigFrom(cfg)
+	if err != nil {
+		return nil, err
+	}
+	agentCfg := configuration.DefaultConfiguration()
+	if err := rawCfg.UnpackTo(agentCfg); err != nil {
+		return nil, err
+	}
+	monitor := monitoring.New(agentCfg.Settings.V1MonitoringEnabled, agentCfg.Settings.DownloadConfig.OS(), agentCfg.Settings.MonitoringConfig, agentInfo)
+	return monitor.MonitoringConfig, nil
+}
+
+// renderVarsFile is either the variables.yaml file of a diagnostics bundle or the output of 'inspect variables'.
+type renderVarsFile struct {
+	Variables       []map[string]interface{} `yaml:"variables"`
+	DefaultProvider string                   `yaml:"default_provider"`
+	Vars            []client.VarsMapping     `yaml:"vars"`
+}
+
+// loadRenderVars loads the variables to render the policy with, an empty set of variables when path is empty.
+func loadRenderVars(path string) ([]*transpiler.Vars, error) {
+	if path == "" {
+		v, err := transpiler.NewVars("", map[string]interface{}{}, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		return []*transpiler.Vars{v}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.New(err, "failed to read the variables", errors.TypeFilesystem, errors.M(errors.MetaKeyPath, path))
+	}
+	var file renderVarsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, errors.New(err, "failed to parse the variables", errors.TypeConfig, errors.M(errors.MetaKeyPath, path))
+	}
+
+	mappings := file.Vars
+	for _, m := range file.Variables {
+		mappings = append(mappings, client.VarsMapping{Mapping: m})
+	}
+	if len(mappings) == 0 {
+		return nil, errors.New(fmt.Sprintf("no variables found in %s", path), errors.TypeConfig, errors.M(errors.MetaKeyPath, path))
+	}
+	vars := make([]*transpiler.Vars, 0, len(mappings))
+	for _, m := range mappings {
+		v, err := transpiler.NewVars(m.ID, normalizeRenderMapping(m.Mapping), nil, file.DefaultProvider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create variables %q: %w", m.ID, err)
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// normalizeRenderMapping converts the map[interface{}]interface{} values decoded from YAML to
+// map[string]interface{} values expected by the AST.
+func normalizeRenderMapping(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = normalizeRenderValue(v)
+	}
+	return out
+}
+
+func normalizeRenderValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, v := range val {
+			out[fmt.Sprint(k)] = normalizeRenderValue(v)
+		}
+		return out
+	case map[string]interface{}:
+		return normalizeRenderMapping(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, v := range val {
+			out[i] = normalizeRenderValue(v)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// renderAgentInfo is the static agent information of a rendering, it never reads the state of the local Elastic
+// Agent so the rendering only depends on its inputs.
+type renderAgentInfo struct {
+	id       string
+	logLevel string
+}
+
+func (i *renderAgentInfo) AgentID() string                           { return i.id }
+func (i *renderAgentInfo) Headers() map[string]string                { return nil }
+func (i *renderAgentInfo) LogLevel() string                          { return i.logLevel }
+func (i *renderAgentInfo) RawLogLevel() string                       { return i.logLevel }
+func (i *renderAgentInfo) ReloadID(context.Context) error            { return nil }
+func (i *renderAgentInfo) SetLogLevel(context.Context, string) error { return nil }
+func (i *renderAgentInfo) Snapshot() bool                            { return false }
+func (i *renderAgentInfo) Version() string                           { return "" }
+func (i *renderAgentInfo) Unprivileged() bool                        { return false }
+func (i *renderAgentInfo) IsStandalone() bool                        { return true }
+func (i *renderAgentInfo) ECSMetadata(*logger.Logger) (*info.ECSMeta, error) {
+	return nil, fmt.Errorf("not supported when rendering")
+}
+
+var _ info.Agent = (*renderAgentInfo)(nil)
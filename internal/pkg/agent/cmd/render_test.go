@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestRender(t *testing.T) {
+	opts := renderOpts{
+		policyPath: filepath.Join("testdata", "render", "policy.yml"),
+		varsPath:   filepath.Join("testdata", "render", "variables.yaml"),
+		specsPath:  filepath.Join("..", "..", "..", "..", "specs"),
+		now:        time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		platform:   "linux/amd64",
+		agentID:    renderAgentID,
+		monitoring: true,
+	}
+	expected, err := os.ReadFile(filepath.Join("testdata", "render", "expected.yml"))
+	require.NoError(t, err)
+
+	// rendering twice must produce byte-identical output
+	for i := 0; i < 2; i++ {
+		out := &bytes.Buffer{}
+		err := render(opts, &cli.IOStreams{Out: out, Err: &bytes.Buffer{}})
+		require.NoError(t, err)
+		assert.Equal(t, string(expected), out.String())
+	}
+}
+
+func TestRenderInvalidPlatform(t *testing.T) {
+	_, err := renderPolicy(renderOpts{
+		policyPath: filepath.Join("testdata", "render", "policy.yml"),
+		specsPath:  filepath.Join("..", "..", "..", "..", "specs"),
+		platform:   "plan9/amd64",
+	})
+	assert.ErrorContains(t, err, `unsupported platform "plan9/amd64"`)
+}
+
+func TestLoadRenderVars(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("diagnostics variables", func(t *testing.T) {
+		path := filepath.Join(dir, "variables.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("variables:\n- host:\n    name: web01\n"), 0o644))
+		vars, err := loadRenderVars(path)
+		require.NoError(t, err)
+		require.Len(t, vars, 1)
+		name, ok := vars[0].Lookup("host.name")
+		require.True(t, ok)
+		assert.Equal(t, "web01", name)
+	})
+
+	t.Run("inspect variables", func(t *testing.T) {
+		vars, err := loadRenderVars(filepath.Join("testdata", "render", "variables.yaml"))
+		require.NoError(t, err)
+		require.Len(t, vars, 3)
+		assert.Equal(t, "kubernetes-pod-2", vars[1].ID())
+		name, ok := vars[1].Lookup("kubernetes.pod.name")
+		require.True(t, ok)
+		assert.Equal(t, "pod-2", name)
+	})
+
+	t.Run("no variables", func(t *testing.T) {
+		path := filepath.Join(dir, "empty.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("{}\n"), 0o644))
+		_, err := loadRenderVars(path)
+		assert.ErrorContains(t, err, "no variables found")
+	})
+}
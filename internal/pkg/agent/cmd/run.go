@@ -32,20 +32,29 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/autocapture"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/eventlog"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/filelock"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/guardrail"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/memorypressure"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring/reload"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/policyhistory"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/rundir"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact/download/peercache"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/watchdog"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/migration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
@@ -53,6 +62,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
+	agentclient "github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/control/v2/server"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
@@ -87,7 +97,10 @@ func newRunCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
 			fleetInitTimeout, _ := cmd.Flags().GetDuration("fleet-init-timeout")
 			testingMode, _ := cmd.Flags().GetBool("testing-mode")
 			if err := run(nil, testingMode, fleetInitTimeout); err != nil && !errors.Is(err, context.Canceled) {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				if flag := cmd.Flag(cli.ErrorFormatFlag); flag == nil || flag.Value.String() != cli.ErrorFormatJSON {
+					// in JSON the returned error is written by the caller
+					fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				}
 				logExternal(fmt.Sprintf("%s run failed: %s", paths.BinaryName, err))
 				return err
 			}
@@ -188,7 +201,11 @@ func runElasticAgent(
 	if cfg.Settings.LoggingConfig != nil {
 		logLvl = cfg.Settings.LoggingConfig.Level
 	}
-	baseLogger, err := logger.NewFromConfig("", cfg.Settings.LoggingConfig, cfg.Settings.EventLoggingConfig, true)
+	var logOpts []logger.Option
+	if cfg.Settings.ToJournald {
+		logOpts = append(logOpts, logger.WithJournald(cfg.Settings.JournaldConfig))
+	}
+	baseLogger, err := logger.NewFromConfig("", cfg.Settings.LoggingConfig, cfg.Settings.EventLoggingConfig, true, logOpts...)
 	if err != nil {
 		return err
 	}
@@ -211,6 +228,20 @@ func runElasticAgent(
 		"agent.version", version.GetAgentPackageVersion(),
 		"agent.unprivileged", !isRoot)
 
+	if cfg.Settings.Paths != nil && cfg.Settings.Paths.Data != "" && paths.RunningInstalled() && !isDataRelocatedTo(paths.Top(), cfg.Settings.Paths.Data) {
+		// the data directory cannot be relocated while the Elastic Agent runs from it
+		l.Warnf("agent.paths.data is set to %s but the data directory is not relocated to it, run 'elastic-agent relocate-data %s' to relocate it",
+			cfg.Settings.Paths.Data, cfg.Settings.Paths.Data)
+	}
+
+	if cfg.Settings.Vault != nil {
+		// the backend of the vault is saved on install, it cannot change once the vault stores secrets
+		if saved, err := vault.LoadConfig(paths.AgentVaultPath()); err == nil && saved.Backend != cfg.Settings.Vault.Backend {
+			l.Warnf("agent.vault.backend is set to %q but the vault uses the %q backend it was installed with, reinstall the Elastic Agent to change it",
+				cfg.Settings.Vault.Backend, saved.Backend)
+		}
+	}
+
 	cfg, err = tryDelayEnroll(ctx, l, cfg, override)
 	if err != nil {
 		return logReturn(l, errors.New(err, "failed to perform delayed enrollment"))
@@ -312,10 +343,93 @@ func runElasticAgent(
 		}
 	}()
 
+	otlpExporter := monitoring.NewOTLPExporter(l.Named("otlp_monitoring"), monitoringLib.GetNamespace("stats"), coord, agentInfo.AgentID(), cfg.Settings.MonitoringConfig)
+	coord.RegisterOTLPExporter(otlpExporter)
+	otlpExporter.Start()
+	defer otlpExporter.Stop()
+
+	faultHandler := watchdog.New(l.Named("fault_handling"), coord, paths.Top())
+	coord.RegisterFaultHandler(faultHandler)
+	go faultHandler.Run(ctx)
+
+	autoCapture := autocapture.New(l.Named("diagnostics_auto_capture"), coord, paths.Top())
+	coord.RegisterDiagnosticsAutoCapture(autoCapture)
+	go autoCapture.Run(ctx)
+
+	eventLog := eventlog.New(l.Named("event_log"), coord)
+	coord.RegisterEventLog(eventLog)
+	go eventLog.Run(ctx)
+	// the stop is written before exiting, the Run goroutine may not be scheduled once the context is done
+	defer eventLog.Close()
+
+	policyGuardrail := guardrail.New(l.Named("policy_guardrail"), coord)
+	coord.RegisterPolicyGuardrail(policyGuardrail)
+	go policyGuardrail.Run(ctx)
+	policyRollbackStore, err := storage.NewEncryptedDiskStore(ctx, paths.AgentPolicyRollbackFile())
+	if err != nil {
+		// the rollback is not kept across the restarts, the rolled back revision is applied again after one
+		l.Errorf("Failed to create the policy rollback store: %v", err)
+	} else {
+		coord.RegisterPolicyRollbackStore(policyRollbackStore)
+	}
+
+	coord.RegisterPolicyRecorder(policyhistory.NewRecorder(l.Named("policy_history"), paths.AgentPolicyHistoryFile()))
+
+	if cfg.Settings.DownloadConfig.PeerCache.Serve.Enabled {
+		peerCacheServer, err := peercache.NewServer(l.Named("peer_cache"), cfg.Settings.DownloadConfig)
+		if err == nil {
+			err = peerCacheServer.Start()
+		}
+		if err != nil {
+			// the agent works without the cache, its peers download the artifacts themselves
+			l.Errorf("Failed to start the peer cache, the upgrade artifacts are not served to the peers: %v", err)
+		} else {
+			defer func() {
+				_ = peerCacheServer.Stop()
+			}()
+		}
+	}
+
+	if cfg.Settings.MemoryPressure.Enabled {
+		memoryMonitor, err := memorypressure.New(l.Named("memory_pressure"), cfg.Settings.MemoryPressure, func(pressure bool, _ memorypressure.Usage) {
+			coord.SetMemoryPressure(pressure)
+		})
+		if err != nil {
+			return logReturn(l, err)
+		}
+		go memoryMonitor.Run(ctx)
+	}
+
+	if cfg.Settings.RunCleanup.Enabled {
+		collector := rundir.New(l.Named("run_cleanup"), cfg.Settings.RunCleanup, func() []string {
+			state := coord.State()
+			if state.CoordinatorState == agentclient.Failed {
+				// the components of a failed policy are not the ones of the model
+				return nil
+			}
+			var ids []string
+			for _, comp := range state.Components {
+				ids = append(ids, comp.Component.ID)
+			}
+			return ids
+		})
+		go collector.Run(ctx)
+	}
+
 	diagHooks := diagnostics.GlobalHooks()
 	diagHooks = append(diagHooks, coord.DiagnosticHooks()...)
 	controlLog := l.Named("control")
 	control := server.New(controlLog, agentInfo, coord, tracer, diagHooks, cfg.Settings.GRPC)
+	coord.RegisterControlAuthorization(control)
+	if isRoot && paths.RunningInstalled() {
+		coord.RegisterServiceStopProtection(install.NewServiceStopProtection(l.Named("stop_protection")))
+		if perms.IsHardened(paths.Top()) {
+			// lock the protected paths again, a maintenance window does not survive a restart
+			if err := perms.Lock(paths.Top()); err != nil {
+				l.Errorw("Failed to lock the protected paths", "error.message", err)
+			}
+		}
+	}
 
 	// if the configMgr implements the TestModeConfigSetter in means that Elastic Agent is in testing mode and
 	// the configuration will come in over the control protocol, so we set the config setting on the control protocol
@@ -408,6 +522,10 @@ LOOP:
 		l.Info("Shutting down completed.")
 	}
 	if isRex {
+		// hand off the control socket so the commands run during the restart reach the new process
+		if err := control.Handoff(); err != nil {
+			l.Warnf("Failed to hand off the control socket to the re-executed Elastic Agent: %s", err)
+		}
 		rex.ShutdownComplete()
 	}
 	return logReturn(l, err)
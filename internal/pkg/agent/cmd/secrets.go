@@ -0,0 +1,146 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+func newSecretsCommandWithArgs(args []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secrets <subcommand>",
+		Short: "Manage the secrets referenced in the policy",
+		Long: `The secrets are stored encrypted in the vault of the Elastic Agent and referenced in a standalone policy
+with ${secret.<name>}, they are substituted when the policy is rendered and never stored in plaintext.`,
+	}
+
+	cmd.AddCommand(newSecretsSetCommandWithArgs(args, streams))
+	cmd.AddCommand(newSecretsRemoveCommandWithArgs(args, streams))
+
+	return cmd
+}
+
+func newSecretsSetCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <name>",
+		Short: "Set a secret referenced in the policy",
+		Long: `This command stores the value of the secret referenced with ${secret.<name>}. The value is read from stdin,
+or prompted for when stdin is a terminal. The running Elastic Agent renders the policy again with the new value.`,
+		Example: `echo -n "changeme" | elastic-agent secrets set es-password`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := secretsSetCmd(streams, args[0]); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func newSecretsRemoveCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove a secret referenced in the policy",
+		Args:  cobra.ExactArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			if err := secretsRemoveCmd(streams, args[0]); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+}
+
+func secretsSetCmd(streams *cli.IOStreams, name string) error {
+	if err := secret.ValidatePolicySecretName(name); err != nil {
+		return err
+	}
+	value, err := readSecretValue(streams, os.Stdin)
+	if err != nil {
+		return err
+	}
+	opts, ownership, err := secretsVaultOptions()
+	if err != nil {
+		return err
+	}
+	if err := secret.SetPolicySecret(handleSignal(context.Background()), name, value, opts...); err != nil {
+		return fmt.Errorf("failed to set secret %q: %w", name, err)
+	}
+	// the secrets are stored in the keychain on a privileged macOS install, there is no vault path
+	if _, statErr := os.Stat(paths.AgentVaultPath()); ownership != nil && statErr == nil {
+		if err := perms.FixPermissions(paths.AgentVaultPath(), perms.WithOwnership(*ownership)); err != nil {
+			return fmt.Errorf("failed to fix permissions of the vault: %w", err)
+		}
+	}
+	fmt.Fprintf(streams.Out, "Secret %q set, reference it in the policy with ${secret.%s}\n", name, name)
+	return nil
+}
+
+func secretsRemoveCmd(streams *cli.IOStreams, name string) error {
+	opts, _, err := secretsVaultOptions()
+	if err != nil {
+		return err
+	}
+	if err := secret.RemovePolicySecret(handleSignal(context.Background()), name, opts...); err != nil {
+		return fmt.Errorf("failed to remove secret %q: %w", name, err)
+	}
+	fmt.Fprintf(streams.Out, "Secret %q removed\n", name)
+	return nil
+}
+
+// readSecretValue prompts for the value without echoing it when stdin is a terminal, and reads it from stdin
+// otherwise. The trailing newline of the input is not part of the value.
+func readSecretValue(streams *cli.IOStreams, stdin *os.File) ([]byte, error) {
+	var value []byte
+	var err error
+	if term.IsTerminal(int(stdin.Fd())) { //nolint:gosec // G115 file descriptors fit in an int
+		fmt.Fprint(streams.Out, "Secret value: ")
+		value, err = term.ReadPassword(int(stdin.Fd())) //nolint:gosec // G115 file descriptors fit in an int
+		fmt.Fprintln(streams.Out)
+	} else {
+		value, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the secret value: %w", err)
+	}
+	value = []byte(strings.TrimRight(string(value), "\r\n"))
+	if len(value) == 0 {
+		return nil, errors.New("the secret value is empty")
+	}
+	return value, nil
+}
+
+// secretsVaultOptions returns the options of the vault of the Elastic Agent, and the owner of the installed Elastic
+// Agent when running as root so the secrets written by root can be read by an unprivileged Elastic Agent.
+func secretsVaultOptions() ([]vault.OptionFunc, *utils.FileOwner, error) {
+	hasRoot, err := utils.HasRoot()
+	if err != nil {
+		return nil, nil, fmt.Errorf("checking if running with root/Administrator privileges: %w", err)
+	}
+	opts := []vault.OptionFunc{vault.WithUnprivileged(!hasRoot)}
+	if !hasRoot || !paths.RunningInstalled() {
+		return opts, nil, nil
+	}
+	ownership, err := getOwnerFromPath(paths.Top())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get owner from path %s: %w", paths.Top(), err)
+	}
+	return append(opts, vault.WithVaultOwnership(ownership)), &ownership, nil
+}
@@ -0,0 +1,39 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
+
+func TestReadSecretValue(t *testing.T) {
+	streams, _, _, _ := cli.NewTestingIOStreams()
+	stdin := func(input string) *os.File {
+		path := filepath.Join(t.TempDir(), "stdin")
+		require.NoError(t, os.WriteFile(path, []byte(input), 0o600))
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+
+	value, err := readSecretValue(streams, stdin("changeme\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "changeme", string(value), "the trailing newline is not part of the value")
+
+	value, err = readSecretValue(streams, stdin("multi\nline"))
+	require.NoError(t, err)
+	assert.Equal(t, "multi\nline", string(value))
+
+	_, err = readSecretValue(streams, stdin("\n"))
+	assert.Error(t, err, "an empty value is refused")
+}
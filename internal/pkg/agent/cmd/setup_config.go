@@ -4,7 +4,11 @@
 
 package cmd
 
-import "time"
+import (
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+)
 
 // setup configuration
 
@@ -15,27 +19,30 @@ type setupConfig struct {
 }
 
 type fleetConfig struct {
-	CA              string            `config:"ca"`
-	Enroll          bool              `config:"enroll"`
-	EnrollmentToken string            `config:"enrollment_token"`
-	ID              string            `config:"id"`
-	ReplaceToken    string            `config:"replace_token"`
-	Force           bool              `config:"force"`
-	Insecure        bool              `config:"insecure"`
-	TokenName       string            `config:"token_name"`
-	TokenPolicyName string            `config:"token_policy_name"`
-	URL             string            `config:"url"`
-	Headers         map[string]string `config:"headers"`
-	DaemonTimeout   time.Duration     `config:"daemon_timeout"`
-	EnrollTimeout   time.Duration     `config:"enroll_timeout"`
-	Cert            string            `config:"cert"`
-	CertKey         string            `config:"cert_key"`
+	CA               string            `config:"ca"`
+	Enroll           bool              `config:"enroll"`
+	EnrollmentToken  string            `config:"enrollment_token"`
+	IdentityProvider string            `config:"identity_provider"`
+	ID               string            `config:"id"`
+	ReplaceToken     string            `config:"replace_token"`
+	Force            bool              `config:"force"`
+	Insecure         bool              `config:"insecure"`
+	TokenName        string            `config:"token_name"`
+	TokenPolicyName  string            `config:"token_policy_name"`
+	URL              string            `config:"url"`
+	Headers          map[string]string `config:"headers"`
+	DaemonTimeout    time.Duration     `config:"daemon_timeout"`
+	EnrollTimeout    time.Duration     `config:"enroll_timeout"`
+	Cert             string            `config:"cert"`
+	CertKey          string            `config:"cert_key"`
 }
 
 type fleetServerConfig struct {
 	Cert           string              `config:"cert"`
 	CertKey        string              `config:"cert_key"`
 	PassphrasePath string              `config:"key_passphrase_path"`
+	CertSANs       []string            `config:"cert_sans"`
+	CertValidity   time.Duration       `config:"cert_validity"`
 	ClientAuth     string              `config:"client_authentication"`
 	Elasticsearch  elasticsearchConfig `config:"elasticsearch"`
 	Enable         bool                `config:"enable"`
@@ -87,26 +94,29 @@ func defaultAccessConfig() (setupConfig, error) {
 
 	cfg := setupConfig{
 		Fleet: fleetConfig{
-			CA:              envWithDefault("", "FLEET_CA", "KIBANA_CA", "ELASTICSEARCH_CA"),
-			Enroll:          envBool("FLEET_ENROLL", "FLEET_SERVER_ENABLE"),
-			EnrollmentToken: envWithDefault("", "FLEET_ENROLLMENT_TOKEN"),
-			ID:              envWithDefault("", "ELASTIC_AGENT_ID"),
-			ReplaceToken:    envWithDefault("", "FLEET_REPLACE_TOKEN"),
-			Force:           envBool("FLEET_FORCE"),
-			Insecure:        envBool("FLEET_INSECURE"),
-			TokenName:       envWithDefault("Default", "FLEET_TOKEN_NAME"),
-			TokenPolicyName: envWithDefault("", "FLEET_TOKEN_POLICY_NAME"),
-			URL:             envWithDefault("", "FLEET_URL"),
-			Headers:         envMap("FLEET_HEADER"),
-			DaemonTimeout:   envTimeout("FLEET_DAEMON_TIMEOUT"),
-			EnrollTimeout:   envTimeout("FLEET_ENROLL_TIMEOUT"),
-			Cert:            envWithDefault("", "ELASTIC_AGENT_CERT"),
-			CertKey:         envWithDefault("", "ELASTIC_AGENT_CERT_KEY"),
+			CA:               envWithDefault("", "FLEET_CA", "KIBANA_CA", "ELASTICSEARCH_CA"),
+			Enroll:           envBool("FLEET_ENROLL", "FLEET_SERVER_ENABLE"),
+			EnrollmentToken:  envWithDefault("", "FLEET_ENROLLMENT_TOKEN"),
+			IdentityProvider: envWithDefault("", "FLEET_IDENTITY_PROVIDER"),
+			ID:               envWithDefault("", "ELASTIC_AGENT_ID"),
+			ReplaceToken:     envWithDefault("", "FLEET_REPLACE_TOKEN"),
+			Force:            envBool("FLEET_FORCE"),
+			Insecure:         envBool("FLEET_INSECURE"),
+			TokenName:        envWithDefault("Default", "FLEET_TOKEN_NAME"),
+			TokenPolicyName:  envWithDefault("", "FLEET_TOKEN_POLICY_NAME"),
+			URL:              envWithDefault("", "FLEET_URL"),
+			Headers:          envMap("FLEET_HEADER"),
+			DaemonTimeout:    envTimeout("FLEET_DAEMON_TIMEOUT"),
+			EnrollTimeout:    envTimeout("FLEET_ENROLL_TIMEOUT"),
+			Cert:             envWithDefault("", "ELASTIC_AGENT_CERT"),
+			CertKey:          envWithDefault("", "ELASTIC_AGENT_CERT_KEY"),
 		},
 		FleetServer: fleetServerConfig{
 			Cert:           envWithDefault("", "FLEET_SERVER_CERT"),
 			CertKey:        envWithDefault("", "FLEET_SERVER_CERT_KEY"),
 			PassphrasePath: envWithDefault("", "FLEET_SERVER_CERT_KEY_PASSPHRASE"),
+			CertSANs:       cli.StringToSlice(envWithDefault("", "FLEET_SERVER_CERT_SAN")),
+			CertValidity:   envTimeout("FLEET_SERVER_CERT_VALIDITY"),
 			ClientAuth:     envWithDefault("none", "FLEET_SERVER_CLIENT_AUTH"),
 			Elasticsearch: elasticsearchConfig{
 				Host:                 envWithDefault("http://elasticsearch:9200", "FLEET_SERVER_ELASTICSEARCH_HOST", "ELASTICSEARCH_HOST"),
@@ -11,6 +11,8 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
@@ -22,6 +24,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/docker/go-units"
 	"github.com/jedib0t/go-pretty/v6/list"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -33,63 +36,171 @@ type outputter func(io.Writer, interface{}) error
 var statusOutputs = map[string]outputter{
 	"human": humanOutput,
 	"full":  humanFullOutput,
+	"table": tableOutput,
 	"json":  jsonOutput,
 	"yaml":  yamlOutput,
 }
 
+// exit codes of the status command, health checks rely on them
+const (
+	statusExitHealthy = 0
+	// statusExitDegraded is also used while the agent is starting, configuring, upgrading or stopping.
+	statusExitDegraded = 1
+	statusExitFailed   = 2
+	// statusExitUnavailable is used when the status cannot be read, e.g. the agent is not running.
+	statusExitUnavailable = 3
+)
+
 func newStatusCommand(_ []string, streams *cli.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show the current status of the running Elastic Agent daemon",
-		Long:  `This command shows the current status of the running Elastic Agent daemon.`,
+		Long: `This command shows the current status of the running Elastic Agent daemon.
+
+The json and yaml outputs are the unversioned document of the previous releases by default. With
+--schema-version=1 they are a versioned document with the states as names, its schema_version field is the
+selected version. Scripts and health checks should select a version, the fields of a version never change.
+
+The exit code reflects the state of the Elastic Agent:
+  0  healthy
+  1  degraded, or starting, configuring, upgrading or stopping
+  2  failed
+  3  the status cannot be read, e.g. the Elastic Agent daemon is not running`,
 		Run: func(c *cobra.Command, args []string) {
-			if err := statusCmd(streams, c, args); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
-				os.Exit(1)
+			code, err := statusCmd(streams, c, args)
+			if err != nil {
+				printCommandError(c, streams, err)
 			}
+			os.Exit(code)
 		},
 	}
 
-	cmd.Flags().String("output", "human", "Output the status information in either 'human', 'full', 'json', or 'yaml'.  'human' only shows non-healthy details, others show full details. (default: human)")
+	cmd.Flags().String("output", "human", "Output the status information in either 'human', 'full', 'table', 'json', or 'yaml'.  'human' only shows non-healthy details, others show full details. (default: human)")
+	cmd.Flags().Int("schema-version", statusSchemaLegacy, "Version of the schema of the 'json' and 'yaml' outputs, 0 is the unversioned document. (default: 0)")
+	cmd.Flags().Bool("watch", false, "Watch the state changes of the agent, its components and their units, printing one change per line until interrupted. Only 'human' and 'json' outputs are supported.")
 
 	return cmd
 }
 
-func statusCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error {
+// statusCmd prints the status of the agent and returns the exit code reflecting it.
+func statusCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) (int, error) {
 	output, _ := cmd.Flags().GetString("output")
 	outputFunc, ok := statusOutputs[output]
 	if !ok {
-		return fmt.Errorf("unsupported output: %s", output)
+		return statusExitUnavailable, fmt.Errorf("unsupported output: %s", output)
 	}
+	schemaVersion, _ := cmd.Flags().GetInt("schema-version")
 
 	ctx := handleSignal(context.Background())
+	if watch, _ := cmd.Flags().GetBool("watch"); watch {
+		daemon := client.New()
+		if err := daemon.Connect(ctx); err != nil {
+			return statusExitUnavailable, fmt.Errorf("failed to communicate with Elastic Agent daemon: %w", err)
+		}
+		defer daemon.Disconnect()
+		if err := watchStatus(ctx, daemon, streams.Out, output); err != nil {
+			return statusExitUnavailable, err
+		}
+		return statusExitHealthy, nil
+	}
+
 	innerCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
 	state, err := getDaemonState(innerCtx)
 	if errors.Is(err, context.DeadlineExceeded) {
-		return errors.New("timed out after 30 seconds trying to connect to Elastic Agent daemon")
+		return statusExitUnavailable, errors.New("timed out after 30 seconds trying to connect to Elastic Agent daemon")
 	} else if errors.Is(err, context.Canceled) {
-		return nil
+		return statusExitUnavailable, nil
 	} else if err != nil {
-		return fmt.Errorf("failed to communicate with Elastic Agent daemon: %w", err)
+		return statusExitUnavailable, fmt.Errorf("failed to communicate with Elastic Agent daemon: %w", err)
 	}
 
 	sort.SliceStable(state.Components, func(i, j int) bool { return state.Components[i].ID < state.Components[j].ID })
 	for _, c := range state.Components {
 		sort.SliceStable(c.Units, func(i, j int) bool { return c.Units[i].UnitID < c.Units[j].UnitID })
 	}
-	err = outputFunc(streams.Out, state)
+	var out interface{} = state
+	if output == "json" || output == "yaml" {
+		out, err = statusDocument(state, schemaVersion)
+		if err != nil {
+			return statusExitUnavailable, err
+		}
+	}
+	if err := outputFunc(streams.Out, out); err != nil {
+		return statusExitUnavailable, err
+	}
+	return statusExitCode(state.State), nil
+}
+
+// statusExitCode returns the exit code of the status command for the state of the agent.
+func statusExitCode(state client.State) int {
+	switch state {
+	case client.Healthy:
+		return statusExitHealthy
+	case client.Failed:
+		return statusExitFailed
+	default:
+		return statusExitDegraded
+	}
+}
+
+// watchStatus prints the state change events of the running agent until ctx is cancelled.
+func watchStatus(ctx context.Context, daemon client.Client, w io.Writer, output string) error {
+	if output != "human" && output != "json" {
+		return fmt.Errorf("unsupported output with --watch: %s", output)
+	}
+	watch, err := daemon.WatchStatus(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to watch the status of the Elastic Agent daemon: %w", err)
 	}
-	// exit 0 only if the Elastic Agent daemon is healthy
-	if state.State == client.Healthy {
-		os.Exit(0)
-	} else {
-		os.Exit(1)
+	for {
+		event, err := watch.Recv()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to receive the status of the Elastic Agent daemon: %w", err)
+		}
+		if output == "json" {
+			bytes, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\n", bytes)
+			continue
+		}
+		fmt.Fprintln(w, formatStatusEvent(event))
 	}
-	return nil
+}
+
+// formatStatusEvent formats the event as a single line, e.g.
+// "2024-01-03T10:30:00Z unit filestream-default/filestream-default-logs (INPUT) HEALTHY -> DEGRADED: file not found".
+func formatStatusEvent(event *client.StatusEvent) string {
+	var source string
+	switch event.Source {
+	case client.StatusEventSourceAgent:
+		source = "agent"
+	case client.StatusEventSourceFleet:
+		source = "fleet"
+	case client.StatusEventSourceComponent:
+		source = "component " + event.ComponentID
+	case client.StatusEventSourceUnit:
+		source = fmt.Sprintf("unit %s/%s (%s)", event.ComponentID, event.UnitID, event.UnitType)
+	}
+
+	var change string
+	switch event.Reason {
+	case client.StatusEventStateChanged:
+		change = fmt.Sprintf("%s -> %s", event.PreviousState, event.State)
+	case client.StatusEventAdded:
+		change = fmt.Sprintf("added %s", event.State)
+	case client.StatusEventRemoved:
+		change = fmt.Sprintf("removed %s", event.State)
+	default:
+		change = event.State.String()
+	}
+	return fmt.Sprintf("%s %s %s: %s", event.Time.UTC().Format(time.RFC3339), source, change, event.Message)
 }
 
 func formatStatus(state client.State, message string) string {
@@ -223,6 +334,12 @@ func listUpgradeDetails(l list.Writer, upgradeDetails *cproto.UpgradeDetails) {
 		}
 		if upgradeDetails.State == string(details.StateDownloading) {
 			l.AppendItem(fmt.Sprintf("download_percent: %.2f%%", upgradeDetails.Metadata.DownloadPercent*100))
+			if upgradeDetails.Metadata.DownloadRate > 0 {
+				l.AppendItem("download_rate: " + units.HumanSize(float64(upgradeDetails.Metadata.DownloadRate)) + "/s")
+			}
+			if upgradeDetails.Metadata.DownloadEta != "" {
+				l.AppendItem("download_eta: " + humanDurationUntil(upgradeDetails.Metadata.DownloadEta, time.Now()))
+			}
 		}
 		if upgradeDetails.Metadata.RetryUntil != "" {
 			l.AppendItem("retry_until: " + humanDurationUntil(upgradeDetails.Metadata.RetryUntil, time.Now()))
@@ -233,6 +350,12 @@ func listUpgradeDetails(l list.Writer, upgradeDetails *cproto.UpgradeDetails) {
 		if upgradeDetails.Metadata.Reason != "" {
 			l.AppendItem("reason: " + upgradeDetails.Metadata.Reason)
 		}
+		if upgradeDetails.Metadata.HealthGate != "" {
+			l.AppendItem("health_gate: " + upgradeDetails.Metadata.HealthGate)
+		}
+		if upgradeDetails.Metadata.HealthGateUntil != "" && upgradeDetails.Metadata.HealthGate == string(details.HealthGatePending) {
+			l.AppendItem("health_gate_until: " + humanDurationUntil(upgradeDetails.Metadata.HealthGateUntil, time.Now()))
+		}
 		l.UnIndent()
 	}
 
@@ -243,6 +366,72 @@ func listFleetState(l list.Writer, state *client.AgentState, all bool) {
 	l.AppendItem("fleet")
 	l.Indent()
 	l.AppendItem(formatStatus(state.FleetState, state.FleetMessage))
+	listFleetHosts(l, state.FleetHosts, all)
+	l.UnIndent()
+}
+
+// listFleetHosts lists the failing Fleet Server hosts, or all of them with all.
+func listFleetHosts(l list.Writer, hosts []*cproto.FleetHostState, all bool) {
+	for _, host := range hosts {
+		if host.Healthy && !all {
+			continue
+		}
+		title := host.Host
+		if host.Current {
+			title += " (current)"
+		}
+		l.AppendItem(title)
+		l.Indent()
+		if host.Healthy {
+			l.AppendItem("status: healthy")
+		} else {
+			l.AppendItem(fmt.Sprintf("status: failing (%d consecutive failures)", host.Failures))
+			l.AppendItem("error: " + host.Error)
+			if host.RetryAfter != "" {
+				l.AppendItem("retry_after: " + humanDurationUntil(host.RetryAfter, time.Now()))
+			}
+		}
+		if all && host.LastUsed != "" {
+			l.AppendItem("last_used: " + host.LastUsed)
+		}
+		l.UnIndent()
+	}
+}
+
+func listFleetServerState(l list.Writer, fleetServer *cproto.FleetServerState, all bool) {
+	if fleetServer == nil {
+		return
+	}
+
+	l.AppendItem("fleet_server")
+	l.Indent()
+	l.AppendItem(formatStatus(fleetServer.State, fleetServer.Message))
+	if all {
+		l.AppendItem("url: " + fleetServer.Url)
+		if fleetServer.Since != "" {
+			l.AppendItem("since: " + fleetServer.Since)
+		}
+	}
+	l.UnIndent()
+}
+
+func listBlockedComponents(l list.Writer, blocked []*cproto.BlockedComponent, all bool) {
+	if len(blocked) == 0 {
+		return
+	}
+
+	l.AppendItem("blocked_by_capabilities")
+	l.Indent()
+	for _, comp := range blocked {
+		l.AppendItem(comp.Id)
+		l.Indent()
+		l.AppendItem("reason: " + comp.Reason)
+		if all {
+			l.AppendItem("input_type: " + comp.InputType)
+			l.AppendItem("output_type: " + comp.OutputType)
+		}
+		l.UnIndent()
+	}
 	l.UnIndent()
 }
 
@@ -251,7 +440,9 @@ func humanListOutput(w io.Writer, state *client.AgentState, all bool) error {
 	l.SetStyle(list.StyleConnectedLight)
 	l.SetOutputMirror(w)
 	listFleetState(l, state, all)
+	listFleetServerState(l, state.FleetServer, all)
 	listAgentState(l, state, all)
+	listBlockedComponents(l, state.BlockedComponents, all)
 	_ = l.Render()
 	return nil
 }
@@ -272,6 +463,35 @@ func humanOutput(w io.Writer, obj interface{}) error {
 	return humanListOutput(w, status, false)
 }
 
+// tableOutput writes the state of the agent, fleet, its components and their units as a table.
+func tableOutput(w io.Writer, obj interface{}) error {
+	state, ok := obj.(*client.AgentState)
+	if !ok {
+		return fmt.Errorf("unable to cast %T as *client.AgentStatus", obj)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"ID", "TYPE", "STATE", "MESSAGE"}, "\t"))
+	fmt.Fprintf(tw, "elastic-agent\tagent\t%s\t%s\n", state.State, state.Message)
+	fmt.Fprintf(tw, "fleet\tfleet\t%s\t%s\n", state.FleetState, state.FleetMessage)
+	for _, host := range state.FleetHosts {
+		hostState := client.Healthy
+		if !host.Healthy {
+			hostState = client.Failed
+		}
+		fmt.Fprintf(tw, "%s\tfleet host\t%s\t%s\n", host.Host, hostState, host.Error)
+	}
+	if fs := state.FleetServer; fs != nil {
+		fmt.Fprintf(tw, "fleet_server\tfleet_server\t%s\t%s\n", fs.State, fs.Message)
+	}
+	for _, c := range state.Components {
+		fmt.Fprintf(tw, "%s\tcomponent\t%s\t%s\n", c.ID, c.State, c.Message)
+		for _, u := range c.Units {
+			fmt.Fprintf(tw, "%s\t%s unit\t%s\t%s\n", u.UnitID, strings.ToLower(u.UnitType.String()), u.State, u.Message)
+		}
+	}
+	return tw.Flush()
+}
+
 func jsonOutput(w io.Writer, out interface{}) error {
 	bytes, err := json.MarshalIndent(out, "", "    ")
 	if err != nil {
@@ -0,0 +1,144 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+const (
+	// statusSchemaLegacy is the unversioned status document, the state of the agent as returned by the control
+	// protocol with the states as numbers.
+	statusSchemaLegacy = 0
+	// statusSchemaV1 is the first versioned status document, with the states as names. A field is only added to
+	// a schema version, a field removed or changed requires a new version.
+	statusSchemaV1 = 1
+	// statusSchemaLatest is the most recent schema version. The json and yaml outputs default to the legacy
+	// document, the existing consumers parse it.
+	statusSchemaLatest = statusSchemaV1
+)
+
+// statusDocumentV1 is the version 1 of the status document of the json and yaml outputs.
+type statusDocumentV1 struct {
+	SchemaVersion     int                        `json:"schema_version" yaml:"schema_version"`
+	State             string                     `json:"state" yaml:"state"`
+	Message           string                     `json:"message" yaml:"message"`
+	Info              client.AgentStateInfo      `json:"info" yaml:"info"`
+	Fleet             statusFleetV1              `json:"fleet" yaml:"fleet"`
+	FleetServer       *statusFleetServerV1       `json:"fleet_server,omitempty" yaml:"fleet_server,omitempty"`
+	Components        []statusComponentV1        `json:"components" yaml:"components"`
+	Collector         *statusCollectorV1         `json:"collector,omitempty" yaml:"collector,omitempty"`
+	UpgradeDetails    *cproto.UpgradeDetails     `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
+	BlockedComponents []*cproto.BlockedComponent `json:"blocked_components,omitempty" yaml:"blocked_components,omitempty"`
+}
+
+type statusFleetV1 struct {
+	State   string                   `json:"state" yaml:"state"`
+	Message string                   `json:"message" yaml:"message"`
+	Hosts   []*cproto.FleetHostState `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+}
+
+type statusFleetServerV1 struct {
+	State   string `json:"state" yaml:"state"`
+	Message string `json:"message" yaml:"message"`
+	URL     string `json:"url" yaml:"url"`
+	Since   string `json:"since,omitempty" yaml:"since,omitempty"`
+}
+
+type statusComponentV1 struct {
+	ID          string                      `json:"id" yaml:"id"`
+	Name        string                      `json:"name" yaml:"name"`
+	State       string                      `json:"state" yaml:"state"`
+	Message     string                      `json:"message" yaml:"message"`
+	Units       []statusUnitV1              `json:"units" yaml:"units"`
+	VersionInfo client.ComponentVersionInfo `json:"version_info" yaml:"version_info"`
+}
+
+type statusUnitV1 struct {
+	ID      string                 `json:"id" yaml:"id"`
+	Type    string                 `json:"type" yaml:"type"`
+	State   string                 `json:"state" yaml:"state"`
+	Message string                 `json:"message" yaml:"message"`
+	Payload map[string]interface{} `json:"payload,omitempty" yaml:"payload,omitempty"`
+}
+
+type statusCollectorV1 struct {
+	Status     string                        `json:"status" yaml:"status"`
+	Error      string                        `json:"error,omitempty" yaml:"error,omitempty"`
+	Timestamp  *time.Time                    `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+	Components map[string]*statusCollectorV1 `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+// statusDocument returns the status document of the schema version for the json and yaml outputs.
+func statusDocument(state *client.AgentState, version int) (interface{}, error) {
+	switch version {
+	case statusSchemaLegacy:
+		return state, nil
+	case statusSchemaV1:
+		return newStatusDocumentV1(state), nil
+	default:
+		return nil, fmt.Errorf("unsupported schema version %d, must be between %d and %d", version, statusSchemaLegacy, statusSchemaLatest)
+	}
+}
+
+func newStatusDocumentV1(state *client.AgentState) *statusDocumentV1 {
+	doc := &statusDocumentV1{
+		SchemaVersion:     statusSchemaV1,
+		State:             state.State.String(),
+		Message:           state.Message,
+		Info:              state.Info,
+		Fleet:             statusFleetV1{State: state.FleetState.String(), Message: state.FleetMessage, Hosts: state.FleetHosts},
+		Components:        make([]statusComponentV1, 0, len(state.Components)),
+		Collector:         newStatusCollectorV1(state.Collector),
+		UpgradeDetails:    state.UpgradeDetails,
+		BlockedComponents: state.BlockedComponents,
+	}
+	if fs := state.FleetServer; fs != nil {
+		doc.FleetServer = &statusFleetServerV1{State: fs.State.String(), Message: fs.Message, URL: fs.Url, Since: fs.Since}
+	}
+	for _, c := range state.Components {
+		comp := statusComponentV1{
+			ID:          c.ID,
+			Name:        c.Name,
+			State:       c.State.String(),
+			Message:     c.Message,
+			Units:       make([]statusUnitV1, 0, len(c.Units)),
+			VersionInfo: c.VersionInfo,
+		}
+		for _, u := range c.Units {
+			comp.Units = append(comp.Units, statusUnitV1{
+				ID:      u.UnitID,
+				Type:    u.UnitType.String(),
+				State:   u.State.String(),
+				Message: u.Message,
+				Payload: u.Payload,
+			})
+		}
+		doc.Components = append(doc.Components, comp)
+	}
+	return doc
+}
+
+func newStatusCollectorV1(c *client.CollectorComponent) *statusCollectorV1 {
+	if c == nil {
+		return nil
+	}
+	collector := &statusCollectorV1{Status: c.Status.String(), Error: c.Error}
+	if !c.Timestamp.IsZero() {
+		ts := c.Timestamp
+		collector.Timestamp = &ts
+	}
+	if len(c.ComponentStatusMap) > 0 {
+		collector.Components = make(map[string]*statusCollectorV1, len(c.ComponentStatusMap))
+		for id, comp := range c.ComponentStatusMap {
+			collector.Components[id] = newStatusCollectorV1(comp)
+		}
+	}
+	return collector
+}
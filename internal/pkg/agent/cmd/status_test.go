@@ -6,7 +6,10 @@ package cmd
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -21,6 +24,7 @@ import (
 
 	"github.com/elastic/elastic-agent/pkg/control/v2/client"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	mocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
 )
 
 func TestHumanOutput(t *testing.T) {
@@ -135,6 +139,56 @@ func TestHumanOutput(t *testing.T) {
 			},
 		},
 	}
+	stateFleetServer := &client.AgentState{
+		Info:         stateHealthy.Info,
+		State:        client.Starting,
+		Message:      "Fleet Server: Waiting for the Fleet Server API: Get \"https://localhost:8221/api/status\": connection refused",
+		FleetState:   client.Healthy,
+		FleetMessage: "Connected",
+		FleetServer: &cproto.FleetServerState{
+			State:   cproto.State_STARTING,
+			Message: "Waiting for the Fleet Server API: Get \"https://localhost:8221/api/status\": connection refused",
+			Url:     "https://localhost:8221",
+			Since:   "2023-05-24T00:02:00Z",
+		},
+	}
+	stateBlocked := &client.AgentState{
+		Info:         stateHealthy.Info,
+		State:        client.Healthy,
+		Message:      "Running",
+		FleetState:   client.Healthy,
+		FleetMessage: "Connected",
+		BlockedComponents: []*cproto.BlockedComponent{
+			{
+				Id:         "system/metrics-default",
+				InputType:  "system/metrics",
+				OutputType: "elasticsearch",
+				Reason:     `input type denied by capability #2 {rule: deny, input: "system/*"}`,
+			},
+		},
+	}
+	stateFleetHosts := &client.AgentState{
+		Info:         stateHealthy.Info,
+		State:        client.Healthy,
+		Message:      "Running",
+		FleetState:   client.Healthy,
+		FleetMessage: "Connected",
+		FleetHosts: []*cproto.FleetHostState{
+			{
+				Host:     "https://fleet-1:8220/",
+				Healthy:  false,
+				Failures: 3,
+				Error:    "dial tcp: lookup fleet-1: no such host",
+				LastUsed: "2023-05-24T00:01:00Z",
+			},
+			{
+				Host:     "https://fleet-2:8220/",
+				Healthy:  true,
+				Current:  true,
+				LastUsed: "2023-05-24T00:02:00Z",
+			},
+		},
+	}
 	tests := []struct {
 		state      *client.AgentState
 		state_name string
@@ -144,6 +198,12 @@ func TestHumanOutput(t *testing.T) {
 		{output: "human", state_name: "healthy", state: stateHealthy},
 		{output: "full", state_name: "healthy", state: stateHealthy},
 		{output: "full", state_name: "degraded", state: stateDegraded},
+		{output: "human", state_name: "fleet_server", state: stateFleetServer},
+		{output: "full", state_name: "fleet_server", state: stateFleetServer},
+		{output: "human", state_name: "blocked", state: stateBlocked},
+		{output: "full", state_name: "blocked", state: stateBlocked},
+		{output: "human", state_name: "fleet_hosts", state: stateFleetHosts},
+		{output: "full", state_name: "fleet_hosts", state: stateFleetHosts},
 	}
 	for _, test := range tests {
 		b.Reset()
@@ -219,6 +279,24 @@ func TestListUpgradeDetails(t *testing.T) {
    └─ metadata
       ├─ scheduled_at: %s
       └─ download_percent: 17.68%%`, now.Format(control.TimeFormat())),
+		},
+		"downloading_with_eta": {
+			upgradeDetails: &cproto.UpgradeDetails{
+				TargetVersion: "8.12.0",
+				State:         "UPG_DOWNLOADING",
+				Metadata: &cproto.UpgradeDetailsMetadata{
+					DownloadPercent: 0.5,
+					DownloadRate:    2048,
+					DownloadEta:     "3m12s",
+				},
+			},
+			expectedOutput: `── upgrade_details
+   ├─ target_version: 8.12.0
+   ├─ state: UPG_DOWNLOADING
+   └─ metadata
+      ├─ download_percent: 50.00%
+      ├─ download_rate: 2.048kB/s
+      └─ download_eta: 3m12s`,
 		},
 		"retrying_downloading": {
 			upgradeDetails: &cproto.UpgradeDetails{
@@ -280,3 +358,128 @@ func TestHumanDurationUntil(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusExitCode(t *testing.T) {
+	require.Equal(t, statusExitHealthy, statusExitCode(client.Healthy))
+	require.Equal(t, statusExitDegraded, statusExitCode(client.Degraded))
+	require.Equal(t, statusExitDegraded, statusExitCode(client.Starting))
+	require.Equal(t, statusExitDegraded, statusExitCode(client.Upgrading))
+	require.Equal(t, statusExitFailed, statusExitCode(client.Failed))
+}
+
+func testStatusState() *client.AgentState {
+	return &client.AgentState{
+		Info:         client.AgentStateInfo{ID: "9a4921cc-36d4-4b5a-9395-9ec2d204862e", Version: "9.2.0"},
+		State:        client.Degraded,
+		Message:      "1 or more components/units in a failed state",
+		FleetState:   client.Healthy,
+		FleetMessage: "Connected",
+		Components: []client.ComponentState{{
+			ID:      "filestream-default",
+			Name:    "filestream",
+			State:   client.Healthy,
+			Message: "Healthy",
+			Units: []client.ComponentUnitState{{
+				UnitID:   "filestream-default-logs",
+				UnitType: client.UnitTypeInput,
+				State:    client.Failed,
+				Message:  "file not found",
+			}},
+		}},
+	}
+}
+
+func TestStatusDocument(t *testing.T) {
+	state := testStatusState()
+
+	doc, err := statusDocument(state, statusSchemaLegacy)
+	require.NoError(t, err)
+	require.Same(t, state, doc, "the legacy schema should be the state as is")
+
+	doc, err = statusDocument(state, statusSchemaV1)
+	require.NoError(t, err)
+	var b bytes.Buffer
+	require.NoError(t, jsonOutput(&b, doc))
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(b.Bytes(), &decoded))
+	require.EqualValues(t, 1, decoded["schema_version"])
+	require.Equal(t, "DEGRADED", decoded["state"])
+	require.Equal(t, map[string]interface{}{"state": "HEALTHY", "message": "Connected"}, decoded["fleet"])
+	components := decoded["components"].([]interface{})
+	require.Len(t, components, 1)
+	units := components[0].(map[string]interface{})["units"].([]interface{})
+	require.Equal(t, map[string]interface{}{
+		"id":      "filestream-default-logs",
+		"type":    "INPUT",
+		"state":   "FAILED",
+		"message": "file not found",
+	}, units[0])
+
+	b.Reset()
+	require.NoError(t, yamlOutput(&b, doc))
+	require.Contains(t, b.String(), "schema_version: 1\nstate: DEGRADED\n")
+
+	_, err = statusDocument(state, statusSchemaLatest+1)
+	require.Error(t, err)
+}
+
+func TestTableOutput(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, tableOutput(&b, testStatusState()))
+	require.Equal(t, `ID                       TYPE        STATE     MESSAGE
+elastic-agent            agent       DEGRADED  1 or more components/units in a failed state
+fleet                    fleet       HEALTHY   Connected
+filestream-default       component   HEALTHY   Healthy
+filestream-default-logs  input unit  FAILED    file not found
+`, b.String())
+}
+
+type fakeStatusWatch struct {
+	events []*client.StatusEvent
+}
+
+func (f *fakeStatusWatch) Recv() (*client.StatusEvent, error) {
+	if len(f.events) == 0 {
+		return nil, io.EOF
+	}
+	event := f.events[0]
+	f.events = f.events[1:]
+	return event, nil
+}
+
+func TestWatchStatus(t *testing.T) {
+	ts := time.Date(2024, time.January, 3, 10, 30, 0, 0, time.UTC)
+	events := []*client.StatusEvent{
+		{Time: ts, Source: client.StatusEventSourceAgent, Reason: client.StatusEventAdded, State: client.Healthy, Message: "Running"},
+		{Time: ts, Source: client.StatusEventSourceFleet, Reason: client.StatusEventMessageChanged, State: client.Failed, Message: "timeout"},
+		{Time: ts, Source: client.StatusEventSourceComponent, Reason: client.StatusEventRemoved, ComponentID: "filestream-default", State: client.Healthy, Message: "Healthy"},
+		{Time: ts, Source: client.StatusEventSourceUnit, Reason: client.StatusEventStateChanged, ComponentID: "filestream-default", UnitType: client.UnitTypeInput, UnitID: "filestream-default-logs", PreviousState: client.Healthy, State: client.Degraded, Message: "file not found"},
+	}
+
+	t.Run("human", func(t *testing.T) {
+		daemon := mocks.NewClient(t)
+		daemon.EXPECT().WatchStatus(context.Background()).Return(&fakeStatusWatch{events: events}, nil)
+
+		var b bytes.Buffer
+		require.NoError(t, watchStatus(context.Background(), daemon, &b, "human"))
+		require.Equal(t, `2024-01-03T10:30:00Z agent added HEALTHY: Running
+2024-01-03T10:30:00Z fleet FAILED: timeout
+2024-01-03T10:30:00Z component filestream-default removed HEALTHY: Healthy
+2024-01-03T10:30:00Z unit filestream-default/filestream-default-logs (INPUT) HEALTHY -> DEGRADED: file not found
+`, b.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		daemon := mocks.NewClient(t)
+		daemon.EXPECT().WatchStatus(context.Background()).Return(&fakeStatusWatch{events: events[:1]}, nil)
+
+		var b bytes.Buffer
+		require.NoError(t, watchStatus(context.Background(), daemon, &b, "json"))
+		require.Equal(t, `{"time":"2024-01-03T10:30:00Z","source":0,"reason":0,"unit_type":0,"previous_state":0,"state":2,"message":"Running"}
+`, b.String())
+	})
+
+	t.Run("unsupported output", func(t *testing.T) {
+		require.Error(t, watchStatus(context.Background(), mocks.NewClient(t), io.Discard, "yaml"))
+	})
+}
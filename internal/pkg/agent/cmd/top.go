@@ -0,0 +1,170 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+)
+
+// clearScreen moves the cursor to the top left corner and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// topRow is the resource usage of a process between two samples.
+type topRow struct {
+	client.ProcessResources
+	// cpuPct is the percentage of a CPU used since the previous sample, -1 when unknown.
+	cpuPct float64
+}
+
+// topSorts are the supported sort orders, the Elastic Agent is always listed first.
+var topSorts = map[string]func(a, b topRow) bool{
+	"cpu":      func(a, b topRow) bool { return a.cpuPct > b.cpuPct },
+	"memory":   func(a, b topRow) bool { return a.RSS > b.RSS },
+	"fds":      func(a, b topRow) bool { return a.FDCount > b.FDCount },
+	"restarts": func(a, b topRow) bool { return a.Restarts > b.Restarts },
+	"id":       func(a, b topRow) bool { return a.ID < b.ID },
+}
+
+func newTopCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "top",
+		Short: "Display the resource usage of the running Elastic Agent and of its components",
+		Long: `This command displays the CPU, memory and open file descriptors used by the processes of the running
+Elastic Agent and of its components, and the number of times each component was restarted. The view is
+refreshed until interrupted.`,
+		Run: func(c *cobra.Command, _ []string) {
+			if err := topCmd(streams, c); err != nil {
+				printCommandError(c, streams, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().DurationP("interval", "d", 2*time.Second, "Delay between the refreshes of the view.")
+	cmd.Flags().IntP("iterations", "n", 0, "Number of refreshes before exiting, 0 to refresh until interrupted.")
+	cmd.Flags().String("sort", "cpu", "Sort the components by 'cpu', 'memory', 'fds', 'restarts' or 'id'.")
+
+	return cmd
+}
+
+func topCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
+	interval, _ := cmd.Flags().GetDuration("interval")
+	iterations, _ := cmd.Flags().GetInt("iterations")
+	sortBy, _ := cmd.Flags().GetString("sort")
+	if _, ok := topSorts[sortBy]; !ok {
+		return fmt.Errorf("unsupported sort: %s", sortBy)
+	}
+	if interval <= 0 {
+		return errors.New("the interval must be positive")
+	}
+
+	ctx := handleSignal(context.Background())
+	daemon := client.New()
+	if err := daemon.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to communicate with Elastic Agent daemon: %w", err)
+	}
+	defer daemon.Disconnect()
+
+	clearView := false
+	if f, ok := streams.Out.(*os.File); ok {
+		clearView = term.IsTerminal(int(f.Fd())) //nolint:gosec // G115 file descriptors fit in an int
+	}
+	return runTop(ctx, daemon, streams.Out, interval, iterations, sortBy, clearView)
+}
+
+// runTop samples the resource usage of the running agent every interval and renders it to w until ctx is
+// cancelled or the number of iterations is reached. The first view is rendered after one interval, once the
+// CPU usage can be computed.
+func runTop(ctx context.Context, daemon client.Client, w io.Writer, interval time.Duration, iterations int, sortBy string, clearView bool) error {
+	prev, err := daemon.Resources(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the resource usage of the Elastic Agent daemon: %w", err)
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for i := 0; iterations <= 0 || i < iterations; i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-t.C:
+		}
+		curr, err := daemon.Resources(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to get the resource usage of the Elastic Agent daemon: %w", err)
+		}
+		if clearView {
+			fmt.Fprint(w, clearScreen)
+		}
+		renderTop(w, curr.Time, topRows(prev, curr, sortBy))
+		prev = curr
+	}
+	return nil
+}
+
+// topRows returns the rows of the processes of curr with their CPU usage since prev, the Elastic Agent
+// first and the components in the sortBy order.
+func topRows(prev, curr *client.AgentResources, sortBy string) []topRow {
+	prevByID := make(map[string]client.ProcessResources, len(prev.Processes))
+	for _, p := range prev.Processes {
+		prevByID[p.ID] = p
+	}
+	elapsed := curr.Time.Sub(prev.Time)
+
+	rows := make([]topRow, 0, len(curr.Processes))
+	for _, p := range curr.Processes {
+		row := topRow{ProcessResources: p, cpuPct: -1}
+		// a restarted process has a new PID and its CPU time starts over
+		if before, ok := prevByID[p.ID]; ok && p.PID != 0 && before.PID == p.PID && elapsed > 0 && p.CPUTime >= before.CPUTime {
+			row.cpuPct = float64(p.CPUTime-before.CPUTime) / float64(elapsed) * 100
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) > 1 {
+		less := topSorts[sortBy]
+		components := rows[1:]
+		sort.SliceStable(components, func(i, j int) bool { return less(components[i], components[j]) })
+	}
+	return rows
+}
+
+// renderTop writes the rows as a table, the usage of the components without a running process is empty.
+func renderTop(w io.Writer, ts time.Time, rows []topRow) {
+	fmt.Fprintf(w, "elastic-agent top - %s\n\n", ts.Format(time.RFC3339))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join([]string{"ID", "PID", "CPU%", "RSS", "FDS", "RESTARTS"}, "\t"))
+	for _, row := range rows {
+		pid, cpu, rss, fds := "-", "-", "-", "-"
+		if row.PID != 0 {
+			pid = strconv.FormatUint(row.PID, 10)
+			rss = units.BytesSize(float64(row.RSS))
+			fds = strconv.FormatUint(row.FDCount, 10)
+			if row.cpuPct >= 0 {
+				cpu = strconv.FormatFloat(row.cpuPct, 'f', 1, 64)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", row.ID, pid, cpu, rss, fds, row.Restarts)
+	}
+	_ = tw.Flush()
+}
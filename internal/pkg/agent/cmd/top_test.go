@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	mocks "github.com/elastic/elastic-agent/testing/mocks/pkg/control/v2/client"
+)
+
+func TestTopRows(t *testing.T) {
+	ts := time.Date(2024, time.January, 3, 10, 30, 0, 0, time.UTC)
+	prev := &client.AgentResources{Time: ts, Processes: []client.ProcessResources{
+		{ID: "elastic-agent", PID: 1, CPUTime: time.Second},
+		{ID: "filestream-default", PID: 10, CPUTime: time.Second},
+		{ID: "system-default", PID: 20, CPUTime: 5 * time.Second},
+	}}
+	curr := &client.AgentResources{Time: ts.Add(2 * time.Second), Processes: []client.ProcessResources{
+		{ID: "elastic-agent", PID: 1, CPUTime: 1100 * time.Millisecond, RSS: 100},
+		{ID: "filestream-default", PID: 10, CPUTime: 2 * time.Second, RSS: 300},
+		// restarted, the CPU usage is unknown until the next sample
+		{ID: "system-default", PID: 21, CPUTime: 100 * time.Millisecond, RSS: 200, Restarts: 1},
+		{ID: "endpoint-default"},
+	}}
+
+	rows := topRows(prev, curr, "cpu")
+	require.Len(t, rows, 4)
+	assert.Equal(t, "elastic-agent", rows[0].ID, "the agent must be listed first")
+	assert.InDelta(t, 5, rows[0].cpuPct, 0.001)
+	assert.Equal(t, "filestream-default", rows[1].ID)
+	assert.InDelta(t, 50, rows[1].cpuPct, 0.001)
+	assert.Equal(t, -1.0, rows[2].cpuPct)
+	assert.Equal(t, -1.0, rows[3].cpuPct)
+
+	rows = topRows(prev, curr, "memory")
+	assert.Equal(t, []string{"elastic-agent", "filestream-default", "system-default", "endpoint-default"},
+		[]string{rows[0].ID, rows[1].ID, rows[2].ID, rows[3].ID})
+
+	rows = topRows(prev, curr, "restarts")
+	assert.Equal(t, "system-default", rows[1].ID)
+}
+
+func TestRunTop(t *testing.T) {
+	ts := time.Date(2024, time.January, 3, 10, 30, 0, 0, time.UTC)
+	samples := []*client.AgentResources{
+		{Time: ts, Processes: []client.ProcessResources{
+			{ID: "elastic-agent", PID: 1, CPUTime: time.Second, RSS: 64 * 1024 * 1024, FDCount: 40},
+			{ID: "filestream-default", PID: 10, CPUTime: time.Second, RSS: 32 * 1024 * 1024, FDCount: 25, Restarts: 2},
+			{ID: "endpoint-default", Restarts: 1},
+		}},
+		{Time: ts.Add(time.Second), Processes: []client.ProcessResources{
+			{ID: "elastic-agent", PID: 1, CPUTime: 1015 * time.Millisecond, RSS: 64 * 1024 * 1024, FDCount: 40},
+			{ID: "filestream-default", PID: 10, CPUTime: 1120 * time.Millisecond, RSS: 32 * 1024 * 1024, FDCount: 25, Restarts: 2},
+			{ID: "endpoint-default", Restarts: 1},
+		}},
+	}
+	daemon := mocks.NewClient(t)
+	daemon.EXPECT().Resources(context.Background()).Return(samples[0], nil).Once()
+	daemon.EXPECT().Resources(context.Background()).Return(samples[1], nil).Once()
+
+	var b bytes.Buffer
+	require.NoError(t, runTop(context.Background(), daemon, &b, time.Millisecond, 1, "cpu", false))
+	assert.Equal(t, `elastic-agent top - 2024-01-03T10:30:01Z
+
+ID                  PID  CPU%  RSS    FDS  RESTARTS
+elastic-agent       1    1.5   64MiB  40   0
+filestream-default  10   12.0  32MiB  25   2
+endpoint-default    -    -     -      -    1
+`, b.String())
+}
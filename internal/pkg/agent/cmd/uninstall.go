@@ -5,6 +5,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/utils"
@@ -25,10 +27,12 @@ func newUninstallCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comm
 		Long: `This command uninstalls the Elastic Agent permanently from this system.  The system's service manager will no longer manage Elastic agent.
 
 Unless -f is used this command will ask confirmation before performing removal.
+
+When the policy enables the tamper protection, the uninstall token from Fleet must be given with --uninstall-token.
 `,
 		Run: func(c *cobra.Command, _ []string) {
 			if err := uninstallCmd(streams, c); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				logExternal(fmt.Sprintf("%s uninstall failed: %s", paths.BinaryName, err))
 				os.Exit(1)
 			}
@@ -98,6 +102,10 @@ func uninstallCmd(streams *cli.IOStreams, cmd *cobra.Command) error {
 	}()
 
 	err = install.Uninstall(cmd.Context(), paths.ConfigFile(), paths.Top(), uninstallToken, log, progBar, skipFleetAudit)
+	if errors.Is(err, protection.ErrUninstallTokenRequired) {
+		progBar.Describe("Failed to uninstall agent")
+		return fmt.Errorf("error uninstalling agent: %w, provide it with --uninstall-token", err)
+	}
 	if err != nil {
 		progBar.Describe("Failed to uninstall agent")
 		return fmt.Errorf("error uninstalling agent: %w", err)
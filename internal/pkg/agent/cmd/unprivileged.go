@@ -36,7 +36,7 @@ unprivileged it will still perform all the same work, including stopping and sta
 		Args: cobra.ExactArgs(0),
 		Run: func(c *cobra.Command, args []string) {
 			if err := unprivilegedCmd(streams, c); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
@@ -50,6 +50,7 @@ unprivileged it will still perform all the same work, including stopping and sta
 	cmd.Flags().String(flagInstallCustomGroup, "", "Custom group used to access Elastic Agent files")
 	if runtime.GOOS == "windows" {
 		cmd.Flags().String(flagInstallCustomPass, "", "Password for user used to run Elastic Agent")
+		cmd.Flags().Bool(flagInstallVirtualAccount, false, "Run Elastic Agent as the virtual service account of its service instead of a local user")
 	}
 
 	return cmd
@@ -70,6 +71,10 @@ func unprivilegedCmd(streams *cli.IOStreams, cmd *cobra.Command) (err error) {
 	if runtime.GOOS == "windows" {
 		customPass, _ = cmd.Flags().GetString(flagInstallCustomPass)
 	}
+	customUser, err = virtualAccountUser(cmd, customUser, customPass)
+	if err != nil {
+		return err
+	}
 
 	// cannot switch to unprivileged when service components have issues
 	err = ensureNoServiceComponentIssues()
@@ -128,7 +133,7 @@ func ensureNoServiceComponentIssues() error {
 	}
 	// this forces the component calculation to always compute with no root
 	// this allows any runtime preventions to error for a component when it has a no root support
-	comps, err := getComponentsFromPolicy(ctx, l, paths.ConfigFile(), 0, forceNonRoot)
+	comps, err := getComponentsFromPolicy(ctx, l, paths.ConfigFile(), variablesOpts{}, forceNonRoot)
 	if err != nil {
 		return fmt.Errorf("failed to create component model from policy: %w", err)
 	}
@@ -34,6 +34,7 @@ const (
 	flagPGPBytesURI    = "pgp-uri"
 	flagForce          = "force"
 	flagRollback       = "rollback"
+	flagUninstallToken = "uninstall-token"
 )
 
 var (
@@ -52,7 +53,7 @@ func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 		Run: func(c *cobra.Command, args []string) {
 			c.SetContext(context.Background())
 			if err := upgradeCmd(streams, c, args); err != nil {
-				fmt.Fprintf(streams.Err, "Error: %v\n%s\n", err, troubleshootMessage())
+				printCommandError(c, streams, err)
 				os.Exit(1)
 			}
 		},
@@ -66,6 +67,7 @@ func newUpgradeCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Comman
 	cmd.Flags().String(flagPGPBytesPath, "", "Path to a file containing PGP to use for package verification")
 	cmd.Flags().BoolP(flagForce, "", false, "Advanced option to force an upgrade on a fleet managed agent")
 	cmd.Flags().BoolP(flagRollback, "", false, "Roll back an upgrade")
+	cmd.Flags().String(flagUninstallToken, "", "Uninstall token required to upgrade a tamper protected agent")
 	err := cmd.Flags().MarkHidden(flagForce)
 	if err != nil {
 		fmt.Fprintf(streams.Err, "error while setting upgrade force flag attributes: %s", err.Error())
@@ -85,7 +87,8 @@ type upgradeInput struct {
 }
 
 func upgradeCmd(streams *cli.IOStreams, cmd *cobra.Command, args []string) error {
-	c := client.New()
+	uninstallToken, _ := cmd.Flags().GetString(flagUninstallToken)
+	c := client.New(client.WithUninstallToken(uninstallToken))
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 	defer cancel()
 
@@ -120,6 +120,7 @@ func newWatchCommandWithArgs(_ []string, streams *cli.IOStreams) *cobra.Command
 
 type agentWatcher interface {
 	Watch(ctx context.Context, tilGrace, errorCheckInterval time.Duration, log *logp.Logger) error
+	HealthGate(ctx context.Context, until time.Time, hash string, log *logp.Logger) error
 }
 
 func WithPreRestartHook(preRestartHook upgrade.RollbackHook) upgrade.RollbackOption {
@@ -202,10 +203,45 @@ func watchCmd(log *logp.Logger, topDir string, cfg *configuration.UpgradeWatcher
 	saveMarkerFunc := func(marker *upgrade.UpdateMarker, b bool) error {
 		return upgrade.SaveMarker(dataDir, marker, b)
 	}
+	var healthGate details.HealthGateState
+	var healthGateUntil *time.Time
+	if marker.Details != nil {
+		healthGate, healthGateUntil = marker.Details.Metadata.HealthGate, marker.Details.Metadata.HealthGateUntil
+	}
 	upgradeDetails := initUpgradeDetails(marker, saveMarkerFunc, log)
 
 	errorCheckInterval := cfg.ErrorCheck.Interval
 	ctx := context.Background()
+
+	if healthGate != "" && healthGateUntil != nil {
+		upgradeDetails.SetHealthGate(healthGate, healthGateUntil)
+	}
+	if healthGate == details.HealthGatePending && healthGateUntil != nil {
+		// the upgrade is only committed once the new agent passed the health gate
+		if err := watcher.HealthGate(ctx, *healthGateUntil, marker.Hash, log); err != nil {
+			if errors.Is(err, ErrWatchCancelled) {
+				return nil
+			}
+
+			log.Errorf("Health gate failed, proceeding to rollback: %v", err)
+
+			upgradeDetails.SetHealthGate(details.HealthGateFailed, healthGateUntil)
+			upgradeDetails.SetStateWithReason(details.StateRollback, details.ReasonHealthGateFailed)
+			err = installModifier.Rollback(ctx, log, client.New(), paths.Top(), marker.PrevVersionedHome, marker.PrevHash)
+			if err != nil {
+				log.Error("rollback failed", err)
+				upgradeDetails.Fail(err)
+			}
+			return err
+		}
+		upgradeDetails.SetHealthGate(details.HealthGatePassed, healthGateUntil)
+
+		// keep watching for failures for what is left of the grace period
+		isWithinGrace, tilGrace = gracePeriod(marker, cfg.GracePeriod)
+		if !isWithinGrace {
+			tilGrace = 0
+		}
+	}
 	if err := watcher.Watch(ctx, tilGrace, errorCheckInterval, log); err != nil {
 		if errors.Is(err, ErrWatchCancelled) {
 			// the watch has been cancelled prematurely, don't clean or rollback just yet
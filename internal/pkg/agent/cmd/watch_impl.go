@@ -23,6 +23,29 @@ func (a upgradeAgentWatcher) Watch(ctx context.Context, tilGrace, errorCheckInte
 	return watch(ctx, tilGrace, errorCheckInterval, log)
 }
 
+func (a upgradeAgentWatcher) HealthGate(ctx context.Context, until time.Time, hash string, log *logp.Logger) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Allow for signals to interrupt the health gate
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- upgrade.WaitForHealthGate(ctx, log, client.New(), hash, until)
+	}()
+
+	select {
+	case s := <-signals:
+		log.Infof("received signal: (%d): %v. Exiting health gate", s, s)
+		return ErrWatchCancelled
+	case err := <-errChan:
+		return err
+	}
+}
+
 type upgradeInstallationModifier struct{}
 
 func (a upgradeInstallationModifier) Cleanup(log *logger.Logger, topDirPath, currentVersionedHome, currentHash string, removeMarker, keepLogs bool) error {
@@ -5,6 +5,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -188,6 +189,106 @@ func Test_watchCmd(t *testing.T) {
 			},
 			wantErr: assert.NoError,
 		},
+		{
+			name: "health gate passed: watch for the rest of the grace period, cleanup prev install",
+			setupUpgradeMarker: func(t *testing.T, topDir string, watcher *mockAgentWatcher, installModifier *mockInstallationModifier) {
+				dataDirPath := paths.DataFrom(topDir)
+				err := os.MkdirAll(dataDirPath, 0755)
+				require.NoError(t, err)
+				healthGateUntil := time.Now().Add(5 * time.Minute)
+				err = upgrade.SaveMarker(
+					dataDirPath,
+					&upgrade.UpdateMarker{
+						Version:           "4.5.6",
+						Hash:              "newver",
+						VersionedHome:     "elastic-agent-4.5.6-newver",
+						UpdatedOn:         time.Now(),
+						PrevVersion:       "1.2.3",
+						PrevHash:          "prvver",
+						PrevVersionedHome: "elastic-agent-prvver",
+						Details: &details.Details{
+							TargetVersion: "4.5.6",
+							State:         details.StateReplacing,
+							Metadata: details.Metadata{
+								HealthGate:      details.HealthGatePending,
+								HealthGateUntil: &healthGateUntil,
+							},
+						},
+					},
+					true,
+				)
+				require.NoError(t, err)
+
+				watcher.EXPECT().
+					HealthGate(mock.Anything, mock.Anything, "newver", mock.Anything).
+					Return(nil)
+				watcher.EXPECT().
+					Watch(mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Run(func(_ context.Context, _, _ time.Duration, _ *logger.Logger) {
+						marker, err := upgrade.LoadMarker(dataDirPath)
+						require.NoError(t, err)
+						assert.Equal(t, details.HealthGatePassed, marker.Details.Metadata.HealthGate)
+					}).
+					Return(nil)
+
+				expectedRemoveMarkerFlag := runtime.GOOS != "windows"
+				installModifier.EXPECT().
+					Cleanup(mock.Anything, topDir, "elastic-agent-4.5.6-newver", "newver", expectedRemoveMarkerFlag, false).
+					Return(nil)
+			},
+			args: args{
+				cfg: configuration.DefaultUpgradeConfig().Watcher,
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "health gate failed: no watching, rollback to previous install",
+			setupUpgradeMarker: func(t *testing.T, topDir string, watcher *mockAgentWatcher, installModifier *mockInstallationModifier) {
+				dataDirPath := paths.DataFrom(topDir)
+				err := os.MkdirAll(dataDirPath, 0755)
+				require.NoError(t, err)
+				healthGateUntil := time.Now().Add(5 * time.Minute)
+				err = upgrade.SaveMarker(
+					dataDirPath,
+					&upgrade.UpdateMarker{
+						Version:           "4.5.6",
+						Hash:              "newver",
+						VersionedHome:     "elastic-agent-4.5.6-newver",
+						UpdatedOn:         time.Now(),
+						PrevVersion:       "1.2.3",
+						PrevHash:          "prvver",
+						PrevVersionedHome: "elastic-agent-prvver",
+						Details: &details.Details{
+							TargetVersion: "4.5.6",
+							State:         details.StateReplacing,
+							Metadata: details.Metadata{
+								HealthGate:      details.HealthGatePending,
+								HealthGateUntil: &healthGateUntil,
+							},
+						},
+					},
+					true,
+				)
+				require.NoError(t, err)
+
+				watcher.EXPECT().
+					HealthGate(mock.Anything, mock.Anything, "newver", mock.Anything).
+					Return(upgrade.ErrHealthGateFailed)
+				installModifier.EXPECT().
+					Rollback(mock.Anything, mock.Anything, mock.Anything, paths.Top(), "elastic-agent-prvver", "prvver", mock.Anything).
+					RunAndReturn(func(_ context.Context, _ *logger.Logger, _ client.Client, _, _, _ string, _ ...upgrade.RollbackOption) error {
+						marker, err := upgrade.LoadMarker(dataDirPath)
+						require.NoError(t, err)
+						assert.Equal(t, details.HealthGateFailed, marker.Details.Metadata.HealthGate)
+						assert.Equal(t, details.ReasonHealthGateFailed, marker.Details.Metadata.Reason)
+						return nil
+					})
+			},
+			args: args{
+				cfg: configuration.DefaultUpgradeConfig().Watcher,
+			},
+			wantErr: assert.NoError,
+		},
 		{
 			name: "upgrade rolled back: no watching, cleanup must be called",
 			setupUpgradeMarker: func(t *testing.T, topDir string, watcher *mockAgentWatcher, installModifier *mockInstallationModifier) {
@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import "fmt"
+
+const (
+	// CheckinCompressionNone sends the checkin body uncompressed.
+	CheckinCompressionNone = "none"
+	// CheckinCompressionGzip sends the checkin body compressed with gzip.
+	CheckinCompressionGzip = "gzip"
+	// CheckinCompressionZstd sends the checkin body compressed with zstd.
+	CheckinCompressionZstd = "zstd"
+
+	defaultCheckinMaxBodySize        = 1024 * 1024
+	defaultCheckinMaxUnitMessageSize = 1024
+)
+
+// CheckinConfig is the configuration of the body of the checkin requests sent to Fleet. The status messages of
+// the components and units are truncated to fit the budget, so agents running thousands of units do not exceed
+// the body limits of Fleet Server.
+type CheckinConfig struct {
+	// Compression is the compression of the body: none, gzip or zstd.
+	Compression string `yaml:"compression" config:"compression" json:"compression"`
+	// MaxBodySize is the size in bytes of the uncompressed body the status messages are truncated to fit in.
+	MaxBodySize int `yaml:"max_body_size" config:"max_body_size" json:"max_body_size"`
+	// MaxUnitMessageSize is the size in bytes status messages are truncated to.
+	MaxUnitMessageSize int `yaml:"max_unit_message_size" config:"max_unit_message_size" json:"max_unit_message_size"`
+}
+
+// Validate validates the compression and the sizes of the configuration.
+func (c *CheckinConfig) Validate() error {
+	switch c.Compression {
+	case CheckinCompressionNone, CheckinCompressionGzip, CheckinCompressionZstd:
+	default:
+		return fmt.Errorf("invalid checkin compression %q, expected one of none, gzip or zstd", c.Compression)
+	}
+	if c.MaxBodySize <= 0 {
+		return fmt.Errorf("checkin max_body_size %d must be positive", c.MaxBodySize)
+	}
+	if c.MaxUnitMessageSize <= 0 {
+		return fmt.Errorf("checkin max_unit_message_size %d must be positive", c.MaxUnitMessageSize)
+	}
+	return nil
+}
+
+// DefaultCheckinConfig returns the default configuration, the body is not compressed.
+func DefaultCheckinConfig() *CheckinConfig {
+	return &CheckinConfig{
+		Compression:        CheckinCompressionNone,
+		MaxBodySize:        defaultCheckinMaxBodySize,
+		MaxUnitMessageSize: defaultCheckinMaxUnitMessageSize,
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckinConfigValidate(t *testing.T) {
+	assert.NoError(t, DefaultCheckinConfig().Validate())
+
+	cfg := DefaultCheckinConfig()
+	cfg.Compression = CheckinCompressionZstd
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Compression = "brotli"
+	assert.ErrorContains(t, cfg.Validate(), "invalid checkin compression")
+
+	cfg = DefaultCheckinConfig()
+	cfg.MaxBodySize = 0
+	assert.ErrorContains(t, cfg.Validate(), "max_body_size")
+
+	cfg = DefaultCheckinConfig()
+	cfg.MaxUnitMessageSize = -1
+	assert.ErrorContains(t, cfg.Validate(), "max_unit_message_size")
+}
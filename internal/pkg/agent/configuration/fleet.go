@@ -5,6 +5,8 @@
 package configuration
 
 import (
+	"time"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
 	"github.com/elastic/elastic-agent/internal/pkg/remote"
 )
@@ -19,6 +21,21 @@ type FleetAgentConfig struct {
 	Client              remote.Config      `config:",inline" yaml:",inline"`
 	Info                *AgentInfo         `config:"agent" yaml:"agent"`
 	Server              *FleetServerConfig `config:"server" yaml:"server,omitempty"`
+	// ClientCertificate is set when the client certificate of the mutual TLS with Fleet Server is issued by Fleet,
+	// the certificate is stored in the vault and renewed before it expires.
+	ClientCertificate *FleetClientCertificateConfig `config:"client_certificate" yaml:"client_certificate,omitempty"`
+}
+
+// FleetClientCertificateConfig is the configuration of the client certificate issued by Fleet.
+type FleetClientCertificateConfig struct {
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// RenewBefore is how long before its expiration the certificate is renewed, a default is used when not set.
+	RenewBefore time.Duration `config:"renew_before" yaml:"renew_before,omitempty"`
+}
+
+// IssuedClientCertificate returns true when the client certificate is issued by Fleet.
+func (e *FleetAgentConfig) IssuedClientCertificate() bool {
+	return e.ClientCertificate != nil && e.ClientCertificate.Enabled
 }
 
 // Valid validates the required fields for accessing the API.
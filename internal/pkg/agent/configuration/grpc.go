@@ -5,9 +5,11 @@
 package configuration
 
 import (
+	"fmt"
 	"net"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 )
@@ -28,14 +30,71 @@ const (
 
 	// grpcPortContainerEnvVar is the environment variable allowing containers to specify a fixed port.
 	grpcPortContainerEnvVar = "ELASTIC_AGENT_GRPC_PORT"
+
+	// DefaultGRPCUpdateTimeout is the default maximum time a single component model update can spend
+	// waiting on components before the next update is allowed to proceed.
+	DefaultGRPCUpdateTimeout = time.Minute
+	// DefaultGRPCCheckinTimeout is the default maximum time sending the expected state to a component
+	// can take before the check-in stream is considered unresponsive and is closed.
+	DefaultGRPCCheckinTimeout = 30 * time.Second
+	// DefaultGRPCStopTimeout is the default maximum time to wait for a component to report it is stopped.
+	DefaultGRPCStopTimeout = 15 * time.Second
+
+	// GRPCTransportTCP serves the components on the TCP address and port of the configuration.
+	GRPCTransportTCP = "tcp"
+	// GRPCTransportLocal serves the components on a unix domain socket, or a named pipe on Windows, next
+	// to the control socket. The components are verified to run as the user of the Elastic Agent, or as root.
+	GRPCTransportLocal = "local"
 )
 
 // GRPCConfig is a configuration of GRPC server.
 type GRPCConfig struct {
-	Address                 string `config:"address"`
-	Port                    uint16 `config:"port"` // [gRPC:8.15] Change to int32 instead of uint16, when Endpoint is ready for local gRPC
-	MaxMsgSize              int    `config:"max_message_size"`
-	CheckinChunkingDisabled bool   `config:"checkin_chunking_disabled"`
+	// Transport is how the components connect to the Elastic Agent: tcp or local.
+	Transport               string             `config:"transport"`
+	Address                 string             `config:"address"`
+	Port                    uint16             `config:"port"`
+	MaxMsgSize              int                `config:"max_message_size"`
+	CheckinChunkingDisabled bool               `config:"checkin_chunking_disabled"`
+	Timeouts                GRPCTimeoutsConfig `config:"timeouts"`
+}
+
+// GRPCTimeoutsConfig is the configuration of the deadlines applied to operations performed against
+// components over the control protocol. A zero value means the default is used.
+type GRPCTimeoutsConfig struct {
+	// Update bounds how long a component model update waits on components (e.g. for removed
+	// components to stop) before it is considered complete. It is extended to the stop timeout of
+	// the removed components when longer, e.g. the uninstall timeout of a service.
+	Update time.Duration `config:"update"`
+	// Checkin bounds how long sending the expected state to a component can take.
+	Checkin time.Duration `config:"checkin"`
+	// Stop bounds how long to wait for a component to stop. Service components that define an
+	// uninstall timeout in their specification use that instead.
+	Stop time.Duration `config:"stop"`
+}
+
+// DefaultGRPCTimeoutsConfig creates the default component operation timeouts.
+func DefaultGRPCTimeoutsConfig() GRPCTimeoutsConfig {
+	return GRPCTimeoutsConfig{
+		Update:  DefaultGRPCUpdateTimeout,
+		Checkin: DefaultGRPCCheckinTimeout,
+		Stop:    DefaultGRPCStopTimeout,
+	}
+}
+
+// WithDefaults returns a copy of the configuration where every unset timeout is replaced
+// with its default value.
+func (t GRPCTimeoutsConfig) WithDefaults() GRPCTimeoutsConfig {
+	defaults := DefaultGRPCTimeoutsConfig()
+	if t.Update <= 0 {
+		t.Update = defaults.Update
+	}
+	if t.Checkin <= 0 {
+		t.Checkin = defaults.Checkin
+	}
+	if t.Stop <= 0 {
+		t.Stop = defaults.Stop
+	}
+	return t
 }
 
 // DefaultGRPCConfig creates a default server configuration.
@@ -46,10 +105,12 @@ func DefaultGRPCConfig() *GRPCConfig {
 	}
 
 	return &GRPCConfig{
+		Transport:               GRPCTransportTCP,
 		Address:                 "localhost",
 		Port:                    defaultPort,
 		MaxMsgSize:              1024 * 1024 * 100, // grpc default 4MB is unsufficient for diagnostics
 		CheckinChunkingDisabled: false,             // on by default
+		Timeouts:                DefaultGRPCTimeoutsConfig(),
 	}
 }
 
@@ -74,9 +135,16 @@ func (cfg *GRPCConfig) String() string {
 	return net.JoinHostPort(cfg.Address, strconv.Itoa(int(cfg.Port)))
 }
 
-// IsLocal returns true if port value is less than 0
+// IsLocal returns true when the components connect over a unix domain socket or a named pipe.
 func (cfg *GRPCConfig) IsLocal() bool {
-	// [gRPC:8.15] Use the commented implementation once Endpoint is ready for local gRPC
-	// return cfg.Port < 0
-	return false
+	return cfg.Transport == GRPCTransportLocal
+}
+
+// Validate validates the GRPC configuration.
+func (cfg *GRPCConfig) Validate() error {
+	switch cfg.Transport {
+	case "", GRPCTransportTCP, GRPCTransportLocal:
+		return nil
+	}
+	return fmt.Errorf("invalid grpc transport %q, accepted values are '%s' and '%s'", cfg.Transport, GRPCTransportTCP, GRPCTransportLocal)
 }
@@ -7,6 +7,7 @@ package configuration
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -79,3 +80,50 @@ func TestGRPCAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestGRPCTimeoutsWithDefaults(t *testing.T) {
+	testcases := []struct {
+		name     string
+		cfg      GRPCTimeoutsConfig
+		expected GRPCTimeoutsConfig
+	}{{
+		name:     "unset",
+		cfg:      GRPCTimeoutsConfig{},
+		expected: DefaultGRPCTimeoutsConfig(),
+	}, {
+		name: "partially set",
+		cfg:  GRPCTimeoutsConfig{Checkin: 5 * time.Second},
+		expected: GRPCTimeoutsConfig{
+			Update:  DefaultGRPCUpdateTimeout,
+			Checkin: 5 * time.Second,
+			Stop:    DefaultGRPCStopTimeout,
+		},
+	}, {
+		name:     "negative",
+		cfg:      GRPCTimeoutsConfig{Update: -1, Checkin: -1, Stop: -1},
+		expected: DefaultGRPCTimeoutsConfig(),
+	}, {
+		name:     "fully set",
+		cfg:      GRPCTimeoutsConfig{Update: time.Second, Checkin: 2 * time.Second, Stop: 3 * time.Second},
+		expected: GRPCTimeoutsConfig{Update: time.Second, Checkin: 2 * time.Second, Stop: 3 * time.Second},
+	}}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.cfg.WithDefaults())
+		})
+	}
+}
+
+func TestGRPCTransport(t *testing.T) {
+	cfg := DefaultGRPCConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.False(t, cfg.IsLocal())
+
+	cfg.Transport = GRPCTransportLocal
+	assert.NoError(t, cfg.Validate())
+	assert.True(t, cfg.IsLocal())
+
+	cfg.Transport = "udp"
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultMemoryPressureInterval          = 10 * time.Second
+	defaultMemoryPressureThreshold         = 0.9
+	defaultMemoryPressureRecoveryThreshold = 0.8
+)
+
+// MemoryPressureConfig is the configuration of the detection of memory pressure. The memory used is compared
+// to the memory limit of the cgroup of the agent, or to the memory of the host when the agent has no limit.
+// Under pressure the agent caps its own memory, defers the re-rendering of the policy on variable changes and
+// reports itself degraded, rather than getting killed while applying a policy. It is disabled by default.
+type MemoryPressureConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the period between two checks of the memory used.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Threshold is the ratio of the memory limit above which the agent is under pressure.
+	Threshold float64 `yaml:"threshold" config:"threshold" json:"threshold"`
+	// RecoveryThreshold is the ratio of the memory limit below which the agent is no longer under pressure.
+	RecoveryThreshold float64 `yaml:"recovery_threshold" config:"recovery_threshold" json:"recovery_threshold"`
+}
+
+// Validate validates the thresholds of the configuration.
+func (c *MemoryPressureConfig) Validate() error {
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		return fmt.Errorf("memory pressure threshold %v must be in (0, 1]", c.Threshold)
+	}
+	if c.RecoveryThreshold <= 0 || c.RecoveryThreshold > c.Threshold {
+		return fmt.Errorf("memory pressure recovery threshold %v must be in (0, %v]", c.RecoveryThreshold, c.Threshold)
+	}
+	return nil
+}
+
+// DefaultMemoryPressureConfig returns the default configuration of the detection of memory pressure.
+func DefaultMemoryPressureConfig() *MemoryPressureConfig {
+	return &MemoryPressureConfig{
+		Enabled:           false,
+		Interval:          defaultMemoryPressureInterval,
+		Threshold:         defaultMemoryPressureThreshold,
+		RecoveryThreshold: defaultMemoryPressureRecoveryThreshold,
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// PathsConfig is the configuration of the paths of an installed Elastic Agent.
+type PathsConfig struct {
+	// Data is the directory, usually on a separate volume, the data directory with the state, the components and
+	// the logs is relocated to. The data directory of the install is used when empty.
+	Data string `yaml:"data" config:"data" json:"data"`
+}
+
+// Validate validates that the data directory is an absolute path.
+func (c *PathsConfig) Validate() error {
+	if c.Data != "" && !filepath.IsAbs(c.Data) {
+		return fmt.Errorf("agent.paths.data %q must be an absolute path", c.Data)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathsConfigValidate(t *testing.T) {
+	assert.NoError(t, (&PathsConfig{}).Validate())
+	absolute, err := filepath.Abs("data")
+	assert.NoError(t, err)
+	assert.NoError(t, (&PathsConfig{Data: absolute}).Validate())
+	assert.ErrorContains(t, (&PathsConfig{Data: "data"}).Validate(), "must be an absolute path")
+}
@@ -0,0 +1,44 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import "time"
+
+const (
+	// DefaultPeersPort is the UDP port peer health beacons are sent to and received on.
+	DefaultPeersPort = 6795
+
+	defaultPeersInterval = 10 * time.Second
+	defaultPeersTimeout  = 30 * time.Second
+)
+
+// PeersConfig is the configuration of the health beacons exchanged between agents of the same site. The
+// beacons allow the agent to report in its Fleet status whether its peers are reachable, to distinguish a
+// host level issue from a site wide network outage.
+type PeersConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Site identifies the site of the agent, beacons from agents of another site are ignored.
+	Site string `yaml:"site" config:"site" json:"site"`
+	// Port is the UDP port beacons are sent to and received on.
+	Port int `yaml:"port" config:"port" json:"port"`
+	// Subnets are the IPv4 subnets, in CIDR notation, beacons are broadcast to and accepted from.
+	Subnets []string `yaml:"subnets" config:"subnets" json:"subnets"`
+	// Hosts are the addresses of peers beacons are sent to directly, when broadcast is not available.
+	// Beacons from these hosts are accepted regardless of the subnets.
+	Hosts []string `yaml:"hosts" config:"hosts" json:"hosts"`
+	// Interval is the period between two beacons.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Timeout is the time after which a peer that sent no beacon is considered unreachable.
+	Timeout time.Duration `yaml:"timeout" config:"timeout" json:"timeout"`
+}
+
+// DefaultPeersConfig returns the default configuration, peer health beacons are disabled.
+func DefaultPeersConfig() *PeersConfig {
+	return &PeersConfig{
+		Port:     DefaultPeersPort,
+		Interval: defaultPeersInterval,
+		Timeout:  defaultPeersTimeout,
+	}
+}
@@ -5,6 +5,7 @@
 package configuration
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -15,10 +16,19 @@ var (
 	ErrInvalidPeriod = errors.New("period must be higher than zero")
 )
 
+const (
+	// ReloadModeWatch reloads the standalone configuration as soon as the files change.
+	ReloadModeWatch = "watch"
+	// ReloadModePeriodic checks the standalone configuration files for changes every period.
+	ReloadModePeriodic = "periodic"
+)
+
 // ReloadConfig defines behavior of a reloader for standalone configuration.
 type ReloadConfig struct {
-	Enabled bool          `config:"enabled" yaml:"enabled"`
-	Period  time.Duration `config:"period" yaml:"period"`
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// Mode is how the changes of the files are detected, watch or periodic.
+	Mode   string        `config:"mode" yaml:"mode"`
+	Period time.Duration `config:"period" yaml:"period"`
 }
 
 // Validate validates settings of configuration.
@@ -28,13 +38,24 @@ func (r *ReloadConfig) Validate() error {
 			return ErrInvalidPeriod
 		}
 	}
+	switch r.Mode {
+	case "", ReloadModeWatch, ReloadModePeriodic:
+	default:
+		return fmt.Errorf("invalid reload mode %q, must be %s or %s", r.Mode, ReloadModeWatch, ReloadModePeriodic)
+	}
 	return nil
 }
 
+// WatchFiles returns true when the changes of the files are detected by watching them, the default.
+func (r *ReloadConfig) WatchFiles() bool {
+	return r.Mode != ReloadModePeriodic
+}
+
 // DefaultReloadConfig creates a default configuration for standalone mode.
 func DefaultReloadConfig() *ReloadConfig {
 	return &ReloadConfig{
 		Enabled: true,
+		Mode:    ReloadModeWatch,
 		Period:  10 * time.Second,
 	}
 }
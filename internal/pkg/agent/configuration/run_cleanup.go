@@ -0,0 +1,31 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import "time"
+
+const (
+	defaultRunCleanupInterval  = 24 * time.Hour
+	defaultRunCleanupRetention = 7 * 24 * time.Hour
+)
+
+// RunCleanupConfig is the configuration of the removal of the run directories of the components no longer in
+// the model and of the versioned homes left by failed upgrades.
+type RunCleanupConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled" json:"enabled"`
+	// Interval is the period between two cleanups.
+	Interval time.Duration `yaml:"interval" config:"interval" json:"interval"`
+	// Retention is the time an orphaned run directory is kept after it was last modified.
+	Retention time.Duration `yaml:"retention" config:"retention" json:"retention"`
+}
+
+// DefaultRunCleanupConfig returns the default configuration of the cleanup of the run directories.
+func DefaultRunCleanupConfig() *RunCleanupConfig {
+	return &RunCleanupConfig{
+		Enabled:   true,
+		Interval:  defaultRunCleanupInterval,
+		Retention: defaultRunCleanupRetention,
+	}
+}
@@ -6,6 +6,7 @@ package configuration
 
 import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/artifact"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
 
 	monitoringCfg "github.com/elastic/elastic-agent/internal/pkg/core/monitoring/config"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
@@ -21,7 +22,15 @@ type SettingsConfig struct {
 	MonitoringConfig   *monitoringCfg.MonitoringConfig `yaml:"monitoring" config:"monitoring" json:"monitoring"`
 	LoggingConfig      *logger.Config                  `yaml:"logging,omitempty" config:"logging,omitempty" json:"logging,omitempty"`
 	EventLoggingConfig *logger.Config                  `yaml:"logging.event_data,omitempty" config:"logging.event_data,omitempty" json:"logging.event_data,omitempty"`
+	ToJournald         bool                            `yaml:"logging.to_journald,omitempty" config:"logging.to_journald,omitempty" json:"logging.to_journald,omitempty"`
+	JournaldConfig     *logger.JournaldConfig          `yaml:"logging.journald,omitempty" config:"logging.journald,omitempty" json:"logging.journald,omitempty"`
 	Upgrade            *UpgradeConfig                  `yaml:"upgrade" config:"upgrade" json:"upgrade"`
+	Peers              *PeersConfig                    `yaml:"peers" config:"peers" json:"peers"`
+	RunCleanup         *RunCleanupConfig               `yaml:"run_cleanup" config:"run_cleanup" json:"run_cleanup"`
+	MemoryPressure     *MemoryPressureConfig           `yaml:"memory_pressure" config:"memory_pressure" json:"memory_pressure"`
+	Checkin            *CheckinConfig                  `yaml:"checkin" config:"checkin" json:"checkin"`
+	Paths              *PathsConfig                    `yaml:"paths,omitempty" config:"paths,omitempty" json:"paths,omitempty"`
+	Vault              *vault.Config                   `yaml:"vault,omitempty" config:"vault,omitempty" json:"vault,omitempty"`
 
 	// standalone config
 	Reload              *ReloadConfig `config:"reload" yaml:"reload" json:"reload"`
@@ -36,9 +45,14 @@ func DefaultSettingsConfig() *SettingsConfig {
 		DownloadConfig:      artifact.DefaultConfig(),
 		LoggingConfig:       logger.DefaultLoggingConfig(),
 		EventLoggingConfig:  logger.DefaultEventLoggingConfig(),
+		JournaldConfig:      logger.DefaultJournaldConfig(),
 		MonitoringConfig:    monitoringCfg.DefaultConfig(),
 		GRPC:                DefaultGRPCConfig(),
 		Upgrade:             DefaultUpgradeConfig(),
+		Peers:               DefaultPeersConfig(),
+		RunCleanup:          DefaultRunCleanupConfig(),
+		MemoryPressure:      DefaultMemoryPressureConfig(),
+		Checkin:             DefaultCheckinConfig(),
 		Reload:              DefaultReloadConfig(),
 		V1MonitoringEnabled: true,
 	}
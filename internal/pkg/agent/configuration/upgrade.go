@@ -18,12 +18,27 @@ const (
 	// this is temporarily set to 0 to disable the rollback window until manual rollback functionality is complete.
 	// defaultRollbackWindowDuration = 7 * 24 * time.Hour // 7 days
 	defaultRollbackWindowDuration = 0
+
+	// free space, in bytes, left once an upgrade is complete.
+	defaultDiskSpaceReserve = 100 * 1024 * 1024
+
+	// period during which an upgraded Agent must report healthy before the upgrade is committed.
+	defaultHealthGateWindow = 5 * time.Minute
 )
 
 // UpgradeConfig is the configuration related to Agent upgrades.
 type UpgradeConfig struct {
 	Watcher  *UpgradeWatcherConfig  `yaml:"watcher" config:"watcher" json:"watcher"`
 	Rollback *UpgradeRollbackConfig `yaml:"rollback" config:"rollback" json:"rollback"`
+	// DiskSpace is the check of the free disk space done before downloading an upgrade.
+	DiskSpace *UpgradeDiskSpaceConfig `yaml:"disk_space" config:"disk_space" json:"disk_space"`
+	// Retention is the number of versions kept on disk across upgrades.
+	Retention *UpgradeRetentionConfig `yaml:"retention" config:"retention" json:"retention"`
+	// HealthGate is the health check the upgraded Agent must pass before the upgrade is committed.
+	HealthGate *UpgradeHealthGateConfig `yaml:"health_gate" config:"health_gate" json:"health_gate"`
+	// Window is the maintenance windows during which upgrades requested by Fleet are allowed, nil allows them at
+	// any time.
+	Window *UpgradeWindowConfig `yaml:"window" config:"window" json:"window"`
 }
 
 type UpgradeWatcherConfig struct {
@@ -38,6 +53,27 @@ type UpgradeRollbackConfig struct {
 	Window time.Duration `yaml:"window" config:"window" json:"window"`
 }
 
+type UpgradeDiskSpaceConfig struct {
+	// Check refuses to upgrade when the space required to download, unpack and copy the new version is not
+	// available.
+	Check bool `yaml:"check" config:"check" json:"check"`
+	// Reserve is the free space, in bytes, that must be left once the upgrade is complete.
+	Reserve uint64 `yaml:"reserve" config:"reserve" json:"reserve"`
+}
+
+type UpgradeRetentionConfig struct {
+	// Versions is the number of versions, the running one included, whose installs and downloads are kept
+	// before an upgrade. Older ones are removed, unless they can still be rolled back to. 0 keeps them all.
+	Versions int `yaml:"versions" config:"versions" json:"versions" validate:"min=0"`
+}
+
+type UpgradeHealthGateConfig struct {
+	// Enabled requires the upgraded Agent to report healthy, with all its components healthy and its last Fleet
+	// checkin successful, within Window. The upgrade is rolled back otherwise.
+	Enabled bool          `yaml:"enabled" config:"enabled" json:"enabled"`
+	Window  time.Duration `yaml:"window" config:"window" json:"window"`
+}
+
 func DefaultUpgradeConfig() *UpgradeConfig {
 	return &UpgradeConfig{
 		Watcher: &UpgradeWatcherConfig{
@@ -49,5 +85,13 @@ func DefaultUpgradeConfig() *UpgradeConfig {
 		Rollback: &UpgradeRollbackConfig{
 			Window: defaultRollbackWindowDuration,
 		},
+		DiskSpace: &UpgradeDiskSpaceConfig{
+			Check:   true,
+			Reserve: defaultDiskSpaceReserve,
+		},
+		Retention: &UpgradeRetentionConfig{},
+		HealthGate: &UpgradeHealthGateConfig{
+			Window: defaultHealthGateWindow,
+		},
 	}
 }
@@ -30,6 +30,14 @@ func TestParseUpgradeConfig(t *testing.T) {
 				Rollback: &UpgradeRollbackConfig{
 					Window: defaultRollbackWindowDuration,
 				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   true,
+					Reserve: defaultDiskSpaceReserve,
+				},
+				Retention: &UpgradeRetentionConfig{},
+				HealthGate: &UpgradeHealthGateConfig{
+					Window: defaultHealthGateWindow,
+				},
 			},
 		},
 		"watcher_grace_period": {
@@ -48,6 +56,14 @@ func TestParseUpgradeConfig(t *testing.T) {
 				Rollback: &UpgradeRollbackConfig{
 					Window: defaultRollbackWindowDuration,
 				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   true,
+					Reserve: defaultDiskSpaceReserve,
+				},
+				Retention: &UpgradeRetentionConfig{},
+				HealthGate: &UpgradeHealthGateConfig{
+					Window: defaultHealthGateWindow,
+				},
 			},
 		},
 		"watcher_error_check_interval": {
@@ -68,6 +84,14 @@ func TestParseUpgradeConfig(t *testing.T) {
 				Rollback: &UpgradeRollbackConfig{
 					Window: defaultRollbackWindowDuration,
 				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   true,
+					Reserve: defaultDiskSpaceReserve,
+				},
+				Retention: &UpgradeRetentionConfig{},
+				HealthGate: &UpgradeHealthGateConfig{
+					Window: defaultHealthGateWindow,
+				},
 			},
 		},
 		"rollback_window": {
@@ -84,6 +108,97 @@ func TestParseUpgradeConfig(t *testing.T) {
 				Rollback: &UpgradeRollbackConfig{
 					Window: 8 * time.Hour,
 				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   true,
+					Reserve: defaultDiskSpaceReserve,
+				},
+				Retention: &UpgradeRetentionConfig{},
+				HealthGate: &UpgradeHealthGateConfig{
+					Window: defaultHealthGateWindow,
+				},
+			},
+		},
+		"disk_space_and_retention": {
+			cfg: map[string]any{
+				"disk_space.check":   false,
+				"disk_space.reserve": 1024,
+				"retention.versions": 2,
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window: defaultRollbackWindowDuration,
+				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   false,
+					Reserve: 1024,
+				},
+				Retention: &UpgradeRetentionConfig{
+					Versions: 2,
+				},
+				HealthGate: &UpgradeHealthGateConfig{
+					Window: defaultHealthGateWindow,
+				},
+			},
+		},
+		"health_gate": {
+			cfg: map[string]any{
+				"health_gate.enabled": true,
+				"health_gate.window":  "10m",
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window: defaultRollbackWindowDuration,
+				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   true,
+					Reserve: defaultDiskSpaceReserve,
+				},
+				Retention: &UpgradeRetentionConfig{},
+				HealthGate: &UpgradeHealthGateConfig{
+					Enabled: true,
+					Window:  10 * time.Minute,
+				},
+			},
+		},
+		"window": {
+			cfg: map[string]any{
+				"window.timezone": "Europe/Paris",
+				"window.ranges":   []any{"Mon-Fri 22:00-06:00", "Sat,Sun 00:00-24:00"},
+			},
+			expected: UpgradeConfig{
+				Watcher: &UpgradeWatcherConfig{
+					GracePeriod: defaultGracePeriodDuration,
+					ErrorCheck: UpgradeWatcherCheckConfig{
+						Interval: defaultStatusCheckInterval,
+					},
+				},
+				Rollback: &UpgradeRollbackConfig{
+					Window: defaultRollbackWindowDuration,
+				},
+				DiskSpace: &UpgradeDiskSpaceConfig{
+					Check:   true,
+					Reserve: defaultDiskSpaceReserve,
+				},
+				Retention: &UpgradeRetentionConfig{},
+				HealthGate: &UpgradeHealthGateConfig{
+					Window: defaultHealthGateWindow,
+				},
+				Window: &UpgradeWindowConfig{
+					Timezone: "Europe/Paris",
+					Ranges:   []string{"Mon-Fri 22:00-06:00", "Sat,Sun 00:00-24:00"},
+				},
 			},
 		},
 	}
@@ -0,0 +1,189 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// UpgradeWindowConfig restricts the upgrades requested by Fleet to maintenance windows. An upgrade received outside
+// of the windows is deferred to the start of the next one.
+type UpgradeWindowConfig struct {
+	// Timezone is the IANA name of the timezone of the windows, e.g. Europe/Paris. Defaults to the local timezone.
+	Timezone string `yaml:"timezone" config:"timezone" json:"timezone"`
+	// Ranges are the windows, each one as days of the week and a time range, e.g. "Mon-Fri 22:00-06:00",
+	// "Sat,Sun 00:00-24:00" or "* 02:00-04:00" for every day. A time range ending before it starts ends the next day.
+	Ranges []string `yaml:"ranges" config:"ranges" json:"ranges"`
+}
+
+type upgradeWindowRange struct {
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+// Validate validates the timezone and the ranges of the windows.
+func (c *UpgradeWindowConfig) Validate() error {
+	_, _, err := c.parse()
+	return err
+}
+
+// Next returns t when t is within a window, the start of the next window otherwise. t is returned as well when no
+// window is configured.
+func (c *UpgradeWindowConfig) Next(t time.Time) (time.Time, error) {
+	loc, ranges, err := c.parse()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(ranges) == 0 {
+		return t, nil
+	}
+
+	var next time.Time
+	local := t.In(loc)
+	// a range started the day before can still be running, a week later every range started at least once
+	for offset := -1; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, loc)
+		for _, r := range ranges {
+			if !r.days[day.Weekday()] {
+				continue
+			}
+			start := atTimeOfDay(day, r.start)
+			end := atTimeOfDay(day, r.end)
+			if r.end <= r.start {
+				end = atTimeOfDay(day.AddDate(0, 0, 1), r.end)
+			}
+			if !t.Before(start) && t.Before(end) {
+				return t, nil
+			}
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+	}
+	return next, nil
+}
+
+func (c *UpgradeWindowConfig) parse() (*time.Location, []upgradeWindowRange, error) {
+	loc := time.Local
+	if c.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(c.Timezone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid upgrade window timezone %q: %w", c.Timezone, err)
+		}
+	}
+
+	ranges := make([]upgradeWindowRange, 0, len(c.Ranges))
+	for _, s := range c.Ranges {
+		r, err := parseUpgradeWindowRange(s)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid upgrade window range %q: %w", s, err)
+		}
+		ranges = append(ranges, r)
+	}
+	return loc, ranges, nil
+}
+
+// parseUpgradeWindowRange parses a range like "Mon-Fri 22:00-06:00".
+func parseUpgradeWindowRange(s string) (upgradeWindowRange, error) {
+	var r upgradeWindowRange
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return r, errors.New("expected days and a time range separated by a space")
+	}
+
+	days, err := parseWeekdays(fields[0])
+	if err != nil {
+		return r, err
+	}
+	r.days = days
+
+	startStr, endStr, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return r, errors.New("expected a time range like 22:00-06:00")
+	}
+	if r.start, err = parseTimeOfDay(startStr); err != nil {
+		return r, err
+	}
+	if r.end, err = parseTimeOfDay(endStr); err != nil {
+		return r, err
+	}
+	if r.start == 24*time.Hour {
+		return r, errors.New("a time range can't start at 24:00")
+	}
+	if r.start == r.end {
+		return r, errors.New("a time range can't be empty")
+	}
+	return r, nil
+}
+
+// parseWeekdays parses "*", or a comma separated list of days or ranges of days, like "Mon-Wed,Fri".
+func parseWeekdays(s string) ([7]bool, error) {
+	var days [7]bool
+	if s == "*" {
+		for i := range days {
+			days[i] = true
+		}
+		return days, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		firstStr, lastStr, isRange := strings.Cut(part, "-")
+		first, ok := weekdays[strings.ToLower(firstStr)]
+		if !ok {
+			return days, fmt.Errorf("unknown day %q", firstStr)
+		}
+		last := first
+		if isRange {
+			if last, ok = weekdays[strings.ToLower(lastStr)]; !ok {
+				return days, fmt.Errorf("unknown day %q", lastStr)
+			}
+		}
+		// ranges can wrap around the end of the week, e.g. Fri-Mon
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseTimeOfDay parses a time of the day like 06:30, 24:00 being the end of the day.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	hStr, mStr, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	h, err := strconv.Atoi(hStr)
+	if err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid hour in %q", s)
+	}
+	m, err := strconv.Atoi(mStr)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("invalid minute in %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// atTimeOfDay returns the time of the day d, the wall clock is used so the time is correct across DST changes.
+func atTimeOfDay(day time.Time, d time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, int(d/time.Minute), 0, 0, day.Location())
+}
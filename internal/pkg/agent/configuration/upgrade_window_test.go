@@ -0,0 +1,120 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package configuration
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func TestUpgradeWindowNext(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, paris)
+	}
+
+	window := &UpgradeWindowConfig{
+		Timezone: "Europe/Paris",
+		Ranges:   []string{"Mon-Thu 22:00-06:00", "Sat,Sun 00:00-24:00"},
+	}
+
+	testcases := map[string]struct {
+		window   *UpgradeWindowConfig
+		t        time.Time
+		expected time.Time
+	}{
+		"no ranges": {
+			window:   &UpgradeWindowConfig{},
+			t:        at(2024, time.January, 3, 12, 0),
+			expected: at(2024, time.January, 3, 12, 0),
+		},
+		"within a window": {
+			window:   window,
+			t:        at(2024, time.January, 3, 23, 0), // Wednesday
+			expected: at(2024, time.January, 3, 23, 0),
+		},
+		"within a window started the day before": {
+			window:   window,
+			t:        at(2024, time.January, 4, 5, 59), // Thursday
+			expected: at(2024, time.January, 4, 5, 59),
+		},
+		"at the end of a window": {
+			window:   window,
+			t:        at(2024, time.January, 4, 6, 0), // Thursday
+			expected: at(2024, time.January, 4, 22, 0),
+		},
+		"deferred to the weekend": {
+			window:   window,
+			t:        at(2024, time.January, 5, 6, 0), // Friday
+			expected: at(2024, time.January, 6, 0, 0),
+		},
+		"within a whole day window": {
+			window:   window,
+			t:        at(2024, time.January, 7, 23, 59), // Sunday
+			expected: at(2024, time.January, 7, 23, 59),
+		},
+		"other timezone": {
+			window:   window,
+			t:        time.Date(2024, time.January, 3, 20, 30, 0, 0, time.UTC), // Wednesday 21:30 in Paris
+			expected: at(2024, time.January, 3, 22, 0),
+		},
+		"every day": {
+			window:   &UpgradeWindowConfig{Timezone: "UTC", Ranges: []string{"* 02:00-04:00"}},
+			t:        time.Date(2024, time.January, 3, 4, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, time.January, 4, 2, 0, 0, 0, time.UTC),
+		},
+		"days wrapping around the week": {
+			window:   &UpgradeWindowConfig{Timezone: "UTC", Ranges: []string{"Sat-Mon 02:00-04:00"}},
+			t:        time.Date(2024, time.January, 2, 3, 0, 0, 0, time.UTC), // Tuesday
+			expected: time.Date(2024, time.January, 6, 2, 0, 0, 0, time.UTC),
+		},
+		"across a DST change": {
+			window:   &UpgradeWindowConfig{Timezone: "Europe/Paris", Ranges: []string{"Sun 04:00-05:00"}},
+			t:        at(2024, time.March, 30, 12, 0),
+			expected: at(2024, time.March, 31, 4, 0),
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			next, err := tc.window.Next(tc.t)
+			require.NoError(t, err)
+			assert.True(t, tc.expected.Equal(next), "expected %s, got %s", tc.expected, next)
+		})
+	}
+}
+
+func TestUpgradeWindowValidate(t *testing.T) {
+	testcases := map[string]struct {
+		window map[string]any
+		err    bool
+	}{
+		"valid":            {window: map[string]any{"timezone": "UTC", "ranges": []any{"Mon,wed-fri 09:30-24:00"}}},
+		"unknown timezone": {window: map[string]any{"timezone": "Not/AZone"}, err: true},
+		"unknown day":      {window: map[string]any{"ranges": []any{"Monday 09:00-10:00"}}, err: true},
+		"missing days":     {window: map[string]any{"ranges": []any{"09:00-10:00"}}, err: true},
+		"invalid time":     {window: map[string]any{"ranges": []any{"Mon 9h-10h"}}, err: true},
+		"invalid hour":     {window: map[string]any{"ranges": []any{"Mon 09:00-25:00"}}, err: true},
+		"empty range":      {window: map[string]any{"ranges": []any{"Mon 09:00-09:00"}}, err: true},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			c := DefaultUpgradeConfig()
+			err := config.MustNewConfigFrom(map[string]any{"window": tc.window}).UnpackTo(c)
+			if tc.err {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
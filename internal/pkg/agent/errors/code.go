@@ -0,0 +1,73 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package errors
+
+import (
+	"context"
+	goerrors "errors"
+	"io/fs"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Classify returns the type of the error. Agent errors report their own type, other errors are
+// classified by their cause so errors of the standard library and of the control protocol map
+// to the same types as the errors of the agent.
+func Classify(err error) ErrorType {
+	if err == nil {
+		return TypeUnexpected
+	}
+
+	var agentErr Error
+	if goerrors.As(err, &agentErr) {
+		if t := agentErr.Type(); t != TypeUnexpected {
+			return t
+		}
+	}
+
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		switch s.Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return TypeNetwork
+		case codes.PermissionDenied, codes.Unauthenticated:
+			return TypePermission
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+			return TypeValidation
+		}
+	}
+
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	switch {
+	case goerrors.Is(err, fs.ErrPermission):
+		return TypePermission
+	case goerrors.As(err, &opErr), goerrors.As(err, &dnsErr), goerrors.Is(err, context.DeadlineExceeded):
+		return TypeNetwork
+	case goerrors.Is(err, fs.ErrNotExist):
+		return TypePath
+	}
+	return TypeUnexpected
+}
+
+// Code returns the stable code of the error in the error catalog, e.g. NETWORK or PERMISSION.
+// Tooling wrapping the agent can rely on the code to tell the class of an error apart without
+// parsing its message.
+func Code(err error) string {
+	if code, found := readableTypes[Classify(err)]; found {
+		return code
+	}
+	return readableTypes[TypeUnexpected]
+}
+
+// Metadata returns the metadata of the error, empty when the error is not an agent error.
+func Metadata(err error) map[string]interface{} {
+	var agentErr Error
+	if goerrors.As(err, &agentErr) {
+		return agentErr.Meta()
+	}
+	return nil
+}
@@ -8,9 +8,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestErrorsIs(t *testing.T) {
@@ -201,3 +206,28 @@ func TestMetaCallDoesNotModifyCollection(t *testing.T) {
 		t.Fatalf("err4.meta modified by calling Meta(): %v", resultingErr.meta)
 	}
 }
+
+func TestCode(t *testing.T) {
+	tt := []struct {
+		id       string
+		err      error
+		expected string
+	}{
+		{"agent error", New("bad config", TypeConfig), "CONFIG"},
+		{"wrapped agent error", fmt.Errorf("wrapping %w", New("denied", TypePermission)), "PERMISSION"},
+		{"permission", &fs.PathError{Op: "open", Path: "/etc/elastic-agent", Err: fs.ErrPermission}, "PERMISSION"},
+		{"not exist", fmt.Errorf("wrapping %w", fs.ErrNotExist), "PATH"},
+		{"path error", &fs.PathError{Op: "open", Path: "/etc/elastic-agent.yml", Err: syscall.ENOENT}, "PATH"},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, "NETWORK"},
+		{"grpc unavailable", status.Error(codes.Unavailable, "connection error"), "NETWORK"},
+		{"grpc invalid argument", status.Error(codes.InvalidArgument, "bad request"), "VALIDATION"},
+		{"untyped agent error", New(io.ErrNoProgress), "UNEXPECTED"},
+		{"unknown", errors.New("unknown"), "UNEXPECTED"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.id, func(t *testing.T) {
+			assert.Equal(t, tc.expected, Code(tc.err))
+		})
+	}
+}
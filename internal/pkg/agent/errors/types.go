@@ -24,6 +24,10 @@ const (
 	TypeFilesystem
 	// TypeSecurity represents set of errors related to security, encryption, etc.
 	TypeSecurity
+	// TypePermission represents set of errors caused by missing permissions.
+	TypePermission
+	// TypeValidation represents set of errors caused by invalid input, e.g. command line arguments.
+	TypeValidation
 )
 
 const (
@@ -46,4 +50,6 @@ var readableTypes = map[ErrorType]string{
 	TypeNetwork:          "NETWORK",
 	TypeFilesystem:       "FILESYSTEM",
 	TypeSecurity:         "SECURITY",
+	TypePermission:       "PERMISSION",
+	TypeValidation:       "VALIDATION",
 }
@@ -5,6 +5,7 @@
 package install
 
 import (
+	"context"
 	goerrors "errors"
 	"fmt"
 	"os"
@@ -46,6 +47,11 @@ func Install(cfgFile, topPath string, unprivileged bool, log *logp.Logger, pt *p
 		return utils.FileOwner{}, errors.New(err, "failed to discover the source directory for installation", errors.TypeFilesystem)
 	}
 
+	err = checkPathLength(dir, topPath)
+	if err != nil {
+		return utils.FileOwner{}, err
+	}
+
 	var ownership utils.FileOwner
 	username := ""
 	groupName := ""
@@ -53,7 +59,7 @@ func Install(cfgFile, topPath string, unprivileged bool, log *logp.Logger, pt *p
 	if unprivileged {
 		username, password = UnprivilegedUser(customUser, userPassword)
 		groupName = UnprivilegedGroup(customGroup)
-		ownership, err = EnsureUserAndGroup(username, groupName, pt, username == ElasticUsername && password == "") // force create only elastic user
+		ownership, err = EnsureUserAndGroup(username, groupName, pt, (username == ElasticUsername && password == "") || IsVirtualServiceAccount(username)) // force create only elastic user or the group of the virtual account
 		if err != nil {
 			// error context already added by EnsureUserAndGroup
 			return utils.FileOwner{}, err
@@ -389,13 +395,31 @@ func StopService(topPath string, timeout time.Duration, interval time.Duration)
 	return nil
 }
 
-// RestartService restarts the installed service.
-func RestartService(topPath string) error {
+// RestartService restarts the installed service. The uninstall token is required when the policy protects the
+// Elastic Agent, as the stop protection of the service is removed to restart it.
+func RestartService(ctx context.Context, topPath, uninstallToken string, log *logp.Logger) error {
+	if err := validateInstalledUninstallToken(ctx, paths.ConfigFile(), uninstallToken, log); err != nil {
+		return err
+	}
+	return restartService(topPath)
+}
+
+// RestartServiceAfterRollback restarts the service without the uninstall token. It is only called by the
+// upgrade watcher, running as root, to start the Elastic Agent it rolled back.
+func RestartServiceAfterRollback(topPath string) error {
+	return restartService(topPath)
+}
+
+func restartService(topPath string) error {
 	// only restarting the service, so no need to set the username and group to any value
 	svc, err := newService(topPath)
 	if err != nil {
 		return fmt.Errorf("error creating new service handler for restart: %w", err)
 	}
+	// the tamper protected service refuses the manual restarts, the restarted agent protects it again
+	if err := RemoveServiceStopProtection(); err != nil {
+		return fmt.Errorf("failed to remove the stop protection of the service: %w", err)
+	}
 	err = svc.Restart()
 	if err != nil {
 		return fmt.Errorf("failed to restart service (%s): %w", paths.ServiceName(), err)
@@ -469,6 +493,46 @@ func findDirectory() (string, error) {
 	return sourceDir, nil
 }
 
+// checkPathLength ensures that the files copied from the source directory fit under the maximum path length
+// of the system once installed into topPath. Only limited on Windows when long path support is not enabled.
+func checkPathLength(sourceDir string, topPath string) error {
+	if utils.MaxPathLength <= 0 {
+		return nil
+	}
+	enabled, err := utils.LongPathsEnabled()
+	if err == nil && enabled {
+		return nil
+	}
+	longest, err := longestRelativePath(sourceDir)
+	if err != nil {
+		return fmt.Errorf("failed to determine the longest path in %s: %w", sourceDir, err)
+	}
+	installed := filepath.Join(topPath, longest)
+	if len(installed) >= utils.MaxPathLength {
+		return fmt.Errorf("installing into %s results in the path %s of %d characters which exceeds the maximum path length of %d characters; enable long path support (LongPathsEnabled) on the system or use a shorter --base-path", topPath, installed, len(installed), utils.MaxPathLength)
+	}
+	return nil
+}
+
+// longestRelativePath returns the longest path relative to dir of all the files in dir.
+func longestRelativePath(dir string) (string, error) {
+	var longest string
+	err := filepath.WalkDir(dir, func(path string, _ os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if len(rel) > len(longest) {
+			longest = rel
+		}
+		return nil
+	})
+	return longest, err
+}
+
 // verifyDirectory ensures that the directory includes the executable.
 func verifyDirectory(dir string) error {
 	_, err := os.Stat(filepath.Join(dir, paths.BinaryName))
@@ -228,3 +228,23 @@ func TestSetupInstallPath(t *testing.T) {
 	require.NoError(t, err)
 	require.FileExists(t, filepath.Join(tmpdir, paths.MarkerFileName))
 }
+
+func TestLongestRelativePath(t *testing.T) {
+	tmpdir := t.TempDir()
+	nested := filepath.Join("data", "elastic-agent-abcdef", "components", "nested")
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpdir, nested), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, nested, "component.spec.yml"), []byte{}, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, "elastic-agent"), []byte{}, 0o644))
+
+	longest, err := longestRelativePath(tmpdir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(nested, "component.spec.yml"), longest)
+}
+
+func TestCheckPathLength(t *testing.T) {
+	tmpdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpdir, "elastic-agent"), []byte{}, 0o644))
+
+	// a short install path is always valid
+	assert.NoError(t, checkPathLength(tmpdir, filepath.Join(tmpdir, "install")))
+}
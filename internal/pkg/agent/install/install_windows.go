@@ -69,6 +69,11 @@ func withServiceOptions(username string, groupName string, password string) ([]s
 		return []serviceOpt{}, nil
 	}
 
+	if IsVirtualServiceAccount(username) {
+		// the virtual service account has no password
+		return []serviceOpt{withUserGroup(username, groupName)}, nil
+	}
+
 	if password != "" {
 		if isFullDomainName, err := isWindowsDomainUsername(username); err != nil {
 			return nil, fmt.Errorf("failed to parse username: %w", err)
@@ -126,6 +131,12 @@ func serviceConfigure(ownership utils.FileOwner) error {
 	if err != nil {
 		return fmt.Errorf("failed to set DACL for service(%s): %w", paths.ServiceName(), err)
 	}
+	if ownership.UID == VirtualServiceAccountSID(paths.ServiceName()) {
+		// the virtual service account can only be resolved once the service exists
+		if err := EnsureRights(VirtualServiceAccount()); err != nil {
+			return fmt.Errorf("failed to set proper rights to %s: %w", VirtualServiceAccount(), err)
+		}
+	}
 	return nil
 }
 
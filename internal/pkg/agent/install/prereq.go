@@ -34,6 +34,12 @@ func EnsureUserAndGroup(username string, groupName string, pt *progressbar.Progr
 		pt.Describe(fmt.Sprintf("Successfully created group %s", groupName))
 	}
 
+	if IsVirtualServiceAccount(username) {
+		// the virtual service account only exists once the service is installed, its rights are set when the
+		// service is configured
+		return virtualServiceAccountOwnership(ownership)
+	}
+
 	// ensure required user
 	ownership.UID, err = FindUID(username)
 	if err != nil && !errors.Is(err, ErrUserNotFound) {
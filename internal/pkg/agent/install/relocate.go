@@ -0,0 +1,198 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	goerrors "errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/kardianos/service"
+	"github.com/otiai10/copy"
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// relocatedDataMarker is the file marking a relocated data directory created by the Elastic Agent, the directory
+// itself is only removed when the Elastic Agent created it. A directory chosen by the user is kept and only its
+// contents, which the Elastic Agent copied in it, are removed.
+const relocatedDataMarker = ".elastic-agent-created"
+
+// RelocateData relocates the data directory of the installed Elastic Agent to target.
+//
+// The service is stopped while the data is moved and always started once it is relocated. When the relocation fails
+// the service is placed back to running, in the case that it was running when the relocation started.
+func RelocateData(topPath string, target string, ownership utils.FileOwner, pt *progressbar.ProgressBar) error {
+	status, err := EnsureStoppedService(topPath, pt)
+	if err != nil {
+		// context for the error already provided in the EnsureStoppedService function
+		return err
+	}
+
+	err = RelocateDataDir(topPath, target, ownership, pt)
+	if err != nil {
+		if status == service.StatusRunning {
+			_ = StartService(topPath)
+		}
+		return err
+	}
+
+	pt.Describe("Starting service")
+	// context for the error already provided in the StartService function
+	return StartService(topPath)
+}
+
+// RelocateDataDir moves the data directory of the install at topPath, with the state, the components and the logs,
+// to target and replaces it with a symlink to target. The Elastic Agent must not be running. Relocating to the
+// directory the data is already relocated to does nothing, relocating it again moves it to the new target.
+func RelocateDataDir(topPath string, target string, ownership utils.FileOwner, pt *progressbar.ProgressBar) error {
+	if !filepath.IsAbs(target) {
+		return fmt.Errorf("data path %q must be an absolute path", target)
+	}
+	target = filepath.Clean(target)
+
+	dataPath := paths.DataFrom(topPath)
+	source := dataPath
+	if relocated, ok := paths.RelocatedDataFrom(topPath); ok {
+		source = relocated
+	}
+	if paths.ArePathsEqual(source, target) {
+		pt.Describe("Data already relocated to " + target)
+		return nil
+	}
+	if paths.HasPrefix(target, topPath) || paths.HasPrefix(target, source) {
+		return fmt.Errorf("data path %q cannot be inside the install path %q or the data directory %q", target, topPath, source)
+	}
+
+	created, err := ensureEmptyDir(target)
+	if err != nil {
+		return err
+	}
+
+	// the protected paths of a hardened install cannot be removed while they are locked
+	if perms.IsHardened(topPath) {
+		if err := perms.Unlock(topPath); err != nil {
+			return fmt.Errorf("failed to unlock the protected paths: %w", err)
+		}
+		defer func() {
+			_ = perms.Lock(topPath)
+		}()
+	}
+
+	pt.Describe("Copying data to " + target)
+	err = copy.Copy(source, target, copy.Options{
+		OnSymlink: func(_ string) copy.SymlinkAction {
+			return copy.Shallow
+		},
+		Skip: func(srcinfo os.FileInfo, _, _ string) (bool, error) {
+			// sockets and pipes left by the stopped Elastic Agent cannot be copied, they are re-created on start
+			return !srcinfo.Mode().IsRegular() && !srcinfo.IsDir() && srcinfo.Mode()&fs.ModeSymlink == 0, nil
+		},
+		PreserveTimes: true,
+		PreserveOwner: true,
+		Sync:          true,
+	})
+	if err == nil {
+		err = markRelocatedData(target, created)
+	}
+	if err == nil {
+		err = perms.FixPermissions(target, perms.WithOwnership(ownership))
+	}
+	if err != nil {
+		return goerrors.Join(fmt.Errorf("failed to copy the data directory %q to %q: %w", source, target, err), cleanupDir(target, created))
+	}
+
+	// **start critical section**
+	// the copy is complete, the data directory (or the symlink to the previous copy) is moved aside and replaced
+	// by a symlink to the copy; it's only removed once the symlink exists, so the install always has a data directory
+	pt.Describe("Replacing " + dataPath)
+	aside := dataPath + ".relocating"
+	if err := os.Rename(dataPath, aside); err != nil {
+		return goerrors.Join(fmt.Errorf("failed to move the data directory %q aside: %w", dataPath, err), cleanupDir(target, created))
+	}
+	if err := os.Symlink(target, dataPath); err != nil {
+		if rerr := os.Rename(aside, dataPath); rerr != nil {
+			return fmt.Errorf("failed to link the data directory %q to %q: %w, the data directory was moved to %q: %w", dataPath, target, err, aside, rerr)
+		}
+		return goerrors.Join(fmt.Errorf("failed to link the data directory %q to %q: %w", dataPath, target, err), cleanupDir(target, created))
+	}
+	// **end critical section**
+
+	pt.Describe("Removing " + aside)
+	if err := RemovePath(aside); err != nil {
+		return fmt.Errorf("failed to remove the previous data directory %q: %w", aside, err)
+	}
+	if source != dataPath {
+		// the data was relocated before, the previous copy is no longer used
+		pt.Describe("Removing " + source)
+		if err := removeRelocatedData(source); err != nil {
+			return fmt.Errorf("failed to remove the previous data directory %q: %w", source, err)
+		}
+	}
+	pt.Describe("Data relocated to " + target)
+	return nil
+}
+
+// markRelocatedData marks the relocated data directory as created by the Elastic Agent, the marker copied from a
+// previously relocated data directory is removed when the Elastic Agent did not create it.
+func markRelocatedData(path string, created bool) error {
+	marker := filepath.Join(path, relocatedDataMarker)
+	if created {
+		return os.WriteFile(marker, nil, 0o600)
+	}
+	if err := os.Remove(marker); err != nil && !goerrors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// removeRelocatedData removes the relocated data directory at path. Only what the Elastic Agent created is removed,
+// the directory is kept when the user created it.
+func removeRelocatedData(path string) error {
+	_, err := os.Stat(filepath.Join(path, relocatedDataMarker))
+	if err != nil && !goerrors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return cleanupDir(path, err == nil)
+}
+
+// ensureEmptyDir ensures path is an empty directory, it returns true when the directory is created.
+func ensureEmptyDir(path string) (bool, error) {
+	entries, err := os.ReadDir(path)
+	if goerrors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(path, 0750); err != nil {
+			return false, fmt.Errorf("failed to create data path %q: %w", path, err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read data path %q: %w", path, err)
+	}
+	if len(entries) > 0 {
+		return false, fmt.Errorf("data path %q must be empty", path)
+	}
+	return false, nil
+}
+
+// cleanupDir removes the partial copy in path, the directory itself is kept when it existed before.
+func cleanupDir(path string, created bool) error {
+	if created {
+		return RemovePath(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, entry := range entries {
+		errs = append(errs, RemovePath(filepath.Join(path, entry.Name())))
+	}
+	return goerrors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+func TestRelocateDataDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("creating symlinks requires Administrator rights on Windows")
+	}
+	pt := progressbar.NewOptions(-1, progressbar.OptionSetWriter(io.Discard))
+	ownership, err := utils.CurrentFileOwner()
+	require.NoError(t, err)
+
+	topPath := t.TempDir()
+	statePath := filepath.Join("elastic-agent-1.0.0-abc", "state.enc")
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.DataFrom(topPath), "elastic-agent-1.0.0-abc", "logs"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(paths.DataFrom(topPath), statePath), []byte("state"), 0o600))
+
+	volume := t.TempDir()
+	first := filepath.Join(volume, "first")
+	require.NoError(t, RelocateDataDir(topPath, first, ownership, pt))
+
+	relocated, ok := paths.RelocatedDataFrom(topPath)
+	require.True(t, ok)
+	assert.Equal(t, first, relocated)
+	contents, err := os.ReadFile(filepath.Join(paths.DataFrom(topPath), statePath))
+	require.NoError(t, err)
+	assert.Equal(t, "state", string(contents))
+	assert.DirExists(t, filepath.Join(first, "elastic-agent-1.0.0-abc", "logs"))
+
+	// relocating to the same directory does nothing
+	require.NoError(t, RelocateDataDir(topPath, first, ownership, pt))
+
+	// relocating again removes the previous copy
+	second := filepath.Join(volume, "second")
+	require.NoError(t, os.MkdirAll(second, 0o750))
+	require.NoError(t, RelocateDataDir(topPath, second, ownership, pt))
+	relocated, ok = paths.RelocatedDataFrom(topPath)
+	require.True(t, ok)
+	assert.Equal(t, second, relocated)
+	assert.NoDirExists(t, first)
+	assert.NoDirExists(t, paths.DataFrom(topPath)+".relocating")
+	assert.FileExists(t, filepath.Join(second, statePath))
+
+	// the directory created by the user is kept, only the copied data is removed
+	third := filepath.Join(volume, "third")
+	require.NoError(t, RelocateDataDir(topPath, third, ownership, pt))
+	assert.FileExists(t, filepath.Join(third, statePath))
+	assert.DirExists(t, second)
+	entries, err := os.ReadDir(second)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestRelocateDataDirInvalidTarget(t *testing.T) {
+	pt := progressbar.NewOptions(-1, progressbar.OptionSetWriter(io.Discard))
+	ownership, err := utils.CurrentFileOwner()
+	require.NoError(t, err)
+
+	topPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(paths.DataFrom(topPath), 0o750))
+
+	notEmpty := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(notEmpty, "file"), nil, 0o600))
+
+	for name, target := range map[string]string{
+		"relative":    "data",
+		"inside top":  filepath.Join(topPath, "relocated"),
+		"not empty":   notEmpty,
+		"inside data": filepath.Join(paths.DataFrom(topPath), "relocated"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, RelocateDataDir(topPath, target, ownership, pt))
+			_, ok := paths.RelocatedDataFrom(topPath)
+			assert.False(t, ok)
+		})
+	}
+}
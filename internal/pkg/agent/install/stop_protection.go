@@ -0,0 +1,78 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/features"
+)
+
+// ServiceStopProtection refuses the manual stops of the installed service while the policy protects the
+// Elastic Agent with an uninstall token, so that the service is only stopped by the uninstall command
+// given the token.
+type ServiceStopProtection struct {
+	log *logger.Logger
+	// applied is the protection applied to the service, nil until the first policy is reloaded.
+	applied *bool
+
+	tamperProtectionFn func() bool // allows to inject the flag for tests, defaults to features.TamperProtection
+	setFn              func(enabled bool) error
+}
+
+// NewServiceStopProtection creates the protection of the service, reloaded with each policy.
+func NewServiceStopProtection(log *logger.Logger) *ServiceStopProtection {
+	return &ServiceStopProtection{
+		log:                log,
+		tamperProtectionFn: features.TamperProtection,
+		setFn:              setServiceStopProtection,
+	}
+}
+
+// Reload protects the service when the policy requires the uninstall token.
+func (p *ServiceStopProtection) Reload(rawConfig *config.Config) error {
+	cfg, err := protectionConfig(rawConfig)
+	if err != nil {
+		return err
+	}
+
+	enabled := p.tamperProtectionFn() && cfg.UninstallTokenRequired()
+	if p.applied != nil && *p.applied == enabled {
+		return nil
+	}
+	if err := p.setFn(enabled); err != nil {
+		return fmt.Errorf("failed to set the stop protection of the service: %w", err)
+	}
+	p.applied = &enabled
+	if enabled {
+		p.log.Info("Service is tamper protected, it can only be stopped by uninstalling with the uninstall token")
+	} else {
+		p.log.Debug("Service is not tamper protected")
+	}
+	return nil
+}
+
+// RemoveServiceStopProtection allows the manual stops of the installed service again, the uninstall token
+// must be validated first.
+func RemoveServiceStopProtection() error {
+	return setServiceStopProtection(false)
+}
+
+// protectionConfig reads the protection configuration of the policy from the agent configuration.
+func protectionConfig(rawConfig *config.Config) (protection.Config, error) {
+	m, err := rawConfig.ToMapStr()
+	if err != nil {
+		return protection.Config{}, fmt.Errorf("could not create the map from the configuration: %w", err)
+	}
+	cfg, err := protection.GetAgentProtectionConfig(m)
+	if err != nil && !errors.Is(err, protection.ErrNotFound) {
+		return protection.Config{}, fmt.Errorf("could not read the agent protection configuration: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build darwin
+
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// launchdDaemonsDir is the directory of the plist of the launch daemon, overridden in tests.
+var launchdDaemonsDir = "/Library/LaunchDaemons"
+
+// setServiceStopProtection sets or clears the user immutable flag of the plist of the launch daemon, as
+// `chflags uchg` does. launchd cannot refuse to stop a job, the KeepAlive of the job restarts it when it is
+// stopped with `launchctl stop` or killed. The immutable plist cannot be removed or edited to disable the job,
+// so a job unloaded with `launchctl bootout` is loaded again at boot.
+func setServiceStopProtection(enabled bool) error {
+	path := filepath.Join(launchdDaemonsDir, paths.ServiceName()+".plist")
+	var stat unix.Stat_t
+	err := unix.Lstat(path, &stat)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	flags := stat.Flags &^ unix.UF_IMMUTABLE
+	if enabled {
+		flags = stat.Flags | unix.UF_IMMUTABLE
+	}
+	if flags == stat.Flags {
+		return nil
+	}
+	if err := unix.Chflags(path, int(flags)); err != nil {
+		return fmt.Errorf("failed to set the flags of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package install
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// stopProtectionDropIn is the systemd drop-in refusing the manual stops of the service, `systemctl stop`
+// fails with "Operation refused" while it is present.
+const stopProtectionDropIn = `# Written by the Elastic Agent while it is tamper protected, removed on uninstall.
+[Unit]
+RefuseManualStop=yes
+`
+
+// systemdUnitDir is the directory of the unit of the service, overridden in tests.
+var systemdUnitDir = "/etc/systemd/system"
+
+// setServiceStopProtection adds or removes the drop-in of the systemd unit of the service.
+func setServiceStopProtection(enabled bool) error {
+	if !isSystemdRunning() {
+		return nil
+	}
+	dir := filepath.Join(systemdUnitDir, paths.ServiceName()+".service.d")
+	path := filepath.Join(dir, "tamper-protection.conf")
+	if enabled {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+		if err := os.WriteFile(path, []byte(stopProtectionDropIn), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	} else {
+		err := os.Remove(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+
+	cmd := exec.Command("systemctl", "daemon-reload")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to reload systemd daemon: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux && !darwin
+
+package install
+
+// setServiceStopProtection is a no-op, on Windows the service is protected by Elastic Defend and rc.d cannot
+// refuse to stop a service.
+func setServiceStopProtection(_ bool) error {
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestServiceStopProtection(t *testing.T) {
+	log, _ := loggertest.New("stop_protection")
+	var calls []bool
+	p := NewServiceStopProtection(log)
+	p.tamperProtectionFn = func() bool { return true }
+	p.setFn = func(enabled bool) error {
+		calls = append(calls, enabled)
+		return nil
+	}
+
+	protected := config.MustNewConfigFrom(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"protection": map[string]interface{}{
+				"enabled":              true,
+				"uninstall_token_hash": protection.HashUninstallToken("token"),
+			},
+		},
+	})
+	unprotected := config.MustNewConfigFrom(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"logging.level": "info",
+		},
+	})
+
+	require.NoError(t, p.Reload(unprotected))
+	require.NoError(t, p.Reload(protected))
+	require.NoError(t, p.Reload(protected))
+	require.NoError(t, p.Reload(unprotected))
+	assert.Equal(t, []bool{false, true, false}, calls, "the protection should only be set when it changes")
+
+	p.tamperProtectionFn = func() bool { return false }
+	require.NoError(t, p.Reload(protected))
+	assert.Equal(t, []bool{false, true, false}, calls, "the service should not be protected without the tamper protection feature")
+}
+
+func TestValidateUninstallToken(t *testing.T) {
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"features": map[string]interface{}{
+				"tamper_protection": map[string]interface{}{"enabled": true},
+			},
+			"protection": map[string]interface{}{
+				"enabled":              true,
+				"uninstall_token_hash": protection.HashUninstallToken("token"),
+			},
+		},
+	})
+	assert.ErrorIs(t, validateUninstallToken(cfg, ""), protection.ErrUninstallTokenRequired)
+	assert.ErrorIs(t, validateUninstallToken(cfg, "other"), protection.ErrInvalidUninstallToken)
+	assert.NoError(t, validateUninstallToken(cfg, "token"))
+}
+
+func TestValidateInstalledUninstallToken(t *testing.T) {
+	log, _ := loggertest.New("stop_protection")
+	// without a readable configuration the agent could be tamper protected, the service must not be restarted
+	err := validateInstalledUninstallToken(t.Context(), filepath.Join(t.TempDir(), "elastic-agent.yml"), "", log)
+	assert.Error(t, err)
+}
+
+func TestValidateUninstallTokenOrFailClosed(t *testing.T) {
+	loadErr := errors.New("failed to decrypt the policy")
+	fleetConfigFile := filepath.Join(t.TempDir(), "fleet.enc")
+
+	assert.NoError(t, validateUninstallTokenOrFailClosed(nil, loadErr, fleetConfigFile, ""),
+		"a standalone agent without the policy of Fleet cannot be tamper protected")
+
+	require.NoError(t, os.WriteFile(fleetConfigFile, []byte("encrypted"), 0o600))
+	assert.ErrorIs(t, validateUninstallTokenOrFailClosed(nil, loadErr, fleetConfigFile, "token"), loadErr,
+		"the uninstall must fail closed when the policy of Fleet cannot be read")
+
+	cfg := config.MustNewConfigFrom(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"features": map[string]interface{}{
+				"tamper_protection": map[string]interface{}{"enabled": true},
+			},
+			"protection": map[string]interface{}{
+				"enabled":              true,
+				"uninstall_token_hash": protection.HashUninstallToken("token"),
+			},
+		},
+	})
+	assert.ErrorIs(t, validateUninstallTokenOrFailClosed(cfg, nil, fleetConfigFile, ""), protection.ErrUninstallTokenRequired)
+	assert.NoError(t, validateUninstallTokenOrFailClosed(cfg, nil, fleetConfigFile, "token"))
+}
@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 
 	"github.com/kardianos/service"
 	"gopkg.in/ini.v1"
@@ -30,6 +31,12 @@ const (
 	// depending on the shutdown timing.
 	darwinServiceExitTimeout = 60
 
+	// serviceRestartDelay is the delay in seconds before the OpenRC and rc.d services restart a failed Elastic Agent.
+	serviceRestartDelay = 15
+	// serviceStopTimeout is the time in seconds the OpenRC service waits for the Elastic Agent to stop gracefully
+	// before killing it, for the same reason as darwinServiceExitTimeout.
+	serviceStopTimeout = 60
+
 	SystemdUserNameKey  = "User"
 	SystemdGroupNameKey = "Group"
 
@@ -118,7 +125,8 @@ func newService(topPath string, opt ...serviceOpt) (service.Service, error) {
 	if runtime.GOOS == "linux" {
 		// The github.com/kardianos/service library doesn't support KillMode in their prebuilt template.
 		// This option allows to pass our own template for the systemd unit configuration, which is a copy
-		// of the prebuilt template with added KillMode option
+		// of the prebuilt template with added KillMode option. The unit also sets Delegate=yes so systemd
+		// leaves alone the cgroups the Elastic Agent creates for the components with a resource budget.
 		cfg.Option["SystemdScript"] = linuxSystemdScript
 
 		// By setting KillMode=process in Elastic Agent's systemd unit configuration file, we ensure
@@ -129,6 +137,28 @@ func newService(topPath string, opt ...serviceOpt) (service.Service, error) {
 		cfg.Option["KillMode"] = "process"
 	}
 
+	if runtime.GOOS == "linux" || runtime.GOOS == "freebsd" {
+		// Set the stdout and stderr logs of the OpenRC and rc.d services to be inside the installation directory,
+		// systemd sends them to the journal.
+		cfg.Option["StandardOutPath"] = filepath.Join(topPath, fmt.Sprintf("%s.out.log", paths.ServiceName()))
+		cfg.Option["StandardErrorPath"] = filepath.Join(topPath, fmt.Sprintf("%s.err.log", paths.ServiceName()))
+		cfg.Option["RestartDelay"] = serviceRestartDelay
+		cfg.Option["StopTimeout"] = serviceStopTimeout
+	}
+
+	if runtime.GOOS == "linux" {
+		// The prebuilt OpenRC template of github.com/kardianos/service doesn't restart the service when it fails,
+		// doesn't run it as the user of an unprivileged install and writes its output to /var/log.
+		cfg.Option["OpenRCScript"] = linuxOpenRCScript
+	}
+
+	if runtime.GOOS == "freebsd" {
+		// The prebuilt rc.d template of github.com/kardianos/service doesn't declare the rcvar enabling the service,
+		// so it only starts with onestart, and passes the working directory to daemon(8) as the command to run.
+		cfg.Option["SysvScript"] = freebsdRCScript
+		cfg.Option["RCName"] = rcName(paths.ServiceName())
+	}
+
 	if runtime.GOOS == "darwin" {
 		// The github.com/kardianos/service library doesn't support ExitTimeOut in their prebuilt template.
 		// This option allows to pass our own template for the launch daemon plist, which is a copy
@@ -145,6 +175,28 @@ func newService(topPath string, opt ...serviceOpt) (service.Service, error) {
 	return service.New(nil, cfg)
 }
 
+// reinstallService installs the service again with the username and group, used to change the user of the services
+// that are fully defined by a script of the install, OpenRC and rc.d.
+func reinstallService(topPath string, username string, groupName string) error {
+	svc, err := newService(topPath, withUserGroup(username, groupName))
+	if err != nil {
+		return fmt.Errorf("error creating new service handler for reinstall: %w", err)
+	}
+	if err := svc.Uninstall(); err != nil && !errors.Is(err, service.ErrNotInstalled) {
+		return fmt.Errorf("failed to uninstall service (%s): %w", paths.ServiceName(), err)
+	}
+	if err := svc.Install(); err != nil {
+		return fmt.Errorf("failed to install service (%s): %w", paths.ServiceName(), err)
+	}
+	return nil
+}
+
+// rcName returns the service name as the name of an rc.d script, its rc.conf variables are prefixed with it so it
+// cannot contain a dash.
+func rcName(serviceName string) string {
+	return strings.ReplaceAll(serviceName, "-", "_")
+}
+
 func changeSystemdServiceFile(serviceName string, serviceFilePath string, username string, groupName string) error {
 	svcCfg, err := ini.Load(serviceFilePath)
 	if err != nil {
@@ -309,9 +361,70 @@ StandardError=file:/var/log/{{.Name}}.err
 {{if .Restart}}Restart={{.Restart}}{{end}}
 {{if .SuccessExitStatus}}SuccessExitStatus={{.SuccessExitStatus}}{{end}}
 {{if .Config.Option.KillMode}}KillMode={{.Config.Option.KillMode}}{{end}}
+Delegate=yes
 RestartSec=120
 EnvironmentFile=-/etc/sysconfig/{{.Name}}
 
 [Install]
 WantedBy=multi-user.target
 `
+
+// The OpenRC script of the service, the process is supervised by supervise-daemon which restarts it when it fails.
+// On stop only the Elastic Agent is signaled, the Upgrade Watcher keeps running so it can roll back an upgraded
+// Elastic Agent that keeps failing, like KillMode=process of the systemd unit.
+const linuxOpenRCScript = `#!/sbin/openrc-run
+supervisor=supervise-daemon
+name="{{.DisplayName}}"
+description="{{.Description}}"
+command={{.Path|cmdEscape}}
+{{- if .Arguments }}
+command_args="{{range .Arguments}}{{.}} {{end}}"
+{{- end }}
+{{- if .UserName }}
+command_user="{{.UserName}}{{if .Config.Option.GroupName}}:{{.Config.Option.GroupName}}{{end}}"
+{{- end }}
+{{- if .WorkingDirectory }}
+directory={{.WorkingDirectory|cmdEscape}}
+{{- end }}
+output_log={{.Config.Option.StandardOutPath|cmdEscape}}
+error_log={{.Config.Option.StandardErrorPath|cmdEscape}}
+respawn_delay={{.Config.Option.RestartDelay}}
+respawn_max=0
+retry="TERM/{{.Config.Option.StopTimeout}}/KILL/5"
+
+depend() {
+	need net
+	after firewall
+{{- range $i, $dep := .Dependencies}}
+	{{$dep}}
+{{- end}}
+}
+`
+
+// The rc.d script of the service, the process is supervised by daemon(8) which restarts it when it fails and
+// forwards the stop signal to it only, leaving the Upgrade Watcher running. The service is enabled unless
+// <name>_enable is set to NO in rc.conf, it is installed to run.
+const freebsdRCScript = `#!/bin/sh
+
+# PROVIDE: {{.Name}}
+# REQUIRE: LOGIN NETWORKING
+# KEYWORD: shutdown
+
+. /etc/rc.subr
+
+name="{{.Config.Option.RCName}}"
+rcvar="${name}_enable"
+
+load_rc_config $name
+
+: ${ {{- .Config.Option.RCName}}_enable:="YES"}
+{{- if .WorkingDirectory }}
+: ${ {{- .Config.Option.RCName}}_chdir:="{{.WorkingDirectory}}"}
+{{- end }}
+
+pidfile="/var/run/${name}.pid"
+command="/usr/sbin/daemon"
+command_args="-c -f -r -R {{.Config.Option.RestartDelay}} -P ${pidfile} -t \"${name}: daemon\"{{if .UserName}} -u {{.UserName}}{{end}} -o {{.Config.Option.StandardOutPath}} {{.Path}}{{range .Arguments}} {{.}}{{end}}"
+
+run_rc_command "$1"
+`
@@ -0,0 +1,16 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build freebsd
+
+package install
+
+import (
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// changeUser changes user associated with a service by generating its rc.d script again
+func changeUser(topPath string, ownership utils.FileOwner, username string, groupName string, _ string) error {
+	return reinstallService(topPath, username, groupName)
+}
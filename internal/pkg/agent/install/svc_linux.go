@@ -11,12 +11,18 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/kardianos/service"
+
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/pkg/utils"
 )
 
 // changeUser changes user associated with a service without reinstalling the service itself
 func changeUser(topPath string, ownership utils.FileOwner, username string, groupName string, _ string) error {
+	if service.ChosenSystem().String() == "linux-openrc" {
+		// the OpenRC script is fully generated by the install, it is generated again with the user
+		return reinstallService(topPath, username, groupName)
+	}
 	if !isSystemdRunning() {
 		return ErrChangeUserUnsupported
 	}
@@ -5,12 +5,15 @@
 package install
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"text/template"
 
+	"github.com/kardianos/service"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -458,3 +461,70 @@ func extractServiceSection(content string) string {
 
 	return strings.Join(serviceLines, "\n")
 }
+
+// executeServiceScript executes the script template like github.com/kardianos/service does on install.
+func executeServiceScript(t *testing.T, script string, username string, groupName string) string {
+	t.Helper()
+	tmpl, err := template.New("").Funcs(template.FuncMap{
+		"cmdEscape": func(s string) string { return strings.ReplaceAll(s, " ", `\x20`) },
+	}).Parse(script)
+	require.NoError(t, err)
+
+	topPath := "/opt/Elastic/Agent"
+	data := struct {
+		*service.Config
+		Path string
+	}{
+		Config: &service.Config{
+			Name:             "elastic-agent",
+			DisplayName:      "Elastic Agent",
+			Description:      ServiceDescription,
+			WorkingDirectory: topPath,
+			UserName:         username,
+			Option: service.KeyValue{
+				"GroupName":         groupName,
+				"StandardOutPath":   topPath + "/elastic-agent.out.log",
+				"StandardErrorPath": topPath + "/elastic-agent.err.log",
+				"RestartDelay":      serviceRestartDelay,
+				"StopTimeout":       serviceStopTimeout,
+				"RCName":            rcName("elastic-agent"),
+			},
+		},
+		Path: topPath + "/elastic-agent",
+	}
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, data))
+	return buf.String()
+}
+
+func TestLinuxOpenRCScript(t *testing.T) {
+	script := executeServiceScript(t, linuxOpenRCScript, "elastic-agent-user", "elastic-agent")
+	assert.Contains(t, script, "supervisor=supervise-daemon\n")
+	assert.Contains(t, script, "command=/opt/Elastic/Agent/elastic-agent\n")
+	assert.Contains(t, script, `command_user="elastic-agent-user:elastic-agent"`)
+	assert.Contains(t, script, "directory=/opt/Elastic/Agent\n")
+	assert.Contains(t, script, "output_log=/opt/Elastic/Agent/elastic-agent.out.log\n")
+	assert.Contains(t, script, "respawn_delay=15\n")
+	assert.Contains(t, script, `retry="TERM/60/KILL/5"`)
+
+	script = executeServiceScript(t, linuxOpenRCScript, "", "")
+	assert.NotContains(t, script, "command_user", "a privileged install runs as root")
+}
+
+func TestFreeBSDRCScript(t *testing.T) {
+	script := executeServiceScript(t, freebsdRCScript, "elastic-agent-user", "elastic-agent")
+	assert.Contains(t, script, "# PROVIDE: elastic-agent\n")
+	assert.Contains(t, script, "name=\"elastic_agent\"\n")
+	assert.Contains(t, script, ": ${elastic_agent_enable:=\"YES\"}\n")
+	assert.Contains(t, script, ": ${elastic_agent_chdir:=\"/opt/Elastic/Agent\"}\n")
+	assert.Contains(t, script, ` -u elastic-agent-user -o /opt/Elastic/Agent/elastic-agent.out.log /opt/Elastic/Agent/elastic-agent"`)
+	assert.Contains(t, script, "-r -R 15 ")
+
+	script = executeServiceScript(t, freebsdRCScript, "", "")
+	assert.NotContains(t, script, " -u ", "a privileged install runs as root")
+}
+
+func TestRCName(t *testing.T) {
+	assert.Equal(t, "elastic_agent", rcName("elastic-agent"))
+	assert.Equal(t, "elastic_agent_ns", rcName("elastic-agent-ns"))
+}
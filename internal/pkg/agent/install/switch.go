@@ -40,7 +40,7 @@ func SwitchExecutingMode(topPath string, pt *progressbar.ProgressBar, username s
 	// ensure user/group are created
 	var ownership utils.FileOwner
 	if username != "" && groupName != "" {
-		ownership, err = EnsureUserAndGroup(username, groupName, pt, username == ElasticUsername)
+		ownership, err = EnsureUserAndGroup(username, groupName, pt, username == ElasticUsername || IsVirtualServiceAccount(username))
 		if err != nil {
 			// context for the error already provided in the EnsureUserAndGroup function
 			return err
@@ -22,6 +22,19 @@ import (
 func switchPlatformMode(pt *progressbar.ProgressBar, ownership utils.FileOwner) error {
 	ctx := context.Background()
 
+	// only the default backend moves between the keychain and the file vault, the seed of the other backends is
+	// protected the same way in both modes
+	vaultCfg, err := vault.LoadConfig(paths.AgentVaultPath())
+	if err != nil {
+		return fmt.Errorf("error reading vault backend: %w", err)
+	}
+	if !vaultCfg.IsDefault() {
+		if vaultCfg.Backend == vault.BackendKeychain && ownership.UID != 0 {
+			return fmt.Errorf("vault backend %q requires a privileged install", vaultCfg.Backend)
+		}
+		return nil
+	}
+
 	unprivilegedVault, err := checkForUnprivilegedVault(ctx)
 	if err != nil {
 		return fmt.Errorf("error checking for unprivileged vault: %w", err)
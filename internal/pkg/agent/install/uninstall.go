@@ -20,10 +20,13 @@ import (
 	"github.com/schollz/progressbar/v3"
 
 	"github.com/elastic/elastic-agent-libs/logp"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/clientcert"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	aerrors "github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vars"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
@@ -76,13 +79,17 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 	notifyFleet := false
 	var agentID agentInfo
 	var cfg *configuration.Configuration
+	var rawCfg *config.Config
+	var rawCfgErr error
 	func() { // check if we need to notify in a func to allow us to return early if a (non-fatal) error is encountered.
 		// read local config
 		c, err := operations.LoadFullAgentConfig(ctx, log, cfgFile, false, unprivileged)
 		if err != nil {
+			rawCfgErr = err
 			pt.Describe("notify Fleet failed: unable to read config")
 			return
 		}
+		rawCfg = c
 		cfg, err = configuration.NewFromConfig(c)
 		if err != nil {
 			pt.Describe("notify Fleet failed: error transforming config")
@@ -95,9 +102,19 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 			if cfg.Fleet != nil && cfg.Fleet.Server != nil {
 				localFleet = true
 			}
+			// Fleet Server requires the client certificate issued by Fleet with mutual TLS
+			if err := clientcert.Load(ctx, cfg.Fleet, vault.WithUnprivileged(unprivileged)); err != nil {
+				pt.Describe(fmt.Sprintf("notify Fleet: unable to load the client certificate: %v", err))
+			}
 		}
 	}()
 
+	// a tamper protected agent is left untouched without a valid uninstall token, the token is validated
+	// before Fleet is notified and the service is stopped
+	if err := validateUninstallTokenOrFailClosed(rawCfg, rawCfgErr, paths.AgentConfigFile(), uninstallToken); err != nil {
+		return err
+	}
+
 	// Notify fleet-server while it is still running if it's running locally
 	if notifyFleet && localFleet {
 		// host is set in the agent/cmd/enroll_cmd.go by createFleetServerBootstrapConfig
@@ -108,6 +125,11 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 		notifyFleetAuditUninstall(ctx, log, pt, cfg, &agentID) //nolint:errcheck // ignore the error as we can't act on it
 	}
 
+	// allow the service to be stopped, it refuses the manual stops while tamper protected
+	if err := RemoveServiceStopProtection(); err != nil {
+		return fmt.Errorf("failed to remove the stop protection of the service: %w", err)
+	}
+
 	// ensure service is stopped
 	status, err := EnsureStoppedService(topPath, pt)
 	if err != nil {
@@ -154,6 +176,21 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 		}
 	}
 
+	// the protected paths of a hardened install cannot be removed while they are locked
+	if perms.IsHardened(topPath) {
+		if err := perms.Unlock(topPath); err != nil {
+			return aerrors.New(err, "failed to unlock the protected paths", aerrors.M("directory", topPath))
+		}
+	}
+
+	// the relocated data directory is outside the install directory, the directory is kept when the user created it
+	if relocated, ok := paths.RelocatedDataFrom(topPath); ok {
+		pt.Describe("Removing relocated data directory")
+		if err := removeRelocatedData(relocated); err != nil {
+			return aerrors.New(err, fmt.Sprintf("failed to remove relocated data directory (%s)", relocated), aerrors.M("directory", relocated))
+		}
+	}
+
 	// remove existing directory
 	pt.Describe("Removing install directory")
 	err = RemovePath(topPath)
@@ -170,6 +207,49 @@ func Uninstall(ctx context.Context, cfgFile, topPath, uninstallToken string, log
 	return nil
 }
 
+// validateUninstallToken validates the uninstall token when the policy protects the Elastic Agent.
+func validateUninstallToken(rawConfig *config.Config, uninstallToken string) error {
+	if err := features.Apply(rawConfig); err != nil {
+		return fmt.Errorf("could not parse and apply feature flags config: %w", err)
+	}
+	if !features.TamperProtection() {
+		return nil
+	}
+	cfg, err := protectionConfig(rawConfig)
+	if err != nil {
+		return err
+	}
+	return protection.ValidateUninstallToken(cfg, uninstallToken)
+}
+
+// validateUninstallTokenOrFailClosed validates the uninstall token against the loaded configuration. Only the
+// policy of Fleet protects the Elastic Agent: when the configuration could not be loaded and the policy of Fleet
+// is stored at fleetConfigFile, the agent could be protected and the token cannot be validated, it's then an
+// error.
+func validateUninstallTokenOrFailClosed(rawConfig *config.Config, loadErr error, fleetConfigFile, uninstallToken string) error {
+	if rawConfig != nil {
+		return validateUninstallToken(rawConfig, uninstallToken)
+	}
+	if _, err := os.Stat(fleetConfigFile); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return fmt.Errorf("unable to read the configuration to validate the uninstall token: %w", loadErr)
+}
+
+// validateInstalledUninstallToken validates the uninstall token against the configuration of the installed
+// Elastic Agent. The token cannot be validated when the configuration is not readable, it's then an error.
+func validateInstalledUninstallToken(ctx context.Context, cfgFile, uninstallToken string, log *logp.Logger) error {
+	unprivileged, err := checkForUnprivilegedVault(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking for unprivileged vault: %w", err)
+	}
+	rawCfg, err := operations.LoadFullAgentConfig(ctx, log, cfgFile, false, unprivileged)
+	if err != nil {
+		return fmt.Errorf("unable to read the configuration to validate the uninstall token: %w", err)
+	}
+	return validateUninstallToken(rawCfg, uninstallToken)
+}
+
 // Injecting notifyFleetAuditUninstall for easier unit testing
 func notifyFleetIfNeeded(ctx context.Context, log *logp.Logger, pt *progressbar.ProgressBar, cfg *configuration.Configuration, agentID agentInfo, notifyFleet, localFleet, skipFleetAudit bool, notifyFleetAuditUninstall NotifyFleetAuditUninstall) {
 	if notifyFleet && !localFleet && !skipFleetAudit {
@@ -286,7 +366,7 @@ func RemovePath(path string) error {
 	start := time.Now()
 	var lastErr error
 	for time.Since(start) <= arbitraryTimeout {
-		lastErr = os.RemoveAll(path)
+		lastErr = os.RemoveAll(utils.FixLongPath(path))
 
 		if lastErr == nil || !isRetryableError(lastErr) {
 			return lastErr
@@ -447,16 +527,20 @@ func applyDynamics(ctx context.Context, log *logger.Logger, cfg *config.Config)
 	if err != nil {
 		return nil, err
 	}
+	if err := transpiler.ExpandPresets(ast); err != nil {
+		return nil, err
+	}
 
-	// apply dynamic inputs
-	inputs, ok := transpiler.Lookup(ast, "inputs")
-	if ok {
+	// apply dynamic inputs and input templates
+	_, hasInputs := transpiler.Lookup(ast, "inputs")
+	_, hasTemplates := transpiler.Lookup(ast, "input_templates")
+	if hasInputs || hasTemplates {
 		varsArray, err := vars.WaitForVariables(ctx, log, cfg, 0)
 		if err != nil {
 			return nil, err
 		}
 
-		renderedInputs, err := transpiler.RenderInputs(inputs, varsArray)
+		renderedInputs, _, err := transpiler.RenderAllInputs(ast, varsArray)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build freebsd
+
+package install
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// FindGID returns the group's GID on the machine.
+func FindGID(name string) (int, error) {
+	id, err := getentGetID("group", name)
+	if e := (&exec.ExitError{}); errors.As(err, &e) {
+		if e.ExitCode() == 2 {
+			// exit code 2 is the key doesn't exist in the database
+			return -1, ErrGroupNotFound
+		}
+	}
+	return id, err
+}
+
+// CreateGroup creates a group on the machine.
+func CreateGroup(name string) (int, error) {
+	gid, err := FindGID(name)
+	if err == nil {
+		// like groupadd -f on Linux, an existing group is not an error
+		return gid, nil
+	}
+	cmd := exec.Command("pw", "groupadd", "-n", name)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return -1, fmt.Errorf("pw groupadd -n %s failed: %w (output: %s)", name, err, output)
+	}
+	return FindGID(name)
+}
+
+// FindUID returns the user's UID on the machine.
+func FindUID(name string) (int, error) {
+	id, err := getentGetID("passwd", name)
+	if e := (&exec.ExitError{}); errors.As(err, &e) {
+		if e.ExitCode() == 2 {
+			// exit code 2 is the key doesn't exist in the database
+			return -1, ErrUserNotFound
+		}
+	}
+	return id, err
+}
+
+// CreateUser creates a user on the machine.
+func CreateUser(name string, gid int) (int, error) {
+	args := []string{
+		"useradd",
+		"-n", name,
+		"-g", strconv.Itoa(gid),
+		"-d", "/nonexistent",
+		"-s", "/usr/sbin/nologin",
+		"-c", "Elastic Agent",
+	}
+	cmd := exec.Command("pw", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		command := fmt.Sprintf("pw %s", strings.Join(args, " "))
+		return -1, fmt.Errorf("%s failed: %w (output: %s)", command, err, output)
+	}
+	return FindUID(name)
+}
+
+// AddUserToGroup adds a user to  a group.
+func AddUserToGroup(username string, groupName string) error {
+	cmd := exec.Command("pw", "groupmod", groupName, "-m", username)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("pw groupmod %s -m %s failed: %w (output: %s)", groupName, username, err, output)
+	}
+	return nil
+}
+
+func getentGetID(database string, key string) (int, error) {
+	cmd := exec.Command("getent", database, key)
+	output, err := cmd.Output()
+	if err != nil {
+		return -1, fmt.Errorf("getent %s %s failed: %w (output: %s)", database, key, err, output)
+	}
+	split := strings.Split(string(output), ":")
+	if len(split) < 3 {
+		return -1, fmt.Errorf("unexpected format: %s", output)
+	}
+	val, err := strconv.Atoi(split[2])
+	if err != nil {
+		return -1, fmt.Errorf("failed to convert %s to int: %w", split[2], err)
+	}
+	return val, nil
+}
+
+func EnsureRights(_ string) error { return nil }
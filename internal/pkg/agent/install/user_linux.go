@@ -28,6 +28,9 @@ func FindGID(name string) (int, error) {
 
 // CreateGroup creates a group on the machine.
 func CreateGroup(name string) (int, error) {
+	if !hasShadowUtils() {
+		return createGroupBusybox(name)
+	}
 	cmd := exec.Command("groupadd", "-f", name)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -50,6 +53,9 @@ func FindUID(name string) (int, error) {
 
 // CreateUser creates a user on the machine.
 func CreateUser(name string, gid int) (int, error) {
+	if !hasShadowUtils() {
+		return createUserBusybox(name, gid)
+	}
 	args := []string{
 		"--gid", strconv.Itoa(gid),
 		"--system",
@@ -68,6 +74,15 @@ func CreateUser(name string, gid int) (int, error) {
 
 // AddUserToGroup adds a user to  a group.
 func AddUserToGroup(username string, groupName string) error {
+	if !hasShadowUtils() {
+		// busybox addgroup adds an existing user to an existing group
+		cmd := exec.Command("addgroup", username, groupName)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("addgroup %s %s failed: %w (output: %s)", username, groupName, err, output)
+		}
+		return nil
+	}
 	cmd := exec.Command("usermod", "-a", "-G", groupName, username)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -76,6 +91,63 @@ func AddUserToGroup(username string, groupName string) error {
 	return nil
 }
 
+// hasShadowUtils returns true when the user management commands of shadow are installed, Alpine only ships the
+// commands of busybox by default.
+func hasShadowUtils() bool {
+	_, err := exec.LookPath("useradd")
+	return err == nil
+}
+
+func createGroupBusybox(name string) (int, error) {
+	gid, err := FindGID(name)
+	if err == nil {
+		// like groupadd -f, an existing group is not an error
+		return gid, nil
+	}
+	cmd := exec.Command("addgroup", "-S", name)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return -1, fmt.Errorf("addgroup -S %s failed: %w (output: %s)", name, err, output)
+	}
+	return FindGID(name)
+}
+
+func createUserBusybox(name string, gid int) (int, error) {
+	// busybox adduser takes the name of the group, not its GID
+	groupName, err := getentGetName("group", strconv.Itoa(gid))
+	if err != nil {
+		return -1, err
+	}
+	args := []string{
+		"-S",
+		"-D",
+		"-H",
+		"-G", groupName,
+		"-s", "/sbin/nologin",
+		name,
+	}
+	cmd := exec.Command("adduser", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		command := fmt.Sprintf("adduser %s", strings.Join(args, " "))
+		return -1, fmt.Errorf("%s failed: %w (output: %s)", command, err, output)
+	}
+	return FindUID(name)
+}
+
+func getentGetName(database string, key string) (string, error) {
+	cmd := exec.Command("getent", database, key)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("getent %s %s failed: %w (output: %s)", database, key, err, output)
+	}
+	split := strings.Split(string(output), ":")
+	if len(split) < 3 {
+		return "", fmt.Errorf("unexpected format: %s", output)
+	}
+	return split[0], nil
+}
+
 func getentGetID(database string, key string) (int, error) {
 	cmd := exec.Command("getent", database, key)
 	output, err := cmd.Output()
@@ -0,0 +1,28 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// SaveVaultBackend saves the backend of the vault of the installed Elastic Agent at topPath, before it is enrolled
+// and stores its secrets. The default backend is not saved.
+func SaveVaultBackend(topPath string, cfg vault.Config, ownership utils.FileOwner) error {
+	if cfg.IsDefault() {
+		return nil
+	}
+	vaultPath := filepath.Join(topPath, filepath.Base(paths.AgentVaultPath()))
+	if err := vault.SaveConfig(vaultPath, cfg); err != nil {
+		return fmt.Errorf("failed to save vault backend: %w", err)
+	}
+	return perms.FixPermissions(vaultPath, perms.WithOwnership(ownership))
+}
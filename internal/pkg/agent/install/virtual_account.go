@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	"crypto/sha1" //nolint:gosec // G505 the service SIDs are defined by Windows with SHA-1
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// virtualAccountDomain is the domain of the virtual service accounts of Windows.
+const virtualAccountDomain = `NT SERVICE\`
+
+// VirtualServiceAccount returns the virtual service account of the Elastic Agent service on Windows. The account
+// is managed by Windows: it has no password and exists as long as the service does.
+func VirtualServiceAccount() string {
+	return virtualAccountDomain + paths.ServiceName()
+}
+
+// IsVirtualServiceAccount returns true when the username is the virtual service account of the Elastic Agent
+// service. The virtual accounts of the other services are not, the ownership is always resolved from the SID of
+// the Elastic Agent service.
+func IsVirtualServiceAccount(username string) bool {
+	return strings.EqualFold(username, VirtualServiceAccount())
+}
+
+// VirtualServiceAccountSID returns the SID of the virtual service account of the service. Windows derives it
+// from the service name, so it is known before the service is installed and the account can be resolved.
+func VirtualServiceAccountSID(serviceName string) string {
+	name := utf16.Encode([]rune(strings.ToUpper(serviceName)))
+	buf := make([]byte, 2*len(name))
+	for i, c := range name {
+		binary.LittleEndian.PutUint16(buf[2*i:], c)
+	}
+	sum := sha1.Sum(buf) //nolint:gosec // G401 the service SIDs are defined by Windows with SHA-1
+
+	sid := "S-1-5-80"
+	for i := 0; i < len(sum); i += 4 {
+		sid += fmt.Sprintf("-%d", binary.LittleEndian.Uint32(sum[i:]))
+	}
+	return sid
+}
@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package install
+
+import (
+	"errors"
+
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// virtualServiceAccountOwnership fails, the virtual service accounts only exist on Windows.
+func virtualServiceAccountOwnership(_ utils.FileOwner) (utils.FileOwner, error) {
+	return utils.FileOwner{}, errors.New("virtual service accounts are only supported on Windows")
+}
@@ -0,0 +1,27 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package install
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtualServiceAccountSID(t *testing.T) {
+	// well-known SID of NT SERVICE\TrustedInstaller
+	assert.Equal(t, "S-1-5-80-956008885-3418522649-1831038044-1853292631-2271478464", VirtualServiceAccountSID("TrustedInstaller"))
+	// the service name is case insensitive
+	assert.Equal(t, VirtualServiceAccountSID("TrustedInstaller"), VirtualServiceAccountSID("trustedinstaller"))
+}
+
+func TestIsVirtualServiceAccount(t *testing.T) {
+	assert.True(t, IsVirtualServiceAccount(VirtualServiceAccount()))
+	assert.True(t, IsVirtualServiceAccount(strings.ToLower(VirtualServiceAccount())))
+	assert.False(t, IsVirtualServiceAccount(ElasticUsername))
+	assert.False(t, IsVirtualServiceAccount(`DOMAIN\elastic-agent-user`))
+	assert.False(t, IsVirtualServiceAccount(`NT SERVICE\TrustedInstaller`), "only the account of the Elastic Agent service is supported")
+}
@@ -0,0 +1,19 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package install
+
+import (
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// virtualServiceAccountOwnership sets the SID of the virtual service account as the owner, it is derived from
+// the service name as the account cannot be resolved before the service is installed.
+func virtualServiceAccountOwnership(ownership utils.FileOwner) (utils.FileOwner, error) {
+	ownership.UID = VirtualServiceAccountSID(paths.ServiceName())
+	return ownership, nil
+}
@@ -0,0 +1,102 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package perms
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+const (
+	// hardenedMarker is the file marking an install hardened with protected paths.
+	hardenedMarker = ".hardened"
+
+	componentsDir = "components"
+)
+
+// Harden marks the install at topPath as hardened and locks its protected paths, the Elastic Agent locks them
+// again each time it starts.
+func Harden(topPath string) error {
+	if err := os.WriteFile(filepath.Join(topPath, hardenedMarker), nil, 0o600); err != nil {
+		return fmt.Errorf("failed to write the hardened marker: %w", err)
+	}
+	return Lock(topPath)
+}
+
+// IsHardened returns true when the install at topPath was hardened with protected paths.
+func IsHardened(topPath string) bool {
+	_, err := os.Stat(filepath.Join(topPath, hardenedMarker))
+	return err == nil
+}
+
+// Lock protects the binaries and the components of every versioned home of the install at topPath from being
+// modified, even by root or Administrators. The state, the logs and the configuration are left writable.
+func Lock(topPath string) error {
+	roots, err := protectedPaths(topPath)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, root := range roots {
+		if err := lockPath(root); err != nil {
+			errs = append(errs, fmt.Errorf("failed to lock %q: %w", root, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Unlock removes the protection applied by Lock, so the install at topPath can be upgraded or removed.
+func Unlock(topPath string) error {
+	roots, err := protectedPaths(topPath)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, root := range roots {
+		if err := unlockPath(root); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unlock %q: %w", root, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// protectedPaths returns the binary and the components directory of every versioned home of the install at topPath.
+func protectedPaths(topPath string) ([]string, error) {
+	homes, err := filepath.Glob(filepath.Join(paths.DataFrom(topPath), "elastic-agent-*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the versioned homes: %w", err)
+	}
+	var roots []string
+	for _, home := range homes {
+		for _, root := range []string{filepath.Join(home, paths.BinaryName), filepath.Join(home, componentsDir)} {
+			if _, err := os.Lstat(root); err == nil {
+				roots = append(roots, root)
+			}
+		}
+	}
+	return roots, nil
+}
+
+// walkProtected calls fn on root and on every file and directory below it, symlinks are skipped as their
+// attributes cannot be changed.
+func walkProtected(root string, fn func(path string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
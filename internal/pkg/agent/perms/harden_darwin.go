@@ -0,0 +1,50 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build darwin
+
+package perms
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+func lockPath(root string) error {
+	return walkProtected(root, func(path string, _ fs.DirEntry) error {
+		return setImmutable(path, true)
+	})
+}
+
+func unlockPath(root string) error {
+	return walkProtected(root, func(path string, _ fs.DirEntry) error {
+		return setImmutable(path, false)
+	})
+}
+
+// setImmutable sets or clears the user immutable flag of the file, as `chflags uchg` does.
+func setImmutable(path string, immutable bool) error {
+	var stat unix.Stat_t
+	if err := unix.Lstat(path, &stat); err != nil {
+		return fmt.Errorf("failed to stat %q: %w", path, err)
+	}
+	updated := stat.Flags &^ unix.UF_IMMUTABLE
+	if immutable {
+		updated = stat.Flags | unix.UF_IMMUTABLE
+	}
+	if updated == stat.Flags {
+		return nil
+	}
+	err := unix.Chflags(path, int(updated))
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set the flags of %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package perms
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/unix"
+)
+
+// fsImmutableFL is the immutable attribute set by `chattr +i`.
+const fsImmutableFL = 0x00000010
+
+func lockPath(root string) error {
+	return walkProtected(root, func(path string, d fs.DirEntry) error {
+		return setImmutable(path, d, true)
+	})
+}
+
+func unlockPath(root string) error {
+	return walkProtected(root, func(path string, d fs.DirEntry) error {
+		return setImmutable(path, d, false)
+	})
+}
+
+// setImmutable sets or clears the immutable attribute of the file, file systems not supporting the attribute
+// are skipped.
+func setImmutable(path string, d fs.DirEntry, immutable bool) error {
+	if !d.IsDir() && !d.Type().IsRegular() {
+		return nil
+	}
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", path, err)
+	}
+	defer unix.Close(fd)
+
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if isUnsupported(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the attributes of %q: %w", path, err)
+	}
+	updated := flags &^ fsImmutableFL
+	if immutable {
+		updated = flags | fsImmutableFL
+	}
+	if updated == flags {
+		return nil
+	}
+	err = unix.IoctlSetPointerInt(fd, unix.FS_IOC_SETFLAGS, int(updated))
+	if isUnsupported(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set the attributes of %q: %w", path, err)
+	}
+	return nil
+}
+
+func isUnsupported(err error) bool {
+	return errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL)
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package perms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+func isImmutable(t *testing.T, path string) bool {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NONBLOCK, 0)
+	require.NoError(t, err)
+	defer unix.Close(fd)
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if isUnsupported(err) {
+		t.Skip("file system does not support the immutable attribute")
+	}
+	require.NoError(t, err)
+	return flags&fsImmutableFL != 0
+}
+
+func TestHardenLinux(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("setting the immutable attribute requires root")
+	}
+	topPath := newInstall(t)
+	t.Cleanup(func() {
+		_ = Unlock(topPath)
+	})
+
+	require.NoError(t, Harden(topPath))
+	assert.True(t, IsHardened(topPath))
+
+	home := filepath.Join(paths.DataFrom(topPath), "elastic-agent-1.0.0-abc")
+	beat := filepath.Join(home, componentsDir, "filebeat", "filebeat")
+	if !isImmutable(t, beat) {
+		t.Skip("file system ignores the immutable attribute")
+	}
+	assert.True(t, isImmutable(t, filepath.Join(home, paths.BinaryName)))
+	assert.True(t, isImmutable(t, filepath.Join(home, componentsDir)))
+	assert.False(t, isImmutable(t, filepath.Join(home, "logs")))
+	assert.Error(t, os.WriteFile(beat, []byte("replaced"), 0o750))
+	assert.Error(t, os.Remove(beat))
+
+	require.NoError(t, Unlock(topPath))
+	assert.False(t, isImmutable(t, beat))
+	require.NoError(t, os.RemoveAll(home))
+	// the marker is kept so the Elastic Agent locks the paths again when it starts
+	assert.True(t, IsHardened(topPath))
+}
@@ -0,0 +1,15 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux && !darwin && !windows
+
+package perms
+
+func lockPath(string) error {
+	return nil
+}
+
+func unlockPath(string) error {
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package perms
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// newInstall creates an install with two versioned homes under a temporary top path.
+func newInstall(t *testing.T) string {
+	topPath := t.TempDir()
+	for _, home := range []string{"elastic-agent-1.0.0-abc", "elastic-agent-1.1.0-def"} {
+		homePath := filepath.Join(paths.DataFrom(topPath), home)
+		require.NoError(t, os.MkdirAll(filepath.Join(homePath, componentsDir, "filebeat"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(homePath, paths.BinaryName), []byte("agent"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(homePath, componentsDir, "filebeat", "filebeat"), []byte("beat"), 0o750))
+		require.NoError(t, os.MkdirAll(filepath.Join(homePath, "logs"), 0o750))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(paths.DataFrom(topPath), "run"), 0o750))
+	return topPath
+}
+
+func TestProtectedPaths(t *testing.T) {
+	topPath := newInstall(t)
+
+	roots, err := protectedPaths(topPath)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(paths.DataFrom(topPath), "elastic-agent-1.0.0-abc", paths.BinaryName),
+		filepath.Join(paths.DataFrom(topPath), "elastic-agent-1.0.0-abc", componentsDir),
+		filepath.Join(paths.DataFrom(topPath), "elastic-agent-1.1.0-def", paths.BinaryName),
+		filepath.Join(paths.DataFrom(topPath), "elastic-agent-1.1.0-def", componentsDir),
+	}, roots)
+}
+
+func TestIsHardened(t *testing.T) {
+	topPath := newInstall(t)
+	assert.False(t, IsHardened(topPath))
+
+	require.NoError(t, os.WriteFile(filepath.Join(topPath, hardenedMarker), nil, 0o600))
+	assert.True(t, IsHardened(topPath))
+}
@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package perms
+
+import (
+	"fmt"
+	"io/fs"
+
+	"golang.org/x/sys/windows"
+
+	"github.com/elastic/elastic-agent/internal/pkg/acl"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const (
+	genericAll         = 0xF10F0000
+	genericReadExecute = windows.GENERIC_READ | windows.GENERIC_EXECUTE
+)
+
+// lockPath only leaves SYSTEM, the account the Elastic Agent service runs as, with the permission to modify the
+// files. Administrators can still read and execute them.
+func lockPath(root string) error {
+	systemSID, err := windows.StringToSid(utils.SystemSID)
+	if err != nil {
+		return fmt.Errorf("failed to get SYSTEM SID: %w", err)
+	}
+	administratorsSID, err := windows.StringToSid(utils.AdministratorSID)
+	if err != nil {
+		return fmt.Errorf("failed to get Administrators SID: %w", err)
+	}
+	grants := []acl.ExplicitAccess{
+		acl.GrantSid(genericAll, systemSID),
+		acl.GrantSid(genericReadExecute, administratorsSID),
+	}
+	return walkProtected(root, func(path string, _ fs.DirEntry) error {
+		// first level doesn't inherit
+		return applyPermissions(path, true, path != root, nil, nil, grants...)
+	})
+}
+
+// unlockPath restores the permissions applied at install.
+func unlockPath(root string) error {
+	return FixPermissions(root)
+}
@@ -0,0 +1,18 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package perms
+
+import (
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+// fixRoots returns the paths FixPermissions walks for topPath. The relocated data directory of an install is
+// walked as well, the walk does not follow the symlink to it.
+func fixRoots(topPath string) []string {
+	if relocated, ok := paths.RelocatedDataFrom(topPath); ok {
+		return []string{topPath, relocated}
+	}
+	return []string{topPath}
+}
@@ -20,6 +20,15 @@ func FixPermissions(topPath string, opts ...OptFunc) error {
 	if err != nil {
 		return err
 	}
+	for _, root := range fixRoots(topPath) {
+		if err := fixPermissions(root, o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fixPermissions(topPath string, o *opts) error {
 	return filepath.Walk(topPath, func(name string, info fs.FileInfo, err error) error {
 		if errors.Is(err, fs.ErrNotExist) {
 			return nil
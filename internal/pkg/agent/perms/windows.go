@@ -80,28 +80,31 @@ func FixPermissions(topPath string, opts ...OptFunc) error {
 	if isAdmin {
 		// since we are running as Administrator, we will change the ownership which requires SeRestorePrivilege
 		return winio.RunWithPrivileges([]string{winio.SeRestorePrivilege}, func() error {
-			return filepath.WalkDir(topPath, func(walkPath string, _ fs.DirEntry, err error) error {
-				switch {
-				case err == nil:
-					// first level doesn't inherit
-					inherit := topPath != walkPath
-					return applyPermissions(walkPath, true, inherit, userSID, groupSID, grants...)
-				case errors.Is(err, fs.ErrNotExist):
-					return nil
-				default:
+			for _, root := range fixRoots(topPath) {
+				if err := fixPermissions(root, userSID, groupSID, grants); err != nil {
 					return err
 				}
-			})
+			}
+			return nil
 		})
 	}
 
 	// ownership cannot be changed, this will keep the ownership as it currently is but apply the ACL's
+	for _, root := range fixRoots(topPath) {
+		if err := fixPermissions(root, nil, nil, grants); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fixPermissions(topPath string, userSID *windows.SID, groupSID *windows.SID, grants []acl.ExplicitAccess) error {
 	return filepath.WalkDir(topPath, func(walkPath string, _ fs.DirEntry, err error) error {
 		switch {
 		case err == nil:
 			// first level doesn't inherit
 			inherit := topPath != walkPath
-			return applyPermissions(walkPath, true, inherit, nil, nil, grants...)
+			return applyPermissions(walkPath, true, inherit, userSID, groupSID, grants...)
 		case errors.Is(err, fs.ErrNotExist):
 			return nil
 		default:
@@ -115,11 +118,13 @@ func FixPermissions(topPath string, opts ...OptFunc) error {
 // If you are not running as Administrator, pass nil for userSID and/or groupSID. Note that windows.ERROR_FILE_NOT_FOUND and
 // windows.ERROR_PATH_NOT_FOUND are explicitly ignored.
 func applyPermissions(path string, replace bool, inherit bool, userSID *windows.SID, groupSID *windows.SID, entries ...acl.ExplicitAccess) error {
-	if err := acl.Apply(path, replace, inherit, entries...); err != nil {
+	// the ACL API doesn't handle paths longer than MAX_PATH unless they are extended-length paths
+	longPath := utils.FixLongPath(path)
+	if err := acl.Apply(longPath, replace, inherit, entries...); err != nil {
 		return filterNotFoundErrno(fmt.Errorf("apply ACL for %s failed: %w", path, err))
 	}
 	if userSID != nil && groupSID != nil {
-		if err := acl.TakeOwnership(path, userSID, groupSID); err != nil {
+		if err := acl.TakeOwnership(longPath, userSID, groupSID); err != nil {
 			return filterNotFoundErrno(fmt.Errorf("take ownership for %s failed: %w", path, err))
 		}
 	}
@@ -0,0 +1,44 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package protection
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	ErrUninstallTokenRequired = errors.New("the uninstall token is required, the Elastic Agent is tamper protected")
+	ErrInvalidUninstallToken  = errors.New("invalid uninstall token")
+)
+
+// UninstallTokenRequired returns true when the policy protects the Elastic Agent with an uninstall token.
+func (c Config) UninstallTokenRequired() bool {
+	return c.Enabled && c.UninstallTokenHash != ""
+}
+
+// HashUninstallToken returns the hash of the uninstall token, in the format of the uninstall_token_hash sent
+// by Fleet: the base64 encoded SHA-256 of the token.
+func HashUninstallToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// ValidateUninstallToken validates the uninstall token against the hash of the protection configuration,
+// any token is valid when the Elastic Agent is not protected.
+func ValidateUninstallToken(cfg Config, token string) error {
+	if !cfg.UninstallTokenRequired() {
+		return nil
+	}
+	if token == "" {
+		return ErrUninstallTokenRequired
+	}
+	if subtle.ConstantTimeCompare([]byte(HashUninstallToken(token)), []byte(cfg.UninstallTokenHash)) != 1 {
+		return ErrInvalidUninstallToken
+	}
+	return nil
+}
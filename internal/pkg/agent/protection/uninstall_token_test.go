@@ -0,0 +1,48 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package protection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateUninstallToken(t *testing.T) {
+	// hash of "token" as sent by Fleet
+	const hash = "PEaenWxYddN6Q/NT1PiOYfz4EsZu7jRXRlpAsNpBU+A="
+	assert.Equal(t, hash, HashUninstallToken("token"))
+
+	tests := map[string]struct {
+		cfg   Config
+		token string
+		err   error
+	}{
+		"not protected": {
+			cfg: Config{UninstallTokenHash: hash},
+		},
+		"protected without a hash": {
+			cfg: Config{Enabled: true},
+		},
+		"missing token": {
+			cfg: Config{Enabled: true, UninstallTokenHash: hash},
+			err: ErrUninstallTokenRequired,
+		},
+		"invalid token": {
+			cfg:   Config{Enabled: true, UninstallTokenHash: hash},
+			token: "other",
+			err:   ErrInvalidUninstallToken,
+		},
+		"valid token": {
+			cfg:   Config{Enabled: true, UninstallTokenHash: hash},
+			token: "token",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, ValidateUninstallToken(tc.cfg, tc.token), tc.err)
+		})
+	}
+}
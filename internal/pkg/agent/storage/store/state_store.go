@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"slices"
 	"sync"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/storage"
@@ -23,6 +24,10 @@ import (
 // introduced, it should be increased and a migration added.
 const Version = "1"
 
+// maxExecutedActions is the number of executed action IDs kept to recognize
+// actions Fleet delivers again because their ack was not committed.
+const maxExecutedActions = 100
+
 type saver interface {
 	Save(io.Reader) error
 }
@@ -36,6 +41,8 @@ type saveLoader interface {
 //   - the last fleet action (not all actions are stored, refer to Save for details)
 //   - a queue of scheduled actions
 //   - the ack token
+//   - the actions delivered by Fleet that did not complete yet, and the IDs
+//     of the last executed actions, so actions are executed once across restarts
 //
 // See each method documentation for details.
 type StateStore struct {
@@ -52,6 +59,16 @@ type state struct {
 	ActionSerializer actionSerializer `json:"action,omitempty"`
 	AckToken         string           `json:"ack_token,omitempty"`
 	Queue            actionQueue      `json:"action_queue,omitempty"`
+	Pending          []pendingAction  `json:"pending_actions,omitempty"`
+	Executed         []string         `json:"executed_actions,omitempty"`
+}
+
+// pendingAction is an action delivered by Fleet which did not complete yet.
+// Started is set once its handler is called, an action that started but did
+// not complete was interrupted and cannot safely run again.
+type pendingAction struct {
+	ActionSerializer actionSerializer `json:"action"`
+	Started          bool             `json:"started,omitempty"`
 }
 
 // actionSerializer is JSON Marshaler/Unmarshaler for fleetapi.Action.
@@ -212,12 +229,112 @@ func (s *StateStore) SetAckToken(ackToken string) {
 	s.state.AckToken = ackToken
 }
 
-// SetQueue sets the action_queue to agent state
+// SetQueue sets the action_queue to agent state. The queued actions are no
+// longer pending, the queue itself persists them.
 func (s *StateStore) SetQueue(q []fleetapi.ScheduledAction) {
 	s.mx.Lock()
 	defer s.mx.Unlock()
 	s.state.Queue = q
+	for _, a := range q {
+		s.removePending(a.ID())
+	}
+	s.dirty = true
+}
+
+// AddPendingActions records actions delivered by Fleet until they complete.
+// Actions that are already pending or were already executed are ignored.
+func (s *StateStore) AddPendingActions(actions ...fleetapi.Action) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	for _, a := range actions {
+		if s.pendingIndex(a.ID()) >= 0 || slices.Contains(s.state.Executed, a.ID()) {
+			continue
+		}
+		s.state.Pending = append(s.state.Pending, pendingAction{ActionSerializer: actionSerializer{Action: a}})
+		s.dirty = true
+	}
+}
+
+// StartPendingAction marks the pending action as started, it is added as
+// pending if it is not, e.g. when it comes from the queue.
+func (s *StateStore) StartPendingAction(a fleetapi.Action) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	idx := s.pendingIndex(a.ID())
+	if idx < 0 {
+		s.state.Pending = append(s.state.Pending, pendingAction{ActionSerializer: actionSerializer{Action: a}})
+		idx = len(s.state.Pending) - 1
+	}
+	s.state.Pending[idx].Started = true
+	s.dirty = true
+}
+
+// CompletePendingAction removes the action from the pending actions. When
+// executed is true, the action ID is kept so the action is not executed again
+// if Fleet delivers it again.
+func (s *StateStore) CompletePendingAction(actionID string, executed bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	s.removePending(actionID)
+	if executed && !slices.Contains(s.state.Executed, actionID) {
+		s.state.Executed = append(s.state.Executed, actionID)
+		if len(s.state.Executed) > maxExecutedActions {
+			s.state.Executed = s.state.Executed[len(s.state.Executed)-maxExecutedActions:]
+		}
+	}
+	s.dirty = true
+}
+
+// PendingActions returns the pending actions which did not start, and the
+// ones which started but did not complete.
+func (s *StateStore) PendingActions() (pending []fleetapi.Action, interrupted []fleetapi.Action) {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+
+	for _, p := range s.state.Pending {
+		if p.Started {
+			interrupted = append(interrupted, p.ActionSerializer.Action)
+			continue
+		}
+		pending = append(pending, p.ActionSerializer.Action)
+	}
+	return pending, interrupted
+}
+
+// ActionExecuted returns true if the action was already executed.
+func (s *StateStore) ActionExecuted(actionID string) bool {
+	s.mx.RLock()
+	defer s.mx.RUnlock()
+	return slices.Contains(s.state.Executed, actionID)
+}
+
+func (s *StateStore) pendingIndex(actionID string) int {
+	return slices.IndexFunc(s.state.Pending, func(p pendingAction) bool {
+		return p.ActionSerializer.Action.ID() == actionID
+	})
+}
+
+func (s *StateStore) removePending(actionID string) {
+	s.state.Pending = slices.DeleteFunc(s.state.Pending, func(p pendingAction) bool {
+		return p.ActionSerializer.Action.ID() == actionID
+	})
+}
+
+// ResetForMigration clears the state bound to the Fleet cluster the agent migrates away from:
+// the ack token, the scheduled and the pending actions. The current policy is kept, so the agent keeps
+// running it until the target cluster sends a new one.
+func (s *StateStore) ResetForMigration() error {
+	s.mx.Lock()
+	s.state.AckToken = ""
+	s.state.Queue = nil
+	s.state.Pending = nil
 	s.dirty = true
+	s.mx.Unlock()
+
+	return s.Save()
 }
 
 // Save saves the actions into the state store. If the action type is not
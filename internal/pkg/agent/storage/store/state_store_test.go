@@ -6,6 +6,7 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -426,6 +427,107 @@ func runTestStateStore(t *testing.T, ackToken string) {
 		}
 	})
 
+	t.Run("reset for migration keeps the policy only", func(t *testing.T) {
+		actionPolicyChange := &fleetapi.ActionPolicyChange{
+			ActionID:   "abc123",
+			ActionType: fleetapi.ActionTypePolicyChange,
+			Data: fleetapi.ActionPolicyChangeData{
+				Policy: map[string]interface{}{"hello": "world"},
+			},
+		}
+		queue := []fleetapi.ScheduledAction{&fleetapi.ActionUpgrade{
+			ActionID:        "test",
+			ActionType:      fleetapi.ActionTypeUpgrade,
+			ActionStartTime: time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+			Data: fleetapi.ActionUpgradeData{
+				Version: "1.2.3",
+			}}}
+
+		storePath := filepath.Join(t.TempDir(), "state.json")
+		s, err := storage.NewDiskStore(storePath)
+		require.NoError(t, err, "failed creating DiskStore")
+
+		store, err := NewStateStore(log, s)
+		require.NoError(t, err)
+		store.SetAction(actionPolicyChange)
+		store.SetAckToken(ackToken)
+		store.SetQueue(queue)
+		require.NoError(t, store.Save())
+
+		require.NoError(t, store.ResetForMigration())
+
+		// Load state store from disk
+		s, err = storage.NewDiskStore(storePath)
+		require.NoError(t, err, "failed creating DiskStore")
+		store, err = NewStateStore(log, s)
+		require.NoError(t, err, "could not load store from disk")
+
+		assert.Equal(t, actionPolicyChange, store.Action(), "the policy should be kept")
+		assert.Empty(t, store.AckToken())
+		assert.Empty(t, store.Queue())
+	})
+
+	t.Run("pending actions are kept across restarts until they complete", func(t *testing.T) {
+		unenroll := &fleetapi.ActionUnenroll{ActionID: "unenroll", ActionType: fleetapi.ActionTypeUnenroll}
+		settings := &fleetapi.ActionSettings{ActionID: "settings", ActionType: fleetapi.ActionTypeSettings}
+		upgrade := &fleetapi.ActionUpgrade{
+			ActionID:        "upgrade",
+			ActionType:      fleetapi.ActionTypeUpgrade,
+			ActionStartTime: time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+		}
+
+		storePath := filepath.Join(t.TempDir(), "state.json")
+		s, err := storage.NewDiskStore(storePath)
+		require.NoError(t, err, "failed creating DiskStore")
+
+		store, err := NewStateStore(log, s)
+		require.NoError(t, err)
+		store.AddPendingActions(unenroll, settings, upgrade)
+		store.AddPendingActions(settings)
+		store.StartPendingAction(settings)
+		// queued actions are persisted by the queue
+		store.SetQueue([]fleetapi.ScheduledAction{upgrade})
+		require.NoError(t, store.Save())
+
+		// Load state store from disk
+		s, err = storage.NewDiskStore(storePath)
+		require.NoError(t, err, "failed creating DiskStore")
+		store, err = NewStateStore(log, s)
+		require.NoError(t, err, "could not load store from disk")
+
+		pending, interrupted := store.PendingActions()
+		assert.Equal(t, []fleetapi.Action{unenroll}, pending)
+		assert.Equal(t, []fleetapi.Action{settings}, interrupted)
+
+		store.CompletePendingAction(unenroll.ID(), true)
+		store.CompletePendingAction(settings.ID(), false)
+		require.NoError(t, store.Save())
+		pending, interrupted = store.PendingActions()
+		assert.Empty(t, pending)
+		assert.Empty(t, interrupted)
+		assert.True(t, store.ActionExecuted(unenroll.ID()))
+		assert.False(t, store.ActionExecuted(settings.ID()))
+
+		// an executed action delivered again is not pending
+		store.AddPendingActions(unenroll)
+		pending, _ = store.PendingActions()
+		assert.Empty(t, pending)
+	})
+
+	t.Run("executed actions are bounded", func(t *testing.T) {
+		s, err := storage.NewDiskStore(filepath.Join(t.TempDir(), "state.json"))
+		require.NoError(t, err, "failed creating DiskStore")
+		store, err := NewStateStore(log, s)
+		require.NoError(t, err)
+
+		for i := 0; i <= maxExecutedActions; i++ {
+			store.CompletePendingAction(fmt.Sprintf("action-%d", i), true)
+		}
+		assert.False(t, store.ActionExecuted("action-0"))
+		assert.True(t, store.ActionExecuted("action-1"))
+		assert.True(t, store.ActionExecuted(fmt.Sprintf("action-%d", maxExecutedActions)))
+	})
+
 	t.Run("when we ACK we save to disk", func(t *testing.T) {
 		ActionPolicyChange := &fleetapi.ActionPolicyChange{
 			ActionID: "abc123",
@@ -9,17 +9,19 @@ import (
 	"encoding/base64"
 	"fmt"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 
-	"github.com/cespare/xxhash/v2"
-
 	"github.com/elastic/elastic-agent/internal/pkg/eql"
 )
 
 const (
 	selectorSep = "."
+
+	// listAppendIndex is the list index of a selector that appends to the list, e.g. `inputs[-]`.
+	listAppendIndex = "-"
 	// conditionKey is the name of the reserved key that will be computed using EQL to a boolean result.
 	//
 	// This makes the key "condition" inside of a dictionary a reserved name.
@@ -59,8 +61,8 @@ type Node interface {
 	// Hash compute a sha256 hash of the current node and recursively call any children.
 	Hash() []byte
 
-	// Hash64With recursively computes the given hash for the Node and its children
-	Hash64With(h *xxhash.Digest) error
+	// Hash64With recursively computes the given 64-bit hash for the Node and its children
+	Hash64With(h Hasher64) error
 
 	// Vars adds to the array with the variables identified in the node. Returns the array in-case
 	// the capacity of the array had to be changed.
@@ -86,7 +88,8 @@ func (a *AST) String() string {
 }
 
 // Dict represents a dictionary in the Tree, where each key is a entry into an array. The Dict will
-// keep the ordering.
+// keep the ordering: the keys of a Dict loaded from a map are sorted, inserted keys are appended. The
+// ordering must not be relied on to compare dictionaries, see AST.Normalize.
 type Dict struct {
 	value      []Node
 	processors []map[string]interface{}
@@ -181,7 +184,7 @@ func (d *Dict) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (d *Dict) Hash64With(h *xxhash.Digest) error {
+func (d *Dict) Hash64With(h Hasher64) error {
 	for _, v := range d.value {
 		if v == nil {
 			continue
@@ -328,7 +331,7 @@ func (k *Key) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (k *Key) Hash64With(h *xxhash.Digest) error {
+func (k *Key) Hash64With(h Hasher64) error {
 	if _, err := h.WriteString(k.name); err != nil {
 		return err
 	}
@@ -435,7 +438,7 @@ func (l *List) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (l *List) Hash64With(h *xxhash.Digest) error {
+func (l *List) Hash64With(h Hasher64) error {
 	for _, v := range l.value {
 		if v == nil {
 			continue
@@ -585,7 +588,7 @@ func (s *StrVal) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (s *StrVal) Hash64With(h *xxhash.Digest) error {
+func (s *StrVal) Hash64With(h Hasher64) error {
 	_, err := h.WriteString(s.value)
 	return err
 }
@@ -667,7 +670,7 @@ func (s *IntVal) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (s *IntVal) Hash64With(h *xxhash.Digest) error {
+func (s *IntVal) Hash64With(h Hasher64) error {
 	_, err := h.WriteString(s.String())
 	return err
 }
@@ -724,7 +727,7 @@ func (s *UIntVal) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (s *UIntVal) Hash64With(h *xxhash.Digest) error {
+func (s *UIntVal) Hash64With(h Hasher64) error {
 	_, err := h.WriteString(s.String())
 	return err
 }
@@ -792,7 +795,7 @@ func (s *FloatVal) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (s *FloatVal) Hash64With(h *xxhash.Digest) error {
+func (s *FloatVal) Hash64With(h Hasher64) error {
 	_, err := h.WriteString(s.hashString())
 	return err
 }
@@ -870,7 +873,7 @@ func (s *BoolVal) Hash() []byte {
 }
 
 // Hash64With recursively computes the given hash for the Node and its children
-func (s *BoolVal) Hash64With(h *xxhash.Digest) error {
+func (s *BoolVal) Hash64With(h Hasher64) error {
 	var encodedBool []byte
 	if s.value {
 		encodedBool = trueVal
@@ -996,33 +999,27 @@ func (a *AST) ShallowClone() *AST {
 	return &AST{root: a.root.ShallowClone()}
 }
 
-// Hash calculates a hash from all the included nodes in the tree.
+// Hash calculates a sha256 hash from all the included nodes of the canonical form of the tree, see
+// HashWith to select another algorithm.
 func (a *AST) Hash() []byte {
-	return a.root.Hash()
+	return a.hashWith(HashSHA256)
 }
 
-// Hash64With recursively computes the given hash for the Node and its children
-func (a *AST) Hash64With(h *xxhash.Digest) error {
+// Hash64With recursively computes the given hash for the Node and its children. The hash depends on
+// the order of the keys, use Normalize first to compute the hash of the canonical form.
+func (a *AST) Hash64With(h Hasher64) error {
 	return a.root.Hash64With(h)
 }
 
-// HashStr return the calculated hash as a base64 url encoded string.
+// HashStr return the calculated hash of the canonical form as a base64 url encoded string.
 func (a *AST) HashStr() string {
-	return base64.URLEncoding.EncodeToString(a.root.Hash())
+	return base64.URLEncoding.EncodeToString(a.Hash())
 }
 
-// Equal check if two AST are equals by using the computed hash.
+// Equal check if two AST are equals by using the computed xxhash64 hash of their canonical forms, see
+// EqualWith to select another algorithm.
 func (a *AST) Equal(other *AST) bool {
-	if a.root == nil || other.root == nil {
-		return a.root == other.root
-	}
-	hasher := xxhash.New()
-	_ = a.Hash64With(hasher)
-	thisHash := hasher.Sum64()
-	hasher.Reset()
-	_ = other.Hash64With(hasher)
-	otherHash := hasher.Sum64()
-	return thisHash == otherHash
+	return a.equalWith(other, HashXXHash64)
 }
 
 // Lookup looks for a value from the AST.
@@ -1045,6 +1042,50 @@ func (a *AST) Lookup(name string) (interface{}, bool) {
 	return m.Content, true
 }
 
+// UsesTime returns true when a condition of the AST calls a time function of EQL, e.g. hour(), the result of
+// the condition then changes over time and the AST must be rendered again periodically.
+func (a *AST) UsesTime() bool {
+	if a == nil {
+		return false
+	}
+	return usesTime(a.root)
+}
+
+func usesTime(node Node) bool {
+	switch n := node.(type) {
+	case *Dict:
+		for _, v := range n.value {
+			if usesTime(v) {
+				return true
+			}
+		}
+	case *List:
+		for _, v := range n.value {
+			if usesTime(v) {
+				return true
+			}
+		}
+	case *Key:
+		if n.name != conditionKey {
+			return usesTime(n.value)
+		}
+		v, ok := n.value.(*StrVal)
+		if !ok {
+			return false
+		}
+		if n.condition == nil {
+			condition, err := eql.New(v.value)
+			if err != nil {
+				// reported when the AST is rendered
+				return false
+			}
+			n.condition = condition
+		}
+		return n.condition.UsesTime()
+	}
+	return false
+}
+
 func splitPath(s Selector) []string {
 	if s == "" {
 		return nil
@@ -1202,10 +1243,21 @@ func (a *AST) Insert(b *AST, to Selector) error {
 
 // Insert inserts a node into an existing AST, will return and error if the target position cannot
 // accept a new node.
+//
+// The last part of the selector can target a position in a list, `inputs[-]` appends the node to the
+// list and `inputs[3]` inserts the node at index 3 shifting the following entries. The list is created
+// when it doesn't exist. Other parts of the selector can use `inputs[3]` as an alias of `inputs.3`.
 func Insert(a *AST, node Node, to Selector) error {
+	parts := splitIndexedPath(to)
+	if len(parts) > 0 {
+		if name, idx, ok := splitListIndex(parts[len(parts)-1]); ok {
+			return insertListItem(a, node, append(parts[:len(parts)-1], name), idx)
+		}
+	}
+
 	current := a.root
 
-	for _, part := range splitPath(to) {
+	for _, part := range parts {
 		n, ok := current.Find(part)
 		if !ok {
 			switch t := current.(type) {
@@ -1286,6 +1338,77 @@ func Insert(a *AST, node Node, to Selector) error {
 	return nil
 }
 
+// splitIndexedPath splits the selector into its parts, expanding `name[3]` into `name` and `3` for every
+// part but the last one.
+func splitIndexedPath(s Selector) []string {
+	parts := splitPath(s)
+	expanded := make([]string, 0, len(parts))
+	for i, part := range parts {
+		if i < len(parts)-1 {
+			if name, idx, ok := splitListIndex(part); ok && idx != listAppendIndex {
+				expanded = append(expanded, name, idx)
+				continue
+			}
+		}
+		expanded = append(expanded, part)
+	}
+	return expanded
+}
+
+// splitListIndex splits `name[idx]` into the name and the index.
+func splitListIndex(part string) (string, string, bool) {
+	if !strings.HasSuffix(part, "]") {
+		return "", "", false
+	}
+	start := strings.LastIndex(part, "[")
+	if start <= 0 {
+		return "", "", false
+	}
+	return part[:start], part[start+1 : len(part)-1], true
+}
+
+// insertListItem inserts the node into the list at the path, creating the list when it doesn't exist.
+func insertListItem(a *AST, node Node, path []string, idx string) error {
+	selector := strings.Join(path, selectorSep)
+	target, ok := Lookup(a, selector)
+	if !ok {
+		if err := Insert(a, &List{}, selector); err != nil {
+			return err
+		}
+		target, _ = Lookup(a, selector)
+	}
+	key, ok := target.(*Key)
+	if !ok {
+		return fmt.Errorf("expecting Key and received %T for '%s'", target, selector)
+	}
+	if key.value == nil {
+		key.value = &List{}
+	}
+	list, ok := key.value.(*List)
+	if !ok {
+		return fmt.Errorf("expecting List and received %T for '%s'", key.value, selector)
+	}
+
+	if k, ok := node.(*Key); ok {
+		// list entries cannot be keys
+		node = &Dict{value: []Node{k}}
+	}
+
+	if idx == listAppendIndex {
+		list.value = append(list.value, node)
+		return nil
+	}
+	i, err := strconv.Atoi(idx)
+	if err != nil {
+		return fmt.Errorf("invalid list index '%s' for '%s': %w", idx, selector, err)
+	}
+	if i < 0 || i > len(list.value) {
+		return fmt.Errorf("list index %d out of range for '%s' with %d entries", i, selector, len(list.value))
+	}
+	list.value = slices.Insert(list.value, i, node)
+	return nil
+}
+
 // Map transforms the AST into a map[string]interface{} and will abort and return any errors related
 // to type conversion.
 func (a *AST) Map() (map[string]interface{}, error) {
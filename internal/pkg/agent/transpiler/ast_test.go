@@ -396,6 +396,154 @@ func TestAST(t *testing.T) {
 	})
 }
 
+func TestInsertListIndex(t *testing.T) {
+	testcases := map[string]struct {
+		hashmap  map[string]interface{}
+		selector Selector
+		node     Node
+		expected map[string]interface{}
+		err      bool
+	}{
+		"append": {
+			selector: "inputs[-]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("c"))}),
+			hashmap: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+					map[string]interface{}{"id": "c"},
+				},
+			},
+		},
+		"append creates list": {
+			selector: "outputs.default.hosts[-]",
+			node:     NewStrVal("localhost:9200"),
+			hashmap: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{"type": "elasticsearch"},
+				},
+			},
+			expected: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":  "elasticsearch",
+						"hosts": []interface{}{"localhost:9200"},
+					},
+				},
+			},
+		},
+		"insert at position": {
+			selector: "inputs[1]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("c"))}),
+			hashmap: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "c"},
+					map[string]interface{}{"id": "b"},
+				},
+			},
+		},
+		"insert at end": {
+			selector: "inputs[2]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("c"))}),
+			hashmap: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+					map[string]interface{}{"id": "c"},
+				},
+			},
+		},
+		"nested list index": {
+			selector: "inputs[0].streams[-]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("s2"))}),
+			hashmap: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id": "a",
+						"streams": []interface{}{
+							map[string]interface{}{"id": "s1"},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id": "a",
+						"streams": []interface{}{
+							map[string]interface{}{"id": "s1"},
+							map[string]interface{}{"id": "s2"},
+						},
+					},
+				},
+			},
+		},
+		"index out of range": {
+			selector: "inputs[3]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("c"))}),
+			hashmap: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "a"},
+				},
+			},
+			err: true,
+		},
+		"invalid index": {
+			selector: "inputs[first]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("c"))}),
+			hashmap: map[string]interface{}{
+				"inputs": []interface{}{},
+			},
+			err: true,
+		},
+		"not a list": {
+			selector: "outputs[-]",
+			node:     NewDict([]Node{NewKey("id", NewStrVal("c"))}),
+			hashmap: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{"type": "elasticsearch"},
+				},
+			},
+			err: true,
+		},
+	}
+
+	for name, test := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ast, err := NewAST(test.hashmap)
+			require.NoError(t, err)
+			err = Insert(ast, test.node, test.selector)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			m, err := ast.Map()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, m)
+		})
+	}
+}
+
 func TestInsert(t *testing.T) {
 	testcases := map[string]struct {
 		hashmap  map[string]interface{}
@@ -1208,6 +1356,54 @@ func TestCondition(t *testing.T) {
 	assert.Nil(t, input2.condition)
 }
 
+func TestUsesTime(t *testing.T) {
+	testcases := map[string]struct {
+		cfg      map[string]interface{}
+		expected bool
+	}{
+		"no condition": {
+			cfg: map[string]interface{}{
+				"inputs": []interface{}{map[string]interface{}{"type": "logfile"}},
+			},
+		},
+		"condition without time": {
+			cfg: map[string]interface{}{
+				"inputs": []interface{}{map[string]interface{}{
+					"type":      "logfile",
+					"condition": "${host.name} == 'hour()'",
+				}},
+			},
+		},
+		"input condition with time": {
+			cfg: map[string]interface{}{
+				"inputs": []interface{}{map[string]interface{}{
+					"type":      "logfile",
+					"condition": "hour('Europe/Paris') >= 9 and hour('Europe/Paris') < 17",
+				}},
+			},
+			expected: true,
+		},
+		"stream condition with time": {
+			cfg: map[string]interface{}{
+				"inputs": []interface{}{map[string]interface{}{
+					"type": "logfile",
+					"streams": []interface{}{map[string]interface{}{
+						"condition": "dayOfWeek() <= 5",
+					}},
+				}},
+			},
+			expected: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ast, err := NewAST(tc.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ast.UsesTime())
+		})
+	}
+}
+
 // check that all the methods handle nil values correctly
 func TestNullValues(t *testing.T) {
 	cfgMap := map[string]any{
@@ -0,0 +1,75 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"fmt"
+)
+
+// RenderComponents renders the agent.components section, the environment, working directory and arguments of
+// the components.
+//
+// Like the outputs the components are only rendered using the context variables. The agent section is replaced by
+// a copy in the AST, the AST it was shallow cloned from is not modified.
+func RenderComponents(a *AST, varsArray []*Vars) error {
+	if len(varsArray) == 0 {
+		// no context vars (nothing to do)
+		return nil
+	}
+	vars := varsArray[0]
+
+	root, ok := a.root.(*Dict)
+	if !ok {
+		return nil
+	}
+	agentNode, ok := root.Find("agent")
+	if !ok {
+		return nil
+	}
+	agent := agentNode.(*Key)
+	agentDict, ok := agent.value.(*Dict)
+	if !ok {
+		return nil
+	}
+	componentsNode, ok := agentDict.Find("components")
+	if !ok {
+		return nil
+	}
+	components := componentsNode.(*Key)
+	d, ok := components.value.(*Dict)
+	if !ok {
+		return fmt.Errorf("agent.components must be an dict, got %T instead", components.value)
+	}
+
+	nodes := d.Value().([]Node)
+	keys := make([]Node, len(nodes))
+	for i, node := range nodes {
+		key, ok := node.(*Key)
+		if !ok || key.value == nil {
+			// not possible, but be defensive
+			keys[i] = node
+			continue
+		}
+		// Apply creates a new Node with a deep copy of all the values, like the outputs
+		// a variable that doesn't match is an error
+		value, err := key.value.Apply(vars)
+		if err != nil {
+			return fmt.Errorf("rendering component %q failed: %w", key.name, err)
+		}
+		keys[i] = &Key{
+			name:  key.name,
+			value: value,
+		}
+	}
+
+	rendered := agentDict.ShallowClone().(*Dict)
+	for i, node := range rendered.value {
+		if node.(*Key).name == "components" {
+			rendered.value[i] = &Key{name: "components", value: &Dict{keys, nil}}
+		}
+	}
+	agent.value = rendered
+	return nil
+}
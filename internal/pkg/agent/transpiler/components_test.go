@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderComponents(t *testing.T) {
+	ast, err := NewAST(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"logging": map[string]interface{}{"level": "${env.LEVEL}"},
+			"components": map[string]interface{}{
+				"filestream": map[string]interface{}{
+					"env":  map[string]interface{}{"JAVA_HOME": "${env.JAVA_HOME}"},
+					"args": []interface{}{"--site", "${host.site|'default'}"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	vars := mustMakeVars(map[string]interface{}{
+		"env":  map[string]interface{}{"JAVA_HOME": "/usr/lib/jvm/java-17", "LEVEL": "debug"},
+		"host": map[string]interface{}{},
+	})
+
+	rendered := ast.ShallowClone()
+	require.NoError(t, RenderComponents(rendered, []*Vars{vars}))
+	m, err := rendered.Map()
+	require.NoError(t, err)
+	agent := m["agent"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"filestream": map[string]interface{}{
+			"env":  map[string]interface{}{"JAVA_HOME": "/usr/lib/jvm/java-17"},
+			"args": []interface{}{"--site", "default"},
+		},
+	}, agent["components"])
+	assert.Equal(t, map[string]interface{}{"level": "${env.LEVEL}"}, agent["logging"], "only the components are rendered")
+
+	original, err := ast.Map()
+	require.NoError(t, err)
+	assert.Equal(t, "${env.JAVA_HOME}", original["agent"].(map[string]interface{})["components"].(map[string]interface{})["filestream"].(map[string]interface{})["env"].(map[string]interface{})["JAVA_HOME"], "the original AST is not modified")
+
+	missing := ast.ShallowClone()
+	assert.ErrorContains(t, RenderComponents(missing, []*Vars{mustMakeVars(map[string]interface{}{})}), `rendering component "filestream" failed`)
+}
@@ -0,0 +1,203 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// PatchOp is the operation of a Patch.
+type PatchOp string
+
+const (
+	// PatchSet sets the value at the path, adding the key to the dictionary or appending the value to the list
+	// when the index is the length of the list.
+	PatchSet PatchOp = "set"
+	// PatchDelete removes the key from the dictionary or the entry from the list at the path, the following
+	// entries of the list are shifted.
+	PatchDelete PatchOp = "delete"
+)
+
+// Patch is a change of a node of an AST. The difference between two ASTs is the list of patches to apply in
+// order to the first one to get the second one, see Diff.
+type Patch struct {
+	Op PatchOp `json:"op" yaml:"op"`
+	// Path are the keys of the dictionaries and the indexes of the lists leading to the node. The keys can
+	// contain the selector separator, a path is not a Selector.
+	Path []string `json:"path" yaml:"path"`
+	// Value is the value set by a PatchSet.
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Diff returns the patches changing the AST from into the AST to. The entries of the lists are compared by their
+// position, an entry added or removed in the middle of a list changes all the following entries.
+func Diff(from, to *AST) []Patch {
+	return diffNode(nil, nil, from.root, to.root)
+}
+
+func diffNode(patches []Patch, path []string, from, to Node) []Patch {
+	if k, ok := from.(*Key); ok {
+		from = k.value
+	}
+	if k, ok := to.(*Key); ok {
+		to = k.value
+	}
+
+	switch f := from.(type) {
+	case *Dict:
+		t, ok := to.(*Dict)
+		if !ok {
+			break
+		}
+		for _, n := range f.value {
+			name := n.(*Key).name
+			if _, ok := t.Find(name); !ok {
+				patches = append(patches, Patch{Op: PatchDelete, Path: childPath(path, name)})
+			}
+		}
+		for _, n := range t.value {
+			name := n.(*Key).name
+			if fn, ok := f.Find(name); ok {
+				patches = diffNode(patches, childPath(path, name), fn, n)
+			} else {
+				patches = append(patches, Patch{Op: PatchSet, Path: childPath(path, name), Value: nodeValue(n)})
+			}
+		}
+		return patches
+	case *List:
+		t, ok := to.(*List)
+		if !ok {
+			break
+		}
+		for i := 0; i < len(f.value) && i < len(t.value); i++ {
+			patches = diffNode(patches, childPath(path, strconv.Itoa(i)), f.value[i], t.value[i])
+		}
+		for i := len(f.value); i < len(t.value); i++ {
+			patches = append(patches, Patch{Op: PatchSet, Path: childPath(path, strconv.Itoa(i)), Value: nodeValue(t.value[i])})
+		}
+		// removed from the end, so that the indexes of the remaining entries don't change
+		for i := len(f.value) - 1; i >= len(t.value); i-- {
+			patches = append(patches, Patch{Op: PatchDelete, Path: childPath(path, strconv.Itoa(i))})
+		}
+		return patches
+	}
+
+	if from == nil && to == nil {
+		return patches
+	}
+	if from == nil || to == nil || reflect.TypeOf(from) != reflect.TypeOf(to) || !bytes.Equal(from.Hash(), to.Hash()) {
+		patches = append(patches, Patch{Op: PatchSet, Path: path, Value: nodeValue(to)})
+	}
+	return patches
+}
+
+func childPath(path []string, name string) []string {
+	p := make([]string, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, name)
+}
+
+func nodeValue(n Node) interface{} {
+	if k, ok := n.(*Key); ok {
+		n = k.value
+	}
+	if n == nil {
+		return nil
+	}
+	m := &MapVisitor{}
+	(&AST{}).dispatch(n, m)
+	return m.Content
+}
+
+// Patch returns a copy of the AST with the patches applied in order. It fails when a patch doesn't apply, the
+// AST is not the one the patches were computed from.
+func (a *AST) Patch(patches []Patch) (*AST, error) {
+	patched := a.Clone()
+	for i, p := range patches {
+		if err := patched.applyPatch(p); err != nil {
+			return nil, fmt.Errorf("failed to apply patch %d (%s %v): %w", i, p.Op, p.Path, err)
+		}
+	}
+	return patched, nil
+}
+
+func (a *AST) applyPatch(p Patch) error {
+	var value Node
+	if p.Op == PatchSet && p.Value != nil {
+		var err error
+		value, err = loadForNew(p.Value)
+		if err != nil {
+			return err
+		}
+	} else if p.Op != PatchSet && p.Op != PatchDelete {
+		return fmt.Errorf("unknown operation %q", p.Op)
+	}
+
+	if len(p.Path) == 0 {
+		root, ok := value.(*Dict)
+		if !ok {
+			return fmt.Errorf("the root can only be set to a dictionary")
+		}
+		a.root = root
+		return nil
+	}
+
+	parent := a.root
+	for _, part := range p.Path[:len(p.Path)-1] {
+		n, ok := parent.Find(part)
+		if !ok {
+			return fmt.Errorf("%q not found", part)
+		}
+		parent = n
+	}
+	if k, ok := parent.(*Key); ok {
+		parent = k.value
+	}
+
+	last := p.Path[len(p.Path)-1]
+	switch t := parent.(type) {
+	case *Dict:
+		n, found := t.Find(last)
+		switch {
+		case p.Op == PatchDelete && !found:
+			return fmt.Errorf("key %q not found", last)
+		case p.Op == PatchDelete:
+			t.value = removeNode(t.value, n)
+		case found:
+			n.(*Key).value = value
+		default:
+			t.value = append(t.value, &Key{name: last, value: value})
+			t.sort()
+		}
+	case *List:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i > len(t.value) || (i == len(t.value) && p.Op == PatchDelete) {
+			return fmt.Errorf("index %q out of the list of %d entries", last, len(t.value))
+		}
+		switch {
+		case p.Op == PatchDelete:
+			t.value = append(t.value[:i], t.value[i+1:]...)
+		case i == len(t.value):
+			t.value = append(t.value, value)
+		default:
+			t.value[i] = value
+		}
+	default:
+		return fmt.Errorf("%v is not a dictionary or a list", p.Path[:len(p.Path)-1])
+	}
+	return nil
+}
+
+func removeNode(nodes []Node, n Node) []Node {
+	for i, v := range nodes {
+		if v == n {
+			return append(nodes[:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffPatch(t *testing.T) {
+	from := map[string]interface{}{
+		"revision": 1,
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"https://a:9200", "https://b:9200"}},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{"id": "logs", "type": "filestream", "paths": []interface{}{"/var/log/*.log"}},
+			map[string]interface{}{"id": "metrics", "type": "system/metrics", "period": "10s"},
+			map[string]interface{}{"id": "audit", "type": "audit/auditd"},
+		},
+		"agent.monitoring": map[string]interface{}{"enabled": true},
+	}
+	to := map[string]interface{}{
+		"revision": 2,
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"https://a:9200"}, "api_key": "key"},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{"id": "logs", "type": "filestream", "paths": []interface{}{"/var/log/*.log", "/var/log/app/*.log"}},
+			map[string]interface{}{"id": "metrics", "type": "system/metrics", "period": 30},
+		},
+		"agent.monitoring": nil,
+	}
+
+	fromAST, err := NewAST(from)
+	require.NoError(t, err)
+	toAST, err := NewAST(to)
+	require.NoError(t, err)
+
+	patches := Diff(fromAST, toAST)
+	assert.Contains(t, patches, Patch{Op: PatchSet, Path: []string{"agent.monitoring"}}, "a key with a dot is a single part of the path")
+	assert.Contains(t, patches, Patch{Op: PatchDelete, Path: []string{"inputs", "2"}})
+	assert.Contains(t, patches, Patch{Op: PatchSet, Path: []string{"inputs", "0", "paths", "1"}, Value: "/var/log/app/*.log"})
+	assert.Contains(t, patches, Patch{Op: PatchSet, Path: []string{"inputs", "1", "period"}, Value: 30})
+	assert.NotContains(t, patches, Patch{Op: PatchSet, Path: []string{"inputs", "0", "id"}, Value: "logs"}, "unchanged values should not be patched")
+
+	patched, err := fromAST.Patch(patches)
+	require.NoError(t, err)
+	assert.True(t, patched.Equal(toAST), "the patched AST should be the target: %s", patched)
+	assert.True(t, fromAST.Equal(mustAST(t, from)), "the patched AST should not be modified")
+
+	assert.Empty(t, Diff(toAST, toAST))
+}
+
+func TestPatchMismatch(t *testing.T) {
+	ast := mustAST(t, map[string]interface{}{
+		"inputs": []interface{}{map[string]interface{}{"id": "logs"}},
+	})
+
+	for name, p := range map[string]Patch{
+		"missing key":       {Op: PatchDelete, Path: []string{"outputs"}},
+		"missing parent":    {Op: PatchSet, Path: []string{"outputs", "default", "type"}, Value: "logstash"},
+		"index out of list": {Op: PatchSet, Path: []string{"inputs", "2"}, Value: map[string]interface{}{"id": "metrics"}},
+		"not a container":   {Op: PatchSet, Path: []string{"inputs", "0", "id", "name"}, Value: "logs"},
+		"unknown operation": {Op: "move", Path: []string{"inputs"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ast.Patch([]Patch{p})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func mustAST(t *testing.T, m map[string]interface{}) *AST {
+	ast, err := NewAST(m)
+	require.NoError(t, err)
+	return ast
+}
@@ -0,0 +1,184 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	"lukechampine.com/blake3"
+)
+
+// HashAlgorithm is the hash implementation used to hash an AST.
+type HashAlgorithm string
+
+const (
+	// HashSHA256 is the default algorithm, SHA-256 is the hash function of Hash and HashStr.
+	HashSHA256 HashAlgorithm = "sha256"
+	// HashXXHash64 is the algorithm of Equal, fast but not collision resistant.
+	HashXXHash64 HashAlgorithm = "xxhash64"
+	// HashBLAKE3 is collision resistant like sha256, see BenchmarkHash for the cost of each algorithm.
+	HashBLAKE3 HashAlgorithm = "blake3"
+)
+
+// Hasher64 is the 64-bit hash written by Hash64With, any implementation can be plugged in. The hash of
+// HashXXHash64 is a *xxhash.Digest.
+type Hasher64 interface {
+	hash.Hash64
+	io.StringWriter
+}
+
+// HashOptions selects how HashWith, HashStrWith and EqualWith hash the AST.
+type HashOptions struct {
+	// Algorithm defaults to HashSHA256.
+	Algorithm HashAlgorithm
+}
+
+func (o HashOptions) algorithm() (HashAlgorithm, error) {
+	switch o.Algorithm {
+	case "":
+		return HashSHA256, nil
+	case HashSHA256, HashXXHash64, HashBLAKE3:
+		return o.Algorithm, nil
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm %q", o.Algorithm)
+	}
+}
+
+// HashWith calculates the hash of the canonical form of the tree with the algorithm of the options.
+// With HashSHA256 and HashBLAKE3 every node is hashed with the tag of its type and length prefixed keys
+// and values, the result differs from Hash. With HashXXHash64 it is the big-endian encoding of the
+// 64-bit hash.
+func (a *AST) HashWith(opts HashOptions) ([]byte, error) {
+	alg, err := opts.algorithm()
+	if err != nil {
+		return nil, err
+	}
+	return a.hashWith(alg), nil
+}
+
+// HashStrWith returns the hash of the canonical form computed by HashWith as a base64 url encoded string.
+func (a *AST) HashStrWith(opts HashOptions) (string, error) {
+	h, err := a.HashWith(opts)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(h), nil
+}
+
+// EqualWith checks if two AST are equals by comparing the hashes of their canonical forms computed with
+// the algorithm of the options.
+func (a *AST) EqualWith(other *AST, opts HashOptions) (bool, error) {
+	alg, err := opts.algorithm()
+	if err != nil {
+		return false, err
+	}
+	return a.equalWith(other, alg), nil
+}
+
+func (a *AST) hashWith(alg HashAlgorithm) []byte {
+	switch alg {
+	case HashXXHash64:
+		return binary.BigEndian.AppendUint64(nil, a.canonicalHash64())
+	case HashBLAKE3:
+		return hashNode(a.Normalize().root, func() hash.Hash { return blake3.New(32, nil) })
+	default:
+		return hashNode(a.Normalize().root, sha256.New)
+	}
+}
+
+func (a *AST) equalWith(other *AST, alg HashAlgorithm) bool {
+	if a.root == nil || other.root == nil {
+		return a.root == other.root
+	}
+	if alg == HashXXHash64 {
+		// skip the encoding of the 64-bit hashes
+		return a.canonicalHash64() == other.canonicalHash64()
+	}
+	return bytes.Equal(a.hashWith(alg), other.hashWith(alg))
+}
+
+// hashNode computes the hash of the node using newHash for every node. The hash of each node starts with the tag
+// of its type and every key, value and list of children is prefixed by its length, so that two different trees
+// can't produce the same stream of bytes. The bytes of the values are the ones of their Hash method.
+func hashNode(n Node, newHash func() hash.Hash) []byte {
+	h := newHash()
+	switch t := n.(type) {
+	case *Dict:
+		h.Write([]byte{hashTagDict})
+		writeChildren(h, t.value, newHash)
+	case *List:
+		h.Write([]byte{hashTagList})
+		writeChildren(h, t.value, newHash)
+	case *Key:
+		h.Write([]byte{hashTagKey})
+		writeLengthPrefixed(h, []byte(t.name))
+		if t.value == nil {
+			h.Write([]byte{0})
+		} else {
+			h.Write([]byte{1})
+			writeLengthPrefixed(h, hashNode(t.value, newHash))
+		}
+	default:
+		h.Write([]byte{valueHashTag(n)})
+		writeLengthPrefixed(h, n.Hash())
+	}
+	return h.Sum(nil)
+}
+
+// the tags of the node types written first in their hash by hashNode
+const (
+	hashTagDict byte = iota + 1
+	hashTagList
+	hashTagKey
+	hashTagStr
+	hashTagInt
+	hashTagUInt
+	hashTagFloat
+	hashTagBool
+	hashTagOther
+)
+
+func valueHashTag(n Node) byte {
+	switch n.(type) {
+	case *StrVal:
+		return hashTagStr
+	case *IntVal:
+		return hashTagInt
+	case *UIntVal:
+		return hashTagUInt
+	case *FloatVal:
+		return hashTagFloat
+	case *BoolVal:
+		return hashTagBool
+	default:
+		return hashTagOther
+	}
+}
+
+// writeChildren writes the number of the children followed by their length prefixed hashes.
+func writeChildren(h hash.Hash, nodes []Node, newHash func() hash.Hash) {
+	children := make([][]byte, 0, len(nodes))
+	for _, v := range nodes {
+		if v == nil {
+			continue
+		}
+		children = append(children, hashNode(v, newHash))
+	}
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(children))))
+	for _, child := range children {
+		writeLengthPrefixed(h, child)
+	}
+}
+
+func writeLengthPrefixed(h hash.Hash, b []byte) {
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(b))))
+	h.Write(b)
+}
@@ -0,0 +1,169 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashWith(t *testing.T) {
+	ast, err := NewAST(map[string]interface{}{
+		"outputs": map[string]interface{}{"default": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"localhost:9200"}}},
+		"inputs":  []interface{}{map[string]interface{}{"type": "filestream", "id": "logs"}},
+	})
+	require.NoError(t, err)
+	// same configuration with the keys inserted in another order
+	reordered := &AST{root: &Dict{value: []Node{ast.root.(*Dict).value[1], ast.root.(*Dict).value[0]}}}
+	changed, err := NewAST(map[string]interface{}{
+		"outputs": map[string]interface{}{"default": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"localhost:9201"}}},
+		"inputs":  []interface{}{map[string]interface{}{"type": "filestream", "id": "logs"}},
+	})
+	require.NoError(t, err)
+
+	sha, err := ast.HashWith(HashOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, hashNode(ast.Normalize().root, sha256.New), sha, "sha256 should be the default")
+
+	xx, err := ast.HashWith(HashOptions{Algorithm: HashXXHash64})
+	require.NoError(t, err)
+	assert.Equal(t, ast.canonicalHash64(), binary.BigEndian.Uint64(xx))
+
+	b3, err := ast.HashWith(HashOptions{Algorithm: HashBLAKE3})
+	require.NoError(t, err)
+	assert.Len(t, b3, 32)
+	assert.NotEqual(t, sha, b3)
+
+	for _, alg := range []HashAlgorithm{HashSHA256, HashXXHash64, HashBLAKE3} {
+		t.Run(string(alg), func(t *testing.T) {
+			opts := HashOptions{Algorithm: alg}
+			hashStr, err := ast.HashStrWith(opts)
+			require.NoError(t, err)
+			reorderedStr, err := reordered.HashStrWith(opts)
+			require.NoError(t, err)
+			assert.Equal(t, hashStr, reorderedStr, "the hash should not depend on the order of the keys")
+
+			equal, err := ast.EqualWith(reordered, opts)
+			require.NoError(t, err)
+			assert.True(t, equal)
+			equal, err = ast.EqualWith(changed, opts)
+			require.NoError(t, err)
+			assert.False(t, equal)
+		})
+	}
+
+	_, err = ast.HashWith(HashOptions{Algorithm: "md5"})
+	assert.ErrorContains(t, err, `unsupported hash algorithm "md5"`)
+	_, err = ast.EqualWith(changed, HashOptions{Algorithm: "md5"})
+	assert.Error(t, err)
+}
+
+func TestHashWithUnambiguous(t *testing.T) {
+	scenarios := map[string]struct {
+		a, b Node
+	}{
+		"key and value boundary": {
+			a: &Key{name: "a", value: &StrVal{value: "bc"}},
+			b: &Key{name: "ab", value: &StrVal{value: "c"}},
+		},
+		"value types": {
+			a: &List{value: []Node{&StrVal{value: "1"}}},
+			b: &List{value: []Node{&IntVal{value: 1}}},
+		},
+		"dict and list": {
+			a: &Dict{value: []Node{&Key{name: "a", value: &StrVal{value: "b"}}}},
+			b: &List{value: []Node{&Key{name: "a", value: &StrVal{value: "b"}}}},
+		},
+		"list boundaries": {
+			a: &List{value: []Node{&StrVal{value: "ab"}, &StrVal{value: "c"}}},
+			b: &List{value: []Node{&StrVal{value: "a"}, &StrVal{value: "bc"}}},
+		},
+		"key without value": {
+			a: &Key{name: "a"},
+			b: &Key{name: "a", value: &StrVal{value: ""}},
+		},
+	}
+	for name, s := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			for _, alg := range []HashAlgorithm{HashSHA256, HashBLAKE3} {
+				a, err := (&AST{root: s.a}).HashWith(HashOptions{Algorithm: alg})
+				require.NoError(t, err)
+				b, err := (&AST{root: s.b}).HashWith(HashOptions{Algorithm: alg})
+				require.NoError(t, err)
+				assert.NotEqual(t, a, b, alg)
+			}
+		})
+	}
+}
+
+// fnvHasher64 plugs the 64-bit FNV-1a hash of the standard library into Hash64With.
+type fnvHasher64 struct {
+	hash.Hash64
+}
+
+func (f fnvHasher64) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+func TestHash64WithHasher(t *testing.T) {
+	ast := largePolicy(t, 2)
+	reordered := &AST{root: &Dict{value: []Node{ast.root.(*Dict).value[1], ast.root.(*Dict).value[0]}}}
+	changed := largePolicy(t, 3)
+
+	hash64 := func(ast *AST, h Hasher64) uint64 {
+		require.NoError(t, ast.Normalize().Hash64With(h))
+		return h.Sum64()
+	}
+	for name, newHasher := range map[string]func() Hasher64{
+		"xxhash": func() Hasher64 { return xxhash.New() },
+		"fnv":    func() Hasher64 { return fnvHasher64{fnv.New64a()} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, hash64(ast, newHasher()), hash64(reordered, newHasher()))
+			assert.NotEqual(t, hash64(ast, newHasher()), hash64(changed, newHasher()))
+		})
+	}
+	assert.Equal(t, ast.canonicalHash64(), hash64(ast, xxhash.New()))
+}
+
+// BenchmarkHash compares the hashes of a large policy with many programs: the tree of MD5 and SHA-256 sums of
+// the cryptographic hashes against the single pass of the 64-bit hashes plugged into Hash64With.
+func BenchmarkHash(b *testing.B) {
+	ast := largePolicy(b, 200).Normalize()
+
+	b.Run("md5", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			hashNode(ast.root, md5.New)
+		}
+	})
+	for _, alg := range []HashAlgorithm{HashSHA256, HashBLAKE3} {
+		b.Run(string(alg), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ast.hashWith(alg)
+			}
+		})
+	}
+	for name, h := range map[string]Hasher64{
+		"xxhash64": xxhash.New(),
+		"fnv64a":   fnvHasher64{fnv.New64a()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				h.Reset()
+				_ = ast.Hash64With(h)
+				h.Sum64()
+			}
+		})
+	}
+}
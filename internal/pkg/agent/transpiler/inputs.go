@@ -16,8 +16,117 @@ const (
 	// an input defines a set of streams and after conditions are applied all the streams are removed then
 	// the entire input is removed.
 	streamsKey = "streams"
+
+	// inputsKey is the name of the top-level key that holds the inputs of the policy.
+	inputsKey = "inputs"
+
+	// inputTemplatesKey is the name of the top-level key that holds the input templates of the policy. Each
+	// input template selects a dynamic provider with `provider` and defines the `input` that is rendered
+	// once for every mapping of that provider.
+	inputTemplatesKey = "input_templates"
 )
 
+// RenderAllInputs renders both the `inputs` and the `input_templates` sections of the AST. The inputs
+// rendered from the input templates are appended after the rendered inputs. Returns false when the AST
+// defines neither section.
+func RenderAllInputs(ast *AST, varsArray []*Vars) (Node, bool, error) {
+	var rendered []Node
+	inputs, hasInputs := Lookup(ast, inputsKey)
+	if hasInputs {
+		renderedInputs, err := RenderInputs(inputs, varsArray)
+		if err != nil {
+			return nil, false, err
+		}
+		rendered = renderedInputs.Value().([]Node)
+	}
+	templates, hasTemplates := Lookup(ast, inputTemplatesKey)
+	if hasTemplates {
+		templateInputs, err := RenderInputTemplates(templates, varsArray)
+		if err != nil {
+			return nil, false, err
+		}
+		rendered = append(rendered, templateInputs...)
+	}
+	if !hasInputs && !hasTemplates {
+		return nil, false, nil
+	}
+	return NewList(rendered), true, nil
+}
+
+// RenderInputTemplates renders the input templates, each template is rendered once for every mapping
+// of the dynamic provider it selects. Mappings of other providers are not used to render the template.
+func RenderInputTemplates(templates Node, varsArray []*Vars) ([]Node, error) {
+	l, ok := templates.Value().(*List)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an array", inputTemplatesKey)
+	}
+	var rendered []Node
+	for i, node := range l.Value().([]Node) {
+		provider, input, err := inputTemplate(node)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%d: %w", inputTemplatesKey, i, err)
+		}
+		var providerVars []*Vars
+		for _, vars := range varsArray {
+			if vars.processorsKey == provider {
+				providerVars = append(providerVars, vars)
+			}
+		}
+		if len(providerVars) == 0 {
+			// provider has no mappings (or is not running yet)
+			continue
+		}
+		renderedInputs, err := RenderInputs(NewKey(inputsKey, NewList([]Node{input})), providerVars)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%d: %w", inputTemplatesKey, i, err)
+		}
+		rendered = append(rendered, renderedInputs.Value().([]Node)...)
+	}
+	return rendered, nil
+}
+
+// InputTemplatesVars returns the variables referenced by the input templates along with the name of
+// the provider selected by each template, so the selected providers are always running.
+func InputTemplatesVars(templates Node, vars []string, defaultProvider string) []string {
+	vars = templates.Vars(vars, defaultProvider)
+	l, ok := templates.Value().(*List)
+	if !ok {
+		return vars
+	}
+	for _, node := range l.Value().([]Node) {
+		provider, _, err := inputTemplate(node)
+		if err != nil {
+			continue
+		}
+		vars = append(vars, provider)
+	}
+	return vars
+}
+
+func inputTemplate(node Node) (string, *Dict, error) {
+	dict, ok := node.(*Dict)
+	if !ok {
+		return "", nil, errors.New("input template must be a dict")
+	}
+	providerNode, ok := dict.Find("provider")
+	if !ok {
+		return "", nil, errors.New("input template must define a provider")
+	}
+	provider, ok := providerNode.Value().(*StrVal)
+	if !ok || provider.value == "" {
+		return "", nil, errors.New("input template provider must be a non-empty string")
+	}
+	inputNode, ok := dict.Find("input")
+	if !ok {
+		return "", nil, errors.New("input template must define an input")
+	}
+	input, ok := inputNode.Value().(*Dict)
+	if !ok {
+		return "", nil, errors.New("input template input must be a dict")
+	}
+	return provider.value, input, nil
+}
+
 // RenderInputs renders dynamic inputs section
 func RenderInputs(inputs Node, varsArray []*Vars) (Node, error) {
 	l, ok := inputs.Value().(*List)
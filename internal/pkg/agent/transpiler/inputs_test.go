@@ -803,6 +803,200 @@ func TestRenderInputs(t *testing.T) {
 	}
 }
 
+func TestRenderAllInputs(t *testing.T) {
+	varsArray := []*Vars{
+		mustMakeVars(map[string]interface{}{
+			"host": map[string]interface{}{
+				"name": "agent-host",
+			},
+		}),
+		mustMakeVarsP("docker-abc", map[string]interface{}{
+			"docker": map[string]interface{}{
+				"container": map[string]interface{}{
+					"id": "abc",
+				},
+			},
+		}, "docker", nil),
+		mustMakeVarsP("docker-def", map[string]interface{}{
+			"docker": map[string]interface{}{
+				"container": map[string]interface{}{
+					"id": "def",
+				},
+			},
+		}, "docker", nil),
+		mustMakeVarsP("kubernetes-pod", map[string]interface{}{
+			"kubernetes": map[string]interface{}{
+				"pod": map[string]interface{}{
+					"name": "pod",
+				},
+			},
+		}, "kubernetes", nil),
+	}
+
+	testcases := map[string]struct {
+		config   map[string]interface{}
+		expected []interface{}
+		ok       bool
+		err      bool
+	}{
+		"no inputs or templates": {
+			config: map[string]interface{}{},
+		},
+		"templates not list": {
+			config: map[string]interface{}{
+				"input_templates": "not list",
+			},
+			err: true,
+		},
+		"template missing provider": {
+			config: map[string]interface{}{
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"input": map[string]interface{}{
+							"type": "filestream",
+						},
+					},
+				},
+			},
+			err: true,
+		},
+		"template missing input": {
+			config: map[string]interface{}{
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"provider": "docker",
+					},
+				},
+			},
+			err: true,
+		},
+		"template only renders selected provider mappings": {
+			config: map[string]interface{}{
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"provider": "docker",
+						"input": map[string]interface{}{
+							"id":   "container-logs",
+							"type": "filestream",
+							"path": "/var/lib/docker/containers/${docker.container.id}/*.log",
+						},
+					},
+				},
+			},
+			expected: []interface{}{
+				map[string]interface{}{
+					"id":          "container-logs-docker-abc",
+					"original_id": "container-logs",
+					"type":        "filestream",
+					"path":        "/var/lib/docker/containers/abc/*.log",
+				},
+				map[string]interface{}{
+					"id":          "container-logs-docker-def",
+					"original_id": "container-logs",
+					"type":        "filestream",
+					"path":        "/var/lib/docker/containers/def/*.log",
+				},
+			},
+			ok: true,
+		},
+		"template for provider without mappings": {
+			config: map[string]interface{}{
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"provider": "local_dynamic",
+						"input": map[string]interface{}{
+							"id":   "never",
+							"type": "filestream",
+						},
+					},
+				},
+			},
+			expected: []interface{}{},
+			ok:       true,
+		},
+		"templates appended after inputs": {
+			config: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id":   "system",
+						"type": "system/metrics",
+						"host": "${host.name}",
+					},
+				},
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"provider": "kubernetes",
+						"input": map[string]interface{}{
+							"id":   "pod",
+							"type": "filestream",
+							"name": "${kubernetes.pod.name}",
+						},
+					},
+				},
+			},
+			expected: []interface{}{
+				map[string]interface{}{
+					"id":   "system",
+					"type": "system/metrics",
+					"host": "agent-host",
+				},
+				map[string]interface{}{
+					"id":          "pod-kubernetes-pod",
+					"original_id": "pod",
+					"type":        "filestream",
+					"name":        "pod",
+				},
+			},
+			ok: true,
+		},
+	}
+
+	for name, test := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ast, err := NewAST(test.config)
+			require.NoError(t, err)
+			v, ok, err := RenderAllInputs(ast, varsArray)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.ok, ok)
+			if !test.ok {
+				return
+			}
+			rendered := &AST{root: NewDict([]Node{NewKey("inputs", v)})}
+			m, err := rendered.Map()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, m["inputs"])
+		})
+	}
+}
+
+func TestInputTemplatesVars(t *testing.T) {
+	ast, err := NewAST(map[string]interface{}{
+		"input_templates": []interface{}{
+			map[string]interface{}{
+				"provider": "docker",
+				"input": map[string]interface{}{
+					"type": "filestream",
+					"path": "${docker.container.id}",
+				},
+			},
+			map[string]interface{}{
+				"provider": "local_dynamic",
+				"input": map[string]interface{}{
+					"type": "filestream",
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	templates, ok := Lookup(ast, "input_templates")
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{"docker.container.id", "docker", "local_dynamic"}, InputTemplatesVars(templates, nil, ""))
+}
+
 func mustMakeVarsP(id string, mapping map[string]interface{}, processorKey string, processors Processors) *Vars {
 	v, err := NewVarsWithProcessors(id, mapping, processorKey, processors, nil, "")
 	if err != nil {
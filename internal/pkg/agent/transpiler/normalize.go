@@ -0,0 +1,113 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"math"
+
+	"github.com/cespare/xxhash/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalize returns the canonical form of the AST, the AST is not modified. Two ASTs describing the same
+// configuration have the same canonical form, whatever the order in which their keys were inserted and the
+// types the decoder of the policy used for their numbers:
+//   - the keys of every dictionary are sorted, a key present more than once keeps its last value,
+//   - empty entries of dictionaries and lists are removed, the order of the list items is kept,
+//   - whole numbers are IntVal, UIntVal only when above the range of an int, other numbers are FloatVal,
+//   - keys are in Unicode Normalization Form C, the keys of a dictionary only differing by their
+//     normalization are all kept with their own names.
+//
+// String values are kept byte for byte, two secrets only differing by their Unicode normalization are
+// different configurations.
+//
+// The processors attached to the nodes are kept. Hash, HashStr and Equal compare the canonical forms.
+func (a *AST) Normalize() *AST {
+	if a.root == nil {
+		return &AST{}
+	}
+	return &AST{root: normalizeNode(a.root)}
+}
+
+func normalizeNode(n Node) Node {
+	switch t := n.(type) {
+	case *Dict:
+		byName := make(map[string]*Key, len(t.value))
+		for _, child := range t.value {
+			key, ok := child.(*Key)
+			if !ok || key == nil {
+				continue
+			}
+			byName[key.name] = key
+		}
+		// the names are only normalized when it doesn't make two keys collide, otherwise one of the keys
+		// would be dropped
+		normalized := make(map[string]int, len(byName))
+		for name := range byName {
+			normalized[norm.NFC.String(name)]++
+		}
+		nodes := make([]Node, 0, len(byName))
+		for name, key := range byName {
+			n := &Key{name: name}
+			if nfc := norm.NFC.String(name); normalized[nfc] == 1 {
+				n.name = nfc
+			}
+			if key.value != nil {
+				n.value = normalizeNode(key.value)
+			}
+			nodes = append(nodes, n)
+		}
+		dict := &Dict{value: nodes, processors: t.processors}
+		dict.sort()
+		return dict
+	case *Key:
+		key := &Key{name: norm.NFC.String(t.name)}
+		if t.value != nil {
+			key.value = normalizeNode(t.value)
+		}
+		return key
+	case *List:
+		nodes := make([]Node, 0, len(t.value))
+		for _, child := range t.value {
+			if child == nil {
+				continue
+			}
+			nodes = append(nodes, normalizeNode(child))
+		}
+		return &List{value: nodes, processors: t.processors}
+	case *UIntVal:
+		if t.value <= math.MaxInt {
+			return &IntVal{value: int(t.value), processors: t.processors}
+		}
+		return &UIntVal{value: t.value, processors: t.processors}
+	case *FloatVal:
+		return normalizeFloat(t)
+	default:
+		return n.Clone()
+	}
+}
+
+// normalizeFloat converts the floats holding a whole number to IntVal or UIntVal, decoders of JSON
+// policies return float64 for every number.
+func normalizeFloat(f *FloatVal) Node {
+	if f.value != math.Trunc(f.value) || math.IsInf(f.value, 0) {
+		return &FloatVal{value: f.value, processors: f.processors}
+	}
+	// float64(math.MaxInt) rounds up to 2^63, it is out of the range of an int
+	if f.value >= math.MinInt && f.value < math.MaxInt {
+		return &IntVal{value: int(f.value), processors: f.processors}
+	}
+	if f.value > 0 && f.value < math.MaxUint64 {
+		return &UIntVal{value: uint64(f.value), processors: f.processors}
+	}
+	return &FloatVal{value: f.value, processors: f.processors}
+}
+
+// canonicalHash64 computes the hash of the canonical form of the AST.
+func (a *AST) canonicalHash64() uint64 {
+	hasher := xxhash.New()
+	_ = a.Normalize().Hash64With(hasher)
+	return hasher.Sum64()
+}
@@ -0,0 +1,333 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"testing/quick"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]struct {
+		ast      *AST
+		expected map[string]interface{}
+	}{
+		"sorts inserted keys": {
+			ast: &AST{root: &Dict{value: []Node{
+				&Key{name: "b", value: &StrVal{value: "b"}},
+				&Key{name: "a", value: &Dict{value: []Node{
+					&Key{name: "z", value: &IntVal{value: 1}},
+					&Key{name: "y", value: &IntVal{value: 2}},
+				}}},
+			}}},
+			expected: map[string]interface{}{
+				"a": map[string]interface{}{"y": 2, "z": 1},
+				"b": "b",
+			},
+		},
+		"last duplicated key wins": {
+			ast: &AST{root: &Dict{value: []Node{
+				&Key{name: "a", value: &IntVal{value: 1}},
+				&Key{name: "a", value: &IntVal{value: 2}},
+			}}},
+			expected: map[string]interface{}{"a": 2},
+		},
+		"whole numbers are integers": {
+			ast: &AST{root: &Dict{value: []Node{
+				&Key{name: "float", value: &FloatVal{value: 10}},
+				&Key{name: "negative", value: &FloatVal{value: -3}},
+				&Key{name: "fraction", value: &FloatVal{value: 1.5}},
+				&Key{name: "uint", value: &UIntVal{value: 42}},
+				&Key{name: "big", value: &UIntVal{value: math.MaxUint64}},
+			}}},
+			expected: map[string]interface{}{
+				"float":    10,
+				"negative": -3,
+				"fraction": 1.5,
+				"uint":     42,
+				"big":      uint64(math.MaxUint64),
+			},
+		},
+		"keys in normalization form C": {
+			ast: &AST{root: &Dict{value: []Node{
+				&Key{name: "cafe\u0301", value: &StrVal{value: "re\u0301sume\u0301"}},
+			}}},
+			expected: map[string]interface{}{"caf\u00e9": "re\u0301sume\u0301"},
+		},
+		"keys colliding once normalized are kept": {
+			ast: &AST{root: &Dict{value: []Node{
+				&Key{name: "cafe\u0301", value: &IntVal{value: 1}},
+				&Key{name: "caf\u00e9", value: &IntVal{value: 2}},
+			}}},
+			expected: map[string]interface{}{"cafe\u0301": 1, "caf\u00e9": 2},
+		},
+		"empty entries removed": {
+			ast: &AST{root: &Dict{value: []Node{
+				nil,
+				&Key{name: "list", value: &List{value: []Node{nil, &StrVal{value: "b"}, &StrVal{value: "a"}}}},
+			}}},
+			expected: map[string]interface{}{"list": []interface{}{"b", "a"}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			before := test.ast.String()
+			normalized := test.ast.Normalize()
+			assert.Equal(t, before, test.ast.String(), "Normalize must not modify the AST")
+
+			m, err := normalized.Map()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, m)
+		})
+	}
+}
+
+func TestNormalizeDottedKeys(t *testing.T) {
+	// the dotted key is merged into the existing dictionary after its keys
+	dotted, err := NewAST(map[string]interface{}{
+		"a":   map[string]interface{}{"c": 1},
+		"a.b": map[string]interface{}{"d": 2},
+	})
+	require.NoError(t, err)
+	nested, err := NewAST(map[string]interface{}{
+		"a": map[string]interface{}{"b": map[string]interface{}{"d": 2}, "c": 1},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, dotted.Equal(nested))
+	assert.Equal(t, dotted.HashStr(), nested.HashStr())
+	assert.Equal(t, dotted.Normalize().String(), nested.Normalize().String())
+}
+
+func TestNormalizeKeepsStringValues(t *testing.T) {
+	nfc, err := NewAST(map[string]interface{}{"password": "r\u00e9sum\u00e9"})
+	require.NoError(t, err)
+	nfd, err := NewAST(map[string]interface{}{"password": "re\u0301sume\u0301"})
+	require.NoError(t, err)
+
+	assert.False(t, nfc.Equal(nfd))
+	assert.NotEqual(t, nfc.HashStr(), nfd.HashStr())
+}
+
+func TestNormalizeCollidingKeys(t *testing.T) {
+	colliding := func(keys ...string) *AST {
+		nodes := make([]Node, 0, len(keys))
+		for _, key := range keys {
+			nodes = append(nodes, &Key{name: key, value: &IntVal{value: len(key)}})
+		}
+		return &AST{root: &Dict{value: nodes}}
+	}
+	// the keys are kept whatever their insertion order
+	ast := colliding("cafe\u0301", "caf\u00e9")
+	assert.Equal(t, ast.Normalize().String(), colliding("caf\u00e9", "cafe\u0301").Normalize().String())
+	assert.Equal(t, ast.Normalize().String(), ast.Normalize().Normalize().String())
+	// dropping one of the keys changes the configuration
+	assert.False(t, ast.Equal(colliding("cafe\u0301")))
+	assert.False(t, ast.Equal(colliding("caf\u00e9")))
+}
+
+func TestHashOfTheCanonicalForm(t *testing.T) {
+	// the keys of the dictionary are not sorted and the whole number is a float
+	ast := &AST{root: NewDict([]Node{
+		NewKey("b", NewFloatVal(1)),
+		NewKey("a", NewStrVal("value")),
+	})}
+	canonical := &AST{root: NewDict([]Node{
+		NewKey("a", NewStrVal("value")),
+		NewKey("b", NewIntVal(1)),
+	})}
+	assert.NotEqual(t, ast.root.Hash(), canonical.root.Hash())
+	assert.Equal(t, base64.URLEncoding.EncodeToString(hashNode(canonical.root, sha256.New)), ast.HashStr())
+	assert.Equal(t, canonical.Hash(), ast.Hash())
+	assert.True(t, ast.Equal(canonical))
+}
+
+// TestNormalizeProperties checks the properties of the canonical form on random configurations: it is
+// independent of the insertion order of the keys and of the decoder of the policy, and it is stable.
+func TestNormalizeProperties(t *testing.T) {
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec // deterministic randomness for the test
+		cfg := randomDict(r, 3)
+
+		ast, err := NewAST(cfg)
+		if err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+
+		// same configuration with the keys inserted in another order
+		shuffled := &AST{root: shuffleKeys(r, ast.root.Clone())}
+
+		// same configuration decoded from JSON, numbers are float64
+		raw, err := json.Marshal(cfg)
+		if err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(raw, &decoded); err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+		fromJSON, err := NewAST(decoded)
+		if err != nil {
+			t.Logf("seed %d: %v", seed, err)
+			return false
+		}
+
+		canonical := ast.Normalize().String()
+		for name, other := range map[string]*AST{"shuffled": shuffled, "json": fromJSON} {
+			if !ast.Equal(other) || ast.HashStr() != other.HashStr() {
+				t.Logf("seed %d: %s configuration is not equal", seed, name)
+				return false
+			}
+			if got := other.Normalize().String(); got != canonical {
+				t.Logf("seed %d: %s canonical form differs:\n%s\n%s", seed, name, canonical, got)
+				return false
+			}
+		}
+		if again := ast.Normalize().Normalize().String(); again != canonical {
+			t.Logf("seed %d: Normalize is not idempotent", seed)
+			return false
+		}
+		return true
+	}
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 200}))
+}
+
+func TestNormalizeDetectsChanges(t *testing.T) {
+	property := func(seed int64) bool {
+		r := rand.New(rand.NewSource(seed)) //nolint:gosec // deterministic randomness for the test
+		cfg := randomDict(r, 2)
+		ast, err := NewAST(cfg)
+		if err != nil {
+			return false
+		}
+		cfg["changed"] = fmt.Sprintf("value-%d", r.Int())
+		changed, err := NewAST(cfg)
+		if err != nil {
+			return false
+		}
+		return !ast.Equal(changed) && ast.HashStr() != changed.HashStr()
+	}
+	require.NoError(t, quick.Check(property, &quick.Config{MaxCount: 100}))
+}
+
+// BenchmarkEqual measures the comparison of the canonical forms of two large policies done by Equal, against
+// the comparison of their hashes as is.
+func BenchmarkEqual(b *testing.B) {
+	ast := largePolicy(b, 200)
+	other := largePolicy(b, 200)
+
+	b.Run("canonical", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ast.Equal(other)
+		}
+	})
+	b.Run("as is", func(b *testing.B) {
+		h := xxhash.New()
+		for i := 0; i < b.N; i++ {
+			h.Reset()
+			_ = ast.Hash64With(h)
+			h.Reset()
+			_ = other.Hash64With(h)
+		}
+	})
+}
+
+// largePolicy returns a policy with programs inputs of 10 streams each.
+func largePolicy(t testing.TB, programs int) *AST {
+	inputs := make([]interface{}, 0, programs)
+	for i := 0; i < programs; i++ {
+		streams := make([]interface{}, 0, 10)
+		for j := 0; j < 10; j++ {
+			streams = append(streams, map[string]interface{}{
+				"id":            fmt.Sprintf("stream-%d-%d", i, j),
+				"data_stream":   map[string]interface{}{"dataset": fmt.Sprintf("program_%d.stream_%d", i, j), "type": "logs"},
+				"paths":         []interface{}{fmt.Sprintf("/var/log/program-%d/stream-%d.log", i, j)},
+				"close_renamed": true,
+				"harvester":     map[string]interface{}{"buffer_size": 16384, "limit": 0.5},
+			})
+		}
+		inputs = append(inputs, map[string]interface{}{
+			"id":         fmt.Sprintf("program-%d", i),
+			"type":       "filestream",
+			"use_output": "default",
+			"streams":    streams,
+		})
+	}
+	ast, err := NewAST(map[string]interface{}{
+		"outputs": map[string]interface{}{"default": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"localhost:9200"}}},
+		"inputs":  inputs,
+	})
+	require.NoError(t, err)
+	return ast
+}
+
+func randomDict(r *rand.Rand, depth int) map[string]interface{} {
+	m := make(map[string]interface{})
+	for i := r.Intn(5) + 1; i > 0; i-- {
+		m[fmt.Sprintf("key%d", r.Intn(20))] = randomValue(r, depth)
+	}
+	return m
+}
+
+func randomValue(r *rand.Rand, depth int) interface{} {
+	kinds := 5
+	if depth > 0 {
+		kinds = 7
+	}
+	switch r.Intn(kinds) {
+	case 0:
+		return fmt.Sprintf("str-%d", r.Intn(100))
+	case 1:
+		return r.Intn(2000) - 1000
+	case 2:
+		return float64(r.Intn(1000)) + 0.5
+	case 3:
+		return r.Intn(2) == 0
+	case 4:
+		return uint64(r.Intn(1000))
+	case 5:
+		list := make([]interface{}, r.Intn(4))
+		for i := range list {
+			list[i] = randomValue(r, depth-1)
+		}
+		return list
+	default:
+		return randomDict(r, depth-1)
+	}
+}
+
+// shuffleKeys shuffles the keys of the dictionaries, the order of the lists is kept.
+func shuffleKeys(r *rand.Rand, n Node) Node {
+	switch t := n.(type) {
+	case *Dict:
+		r.Shuffle(len(t.value), func(i, j int) { t.value[i], t.value[j] = t.value[j], t.value[i] })
+		for _, child := range t.value {
+			shuffleKeys(r, child)
+		}
+	case *Key:
+		if t.value != nil {
+			shuffleKeys(r, t.value)
+		}
+	case *List:
+		for _, child := range t.value {
+			shuffleKeys(r, child)
+		}
+	}
+	return n
+}
@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import "fmt"
+
+const (
+	// presetsKey is the name of the top-level key that holds the presets of the policy, a preset is a
+	// partial input shared by the inputs that reference it.
+	presetsKey = "presets"
+
+	// usePresetKey is the name of the key of an input that references presets, either a single name or a
+	// list of names applied in order.
+	usePresetKey = "use_preset"
+)
+
+// ExpandPresets expands the presets referenced by the inputs and the input templates with `use_preset`, the
+// `presets` section is removed from the AST once expanded.
+//
+// The presets are merged in the order they are referenced and the input is merged last, so the input
+// overrides its presets and a preset overrides the previous ones. Dicts are merged key by key, any other
+// value, lists included, replaces the previous one.
+func ExpandPresets(ast *AST) error {
+	root, ok := ast.root.(*Dict)
+	if !ok {
+		return nil
+	}
+
+	presets := make(map[string]*Dict)
+	if node, ok := root.Find(presetsKey); ok {
+		dict, ok := node.Value().(*Dict)
+		if !ok {
+			return fmt.Errorf("%s must be a dict", presetsKey)
+		}
+		for _, n := range dict.value {
+			key := n.(*Key)
+			preset, ok := key.value.(*Dict)
+			if !ok {
+				return fmt.Errorf("%s.%s must be a dict", presetsKey, key.name)
+			}
+			if _, ok := preset.Find(usePresetKey); ok {
+				return fmt.Errorf("%s.%s: presets cannot use other presets", presetsKey, key.name)
+			}
+			presets[key.name] = preset
+		}
+		removeKey(root, presetsKey)
+	}
+
+	if node, ok := root.Find(inputsKey); ok {
+		if l, ok := node.Value().(*List); ok {
+			for i, input := range l.value {
+				expanded, err := expandInput(input, presets)
+				if err != nil {
+					return fmt.Errorf("%s.%d: %w", inputsKey, i, err)
+				}
+				l.value[i] = expanded
+			}
+		}
+	}
+
+	if node, ok := root.Find(inputTemplatesKey); ok {
+		if l, ok := node.Value().(*List); ok {
+			for i, template := range l.value {
+				dict, ok := template.(*Dict)
+				if !ok {
+					continue
+				}
+				inputNode, ok := dict.Find("input")
+				if !ok {
+					continue
+				}
+				inputKey := inputNode.(*Key)
+				expanded, err := expandInput(inputKey.value, presets)
+				if err != nil {
+					return fmt.Errorf("%s.%d.input: %w", inputTemplatesKey, i, err)
+				}
+				inputKey.value = expanded
+			}
+		}
+	}
+	return nil
+}
+
+// expandInput returns the input merged over the presets it references.
+func expandInput(input Node, presets map[string]*Dict) (Node, error) {
+	dict, ok := input.(*Dict)
+	if !ok {
+		return input, nil
+	}
+	node, ok := dict.Find(usePresetKey)
+	if !ok {
+		return input, nil
+	}
+
+	var names []string
+	switch v := node.Value().(type) {
+	case *StrVal:
+		names = []string{v.value}
+	case *List:
+		for _, n := range v.value {
+			name, ok := n.(*StrVal)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a preset name or a list of preset names", usePresetKey)
+			}
+			names = append(names, name.value)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a preset name or a list of preset names", usePresetKey)
+	}
+
+	expanded := &Dict{}
+	for _, name := range names {
+		preset, ok := presets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown preset %q", name)
+		}
+		expanded = mergeDict(expanded, preset)
+	}
+	input = dict.Clone()
+	removeKey(input.(*Dict), usePresetKey)
+	return mergeDict(expanded, input.(*Dict)), nil
+}
+
+// mergeDict returns a clone of base with the keys of override merged into it.
+func mergeDict(base, override *Dict) *Dict {
+	merged := base.Clone().(*Dict)
+	for _, n := range override.value {
+		key := n.(*Key)
+		existing, ok := merged.Find(key.name)
+		if !ok {
+			merged.value = append(merged.value, key.Clone())
+			continue
+		}
+		existingKey := existing.(*Key)
+		baseDict, baseIsDict := existingKey.value.(*Dict)
+		overrideDict, overrideIsDict := key.value.(*Dict)
+		if baseIsDict && overrideIsDict {
+			existingKey.value = mergeDict(baseDict, overrideDict)
+			continue
+		}
+		if key.value == nil {
+			existingKey.value = nil
+			continue
+		}
+		existingKey.value = key.value.Clone()
+	}
+	merged.sort()
+	return merged
+}
+
+func removeKey(dict *Dict, name string) {
+	nodes := dict.value[:0]
+	for _, n := range dict.value {
+		if n != nil && n.(*Key).name == name {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	dict.value = nodes
+}
@@ -0,0 +1,213 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package transpiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandPresets(t *testing.T) {
+	presets := map[string]interface{}{
+		"logs": map[string]interface{}{
+			"type":       "filestream",
+			"use_output": "default",
+			"parsers": map[string]interface{}{
+				"multiline": "pattern",
+				"ndjson":    true,
+			},
+			"tags": []interface{}{"preset"},
+		},
+		"monitoring": map[string]interface{}{
+			"use_output": "monitoring",
+		},
+	}
+
+	testcases := map[string]struct {
+		config   map[string]interface{}
+		expected map[string]interface{}
+		err      bool
+	}{
+		"no presets": {
+			config: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "input", "type": "filestream"},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "input", "type": "filestream"},
+				},
+			},
+		},
+		"input overrides preset": {
+			config: map[string]interface{}{
+				"presets": presets,
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id":         "input",
+						"use_preset": "logs",
+						"parsers": map[string]interface{}{
+							"ndjson": false,
+						},
+						"tags": []interface{}{"input"},
+					},
+					map[string]interface{}{"id": "other", "type": "system/metrics"},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id":         "input",
+						"type":       "filestream",
+						"use_output": "default",
+						"parsers": map[string]interface{}{
+							"multiline": "pattern",
+							"ndjson":    false,
+						},
+						"tags": []interface{}{"input"},
+					},
+					map[string]interface{}{"id": "other", "type": "system/metrics"},
+				},
+			},
+		},
+		"presets applied in order": {
+			config: map[string]interface{}{
+				"presets": presets,
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id":         "input",
+						"use_preset": []interface{}{"logs", "monitoring"},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"id":         "input",
+						"type":       "filestream",
+						"use_output": "monitoring",
+						"parsers": map[string]interface{}{
+							"multiline": "pattern",
+							"ndjson":    true,
+						},
+						"tags": []interface{}{"preset"},
+					},
+				},
+			},
+		},
+		"input template": {
+			config: map[string]interface{}{
+				"presets": presets,
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"provider": "docker",
+						"input": map[string]interface{}{
+							"id":         "container",
+							"use_preset": "monitoring",
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"input_templates": []interface{}{
+					map[string]interface{}{
+						"provider": "docker",
+						"input": map[string]interface{}{
+							"id":         "container",
+							"use_output": "monitoring",
+						},
+					},
+				},
+			},
+		},
+		"unknown preset": {
+			config: map[string]interface{}{
+				"presets": presets,
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "input", "use_preset": "missing"},
+				},
+			},
+			err: true,
+		},
+		"invalid use_preset": {
+			config: map[string]interface{}{
+				"presets": presets,
+				"inputs": []interface{}{
+					map[string]interface{}{"id": "input", "use_preset": map[string]interface{}{"name": "logs"}},
+				},
+			},
+			err: true,
+		},
+		"nested presets": {
+			config: map[string]interface{}{
+				"presets": map[string]interface{}{
+					"nested": map[string]interface{}{"use_preset": "logs"},
+				},
+			},
+			err: true,
+		},
+		"presets not a dict": {
+			config: map[string]interface{}{
+				"presets": []interface{}{"logs"},
+			},
+			err: true,
+		},
+	}
+
+	for name, test := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ast, err := NewAST(test.config)
+			require.NoError(t, err)
+			err = ExpandPresets(ast)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			m, err := ast.Map()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, m)
+		})
+	}
+}
+
+func TestExpandPresetsDoesNotModifyPresets(t *testing.T) {
+	ast, err := NewAST(map[string]interface{}{
+		"presets": map[string]interface{}{
+			"logs": map[string]interface{}{
+				"streams": map[string]interface{}{"paths": "/var/log/*.log"},
+			},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"id":         "first",
+				"use_preset": "logs",
+				"streams":    map[string]interface{}{"paths": "/var/log/first.log"},
+			},
+			map[string]interface{}{
+				"id":         "second",
+				"use_preset": "logs",
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ExpandPresets(ast))
+
+	m, err := ast.Map()
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"id":      "first",
+			"streams": map[string]interface{}{"paths": "/var/log/first.log"},
+		},
+		map[string]interface{}{
+			"id":      "second",
+			"streams": map[string]interface{}{"paths": "/var/log/*.log"},
+		},
+	}, m["inputs"])
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"unicode"
 
@@ -107,6 +108,41 @@ func (v *Vars) lookupNode(name string) (Node, bool) {
 	return Lookup(v.tree, name)
 }
 
+// ValidateVars checks the syntax of the variables in the string values of the mapping, without resolving them.
+func ValidateVars(mapping map[string]interface{}) error {
+	return validateVars("", mapping)
+}
+
+func validateVars(path string, value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if err := validateVars(joinPath(path, key), child); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if err := validateVars(joinPath(path, strconv.Itoa(i)), child); err != nil {
+				return err
+			}
+		}
+	case string:
+		noMatch := func(string) (Node, Processors, bool) { return nil, nil, false }
+		if _, err := replaceVars(v, noMatch, false, ""); err != nil {
+			return fmt.Errorf("invalid variable in '%s': %w", path, err)
+		}
+	}
+	return nil
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + varsSeparator + key
+}
+
 func replaceVars(value string, replacer func(variable string) (Node, Processors, bool), reqMatch bool, defaultProvider string) (Node, error) {
 	var processors Processors
 	matchIdxs := varsRegex.FindAllSubmatchIndex([]byte(value), -1)
@@ -442,6 +442,29 @@ func TestVars_ReplaceWithFetchContextProvider(t *testing.T) {
 	assert.Equal(t, NewStrVal("mockedFetchContent"), res)
 }
 
+func TestValidateVars(t *testing.T) {
+	assert.NoError(t, ValidateVars(map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"paths": []interface{}{"/var/log/${host.name}.log", "${env.HOME|'/root'}", "$${literal}"},
+				"port":  9200,
+			},
+		},
+	}), "unresolved variables are not validation errors")
+
+	err := ValidateVars(map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{"paths": []interface{}{"/var/log/${host.name.log"}},
+		},
+	})
+	assert.ErrorContains(t, err, "invalid variable in 'inputs.0.paths.0': starting ${ is missing ending }")
+
+	err = ValidateVars(map[string]interface{}{
+		"outputs": map[string]interface{}{"default": map[string]interface{}{"hosts": "${host.}"}},
+	})
+	assert.ErrorContains(t, err, "invalid variable in 'outputs.default.hosts'")
+}
+
 type contextProviderMock struct {
 }
 
@@ -0,0 +1,87 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vars
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+// LoadVariablesFile reads the variables from a YAML file instead of gathering them from the providers, the
+// policy is rendered as on a host where the providers would have returned them.
+//
+// The file is either a mapping, a single set of variables, or a list of mappings with a set of variables each,
+// as a dynamic provider returns one for each discovered resource. The variables are mapped under the name of their
+// provider, a variable of the policy without a provider prefix is looked up under the default provider of the
+// configuration.
+func LoadVariablesFile(cfg *config.Config, path string) ([]*transpiler.Vars, error) {
+	var providersCfg composable.Config
+	if cfg != nil {
+		if err := cfg.UnpackTo(&providersCfg); err != nil {
+			return nil, fmt.Errorf("failed to unpack providers config: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read variables file: %w", err)
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse variables file %s: %w", path, err)
+	}
+
+	// a single mapping is like the variables of the context providers, the inputs rendered with it keep their ID
+	var sets []interface{}
+	single := false
+	switch v := raw.(type) {
+	case nil:
+	case []interface{}:
+		sets = v
+	default:
+		sets = []interface{}{v}
+		single = true
+	}
+
+	vars := make([]*transpiler.Vars, 0, len(sets))
+	for i, set := range sets {
+		mapping, err := variablesMapping(set)
+		if err != nil {
+			return nil, fmt.Errorf("invalid set %d of variables file %s: %w", i, path, err)
+		}
+		id := fmt.Sprintf("vars-file-%d", i)
+		if single {
+			id = ""
+		}
+		v, err := transpiler.NewVars(id, mapping, nil, providersCfg.DefaultProvider())
+		if err != nil {
+			return nil, fmt.Errorf("invalid set %d of variables file %s: %w", i, path, err)
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+// variablesMapping converts a set of variables parsed from YAML to the mapping of the variables.
+func variablesMapping(set interface{}) (map[string]interface{}, error) {
+	c, err := config.NewConfigFrom(set)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.ToMapStr()
+	if err != nil {
+		return nil, err
+	}
+	if len(m) == 0 {
+		return nil, fmt.Errorf("a set of variables must be a mapping")
+	}
+	return m, nil
+}
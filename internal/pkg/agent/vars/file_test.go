@@ -0,0 +1,101 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vars
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+)
+
+func writeVarsFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "vars.yml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadVariablesFile(t *testing.T) {
+	policy := map[string]interface{}{
+		"inputs": []interface{}{
+			map[string]interface{}{
+				"type":    "filestream",
+				"id":      "logs-${kubernetes.labels.app}",
+				"paths":   []interface{}{"/var/log/${kubernetes.labels.app}/*.log"},
+				"message": "on ${host.name} in ${REGION}",
+			},
+		},
+	}
+
+	testCases := map[string]struct {
+		content  string
+		expected []string
+	}{
+		"single mapping": {
+			content: `
+host.name: web-1
+env.REGION: eu-west-1
+kubernetes:
+  labels:
+    app: nginx
+`,
+			expected: []string{"logs-nginx"},
+		},
+		"list of mappings": {
+			content: `
+- host.name: web-1
+  env.REGION: eu-west-1
+  kubernetes.labels.app: nginx
+- host.name: web-1
+  env.REGION: eu-west-1
+  kubernetes.labels.app: redis
+`,
+			expected: []string{"logs-nginx-vars-file-0", "logs-redis-vars-file-1"},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			vars, err := LoadVariablesFile(config.MustNewConfigFrom(map[string]interface{}{
+				"agent.providers.default": "env",
+			}), writeVarsFile(t, tc.content))
+			require.NoError(t, err)
+
+			ast, err := transpiler.NewAST(policy)
+			require.NoError(t, err)
+			inputs, ok, err := transpiler.RenderAllInputs(ast, vars)
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.NoError(t, transpiler.Insert(ast, inputs, "inputs"))
+			m, err := ast.Map()
+			require.NoError(t, err)
+
+			rendered := m["inputs"].([]interface{})
+			require.Len(t, rendered, len(tc.expected))
+			for i, id := range tc.expected {
+				input := rendered[i].(map[string]interface{})
+				assert.Equal(t, id, input["id"])
+				assert.Equal(t, "on web-1 in eu-west-1", input["message"], "the variables of the default provider should be substituted")
+			}
+		})
+	}
+}
+
+func TestLoadVariablesFileErrors(t *testing.T) {
+	_, err := LoadVariablesFile(nil, filepath.Join(t.TempDir(), "missing.yml"))
+	assert.Error(t, err)
+
+	_, err = LoadVariablesFile(nil, writeVarsFile(t, "- host.name: web-1\n- web-2\n"))
+	assert.ErrorContains(t, err, "invalid set 1")
+
+	vars, err := LoadVariablesFile(nil, writeVarsFile(t, ""))
+	require.NoError(t, err)
+	assert.Empty(t, vars)
+}
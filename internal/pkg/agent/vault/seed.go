@@ -23,6 +23,9 @@ const (
 	// seedFileV2 is len(aesgcm.AES256)+4 and contains the random seed followed by a non-zero salt size (little endian uint32)
 	seedFileV2     = ".seedV2"
 	seedFileV2Size = seedFileSize + 4
+	// protectedSuffix is appended to the name of the seed file when the seed is protected by the backend of the
+	// vault, the file contains the protected seed file
+	protectedSuffix = ".protected"
 )
 
 const (
@@ -34,12 +37,38 @@ var (
 	mxSeed sync.Mutex
 )
 
+// readSeedFile reads the seed file, unprotecting it with the protector of the backend when not nil.
+func readSeedFile(path string, name string, protector seedProtector) ([]byte, error) {
+	if protector == nil {
+		return os.ReadFile(filepath.Join(path, name))
+	}
+	protected, err := os.ReadFile(filepath.Join(path, name+protectedSuffix))
+	if err != nil {
+		return nil, err
+	}
+	b, err := protector.Unprotect(protected)
+	if err != nil {
+		return nil, fmt.Errorf("could not unprotect seed file: %w", err)
+	}
+	return b, nil
+}
+
+// writeSeedFile writes the seed file, protecting it with the protector of the backend when not nil.
+func writeSeedFile(path string, name string, b []byte, protector seedProtector) error {
+	if protector == nil {
+		return os.WriteFile(filepath.Join(path, name), b, 0600)
+	}
+	protected, err := protector.Protect(b)
+	if err != nil {
+		return fmt.Errorf("could not protect seed file: %w", err)
+	}
+	return os.WriteFile(filepath.Join(path, name+protectedSuffix), protected, 0600)
+}
+
 // getSeedV1 will read the V1 .seed file
 // Will return fs.ErrNotExists if the bytecount does not match
-func getSeedV1(path string) ([]byte, error) {
-	fp := filepath.Join(path, seedFile)
-
-	b, err := os.ReadFile(fp)
+func getSeedV1(path string, protector seedProtector) ([]byte, error) {
+	b, err := readSeedFile(path, seedFile, protector)
 	if err != nil {
 		return nil, fmt.Errorf("could not read seed file: %w", err)
 	}
@@ -54,10 +83,8 @@ func getSeedV1(path string) ([]byte, error) {
 // getSeedV2 will read a seedV2 file and return the passphrase and saltSize
 // Will return fs.ErrNotExists if the byte count does not match, or saltSize is 0
 // when in FIPS mode will return fs.ErrUnsupported when saltSize is non-zero but less then 16
-func getSeedV2(path string) ([]byte, int, error) {
-	fp := filepath.Join(path, seedFileV2)
-
-	b, err := os.ReadFile(fp)
+func getSeedV2(path string, protector seedProtector) ([]byte, int, error) {
+	b, err := readSeedFile(path, seedFileV2, protector)
 	if err != nil {
 		return nil, 0, fmt.Errorf("could not read seed file: %w", err)
 	}
@@ -77,9 +104,9 @@ func getSeedV2(path string) ([]byte, int, error) {
 	return pass, int(saltSize), nil
 }
 
-func getOrCreateSeed(path string, readonly bool) ([]byte, int, error) {
+func getOrCreateSeed(path string, readonly bool, protector seedProtector) ([]byte, int, error) {
 	if readonly {
-		return getSeed(path)
+		return getSeed(path, protector)
 	}
-	return createSeedIfNotExists(path)
+	return createSeedIfNotExists(path, protector)
 }
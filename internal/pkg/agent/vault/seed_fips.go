@@ -11,19 +11,18 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
 )
 
 // getSeed returns the seed and salt size from the V2 seed file.
 // If the byte count does not match, or a 0 length salt is detected fs.ErrNotExist will be returned.
-func getSeed(path string) ([]byte, int, error) {
+func getSeed(path string, protector seedProtector) ([]byte, int, error) {
 	mxSeed.Lock()
 	defer mxSeed.Unlock()
 
 	// FIPS only supports V2
-	b, saltSize, err := getSeedV2(path)
+	b, saltSize, err := getSeedV2(path, protector)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -40,11 +39,11 @@ func checkSalt(size int) error {
 
 // createSeedIfNotExists returns the seed and salt size from the V2 seed file.
 // If the seed file does not exist it will create and write a new V2 seed file with a salt size of 16.
-func createSeedIfNotExists(path string) ([]byte, int, error) {
+func createSeedIfNotExists(path string, protector seedProtector) ([]byte, int, error) {
 	mxSeed.Lock()
 	defer mxSeed.Unlock()
 
-	pass, saltSize, err := getSeedV2(path)
+	pass, saltSize, err := getSeedV2(path, protector)
 	if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			return nil, 0, err
@@ -61,7 +60,7 @@ func createSeedIfNotExists(path string) ([]byte, int, error) {
 	l := make([]byte, 4)
 	binary.LittleEndian.PutUint32(l, uint32(defaultSaltSizeV2))
 
-	err = os.WriteFile(filepath.Join(path, seedFileV2), append(seed, l...), 0600)
+	err = writeSeedFile(path, seedFileV2, append(seed, l...), protector)
 	if err != nil {
 		return nil, 0, err
 	}
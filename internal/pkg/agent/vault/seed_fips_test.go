@@ -35,7 +35,7 @@ func TestGetSeedFailsV1File(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, _, err = getSeed(dir)
+	_, _, err = getSeed(dir, nil)
 	if !errors.Is(err, os.ErrNotExist) {
 		t.Fatalf("FIPS mode must not read v1 seeds. expected error %v to be os.ErrNotExist", err)
 	}
@@ -60,7 +60,7 @@ func TestGetSeedV2File(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	b, saltSize, err := getSeed(dir)
+	b, saltSize, err := getSeed(dir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,7 +86,7 @@ func TestCreateSeedIfNotExists(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	b, saltSize, err := createSeedIfNotExists(dir)
+	b, saltSize, err := createSeedIfNotExists(dir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
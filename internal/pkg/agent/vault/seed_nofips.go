@@ -9,19 +9,18 @@ package vault
 import (
 	"errors"
 	"os"
-	"path/filepath"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
 )
 
 // getSeed returns the seed from the v1 .seed file
 // or fs.ErrNotExist if the bytecount does not match
-func getSeed(path string) ([]byte, int, error) {
+func getSeed(path string, protector seedProtector) ([]byte, int, error) {
 	mxSeed.Lock()
 	defer mxSeed.Unlock()
 
 	// Non fips only supports V1 seed
-	b, err := getSeedV1(path)
+	b, err := getSeedV1(path, protector)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -35,11 +34,11 @@ func checkSalt(_ int) error {
 
 // createSeedIfNotExists returns the seed from the v1 .seed file
 // If the seed file does not exist it will create and write a new v1 seed file.
-func createSeedIfNotExists(path string) ([]byte, int, error) {
+func createSeedIfNotExists(path string, protector seedProtector) ([]byte, int, error) {
 	mxSeed.Lock()
 	defer mxSeed.Unlock()
 
-	pass, err := getSeedV1(path)
+	pass, err := getSeedV1(path, protector)
 	if err != nil {
 		if !errors.Is(err, os.ErrNotExist) {
 			return nil, 0, err
@@ -53,7 +52,7 @@ func createSeedIfNotExists(path string) ([]byte, int, error) {
 	if err != nil {
 		return nil, 0, err
 	}
-	err = os.WriteFile(filepath.Join(path, seedFile), seed, 0600)
+	err = writeSeedFile(path, seedFile, seed, protector)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -35,7 +35,7 @@ func TestGetSeedReturnsV1File(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	b, saltSize, err := getSeed(dir)
+	b, saltSize, err := getSeed(dir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -66,7 +66,7 @@ func TestGetSeedFailsV2File(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, _, err = getSeed(dir)
+	_, _, err = getSeed(dir, nil)
 	if !errors.Is(err, os.ErrNotExist) {
 		t.Fatalf("non-FIPS mode must not read v2 seeds. expected error %v to be os.ErrNotExist", err)
 	}
@@ -85,7 +85,7 @@ func TestCreateSeedIfNotExists(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	b, saltSize, err := createSeedIfNotExists(dir)
+	b, saltSize, err := createSeedIfNotExists(dir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
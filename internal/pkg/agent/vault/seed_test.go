@@ -31,7 +31,7 @@ func TestGetSeedV1(t *testing.T) {
 	}
 
 	// seed is not yet created
-	if _, err := getSeedV1(dir); !errors.Is(err, os.ErrNotExist) {
+	if _, err := getSeedV1(dir, nil); !errors.Is(err, os.ErrNotExist) {
 		t.Fatal(err)
 	}
 	// should be not found
@@ -53,7 +53,7 @@ func TestGetSeedV1(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	b, err := getSeedV1(dir)
+	b, err := getSeedV1(dir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -79,7 +79,7 @@ func TestGetSeedV2(t *testing.T) {
 	}
 
 	// seed is not yet created
-	if _, _, err := getSeedV2(dir); !errors.Is(err, os.ErrNotExist) {
+	if _, _, err := getSeedV2(dir, nil); !errors.Is(err, os.ErrNotExist) {
 		t.Fatal(err)
 	}
 
@@ -104,7 +104,7 @@ func TestGetSeedV2(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	b, saltSize, err := getSeedV2(dir)
+	b, saltSize, err := getSeedV2(dir, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -136,7 +136,7 @@ func TestCreateSeedIfNotExistsRace(t *testing.T) {
 	for i := 0; i < count; i++ {
 		g.Go(func(idx int) func() error {
 			return func() error {
-				seed, _, err := createSeedIfNotExists(dir)
+				seed, _, err := createSeedIfNotExists(dir, nil)
 				mx.Lock()
 				res[idx] = seed
 				mx.Unlock()
@@ -6,6 +6,7 @@ package vault
 
 import (
 	"context"
+	"fmt"
 	"runtime"
 	"time"
 )
@@ -32,7 +33,19 @@ func New(ctx context.Context, opts ...OptionFunc) (Vault, error) {
 		return nil, err
 	}
 
-	if runtime.GOOS == "darwin" && !options.unprivileged {
+	cfg, err := options.backendConfig()
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Backend {
+	case BackendDefault:
+		if runtime.GOOS == "darwin" && !options.unprivileged {
+			return NewDarwinKeyChainVault(ctx, options)
+		}
+	case BackendKeychain:
+		if options.unprivileged {
+			return nil, fmt.Errorf("vault backend %q requires a privileged Elastic Agent", cfg.Backend)
+		}
 		return NewDarwinKeyChainVault(ctx, options)
 	}
 
@@ -0,0 +1,215 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
+)
+
+// Backend is the backend storing the secrets of the vault.
+type Backend string
+
+const (
+	// BackendDefault is the keychain on a privileged macOS install and the file vault otherwise.
+	BackendDefault Backend = ""
+	// BackendFile is the file vault, its seed is stored in a file of the vault path.
+	BackendFile Backend = "file"
+	// BackendKeychain is the macOS keychain.
+	BackendKeychain Backend = "keychain"
+	// BackendTPM is the file vault with its seed sealed by a key of the TPM, Windows only.
+	BackendTPM Backend = "tpm"
+	// BackendKeyring is the file vault with its seed encrypted by a key of the Linux kernel keyring, provisioned on
+	// boot by the secret management of the host.
+	BackendKeyring Backend = "keyring"
+	// BackendKMS is the file vault with its seed wrapped by an external KMS command.
+	BackendKMS Backend = "kms"
+)
+
+// String returns the name of the backend, "default" for the default one.
+func (b Backend) String() string {
+	if b == BackendDefault {
+		return "default"
+	}
+	return string(b)
+}
+
+// backendFile is the file of the vault path the configuration of its backend is persisted in, the backend of a
+// vault cannot change once its secrets are stored.
+const backendFile = ".backend"
+
+const (
+	defaultTPMKey     = "Elastic Agent Vault"
+	defaultKeyringKey = "elastic-agent:vault"
+	defaultKMSTimeout = 30 * time.Second
+)
+
+// ErrBackendUnavailable is returned when the backend of the vault is not available on the platform.
+var ErrBackendUnavailable = errors.New("vault backend is not available on this platform")
+
+// Config is the configuration of the backend of the vault, read from agent.vault.
+type Config struct {
+	// Backend selects the backend of the vault.
+	Backend Backend       `config:"backend" yaml:"backend,omitempty" json:"backend,omitempty"`
+	TPM     TPMConfig     `config:"tpm" yaml:"tpm,omitempty" json:"tpm,omitempty"`
+	Keyring KeyringConfig `config:"keyring" yaml:"keyring,omitempty" json:"keyring,omitempty"`
+	KMS     KMSConfig     `config:"kms" yaml:"kms,omitempty" json:"kms,omitempty"`
+}
+
+// TPMConfig is the configuration of the tpm backend.
+type TPMConfig struct {
+	// Key is the name of the key of the Platform Crypto Provider sealing the seed, created when it doesn't exist.
+	Key string `config:"key" yaml:"key,omitempty" json:"key,omitempty"`
+}
+
+// KeyringConfig is the configuration of the keyring backend.
+type KeyringConfig struct {
+	// Key is the description of the user key of the kernel keyring encrypting the seed, it holds 32 bytes.
+	Key string `config:"key" yaml:"key,omitempty" json:"key,omitempty"`
+}
+
+// KMSConfig is the configuration of the kms backend.
+type KMSConfig struct {
+	// Command is the absolute path of the command wrapping the seed, it is executed with the wrap or unwrap
+	// argument, reads the seed or the wrapped seed on stdin and writes the result on stdout.
+	Command string `config:"command" yaml:"command,omitempty" json:"command,omitempty"`
+	// Timeout of the command.
+	Timeout time.Duration `config:"timeout" yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Validate validates the configuration.
+func (c *Config) Validate() error {
+	switch c.Backend {
+	case BackendDefault, BackendFile:
+	case BackendKeychain:
+		if runtime.GOOS != "darwin" {
+			return fmt.Errorf("vault backend %q is only available on macOS: %w", c.Backend, ErrBackendUnavailable)
+		}
+	case BackendTPM:
+		if runtime.GOOS != "windows" {
+			return fmt.Errorf("vault backend %q is only available on Windows: %w", c.Backend, ErrBackendUnavailable)
+		}
+	case BackendKeyring:
+		if runtime.GOOS != "linux" {
+			return fmt.Errorf("vault backend %q is only available on Linux: %w", c.Backend, ErrBackendUnavailable)
+		}
+	case BackendKMS:
+		if !filepath.IsAbs(c.KMS.Command) {
+			return fmt.Errorf("vault kms command %q must be an absolute path", c.KMS.Command)
+		}
+	default:
+		return fmt.Errorf("unknown vault backend %q", c.Backend)
+	}
+	return nil
+}
+
+// IsDefault returns true when the secrets are stored in the default backend of the platform.
+func (c Config) IsDefault() bool {
+	return c.Backend == BackendDefault
+}
+
+// Check checks the backend can be used on this host, its protection of the seed is tested with a random seed.
+func (c Config) Check() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	protector, err := newSeedProtector(c)
+	if err != nil || protector == nil {
+		return err
+	}
+	seed, err := aesgcm.NewKey(aesgcm.AES256)
+	if err != nil {
+		return err
+	}
+	protected, err := protector.Protect(seed)
+	if err != nil {
+		return fmt.Errorf("vault backend %q failed to protect the seed: %w", c.Backend, err)
+	}
+	if _, err := protector.Unprotect(protected); err != nil {
+		return fmt.Errorf("vault backend %q failed to unprotect the seed: %w", c.Backend, err)
+	}
+	return nil
+}
+
+// LoadConfig returns the configuration of the backend of the vault at path, the default one when it was not saved.
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(filepath.Join(path, backendFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("could not read vault backend: %w", err)
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("could not parse vault backend %s: %w", filepath.Join(path, backendFile), err)
+	}
+	return cfg, cfg.Validate()
+}
+
+// SaveConfig persists the configuration of the backend of the vault at path, it must be saved before the vault
+// stores secrets.
+func SaveConfig(path string, cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("could not marshal vault backend: %w", err)
+	}
+	if err := os.MkdirAll(path, 0750); err != nil {
+		return fmt.Errorf("failed to create vault path: %v, err: %w", path, err)
+	}
+	return writeFile(filepath.Join(path, backendFile), b)
+}
+
+// backendConfig returns the configuration of the backend of the options, or the one saved in the vault path.
+func (o Options) backendConfig() (Config, error) {
+	if o.config != nil {
+		return *o.config, o.config.Validate()
+	}
+	return LoadConfig(o.vaultPath)
+}
+
+// seedProtector protects the seed of the file vault at rest, the protected seed is stored in place of the seed.
+type seedProtector interface {
+	Protect(seed []byte) ([]byte, error)
+	Unprotect(protected []byte) ([]byte, error)
+}
+
+// newSeedProtector returns the protector of the backend, nil when the seed is stored as is.
+func newSeedProtector(cfg Config) (seedProtector, error) {
+	switch cfg.Backend {
+	case BackendTPM:
+		key := cfg.TPM.Key
+		if key == "" {
+			key = defaultTPMKey
+		}
+		return newTPMProtector(key)
+	case BackendKeyring:
+		key := cfg.Keyring.Key
+		if key == "" {
+			key = defaultKeyringKey
+		}
+		return newKeyringProtector(key)
+	case BackendKMS:
+		timeout := cfg.KMS.Timeout
+		if timeout <= 0 {
+			timeout = defaultKMSTimeout
+		}
+		return newKMSProtector(cfg.KMS.Command, timeout)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+)
+
+func TestConfigValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{},
+		{Backend: BackendFile},
+		{Backend: BackendKMS, KMS: KMSConfig{Command: filepath.Join(t.TempDir(), "kms")}},
+	} {
+		assert.NoError(t, cfg.Validate(), cfg.Backend)
+	}
+
+	assert.Error(t, (&Config{Backend: "unknown"}).Validate())
+	assert.Error(t, (&Config{Backend: BackendKMS, KMS: KMSConfig{Command: "kms"}}).Validate(), "the kms command must be absolute")
+	if runtime.GOOS != "darwin" {
+		assert.ErrorIs(t, (&Config{Backend: BackendKeychain}).Validate(), ErrBackendUnavailable)
+	}
+	if runtime.GOOS != "windows" {
+		assert.ErrorIs(t, (&Config{Backend: BackendTPM}).Validate(), ErrBackendUnavailable)
+	}
+	if runtime.GOOS != "linux" {
+		assert.ErrorIs(t, (&Config{Backend: BackendKeyring}).Validate(), ErrBackendUnavailable)
+	}
+}
+
+func TestSaveLoadConfig(t *testing.T) {
+	vaultPath := getTestFileVaultPath(t)
+
+	cfg, err := LoadConfig(vaultPath)
+	require.NoError(t, err)
+	assert.True(t, cfg.IsDefault(), "a vault without saved backend uses the default one")
+
+	saved := Config{Backend: BackendKMS, KMS: KMSConfig{Command: "/usr/local/bin/kms", Timeout: 10 * time.Second}}
+	require.NoError(t, SaveConfig(vaultPath, saved))
+	cfg, err = LoadConfig(vaultPath)
+	require.NoError(t, err)
+	assert.Equal(t, saved, cfg)
+
+	assert.Error(t, SaveConfig(vaultPath, Config{Backend: "unknown"}))
+}
+
+func TestUnavailableBackends(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		assert.ErrorIs(t, Config{Backend: BackendTPM}.Check(), ErrBackendUnavailable)
+	}
+	if runtime.GOOS != "linux" {
+		assert.ErrorIs(t, Config{Backend: BackendKeyring}.Check(), ErrBackendUnavailable)
+	}
+}
+
+// writeTestKMSCommand writes a kms command encoding the seed in base64, enough to tell a wrapped seed apart.
+func writeTestKMSCommand(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test kms command is a shell script")
+	}
+	command := filepath.Join(t.TempDir(), "kms")
+	script := `#!/bin/sh
+case "$1" in
+wrap) base64 ;;
+unwrap) base64 -d ;;
+*) echo "unknown action $1" >&2; exit 1 ;;
+esac
+`
+	require.NoError(t, os.WriteFile(command, []byte(script), 0o700))
+	return command
+}
+
+func TestFileVaultKMS(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "vault does not use NewGCMWithRandomNonce.")
+	ctx := context.Background()
+	vaultPath := getTestFileVaultPath(t)
+	cfg := Config{Backend: BackendKMS, KMS: KMSConfig{Command: writeTestKMSCommand(t)}}
+	require.NoError(t, cfg.Check())
+	require.NoError(t, SaveConfig(vaultPath, cfg))
+
+	v, err := New(ctx, WithVaultPath(vaultPath))
+	require.NoError(t, err)
+	require.NoError(t, v.Set(ctx, "key", []byte("secret")))
+	require.NoError(t, v.Close())
+
+	protected, err := filepath.Glob(filepath.Join(vaultPath, ".seed*"+protectedSuffix))
+	require.NoError(t, err)
+	assert.Len(t, protected, 1, "the seed is stored protected")
+	plain, err := filepath.Glob(filepath.Join(vaultPath, ".seed"))
+	require.NoError(t, err)
+	assert.Empty(t, plain, "the seed is not stored as is")
+
+	// the saved backend is used when the vault is opened again
+	v, err = New(ctx, WithVaultPath(vaultPath), WithReadonly(true))
+	require.NoError(t, err)
+	b, err := v.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(b))
+
+	// the seed cannot be read without the kms
+	_, err = New(ctx, WithVaultPath(vaultPath), WithReadonly(true), WithConfig(Config{Backend: BackendFile}))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "unexpected error: %v", err)
+}
+
+func TestKMSCommandCheck(t *testing.T) {
+	command := writeTestKMSCommand(t)
+	require.NoError(t, os.Chmod(command, 0o722))
+	_, err := newKMSProtector(command, time.Second)
+	assert.Error(t, err, "a command writable by others is refused")
+
+	_, err = newKMSProtector(filepath.Join(t.TempDir(), "missing"), time.Second)
+	assert.Error(t, err)
+
+	// the command is checked again before it receives the seed
+	require.NoError(t, os.Chmod(command, 0o700))
+	protector, err := newKMSProtector(command, time.Second)
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(command, 0o722))
+	_, err = protector.Protect([]byte("seed"))
+	assert.ErrorContains(t, err, "must not be writable by its group or others")
+}
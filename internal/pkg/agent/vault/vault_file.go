@@ -22,6 +22,8 @@ type FileVault struct {
 	path     string
 	seed     []byte
 	saltSize int
+	// protector protects the seed at rest, nil when it is stored as is
+	protector seedProtector
 
 	lockRetryDelay time.Duration
 	lock           *flock.Flock
@@ -61,8 +63,20 @@ func NewFileVault(ctx context.Context, options Options) (v *FileVault, err error
 		}
 	}
 
+	// the backend is read from the resolved path, the vault path of the options can be relative to the executable
+	options.vaultPath = path
+	cfg, err := options.backendConfig()
+	if err != nil {
+		return nil, err
+	}
+	protector, err := newSeedProtector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	r := &FileVault{
 		path:           path,
+		protector:      protector,
 		lockRetryDelay: options.lockRetryDelay,
 		lock:           flock.New(filepath.Join(path, lockFile)),
 	}
@@ -75,7 +89,7 @@ func NewFileVault(ctx context.Context, options Options) (v *FileVault, err error
 		err = r.unlockAndJoinErrors(err)
 	}()
 
-	r.seed, r.saltSize, err = getOrCreateSeed(path, options.readonly)
+	r.seed, r.saltSize, err = getOrCreateSeed(path, options.readonly, r.protector)
 	if err != nil {
 		return nil, fmt.Errorf("could not get or create seed for the vault at %s: %w", path, err)
 	}
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package vault
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault/aesgcm"
+)
+
+// keyringProtector encrypts the seed with a user key of the kernel keyring of the user running the agent. The
+// kernel keyring doesn't persist across reboots, the key is provisioned on boot by the secret management of the
+// host so it is never stored on disk next to the seed.
+type keyringProtector struct {
+	description string
+}
+
+func newKeyringProtector(description string) (*keyringProtector, error) {
+	return &keyringProtector{description: description}, nil
+}
+
+func (p *keyringProtector) Protect(seed []byte) ([]byte, error) {
+	key, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	return aesgcm.Encrypt(key, seed)
+}
+
+func (p *keyringProtector) Unprotect(protected []byte) ([]byte, error) {
+	key, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	return aesgcm.Decrypt(key, protected)
+}
+
+// key reads the key from the user keyring.
+func (p *keyringProtector) key() ([]byte, error) {
+	id, err := unix.KeyctlSearch(unix.KEY_SPEC_USER_KEYRING, "user", p.description, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not find user key %q in the kernel keyring: %w", p.description, err)
+	}
+	// one more byte than the key to detect a longer key
+	key := make([]byte, int(aesgcm.AES256)+1)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, key, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not read user key %q of the kernel keyring: %w", p.description, err)
+	}
+	if n != int(aesgcm.AES256) {
+		return nil, fmt.Errorf("user key %q of the kernel keyring must be %d bytes", p.description, aesgcm.AES256)
+	}
+	return key[:n], nil
+}
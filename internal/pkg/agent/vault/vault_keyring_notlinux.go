@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux
+
+package vault
+
+import "fmt"
+
+func newKeyringProtector(_ string) (seedProtector, error) {
+	return nil, fmt.Errorf("vault backend %q: %w", BackendKeyring, ErrBackendUnavailable)
+}
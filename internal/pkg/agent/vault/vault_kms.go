@@ -0,0 +1,69 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package vault
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// kmsProtector wraps the seed with an external KMS command, the key wrapping the seed never leaves the KMS.
+type kmsProtector struct {
+	command string
+	timeout time.Duration
+}
+
+func newKMSProtector(command string, timeout time.Duration) (*kmsProtector, error) {
+	if err := checkKMSCommand(command); err != nil {
+		return nil, err
+	}
+	return &kmsProtector{command: command, timeout: timeout}, nil
+}
+
+// checkKMSCommand ensures the command receiving the seed cannot be replaced by a less privileged user.
+func checkKMSCommand(command string) error {
+	if err := utils.CheckTrustedFile(command); err != nil {
+		return fmt.Errorf("refusing kms command: %w", err)
+	}
+	return nil
+}
+
+func (p *kmsProtector) Protect(seed []byte) ([]byte, error) {
+	return p.run("wrap", seed)
+}
+
+func (p *kmsProtector) Unprotect(protected []byte) ([]byte, error) {
+	return p.run("unwrap", protected)
+}
+
+func (p *kmsProtector) run(action string, input []byte) ([]byte, error) {
+	// the command is checked again before every run, it could have been replaced since the protector was created
+	if err := checkKMSCommand(p.command); err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command, action) //nolint:gosec // the command is checked before it runs
+	cmd.Dir = filepath.Dir(p.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("kms command %s %s failed: %w (stderr: %s)", p.command, action, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("kms command %s %s returned no output", p.command, action)
+	}
+	return stdout.Bytes(), nil
+}
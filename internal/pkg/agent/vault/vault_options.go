@@ -29,10 +29,15 @@ type KeychainVaultOptions struct {
 	entryName string
 }
 
+type BackendVaultOptions struct {
+	config *Config
+}
+
 type Options struct {
 	CommonVaultOptions
 	FileVaultOptions
 	KeychainVaultOptions
+	BackendVaultOptions
 }
 
 // WithReadonly opens storage for read-only access only, noop for Darwin
@@ -63,6 +68,13 @@ func WithUnprivileged(unprivileged bool) OptionFunc {
 	}
 }
 
+// WithConfig allows to specify the backend of the vault, by default it is the backend saved in the vault path
+func WithConfig(cfg Config) OptionFunc {
+	return func(o *Options) {
+		o.config = &cfg
+	}
+}
+
 // ApplyOptions applies options for Windows, Linux and Mac, not all the options may be used
 func ApplyOptions(opts ...OptionFunc) (Options, error) {
 	ownership, err := utils.CurrentFileOwner()
@@ -0,0 +1,179 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// msPlatformCryptoProvider is the key storage provider of the TPM.
+	msPlatformCryptoProvider = "Microsoft Platform Crypto Provider"
+
+	ncryptMachineKeyFlag = 0x00000020
+	ncryptPadOAEPFlag    = 0x00000004
+
+	// nteBadKeyset is returned when the key doesn't exist.
+	nteBadKeyset = 0x80090016
+)
+
+var (
+	modncrypt = windows.NewLazySystemDLL("ncrypt.dll")
+
+	procNCryptOpenStorageProvider = modncrypt.NewProc("NCryptOpenStorageProvider")
+	procNCryptOpenKey             = modncrypt.NewProc("NCryptOpenKey")
+	procNCryptCreatePersistedKey  = modncrypt.NewProc("NCryptCreatePersistedKey")
+	procNCryptFinalizeKey         = modncrypt.NewProc("NCryptFinalizeKey")
+	procNCryptEncrypt             = modncrypt.NewProc("NCryptEncrypt")
+	procNCryptDecrypt             = modncrypt.NewProc("NCryptDecrypt")
+	procNCryptFreeObject          = modncrypt.NewProc("NCryptFreeObject")
+)
+
+// bcryptOAEPPaddingInfo is BCRYPT_OAEP_PADDING_INFO.
+type bcryptOAEPPaddingInfo struct {
+	algID    *uint16
+	label    *byte
+	labelLen uint32
+}
+
+// ncryptError is a SECURITY_STATUS returned by NCrypt.
+type ncryptError uint32
+
+func (e ncryptError) Error() string {
+	return fmt.Sprintf("ncrypt error 0x%08X", uint32(e))
+}
+
+// tpmProtector seals the seed with an RSA key of the TPM, created on first use as a machine key of the Platform
+// Crypto Provider. The private key never leaves the TPM, the seed can only be unsealed on this machine.
+type tpmProtector struct {
+	name string
+}
+
+func newTPMProtector(name string) (*tpmProtector, error) {
+	return &tpmProtector{name: name}, nil
+}
+
+func (p *tpmProtector) Protect(seed []byte) ([]byte, error) {
+	key, err := p.openOrCreateKey()
+	if err != nil {
+		return nil, err
+	}
+	defer freeObject(key)
+	return ncryptCrypt(procNCryptEncrypt, key, seed)
+}
+
+func (p *tpmProtector) Unprotect(protected []byte) ([]byte, error) {
+	key, err := p.openKey()
+	if err != nil {
+		return nil, err
+	}
+	defer freeObject(key)
+	return ncryptCrypt(procNCryptDecrypt, key, protected)
+}
+
+func (p *tpmProtector) openProvider() (uintptr, error) {
+	provider, err := windows.UTF16PtrFromString(msPlatformCryptoProvider)
+	if err != nil {
+		return 0, err
+	}
+	var handle uintptr
+	if err := ncryptCall(procNCryptOpenStorageProvider, uintptr(unsafe.Pointer(&handle)), uintptr(unsafe.Pointer(provider)), 0); err != nil {
+		return 0, fmt.Errorf("could not open the TPM key storage provider: %w", err)
+	}
+	return handle, nil
+}
+
+func (p *tpmProtector) openKey() (uintptr, error) {
+	provider, err := p.openProvider()
+	if err != nil {
+		return 0, err
+	}
+	defer freeObject(provider)
+
+	name, err := windows.UTF16PtrFromString(p.name)
+	if err != nil {
+		return 0, err
+	}
+	var key uintptr
+	if err := ncryptCall(procNCryptOpenKey, provider, uintptr(unsafe.Pointer(&key)), uintptr(unsafe.Pointer(name)), 0, ncryptMachineKeyFlag); err != nil {
+		return 0, fmt.Errorf("could not open TPM key %q: %w", p.name, err)
+	}
+	return key, nil
+}
+
+func (p *tpmProtector) openOrCreateKey() (uintptr, error) {
+	key, err := p.openKey()
+	if err == nil {
+		return key, nil
+	}
+	var nerr ncryptError
+	if !errors.As(err, &nerr) || nerr != nteBadKeyset {
+		return 0, err
+	}
+
+	provider, err := p.openProvider()
+	if err != nil {
+		return 0, err
+	}
+	defer freeObject(provider)
+
+	name, err := windows.UTF16PtrFromString(p.name)
+	if err != nil {
+		return 0, err
+	}
+	alg, err := windows.UTF16PtrFromString("RSA")
+	if err != nil {
+		return 0, err
+	}
+	if err := ncryptCall(procNCryptCreatePersistedKey, provider, uintptr(unsafe.Pointer(&key)), uintptr(unsafe.Pointer(alg)), uintptr(unsafe.Pointer(name)), 0, ncryptMachineKeyFlag); err != nil {
+		return 0, fmt.Errorf("could not create TPM key %q: %w", p.name, err)
+	}
+	if err := ncryptCall(procNCryptFinalizeKey, key, 0); err != nil {
+		freeObject(key)
+		return 0, fmt.Errorf("could not finalize TPM key %q: %w", p.name, err)
+	}
+	return key, nil
+}
+
+// ncryptCrypt calls NCryptEncrypt or NCryptDecrypt with OAEP SHA256 padding, the first call returns the size of
+// the output.
+func ncryptCrypt(proc *windows.LazyProc, key uintptr, input []byte) ([]byte, error) {
+	alg, err := windows.UTF16PtrFromString("SHA256")
+	if err != nil {
+		return nil, err
+	}
+	padding := bcryptOAEPPaddingInfo{algID: alg}
+
+	var size uint32
+	if err := ncryptCall(proc, key, uintptr(unsafe.Pointer(&input[0])), uintptr(len(input)), uintptr(unsafe.Pointer(&padding)), 0, 0, uintptr(unsafe.Pointer(&size)), ncryptPadOAEPFlag); err != nil {
+		return nil, fmt.Errorf("%s failed: %w", proc.Name, err)
+	}
+	output := make([]byte, size)
+	if err := ncryptCall(proc, key, uintptr(unsafe.Pointer(&input[0])), uintptr(len(input)), uintptr(unsafe.Pointer(&padding)), uintptr(unsafe.Pointer(&output[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), ncryptPadOAEPFlag); err != nil {
+		return nil, fmt.Errorf("%s failed: %w", proc.Name, err)
+	}
+	return output[:size], nil
+}
+
+func ncryptCall(proc *windows.LazyProc, args ...uintptr) error {
+	if err := proc.Find(); err != nil {
+		return err
+	}
+	r, _, _ := proc.Call(args...)
+	if r != 0 {
+		return ncryptError(r)
+	}
+	return nil
+}
+
+func freeObject(handle uintptr) {
+	_ = ncryptCall(procNCryptFreeObject, handle)
+}
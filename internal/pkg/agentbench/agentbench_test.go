@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package agentbench
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	p := Profile{Inputs: 4, StreamsPerInput: 2, Variables: 3, Conditions: 2}
+	policy, err := Generate(p)
+	require.NoError(t, err)
+
+	again, err := Generate(p)
+	require.NoError(t, err)
+	assert.Equal(t, policy, again, "the generation should be deterministic")
+
+	inputs, ok := policy.Config["inputs"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, inputs, 4)
+	assert.Len(t, policy.Vars[VarsProvider], 3)
+
+	types := make([]string, 0, len(inputs))
+	for i, raw := range inputs {
+		input, ok := raw.(map[string]interface{})
+		require.True(t, ok)
+		types = append(types, input["type"].(string))
+		assert.Len(t, input["streams"], 2)
+		_, hasCondition := input["condition"]
+		assert.Equal(t, i < 2, hasCondition, "input %d", i)
+	}
+	assert.Equal(t, []string{"filestream", "system/metrics", "filestream", "system/metrics"}, types)
+	assert.Equal(t, "${bench.var0} == 'value-0'", inputs[0].(map[string]interface{})["condition"])
+	assert.Equal(t, "${bench.var1} != 'value-1'", inputs[1].(map[string]interface{})["condition"])
+
+	stream := inputs[1].(map[string]interface{})["streams"].([]interface{})[1].(map[string]interface{})
+	assert.Equal(t, []interface{}{"/var/log/bench/${bench.var0}/1/*.log"}, stream["paths"])
+}
+
+func TestProfileValidate(t *testing.T) {
+	testCases := map[string]struct {
+		profile Profile
+		wantErr string
+	}{
+		"valid":                    {profile: Profile{Inputs: 1}},
+		"no inputs":                {profile: Profile{}, wantErr: "number of inputs must be greater than zero"},
+		"negative streams":         {profile: Profile{Inputs: 1, StreamsPerInput: -1}, wantErr: "streams per input cannot be negative"},
+		"more conditions":          {profile: Profile{Inputs: 1, Variables: 1, Conditions: 2}, wantErr: "cannot be greater than the number of inputs"},
+		"conditions without vars":  {profile: Profile{Inputs: 1, Conditions: 1}, wantErr: "conditions require at least one variable"},
+		"negative variables count": {profile: Profile{Inputs: 1, Variables: -1}, wantErr: "number of variables cannot be negative"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.profile.Validate()
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	assert.Equal(t, Summary{}, Summarize(nil))
+
+	durations := make([]time.Duration, 0, 20)
+	for i := 20; i > 0; i-- {
+		durations = append(durations, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, Summary{
+		Iterations: 20,
+		Min:        time.Millisecond,
+		Mean:       10500 * time.Microsecond,
+		P50:        10 * time.Millisecond,
+		P95:        19 * time.Millisecond,
+		Max:        20 * time.Millisecond,
+	}, Summarize(durations))
+	assert.Equal(t, 20*time.Millisecond, durations[0], "the durations should not be modified")
+}
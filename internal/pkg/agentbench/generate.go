@@ -0,0 +1,138 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package agentbench generates synthetic policies of a given size and shape and summarizes the time it takes
+// to process them, to catch performance regressions between releases and to size large deployments.
+package agentbench
+
+import (
+	"errors"
+	"fmt"
+)
+
+// VarsProvider is the name of the provider of the variables of the synthetic policies.
+const VarsProvider = "bench"
+
+// DefaultInputTypes are the input types of the synthetic policies when none are given.
+var DefaultInputTypes = []string{"filestream", "system/metrics"}
+
+// Profile describes the size and the shape of a synthetic policy.
+type Profile struct {
+	// Inputs is the number of inputs of the policy.
+	Inputs int
+	// StreamsPerInput is the number of streams of each input.
+	StreamsPerInput int
+	// Variables is the number of variables provided to the policy, referenced by the streams.
+	Variables int
+	// Conditions is the number of inputs with a condition on a variable, every other condition is false so
+	// half of the conditioned inputs are removed from the computed configuration.
+	Conditions int
+	// InputTypes are the types of the inputs, assigned in turn. Default to DefaultInputTypes.
+	InputTypes []string
+}
+
+// Validate validates the profile.
+func (p Profile) Validate() error {
+	switch {
+	case p.Inputs <= 0:
+		return errors.New("the number of inputs must be greater than zero")
+	case p.StreamsPerInput < 0:
+		return errors.New("the number of streams per input cannot be negative")
+	case p.Variables < 0:
+		return errors.New("the number of variables cannot be negative")
+	case p.Conditions < 0:
+		return errors.New("the number of conditions cannot be negative")
+	case p.Conditions > p.Inputs:
+		return fmt.Errorf("the number of conditions (%d) cannot be greater than the number of inputs (%d)", p.Conditions, p.Inputs)
+	case p.Conditions > 0 && p.Variables == 0:
+		return errors.New("conditions require at least one variable")
+	}
+	return nil
+}
+
+// Policy is a synthetic policy and the variables it references.
+type Policy struct {
+	// Config is the policy.
+	Config map[string]interface{}
+	// Vars is the mapping of the variables, under the VarsProvider key.
+	Vars map[string]interface{}
+}
+
+// Generate generates the synthetic policy of the profile. The generation is deterministic, the same profile
+// always generates the same policy.
+func Generate(p Profile) (*Policy, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	inputTypes := p.InputTypes
+	if len(inputTypes) == 0 {
+		inputTypes = DefaultInputTypes
+	}
+
+	values := make(map[string]interface{}, p.Variables)
+	for v := 0; v < p.Variables; v++ {
+		values[varName(v)] = varValue(v)
+	}
+
+	inputs := make([]interface{}, 0, p.Inputs)
+	for i := 0; i < p.Inputs; i++ {
+		inputID := fmt.Sprintf("bench-%d", i)
+		streams := make([]interface{}, 0, p.StreamsPerInput)
+		for s := 0; s < p.StreamsPerInput; s++ {
+			stream := map[string]interface{}{
+				"id": fmt.Sprintf("%s-%d", inputID, s),
+				"data_stream": map[string]interface{}{
+					"dataset": fmt.Sprintf("bench.stream%d", s),
+				},
+				"paths": []interface{}{fmt.Sprintf("/var/log/bench/%s/%d/*.log", inputID, s)},
+			}
+			if p.Variables > 0 {
+				v := (i*p.StreamsPerInput + s) % p.Variables
+				stream["paths"] = []interface{}{fmt.Sprintf("/var/log/bench/${%s.%s}/%d/*.log", VarsProvider, varName(v), s)}
+				stream["tags"] = []interface{}{fmt.Sprintf("${%s.%s}", VarsProvider, varName(v))}
+			}
+			streams = append(streams, stream)
+		}
+		input := map[string]interface{}{
+			"id":         inputID,
+			"type":       inputTypes[i%len(inputTypes)],
+			"use_output": "default",
+			"data_stream": map[string]interface{}{
+				"namespace": "default",
+			},
+			"streams": streams,
+		}
+		if i < p.Conditions {
+			v := i % p.Variables
+			op := "=="
+			if i%2 == 1 {
+				op = "!="
+			}
+			input["condition"] = fmt.Sprintf("${%s.%s} %s '%s'", VarsProvider, varName(v), op, varValue(v))
+		}
+		inputs = append(inputs, input)
+	}
+
+	return &Policy{
+		Config: map[string]interface{}{
+			"outputs": map[string]interface{}{
+				"default": map[string]interface{}{
+					"type":    "elasticsearch",
+					"hosts":   []interface{}{"http://localhost:9200"},
+					"api_key": "bench:key",
+				},
+			},
+			"inputs": inputs,
+		},
+		Vars: map[string]interface{}{VarsProvider: values},
+	}, nil
+}
+
+func varName(v int) string {
+	return fmt.Sprintf("var%d", v)
+}
+
+func varValue(v int) string {
+	return fmt.Sprintf("value-%d", v)
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package agentbench
+
+import (
+	"slices"
+	"time"
+)
+
+// Summary summarizes the durations of the iterations of a measurement.
+type Summary struct {
+	Iterations int           `json:"iterations" yaml:"iterations"`
+	Min        time.Duration `json:"min" yaml:"min"`
+	Mean       time.Duration `json:"mean" yaml:"mean"`
+	P50        time.Duration `json:"p50" yaml:"p50"`
+	P95        time.Duration `json:"p95" yaml:"p95"`
+	Max        time.Duration `json:"max" yaml:"max"`
+}
+
+// Summarize summarizes the durations, the percentiles are computed with the nearest-rank method.
+func Summarize(durations []time.Duration) Summary {
+	if len(durations) == 0 {
+		return Summary{}
+	}
+	sorted := slices.Clone(durations)
+	slices.Sort(sorted)
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return Summary{
+		Iterations: len(sorted),
+		Min:        sorted[0],
+		Mean:       total / time.Duration(len(sorted)),
+		P50:        percentile(sorted, 50),
+		P95:        percentile(sorted, 95),
+		Max:        sorted[len(sorted)-1],
+	}
+}
+
+// percentile returns the nearest-rank percentile p of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
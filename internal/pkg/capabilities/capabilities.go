@@ -19,6 +19,17 @@ type Capabilities interface {
 	AllowUpgrade(version string, sourceURI string) bool
 	AllowInput(name string) bool
 	AllowOutput(name string) bool
+
+	// InputDeniedBy returns a description of the rule denying the input,
+	// the second return value is false when the input is allowed.
+	InputDeniedBy(name string) (string, bool)
+	// OutputDeniedBy returns a description of the rule denying the output,
+	// the second return value is false when the output is allowed.
+	OutputDeniedBy(name string) (string, bool)
+
+	// FilterPolicy strips the parts of the policy denied by the selector
+	// rules in place and returns them.
+	FilterPolicy(policy map[string]interface{}) []Denial
 }
 
 type capabilitiesManager struct {
@@ -26,6 +37,7 @@ type capabilitiesManager struct {
 	inputChecks  []*stringMatcher
 	outputChecks []*stringMatcher
 	upgradeCaps  []*upgradeCapability
+	selectorCaps []*selectorCapability
 }
 
 func (cm *capabilitiesManager) AllowInput(inputType string) bool {
@@ -36,10 +48,29 @@ func (cm *capabilitiesManager) AllowOutput(outputType string) bool {
 	return matchString(outputType, cm.outputChecks)
 }
 
+func (cm *capabilitiesManager) InputDeniedBy(inputType string) (string, bool) {
+	return describeDenial(deniedBy(inputType, cm.inputChecks))
+}
+
+func (cm *capabilitiesManager) OutputDeniedBy(outputType string) (string, bool) {
+	return describeDenial(deniedBy(outputType, cm.outputChecks))
+}
+
+func describeDenial(matcher *stringMatcher, denied bool) (string, bool) {
+	if !denied {
+		return "", false
+	}
+	return matcher.String(), true
+}
+
 func (cm *capabilitiesManager) AllowUpgrade(version string, uri string) bool {
 	return allowUpgrade(cm.log, version, uri, cm.upgradeCaps)
 }
 
+func (cm *capabilitiesManager) FilterPolicy(policy map[string]interface{}) []Denial {
+	return filterPolicy(cm.log, policy, cm.selectorCaps)
+}
+
 func LoadFile(capsFile string, log *logger.Logger) (Capabilities, error) {
 	// load capabilities from file
 	fd, err := os.Open(capsFile)
@@ -68,8 +99,10 @@ func Load(capsReader io.Reader, log *logger.Logger) (Capabilities, error) {
 	caps := spec.Capabilities
 
 	return &capabilitiesManager{
+		log:          log,
 		inputChecks:  caps.inputChecks,
 		outputChecks: caps.outputChecks,
 		upgradeCaps:  caps.upgradeChecks,
+		selectorCaps: caps.selectorChecks,
 	}, nil
 }
@@ -112,3 +112,31 @@ func TestNoCaps(t *testing.T) {
 	assert.True(t, caps.AllowInput("system/logs"))
 	assert.True(t, caps.AllowOutput("elasticsearch"))
 }
+
+func TestDeniedBy(t *testing.T) {
+	yml := `
+capabilities:
+- rule: allow
+  input: system/metrics
+- rule: deny
+  input: "system/*"
+- rule: deny
+  output: kafka
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	_, denied := caps.InputDeniedBy("system/metrics")
+	assert.False(t, denied, "allowed by the first rule")
+	rule, denied := caps.InputDeniedBy("system/logs")
+	assert.True(t, denied)
+	assert.Equal(t, `capability #2 {rule: deny, input: "system/*"}`, rule)
+	_, denied = caps.InputDeniedBy("filestream")
+	assert.False(t, denied, "no rule matches")
+
+	rule, denied = caps.OutputDeniedBy("kafka")
+	assert.True(t, denied)
+	assert.Equal(t, `capability #3 {rule: deny, output: "kafka"}`, rule)
+	_, denied = caps.OutputDeniedBy("elasticsearch")
+	assert.False(t, denied)
+}
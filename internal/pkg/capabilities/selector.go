@@ -0,0 +1,193 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package capabilities
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/transpiler"
+	"github.com/elastic/elastic-agent/internal/pkg/eql"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Denial is a part of the policy stripped by a selector rule.
+type Denial struct {
+	// Path is the path of the stripped part, e.g. inputs.3 or outputs.default.hosts.
+	Path string `yaml:"path"`
+	// Rule describes the rule as it is written in the capabilities file.
+	Rule string `yaml:"rule"`
+}
+
+type selectorCapability struct {
+	// The position of the rule in the capabilities file, starting at 1.
+	index int
+
+	// The selector of the policy parts, dot separated keys where '*' matches
+	// every key of a dict or every item of a list, e.g. "inputs.*".
+	selector string
+	parts    []string
+
+	// The condition evaluated against each selected part, nil when every
+	// selected part matches.
+	condition    *eql.Expression
+	conditionStr string
+
+	// Whether matching this rule results in keeping or stripping the part.
+	rule allowOrDeny
+}
+
+func newSelectorCapability(index int, selector string, condition string, rule allowOrDeny) (*selectorCapability, error) {
+	if selector == "" {
+		return nil, fmt.Errorf("capability #%d has an empty selector", index)
+	}
+	c := &selectorCapability{
+		index:        index,
+		selector:     selector,
+		parts:        strings.Split(selector, "."),
+		conditionStr: condition,
+		rule:         rule,
+	}
+	if condition != "" {
+		expr, err := eql.New(condition)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't load selector condition %q: %w", condition, err)
+		}
+		c.condition = expr
+	}
+	return c, nil
+}
+
+// String describes the rule as it is written in the capabilities file.
+func (c *selectorCapability) String() string {
+	if c.conditionStr == "" {
+		return fmt.Sprintf("capability #%d {rule: %s, selector: %q}", c.index, c.rule, c.selector)
+	}
+	return fmt.Sprintf("capability #%d {rule: %s, selector: %q, condition: %q}", c.index, c.rule, c.selector, c.conditionStr)
+}
+
+// matches evaluates the condition against the selected part, a dict exposes
+// its keys as variables and any other value is exposed as ${value}.
+func (c *selectorCapability) matches(log *logger.Logger, node interface{}) bool {
+	if c.condition == nil {
+		return true
+	}
+	vars, ok := node.(map[string]interface{})
+	if !ok {
+		vars = map[string]interface{}{"value": node}
+	}
+	varStore, err := transpiler.NewAST(vars)
+	if err != nil {
+		log.Warnf("failed creating a varStore for %s, skipping: %v", c, err)
+		return false
+	}
+	result, err := c.condition.Eval(varStore, true)
+	if err != nil {
+		log.Warnf("failed evaluating eql formula %q, skipping: %v", c.conditionStr, err)
+		return false
+	}
+	return result
+}
+
+// stripped marks a policy part to remove once all the rules are evaluated.
+type stripped struct{}
+
+// filterPolicy strips the parts of the policy denied by the selector rules.
+// Each part is decided by the first rule selecting it with a matching
+// condition, the parts no rule decides are kept.
+func filterPolicy(log *logger.Logger, policy map[string]interface{}, selectorCaps []*selectorCapability) []Denial {
+	if len(selectorCaps) == 0 || policy == nil {
+		return nil
+	}
+	decided := make(map[string]bool)
+	var denials []Denial
+	for _, c := range selectorCaps {
+		selectPolicy(policy, c.parts, nil, func(path []string, node interface{}, set func(interface{})) {
+			key := strings.Join(path, "\x00")
+			if decided[key] || !c.matches(log, node) {
+				return
+			}
+			decided[key] = true
+			if c.rule == ruleTypeDeny {
+				denials = append(denials, Denial{Path: strings.Join(path, "."), Rule: c.String()})
+				set(stripped{})
+			}
+		})
+	}
+	prune(policy)
+	return denials
+}
+
+// selectPolicy calls fn with every part of node selected by parts, set
+// replaces the part in its parent. The keys of a dict can contain dots,
+// they are matched against as many parts of the selector.
+func selectPolicy(node interface{}, parts []string, path []string, fn func(path []string, node interface{}, set func(interface{}))) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			keyParts := strings.Split(key, ".")
+			if len(keyParts) > len(parts) || !matchParts(parts[:len(keyParts)], keyParts) {
+				continue
+			}
+			keyPath := append(slices.Clone(path), key)
+			if len(keyParts) == len(parts) {
+				fn(keyPath, v[key], func(value interface{}) { v[key] = value })
+				continue
+			}
+			selectPolicy(v[key], parts[len(keyParts):], keyPath, fn)
+		}
+	case []interface{}:
+		for i := range v {
+			if parts[0] != wild && parts[0] != strconv.Itoa(i) {
+				continue
+			}
+			itemPath := append(slices.Clone(path), strconv.Itoa(i))
+			if len(parts) == 1 {
+				fn(itemPath, v[i], func(value interface{}) { v[i] = value })
+				continue
+			}
+			selectPolicy(v[i], parts[1:], itemPath, fn)
+		}
+	}
+}
+
+func matchParts(pattern []string, target []string) bool {
+	for i, p := range pattern {
+		if p != wild && p != target[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// prune removes the stripped parts of node, it returns node without them.
+func prune(node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if _, ok := value.(stripped); ok {
+				delete(v, key)
+				continue
+			}
+			v[key] = prune(value)
+		}
+	case []interface{}:
+		kept := v[:0]
+		for _, value := range v {
+			if _, ok := value.(stripped); !ok {
+				kept = append(kept, prune(value))
+			}
+		}
+		return kept
+	}
+	return node
+}
@@ -0,0 +1,103 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package capabilities
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+func TestFilterPolicy(t *testing.T) {
+	yml := `
+capabilities:
+- rule: allow
+  selector: "inputs.*"
+  condition: "${id} == 'keep-logs'"
+- rule: deny
+  selector: "inputs.*"
+  condition: "${type} == 'filestream' or ${type} == 'osquery'"
+- rule: deny
+  selector: "outputs.*.ssl.verification_mode"
+  condition: "${value} == 'none'"
+- rule: deny
+  selector: "agent.monitoring.http"
+`
+	caps, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	require.NoError(t, err, "Loading capabilities should succeed")
+
+	policy := map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"type":                  "elasticsearch",
+				"ssl.verification_mode": "none",
+			},
+			"secure": map[string]interface{}{
+				"type": "elasticsearch",
+				"ssl": map[string]interface{}{
+					"verification_mode": "full",
+				},
+			},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{"id": "filestream-1", "type": "filestream"},
+			map[string]interface{}{"id": "keep-logs", "type": "filestream"},
+			map[string]interface{}{"id": "system-1", "type": "system/metrics"},
+			map[string]interface{}{"id": "osquery-1", "type": "osquery"},
+		},
+		"agent": map[string]interface{}{
+			"monitoring": map[string]interface{}{
+				"enabled": true,
+				"http":    map[string]interface{}{"enabled": true},
+			},
+		},
+	}
+
+	denials := caps.FilterPolicy(policy)
+	assert.Equal(t, []Denial{
+		{Path: "inputs.0", Rule: `capability #2 {rule: deny, selector: "inputs.*", condition: "${type} == 'filestream' or ${type} == 'osquery'"}`},
+		{Path: "inputs.3", Rule: `capability #2 {rule: deny, selector: "inputs.*", condition: "${type} == 'filestream' or ${type} == 'osquery'"}`},
+		{Path: "outputs.default.ssl.verification_mode", Rule: `capability #3 {rule: deny, selector: "outputs.*.ssl.verification_mode", condition: "${value} == 'none'"}`},
+		{Path: "agent.monitoring.http", Rule: `capability #4 {rule: deny, selector: "agent.monitoring.http"}`},
+	}, denials)
+
+	assert.Equal(t, map[string]interface{}{
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"type": "elasticsearch",
+			},
+			"secure": map[string]interface{}{
+				"type": "elasticsearch",
+				"ssl": map[string]interface{}{
+					"verification_mode": "full",
+				},
+			},
+		},
+		"inputs": []interface{}{
+			map[string]interface{}{"id": "keep-logs", "type": "filestream"},
+			map[string]interface{}{"id": "system-1", "type": "system/metrics"},
+		},
+		"agent": map[string]interface{}{
+			"monitoring": map[string]interface{}{
+				"enabled": true,
+			},
+		},
+	}, policy)
+}
+
+func TestFilterPolicyInvalidCondition(t *testing.T) {
+	yml := `
+capabilities:
+- rule: deny
+  selector: "inputs.*"
+  condition: "${type} =="
+`
+	_, err := Load(strings.NewReader(yml), logger.NewWithoutConfig("testing"))
+	assert.Error(t, err, "an invalid condition must fail loading")
+}
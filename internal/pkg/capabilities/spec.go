@@ -13,9 +13,10 @@ import (
 // capabilitiesList deserializes a YAML list of capabilities into organized
 // arrays based on their type, for easy use by capabilitiesManager.
 type capabilitiesList struct {
-	inputChecks   []*stringMatcher
-	outputChecks  []*stringMatcher
-	upgradeChecks []*upgradeCapability
+	inputChecks    []*stringMatcher
+	outputChecks   []*stringMatcher
+	upgradeChecks  []*upgradeCapability
+	selectorChecks []*selectorCapability
 }
 
 // a type for capability values that must equal "allow" or "deny", enforced
@@ -55,7 +56,7 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 				return err
 			}
 			r.inputChecks = append(r.inputChecks,
-				&stringMatcher{pattern: spec.Input, rule: spec.Type})
+				&stringMatcher{index: i + 1, kind: "input", pattern: spec.Input, rule: spec.Type})
 		} else if _, found = mm["output"]; found {
 			spec := struct {
 				Type   allowOrDeny `yaml:"rule"`
@@ -65,7 +66,7 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 				return err
 			}
 			r.outputChecks = append(r.outputChecks,
-				&stringMatcher{pattern: spec.Output, rule: spec.Type})
+				&stringMatcher{index: i + 1, kind: "output", pattern: spec.Output, rule: spec.Type})
 		} else if _, found = mm["upgrade"]; found {
 			// Serialize upgrade constraints to a temporary struct so we can
 			// safely assemble the associated EQL expression
@@ -81,6 +82,20 @@ func (r *capabilitiesList) UnmarshalYAML(unmarshal func(interface{}) error) erro
 				return err
 			}
 			r.upgradeChecks = append(r.upgradeChecks, cap)
+		} else if _, found = mm["selector"]; found {
+			spec := struct {
+				Type      allowOrDeny `yaml:"rule"`
+				Selector  string      `yaml:"selector"`
+				Condition string      `yaml:"condition"`
+			}{}
+			if err := yaml.Unmarshal(partialYaml, &spec); err != nil {
+				return err
+			}
+			cap, err := newSelectorCapability(i+1, spec.Selector, spec.Condition, spec.Type)
+			if err != nil {
+				return err
+			}
+			r.selectorChecks = append(r.selectorChecks, cap)
 		} else {
 			return fmt.Errorf("unexpected capability type for definition number '%d'", i)
 		}
@@ -4,7 +4,15 @@
 
 package capabilities
 
+import "fmt"
+
 type stringMatcher struct {
+	// The position of the rule in the capabilities file, starting at 1.
+	index int
+
+	// The kind of string matched by the pattern, "input" or "output".
+	kind string
+
 	// The pattern to match against, a string that can use '*' as a wildcard
 	// by itself or in between slashes, e.g.
 	// "system/metrics" matches the patterns "*", "system/*", "*/metrics".
@@ -15,13 +23,25 @@ type stringMatcher struct {
 	rule allowOrDeny
 }
 
+// String describes the rule as it is written in the capabilities file.
+func (m *stringMatcher) String() string {
+	return fmt.Sprintf("capability #%d {rule: %s, %s: %q}", m.index, m.rule, m.kind, m.pattern)
+}
+
 func matchString(str string, matchers []*stringMatcher) bool {
+	_, denied := deniedBy(str, matchers)
+	return !denied
+}
+
+// deniedBy returns the matcher denying the string, the second return value
+// is false when the string is allowed.
+func deniedBy(str string, matchers []*stringMatcher) (*stringMatcher, bool) {
 	for _, matcher := range matchers {
 		if matchesExpr(matcher.pattern, str) {
 			// The check passed, allow or reject as appropriate
-			return matcher.rule == ruleTypeAllow
+			return matcher, matcher.rule != ruleTypeAllow
 		}
 	}
 	// If nothing blocked it, default to allow.
-	return true
+	return nil, false
 }
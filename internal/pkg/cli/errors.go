@@ -0,0 +1,88 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+const (
+	// ErrorFormatFlag is the name of the flag selecting the format errors are written in.
+	ErrorFormatFlag = "error-format"
+
+	// ErrorFormatText writes errors as plain text.
+	ErrorFormatText = "text"
+	// ErrorFormatJSON writes errors as a JSON object with the code of the error from the error catalog.
+	ErrorFormatJSON = "json"
+)
+
+// ValidateErrorFormat returns an error when the format is not a supported error format.
+func ValidateErrorFormat(format string) error {
+	switch format {
+	case ErrorFormatText, ErrorFormatJSON:
+		return nil
+	}
+	return errors.New(fmt.Sprintf("unsupported error format %q, must be one of %q or %q", format, ErrorFormatText, ErrorFormatJSON), errors.TypeValidation)
+}
+
+// ErrorFormatFromArgs returns the error format selected in the command line arguments. The arguments are
+// scanned directly so the format is known even when parsing the flags of the command fails.
+func ErrorFormatFromArgs(args []string) string {
+	flag := "--" + ErrorFormatFlag
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value
+		}
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ErrorFormatText
+}
+
+type errorOutput struct {
+	Error errorDetails `json:"error"`
+}
+
+type errorDetails struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Meta    map[string]interface{} `json:"meta,omitempty"`
+}
+
+// WriteError writes the error to the writer in the format. In the JSON format the error is written as
+// {"error": {"code": "...", "message": "...", "meta": {...}}} on a single line, where code is the stable
+// code of the error in the error catalog shared with the daemon.
+func WriteError(w io.Writer, format string, err error) {
+	if format != ErrorFormatJSON {
+		fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	out := errorOutput{
+		Error: errorDetails{
+			Code:    errors.Code(err),
+			Message: err.Error(),
+			Meta:    errors.Metadata(err),
+		},
+	}
+	if len(out.Error.Meta) == 0 {
+		out.Error.Meta = nil
+	}
+	data, jsonErr := json.Marshal(out)
+	if jsonErr != nil {
+		// metadata is not serializable, the code and the message are enough
+		out.Error.Meta = nil
+		data, _ = json.Marshal(out)
+	}
+	fmt.Fprintf(w, "%s\n", data)
+}
@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+)
+
+func TestErrorFormatFromArgs(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		expected string
+	}{
+		"default":        {args: []string{"elastic-agent", "status"}, expected: ErrorFormatText},
+		"with equals":    {args: []string{"elastic-agent", "status", "--error-format=json"}, expected: ErrorFormatJSON},
+		"separate value": {args: []string{"elastic-agent", "--error-format", "json", "status"}, expected: ErrorFormatJSON},
+		"missing value":  {args: []string{"elastic-agent", "status", "--error-format"}, expected: ErrorFormatText},
+		"after dash":     {args: []string{"elastic-agent", "otel", "--", "--error-format=json"}, expected: ErrorFormatText},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ErrorFormatFromArgs(tc.args))
+		})
+	}
+}
+
+func TestValidateErrorFormat(t *testing.T) {
+	assert.NoError(t, ValidateErrorFormat(ErrorFormatText))
+	assert.NoError(t, ValidateErrorFormat(ErrorFormatJSON))
+	err := ValidateErrorFormat("xml")
+	assert.Error(t, err)
+	assert.Equal(t, "VALIDATION", errors.Code(err))
+}
+
+func TestWriteError(t *testing.T) {
+	err := fmt.Errorf("upgrade failed: %w", errors.New("fetching package failed", errors.TypeNetwork, errors.M(errors.MetaKeyURI, "https://artifacts.elastic.co")))
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		WriteError(&buf, ErrorFormatText, err)
+		assert.Equal(t, "upgrade failed: fetching package failed\n", buf.String())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		WriteError(&buf, ErrorFormatJSON, err)
+		assert.JSONEq(t, `{"error": {"code": "NETWORK", "message": "upgrade failed: fetching package failed", "meta": {"uri": "https://artifacts.elastic.co"}}}`, buf.String())
+	})
+
+	t.Run("json without metadata", func(t *testing.T) {
+		var buf bytes.Buffer
+		WriteError(&buf, ErrorFormatJSON, fmt.Errorf("plain error"))
+		assert.JSONEq(t, `{"error": {"code": "UNEXPECTED", "message": "plain error"}}`, buf.String())
+	})
+}
@@ -17,3 +17,11 @@ type Config struct {
 	ProvidersRestartInterval *time.Duration            `config:"agent.providers.restart_interval"`
 	ProvidersDefaultProvider *string                   `config:"agent.providers.default"`
 }
+
+// DefaultProvider returns the provider of the variables without a provider prefix.
+func (c *Config) DefaultProvider() string {
+	if c.ProvidersDefaultProvider != nil {
+		return *c.ProvidersDefaultProvider
+	}
+	return defaultDefaultProvider
+}
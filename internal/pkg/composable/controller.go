@@ -108,10 +108,7 @@ func NewWithProviders(log *logger.Logger, c *config.Config, managed bool, provid
 		restartInterval = *providersCfg.ProvidersRestartInterval
 	}
 
-	defaultProvider := defaultDefaultProvider
-	if providersCfg.ProvidersDefaultProvider != nil {
-		defaultProvider = *providersCfg.ProvidersDefaultProvider
-	}
+	defaultProvider := providersCfg.DefaultProvider()
 
 	// build all the context providers
 	contextProviders := map[string]contextProvider{}
@@ -122,6 +119,7 @@ func NewWithProviders(log *logger.Logger, c *config.Config, managed bool, provid
 			continue
 		}
 		contextProviders[name] = contextProvider{
+			typ:     name,
 			builder: builder,
 			cfg:     pCfg,
 		}
@@ -136,11 +134,18 @@ func NewWithProviders(log *logger.Logger, c *config.Config, managed bool, provid
 			continue
 		}
 		dynamicProviders[name] = dynamicProvider{
+			typ:     name,
 			builder: builder,
 			cfg:     pCfg,
 		}
 	}
 
+	// build all the named instances of the registered providers
+	err := addProviderInstances(providersCfg.Providers, providers, contextProviders, dynamicProviders)
+	if err != nil {
+		return nil, err
+	}
+
 	return &controller{
 		logger:                  l,
 		ch:                      make(chan []*transpiler.Vars, 1),
@@ -455,6 +460,9 @@ func (c *controller) startContextProvider(ctx context.Context, wg *sync.WaitGrou
 
 			fp, fpok := provider.(corecomp.FetchContextProvider)
 			if fpok {
+				if info.typ != name {
+					fp = &instanceFetchProvider{name: name, typ: info.typ, provider: fp}
+				}
 				sendFetchProvider(ctx, fetchCh, name, fp)
 			}
 
@@ -577,15 +585,97 @@ func closeProvider(l *logger.Logger, name string, provider interface{}) {
 }
 
 type contextProvider struct {
+	typ     string
 	builder ContextProviderBuilder
 	cfg     *config.Config
 }
 
 type dynamicProvider struct {
+	typ     string
 	builder DynamicProviderBuilder
 	cfg     *config.Config
 }
 
+// providerInstanceConfig is the part of a provider configuration that declares
+// it as a named instance of a registered provider type.
+type providerInstanceConfig struct {
+	Type string `config:"type"`
+}
+
+// addProviderInstances adds the named instances of the registered providers.
+//
+// A named instance is a provider configuration under a name that is not a registered provider and
+// sets the `type` to a registered provider. This allows the same provider to be run multiple times
+// with different configurations, each with its variables namespaced by the instance name.
+func addProviderInstances(cfgs map[string]*config.Config, providers *ProviderRegistry, contextProviders map[string]contextProvider, dynamicProviders map[string]dynamicProvider) error {
+	for name, pCfg := range cfgs {
+		if pCfg == nil {
+			continue
+		}
+		var instanceCfg providerInstanceConfig
+		err := pCfg.UnpackTo(&instanceCfg)
+		if err != nil {
+			return errors.New(err, fmt.Sprintf("failed to unpack provider %q config", name), errors.TypeConfig)
+		}
+		if instanceCfg.Type == "" || instanceCfg.Type == name {
+			// not a named instance
+			continue
+		}
+		if providers.exists(name) {
+			return errors.New(fmt.Sprintf("provider %q cannot be an instance of %q, the name is already used by a registered provider", name, instanceCfg.Type), errors.TypeConfig)
+		}
+		if strings.ToLower(name) != name {
+			return errors.New(fmt.Sprintf("provider %q name must be lowercase", name), errors.TypeConfig)
+		}
+		if !pCfg.Enabled() {
+			// explicitly disabled; skipping
+			continue
+		}
+		if builder, ok := providers.GetContextProvider(instanceCfg.Type); ok {
+			contextProviders[name] = contextProvider{
+				typ:     instanceCfg.Type,
+				builder: builder,
+				cfg:     pCfg,
+			}
+			continue
+		}
+		if builder, ok := providers.GetDynamicProvider(instanceCfg.Type); ok {
+			dynamicProviders[name] = dynamicProvider{
+				typ:     instanceCfg.Type,
+				builder: builder,
+				cfg:     pCfg,
+			}
+			continue
+		}
+		return errors.New(fmt.Sprintf("provider %q is of unknown type %q", name, instanceCfg.Type), errors.TypeConfig)
+	}
+	return nil
+}
+
+// instanceFetchProvider wraps a fetch context provider of a named instance.
+//
+// Fetch providers expect the keys to be prefixed by their type, so the instance name prefix
+// is replaced with the type of the provider before fetching.
+type instanceFetchProvider struct {
+	name     string
+	typ      string
+	provider corecomp.FetchContextProvider
+}
+
+// Run runs the wrapped provider.
+func (p *instanceFetchProvider) Run(ctx context.Context, comm corecomp.ContextProviderComm) error {
+	return p.provider.Run(ctx, comm)
+}
+
+// Fetch fetches the key from the wrapped provider.
+func (p *instanceFetchProvider) Fetch(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, p.name+".")
+	if !ok {
+		return "", false
+	}
+	return p.provider.Fetch(p.typ + "." + rest)
+}
+
 type fetchProvider struct {
 	name          string
 	fetchProvider corecomp.FetchContextProvider
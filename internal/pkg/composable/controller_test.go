@@ -219,6 +219,169 @@ func TestControllerWithFetchProvider(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestProviderInstances(t *testing.T) {
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+
+	t.Run("namespaced variables", func(t *testing.T) {
+		cfg, err := config.NewConfigFrom(map[string]interface{}{
+			"providers": map[string]interface{}{
+				"inventory_east": map[string]interface{}{
+					"type": "local",
+					"vars": map[string]interface{}{
+						"region": "east",
+					},
+				},
+				"inventory_west": map[string]interface{}{
+					"type": "local",
+					"vars": map[string]interface{}{
+						"region": "west",
+					},
+				},
+				"inventory_disabled": map[string]interface{}{
+					"type":    "local",
+					"enabled": false,
+				},
+			},
+		})
+		require.NoError(t, err)
+		c, err := composable.New(log, cfg, false)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		var setVars []*transpiler.Vars
+		go func() {
+			defer cancel()
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.Watch():
+				var observeErr error
+				setVars, observeErr = c.Observe(ctx, []string{"inventory_east.region", "inventory_west.region", "inventory_disabled.region"})
+				assert.NoError(t, observeErr)
+			}
+		}()
+
+		err = c.Run(ctx)
+		if errors.Is(err, context.Canceled) {
+			err = nil
+		}
+		require.NoError(t, err)
+
+		require.Len(t, setVars, 1)
+		varsMap, err := setVars[0].Map()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"inventory_east": map[string]interface{}{"region": "east"},
+			"inventory_west": map[string]interface{}{"region": "west"},
+		}, varsMap)
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		cfg, err := config.NewConfigFrom(map[string]interface{}{
+			"providers": map[string]interface{}{
+				"inventory": map[string]interface{}{
+					"type": "unknown",
+				},
+			},
+		})
+		require.NoError(t, err)
+		_, err = composable.New(log, cfg, false)
+		assert.ErrorContains(t, err, `provider "inventory" is of unknown type "unknown"`)
+	})
+
+	t.Run("name of registered provider", func(t *testing.T) {
+		cfg, err := config.NewConfigFrom(map[string]interface{}{
+			"providers": map[string]interface{}{
+				"host": map[string]interface{}{
+					"type": "local",
+				},
+			},
+		})
+		require.NoError(t, err)
+		_, err = composable.New(log, cfg, false)
+		assert.ErrorContains(t, err, `provider "host" cannot be an instance of "local"`)
+	})
+
+	t.Run("same type as name", func(t *testing.T) {
+		cfg, err := config.NewConfigFrom(map[string]interface{}{
+			"providers": map[string]interface{}{
+				"local": map[string]interface{}{
+					"type": "local",
+				},
+			},
+		})
+		require.NoError(t, err)
+		_, err = composable.New(log, cfg, false)
+		assert.NoError(t, err)
+	})
+}
+
+func TestProviderInstancesWithFetchProvider(t *testing.T) {
+	providers := composable.NewProviderRegistry()
+	providers.MustAddContextProvider("custom_fetch", func(_ *logger.Logger, _ *config.Config, _ bool) (corecomp.ContextProvider, error) {
+		return &customFetchProvider{}, nil
+	})
+
+	cfg, err := config.NewConfigFrom(map[string]interface{}{
+		"providers": map[string]interface{}{
+			"other_fetch": map[string]interface{}{
+				"type": "custom_fetch",
+			},
+		},
+	})
+	require.NoError(t, err)
+	log, err := logger.New("", false)
+	require.NoError(t, err)
+	c, err := composable.NewWithProviders(log, cfg, false, providers)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	observed := false
+	replaced := make(chan string, 1)
+	go func() {
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case vars := <-c.Watch():
+				if !observed {
+					var observeErr error
+					vars, observeErr = c.Observe(ctx, []string{"other_fetch.vars.key1"})
+					if observeErr != nil {
+						return
+					}
+					observed = true
+				}
+				if len(vars) > 0 {
+					node, err := vars[0].Replace("${other_fetch.vars.key1}")
+					if err == nil {
+						replaced <- node.String()
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	err = c.Run(ctx)
+	if errors.Is(err, context.Canceled) {
+		err = nil
+	}
+	require.NoError(t, err)
+	select {
+	case val := <-replaced:
+		assert.Equal(t, "vars.key1", val)
+	default:
+		t.Fatal("variable was never replaced by the fetch provider instance")
+	}
+}
+
 func TestProvidersDefaultDisabled(t *testing.T) {
 	tests := []struct {
 		name     string
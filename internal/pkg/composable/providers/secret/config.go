@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import "time"
+
+// Config for the secret provider.
+type Config struct {
+	// RefreshInterval is the interval the referenced secrets are read again from the vault, the policy is rendered
+	// again when one of them changed.
+	RefreshInterval time.Duration `config:"refresh_interval" validate:"positive,nonzero"`
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		RefreshInterval: 30 * time.Second,
+	}
+}
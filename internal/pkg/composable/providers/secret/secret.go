@@ -0,0 +1,155 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+
+	agentsecret "github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	agenterrors "github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	corecomp "github.com/elastic/elastic-agent/internal/pkg/core/composable"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+var _ corecomp.FetchContextProvider = (*contextProvider)(nil)
+
+const secretProviderName = "secret"
+
+// fetchTimeout bounds the time to read a secret while the policy is rendered, the vault is locked while it is
+// written.
+const fetchTimeout = 5 * time.Second
+
+func init() {
+	composable.Providers.MustAddContextProvider(secretProviderName, ContextProviderBuilder)
+}
+
+// fetched is the value of a referenced secret when it was last read from the vault.
+type fetched struct {
+	value string
+	found bool
+}
+
+type contextProvider struct {
+	logger    *logger.Logger
+	config    *Config
+	vaultOpts []vault.OptionFunc
+
+	mx      sync.Mutex
+	fetched map[string]fetched
+}
+
+// ContextProviderBuilder builds the secret context provider. It resolves ${secret.<name>} with the secrets stored
+// in the vault of the Elastic Agent with `elastic-agent secrets set <name>`, they never appear in plaintext in the
+// policy. The referenced secrets are read again every Config.RefreshInterval and the provider signals the agent when
+// one of them changed.
+func ContextProviderBuilder(logger *logger.Logger, c *config.Config, _ bool) (corecomp.ContextProvider, error) {
+	cfg := defaultConfig()
+	if c == nil {
+		c = config.New()
+	}
+	if err := c.UnpackTo(cfg); err != nil {
+		return nil, agenterrors.New(err, "failed to unpack configuration")
+	}
+	hasRoot, err := utils.HasRoot()
+	if err != nil {
+		return nil, agenterrors.New(err, "failed to check for root/Administrator privileges")
+	}
+	return &contextProvider{
+		logger:    logger,
+		config:    cfg,
+		vaultOpts: []vault.OptionFunc{vault.WithUnprivileged(!hasRoot)},
+		fetched:   make(map[string]fetched),
+	}, nil
+}
+
+// Run refreshes the referenced secrets until the provider is stopped.
+func (p *contextProvider) Run(ctx context.Context, comm corecomp.ContextProviderComm) error {
+	ticker := time.NewTicker(p.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-comm.Done():
+			return comm.Err()
+		case <-ticker.C:
+			if p.refresh(ctx) {
+				p.logger.Info("Referenced secrets changed, the policy will be rendered again")
+				comm.Signal()
+			}
+		}
+	}
+}
+
+// Fetch returns the value of the secret.<name> key.
+func (p *contextProvider) Fetch(key string) (string, bool) {
+	name, ok := strings.CutPrefix(key, secretProviderName+".")
+	if !ok {
+		return "", false
+	}
+	if err := agentsecret.ValidatePolicySecretName(name); err != nil {
+		p.logger.Warnf("Invalid secret reference %q: %s", key, err)
+		return "", false
+	}
+
+	f, err := p.read(context.Background(), name)
+	if err != nil {
+		p.logger.Errorf("Failed to read secret %q from the vault: %s", name, err)
+	} else if !f.found {
+		p.logger.Warnf("Secret %q is not set, set it with 'elastic-agent secrets set %s'", name, name)
+	}
+	p.mx.Lock()
+	p.fetched[name] = f
+	p.mx.Unlock()
+	return f.value, f.found
+}
+
+// refresh reads the referenced secrets again and returns true when one of them changed.
+func (p *contextProvider) refresh(ctx context.Context) bool {
+	p.mx.Lock()
+	names := make([]string, 0, len(p.fetched))
+	for name := range p.fetched {
+		names = append(names, name)
+	}
+	p.mx.Unlock()
+
+	changed := false
+	for _, name := range names {
+		f, err := p.read(ctx, name)
+		if err != nil {
+			// keep the last value, the vault can be locked while a secret is set
+			p.logger.Errorf("Failed to refresh secret %q from the vault: %s", name, err)
+			continue
+		}
+		p.mx.Lock()
+		if p.fetched[name] != f {
+			p.fetched[name] = f
+			changed = true
+		}
+		p.mx.Unlock()
+	}
+	return changed
+}
+
+// read reads the secret from the vault, it is not found when it is not set.
+func (p *contextProvider) read(ctx context.Context, name string) (fetched, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	value, err := agentsecret.GetPolicySecret(ctx, name, p.vaultOpts...)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fetched{}, nil
+	}
+	if err != nil {
+		return fetched{}, err
+	}
+	return fetched{value: string(value), found: true}, nil
+}
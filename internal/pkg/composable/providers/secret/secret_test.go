@@ -0,0 +1,111 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package secret
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	agentsecret "github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/internal/pkg/composable"
+	ctesting "github.com/elastic/elastic-agent/internal/pkg/composable/testing"
+	"github.com/elastic/elastic-agent/internal/pkg/testutils/fipsutils"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func newTestProvider(t *testing.T) (*contextProvider, []vault.OptionFunc) {
+	builder, ok := composable.Providers.GetContextProvider(secretProviderName)
+	require.True(t, ok)
+	log, _ := loggertest.New("secret")
+	provider, err := builder(log, nil, false)
+	require.NoError(t, err)
+
+	p, ok := provider.(*contextProvider)
+	require.True(t, ok)
+	opts := []vault.OptionFunc{
+		vault.WithVaultPath(filepath.Join(t.TempDir(), "vault")),
+		vault.WithUnprivileged(true),
+	}
+	p.vaultOpts = opts
+	return p, opts
+}
+
+func TestFetch(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "secret storage does not use NewGCMWithRandomNonce.")
+	ctx := context.Background()
+	p, opts := newTestProvider(t)
+	require.NoError(t, agentsecret.SetPolicySecret(ctx, "es-password", []byte("changeme"), opts...))
+
+	value, found := p.Fetch("secret.es-password")
+	assert.True(t, found)
+	assert.Equal(t, "changeme", value)
+
+	_, found = p.Fetch("secret.missing")
+	assert.False(t, found)
+	_, found = p.Fetch("secret._invalid")
+	assert.False(t, found)
+	_, found = p.Fetch("env.es-password")
+	assert.False(t, found)
+}
+
+func TestRefresh(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "secret storage does not use NewGCMWithRandomNonce.")
+	ctx := context.Background()
+	p, opts := newTestProvider(t)
+	require.NoError(t, agentsecret.SetPolicySecret(ctx, "api-key", []byte("v1"), opts...))
+
+	_, _ = p.Fetch("secret.api-key")
+	_, _ = p.Fetch("secret.later")
+	assert.False(t, p.refresh(ctx), "no secret changed")
+
+	require.NoError(t, agentsecret.SetPolicySecret(ctx, "api-key", []byte("v2"), opts...))
+	assert.True(t, p.refresh(ctx), "a referenced secret changed")
+	value, _ := p.Fetch("secret.api-key")
+	assert.Equal(t, "v2", value)
+
+	require.NoError(t, agentsecret.SetPolicySecret(ctx, "later", []byte("set"), opts...))
+	assert.True(t, p.refresh(ctx), "a referenced secret was set")
+
+	require.NoError(t, agentsecret.RemovePolicySecret(ctx, "api-key", opts...))
+	assert.True(t, p.refresh(ctx), "a referenced secret was removed")
+	_, found := p.Fetch("secret.api-key")
+	assert.False(t, found)
+}
+
+func TestRunSignals(t *testing.T) {
+	fipsutils.SkipIfFIPSOnly(t, "secret storage does not use NewGCMWithRandomNonce.")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	p, opts := newTestProvider(t)
+	p.config.RefreshInterval = 10 * time.Millisecond
+	_, _ = p.Fetch("secret.token")
+
+	comm := ctesting.NewContextComm(ctx)
+	signaled := make(chan struct{}, 1)
+	comm.CallOnSignal(func() {
+		select {
+		case signaled <- struct{}{}:
+		default:
+		}
+	})
+	go func() {
+		_ = p.Run(ctx, comm)
+	}()
+	require.NoError(t, agentsecret.SetPolicySecret(ctx, "token", []byte("set"), opts...))
+	select {
+	case <-signaled:
+	case <-ctx.Done():
+		t.Fatal("the provider did not signal the change of the secret")
+	}
+	value, found := p.Fetch("secret.token")
+	assert.True(t, found)
+	assert.Equal(t, "set", value)
+}
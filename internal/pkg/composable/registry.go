@@ -30,3 +30,13 @@ func NewProviderRegistry() *ProviderRegistry {
 
 // Providers holds all known providers, they must be added to it to enable them for use
 var Providers = NewProviderRegistry()
+
+// exists returns true when a context or dynamic provider is registered with the given name.
+func (r *ProviderRegistry) exists(name string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	_, contextExists := r.contextProviders[name]
+	_, dynamicExists := r.dynamicProviders[name]
+	return contextExists || dynamicExists
+}
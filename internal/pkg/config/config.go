@@ -9,13 +9,18 @@ import (
 	"io"
 	"maps"
 	"os"
+	"strings"
 
 	"go.opentelemetry.io/collector/confmap"
 	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/go-ucfg"
+	"github.com/elastic/go-ucfg/parse"
 )
 
+// envProviderPrefix is the prefix of the variables of the env provider.
+const envProviderPrefix = "env."
+
 // options hold the specified options
 type options struct {
 	otelKeys []string
@@ -54,12 +59,30 @@ var NoResolveOptions = []ucfg.Option{
 var DefaultOptions = []interface{}{
 	ucfg.PathSep("."),
 	ucfg.IgnoreCommas,
+	ucfg.Resolve(resolveEnvProvider),
 	ucfg.ResolveEnv,
 	ucfg.VarExp,
 	VarSkipKeys("inputs", "outputs"),
 	OTelKeys("connectors", "receivers", "processors", "exporters", "extensions", "service"),
 }
 
+// resolveEnvProvider resolves ${env.NAME} from the environment variable NAME, the same variable the env provider
+// resolves in the inputs and the outputs.
+//
+// A set but empty variable is missing like an unset one, ucfg already treats an empty value as unresolved, so
+// ${env.NAME:default} falls back to the default and ${env.NAME} fails to resolve in both cases.
+func resolveEnvProvider(name string) (string, parse.Config, error) {
+	key, ok := strings.CutPrefix(name, envProviderPrefix)
+	if !ok {
+		return "", parse.EnvConfig, ucfg.ErrMissing
+	}
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return "", parse.EnvConfig, ucfg.ErrMissing
+	}
+	return value, parse.EnvConfig, nil
+}
+
 // Config custom type that can provide both an Agent configuration alongside of an optional OTel configuration.
 type Config struct {
 	// Agent configuration
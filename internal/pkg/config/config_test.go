@@ -360,3 +360,48 @@ func TestConfigMerge(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvProviderVariables(t *testing.T) {
+	t.Setenv("ES_HOST", "https://es.example.com:9200")
+	t.Setenv("EMPTY_VALUE", "")
+	t.Setenv("HOST_VARIABLE", "ES_HOST")
+	in := map[string]interface{}{
+		"agent": map[string]interface{}{
+			"download": map[string]interface{}{
+				"sourceURI":   "${env.ES_HOST}/downloads",
+				"target":      "${env.MISSING_VALUE:/tmp/downloads}",
+				"proxy_url":   "${env.EMPTY_VALUE:http://proxy}",
+				"description": "$${env.ES_HOST}",
+				"escaped":     "$$${env.ES_HOST}",
+				"nested":      "${env.MISSING_VALUE:${env.EMPTY_VALUE:/var/cache}}",
+				"indirect":    "${env.${env.HOST_VARIABLE}}",
+			},
+		},
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{
+				"type":  "elasticsearch",
+				"hosts": []interface{}{"${env.ES_HOST:http://localhost:9200}"},
+			},
+		},
+	}
+	c := MustNewConfigFrom(in)
+	out, err := c.ToMapStr()
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"sourceURI":   "https://es.example.com:9200/downloads",
+		"target":      "/tmp/downloads",
+		"proxy_url":   "http://proxy",
+		"description": "${env.ES_HOST}",
+		"escaped":     "$https://es.example.com:9200",
+		"nested":      "/var/cache",
+		"indirect":    "https://es.example.com:9200",
+	}, out["agent"].(map[string]interface{})["download"])
+	assert.Equal(t, in["outputs"], out["outputs"], "the outputs are rendered by the transpiler with the env provider")
+
+	for _, value := range []string{"${env.EMPTY_VALUE}", "${env.MISSING_VALUE}"} {
+		c := MustNewConfigFrom(map[string]interface{}{"agent": map[string]interface{}{"id": value}})
+		_, err := c.ToMapStr()
+		assert.Errorf(t, err, "%s should not resolve without a default", value)
+	}
+}
@@ -5,8 +5,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"go.opentelemetry.io/collector/confmap"
@@ -17,44 +19,67 @@ import (
 )
 
 // Loader is used to load configuration from the paths
-// including appending multiple input configurations.
+// including appending multiple input and output configurations.
 type Loader struct {
-	logger       *logger.Logger
-	inputsFolder string
+	logger        *logger.Logger
+	inputsFolder  string
+	outputsFolder string
 }
 
 // NewLoader creates a new Loader instance to load configuration
 // files from different paths.
-func NewLoader(logger *logger.Logger, inputsFolder string) *Loader {
-	return &Loader{logger: logger, inputsFolder: inputsFolder}
+func NewLoader(logger *logger.Logger, inputsFolder string, outputsFolder string) *Loader {
+	return &Loader{logger: logger, inputsFolder: inputsFolder, outputsFolder: outputsFolder}
+}
+
+// fragment is a section read from a file of the inputs or the outputs folder.
+type fragment struct {
+	// file is the path of the file, empty for the standalone configuration files
+	file string
+	// key is the ID of an input or the name of an output, inputs without an ID have no key
+	key string
+	cfg *ucfg.Config
 }
 
 // Load iterates over the list of files and loads the confguration from them.
-// If a configuration file is under the folder set in `agent.config.inputs.path`
-// it is appended to a list. If it is a regular config file, it is merged into
-// the result config. The list of input configurations is merged into the result
-// last.
+// If a configuration file is under the inputs or the outputs folder its
+// inputs or outputs are collected. If it is a regular config file, it is
+// merged into the result config. The collected inputs and outputs are merged
+// into the result last.
+//
+// The files are merged in the order they are given, the regular configuration
+// files first. An input with the same ID or an output with the same name as
+// one merged before replaces it. Every invalid file of the inputs and the
+// outputs folders is reported in the returned error.
 func (l *Loader) Load(files []string) (*Config, error) {
-	inputsList := make([]*ucfg.Config, 0)
+	var inputs, outputs []fragment
+	var errs []error
 	merger := cfgutil.NewCollector(nil)
 	var otelCfg *confmap.Conf
 	for _, f := range files {
-		cfg, err := LoadFile(f)
-		if err != nil {
-			if l.isFileUnderInputsFolder(f) {
-				return nil, fmt.Errorf("failed to load external configuration file '%s': %w. Are you sure it contains an inputs section?", f, err)
-			}
-			return nil, fmt.Errorf("failed to load configuration file '%s': %w", f, err)
-		}
-		l.logger.Debugf("Loaded configuration from %s", f)
-		if l.isFileUnderInputsFolder(f) {
-			inp, err := getInput(cfg)
+		switch {
+		case l.isFileUnderInputsFolder(f):
+			inp, err := loadInputs(f)
 			if err != nil {
-				return nil, fmt.Errorf("cannot get configuration from '%s': %w", f, err)
+				errs = append(errs, err)
+				continue
 			}
-			inputsList = append(inputsList, inp...)
+			inputs = append(inputs, inp...)
 			l.logger.Debugf("Loaded %d input(s) from configuration from %s", len(inp), f)
-		} else {
+		case l.isFileUnderOutputsFolder(f):
+			out, err := loadOutputs(f)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			outputs = append(outputs, out...)
+			l.logger.Debugf("Loaded %d output(s) from configuration from %s", len(out), f)
+		default:
+			cfg, err := LoadFile(f)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load configuration file '%s': %w", f, err)
+			}
+			l.logger.Debugf("Loaded configuration from %s", f)
 			if err := merger.Add(cfg.access(), err); err != nil {
 				return nil, fmt.Errorf("failed to merge configuration file '%s' to existing one: %w", f, err)
 			}
@@ -64,32 +89,180 @@ func (l *Loader) Load(files []string) (*Config, error) {
 			}
 		}
 	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
 	config := merger.Config()
 
-	// if there is no input configuration, return what we have collected.
-	if len(inputsList) == 0 {
-		l.logger.Debugf("Merged all configuration files from %v, no external input files", files)
+	// if there is no input or output configuration, return what we have collected.
+	if len(inputs) == 0 && len(outputs) == 0 {
+		l.logger.Debugf("Merged all configuration files from %v, no external input or output files", files)
 		return newConfigFrom(config, otelCfg), nil
 	}
 
-	// merge inputs sections from the last standalone configuration
-	// file and all files from the inputs folder
-	start := 0
+	if err := l.mergeInputs(config, inputs); err != nil {
+		return nil, err
+	}
+	if err := l.mergeOutputs(config, outputs); err != nil {
+		return nil, err
+	}
+
+	l.logger.Debugf("Merged all configuration files from %v, with external input and output files", files)
+	return newConfigFrom(config, otelCfg), nil
+}
+
+// mergeInputs appends the inputs to the inputs section of the configuration, an input with the ID of an input
+// already in the section replaces it.
+func (l *Loader) mergeInputs(config *ucfg.Config, inputs []fragment) error {
+	if len(inputs) == 0 {
+		return nil
+	}
+
+	// the inputs from the standalone configuration files come first
+	var merged []fragment
 	if config.HasField("inputs") {
+		count, err := config.CountField("inputs")
+		if err != nil {
+			return fmt.Errorf("failed to count the number of inputs in the configuration: %w", err)
+		}
+		for i := 0; i < count; i++ {
+			inp, err := config.Child("inputs", i)
+			if err != nil {
+				return fmt.Errorf("failed to read 'inputs.%d' from the configuration: %w", i, err)
+			}
+			id, _ := inp.String("id", -1)
+			merged = append(merged, fragment{key: id, cfg: inp})
+		}
+	}
+
+	index := make(map[string]int, len(merged))
+	for i, inp := range merged {
+		if inp.key != "" {
+			index[inp.key] = i
+		}
+	}
+	for _, inp := range inputs {
+		i, ok := index[inp.key]
+		if inp.key == "" || !ok {
+			if inp.key != "" {
+				index[inp.key] = len(merged)
+			}
+			merged = append(merged, inp)
+			continue
+		}
+		l.logger.Infof("Input '%s' from '%s' replaces the input with the same ID from %s", inp.key, inp.file, describeFile(merged[i].file))
+		merged[i] = inp
+	}
+
+	for i, inp := range merged {
+		if err := config.SetChild("inputs", i, inp.cfg); err != nil {
+			return fmt.Errorf("failed to add inputs to result configuration: %w", err)
+		}
+	}
+	return nil
+}
+
+// mergeOutputs sets the outputs in the outputs section of the configuration, an output with the name of an
+// output already in the section replaces it.
+func (l *Loader) mergeOutputs(config *ucfg.Config, outputs []fragment) error {
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	section := ucfg.New()
+	if config.HasField("outputs") {
 		var err error
-		start, err = config.CountField("inputs")
+		section, err = config.Child("outputs", -1)
 		if err != nil {
-			return nil, fmt.Errorf("failed to count the number of inputs in the configuration: %w", err)
+			return fmt.Errorf("failed to read the outputs section of the configuration: %w", err)
 		}
 	}
-	for i, ll := range inputsList {
-		if err := config.SetChild("inputs", start+i, ll); err != nil {
-			return nil, fmt.Errorf("failed to add inputs to result configuration: %w", err)
+
+	defined := make(map[string]string)
+	for _, name := range section.GetFields() {
+		defined[name] = ""
+	}
+	for _, out := range outputs {
+		if previous, ok := defined[out.key]; ok {
+			l.logger.Infof("Output '%s' from '%s' replaces the output with the same name from %s", out.key, out.file, describeFile(previous))
+			if _, err := section.Remove(out.key, -1); err != nil {
+				return fmt.Errorf("failed to replace output '%s' in result configuration: %w", out.key, err)
+			}
+		}
+		if err := section.SetChild(out.key, -1, out.cfg); err != nil {
+			return fmt.Errorf("failed to add output '%s' to result configuration: %w", out.key, err)
 		}
+		defined[out.key] = out.file
 	}
 
-	l.logger.Debugf("Merged all configuration files from %v, with external input files", files)
-	return newConfigFrom(config, otelCfg), nil
+	if err := config.SetChild("outputs", -1, section); err != nil {
+		return fmt.Errorf("failed to add outputs to result configuration: %w", err)
+	}
+	return nil
+}
+
+// loadInputs loads the inputs of a file from the inputs folder.
+func loadInputs(f string) ([]fragment, error) {
+	cfg, err := LoadFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load external configuration file '%s': %w. Are you sure it contains an inputs section?", f, err)
+	}
+	inputs, err := getInput(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get configuration from '%s': %w", f, err)
+	}
+
+	result := make([]fragment, 0, len(inputs))
+	ids := make(map[string]int, len(inputs))
+	for i, inp := range inputs {
+		id, _ := inp.String("id", -1)
+		if id != "" {
+			if first, ok := ids[id]; ok {
+				return nil, fmt.Errorf("invalid external configuration file '%s': 'inputs.%d' has the same id %q as 'inputs.%d'", f, i, id, first)
+			}
+			ids[id] = i
+		}
+		result = append(result, fragment{file: f, key: id, cfg: inp})
+	}
+	return result, nil
+}
+
+// loadOutputs loads the outputs of a file from the outputs folder, the file must only have an outputs section.
+func loadOutputs(f string) ([]fragment, error) {
+	cfg, err := LoadFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load external configuration file '%s': %w. Are you sure it contains an outputs section?", f, err)
+	}
+	for _, key := range cfg.access().GetFields() {
+		if key != "outputs" {
+			return nil, fmt.Errorf("invalid external configuration file '%s': unexpected section '%s', only the outputs section is allowed", f, key)
+		}
+	}
+
+	tmpConfig := struct {
+		Outputs map[string]*ucfg.Config `config:"outputs"`
+	}{}
+	if err := cfg.UnpackTo(&tmpConfig); err != nil {
+		return nil, fmt.Errorf("cannot get configuration from '%s': failed to parse outputs section from configuration: %w", f, err)
+	}
+
+	names := make([]string, 0, len(tmpConfig.Outputs))
+	for name := range tmpConfig.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]fragment, 0, len(names))
+	for _, name := range names {
+		out := tmpConfig.Outputs[name]
+		if out == nil {
+			return nil, fmt.Errorf("invalid external configuration file '%s': output '%s' is empty", f, name)
+		}
+		if outputType, _ := out.String("type", -1); outputType == "" {
+			return nil, fmt.Errorf("invalid external configuration file '%s': output '%s' has no type", f, name)
+		}
+		result = append(result, fragment{file: f, key: name, cfg: out})
+	}
+	return result, nil
 }
 
 func getInput(c *Config) ([]*ucfg.Config, error) {
@@ -103,6 +276,14 @@ func getInput(c *Config) ([]*ucfg.Config, error) {
 	return tmpConfig.Inputs, nil
 }
 
+// describeFile describes where a fragment comes from in the log messages.
+func describeFile(file string) string {
+	if file == "" {
+		return "the standalone configuration"
+	}
+	return "'" + file + "'"
+}
+
 // isFileUnderInputsFolder checks if the given f path matches the Loader inputsFolder or
 // if the parent directory of it has the suffix inputs.d
 func (l *Loader) isFileUnderInputsFolder(f string) bool {
@@ -111,3 +292,12 @@ func (l *Loader) isFileUnderInputsFolder(f string) bool {
 	}
 	return true
 }
+
+// isFileUnderOutputsFolder checks if the given f path matches the Loader outputsFolder or
+// if the parent directory of it has the suffix outputs.d
+func (l *Loader) isFileUnderOutputsFolder(f string) bool {
+	if matches, err := filepath.Match(l.outputsFolder, f); !matches || err != nil {
+		return strings.HasSuffix(filepath.Dir(f), "outputs.d")
+	}
+	return true
+}
@@ -207,10 +207,125 @@ func TestExternalConfigLoading(t *testing.T) {
 	}
 }
 
+func TestExternalConfigPrecedence(t *testing.T) {
+	cases := map[string]struct {
+		configs        []string
+		expectedConfig map[string]interface{}
+		errs           []string
+	}{
+		"outputs of the outputs folder are added and replace the ones with the same name": {
+			configs: []string{
+				filepath.Join("testdata", "standalone1.yml"),
+				filepath.Join("testdata", "outputs", "monitoring-outputs.yml"),
+				filepath.Join("testdata", "outputs", "override-outputs.yml"),
+			},
+			expectedConfig: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":  "logstash",
+						"hosts": []interface{}{"127.0.0.1:5044"},
+					},
+					"monitoring": map[string]interface{}{
+						"type":    "elasticsearch",
+						"hosts":   []interface{}{"127.0.0.1:9202"},
+						"api_key": "monitoring-key",
+					},
+				},
+			},
+		},
+		"inputs of a later file replace the ones with the same id": {
+			configs: []string{
+				filepath.Join("testdata", "standalone-with-inputs.yml"),
+				filepath.Join("testdata", "inputs", "log-inputs.yml"),
+				filepath.Join("testdata", "inputs", "override-inputs.yml"),
+			},
+			expectedConfig: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"hosts":   []interface{}{"127.0.0.1:9201"},
+						"api_key": "my-secret-key",
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type": "system/metrics",
+						"data_stream": map[string]interface{}{
+							"namespace": "default",
+						},
+						"use_output": "default",
+						"streams": []interface{}{
+							map[string]interface{}{
+								"metricset": "cpu",
+								"data_stream": map[string]interface{}{
+									"dataset": "system.cpu",
+								},
+							},
+						},
+					},
+					map[string]interface{}{
+						"data_stream": map[string]interface{}{
+							"dataset": "system.auth",
+							"type":    "logs",
+						},
+						"exclude_files": []interface{}{".gz$"},
+						"id":            "logfile-system.auth-my-id",
+						"paths":         []interface{}{"/var/log/auth.log*", "/var/log/secure*"},
+						"use_output":    "default",
+					},
+					map[string]interface{}{
+						"data_stream": map[string]interface{}{
+							"dataset": "system.syslog",
+							"type":    "logs",
+						},
+						"type":       "filestream",
+						"id":         "logfile-system.syslog-my-id",
+						"paths":      []interface{}{"/var/log/syslog*"},
+						"use_output": "monitoring",
+					},
+				},
+			},
+		},
+		"every invalid file is reported": {
+			configs: []string{
+				filepath.Join("testdata", "standalone1.yml"),
+				filepath.Join("testdata", "inputs", "duplicate-inputs.yml"),
+				filepath.Join("testdata", "inputs", "log-inputs.yml"),
+				filepath.Join("testdata", "outputs", "invalid-outputs.yml"),
+				filepath.Join("testdata", "outputs", "untyped-outputs.yml"),
+			},
+			errs: []string{
+				"'" + filepath.Join("testdata", "inputs", "duplicate-inputs.yml") + "': 'inputs.1' has the same id \"filestream-my-id\" as 'inputs.0'",
+				"'" + filepath.Join("testdata", "outputs", "invalid-outputs.yml") + "': unexpected section 'inputs'",
+				"'" + filepath.Join("testdata", "outputs", "untyped-outputs.yml") + "': output 'other' has no type",
+			},
+		},
+	}
+
+	for name, test := range cases {
+		t.Run(name, func(t *testing.T) {
+			l := mustNewLoader(filepath.Join("testdata", "inputs", "*.yml"))
+			c, err := l.Load(test.configs)
+			if len(test.errs) > 0 {
+				require.Error(t, err)
+				for _, msg := range test.errs {
+					require.ErrorContains(t, err, msg)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			raw, err := c.ToMapStr()
+			require.NoError(t, err)
+			require.Equal(t, test.expectedConfig, raw)
+		})
+	}
+}
+
 func mustNewLoader(inputsFolder string) *Loader {
 	log, err := logger.New("loader_test", true)
 	if err != nil {
 		panic(err)
 	}
-	return NewLoader(log, inputsFolder)
+	return NewLoader(log, inputsFolder, filepath.Join("testdata", "outputs", "*.yml"))
 }
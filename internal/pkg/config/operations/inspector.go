@@ -38,9 +38,10 @@ func LoadFullAgentConfig(ctx context.Context, logger *logger.Logger, cfgPath str
 	}
 
 	if configuration.IsStandalone(cfg.Fleet) {
-		// When in standalone we load the configuration again with inputs that are defined in the paths.ExternalInputs.
-		loader := config.NewLoader(logger, paths.ExternalInputs())
-		discover := config.Discoverer(cfgPath, cfg.Settings.Path, paths.ExternalInputs())
+		// When in standalone we load the configuration again with inputs and outputs that are defined in the
+		// paths.ExternalInputs and paths.ExternalOutputs.
+		loader := config.NewLoader(logger, paths.ExternalInputs(), paths.ExternalOutputs())
+		discover := config.Discoverer(cfgPath, cfg.Settings.Path, paths.ExternalInputs(), paths.ExternalOutputs())
 		files, err := discover()
 		if err != nil {
 			return nil, fmt.Errorf("could not discover configuration files: %w", err)
@@ -18,6 +18,7 @@ import (
 	"encoding/pem"
 	"log"
 	"math/big"
+	"net"
 	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
@@ -117,14 +118,39 @@ func generateSubjectKeyID(pub crypto.PublicKey) []byte {
 	return h[:20]
 }
 
+// PairOption customizes the child certificate generated by GeneratePairWithName.
+type PairOption func(*x509.Certificate)
+
+// WithSANs adds subject alternative names to the child certificate. IP addresses are added as
+// IP SANs, any other name as a DNS SAN.
+func WithSANs(names ...string) PairOption {
+	return func(cert *x509.Certificate) {
+		for _, name := range names {
+			if ip := net.ParseIP(name); ip != nil {
+				cert.IPAddresses = append(cert.IPAddresses, ip)
+			} else {
+				cert.DNSNames = append(cert.DNSNames, name)
+			}
+		}
+	}
+}
+
+// WithValidity sets how long the child certificate is valid for, 10 years by default.
+func WithValidity(validity time.Duration) PairOption {
+	return func(cert *x509.Certificate) {
+		cert.NotAfter = cert.NotBefore.Add(validity)
+	}
+}
+
 // GeneratePair generates child certificate
 func (c *CertificateAuthority) GeneratePair() (*Pair, error) {
 	return c.GeneratePairWithName("localhost")
 }
 
 // GeneratePairWithName generates child certificate with provided name as the common name.
-func (c *CertificateAuthority) GeneratePairWithName(name string) (*Pair, error) {
+func (c *CertificateAuthority) GeneratePairWithName(name string, opts ...PairOption) (*Pair, error) {
 	// Prepare certificate
+	now := time.Now()
 	certTemplate := &x509.Certificate{
 		SerialNumber: big.NewInt(1658),
 		DNSNames:     []string{name},
@@ -132,11 +158,14 @@ func (c *CertificateAuthority) GeneratePairWithName(name string) (*Pair, error)
 			Organization: []string{"elastic-fleet"},
 			CommonName:   name,
 		},
-		NotBefore:   time.Now(),
-		NotAfter:    time.Now().AddDate(10, 0, 0),
+		NotBefore:   now,
+		NotAfter:    now.AddDate(10, 0, 0),
 		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
 		KeyUsage:    x509.KeyUsageDigitalSignature,
 	}
+	for _, opt := range opts {
+		opt(certTemplate)
+	}
 	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
 	publicKey := &privateKey.PublicKey
 
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package authority
+
+import (
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePairWithName(t *testing.T) {
+	ca, err := NewCA()
+	require.NoError(t, err)
+
+	t.Run("defaults", func(t *testing.T) {
+		pair, err := ca.GeneratePairWithName("fleet-host")
+		require.NoError(t, err)
+		cert := parseLeaf(t, pair)
+
+		assert.Equal(t, "fleet-host", cert.Subject.CommonName)
+		assert.Equal(t, []string{"fleet-host"}, cert.DNSNames)
+		assert.Empty(t, cert.IPAddresses)
+		assert.Equal(t, cert.NotBefore.AddDate(10, 0, 0), cert.NotAfter)
+	})
+
+	t.Run("with SANs and validity", func(t *testing.T) {
+		pair, err := ca.GeneratePairWithName("fleet-host",
+			WithSANs("localhost", "fleet.example.com", "127.0.0.1", "::1"),
+			WithValidity(90*24*time.Hour))
+		require.NoError(t, err)
+		cert := parseLeaf(t, pair)
+
+		assert.Equal(t, []string{"fleet-host", "localhost", "fleet.example.com"}, cert.DNSNames)
+		require.Len(t, cert.IPAddresses, 2)
+		assert.True(t, cert.IPAddresses[0].Equal(net.ParseIP("127.0.0.1")))
+		assert.True(t, cert.IPAddresses[1].Equal(net.IPv6loopback))
+		assert.Equal(t, 90*24*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+
+		roots := x509.NewCertPool()
+		require.True(t, roots.AppendCertsFromPEM(ca.Crt()))
+		_, err = cert.Verify(x509.VerifyOptions{DNSName: "127.0.0.1", Roots: roots})
+		assert.NoError(t, err, "certificate should be valid for its IP SAN")
+	})
+}
+
+func parseLeaf(t *testing.T, pair *Pair) *x509.Certificate {
+	t.Helper()
+	cert, err := x509.ParseCertificate(pair.Certificate.Certificate[0])
+	require.NoError(t, err)
+	return cert
+}
@@ -0,0 +1,168 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package agentmetrics holds the metrics of the Elastic Agent itself: the Fleet check-ins, the rendering of the
+// policy, the requests downloading the upgrade artifacts, the restarts of the components and the sizes of the queues. They are registered in the stats namespace
+// served by the monitoring HTTP server, next to the process metrics of the Elastic Agent.
+package agentmetrics
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+// Metrics are the metrics of the Elastic Agent.
+type Metrics struct {
+	checkinRequests        *monitoring.Uint
+	checkinFailures        *monitoring.Uint
+	checkinLastDuration    *monitoring.Float
+	checkinDurationTotal   *monitoring.Float
+	renders                *monitoring.Uint
+	renderFailures         *monitoring.Uint
+	renderLastDuration     *monitoring.Float
+	renderDurationTotal    *monitoring.Float
+	downloadRequests       *monitoring.Uint
+	downloadFailures       *monitoring.Uint
+	downloadRetryable      *monitoring.Uint
+	downloadBytes          *monitoring.Uint
+	downloadLastDuration   *monitoring.Float
+	downloadLastStatusCode *monitoring.Int
+	actionQueueSize        *monitoring.Int
+	ackRetryQueueSize      *monitoring.Int
+	componentRestartsMx    sync.Mutex
+	componentRestartsCount map[string]uint64
+}
+
+// New registers the metrics in the registry.
+func New(reg *monitoring.Registry) *Metrics {
+	fleet := reg.GetOrCreateRegistry("fleet")
+	policy := reg.GetOrCreateRegistry("policy")
+	download := reg.GetOrCreateRegistry("download")
+	queue := reg.GetOrCreateRegistry("queue")
+	return &Metrics{
+		checkinRequests:        monitoring.NewUint(fleet, "checkin.requests_total"),
+		checkinFailures:        monitoring.NewUint(fleet, "checkin.failures_total"),
+		checkinLastDuration:    monitoring.NewFloat(fleet, "checkin.last_duration_seconds"),
+		checkinDurationTotal:   monitoring.NewFloat(fleet, "checkin.duration_seconds_total"),
+		renders:                monitoring.NewUint(policy, "render.total"),
+		renderFailures:         monitoring.NewUint(policy, "render.failures_total"),
+		renderLastDuration:     monitoring.NewFloat(policy, "render.last_duration_seconds"),
+		renderDurationTotal:    monitoring.NewFloat(policy, "render.duration_seconds_total"),
+		downloadRequests:       monitoring.NewUint(download, "requests_total"),
+		downloadFailures:       monitoring.NewUint(download, "failures_total"),
+		downloadRetryable:      monitoring.NewUint(download, "retryable_failures_total"),
+		downloadBytes:          monitoring.NewUint(download, "bytes_total"),
+		downloadLastDuration:   monitoring.NewFloat(download, "last_duration_seconds"),
+		downloadLastStatusCode: monitoring.NewInt(download, "last_status_code"),
+		actionQueueSize:        monitoring.NewInt(queue, "actions"),
+		ackRetryQueueSize:      monitoring.NewInt(queue, "ack_retries"),
+		componentRestartsCount: map[string]uint64{},
+	}
+}
+
+// defaultMetrics are registered in the stats namespace, served by the monitoring HTTP server and shipped by the
+// monitoring of the Elastic Agent.
+var defaultMetrics = New(monitoring.GetNamespace("stats").GetRegistry())
+
+// ObserveCheckin records a Fleet check-in request. Check-ins are long-polling requests, their duration includes
+// the time Fleet Server holds the request until it has new actions for the Elastic Agent.
+func ObserveCheckin(took time.Duration, err error) {
+	defaultMetrics.ObserveCheckin(took, err)
+}
+
+// ObserveCheckin records a Fleet check-in request.
+func (m *Metrics) ObserveCheckin(took time.Duration, err error) {
+	m.checkinRequests.Inc()
+	if err != nil {
+		m.checkinFailures.Inc()
+	}
+	m.checkinLastDuration.Set(took.Seconds())
+	m.checkinDurationTotal.Add(took.Seconds())
+}
+
+// ObservePolicyRender records a rendering of the policy into the components model.
+func ObservePolicyRender(took time.Duration, err error) {
+	defaultMetrics.ObservePolicyRender(took, err)
+}
+
+// ObservePolicyRender records a rendering of the policy into the components model.
+func (m *Metrics) ObservePolicyRender(took time.Duration, err error) {
+	m.renders.Inc()
+	if err != nil {
+		m.renderFailures.Inc()
+	}
+	m.renderLastDuration.Set(took.Seconds())
+	m.renderDurationTotal.Add(took.Seconds())
+}
+
+// ObserveDownloadRequest records a request downloading an upgrade artifact, or a range of it. The status code is 0
+// when the request failed without a response, a failed request is retryable unless its error is fatal.
+func ObserveDownloadRequest(took time.Duration, bytes int64, statusCode int, err error, retryable bool) {
+	defaultMetrics.ObserveDownloadRequest(took, bytes, statusCode, err, retryable)
+}
+
+// ObserveDownloadRequest records a request downloading an upgrade artifact, or a range of it.
+func (m *Metrics) ObserveDownloadRequest(took time.Duration, bytes int64, statusCode int, err error, retryable bool) {
+	m.downloadRequests.Inc()
+	if err != nil {
+		m.downloadFailures.Inc()
+		if retryable {
+			m.downloadRetryable.Inc()
+		}
+	}
+	if bytes > 0 {
+		m.downloadBytes.Add(uint64(bytes))
+	}
+	m.downloadLastDuration.Set(took.Seconds())
+	m.downloadLastStatusCode.Set(int64(statusCode))
+}
+
+// ComponentRestarted records an unexpected exit of the process of a component, restarted by the Elastic Agent.
+func ComponentRestarted(componentID string) {
+	defaultMetrics.ComponentRestarted(componentID)
+}
+
+// ComponentRestarted records an unexpected exit of the process of a component, restarted by the Elastic Agent.
+func (m *Metrics) ComponentRestarted(componentID string) {
+	m.componentRestartsMx.Lock()
+	defer m.componentRestartsMx.Unlock()
+	m.componentRestartsCount[componentID]++
+}
+
+// ComponentRemoved forgets the restarts of a component removed by the Elastic Agent.
+func ComponentRemoved(componentID string) {
+	defaultMetrics.ComponentRemoved(componentID)
+}
+
+// ComponentRemoved forgets the restarts of a component removed by the Elastic Agent.
+func (m *Metrics) ComponentRemoved(componentID string) {
+	m.componentRestartsMx.Lock()
+	defer m.componentRestartsMx.Unlock()
+	delete(m.componentRestartsCount, componentID)
+}
+
+// ComponentRestarts returns the number of restarts by component ID.
+func ComponentRestarts() map[string]uint64 {
+	return defaultMetrics.ComponentRestarts()
+}
+
+// ComponentRestarts returns the number of restarts by component ID.
+func (m *Metrics) ComponentRestarts() map[string]uint64 {
+	m.componentRestartsMx.Lock()
+	defer m.componentRestartsMx.Unlock()
+	return maps.Clone(m.componentRestartsCount)
+}
+
+// SetActionQueueSize sets the number of scheduled Fleet actions waiting for their start time.
+func SetActionQueueSize(n int) {
+	defaultMetrics.actionQueueSize.Set(int64(n))
+}
+
+// SetAckRetryQueueSize sets the number of acknowledgements of Fleet actions waiting to be retried.
+func SetAckRetryQueueSize(n int) {
+	defaultMetrics.ackRetryQueueSize.Set(int64(n))
+}
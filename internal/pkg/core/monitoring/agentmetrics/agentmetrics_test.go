@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package agentmetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-libs/monitoring"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := monitoring.NewRegistry()
+	m := New(reg)
+
+	m.ObserveCheckin(2*time.Second, nil)
+	m.ObserveCheckin(500*time.Millisecond, errors.New("connection refused"))
+	m.ObservePolicyRender(250*time.Millisecond, nil)
+	m.ObserveDownloadRequest(3*time.Second, 1024, 206, nil, false)
+	m.ObserveDownloadRequest(time.Second, 10, 503, errors.New("service unavailable"), true)
+	m.ObserveDownloadRequest(100*time.Millisecond, 0, 404, errors.New("not found"), false)
+	m.ComponentRestarted("filestream-default")
+	m.ComponentRestarted("filestream-default")
+
+	snapshot := monitoring.CollectStructSnapshot(reg, monitoring.Full, false)
+	assert.Equal(t, map[string]interface{}{
+		"requests_total":         int64(2),
+		"failures_total":         int64(1),
+		"last_duration_seconds":  0.5,
+		"duration_seconds_total": 2.5,
+	}, snapshot["fleet"].(map[string]interface{})["checkin"])
+	assert.Equal(t, map[string]interface{}{
+		"total":                  int64(1),
+		"failures_total":         int64(0),
+		"last_duration_seconds":  0.25,
+		"duration_seconds_total": 0.25,
+	}, snapshot["policy"].(map[string]interface{})["render"])
+	assert.Equal(t, map[string]interface{}{
+		"requests_total":           int64(3),
+		"failures_total":           int64(2),
+		"retryable_failures_total": int64(1),
+		"bytes_total":              int64(1034),
+		"last_duration_seconds":    0.1,
+		"last_status_code":         int64(404),
+	}, snapshot["download"])
+
+	restarts := m.ComponentRestarts()
+	assert.Equal(t, map[string]uint64{"filestream-default": 2}, restarts)
+	restarts["filestream-default"] = 10
+	assert.Equal(t, uint64(2), m.ComponentRestarts()["filestream-default"], "the restarts should be copied")
+	m.ComponentRemoved("filestream-default")
+	assert.Empty(t, m.ComponentRestarts(), "the restarts of a removed component should be forgotten")
+}
+
+func TestDefaultMetricsInStatsNamespace(t *testing.T) {
+	ObservePolicyRender(time.Second, nil)
+
+	snapshot := monitoring.CollectStructSnapshot(monitoring.GetNamespace("stats").GetRegistry(), monitoring.Full, false)
+	require.Contains(t, snapshot, "policy")
+	render := snapshot["policy"].(map[string]interface{})["render"].(map[string]interface{})
+	assert.GreaterOrEqual(t, render["total"], int64(1))
+}
@@ -5,6 +5,9 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -20,6 +23,12 @@ const (
 	ProcessRuntimeManager = "process"
 	OtelRuntimeManager    = "otel"
 	DefaultRuntimeManager = ProcessRuntimeManager
+
+	// OTLPProtocolGRPC and OTLPProtocolHTTP are the protocols of the OTLP export of the metrics.
+	OTLPProtocolGRPC    = "grpc"
+	OTLPProtocolHTTP    = "http"
+	defaultOTLPInterval = 60 * time.Second
+	defaultOTLPTimeout  = 10 * time.Second
 )
 
 // MonitoringConfig describes a configuration of a monitoring
@@ -37,6 +46,136 @@ type MonitoringConfig struct {
 	APM              APMConfig             `yaml:"apm,omitempty" config:"apm,omitempty" json:"apm,omitempty"`
 	Diagnostics      Diagnostics           `yaml:"diagnostics,omitempty" json:"diagnostics,omitempty"`
 	RuntimeManager   string                `yaml:"_runtime_experimental,omitempty" config:"_runtime_experimental,omitempty"`
+	// Components overrides the collection of logs and metrics per component, keyed by component ID
+	// or by binary name.
+	Components map[string]ComponentMonitoringConfig `yaml:"components,omitempty" config:"components,omitempty"`
+	// OTLP exports the metrics of the agent and of its components to an OpenTelemetry collector.
+	OTLP OTLPConfig `yaml:"otlp,omitempty" config:"otlp,omitempty"`
+	// Metricsets filters the metrics streams generated for the monitoring of the agent and of its components.
+	Metricsets MetricsetsFilter `yaml:"metricsets,omitempty" config:"metricsets,omitempty"`
+	// LogLevelOverrides sets the minimum level of the collected logs per component, keyed by component ID
+	// or by binary name. The logs below the level are dropped by the monitoring, the components keep
+	// their own log level.
+	LogLevelOverrides map[string]string `yaml:"log_level_overrides,omitempty" config:"log_level_overrides,omitempty"`
+}
+
+// collectedLogLevels are the log levels that can be set in the log level overrides, from the lowest.
+var collectedLogLevels = []string{"debug", "info", "warning", "error"}
+
+// Validate validates the monitoring configuration.
+func (c *MonitoringConfig) Validate() error {
+	for key, level := range c.LogLevelOverrides {
+		if !slices.Contains(collectedLogLevels, level) {
+			return fmt.Errorf("invalid monitoring log level %q for %q, must be one of %s", level, key, strings.Join(collectedLogLevels, ", "))
+		}
+	}
+	for key, override := range c.Components {
+		if override.Namespace != "" && !validNamespace(override.Namespace) {
+			return fmt.Errorf("invalid monitoring namespace %q for %q", override.Namespace, key)
+		}
+	}
+	return nil
+}
+
+// validNamespace checks the data stream namespace restrictions, lowercase and without dashes or the
+// characters that are invalid in an index name.
+func validNamespace(namespace string) bool {
+	return len(namespace) <= 100 && strings.ToLower(namespace) == namespace && !strings.ContainsAny(namespace, `-\/*?"<>| ,#:`)
+}
+
+// MetricsetsFilter includes or excludes monitoring metrics streams. An entry matches a stream by its
+// metricset in module.metricset form, for example beat.stats, http.json or system.process, or by its
+// dataset, for example elastic_agent.filebeat_input. An empty include list includes every stream, the
+// exclusions are applied after the inclusions.
+type MetricsetsFilter struct {
+	Include []string `yaml:"include,omitempty" config:"include"`
+	Exclude []string `yaml:"exclude,omitempty" config:"exclude"`
+}
+
+// Validate validates the metricsets filter.
+func (f *MetricsetsFilter) Validate() error {
+	for _, entry := range append(slices.Clone(f.Include), f.Exclude...) {
+		if strings.TrimSpace(entry) == "" {
+			return errors.New("monitoring metricsets filter cannot contain empty entries")
+		}
+	}
+	return nil
+}
+
+// Allows returns true when a stream with the given names, its metricsets and its dataset, passes the filter.
+func (f *MetricsetsFilter) Allows(names ...string) bool {
+	matches := func(entries []string) bool {
+		return slices.ContainsFunc(names, func(name string) bool { return slices.Contains(entries, name) })
+	}
+	if len(f.Include) > 0 && !matches(f.Include) {
+		return false
+	}
+	return !matches(f.Exclude)
+}
+
+// ComponentMonitoringConfig overrides the collection of logs and metrics of a component, unset values
+// follow the global toggles.
+type ComponentMonitoringConfig struct {
+	Logs    *bool `yaml:"logs,omitempty" config:"logs,omitempty"`
+	Metrics *bool `yaml:"metrics,omitempty" config:"metrics,omitempty"`
+	// Namespace routes the logs of the component to another data stream namespace than the monitoring one.
+	Namespace string `yaml:"namespace,omitempty" config:"namespace,omitempty"`
+}
+
+// MonitorComponentLogs returns true when the logs of the component are collected. An override of the
+// component ID takes precedence over an override of its binary name.
+func (c *MonitoringConfig) MonitorComponentLogs(componentID, binaryName string) bool {
+	if !c.MonitorLogs {
+		return false
+	}
+	return c.componentOverride(componentID, binaryName, func(o ComponentMonitoringConfig) *bool { return o.Logs })
+}
+
+// MonitorComponentMetrics returns true when the metrics of the component are collected. An override of
+// the component ID takes precedence over an override of its binary name.
+func (c *MonitoringConfig) MonitorComponentMetrics(componentID, binaryName string) bool {
+	if !c.MonitorMetrics {
+		return false
+	}
+	return c.componentOverride(componentID, binaryName, func(o ComponentMonitoringConfig) *bool { return o.Metrics })
+}
+
+// ComponentLogsNamespace returns the data stream namespace of the logs of the component, the monitoring
+// namespace unless overridden. An override of the component ID takes precedence over an override of its
+// binary name.
+func (c *MonitoringConfig) ComponentLogsNamespace(componentID, binaryName string) string {
+	for _, key := range []string{componentID, binaryName} {
+		if override, ok := c.Components[key]; ok && override.Namespace != "" {
+			return override.Namespace
+		}
+	}
+	return c.Namespace
+}
+
+// DroppedLogLevels returns the levels of the logs of the component that are not collected, none unless
+// a log level override is set. An override of the component ID takes precedence over an override of its
+// binary name.
+func (c *MonitoringConfig) DroppedLogLevels(componentID, binaryName string) []string {
+	for _, key := range []string{componentID, binaryName} {
+		if level, ok := c.LogLevelOverrides[key]; ok {
+			dropped := slices.Clone(collectedLogLevels[:slices.Index(collectedLogLevels, level)])
+			if slices.Contains(dropped, "warning") {
+				// the Beats log warnings at the warn level
+				dropped = append(dropped, "warn")
+			}
+			return dropped
+		}
+	}
+	return nil
+}
+
+func (c *MonitoringConfig) componentOverride(componentID, binaryName string, value func(ComponentMonitoringConfig) *bool) bool {
+	for _, key := range []string{componentID, binaryName} {
+		if override, ok := c.Components[key]; ok && value(override) != nil {
+			return *value(override)
+		}
+	}
+	return true
 }
 
 // MonitoringHTTPConfig is a config defining HTTP endpoint published by agent
@@ -109,6 +248,47 @@ type BufferConfig struct {
 	Enabled bool `yaml:"enabled" config:"enabled"`
 }
 
+// OTLPConfig configures the export of the metrics of the agent and of its components with OTLP.
+type OTLPConfig struct {
+	Enabled bool `yaml:"enabled" config:"enabled"`
+	// Endpoint of the collector, either host:port or a URL.
+	Endpoint string `yaml:"endpoint" config:"endpoint"`
+	// Protocol is either grpc or http.
+	Protocol string            `yaml:"protocol" config:"protocol"`
+	Insecure bool              `yaml:"insecure" config:"insecure"`
+	Headers  map[string]string `yaml:"headers,omitempty" config:"headers"`
+	// Interval between two exports.
+	Interval time.Duration `yaml:"interval" config:"interval"`
+	// Timeout of an export.
+	Timeout time.Duration `yaml:"timeout" config:"timeout"`
+}
+
+// Validate validates the OTLP export settings.
+func (o *OTLPConfig) Validate() error {
+	if !o.Enabled {
+		return nil
+	}
+	if strings.TrimSpace(o.Endpoint) == "" {
+		return errors.New("monitoring OTLP export requires an endpoint")
+	}
+	if o.Protocol != OTLPProtocolGRPC && o.Protocol != OTLPProtocolHTTP {
+		return fmt.Errorf("invalid monitoring OTLP protocol %q, must be %s or %s", o.Protocol, OTLPProtocolGRPC, OTLPProtocolHTTP)
+	}
+	if o.Interval <= 0 {
+		return fmt.Errorf("invalid monitoring OTLP interval %s, must be greater than zero", o.Interval)
+	}
+	return nil
+}
+
+func defaultOTLPConfig() OTLPConfig {
+	return OTLPConfig{
+		Enabled:  false,
+		Protocol: OTLPProtocolGRPC,
+		Interval: defaultOTLPInterval,
+		Timeout:  defaultOTLPTimeout,
+	}
+}
+
 // DefaultConfig creates a config with pre-set default values.
 func DefaultConfig() *MonitoringConfig {
 	return &MonitoringConfig{
@@ -126,6 +306,7 @@ func DefaultConfig() *MonitoringConfig {
 		APM:            defaultAPMConfig(),
 		Diagnostics:    defaultDiagnostics(),
 		RuntimeManager: DefaultRuntimeManager,
+		OTLP:           defaultOTLPConfig(),
 	}
 }
 
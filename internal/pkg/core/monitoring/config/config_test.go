@@ -229,3 +229,99 @@ func TestAPMConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestComponentOverrides(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+logs: true
+metrics: true
+components:
+  filebeat:
+    logs: false
+  log-default:
+    logs: true
+    metrics: false
+  metricbeat:
+    metrics: false
+`)
+	require.NoError(t, err)
+	cfg := DefaultConfig()
+	require.NoError(t, c.UnpackTo(cfg))
+
+	// binary name override
+	assert.False(t, cfg.MonitorComponentLogs("filestream-default", "filebeat"))
+	assert.True(t, cfg.MonitorComponentMetrics("filestream-default", "filebeat"))
+	// component ID override takes precedence
+	assert.True(t, cfg.MonitorComponentLogs("log-default", "filebeat"))
+	assert.False(t, cfg.MonitorComponentMetrics("log-default", "filebeat"))
+	// no override
+	assert.True(t, cfg.MonitorComponentLogs("system/metrics-default", "metricbeat"))
+	assert.False(t, cfg.MonitorComponentMetrics("system/metrics-default", "metricbeat"))
+	assert.True(t, cfg.MonitorComponentLogs("endpoint-default", "endpoint-security"))
+
+	// overrides cannot enable what is disabled globally
+	cfg.MonitorLogs = false
+	assert.False(t, cfg.MonitorComponentLogs("log-default", "filebeat"))
+}
+
+func TestMetricsetsFilter(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+metricsets:
+  include: [beat.stats, http.json]
+  exclude: [elastic_agent.filebeat_input]
+`)
+	require.NoError(t, err)
+	cfg := DefaultConfig()
+	require.NoError(t, c.UnpackTo(cfg))
+
+	assert.True(t, cfg.Metricsets.Allows("beat.stats", "elastic_agent.filebeat"))
+	assert.True(t, cfg.Metricsets.Allows("http.json", "elastic_agent.elastic_agent"))
+	assert.False(t, cfg.Metricsets.Allows("http.json", "elastic_agent.filebeat_input"), "exclusions apply after the inclusions")
+	assert.False(t, cfg.Metricsets.Allows("system.process", "elastic_agent.endpoint_security"), "not included")
+
+	// everything is included by default
+	assert.True(t, DefaultConfig().Metricsets.Allows("system.process", "elastic_agent.endpoint_security"))
+
+	c, err = config.NewConfigFrom(`
+metricsets:
+  exclude: [""]
+`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, c.UnpackTo(DefaultConfig()), "cannot contain empty entries")
+}
+
+func TestComponentLogsOverrides(t *testing.T) {
+	c, err := config.NewConfigFrom(`
+namespace: production
+log_level_overrides:
+  filebeat: warning
+  filestream-default: error
+components:
+  endpoint-default:
+    namespace: security
+`)
+	require.NoError(t, err)
+	cfg := DefaultConfig()
+	require.NoError(t, c.UnpackTo(cfg))
+
+	assert.Equal(t, []string{"debug", "info", "warning", "warn"}, cfg.DroppedLogLevels("filestream-default", "filebeat"), "component ID takes precedence")
+	assert.Equal(t, []string{"debug", "info"}, cfg.DroppedLogLevels("log-default", "filebeat"))
+	assert.Empty(t, cfg.DroppedLogLevels("system/metrics-default", "metricbeat"))
+
+	assert.Equal(t, "security", cfg.ComponentLogsNamespace("endpoint-default", "endpoint-security"))
+	assert.Equal(t, "production", cfg.ComponentLogsNamespace("filestream-default", "filebeat"))
+
+	c, err = config.NewConfigFrom(`
+log_level_overrides:
+  filebeat: verbose
+`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, c.UnpackTo(DefaultConfig()), `invalid monitoring log level "verbose"`)
+
+	c, err = config.NewConfigFrom(`
+components:
+  filebeat:
+    namespace: Not-Valid
+`)
+	require.NoError(t, err)
+	assert.ErrorContains(t, c.UnpackTo(DefaultConfig()), `invalid monitoring namespace "Not-Valid"`)
+}
@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// Collector collects the diagnostics of the units and of the components, it is implemented by the Coordinator.
+type Collector interface {
+	PerformDiagnostics(ctx context.Context, req ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic
+	PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error)
+}
+
+// Bundle is a diagnostics bundle the Elastic Agent captures by itself, e.g. when a component is faulty.
+type Bundle struct {
+	// Dir is the directory the bundle is written to.
+	Dir string
+	// Prefix starts the name of the bundle, followed by its capture time and Suffix.
+	Prefix string
+	Suffix string
+	// Keep is the number of bundles starting with Prefix kept in Dir, the oldest ones are removed.
+	Keep int
+	// TopPath is the top path of the agent, the logs of the bundle are collected from it.
+	TopPath string
+	// Agent are the diagnostics of the agent.
+	Agent []client.DiagnosticFileResult
+	// Components are the components whose diagnostics are collected, all of them when empty.
+	Components []component.Component
+}
+
+// Write collects the diagnostics of the units and of the components of the bundle, writes them with the
+// diagnostics of the agent and its logs to the bundle and returns its path.
+func (b Bundle) Write(ctx context.Context, log *logger.Logger, collector Collector, now time.Time) (string, error) {
+	var unitReqs []runtime.ComponentUnitDiagnosticRequest
+	for _, comp := range b.Components {
+		for _, unit := range comp.Units {
+			unitReqs = append(unitReqs, runtime.ComponentUnitDiagnosticRequest{Component: comp, Unit: unit})
+		}
+	}
+	var unitDiags []client.DiagnosticUnitResult
+	// no request collects the diagnostics of all the units, none are collected for components without units
+	if len(b.Components) == 0 || len(unitReqs) > 0 {
+		for _, r := range collector.PerformDiagnostics(ctx, unitReqs...) {
+			unitDiags = append(unitDiags, client.DiagnosticUnitResult{
+				ComponentID: r.Component.ID,
+				UnitID:      r.Unit.ID,
+				UnitType:    cproto.UnitType(r.Unit.Type),
+				Err:         r.Err,
+				Results:     fileResults(r.Results),
+			})
+		}
+	}
+
+	compResults, err := collector.PerformComponentDiagnostics(ctx, nil, 0, b.Components...)
+	if err != nil {
+		log.Debugf("failed to get the component diagnostics of the diagnostics bundle: %v", err)
+	}
+	var compDiags []client.DiagnosticComponentResult
+	for _, r := range compResults {
+		compDiags = append(compDiags, client.DiagnosticComponentResult{
+			ComponentID: r.Component.ID,
+			Err:         r.Err,
+			Results:     fileResults(r.Results),
+		})
+	}
+
+	if err := os.MkdirAll(b.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create the diagnostics directory: %w", err)
+	}
+	path := filepath.Join(b.Dir, b.Prefix+now.UTC().Format("20060102T150405Z")+b.Suffix+".zip")
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the diagnostics bundle: %w", err)
+	}
+	var errOut bytes.Buffer
+	err = ZipArchive(&errOut, f, b.TopPath, b.Agent, unitDiags, compDiags, true, DefaultRedactor())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if str := errOut.String(); str != "" {
+		log.Warn(str)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", fmt.Errorf("failed to write the diagnostics bundle: %w", err)
+	}
+
+	if err := RemoveOldBundles(b.Dir, b.Prefix, b.Keep); err != nil {
+		log.Debugf("failed to remove old diagnostics bundles: %v", err)
+	}
+	return path, nil
+}
+
+func fileResults(results []*proto.ActionDiagnosticUnitResult) []client.DiagnosticFileResult {
+	files := make([]client.DiagnosticFileResult, 0, len(results))
+	for _, res := range results {
+		files = append(files, client.DiagnosticFileResult{
+			Name:        res.Name,
+			Filename:    res.Filename,
+			Description: res.Description,
+			ContentType: res.ContentType,
+			Content:     res.Content,
+			Generated:   res.Generated.AsTime(),
+		})
+	}
+	return files
+}
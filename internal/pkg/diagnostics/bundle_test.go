@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeCollector struct {
+	unitReqs  [][]runtime.ComponentUnitDiagnosticRequest
+	compReqs  [][]component.Component
+	generated time.Time
+}
+
+func (c *fakeCollector) PerformDiagnostics(_ context.Context, req ...runtime.ComponentUnitDiagnosticRequest) []runtime.ComponentUnitDiagnostic {
+	c.unitReqs = append(c.unitReqs, req)
+	var diags []runtime.ComponentUnitDiagnostic
+	for _, r := range req {
+		diags = append(diags, runtime.ComponentUnitDiagnostic{
+			Component: r.Component,
+			Unit:      r.Unit,
+			Results: []*proto.ActionDiagnosticUnitResult{{
+				Name:        "unit",
+				Filename:    "unit.txt",
+				ContentType: "text/plain",
+				Content:     []byte("unit"),
+				Generated:   timestamppb.New(c.generated),
+			}},
+		})
+	}
+	return diags
+}
+
+func (c *fakeCollector) PerformComponentDiagnostics(_ context.Context, _ []cproto.AdditionalDiagnosticRequest, _ time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+	c.compReqs = append(c.compReqs, req)
+	return nil, nil
+}
+
+func TestBundleWrite(t *testing.T) {
+	log, _ := loggertest.New("diagnostics")
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	dir := filepath.Join(t.TempDir(), "bundles")
+	topPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(topPath, "data"), 0o750))
+	collector := &fakeCollector{generated: now}
+	comp := component.Component{
+		ID:    "filestream-default",
+		Units: []component.Unit{{ID: "filestream-default", Type: client.UnitTypeOutput}},
+	}
+
+	path, err := Bundle{
+		Dir:        dir,
+		Prefix:     "test-",
+		Suffix:     "-filestream-default",
+		Keep:       1,
+		TopPath:    topPath,
+		Components: []component.Component{comp},
+	}.Write(t.Context(), log, collector, now)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "test-20251001T120000Z-filestream-default.zip"), path)
+	require.Len(t, collector.unitReqs, 1)
+	assert.Equal(t, []runtime.ComponentUnitDiagnosticRequest{{Component: comp, Unit: comp.Units[0]}}, collector.unitReqs[0])
+	assert.Equal(t, [][]component.Component{{comp}}, collector.compReqs)
+
+	r, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	require.NoError(t, r.Close())
+	assert.Contains(t, names, "components/filestream-default/filestream-default/unit.txt")
+
+	// a component without units doesn't collect the diagnostics of all the units
+	collector = &fakeCollector{generated: now}
+	_, err = Bundle{
+		Dir:        dir,
+		Prefix:     "test-",
+		Keep:       1,
+		TopPath:    topPath,
+		Components: []component.Component{{ID: "endpoint-default"}},
+	}.Write(t.Context(), log, collector, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, collector.unitReqs)
+
+	// only the most recent bundle is kept
+	bundles, err := filepath.Glob(filepath.Join(dir, "test-*.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "test-20251001T120100Z.zip")}, bundles)
+}
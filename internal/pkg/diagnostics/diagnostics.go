@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime/pprof"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
+	"github.com/elastic/elastic-agent/internal/pkg/remote/proxypath"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/version"
 )
@@ -95,6 +97,19 @@ func GlobalHooks() Hooks {
 				return fileBytes
 			},
 		},
+		{
+			Name:        "proxy",
+			Filename:    "proxy.yaml",
+			Description: "network path, proxy or direct connection, used by the clients with proxy failover paths",
+			ContentType: "application/yaml",
+			Hook: func(_ context.Context) []byte {
+				o, err := yaml.Marshal(proxypath.States())
+				if err != nil {
+					return []byte(fmt.Sprintf("error: %q", err))
+				}
+				return o
+			},
+		},
 		{
 			Name:        "goroutine",
 			Filename:    "goroutine.pprof.gz",
@@ -173,6 +188,7 @@ func CreateCPUProfile(ctx context.Context, period time.Duration) ([]byte, error)
 }
 
 // ZipArchive creates a zipped diagnostics bundle using the passed writer with the passed diagnostics and local logs.
+// The secrets of the files and of the logs are redacted with the redactor, nothing is redacted when it is nil.
 // If any error is encountered when writing the contents of the archive it is returned.
 func ZipArchive(
 	errOut,
@@ -181,7 +197,8 @@ func ZipArchive(
 	agentDiag []client.DiagnosticFileResult,
 	unitDiags []client.DiagnosticUnitResult,
 	compDiags []client.DiagnosticComponentResult,
-	excludeEvents bool) error {
+	excludeEvents bool,
+	redactor *Redactor) error {
 
 	ts := time.Now().UTC()
 	zw := zip.NewWriter(w)
@@ -196,7 +213,7 @@ func ZipArchive(
 		if err != nil {
 			return fmt.Errorf("error creating header for agent diagnostics: %w", err)
 		}
-		err = writeRedacted(errOut, zf, ad.Filename, ad)
+		err = writeRedacted(errOut, zf, ad.Filename, ad, redactor)
 		if err != nil {
 			return fmt.Errorf("error writing file for agent diagnostics: %w", err)
 		}
@@ -256,7 +273,7 @@ func ZipArchive(
 					if err != nil {
 						return fmt.Errorf("error creating .zip header for %s: %w", res.Filename, err)
 					}
-					err = writeRedacted(errOut, resFileWriter, filePath, res)
+					err = writeRedacted(errOut, resFileWriter, filePath, res, redactor)
 					if err != nil {
 						return fmt.Errorf("error writing %s in zip file: %w", res.Filename, err)
 					}
@@ -293,7 +310,7 @@ func ZipArchive(
 				if err != nil {
 					return err
 				}
-				err = writeRedacted(errOut, w, filePath, fr)
+				err = writeRedacted(errOut, w, filePath, fr, redactor)
 				if err != nil {
 					return err
 				}
@@ -302,7 +319,24 @@ func ZipArchive(
 	}
 
 	// Gather Logs:
-	return zipLogs(zw, ts, topPath, excludeEvents)
+	return zipLogs(zw, ts, topPath, excludeEvents, redactor)
+}
+
+// RemoveOldBundles removes the oldest diagnostics bundles of dir whose names are prefix followed by their
+// capture time, only the keep most recent ones are kept.
+func RemoveOldBundles(dir, prefix string, keep int) error {
+	bundles, err := filepath.Glob(filepath.Join(dir, prefix+"*.zip"))
+	if err != nil || len(bundles) <= keep {
+		return err
+	}
+	slices.Sort(bundles)
+	var errs []error
+	for _, bundle := range bundles[:len(bundles)-keep] {
+		if err := os.Remove(bundle); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove diagnostics bundle %s: %w", bundle, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
@@ -323,24 +357,32 @@ func writeErrorResult(zw *zip.Writer, path string, errBody string) error {
 	return nil
 }
 
-func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResult client.DiagnosticFileResult) error {
-	out := &fileResult.Content
+// writeRedacted writes the content of the file redacted with the redactor. The keys of YAML files are redacted
+// from their structure while the other text files are redacted line by line, binary files are written as is.
+func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResult client.DiagnosticFileResult, redactor *Redactor) error {
+	if redactor == nil || fileResult.ContentType == "application/octet-stream" {
+		_, err := resultWriter.Write(fileResult.Content)
+		return err
+	}
 
-	// Should we support json too?
+	out := &fileResult.Content
+	keyValues := true
 	if fileResult.ContentType == "application/yaml" {
 		var unmarshalled any
 		err := yaml.Unmarshal(fileResult.Content, &unmarshalled)
 		if err != nil {
-			// Best effort, output a warning but still include the file
-			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error: %s\n", fullFilePath, err)
+			// Best effort, output a warning but still include the file redacted as text
+			fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to unmarshalling error, redacting it as text: %s\n", fullFilePath, err)
 		} else {
+			keyValues = false
 			switch t := unmarshalled.(type) { // could be a plain string, we only redact if this is a proper map
 			case map[string]any:
-				t = Redact(t, errOut)
+				t = redactMap(errOut, redactor, t, false)
 				redacted, err := yaml.Marshal(t)
 				if err != nil {
-					// Best effort, output a warning but still include the file
-					fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error: %s\n", fullFilePath, err)
+					// Best effort, output a warning but still include the file redacted as text
+					fmt.Fprintf(errOut, "[WARNING] Could not redact %s due to marshalling error, redacting it as text: %s\n", fullFilePath, err)
+					keyValues = true
 				} else {
 					out = &redacted
 				}
@@ -349,8 +391,7 @@ func writeRedacted(errOut, resultWriter io.Writer, fullFilePath string, fileResu
 		}
 	}
 
-	_, err := resultWriter.Write(*out)
-	return err
+	return redactor.redactText(resultWriter, bytes.NewReader(*out), keyValues)
 }
 
 const redactionMarkerPrefix = "__mark_redact_"
@@ -359,7 +400,7 @@ const redactionMarkerPrefix = "__mark_redact_"
 // the whole generic function here is out of paranoia. Although extremely unlikely,
 // we have no way of guaranteeing we'll get a "normal" map[string]interface{},
 // since the diagnostic interface is a bit of a free-for-all
-func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}, sliceElem bool) map[K]interface{} {
+func redactMap[K comparable](errOut io.Writer, redactor *Redactor, inputMap map[K]interface{}, sliceElem bool) map[K]interface{} {
 	if inputMap == nil {
 		return nil
 	}
@@ -369,11 +410,11 @@ func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}, slice
 		if rootValue != nil {
 			switch cast := rootValue.(type) {
 			case map[string]interface{}:
-				rootValue = redactMap(errOut, cast, sliceElem)
+				rootValue = redactMap(errOut, redactor, cast, sliceElem)
 			case map[interface{}]interface{}:
-				rootValue = redactMap(errOut, cast, sliceElem)
+				rootValue = redactMap(errOut, redactor, cast, sliceElem)
 			case map[int]interface{}:
-				rootValue = redactMap(errOut, cast, sliceElem)
+				rootValue = redactMap(errOut, redactor, cast, sliceElem)
 			case []interface{}:
 				// Recursively process each element in the slice so that we also walk
 				// through lists (e.g. inputs[4].streams[0]). This is required to
@@ -382,18 +423,18 @@ func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}, slice
 				for i, value := range cast {
 					switch m := value.(type) {
 					case map[string]interface{}:
-						cast[i] = redactMap(errOut, m, true)
+						cast[i] = redactMap(errOut, redactor, m, true)
 					case map[interface{}]interface{}:
-						cast[i] = redactMap(errOut, m, true)
+						cast[i] = redactMap(errOut, redactor, m, true)
 					case map[int]interface{}:
-						cast[i] = redactMap(errOut, m, true)
+						cast[i] = redactMap(errOut, redactor, m, true)
 					}
 				}
 				rootValue = cast
 			case string:
 				if keyString, ok := any(rootKey).(string); ok {
 					// Avoid global redaction of slice elements.
-					if redactKey(keyString) && !sliceElem {
+					if redactor.redactKey(keyString) && !sliceElem {
 						rootValue = REDACTED
 					}
 				}
@@ -434,31 +475,14 @@ func redactMap[K comparable](errOut io.Writer, inputMap map[K]interface{}, slice
 	return inputMap
 }
 
-func redactKey(k string) bool {
-	// "routekey" shouldn't be redacted.
-	// Add any other exceptions here.
-	if k == "routekey" {
-		return false
-	}
-
-	k = strings.ToLower(k)
-	return strings.Contains(k, "auth") ||
-		strings.Contains(k, "certificate") ||
-		strings.Contains(k, "passphrase") ||
-		strings.Contains(k, "password") ||
-		strings.Contains(k, "token") ||
-		strings.Contains(k, "key") ||
-		strings.Contains(k, "secret")
-}
-
-func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool) error {
+func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool, redactor *Redactor) error {
 	homePath := paths.HomeFrom(topPath)
 	dataPath := paths.DataFrom(topPath)
 	currentDir := filepath.Base(homePath)
 	if !paths.IsVersionHome() {
 		// running in a container with custom top path set
 		// logs are directly under top path
-		return zipLogsWithPath(homePath, currentDir, true, excludeEvents, zw, ts)
+		return zipLogsWithPath(homePath, currentDir, true, excludeEvents, zw, ts, redactor)
 	}
 
 	dataDir, err := os.Open(dataPath)
@@ -479,7 +503,7 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool) e
 		}
 		collectServices := dir == currentDir
 		path := filepath.Join(dataPath, dir)
-		if err := zipLogsWithPath(path, dir, collectServices, excludeEvents, zw, ts); err != nil {
+		if err := zipLogsWithPath(path, dir, collectServices, excludeEvents, zw, ts, redactor); err != nil {
 			return err
 		}
 	}
@@ -488,7 +512,7 @@ func zipLogs(zw *zip.Writer, ts time.Time, topPath string, excludeEvents bool) e
 }
 
 // zipLogs walks paths.Logs() and copies the file structure into zw in "logs/"
-func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvents bool, zw *zip.Writer, ts time.Time) error {
+func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvents bool, zw *zip.Writer, ts time.Time, redactor *Redactor) error {
 	_, err := zw.CreateHeader(&zip.FileHeader{
 		Name:     "logs/",
 		Method:   zip.Deflate,
@@ -499,7 +523,7 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvent
 	}
 
 	if collectServices {
-		if err := collectServiceComponentsLogs(zw); err != nil {
+		if err := collectServiceComponentsLogs(zw, redactor); err != nil {
 			return fmt.Errorf("failed to collect endpoint-security logs: %w", err)
 		}
 	}
@@ -554,14 +578,14 @@ func zipLogsWithPath(pathsHome, commitName string, collectServices, excludeEvent
 
 		// Add the file to the zip.
 		// Ignore files that don't exist to account for races with log rotation.
-		if err := saveLogs(name, path, zw); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		if err := saveLogs(name, path, zw, redactor); err != nil && !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 		return nil
 	})
 }
 
-func collectServiceComponentsLogs(zw *zip.Writer) error {
+func collectServiceComponentsLogs(zw *zip.Writer, redactor *Redactor) error {
 	platform, err := component.LoadPlatformDetail()
 	if err != nil {
 		return fmt.Errorf("failed to gather system information: %w", err)
@@ -595,7 +619,7 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 				return nil
 			}
 
-			return saveLogs("services/"+name, path, zw)
+			return saveLogs("services/"+name, path, zw, redactor)
 		})
 		if err != nil {
 			return err
@@ -604,7 +628,7 @@ func collectServiceComponentsLogs(zw *zip.Writer) error {
 	return nil
 }
 
-func saveLogs(name string, logPath string, zw *zip.Writer) error {
+func saveLogs(name string, logPath string, zw *zip.Writer, redactor *Redactor) error {
 	ts := time.Now().UTC()
 	lf, err := os.Open(logPath)
 	if err != nil {
@@ -622,6 +646,9 @@ func saveLogs(name string, logPath string, zw *zip.Writer) error {
 	if err != nil {
 		return err
 	}
+	if redactor != nil {
+		return redactor.redactText(zf, lf, true)
+	}
 	_, err = io.Copy(zf, lf)
 	if err != nil {
 		return err
@@ -632,7 +659,7 @@ func saveLogs(name string, logPath string, zw *zip.Writer) error {
 
 // Redact redacts sensitive values from the passed mapStr.
 func Redact(mapStr map[string]any, errOut io.Writer) map[string]any {
-	return redactMap(errOut, mapStr, false)
+	return redactMap(errOut, DefaultRedactor(), mapStr, false)
 }
 
 // AddSecretMarkers adds secret redaction markers to the config by looking at the secret_paths field.
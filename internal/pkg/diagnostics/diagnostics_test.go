@@ -113,7 +113,7 @@ i4EFZLWrFRsAAAARYWxleGtAZ3JlbWluLm5lc3QBAg==
 	outWriter := strings.Builder{}
 	res := client.DiagnosticFileResult{Content: formatted, ContentType: "application/yaml"}
 
-	err = writeRedacted(&errOut, &outWriter, "test/path", res)
+	err = writeRedacted(&errOut, &outWriter, "test/path", res, DefaultRedactor())
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -127,7 +127,7 @@ func TestRedactPlainString(t *testing.T) {
 	inputString := "Just a string"
 	res := client.DiagnosticFileResult{Content: []byte(inputString), ContentType: "application/yaml"}
 
-	err := writeRedacted(&errOut, &outWriter, "test/path", res)
+	err := writeRedacted(&errOut, &outWriter, "test/path", res, DefaultRedactor())
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -151,7 +151,7 @@ mapping:
 	outWriter := strings.Builder{}
 
 	res := client.DiagnosticFileResult{Content: []byte(testComplexKey), ContentType: "application/yaml"}
-	err := writeRedacted(&errOut, &outWriter, "test/path", res)
+	err := writeRedacted(&errOut, &outWriter, "test/path", res, DefaultRedactor())
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -355,7 +355,7 @@ func TestRedactWithMarkers(t *testing.T) {
 			var out bytes.Buffer
 			var errOut bytes.Buffer
 
-			err = writeRedacted(&errOut, &out, "testPath", file)
+			err = writeRedacted(&errOut, &out, "testPath", file, DefaultRedactor())
 			require.NoError(t, err)
 
 			var actual map[string]any
@@ -386,7 +386,7 @@ func TestUnitAndStateMapping(t *testing.T) {
 	outWriter := strings.Builder{}
 	res := client.DiagnosticFileResult{Content: formatted, ContentType: "application/yaml"}
 
-	err = writeRedacted(&errOut, &outWriter, "test/path", res)
+	err = writeRedacted(&errOut, &outWriter, "test/path", res, DefaultRedactor())
 	require.NoError(t, err)
 
 	require.Empty(t, errOut.String())
@@ -450,7 +450,7 @@ func zipLogsAndAssertFiles(t *testing.T, topPath string, excludeEvents bool, exp
 	// Zip the logs directory.
 	buf := new(bytes.Buffer)
 	w := zip.NewWriter(buf)
-	require.NoError(t, zipLogs(w, time.Now(), topPath, excludeEvents))
+	require.NoError(t, zipLogs(w, time.Now(), topPath, excludeEvents, nil))
 	require.NoError(t, w.Close())
 
 	// Read back the contents.
@@ -495,6 +495,9 @@ func TestGlobalHooks(t *testing.T) {
 			assert.NoErrorf(t, err, "hook %q validation error: %v", err)
 		case "package version":
 			assert.Equal(t, testPkgVer, string(output), "hook package version does not match")
+		case "proxy":
+			var states []map[string]interface{}
+			assert.NoErrorf(t, yaml.Unmarshal(output, &states), "hook %q returned invalid yaml", h.Name)
 		default:
 			ok, err = isPprof(output)
 			assert.Truef(t, ok, "hook %q returned incompatible data: %q", h.Name, hex.EncodeToString(output))
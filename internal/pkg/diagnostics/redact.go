@@ -0,0 +1,172 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactSelectors are the selectors of the keys whose values are redacted by default.
+var DefaultRedactSelectors = []string{
+	"*auth*",
+	"*certificate*",
+	"*passphrase*",
+	"*password*",
+	"*token*",
+	"*key*",
+	"*secret*",
+	// "routekey" shouldn't be redacted.
+	"!routekey",
+}
+
+var (
+	// pemPattern matches a whole PEM block, either on a single line or with escaped line breaks as in the logs.
+	pemPattern = regexp.MustCompile(`(-----BEGIN [A-Z0-9 ]+-----)[\s\S]*?(-----END [A-Z0-9 ]+-----)`)
+	// pemBeginPattern and pemEndPattern match the boundaries of a PEM block spanning several lines.
+	pemBeginPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----`)
+	pemEndPattern   = regexp.MustCompile(`-----END [A-Z0-9 ]+-----`)
+	// keyValuePattern matches the scalar value of a key in a JSON object, also when escaped in a JSON string,
+	// or in a key=value or key: value pair. The submatches are the opening quote of the key, the key, the
+	// separator and the value.
+	keyValuePattern = regexp.MustCompile(`(\\?"?)([A-Za-z0-9_.\-]+)(\\?"?[ \t]*[:=][ \t]*)(\\"(?:[^"\\\n]|\\[^"\n])*\\"|"(?:[^"\\\n]|\\.)*"|[^\s,;&"{}\[\]]+)`)
+)
+
+// Redactor redacts the secrets from the files written to a diagnostics bundle. The values of the keys matching
+// its selectors are replaced by REDACTED in the YAML files, and in the key/value pairs of the other text files
+// and of the logs. The content of the PEM blocks is redacted from all of them.
+type Redactor struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewRedactor returns a Redactor for the given selectors. A selector is a case-insensitive pattern of the keys
+// to redact where * matches any sequence of characters, e.g. *password*. A selector prefixed with ! excludes
+// the matching keys from the redaction.
+func NewRedactor(selectors ...string) *Redactor {
+	r := &Redactor{}
+	for _, selector := range selectors {
+		exclude := strings.HasPrefix(selector, "!")
+		pattern := regexp.QuoteMeta(strings.ToLower(strings.TrimPrefix(selector, "!")))
+		re := regexp.MustCompile("^" + strings.ReplaceAll(pattern, `\*`, ".*") + "$")
+		if exclude {
+			r.exclude = append(r.exclude, re)
+		} else {
+			r.include = append(r.include, re)
+		}
+	}
+	return r
+}
+
+// DefaultRedactor returns a Redactor for the DefaultRedactSelectors.
+func DefaultRedactor() *Redactor {
+	return NewRedactor(DefaultRedactSelectors...)
+}
+
+// redactKey returns true when the value of the key must be redacted.
+func (r *Redactor) redactKey(k string) bool {
+	k = strings.ToLower(k)
+	for _, re := range r.exclude {
+		if re.MatchString(k) {
+			return false
+		}
+	}
+	for _, re := range r.include {
+		if re.MatchString(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactText copies the text of src to w, redacting the PEM blocks and the values of the redacted keys.
+func (r *Redactor) RedactText(w io.Writer, src io.Reader) error {
+	return r.redactText(w, src, true)
+}
+
+// redactText copies src to w line by line, redacting the PEM blocks and, when keyValues is set, the values of
+// the redacted keys. The lines of a PEM block spanning several lines are replaced by a single REDACTED.
+func (r *Redactor) redactText(w io.Writer, src io.Reader, keyValues bool) error {
+	br := bufio.NewReader(src)
+	inPEM := false
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			if inPEM {
+				end := pemEndPattern.FindIndex(line)
+				if end == nil {
+					line = nil
+				} else {
+					line = line[end[0]:]
+					inPEM = false
+				}
+			}
+			if len(line) > 0 {
+				line = r.redactLine(line, keyValues)
+				if begins := pemBeginPattern.FindAllIndex(line, -1); len(begins) > 0 {
+					begin := begins[len(begins)-1]
+					if !pemEndPattern.Match(line[begin[1]:]) {
+						// the block continues on the next lines
+						line = append(line[:begin[1]:begin[1]], REDACTED+"\n"...)
+						inPEM = true
+					}
+				}
+				if _, wErr := w.Write(line); wErr != nil {
+					return wErr
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// redactLine redacts the PEM blocks and, when keyValues is set, the values of the redacted keys of the line.
+func (r *Redactor) redactLine(line []byte, keyValues bool) []byte {
+	line = pemPattern.ReplaceAll(line, []byte("${1}"+REDACTED+"${2}"))
+	if !keyValues {
+		return line
+	}
+
+	var out bytes.Buffer
+	last := 0
+	for pos := 0; pos < len(line); {
+		m := keyValuePattern.FindSubmatchIndex(line[pos:])
+		if m == nil {
+			break
+		}
+		for i := range m {
+			m[i] += pos
+		}
+		if !r.redactKey(string(line[m[4]:m[5]])) {
+			// the value is scanned as well, it can hold escaped JSON
+			pos = m[7]
+			continue
+		}
+		pos = m[9]
+		quote, value := line[m[2]:m[3]], line[m[8]:m[9]]
+		out.Write(line[last:m[8]])
+		switch {
+		case bytes.HasPrefix(value, []byte(`\"`)) || bytes.HasPrefix(quote, []byte(`\"`)):
+			out.WriteString(`\"` + REDACTED + `\"`)
+		case bytes.HasPrefix(value, []byte(`"`)) || len(quote) > 0:
+			// the value of a quoted key is quoted to keep JSON valid
+			out.WriteString(`"` + REDACTED + `"`)
+		default:
+			out.WriteString(REDACTED)
+		}
+		last = m[9]
+	}
+	out.Write(line[last:])
+	return out.Bytes()
+}
@@ -12,6 +12,10 @@ import (
 type operand interface{}
 
 func compareEQ(left, right operand) (bool, error) {
+	if c, ok, err := compareTimes(left, right); ok {
+		// a time is never equal to a value that isn't a time
+		return err == nil && c == 0, nil
+	}
 	switch v := left.(type) {
 	case *null:
 		_, ok := right.(*null)
@@ -115,6 +119,9 @@ func compareEQ(left, right operand) (bool, error) {
 }
 
 func compareNEQ(left, right operand) (bool, error) {
+	if c, ok, err := compareTimes(left, right); ok {
+		return err != nil || c != 0, nil
+	}
 	switch v := left.(type) {
 	case *null:
 		_, ok := right.(*null)
@@ -218,6 +225,9 @@ func compareNEQ(left, right operand) (bool, error) {
 }
 
 func compareLT(left, right operand) (bool, error) {
+	if c, ok, err := compareTimes(left, right); ok {
+		return err == nil && c < 0, err
+	}
 	switch v := left.(type) {
 	case int:
 		switch rv := right.(type) {
@@ -255,6 +265,9 @@ func compareLT(left, right operand) (bool, error) {
 }
 
 func compareLTE(left, right operand) (bool, error) {
+	if c, ok, err := compareTimes(left, right); ok {
+		return err == nil && c <= 0, err
+	}
 	switch v := left.(type) {
 	case int:
 		switch rv := right.(type) {
@@ -292,6 +305,9 @@ func compareLTE(left, right operand) (bool, error) {
 }
 
 func compareGT(left, right operand) (bool, error) {
+	if c, ok, err := compareTimes(left, right); ok {
+		return err == nil && c > 0, err
+	}
 	switch v := left.(type) {
 	case int:
 		switch rv := right.(type) {
@@ -329,6 +345,9 @@ func compareGT(left, right operand) (bool, error) {
 }
 
 func compareGTE(left, right operand) (bool, error) {
+	if c, ok, err := compareTimes(left, right); ok {
+		return err == nil && c >= 0, err
+	}
 	switch v := left.(type) {
 	case int:
 		switch rv := right.(type) {
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/antlr4-go/antlr/v4"
 	"github.com/stretchr/testify/assert"
@@ -404,6 +405,76 @@ func TestEql(t *testing.T) {
 	}
 }
 
+func TestEqlTime(t *testing.T) {
+	// Wednesday
+	fixed := time.Date(2024, time.January, 3, 10, 30, 0, 0, time.UTC)
+	timeNow = func() time.Time { return fixed }
+	defer func() { timeNow = time.Now }()
+
+	testcases := []struct {
+		expression string
+		result     bool
+		err        bool
+	}{
+		{expression: "hour() == 10", result: true},
+		{expression: "minute() == 30", result: true},
+		{expression: "dayOfWeek() == 3", result: true},
+		{expression: "hour('Europe/Paris') == 11", result: true},
+		{expression: "hour('America/New_York') == 5", result: true},
+		{expression: "hour(now('Asia/Tokyo')) == 19", result: true},
+		{expression: "dayOfWeek('Pacific/Kiritimati') == 4", result: true},
+		{expression: "hour() >= 9 and hour() < 17 and dayOfWeek() <= 5", result: true},
+		{expression: "hour('Asia/Tokyo') >= 9 and hour('Asia/Tokyo') < 17", result: false},
+		{expression: "now() == '2024-01-03T10:30:00Z'", result: true},
+		{expression: "now() == '2024-01-03T11:30:00+01:00'", result: true},
+		{expression: "now('Europe/Paris') == '2024-01-03T10:30:00Z'", result: true},
+		{expression: "'2024-01-03T10:30:00Z' != now()", result: false},
+		{expression: "now() > '2024-01-03T11:00:00+01:00'", result: true},
+		{expression: "now() >= '2024-01-03T10:30:00Z'", result: true},
+		{expression: "now() < '2024-01-03T09:00:00-05:00'", result: true},
+		{expression: "'2024-01-03T09:00:00-05:00' <= now()", result: false},
+		{expression: "now() == 1", result: false},
+		{expression: "now() != 'not a time'", result: true},
+		{expression: "now() < 'not a time'", err: true},
+		{expression: "now() > 1", err: true},
+		{expression: "hour('Not/AZone') == 1", err: true},
+		{expression: "hour(1) == 1", err: true},
+		{expression: "now('UTC', 'UTC') == 1", err: true},
+	}
+
+	store := &testVarStore{}
+	for _, test := range testcases {
+		t.Run(test.expression, func(t *testing.T) {
+			r, err := Eval(test.expression, store, false)
+			if test.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.result, r)
+		})
+	}
+}
+
+func TestEqlUsesTime(t *testing.T) {
+	testcases := map[string]bool{
+		"hour() >= 9 and hour() < 17":                  true,
+		"${host.name} == 'a' or dayOfWeek() == 1":      true,
+		"length(string(now('UTC'))) > 0":               true,
+		"minute() == 0":                                true,
+		"${host.name} == 'hour()'":                     false,
+		"stringContains(${host.name}, 'now')":          false,
+		"arrayContains(${host.tags}, 'minute', 'now')": false,
+	}
+	for expression, expected := range testcases {
+		t.Run(expression, func(t *testing.T) {
+			e, err := New(expression)
+			require.NoError(t, err)
+			assert.Equal(t, expected, e.UsesTime())
+		})
+	}
+}
+
 func debug(t *testing.T, expression string) {
 	raw := antlr.NewInputStream(expression)
 
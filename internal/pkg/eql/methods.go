@@ -35,4 +35,10 @@ var methods = map[string]callFunc{
 	"startsWith":     startsWith,
 	"string":         str,
 	"stringContains": stringContains,
+
+	// time
+	"dayOfWeek": dayOfWeek,
+	"hour":      hour,
+	"minute":    minute,
+	"now":       now,
 }
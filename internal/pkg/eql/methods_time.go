@@ -0,0 +1,162 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package eql
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/antlr4-go/antlr/v4"
+
+	"github.com/elastic/elastic-agent/internal/pkg/eql/parser"
+
+	// embed the timezone database, hosts like Windows don't always provide one
+	_ "time/tzdata"
+)
+
+// timeNow returns the current time, replaced in tests.
+var timeNow = time.Now
+
+// timeMethods are the methods returning the current time, or a part of it.
+var timeMethods = map[string]struct{}{
+	"dayOfWeek": {},
+	"hour":      {},
+	"minute":    {},
+	"now":       {},
+}
+
+// UsesTime returns true when the expression calls a time function, e.g. hour(), the result of the expression
+// then changes over time and it must be evaluated again periodically.
+func (e *Expression) UsesTime() bool {
+	return usesTime(e.tree)
+}
+
+func usesTime(tree antlr.Tree) bool {
+	if f, ok := tree.(*parser.ExpFunctionContext); ok {
+		if _, ok := timeMethods[f.NAME().GetText()]; ok {
+			return true
+		}
+	}
+	for _, child := range tree.GetChildren() {
+		if usesTime(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// now returns the current time, in the local timezone or in the timezone given as argument
+func now(args []interface{}) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("now: accepts between 0-1 arguments; received %d", len(args))
+	}
+	if len(args) == 0 {
+		return timeNow(), nil
+	}
+	loc, err := location("now", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return timeNow().In(loc), nil
+}
+
+// hour returns the hour, between 0 and 23, of the time or of the current time in the timezone given as argument
+func hour(args []interface{}) (interface{}, error) {
+	t, err := timeArg("hour", args)
+	if err != nil {
+		return nil, err
+	}
+	return t.Hour(), nil
+}
+
+// minute returns the minute, between 0 and 59, of the time or of the current time in the timezone given as argument
+func minute(args []interface{}) (interface{}, error) {
+	t, err := timeArg("minute", args)
+	if err != nil {
+		return nil, err
+	}
+	return t.Minute(), nil
+}
+
+// dayOfWeek returns the ISO 8601 day of the week, from 1 for Monday to 7 for Sunday, of the time or of the current
+// time in the timezone given as argument
+func dayOfWeek(args []interface{}) (interface{}, error) {
+	t, err := timeArg("dayOfWeek", args)
+	if err != nil {
+		return nil, err
+	}
+	if t.Weekday() == time.Sunday {
+		return 7, nil
+	}
+	return int(t.Weekday()), nil
+}
+
+// timeArg returns the time given as argument, or the current time in the local timezone or in the timezone given
+// as argument.
+func timeArg(name string, args []interface{}) (time.Time, error) {
+	if len(args) > 1 {
+		return time.Time{}, fmt.Errorf("%s: accepts between 0-1 arguments; received %d", name, len(args))
+	}
+	if len(args) == 0 {
+		return timeNow(), nil
+	}
+	if t, ok := args[0].(time.Time); ok {
+		return t, nil
+	}
+	loc, err := location(name, args[0])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return timeNow().In(loc), nil
+}
+
+// location loads the timezone with the IANA name, e.g. Europe/Paris.
+func location(name string, arg interface{}) (*time.Location, error) {
+	tz, ok := arg.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s: argument 0 must be a time or a timezone; received %T", name, arg)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("%s: unknown timezone %q: %w", name, tz, err)
+	}
+	return loc, nil
+}
+
+// toTime converts a time or a RFC 3339 string, e.g. 2024-01-02T09:00:00+01:00, into a time.
+func toTime(v operand) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("compare: %q is not a RFC 3339 time: %w", t, err)
+		}
+		return parsed, nil
+	default:
+		return time.Time{}, fmt.Errorf("compare: incompatible type to compare with a time, %T", v)
+	}
+}
+
+// compareTimes compares the operands when at least one of them is a time, the other one is converted with toTime.
+// The comparison is done on the instants, so times in different timezones compare as expected. The returned bool
+// is false when none of the operands is a time.
+func compareTimes(left, right operand) (int, bool, error) {
+	_, leftIsTime := left.(time.Time)
+	_, rightIsTime := right.(time.Time)
+	if !leftIsTime && !rightIsTime {
+		return 0, false, nil
+	}
+	l, err := toTime(left)
+	if err != nil {
+		return 0, true, err
+	}
+	r, err := toTime(right)
+	if err != nil {
+		return 0, true, err
+	}
+	return l.Compare(r), true, nil
+}
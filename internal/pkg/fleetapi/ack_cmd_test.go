@@ -51,9 +51,7 @@ func TestAck(t *testing.T) {
 			action := &ActionPolicyChange{
 				ActionID:   "my-id",
 				ActionType: "POLICY_CHANGE",
-				Data: struct {
-					Policy map[string]interface{} `json:"policy" yaml:"policy,omitempty"`
-				}{Policy: map[string]interface{}{
+				Data: ActionPolicyChangeData{Policy: map[string]interface{}{
 					"id": "config_id",
 				}},
 			}
@@ -13,6 +13,7 @@ import (
 	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -138,6 +139,14 @@ func TestAcker_Ack(t *testing.T) {
 					},
 					Err: errors.New("upgrade failed"),
 				},
+				&fleetapi.ActionUpgrade{
+					ActionID:   "upgrade-deferred",
+					ActionType: fleetapi.ActionTypeUpgrade,
+					Err: &fleetapi.DeferredError{
+						Until:  time.Date(2024, time.January, 6, 0, 0, 0, 0, time.UTC),
+						Reason: "outside of the upgrade windows",
+					},
+				},
 			},
 		},
 	}
@@ -164,15 +173,20 @@ func TestAcker_Ack(t *testing.T) {
 					// Check payload
 					require.NotEmpty(t, req.Events[i].Payload)
 					var pl struct {
-						Retry   bool `json:"retry"`
-						Attempt int  `json:"retry_attempt,omitempty"`
+						Retry         bool   `json:"retry"`
+						Attempt       int    `json:"retry_attempt,omitempty"`
+						DeferredUntil string `json:"deferred_until,omitempty"`
 					}
 					err := json.Unmarshal(req.Events[i].Payload, &pl)
 					require.NoError(t, err)
 					assert.Equal(t, a.Data.Retry, pl.Attempt,
 						"action ID %s failed", a.ActionID)
 					// Check retry flag
-					if pl.Attempt > 0 {
+					var deferred *fleetapi.DeferredError
+					if errors.As(a.Err, &deferred) {
+						assert.True(t, pl.Retry)
+						assert.Equal(t, "2024-01-06T00:00:00Z", pl.DeferredUntil)
+					} else if pl.Attempt > 0 {
 						assert.True(t, pl.Retry)
 					} else {
 						assert.False(t, pl.Retry)
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/core/backoff"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
@@ -112,6 +113,7 @@ func (r *Retrier) Enqueue(actions []fleetapi.Action) {
 
 	r.mx.Lock()
 	r.actions = append(r.actions, actions...)
+	agentmetrics.SetAckRetryQueueSize(len(r.actions))
 	r.mx.Unlock()
 
 	// Signal to kick off retry loop, non blocking if the signal is already pending
@@ -157,6 +159,7 @@ func (r *Retrier) runRetries(ctx context.Context) {
 			b.Reset() // reset backoff if new actions came while committing
 		}
 		r.actions = append(failed, r.actions...)
+		agentmetrics.SetAckRetryQueueSize(len(r.actions))
 		r.log.Debugf("ack retrier: total actions: %#v", r.actions)
 		exit := (len(r.actions) == 0)
 
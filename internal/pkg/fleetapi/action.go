@@ -36,6 +36,8 @@ const (
 	ActionTypeDiagnostics = "REQUEST_DIAGNOSTICS"
 	// ActionTypeDiagnostics specifies a diagnostics action.
 	ActionTypeMigrate = "MIGRATE"
+	// ActionTypeOperation specifies a signed operational action from the local allowlist.
+	ActionTypeOperation = "OPERATION"
 )
 
 // Error values that the Action interface can return
@@ -44,6 +46,17 @@ var (
 	ErrNoExpiration = fmt.Errorf("action has no expiration")
 )
 
+// DeferredError is returned by the handler of a RetryableAction that can't run the action yet. The action is
+// queued again to start at Until, and the deferral is acknowledged to Fleet as a retry.
+type DeferredError struct {
+	Until  time.Time
+	Reason string
+}
+
+func (e *DeferredError) Error() string {
+	return fmt.Sprintf("action deferred until %s: %s", e.Until.Format(time.RFC3339), e.Reason)
+}
+
 // Action base interface for all the implemented action from the fleet API.
 type Action interface {
 	fmt.Stringer
@@ -119,6 +132,8 @@ func NewAction(actionType string) Action {
 		action = &ActionUpgrade{}
 	case ActionTypeMigrate:
 		action = &ActionMigrate{}
+	case ActionTypeOperation:
+		action = &ActionOperation{}
 	default:
 		action = &ActionUnknown{OriginalType: actionType}
 	}
@@ -222,6 +237,19 @@ type ActionPolicyChange struct {
 
 type ActionPolicyChangeData struct {
 	Policy map[string]interface{} `json:"policy" yaml:"policy,omitempty"`
+	// PolicyDelta is sent instead of the policy to an agent reporting the hash of its policy on checkin.
+	PolicyDelta *PolicyDelta `json:"policy_delta,omitempty" yaml:"policy_delta,omitempty"`
+}
+
+// PolicyDelta is the difference between the policy of the agent and the new policy.
+type PolicyDelta struct {
+	// BaseHash is the hash of the policy the patches apply to, as reported by the agent on checkin.
+	BaseHash string `json:"base_hash" yaml:"base_hash"`
+	// Hash is the hash of the new policy, the hash of a policy is its PolicyHash.
+	Hash string `json:"hash" yaml:"hash"`
+	// Patches are the changes to apply in order to the policy, e.g. {"op": "set", "path": ["outputs",
+	// "default", "hosts", "0"], "value": "https://es:9200"}. They are applied by the policy change handler.
+	Patches []map[string]interface{} `json:"patches" yaml:"patches"`
 }
 
 func (a *ActionPolicyChange) String() string {
@@ -281,12 +309,17 @@ func (a *ActionUpgrade) AckEvent() AckEvent {
 		// FIXME Do we want to change EventType/SubType here?
 		event.Error = a.Err.Error()
 		var payload struct {
-			Retry   bool `json:"retry"`
-			Attempt int  `json:"retry_attempt,omitempty"`
+			Retry         bool   `json:"retry"`
+			Attempt       int    `json:"retry_attempt,omitempty"`
+			DeferredUntil string `json:"deferred_until,omitempty"`
 		}
 		payload.Retry = true
 		payload.Attempt = a.Data.Retry
-		if a.Data.Retry < 1 { // retry is set to -1 if it will not re attempt
+		var deferred *DeferredError
+		if errors.As(a.Err, &deferred) {
+			// a deferred action is always attempted again, without counting as a retry
+			payload.DeferredUntil = deferred.Until.UTC().Format(time.RFC3339)
+		} else if a.Data.Retry < 1 { // retry is set to -1 if it will not re attempt
 			payload.Retry = false
 		}
 		p, _ := json.Marshal(payload)
@@ -486,6 +519,80 @@ type ActionMigrateData struct {
 	Settings json.RawMessage `json:"settings" yaml:"settings,omitempty"`
 }
 
+// ActionOperation is a request to run one of the operations of the local allowlist, its payload must be signed.
+type ActionOperation struct {
+	ActionID   string              `json:"id" yaml:"id"`
+	ActionType string              `json:"type" yaml:"type"`
+	Data       ActionOperationData `json:"data,omitempty"`
+
+	Signature *Signed `json:"signed,omitempty" yaml:"signed,omitempty" mapstructure:"signed,omitempty"`
+
+	// Result is the audit record of the execution reported back to Fleet.
+	Result *ActionOperationResult `json:"-" yaml:"-" mapstructure:"-"`
+	Err    error                  `json:"-" yaml:"-" mapstructure:"-"`
+}
+
+type ActionOperationData struct {
+	// Name: name of the operation in the allowlist.
+	Name string `json:"name" yaml:"name"`
+
+	// Args: additional arguments, only allowed when the operation accepts them.
+	Args []string `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+// ActionOperationResult is the audit record of an operation execution.
+type ActionOperationResult struct {
+	Name        string   `json:"name"`
+	Command     string   `json:"command,omitempty"`
+	Args        []string `json:"args,omitempty"`
+	SHA256      string   `json:"payload_sha256,omitempty"`
+	StartedAt   string   `json:"started_at,omitempty"`
+	CompletedAt string   `json:"completed_at,omitempty"`
+	ExitCode    int      `json:"exit_code"`
+	Output      string   `json:"output,omitempty"`
+	Truncated   bool     `json:"output_truncated,omitempty"`
+}
+
+// ID returns the ID of the Action.
+func (a *ActionOperation) ID() string {
+	return a.ActionID
+}
+
+// Signed returns the Signed portion of the Action.
+func (a *ActionOperation) Signed() *Signed {
+	return a.Signature
+}
+
+// Type returns the type of the Action.
+func (a *ActionOperation) Type() string {
+	return a.ActionType
+}
+
+func (a *ActionOperation) String() string {
+	var s strings.Builder
+	s.WriteString("id: ")
+	s.WriteString(a.ActionID)
+	s.WriteString(", type: ")
+	s.WriteString(a.ActionType)
+	s.WriteString(", name: ")
+	s.WriteString(a.Data.Name)
+	return s.String()
+}
+
+func (a *ActionOperation) AckEvent() AckEvent {
+	event := newAckEvent(a.ActionID, a.ActionType)
+	if a.Err != nil {
+		event.Error = a.Err.Error()
+	}
+	if a.Result != nil {
+		event.StartedAt = a.Result.StartedAt
+		event.CompletedAt = a.Result.CompletedAt
+		p, _ := json.Marshal(a.Result)
+		event.Data = p
+	}
+	return event
+}
+
 func (a *ActionSettings) AckEvent() AckEvent {
 	return newAckEvent(a.ActionID, a.ActionType)
 }
@@ -0,0 +1,118 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+const renewCertificatePath = "/api/fleet/agents/%s/certificate"
+
+// RenewCertificateRequest requests a new client certificate for the mutual TLS with Fleet Server, it
+// is sent with the current certificate of the agent.
+//
+// Example:
+// POST /api/fleet/agents/{agent_id}/certificate
+//
+//	{
+//	  "csr": "-----BEGIN CERTIFICATE REQUEST-----..."
+//	}
+type RenewCertificateRequest struct {
+	CSR string `json:"csr"`
+}
+
+// Validate validates the certificate request before sending it to the API.
+func (e *RenewCertificateRequest) Validate() error {
+	if len(e.CSR) == 0 {
+		return errors.New("missing certificate signing request")
+	}
+	return nil
+}
+
+// RenewCertificateResponse is the new client certificate of the agent.
+//
+// Example:
+//
+//	{
+//	  "certificate": "-----BEGIN CERTIFICATE-----..."
+//	}
+type RenewCertificateResponse struct {
+	Certificate string `json:"certificate"`
+}
+
+// Validate validates the response send from the server.
+func (e *RenewCertificateResponse) Validate() error {
+	if len(e.Certificate) == 0 {
+		return errors.New("client certificate is missing")
+	}
+	return nil
+}
+
+// RenewCertificateCmd is the command requesting a new client certificate for the agent.
+type RenewCertificateCmd struct {
+	client client.Sender
+	info   AgentInfo
+}
+
+// NewRenewCertificateCmd creates a new RenewCertificateCmd.
+func NewRenewCertificateCmd(info AgentInfo, client client.Sender) *RenewCertificateCmd {
+	return &RenewCertificateCmd{
+		client: client,
+		info:   info,
+	}
+}
+
+// Execute requests a new client certificate for the certificate signing request.
+func (e *RenewCertificateCmd) Execute(ctx context.Context, r *RenewCertificateRequest) (*RenewCertificateResponse, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.New(err, "fail to encode the certificate request")
+	}
+
+	p := fmt.Sprintf(renewCertificatePath, e.info.AgentID())
+	resp, err := e.client.Send(ctx, "POST", p, nil, nil, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, errors.New(err,
+			"fail to request a client certificate from fleet",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, p))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrTooManyRequests
+	}
+
+	if status, temporary := temporaryServerErrorCodes[resp.StatusCode]; temporary {
+		return nil, fmt.Errorf("received status code %d (%s): %w", resp.StatusCode, status, ErrTemporaryServerError)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, client.ExtractError(resp.Body)
+	}
+
+	certResponse := &RenewCertificateResponse{}
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(certResponse); err != nil {
+		return nil, errors.New(err, "fail to decode certificate response")
+	}
+
+	if err := certResponse.Validate(); err != nil {
+		return nil, err
+	}
+
+	return certResponse, nil
+}
@@ -0,0 +1,60 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+func TestRenewCertificateCmd(t *testing.T) {
+	const withAPIKey = "secret"
+	agentInfo := &agentinfo{}
+
+	t.Run("request a new certificate", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/id/certificate", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				req := &RenewCertificateRequest{}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+				assert.Equal(t, "csr", req.CSR)
+				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(RenewCertificateResponse{Certificate: "certificate"})
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			resp, err := NewRenewCertificateCmd(agentInfo, client).Execute(context.Background(), &RenewCertificateRequest{CSR: "csr"})
+			require.NoError(t, err)
+			assert.Equal(t, "certificate", resp.Certificate)
+		},
+	))
+
+	t.Run("rejected request", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/id/certificate", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"statusCode": 403, "error": "Forbidden", "message": "certificate renewal is disabled"}`))
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			_, err := NewRenewCertificateCmd(agentInfo, client).Execute(context.Background(), &RenewCertificateRequest{CSR: "csr"})
+			assert.ErrorContains(t, err, "certificate renewal is disabled")
+
+			_, err = NewRenewCertificateCmd(agentInfo, client).Execute(context.Background(), &RenewCertificateRequest{})
+			assert.Error(t, err)
+		},
+	))
+}
@@ -0,0 +1,130 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// TruncatedIndicator is appended to the status messages truncated to fit the checkin payload budget.
+	TruncatedIndicator = "... [truncated]"
+
+	// minTruncatedMessageSize is the size status messages are not truncated below to fit the body budget.
+	minTruncatedMessageSize = 64
+)
+
+// zstdEncoder is shared by the checkins, EncodeAll can be called concurrently and the encoder is expensive to
+// allocate.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil)
+})
+
+// marshalWithBudget marshals the request, truncating the status messages of the components and units to
+// maxUnitMessageSize bytes. When the body is larger than maxBodySize, the messages are truncated further and
+// the unit payloads are dropped as a last resort. The messages of the request are modified in place.
+func (e *CheckinRequest) marshalWithBudget(maxBodySize, maxUnitMessageSize int) ([]byte, error) {
+	messages := e.messages()
+	limit := maxUnitMessageSize
+	if limit > 0 {
+		truncateMessages(messages, limit)
+	}
+	b, err := json.Marshal(e)
+	if err != nil || maxBodySize <= 0 || len(b) <= maxBodySize {
+		return b, err
+	}
+
+	if limit <= 0 {
+		for _, m := range messages {
+			limit = max(limit, len(m.original))
+		}
+	}
+	for len(b) > maxBodySize && limit > minTruncatedMessageSize {
+		limit = max(limit/2, minTruncatedMessageSize)
+		truncateMessages(messages, limit)
+		if b, err = json.Marshal(e); err != nil {
+			return nil, err
+		}
+	}
+	if len(b) <= maxBodySize {
+		return b, nil
+	}
+
+	for i := range e.Components {
+		for j := range e.Components[i].Units {
+			e.Components[i].Units[j].Payload = nil
+		}
+	}
+	return json.Marshal(e)
+}
+
+type checkinMessage struct {
+	message  *string
+	original string
+}
+
+// messages returns the status messages of the components and units of the request.
+func (e *CheckinRequest) messages() []checkinMessage {
+	var messages []checkinMessage
+	for i := range e.Components {
+		c := &e.Components[i]
+		messages = append(messages, checkinMessage{message: &c.Message, original: c.Message})
+		for j := range c.Units {
+			u := &c.Units[j]
+			messages = append(messages, checkinMessage{message: &u.Message, original: u.Message})
+		}
+	}
+	return messages
+}
+
+func truncateMessages(messages []checkinMessage, limit int) {
+	for _, m := range messages {
+		*m.message = truncateMessage(m.original, limit)
+	}
+}
+
+// truncateMessage truncates the message to limit bytes, including the indicator, on a rune boundary.
+func truncateMessage(message string, limit int) string {
+	if len(message) <= limit {
+		return message
+	}
+	cut := max(limit-len(TruncatedIndicator), 0)
+	for cut > 0 && !utf8.RuneStart(message[cut]) {
+		cut--
+	}
+	return message[:cut] + TruncatedIndicator
+}
+
+// compress compresses the body and returns the matching Content-Encoding, the encoding is empty when the body
+// is not compressed.
+func compress(compression string, b []byte) (string, []byte, error) {
+	switch compression {
+	case "gzip":
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(b); err != nil {
+			return "", nil, fmt.Errorf("failed to compress with gzip: %w", err)
+		}
+		if err := w.Close(); err != nil {
+			return "", nil, fmt.Errorf("failed to compress with gzip: %w", err)
+		}
+		return "gzip", buf.Bytes(), nil
+	case "zstd":
+		w, err := zstdEncoder()
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to compress with zstd: %w", err)
+		}
+		return "zstd", w.EncodeAll(b, nil), nil
+	default:
+		return "", b, nil
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateMessage(t *testing.T) {
+	assert.Equal(t, "healthy", truncateMessage("healthy", 20))
+	assert.Equal(t, "0123"+TruncatedIndicator, truncateMessage("0123456789"+strings.Repeat("x", 20), 4+len(TruncatedIndicator)))
+	// the message is not cut in the middle of a rune
+	assert.Equal(t, "é"+TruncatedIndicator, truncateMessage("ééééééééééé", 3+len(TruncatedIndicator)))
+}
+
+func TestCheckinRequestMarshalWithBudget(t *testing.T) {
+	newRequest := func(units int) *CheckinRequest {
+		unitList := make([]CheckinUnit, units)
+		for i := range unitList {
+			unitList[i] = CheckinUnit{
+				ID:      fmt.Sprintf("unit-%d", i),
+				Type:    "input",
+				Status:  "FAILED",
+				Message: strings.Repeat("error ", 200),
+				Payload: map[string]interface{}{"streams": strings.Repeat("s", 100)},
+			}
+		}
+		return &CheckinRequest{
+			Status:     "online",
+			Message:    "Running",
+			Components: []CheckinComponent{{ID: "filestream-default", Message: "Healthy", Units: unitList}},
+		}
+	}
+
+	t.Run("no budget", func(t *testing.T) {
+		r := newRequest(10)
+		b, err := r.marshalWithBudget(0, 0)
+		require.NoError(t, err)
+		expected, err := json.Marshal(newRequest(10))
+		require.NoError(t, err)
+		assert.Equal(t, expected, b)
+	})
+
+	t.Run("unit messages are truncated", func(t *testing.T) {
+		b, err := newRequest(10).marshalWithBudget(0, 100)
+		require.NoError(t, err)
+		var r CheckinRequest
+		require.NoError(t, json.Unmarshal(b, &r))
+		assert.Equal(t, "Healthy", r.Components[0].Message)
+		for _, u := range r.Components[0].Units {
+			assert.Len(t, u.Message, 100)
+			assert.True(t, strings.HasSuffix(u.Message, TruncatedIndicator))
+			assert.NotEmpty(t, u.Payload)
+		}
+	})
+
+	t.Run("messages are truncated further to fit the body", func(t *testing.T) {
+		b, err := newRequest(100).marshalWithBudget(64*1024, 1024)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(b), 64*1024)
+		var r CheckinRequest
+		require.NoError(t, json.Unmarshal(b, &r))
+		require.Len(t, r.Components[0].Units, 100)
+		assert.Less(t, len(r.Components[0].Units[0].Message), 1024)
+		assert.True(t, strings.HasSuffix(r.Components[0].Units[0].Message, TruncatedIndicator))
+		assert.NotEmpty(t, r.Components[0].Units[0].Payload)
+	})
+
+	t.Run("payloads are dropped as a last resort", func(t *testing.T) {
+		b, err := newRequest(200).marshalWithBudget(40*1024, 1024)
+		require.NoError(t, err)
+		var r CheckinRequest
+		require.NoError(t, json.Unmarshal(b, &r))
+		require.Len(t, r.Components[0].Units, 200)
+		assert.Len(t, r.Components[0].Units[0].Message, minTruncatedMessageSize)
+		assert.Empty(t, r.Components[0].Units[0].Payload)
+	})
+}
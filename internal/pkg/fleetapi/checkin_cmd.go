@@ -47,6 +47,20 @@ type CheckinRequest struct {
 	Message        string             `json:"message"`    // V2 Agent message
 	Components     []CheckinComponent `json:"components"` // V2 Agent components
 	UpgradeDetails *details.Details   `json:"upgrade_details,omitempty"`
+	PeerHealth     *PeerHealth        `json:"peer_health,omitempty"`
+	// PolicyHash is the hash of the current policy, Fleet sends the changes of the policy as a PolicyDelta based
+	// on it. It is empty when the agent has no policy or failed to apply a delta, Fleet sends the full policy.
+	PolicyHash string `json:"policy_hash,omitempty"`
+}
+
+// PeerHealth provides information about the reachability of the agents of the same site during checkin.
+type PeerHealth struct {
+	Site        string   `json:"site,omitempty"`
+	Peers       int      `json:"peers"`
+	Reachable   int      `json:"reachable"`
+	Unreachable []string `json:"unreachable,omitempty"` // IDs or addresses of the unreachable peers
+	Unhealthy   []string `json:"unhealthy,omitempty"`   // IDs of the reachable peers not reporting healthy
+	Message     string   `json:"message,omitempty"`
 }
 
 // SerializableEvent is a representation of the event to be send to the Fleet Server API via the checkin
@@ -84,8 +98,30 @@ func (e *CheckinResponse) Validate() error {
 
 // CheckinCmd is a fleet API command.
 type CheckinCmd struct {
-	client client.Sender
-	info   AgentInfo
+	client             client.Sender
+	info               AgentInfo
+	compression        string
+	maxBodySize        int
+	maxUnitMessageSize int
+}
+
+// CheckinOption configures the CheckinCmd.
+type CheckinOption func(*CheckinCmd)
+
+// WithCompression compresses the body of the request with gzip or zstd, any other value sends it uncompressed.
+func WithCompression(compression string) CheckinOption {
+	return func(c *CheckinCmd) {
+		c.compression = compression
+	}
+}
+
+// WithPayloadBudget truncates the status messages of the components and units to maxUnitMessageSize bytes, and
+// further until the body fits in maxBodySize bytes. A zero value disables the limit.
+func WithPayloadBudget(maxBodySize, maxUnitMessageSize int) CheckinOption {
+	return func(c *CheckinCmd) {
+		c.maxBodySize = maxBodySize
+		c.maxUnitMessageSize = maxUnitMessageSize
+	}
 }
 
 type AgentInfo interface {
@@ -93,11 +129,15 @@ type AgentInfo interface {
 }
 
 // NewCheckinCmd creates a new api command.
-func NewCheckinCmd(info AgentInfo, client client.Sender) *CheckinCmd {
-	return &CheckinCmd{
+func NewCheckinCmd(info AgentInfo, client client.Sender, opts ...CheckinOption) *CheckinCmd {
+	c := &CheckinCmd{
 		client: client,
 		info:   info,
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Execute enroll the Agent in the Fleet Server. Returns the decoded check in response, a duration indicating
@@ -107,16 +147,26 @@ func (e *CheckinCmd) Execute(ctx context.Context, r *CheckinRequest) (*CheckinRe
 		return nil, 0, err
 	}
 
-	b, err := json.Marshal(r)
+	b, err := r.marshalWithBudget(e.maxBodySize, e.maxUnitMessageSize)
 	if err != nil {
 		return nil, 0, errors.New(err,
 			"fail to encode the checkin request",
 			errors.TypeUnexpected)
 	}
 
+	var headers http.Header
+	if encoding, compressed, err := compress(e.compression, b); err != nil {
+		return nil, 0, errors.New(err,
+			"fail to compress the checkin request",
+			errors.TypeUnexpected)
+	} else if encoding != "" {
+		headers = http.Header{"Content-Encoding": []string{encoding}}
+		b = compressed
+	}
+
 	cp := fmt.Sprintf(checkingPath, e.info.AgentID())
 	sendStart := time.Now()
-	resp, err := e.client.Send(ctx, "POST", cp, nil, nil, bytes.NewBuffer(b))
+	resp, err := e.client.Send(ctx, "POST", cp, nil, headers, bytes.NewBuffer(b))
 	sendDuration := time.Since(sendStart)
 	if err != nil {
 		return nil, sendDuration, errors.New(err,
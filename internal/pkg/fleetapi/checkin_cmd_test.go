@@ -5,6 +5,7 @@
 package fleetapi
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -271,6 +273,52 @@ func TestCheckin(t *testing.T) {
 		},
 	))
 
+	for _, compression := range []string{"gzip", "zstd"} {
+		t.Run("Body is compressed with "+compression, withServerWithAuthClient(
+			func(t *testing.T) *http.ServeMux {
+				raw := `{"actions": []}`
+				mux := http.NewServeMux()
+				path := fmt.Sprintf("/api/fleet/agents/%s/checkin", agentInfo.AgentID())
+				mux.HandleFunc(path, authHandler(func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, compression, r.Header.Get("Content-Encoding"))
+
+					var body io.Reader
+					switch compression {
+					case "gzip":
+						gr, err := gzip.NewReader(r.Body)
+						require.NoError(t, err)
+						body = gr
+					case "zstd":
+						zr, err := zstd.NewReader(r.Body)
+						require.NoError(t, err)
+						defer zr.Close()
+						body = zr
+					}
+
+					var req CheckinRequest
+					content, err := io.ReadAll(body)
+					assert.NoError(t, err)
+					assert.NoError(t, json.Unmarshal(content, &req))
+					assert.Equal(t, "online", req.Status)
+
+					w.WriteHeader(http.StatusOK)
+					fmt.Fprint(w, raw)
+				}, withAPIKey))
+				return mux
+			}, withAPIKey,
+			func(t *testing.T, client client.Sender) {
+				cmd := NewCheckinCmd(agentInfo, client, WithCompression(compression))
+
+				request := CheckinRequest{Status: "online"}
+
+				r, _, err := cmd.Execute(ctx, &request)
+				require.NoError(t, err)
+
+				require.Equal(t, 0, len(r.Actions))
+			},
+		))
+	}
+
 	t.Run("Headers are sent", withServerWithAuthClient(
 		func(t *testing.T) *http.ServeMux {
 			raw := `{"actions": []}`
@@ -93,6 +93,7 @@ func (p EnrollType) MarshalJSON() ([]byte, error) {
 //		  "type": "PERMANENT",
 //		  "id": "custom-id", // optional
 //		  "replace_token": "replacetokenvalue", // optional
+//		  "csr": "-----BEGIN CERTIFICATE REQUEST-----...", // optional
 //		  "metadata": {
 //		    "local": { "os": "macos"},
 //		    "user_provided": { "region": "us-east"}
@@ -103,7 +104,10 @@ type EnrollRequest struct {
 	Type         EnrollType `json:"type"`
 	ID           string     `json:"id"`
 	ReplaceToken string     `json:"replace_token"`
-	Metadata     Metadata   `json:"metadata"`
+	// CSR is the PEM encoded certificate signing request of the client certificate of the agent, Fleet
+	// issues the certificate used for the mutual TLS with Fleet Server.
+	CSR      string   `json:"csr,omitempty"`
+	Metadata Metadata `json:"metadata"`
 }
 
 // Metadata is a all the metadata send or received from the elastic-agent.
@@ -163,6 +167,8 @@ type EnrollItemResponse struct {
 	Actions              []interface{}          `json:"actions"`
 	AccessAPIKey         string                 `json:"access_api_key"`
 	Tags                 []string               `json:"tags"`
+	// ClientCertificate is the PEM encoded client certificate issued for the CSR of the request.
+	ClientCertificate string `json:"client_certificate,omitempty"`
 }
 
 // Validate validates the response send from the server.
@@ -0,0 +1,141 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	goerrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+// IdentityTokenRequest is the identity of the cloud instance exchanged by the elastic-agent for an
+// enrollment token, the instance proves its identity with the document signed by the cloud provider.
+//
+// Example:
+// POST /api/fleet/agents/identity_token
+//
+//	{
+//	  "provider": "aws",
+//	  "document": "{\"instanceId\": \"i-1234567890abcdef0\", ...}",
+//	  "signature": "MIAGCSqGSIb3DQEHAqCAMIACAQExDzANBglghkgBZQMEAgEFADCABgkqhkiG9w0BBwGggCSABIIB3nsKIC..."
+//	}
+type IdentityTokenRequest struct {
+	Provider  string `json:"provider"`
+	Document  string `json:"document"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// Validate validates the identity token request before sending it to the API.
+func (e *IdentityTokenRequest) Validate() error {
+	var errs []error
+
+	if len(e.Provider) == 0 {
+		errs = append(errs, errors.New("missing identity provider"))
+	}
+
+	if len(e.Document) == 0 {
+		errs = append(errs, errors.New("missing identity document"))
+	}
+
+	return goerrors.Join(errs...)
+}
+
+// IdentityTokenResponse is the enrollment token received in exchange of the identity of the instance.
+//
+// Example:
+//
+//	{
+//	  "enrollment_token": "ENROLLMENT_API_KEY"
+//	}
+type IdentityTokenResponse struct {
+	EnrollmentToken string `json:"enrollment_token"`
+}
+
+// Validate validates the response send from the server.
+func (e *IdentityTokenResponse) Validate() error {
+	if len(e.EnrollmentToken) == 0 {
+		return errors.New("enrollment token is missing")
+	}
+	return nil
+}
+
+// IdentityTokenCmd is the command exchanging the identity of a cloud instance for an enrollment token.
+type IdentityTokenCmd struct {
+	client client.Sender
+}
+
+// Execute exchanges the identity of the instance for an enrollment token.
+func (e *IdentityTokenCmd) Execute(ctx context.Context, r *IdentityTokenRequest) (*IdentityTokenResponse, error) {
+	const p = "/api/fleet/agents/identity_token"
+
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.New(err, "fail to encode the identity token request")
+	}
+
+	resp, err := e.client.Send(ctx, "POST", p, nil, nil, bytes.NewBuffer(b))
+	if err != nil {
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return nil, ErrConnRefused
+		}
+
+		var et *url.Error
+		if errors.As(err, &et) {
+			return nil, et.Err
+		}
+
+		var netOp *net.OpError
+		if errors.As(err, &netOp) {
+			return nil, ErrConnRefused
+		}
+
+		return nil, errors.New(err,
+			"fail to execute request to fleet-server",
+			errors.TypeNetwork)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrTooManyRequests
+	}
+
+	if status, temporary := temporaryServerErrorCodes[resp.StatusCode]; temporary {
+		return nil, fmt.Errorf("received status code %d (%s): %w", resp.StatusCode, status, ErrTemporaryServerError)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, client.ExtractError(resp.Body)
+	}
+
+	tokenResponse := &IdentityTokenResponse{}
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(tokenResponse); err != nil {
+		return nil, errors.New(err, "fail to decode identity token response")
+	}
+
+	if err := tokenResponse.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tokenResponse, nil
+}
+
+// NewIdentityTokenCmd creates a new IdentityTokenCmd.
+func NewIdentityTokenCmd(client client.Sender) *IdentityTokenCmd {
+	return &IdentityTokenCmd{client: client}
+}
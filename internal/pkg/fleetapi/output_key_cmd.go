@@ -0,0 +1,175 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/errors"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+const (
+	outputKeysPath      = "/api/fleet/agents/%s/output_keys"
+	retireOutputKeyPath = "/api/fleet/agents/%s/output_keys/%s"
+)
+
+// OutputKeyRequest requests a new API key for an output of the policy, the current key of the output
+// stays valid until it is retired.
+//
+// Example:
+// POST /api/fleet/agents/{agent_id}/output_keys
+//
+//	{
+//	  "output_id": "default"
+//	}
+type OutputKeyRequest struct {
+	OutputID string `json:"output_id"`
+}
+
+// Validate validates the output key request before sending it to the API.
+func (e *OutputKeyRequest) Validate() error {
+	if len(e.OutputID) == 0 {
+		return errors.New("missing output id")
+	}
+	return nil
+}
+
+// OutputKeyResponse is the new API key of the output.
+//
+// Example:
+//
+//	{
+//	  "id": "VuaCfGcBCdbkQm-e5aOx",
+//	  "api_key": "VuaCfGcBCdbkQm-e5aOx:ui2lp2axTNmsyakw9tvNnw",
+//	  "expiration": "2026-11-17T00:00:00Z"
+//	}
+type OutputKeyResponse struct {
+	ID         string    `json:"id"`
+	APIKey     string    `json:"api_key"`
+	Expiration time.Time `json:"expiration,omitempty"`
+}
+
+// Validate validates the response send from the server.
+func (e *OutputKeyResponse) Validate() error {
+	if len(e.ID) == 0 {
+		return errors.New("output key id is missing")
+	}
+	if len(e.APIKey) == 0 {
+		return errors.New("output api key is missing")
+	}
+	return nil
+}
+
+// OutputKeyCmd is the command requesting a new API key for an output of the policy.
+type OutputKeyCmd struct {
+	client client.Sender
+	info   AgentInfo
+}
+
+// NewOutputKeyCmd creates a new OutputKeyCmd.
+func NewOutputKeyCmd(info AgentInfo, client client.Sender) *OutputKeyCmd {
+	return &OutputKeyCmd{
+		client: client,
+		info:   info,
+	}
+}
+
+// Execute requests a new API key for the output.
+func (e *OutputKeyCmd) Execute(ctx context.Context, r *OutputKeyRequest) (*OutputKeyResponse, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, errors.New(err, "fail to encode the output key request")
+	}
+
+	p := fmt.Sprintf(outputKeysPath, e.info.AgentID())
+	resp, err := e.client.Send(ctx, "POST", p, nil, nil, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, errors.New(err,
+			"fail to request an output key from fleet",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, p))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, ErrTooManyRequests
+	}
+
+	if status, temporary := temporaryServerErrorCodes[resp.StatusCode]; temporary {
+		return nil, fmt.Errorf("received status code %d (%s): %w", resp.StatusCode, status, ErrTemporaryServerError)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, client.ExtractError(resp.Body)
+	}
+
+	keyResponse := &OutputKeyResponse{}
+	decoder := json.NewDecoder(resp.Body)
+	if err := decoder.Decode(keyResponse); err != nil {
+		return nil, errors.New(err, "fail to decode output key response")
+	}
+
+	if err := keyResponse.Validate(); err != nil {
+		return nil, err
+	}
+
+	return keyResponse, nil
+}
+
+// RetireOutputKeyCmd is the command retiring an API key of an output once it is replaced.
+//
+// Example:
+// DELETE /api/fleet/agents/{agent_id}/output_keys/{key_id}
+type RetireOutputKeyCmd struct {
+	client client.Sender
+	info   AgentInfo
+}
+
+// NewRetireOutputKeyCmd creates a new RetireOutputKeyCmd.
+func NewRetireOutputKeyCmd(info AgentInfo, client client.Sender) *RetireOutputKeyCmd {
+	return &RetireOutputKeyCmd{
+		client: client,
+		info:   info,
+	}
+}
+
+// Execute retires the API key, a key that is already retired is not an error.
+func (e *RetireOutputKeyCmd) Execute(ctx context.Context, keyID string) error {
+	if len(keyID) == 0 {
+		return errors.New("missing output key id")
+	}
+
+	p := fmt.Sprintf(retireOutputKeyPath, e.info.AgentID(), url.PathEscape(keyID))
+	resp, err := e.client.Send(ctx, "DELETE", p, nil, nil, nil)
+	if err != nil {
+		return errors.New(err,
+			"fail to retire the output key in fleet",
+			errors.TypeNetwork,
+			errors.M(errors.MetaKeyURI, p))
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	case http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	}
+	if status, temporary := temporaryServerErrorCodes[resp.StatusCode]; temporary {
+		return fmt.Errorf("received status code %d (%s): %w", resp.StatusCode, status, ErrTemporaryServerError)
+	}
+	return client.ExtractError(resp.Body)
+}
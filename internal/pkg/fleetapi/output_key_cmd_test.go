@@ -0,0 +1,81 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi/client"
+)
+
+func TestOutputKeyCmd(t *testing.T) {
+	const withAPIKey = "secret"
+	agentInfo := &agentinfo{}
+	expiration := time.Date(2026, 11, 17, 0, 0, 0, 0, time.UTC)
+
+	t.Run("request a new key", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/id/output_keys", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				req := &OutputKeyRequest{}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(req))
+				assert.Equal(t, "default", req.OutputID)
+				w.WriteHeader(http.StatusOK)
+				_ = json.NewEncoder(w).Encode(OutputKeyResponse{ID: "new-id", APIKey: "new-id:new-key", Expiration: expiration})
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			resp, err := NewOutputKeyCmd(agentInfo, client).Execute(context.Background(), &OutputKeyRequest{OutputID: "default"})
+			require.NoError(t, err)
+			assert.Equal(t, "new-id", resp.ID)
+			assert.Equal(t, "new-id:new-key", resp.APIKey)
+			assert.Equal(t, expiration, resp.Expiration)
+		},
+	))
+
+	t.Run("invalid response", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/id/output_keys", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, _ = w.Write([]byte(`{"id": "new-id"}`))
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			_, err := NewOutputKeyCmd(agentInfo, client).Execute(context.Background(), &OutputKeyRequest{OutputID: "default"})
+			assert.Error(t, err)
+		},
+	))
+
+	t.Run("retire a key", withServerWithAuthClient(
+		func(t *testing.T) *http.ServeMux {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/fleet/agents/id/output_keys/old-id", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodDelete, r.Method)
+				w.WriteHeader(http.StatusNoContent)
+			}, withAPIKey))
+			mux.HandleFunc("/api/fleet/agents/id/output_keys/retired-id", authHandler(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}, withAPIKey))
+			return mux
+		}, withAPIKey,
+		func(t *testing.T, client client.Sender) {
+			cmd := NewRetireOutputKeyCmd(agentInfo, client)
+			require.NoError(t, cmd.Execute(context.Background(), "old-id"))
+			require.NoError(t, cmd.Execute(context.Background(), "retired-id"), "a retired key is not an error")
+			assert.Error(t, cmd.Execute(context.Background(), ""))
+		},
+	))
+}
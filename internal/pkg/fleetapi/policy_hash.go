@@ -0,0 +1,33 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// policyHashVersion prefixes the policy hashes exchanged with Fleet, a change of the canonical form of the
+// policy requires a new version.
+const policyHashVersion = "v1"
+
+// PolicyHash returns the hash of the policy exchanged with Fleet in the policy deltas and on checkin. It is the
+// version followed by the hex encoded SHA-256 of the canonical JSON of the policy, e.g. "v1:9f86d0...". The
+// canonical JSON has the keys of the objects sorted, no insignificant whitespace and the HTML characters not
+// escaped.
+func PolicyHash(policy map[string]interface{}) (string, error) {
+	var canonical bytes.Buffer
+	enc := json.NewEncoder(&canonical)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(policy); err != nil {
+		return "", fmt.Errorf("failed to encode the policy: %w", err)
+	}
+	// Encode terminates the value with a newline
+	sum := sha256.Sum256(bytes.TrimSuffix(canonical.Bytes(), []byte("\n")))
+	return policyHashVersion + ":" + hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,36 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package fleetapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyHash(t *testing.T) {
+	policy := map[string]interface{}{
+		"revision": 2,
+		"outputs": map[string]interface{}{
+			"default": map[string]interface{}{"type": "elasticsearch", "hosts": []interface{}{"https://localhost:9200"}},
+		},
+		"inputs": []interface{}{map[string]interface{}{"id": "logs", "condition": "${host.name} == 'a&b'"}},
+	}
+	canonical := `{"inputs":[{"condition":"${host.name} == 'a&b'","id":"logs"}],"outputs":{"default":{"hosts":["https://localhost:9200"],"type":"elasticsearch"}},"revision":2}`
+	sum := sha256.Sum256([]byte(canonical))
+
+	hash, err := PolicyHash(policy)
+	require.NoError(t, err)
+	assert.Equal(t, "v1:"+hex.EncodeToString(sum[:]), hash)
+
+	// the numbers decoded from the JSON of Fleet hash the same
+	policy["revision"] = float64(2)
+	decoded, err := PolicyHash(policy)
+	require.NoError(t, err)
+	assert.Equal(t, hash, decoded)
+}
@@ -18,6 +18,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
 
@@ -92,7 +93,7 @@ func (m *OTelManager) PerformDiagnostics(ctx context.Context, req ...runtime.Com
 // PerformComponentDiagnostics executes the diagnostic action for the provided components. If no components are provided,
 // then it performs the diagnostics for all current components.
 func (m *OTelManager) PerformComponentDiagnostics(
-	ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component,
+	ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, _ time.Duration, req ...component.Component,
 ) ([]runtime.ComponentDiagnostic, error) {
 	var diagnostics []runtime.ComponentDiagnostic
 	m.mx.RLock()
@@ -61,7 +61,7 @@ func TestPerformComponentDiagnostics(t *testing.T) {
 		},
 	}
 
-	diags, err := m.PerformComponentDiagnostics(t.Context(), nil)
+	diags, err := m.PerformComponentDiagnostics(t.Context(), nil, 0)
 	require.NoError(t, err)
 	for i, d := range diags {
 		assert.Equal(t, expectedDiags[i].Component.ID, d.Component.ID)
@@ -171,7 +171,7 @@ func TestBeatMetrics(t *testing.T) {
 		assert.NoError(t, cErr)
 	})
 
-	diags, err := m.PerformComponentDiagnostics(t.Context(), nil)
+	diags, err := m.PerformComponentDiagnostics(t.Context(), nil, 0)
 	require.NoError(t, err)
 	assert.Len(t, obs.All(), 0)
 	require.Len(t, diags, 1)
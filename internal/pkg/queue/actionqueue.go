@@ -8,6 +8,7 @@ import (
 	"container/heap"
 	"time"
 
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 )
 
@@ -97,6 +98,7 @@ func NewActionQueue(actions []fleetapi.ScheduledAction, s saver) (*ActionQueue,
 	if err != nil {
 		return nil, err
 	}
+	agentmetrics.SetActionQueueSize(q.Len())
 	return &ActionQueue{
 		q: q,
 		s: s,
@@ -112,6 +114,7 @@ func (q *ActionQueue) Add(action fleetapi.ScheduledAction, priority int64) {
 		priority: priority,
 	}
 	heap.Push(q.q, e)
+	agentmetrics.SetActionQueueSize(q.q.Len())
 }
 
 // DequeueActions will dequeue all actions that have a priority less then time.Now().
@@ -126,6 +129,7 @@ func (q *ActionQueue) DequeueActions() []fleetapi.ScheduledAction {
 		item := heap.Pop(q.q).(*item)
 		actions = append(actions, item.action)
 	}
+	agentmetrics.SetActionQueueSize(q.q.Len())
 	return actions
 }
 
@@ -141,6 +145,7 @@ func (q *ActionQueue) Cancel(actionID string) int {
 	for _, item := range items {
 		heap.Remove(q.q, item.index)
 	}
+	agentmetrics.SetActionQueueSize(q.q.Len())
 	return len(items)
 }
 
@@ -164,6 +169,7 @@ func (q *ActionQueue) CancelType(actionType string) int {
 	for _, item := range items {
 		heap.Remove(q.q, item.index)
 	}
+	agentmetrics.SetActionQueueSize(q.q.Len())
 	return len(items)
 }
 
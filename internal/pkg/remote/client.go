@@ -21,12 +21,17 @@ import (
 	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/id"
+	"github.com/elastic/elastic-agent/internal/pkg/remote/proxypath"
+	"github.com/elastic/elastic-agent/internal/pkg/remote/revocation"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
 const (
+	// retryOnBadConnTimeout is the longest backoff of a failing host.
 	retryOnBadConnTimeout = 5 * time.Minute
-	requestIDHeaderName   = "X-Request-ID"
+	// retryOnBadConnInit is the backoff of a host after its first failure, doubled on each consecutive failure.
+	retryOnBadConnInit  = 10 * time.Second
+	requestIDHeaderName = "X-Request-ID"
 )
 
 type wrapperFunc func(rt http.RoundTripper) (http.RoundTripper, error)
@@ -37,16 +42,46 @@ type requestClient struct {
 	lastUsed   time.Time
 	lastErr    error
 	lastErrOcc time.Time
+	// failures is the number of consecutive failures of the host.
+	failures int
+	// retryAfter is the end of the backoff of the failing host, it is only used again before when all the other
+	// hosts failed.
+	retryAfter time.Time
 }
 
+// SetLastError records the result of a request to the host, a failure starts a jittered exponential backoff.
 func (r *requestClient) SetLastError(err error) {
 	r.lastUsed = time.Now().UTC()
 	r.lastErr = err
-	if err != nil {
-		r.lastErrOcc = r.lastUsed
-	} else {
+	if err == nil {
 		r.lastErrOcc = time.Time{}
+		r.failures = 0
+		r.retryAfter = time.Time{}
+		return
+	}
+	r.lastErrOcc = r.lastUsed
+	r.failures++
+	backoff := retryOnBadConnTimeout
+	if r.failures < 6 {
+		backoff = min(retryOnBadConnInit<<(r.failures-1), retryOnBadConnTimeout)
 	}
+	// a random half of the backoff, so all the agents don't retry the host at the same time
+	backoff = backoff/2 + rand.N(backoff/2)
+	r.retryAfter = r.lastUsed.Add(backoff)
+}
+
+// HostState is the health of a host of the client.
+type HostState struct {
+	Host string `yaml:"host"`
+	// Healthy is true when the last request to the host succeeded or it was not used yet.
+	Healthy bool `yaml:"healthy"`
+	// Current is true for the host the requests are sent to first, the last host a request succeeded with.
+	Current  bool      `yaml:"current"`
+	Failures int       `yaml:"failures,omitempty"`
+	Error    string    `yaml:"error,omitempty"`
+	LastUsed time.Time `yaml:"last_used,omitempty"`
+	// RetryAfter is the end of the backoff of a failing host.
+	RetryAfter time.Time `yaml:"retry_after,omitempty"`
 }
 
 // Client wraps a http.Client and takes care of making the raw calls, the client should
@@ -57,7 +92,9 @@ type Client struct {
 	log        *logger.Logger
 	clientLock sync.Mutex
 	clients    []*requestClient
-	config     Config
+	// current is the last host a request succeeded with, the requests stick to it while it succeeds.
+	current *requestClient
+	config  Config
 }
 
 // NewConfigFromURL returns a Config based on a received host.
@@ -112,6 +149,30 @@ func NewWithConfig(log *logger.Logger, cfg Config, wrapper wrapperFunc) (*Client
 	hostCount := len(hosts)
 	log.With("hosts", hosts).Debugf(
 		"creating remote client with %d hosts", hostCount)
+	transportOpts := []httpcommon.TransportOption{
+		httpcommon.WithAPMHTTPInstrumentation(),
+		httpcommon.WithForceAttemptHTTP2(true),
+	}
+	var failover *proxypath.Failover
+	if cfg.ProxyPaths.Enabled() && !cfg.Transport.Proxy.Disable {
+		var err error
+		failover, err = proxypath.New(log, "fleet", (*url.URL)(cfg.Transport.Proxy.URL), cfg.ProxyPaths)
+		if err != nil {
+			return nil, err
+		}
+		transportOpts = append(transportOpts, httpcommon.WithTransportFunc(func(t *http.Transport) {
+			t.Proxy = failover.Proxy
+		}))
+	}
+
+	if cfg.Revocation.Enabled() {
+		revocationOpt, err := revocation.New(log.Named("revocation"), cfg.Revocation).TransportOption(&cfg.Transport)
+		if err != nil {
+			return nil, err
+		}
+		transportOpts = append(transportOpts, revocationOpt)
+	}
+
 	clients := make([]*requestClient, hostCount)
 	for i, host := range hosts {
 		baseURL, err := urlutil.MakeURL(string(cfg.Protocol), p, host, 0)
@@ -119,14 +180,15 @@ func NewWithConfig(log *logger.Logger, cfg Config, wrapper wrapperFunc) (*Client
 			return nil, fmt.Errorf("invalid fleet-server endpoint: %w", err)
 		}
 
-		transport, err := cfg.Transport.RoundTripper(
-			httpcommon.WithAPMHTTPInstrumentation(),
-			httpcommon.WithForceAttemptHTTP2(true),
-		)
+		transport, err := cfg.Transport.RoundTripper(transportOpts...)
 		if err != nil {
 			return nil, err
 		}
 
+		if failover != nil {
+			transport = failover.RoundTripper(transport)
+		}
+
 		if cfg.Headers != nil {
 			transport = &headersRoundTripper{rt: transport, headers: cfg.Headers}
 		}
@@ -213,6 +275,12 @@ func (c *Client) Send(
 		// Using the same lock that was used for sorting above
 		c.clientLock.Lock()
 		requester.SetLastError(err)
+		if err == nil && c.current != requester {
+			if c.current != nil {
+				c.log.Infof("Failing over from host %s to host %s", c.current.host, requester.host)
+			}
+			c.current = requester
+		}
 		c.clientLock.Unlock()
 
 		if err != nil {
@@ -284,63 +352,72 @@ func newClient(
 	return c, nil
 }
 
-// sortClients sort the clients according to the following priority:
-//   - never used
-//   - without errors, last used first when more than one does not have errors
-//   - last errored.
-//
-// It also removes the last error after retryOnBadConnTimeout has elapsed.
+// sortClients returns the clients in the order the hosts are tried:
+//   - the current host, the last one a request succeeded with, while it does not fail
+//   - the hosts without errors, in their shuffled order
+//   - the failing hosts out of their backoff, the one that failed first first
+//   - the failing hosts in their backoff, the one with the earliest end of backoff first.
 func (c *Client) sortClients() []*requestClient {
 	c.clientLock.Lock()
 	defer c.clientLock.Unlock()
 
 	now := time.Now().UTC()
-
-	sort.Slice(c.clients, func(i, j int) bool {
-		// First, set them good if the timout has elapsed
-		if c.clients[i].lastErr != nil &&
-			now.Sub(c.clients[i].lastErrOcc) > retryOnBadConnTimeout {
-			c.clients[i].lastErr = nil
-			c.clients[i].lastErrOcc = time.Time{}
-		}
-		if c.clients[j].lastErr != nil &&
-			now.Sub(c.clients[j].lastErrOcc) > retryOnBadConnTimeout {
-			c.clients[j].lastErr = nil
-			c.clients[j].lastErrOcc = time.Time{}
+	rank := func(r *requestClient) int {
+		switch {
+		case r.lastErr == nil && r == c.current:
+			return 0
+		case r.lastErr == nil:
+			return 1
+		case !now.Before(r.retryAfter):
+			return 2
+		default:
+			return 3
 		}
+	}
 
-		// Pick not yet used first, but if both haven't been used yet,
-		// we return false to comply with the sort.Interface definition.
-		if c.clients[i].lastUsed.IsZero() &&
-			c.clients[j].lastUsed.IsZero() {
+	// return a copy of the slice so we can iterate over it without the lock
+	res := make([]*requestClient, len(c.clients))
+	copy(res, c.clients)
+	sort.SliceStable(res, func(i, j int) bool {
+		ri, rj := rank(res[i]), rank(res[j])
+		switch {
+		case ri != rj:
+			return ri < rj
+		case ri == 2:
+			return res[i].lastErrOcc.Before(res[j].lastErrOcc)
+		case ri == 3:
+			return res[i].retryAfter.Before(res[j].retryAfter)
+		default:
 			return false
 		}
+	})
+	return res
+}
 
-		// Pick not yet used first
-		if c.clients[i].lastUsed.IsZero() {
-			return true
-		}
+// HostStates returns the health of the hosts of the client, sorted by host.
+func (c *Client) HostStates() []HostState {
+	c.clientLock.Lock()
+	defer c.clientLock.Unlock()
 
-		// If none has errors, pick the last used
-		// Then, the one without errors
-		if c.clients[i].lastErr == nil &&
-			c.clients[j].lastErr == nil {
-			return c.clients[i].lastUsed.Before(c.clients[j].lastUsed)
+	states := make([]HostState, 0, len(c.clients))
+	for _, r := range c.clients {
+		s := HostState{
+			Host:       r.host,
+			Healthy:    r.lastErr == nil,
+			Current:    r == c.current && r.lastErr == nil,
+			Failures:   r.failures,
+			LastUsed:   r.lastUsed,
+			RetryAfter: r.retryAfter,
 		}
-
-		// Then, the one without error
-		if c.clients[i].lastErr == nil {
-			return true
+		if r.lastErr != nil {
+			s.Error = r.lastErr.Error()
 		}
-
-		// Lastly, the one that errored last
-		return c.clients[i].lastUsed.Before(c.clients[j].lastUsed)
+		states = append(states, s)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Host < states[j].Host
 	})
-
-	// return a copy of the slice so we can iterate over it without the lock
-	res := make([]*requestClient, len(c.clients))
-	copy(res, c.clients)
-	return res
+	return states
 }
 
 func (r requestClient) newRequest(method string, path string, params url.Values, body io.Reader) (*http.Request, error) {
@@ -375,8 +375,7 @@ func TestSortClients(t *testing.T) {
 	t.Run("Picks first requester on initial call", func(t *testing.T) {
 		one := &requestClient{}
 		two := &requestClient{}
-		client, err := newClient(nil, Config{}, one, two)
-		require.NoError(t, err)
+		client := &Client{clients: []*requestClient{one, two}}
 
 		clients := client.sortClients()
 
@@ -384,11 +383,8 @@ func TestSortClients(t *testing.T) {
 	})
 
 	t.Run("Picks second requester when first has error", func(t *testing.T) {
-		one := &requestClient{
-			lastUsed:   time.Now().UTC(),
-			lastErr:    fmt.Errorf("fake error"),
-			lastErrOcc: time.Now().UTC(),
-		}
+		one := &requestClient{}
+		one.SetLastError(fmt.Errorf("fake error"))
 		two := &requestClient{}
 		client, err := newClient(nil, Config{}, one, two)
 		require.NoError(t, err)
@@ -398,81 +394,116 @@ func TestSortClients(t *testing.T) {
 		assert.Equal(t, two, clients[0])
 	})
 
-	t.Run("Picks second requester when first has been used", func(t *testing.T) {
-		one := &requestClient{
-			lastUsed: time.Now().UTC(),
-		}
+	t.Run("Sticks to the last good requester", func(t *testing.T) {
+		one := &requestClient{}
 		two := &requestClient{}
-		client, err := newClient(nil, Config{}, one, two)
-		require.NoError(t, err)
+		three := &requestClient{}
+		two.SetLastError(nil)
+		client := &Client{clients: []*requestClient{one, two, three}, current: two}
 
 		clients := client.sortClients()
 
-		assert.Equal(t, two, clients[0])
+		assert.Equal(t, []*requestClient{two, one, three}, clients)
 	})
 
-	t.Run("Picks second requester when it's the oldest", func(t *testing.T) {
-		one := &requestClient{
-			lastUsed: time.Now().UTC().Add(-time.Minute),
-		}
-		two := &requestClient{
-			lastUsed: time.Now().UTC().Add(-3 * time.Minute),
-		}
-		three := &requestClient{
-			lastUsed: time.Now().UTC().Add(-2 * time.Minute),
-		}
-		client, err := newClient(nil, Config{}, one, two, three)
-		require.NoError(t, err)
+	t.Run("Fails over when the last good requester has error", func(t *testing.T) {
+		one := &requestClient{}
+		two := &requestClient{}
+		three := &requestClient{}
+		two.SetLastError(fmt.Errorf("fake error"))
+		client := &Client{clients: []*requestClient{one, two, three}, current: two}
 
 		clients := client.sortClients()
 
-		assert.Equal(t, two, clients[0])
+		assert.Equal(t, []*requestClient{one, three, two}, clients)
 	})
 
-	t.Run("Picks third requester when second has error and first is last used", func(t *testing.T) {
+	t.Run("Picks requesters in backoff last", func(t *testing.T) {
+		now := time.Now().UTC()
 		one := &requestClient{
-			lastUsed: time.Now().UTC().Add(-time.Minute),
+			lastErr:    fmt.Errorf("fake error"),
+			lastErrOcc: now.Add(-time.Minute),
+			retryAfter: now.Add(2 * time.Minute),
 		}
 		two := &requestClient{
-			lastUsed:   time.Now().UTC().Add(-3 * time.Minute),
 			lastErr:    fmt.Errorf("fake error"),
-			lastErrOcc: time.Now().Add(-time.Minute),
+			lastErrOcc: now.Add(-2 * time.Minute),
+			retryAfter: now.Add(time.Minute),
 		}
 		three := &requestClient{
-			lastUsed: time.Now().UTC().Add(-2 * time.Minute),
-		}
-		client := &Client{clients: []*requestClient{one, two, three}}
-
-		clients := client.sortClients()
-
-		assert.Equal(t, three, clients[0])
-	})
-
-	t.Run("Picks second requester when its oldest and all have old errors", func(t *testing.T) {
-		one := &requestClient{
-			lastUsed:   time.Now().UTC().Add(-time.Minute),
 			lastErr:    fmt.Errorf("fake error"),
-			lastErrOcc: time.Now().Add(-time.Minute),
+			lastErrOcc: now.Add(-3 * time.Minute),
+			retryAfter: now.Add(-time.Minute),
 		}
-		two := &requestClient{
-			lastUsed:   time.Now().UTC().Add(-3 * time.Minute),
+		four := &requestClient{
 			lastErr:    fmt.Errorf("fake error"),
-			lastErrOcc: time.Now().Add(-3 * time.Minute),
+			lastErrOcc: now.Add(-4 * time.Minute),
+			retryAfter: now.Add(-2 * time.Minute),
 		}
-		three := &requestClient{
-			lastUsed:   time.Now().UTC().Add(-2 * time.Minute),
-			lastErr:    fmt.Errorf("fake error"),
-			lastErrOcc: time.Now().Add(-2 * time.Minute),
-		}
-		client, err := newClient(nil, Config{}, one, two, three)
-		require.NoError(t, err)
+		client := &Client{clients: []*requestClient{one, two, three, four}}
 
 		clients := client.sortClients()
 
-		assert.Equal(t, two, clients[0])
+		assert.Equal(t, []*requestClient{four, three, two, one}, clients)
 	})
 }
 
+func TestRequestClientBackoff(t *testing.T) {
+	r := &requestClient{}
+	var previous time.Duration
+	for i := 1; i <= 8; i++ {
+		r.SetLastError(fmt.Errorf("fake error"))
+		backoff := r.retryAfter.Sub(r.lastUsed)
+		expected := min(retryOnBadConnInit<<(i-1), retryOnBadConnTimeout)
+		assert.Equal(t, i, r.failures)
+		assert.GreaterOrEqual(t, backoff, expected/2, "failure %d", i)
+		assert.Less(t, backoff, expected, "failure %d", i)
+		assert.GreaterOrEqual(t, backoff, previous/2, "the backoff should grow with the failures")
+		previous = backoff
+	}
+
+	r.SetLastError(nil)
+	assert.Zero(t, r.failures)
+	assert.Zero(t, r.retryAfter)
+}
+
+func TestHostStates(t *testing.T) {
+	l, err := logger.New("", false)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	failing := &requestClient{host: "http://must.fail-1.co/"}
+	good := &requestClient{host: fmt.Sprintf("http://%s/", s.Listener.Addr())}
+	c := &Client{clients: []*requestClient{failing, good}, log: l}
+
+	resp, err := c.Send(ctx, http.MethodGet, "/", nil, nil, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	states := c.HostStates()
+	require.Len(t, states, 2)
+	assert.Equal(t, good.host, states[0].Host)
+	assert.True(t, states[0].Healthy)
+	assert.True(t, states[0].Current)
+	assert.Equal(t, failing.host, states[1].Host)
+	assert.False(t, states[1].Healthy)
+	assert.False(t, states[1].Current)
+	assert.Equal(t, 1, states[1].Failures)
+	assert.NotEmpty(t, states[1].Error)
+	assert.True(t, states[1].RetryAfter.After(states[1].LastUsed))
+
+	// the next requests stick to the good host without retrying the failing one
+	resp, err = c.Send(ctx, http.MethodGet, "/", nil, nil, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, c.HostStates()[1].Failures)
+}
+
 func withServer(m func(t *testing.T) *http.ServeMux, test func(t *testing.T, host string)) func(t *testing.T) {
 	return func(t *testing.T) {
 		s := httptest.NewServer(m(t))
@@ -9,6 +9,9 @@ import (
 	"time"
 
 	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+
+	"github.com/elastic/elastic-agent/internal/pkg/remote/proxypath"
+	"github.com/elastic/elastic-agent/internal/pkg/remote/revocation"
 )
 
 // Config is the configuration for the client.
@@ -21,6 +24,12 @@ type Config struct {
 	Headers  map[string]string `config:"headers" yaml:"headers,omitempty"`
 
 	Transport httpcommon.HTTPTransportSettings `config:",inline" yaml:",inline"`
+
+	// ProxyPaths are the paths used when the proxy is not reachable.
+	ProxyPaths proxypath.Config `config:",inline" yaml:",inline"`
+
+	// Revocation configures the revocation checks of the certificates of the servers.
+	Revocation revocation.Config `config:"revocation" yaml:"revocation,omitempty"`
 }
 
 // Protocol define the protocol to use to make the connection. (Either HTTPS or HTTP)
@@ -71,8 +80,13 @@ func (c *Config) GetHosts() []string {
 // Validate returns an error if the configuration is invalid; nil, otherwise.
 func (c *Config) Validate() error {
 	if c.Transport.TLS != nil {
-		return c.Transport.TLS.Validate()
+		if err := c.Transport.TLS.Validate(); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	if err := c.ProxyPaths.Validate(); err != nil {
+		return err
+	}
+	return c.Revocation.Validate()
 }
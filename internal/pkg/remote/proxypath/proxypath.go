@@ -0,0 +1,354 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package proxypath selects the network path, a proxy or a direct connection, of the HTTP clients of
+// the Elastic Agent when several are configured. The paths preferred over the one in use are probed and
+// the client fails back to them once they recover. It applies to the connections of the Elastic Agent to
+// Fleet Server and to the download servers, the outputs of the components keep their own proxy settings.
+package proxypath
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// Direct is the failover entry of a direct connection, without proxy.
+	Direct = "direct"
+	// environment is the name of the preferred path when the proxy of the client comes from the environment.
+	environment = "environment"
+
+	defaultProbeInterval = 30 * time.Second
+	probeTimeout         = 5 * time.Second
+)
+
+// Config configures the paths used when the proxy of a client is not reachable.
+type Config struct {
+	// Failover lists the paths used in order when the proxy URL is not reachable, proxy URLs or "direct".
+	Failover []string `config:"proxy_failover" yaml:"proxy_failover,omitempty"`
+
+	// ProbeInterval is how often the paths preferred over the one in use are probed.
+	ProbeInterval time.Duration `config:"proxy_probe_interval" yaml:"proxy_probe_interval,omitempty"`
+}
+
+// Enabled returns true when failover paths are configured.
+func (c *Config) Enabled() bool {
+	return len(c.Failover) > 0
+}
+
+// Validate validates the failover paths.
+func (c *Config) Validate() error {
+	if c.ProbeInterval < 0 {
+		return errors.New("proxy_probe_interval cannot be negative")
+	}
+	_, err := parsePaths(c.Failover)
+	return err
+}
+
+func parsePaths(entries []string) ([]*url.URL, error) {
+	paths := make([]*url.URL, 0, len(entries))
+	for _, entry := range entries {
+		if strings.EqualFold(entry, Direct) {
+			paths = append(paths, nil)
+			continue
+		}
+		u, err := url.Parse(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy_failover entry %q: %w", entry, err)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy_failover entry %q: expected a proxy URL or %q", entry, Direct)
+		}
+		paths = append(paths, u)
+	}
+	return paths, nil
+}
+
+type pathState struct {
+	proxy *url.URL
+	// fromEnv is true when the proxy is read from the environment like http.ProxyFromEnvironment.
+	fromEnv bool
+	healthy bool
+	err     error
+}
+
+func (p *pathState) String() string {
+	if p.fromEnv {
+		return environment
+	}
+	if p.proxy == nil {
+		return Direct
+	}
+	return p.proxy.Redacted()
+}
+
+// Failover selects the path of the requests of a client out of its proxy and its failover paths. A path
+// whose proxy fails to connect is marked unhealthy and the next healthy path is used, the preferred paths are
+// probed while another one is used.
+type Failover struct {
+	log      *logger.Logger
+	client   string
+	interval time.Duration
+	dial     func(ctx context.Context, network, address string) (net.Conn, error)
+	envProxy func(req *http.Request) (*url.URL, error)
+	now      func() time.Time
+
+	mx        sync.Mutex
+	tlsConfig *tls.Config
+	paths     []*pathState
+	current   int
+	since     time.Time
+	lastProbe time.Time
+	probing   bool
+}
+
+// New creates the failover of a client, preferred is the proxy of the client, nil when the client uses the
+// proxy of the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY) or none. The failover is registered under
+// the client name for the diagnostics, replacing the previous one.
+func New(log *logger.Logger, client string, preferred *url.URL, cfg Config) (*Failover, error) {
+	failover, err := parsePaths(cfg.Failover)
+	if err != nil {
+		return nil, err
+	}
+	interval := cfg.ProbeInterval
+	if interval == 0 {
+		interval = defaultProbeInterval
+	}
+
+	f := &Failover{
+		log:      log,
+		client:   client,
+		interval: interval,
+		dial:     (&net.Dialer{}).DialContext,
+		envProxy: http.ProxyFromEnvironment,
+		now:      time.Now,
+	}
+	f.paths = append(f.paths, &pathState{proxy: preferred, fromEnv: preferred == nil, healthy: true})
+	for _, proxy := range failover {
+		f.paths = append(f.paths, &pathState{proxy: proxy, healthy: true})
+	}
+	f.since = f.now()
+
+	register(f)
+	return f, nil
+}
+
+// Proxy returns the proxy of the request, nil when the request is sent directly. It has the signature
+// of http.Transport.Proxy. When a path preferred over the current one is due for a probe, it is probed
+// in the background.
+func (f *Failover) Proxy(req *http.Request) (*url.URL, error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	if f.current > 0 && !f.probing && f.now().Sub(f.lastProbe) >= f.interval {
+		f.probing = true
+		f.lastProbe = f.now()
+		go f.probe(req.URL)
+	}
+	return f.proxyOf(f.paths[f.current], req)
+}
+
+// proxyOf returns the proxy of the request on the path.
+func (f *Failover) proxyOf(path *pathState, req *http.Request) (*url.URL, error) {
+	if path.fromEnv {
+		return f.envProxy(req)
+	}
+	return path.proxy, nil
+}
+
+// RoundTripper wraps rt so the path of a request failing to connect to its proxy is marked unhealthy.
+func (f *Failover) RoundTripper(rt http.RoundTripper) http.RoundTripper {
+	if transport, ok := rt.(*http.Transport); ok {
+		// the HTTPS proxies are probed with the TLS settings the transport connects to them with
+		f.mx.Lock()
+		f.tlsConfig = transport.TLSClientConfig
+		f.mx.Unlock()
+	}
+	return &roundTripper{failover: f, rt: rt}
+}
+
+type roundTripper struct {
+	failover *Failover
+	rt       http.RoundTripper
+}
+
+func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.failover.mx.Lock()
+	idx := r.failover.current
+	proxy, _ := r.failover.proxyOf(r.failover.paths[idx], req)
+	r.failover.mx.Unlock()
+
+	resp, err := r.rt.RoundTrip(req)
+	if err != nil && isPathFailure(err, proxy) {
+		r.failover.markFailed(idx, err)
+	}
+	return resp, err
+}
+
+// isPathFailure returns true when the error is a failure to connect to the proxy of the request: the proxy could
+// not be dialed, or the TLS handshake with the proxy failed. A failure to reach the destination is not fixed by
+// another path.
+func isPathFailure(err error, proxy *url.URL) bool {
+	if proxy == nil {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		switch opErr.Op {
+		case "proxyconnect", "dial", "socks connect":
+			return true
+		}
+	}
+	if proxy.Scheme != "https" {
+		return false
+	}
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	return errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr)
+}
+
+// markFailed marks the path unhealthy, and switches to the next healthy path when it is the current one.
+func (f *Failover) markFailed(idx int, err error) {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	failed := f.paths[idx]
+	failed.healthy = false
+	failed.err = err
+	if idx != f.current {
+		return
+	}
+
+	next := (idx + 1) % len(f.paths)
+	for i := 1; i < len(f.paths); i++ {
+		candidate := (idx + i) % len(f.paths)
+		if f.paths[candidate].healthy {
+			next = candidate
+			break
+		}
+	}
+	f.log.Warnf("%s connection through %s failed, switching to %s: %v", f.client, failed, f.paths[next], err)
+	f.switchTo(next)
+}
+
+// probe probes the paths preferred over the current one and fails back to the first reachable one.
+func (f *Failover) probe(target *url.URL) {
+	f.mx.Lock()
+	candidates := slices.Clone(f.paths[:f.current])
+	tlsConfig := f.tlsConfig
+	f.mx.Unlock()
+
+	defer func() {
+		f.mx.Lock()
+		f.probing = false
+		f.mx.Unlock()
+	}()
+
+	for i, path := range candidates {
+		address := hostPort(target)
+		proxy, err := f.proxyOf(path, &http.Request{URL: target})
+		if err == nil && proxy != nil {
+			address = hostPort(proxy)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+		conn, err := f.dial(ctx, "tcp", address)
+		if err == nil && proxy != nil && proxy.Scheme == "https" {
+			// the proxy is reachable only once its TLS handshake succeeds
+			conn, err = handshake(ctx, conn, proxy, tlsConfig)
+		}
+		cancel()
+
+		f.mx.Lock()
+		if err != nil {
+			path.err = err
+			f.mx.Unlock()
+			continue
+		}
+		_ = conn.Close()
+		path.healthy = true
+		path.err = nil
+		if i < f.current {
+			f.log.Infof("%s connection through %s recovered, failing back from %s", f.client, path, f.paths[f.current])
+			f.switchTo(i)
+		}
+		f.mx.Unlock()
+		return
+	}
+}
+
+// handshake completes the TLS handshake with the HTTPS proxy, closing the connection when it fails.
+func handshake(ctx context.Context, conn net.Conn, proxy *url.URL, cfg *tls.Config) (net.Conn, error) {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = proxy.Hostname()
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// switchTo changes the current path, f.mx must be held.
+func (f *Failover) switchTo(idx int) {
+	f.current = idx
+	f.since = f.now()
+}
+
+// state returns the state of the failover for the diagnostics.
+func (f *Failover) state() State {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+
+	s := State{
+		Client: f.client,
+		Path:   f.paths[f.current].String(),
+		Since:  f.since,
+	}
+	for _, path := range f.paths {
+		p := PathState{Path: path.String(), Healthy: path.healthy}
+		if path.err != nil {
+			p.Error = path.err.Error()
+		}
+		s.Paths = append(s.Paths, p)
+	}
+	return s
+}
+
+// hostPort returns the address of the URL, with the default port of its scheme when it has none.
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
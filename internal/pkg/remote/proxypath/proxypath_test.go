@@ -0,0 +1,214 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxypath
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestConfigValidate(t *testing.T) {
+	cfg := Config{Failover: []string{"http://proxy-2:3128", "DIRECT"}}
+	assert.NoError(t, cfg.Validate())
+	assert.True(t, cfg.Enabled())
+
+	cfg = Config{Failover: []string{"proxy-2"}}
+	assert.ErrorContains(t, cfg.Validate(), `expected a proxy URL or "direct"`)
+
+	cfg = Config{ProbeInterval: -time.Second}
+	assert.Error(t, cfg.Validate())
+	assert.False(t, cfg.Enabled())
+}
+
+func TestFailover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// a proxy that is not listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	require.NoError(t, l.Close())
+
+	log, _ := loggertest.New("proxypath")
+	f, err := New(log, "test", proxyURL, Config{Failover: []string{Direct}, ProbeInterval: time.Millisecond})
+	require.NoError(t, err)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = f.Proxy
+	client := &http.Client{Transport: f.RoundTripper(transport)}
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err, "the proxy is not reachable")
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err, "the request should fail over to a direct connection")
+	resp.Body.Close()
+
+	states := States()
+	require.Len(t, states, 1)
+	assert.Equal(t, "test", states[0].Client)
+	assert.Equal(t, Direct, states[0].Path)
+	require.Len(t, states[0].Paths, 2)
+	assert.False(t, states[0].Paths[0].Healthy)
+	assert.NotEmpty(t, states[0].Paths[0].Error)
+	assert.True(t, states[0].Paths[1].Healthy)
+
+	// the proxy recovers
+	f.mx.Lock()
+	f.dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		assert.Equal(t, proxyURL.Host, address, "the preferred proxy should be probed")
+		client, server := net.Pipe()
+		_ = server.Close()
+		return client, nil
+	}
+	f.mx.Unlock()
+
+	require.Eventually(t, func() bool {
+		proxy, err := f.Proxy(&http.Request{URL: &url.URL{Scheme: "http", Host: "fleet:8220"}})
+		return err == nil && proxy == proxyURL
+	}, 5*time.Second, 10*time.Millisecond, "the client should fail back to the proxy")
+	assert.Equal(t, proxyURL.Redacted(), States()[0].Path)
+}
+
+func TestFailoverIgnoresDestinationErrors(t *testing.T) {
+	// a destination that is not listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	destination := "http://" + l.Addr().String()
+	require.NoError(t, l.Close())
+
+	log, _ := loggertest.New("proxypath")
+	f, err := New(log, "test-direct", nil, Config{Failover: []string{"http://proxy-2:3128"}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		registry.mx.Lock()
+		delete(registry.failovers, f.client)
+		registry.mx.Unlock()
+	})
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = f.Proxy
+	client := &http.Client{Transport: f.RoundTripper(transport)}
+
+	_, err = client.Get(destination)
+	require.Error(t, err, "the destination is not reachable")
+
+	proxy, err := f.Proxy(&http.Request{URL: &url.URL{Scheme: "http", Host: "fleet:8220"}})
+	require.NoError(t, err)
+	assert.Nil(t, proxy, "a failure to dial the destination should not switch the path")
+	assert.True(t, f.state().Paths[0].Healthy)
+}
+
+func TestFailoverProxyTLSErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	// an HTTPS proxy whose certificate is not trusted
+	proxy := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+
+	log, _ := loggertest.New("proxypath")
+	f, err := New(log, "test-tls", proxyURL, Config{Failover: []string{Direct}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		registry.mx.Lock()
+		delete(registry.failovers, f.client)
+		registry.mx.Unlock()
+	})
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = f.Proxy
+	client := &http.Client{Transport: f.RoundTripper(transport)}
+
+	_, err = client.Get(server.URL)
+	require.Error(t, err, "the certificate of the proxy is not trusted")
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err, "the request should fail over to a direct connection")
+	resp.Body.Close()
+
+	// the proxy accepts connections but its handshake still fails, it is not failed back to
+	assert.Never(t, func() bool {
+		return f.state().Path != Direct
+	}, 500*time.Millisecond, 50*time.Millisecond)
+	assert.False(t, f.state().Paths[0].Healthy)
+}
+
+func TestFailoverEnvironmentProxy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	// the proxy of the environment is not listening
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxyURL := &url.URL{Scheme: "http", Host: l.Addr().String()}
+	require.NoError(t, l.Close())
+
+	log, _ := loggertest.New("proxypath")
+	f, err := New(log, "test-env", nil, Config{Failover: []string{Direct}})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		registry.mx.Lock()
+		delete(registry.failovers, f.client)
+		registry.mx.Unlock()
+	})
+	f.envProxy = func(*http.Request) (*url.URL, error) { return proxyURL, nil }
+
+	proxy, err := f.Proxy(&http.Request{URL: &url.URL{Scheme: "http", Host: "fleet:8220"}})
+	require.NoError(t, err)
+	assert.Equal(t, proxyURL, proxy, "the proxy of the environment should be used first")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = f.Proxy
+	client := &http.Client{Transport: f.RoundTripper(transport)}
+	_, err = client.Get(server.URL)
+	require.Error(t, err, "the proxy of the environment is not reachable")
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err, "the request should fail over to a direct connection")
+	resp.Body.Close()
+
+	state := f.state()
+	assert.Equal(t, Direct, state.Path)
+	assert.Equal(t, "environment", state.Paths[0].Path)
+	assert.False(t, state.Paths[0].Healthy)
+}
+
+func TestIsPathFailure(t *testing.T) {
+	httpProxy := &url.URL{Scheme: "http", Host: "proxy:3128"}
+	httpsProxy := &url.URL{Scheme: "https", Host: "proxy:3128"}
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	tlsErr := &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}
+
+	assert.True(t, isPathFailure(&net.OpError{Op: "proxyconnect", Net: "tcp", Err: dialErr}, httpProxy))
+	assert.True(t, isPathFailure(dialErr, httpProxy))
+	assert.False(t, isPathFailure(dialErr, nil), "a destination that cannot be dialed is not fixed by another path")
+	assert.True(t, isPathFailure(tlsErr, httpsProxy))
+	assert.False(t, isPathFailure(tlsErr, httpProxy), "the TLS errors of the destination are not fixed by another path")
+	assert.False(t, isPathFailure(errors.New("unexpected EOF"), httpProxy))
+}
+
+func TestHostPort(t *testing.T) {
+	assert.Equal(t, "proxy:3128", hostPort(&url.URL{Scheme: "http", Host: "proxy:3128"}))
+	assert.Equal(t, "proxy:80", hostPort(&url.URL{Scheme: "http", Host: "proxy"}))
+	assert.Equal(t, "fleet:443", hostPort(&url.URL{Scheme: "https", Host: "fleet"}))
+	assert.Equal(t, "proxy:1080", hostPort(&url.URL{Scheme: "socks5", Host: "proxy"}))
+}
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package proxypath
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var registry = struct {
+	mx        sync.Mutex
+	failovers map[string]*Failover
+}{failovers: map[string]*Failover{}}
+
+// State is the path used by a client.
+type State struct {
+	Client string      `yaml:"client"`
+	Path   string      `yaml:"path"`
+	Since  time.Time   `yaml:"since"`
+	Paths  []PathState `yaml:"paths"`
+}
+
+// PathState is the health of a path of a client.
+type PathState struct {
+	Path    string `yaml:"path"`
+	Healthy bool   `yaml:"healthy"`
+	Error   string `yaml:"error,omitempty"`
+}
+
+func register(f *Failover) {
+	registry.mx.Lock()
+	defer registry.mx.Unlock()
+	registry.failovers[f.client] = f
+}
+
+// States returns the path used by each client with failover paths, sorted by client.
+func States() []State {
+	registry.mx.Lock()
+	failovers := make([]*Failover, 0, len(registry.failovers))
+	for _, f := range registry.failovers {
+		failovers = append(failovers, f)
+	}
+	registry.mx.Unlock()
+
+	states := make([]State, 0, len(failovers))
+	for _, f := range failovers {
+		states = append(states, f.state())
+	}
+	slices.SortFunc(states, func(a, b State) int {
+		return strings.Compare(a.Client, b.Client)
+	})
+	return states
+}
@@ -0,0 +1,109 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package revocation
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxCRLSize is the maximum size of a fetched CRL.
+	maxCRLSize = 32 * 1024 * 1024
+	// failedFetchRetry is how long a CRL that failed to be fetched is not fetched again.
+	failedFetchRetry = time.Minute
+)
+
+// crlCache caches the fetched CRLs by URL, it's shared by the clients as they are recreated on every
+// configuration change.
+var crlCache = struct {
+	mx      sync.Mutex
+	entries map[string]*crlEntry
+}{entries: map[string]*crlEntry{}}
+
+type crlEntry struct {
+	// mx serializes the fetches of the CRL.
+	mx        sync.Mutex
+	list      *x509.RevocationList
+	fetchedAt time.Time
+	err       error
+}
+
+func cachedCRL(url string) *crlEntry {
+	crlCache.mx.Lock()
+	defer crlCache.mx.Unlock()
+	entry, ok := crlCache.entries[url]
+	if !ok {
+		entry = &crlEntry{}
+		crlCache.entries[url] = entry
+	}
+	return entry
+}
+
+// crl returns the CRL of the URL signed by the issuer, fetched again once older than the cache TTL or
+// when its next update is due.
+func (c *Checker) crl(ctx context.Context, url string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	entry := cachedCRL(url)
+	entry.mx.Lock()
+	defer entry.mx.Unlock()
+
+	now := c.now()
+	switch {
+	case entry.err != nil && now.Sub(entry.fetchedAt) < failedFetchRetry:
+		return nil, entry.err
+	case entry.list != nil && now.Sub(entry.fetchedAt) < c.cfg.CRLCacheTTL && !expired(entry.list, now):
+	default:
+		entry.list, entry.err = c.fetchCRL(ctx, url)
+		entry.fetchedAt = now
+		if entry.err != nil {
+			return nil, entry.err
+		}
+	}
+
+	if err := entry.list.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("CRL %s is not signed by %q: %w", url, issuer.Subject.String(), err)
+	}
+	if expired(entry.list, now) {
+		return nil, fmt.Errorf("CRL %s expired at %s", url, entry.list.NextUpdate.Format(time.RFC3339))
+	}
+	return entry.list, nil
+}
+
+// expired returns true when the next update of the CRL is due, a CRL without next update doesn't expire.
+func expired(list *x509.RevocationList, now time.Time) bool {
+	return !list.NextUpdate.IsZero() && now.After(list.NextUpdate)
+}
+
+func (c *Checker) fetchCRL(ctx context.Context, url string) (*x509.RevocationList, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CRL distribution point %s: %w", url, err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the CRL %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch the CRL %s: status code %d", url, resp.StatusCode)
+	}
+	der, err := io.ReadAll(io.LimitReader(resp.Body, maxCRLSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the CRL %s: %w", url, err)
+	}
+	list, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CRL %s: %w", url, err)
+	}
+	c.log.Debugf("fetched the CRL %s, next update at %s", url, list.NextUpdate.Format(time.RFC3339))
+	return list, nil
+}
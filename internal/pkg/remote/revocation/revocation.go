@@ -0,0 +1,305 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package revocation checks the revocation status of the certificates of the TLS servers the Elastic Agent
+// connects to, with the OCSP response stapled by the server and the CRLs of the certificates. The checks
+// run once the TLS handshake verified the certificate chain, a revoked certificate fails the connection.
+package revocation
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/elastic-agent-libs/transport"
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	defaultCRLCacheTTL = time.Hour
+	defaultTimeout     = 10 * time.Second
+)
+
+// Mode is how the revocation status of the certificates is enforced.
+type Mode string
+
+const (
+	// ModeOff disables the revocation checks.
+	ModeOff Mode = "off"
+	// ModeSoft rejects the revoked certificates and accepts the certificates whose revocation status
+	// cannot be determined.
+	ModeSoft Mode = "soft"
+	// ModeHard rejects the revoked certificates and the certificates whose revocation status cannot be
+	// determined.
+	ModeHard Mode = "hard"
+)
+
+// Unpack the mode.
+func (m *Mode) Unpack(from string) error {
+	switch Mode(from) {
+	case "", ModeOff, ModeSoft, ModeHard:
+		*m = Mode(from)
+		return nil
+	}
+	return fmt.Errorf("invalid revocation mode %q, accepted values are 'off', 'soft' and 'hard'", from)
+}
+
+const (
+	// SourceOCSPStapling is the OCSP response stapled by the server to the TLS handshake.
+	SourceOCSPStapling = "ocsp_stapling"
+	// SourceCRL is the certificate revocation list of the CRL distribution points of the certificates.
+	SourceCRL = "crl"
+)
+
+// Config configures the revocation checks of the certificates of the servers.
+type Config struct {
+	// Mode enables the revocation checks in soft or hard fail mode, off by default.
+	Mode Mode `config:"mode" yaml:"mode,omitempty"`
+
+	// Sources are the sources of the revocation status, ocsp_stapling and crl, all by default.
+	Sources []string `config:"sources" yaml:"sources,omitempty"`
+
+	// CRLCacheTTL is how long a fetched CRL is used before being fetched again, the CRL is fetched
+	// again earlier when its next update is due.
+	CRLCacheTTL time.Duration `config:"crl_cache_ttl" yaml:"crl_cache_ttl,omitempty"`
+
+	// Timeout is the timeout of fetching a CRL.
+	Timeout time.Duration `config:"timeout" yaml:"timeout,omitempty"`
+}
+
+// Enabled returns true when the revocation status of the certificates is checked.
+func (c *Config) Enabled() bool {
+	return c.Mode == ModeSoft || c.Mode == ModeHard
+}
+
+// Validate validates the revocation configuration.
+func (c *Config) Validate() error {
+	if err := c.Mode.Unpack(string(c.Mode)); err != nil {
+		return err
+	}
+	for _, source := range c.Sources {
+		if source != SourceOCSPStapling && source != SourceCRL {
+			return fmt.Errorf("invalid revocation source %q, accepted values are '%s' and '%s'", source, SourceOCSPStapling, SourceCRL)
+		}
+	}
+	if c.CRLCacheTTL < 0 {
+		return errors.New("revocation crl_cache_ttl cannot be negative")
+	}
+	if c.Timeout < 0 {
+		return errors.New("revocation timeout cannot be negative")
+	}
+	return nil
+}
+
+func (c *Config) source(source string) bool {
+	return len(c.Sources) == 0 || slices.Contains(c.Sources, source)
+}
+
+// status is the revocation status of a certificate.
+type status int
+
+const (
+	statusGood status = iota
+	statusRevoked
+	statusUnknown
+)
+
+// Checker checks the revocation status of the certificates of the servers.
+type Checker struct {
+	log    *logger.Logger
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// New creates a checker of the revocation status of the certificates.
+func New(log *logger.Logger, cfg Config) *Checker {
+	if cfg.CRLCacheTTL == 0 {
+		cfg.CRLCacheTTL = defaultCRLCacheTTL
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	return &Checker{
+		log:    log,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		now:    time.Now,
+	}
+}
+
+// TransportOption returns the option checking the revocation status of the certificates of the TLS
+// connections of the transport of the settings.
+//
+// The TLS connections dialed directly are established with the TLS configuration of the settings and
+// checked once established, the TLS connections tunneled through a proxy are checked when they are
+// verified.
+func (c *Checker) TransportOption(settings *httpcommon.HTTPTransportSettings) (httpcommon.TransportOption, error) {
+	tlsCfg, err := tlscommon.LoadTLSConfig(settings.TLS, c.log)
+	if err != nil {
+		return nil, err
+	}
+	if tlsCfg != nil && tlsCfg.Verification == tlscommon.VerifyNone {
+		c.log.Warn("revocation checks are disabled, the verification of the certificates is disabled")
+		return httpcommon.WithTransportFunc(func(*http.Transport) {}), nil
+	}
+	var roots *x509.CertPool
+	if tlsCfg != nil {
+		roots = tlsCfg.RootCAs
+	}
+	dialer := transport.TLSDialer(transport.NetDialer(settings.Timeout), tlsCfg, settings.Timeout, c.log)
+
+	return httpcommon.WithTransportFunc(func(t *http.Transport) {
+		t.DialTLSContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				if err := c.Verify(ctx, address, tlsConn.ConnectionState(), roots); err != nil {
+					_ = conn.Close()
+					return nil, err
+				}
+			}
+			return conn, nil
+		}
+		if t.TLSClientConfig != nil {
+			tlsConfig := t.TLSClientConfig.Clone()
+			verify := tlsConfig.VerifyConnection
+			tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+				if verify != nil {
+					if err := verify(cs); err != nil {
+						return err
+					}
+				}
+				return c.Verify(context.Background(), cs.ServerName, cs, roots)
+			}
+			t.TLSClientConfig = tlsConfig
+		}
+	}), nil
+}
+
+// Verify checks the revocation status of the certificates of the chain of the connection to the server,
+// roots are the trusted certificate authorities, the system ones when nil.
+func (c *Checker) Verify(ctx context.Context, server string, cs tls.ConnectionState, roots *x509.CertPool) error {
+	chain, err := verifiedChain(cs, roots)
+	if err != nil {
+		return c.unknown(server, nil, err)
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		cert, issuer := chain[i], chain[i+1]
+		var staple []byte
+		if i == 0 {
+			staple = cs.OCSPResponse
+		}
+		status, err := c.status(ctx, cert, issuer, staple)
+		switch status {
+		case statusRevoked:
+			return fmt.Errorf("certificate %q of %s is revoked: %w", cert.Subject.String(), server, err)
+		case statusUnknown:
+			if err := c.unknown(server, cert, err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// unknown handles a certificate whose revocation status cannot be determined, it's rejected in hard mode.
+func (c *Checker) unknown(server string, cert *x509.Certificate, err error) error {
+	subject := "the certificate chain"
+	if cert != nil {
+		subject = fmt.Sprintf("certificate %q", cert.Subject.String())
+	}
+	if c.cfg.Mode == ModeHard {
+		return fmt.Errorf("revocation status of %s of %s is unknown: %w", subject, server, err)
+	}
+	c.log.Warnf("revocation status of %s of %s is unknown, accepted in soft mode: %v", subject, server, err)
+	return nil
+}
+
+// status returns the revocation status of the certificate, from the stapled OCSP response first and from
+// its CRLs otherwise. A certificate without OCSP responder nor CRL distribution point cannot be revoked.
+func (c *Checker) status(ctx context.Context, cert *x509.Certificate, issuer *x509.Certificate, staple []byte) (status, error) {
+	var errs []error
+	if len(staple) > 0 && c.cfg.source(SourceOCSPStapling) {
+		resp, err := ocsp.ParseResponseForCert(staple, cert, issuer)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("invalid stapled OCSP response: %w", err))
+		case !resp.NextUpdate.IsZero() && c.now().After(resp.NextUpdate):
+			errs = append(errs, fmt.Errorf("stapled OCSP response expired at %s", resp.NextUpdate.Format(time.RFC3339)))
+		case resp.Status == ocsp.Good:
+			return statusGood, nil
+		case resp.Status == ocsp.Revoked:
+			return statusRevoked, fmt.Errorf("revoked at %s according to the stapled OCSP response", resp.RevokedAt.Format(time.RFC3339))
+		default:
+			errs = append(errs, errors.New("stapled OCSP response has an unknown status"))
+		}
+	}
+
+	if len(cert.CRLDistributionPoints) > 0 && c.cfg.source(SourceCRL) {
+		for _, url := range cert.CRLDistributionPoints {
+			crl, err := c.crl(ctx, url, issuer)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, entry := range crl.RevokedCertificateEntries {
+				if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return statusRevoked, fmt.Errorf("revoked at %s according to the CRL %s", entry.RevocationTime.Format(time.RFC3339), url)
+				}
+			}
+			return statusGood, nil
+		}
+	}
+
+	if len(errs) > 0 {
+		return statusUnknown, errors.Join(errs...)
+	}
+	if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+		return statusGood, nil
+	}
+	return statusUnknown, errors.New("no revocation information from the enabled sources")
+}
+
+// verifiedChain returns the chain of the certificate of the server up to a trusted certificate authority,
+// it's built again when the TLS configuration verifies the certificates itself. The chain presented by the
+// server is used when it's trusted otherwise, e.g. by the fingerprint of its certificate authority.
+func verifiedChain(cs tls.ConnectionState, roots *x509.CertPool) ([]*x509.Certificate, error) {
+	if len(cs.VerifiedChains) > 0 {
+		return cs.VerifiedChains[0], nil
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return nil, errors.New("the server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	chains, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err == nil {
+		return chains[0], nil
+	}
+	for i := 0; i < len(cs.PeerCertificates)-1; i++ {
+		if signErr := cs.PeerCertificates[i].CheckSignatureFrom(cs.PeerCertificates[i+1]); signErr != nil {
+			return nil, err
+		}
+	}
+	return cs.PeerCertificates, nil
+}
@@ -0,0 +1,187 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package revocation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/elastic/elastic-agent-libs/transport/httpcommon"
+	"github.com/elastic/elastic-agent-libs/transport/tlscommon"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type testCA struct {
+	t    *testing.T
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "revocation test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{t: t, cert: cert, key: key}
+}
+
+func (ca *testCA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+}
+
+// server issues the certificate of localhost with the CRL distribution point and starts a TLS server with it.
+func (ca *testCA) server(serial int64, crlURL string, staple func(leaf *x509.Certificate) []byte) *httptest.Server {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(ca.t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:   []string{"http://127.0.0.1:1/ocsp"},
+	}
+	if crlURL != "" {
+		template.CRLDistributionPoints = []string{crlURL}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(ca.t, err)
+	leaf, err := x509.ParseCertificate(der)
+	require.NoError(ca.t, err)
+
+	tlsCert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+	if staple != nil {
+		tlsCert.OCSPStaple = staple(leaf)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{tlsCert}}
+	srv.StartTLS()
+	ca.t.Cleanup(srv.Close)
+	return srv
+}
+
+// crlServer serves the CRL of the certificate authority revoking the serials.
+func (ca *testCA) crlServer(revoked ...int64) *httptest.Server {
+	entries := make([]x509.RevocationListEntry, 0, len(revoked))
+	for _, serial := range revoked {
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: big.NewInt(serial), RevocationTime: time.Now().Add(-time.Minute)})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    big.NewInt(1),
+		ThisUpdate:                time.Now().Add(-time.Minute),
+		NextUpdate:                time.Now().Add(time.Hour),
+		RevokedCertificateEntries: entries,
+	}, ca.cert, ca.key)
+	require.NoError(ca.t, err)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(der)
+	}))
+	ca.t.Cleanup(srv.Close)
+	return srv
+}
+
+func (ca *testCA) staple(status int) func(leaf *x509.Certificate) []byte {
+	return func(leaf *x509.Certificate) []byte {
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: leaf.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, crypto.Signer(ca.key))
+		require.NoError(ca.t, err)
+		return resp
+	}
+}
+
+func get(t *testing.T, ca *testCA, cfg Config, url string) error {
+	log, _ := loggertest.New("revocation")
+	settings := httpcommon.DefaultHTTPTransportSettings()
+	settings.TLS = &tlscommon.Config{CAs: []string{ca.pem()}}
+	settings.Timeout = 5 * time.Second
+	opt, err := New(log, cfg).TransportOption(&settings)
+	require.NoError(t, err)
+	client, err := settings.Client(opt)
+	require.NoError(t, err)
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+func TestCRL(t *testing.T) {
+	ca := newTestCA(t)
+	crl := ca.crlServer(3)
+
+	assert.NoError(t, get(t, ca, Config{Mode: ModeHard}, ca.server(2, crl.URL, nil).URL))
+	assert.ErrorContains(t, get(t, ca, Config{Mode: ModeSoft}, ca.server(3, crl.URL, nil).URL), "is revoked")
+
+	// the CRL cannot be fetched
+	unreachable := ca.server(4, "http://127.0.0.1:1/ca.crl", nil)
+	assert.NoError(t, get(t, ca, Config{Mode: ModeSoft}, unreachable.URL))
+	assert.ErrorContains(t, get(t, ca, Config{Mode: ModeHard}, unreachable.URL), "is unknown")
+}
+
+func TestOCSPStapling(t *testing.T) {
+	ca := newTestCA(t)
+
+	assert.NoError(t, get(t, ca, Config{Mode: ModeHard}, ca.server(2, "", ca.staple(ocsp.Good)).URL))
+	assert.ErrorContains(t, get(t, ca, Config{Mode: ModeSoft}, ca.server(3, "", ca.staple(ocsp.Revoked)).URL), "is revoked")
+
+	// the certificate has an OCSP responder but no response is stapled
+	notStapled := ca.server(4, "", nil)
+	assert.NoError(t, get(t, ca, Config{Mode: ModeSoft}, notStapled.URL))
+	assert.ErrorContains(t, get(t, ca, Config{Mode: ModeHard}, notStapled.URL), "is unknown")
+
+	// the stapled response is ignored when only the CRLs are checked
+	crl := ca.crlServer()
+	assert.NoError(t, get(t, ca, Config{Mode: ModeHard, Sources: []string{SourceCRL}}, ca.server(5, crl.URL, ca.staple(ocsp.Revoked)).URL))
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := Config{Mode: ModeSoft, Sources: []string{SourceCRL, SourceOCSPStapling}}
+	require.NoError(t, cfg.Validate())
+	assert.True(t, cfg.Enabled())
+
+	assert.False(t, (&Config{}).Enabled())
+	assert.Error(t, (&Config{Mode: "strict"}).Validate())
+	assert.Error(t, (&Config{Sources: []string{"ocsp"}}).Validate())
+	assert.Error(t, (&Config{CRLCacheTTL: -time.Minute}).Validate())
+}
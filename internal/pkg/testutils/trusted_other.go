@@ -0,0 +1,29 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package testutils
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TrustFile makes the file pass utils.CheckTrustedFile, it is owned by the current user so only its group and the
+// others lose the write permission.
+func TrustFile(t *testing.T, path string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Chmod(path, info.Mode().Perm()&^0o022))
+}
+
+// UntrustFile makes the file fail utils.CheckTrustedFile by letting its group and the others write it.
+func UntrustFile(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.Chmod(path, 0o722))
+}
@@ -0,0 +1,46 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package testutils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows"
+
+	"github.com/elastic/elastic-agent/internal/pkg/acl"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// TrustFile makes the file pass utils.CheckTrustedFile: it is given to Administrators and only Administrators and
+// SYSTEM can write it. The test is skipped without Administrator rights.
+func TrustFile(t *testing.T, path string) {
+	t.Helper()
+	if root, _ := utils.HasRoot(); !root {
+		t.Skip("giving the ownership of a file to Administrators requires Administrator rights")
+	}
+	admins, err := windows.CreateWellKnownSid(windows.WinBuiltinAdministratorsSid)
+	require.NoError(t, err)
+	system, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	require.NoError(t, err)
+	everyone, err := windows.CreateWellKnownSid(windows.WinWorldSid)
+	require.NoError(t, err)
+	require.NoError(t, acl.TakeOwnership(path, admins, nil))
+	require.NoError(t, acl.Apply(path, true, false,
+		acl.GrantSid(windows.GENERIC_ALL, admins),
+		acl.GrantSid(windows.GENERIC_ALL, system),
+		acl.GrantSid(windows.GENERIC_READ|windows.GENERIC_EXECUTE, everyone),
+	))
+}
+
+// UntrustFile makes the file fail utils.CheckTrustedFile by letting everyone write it.
+func UntrustFile(t *testing.T, path string) {
+	t.Helper()
+	everyone, err := windows.CreateWellKnownSid(windows.WinWorldSid)
+	require.NoError(t, err)
+	require.NoError(t, acl.Apply(path, false, false, acl.GrantSid(windows.GENERIC_WRITE, everyone)))
+}
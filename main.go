@@ -9,6 +9,7 @@ import (
 	"os"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/cmd"
+	"github.com/elastic/elastic-agent/internal/pkg/cli"
 	"github.com/elastic/elastic-agent/pkg/core/process"
 )
 
@@ -31,7 +32,7 @@ func main() {
 	command := cmd.NewCommand()
 	err = command.Execute()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		cli.WriteError(os.Stderr, cli.ErrorFormatFromArgs(os.Args), err)
 		return
 	}
 }
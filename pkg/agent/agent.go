@@ -0,0 +1,313 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+// Package agent is the supported API for embedding the Elastic Agent runtime in another process.
+//
+// Appliance vendors use it to run the agent inside their own supervisor instead of shipping and supervising the
+// standalone binary. The embedding process owns the policy: it starts the agent, applies policies to it and
+// receives the status of the agent and its components through a callback.
+//
+// The agent relies on process wide state (paths, logging and feature flags), only one agent can be embedded in
+// a process.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/elastic/elastic-agent-libs/logp"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/reexec"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/secret"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/vault"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// statusBufferLen is the number of status changes buffered for the status callback, older changes are dropped
+// when the callback cannot keep up.
+const statusBufferLen = 32
+
+var (
+	// ErrNotStarted is returned when the agent is used before it is started.
+	ErrNotStarted = errors.New("agent is not started")
+	// ErrAlreadyStarted is returned when the agent is started more than once.
+	ErrAlreadyStarted = errors.New("agent is already started")
+)
+
+// Config is the configuration of an embedded agent.
+type Config struct {
+	// TopPath is the directory holding the state of the agent. Defaults to the directory of the executable.
+	TopPath string
+	// ComponentsPath is the directory holding the component specifications and binaries. Defaults to the
+	// components directory of the versioned home under TopPath.
+	ComponentsPath string
+	// LogsPath is the directory holding the logs of the components. Defaults to the logs directory under TopPath.
+	LogsPath string
+
+	// Logger is the logger of the agent. Defaults to a logger writing to stderr.
+	Logger *logger.Logger
+	// LogLevel is the log level of the agent and its components. Defaults to info.
+	LogLevel logp.Level
+
+	// Policy is the initial policy, in YAML or JSON. The agent runs no components until a policy is applied.
+	Policy string
+
+	// OnStatus is called with the status of the agent on every change, from a single goroutine. It must not
+	// block for long, changes are dropped while it is running.
+	OnStatus func(Status)
+	// OnRestart is called when the agent requires to be restarted, e.g. after an upgrade. The embedding process
+	// is expected to stop and start the agent again. The request is only logged when nil.
+	OnRestart func()
+}
+
+// Status is the status of the agent and its components.
+type Status struct {
+	// State is the overall state of the agent, aggregated from the state of its components.
+	State client.State
+	// Message describes the state.
+	Message string
+	// Components is the status of the components, sorted by ID.
+	Components []client.ComponentState
+}
+
+// Agent is an embedded Elastic Agent.
+type Agent struct {
+	cfg Config
+	log *logger.Logger
+
+	mx      sync.Mutex
+	coord   *coordinator.Coordinator
+	setter  configSetter
+	cancel  context.CancelFunc
+	done    chan struct{}
+	runErr  error
+	started bool
+}
+
+// configSetter sets the policy of the config manager of the agent in testing mode.
+type configSetter interface {
+	SetConfig(ctx context.Context, cfg string) error
+}
+
+// New creates an embedded agent. The agent does not run until it is started.
+func New(cfg Config) *Agent {
+	log := cfg.Logger
+	if log == nil {
+		log = logger.NewWithoutConfig("elastic-agent")
+	}
+	if cfg.LogLevel == 0 {
+		cfg.LogLevel = logger.DefaultLogLevel
+	}
+	return &Agent{
+		cfg:  cfg,
+		log:  log,
+		done: make(chan struct{}),
+	}
+}
+
+// Start starts the agent. The context bounds the start, the agent runs until it is stopped or fails, which
+// closes the channel returned by Done.
+func (a *Agent) Start(ctx context.Context) error {
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	if a.started {
+		return ErrAlreadyStarted
+	}
+
+	if a.cfg.TopPath != "" {
+		paths.SetTop(a.cfg.TopPath)
+	}
+	if a.cfg.ComponentsPath != "" {
+		paths.SetComponents(a.cfg.ComponentsPath)
+	}
+	if a.cfg.LogsPath != "" {
+		paths.SetLogs(a.cfg.LogsPath)
+	}
+	logger.SetLevel(a.cfg.LogLevel)
+
+	isRoot, err := utils.HasRoot()
+	if err != nil {
+		return fmt.Errorf("failed to check for root/Administrator privileges: %w", err)
+	}
+	if err := secret.CreateAgentSecret(ctx, vault.WithUnprivileged(!isRoot)); err != nil {
+		return fmt.Errorf("failed to read/write secrets: %w", err)
+	}
+	agentInfo, err := info.NewAgentInfoWithLog(ctx, a.cfg.LogLevel.String(), true)
+	if err != nil {
+		return fmt.Errorf("could not load agent info: %w", err)
+	}
+
+	// the embedding process owns the policy, the same as in testing mode where the policy is received over the
+	// control protocol, so the agent never reads its configuration from the disk nor runs its own monitoring
+	coord, configMgr, _, err := application.New(ctx, a.log, a.log, a.cfg.LogLevel, agentInfo, &reexecManager{log: a.log, onRestart: a.cfg.OnRestart}, nil, true, 0, true, nil, nil)
+	if err != nil {
+		return err
+	}
+	setter, ok := configMgr.(configSetter)
+	if !ok {
+		return fmt.Errorf("config manager %T does not accept policies", configMgr)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	if a.cfg.OnStatus != nil {
+		states := coord.StateSubscribe(runCtx, statusBufferLen)
+		go func() {
+			for state := range states {
+				a.cfg.OnStatus(toStatus(state))
+			}
+		}()
+	}
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- coord.Run(runCtx)
+	}()
+
+	// the agent is only started once it accepted the initial policy, otherwise the coordinator is stopped so
+	// the start can be retried
+	if a.cfg.Policy != "" {
+		if err := setter.SetConfig(ctx, a.cfg.Policy); err != nil {
+			cancel()
+			<-runErr
+			return fmt.Errorf("failed to apply the initial policy: %w", err)
+		}
+	}
+
+	go func() {
+		err := <-runErr
+		a.mx.Lock()
+		if !errors.Is(err, context.Canceled) {
+			a.runErr = err
+		}
+		a.mx.Unlock()
+		close(a.done)
+	}()
+
+	a.coord = coord
+	a.setter = setter
+	a.cancel = cancel
+	a.started = true
+	return nil
+}
+
+// Stop stops the agent and its components and waits for them to exit, or for the context to be done. It returns
+// the error the agent failed with, if any.
+func (a *Agent) Stop(ctx context.Context) error {
+	a.mx.Lock()
+	if !a.started {
+		a.mx.Unlock()
+		return ErrNotStarted
+	}
+	a.cancel()
+	a.mx.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-a.done:
+	}
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	return a.runErr
+}
+
+// Done returns a channel closed once the agent stopped, either by Stop or because it failed.
+func (a *Agent) Done() <-chan struct{} {
+	return a.done
+}
+
+// ApplyPolicy applies the policy, in YAML or JSON, replacing the current one. It returns once the agent accepted
+// the policy, the components are updated asynchronously and report their progress through the status.
+func (a *Agent) ApplyPolicy(ctx context.Context, policy string) error {
+	a.mx.Lock()
+	setter := a.setter
+	a.mx.Unlock()
+	if setter == nil {
+		return ErrNotStarted
+	}
+	select {
+	case <-a.done:
+		return ErrNotStarted
+	default:
+	}
+	return setter.SetConfig(ctx, policy)
+}
+
+// Status returns the current status of the agent.
+func (a *Agent) Status() (Status, error) {
+	a.mx.Lock()
+	coord := a.coord
+	a.mx.Unlock()
+	if coord == nil {
+		return Status{}, ErrNotStarted
+	}
+	return toStatus(coord.State()), nil
+}
+
+func toStatus(state coordinator.State) Status {
+	components := make([]client.ComponentState, 0, len(state.Components))
+	for _, comp := range state.Components {
+		units := make([]client.ComponentUnitState, 0, len(comp.State.Units))
+		for key, unit := range comp.State.Units {
+			units = append(units, client.ComponentUnitState{
+				UnitID:   key.UnitID,
+				UnitType: client.UnitType(key.UnitType),
+				State:    client.State(unit.State),
+				Message:  unit.Message,
+				Payload:  unit.Payload,
+			})
+		}
+		sort.Slice(units, func(i, j int) bool {
+			return units[i].UnitID < units[j].UnitID
+		})
+		components = append(components, client.ComponentState{
+			ID:      comp.Component.ID,
+			Name:    comp.Component.Type(),
+			State:   client.State(comp.State.State),
+			Message: comp.State.Message,
+			Units:   units,
+			VersionInfo: client.ComponentVersionInfo{
+				Name: comp.State.VersionInfo.Name,
+				Meta: comp.State.VersionInfo.Meta,
+			},
+		})
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i].ID < components[j].ID
+	})
+	return Status{
+		State:      state.State,
+		Message:    state.Message,
+		Components: components,
+	}
+}
+
+// reexecManager hands restarts of the agent to the embedding process, the agent cannot re-execute the process
+// it is embedded in.
+type reexecManager struct {
+	log       *logger.Logger
+	onRestart func()
+}
+
+func (r *reexecManager) ReExec(callback reexec.ShutdownCallbackFn, _ ...string) {
+	if callback != nil {
+		if err := callback(); err != nil {
+			r.log.Errorf("failed to prepare the restart of the agent: %v", err)
+			return
+		}
+	}
+	if r.onRestart == nil {
+		r.log.Warn("agent requires a restart, the embedding process does not handle restarts")
+		return
+	}
+	r.onRestart()
+}
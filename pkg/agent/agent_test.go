@@ -0,0 +1,149 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	agentclient "github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+func TestAgentNotStarted(t *testing.T) {
+	a := New(Config{})
+
+	_, err := a.Status()
+	assert.ErrorIs(t, err, ErrNotStarted)
+	assert.ErrorIs(t, a.ApplyPolicy(context.Background(), "inputs: []"), ErrNotStarted)
+	assert.ErrorIs(t, a.Stop(context.Background()), ErrNotStarted)
+}
+
+func TestAgentStartInvalidPolicy(t *testing.T) {
+	a := New(Config{
+		TopPath:        t.TempDir(),
+		ComponentsPath: t.TempDir(),
+		LogsPath:       t.TempDir(),
+		Policy:         "inputs: [",
+	})
+
+	for i := 0; i < 2; i++ {
+		err := a.Start(context.Background())
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrAlreadyStarted, "a failed start must be retryable")
+		assert.ErrorContains(t, err, "failed to apply the initial policy")
+	}
+
+	_, err := a.Status()
+	assert.ErrorIs(t, err, ErrNotStarted)
+	assert.ErrorIs(t, a.Stop(context.Background()), ErrNotStarted)
+	select {
+	case <-a.Done():
+		t.Fatal("the agent never started, it must not be done")
+	default:
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	state := coordinator.State{
+		State:   client.Degraded,
+		Message: "1 or more components/units in a degraded state",
+		Components: []runtime.ComponentComponentState{
+			{
+				Component: component.Component{
+					ID:        "system/metrics-default",
+					InputSpec: &component.InputRuntimeSpec{InputType: "system/metrics"},
+				},
+				State: runtime.ComponentState{
+					State:   agentclient.UnitStateDegraded,
+					Message: "Degraded",
+					Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+						{UnitType: agentclient.UnitTypeOutput, UnitID: "system/metrics-default"}: {
+							State:   agentclient.UnitStateHealthy,
+							Message: "Healthy",
+						},
+						{UnitType: agentclient.UnitTypeInput, UnitID: "system/metrics-default-cpu"}: {
+							State:   agentclient.UnitStateDegraded,
+							Message: "Degraded",
+							Payload: map[string]interface{}{"error": "failed"},
+						},
+					},
+					VersionInfo: runtime.ComponentVersionInfo{Name: "beat-v2-client"},
+				},
+			},
+			{
+				Component: component.Component{
+					ID:        "filestream-default",
+					InputSpec: &component.InputRuntimeSpec{InputType: "filestream"},
+				},
+				State: runtime.ComponentState{
+					State:   agentclient.UnitStateHealthy,
+					Message: "Healthy",
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, Status{
+		State:   client.Degraded,
+		Message: "1 or more components/units in a degraded state",
+		Components: []client.ComponentState{
+			{
+				ID:      "filestream-default",
+				Name:    "filestream",
+				State:   client.Healthy,
+				Message: "Healthy",
+				Units:   []client.ComponentUnitState{},
+			},
+			{
+				ID:      "system/metrics-default",
+				Name:    "system/metrics",
+				State:   client.Degraded,
+				Message: "Degraded",
+				Units: []client.ComponentUnitState{
+					{
+						UnitID:   "system/metrics-default",
+						UnitType: client.UnitTypeOutput,
+						State:    client.Healthy,
+						Message:  "Healthy",
+					},
+					{
+						UnitID:   "system/metrics-default-cpu",
+						UnitType: client.UnitTypeInput,
+						State:    client.Degraded,
+						Message:  "Degraded",
+						Payload:  map[string]interface{}{"error": "failed"},
+					},
+				},
+				VersionInfo: client.ComponentVersionInfo{Name: "beat-v2-client"},
+			},
+		},
+	}, toStatus(state))
+}
+
+func TestReExecManager(t *testing.T) {
+	log, _ := loggertest.New("agent")
+
+	restarted := 0
+	r := &reexecManager{log: log, onRestart: func() { restarted++ }}
+	r.ReExec(func() error { return nil })
+	assert.Equal(t, 1, restarted)
+
+	r.ReExec(func() error { return errors.New("failed") })
+	assert.Equal(t, 1, restarted, "restart must not be requested when preparing it failed")
+
+	require.NotPanics(t, func() {
+		(&reexecManager{log: log}).ReExec(nil)
+	})
+}
@@ -24,6 +24,9 @@ type PackageDesc struct {
 	VersionedHome string              `yaml:"versioned-home,omitempty" json:"versionedHome,omitempty"`
 	PathMappings  []map[string]string `yaml:"path-mappings,omitempty" json:"pathMappings,omitempty"`
 	Flavors       map[string][]string `yaml:"flavors,omitempty" json:"flavors,omitempty"`
+	// StateStoreVersion is the version of the state store format the package reads, checked before downgrading
+	// to the package. Empty for packages built before it was declared.
+	StateStoreVersion string `yaml:"state-store-version,omitempty" json:"stateStoreVersion,omitempty"`
 }
 
 type PackageManifest struct {
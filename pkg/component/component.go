@@ -174,6 +174,13 @@ type Component struct {
 
 	// Component-level configuration
 	Component *proto.Component `yaml:"component,omitempty"`
+
+	// Budget is the resource budget the runtime enforces on the component, nil when it has none.
+	Budget *limits.ComponentBudget `yaml:"budget,omitempty"`
+
+	// CommandOverride is the environment, working directory and extra arguments of the component from the policy,
+	// nil when it has none.
+	CommandOverride *CommandOverride `yaml:"command_override,omitempty"`
 }
 
 func (c Component) MarshalYAML() (interface{}, error) {
@@ -480,8 +487,13 @@ func (r *RuntimeSpecs) PolicyToComponents(
 	}
 	// for now it's a shared component configuration for all components
 	// subject to change in the future
+	overrides, err := parseCommandOverrides(policy)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse components from policy: %w", err)
+	}
 	componentConfig := &ComponentConfig{
-		Limits: ComponentLimits(*limits),
+		Limits:           ComponentLimits(*limits),
+		CommandOverrides: overrides,
 	}
 
 	var components []Component
@@ -492,6 +504,10 @@ func (r *RuntimeSpecs) PolicyToComponents(
 				r.componentsForOutput(output, featureFlags, componentConfig)...)
 		}
 	}
+	for i := range components {
+		components[i].Budget = componentConfig.budgetFor(&components[i])
+		components[i].CommandOverride = componentConfig.commandOverrideFor(&components[i])
+	}
 
 	return components, nil
 }
@@ -695,6 +711,10 @@ func toIntermediate(policy map[string]interface{}, aliasMapping map[string]strin
 			delete(input, runtimeManagerKey)
 		}
 
+		if err := applyStartPosition(t, input); err != nil {
+			return nil, fmt.Errorf("invalid 'inputs.%d.start_position', %w", idx, err)
+		}
+
 		// Inject the top level fleet policy revision into each input configuration. This
 		// allows individual inputs (like endpoint) to detect policy changes more easily.
 		injectInputPolicyID(policy, input)
@@ -725,6 +745,7 @@ type inputI struct {
 
 	// The raw configuration for this input, with small cleanups:
 	// - the "enabled", "use_output", and "log_level" keys are removed
+	// - the "start_position" key is translated into the input type configuration
 	// - the key "policy.revision" is set to the current fleet policy revision
 	config map[string]interface{}
 }
@@ -762,6 +783,18 @@ func varsForPlatform(platform PlatformDetail, defaultProvider string) (*transpil
 			"family":      platform.Family,
 			"major":       platform.Major,
 			"minor":       platform.Minor,
+			"kernel": map[string]interface{}{
+				"version": platform.Host.Kernel.Version,
+				"major":   platform.Host.Kernel.Major,
+				"minor":   platform.Host.Kernel.Minor,
+			},
+			"cgroup": map[string]interface{}{
+				"version": platform.Host.CgroupVersion,
+			},
+			"selinux": map[string]interface{}{
+				"mode": platform.Host.SELinuxMode,
+			},
+			"wsl": platform.Host.WSL,
 		},
 		"user": map[string]interface{}{
 			"root": platform.User.Root,
@@ -792,7 +825,7 @@ func validateRuntimeChecks(
 		}
 		if preventionTrigger {
 			// true means the prevention valid (so input should not run)
-			preventionMessages = append(preventionMessages, prevention.Message)
+			preventionMessages = append(preventionMessages, preventionMessage(vars, prevention.Message))
 		}
 	}
 	if len(preventionMessages) > 0 {
@@ -801,6 +834,17 @@ func validateRuntimeChecks(
 	return nil
 }
 
+// preventionMessage substitutes the variables referenced in the message of a prevention, so the
+// message can state the exact host facts that triggered the prevention. The message is returned
+// unmodified when the substitution fails.
+func preventionMessage(vars *transpiler.Vars, message string) string {
+	node, err := vars.Replace(message)
+	if err != nil {
+		return message
+	}
+	return node.String()
+}
+
 func hasDuplicate(outputsMap map[string]outputI, id string) bool {
 	for _, o := range outputsMap {
 		for _, i := range o.inputs {
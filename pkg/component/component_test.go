@@ -274,6 +274,44 @@ func TestToComponents(t *testing.T) {
 			},
 			Err: "invalid 'inputs.0.runtime', valid values are: otel, process",
 		},
+		{
+			Name:     "Invalid: input start position value",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"enabled": true,
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type":           "filestream",
+						"start_position": "middle",
+					},
+				},
+			},
+			Err: "invalid 'inputs.0.start_position', invalid value \"middle\", valid values are: beginning, end, since <duration>",
+		},
+		{
+			Name:     "Invalid: input start position not supported by the input",
+			Platform: linuxAMD64Platform,
+			Policy: map[string]interface{}{
+				"outputs": map[string]interface{}{
+					"default": map[string]interface{}{
+						"type":    "elasticsearch",
+						"enabled": true,
+					},
+				},
+				"inputs": []interface{}{
+					map[string]interface{}{
+						"type":           "system/metrics",
+						"start_position": "end",
+					},
+				},
+			},
+			Err: "invalid 'inputs.0.start_position', not supported by the system/metrics input",
+		},
 		{
 			Name:     "Invalid: inputs entry duplicate because of missing id",
 			Platform: linuxAMD64Platform,
@@ -2564,6 +2602,18 @@ func TestPreventionsAreValid(t *testing.T) {
 			"family":      "family",
 			"major":       1,
 			"minor":       2,
+			"kernel": map[string]interface{}{
+				"version": "5.15.0",
+				"major":   5,
+				"minor":   15,
+			},
+			"cgroup": map[string]interface{}{
+				"version": 2,
+			},
+			"selinux": map[string]interface{}{
+				"mode": "disabled",
+			},
+			"wsl": false,
 		},
 		"user": map[string]interface{}{
 			"root": false,
@@ -2582,6 +2632,81 @@ func TestPreventionsAreValid(t *testing.T) {
 	}
 }
 
+func TestValidateRuntimeChecksHostFacts(t *testing.T) {
+	platform := PlatformDetail{
+		Platform: Platform{
+			OS:   Linux,
+			Arch: AMD64,
+			GOOS: Linux,
+		},
+		Host: HostDetail{
+			Kernel:        parseKernelVersion("4.18.0-513.el8.x86_64"),
+			CgroupVersion: 1,
+			SELinuxMode:   "enforcing",
+			WSL:           true,
+		},
+	}
+
+	testcases := map[string]struct {
+		prevention RuntimePreventionSpec
+		err        string
+	}{
+		"kernel version": {
+			prevention: RuntimePreventionSpec{
+				Condition: "${runtime.kernel.major} < 5",
+				Message:   "Kernel ${runtime.kernel.version} is not supported",
+			},
+			err: "Kernel 4.18.0-513.el8.x86_64 is not supported",
+		},
+		"cgroup version": {
+			prevention: RuntimePreventionSpec{
+				Condition: "${runtime.cgroup.version} == 1",
+				Message:   "cgroup v${runtime.cgroup.version} is not supported",
+			},
+			err: "cgroup v1 is not supported",
+		},
+		"selinux mode": {
+			prevention: RuntimePreventionSpec{
+				Condition: "${runtime.selinux.mode} == 'enforcing'",
+				Message:   "SELinux must not be in ${runtime.selinux.mode} mode",
+			},
+			err: "SELinux must not be in enforcing mode",
+		},
+		"wsl": {
+			prevention: RuntimePreventionSpec{
+				Condition: "${runtime.wsl} == true",
+				Message:   "WSL is not supported",
+			},
+			err: "WSL is not supported",
+		},
+		"not prevented": {
+			prevention: RuntimePreventionSpec{
+				Condition: "${runtime.kernel.major} == 4 and ${runtime.kernel.minor} > 18",
+				Message:   "not prevented",
+			},
+		},
+		"unknown variable in message": {
+			prevention: RuntimePreventionSpec{
+				Condition: "${runtime.wsl} == true",
+				Message:   "WSL ${runtime.unknown} is not supported",
+			},
+			err: "WSL ${runtime.unknown} is not supported",
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			err := validateRuntimeChecks(&RuntimeSpec{Preventions: []RuntimePreventionSpec{tc.prevention}}, platform)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var checkErr *ErrInputRuntimeCheckFail
+			require.ErrorAs(t, err, &checkErr)
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
 func TestSpecDurationsAreValid(t *testing.T) {
 	// Test that durations specified in all spec files explicitly specify valid units.
 
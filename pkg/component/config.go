@@ -8,7 +8,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/go-viper/mapstructure/v2"
@@ -52,6 +55,110 @@ func (c ComponentLimits) AsProto() *proto.ComponentLimits {
 
 type ComponentConfig struct {
 	Limits ComponentLimits
+	// CommandOverrides are the command overrides of the components by name, from the agent.components section of
+	// the policy.
+	CommandOverrides map[string]CommandOverride
+}
+
+// CommandOverride is the environment, working directory and extra arguments of a component from the policy, the
+// variables in the values are substituted by the transpiler. They apply to the components running as a command.
+type CommandOverride struct {
+	// Env are the environment variables set for the component, they take precedence over the ones of its spec.
+	Env map[string]string `config:"env" yaml:"env,omitempty"`
+	// WorkingDirectory is the working directory of the component instead of its run directory.
+	WorkingDirectory string `config:"working_directory" yaml:"working_directory,omitempty"`
+	// Args are appended to the arguments of the spec of the component.
+	Args []string `config:"args" yaml:"args,omitempty"`
+}
+
+// loaderEnvPrefixes are the prefixes of the environment variables of the dynamic loaders, they inject code into
+// the component.
+var loaderEnvPrefixes = []string{"LD_", "DYLD_"}
+
+// interpreterEnvVars are the environment variables that load code into the interpreters and runtimes the components
+// could start.
+var interpreterEnvVars = []string{
+	"BASH_ENV", "ENV", "JAVA_TOOL_OPTIONS", "_JAVA_OPTIONS", "JDK_JAVA_OPTIONS", "NODE_OPTIONS", "PERL5LIB",
+	"PERL5OPT", "PERLLIB", "PYTHONHOME", "PYTHONPATH", "PYTHONSTARTUP", "RUBYLIB", "RUBYOPT",
+}
+
+// Validate validates the command override. The environment variables of the dynamic loaders and of the
+// interpreters are refused: anyone editing the policy could run code with the privileges of the Elastic Agent.
+func (o *CommandOverride) Validate() error {
+	for name := range o.Env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+		// the names are case-insensitive on Windows
+		upper := strings.ToUpper(name)
+		if slices.ContainsFunc(loaderEnvPrefixes, func(prefix string) bool { return strings.HasPrefix(upper, prefix) }) ||
+			slices.Contains(interpreterEnvVars, upper) {
+			return fmt.Errorf("environment variable %q is not allowed, it loads code into the component", name)
+		}
+	}
+	if o.WorkingDirectory != "" && !filepath.IsAbs(o.WorkingDirectory) {
+		return fmt.Errorf("working directory %q must be absolute", o.WorkingDirectory)
+	}
+	return nil
+}
+
+// IsZero returns true when the command override doesn't change anything.
+func (o *CommandOverride) IsZero() bool {
+	return len(o.Env) == 0 && o.WorkingDirectory == "" && len(o.Args) == 0
+}
+
+// EnvList returns the environment variables as NAME=value entries sorted by name.
+func (o *CommandOverride) EnvList() []string {
+	env := make([]string, 0, len(o.Env))
+	for _, name := range slices.Sorted(maps.Keys(o.Env)) {
+		env = append(env, name+"="+o.Env[name])
+	}
+	return env
+}
+
+// parseCommandOverrides returns the command overrides of the agent.components section of the policy.
+func parseCommandOverrides(policy map[string]interface{}) (map[string]CommandOverride, error) {
+	cfg, err := config.NewConfigFrom(policy)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		Agent struct {
+			Components map[string]CommandOverride `config:"components"`
+		} `config:"agent"`
+	}
+	if err := cfg.Unpack(&parsed); err != nil {
+		return nil, err
+	}
+	return parsed.Agent.Components, nil
+}
+
+// commandOverrideFor returns the command override of the component, like the budgets the one named after its ID
+// takes precedence over the one named after its input type, then the one named after its binary.
+func (c *ComponentConfig) commandOverrideFor(comp *Component) *CommandOverride {
+	for _, name := range []string{comp.ID, comp.InputType, comp.BinaryName()} {
+		if name == "" {
+			continue
+		}
+		if override, ok := c.CommandOverrides[name]; ok && !override.IsZero() {
+			return &override
+		}
+	}
+	return nil
+}
+
+// budgetFor returns the resource budget of the component, the budget named after its ID takes precedence over
+// the one named after its input type, then the one named after its binary.
+func (c *ComponentConfig) budgetFor(comp *Component) *limits.ComponentBudget {
+	for _, name := range []string{comp.ID, comp.InputType, comp.BinaryName()} {
+		if name == "" {
+			continue
+		}
+		if budget, ok := c.Limits.Components[name]; ok && !budget.IsZero() {
+			return &budget
+		}
+	}
+	return nil
 }
 
 func (c ComponentConfig) AsProto() *proto.Component {
@@ -15,6 +15,7 @@ import (
 	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/pkg/limits"
 )
 
 func TestExpectedConfig(t *testing.T) {
@@ -209,3 +210,80 @@ func TestExpectedConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestComponentConfigBudgetFor(t *testing.T) {
+	cfg := ComponentConfig{
+		Limits: ComponentLimits{
+			Components: map[string]limits.ComponentBudget{
+				"filestream-default": {CPU: 2},
+				"filestream":         {CPU: 1},
+				"metricbeat":         {Memory: "1GB"},
+				"log":                {},
+			},
+		},
+	}
+	binary := func(name string) *InputRuntimeSpec {
+		return &InputRuntimeSpec{BinaryName: name}
+	}
+
+	budget := cfg.budgetFor(&Component{ID: "filestream-default", InputType: "filestream", InputSpec: binary("filebeat")})
+	require.NotNil(t, budget)
+	assert.Equal(t, 2.0, budget.CPU, "the budget named after the ID should take precedence")
+
+	budget = cfg.budgetFor(&Component{ID: "filestream-monitoring", InputType: "filestream", InputSpec: binary("filebeat")})
+	require.NotNil(t, budget)
+	assert.Equal(t, 1.0, budget.CPU, "the budget named after the input type should apply")
+
+	budget = cfg.budgetFor(&Component{ID: "system/metrics-default", InputType: "system/metrics", InputSpec: binary("metricbeat")})
+	require.NotNil(t, budget)
+	assert.Equal(t, "1GB", budget.Memory, "the budget named after the binary should apply")
+
+	assert.Nil(t, cfg.budgetFor(&Component{ID: "log-default", InputType: "log", InputSpec: binary("filebeat")}), "an empty budget should not apply")
+}
+
+func TestParseCommandOverrides(t *testing.T) {
+	overrides, err := parseCommandOverrides(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"components": map[string]interface{}{
+				"filestream-default": map[string]interface{}{
+					"env": map[string]interface{}{
+						"LC_ALL":    "en_US.UTF-8",
+						"JAVA_HOME": "/usr/lib/jvm/java-17",
+					},
+					"working_directory": "/var/lib/filestream",
+					"args":              []interface{}{"--site", "paris"},
+				},
+				"metricbeat": map[string]interface{}{},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	cfg := ComponentConfig{CommandOverrides: overrides}
+	override := cfg.commandOverrideFor(&Component{ID: "filestream-default", InputType: "filestream", InputSpec: &InputRuntimeSpec{BinaryName: "filebeat"}})
+	require.NotNil(t, override)
+	assert.Equal(t, []string{"JAVA_HOME=/usr/lib/jvm/java-17", "LC_ALL=en_US.UTF-8"}, override.EnvList())
+	assert.Equal(t, "/var/lib/filestream", override.WorkingDirectory)
+	assert.Equal(t, []string{"--site", "paris"}, override.Args)
+	assert.Nil(t, cfg.commandOverrideFor(&Component{ID: "system/metrics-default", InputType: "system/metrics", InputSpec: &InputRuntimeSpec{BinaryName: "metricbeat"}}), "an empty override should not apply")
+
+	_, err = parseCommandOverrides(map[string]interface{}{
+		"agent": map[string]interface{}{
+			"components": map[string]interface{}{
+				"filestream": map[string]interface{}{"working_directory": "relative"},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, `working directory "relative" must be absolute`)
+
+	for _, name := range []string{"LD_PRELOAD", "LD_LIBRARY_PATH", "DYLD_INSERT_LIBRARIES", "ld_preload", "PYTHONPATH", "NODE_OPTIONS"} {
+		_, err = parseCommandOverrides(map[string]interface{}{
+			"agent": map[string]interface{}{
+				"components": map[string]interface{}{
+					"filestream": map[string]interface{}{"env": map[string]interface{}{name: "/tmp/inject"}},
+				},
+			},
+		})
+		assert.ErrorContainsf(t, err, "is not allowed, it loads code into the component", "variable %s", name)
+	}
+}
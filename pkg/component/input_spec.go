@@ -6,6 +6,7 @@ package component
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/elastic/elastic-agent/internal/pkg/eql"
 )
@@ -22,7 +23,10 @@ type InputSpec struct {
 
 	Command      *CommandSpec `config:"command,omitempty" yaml:"command,omitempty"`
 	Service      *ServiceSpec `config:"service,omitempty" yaml:"service,omitempty"`
+	Image        *ImageSpec   `config:"image,omitempty" yaml:"image,omitempty"`
 	IsolateUnits bool         `config:"isolate_units,omitempty" yaml:"isolate_units,omitempty"`
+
+	HealthCheck *HealthCheckSpec `config:"health_check,omitempty" yaml:"health_check,omitempty"`
 }
 
 // Validate ensures correctness of input specification.
@@ -30,6 +34,19 @@ func (s *InputSpec) Validate() error {
 	if s.Command == nil && s.Service == nil {
 		return fmt.Errorf("input '%s' must define either command or service", s.Name)
 	}
+	if s.Image != nil {
+		if s.Command == nil {
+			return fmt.Errorf("input '%s' must define the command to run in its image", s.Name)
+		}
+		for _, platform := range s.Platforms {
+			if !strings.HasPrefix(platform, Linux+"/") {
+				return fmt.Errorf("input '%s' defines an image, it can only run on linux and not on '%s'", s.Name, platform)
+			}
+		}
+	}
+	if s.HealthCheck != nil && s.Command == nil {
+		return fmt.Errorf("input '%s' defines a health check, it is only evaluated for a command", s.Name)
+	}
 	for i, a := range s.Platforms {
 		if !GlobalPlatforms.Exists(a) {
 			return fmt.Errorf("input '%s' defines an unknown platform '%s'", s.Name, a)
@@ -33,10 +33,12 @@ var (
 
 // InputRuntimeSpec returns the specification for running this input on the current platform.
 type InputRuntimeSpec struct {
-	InputType  string    `yaml:"input_type"`
-	BinaryName string    `yaml:"binary_name"`
-	BinaryPath string    `yaml:"binary_path"`
-	Spec       InputSpec `yaml:"spec"`
+	InputType  string `yaml:"input_type"`
+	BinaryName string `yaml:"binary_name"`
+	BinaryPath string `yaml:"binary_path"`
+	// Custom is true when the input is provided by a custom component of the user specs directory.
+	Custom bool      `yaml:"custom,omitempty"`
+	Spec   InputSpec `yaml:"spec"`
 }
 
 // RuntimeSpecs return all the specifications for inputs that are supported on the current platform.
@@ -52,10 +54,14 @@ type RuntimeSpecs struct {
 
 	// aliasMapping maps aliases to real input name
 	aliasMapping map[string]string
+
+	// userSpecErrors are the errors of the user specifications that were skipped, keyed by file path
+	userSpecErrors map[string]error
 }
 
 type loadRuntimeOpts struct {
 	skipBinaryCheck bool
+	userSpecsDir    string
 }
 
 // LoadRuntimeOption are options for loading the runtime specs.
@@ -68,6 +74,14 @@ func SkipBinaryCheck() LoadRuntimeOption {
 	}
 }
 
+// WithUserSpecs loads the specifications of the custom components from the user directory, next to the
+// specifications of the components shipped with Elastic Agent.
+func WithUserSpecs(dir string) LoadRuntimeOption {
+	return func(o *loadRuntimeOpts) {
+		o.userSpecsDir = dir
+	}
+}
+
 // ParseComponentFiles parses spec files and returns list of associated paths with component.
 // Default set consisting of binary, spec file and default config file is always present
 func ParseComponentFiles(content []byte, filename string, includeDefaults bool) ([]string, error) {
@@ -103,6 +117,9 @@ func ParseComponentFiles(content []byte, filename string, includeDefaults bool)
 // are required to be {binary-name} with {binary-name}.spec.yml to be next to it. If a {binary-name}.spec.yml exists
 // but no matching {binary-name} is found that will result in an error. If a {binary-name} exists without a
 // {binary-name}.spec.yml then it will be ignored.
+//
+// With WithUserSpecs the specifications of the custom components are loaded from the user directory after the
+// ones of the provided directory, a user specification that fails to load is skipped instead of failing.
 func LoadRuntimeSpecs(dir string, platform PlatformDetail, opts ...LoadRuntimeOption) (RuntimeSpecs, error) {
 	var opt loadRuntimeOpts
 	for _, o := range opts {
@@ -112,64 +129,153 @@ func LoadRuntimeSpecs(dir string, platform PlatformDetail, opts ...LoadRuntimeOp
 	if err != nil {
 		return RuntimeSpecs{}, err
 	}
-	var inputTypes []string
-	inputSpecs := make(map[string]InputRuntimeSpec)
-	inputAliases := make(map[string]string)
+	specs := RuntimeSpecs{
+		platform:     platform,
+		inputSpecs:   make(map[string]InputRuntimeSpec),
+		aliasMapping: make(map[string]string),
+	}
 	for path, spec := range specFiles {
-		binaryName := filepath.Base(path[:len(path)-len(specGlobPattern)+1])
-		binaryPath := path[:len(path)-len(specGlobPattern)+1]
-		if platform.OS == Windows {
-			binaryPath += ".exe"
+		if err := specs.addSpec(path, spec, false, opt.skipBinaryCheck); err != nil {
+			return RuntimeSpecs{}, err
 		}
-		if !opt.skipBinaryCheck {
-			info, err := os.Stat(binaryPath)
-			if errors.Is(err, os.ErrNotExist) {
-				return RuntimeSpecs{}, fmt.Errorf("missing matching binary for %s", path)
-			} else if err != nil {
-				return RuntimeSpecs{}, fmt.Errorf("failed to stat %s: %w", binaryPath, err)
-			} else if info.IsDir() {
-				return RuntimeSpecs{}, fmt.Errorf("missing matching binary for %s", path)
-			}
+	}
+	if opt.userSpecsDir != "" {
+		specs.loadUserSpecs(opt.userSpecsDir, opt.skipBinaryCheck)
+	}
+	return specs, nil
+}
+
+// loadUserSpecs loads the specifications of the custom components from the user directory. The errors are
+// recorded per specification file, the other specifications are still loaded.
+func (r *RuntimeSpecs) loadUserSpecs(dir string, skipBinaryCheck bool) {
+	r.userSpecErrors = make(map[string]error)
+	matches, err := filepath.Glob(filepath.Join(dir, specGlobPattern))
+	if err != nil {
+		r.userSpecErrors[dir] = err
+		return
+	}
+	// sorted by the glob, a collision between user specifications always rejects the same one
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			r.userSpecErrors[match] = fmt.Errorf("failed reading spec %s: %w", match, err)
+			continue
 		}
-		for _, input := range spec.Inputs {
-			if !containsStr(inputTypes, input.Name) {
-				inputTypes = append(inputTypes, input.Name)
-			}
-			if !containsStr(input.Platforms, platform.String()) {
-				// input spec doesn't support this platform
-				continue
-			}
-			if existing, exists := inputSpecs[input.Name]; exists {
-				return RuntimeSpecs{}, fmt.Errorf("failed loading spec '%s': input '%s' already exists in spec '%s'", path, input.Name, existing.BinaryName)
-			}
-			if existing, exists := inputAliases[input.Name]; exists {
-				return RuntimeSpecs{}, fmt.Errorf("failed loading spec '%s': input '%s' collides with an alias from another input '%s'", path, input.Name, existing)
-			}
-			for _, alias := range input.Aliases {
-				if existing, exists := inputSpecs[alias]; exists {
-					return RuntimeSpecs{}, fmt.Errorf("failed loading spec '%s': input alias '%s' collides with an already defined input in spec '%s'", path, alias, existing.BinaryName)
-				}
-				if existing, exists := inputAliases[alias]; exists {
-					return RuntimeSpecs{}, fmt.Errorf("failed loading spec '%s': input alias '%s' collides with an already defined input alias for input '%s'", path, alias, existing)
+		spec, err := LoadSpec(data)
+		if err != nil {
+			r.userSpecErrors[match] = fmt.Errorf("failed reading spec %s: %w", match, err)
+			continue
+		}
+		if err := validateUserSpec(spec); err != nil {
+			r.userSpecErrors[match] = fmt.Errorf("failed loading spec '%s': %w", match, err)
+			continue
+		}
+		// a failing specification adds none of its inputs
+		next := r.clone()
+		if err := next.addSpec(match, spec, true, skipBinaryCheck); err != nil {
+			r.userSpecErrors[match] = err
+			continue
+		}
+		r.inputTypes, r.inputSpecs, r.aliasMapping = next.inputTypes, next.inputSpecs, next.aliasMapping
+	}
+}
+
+// validateUserSpec validates the parts of a specification that a custom component cannot use.
+func validateUserSpec(spec Spec) error {
+	for _, input := range spec.Inputs {
+		if input.Command == nil {
+			return fmt.Errorf("input '%s' of a custom component must define a command", input.Name)
+		}
+	}
+	return nil
+}
+
+// addSpec adds the inputs of the specification loaded from path.
+func (r *RuntimeSpecs) addSpec(path string, spec Spec, custom bool, skipBinaryCheck bool) error {
+	binaryName := filepath.Base(path[:len(path)-len(specGlobPattern)+1])
+	specBinaryPath := path[:len(path)-len(specGlobPattern)+1]
+	if r.platform.OS == Windows {
+		specBinaryPath += ".exe"
+	}
+	if !skipBinaryCheck && spec.bundlesBinary() {
+		if err := checkBinary(path, specBinaryPath); err != nil {
+			return err
+		}
+	}
+	for _, input := range spec.Inputs {
+		if !containsStr(r.inputTypes, input.Name) {
+			r.inputTypes = append(r.inputTypes, input.Name)
+		}
+		if !containsStr(input.Platforms, r.platform.String()) {
+			// input spec doesn't support this platform
+			continue
+		}
+		binaryPath := specBinaryPath
+		if input.Command != nil && input.Command.Path != "" {
+			binaryPath = input.Command.Path
+			if !skipBinaryCheck && input.Image == nil {
+				if err := checkBinary(path, binaryPath); err != nil {
+					return err
 				}
 			}
-			inputSpecs[input.Name] = InputRuntimeSpec{
-				InputType:  input.Name,
-				BinaryName: binaryName,
-				BinaryPath: binaryPath,
-				Spec:       input,
+		}
+		if existing, exists := r.inputSpecs[input.Name]; exists {
+			return fmt.Errorf("failed loading spec '%s': input '%s' already exists in spec '%s'", path, input.Name, existing.BinaryName)
+		}
+		if existing, exists := r.aliasMapping[input.Name]; exists {
+			return fmt.Errorf("failed loading spec '%s': input '%s' collides with an alias from another input '%s'", path, input.Name, existing)
+		}
+		for _, alias := range input.Aliases {
+			if existing, exists := r.inputSpecs[alias]; exists {
+				return fmt.Errorf("failed loading spec '%s': input alias '%s' collides with an already defined input in spec '%s'", path, alias, existing.BinaryName)
 			}
-			for _, alias := range input.Aliases {
-				inputAliases[alias] = input.Name
+			if existing, exists := r.aliasMapping[alias]; exists {
+				return fmt.Errorf("failed loading spec '%s': input alias '%s' collides with an already defined input alias for input '%s'", path, alias, existing)
 			}
 		}
+		r.inputSpecs[input.Name] = InputRuntimeSpec{
+			InputType:  input.Name,
+			BinaryName: binaryName,
+			BinaryPath: binaryPath,
+			Custom:     custom,
+			Spec:       input,
+		}
+		for _, alias := range input.Aliases {
+			r.aliasMapping[alias] = input.Name
+		}
 	}
-	return RuntimeSpecs{
-		platform:     platform,
-		inputTypes:   inputTypes,
-		inputSpecs:   inputSpecs,
-		aliasMapping: inputAliases,
-	}, nil
+	return nil
+}
+
+// checkBinary checks that the binary of the specification loaded from path exists.
+func checkBinary(path string, binaryPath string) error {
+	info, err := os.Stat(binaryPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("missing matching binary for %s", path)
+	} else if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", binaryPath, err)
+	} else if info.IsDir() {
+		return fmt.Errorf("missing matching binary for %s", path)
+	}
+	return nil
+}
+
+// clone returns a copy of the specifications that can be modified without modifying r.
+func (r *RuntimeSpecs) clone() RuntimeSpecs {
+	c := RuntimeSpecs{
+		platform:       r.platform,
+		inputTypes:     append([]string(nil), r.inputTypes...),
+		inputSpecs:     make(map[string]InputRuntimeSpec, len(r.inputSpecs)),
+		aliasMapping:   make(map[string]string, len(r.aliasMapping)),
+		userSpecErrors: r.userSpecErrors,
+	}
+	for k, v := range r.inputSpecs {
+		c.inputSpecs[k] = v
+	}
+	for k, v := range r.aliasMapping {
+		c.aliasMapping[k] = v
+	}
+	return c
 }
 
 // specFilesForDirectory loads all spec files in the target directory
@@ -257,6 +363,11 @@ func (r *RuntimeSpecs) GetInput(inputType string) (InputRuntimeSpec, error) {
 	return runtimeSpec, err
 }
 
+// UserSpecErrors returns the errors of the user specifications that were skipped, keyed by file path.
+func (r *RuntimeSpecs) UserSpecErrors() map[string]error {
+	return r.userSpecErrors
+}
+
 // ServiceSpecs returns only the input specification that are based on the service runtime.
 func (r *RuntimeSpecs) ServiceSpecs() []InputRuntimeSpec {
 	var services []InputRuntimeSpec
@@ -5,6 +5,7 @@
 package component
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -187,3 +188,57 @@ func TestLoadSpec_Components(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadRuntimeSpecs_UserSpecs(t *testing.T) {
+	const specFmt = `
+version: 2
+inputs:
+  - name: %s
+    description: "Custom collector"
+    platforms:
+      - linux/amd64
+    outputs:
+      - elasticsearch
+    command:
+      path: %q
+`
+	writeSpec := func(t *testing.T, path string, input string, binaryPath string) {
+		require.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(specFmt, input, binaryPath)), 0o644))
+	}
+	dir := t.TempDir()
+	userDir := t.TempDir()
+	binaryPath := filepath.Join(t.TempDir(), "collector")
+	require.NoError(t, os.WriteFile(binaryPath, []byte("binary"), 0o755))
+
+	writeSpec(t, filepath.Join(dir, "builtin.spec.yml"), "builtin", "")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "builtin"), []byte("binary"), 0o755))
+	writeSpec(t, filepath.Join(userDir, "collector.spec.yml"), "collector", binaryPath)
+	writeSpec(t, filepath.Join(userDir, "shadow.spec.yml"), "builtin", binaryPath)
+	writeSpec(t, filepath.Join(userDir, "missing.spec.yml"), "missing", "")
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "broken.spec.yml"), []byte("version: 1"), 0o644))
+
+	detail := PlatformDetail{Platform: Platform{OS: Linux, Arch: AMD64, GOOS: Linux}}
+	specs, err := LoadRuntimeSpecs(dir, detail, WithUserSpecs(userDir))
+	require.NoError(t, err, "a broken user specification is not fatal")
+	assert.ElementsMatch(t, []string{"builtin", "collector"}, specs.Inputs())
+
+	builtin, err := specs.GetInput("builtin")
+	require.NoError(t, err)
+	assert.False(t, builtin.Custom)
+	assert.Equal(t, filepath.Join(dir, "builtin"), builtin.BinaryPath)
+
+	collector, err := specs.GetInput("collector")
+	require.NoError(t, err)
+	assert.True(t, collector.Custom)
+	assert.Equal(t, "collector", collector.BinaryName)
+	assert.Equal(t, binaryPath, collector.BinaryPath)
+
+	errs := specs.UserSpecErrors()
+	assert.Len(t, errs, 3)
+	assert.ErrorContains(t, errs[filepath.Join(userDir, "shadow.spec.yml")], "input 'builtin' already exists in spec 'builtin'")
+	assert.ErrorContains(t, errs[filepath.Join(userDir, "missing.spec.yml")], "missing matching binary")
+	assert.ErrorContains(t, errs[filepath.Join(userDir, "broken.spec.yml")], "only version 2 is allowed")
+
+	_, err = LoadRuntimeSpecs(userDir, detail)
+	assert.Error(t, err, "the errors of the other specifications are fatal")
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package component
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent-libs/mapstr"
+)
+
+// liveOutputKeys are the keys of an output configuration the components opting in live output reload apply
+// without restarting: the credentials and the hosts.
+var liveOutputKeys = []string{
+	"hosts",
+	"api_key",
+	"username",
+	"password",
+	"service_token",
+	"ssl.certificate",
+	"ssl.key",
+	"ssl.key_passphrase",
+	"ssl.certificate_authorities",
+}
+
+// OutputChangeIsLive returns true when the output configuration only changed in its credentials or its hosts, the
+// components opting in live output reload apply such a change with the update of the output unit.
+func OutputChangeIsLive(prev *proto.UnitExpectedConfig, next *proto.UnitExpectedConfig) bool {
+	if prev == nil || next == nil || prev.Type != next.Type {
+		return false
+	}
+	return reflect.DeepEqual(withoutLiveOutputKeys(prev), withoutLiveOutputKeys(next))
+}
+
+// withoutLiveOutputKeys returns the flattened source of the output configuration without the keys applied live.
+func withoutLiveOutputKeys(cfg *proto.UnitExpectedConfig) mapstr.M {
+	flat := mapstr.M(cfg.GetSource().AsMap()).Flatten()
+	for key := range flat {
+		for _, live := range liveOutputKeys {
+			// lists are flattened as values, only the maps are flattened as keys
+			if key == live || strings.HasPrefix(key, live+".") {
+				delete(flat, key)
+				break
+			}
+		}
+	}
+	return flat
+}
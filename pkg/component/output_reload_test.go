@@ -0,0 +1,68 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package component
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputChangeIsLive(t *testing.T) {
+	prev := map[string]interface{}{
+		"type":          "elasticsearch",
+		"hosts":         []interface{}{"https://es-1:9200"},
+		"api_key":       "id:key",
+		"bulk_max_size": 1600,
+		"ssl": map[string]interface{}{
+			"certificate_authorities": []interface{}{"/etc/ca.pem"},
+			"verification_mode":       "full",
+		},
+	}
+	scenarios := map[string]struct {
+		next map[string]interface{}
+		live bool
+	}{
+		"unchanged": {
+			next: prev,
+			live: true,
+		},
+		"rotated api key and new hosts": {
+			next: map[string]interface{}{
+				"type":                        "elasticsearch",
+				"hosts":                       []interface{}{"https://es-1:9200", "https://es-2:9200"},
+				"api_key":                     "id:rotated",
+				"bulk_max_size":               1600,
+				"ssl.certificate_authorities": []interface{}{"/etc/new-ca.pem"},
+				"ssl.verification_mode":       "full",
+			},
+			live: true,
+		},
+		"changed queue": {
+			next: map[string]interface{}{
+				"type":          "elasticsearch",
+				"hosts":         []interface{}{"https://es-1:9200"},
+				"api_key":       "id:key",
+				"bulk_max_size": 3200,
+				"ssl": map[string]interface{}{
+					"certificate_authorities": []interface{}{"/etc/ca.pem"},
+					"verification_mode":       "full",
+				},
+			},
+		},
+		"changed type": {
+			next: map[string]interface{}{
+				"type":  "logstash",
+				"hosts": []interface{}{"logstash:5044"},
+			},
+		},
+	}
+	for name, s := range scenarios {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, s.live, OutputChangeIsLive(MustExpectedConfig(prev), MustExpectedConfig(s.next)))
+		})
+	}
+	assert.False(t, OutputChangeIsLive(nil, MustExpectedConfig(prev)), "a new output is not a live change")
+}
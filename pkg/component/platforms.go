@@ -7,7 +7,9 @@ package component
 import (
 	"fmt"
 	goruntime "runtime"
+	"strconv"
 	"strings"
+	"unicode"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/install/pkgmgr"
 
@@ -107,6 +109,27 @@ type UserDetail struct {
 	Root bool
 }
 
+// KernelDetail provides the kernel information of the running platform.
+type KernelDetail struct {
+	Version string
+	Major   int
+	Minor   int
+}
+
+// HostDetail provides host facts about the running platform.
+//
+// The facts are only gathered on the platforms that support them, otherwise they are left empty.
+type HostDetail struct {
+	Kernel KernelDetail
+	// CgroupVersion is the version of the mounted cgroup hierarchy (1 or 2), 0 when unknown.
+	CgroupVersion int
+	// SELinuxMode is the mode SELinux is running in ("enforcing", "permissive" or "disabled"),
+	// empty when SELinux is not supported.
+	SELinuxMode string
+	// WSL is true when running inside the Windows Subsystem for Linux.
+	WSL bool
+}
+
 // PlatformDetail is platform that has more detail information about the running platform.
 type PlatformDetail struct {
 	Platform
@@ -118,6 +141,7 @@ type PlatformDetail struct {
 
 	IsInstalledViaExternalPkgMgr bool
 	User                         UserDetail
+	Host                         HostDetail
 }
 
 // PlatformModifier can modify the platform details before the runtime specifications are loaded.
@@ -159,9 +183,35 @@ func LoadPlatformDetail(modifiers ...PlatformModifier) (PlatformDetail, error) {
 			Root: hasRoot,
 		},
 		IsInstalledViaExternalPkgMgr: pkgmgr.InstalledViaExternalPkgMgr(),
+		Host:                         loadHostDetail(info.Info().KernelVersion),
 	}
 	for _, modifier := range modifiers {
 		detail = modifier(detail)
 	}
 	return detail, nil
 }
+
+// parseKernelVersion parses the major and minor version from a kernel version string (e.g. 5.15.0-1023-aws).
+func parseKernelVersion(version string) KernelDetail {
+	detail := KernelDetail{Version: version}
+	pieces := strings.SplitN(version, ".", 3)
+	if len(pieces) < 2 {
+		return detail
+	}
+	major, err := strconv.Atoi(pieces[0])
+	if err != nil {
+		return detail
+	}
+	minorStr := pieces[1]
+	if idx := strings.IndexFunc(minorStr, func(r rune) bool { return !unicode.IsDigit(r) }); idx >= 0 {
+		// 2 component versions can have a suffix (e.g. 6.1-rc1)
+		minorStr = minorStr[:idx]
+	}
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return detail
+	}
+	detail.Major = major
+	detail.Minor = minor
+	return detail
+}
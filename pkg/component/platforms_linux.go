@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	cgroupRoot       = "/sys/fs/cgroup"
+	selinuxEnforce   = "/sys/fs/selinux/enforce"
+	kernelOSRelease  = "/proc/sys/kernel/osrelease"
+	wslInteropSocket = "/run/WSL"
+)
+
+// loadHostDetail gathers the host facts of the running Linux system.
+func loadHostDetail(kernelVersion string) HostDetail {
+	return HostDetail{
+		Kernel:        parseKernelVersion(kernelVersion),
+		CgroupVersion: cgroupVersion(cgroupRoot),
+		SELinuxMode:   selinuxMode(selinuxEnforce),
+		WSL:           isWSL(kernelOSRelease),
+	}
+}
+
+// cgroupVersion determines the version of the cgroup hierarchy mounted at root.
+func cgroupVersion(root string) int {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		// only the unified hierarchy has cgroup.controllers at the root
+		return 2
+	}
+	if _, err := os.Stat(root); err == nil {
+		return 1
+	}
+	return 0
+}
+
+// selinuxMode determines the SELinux mode from the enforce file of the selinuxfs.
+func selinuxMode(enforcePath string) string {
+	data, err := os.ReadFile(enforcePath)
+	if err != nil {
+		// selinuxfs is not mounted when SELinux is disabled
+		return "disabled"
+	}
+	switch strings.TrimSpace(string(data)) {
+	case "1":
+		return "enforcing"
+	case "0":
+		return "permissive"
+	}
+	return "disabled"
+}
+
+// isWSL determines if running inside the Windows Subsystem for Linux.
+func isWSL(osReleasePath string) bool {
+	if _, err := os.Stat(wslInteropSocket); err == nil {
+		return true
+	}
+	data, err := os.ReadFile(osReleasePath)
+	if err != nil {
+		return false
+	}
+	release := strings.ToLower(string(data))
+	return strings.Contains(release, "microsoft") || strings.Contains(release, "wsl")
+}
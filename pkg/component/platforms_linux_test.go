@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package component
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupVersion(t *testing.T) {
+	v1 := t.TempDir()
+	v2 := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(v2, "cgroup.controllers"), []byte("cpu memory"), 0o644))
+
+	assert.Equal(t, 1, cgroupVersion(v1))
+	assert.Equal(t, 2, cgroupVersion(v2))
+	assert.Equal(t, 0, cgroupVersion(filepath.Join(v1, "missing")))
+}
+
+func TestSELinuxMode(t *testing.T) {
+	dir := t.TempDir()
+	enforcing := filepath.Join(dir, "enforcing")
+	permissive := filepath.Join(dir, "permissive")
+	require.NoError(t, os.WriteFile(enforcing, []byte("1"), 0o644))
+	require.NoError(t, os.WriteFile(permissive, []byte("0\n"), 0o644))
+
+	assert.Equal(t, "enforcing", selinuxMode(enforcing))
+	assert.Equal(t, "permissive", selinuxMode(permissive))
+	assert.Equal(t, "disabled", selinuxMode(filepath.Join(dir, "missing")))
+}
+
+func TestIsWSL(t *testing.T) {
+	dir := t.TempDir()
+	wsl := filepath.Join(dir, "wsl")
+	native := filepath.Join(dir, "native")
+	require.NoError(t, os.WriteFile(wsl, []byte("5.15.153.1-microsoft-standard-WSL2\n"), 0o644))
+	require.NoError(t, os.WriteFile(native, []byte("6.8.0-45-generic\n"), 0o644))
+
+	assert.True(t, isWSL(wsl))
+	if _, err := os.Stat(wslInteropSocket); err != nil {
+		assert.False(t, isWSL(native))
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux
+
+package component
+
+// loadHostDetail gathers the host facts of the running system.
+//
+// Only the kernel version is available on non-Linux systems.
+func loadHostDetail(kernelVersion string) HostDetail {
+	return HostDetail{
+		Kernel: parseKernelVersion(kernelVersion),
+	}
+}
@@ -15,3 +15,19 @@ func TestLoadPlatformDetail(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, platformDetail)
 }
+
+func TestParseKernelVersion(t *testing.T) {
+	testcases := map[string]KernelDetail{
+		"5.15.0-1023-aws":                        {Version: "5.15.0-1023-aws", Major: 5, Minor: 15},
+		"6.1-rc1":                                {Version: "6.1-rc1", Major: 6, Minor: 1},
+		"5.15.153.1-microsoft-standard-WSL2":     {Version: "5.15.153.1-microsoft-standard-WSL2", Major: 5, Minor: 15},
+		"10.0.19045.2965 (WinBuild.160101.0800)": {Version: "10.0.19045.2965 (WinBuild.160101.0800)", Major: 10, Minor: 0},
+		"":                                       {},
+		"invalid":                                {Version: "invalid"},
+	}
+	for version, expected := range testcases {
+		t.Run(version, func(t *testing.T) {
+			assert.Equal(t, expected, parseKernelVersion(version))
+		})
+	}
+}
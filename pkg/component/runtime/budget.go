@@ -0,0 +1,94 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/docker/go-units"
+
+	"github.com/elastic/elastic-agent/pkg/limits"
+)
+
+const (
+	// EnforcementCgroup is the enforcement of a budget with a cgroup v2.
+	EnforcementCgroup = "cgroup"
+	// EnforcementJobObject is the enforcement of a budget with a Windows job object.
+	EnforcementJobObject = "job_object"
+	// EnforcementRlimit is the enforcement of the memory budget only with a resource limit.
+	EnforcementRlimit = "rlimit"
+	// EnforcementNone is reported when the budget cannot be enforced on the platform.
+	EnforcementNone = "none"
+)
+
+// BudgetState is the state of the resource budget of a component.
+type BudgetState struct {
+	// CPU is the number of CPUs the component can use, 0 when unlimited.
+	CPU float64 `yaml:"cpu,omitempty"`
+	// Memory is the memory the component can use in bytes, 0 when unlimited.
+	Memory uint64 `yaml:"memory,omitempty"`
+	// Enforcement is how the budget is enforced.
+	Enforcement string `yaml:"enforcement"`
+	// Throttled is true when the component was throttled for using its whole CPU budget since the
+	// previous check-in period.
+	Throttled bool `yaml:"throttled"`
+	// MemoryKills is the number of times the component was killed for exceeding its memory budget.
+	MemoryKills uint64 `yaml:"memory_kills"`
+}
+
+// budgetEnforcer enforces the resource budget of the process of a component.
+type budgetEnforcer interface {
+	// enforcement returns how the budget is enforced.
+	enforcement() string
+	// update applies a new budget to the process.
+	update(budget resourceBudget) error
+	// check returns whether the process was throttled and the number of times it was killed for
+	// exceeding its memory budget since the previous check.
+	check() (throttled bool, memoryKills uint64)
+	// close releases the resources of the enforcement once the process exited.
+	close() error
+}
+
+// resourceBudget is a parsed budget.
+type resourceBudget struct {
+	cpu    float64
+	memory uint64
+}
+
+func newResourceBudget(budget *limits.ComponentBudget) (resourceBudget, error) {
+	if budget == nil {
+		return resourceBudget{}, nil
+	}
+	memory, err := budget.MemoryBytes()
+	if err != nil {
+		return resourceBudget{}, err
+	}
+	return resourceBudget{cpu: budget.CPU, memory: memory}, nil
+}
+
+func (b resourceBudget) isZero() bool {
+	return b.cpu == 0 && b.memory == 0
+}
+
+// String returns a readable description of the budget.
+func (b resourceBudget) String() string {
+	switch {
+	case b.cpu > 0 && b.memory > 0:
+		return fmt.Sprintf("%s CPU and %s of memory", strconv.FormatFloat(b.cpu, 'f', -1, 64), units.BytesSize(float64(b.memory)))
+	case b.cpu > 0:
+		return fmt.Sprintf("%s CPU", strconv.FormatFloat(b.cpu, 'f', -1, 64))
+	default:
+		return units.BytesSize(float64(b.memory)) + " of memory"
+	}
+}
+
+// noBudgetEnforcer reports the budgets that cannot be enforced on the platform.
+type noBudgetEnforcer struct{}
+
+func (noBudgetEnforcer) enforcement() string         { return EnforcementNone }
+func (noBudgetEnforcer) update(resourceBudget) error { return nil }
+func (noBudgetEnforcer) check() (bool, uint64)       { return false, 0 }
+func (noBudgetEnforcer) close() error                { return nil }
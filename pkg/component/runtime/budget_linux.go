@@ -0,0 +1,220 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	cgroupMountPath = "/sys/fs/cgroup"
+	// cgroupCPUPeriod is the period of the CPU bandwidth of the components in microseconds.
+	cgroupCPUPeriod = 100000
+	// agentCgroupName is the leaf cgroup the processes of the agent are moved to, a cgroup v2 with
+	// controllers enabled for its children cannot hold processes itself.
+	agentCgroupName = "agent"
+)
+
+var (
+	cgroupBaseOnce sync.Once
+	cgroupBase     string
+	cgroupBaseErr  error
+)
+
+// newBudgetEnforcer enforces the budget on the process with a cgroup v2 child of the cgroup of the agent,
+// or only the memory budget with RLIMIT_DATA when the cgroup cannot be delegated to the agent.
+func newBudgetEnforcer(id string, pid int, budget resourceBudget) (budgetEnforcer, error) {
+	cgroupBaseOnce.Do(func() {
+		cgroupBase, cgroupBaseErr = delegateCgroup()
+	})
+	if cgroupBaseErr == nil {
+		e := &cgroupEnforcer{path: filepath.Join(cgroupBase, "component-"+strings.ReplaceAll(id, "/", "-"))}
+		err := e.start(pid, budget)
+		if err == nil {
+			return e, nil
+		}
+		_ = e.close()
+		return nil, err
+	}
+	if budget.memory == 0 {
+		return nil, fmt.Errorf("CPU budget requires a cgroup v2: %w", cgroupBaseErr)
+	}
+	e := &rlimitEnforcer{pid: pid}
+	if err := e.update(budget); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// delegateCgroup prepares the cgroup v2 of the agent to hold the cgroups of the components and returns its path.
+// The processes of the cgroup are moved to a leaf cgroup, then the cpu and memory controllers are enabled for
+// its children.
+func delegateCgroup() (string, error) {
+	if _, err := os.Stat(filepath.Join(cgroupMountPath, "cgroup.controllers")); err != nil {
+		return "", fmt.Errorf("cgroup v2 not mounted: %w", err)
+	}
+	self, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("failed to read the cgroup of the agent: %w", err)
+	}
+	var relative string
+	for _, line := range strings.Split(string(self), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			relative = path
+			break
+		}
+	}
+	if relative == "" {
+		return "", errors.New("the agent is not in a cgroup v2")
+	}
+	base := filepath.Join(cgroupMountPath, relative)
+	if filepath.Base(base) == agentCgroupName {
+		// already moved by a previous run of the agent re-executed in place
+		base = filepath.Dir(base)
+	}
+
+	leaf := filepath.Join(base, agentCgroupName)
+	if err := os.Mkdir(leaf, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("failed to create the cgroup of the agent: %w", err)
+	}
+	procs, err := os.ReadFile(filepath.Join(base, "cgroup.procs"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the processes of the cgroup of the agent: %w", err)
+	}
+	for _, pid := range strings.Fields(string(procs)) {
+		if err := os.WriteFile(filepath.Join(leaf, "cgroup.procs"), []byte(pid), 0); err != nil && !errors.Is(err, unix.ESRCH) {
+			return "", fmt.Errorf("failed to move pid %s to the cgroup of the agent: %w", pid, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(base, "cgroup.subtree_control"), []byte("+cpu +memory"), 0); err != nil {
+		return "", fmt.Errorf("failed to enable the cpu and memory controllers: %w", err)
+	}
+	return base, nil
+}
+
+// cgroupEnforcer enforces the budget with a cgroup v2 holding the process of the component.
+type cgroupEnforcer struct {
+	path string
+
+	throttled   uint64
+	memoryKills uint64
+}
+
+func (e *cgroupEnforcer) start(pid int, budget resourceBudget) error {
+	if err := os.Mkdir(e.path, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("failed to create the cgroup of the component: %w", err)
+	}
+	if err := e.update(budget); err != nil {
+		return err
+	}
+	// start counting from the current values, the cgroup may be reused from a previous process
+	e.throttled, _ = readCgroupStat(filepath.Join(e.path, "cpu.stat"), "nr_throttled")
+	e.memoryKills, _ = readCgroupStat(filepath.Join(e.path, "memory.events"), "oom_kill")
+	if err := os.WriteFile(filepath.Join(e.path, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0); err != nil {
+		return fmt.Errorf("failed to move pid %d to the cgroup of the component: %w", pid, err)
+	}
+	return nil
+}
+
+func (e *cgroupEnforcer) enforcement() string {
+	return EnforcementCgroup
+}
+
+func (e *cgroupEnforcer) update(budget resourceBudget) error {
+	cpuMax := "max " + strconv.Itoa(cgroupCPUPeriod)
+	if budget.cpu > 0 {
+		cpuMax = fmt.Sprintf("%d %d", max(int(budget.cpu*cgroupCPUPeriod), 1000), cgroupCPUPeriod)
+	}
+	if err := os.WriteFile(filepath.Join(e.path, "cpu.max"), []byte(cpuMax), 0); err != nil {
+		return fmt.Errorf("failed to set the CPU budget: %w", err)
+	}
+	memoryMax := "max"
+	if budget.memory > 0 {
+		memoryMax = strconv.FormatUint(budget.memory, 10)
+	}
+	if err := os.WriteFile(filepath.Join(e.path, "memory.max"), []byte(memoryMax), 0); err != nil {
+		return fmt.Errorf("failed to set the memory budget: %w", err)
+	}
+	return nil
+}
+
+func (e *cgroupEnforcer) check() (bool, uint64) {
+	var throttled bool
+	if n, err := readCgroupStat(filepath.Join(e.path, "cpu.stat"), "nr_throttled"); err == nil {
+		throttled = n > e.throttled
+		e.throttled = n
+	}
+	var kills uint64
+	if n, err := readCgroupStat(filepath.Join(e.path, "memory.events"), "oom_kill"); err == nil && n > e.memoryKills {
+		kills = n - e.memoryKills
+		e.memoryKills = n
+	}
+	return throttled, kills
+}
+
+func (e *cgroupEnforcer) close() error {
+	// fails while the cgroup still holds processes, it is reused by the next process of the component
+	if err := os.Remove(e.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// readCgroupStat reads a counter from a flat keyed cgroup file like cpu.stat.
+func readCgroupStat(path string, key string) (uint64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+	return 0, fmt.Errorf("%s not found in %s", key, path)
+}
+
+// rlimitEnforcer enforces the memory budget only, with the RLIMIT_DATA of the process. The process fails to
+// allocate memory past its budget instead of being killed, so no kill is reported.
+type rlimitEnforcer struct {
+	pid int
+}
+
+func (e *rlimitEnforcer) enforcement() string {
+	return EnforcementRlimit
+}
+
+func (e *rlimitEnforcer) update(budget resourceBudget) error {
+	limit := uint64(unix.RLIM_INFINITY)
+	if budget.memory > 0 {
+		limit = budget.memory
+	}
+	if err := unix.Prlimit(e.pid, unix.RLIMIT_DATA, &unix.Rlimit{Cur: limit, Max: limit}, nil); err != nil {
+		return fmt.Errorf("failed to set the memory budget: %w", err)
+	}
+	return nil
+}
+
+func (e *rlimitEnforcer) check() (bool, uint64) {
+	return false, 0
+}
+
+func (e *rlimitEnforcer) close() error {
+	return nil
+}
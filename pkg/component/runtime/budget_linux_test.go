@@ -0,0 +1,56 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package runtime
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgroupEnforcer(t *testing.T) {
+	// the cgroup files are plain files outside of the cgroup filesystem
+	path := filepath.Join(t.TempDir(), "component-filestream-default")
+	require.NoError(t, os.Mkdir(path, 0o755))
+	writeStat := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(path, name), []byte(content), 0o644))
+	}
+	readFile := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(path, name))
+		require.NoError(t, err)
+		return string(data)
+	}
+	writeStat("cpu.stat", "usage_usec 100\nnr_periods 10\nnr_throttled 2\nthrottled_usec 50\n")
+	writeStat("memory.events", "low 0\nhigh 0\nmax 0\noom 1\noom_kill 1\n")
+
+	e := &cgroupEnforcer{path: path}
+	require.NoError(t, e.start(1234, resourceBudget{cpu: 0.5, memory: 256 * 1024 * 1024}))
+	assert.Equal(t, "50000 100000", readFile("cpu.max"))
+	assert.Equal(t, "268435456", readFile("memory.max"))
+	assert.Equal(t, "1234", readFile("cgroup.procs"))
+
+	throttled, kills := e.check()
+	assert.False(t, throttled, "the counters before the start should not be reported")
+	assert.Zero(t, kills)
+
+	writeStat("cpu.stat", "usage_usec 200\nnr_periods 20\nnr_throttled 5\nthrottled_usec 90\n")
+	writeStat("memory.events", "low 0\nhigh 0\nmax 3\noom 2\noom_kill 2\n")
+	throttled, kills = e.check()
+	assert.True(t, throttled)
+	assert.Equal(t, uint64(1), kills)
+
+	throttled, kills = e.check()
+	assert.False(t, throttled, "no throttling since the previous check")
+	assert.Zero(t, kills)
+
+	require.NoError(t, e.update(resourceBudget{}))
+	assert.Equal(t, "max 100000", readFile("cpu.max"))
+	assert.Equal(t, "max", readFile("memory.max"))
+}
@@ -0,0 +1,13 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux && !windows
+
+package runtime
+
+// newBudgetEnforcer reports the budget as not enforced, the resources of another process cannot be limited
+// once it runs on this platform.
+func newBudgetEnforcer(string, int, resourceBudget) (budgetEnforcer, error) {
+	return noBudgetEnforcer{}, nil
+}
@@ -0,0 +1,17 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceBudgetString(t *testing.T) {
+	assert.Equal(t, "0.5 CPU and 512MiB of memory", resourceBudget{cpu: 0.5, memory: 512 * 1024 * 1024}.String())
+	assert.Equal(t, "2 CPU", resourceBudget{cpu: 2}.String())
+	assert.Equal(t, "1GiB of memory", resourceBudget{memory: 1024 * 1024 * 1024}.String())
+}
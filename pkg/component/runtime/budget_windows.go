@@ -0,0 +1,150 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package runtime
+
+import (
+	"fmt"
+	goruntime "runtime"
+	"time"
+	"unsafe"
+
+	winsys "golang.org/x/sys/windows"
+)
+
+const (
+	jobObjectCPURateControlEnable  = 0x1
+	jobObjectCPURateControlHardCap = 0x4
+
+	// throttledRatio is the ratio of the CPU budget above which the process is considered throttled.
+	throttledRatio = 0.95
+)
+
+// jobObjectCPURateControlInformation is JOBOBJECT_CPU_RATE_CONTROL_INFORMATION with the CpuRate member.
+type jobObjectCPURateControlInformation struct {
+	ControlFlags uint32
+	CPURate      uint32
+}
+
+// jobObjectBasicAccountingInformation is JOBOBJECT_BASIC_ACCOUNTING_INFORMATION.
+type jobObjectBasicAccountingInformation struct {
+	TotalUserTime             int64
+	TotalKernelTime           int64
+	ThisPeriodTotalUserTime   int64
+	ThisPeriodTotalKernelTime int64
+	TotalPageFaultCount       uint32
+	TotalProcesses            uint32
+	ActiveProcesses           uint32
+	TotalTerminatedProcesses  uint32
+}
+
+// newBudgetEnforcer enforces the budget with a job object holding the process, nested in the job object of
+// the agent.
+func newBudgetEnforcer(_ string, pid int, budget resourceBudget) (budgetEnforcer, error) {
+	h, err := winsys.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the job object of the component: %w", err)
+	}
+	e := &jobObjectEnforcer{job: h, lastCheck: time.Now()}
+	if err := e.update(budget); err != nil {
+		_ = e.close()
+		return nil, err
+	}
+
+	process, err := winsys.OpenProcess(winsys.PROCESS_SET_QUOTA|winsys.PROCESS_TERMINATE, false, uint32(pid)) //nolint:gosec // G115 pid is positive
+	if err != nil {
+		_ = e.close()
+		return nil, fmt.Errorf("opening process handle: %w", err)
+	}
+	defer winsys.CloseHandle(process) //nolint:errcheck // No way to handle errors returned here so safe to ignore.
+	if err := winsys.AssignProcessToJobObject(h, process); err != nil {
+		_ = e.close()
+		return nil, fmt.Errorf("assigning to the job object of the component: %w", err)
+	}
+	return e, nil
+}
+
+// jobObjectEnforcer enforces the budget with the limits of a job object.
+type jobObjectEnforcer struct {
+	job    winsys.Handle
+	budget resourceBudget
+
+	lastCheck   time.Time
+	lastCPUTime int64
+	memoryKills uint64
+}
+
+func (e *jobObjectEnforcer) enforcement() string {
+	return EnforcementJobObject
+}
+
+func (e *jobObjectEnforcer) update(budget resourceBudget) error {
+	var memory winsys.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	if budget.memory > 0 {
+		memory.BasicLimitInformation.LimitFlags = winsys.JOB_OBJECT_LIMIT_JOB_MEMORY
+		memory.JobMemoryLimit = uintptr(budget.memory)
+	}
+	if _, err := winsys.SetInformationJobObject(e.job, winsys.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&memory)), uint32(unsafe.Sizeof(memory))); err != nil {
+		return fmt.Errorf("failed to set the memory budget: %w", err)
+	}
+
+	// the rate is the share of the CPU of the whole machine, in hundredths of percent
+	var cpu jobObjectCPURateControlInformation
+	if budget.cpu > 0 {
+		rate := budget.cpu / float64(goruntime.NumCPU()) * 10000
+		cpu.ControlFlags = jobObjectCPURateControlEnable | jobObjectCPURateControlHardCap
+		cpu.CPURate = uint32(min(max(rate, 1), 10000))
+	}
+	if _, err := winsys.SetInformationJobObject(e.job, winsys.JobObjectCpuRateControlInformation,
+		uintptr(unsafe.Pointer(&cpu)), uint32(unsafe.Sizeof(cpu))); err != nil {
+		return fmt.Errorf("failed to set the CPU budget: %w", err)
+	}
+	e.budget = budget
+	return nil
+}
+
+// check reports the process as throttled when it used nearly all of its CPU budget since the previous check,
+// job objects have no throttling counter. A job object only fails the allocations above its memory limit, so
+// the process reaching its memory budget is terminated, like the OOM kill of a cgroup, and reported as a kill.
+func (e *jobObjectEnforcer) check() (bool, uint64) {
+	now := time.Now()
+	var throttled bool
+	var accounting jobObjectBasicAccountingInformation
+	if err := winsys.QueryInformationJobObject(e.job, winsys.JobObjectBasicAccountingInformation,
+		uintptr(unsafe.Pointer(&accounting)), uint32(unsafe.Sizeof(accounting)), nil); err == nil {
+		cpuTime := accounting.TotalUserTime + accounting.TotalKernelTime
+		if elapsed := now.Sub(e.lastCheck); e.budget.cpu > 0 && elapsed > 0 {
+			// the times are in 100ns units
+			used := time.Duration(cpuTime-e.lastCPUTime) * 100
+			throttled = used.Seconds() >= elapsed.Seconds()*e.budget.cpu*throttledRatio
+		}
+		e.lastCPUTime = cpuTime
+	}
+	e.lastCheck = now
+
+	var kills uint64
+	var limit winsys.JOBOBJECT_EXTENDED_LIMIT_INFORMATION
+	if err := winsys.QueryInformationJobObject(e.job, winsys.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&limit)), uint32(unsafe.Sizeof(limit)), nil); err == nil {
+		if e.budget.memory > 0 && e.memoryKills == 0 && uint64(limit.PeakJobMemoryUsed) >= e.budget.memory {
+			if err := winsys.TerminateJobObject(e.job, 1); err == nil {
+				kills = 1
+				e.memoryKills = 1
+			}
+		}
+	}
+	return throttled, kills
+}
+
+func (e *jobObjectEnforcer) close() error {
+	if e.job == 0 {
+		return nil
+	}
+	err := winsys.CloseHandle(e.job)
+	e.job = 0
+	return err
+}
@@ -11,16 +11,19 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"time"
 
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/time/rate"
+	gproto "google.golang.org/protobuf/proto"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/core/process"
@@ -106,6 +109,22 @@ type commandRuntime struct {
 	actionCh chan actionMode
 
 	proc *process.Info
+	// budget enforces the resource budget of the component on proc, nil without budget.
+	budget budgetEnforcer
+
+	// healthCheckCh receives the results of the probes of the health check of the component, they are handled
+	// by (*commandRuntime).Run.
+	healthCheckCh chan healthCheckResult
+	// healthCheckCancel stops probing proc, nil without health check.
+	healthCheckCancel context.CancelFunc
+
+	// drainTimer bounds the drain of the units before proc is stopped, nil when not draining.
+	drainTimer *time.Timer
+	// drainingOutputs is true once the input units stopped and the output units are draining.
+	drainingOutputs bool
+
+	// restartReason is why proc is stopped to restart it with its changed component, empty when it is not.
+	restartReason string
 
 	state          ComponentState
 	lastCheckin    time.Time
@@ -116,15 +135,16 @@ type commandRuntime struct {
 // newCommandRuntime creates a new command runtime for the provided component.
 func newCommandRuntime(comp component.Component, log *logger.Logger, monitor MonitoringManager) (*commandRuntime, error) {
 	c := &commandRuntime{
-		log:         log,
-		current:     comp,
-		monitor:     monitor,
-		ch:          make(chan ComponentState),
-		actionCh:    make(chan actionMode, 1),
-		procCh:      make(chan procState),
-		compCh:      make(chan component.Component, 1),
-		actionState: actionStop,
-		state:       newComponentState(&comp),
+		log:           log,
+		current:       comp,
+		monitor:       monitor,
+		ch:            make(chan ComponentState),
+		actionCh:      make(chan actionMode, 1),
+		procCh:        make(chan procState),
+		healthCheckCh: make(chan healthCheckResult),
+		compCh:        make(chan component.Component, 1),
+		actionState:   actionStop,
+		state:         newComponentState(&comp),
 	}
 	cmdSpec := c.getCommandSpec()
 	if cmdSpec == nil {
@@ -152,6 +172,8 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 	c.forceCompState(client.UnitStateStarting, "Starting")
 	t := time.NewTicker(checkinPeriod)
 	defer t.Stop()
+	defer c.stopHealthCheck()
+	defer c.stopDrain()
 	for {
 		select {
 		case <-ctx.Done():
@@ -160,11 +182,16 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 			c.actionState = as
 			switch as {
 			case actionStart:
+				c.cancelDrain(comm)
 				if err := c.start(comm); err != nil {
 					c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
 				}
 				t.Reset(checkinPeriod)
 			case actionStop, actionTeardown:
+				if c.startDrain(comm) {
+					// stopped once drained
+					continue
+				}
 				if err := c.stop(ctx); err != nil {
 					c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
 				}
@@ -173,14 +200,23 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 			// ignores old processes
 			if ps.proc == c.proc {
 				c.proc = nil
-				if c.handleProc(ps.state) {
+				c.state.Pid = 0
+				c.stopHealthCheck()
+				c.stopDrain()
+				killed := c.releaseBudget()
+				if c.handleProc(ps.state, killed) {
 					// start again after restart period
 					t.Reset(restartPeriod)
 				}
 			}
 		case newComp := <-c.compCh:
+			budgetChanged := !reflect.DeepEqual(c.current.Budget, newComp.Budget)
+			restartReason := c.restartReasonFor(&newComp)
 			c.current = newComp
 			c.syncLogLevels()
+			if budgetChanged && c.updateBudget() {
+				c.sendObserved()
+			}
 
 			sendExpected := c.state.syncExpected(&newComp)
 			changed := c.state.syncUnits(&newComp)
@@ -191,6 +227,13 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 			if changed {
 				c.sendObserved()
 			}
+			if restartReason != "" {
+				c.log.Infof("Restarting component %s for %s", c.current.ID, restartReason)
+				c.restartReason = restartReason
+				if err := c.stop(ctx); err != nil {
+					c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+				}
+			}
 		case checkin := <-comm.CheckinObserved():
 			sendExpected := false
 			changed := false
@@ -226,6 +269,18 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 			if c.state.cleanupStopped() {
 				c.sendObserved()
 			}
+			if c.drainTimer != nil && c.continueDrain(comm) {
+				c.log.Infof("Component %s is drained, stopping pid '%d'", c.current.ID, c.proc.PID)
+				c.finishDrain(ctx)
+			}
+		case <-c.drainTimeout():
+			c.log.Warnf("Component %s did not drain within %s, stopping pid '%d'", c.current.ID, c.getCommandSpec().Timeouts.Drain, c.proc.PID)
+			c.finishDrain(ctx)
+		case res := <-c.healthCheckCh:
+			// ignores old processes
+			if res.proc == c.proc {
+				c.handleHealthCheck(res)
+			}
 		case <-t.C:
 			t.Reset(checkinPeriod)
 			if c.actionState == actionStart {
@@ -242,6 +297,7 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 					// UTC(), Round(), AddDate(), etc. remove the
 					// monotonic clock.  See
 					// https://pkg.go.dev/time
+					c.checkBudget()
 					now := time.Now()
 					if now.Sub(c.lastCheckin) <= checkinPeriod {
 						c.missedCheckins = 0
@@ -249,10 +305,8 @@ func (c *commandRuntime) Run(ctx context.Context, comm Communicator) error {
 						c.missedCheckins++
 						c.log.Debugf("Last check-in was: %s, now is: %s. The diff %s is higher than allowed %s.", c.lastCheckin.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano), now.Sub(c.lastCheckin), checkinPeriod)
 					}
-					if c.missedCheckins == 0 {
-						c.compState(client.UnitStateHealthy)
-					} else if c.missedCheckins > 0 && c.missedCheckins < maxCheckinMisses {
-						c.compState(client.UnitStateDegraded)
+					if c.missedCheckins < maxCheckinMisses {
+						c.compState(c.checkinState())
 					} else if c.missedCheckins >= maxCheckinMisses {
 						// something is wrong; the command should be checking in
 						//
@@ -339,6 +393,9 @@ func (c *commandRuntime) compState(state client.UnitState) {
 	msg := stateUnknownMessage
 	if state == client.UnitStateHealthy {
 		msg = fmt.Sprintf("Healthy: communicating with pid '%d'", c.proc.PID)
+		if c.state.Budget != nil && c.state.Budget.Throttled {
+			msg += ", CPU throttled by its budget"
+		}
 	} else if state == client.UnitStateDegraded {
 		if c.missedCheckins == 1 {
 			msg = fmt.Sprintf("Degraded: pid '%d' missed 1 check-in", c.proc.PID)
@@ -346,11 +403,20 @@ func (c *commandRuntime) compState(state client.UnitState) {
 			msg = fmt.Sprintf("Degraded: pid '%d' missed %d check-ins", c.proc.PID, c.missedCheckins)
 		}
 	}
+	state, msg = c.state.healthCheckState(state, msg)
 	if c.state.compState(state, msg) {
 		c.sendObserved()
 	}
 }
 
+// checkinState returns the state of the component from its check-ins.
+func (c *commandRuntime) checkinState() client.UnitState {
+	if c.missedCheckins == 0 {
+		return client.UnitStateHealthy
+	}
+	return client.UnitStateDegraded
+}
+
 func (c *commandRuntime) sendObserved() {
 	c.ch <- c.state.Copy()
 }
@@ -365,6 +431,11 @@ func (c *commandRuntime) start(comm Communicator) error {
 	for _, e := range cmdSpec.Env {
 		env = append(env, fmt.Sprintf("%s=%s", e.Name, e.Value))
 	}
+	override := c.current.CommandOverride
+	if override != nil {
+		// the environment of the policy takes precedence over the one of the spec
+		env = append(env, override.EnvList()...)
+	}
 	env = append(env, fmt.Sprintf("%s=%s", envAgentComponentID, c.current.ID))
 	env = append(env, fmt.Sprintf("%s=%s", envAgentComponentType, c.getSpecType()))
 	uid, gid := os.Geteuid(), os.Getegid()
@@ -372,38 +443,66 @@ func (c *commandRuntime) start(comm Communicator) error {
 	if err != nil {
 		return err
 	}
-	path, err := filepath.Abs(c.getSpecBinaryPath())
-	if err != nil {
-		return fmt.Errorf("failed to determine absolute path: %w", err)
-	}
-	err = utils.HasStrictExecPerms(path, uid)
-	if err != nil {
-		return fmt.Errorf("execution of component prevented: %w", err)
+	image := c.getImageSpec()
+	var path string
+	if image == nil {
+		path, err = filepath.Abs(c.getSpecBinaryPath())
+		if err != nil {
+			return fmt.Errorf("failed to determine absolute path: %w", err)
+		}
+		err = utils.HasStrictExecPerms(path, uid)
+		if err != nil {
+			return fmt.Errorf("execution of component prevented: %w", err)
+		}
 	}
 
 	if err := c.monitor.Prepare(c.current.ID); err != nil {
 		return err
 	}
-	args := c.monitor.EnrichArgs(c.current.ID, c.getSpecBinaryName(), cmdSpec.Args)
+	args := cmdSpec.Args
+	if override != nil && len(override.Args) > 0 {
+		args = append(append([]string{}, args...), override.Args...)
+	}
+	if c.current.InputSpec == nil || !c.current.InputSpec.Custom {
+		args = c.monitor.EnrichArgs(c.current.ID, c.getSpecBinaryName(), args)
 
-	// differentiate data paths
-	dataPath := filepath.Join(paths.Run(), c.current.ID)
-	_ = os.MkdirAll(dataPath, 0755)
-	args = append(args, "-E", "path.data="+dataPath)
+		// differentiate data paths
+		dataPath := filepath.Join(paths.Run(), c.current.ID)
+		_ = os.MkdirAll(dataPath, 0755)
+		args = append(args, "-E", "path.data="+dataPath)
+	}
+
+	if image != nil {
+		path, args, err = c.containerCommand(image, args, env, workDir)
+		if err != nil {
+			return fmt.Errorf("failed to prepare the container of the component: %w", err)
+		}
+	}
 
 	// reset checkin state before starting the process.
 	c.lastCheckin = time.Time{}
 	c.missedCheckins = 0
 
+	cmdDir := workDir
+	if image == nil && override != nil && override.WorkingDirectory != "" {
+		cmdDir = override.WorkingDirectory
+	}
+	cmdOpts := []process.CmdOption{attachOutErr(c.logStd, c.logErr), dirPath(cmdDir)}
+	if image == nil && cmdSpec.Sandbox.CleanEnv {
+		cmdOpts = append(cmdOpts, cleanEnv(env))
+	}
 	proc, err := process.Start(path,
 		process.WithArgs(args),
 		process.WithEnv(env),
-		process.WithCmdOptions(attachOutErr(c.logStd, c.logErr), dirPath(workDir)))
+		process.WithCmdOptions(cmdOpts...))
 	if err != nil {
 		return err
 	}
 
 	c.proc = proc
+	c.state.Pid = uint64(proc.PID) //nolint:gosec // G115 pid is positive
+	c.applyBudget(proc.PID)
+	c.startHealthCheck(proc, env)
 	c.forceCompState(client.UnitStateStarting, fmt.Sprintf("Starting: spawned pid '%d'", c.proc.PID))
 	c.startWatcher(proc, comm)
 	return nil
@@ -459,10 +558,22 @@ func (c *commandRuntime) startWatcher(info *process.Info, comm Communicator) {
 	}()
 }
 
-func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
+// handleProc handles the exit of the process, killed is true when the process was killed for exceeding its
+// memory budget.
+func (c *commandRuntime) handleProc(state *os.ProcessState, killed bool) bool {
+	restartReason := c.restartReason
+	c.restartReason = ""
 	switch c.actionState {
 	case actionStart:
-		if c.restartBucket != nil && c.restartBucket.Allow() {
+		agentmetrics.ComponentRestarted(c.current.ID)
+		if restartReason != "" {
+			stopMsg := fmt.Sprintf("Stopped: pid '%d' restarted for %s", state.Pid(), restartReason)
+			c.forceCompState(client.UnitStateStopped, stopMsg)
+		} else if killed {
+			// always reported, the component keeps being killed until its budget is raised
+			stopMsg := fmt.Sprintf("Failed: pid '%d' killed for exceeding its memory budget", state.Pid())
+			c.forceCompState(client.UnitStateFailed, stopMsg)
+		} else if c.restartBucket != nil && c.restartBucket.Allow() {
 			stopMsg := fmt.Sprintf("Suppressing FAILED state due to restart for '%d' exited with code '%d'", state.Pid(), state.ExitCode())
 			c.forceCompState(client.UnitStateStopped, stopMsg)
 		} else {
@@ -483,6 +594,130 @@ func (c *commandRuntime) handleProc(state *os.ProcessState) bool {
 	return false
 }
 
+// restartReasonFor returns why the running component must restart to apply newComp, empty when newComp is
+// applied live.
+func (c *commandRuntime) restartReasonFor(newComp *component.Component) string {
+	if c.proc == nil || c.restartReason != "" {
+		return ""
+	}
+	if !reflect.DeepEqual(c.current.CommandOverride, newComp.CommandOverride) {
+		return "its command change"
+	}
+	if c.outputRestartRequired(newComp) {
+		return "its output change"
+	}
+	return ""
+}
+
+// outputRestartRequired returns true when the running component must restart to apply the change of its output,
+// the components opting in live output reload apply the changes of the credentials and of the hosts with the
+// update of the output unit.
+func (c *commandRuntime) outputRestartRequired(newComp *component.Component) bool {
+	cmdSpec := c.getCommandSpec()
+	if cmdSpec == nil || !cmdSpec.RestartOnOutputChange || c.proc == nil {
+		return false
+	}
+	for _, unit := range newComp.Units {
+		if unit.Type != client.UnitTypeOutput {
+			continue
+		}
+		expected, ok := c.state.expectedUnits[ComponentUnitKey{UnitType: unit.Type, UnitID: unit.ID}]
+		if !ok || expected.config == nil || gproto.Equal(expected.config, unit.Config) {
+			continue
+		}
+		if !cmdSpec.LiveOutputReload || !component.OutputChangeIsLive(expected.config, unit.Config) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyBudget enforces the resource budget of the component on its new process. The component runs without
+// its budget when it cannot be enforced, which is reported in its state.
+func (c *commandRuntime) applyBudget(pid int) {
+	c.budget = nil
+	c.state.Budget = nil
+	budget, err := newResourceBudget(c.current.Budget)
+	if err != nil {
+		c.log.Warnf("invalid resource budget for component %s: %v", c.current.ID, err)
+		return
+	}
+	if budget.isZero() {
+		return
+	}
+	enforcer, err := newBudgetEnforcer(c.current.ID, pid, budget)
+	if err != nil {
+		c.log.Warnf("failed to enforce the resource budget of %s on pid '%d': %v", budget, pid, err)
+		enforcer = noBudgetEnforcer{}
+	}
+	c.budget = enforcer
+	c.state.Budget = &BudgetState{
+		CPU:         budget.cpu,
+		Memory:      budget.memory,
+		Enforcement: enforcer.enforcement(),
+	}
+}
+
+// updateBudget applies the new resource budget of the component to its running process, it returns true when
+// the state changed.
+func (c *commandRuntime) updateBudget() bool {
+	if c.proc == nil {
+		// applied when the process starts
+		return false
+	}
+	if c.budget == nil {
+		c.applyBudget(c.proc.PID)
+		return c.state.Budget != nil
+	}
+	budget, err := newResourceBudget(c.current.Budget)
+	if err != nil {
+		c.log.Warnf("invalid resource budget for component %s: %v", c.current.ID, err)
+		return false
+	}
+	if err := c.budget.update(budget); err != nil {
+		c.log.Warnf("failed to update the resource budget of %s on pid '%d': %v", budget, c.proc.PID, err)
+		return false
+	}
+	if budget.isZero() {
+		c.state.Budget = nil
+	} else {
+		c.state.Budget.CPU = budget.cpu
+		c.state.Budget.Memory = budget.memory
+	}
+	return true
+}
+
+// checkBudget records whether the process was throttled or killed since the previous check.
+func (c *commandRuntime) checkBudget() {
+	if c.budget == nil || c.state.Budget == nil {
+		return
+	}
+	throttled, kills := c.budget.check()
+	if throttled != c.state.Budget.Throttled || kills > 0 {
+		c.state.Budget.Throttled = throttled
+		c.state.Budget.MemoryKills += kills
+		c.sendObserved()
+	}
+}
+
+// releaseBudget releases the enforcement of the budget of the exited process, it returns true when the
+// process was killed for exceeding its memory budget.
+func (c *commandRuntime) releaseBudget() bool {
+	if c.budget == nil {
+		return false
+	}
+	_, kills := c.budget.check()
+	if err := c.budget.close(); err != nil {
+		c.log.Debugf("failed to release the resource budget of component %s: %v", c.current.ID, err)
+	}
+	c.budget = nil
+	if c.state.Budget != nil {
+		c.state.Budget.Throttled = false
+		c.state.Budget.MemoryKills += kills
+	}
+	return kills > 0
+}
+
 func (c *commandRuntime) workDirPath() string {
 	return filepath.Join(paths.Run(), c.current.ID)
 }
@@ -532,6 +767,20 @@ func (c *commandRuntime) getCommandSpec() *component.CommandSpec {
 	return nil
 }
 
+func (c *commandRuntime) getImageSpec() *component.ImageSpec {
+	if c.current.InputSpec != nil {
+		return c.current.InputSpec.Spec.Image
+	}
+	return nil
+}
+
+func (c *commandRuntime) getHealthCheckSpec() *component.HealthCheckSpec {
+	if c.current.InputSpec != nil {
+		return c.current.InputSpec.Spec.HealthCheck
+	}
+	return nil
+}
+
 func (c *commandRuntime) syncLogLevels() {
 	ll, unitLevels := getLogLevels(c.current)
 	c.logStd.SetLevels(ll, unitLevels)
@@ -602,6 +851,14 @@ func dirPath(path string) process.CmdOption {
 	}
 }
 
+// cleanEnv replaces the environment inherited from Elastic Agent with env.
+func cleanEnv(env []string) process.CmdOption {
+	return func(cmd *exec.Cmd) error {
+		cmd.Env = env
+		return nil
+	}
+}
+
 func newRateLimiter(restartMonitoringPeriod time.Duration, maxEventsPerPeriod int) *rate.Limiter {
 	if restartMonitoringPeriod <= 0 || maxEventsPerPeriod <= 0 {
 		return nil
@@ -15,6 +15,8 @@ import (
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
 	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/core/process"
 )
 
 func TestAddToBucket(t *testing.T) {
@@ -204,3 +206,76 @@ func TestSyncExpected(t *testing.T) {
 	})
 
 }
+
+func TestSyncCheckinPid(t *testing.T) {
+	// the PID of a spawned process is kept when the component does not report one
+	state := ComponentState{Pid: 123}
+	state.syncCheckin(&proto.CheckinObserved{})
+	assert.Equal(t, uint64(123), state.Pid)
+
+	// the PID reported by a component is used
+	assert.True(t, state.syncCheckin(&proto.CheckinObserved{Pid: 456}))
+	assert.Equal(t, uint64(456), state.Pid)
+}
+
+func TestOutputRestartRequired(t *testing.T) {
+	output := func(apiKey string, bulkMaxSize int) component.Component {
+		return component.Component{
+			ID: "testing",
+			InputSpec: &component.InputRuntimeSpec{
+				Spec: component.InputSpec{
+					Command: &component.CommandSpec{RestartOnOutputChange: true, LiveOutputReload: true},
+				},
+			},
+			Units: []component.Unit{{
+				ID:   "testing-output",
+				Type: client.UnitTypeOutput,
+				Config: component.MustExpectedConfig(map[string]interface{}{
+					"type":          "elasticsearch",
+					"api_key":       apiKey,
+					"bulk_max_size": bulkMaxSize,
+				}),
+			}},
+		}
+	}
+	log, _ := loggertest.New("output")
+	c, err := newCommandRuntime(output("id:key", 1600), log, nil)
+	require.NoError(t, err)
+
+	rotated := output("id:rotated", 1600)
+	assert.False(t, c.outputRestartRequired(&rotated), "a component that is not running is not restarted")
+
+	c.proc = &process.Info{PID: 1}
+	assert.False(t, c.outputRestartRequired(&rotated), "the credentials are applied live")
+	resized := output("id:key", 3200)
+	assert.True(t, c.outputRestartRequired(&resized))
+
+	c.current.InputSpec.Spec.Command.LiveOutputReload = false
+	assert.True(t, c.outputRestartRequired(&rotated), "the component cannot reload its output")
+
+	c.current.InputSpec.Spec.Command.RestartOnOutputChange = false
+	assert.False(t, c.outputRestartRequired(&resized), "the component applies all the changes live")
+}
+
+func TestRestartReasonFor(t *testing.T) {
+	comp := component.Component{
+		ID: "testing",
+		InputSpec: &component.InputRuntimeSpec{
+			Spec: component.InputSpec{Command: &component.CommandSpec{}},
+		},
+	}
+	log, _ := loggertest.New("restart")
+	c, err := newCommandRuntime(comp, log, nil)
+	require.NoError(t, err)
+
+	changed := comp
+	changed.CommandOverride = &component.CommandOverride{Env: map[string]string{"LC_ALL": "C"}}
+	assert.Empty(t, c.restartReasonFor(&changed), "a component that is not running is not restarted")
+
+	c.proc = &process.Info{PID: 1}
+	assert.Empty(t, c.restartReasonFor(&comp))
+	assert.Equal(t, "its command change", c.restartReasonFor(&changed))
+
+	c.restartReason = "its output change"
+	assert.Empty(t, c.restartReasonFor(&changed), "the component is already restarting")
+}
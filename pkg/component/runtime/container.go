@@ -0,0 +1,204 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+const (
+	// runcBinary is the OCI runtime running the containers of the components, it's looked up next to the
+	// components before the PATH.
+	runcBinary = "runc"
+	// containerBundleDir is the directory of the OCI bundle of a component in its run directory.
+	containerBundleDir = "container"
+	// imagePullTimeout bounds pulling and unpacking the image of a component.
+	imagePullTimeout = 10 * time.Minute
+	// containerStateRoot is the directory of the state of runc, on the tmpfs of /run so that the state of the
+	// containers does not outlive a reboot of the host.
+	containerStateRoot = "/run/elastic-agent-runc"
+)
+
+// containerCapabilities are the capabilities of the processes of the containers, the default set of the container
+// engines.
+var containerCapabilities = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_FSETID", "CAP_FOWNER", "CAP_MKNOD", "CAP_NET_RAW", "CAP_SETGID",
+	"CAP_SETUID", "CAP_SETFCAP", "CAP_SETPCAP", "CAP_NET_BIND_SERVICE", "CAP_SYS_CHROOT", "CAP_KILL", "CAP_AUDIT_WRITE",
+}
+
+// containerCommand returns the command running the component with the arguments and the environment in a container
+// of its image. The image is unpacked once and shared by the containers as their read-only root filesystem, the
+// paths of the Elastic Agent are bind-mounted at the same location so that the component reaches its data path and
+// the sockets of the Elastic Agent. The containers share the network of the host, for the TCP transport of the
+// components and their outputs.
+func (c *commandRuntime) containerCommand(image *component.ImageSpec, args []string, env []string, workDir string) (string, []string, error) {
+	if root, _ := utils.HasRoot(); !root {
+		return "", nil, errors.New("components packaged as images require the Elastic Agent to run as root")
+	}
+	runc, err := runcPath()
+	if err != nil {
+		return "", nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), imagePullTimeout)
+	defer cancel()
+	store := newImageStore(c.log, filepath.Join(paths.Components(), imagesDirName), filepath.Join(paths.Data(), imagesDirName))
+	unpacked, err := store.Unpack(ctx, image)
+	if err != nil {
+		return "", nil, err
+	}
+
+	bundle := filepath.Join(workDir, containerBundleDir)
+	if err := os.MkdirAll(bundle, runDirMod); err != nil {
+		return "", nil, fmt.Errorf("failed to create path %q: %w", bundle, err)
+	}
+	spec := containerSpec(c.current.ID, unpacked, image.Entrypoint, args, env, workDir, containerMounts(workDir))
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.WriteFile(filepath.Join(bundle, "config.json"), data, 0o600); err != nil {
+		return "", nil, fmt.Errorf("failed to write the container configuration: %w", err)
+	}
+
+	// the container of a previous run is left behind when the Elastic Agent or runc were killed, runc refuses to
+	// run a container with the ID of an existing one
+	stateDir := containerStateDir()
+	id := containerID(c.current.ID)
+	if out, err := exec.CommandContext(ctx, runc, "--root", stateDir, "delete", "--force", id).CombinedOutput(); err == nil {
+		c.log.Debugf("Deleted the container %s left by a previous run", id)
+	} else if !strings.Contains(string(out), "does not exist") {
+		c.log.Warnf("Failed to delete the container %s left by a previous run: %s: %s", id, err, strings.TrimSpace(string(out)))
+	}
+
+	// runc runs in the foreground: it passes the standard streams through, forwards the signals to the component
+	// and deletes the container once it exits.
+	return runc, []string{"--root", stateDir, "run", "--bundle", bundle, id}, nil
+}
+
+// containerStateDir returns the directory of the state of runc for this installation of the Elastic Agent, the
+// installations sharing the host do not see the containers of each other.
+func containerStateDir() string {
+	sum := sha256.Sum256([]byte(paths.Top()))
+	return filepath.Join(containerStateRoot, hex.EncodeToString(sum[:8]))
+}
+
+// runcPath returns the path of runc, the one packaged with the components first.
+func runcPath() (string, error) {
+	packaged := filepath.Join(paths.Components(), runcBinary)
+	if info, err := os.Stat(packaged); err == nil && !info.IsDir() {
+		return packaged, nil
+	}
+	path, err := exec.LookPath(runcBinary)
+	if err != nil {
+		return "", fmt.Errorf("components packaged as images require %s, it was not found in %s nor in the PATH: %w", runcBinary, paths.Components(), err)
+	}
+	return path, nil
+}
+
+// containerID returns the ID of the container of the component, runc only accepts letters, digits and ._- in it.
+func containerID(componentID string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, componentID)
+}
+
+// containerMounts returns the paths of the host bind-mounted in the containers.
+func containerMounts(workDir string) []string {
+	mounts := append([]string{paths.Top(), paths.Run(), workDir}, socketMounts...)
+	// resolve the names of the host network the containers share
+	mounts = append(mounts, "/etc/resolv.conf", "/etc/hosts")
+	var existing []string
+	for _, mount := range mounts {
+		if _, err := os.Stat(mount); err == nil && !slices.Contains(existing, mount) {
+			existing = append(existing, mount)
+		}
+	}
+	return existing
+}
+
+// containerSpec returns the OCI runtime specification of the container of the component.
+func containerSpec(id string, image *unpackedImage, entrypoint []string, args []string, env []string, workDir string, binds []string) *specs.Spec {
+	if len(entrypoint) == 0 {
+		entrypoint = image.config.Config.Entrypoint
+	}
+	processArgs := append(slices.Clone(entrypoint), args...)
+	processEnv := append(slices.Clone(image.config.Config.Env), env...)
+
+	mounts := []specs.Mount{
+		{Destination: "/proc", Type: "proc", Source: "proc", Options: []string{"nosuid", "noexec", "nodev"}},
+		{Destination: "/dev", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+		{Destination: "/dev/pts", Type: "devpts", Source: "devpts", Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620"}},
+		{Destination: "/dev/shm", Type: "tmpfs", Source: "shm", Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=65536k"}},
+		{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue", Options: []string{"nosuid", "noexec", "nodev"}},
+		{Destination: "/sys", Type: "sysfs", Source: "sysfs", Options: []string{"nosuid", "noexec", "nodev", "ro"}},
+		{Destination: "/tmp", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "nodev", "mode=1777"}},
+	}
+	for _, bind := range binds {
+		mounts = append(mounts, specs.Mount{Destination: bind, Type: "bind", Source: bind, Options: []string{"rbind", "rw"}})
+	}
+
+	return &specs.Spec{
+		Version: specs.Version,
+		Root: &specs.Root{
+			Path:     image.rootfs,
+			Readonly: true,
+		},
+		Process: &specs.Process{
+			Args: processArgs,
+			Env:  processEnv,
+			Cwd:  workDir,
+			User: specs.User{
+				UID: uint32(os.Geteuid()), //nolint:gosec // the user ID is never negative on linux
+				GID: uint32(os.Getegid()), //nolint:gosec // the group ID is never negative on linux
+			},
+			Capabilities: &specs.LinuxCapabilities{
+				Bounding:  containerCapabilities,
+				Effective: containerCapabilities,
+				Permitted: containerCapabilities,
+			},
+			NoNewPrivileges: true,
+		},
+		Hostname: containerID(id),
+		Mounts:   mounts,
+		Linux: &specs.Linux{
+			// no network namespace, the containers share the network of the host
+			Namespaces: []specs.LinuxNamespace{
+				{Type: specs.PIDNamespace},
+				{Type: specs.IPCNamespace},
+				{Type: specs.UTSNamespace},
+				{Type: specs.MountNamespace},
+			},
+			MaskedPaths: []string{
+				"/proc/acpi", "/proc/asound", "/proc/kcore", "/proc/keys", "/proc/latency_stats", "/proc/timer_list",
+				"/proc/timer_stats", "/proc/sched_debug", "/sys/firmware", "/proc/scsi",
+			},
+			ReadonlyPaths: []string{
+				"/proc/bus", "/proc/fs", "/proc/irq", "/proc/sys", "/proc/sysrq-trigger",
+			},
+		},
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+)
+
+func TestContainerSpec(t *testing.T) {
+	image := &unpackedImage{
+		rootfs: "/opt/Elastic/Agent/data/images/sha256-abc/rootfs",
+		config: &v1.ConfigFile{Config: v1.Config{Entrypoint: []string{"/usr/share/input/input"}, Env: []string{"PATH=/usr/bin"}}},
+	}
+	workDir := "/opt/Elastic/Agent/data/run/input-default"
+
+	spec := containerSpec("input-default/1", image, nil, []string{"-e"}, []string{"AGENT_COMPONENT_ID=input-default"}, workDir, []string{workDir})
+	assert.Equal(t, []string{"/usr/share/input/input", "-e"}, spec.Process.Args)
+	assert.Equal(t, []string{"PATH=/usr/bin", "AGENT_COMPONENT_ID=input-default"}, spec.Process.Env)
+	assert.Equal(t, workDir, spec.Process.Cwd)
+	assert.Equal(t, "input-default_1", spec.Hostname)
+	require.NotNil(t, spec.Root)
+	assert.True(t, spec.Root.Readonly)
+	assert.Contains(t, spec.Mounts, specs.Mount{Destination: workDir, Type: "bind", Source: workDir, Options: []string{"rbind", "rw"}})
+	for _, ns := range spec.Linux.Namespaces {
+		assert.NotEqual(t, specs.NetworkNamespace, ns.Type, "the container should share the network of the host")
+	}
+
+	spec = containerSpec("input-default", image, []string{"/bin/sh", "-c"}, []string{"input"}, nil, workDir, nil)
+	assert.Equal(t, []string{"/bin/sh", "-c", "input"}, spec.Process.Args, "the entrypoint of the specification overrides the one of the image")
+}
+
+func TestContainerStateDir(t *testing.T) {
+	top := paths.Top()
+	defer paths.SetTop(top)
+
+	paths.SetTop("/opt/Elastic/Agent")
+	first := containerStateDir()
+	assert.Equal(t, containerStateRoot, filepath.Dir(first), "the state is kept on the tmpfs of /run")
+	paths.SetTop("/opt/Elastic/Agent-other")
+	assert.NotEqual(t, first, containerStateDir(), "the installations do not share their containers")
+}
@@ -0,0 +1,14 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package runtime
+
+import (
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// socketMounts are the directories of the control sockets bind-mounted in the containers.
+var socketMounts = []string{utils.SocketFallbackDirectory}
@@ -0,0 +1,89 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+)
+
+// startDrain starts draining the units of the component before its process is stopped, the input units are
+// expected to stop first so that the component stops accepting new work, then the output units so that it flushes
+// its queues. It returns false when the component is not drained: no drain timeout is defined, or the process is
+// not running or never checked in.
+func (c *commandRuntime) startDrain(comm Communicator) bool {
+	if c.drainTimer != nil {
+		// already draining
+		return true
+	}
+	timeout := c.getCommandSpec().Timeouts.Drain
+	if timeout <= 0 || c.proc == nil || c.lastCheckin.IsZero() {
+		return false
+	}
+
+	c.log.Infof("Draining component %s for up to %s before stopping pid '%d'", c.current.ID, timeout, c.proc.PID)
+	c.drainTimer = time.NewTimer(timeout)
+	c.drainingOutputs = false
+	c.state.forceExpectedTypeState(client.UnitTypeInput, client.UnitStateStopped)
+	comm.CheckinExpected(c.state.toCheckinExpected(), nil)
+	if c.state.compState(client.UnitStateStopping, fmt.Sprintf("Stopping: draining pid '%d'", c.proc.PID)) {
+		c.sendObserved()
+	}
+	return true
+}
+
+// continueDrain moves the drain forward after a check-in of the component. It returns true once all the units
+// stopped and the process can be stopped.
+func (c *commandRuntime) continueDrain(comm Communicator) bool {
+	if !c.state.unitsStopped(client.UnitTypeInput) {
+		return false
+	}
+	if !c.drainingOutputs {
+		c.log.Debugf("Input units of component %s are stopped, flushing its output units", c.current.ID)
+		c.drainingOutputs = true
+		c.state.forceExpectedTypeState(client.UnitTypeOutput, client.UnitStateStopped)
+		comm.CheckinExpected(c.state.toCheckinExpected(), nil)
+	}
+	return c.state.unitsStopped(client.UnitTypeOutput)
+}
+
+// finishDrain stops the process of the component once drained, or once the drain timed out.
+func (c *commandRuntime) finishDrain(ctx context.Context) {
+	c.stopDrain()
+	if err := c.stop(ctx); err != nil {
+		c.forceCompState(client.UnitStateFailed, fmt.Sprintf("Failed: %s", err))
+	}
+}
+
+// cancelDrain expects the units in the state of the component definition again, when the component should keep
+// running.
+func (c *commandRuntime) cancelDrain(comm Communicator) {
+	if c.drainTimer == nil {
+		return
+	}
+	c.log.Infof("Component %s should keep running, cancelled its drain", c.current.ID)
+	c.stopDrain()
+	c.state.restoreExpectedState(&c.current)
+	comm.CheckinExpected(c.state.toCheckinExpected(), nil)
+}
+
+// stopDrain stops the timeout of the drain.
+func (c *commandRuntime) stopDrain() {
+	if c.drainTimer != nil {
+		c.drainTimer.Stop()
+		c.drainTimer = nil
+	}
+}
+
+// drainTimeout returns the channel of the timeout of the drain, nil when not draining.
+func (c *commandRuntime) drainTimeout() <-chan time.Time {
+	if c.drainTimer == nil {
+		return nil
+	}
+	return c.drainTimer.C
+}
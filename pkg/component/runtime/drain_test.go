@@ -0,0 +1,80 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+func TestDrain(t *testing.T) {
+	input := ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "testing-input"}
+	output := ComponentUnitKey{UnitType: client.UnitTypeOutput, UnitID: "testing-output"}
+	comp := component.Component{
+		ID: "testing",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType: "testing",
+			Spec: component.InputSpec{
+				Command: &component.CommandSpec{Timeouts: component.CommandTimeoutSpec{Drain: time.Minute}},
+			},
+		},
+		Units: []component.Unit{
+			{ID: input.UnitID, Type: input.UnitType, Config: &proto.UnitExpectedConfig{}},
+			{ID: output.UnitID, Type: output.UnitType, Config: &proto.UnitExpectedConfig{}},
+		},
+	}
+	log, _ := loggertest.New("drain")
+	c, err := newCommandRuntime(comp, log, nil)
+	require.NoError(t, err)
+	c.ch = make(chan ComponentState, 10)
+	comm := newMockCommunicator("")
+
+	checkin := func(inputState, outputState proto.State) {
+		c.state.syncCheckin(&proto.CheckinObserved{Units: []*proto.UnitObserved{
+			{Id: input.UnitID, Type: proto.UnitType_INPUT, State: inputState, ConfigStateIdx: 1},
+			{Id: output.UnitID, Type: proto.UnitType_OUTPUT, State: outputState, ConfigStateIdx: 1},
+		}})
+	}
+	expected := func(key ComponentUnitKey) client.UnitState {
+		return c.state.expectedUnits[key].state
+	}
+
+	assert.False(t, c.startDrain(comm), "a component that is not running is not drained")
+
+	c.proc = &process.Info{PID: 1}
+	c.lastCheckin = time.Now()
+	checkin(proto.State_HEALTHY, proto.State_HEALTHY)
+	require.True(t, c.startDrain(comm))
+	defer c.stopDrain()
+	assert.Equal(t, client.UnitStateStopping, c.state.State)
+	assert.Equal(t, client.UnitStateStopped, expected(input), "the inputs stop first")
+	assert.Equal(t, client.UnitStateHealthy, expected(output))
+
+	checkin(proto.State_STOPPING, proto.State_HEALTHY)
+	assert.False(t, c.continueDrain(comm))
+	assert.Equal(t, client.UnitStateHealthy, expected(output), "the output drains once the inputs stopped")
+
+	checkin(proto.State_STOPPED, proto.State_HEALTHY)
+	assert.False(t, c.continueDrain(comm))
+	assert.Equal(t, client.UnitStateStopped, expected(output))
+
+	c.cancelDrain(comm)
+	assert.Nil(t, c.drainTimer)
+	assert.Equal(t, client.UnitStateHealthy, expected(input), "a cancelled drain restores the units")
+	assert.Equal(t, client.UnitStateHealthy, expected(output))
+
+	require.True(t, c.startDrain(comm))
+	checkin(proto.State_STOPPED, proto.State_STOPPED)
+	assert.True(t, c.continueDrain(comm))
+}
@@ -0,0 +1,209 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+// healthCheckOutputLimit bounds the output of an exec health check reported in the state of the component.
+const healthCheckOutputLimit = 256
+
+// HealthCheckState is the state of the health check of a component.
+type HealthCheckState struct {
+	// State is healthy until the failure threshold is reached, it is then the state of the units defined by the
+	// health check.
+	State client.UnitState `yaml:"state"`
+	// Message describes the last failed probe, empty once a probe succeeds.
+	Message string `yaml:"message,omitempty"`
+	// Failures is the number of consecutive failed probes.
+	Failures int `yaml:"failures"`
+	// LastProbe is the time of the last probe.
+	LastProbe time.Time `yaml:"last_probe"`
+}
+
+// healthProbe probes a running component, it returns an error when the component is unhealthy.
+type healthProbe func(ctx context.Context) error
+
+// healthCheckResult is the result of a probe of the process of a component.
+type healthCheckResult struct {
+	proc *process.Info
+	err  error
+	at   time.Time
+}
+
+// newHealthProbe returns the probe of the health check specification.
+func newHealthProbe(spec *component.HealthCheckSpec, env []string) healthProbe {
+	switch {
+	case spec.HTTP != nil:
+		return httpHealthProbe(spec.HTTP.URL)
+	case spec.TCP != nil:
+		return tcpHealthProbe(spec.TCP.Address)
+	default:
+		return execHealthProbe(spec.Exec, env)
+	}
+}
+
+func httpHealthProbe(url string) healthProbe {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+func tcpHealthProbe(address string) healthProbe {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+func execHealthProbe(spec *component.HealthCheckExecSpec, env []string) healthProbe {
+	path := spec.Command[0]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(paths.Components(), path)
+	}
+	env = append(os.Environ(), env...)
+	for _, e := range spec.Env {
+		env = append(env, fmt.Sprintf("%s=%s", e.Name, e.Value))
+	}
+	return func(ctx context.Context) error {
+		cmd := exec.CommandContext(ctx, path, spec.Command[1:]...)
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			output = bytes.TrimSpace(output)
+			if len(output) > healthCheckOutputLimit {
+				output = output[:healthCheckOutputLimit]
+			}
+			if len(output) > 0 {
+				return fmt.Errorf("%s: %w: %s", filepath.Base(path), err, output)
+			}
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		return nil
+	}
+}
+
+// startHealthCheck probes the process of the component at the interval of its health check until the process exits,
+// the results are handled by (*commandRuntime).Run.
+func (c *commandRuntime) startHealthCheck(proc *process.Info, env []string) {
+	c.state.HealthCheck = nil
+	spec := c.getHealthCheckSpec()
+	if spec == nil {
+		return
+	}
+	c.state.HealthCheck = &HealthCheckState{State: client.UnitStateHealthy}
+	probe := newHealthProbe(spec, env)
+	ctx, cancel := context.WithCancel(context.Background())
+	c.healthCheckCancel = cancel
+	go func() {
+		t := time.NewTicker(spec.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+			probeCtx, probeCancel := context.WithTimeout(ctx, spec.Timeout)
+			err := probe(probeCtx)
+			timedOut := errors.Is(probeCtx.Err(), context.DeadlineExceeded)
+			probeCancel()
+			if ctx.Err() != nil {
+				// process exited while probing
+				return
+			}
+			if err != nil && timedOut {
+				err = fmt.Errorf("timed out after %s", spec.Timeout)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case c.healthCheckCh <- healthCheckResult{proc: proc, err: err, at: time.Now()}:
+			}
+		}
+	}()
+}
+
+// stopHealthCheck stops probing the process of the component.
+func (c *commandRuntime) stopHealthCheck() {
+	if c.healthCheckCancel != nil {
+		c.healthCheckCancel()
+		c.healthCheckCancel = nil
+	}
+}
+
+// handleHealthCheck applies the result of a probe to the state of the component.
+func (c *commandRuntime) handleHealthCheck(res healthCheckResult) {
+	spec := c.getHealthCheckSpec()
+	prev := c.state.HealthCheck
+	if spec == nil || prev == nil {
+		return
+	}
+	hc := &HealthCheckState{State: client.UnitStateHealthy, LastProbe: res.at}
+	if res.err != nil {
+		hc.Failures = prev.Failures + 1
+		hc.Message = fmt.Sprintf("health check failed %d consecutive time(s): %s", hc.Failures, res.err)
+		if hc.Failures >= spec.FailureThreshold {
+			if spec.State == component.HealthCheckFailed {
+				hc.State = client.UnitStateFailed
+				hc.Message = "Failed: " + hc.Message
+			} else {
+				hc.State = client.UnitStateDegraded
+				hc.Message = "Degraded: " + hc.Message
+			}
+		}
+	}
+	if hc.State != prev.State {
+		if hc.State == client.UnitStateHealthy {
+			c.log.Infof("Component %s passes its health check again", c.current.ID)
+		} else {
+			c.log.Warnf("Component %s is %s: %s", c.current.ID, hc.State, hc.Message)
+		}
+	}
+
+	if c.lastCheckin.IsZero() || c.drainTimer != nil {
+		// the state is forced by the runtime, the health check applies with the next check-in
+		c.state.HealthCheck = hc
+		return
+	}
+	// the units check in, apply the health check to them and the component
+	changed := c.state.syncHealthCheck(hc)
+	c.compState(c.checkinState())
+	if changed {
+		c.sendObserved()
+	}
+}
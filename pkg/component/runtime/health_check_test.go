@@ -0,0 +1,108 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+	"github.com/elastic/elastic-agent-client/v7/pkg/proto"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/core/process"
+)
+
+func TestHealthProbes(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthy" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	assert.NoError(t, httpHealthProbe(srv.URL+"/healthy")(ctx))
+	assert.ErrorContains(t, httpHealthProbe(srv.URL+"/wedged")(ctx), "returned status 503")
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := lis.Addr().String()
+	assert.NoError(t, tcpHealthProbe(address)(ctx))
+	require.NoError(t, lis.Close())
+	assert.Error(t, tcpHealthProbe(address)(ctx))
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	assert.NoError(t, execHealthProbe(&component.HealthCheckExecSpec{Command: []string{"/bin/sh", "-c", `test "$COMPONENT" = "$AGENT_COMPONENT_ID"`}, Env: []component.CommandEnvSpec{{Name: "COMPONENT", Value: "testing"}}}, []string{envAgentComponentID + "=testing"})(ctx))
+	assert.ErrorContains(t, execHealthProbe(&component.HealthCheckExecSpec{Command: []string{"/bin/sh", "-c", "echo wedged; exit 1"}}, nil)(ctx), "sh: exit status 1: wedged")
+}
+
+func TestHandleHealthCheck(t *testing.T) {
+	unit := ComponentUnitKey{UnitType: client.UnitTypeInput, UnitID: "testing-unit"}
+	comp := component.Component{
+		ID: "testing",
+		InputSpec: &component.InputRuntimeSpec{
+			InputType: "testing",
+			Spec: component.InputSpec{
+				Command: &component.CommandSpec{},
+				HealthCheck: &component.HealthCheckSpec{
+					TCP:              &component.HealthCheckTCPSpec{Address: "localhost:6789"},
+					Interval:         time.Hour,
+					Timeout:          time.Second,
+					FailureThreshold: 2,
+					State:            component.HealthCheckFailed,
+				},
+			},
+		},
+		Units: []component.Unit{{ID: unit.UnitID, Type: unit.UnitType, Config: &proto.UnitExpectedConfig{}}},
+	}
+	log, _ := loggertest.New("health_check")
+	c, err := newCommandRuntime(comp, log, nil)
+	require.NoError(t, err)
+	c.ch = make(chan ComponentState, 10)
+
+	proc := &process.Info{PID: 1}
+	c.proc = proc
+	c.startHealthCheck(proc, nil)
+	defer c.stopHealthCheck()
+	c.state.State = client.UnitStateHealthy
+	c.lastCheckin = time.Now()
+	c.state.syncCheckin(&proto.CheckinObserved{Units: []*proto.UnitObserved{
+		{Id: unit.UnitID, Type: proto.UnitType(unit.UnitType), State: proto.State_HEALTHY, Message: "Healthy"},
+	}})
+
+	c.handleHealthCheck(healthCheckResult{proc: proc, err: errors.New("connection refused")})
+	assert.Equal(t, client.UnitStateHealthy, c.state.Units[unit].State, "below the failure threshold")
+	assert.Equal(t, 1, c.state.HealthCheck.Failures)
+
+	c.handleHealthCheck(healthCheckResult{proc: proc, err: errors.New("connection refused")})
+	assert.Equal(t, client.UnitStateFailed, c.state.State)
+	assert.Equal(t, client.UnitStateFailed, c.state.Units[unit].State)
+	assert.Equal(t, "Failed: health check failed 2 consecutive time(s): connection refused", c.state.Units[unit].Message)
+
+	// the check-ins of the unit keep the state of the health check
+	c.state.syncCheckin(&proto.CheckinObserved{Units: []*proto.UnitObserved{
+		{Id: unit.UnitID, Type: proto.UnitType(unit.UnitType), State: proto.State_HEALTHY, Message: "Healthy"},
+	}})
+	assert.Equal(t, client.UnitStateFailed, c.state.Units[unit].State)
+
+	c.handleHealthCheck(healthCheckResult{proc: proc})
+	assert.Equal(t, client.UnitStateHealthy, c.state.State)
+	assert.Equal(t, client.UnitStateHealthy, c.state.Units[unit].State)
+	assert.Equal(t, "Healthy", c.state.Units[unit].Message)
+	assert.Zero(t, c.state.HealthCheck.Failures)
+}
@@ -0,0 +1,359 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+const (
+	// imagesDirName is the name of the directory of the OCI image layouts packaged with the components, and of
+	// the directory of the unpacked images in the data path.
+	imagesDirName = "images"
+	// imageConfigFile is the configuration of an unpacked image, it's written last to mark the image unpacked.
+	imageConfigFile = "config.json"
+	// imageRootfsDir is the root filesystem of an unpacked image.
+	imageRootfsDir = "rootfs"
+)
+
+// imagesMx serializes the unpacking of the images, the components sharing an image unpack it once.
+var imagesMx sync.Mutex
+
+// unpackedImage is an image unpacked on disk.
+type unpackedImage struct {
+	rootfs string
+	config *v1.ConfigFile
+}
+
+// imageStore unpacks the images of the components.
+type imageStore struct {
+	log *logger.Logger
+	// layouts is the directory of the OCI image layouts of the pre-loaded images.
+	layouts string
+	// dir is the directory of the unpacked images.
+	dir string
+	// platform is the platform of the images to run.
+	platform v1.Platform
+	// remoteOptions are the options of the pulls of the images from their registry.
+	remoteOptions []remote.Option
+}
+
+func newImageStore(log *logger.Logger, layouts string, dir string) *imageStore {
+	return &imageStore{
+		log:      log,
+		layouts:  layouts,
+		dir:      dir,
+		platform: v1.Platform{OS: component.Linux, Architecture: runtime.GOARCH},
+		remoteOptions: []remote.Option{
+			remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		},
+	}
+}
+
+// Unpack returns the image of the specification unpacked, it's taken from the pre-loaded images or pulled from its
+// registry by digest and unpacked on first use.
+func (s *imageStore) Unpack(ctx context.Context, spec *component.ImageSpec) (*unpackedImage, error) {
+	hash, err := v1.NewHash(spec.Digest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image digest %q: %w", spec.Digest, err)
+	}
+	dir := filepath.Join(s.dir, hash.Algorithm+"-"+hash.Hex)
+	unpacked := &unpackedImage{rootfs: filepath.Join(dir, imageRootfsDir)}
+
+	imagesMx.Lock()
+	defer imagesMx.Unlock()
+	if f, err := os.Open(filepath.Join(dir, imageConfigFile)); err == nil {
+		defer f.Close()
+		unpacked.config, err = v1.ParseConfigFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the configuration of the unpacked image %s: %w", spec.Reference, err)
+		}
+		return unpacked, nil
+	}
+
+	img, err := s.image(ctx, spec, hash)
+	if err != nil {
+		return nil, err
+	}
+	rawConfig, err := img.RawConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration of the image %s: %w", spec.Reference, err)
+	}
+	unpacked.config, err = v1.ParseConfigFile(bytes.NewReader(rawConfig))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration of the image %s: %w", spec.Reference, err)
+	}
+
+	tmp := dir + ".tmp"
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", tmp, err)
+	}
+	defer os.RemoveAll(tmp)
+	s.log.Infof("Unpacking image %s@%s", spec.Reference, spec.Digest)
+	rc := mutate.Extract(img)
+	defer rc.Close()
+	if err := untar(rc, filepath.Join(tmp, imageRootfsDir)); err != nil {
+		return nil, fmt.Errorf("failed to unpack the image %s: %w", spec.Reference, err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, imageConfigFile), rawConfig, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write the configuration of the image %s: %w", spec.Reference, err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		return nil, fmt.Errorf("failed to move the unpacked image %s: %w", spec.Reference, err)
+	}
+	return unpacked, nil
+}
+
+// image returns the image of the digest, from the pre-loaded images first.
+func (s *imageStore) image(ctx context.Context, spec *component.ImageSpec, hash v1.Hash) (v1.Image, error) {
+	img, err := s.preloaded(hash)
+	if err == nil {
+		s.log.Debugf("Using the pre-loaded image %s@%s", spec.Reference, spec.Digest)
+		return img, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		s.log.Warnf("Failed to use the pre-loaded image %s@%s, pulling it: %s", spec.Reference, spec.Digest, err)
+	}
+
+	ref, err := name.ParseReference(spec.Reference)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %q: %w", spec.Reference, err)
+	}
+	digest := ref.Context().Digest(spec.Digest)
+	s.log.Infof("Pulling image %s", digest)
+	opts := append([]remote.Option{remote.WithContext(ctx), remote.WithPlatform(s.platform)}, s.remoteOptions...)
+	desc, err := remote.Get(digest, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull the image %s: %w", digest, err)
+	}
+	// the manifest fetched by digest is verified against it, the layers are verified against the manifest
+	img, err = desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull the image %s for %s/%s: %w", digest, s.platform.OS, s.platform.Architecture, err)
+	}
+	return img, nil
+}
+
+// preloaded returns the image of the digest from the OCI image layouts, os.ErrNotExist when it's not pre-loaded. The
+// digest is either the one of the image or of an index of images of the platforms.
+func (s *imageStore) preloaded(hash v1.Hash) (v1.Image, error) {
+	entries, err := os.ReadDir(s.layouts)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		p, err := layout.FromPath(filepath.Join(s.layouts, entry.Name()))
+		if err != nil {
+			continue
+		}
+		index, err := p.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("invalid image layout %s: %w", entry.Name(), err)
+		}
+		manifest, err := index.IndexManifest()
+		if err != nil {
+			return nil, fmt.Errorf("invalid image layout %s: %w", entry.Name(), err)
+		}
+		for _, desc := range manifest.Manifests {
+			if desc.Digest != hash {
+				continue
+			}
+			if desc.MediaType.IsIndex() {
+				child, err := index.ImageIndex(hash)
+				if err != nil {
+					return nil, err
+				}
+				if err := verifyDigest(child, hash); err != nil {
+					return nil, err
+				}
+				return s.platformImage(child)
+			}
+			img, err := index.Image(hash)
+			if err != nil {
+				return nil, err
+			}
+			if err := verifyDigest(img, hash); err != nil {
+				return nil, err
+			}
+			return img, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+// platformImage returns the image of the platform from the index.
+func (s *imageStore) platformImage(index v1.ImageIndex) (v1.Image, error) {
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return nil, err
+	}
+	for _, desc := range manifest.Manifests {
+		if desc.Platform != nil && desc.Platform.Satisfies(s.platform) {
+			return index.Image(desc.Digest)
+		}
+	}
+	return nil, fmt.Errorf("no image for %s/%s", s.platform.OS, s.platform.Architecture)
+}
+
+// verifyDigest verifies the digest of the manifest of the pre-loaded image or index.
+func verifyDigest(artifact interface{ Digest() (v1.Hash, error) }, hash v1.Hash) error {
+	digest, err := artifact.Digest()
+	if err != nil {
+		return err
+	}
+	if digest != hash {
+		return fmt.Errorf("pre-loaded image has digest %s instead of %s", digest, hash)
+	}
+	return nil
+}
+
+// untar unpacks the flattened filesystem of an image in dir. The entries are never written through a symbolic
+// link, so that a link of the image cannot redirect them outside of dir.
+func untar(r io.Reader, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		rel := filepath.Clean(string(filepath.Separator) + hdr.Name)
+		if rel == string(filepath.Separator) {
+			continue
+		}
+		target := filepath.Join(dir, rel)
+		if err := mkdirNoSymlinks(dir, filepath.Dir(rel)); err != nil {
+			return fmt.Errorf("failed to unpack %s: %w", hdr.Name, err)
+		}
+		mode := os.FileMode(hdr.Mode).Perm() | os.FileMode(hdr.Mode)&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky) //nolint:gosec // the mode comes from the tar header
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+			if err := os.Mkdir(target, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+				return err
+			}
+		case tar.TypeReg:
+			if err := removeNonDir(target); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr) //nolint:gosec // the size of the image is bounded by its manifest
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := removeNonDir(target); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			linkRel := filepath.Clean(string(filepath.Separator) + hdr.Linkname)
+			if err := mkdirNoSymlinks(dir, filepath.Dir(linkRel)); err != nil {
+				return fmt.Errorf("failed to unpack %s: %w", hdr.Name, err)
+			}
+			if err := removeNonDir(target); err != nil {
+				return err
+			}
+			if err := os.Link(filepath.Join(dir, linkRel), target); err != nil {
+				return err
+			}
+			continue
+		default:
+			// devices and fifos are provided by the container runtime
+			continue
+		}
+
+		if err := os.Lchown(target, hdr.Uid, hdr.Gid); err != nil && !errors.Is(err, os.ErrPermission) {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeSymlink {
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// mkdirNoSymlinks creates the directories of rel in dir, failing when one of them is a symbolic link.
+func mkdirNoSymlinks(dir string, rel string) error {
+	current := dir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "" {
+			continue
+		}
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if err := os.Mkdir(current, 0o755); err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !info.IsDir():
+			return fmt.Errorf("%s is not a directory", current)
+		}
+	}
+	return nil
+}
+
+// removeNonDir removes the entry replaced by another one of the image.
+func removeNonDir(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", path)
+	}
+	return os.Remove(path)
+}
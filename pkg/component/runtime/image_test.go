@@ -0,0 +1,158 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type tarEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+func tarLayer(t *testing.T, entries ...tarEntry) v1.Layer {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Mode: 0o755, Linkname: e.linkname, Size: int64(len(e.content))}
+		require.NoError(t, tw.WriteHeader(hdr))
+		if e.content != "" {
+			_, err := tw.Write([]byte(e.content))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+	data := buf.Bytes()
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	})
+	require.NoError(t, err)
+	return layer
+}
+
+func testImage(t *testing.T) v1.Image {
+	img, err := mutate.AppendLayers(empty.Image,
+		tarLayer(t,
+			tarEntry{name: "usr/bin/", typeflag: tar.TypeDir},
+			tarEntry{name: "usr/bin/input", typeflag: tar.TypeReg, content: "binary"},
+			tarEntry{name: "bin", typeflag: tar.TypeSymlink, linkname: "usr/bin"},
+		),
+		tarLayer(t,
+			tarEntry{name: "usr/bin/input", typeflag: tar.TypeReg, content: "updated binary"},
+		),
+	)
+	require.NoError(t, err)
+	cfg, err := img.ConfigFile()
+	require.NoError(t, err)
+	cfg = cfg.DeepCopy()
+	cfg.OS = component.Linux
+	cfg.Architecture = runtime.GOARCH
+	cfg.Config.Entrypoint = []string{"/usr/bin/input"}
+	cfg.Config.Env = []string{"PATH=/usr/bin"}
+	img, err = mutate.ConfigFile(img, cfg)
+	require.NoError(t, err)
+	return img
+}
+
+func testImageStore(t *testing.T, layouts string) *imageStore {
+	log, _ := loggertest.New("image")
+	return newImageStore(log, layouts, filepath.Join(t.TempDir(), "images"))
+}
+
+func assertUnpacked(t *testing.T, unpacked *unpackedImage) {
+	content, err := os.ReadFile(filepath.Join(unpacked.rootfs, "bin", "input"))
+	require.NoError(t, err)
+	assert.Equal(t, "updated binary", string(content))
+	assert.Equal(t, []string{"/usr/bin/input"}, unpacked.config.Config.Entrypoint)
+}
+
+func TestImageStorePreloaded(t *testing.T) {
+	img := testImage(t)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	layouts := t.TempDir()
+	p, err := layout.Write(filepath.Join(layouts, "input"), empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img))
+
+	store := testImageStore(t, layouts)
+	spec := &component.ImageSpec{Reference: "registry.invalid/input:1.0.0", Digest: digest.String()}
+	unpacked, err := store.Unpack(context.Background(), spec)
+	require.NoError(t, err)
+	assertUnpacked(t, unpacked)
+
+	// the unpacked image is reused without the pre-loaded one
+	require.NoError(t, os.RemoveAll(layouts))
+	unpacked, err = store.Unpack(context.Background(), spec)
+	require.NoError(t, err)
+	assertUnpacked(t, unpacked)
+
+	_, err = store.Unpack(context.Background(), &component.ImageSpec{
+		Reference: "registry.invalid/input:1.0.0",
+		Digest:    "sha256:" + strings.Repeat("0", 64),
+	})
+	assert.Error(t, err, "an image that is not pre-loaded is pulled")
+}
+
+func TestImageStorePull(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	defer srv.Close()
+	img := testImage(t)
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	reference := strings.TrimPrefix(srv.URL, "http://") + "/beats/input:1.0.0"
+	ref, err := name.ParseReference(reference)
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	store := testImageStore(t, t.TempDir())
+	store.remoteOptions = nil
+	unpacked, err := store.Unpack(context.Background(), &component.ImageSpec{Reference: reference, Digest: digest.String()})
+	require.NoError(t, err)
+	assertUnpacked(t, unpacked)
+
+	_, err = store.Unpack(context.Background(), &component.ImageSpec{Reference: reference, Digest: "sha256:" + strings.Repeat("0", 64)})
+	assert.Error(t, err, "the image is pulled by its pinned digest")
+}
+
+func TestUntarSymlinkEscape(t *testing.T) {
+	outside := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc", Typeflag: tar.TypeSymlink, Linkname: outside}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4}))
+	_, err := tw.Write([]byte("root"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	assert.Error(t, untar(&buf, filepath.Join(t.TempDir(), "rootfs")))
+	assert.NoFileExists(t, filepath.Join(outside, "passwd"), "the entries should not be written through a link")
+}
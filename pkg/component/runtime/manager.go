@@ -33,6 +33,7 @@ import (
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/internal/pkg/core/authority"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
@@ -107,6 +108,7 @@ type Manager struct {
 	tracer     *apm.Tracer
 	monitor    MonitoringManager
 	grpcConfig *configuration.GRPCConfig
+	timeouts   configuration.GRPCTimeoutsConfig
 
 	// Set when the RPC server is ready to receive requests, for use by tests.
 	serverReady chan struct{}
@@ -156,7 +158,6 @@ func NewManager(
 	}
 
 	controlAddress := control.Address()
-	// [gRPC:8.15] For 8.14 this always returns local TCP address, until Endpoint is modified to support domain sockets gRPC
 	listenAddr, err := deriveCommsAddress(controlAddress, grpcConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to derive comms GRPC: %w", err)
@@ -177,6 +178,7 @@ func NewManager(
 		errCh:         make(chan error),
 		monitor:       monitor,
 		grpcConfig:    grpcConfig,
+		timeouts:      grpcConfig.Timeouts.WithDefaults(),
 		serverReady:   make(chan struct{}),
 		doneChan:      make(chan struct{}),
 	}
@@ -204,7 +206,7 @@ func (m *Manager) Run(ctx context.Context) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("error starting tcp listener for runtime manager: %w", err)
+		return fmt.Errorf("error starting listener for runtime manager: %w", err)
 	}
 
 	if m.isLocal {
@@ -217,13 +219,16 @@ func (m *Manager) Run(ctx context.Context) error {
 	if ok := certPool.AppendCertsFromPEM(m.ca.Crt()); !ok {
 		return errors.New("failed to append root CA")
 	}
-	creds := credentials.NewTLS(&tls.Config{
+	var creds credentials.TransportCredentials = credentials.NewTLS(&tls.Config{
 		ClientAuth:     tls.RequireAndVerifyClientCert,
 		ClientCAs:      certPool,
 		GetCertificate: m.getCertificate,
 		MinVersion:     tls.VersionTLS12,
 	})
-	m.logger.Infof("Starting grpc control protocol listener on port %v with max_message_size %v", m.grpcConfig.Port, m.grpcConfig.MaxMsgSize)
+	if m.isLocal {
+		creds = newPeerVerifiedCredentials(m.logger, creds)
+	}
+	m.logger.Infof("Starting grpc control protocol listener on %v with max_message_size %v", m.getListenAddr(), m.grpcConfig.MaxMsgSize)
 	if m.tracer != nil {
 		apmInterceptor := apmgrpc.NewUnaryServerInterceptor(apmgrpc.WithRecovery(), apmgrpc.WithTracer(m.tracer))
 		server = grpc.NewServer(
@@ -290,9 +295,15 @@ LOOP:
 		if m.nextUpdate != nil && !updateInProgress {
 			// There is a component model update available, apply it.
 			go func(model component.Model) {
+				// Bound the update so a single unresponsive component cannot hold
+				// back every following policy update. Cancelling ctx (coordinator
+				// shutdown) also aborts the update.
+				updateCtx, cancel := context.WithTimeout(ctx, m.updateTimeout(model))
+				defer cancel()
+
 				// Run the update with tearDown set to true since this is coming
 				// from a user-initiated policy update
-				err := m.update(model, true)
+				err := m.update(updateCtx, model, true)
 
 				// When update is done, send its result back to the coordinator,
 				// unless we're shutting down.
@@ -415,8 +426,9 @@ func (m *Manager) PerformAction(ctx context.Context, comp component.Component, u
 }
 
 // PerformComponentDiagnostics executes the diagnostic action for the given components. If no components are provided then
-// it performs diagnostics for all running components.
-func (m *Manager) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]ComponentDiagnostic, error) {
+// it performs diagnostics for all running components. The cpuProfile duration is forwarded to the components in the
+// parameters of the action when CPU is requested, 0 uses their default duration.
+func (m *Manager) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]ComponentDiagnostic, error) {
 	if len(req) == 0 {
 		if len(req) == 0 {
 			m.currentMx.RLock()
@@ -434,12 +446,7 @@ func (m *Manager) PerformComponentDiagnostics(ctx context.Context, additionalMet
 	respChan := make(chan ComponentDiagnostic, diagnosticCount)
 	for diag := 0; diag < diagnosticCount; diag++ {
 		// transform the additional metrics field into JSON params
-		params := client.DiagnosticParams{}
-		if len(additionalMetrics) > 0 {
-			for _, param := range additionalMetrics {
-				params.AdditionalMetrics = append(params.AdditionalMetrics, param.String())
-			}
-		}
+		params := newDiagnosticParams(additionalMetrics, cpuProfile)
 		// perform diagnostics in parallel; if we have a CPU pprof request, it'll take 30 seconds each.
 		go func(iter int) {
 			diagResponse, err := m.performDiagAction(ctx, req[iter], component.Unit{}, proto.ActionRequest_COMPONENT, params)
@@ -455,7 +462,7 @@ func (m *Manager) PerformComponentDiagnostics(ctx context.Context, additionalMet
 
 	// performDiagAction will have timeouts at various points,
 	// but for the sake of paranoia, create our own timeout
-	collectTimeout, cancel := context.WithTimeout(ctx, time.Minute*2)
+	collectTimeout, cancel := context.WithTimeout(ctx, max(time.Minute*2, cpuProfile+diagnosticTimeoutCPU))
 	defer cancel()
 
 	for res := 0; res < diagnosticCount; res++ {
@@ -525,7 +532,7 @@ func (m *Manager) PerformDiagnostics(ctx context.Context, req ...ComponentUnitDi
 			continue
 		}
 
-		diag, err := m.performDiagAction(ctx, r.Component, r.Unit, proto.ActionRequest_UNIT, client.DiagnosticParams{})
+		diag, err := m.performDiagAction(ctx, r.Component, r.Unit, proto.ActionRequest_UNIT, diagnosticParams{})
 		if err != nil {
 			r.Err = err
 		} else {
@@ -747,8 +754,10 @@ func (m *Manager) Actions(server proto.ElasticAgent_ActionsServer) error {
 // update updates the current state of the running components.
 // It is only called by the main runtime manager goroutine in Manager.Run.
 //
-// This returns as soon as possible, work is performed in the background.
-func (m *Manager) update(model component.Model, teardown bool) error {
+// This returns as soon as possible, work is performed in the background. The only
+// blocking operation is waiting for removed components to stop, which is bounded
+// by the stop timeout of each component and by the provided context.
+func (m *Manager) update(ctx context.Context, model component.Model, teardown bool) error {
 	touched := make(map[string]bool)
 	newComponents := make([]component.Component, 0, len(model.Components))
 	for _, comp := range model.Components {
@@ -788,10 +797,10 @@ func (m *Manager) update(model component.Model, teardown bool) error {
 		// otherwise new instance may be started and components
 		// may fight for resources (e.g. ports, files, locks)
 		go func(state *componentRuntimeState) {
-			err := m.waitForStopped(state)
+			err := m.waitForStopped(ctx, state)
 			if err != nil {
-				m.logger.Errorf("updating components: failed waiting %s stop",
-					state.id)
+				m.logger.Errorf("updating components: failed waiting %s stop: %s",
+					state.id, err)
 			}
 			stoppedWg.Done()
 		}(existing)
@@ -818,21 +827,50 @@ func (m *Manager) update(model component.Model, teardown bool) error {
 	return nil
 }
 
-func (m *Manager) waitForStopped(comp *componentRuntimeState) error {
+// updateTimeout returns the timeout of the update to the model, the update timeout or the longest stop timeout
+// of the components removed by the model when it's longer, e.g. the uninstall timeout of a service.
+func (m *Manager) updateTimeout(model component.Model) time.Duration {
+	touched := make(map[string]bool, len(model.Components))
+	for _, comp := range model.Components {
+		touched[comp.ID] = true
+	}
+	timeout := m.timeouts.Update
+	m.currentMx.RLock()
+	defer m.currentMx.RUnlock()
+	for id, existing := range m.current {
+		if touched[id] {
+			continue
+		}
+		timeout = max(timeout, m.stopTimeout(existing.getCurrent()))
+	}
+	return timeout
+}
+
+// stopTimeout returns how long to wait for the component to stop.
+func (m *Manager) stopTimeout(comp component.Component) time.Duration {
+	timeout := m.timeouts.Stop
+	if comp.InputSpec != nil &&
+		comp.InputSpec.Spec.Service != nil &&
+		comp.InputSpec.Spec.Service.Operations.Uninstall != nil &&
+		comp.InputSpec.Spec.Service.Operations.Uninstall.Timeout > 0 {
+		// if component is a service and timeout is defined, use the one defined
+		timeout = comp.InputSpec.Spec.Service.Operations.Uninstall.Timeout
+	}
+	if comp.InputSpec != nil && comp.InputSpec.Spec.Command != nil {
+		// the component is stopped once drained
+		timeout += comp.InputSpec.Spec.Command.Timeouts.Drain
+	}
+	return timeout
+}
+
+func (m *Manager) waitForStopped(ctx context.Context, comp *componentRuntimeState) error {
 	if comp == nil {
 		return nil
 	}
 
 	currComp := comp.getCurrent()
 	compID := currComp.ID
-	timeout := defaultStopTimeout
-	if currComp.InputSpec != nil &&
-		currComp.InputSpec.Spec.Service != nil &&
-		currComp.InputSpec.Spec.Service.Operations.Uninstall != nil &&
-		currComp.InputSpec.Spec.Service.Operations.Uninstall.Timeout > 0 {
-		// if component is a service and timeout is defined, use the one defined
-		timeout = currComp.InputSpec.Spec.Service.Operations.Uninstall.Timeout
-	}
+	timeout := m.stopTimeout(currComp)
 
 	timeoutCh := time.After(timeout)
 	for {
@@ -854,6 +892,8 @@ func (m *Manager) waitForStopped(comp *componentRuntimeState) error {
 		m.currentMx.RUnlock()
 
 		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting: %w", ctx.Err())
 		case <-timeoutCh:
 			return fmt.Errorf("timeout exceeded after %s", timeout)
 		case <-time.After(stopCheckRetryPeriod):
@@ -865,7 +905,10 @@ func (m *Manager) waitForStopped(comp *componentRuntimeState) error {
 func (m *Manager) shutdown() {
 	// don't tear down as this is just a shutdown, so components most likely will come back
 	// on next start of the manager
-	_ = m.update(component.Model{Components: []component.Component{}}, false)
+	//
+	// the run context is already cancelled at this point, each component is still bounded
+	// by its own stop timeout
+	_ = m.update(context.Background(), component.Model{Components: []component.Component{}}, false)
 
 	// wait until all components are removed
 	for {
@@ -909,6 +952,7 @@ func (m *Manager) stateChanged(state *componentRuntimeState, latest ComponentSta
 		m.currentMx.Lock()
 		delete(m.current, state.id)
 		m.currentMx.Unlock()
+		agentmetrics.ComponentRemoved(state.id)
 
 		exit = true
 	}
@@ -984,14 +1028,37 @@ func (m *Manager) getListenAddr() string {
 	return m.listenAddr
 }
 
+// diagnosticParams are the parameters of the diagnostic action. The CPU profile duration is only set when a
+// duration other than the default one of the components is requested.
+type diagnosticParams struct {
+	client.DiagnosticParams
+	CPUProfileDuration string `json:"cpu_profile_duration,omitempty"`
+}
+
+// newDiagnosticParams returns the parameters of the diagnostic action requesting the additional metrics, with the
+// duration of the CPU profile when CPU is requested.
+func newDiagnosticParams(additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration) diagnosticParams {
+	params := diagnosticParams{}
+	for _, param := range additionalMetrics {
+		params.AdditionalMetrics = append(params.AdditionalMetrics, param.String())
+		if param == cproto.AdditionalDiagnosticRequest_CPU && cpuProfile > 0 {
+			params.CPUProfileDuration = cpuProfile.String()
+		}
+	}
+	return params
+}
+
 // performDiagAction creates a diagnostic ActionRequest and executes it against the runtime that's mapped to the specified component.
 // if the specified actionLevel is ActionRequest_COMPONENT, the unit field is ignored.
-func (m *Manager) performDiagAction(ctx context.Context, comp component.Component, unit component.Unit, actionLevel proto.ActionRequest_Level, params client.DiagnosticParams) ([]*proto.ActionDiagnosticUnitResult, error) {
+func (m *Manager) performDiagAction(ctx context.Context, comp component.Component, unit component.Unit, actionLevel proto.ActionRequest_Level, params diagnosticParams) ([]*proto.ActionDiagnosticUnitResult, error) {
 	// if we're gathering CPU diagnostics, request a longer timeout; CPU diag collection requires the diagnostic hook to sit and gather a CPU profile.
 	finalDiagnosticTime := diagnosticTimeout
 	for _, tag := range params.AdditionalMetrics {
 		if tag == "CPU" {
 			finalDiagnosticTime = diagnosticTimeoutCPU
+			if d, err := time.ParseDuration(params.CPUProfileDuration); err == nil {
+				finalDiagnosticTime = max(finalDiagnosticTime, d+diagnosticTimeout)
+			}
 			break
 		}
 	}
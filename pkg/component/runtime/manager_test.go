@@ -6,18 +6,23 @@ package runtime
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"runtime"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.elastic.co/apm/v2/apmtest"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 )
 
@@ -170,81 +175,113 @@ func waitForReady(ctx context.Context, m *Manager) error {
 	}
 }
 
-// [gRPC:8.15] Uncomment this test only after Agent/Endpoint switches fully to local gRPC, post 8.14
-// func TestDeriveCommsSocketName(t *testing.T) {
-// 	const controlAddressNix = "unix:///tmp/elastic-agent/pge4ao-u1YaV1dmSBfVX4saT8BL7b-Ey.sock"
-// 	const controlAddressWin = "npipe:///_HZ8OL-9bNW-SIU0joRfgUsej2KX0Sra.sock"
-
-// 	validControlAddress := func() string {
-// 		if runtime.GOOS == "windows" {
-// 			return controlAddressWin
-// 		}
-// 		return controlAddressNix
-// 	}
-
-// 	defaultCfg := configuration.DefaultGRPCConfig()
-
-// 	tests := []struct {
-// 		name           string
-// 		controlAddress string
-// 		port           int32
-// 		wantErr        error
-// 		want           string
-// 	}{
-// 		{
-// 			name: "empty uri not local",
-// 			port: 6789,
-// 			want: func() string {
-// 				grpcCfg := *defaultCfg
-// 				grpcCfg.Port = 6789
-// 				return grpcCfg.String()
-// 			}(),
-// 		},
-// 		{
-// 			name:    "empty uri local",
-// 			port:    -1,
-// 			wantErr: errInvalidUri,
-// 		},
-// 		{
-// 			name:           "invalid schema",
-// 			port:           -1,
-// 			controlAddress: "lunix:///2323",
-// 			wantErr:        errInvalidUri,
-// 		},
-// 		{
-// 			name:           "valid schema empty path",
-// 			port:           -1,
-// 			controlAddress: "unix://",
-// 			wantErr:        errInvalidUri,
-// 		},
-// 		{
-// 			name:           "valid path",
-// 			port:           -1,
-// 			controlAddress: validControlAddress(),
-// 			want:           validControlAddress(),
-// 		},
-// 	}
-
-// 	for _, tc := range tests {
-// 		t.Run(tc.name, func(t *testing.T) {
-// 			// Copy default config
-// 			grpcCfg := *defaultCfg // default rpc has port set to -1 == local rpc
-// 			grpcCfg.Port = tc.port
-// 			s, err := deriveCommsAddress(tc.controlAddress, &grpcCfg)
-
-// 			// If want error, test error and return
-// 			if tc.wantErr != nil {
-// 				diff := cmp.Diff(tc.wantErr, err, cmpopts.EquateErrors())
-// 				if diff != "" {
-// 					t.Fatal(diff)
-// 				}
-// 				return
-// 			}
-
-// 			diff := cmp.Diff(len(tc.want), len(s))
-// 			if diff != "" {
-// 				t.Fatal(diff)
-// 			}
-// 		})
-// 	}
-// }
+func TestDeriveCommsSocketName(t *testing.T) {
+	const controlAddressNix = "unix:///tmp/elastic-agent/pge4ao-u1YaV1dmSBfVX4saT8BL7b-Ey.sock"
+	const controlAddressWin = "npipe:///_HZ8OL-9bNW-SIU0joRfgUsej2KX0Sra.sock"
+
+	validControlAddress := func() string {
+		if runtime.GOOS == "windows" {
+			return controlAddressWin
+		}
+		return controlAddressNix
+	}
+
+	tests := []struct {
+		name           string
+		controlAddress string
+		transport      string
+		wantErr        error
+		want           string
+	}{
+		{
+			name:      "empty uri not local",
+			transport: configuration.GRPCTransportTCP,
+			want:      "localhost:6789",
+		},
+		{
+			name:      "empty uri local",
+			transport: configuration.GRPCTransportLocal,
+			wantErr:   errInvalidUri,
+		},
+		{
+			name:           "invalid schema",
+			transport:      configuration.GRPCTransportLocal,
+			controlAddress: "lunix:///2323",
+			wantErr:        errInvalidUri,
+		},
+		{
+			name:           "valid schema empty path",
+			transport:      configuration.GRPCTransportLocal,
+			controlAddress: "unix://",
+			wantErr:        errInvalidUri,
+		},
+		{
+			name:           "valid path",
+			transport:      configuration.GRPCTransportLocal,
+			controlAddress: validControlAddress(),
+			want:           validControlAddress(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			grpcCfg := *configuration.DefaultGRPCConfig()
+			grpcCfg.Port = 6789
+			grpcCfg.Transport = tc.transport
+			s, err := deriveCommsAddress(tc.controlAddress, &grpcCfg)
+
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			// the comms socket is named from the control socket, only the length of the path is preserved
+			require.Len(t, s, len(tc.want))
+		})
+	}
+}
+
+func TestNewDiagnosticParams(t *testing.T) {
+	cpu := []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CONN, cproto.AdditionalDiagnosticRequest_CPU}
+
+	params, err := json.Marshal(newDiagnosticParams(cpu, time.Minute))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"additional_metrics":["CONN","CPU"],"cpu_profile_duration":"1m0s"}`, string(params))
+
+	// the components use their default duration
+	params, err = json.Marshal(newDiagnosticParams(cpu, 0))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"additional_metrics":["CONN","CPU"]}`, string(params))
+
+	// no CPU profile is requested
+	params, err = json.Marshal(newDiagnosticParams(cpu[:1], time.Minute))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"additional_metrics":["CONN"]}`, string(params))
+}
+
+func TestManager_UpdateTimeout(t *testing.T) {
+	m := &Manager{
+		timeouts: configuration.GRPCTimeoutsConfig{Update: time.Minute, Stop: 30 * time.Second},
+		current:  make(map[string]*componentRuntimeState),
+	}
+	service := component.Component{
+		ID: "endpoint-default",
+		InputSpec: &component.InputRuntimeSpec{
+			Spec: component.InputSpec{
+				Service: &component.ServiceSpec{
+					Operations: component.ServiceOperationsSpec{
+						Uninstall: &component.ServiceOperationsCommandSpec{Timeout: 5 * time.Minute},
+					},
+				},
+			},
+		},
+	}
+	state := &componentRuntimeState{id: service.ID}
+	state.setCurrent(service)
+	m.current[service.ID] = state
+
+	assert.Equal(t, time.Minute, m.updateTimeout(component.Model{Components: []component.Component{service}}),
+		"the service is kept, the update timeout is used")
+	assert.Equal(t, 5*time.Minute, m.updateTimeout(component.Model{}),
+		"the service is uninstalled, its uninstall timeout is longer than the update timeout")
+}
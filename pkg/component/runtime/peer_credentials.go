@@ -0,0 +1,76 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc/credentials"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+)
+
+// peerVerifiedCredentials are the transport credentials of the local comms socket, they reject the connections
+// of the processes that are not running as the user of the Elastic Agent, or as root, before the TLS handshake.
+//
+// The components are always spawned as the user of the Elastic Agent, the service components run as root. On
+// Windows the credentials of the peers cannot be read, the access to the named pipe is restricted by its
+// security descriptor.
+type peerVerifiedCredentials struct {
+	credentials.TransportCredentials
+
+	log *logger.Logger
+	// uid is the user running the Elastic Agent.
+	uid uint32
+	// peerCredentials reads the credentials of the peer, allows to inject them for tests.
+	peerCredentials func(net.Conn) (uint32, uint32, error)
+}
+
+func newPeerVerifiedCredentials(log *logger.Logger, creds credentials.TransportCredentials) *peerVerifiedCredentials {
+	return &peerVerifiedCredentials{
+		TransportCredentials: creds,
+		log:                  log,
+		uid:                  uint32(os.Geteuid()), //nolint:gosec // the user ID is never negative on the platforms reading the peer credentials
+		peerCredentials:      ipc.PeerCredentials,
+	}
+}
+
+// ServerHandshake verifies the user of the peer and performs the TLS handshake.
+func (c *peerVerifiedCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if err := c.verify(conn); err != nil {
+		c.log.Warnf("Rejected a connection to the comms socket: %s", err)
+		_ = conn.Close()
+		return nil, nil, err
+	}
+	return c.TransportCredentials.ServerHandshake(conn)
+}
+
+func (c *peerVerifiedCredentials) verify(conn net.Conn) error {
+	uid, _, err := c.peerCredentials(conn)
+	if errors.Is(err, ipc.ErrPeerCredentialsUnsupported) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to verify the peer: %w", err)
+	}
+	if uid != 0 && uid != c.uid {
+		return fmt.Errorf("peer of uid %d is not running as the Elastic Agent user of uid %d", uid, c.uid)
+	}
+	return nil
+}
+
+// Clone implements credentials.TransportCredentials.
+func (c *peerVerifiedCredentials) Clone() credentials.TransportCredentials {
+	return &peerVerifiedCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		log:                  c.log,
+		uid:                  c.uid,
+		peerCredentials:      c.peerCredentials,
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package runtime
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+)
+
+func TestPeerVerifiedCredentials(t *testing.T) {
+	log, _ := loggertest.New("peer")
+	withPeer := func(uid uint32, err error) *peerVerifiedCredentials {
+		c := newPeerVerifiedCredentials(log, credentials.NewTLS(nil))
+		c.uid = 1000
+		c.peerCredentials = func(net.Conn) (uint32, uint32, error) {
+			return uid, uid, err
+		}
+		return c
+	}
+
+	assert.NoError(t, withPeer(1000, nil).verify(nil), "the agent user should be accepted")
+	assert.NoError(t, withPeer(0, nil).verify(nil), "root should be accepted")
+	assert.NoError(t, withPeer(0, ipc.ErrPeerCredentialsUnsupported).verify(nil), "the peers should be accepted when the credentials are not supported")
+	assert.Error(t, withPeer(2000, nil).verify(nil), "another user should be rejected")
+	assert.Error(t, withPeer(0, errors.New("no credentials")).verify(nil), "a peer of unknown identity should be rejected")
+
+	server, client := net.Pipe()
+	defer client.Close()
+	_, _, err := withPeer(2000, nil).ServerHandshake(server)
+	assert.Error(t, err)
+	_, err = client.Write([]byte{0})
+	assert.Error(t, err, "the connection of a rejected peer should be closed")
+}
@@ -5,11 +5,13 @@
 package runtime
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -53,6 +55,10 @@ type runtimeComm struct {
 	maxMessageSize  int
 	chunkingAllowed bool
 
+	// checkinTimeout bounds how long sending an expected message to the component can take
+	// before the check-in stream is considered unresponsive and closed.
+	checkinTimeout time.Duration
+
 	checkinConn bool
 	checkinDone chan bool
 	checkinLock sync.RWMutex
@@ -72,7 +78,7 @@ type runtimeComm struct {
 	actionsResponse chan *proto.ActionResponse
 }
 
-func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.CertificateAuthority, agentInfo info.Agent, maxMessageSize int) (*runtimeComm, error) {
+func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.CertificateAuthority, agentInfo info.Agent, maxMessageSize int, checkinTimeout time.Duration) (*runtimeComm, error) {
 	token, err := uuid.NewV4()
 	if err != nil {
 		return nil, err
@@ -95,6 +101,7 @@ func newRuntimeComm(logger *logger.Logger, listenAddr string, ca *authority.Cert
 		cert:                  pair,
 		maxMessageSize:        maxMessageSize,
 		chunkingAllowed:       false, // not allow until the client says they support it
+		checkinTimeout:        checkinTimeout,
 		checkinConn:           true,
 		initCheckinExpectedCh: make(chan *proto.CheckinExpected),
 		checkinExpected:       make(chan *proto.CheckinExpected, 1),
@@ -295,6 +302,10 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 
 	initCheckinCompleted := false
 	var afterInitCheckinExpectedCh chan *proto.CheckinExpected
+	// sent is closed once the previous send on the stream returned, a stream cannot be written concurrently
+	done := make(chan struct{})
+	close(done)
+	var sent <-chan struct{} = done
 	for {
 		var expected *proto.CheckinExpected
 		select {
@@ -319,7 +330,15 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 		case expected = <-afterInitCheckinExpectedCh:
 		}
 
-		err := sendExpectedChunked(server, expected, c.chunkingAllowed, c.maxMessageSize)
+		select {
+		case <-checkinDone:
+			return status.Error(codes.Unavailable, "component is being destroyed")
+		case <-recvDone:
+			return status.Error(codes.Unavailable, "component is being destroyed")
+		case <-sent:
+		}
+		var err error
+		sent, err = c.sendExpected(server, expected, checkinDone)
 		if err != nil {
 			c.logger.Debugf("check-in stream failed to send expected state: %s", err)
 			if reportableErr(err) {
@@ -330,6 +349,44 @@ func (c *runtimeComm) checkin(server proto.ElasticAgent_CheckinV2Server, init *p
 	}
 }
 
+// sendExpected sends the expected message to the component, giving up once checkinTimeout is reached. The
+// returned channel is closed once the send returned, the next send on the stream must wait for it.
+//
+// A component that doesn't read its check-in stream would otherwise block the send forever and with it
+// every following expected state for that component. On timeout the send is cancelled, no further chunk is
+// written, and a DeadlineExceeded status is returned, which closes the check-in stream; the pending write then
+// fails, and the component re-connects and receives the latest expected state.
+func (c *runtimeComm) sendExpected(server proto.ElasticAgent_CheckinV2Server, expected *proto.CheckinExpected, checkinDone chan bool) (<-chan struct{}, error) {
+	sent := make(chan struct{})
+	if c.checkinTimeout <= 0 {
+		defer close(sent)
+		return sent, sendExpectedChunked(server.Context(), server, expected, c.chunkingAllowed, c.maxMessageSize)
+	}
+
+	ctx, cancel := context.WithTimeout(server.Context(), c.checkinTimeout)
+	sendErr := make(chan error, 1)
+	go func() {
+		// this goroutine will not be leaked, because when the check-in stream is closed
+		// the pending send returns with an error.
+		defer close(sent)
+		sendErr <- sendExpectedChunked(ctx, server, expected, c.chunkingAllowed, c.maxMessageSize)
+	}()
+	defer cancel()
+
+	select {
+	case err := <-sendErr:
+		return sent, err
+	case <-checkinDone:
+		return sent, status.Error(codes.Unavailable, "component is being destroyed")
+	case <-ctx.Done():
+		if server.Context().Err() != nil {
+			return sent, status.Error(codes.Unavailable, "component is being destroyed")
+		}
+		c.logger.Warnf("check-in stream failed to send expected state within %s; closing connection", c.checkinTimeout)
+		return sent, status.Errorf(codes.DeadlineExceeded, "sending expected state exceeded %s", c.checkinTimeout)
+	}
+}
+
 func (c *runtimeComm) actions(server proto.ElasticAgent_ActionsServer) error {
 	c.actionsLock.Lock()
 	if c.actionsDone != nil {
@@ -446,9 +503,13 @@ func genServerName() (string, error) {
 	return strings.ReplaceAll(u.String(), "-", ""), nil
 }
 
-func sendExpectedChunked(server proto.ElasticAgent_CheckinV2Server, msg *proto.CheckinExpected, chunkingAllowed bool, maxSize int) error {
+// sendExpectedChunked sends the expected message, in chunks when allowed. No chunk is sent once ctx is done.
+func sendExpectedChunked(ctx context.Context, server proto.ElasticAgent_CheckinV2Server, msg *proto.CheckinExpected, chunkingAllowed bool, maxSize int) error {
 	if !chunkingAllowed {
 		// chunking is disabled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		return server.Send(msg)
 	}
 	msgs, err := chunk.Expected(msg, maxSize)
@@ -456,6 +517,9 @@ func sendExpectedChunked(server proto.ElasticAgent_CheckinV2Server, msg *proto.C
 		return err
 	}
 	for _, msg := range msgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := server.Send(msg); err != nil {
 			return err
 		}
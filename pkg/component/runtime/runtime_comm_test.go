@@ -620,7 +620,7 @@ func TestRuntimeComm_CheckinFlow(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			log, _ := loggertest.New("TestPolicyChangeHandler")
-			c, err := newRuntimeComm(log, "localhost", ca, agentInfoMock{}, 0)
+			c, err := newRuntimeComm(log, "localhost", ca, agentInfoMock{}, 0, 0)
 			require.NoError(t, err, "could not create runtime comm")
 
 			srv := newTestServer(t.Context())
@@ -629,6 +629,56 @@ func TestRuntimeComm_CheckinFlow(t *testing.T) {
 	}
 }
 
+func TestRuntimeComm_CheckinSendTimeout(t *testing.T) {
+	ca, err := authority.NewCA()
+	require.NoError(t, err, "could not create CA")
+
+	// remember we have slow runners in the CI
+	const waitDuration = 20 * time.Second
+
+	log, _ := loggertest.New("TestRuntimeComm_CheckinSendTimeout")
+	c, err := newRuntimeComm(log, "localhost", ca, agentInfoMock{}, 0, 100*time.Millisecond)
+	require.NoError(t, err, "could not create runtime comm")
+
+	// the test server never reads srv.expected, simulating a component that doesn't read its check-in stream
+	srv := newTestServer(t.Context())
+	initCheckinObserved := &proto.CheckinObserved{}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- c.checkin(srv, initCheckinObserved)
+	}()
+
+	select {
+	case observed := <-c.CheckinObserved():
+		assert.Equal(t, initCheckinObserved, observed, "checkin observed message must match the init checkin observed message")
+	case <-time.After(waitDuration):
+		t.Fatal("timed out waiting for the init checkin observed")
+	}
+
+	c.CheckinExpected(&proto.CheckinExpected{}, initCheckinObserved)
+
+	select {
+	case err := <-errCh:
+		s, ok := status.FromError(err)
+		assert.True(t, ok, "error must be a gRPC status")
+		assert.Equal(t, codes.DeadlineExceeded, s.Code(), "status code must be deadline exceeded")
+	case <-time.After(waitDuration):
+		t.Fatal("timed out waiting for the checkin to return")
+	}
+}
+
+func TestSendExpectedChunkedCancelled(t *testing.T) {
+	srv := newTestServer(t.Context())
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	for _, chunkingAllowed := range []bool{false, true} {
+		// the test server blocks the sends until srv.expected is read, nothing is sent once the send is cancelled
+		err := sendExpectedChunked(ctx, srv, &proto.CheckinExpected{}, chunkingAllowed, 1024*1024)
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+}
+
 func newTestServer(ctx context.Context) *testServer {
 	ctx, cancel := context.WithCancelCause(ctx)
 	return &testServer{
@@ -76,10 +76,21 @@ type ComponentState struct {
 	VersionInfo ComponentVersionInfo `yaml:"version_info"`
 
 	// The PID of the process, as obtained from the *from the Protobuf API*
-	// As of now, this is only used by Endpoint, as agent doesn't know the PID
-	// of the endpoint service. If you need the PID for beats, use the coordinator/communicator
+	// As of now, this is only reported by Endpoint, as agent doesn't know the PID
+	// of the endpoint service. The components spawned by the agent have the PID of
+	// their spawned process, 0 once it exited.
+	//
+	// A check-in without a PID keeps the known PID, see syncCheckin, so the PID of a
+	// spawned process is only set and reset by the command runtime. Only a change of
+	// the PID of a component running as a service updates the monitoring components.
 	Pid uint64
 
+	// Budget is the state of the resource budget of the component, nil when it has none.
+	Budget *BudgetState `yaml:"budget,omitempty"`
+
+	// HealthCheck is the state of the health check of the component, nil when it has none.
+	HealthCheck *HealthCheckState `yaml:"health_check,omitempty"`
+
 	// internal
 	expectedUnits map[ComponentUnitKey]expectedUnitState
 
@@ -136,6 +147,15 @@ func (s *ComponentState) Copy() (c ComponentState) {
 	c.expectedComponent = s.expectedComponent
 	c.expectedComponentIdx = s.expectedComponentIdx
 
+	if s.Budget != nil {
+		budget := *s.Budget
+		c.Budget = &budget
+	}
+	if s.HealthCheck != nil {
+		healthCheck := *s.HealthCheck
+		c.HealthCheck = &healthCheck
+	}
+
 	return c
 }
 
@@ -272,10 +292,13 @@ func (s *ComponentState) syncUnits(comp *component.Component) bool {
 	return changed
 }
 
+// syncCheckin applies the observed check-in of the component and returns true when the state changed.
+// The PID is only taken from a check-in reporting one, the components spawned by the agent check in
+// without a PID and keep the PID of their process set by the command runtime.
 func (s *ComponentState) syncCheckin(checkin *proto.CheckinObserved) bool {
 	changed := false
 
-	if s.Pid != checkin.Pid {
+	if checkin.Pid != 0 && s.Pid != checkin.Pid {
 		changed = true
 		s.Pid = checkin.Pid
 	}
@@ -314,10 +337,11 @@ func (s *ComponentState) syncCheckin(checkin *proto.CheckinObserved) bool {
 				existing.Payload = nil
 			}
 		} else {
-			if existing.unitState != existing.State || existing.unitMessage != existing.Message || diffPayload(existing.unitPayload, existing.Payload) {
+			state, msg := s.healthCheckState(existing.unitState, existing.unitMessage)
+			if state != existing.State || msg != existing.Message || diffPayload(existing.unitPayload, existing.Payload) {
 				changed = true
-				existing.State = existing.unitState
-				existing.Message = existing.unitMessage
+				existing.State = state
+				existing.Message = msg
 				existing.Payload = existing.unitPayload
 			}
 		}
@@ -497,6 +521,42 @@ func (s *ComponentState) forceState(state client.UnitState, msg string) bool {
 	return changed
 }
 
+// syncHealthCheck updates the state of the health check of the component and applies it to the units reporting
+// their own state. It returns true if the state of any of the units changed.
+func (s *ComponentState) syncHealthCheck(healthCheck *HealthCheckState) bool {
+	s.HealthCheck = healthCheck
+	changed := false
+	for k, unit := range s.Units {
+		if _, inExpected := s.expectedUnits[k]; !inExpected || unit.err != nil || unit.unitState == client.UnitStateStopped {
+			// state is not the one reported by the unit
+			continue
+		}
+		state, msg := s.healthCheckState(unit.unitState, unit.unitMessage)
+		if unit.State != state || unit.Message != msg {
+			unit.State = state
+			unit.Message = msg
+			changed = true
+		}
+
+		// unit is a copy and must be set back into the map
+		s.Units[k] = unit
+	}
+	return changed
+}
+
+// healthCheckState returns the state of a unit reporting the provided state once the failing health check of the
+// component is applied, it only ever makes the state worse.
+func (s *ComponentState) healthCheckState(state client.UnitState, msg string) (client.UnitState, string) {
+	hc := s.HealthCheck
+	if hc == nil || hc.State == client.UnitStateHealthy {
+		return state, msg
+	}
+	if state == client.UnitStateHealthy || (state == client.UnitStateDegraded && hc.State == client.UnitStateFailed) {
+		return hc.State, hc.Message
+	}
+	return state, msg
+}
+
 // forceExpectedState force updates the expected state for the entire component, forcing that state on all expected units.
 func (s *ComponentState) forceExpectedState(state client.UnitState) {
 	for k, unit := range s.expectedUnits {
@@ -509,6 +569,48 @@ func (s *ComponentState) forceExpectedState(state client.UnitState) {
 	}
 }
 
+// forceExpectedTypeState force updates the expected state of the units of the type.
+func (s *ComponentState) forceExpectedTypeState(unitType client.UnitType, state client.UnitState) {
+	for k, unit := range s.expectedUnits {
+		if k.UnitType == unitType {
+			unit.state = state
+
+			// unit is a copy and must be set back into the map
+			s.expectedUnits[k] = unit
+		}
+	}
+}
+
+// restoreExpectedState reverts the forced expected states, the units are expected in the state of the component
+// definition again.
+func (s *ComponentState) restoreExpectedState(comp *component.Component) {
+	for k, unit := range s.expectedUnits {
+		unit.state = client.UnitStateHealthy
+		if unit.err != nil {
+			unit.state = client.UnitStateFailed
+		}
+
+		// unit is a copy and must be set back into the map
+		s.expectedUnits[k] = unit
+	}
+	// adds back the units already cleaned up once stopped
+	s.syncExpected(comp)
+}
+
+// unitsStopped returns true when none of the units of the type still run.
+func (s *ComponentState) unitsStopped(unitType client.UnitType) bool {
+	for k, unit := range s.Units {
+		if k.UnitType != unitType {
+			continue
+		}
+		// units in error or missing from the last check-in do not run
+		if unit.unitState != client.UnitStateStopped && unit.err == nil && unit.configStateIdx != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // compState updates just the component state not all the units.
 func (s *ComponentState) compState(state client.UnitState, msg string) bool {
 	if s.State != state || s.Message != msg {
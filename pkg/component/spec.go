@@ -7,7 +7,13 @@ package component
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
 	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 )
 
 // Spec a components specification.
@@ -42,6 +48,17 @@ func (s *Spec) Validate() error {
 	return nil
 }
 
+// bundlesBinary returns true when an input runs the binary next to the specification, the inputs running in the
+// containers of their images or running the binary of their command path need no matching binary.
+func (s *Spec) bundlesBinary() bool {
+	for _, input := range s.Inputs {
+		if input.Image == nil && (input.Command == nil || input.Command.Path == "") {
+			return true
+		}
+	}
+	return len(s.Inputs) == 0
+}
+
 // RuntimeSpec is the specification for runtime options.
 type RuntimeSpec struct {
 	Preventions []RuntimePreventionSpec `config:"preventions,omitempty" yaml:"preventions,omitempty"`
@@ -62,6 +79,33 @@ type CommandSpec struct {
 	Log                     CommandLogSpec     `config:"log,omitempty" yaml:"log,omitempty"`
 	RestartMonitoringPeriod time.Duration      `config:"restart_monitoring_period,omitempty" yaml:"restart_monitoring_period,omitempty"`
 	MaxRestartsPerPeriod    int                `config:"maximum_restarts_per_period,omitempty" yaml:"maximum_restarts_per_period,omitempty"`
+	// RestartOnOutputChange restarts the component when its output changes, the component cannot reload its output.
+	RestartOnOutputChange bool `config:"restart_on_output_change,omitempty" yaml:"restart_on_output_change,omitempty"`
+	// LiveOutputReload marks a component restarting on its output changes as applying the changes of the
+	// credentials and of the hosts of its output live, those changes do not restart it.
+	LiveOutputReload bool `config:"live_output_reload,omitempty" yaml:"live_output_reload,omitempty"`
+	// Path is the absolute path of the binary to execute instead of the binary next to the specification, for the
+	// custom components running a binary installed on the host.
+	Path string `config:"path,omitempty" yaml:"path,omitempty"`
+	// Sandbox restricts the environment the subprocess inherits from Elastic Agent.
+	Sandbox CommandSandboxSpec `config:"sandbox,omitempty" yaml:"sandbox,omitempty"`
+}
+
+// Validate ensures correctness of command specification.
+func (s *CommandSpec) Validate() error {
+	if s.Path != "" && !filepath.IsAbs(s.Path) {
+		return fmt.Errorf("command path '%s' must be absolute", s.Path)
+	}
+	return nil
+}
+
+// CommandSandboxSpec is the specification restricting the environment variables of the subprocess. It does not
+// change the user, the privileges or the filesystem access of the subprocess, it runs as the user of Elastic
+// Agent as required to connect to its control protocol.
+type CommandSandboxSpec struct {
+	// CleanEnv starts the subprocess with only the environment variables of its specification and the ones
+	// identifying the component, instead of inheriting the environment of Elastic Agent.
+	CleanEnv bool `config:"clean_env,omitempty" yaml:"clean_env,omitempty"`
 }
 
 // CommandEnvSpec is the specification that defines environment variables that will be set to execute the subprocess.
@@ -75,6 +119,9 @@ type CommandTimeoutSpec struct {
 	Checkin time.Duration `config:"checkin,omitempty" yaml:"checkin,omitempty"`
 	Restart time.Duration `config:"restart,omitempty" yaml:"restart,omitempty"`
 	Stop    time.Duration `config:"stop,omitempty" yaml:"stop,omitempty"`
+	// Drain bounds the drain of the units before the component is stopped, 0 stops the component without
+	// draining its units.
+	Drain time.Duration `config:"drain,omitempty" yaml:"drain,omitempty"`
 }
 
 // InitDefaults initialized the defaults for the timeouts.
@@ -101,6 +148,127 @@ func (t *CommandLogSpec) InitDefaults() {
 	t.MessageKey = "message"
 }
 
+// ImageSpec is the specification for an input that executes its command in a container of an OCI image.
+type ImageSpec struct {
+	// Reference is the reference of the image in its registry, e.g. docker.elastic.co/beats/filebeat:9.1.0.
+	Reference string `config:"reference" yaml:"reference" validate:"required"`
+	// Digest pins the manifest, or the index, of the image, e.g. sha256:<hex>.
+	Digest string `config:"digest" yaml:"digest" validate:"required"`
+	// Entrypoint overrides the entrypoint of the image, the arguments of the command follow it.
+	Entrypoint []string `config:"entrypoint,omitempty" yaml:"entrypoint,omitempty"`
+}
+
+// Validate ensures correctness of image specification.
+func (s *ImageSpec) Validate() error {
+	if _, err := name.ParseReference(s.Reference); err != nil {
+		return fmt.Errorf("invalid image reference '%s': %w", s.Reference, err)
+	}
+	if _, err := v1.NewHash(s.Digest); err != nil {
+		return fmt.Errorf("invalid image digest '%s': %w", s.Digest, err)
+	}
+	if d, err := name.NewDigest(s.Reference); err == nil && d.DigestStr() != s.Digest {
+		return fmt.Errorf("image reference '%s' is pinned to another digest than '%s'", s.Reference, s.Digest)
+	}
+	return nil
+}
+
+// HealthCheckSpec is the specification of the probe the runtime runs against a running component, the units
+// of the component are reported as unhealthy when it keeps failing even if the component still checks in.
+type HealthCheckSpec struct {
+	HTTP *HealthCheckHTTPSpec `config:"http,omitempty" yaml:"http,omitempty"`
+	TCP  *HealthCheckTCPSpec  `config:"tcp,omitempty" yaml:"tcp,omitempty"`
+	Exec *HealthCheckExecSpec `config:"exec,omitempty" yaml:"exec,omitempty"`
+	// Interval is the period between the probes, the first probe runs one interval after the component started.
+	Interval time.Duration `config:"interval,omitempty" yaml:"interval,omitempty"`
+	// Timeout bounds each probe.
+	Timeout time.Duration `config:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// FailureThreshold is the number of consecutive failed probes reporting the units as unhealthy.
+	FailureThreshold int `config:"failure_threshold,omitempty" yaml:"failure_threshold,omitempty"`
+	// State is the state of the units once the threshold is reached, either degraded or failed.
+	State string `config:"state,omitempty" yaml:"state,omitempty"`
+}
+
+const (
+	// HealthCheckDegraded reports the units of a component failing its health check as degraded.
+	HealthCheckDegraded = "degraded"
+	// HealthCheckFailed reports the units of a component failing its health check as failed.
+	HealthCheckFailed = "failed"
+)
+
+// InitDefaults initialized the defaults for the health check.
+func (s *HealthCheckSpec) InitDefaults() {
+	s.Interval = 30 * time.Second
+	s.Timeout = 5 * time.Second
+	s.FailureThreshold = 3
+	s.State = HealthCheckDegraded
+}
+
+// Validate ensures correctness of health check specification.
+func (s *HealthCheckSpec) Validate() error {
+	probes := 0
+	for _, defined := range []bool{s.HTTP != nil, s.TCP != nil, s.Exec != nil} {
+		if defined {
+			probes++
+		}
+	}
+	if probes != 1 {
+		return errors.New("health check must define exactly one of http, tcp or exec")
+	}
+	if s.Interval <= 0 {
+		return errors.New("health check interval must be positive")
+	}
+	if s.Timeout <= 0 || s.Timeout > s.Interval {
+		return errors.New("health check timeout must be positive and at most the interval")
+	}
+	if s.FailureThreshold < 1 {
+		return errors.New("health check failure_threshold must be at least 1")
+	}
+	if s.State != HealthCheckDegraded && s.State != HealthCheckFailed {
+		return fmt.Errorf("health check state must be either %s or %s, not '%s'", HealthCheckDegraded, HealthCheckFailed, s.State)
+	}
+	return nil
+}
+
+// HealthCheckHTTPSpec is the specification of a health check requesting an HTTP endpoint, the probe succeeds
+// with a 2xx or 3xx status code.
+type HealthCheckHTTPSpec struct {
+	URL string `config:"url" yaml:"url" validate:"required"`
+}
+
+// Validate ensures correctness of HTTP health check specification.
+func (s *HealthCheckHTTPSpec) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		return fmt.Errorf("invalid health check url '%s': %w", s.URL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("health check url '%s' must be an absolute http or https url", s.URL)
+	}
+	return nil
+}
+
+// HealthCheckTCPSpec is the specification of a health check connecting to a TCP address.
+type HealthCheckTCPSpec struct {
+	Address string `config:"address" yaml:"address" validate:"required"`
+}
+
+// Validate ensures correctness of TCP health check specification.
+func (s *HealthCheckTCPSpec) Validate() error {
+	if _, _, err := net.SplitHostPort(s.Address); err != nil {
+		return fmt.Errorf("invalid health check address '%s': %w", s.Address, err)
+	}
+	return nil
+}
+
+// HealthCheckExecSpec is the specification of a health check executing a command, the probe succeeds when it
+// exits with code 0.
+type HealthCheckExecSpec struct {
+	// Command is the path of the executable followed by its arguments, a relative path is relative to the
+	// components directory.
+	Command []string         `config:"command" yaml:"command" validate:"required,min=1"`
+	Env     []CommandEnvSpec `config:"env,omitempty" yaml:"env,omitempty"`
+}
+
 // ServiceTimeoutSpec is the timeout specification for subprocess.
 type ServiceTimeoutSpec struct {
 	Checkin time.Duration `config:"checkin,omitempty" yaml:"checkin,omitempty"`
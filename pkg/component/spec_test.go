@@ -6,6 +6,7 @@ package component
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -111,6 +112,235 @@ func TestSpec_Validation(t *testing.T) {
         `,
 			Err: "input 'testing' at inputs.1 defines the same platform as a previous definition accessing config",
 		},
+		{
+			Name: "Image Without Command",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            service:
+              cport: 6788
+              csocket: ".teaci.sock"
+              operations:
+                install:
+                  args: ["install"]
+                uninstall:
+                  args: ["uninstall"]
+            image:
+              reference: docker.elastic.co/beats/testing:9.1.0
+              digest: sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+        `,
+			Err: "input 'testing' must define the command to run in its image accessing 'inputs.0'",
+		},
+		{
+			Name: "Image On Windows",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+              - windows/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            image:
+              reference: docker.elastic.co/beats/testing:9.1.0
+              digest: sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+        `,
+			Err: "input 'testing' defines an image, it can only run on linux and not on 'windows/amd64' accessing 'inputs.0'",
+		},
+		{
+			Name: "Image Invalid Digest",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            image:
+              reference: docker.elastic.co/beats/testing:9.1.0
+              digest: latest
+        `,
+			Err: `invalid image digest 'latest': cannot parse hash: "latest" accessing 'inputs.0.image'`,
+		},
+		{
+			Name: "Image Reference Pinned To Another Digest",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            image:
+              reference: docker.elastic.co/beats/testing@sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb
+              digest: sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+        `,
+			Err: "image reference 'docker.elastic.co/beats/testing@sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb' is pinned to another digest than 'sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa' accessing 'inputs.0.image'",
+		},
+		{
+			Name: "Valid Image",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+              - linux/arm64
+            outputs:
+              - elasticsearch
+            command:
+              args: ["-e"]
+            image:
+              reference: docker.elastic.co/beats/testing:9.1.0
+              digest: sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa
+        `,
+			CheckFn: func(t *testing.T, spec Spec) {
+				require.NotNil(t, spec.Inputs[0].Image)
+				assert.Equal(t, "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", spec.Inputs[0].Image.Digest)
+				assert.False(t, spec.bundlesBinary())
+			},
+		},
+		{
+			Name: "Relative Command Path",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command:
+              path: bin/collector
+        `,
+			Err: "command path 'bin/collector' must be absolute accessing 'inputs.0.command'",
+		},
+		{
+			Name: "Health Check Without Command",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            service:
+              cport: 6788
+              csocket: ".test.sock"
+              operations:
+                install:
+                  args: ["install"]
+                uninstall:
+                  args: ["uninstall"]
+            health_check:
+              tcp:
+                address: localhost:6789
+        `,
+			Err: "input 'testing' defines a health check, it is only evaluated for a command accessing 'inputs.0'",
+		},
+		{
+			Name: "Health Check Two Probes",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            health_check:
+              tcp:
+                address: localhost:6789
+              http:
+                url: http://localhost:6789/
+        `,
+			Err: "health check must define exactly one of http, tcp or exec accessing 'inputs.0.health_check'",
+		},
+		{
+			Name: "Health Check Unknown State",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            health_check:
+              state: stopped
+              tcp:
+                address: localhost:6789
+        `,
+			Err: "health check state must be either degraded or failed, not 'stopped' accessing 'inputs.0.health_check'",
+		},
+		{
+			Name: "Health Check Relative URL",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            health_check:
+              http:
+                url: /stats
+        `,
+			Err: "health check url '/stats' must be an absolute http or https url accessing 'inputs.0.health_check.http'",
+		},
+		{
+			Name: "Valid Health Check",
+			Spec: `
+        version: 2
+        inputs:
+          - name: testing
+            description: Testing Input
+            platforms:
+              - linux/amd64
+            outputs:
+              - elasticsearch
+            command: {}
+            health_check:
+              interval: 10s
+              http:
+                url: http://localhost:5066/stats
+        `,
+			CheckFn: func(t *testing.T, spec Spec) {
+				hc := spec.Inputs[0].HealthCheck
+				require.NotNil(t, hc)
+				assert.Equal(t, "http://localhost:5066/stats", hc.HTTP.URL)
+				assert.Equal(t, 10*time.Second, hc.Interval)
+				assert.Equal(t, 5*time.Second, hc.Timeout)
+				assert.Equal(t, 3, hc.FailureThreshold)
+				assert.Equal(t, HealthCheckDegraded, hc.State)
+			},
+		},
 		{
 			Name: "Valid",
 			Spec: `
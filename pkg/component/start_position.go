@@ -0,0 +1,153 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package component
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	startPositionKey = "start_position"
+	streamsKey       = "streams"
+
+	// StartPositionBeginning reads the existing content of newly discovered sources.
+	StartPositionBeginning = "beginning"
+	// StartPositionEnd only reads the content written after a source is discovered.
+	StartPositionEnd = "end"
+	// StartPositionSince is the prefix of the start position reading the content written in
+	// the given duration before the input is started, e.g. "since 24h".
+	StartPositionSince = "since"
+)
+
+// startPosition is the parsed value of the start_position input field.
+type startPosition struct {
+	position string
+	since    time.Duration
+}
+
+// startPositionTranslator converts a start position in the configuration keys of an input type.
+// The keys are set on each stream of the input, unless the stream already sets them.
+type startPositionTranslator func(pos startPosition) (map[string]interface{}, error)
+
+// startPositionTranslators are the log input types supporting the start_position field.
+var startPositionTranslators = map[string]startPositionTranslator{
+	"filestream": func(pos startPosition) (map[string]interface{}, error) {
+		switch pos.position {
+		case StartPositionEnd:
+			return map[string]interface{}{"seek_to_tail": true}, nil
+		case StartPositionSince:
+			return map[string]interface{}{"ignore_older": pos.since.String()}, nil
+		}
+		return map[string]interface{}{"seek_to_tail": false}, nil
+	},
+	"log": func(pos startPosition) (map[string]interface{}, error) {
+		switch pos.position {
+		case StartPositionEnd:
+			return map[string]interface{}{"tail_files": true}, nil
+		case StartPositionSince:
+			return map[string]interface{}{"ignore_older": pos.since.String()}, nil
+		}
+		return map[string]interface{}{"tail_files": false}, nil
+	},
+	"journald": func(pos startPosition) (map[string]interface{}, error) {
+		switch pos.position {
+		case StartPositionEnd:
+			return map[string]interface{}{"seek": "tail"}, nil
+		case StartPositionSince:
+			return map[string]interface{}{"seek": "since", "since": (-pos.since).String()}, nil
+		}
+		return map[string]interface{}{"seek": "head"}, nil
+	},
+	"winlog": func(pos startPosition) (map[string]interface{}, error) {
+		switch pos.position {
+		case StartPositionEnd:
+			return nil, errors.New("'end' is not supported by the winlog input")
+		case StartPositionSince:
+			return map[string]interface{}{"ignore_older": pos.since.String()}, nil
+		}
+		return nil, nil
+	},
+}
+
+// parseStartPosition parses a start_position value: "beginning", "end" or "since <duration>".
+func parseStartPosition(raw interface{}) (startPosition, error) {
+	val, ok := raw.(string)
+	if !ok {
+		return startPosition{}, fmt.Errorf("expected a string not a %T", raw)
+	}
+	fields := strings.Fields(val)
+	switch {
+	case len(fields) == 1 && (fields[0] == StartPositionBeginning || fields[0] == StartPositionEnd):
+		return startPosition{position: fields[0]}, nil
+	case len(fields) == 2 && fields[0] == StartPositionSince:
+		since, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return startPosition{}, fmt.Errorf("invalid duration %q: %w", fields[1], err)
+		}
+		if since <= 0 {
+			return startPosition{}, fmt.Errorf("duration %q must be positive", fields[1])
+		}
+		return startPosition{position: StartPositionSince, since: since}, nil
+	}
+	return startPosition{}, fmt.Errorf("invalid value %q, valid values are: %s, %s, %s <duration>",
+		val, StartPositionBeginning, StartPositionEnd, StartPositionSince)
+}
+
+// applyStartPosition removes the start_position field from the input configuration and sets the
+// equivalent configuration of the input type on each of its streams. Explicit stream configuration wins.
+// The start position only affects sources the component has no read state for, which makes it
+// effective for the newly added inputs.
+func applyStartPosition(inputType string, input map[string]interface{}) error {
+	raw, ok := input[startPositionKey]
+	if !ok {
+		return nil
+	}
+	delete(input, startPositionKey)
+
+	pos, err := parseStartPosition(raw)
+	if err != nil {
+		return err
+	}
+	translator, ok := startPositionTranslators[inputType]
+	if !ok {
+		return fmt.Errorf("not supported by the %s input", inputType)
+	}
+	settings, err := translator(pos)
+	if err != nil {
+		return err
+	}
+	if len(settings) == 0 {
+		return nil
+	}
+
+	streamsRaw, ok := input[streamsKey]
+	if !ok {
+		setMissing(input, settings)
+		return nil
+	}
+	streams, ok := streamsRaw.([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid 'streams', expected an array not a %T", streamsRaw)
+	}
+	for idx, streamRaw := range streams {
+		stream, ok := streamRaw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid 'streams.%d', expected a map not a %T", idx, streamRaw)
+		}
+		setMissing(stream, settings)
+	}
+	return nil
+}
+
+func setMissing(m map[string]interface{}, settings map[string]interface{}) {
+	for k, v := range settings {
+		if _, ok := m[k]; !ok {
+			m[k] = v
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package component
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyStartPosition(t *testing.T) {
+	testcases := map[string]struct {
+		inputType string
+		input     map[string]interface{}
+		expected  map[string]interface{}
+		err       string
+	}{
+		"no start position": {
+			inputType: "filestream",
+			input:     map[string]interface{}{"id": "logs"},
+			expected:  map[string]interface{}{"id": "logs"},
+		},
+		"filestream from the beginning": {
+			inputType: "filestream",
+			input: map[string]interface{}{
+				"start_position": "beginning",
+				"streams":        []interface{}{map[string]interface{}{"id": "a"}},
+			},
+			expected: map[string]interface{}{
+				"streams": []interface{}{map[string]interface{}{"id": "a", "seek_to_tail": false}},
+			},
+		},
+		"filestream from the end": {
+			inputType: "filestream",
+			input: map[string]interface{}{
+				"start_position": "end",
+				"streams": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b", "seek_to_tail": false},
+				},
+			},
+			expected: map[string]interface{}{
+				"streams": []interface{}{
+					map[string]interface{}{"id": "a", "seek_to_tail": true},
+					map[string]interface{}{"id": "b", "seek_to_tail": false},
+				},
+			},
+		},
+		"journald since duration": {
+			inputType: "journald",
+			input: map[string]interface{}{
+				"start_position": "since 24h",
+				"streams":        []interface{}{map[string]interface{}{"id": "a"}},
+			},
+			expected: map[string]interface{}{
+				"streams": []interface{}{map[string]interface{}{"id": "a", "seek": "since", "since": "-24h0m0s"}},
+			},
+		},
+		"log without streams": {
+			inputType: "log",
+			input:     map[string]interface{}{"start_position": "end"},
+			expected:  map[string]interface{}{"tail_files": true},
+		},
+		"invalid duration": {
+			inputType: "filestream",
+			input:     map[string]interface{}{"start_position": "since yesterday"},
+			err:       `invalid duration "yesterday"`,
+		},
+		"negative duration": {
+			inputType: "filestream",
+			input:     map[string]interface{}{"start_position": "since -1h"},
+			err:       `duration "-1h" must be positive`,
+		},
+		"not a string": {
+			inputType: "filestream",
+			input:     map[string]interface{}{"start_position": true},
+			err:       "expected a string not a bool",
+		},
+		"unsupported position": {
+			inputType: "winlog",
+			input:     map[string]interface{}{"start_position": "end"},
+			err:       "'end' is not supported by the winlog input",
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			err := applyStartPosition(tc.inputType, tc.input)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tc.input)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package control
+
+// UninstallTokenMetadataKey is the gRPC metadata key carrying the uninstall token, required by the calls
+// changing a tamper protected Elastic Agent.
+const UninstallTokenMetadataKey = "elastic-agent-uninstall-token"
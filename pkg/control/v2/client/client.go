@@ -14,6 +14,9 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"gopkg.in/yaml.v2"
 
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
 	"github.com/elastic/elastic-agent/pkg/control"
@@ -29,6 +32,12 @@ type State = cproto.State
 // CollectorComponentStatus is the status of a collector component
 type CollectorComponentStatus = cproto.CollectorComponentStatus
 
+// StatusEventSource is the source of a state change event
+type StatusEventSource = cproto.StatusEventSource
+
+// StatusEventReason is the reason of a state change event
+type StatusEventReason = cproto.StatusEventReason
+
 // AdditionalMetrics is the type for additional diagnostic requests
 type AdditionalMetrics = cproto.AdditionalDiagnosticRequest
 
@@ -79,9 +88,34 @@ const (
 	CollectorComponentStatusStopped CollectorComponentStatus = cproto.CollectorComponentStatus_StatusStopped
 )
 
+const (
+	// StatusEventSourceAgent is the overall state of the Elastic Agent.
+	StatusEventSourceAgent StatusEventSource = cproto.StatusEventSource_AGENT
+	// StatusEventSourceFleet is the state of the connection to Fleet.
+	StatusEventSourceFleet StatusEventSource = cproto.StatusEventSource_FLEET
+	// StatusEventSourceComponent is the state of a component.
+	StatusEventSourceComponent StatusEventSource = cproto.StatusEventSource_COMPONENT
+	// StatusEventSourceUnit is the state of a unit of a component.
+	StatusEventSourceUnit StatusEventSource = cproto.StatusEventSource_UNIT
+)
+
+const (
+	// StatusEventAdded is when the source appeared.
+	StatusEventAdded StatusEventReason = cproto.StatusEventReason_ADDED
+	// StatusEventStateChanged is when the state of the source changed.
+	StatusEventStateChanged StatusEventReason = cproto.StatusEventReason_STATE_CHANGED
+	// StatusEventMessageChanged is when the message of the source changed while its state didn't.
+	StatusEventMessageChanged StatusEventReason = cproto.StatusEventReason_MESSAGE_CHANGED
+	// StatusEventRemoved is when the source disappeared.
+	StatusEventRemoved StatusEventReason = cproto.StatusEventReason_REMOVED
+)
+
 const (
 	// CPU requests additional CPU diagnostics
 	CPU AdditionalMetrics = cproto.AdditionalDiagnosticRequest_CPU
+	// Profiles requests the heap and goroutine profiles of the components from their monitoring endpoints, and
+	// their CPU profile when CPU is also requested
+	Profiles AdditionalMetrics = cproto.AdditionalDiagnosticRequest_PROFILES
 )
 
 // Version is the current running version of the daemon.
@@ -142,14 +176,17 @@ type AgentStateInfo struct {
 
 // AgentState is the current state of the Elastic Agent.
 type AgentState struct {
-	Info           AgentStateInfo         `json:"info" yaml:"info"`
-	State          State                  `json:"state" yaml:"state"`
-	Message        string                 `json:"message" yaml:"message"`
-	Components     []ComponentState       `json:"components" yaml:"components"`
-	FleetState     State                  `yaml:"fleet_state"`
-	FleetMessage   string                 `yaml:"fleet_message"`
-	UpgradeDetails *cproto.UpgradeDetails `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
-	Collector      *CollectorComponent    `json:"collector,omitempty" yaml:"collector,omitempty"`
+	Info              AgentStateInfo             `json:"info" yaml:"info"`
+	State             State                      `json:"state" yaml:"state"`
+	Message           string                     `json:"message" yaml:"message"`
+	Components        []ComponentState           `json:"components" yaml:"components"`
+	FleetState        State                      `yaml:"fleet_state"`
+	FleetMessage      string                     `yaml:"fleet_message"`
+	UpgradeDetails    *cproto.UpgradeDetails     `json:"upgrade_details,omitempty" yaml:"upgrade_details,omitempty"`
+	Collector         *CollectorComponent        `json:"collector,omitempty" yaml:"collector,omitempty"`
+	FleetServer       *cproto.FleetServerState   `json:"fleet_server,omitempty" yaml:"fleet_server,omitempty"`
+	BlockedComponents []*cproto.BlockedComponent `json:"blocked_components,omitempty" yaml:"blocked_components,omitempty"`
+	FleetHosts        []*cproto.FleetHostState   `json:"fleet_hosts,omitempty" yaml:"fleet_hosts,omitempty"`
 }
 
 // DiagnosticFileResult is a diagnostic file result.
@@ -190,6 +227,93 @@ type DiagnosticComponentResult struct {
 	Results     []DiagnosticFileResult
 }
 
+// VarsMapping is a single set of variables resolved from the providers.
+type VarsMapping struct {
+	ID      string                 `json:"id,omitempty" yaml:"id,omitempty"`
+	Mapping map[string]interface{} `json:"mapping" yaml:"mapping"`
+}
+
+// AgentVars are the currently resolved variables of the running Elastic Agent.
+type AgentVars struct {
+	DefaultProvider string        `json:"default_provider" yaml:"default_provider"`
+	Vars            []VarsMapping `json:"vars" yaml:"vars"`
+}
+
+// StatusEvent is a change of the state of the Elastic Agent, of its connection to Fleet, of a component or of a unit.
+type StatusEvent struct {
+	Time          time.Time         `json:"time" yaml:"time"`
+	Source        StatusEventSource `json:"source" yaml:"source"`
+	Reason        StatusEventReason `json:"reason" yaml:"reason"`
+	ComponentID   string            `json:"component_id,omitempty" yaml:"component_id,omitempty"`
+	UnitType      UnitType          `json:"unit_type" yaml:"unit_type"`
+	UnitID        string            `json:"unit_id,omitempty" yaml:"unit_id,omitempty"`
+	PreviousState State             `json:"previous_state" yaml:"previous_state"`
+	State         State             `json:"state" yaml:"state"`
+	Message       string            `json:"message" yaml:"message"`
+}
+
+// UpgradeProgress is the progress of an ongoing upgrade of the Elastic Agent.
+type UpgradeProgress struct {
+	Time          time.Time `json:"time" yaml:"time"`
+	TargetVersion string    `json:"target_version" yaml:"target_version"`
+	ActionID      string    `json:"action_id,omitempty" yaml:"action_id,omitempty"`
+	State         string    `json:"state" yaml:"state"`
+	// DownloadPercent is between 0 and 1.
+	DownloadPercent float64 `json:"download_percent" yaml:"download_percent"`
+	// DownloadRate is in bytes per second.
+	DownloadRate float64    `json:"download_rate" yaml:"download_rate"`
+	DownloadETA  *time.Time `json:"download_eta,omitempty" yaml:"download_eta,omitempty"`
+}
+
+// ProcessResources is the resource usage of the process of the Elastic Agent or of one of its components.
+type ProcessResources struct {
+	ID string `json:"id" yaml:"id"`
+	// PID is 0 when the component has no running process.
+	PID      uint64        `json:"pid,omitempty" yaml:"pid,omitempty"`
+	CPUTime  time.Duration `json:"cpu_time" yaml:"cpu_time"`
+	RSS      uint64        `json:"rss" yaml:"rss"`
+	FDCount  uint64        `json:"fd_count" yaml:"fd_count"`
+	Restarts uint64        `json:"restarts" yaml:"restarts"`
+}
+
+// AgentResources is the resource usage of the processes of the Elastic Agent and of its components.
+type AgentResources struct {
+	Time time.Time `json:"time" yaml:"time"`
+	// Processes holds the usage of the Elastic Agent, followed by the one of each component.
+	Processes []ProcessResources `json:"processes" yaml:"processes"`
+}
+
+// AgentComponents is the component model computed by the running Elastic Agent from its policy.
+type AgentComponents struct {
+	// Components are the components with their units and configuration, with the secrets redacted.
+	Components []map[string]interface{} `json:"components" yaml:"components"`
+}
+
+// WatchStateRequest selects the state transitions watched on the running Elastic Agent.
+type WatchStateRequest struct {
+	// Sources only watches the transitions of these sources, every source when empty.
+	Sources []StatusEventSource
+	// ComponentIDs only watches the component and unit transitions of the components with these IDs, every
+	// component when empty.
+	ComponentIDs []string
+	// Reasons only watches the transitions with these reasons, every reason when empty.
+	Reasons []StatusEventReason
+}
+
+// LogsRequest selects the log lines streamed from the running Elastic Agent.
+type LogsRequest struct {
+	// Lines is the number of the last log lines received before any new log line.
+	Lines int
+	// Follow keeps streaming the log lines as they are written.
+	Follow bool
+	// ComponentID only streams the log lines of the component with this ID.
+	ComponentID string
+	// Level only streams the log lines with this level or a more severe one.
+	Level string
+	// ExcludeEvents does not stream the log lines of the event log files.
+	ExcludeEvents bool
+}
+
 // Client communicates to Elastic Agent through the control protocol.
 type Client interface {
 	// Connect connects to the running Elastic Agent.
@@ -206,16 +330,37 @@ type Client interface {
 	Restart(ctx context.Context) error
 	// Upgrade triggers upgrade of the current running daemon.
 	Upgrade(ctx context.Context, version string, rollback bool, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) (string, error)
+	// Migrate re-enrolls the current running daemon into another Fleet cluster.
+	Migrate(ctx context.Context, targetURI string, enrollmentToken string, settings []byte) error
 	// DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
-	DiagnosticAgent(ctx context.Context, additionalDiags []AdditionalMetrics) ([]DiagnosticFileResult, error)
+	// The cpuProfile duration is used when CPU is requested, 0 uses the default duration.
+	DiagnosticAgent(ctx context.Context, additionalDiags []AdditionalMetrics, cpuProfile time.Duration) ([]DiagnosticFileResult, error)
 	// DiagnosticUnits gathers diagnostics information from specific units (or all if non are provided).
 	DiagnosticUnits(ctx context.Context, units ...DiagnosticUnitRequest) ([]DiagnosticUnitResult, error)
 	// DiagnosticComponents gathers diagnostic information for specific components
 	// the additionalDiags field specifies optional diagnostics that can also be collected.
-	DiagnosticComponents(ctx context.Context, additionalDiags []AdditionalMetrics, components ...DiagnosticComponentRequest) ([]DiagnosticComponentResult, error)
+	// The cpuProfile duration is used when CPU is requested, 0 uses the default duration.
+	DiagnosticComponents(ctx context.Context, additionalDiags []AdditionalMetrics, cpuProfile time.Duration, components ...DiagnosticComponentRequest) ([]DiagnosticComponentResult, error)
 	// Configure sends a new configuration to the Elastic Agent.
 	// Only works in the case that Elastic Agent is started in testing mode.
 	Configure(ctx context.Context, config string) error
+	// Vars returns the currently resolved variables of the running agent.
+	Vars(ctx context.Context) (*AgentVars, error)
+	// WatchStatus watches the state changes of the running agent as events.
+	WatchStatus(ctx context.Context) (ClientStatusWatch, error)
+	// WatchState watches the state transitions of the running agent selected by the request as events.
+	WatchState(ctx context.Context, req WatchStateRequest) (ClientStatusWatch, error)
+	// WatchUpgradeProgress watches the progress of the upgrades of the running agent.
+	WatchUpgradeProgress(ctx context.Context) (ClientUpgradeProgressWatch, error)
+	// StreamLogs streams the log lines of the running agent and of its components.
+	StreamLogs(ctx context.Context, req LogsRequest) (ClientLogsStream, error)
+	// Resources returns the resource usage of the processes of the running agent and of its components.
+	Resources(ctx context.Context) (*AgentResources, error)
+	// Components returns the component model computed by the running agent from its policy.
+	Components(ctx context.Context) (*AgentComponents, error)
+	// MaintenanceUnlock unlocks the protected paths of a hardened install for the duration, a duration of zero
+	// or less locks them. It returns the time they are locked again, zero when they are locked.
+	MaintenanceUnlock(ctx context.Context, duration time.Duration) (time.Time, error)
 }
 
 // ClientStateWatch allows the state of the running Elastic Agent to be watched.
@@ -224,6 +369,24 @@ type ClientStateWatch interface {
 	Recv() (*AgentState, error)
 }
 
+// ClientStatusWatch allows the state changes of the running Elastic Agent to be watched.
+type ClientStatusWatch interface {
+	// Recv receives the next state change event.
+	Recv() (*StatusEvent, error)
+}
+
+// ClientUpgradeProgressWatch allows the progress of the upgrades of the running Elastic Agent to be watched.
+type ClientUpgradeProgressWatch interface {
+	// Recv receives the next upgrade progress.
+	Recv() (*UpgradeProgress, error)
+}
+
+// ClientLogsStream allows the log lines of the running Elastic Agent to be streamed.
+type ClientLogsStream interface {
+	// Recv receives the next ndjson encoded log line, without the trailing new line.
+	Recv() ([]byte, error)
+}
+
 // Option is an option to adjust how the client operates.
 type Option func(c *client)
 
@@ -234,14 +397,23 @@ func WithAddress(address string) Option {
 	}
 }
 
+// WithUninstallToken sends the uninstall token with the calls, required to change a tamper protected
+// Elastic Agent.
+func WithUninstallToken(token string) Option {
+	return func(c *client) {
+		c.uninstallToken = token
+	}
+}
+
 // client manages the state and communication to the Elastic Agent.
 type client struct {
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
-	client     cproto.ElasticAgentControlClient
-	address    string
-	maxMsgSize int
+	ctx            context.Context
+	cancel         context.CancelFunc
+	wg             sync.WaitGroup
+	client         cproto.ElasticAgentControlClient
+	address        string
+	maxMsgSize     int
+	uninstallToken string
 }
 
 // New creates a client connection to Elastic Agent.
@@ -260,6 +432,16 @@ func New(opts ...Option) Client {
 // Connect connects to the running Elastic Agent.
 func (c *client) Connect(ctx context.Context, opts ...grpc.DialOption) error {
 	c.ctx, c.cancel = context.WithCancel(ctx)
+	if c.uninstallToken != "" {
+		opts = append(opts,
+			grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+				return invoker(c.withUninstallToken(ctx), method, req, reply, cc, callOpts...)
+			}),
+			grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+				return streamer(c.withUninstallToken(ctx), desc, cc, method, callOpts...)
+			}),
+		)
+	}
 	conn, err := dialContext(ctx, c.address, c.maxMsgSize, opts...)
 	if err != nil {
 		return err
@@ -268,6 +450,11 @@ func (c *client) Connect(ctx context.Context, opts ...grpc.DialOption) error {
 	return nil
 }
 
+// withUninstallToken adds the uninstall token to the metadata of the call.
+func (c *client) withUninstallToken(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, control.UninstallTokenMetadataKey, c.uninstallToken)
+}
+
 // Disconnect disconnects from the running Elastic Agent.
 func (c *client) Disconnect() {
 	if c.cancel != nil {
@@ -346,9 +533,26 @@ func (c *client) Upgrade(ctx context.Context, version string, rollback bool, sou
 	return res.Version, nil
 }
 
+// Migrate re-enrolls the current running daemon into another Fleet cluster. The settings, when set,
+// are a JSON object of enrollment settings overriding the current ones.
+func (c *client) Migrate(ctx context.Context, targetURI string, enrollmentToken string, settings []byte) error {
+	res, err := c.client.Migrate(ctx, &cproto.MigrateRequest{
+		TargetUri:       targetURI,
+		EnrollmentToken: enrollmentToken,
+		Settings:        string(settings),
+	})
+	if err != nil {
+		return err
+	}
+	if res.Status == cproto.ActionStatus_FAILURE {
+		return errors.New(res.Error)
+	}
+	return nil
+}
+
 // DiagnosticAgent gathers diagnostics information for the running Elastic Agent.
-func (c *client) DiagnosticAgent(ctx context.Context, additionalMetrics []AdditionalMetrics) ([]DiagnosticFileResult, error) {
-	resp, err := c.client.DiagnosticAgent(ctx, &cproto.DiagnosticAgentRequest{AdditionalMetrics: additionalMetrics})
+func (c *client) DiagnosticAgent(ctx context.Context, additionalMetrics []AdditionalMetrics, cpuProfile time.Duration) ([]DiagnosticFileResult, error) {
+	resp, err := c.client.DiagnosticAgent(ctx, &cproto.DiagnosticAgentRequest{AdditionalMetrics: additionalMetrics, CpuProfileDuration: cpuProfileDuration(cpuProfile)})
 	if err != nil {
 		return nil, fmt.Errorf("error in DiagnosticAgent RPC call: %w", err)
 	}
@@ -367,17 +571,25 @@ func (c *client) DiagnosticAgent(ctx context.Context, additionalMetrics []Additi
 	return files, nil
 }
 
+// cpuProfileDuration returns the duration of the CPU profile to request, nil to use the default one.
+func cpuProfileDuration(d time.Duration) *durationpb.Duration {
+	if d <= 0 {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
 // DiagnosticComponents gathers diagnostic information for components running under elastic-agent
 // errors at the DiagnosticComponents() level are returned as an error value, errors at the level of individual components are returned in
 // the DiagnosticComponentResult struct.
-func (c *client) DiagnosticComponents(ctx context.Context, additionalMetrics []AdditionalMetrics, components ...DiagnosticComponentRequest) ([]DiagnosticComponentResult, error) {
+func (c *client) DiagnosticComponents(ctx context.Context, additionalMetrics []AdditionalMetrics, cpuProfile time.Duration, components ...DiagnosticComponentRequest) ([]DiagnosticComponentResult, error) {
 	reqs := make([]*cproto.DiagnosticComponentRequest, 0, len(components))
 	for _, u := range components {
 		reqs = append(reqs, &cproto.DiagnosticComponentRequest{
 			ComponentId: u.ComponentID,
 		})
 	}
-	respStream, err := c.client.DiagnosticComponents(ctx, &cproto.DiagnosticComponentsRequest{AdditionalMetrics: additionalMetrics, Components: reqs})
+	respStream, err := c.client.DiagnosticComponents(ctx, &cproto.DiagnosticComponentsRequest{AdditionalMetrics: additionalMetrics, CpuProfileDuration: cpuProfileDuration(cpuProfile), Components: reqs})
 	if err != nil {
 		return nil, fmt.Errorf("error in DiagnosticComponents RPC call: %w", err)
 	}
@@ -477,6 +689,187 @@ func (c *client) Configure(ctx context.Context, config string) error {
 	return err
 }
 
+// Vars returns the currently resolved variables of the running agent.
+func (c *client) Vars(ctx context.Context) (*AgentVars, error) {
+	res, err := c.client.Vars(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	vars := make([]VarsMapping, 0, len(res.Vars))
+	for _, v := range res.Vars {
+		var mapping map[string]interface{}
+		if v.Mapping != "" {
+			err := json.Unmarshal([]byte(v.Mapping), &mapping)
+			if err != nil {
+				return nil, fmt.Errorf("failed to unmarshal variables %q mapping: %w", v.Id, err)
+			}
+		}
+		vars = append(vars, VarsMapping{
+			ID:      v.Id,
+			Mapping: mapping,
+		})
+	}
+	return &AgentVars{
+		DefaultProvider: res.DefaultProvider,
+		Vars:            vars,
+	}, nil
+}
+
+// WatchStatus watches the state changes of the running agent as events.
+func (c *client) WatchStatus(ctx context.Context) (ClientStatusWatch, error) {
+	cli, err := c.client.WatchStatus(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return &statusWatcher{cli}, nil
+}
+
+// WatchState watches the state transitions of the running agent selected by the request as events.
+func (c *client) WatchState(ctx context.Context, req WatchStateRequest) (ClientStatusWatch, error) {
+	cli, err := c.client.WatchState(ctx, &cproto.WatchStateRequest{
+		Sources:      req.Sources,
+		ComponentIds: req.ComponentIDs,
+		Reasons:      req.Reasons,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &statusWatcher{cli}, nil
+}
+
+type statusWatcher struct {
+	client cproto.ElasticAgentControl_WatchStatusClient
+}
+
+// Recv receives the next state change event.
+func (sw *statusWatcher) Recv() (*StatusEvent, error) {
+	resp, err := sw.client.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return &StatusEvent{
+		Time:          resp.Time.AsTime(),
+		Source:        resp.Source,
+		Reason:        resp.Reason,
+		ComponentID:   resp.ComponentId,
+		UnitType:      resp.UnitType,
+		UnitID:        resp.UnitId,
+		PreviousState: resp.PreviousState,
+		State:         resp.State,
+		Message:       resp.Message,
+	}, nil
+}
+
+// WatchUpgradeProgress watches the progress of the upgrades of the running agent.
+func (c *client) WatchUpgradeProgress(ctx context.Context) (ClientUpgradeProgressWatch, error) {
+	cli, err := c.client.WatchUpgradeProgress(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	return &upgradeProgressWatcher{cli}, nil
+}
+
+type upgradeProgressWatcher struct {
+	client cproto.ElasticAgentControl_WatchUpgradeProgressClient
+}
+
+// Recv receives the next upgrade progress.
+func (uw *upgradeProgressWatcher) Recv() (*UpgradeProgress, error) {
+	resp, err := uw.client.Recv()
+	if err != nil {
+		return nil, err
+	}
+	progress := &UpgradeProgress{
+		Time:            resp.Time.AsTime(),
+		TargetVersion:   resp.TargetVersion,
+		ActionID:        resp.ActionId,
+		State:           resp.State,
+		DownloadPercent: float64(resp.DownloadPercent),
+		DownloadRate:    float64(resp.DownloadRate),
+	}
+	if resp.DownloadEta != nil {
+		eta := resp.DownloadEta.AsTime()
+		progress.DownloadETA = &eta
+	}
+	return progress, nil
+}
+
+// StreamLogs streams the log lines of the running agent and of its components.
+func (c *client) StreamLogs(ctx context.Context, req LogsRequest) (ClientLogsStream, error) {
+	cli, err := c.client.StreamLogs(ctx, &cproto.StreamLogsRequest{
+		Lines:         int32(req.Lines), //nolint:gosec // G115 the number of lines is a command line flag
+		Follow:        req.Follow,
+		ComponentId:   req.ComponentID,
+		Level:         req.Level,
+		ExcludeEvents: req.ExcludeEvents,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &logsStream{cli}, nil
+}
+
+// Resources returns the resource usage of the processes of the running agent and of its components.
+func (c *client) Resources(ctx context.Context) (*AgentResources, error) {
+	res, err := c.client.Resources(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	resources := &AgentResources{
+		Time:      res.Time.AsTime(),
+		Processes: make([]ProcessResources, 0, len(res.Processes)),
+	}
+	for _, p := range res.Processes {
+		resources.Processes = append(resources.Processes, ProcessResources{
+			ID:       p.Id,
+			PID:      p.Pid,
+			CPUTime:  time.Duration(p.CpuTimeMs) * time.Millisecond, //nolint:gosec // G115 the CPU time fits in a duration
+			RSS:      p.Rss,
+			FDCount:  p.FdCount,
+			Restarts: p.Restarts,
+		})
+	}
+	return resources, nil
+}
+
+// Components returns the component model computed by the running agent from its policy.
+func (c *client) Components(ctx context.Context) (*AgentComponents, error) {
+	res, err := c.client.Components(ctx, &cproto.Empty{})
+	if err != nil {
+		return nil, err
+	}
+	var comps AgentComponents
+	if err := yaml.Unmarshal(res.Model, &comps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the component model: %w", err)
+	}
+	return &comps, nil
+}
+
+// MaintenanceUnlock unlocks the protected paths of a hardened install for the duration.
+func (c *client) MaintenanceUnlock(ctx context.Context, duration time.Duration) (time.Time, error) {
+	res, err := c.client.MaintenanceUnlock(ctx, &cproto.MaintenanceUnlockRequest{Duration: durationpb.New(duration)})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if res.Until == nil {
+		return time.Time{}, nil
+	}
+	return res.Until.AsTime(), nil
+}
+
+type logsStream struct {
+	client cproto.ElasticAgentControl_StreamLogsClient
+}
+
+// Recv receives the next log line.
+func (ls *logsStream) Recv() ([]byte, error) {
+	resp, err := ls.client.Recv()
+	if err != nil {
+		return nil, err
+	}
+	return resp.Line, nil
+}
+
 type stateWatcher struct {
 	client cproto.ElasticAgentControl_StateWatchClient
 }
@@ -502,11 +895,14 @@ func toState(res *cproto.StateResponse) (*AgentState, error) {
 			Unprivileged: res.Info.Unprivileged,
 			IsManaged:    res.Info.IsManaged,
 		},
-		State:          res.State,
-		Message:        res.Message,
-		FleetState:     res.FleetState,
-		FleetMessage:   res.FleetMessage,
-		UpgradeDetails: res.UpgradeDetails,
+		State:             res.State,
+		Message:           res.Message,
+		FleetState:        res.FleetState,
+		FleetMessage:      res.FleetMessage,
+		UpgradeDetails:    res.UpgradeDetails,
+		FleetServer:       res.FleetServer,
+		BlockedComponents: res.BlockedComponents,
+		FleetHosts:        res.FleetHosts,
 
 		Components: make([]ComponentState, 0, len(res.Components)),
 	}
@@ -33,6 +33,15 @@ const (
 	ElasticAgentControl_DiagnosticUnits_FullMethodName      = "/cproto.ElasticAgentControl/DiagnosticUnits"
 	ElasticAgentControl_DiagnosticComponents_FullMethodName = "/cproto.ElasticAgentControl/DiagnosticComponents"
 	ElasticAgentControl_Configure_FullMethodName            = "/cproto.ElasticAgentControl/Configure"
+	ElasticAgentControl_Vars_FullMethodName                 = "/cproto.ElasticAgentControl/Vars"
+	ElasticAgentControl_WatchStatus_FullMethodName          = "/cproto.ElasticAgentControl/WatchStatus"
+	ElasticAgentControl_Migrate_FullMethodName              = "/cproto.ElasticAgentControl/Migrate"
+	ElasticAgentControl_WatchUpgradeProgress_FullMethodName = "/cproto.ElasticAgentControl/WatchUpgradeProgress"
+	ElasticAgentControl_StreamLogs_FullMethodName           = "/cproto.ElasticAgentControl/StreamLogs"
+	ElasticAgentControl_WatchState_FullMethodName           = "/cproto.ElasticAgentControl/WatchState"
+	ElasticAgentControl_Resources_FullMethodName            = "/cproto.ElasticAgentControl/Resources"
+	ElasticAgentControl_Components_FullMethodName           = "/cproto.ElasticAgentControl/Components"
+	ElasticAgentControl_MaintenanceUnlock_FullMethodName    = "/cproto.ElasticAgentControl/MaintenanceUnlock"
 )
 
 // ElasticAgentControlClient is the client API for ElasticAgentControl service.
@@ -65,6 +74,51 @@ type ElasticAgentControlClient interface {
 	// on any Elastic Agent that is not in TESTING_MODE will result in an error being
 	// returned and nothing occurring.
 	Configure(ctx context.Context, in *ConfigureRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Fetches the currently resolved variables from the providers of the Elastic Agent.
+	Vars(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VarsResponse, error)
+	// Streams the state changes of the Elastic Agent, of its connection to Fleet,
+	// of its components and of their units as events.
+	//
+	// Every current source is first reported with an ADDED event, the following
+	// events are only sent for the sources that changed.
+	//
+	// WatchStatus is an alias of WatchState with an empty WatchStateRequest,
+	// it is the stream of every transition used by the status command.
+	WatchStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusEvent], error)
+	// Migrate re-enrolls the Elastic Agent into another Fleet cluster.
+	//
+	// The Elastic Agent keeps running its current policy until the target cluster
+	// sends it a new one.
+	Migrate(ctx context.Context, in *MigrateRequest, opts ...grpc.CallOption) (*MigrateResponse, error)
+	// Streams the progress of the upgrades of the Elastic Agent.
+	//
+	// The current progress is first reported when an upgrade is ongoing, the
+	// following events are sent each time the progress changes.
+	WatchUpgradeProgress(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[UpgradeProgressEvent], error)
+	// Streams the log lines of the Elastic Agent and of its components.
+	//
+	// The last requested log lines are sent first, the following log lines are
+	// sent as they are written when following.
+	StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogLine], error)
+	// Streams the state transitions of the Elastic Agent, of its connection to
+	// Fleet, of its components and of their units selected by the request.
+	//
+	// Every current source selected by the request is first reported with an
+	// ADDED event, the following events are only sent for the selected
+	// transitions as they happen.
+	//
+	// WatchState and WatchStatus stream the same StatusEvent transitions,
+	// WatchState lets supervisors only receive the ones they render. StateWatch
+	// streams the complete StateResponse instead, each time any state changes.
+	WatchState(ctx context.Context, in *WatchStateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusEvent], error)
+	// Fetches the resource usage of the processes of the Elastic Agent and of its components.
+	Resources(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ResourcesResponse, error)
+	// Fetches the component model computed by the Elastic Agent from its policy.
+	Components(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ComponentsResponse, error)
+	// Unlocks the protected paths of a hardened install for maintenance.
+	//
+	// The protected paths are locked again once the requested duration elapses.
+	MaintenanceUnlock(ctx context.Context, in *MaintenanceUnlockRequest, opts ...grpc.CallOption) (*MaintenanceUnlockResponse, error)
 }
 
 type elasticAgentControlClient struct {
@@ -192,6 +246,132 @@ func (c *elasticAgentControlClient) Configure(ctx context.Context, in *Configure
 	return out, nil
 }
 
+func (c *elasticAgentControlClient) Vars(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*VarsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(VarsResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_Vars_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) WatchStatus(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ElasticAgentControl_ServiceDesc.Streams[3], ElasticAgentControl_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Empty, StatusEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_WatchStatusClient = grpc.ServerStreamingClient[StatusEvent]
+
+func (c *elasticAgentControlClient) Migrate(ctx context.Context, in *MigrateRequest, opts ...grpc.CallOption) (*MigrateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MigrateResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_Migrate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) WatchUpgradeProgress(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[UpgradeProgressEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ElasticAgentControl_ServiceDesc.Streams[4], ElasticAgentControl_WatchUpgradeProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Empty, UpgradeProgressEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_WatchUpgradeProgressClient = grpc.ServerStreamingClient[UpgradeProgressEvent]
+
+func (c *elasticAgentControlClient) StreamLogs(ctx context.Context, in *StreamLogsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[LogLine], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ElasticAgentControl_ServiceDesc.Streams[5], ElasticAgentControl_StreamLogs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamLogsRequest, LogLine]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_StreamLogsClient = grpc.ServerStreamingClient[LogLine]
+
+func (c *elasticAgentControlClient) WatchState(ctx context.Context, in *WatchStateRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StatusEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ElasticAgentControl_ServiceDesc.Streams[6], ElasticAgentControl_WatchState_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStateRequest, StatusEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_WatchStateClient = grpc.ServerStreamingClient[StatusEvent]
+
+func (c *elasticAgentControlClient) Resources(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ResourcesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResourcesResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_Resources_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) Components(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*ComponentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ComponentsResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_Components_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *elasticAgentControlClient) MaintenanceUnlock(ctx context.Context, in *MaintenanceUnlockRequest, opts ...grpc.CallOption) (*MaintenanceUnlockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MaintenanceUnlockResponse)
+	err := c.cc.Invoke(ctx, ElasticAgentControl_MaintenanceUnlock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ElasticAgentControlServer is the server API for ElasticAgentControl service.
 // All implementations must embed UnimplementedElasticAgentControlServer
 // for forward compatibility.
@@ -222,6 +402,51 @@ type ElasticAgentControlServer interface {
 	// on any Elastic Agent that is not in TESTING_MODE will result in an error being
 	// returned and nothing occurring.
 	Configure(context.Context, *ConfigureRequest) (*Empty, error)
+	// Fetches the currently resolved variables from the providers of the Elastic Agent.
+	Vars(context.Context, *Empty) (*VarsResponse, error)
+	// Streams the state changes of the Elastic Agent, of its connection to Fleet,
+	// of its components and of their units as events.
+	//
+	// Every current source is first reported with an ADDED event, the following
+	// events are only sent for the sources that changed.
+	//
+	// WatchStatus is an alias of WatchState with an empty WatchStateRequest,
+	// it is the stream of every transition used by the status command.
+	WatchStatus(*Empty, grpc.ServerStreamingServer[StatusEvent]) error
+	// Migrate re-enrolls the Elastic Agent into another Fleet cluster.
+	//
+	// The Elastic Agent keeps running its current policy until the target cluster
+	// sends it a new one.
+	Migrate(context.Context, *MigrateRequest) (*MigrateResponse, error)
+	// Streams the progress of the upgrades of the Elastic Agent.
+	//
+	// The current progress is first reported when an upgrade is ongoing, the
+	// following events are sent each time the progress changes.
+	WatchUpgradeProgress(*Empty, grpc.ServerStreamingServer[UpgradeProgressEvent]) error
+	// Streams the log lines of the Elastic Agent and of its components.
+	//
+	// The last requested log lines are sent first, the following log lines are
+	// sent as they are written when following.
+	StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogLine]) error
+	// Streams the state transitions of the Elastic Agent, of its connection to
+	// Fleet, of its components and of their units selected by the request.
+	//
+	// Every current source selected by the request is first reported with an
+	// ADDED event, the following events are only sent for the selected
+	// transitions as they happen.
+	//
+	// WatchState and WatchStatus stream the same StatusEvent transitions,
+	// WatchState lets supervisors only receive the ones they render. StateWatch
+	// streams the complete StateResponse instead, each time any state changes.
+	WatchState(*WatchStateRequest, grpc.ServerStreamingServer[StatusEvent]) error
+	// Fetches the resource usage of the processes of the Elastic Agent and of its components.
+	Resources(context.Context, *Empty) (*ResourcesResponse, error)
+	// Fetches the component model computed by the Elastic Agent from its policy.
+	Components(context.Context, *Empty) (*ComponentsResponse, error)
+	// Unlocks the protected paths of a hardened install for maintenance.
+	//
+	// The protected paths are locked again once the requested duration elapses.
+	MaintenanceUnlock(context.Context, *MaintenanceUnlockRequest) (*MaintenanceUnlockResponse, error)
 	mustEmbedUnimplementedElasticAgentControlServer()
 }
 
@@ -259,6 +484,33 @@ func (UnimplementedElasticAgentControlServer) DiagnosticComponents(*DiagnosticCo
 func (UnimplementedElasticAgentControlServer) Configure(context.Context, *ConfigureRequest) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Configure not implemented")
 }
+func (UnimplementedElasticAgentControlServer) Vars(context.Context, *Empty) (*VarsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Vars not implemented")
+}
+func (UnimplementedElasticAgentControlServer) WatchStatus(*Empty, grpc.ServerStreamingServer[StatusEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedElasticAgentControlServer) Migrate(context.Context, *MigrateRequest) (*MigrateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Migrate not implemented")
+}
+func (UnimplementedElasticAgentControlServer) WatchUpgradeProgress(*Empty, grpc.ServerStreamingServer[UpgradeProgressEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchUpgradeProgress not implemented")
+}
+func (UnimplementedElasticAgentControlServer) StreamLogs(*StreamLogsRequest, grpc.ServerStreamingServer[LogLine]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamLogs not implemented")
+}
+func (UnimplementedElasticAgentControlServer) WatchState(*WatchStateRequest, grpc.ServerStreamingServer[StatusEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchState not implemented")
+}
+func (UnimplementedElasticAgentControlServer) Resources(context.Context, *Empty) (*ResourcesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resources not implemented")
+}
+func (UnimplementedElasticAgentControlServer) Components(context.Context, *Empty) (*ComponentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Components not implemented")
+}
+func (UnimplementedElasticAgentControlServer) MaintenanceUnlock(context.Context, *MaintenanceUnlockRequest) (*MaintenanceUnlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MaintenanceUnlock not implemented")
+}
 func (UnimplementedElasticAgentControlServer) mustEmbedUnimplementedElasticAgentControlServer() {}
 func (UnimplementedElasticAgentControlServer) testEmbeddedByValue()                             {}
 
@@ -421,6 +673,140 @@ func _ElasticAgentControl_Configure_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ElasticAgentControl_Vars_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).Vars(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_Vars_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).Vars(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ElasticAgentControlServer).WatchStatus(m, &grpc.GenericServerStream[Empty, StatusEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_WatchStatusServer = grpc.ServerStreamingServer[StatusEvent]
+
+func _ElasticAgentControl_Migrate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).Migrate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_Migrate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).Migrate(ctx, req.(*MigrateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_WatchUpgradeProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ElasticAgentControlServer).WatchUpgradeProgress(m, &grpc.GenericServerStream[Empty, UpgradeProgressEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_WatchUpgradeProgressServer = grpc.ServerStreamingServer[UpgradeProgressEvent]
+
+func _ElasticAgentControl_StreamLogs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamLogsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ElasticAgentControlServer).StreamLogs(m, &grpc.GenericServerStream[StreamLogsRequest, LogLine]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_StreamLogsServer = grpc.ServerStreamingServer[LogLine]
+
+func _ElasticAgentControl_WatchState_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ElasticAgentControlServer).WatchState(m, &grpc.GenericServerStream[WatchStateRequest, StatusEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ElasticAgentControl_WatchStateServer = grpc.ServerStreamingServer[StatusEvent]
+
+func _ElasticAgentControl_Resources_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).Resources(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_Resources_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).Resources(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_Components_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).Components(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_Components_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).Components(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ElasticAgentControl_MaintenanceUnlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MaintenanceUnlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ElasticAgentControlServer).MaintenanceUnlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ElasticAgentControl_MaintenanceUnlock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ElasticAgentControlServer).MaintenanceUnlock(ctx, req.(*MaintenanceUnlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ElasticAgentControl_ServiceDesc is the grpc.ServiceDesc for ElasticAgentControl service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -452,6 +838,26 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Configure",
 			Handler:    _ElasticAgentControl_Configure_Handler,
 		},
+		{
+			MethodName: "Vars",
+			Handler:    _ElasticAgentControl_Vars_Handler,
+		},
+		{
+			MethodName: "Migrate",
+			Handler:    _ElasticAgentControl_Migrate_Handler,
+		},
+		{
+			MethodName: "Resources",
+			Handler:    _ElasticAgentControl_Resources_Handler,
+		},
+		{
+			MethodName: "Components",
+			Handler:    _ElasticAgentControl_Components_Handler,
+		},
+		{
+			MethodName: "MaintenanceUnlock",
+			Handler:    _ElasticAgentControl_MaintenanceUnlock_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -469,6 +875,26 @@ var ElasticAgentControl_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ElasticAgentControl_DiagnosticComponents_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _ElasticAgentControl_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchUpgradeProgress",
+			Handler:       _ElasticAgentControl_WatchUpgradeProgress_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamLogs",
+			Handler:       _ElasticAgentControl_StreamLogs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchState",
+			Handler:       _ElasticAgentControl_WatchState_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "control_v2.proto",
 }
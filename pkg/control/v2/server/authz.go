@@ -0,0 +1,373 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+	agentconfig "github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/control"
+	v1proto "github.com/elastic/elastic-agent/pkg/control/v1/proto"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/features"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+)
+
+// Permission is a capability granted to the clients of the control protocol.
+type Permission string
+
+const (
+	// PermissionRead allows reading the version, the state and the resource usage of the Elastic Agent.
+	PermissionRead Permission = "read"
+	// PermissionDiagnostics allows collecting the diagnostics, the variables and the logs of the Elastic Agent.
+	PermissionDiagnostics Permission = "diagnostics"
+	// PermissionManage allows restarting, upgrading, configuring and migrating the Elastic Agent.
+	PermissionManage Permission = "manage"
+)
+
+// allPermissions are granted to the clients when the authorization is disabled.
+var allPermissions = []Permission{PermissionRead, PermissionDiagnostics, PermissionManage}
+
+// methodPermissions is the permission required by each method of the control protocol, a method missing from
+// it requires PermissionManage.
+var methodPermissions = map[string]Permission{
+	cproto.ElasticAgentControl_Version_FullMethodName:              PermissionRead,
+	cproto.ElasticAgentControl_State_FullMethodName:                PermissionRead,
+	cproto.ElasticAgentControl_StateWatch_FullMethodName:           PermissionRead,
+	cproto.ElasticAgentControl_WatchStatus_FullMethodName:          PermissionRead,
+	cproto.ElasticAgentControl_WatchState_FullMethodName:           PermissionRead,
+	cproto.ElasticAgentControl_WatchUpgradeProgress_FullMethodName: PermissionRead,
+	cproto.ElasticAgentControl_Resources_FullMethodName:            PermissionRead,
+	cproto.ElasticAgentControl_DiagnosticAgent_FullMethodName:      PermissionDiagnostics,
+	cproto.ElasticAgentControl_DiagnosticUnits_FullMethodName:      PermissionDiagnostics,
+	cproto.ElasticAgentControl_DiagnosticComponents_FullMethodName: PermissionDiagnostics,
+	cproto.ElasticAgentControl_Vars_FullMethodName:                 PermissionDiagnostics,
+	cproto.ElasticAgentControl_StreamLogs_FullMethodName:           PermissionDiagnostics,
+	cproto.ElasticAgentControl_Components_FullMethodName:           PermissionDiagnostics,
+	cproto.ElasticAgentControl_Restart_FullMethodName:              PermissionManage,
+	cproto.ElasticAgentControl_Upgrade_FullMethodName:              PermissionManage,
+	cproto.ElasticAgentControl_Configure_FullMethodName:            PermissionManage,
+	cproto.ElasticAgentControl_Migrate_FullMethodName:              PermissionManage,
+	cproto.ElasticAgentControl_MaintenanceUnlock_FullMethodName:    PermissionManage,
+
+	v1proto.ElasticAgentControl_Version_FullMethodName: PermissionRead,
+	v1proto.ElasticAgentControl_Status_FullMethodName:  PermissionRead,
+	v1proto.ElasticAgentControl_Restart_FullMethodName: PermissionManage,
+	v1proto.ElasticAgentControl_Upgrade_FullMethodName: PermissionManage,
+}
+
+// tamperProtectedMethods are the methods changing the Elastic Agent, they require the uninstall token while the
+// policy enables the tamper protection.
+var tamperProtectedMethods = []string{
+	cproto.ElasticAgentControl_Restart_FullMethodName,
+	cproto.ElasticAgentControl_Upgrade_FullMethodName,
+	cproto.ElasticAgentControl_Migrate_FullMethodName,
+	cproto.ElasticAgentControl_MaintenanceUnlock_FullMethodName,
+	v1proto.ElasticAgentControl_Restart_FullMethodName,
+	v1proto.ElasticAgentControl_Upgrade_FullMethodName,
+}
+
+// localRestartMethods are the tamper protected methods root and the user running the Elastic Agent may call
+// without the uninstall token, so that the upgrade watcher restarts the Elastic Agent it rolls back.
+var localRestartMethods = []string{
+	cproto.ElasticAgentControl_Restart_FullMethodName,
+	v1proto.ElasticAgentControl_Restart_FullMethodName,
+}
+
+// AuthorizationConfig is the configuration of the authorization of the control protocol, read from
+// agent.control.authorization.
+type AuthorizationConfig struct {
+	// Enabled turns the authorization on. When off every client able to connect to the control socket is
+	// granted all the permissions.
+	Enabled bool `config:"enabled" yaml:"enabled"`
+	// SocketGroup is the local group given access to the control socket, so that its members can connect. It
+	// requires the authorization, otherwise the members of the group would be granted all the permissions.
+	SocketGroup string `config:"socket_group" yaml:"socket_group"`
+	// Rules grant permissions to local users and groups.
+	Rules []AuthorizationRule `config:"rules" yaml:"rules"`
+}
+
+// AuthorizationRule grants permissions to the local users and to the members of the local groups, given by
+// name or by ID.
+type AuthorizationRule struct {
+	Users  []string `config:"users" yaml:"users"`
+	Groups []string `config:"groups" yaml:"groups"`
+	// Permissions are the names of the granted permissions: read, diagnostics or manage.
+	Permissions []string `config:"permissions" yaml:"permissions"`
+}
+
+// DefaultAuthorizationConfig returns the default configuration of the authorization, it is disabled.
+func DefaultAuthorizationConfig() AuthorizationConfig {
+	return AuthorizationConfig{}
+}
+
+// Validate validates the configuration.
+func (c *AuthorizationConfig) Validate() error {
+	if c.SocketGroup != "" && !c.Enabled {
+		return fmt.Errorf("the control socket group %q requires the control authorization to be enabled", c.SocketGroup)
+	}
+	for i, rule := range c.Rules {
+		if len(rule.Users) == 0 && len(rule.Groups) == 0 {
+			return fmt.Errorf("invalid control authorization rule %d, it must have users or groups", i)
+		}
+		for _, p := range rule.Permissions {
+			if !slices.Contains(allPermissions, Permission(p)) {
+				return fmt.Errorf("invalid control authorization rule %d permission %q, must be one of %v", i, p, allPermissions)
+			}
+		}
+	}
+	return nil
+}
+
+// peerIdentity is the local user of a client of the control socket, with the names and the IDs of its user
+// and its groups.
+type peerIdentity struct {
+	uid    string
+	user   string
+	groups []string
+	err    error
+}
+
+// AuthType implements credentials.AuthInfo.
+func (p *peerIdentity) AuthType() string {
+	return "peer"
+}
+
+// newPeerIdentity looks up the user and the groups of the client with the given credentials.
+func newPeerIdentity(uid, gid uint32) *peerIdentity {
+	p := &peerIdentity{uid: strconv.FormatUint(uint64(uid), 10)}
+	gids := []string{strconv.FormatUint(uint64(gid), 10)}
+	if u, err := user.LookupId(p.uid); err == nil {
+		p.user = u.Username
+		if ids, err := u.GroupIds(); err == nil {
+			gids = append(gids, ids...)
+		}
+	}
+	for _, id := range gids {
+		if slices.Contains(p.groups, id) {
+			continue
+		}
+		p.groups = append(p.groups, id)
+		if g, err := user.LookupGroupId(id); err == nil {
+			p.groups = append(p.groups, g.Name)
+		}
+	}
+	return p
+}
+
+func (p *peerIdentity) String() string {
+	if p.user != "" {
+		return fmt.Sprintf("%s (uid %s)", p.user, p.uid)
+	}
+	return "uid " + p.uid
+}
+
+// authorizer authorizes the calls of the control protocol from the identity of the clients.
+type authorizer struct {
+	log *logger.Logger
+	// uid is the user running the Elastic Agent, always granted all the permissions with root.
+	uid string
+	// socketPath is the path of the control socket, empty when it is not a file.
+	socketPath string
+
+	// protection returns the protection configuration of the policy.
+	protection         func() protection.Config
+	tamperProtectionFn func() bool // allows to inject the flag for tests, defaults to features.TamperProtection
+
+	mx  sync.Mutex
+	cfg AuthorizationConfig
+	// socketGroup is the group the control socket is currently given to.
+	socketGroup string
+}
+
+func newAuthorizer(log *logger.Logger, socketPath string) *authorizer {
+	return &authorizer{
+		log:        log,
+		uid:        strconv.Itoa(os.Geteuid()),
+		socketPath: socketPath,
+		protection: func() protection.Config {
+			return protection.Config{}
+		},
+		tamperProtectionFn: features.TamperProtection,
+		cfg:                DefaultAuthorizationConfig(),
+	}
+}
+
+// Reload reads the authorization settings from the agent configuration.
+func (a *authorizer) Reload(rawConfig *agentconfig.Config) error {
+	cfg := struct {
+		Authorization AuthorizationConfig `config:"agent.control.authorization"`
+	}{
+		Authorization: DefaultAuthorizationConfig(),
+	}
+	if err := rawConfig.UnpackTo(&cfg); err != nil {
+		return fmt.Errorf("failed to unpack control authorization config: %w", err)
+	}
+	if cfg.Authorization.Enabled && !ipc.PeerCredentialsSupported {
+		a.log.Warnf("Control authorization is not supported on this platform, access to the control socket is restricted by its permissions only")
+		if cfg.Authorization.SocketGroup != "" {
+			// the members of the group could not be authorized, they would be granted all the permissions
+			a.log.Warnf("The control socket is not given to group %q, control authorization is not supported on this platform", cfg.Authorization.SocketGroup)
+			cfg.Authorization.SocketGroup = ""
+		}
+	}
+
+	a.mx.Lock()
+	defer a.mx.Unlock()
+	a.cfg = cfg.Authorization
+	if a.socketPath != "" && a.socketGroup != a.cfg.SocketGroup {
+		if err := setSocketGroup(a.socketPath, a.cfg.SocketGroup); err != nil {
+			return fmt.Errorf("failed to give the control socket to group %q: %w", a.cfg.SocketGroup, err)
+		}
+		a.socketGroup = a.cfg.SocketGroup
+	}
+	return nil
+}
+
+// permissions returns the permissions granted to the client.
+func (a *authorizer) permissions(p *peerIdentity) []Permission {
+	a.mx.Lock()
+	cfg := a.cfg
+	a.mx.Unlock()
+	if !cfg.Enabled || !ipc.PeerCredentialsSupported {
+		return allPermissions
+	}
+	if p == nil || p.err != nil {
+		return nil
+	}
+	if p.uid == "0" || p.uid == a.uid {
+		return allPermissions
+	}
+
+	var granted []Permission
+	for _, rule := range cfg.Rules {
+		matches := slices.Contains(rule.Users, p.uid) || (p.user != "" && slices.Contains(rule.Users, p.user)) ||
+			slices.ContainsFunc(rule.Groups, func(g string) bool { return slices.Contains(p.groups, g) })
+		if !matches {
+			continue
+		}
+		for _, perm := range rule.Permissions {
+			if !slices.Contains(granted, Permission(perm)) {
+				granted = append(granted, Permission(perm))
+			}
+		}
+	}
+	return granted
+}
+
+// authorize returns a PermissionDenied error when the client of the call is not granted the permission
+// required by the method.
+func (a *authorizer) authorize(ctx context.Context, method string) error {
+	required, ok := methodPermissions[method]
+	if !ok {
+		required = PermissionManage
+	}
+	var identity *peerIdentity
+	if p, ok := peer.FromContext(ctx); ok {
+		identity, _ = p.AuthInfo.(*peerIdentity)
+	}
+	if slices.Contains(a.permissions(identity), required) {
+		return a.authorizeTamperProtected(ctx, method, identity)
+	}
+
+	switch {
+	case identity == nil:
+		a.log.Warnf("Denied %s to a client of unknown identity", method)
+	case identity.err != nil:
+		a.log.Warnf("Denied %s to a client of unknown identity: %s", method, identity.err)
+	default:
+		a.log.Warnf("Denied %s to %s, it requires the %s permission", method, identity, required)
+	}
+	return status.Errorf(codes.PermissionDenied, "%s requires the %s permission", method, required)
+}
+
+// authorizeTamperProtected returns a PermissionDenied error when the call of a tamper protected method does
+// not carry a valid uninstall token.
+func (a *authorizer) authorizeTamperProtected(ctx context.Context, method string, identity *peerIdentity) error {
+	if !slices.Contains(tamperProtectedMethods, method) || !a.tamperProtectionFn() {
+		return nil
+	}
+	if slices.Contains(localRestartMethods, method) && identity != nil && identity.err == nil &&
+		(identity.uid == "0" || identity.uid == a.uid) {
+		return nil
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(control.UninstallTokenMetadataKey); len(values) > 0 {
+			token = values[0]
+		}
+	}
+	if err := protection.ValidateUninstallToken(a.protection(), token); err != nil {
+		a.log.Warnf("Denied %s to a client of the tamper protected Elastic Agent: %s", method, err)
+		return status.Errorf(codes.PermissionDenied, "%s: %s", method, err)
+	}
+	return nil
+}
+
+// unaryInterceptor authorizes the unary calls.
+func (a *authorizer) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := a.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamInterceptor authorizes the streaming calls.
+func (a *authorizer) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := a.authorize(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// peerCredentials are the transport credentials of the control socket, they only read the identity of the
+// clients from the connections.
+type peerCredentials struct{}
+
+// ClientHandshake implements credentials.TransportCredentials, the control protocol client does not use it.
+func (peerCredentials) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, nil, nil
+}
+
+// ServerHandshake reads the identity of the client, a failure is recorded in the identity so that the
+// client is only denied the calls requiring the authorization.
+func (peerCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	uid, gid, err := ipc.PeerCredentials(conn)
+	if err != nil {
+		return conn, &peerIdentity{err: err}, nil
+	}
+	return conn, newPeerIdentity(uid, gid), nil
+}
+
+// Info implements credentials.TransportCredentials.
+func (peerCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peer"}
+}
+
+// Clone implements credentials.TransportCredentials.
+func (c peerCredentials) Clone() credentials.TransportCredentials {
+	return c
+}
+
+// OverrideServerName implements credentials.TransportCredentials.
+func (peerCredentials) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build linux
+
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnPeerCredentials(t *testing.T) {
+	// the socket path must be short, the test temporary directory can be too long
+	dir, err := os.MkdirTemp("", "control")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	lis, err := net.Listen("unix", filepath.Join(dir, "elastic-agent.sock"))
+	require.NoError(t, err)
+	defer lis.Close()
+
+	client, err := net.Dial("unix", lis.Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+	conn, err := lis.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, info, err := peerCredentials{}.ServerHandshake(conn)
+	require.NoError(t, err)
+	identity, ok := info.(*peerIdentity)
+	require.True(t, ok)
+	require.NoError(t, identity.err)
+	assert.Equal(t, strconv.Itoa(os.Getuid()), identity.uid)
+	assert.Contains(t, identity.groups, strconv.Itoa(os.Getgid()))
+
+	_, info, err = peerCredentials{}.ServerHandshake(&net.TCPConn{})
+	require.NoError(t, err)
+	assert.Error(t, info.(*peerIdentity).err, "a connection without credentials should have no identity")
+}
+
+func TestSetSocketGroup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "elastic-agent.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	require.NoError(t, setSocketGroup(path, strconv.Itoa(os.Getgid())))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o770), info.Mode().Perm())
+
+	assert.Error(t, setSocketGroup(path, "no-such-group-elastic-agent"))
+}
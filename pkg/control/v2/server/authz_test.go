@@ -0,0 +1,251 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/protection"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/control/v2/client"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+)
+
+func newTestAuthorizer(t *testing.T, cfg map[string]interface{}) *authorizer {
+	log, _ := loggertest.New("control")
+	a := newAuthorizer(log, "")
+	a.uid = "1000"
+	require.NoError(t, a.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.control.authorization": cfg,
+	})))
+	return a
+}
+
+func peerContext(identity *peerIdentity) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: identity})
+}
+
+func TestAuthorizationConfigValidate(t *testing.T) {
+	cfg := DefaultAuthorizationConfig()
+	require.NoError(t, cfg.Validate())
+
+	cfg.Rules = []AuthorizationRule{{Groups: []string{"monitoring"}, Permissions: []string{"read"}}}
+	require.NoError(t, cfg.Validate())
+
+	cfg.Rules = []AuthorizationRule{{Permissions: []string{"read"}}}
+	assert.Error(t, cfg.Validate(), "a rule without users or groups is invalid")
+
+	cfg.Rules = []AuthorizationRule{{Users: []string{"nagios"}, Permissions: []string{"uninstall"}}}
+	assert.Error(t, cfg.Validate(), "an unknown permission is invalid")
+
+	cfg = DefaultAuthorizationConfig()
+	cfg.SocketGroup = "monitoring"
+	assert.Error(t, cfg.Validate(), "a socket group requires the authorization")
+	cfg.Enabled = true
+	require.NoError(t, cfg.Validate())
+}
+
+func TestAuthorizerDisabled(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]interface{}{})
+	assert.NoError(t, a.authorize(context.Background(), cproto.ElasticAgentControl_Upgrade_FullMethodName))
+	assert.NoError(t, a.authorize(peerContext(&peerIdentity{uid: "2000"}), cproto.ElasticAgentControl_Restart_FullMethodName))
+}
+
+func TestAuthorizerDisabledSocketGroup(t *testing.T) {
+	log, _ := loggertest.New("control")
+	a := newAuthorizer(log, "")
+	err := a.Reload(config.MustNewConfigFrom(map[string]interface{}{
+		"agent.control.authorization": map[string]interface{}{
+			"enabled":      false,
+			"socket_group": "monitoring",
+		},
+	}))
+	require.Error(t, err, "the members of the socket group would be granted all the permissions")
+	assert.Empty(t, a.cfg.SocketGroup)
+}
+
+func TestAuthorizerPermissions(t *testing.T) {
+	if !ipc.PeerCredentialsSupported {
+		t.Skip("control authorization is not supported on this platform")
+	}
+	a := newTestAuthorizer(t, map[string]interface{}{
+		"enabled": true,
+		"rules": []interface{}{
+			map[string]interface{}{"groups": []string{"monitoring"}, "permissions": []string{"read"}},
+			map[string]interface{}{"users": []string{"support", "3000"}, "permissions": []string{"read", "diagnostics"}},
+		},
+	})
+
+	testCases := map[string]struct {
+		identity *peerIdentity
+		method   string
+		allowed  bool
+	}{
+		"root": {
+			identity: &peerIdentity{uid: "0", user: "root"},
+			method:   cproto.ElasticAgentControl_Upgrade_FullMethodName,
+			allowed:  true,
+		},
+		"agent user": {
+			identity: &peerIdentity{uid: "1000", user: "elastic-agent"},
+			method:   cproto.ElasticAgentControl_Restart_FullMethodName,
+			allowed:  true,
+		},
+		"group member reads the state": {
+			identity: &peerIdentity{uid: "2000", user: "metricbeat", groups: []string{"2000", "metricbeat", "4000", "monitoring"}},
+			method:   cproto.ElasticAgentControl_State_FullMethodName,
+			allowed:  true,
+		},
+		"group member restarts": {
+			identity: &peerIdentity{uid: "2000", user: "metricbeat", groups: []string{"4000", "monitoring"}},
+			method:   cproto.ElasticAgentControl_Restart_FullMethodName,
+		},
+		"group member collects diagnostics": {
+			identity: &peerIdentity{uid: "2000", user: "metricbeat", groups: []string{"4000", "monitoring"}},
+			method:   cproto.ElasticAgentControl_DiagnosticAgent_FullMethodName,
+		},
+		"user by name collects diagnostics": {
+			identity: &peerIdentity{uid: "2001", user: "support"},
+			method:   cproto.ElasticAgentControl_DiagnosticAgent_FullMethodName,
+			allowed:  true,
+		},
+		"user by id streams the logs": {
+			identity: &peerIdentity{uid: "3000"},
+			method:   cproto.ElasticAgentControl_StreamLogs_FullMethodName,
+			allowed:  true,
+		},
+		"user upgrades": {
+			identity: &peerIdentity{uid: "3000"},
+			method:   cproto.ElasticAgentControl_Upgrade_FullMethodName,
+		},
+		"unknown user": {
+			identity: &peerIdentity{uid: "5000", user: "nobody"},
+			method:   cproto.ElasticAgentControl_Version_FullMethodName,
+		},
+		"unknown identity": {
+			identity: &peerIdentity{err: errors.New("no credentials")},
+			method:   cproto.ElasticAgentControl_Version_FullMethodName,
+		},
+		"unknown method": {
+			identity: &peerIdentity{uid: "3000"},
+			method:   "/cproto.ElasticAgentControl/Uninstall",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := a.authorize(peerContext(tc.identity), tc.method)
+			if tc.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, codes.PermissionDenied, status.Code(err))
+			}
+		})
+	}
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(context.Background(), cproto.ElasticAgentControl_Version_FullMethodName)),
+		"a call without identity should be denied")
+}
+
+func TestAuthorizerTamperProtected(t *testing.T) {
+	a := newTestAuthorizer(t, map[string]interface{}{})
+	a.tamperProtectionFn = func() bool { return true }
+	a.protection = func() protection.Config {
+		return protection.Config{Enabled: true, UninstallTokenHash: protection.HashUninstallToken("token")}
+	}
+	withToken := func(token string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs(control.UninstallTokenMetadataKey, token))
+	}
+
+	assert.NoError(t, a.authorize(context.Background(), cproto.ElasticAgentControl_State_FullMethodName),
+		"a method that is not tamper protected should not require the token")
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(context.Background(), cproto.ElasticAgentControl_Restart_FullMethodName)),
+		"a restart without token should be denied")
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(context.Background(), cproto.ElasticAgentControl_Upgrade_FullMethodName)),
+		"a call without token should be denied")
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(withToken("other"), cproto.ElasticAgentControl_Migrate_FullMethodName)),
+		"a call with an invalid token should be denied")
+	assert.NoError(t, a.authorize(withToken("token"), cproto.ElasticAgentControl_Upgrade_FullMethodName))
+
+	agentUser := peerContext(&peerIdentity{uid: "1000"})
+	assert.NoError(t, a.authorize(agentUser, cproto.ElasticAgentControl_Restart_FullMethodName),
+		"the agent user should restart without token")
+	assert.NoError(t, a.authorize(peerContext(&peerIdentity{uid: "0"}), cproto.ElasticAgentControl_Restart_FullMethodName),
+		"root should restart without token")
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(agentUser, cproto.ElasticAgentControl_Upgrade_FullMethodName)),
+		"the agent user should still need the token to upgrade")
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(peerContext(&peerIdentity{uid: "1001"}), cproto.ElasticAgentControl_Restart_FullMethodName)),
+		"another user should need the token to restart")
+	assert.Equal(t, codes.PermissionDenied, status.Code(a.authorize(peerContext(&peerIdentity{err: errors.New("unknown")}), cproto.ElasticAgentControl_Restart_FullMethodName)),
+		"a client of unknown identity should need the token to restart")
+
+	a.tamperProtectionFn = func() bool { return false }
+	assert.NoError(t, a.authorize(context.Background(), cproto.ElasticAgentControl_Upgrade_FullMethodName),
+		"the token should not be required when the tamper protection feature is off")
+}
+
+type restartServer struct {
+	cproto.UnimplementedElasticAgentControlServer
+	restarted chan struct{}
+}
+
+func (s *restartServer) Restart(context.Context, *cproto.Empty) (*cproto.RestartResponse, error) {
+	close(s.restarted)
+	return &cproto.RestartResponse{Status: cproto.ActionStatus_SUCCESS}, nil
+}
+
+func TestAuthorizerTamperProtectedRollbackRestart(t *testing.T) {
+	if !ipc.PeerCredentialsSupported {
+		t.Skip("the identity of the clients cannot be read on this platform")
+	}
+
+	log, _ := loggertest.New("control")
+	a := newAuthorizer(log, "")
+	a.tamperProtectionFn = func() bool { return true }
+	a.protection = func() protection.Config {
+		return protection.Config{Enabled: true, UninstallTokenHash: protection.HashUninstallToken("token")}
+	}
+
+	socket := filepath.Join(t.TempDir(), "control.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	srv := grpc.NewServer(
+		grpc.Creds(peerCredentials{}),
+		grpc.ChainUnaryInterceptor(a.unaryInterceptor),
+		grpc.StreamInterceptor(a.streamInterceptor),
+	)
+	restart := &restartServer{restarted: make(chan struct{})}
+	cproto.RegisterElasticAgentControlServer(srv, restart)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	// the upgrade watcher connects as the user running the Elastic Agent, without the uninstall token
+	c := client.New(client.WithAddress("unix://" + socket))
+	require.NoError(t, c.Connect(context.Background()))
+	defer c.Disconnect()
+
+	require.NoError(t, c.Restart(context.Background()), "the rollback should restart the tamper protected agent")
+	select {
+	case <-restart.restarted:
+	default:
+		t.Fatal("the agent was not restarted")
+	}
+	_, err = c.Upgrade(context.Background(), "9.0.0", false, "", false, false)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "the upgrade should still require the token")
+}
@@ -0,0 +1,63 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/component"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+// Components returns the component model computed by the coordinator, with the secrets redacted.
+func (s *Server) Components(_ context.Context, _ *cproto.Empty) (*cproto.ComponentsResponse, error) {
+	model, err := componentsModel(s.coord.ComponentModel())
+	if err != nil {
+		return nil, err
+	}
+	return &cproto.ComponentsResponse{Model: model}, nil
+}
+
+// componentsModel marshals the components to YAML, with the secrets of the configuration of the units redacted.
+// The configuration of the units is marshalled as it is written in the policy instead of as its protobuf structure.
+func componentsModel(comps []component.Component) ([]byte, error) {
+	raw, err := yaml.Marshal(struct {
+		Components []component.Component `yaml:"components"`
+	}{
+		Components: comps,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the component model: %w", err)
+	}
+	var model struct {
+		Components []map[string]interface{} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(raw, &model); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the component model: %w", err)
+	}
+	for i, comp := range comps {
+		units, _ := model.Components[i]["units"].([]interface{})
+		for j, unit := range comp.Units {
+			if j >= len(units) {
+				break
+			}
+			u, ok := units[j].(map[interface{}]interface{})
+			if !ok || unit.Config == nil || unit.Config.GetSource() == nil {
+				continue
+			}
+			u["config"] = diagnostics.Redact(unit.Config.GetSource().AsMap(), io.Discard)
+		}
+	}
+	raw, err = yaml.Marshal(model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the component model: %w", err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,41 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/elastic/elastic-agent-client/v7/pkg/client"
+
+	"github.com/elastic/elastic-agent/pkg/component"
+)
+
+func TestComponentsModel(t *testing.T) {
+	cfg, err := component.ExpectedConfig(map[string]interface{}{
+		"type":    "elasticsearch",
+		"hosts":   []interface{}{"https://localhost:9200"},
+		"api_key": "secret-api-key",
+	})
+	require.NoError(t, err)
+
+	model, err := componentsModel([]component.Component{{
+		ID:         "filestream-default",
+		InputType:  "filestream",
+		OutputType: "elasticsearch",
+		Units: []component.Unit{{
+			ID:     "filestream-default",
+			Type:   client.UnitTypeOutput,
+			Config: cfg,
+		}},
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, string(model), "id: filestream-default")
+	assert.Contains(t, string(model), "- https://localhost:9200", "the unit configuration should be marshalled as in the policy")
+	assert.NotContains(t, string(model), "secret-api-key", "the secrets should be redacted")
+	assert.Contains(t, string(model), "api_key: <REDACTED>", "the secrets should be redacted by key")
+}
@@ -5,17 +5,11 @@
 package server
 
 import (
-	"net"
-
 	"github.com/elastic/elastic-agent/pkg/control"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
 	"github.com/elastic/elastic-agent/pkg/ipc"
 )
 
-func createListener(log *logger.Logger) (net.Listener, error) {
-	return ipc.CreateListener(log, control.Address())
-}
-
 func cleanupListener(log *logger.Logger) {
 	ipc.CleanupListener(log, control.Address())
 }
@@ -0,0 +1,126 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+	"github.com/elastic/elastic-agent/pkg/utils"
+)
+
+// listenerFDEnv holds the file descriptor of the control socket listener handed off to the re-executed
+// Elastic Agent.
+const listenerFDEnv = "ELASTIC_AGENT_CONTROL_LISTENER_FD"
+
+// createListener creates the control socket listener, or takes over the listener handed off by the
+// Elastic Agent process that re-executed into this one.
+func createListener(log *logger.Logger) (net.Listener, error) {
+	address := control.Address()
+	if lis := inheritedListener(log, address); lis != nil {
+		log.Infof("Took over the control socket listener at %s from the previous Elastic Agent process", address)
+		return lis, nil
+	}
+	return ipc.CreateListener(log, address)
+}
+
+// inheritedListener returns the listener handed off through listenerFDEnv, nil when there is none or when
+// it is not listening at the address.
+func inheritedListener(log *logger.Logger, address string) net.Listener {
+	value, ok := os.LookupEnv(listenerFDEnv)
+	if !ok {
+		return nil
+	}
+	// the variable must not leak to the components or to a later re-exec
+	_ = os.Unsetenv(listenerFDEnv)
+
+	fd, err := strconv.Atoi(value)
+	if err != nil {
+		log.Warnf("Ignoring invalid %s %q: %s", listenerFDEnv, value, err)
+		return nil
+	}
+	f := os.NewFile(uintptr(fd), "control-listener")
+	defer f.Close()
+	lis, err := net.FileListener(f)
+	if err != nil {
+		log.Warnf("Failed to take over the control socket listener: %s", err)
+		return nil
+	}
+	path := strings.TrimPrefix(address, "unix://")
+	if lis.Addr().String() != path {
+		// the new version listens at another path, the old socket is abandoned
+		log.Infof("Handed off control socket listener is at %s instead of %s, creating a new listener", lis.Addr(), path)
+		_ = lis.Close()
+		return nil
+	}
+	return lis
+}
+
+// handoffListener keeps the listener open across the re-exec, the returned file must stay open until the
+// process executes.
+func handoffListener(lis net.Listener) (*os.File, error) {
+	ul, ok := lis.(*net.UnixListener)
+	if !ok {
+		return nil, fmt.Errorf("control listener %T is not a unix socket", lis)
+	}
+	f, err := ul.File()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the control listener file: %w", err)
+	}
+	// the duplicated descriptor is closed on exec by default
+	if _, err := unix.FcntlInt(f.Fd(), unix.F_SETFD, 0); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to keep the control listener open on exec: %w", err)
+	}
+	if err := os.Setenv(listenerFDEnv, strconv.Itoa(int(f.Fd()))); err != nil { //nolint:gosec // file descriptors fit in an int
+		_ = f.Close()
+		return nil, err
+	}
+	// the socket file must remain for the connections queued during the re-exec
+	ul.SetUnlinkOnClose(false)
+	return f, nil
+}
+
+// socketPath returns the path of the control socket file.
+func socketPath() string {
+	return strings.TrimPrefix(control.Address(), "unix://")
+}
+
+// setSocketGroup gives the control socket to the group, given by name or by ID, so that its members can
+// connect. An empty group restores the ownership and the permissions the socket is created with.
+func setSocketGroup(path string, group string) error {
+	gid := os.Getgid()
+	mode := os.FileMode(0770)
+	if group != "" {
+		g, err := user.LookupGroup(group)
+		if err != nil {
+			g, err = user.LookupGroupId(group)
+		}
+		if err != nil {
+			return err
+		}
+		gid, err = strconv.Atoi(g.Gid)
+		if err != nil {
+			return fmt.Errorf("invalid gid %q of group %s: %w", g.Gid, group, err)
+		}
+	} else if root, _ := utils.HasRoot(); root {
+		mode = 0700
+	}
+	if err := os.Chown(path, -1, gid); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,82 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+)
+
+func TestHandoffListener(t *testing.T) {
+	log, _ := loggertest.New("control")
+	// the socket path must be short, the test temporary directory can be too long
+	dir, err := os.MkdirTemp("", "control")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	path := filepath.Join(dir, "elastic-agent.sock")
+	address := "unix://" + path
+	// restores the environment once the test is done
+	t.Setenv(listenerFDEnv, "")
+
+	lis, err := ipc.CreateListener(log, address)
+	require.NoError(t, err)
+	f, err := handoffListener(lis)
+	require.NoError(t, err)
+	defer f.Close()
+
+	flags, err := unix.FcntlInt(f.Fd(), unix.F_GETFD, 0)
+	require.NoError(t, err)
+	assert.Zero(t, flags&unix.FD_CLOEXEC, "handed off listener must stay open on exec")
+
+	// the server stops, the socket remains for the connections made during the re-exec
+	require.NoError(t, lis.Close())
+	require.FileExists(t, path)
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	inherited := inheritedListener(log, address)
+	require.NotNil(t, inherited, "listener should be inherited")
+	defer inherited.Close()
+	_, set := os.LookupEnv(listenerFDEnv)
+	assert.False(t, set, "handoff variable should be removed")
+
+	accepted, err := inherited.Accept()
+	require.NoError(t, err, "connection queued during the re-exec should be accepted")
+	_ = accepted.Close()
+}
+
+func TestInheritedListenerOtherAddress(t *testing.T) {
+	log, _ := loggertest.New("control")
+	dir, err := os.MkdirTemp("", "control")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+	t.Setenv(listenerFDEnv, "")
+
+	lis, err := ipc.CreateListener(log, "unix://"+filepath.Join(dir, "old.sock"))
+	require.NoError(t, err)
+	defer lis.Close()
+	f, err := handoffListener(lis)
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.Nil(t, inheritedListener(log, "unix://"+filepath.Join(dir, "new.sock")))
+	assert.Nil(t, inheritedListener(log, "unix://"+filepath.Join(dir, "new.sock")), "handoff should only be taken once")
+}
@@ -0,0 +1,53 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package server
+
+import (
+	"net"
+	"os"
+	"time"
+
+	"github.com/elastic/elastic-agent/pkg/control"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/ipc"
+)
+
+const (
+	// listenerRetryTimeout bounds the retries of the named pipe creation, the previous Elastic Agent
+	// process can still own the pipe while it exits after a re-exec.
+	listenerRetryTimeout = 10 * time.Second
+	listenerRetryBackoff = 250 * time.Millisecond
+)
+
+// createListener creates the control named pipe listener.
+func createListener(log *logger.Logger) (net.Listener, error) {
+	deadline := time.Now().Add(listenerRetryTimeout)
+	for {
+		lis, err := ipc.CreateListener(log, control.Address())
+		if err == nil || time.Now().After(deadline) {
+			return lis, err
+		}
+		log.Debugf("Failed to create the control named pipe, retrying: %s", err)
+		time.Sleep(listenerRetryBackoff)
+	}
+}
+
+// handoffListener cannot pass the named pipe to the new process. Closing the pipe before the new process
+// is spawned lets it recreate the pipe right away, instead of once the current process exits.
+func handoffListener(_ net.Listener) (*os.File, error) {
+	return nil, nil
+}
+
+// socketPath returns no path, the named pipe is not a file.
+func socketPath() string {
+	return ""
+}
+
+// setSocketGroup is not used on Windows, the access to the named pipe is restricted by its security descriptor.
+func setSocketGroup(_ string, _ string) error {
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/perms"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+	"github.com/elastic/elastic-agent/pkg/core/logger"
+)
+
+// maxMaintenanceUnlock is the longest the protected paths stay unlocked for a single request.
+const maxMaintenanceUnlock = 24 * time.Hour
+
+// maintenance unlocks the protected paths of a hardened install for a limited time.
+type maintenance struct {
+	log     *logger.Logger
+	topPath string
+
+	// allow to inject the protection for tests, default to the perms package
+	isHardenedFn func(topPath string) bool
+	lockFn       func(topPath string) error
+	unlockFn     func(topPath string) error
+
+	mx    sync.Mutex
+	timer *time.Timer
+	until time.Time
+}
+
+func newMaintenance(log *logger.Logger, topPath string) *maintenance {
+	return &maintenance{
+		log:          log,
+		topPath:      topPath,
+		isHardenedFn: perms.IsHardened,
+		lockFn:       perms.Lock,
+		unlockFn:     perms.Unlock,
+	}
+}
+
+// unlock unlocks the protected paths until the duration elapses, capped to maxMaintenanceUnlock. A duration of
+// zero or less locks them immediately. It returns the time the paths are locked again, zero when they are locked.
+func (m *maintenance) unlock(duration time.Duration, now time.Time) (time.Time, error) {
+	if !m.isHardenedFn(m.topPath) {
+		return time.Time{}, status.Error(codes.FailedPrecondition, "the install is not hardened with protected paths")
+	}
+
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if duration <= 0 {
+		return time.Time{}, m.lockLocked()
+	}
+	duration = min(duration, maxMaintenanceUnlock)
+	if err := m.unlockFn(m.topPath); err != nil {
+		// leave the paths locked when they are partially unlocked
+		_ = m.lockFn(m.topPath)
+		return time.Time{}, status.Errorf(codes.Internal, "failed to unlock the protected paths: %s", err)
+	}
+	if m.timer != nil {
+		m.timer.Stop()
+	}
+	m.until = now.Add(duration)
+	m.timer = time.AfterFunc(duration, m.relock)
+	m.log.Infow("Protected paths unlocked for maintenance", "until", m.until)
+	return m.until, nil
+}
+
+// relock locks the protected paths again once the maintenance window elapses.
+func (m *maintenance) relock() {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if err := m.lockLocked(); err != nil {
+		m.log.Errorw("Failed to lock the protected paths after maintenance", "error.message", err)
+	}
+}
+
+// stop locks the protected paths when a maintenance window is still open.
+func (m *maintenance) stop() {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	if m.timer == nil {
+		return
+	}
+	if err := m.lockLocked(); err != nil {
+		m.log.Errorw("Failed to lock the protected paths", "error.message", err)
+	}
+}
+
+func (m *maintenance) lockLocked() error {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	m.until = time.Time{}
+	if err := m.lockFn(m.topPath); err != nil {
+		return status.Errorf(codes.Internal, "failed to lock the protected paths: %s", err)
+	}
+	m.log.Info("Protected paths locked")
+	return nil
+}
+
+// MaintenanceUnlock unlocks the protected paths of a hardened install for the requested duration.
+func (s *Server) MaintenanceUnlock(_ context.Context, req *cproto.MaintenanceUnlockRequest) (*cproto.MaintenanceUnlockResponse, error) {
+	until, err := s.maintenance.unlock(req.GetDuration().AsDuration(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	resp := &cproto.MaintenanceUnlockResponse{}
+	if !until.IsZero() {
+		resp.Until = timestamppb.New(until)
+	}
+	return resp, nil
+}
@@ -0,0 +1,97 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/elastic/elastic-agent/pkg/core/logger/loggertest"
+)
+
+type fakeProtection struct {
+	mx       sync.Mutex
+	hardened bool
+	locked   bool
+}
+
+func (p *fakeProtection) isLocked() bool {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	return p.locked
+}
+
+func (p *fakeProtection) set(locked bool) error {
+	p.mx.Lock()
+	defer p.mx.Unlock()
+	p.locked = locked
+	return nil
+}
+
+func newTestMaintenance(t *testing.T, p *fakeProtection) *maintenance {
+	log, _ := loggertest.New(t.Name())
+	m := newMaintenance(log, t.TempDir())
+	m.isHardenedFn = func(string) bool { return p.hardened }
+	m.lockFn = func(string) error { return p.set(true) }
+	m.unlockFn = func(string) error { return p.set(false) }
+	return m
+}
+
+func TestMaintenanceUnlock(t *testing.T) {
+	now := time.Now()
+
+	t.Run("not hardened", func(t *testing.T) {
+		m := newTestMaintenance(t, &fakeProtection{})
+		_, err := m.unlock(time.Hour, now)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	})
+
+	t.Run("relocks after the duration", func(t *testing.T) {
+		p := &fakeProtection{hardened: true, locked: true}
+		m := newTestMaintenance(t, p)
+		until, err := m.unlock(50*time.Millisecond, now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(50*time.Millisecond), until)
+		assert.False(t, p.isLocked())
+		assert.Eventually(t, p.isLocked, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("duration is capped", func(t *testing.T) {
+		p := &fakeProtection{hardened: true, locked: true}
+		m := newTestMaintenance(t, p)
+		defer m.stop()
+		until, err := m.unlock(48*time.Hour, now)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(maxMaintenanceUnlock), until)
+	})
+
+	t.Run("zero duration locks", func(t *testing.T) {
+		p := &fakeProtection{hardened: true, locked: true}
+		m := newTestMaintenance(t, p)
+		_, err := m.unlock(time.Hour, now)
+		require.NoError(t, err)
+		assert.False(t, p.isLocked())
+
+		until, err := m.unlock(0, now)
+		require.NoError(t, err)
+		assert.True(t, until.IsZero())
+		assert.True(t, p.isLocked())
+	})
+
+	t.Run("stop locks", func(t *testing.T) {
+		p := &fakeProtection{hardened: true, locked: true}
+		m := newTestMaintenance(t, p)
+		_, err := m.unlock(time.Hour, now)
+		require.NoError(t, err)
+		m.stop()
+		assert.True(t, p.isLocked())
+	})
+}
@@ -0,0 +1,142 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+// cpuProfileName is the name of the CPU profile in the pprof endpoints.
+const cpuProfileName = "profile"
+
+// profileFetcher reads the named pprof profile of a component from its monitoring endpoint, a CPU profile is
+// collected over the given duration.
+type profileFetcher func(ctx context.Context, componentID, name string, duration time.Duration) ([]byte, error)
+
+// componentProfile is a pprof profile read from the monitoring endpoint of a component. The names are prefixed
+// to not clash with the profiles the components add to their own diagnostics.
+type componentProfile struct {
+	pprofName   string
+	name        string
+	filename    string
+	description string
+}
+
+var componentProfiles = []componentProfile{
+	{
+		pprofName:   "goroutine",
+		name:        "monitoring-goroutine",
+		filename:    "monitoring-goroutine.pprof.gz",
+		description: "stack traces of all current goroutines, read from the monitoring endpoint",
+	},
+	{
+		pprofName:   "heap",
+		name:        "monitoring-heap",
+		filename:    "monitoring-heap.pprof.gz",
+		description: "a sampling of memory allocations of live objects, read from the monitoring endpoint",
+	},
+}
+
+var componentCPUProfile = componentProfile{
+	pprofName:   cpuProfileName,
+	name:        "monitoring-" + diagnostics.DiagCPUName,
+	filename:    "monitoring-" + diagnostics.DiagCPUFilename,
+	description: diagnostics.DiagCPUDescription + ", read from the monitoring endpoint",
+}
+
+// cpuProfileDuration returns the requested duration of a CPU profile or the default one when not set.
+func cpuProfileDuration(d *durationpb.Duration) time.Duration {
+	if d == nil || d.AsDuration() <= 0 {
+		return diagnostics.DiagCPUDuration
+	}
+	return d.AsDuration()
+}
+
+// splitProfilesRequest returns the additional metrics to request from the components themselves and, when the
+// profiles are read from the monitoring endpoints, the duration of the CPU profile to collect there, 0 when none
+// is requested.
+func splitProfilesRequest(req *cproto.DiagnosticComponentsRequest) (metrics []cproto.AdditionalDiagnosticRequest, profiles bool, cpuDuration time.Duration) {
+	for _, metric := range req.AdditionalMetrics {
+		if metric == cproto.AdditionalDiagnosticRequest_PROFILES {
+			profiles = true
+		}
+	}
+	if !profiles {
+		return req.AdditionalMetrics, false, 0
+	}
+	for _, metric := range req.AdditionalMetrics {
+		switch metric {
+		case cproto.AdditionalDiagnosticRequest_PROFILES:
+		case cproto.AdditionalDiagnosticRequest_CPU:
+			// a process can only run a single CPU profile at a time, read it from the monitoring endpoint only
+			cpuDuration = cpuProfileDuration(req.CpuProfileDuration)
+		default:
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics, true, cpuDuration
+}
+
+// collectProfiles reads the profiles of all the components in parallel, as a CPU profile blocks for its whole
+// duration. The results are in the order of the component IDs.
+func collectProfiles(ctx context.Context, componentIDs []string, cpuDuration time.Duration, fetch profileFetcher) [][]*cproto.DiagnosticFileResult {
+	results := make([][]*cproto.DiagnosticFileResult, len(componentIDs))
+	var wg sync.WaitGroup
+	for i, id := range componentIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = componentProfilesResults(ctx, id, cpuDuration, fetch)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// componentProfilesResults reads the profiles of the component, the error is returned as the content of a
+// profile that cannot be read, e.g. because pprof is not enabled on the monitoring endpoint.
+func componentProfilesResults(ctx context.Context, componentID string, cpuDuration time.Duration, fetch profileFetcher) []*cproto.DiagnosticFileResult {
+	profiles := componentProfiles
+	if cpuDuration > 0 {
+		profiles = append(profiles[:len(profiles):len(profiles)], componentCPUProfile)
+	}
+
+	res := make([]*cproto.DiagnosticFileResult, 0, len(profiles))
+	for _, p := range profiles {
+		var duration time.Duration
+		if p.pprofName == cpuProfileName {
+			duration = cpuDuration
+		}
+		content, err := fetch(ctx, componentID, p.pprofName, duration)
+		if err != nil {
+			content = []byte(fmt.Sprintf("failed to read the %s profile from the monitoring endpoint: %s", p.pprofName, err))
+		}
+		res = append(res, &cproto.DiagnosticFileResult{
+			Name:        p.name,
+			Filename:    p.filename,
+			Description: p.description,
+			ContentType: "application/octet-stream",
+			Content:     content,
+			Generated:   timestamppb.New(time.Now().UTC()),
+		})
+	}
+	return res
+}
+
+// fetchComponentProfile reads the profile from the monitoring endpoint of the component.
+func fetchComponentProfile(ctx context.Context, componentID, name string, duration time.Duration) ([]byte, error) {
+	endpoint := monitoring.PrefixedEndpoint(monitoring.BeatsMonitoringEndpoint(componentID))
+	return monitoring.GetProcessProfile(ctx, endpoint, name, duration)
+}
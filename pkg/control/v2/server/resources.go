@@ -0,0 +1,104 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/process"
+	"github.com/elastic/elastic-agent-system-metrics/metric/system/resolve"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/monitoring"
+	"github.com/elastic/elastic-agent/internal/pkg/core/monitoring/agentmetrics"
+	"github.com/elastic/elastic-agent/pkg/component/runtime"
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+// agentProcessID is the ID reported for the process of the Elastic Agent itself.
+const agentProcessID = "elastic-agent"
+
+// processSampler fills the resource usage of the process with the given PID.
+type processSampler func(pid int, usage *cproto.ProcessResources) error
+
+// rootFS resolves the paths of the process metrics against the filesystem the Elastic Agent runs on, the
+// processes of the agent and of its components are never read from an alternate host filesystem.
+type rootFS struct{}
+
+var _ resolve.Resolver = rootFS{}
+
+// ResolveHostFS returns the path from the root of the filesystem.
+func (rootFS) ResolveHostFS(path string) string {
+	return filepath.Join(string(filepath.Separator), path)
+}
+
+// IsSet returns false, the root of the filesystem is not an alternate host filesystem.
+func (rootFS) IsSet() bool {
+	return false
+}
+
+// Join joins the path elements from the root of the filesystem.
+func (rootFS) Join(path ...string) string {
+	return filepath.Join(append([]string{string(filepath.Separator)}, path...)...)
+}
+
+// Resources returns the resource usage of the processes of the Elastic Agent and of its components.
+func (s *Server) Resources(_ context.Context, _ *cproto.Empty) (*cproto.ResourcesResponse, error) {
+	state := s.coord.State()
+	return resourcesResponse(os.Getpid(), state.Components, agentmetrics.ComponentRestarts(), s.sample, time.Now()), nil
+}
+
+// resourcesResponse returns the resource usage of the agent followed by the one of the components sorted by
+// ID. The usage of a process that cannot be sampled, e.g. because it just exited, is left empty.
+func resourcesResponse(agentPID int, components []runtime.ComponentComponentState, restarts map[string]uint64, sample processSampler, now time.Time) *cproto.ResourcesResponse {
+	agent := &cproto.ProcessResources{Id: agentProcessID, Pid: uint64(agentPID)} //nolint:gosec // G115 pid is positive
+	_ = sample(agentPID, agent)
+	resp := &cproto.ResourcesResponse{
+		Time:      timestamppb.New(now),
+		Processes: []*cproto.ProcessResources{agent},
+	}
+
+	procs := make([]*cproto.ProcessResources, 0, len(components))
+	for _, comp := range components {
+		usage := &cproto.ProcessResources{
+			Id:       comp.Component.ID,
+			Pid:      comp.State.Pid,
+			Restarts: restarts[comp.Component.ID],
+		}
+		if usage.Pid != 0 {
+			_ = sample(int(usage.Pid), usage) //nolint:gosec // G115 pids fit in an int
+		}
+		procs = append(procs, usage)
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].Id < procs[j].Id })
+	resp.Processes = append(resp.Processes, procs...)
+	return resp
+}
+
+// newProcessSampler returns the sampler filling the CPU time and the resident memory of the process from the
+// system metrics read through hostfs and its open file descriptors, or handles on Windows.
+func newProcessSampler(hostfs resolve.Resolver) processSampler {
+	return func(pid int, usage *cproto.ProcessResources) error {
+		state, err := process.GetInfoForPid(hostfs, pid)
+		if err != nil {
+			return fmt.Errorf("failed to get the process %d: %w", pid, err)
+		}
+		state, err = process.FillPidMetrics(hostfs, pid, state, func(string) bool { return false })
+		if err != nil && !state.CPU.Total.Ticks.Exists() {
+			return fmt.Errorf("failed to get the metrics of the process %d: %w", pid, err)
+		}
+		usage.CpuTimeMs = state.CPU.Total.Ticks.ValueOr(0)
+		usage.Rss = state.Memory.Rss.Bytes.ValueOr(0)
+		if open, err := monitoring.OpenHandles(pid); err == nil {
+			usage.FdCount = uint64(open) //nolint:gosec // G115 the count is positive
+		}
+		return nil
+	}
+}
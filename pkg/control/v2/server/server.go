@@ -5,12 +5,17 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"net"
 	"os"
+	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/status"
@@ -23,18 +28,26 @@ import (
 
 	"go.elastic.co/apm/module/apmgrpc/v2"
 	"go.elastic.co/apm/v2"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
+	fleetgateway "github.com/elastic/elastic-agent/internal/pkg/agent/application/gateway/fleet"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/paths"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/configuration"
+	"github.com/elastic/elastic-agent/internal/pkg/agent/logs"
+	"github.com/elastic/elastic-agent/internal/pkg/config"
 	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
+	"github.com/elastic/elastic-agent/internal/pkg/fleetapi"
 	"github.com/elastic/elastic-agent/internal/pkg/release"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/core/logger"
+	"github.com/elastic/elastic-agent/pkg/features"
 )
 
 // TestModeConfigSetter is used only for testing mode.
@@ -51,16 +64,26 @@ type Server struct {
 	agentInfo  info.Agent
 	coord      *coordinator.Coordinator
 	listener   net.Listener
+	handoff    *os.File
 	server     *grpc.Server
 	tracer     *apm.Tracer
 	diagHooks  diagnostics.Hooks
 	grpcConfig *configuration.GRPCConfig
+	logsDir    string
+	authz      *authorizer
+	sample     processSampler
+
+	maintenance *maintenance
 
 	tmSetter TestModeConfigSetter
 }
 
 // New creates a new control protocol server.
 func New(log *logger.Logger, agentInfo info.Agent, coord *coordinator.Coordinator, tracer *apm.Tracer, diagHooks diagnostics.Hooks, grpcConfig *configuration.GRPCConfig) *Server {
+	authz := newAuthorizer(log, socketPath())
+	if coord != nil {
+		authz.protection = coord.Protection
+	}
 	return &Server{
 		logger:     log,
 		agentInfo:  agentInfo,
@@ -68,9 +91,19 @@ func New(log *logger.Logger, agentInfo info.Agent, coord *coordinator.Coordinato
 		tracer:     tracer,
 		diagHooks:  diagHooks,
 		grpcConfig: grpcConfig,
+		logsDir:    filepath.Join(paths.Home(), logger.DefaultLogDirectory),
+		authz:      authz,
+		sample:     newProcessSampler(rootFS{}),
+
+		maintenance: newMaintenance(log, paths.Top()),
 	}
 }
 
+// Reload reads the authorization of the control protocol from the agent configuration.
+func (s *Server) Reload(rawConfig *config.Config) error {
+	return s.authz.Reload(rawConfig)
+}
+
 // SetTestModeConfigSetter sets the test mode configuration setter.
 func (s *Server) SetTestModeConfigSetter(setter TestModeConfigSetter) {
 	s.tmSetter = setter
@@ -90,12 +123,17 @@ func (s *Server) Start() error {
 	}
 	s.logger.With("address", control.Address()).Infof("GRPC control socket listening at %s", control.Address())
 	s.listener = lis
+	unaryInterceptors := []grpc.UnaryServerInterceptor{s.authz.unaryInterceptor}
 	if s.tracer != nil {
 		apmInterceptor := apmgrpc.NewUnaryServerInterceptor(apmgrpc.WithRecovery(), apmgrpc.WithTracer(s.tracer))
-		s.server = grpc.NewServer(grpc.UnaryInterceptor(apmInterceptor), grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize))
-	} else {
-		s.server = grpc.NewServer(grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize))
+		unaryInterceptors = append([]grpc.UnaryServerInterceptor{apmInterceptor}, unaryInterceptors...)
 	}
+	s.server = grpc.NewServer(
+		grpc.Creds(peerCredentials{}),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.StreamInterceptor(s.authz.streamInterceptor),
+		grpc.MaxRecvMsgSize(s.grpcConfig.MaxMsgSize),
+	)
 	cproto.RegisterElasticAgentControlServer(s.server, s)
 
 	v1Wrapper := v1server.New(s.logger, s, s.tracer)
@@ -114,6 +152,7 @@ func (s *Server) Start() error {
 
 // Stop stops the GRPC endpoint.
 func (s *Server) Stop() {
+	s.maintenance.stop()
 	if s.server != nil {
 		s.server.Stop()
 		s.server = nil
@@ -122,6 +161,28 @@ func (s *Server) Stop() {
 	}
 }
 
+// Handoff stops serving and hands off the control listener to the Elastic Agent that is about to be
+// re-executed. On Unix the listener is inherited by the new process, the connections made during the
+// restart are queued and served once it runs. On Windows the named pipe is closed to be recreated by the
+// new process. Stop is a no-op after a successful Handoff.
+func (s *Server) Handoff() error {
+	if s.server == nil {
+		return nil
+	}
+	f, err := handoffListener(s.listener)
+	if err != nil {
+		return err
+	}
+	s.handoff = f
+	s.server.Stop()
+	s.server = nil
+	s.listener = nil
+	if f == nil {
+		cleanupListener(s.logger)
+	}
+	return nil
+}
+
 // Version returns the currently running version.
 func (s *Server) Version(_ context.Context, _ *cproto.Empty) (*cproto.VersionResponse, error) {
 	return &cproto.VersionResponse{
@@ -164,6 +225,151 @@ func (s *Server) StateWatch(_ *cproto.Empty, srv cproto.ElasticAgentControl_Stat
 	}
 }
 
+// WatchStatus streams the state changes of the Elastic Agent to the client as events, it is an alias of
+// WatchState with an empty request.
+func (s *Server) WatchStatus(_ *cproto.Empty, srv cproto.ElasticAgentControl_WatchStatusServer) error {
+	return s.WatchState(&cproto.WatchStateRequest{}, srv)
+}
+
+// WatchState streams the state transitions of the Elastic Agent selected by the request to the client.
+func (s *Server) WatchState(req *cproto.WatchStateRequest, srv cproto.ElasticAgentControl_WatchStateServer) error {
+	ctx := srv.Context()
+	return s.watchStatusEvents(ctx, s.coord.StateSubscribe(ctx, 32), newStatusEventFilter(req), srv.Send)
+}
+
+// watchStatusEvents sends the events of the state changes received from subChan selected by the filter, every
+// event is sent when the filter is nil.
+func (s *Server) watchStatusEvents(ctx context.Context, subChan <-chan coordinator.State, filter *statusEventFilter, send func(*cproto.StatusEvent) error) error {
+	var prev *cproto.StateResponse
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case state := <-subChan:
+			curr, err := stateToProto(&state, s.agentInfo)
+			if err != nil {
+				return err
+			}
+			for _, event := range statusEvents(prev, curr, time.Now()) {
+				if !filter.match(event) {
+					continue
+				}
+				if err := send(event); err != nil {
+					return err
+				}
+			}
+			prev = curr
+		}
+	}
+}
+
+// WatchUpgradeProgress streams the progress of the upgrades of the Elastic Agent.
+func (s *Server) WatchUpgradeProgress(_ *cproto.Empty, srv cproto.ElasticAgentControl_WatchUpgradeProgressServer) error {
+	ctx := srv.Context()
+	subChan := s.coord.StateSubscribe(ctx, 32)
+	var prev *details.Details
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case state := <-subChan:
+			curr := state.UpgradeDetails
+			if curr == nil || curr.Equals(prev) {
+				prev = curr
+				continue
+			}
+			if err := srv.Send(upgradeProgressEvent(curr, time.Now())); err != nil {
+				return err
+			}
+			prev = curr
+		}
+	}
+}
+
+// StreamLogs streams the log lines of the Elastic Agent and of its components from its log files.
+func (s *Server) StreamLogs(req *cproto.StreamLogsRequest, srv cproto.ElasticAgentControl_StreamLogsServer) error {
+	var filters []logs.FilterFunc
+	if req.ComponentId != "" {
+		filters = append(filters, logs.ComponentFilter(req.ComponentId))
+	}
+	if req.Level != "" {
+		level, err := logs.ParseLevel(req.Level)
+		if err != nil {
+			return fmt.Errorf("invalid log level: %w", err)
+		}
+		filters = append(filters, logs.LevelFilter(level))
+	}
+	filter := logs.AllFilters(filters...)
+
+	dirs := []string{s.logsDir}
+	if !req.ExcludeEvents {
+		dirs = append(dirs, filepath.Join(s.logsDir, "events"))
+	}
+
+	var mx sync.Mutex
+	g, ctx := errgroup.WithContext(srv.Context())
+	for _, dir := range dirs {
+		w := &logLineSender{mx: &mx, srv: srv}
+		g.Go(func() error {
+			err := logs.Print(ctx, w, dir, int(req.Lines), req.Follow, filter, nil)
+			if errors.Is(err, fs.ErrNotExist) && dir != s.logsDir {
+				// the event log files are only written once an event is logged
+				return nil
+			}
+			if err != nil && ctx.Err() == nil {
+				return err
+			}
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// logLineSender sends every complete line written to it as a log line of the stream.
+type logLineSender struct {
+	mx  *sync.Mutex
+	srv cproto.ElasticAgentControl_StreamLogsServer
+	buf []byte
+}
+
+// Write implements `io.Writer`
+func (w *logLineSender) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.Clone(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if len(line) == 0 {
+			continue
+		}
+		w.mx.Lock()
+		err := w.srv.Send(&cproto.LogLine{Line: line})
+		w.mx.Unlock()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func upgradeProgressEvent(d *details.Details, now time.Time) *cproto.UpgradeProgressEvent {
+	event := &cproto.UpgradeProgressEvent{
+		Time:            timestamppb.New(now),
+		TargetVersion:   d.TargetVersion,
+		ActionId:        d.ActionID,
+		State:           string(d.State),
+		DownloadPercent: float32(d.Metadata.DownloadPercent),
+		DownloadRate:    float32(d.Metadata.DownloadRate),
+	}
+	if d.Metadata.DownloadETA != nil {
+		event.DownloadEta = timestamppb.New(*d.Metadata.DownloadETA)
+	}
+	return event
+}
+
 // Restart performs re-exec.
 func (s *Server) Restart(_ context.Context, _ *cproto.Empty) (*cproto.RestartResponse, error) {
 	s.coord.ReExec(nil)
@@ -193,6 +399,48 @@ func (s *Server) Upgrade(ctx context.Context, request *cproto.UpgradeRequest) (*
 	}, nil
 }
 
+// Migrate re-enrolls the Elastic Agent into another Fleet cluster and re-executes it on success.
+func (s *Server) Migrate(ctx context.Context, request *cproto.MigrateRequest) (*cproto.MigrateResponse, error) {
+	if features.TamperProtection() && s.coord.Protection().Enabled {
+		return &cproto.MigrateResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  "unsupported action: tamper protected agent",
+		}, nil
+	}
+
+	action := &fleetapi.ActionMigrate{
+		ActionType: fleetapi.ActionTypeMigrate,
+		Data: fleetapi.ActionMigrateData{
+			TargetURI:       request.TargetUri,
+			EnrollmentToken: request.EnrollmentToken,
+		},
+	}
+	if request.Settings != "" {
+		action.Data.Settings = json.RawMessage(request.Settings)
+	}
+
+	if err := s.coord.Migrate(ctx, action, fleetgateway.RequestBackoff); err != nil {
+		msg := err.Error()
+		switch {
+		case errors.Is(err, coordinator.ErrNotManaged):
+			msg = "agent is not enrolled into Fleet, use enroll instead"
+		case errors.Is(err, coordinator.ErrFleetServer):
+			msg = "migration is not available for agents running Fleet Server"
+		}
+		//nolint:nilerr // ignore the error, return a failure migrate response
+		return &cproto.MigrateResponse{
+			Status: cproto.ActionStatus_FAILURE,
+			Error:  msg,
+		}, nil
+	}
+
+	// reexec and load the configuration of the target cluster
+	s.coord.ReExec(nil)
+	return &cproto.MigrateResponse{
+		Status: cproto.ActionStatus_SUCCESS,
+	}, nil
+}
+
 // DiagnosticAgent returns diagnostic information for this running Elastic Agent.
 func (s *Server) DiagnosticAgent(ctx context.Context, req *cproto.DiagnosticAgentRequest) (*cproto.DiagnosticAgentResponse, error) {
 	res := make([]*cproto.DiagnosticFileResult, 0, len(s.diagHooks))
@@ -214,7 +462,7 @@ func (s *Server) DiagnosticAgent(ctx context.Context, req *cproto.DiagnosticAgen
 	for _, metric := range req.AdditionalMetrics {
 		switch metric {
 		case cproto.AdditionalDiagnosticRequest_CPU:
-			duration := diagnostics.DiagCPUDuration
+			duration := cpuProfileDuration(req.CpuProfileDuration)
 			s.logger.Infof("Collecting CPU metrics, waiting for %s", duration)
 			cpuResults, err := diagnostics.CreateCPUProfile(ctx, duration)
 			if err != nil {
@@ -244,11 +492,23 @@ func (s *Server) DiagnosticComponents(req *cproto.DiagnosticComponentsRequest, r
 		reqs = append(reqs, component.Component{ID: comp.GetComponentId()})
 	}
 
-	diags, err := s.coord.PerformComponentDiagnostics(respServ.Context(), req.AdditionalMetrics, reqs...)
+	metrics, profiles, cpuDuration := splitProfilesRequest(req)
+	diags, err := s.coord.PerformComponentDiagnostics(respServ.Context(), metrics, cpuProfileDuration(req.CpuProfileDuration), reqs...)
 	if err != nil {
 		return fmt.Errorf("error fetching component-level diagnostics: %w", err)
 	}
-	for _, diag := range diags {
+
+	var profileResults [][]*cproto.DiagnosticFileResult
+	if profiles {
+		componentIDs := make([]string, 0, len(diags))
+		for _, diag := range diags {
+			componentIDs = append(componentIDs, diag.Component.ID)
+		}
+		s.logger.Infof("Collecting profiles of %d components from their monitoring endpoints", len(componentIDs))
+		profileResults = collectProfiles(respServ.Context(), componentIDs, cpuDuration, fetchComponentProfile)
+	}
+
+	for i, diag := range diags {
 		respFiles := []*cproto.DiagnosticFileResult{}
 		for _, file := range diag.Results {
 			respFiles = append(respFiles, &cproto.DiagnosticFileResult{
@@ -260,6 +520,9 @@ func (s *Server) DiagnosticComponents(req *cproto.DiagnosticComponentsRequest, r
 				Generated:   file.Generated,
 			})
 		}
+		if profiles {
+			respFiles = append(respFiles, profileResults[i]...)
+		}
 		respStruct := &cproto.DiagnosticComponentResponse{
 			ComponentId: diag.Component.ID,
 			Results:     respFiles,
@@ -338,6 +601,32 @@ func (s *Server) Configure(ctx context.Context, req *cproto.ConfigureRequest) (*
 	return &cproto.Empty{}, nil
 }
 
+// Vars returns the currently resolved variables of the Elastic Agent.
+//
+// Secret values in the variables are redacted before being returned.
+func (s *Server) Vars(_ context.Context, _ *cproto.Empty) (*cproto.VarsResponse, error) {
+	defaultProvider, vars := s.coord.Vars()
+	res := make([]*cproto.VarsMapping, 0, len(vars))
+	for _, v := range vars {
+		m, err := v.Map()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get variables %q mapping: %w", v.ID(), err)
+		}
+		mapping, err := json.Marshal(diagnostics.Redact(m, io.Discard))
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal variables %q mapping: %w", v.ID(), err)
+		}
+		res = append(res, &cproto.VarsMapping{
+			Id:      v.ID(),
+			Mapping: string(mapping),
+		})
+	}
+	return &cproto.VarsResponse{
+		DefaultProvider: defaultProvider,
+		Vars:            res,
+	}, nil
+}
+
 func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.StateResponse, error) {
 	var err error
 	components := make([]*cproto.ComponentState, 0, len(state.Components))
@@ -384,6 +673,8 @@ func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.State
 				ErrorMsg:        state.UpgradeDetails.Metadata.ErrorMsg,
 				RetryErrorMsg:   state.UpgradeDetails.Metadata.RetryErrorMsg,
 				Reason:          state.UpgradeDetails.Metadata.Reason,
+				HealthGate:      string(state.UpgradeDetails.Metadata.HealthGate),
+				DownloadRate:    float32(state.UpgradeDetails.Metadata.DownloadRate),
 			},
 		}
 
@@ -396,6 +687,56 @@ func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.State
 			!state.UpgradeDetails.Metadata.RetryUntil.IsZero() {
 			upgradeDetails.Metadata.RetryUntil = state.UpgradeDetails.Metadata.RetryUntil.Format(control.TimeFormat())
 		}
+
+		if state.UpgradeDetails.Metadata.DownloadETA != nil &&
+			!state.UpgradeDetails.Metadata.DownloadETA.IsZero() {
+			upgradeDetails.Metadata.DownloadEta = state.UpgradeDetails.Metadata.DownloadETA.Format(control.TimeFormat())
+		}
+
+		if state.UpgradeDetails.Metadata.HealthGateUntil != nil &&
+			!state.UpgradeDetails.Metadata.HealthGateUntil.IsZero() {
+			upgradeDetails.Metadata.HealthGateUntil = state.UpgradeDetails.Metadata.HealthGateUntil.Format(control.TimeFormat())
+		}
+	}
+
+	var fleetServer *cproto.FleetServerState
+	if state.FleetServer != nil {
+		fleetServer = &cproto.FleetServerState{
+			State:   state.FleetServer.State,
+			Message: state.FleetServer.Message,
+			Url:     state.FleetServer.URL,
+		}
+		if !state.FleetServer.Since.IsZero() {
+			fleetServer.Since = state.FleetServer.Since.Format(control.TimeFormat())
+		}
+	}
+
+	var fleetHosts []*cproto.FleetHostState
+	for _, host := range state.FleetHosts {
+		h := &cproto.FleetHostState{
+			Host:     host.Host,
+			Healthy:  host.Healthy,
+			Current:  host.Current,
+			Failures: int32(host.Failures), //nolint:gosec // the failures are counted until a request succeeds
+			Error:    host.Error,
+		}
+		if !host.LastUsed.IsZero() {
+			h.LastUsed = host.LastUsed.Format(control.TimeFormat())
+		}
+		if !host.RetryAfter.IsZero() {
+			h.RetryAfter = host.RetryAfter.Format(control.TimeFormat())
+		}
+		fleetHosts = append(fleetHosts, h)
+	}
+
+	blockedComponents := make([]*cproto.BlockedComponent, 0, len(state.BlockedComponents))
+	for _, blocked := range state.BlockedComponents {
+		blockedComponents = append(blockedComponents, &cproto.BlockedComponent{
+			Id:         blocked.ID,
+			InputType:  blocked.InputType,
+			OutputType: blocked.OutputType,
+			Reason:     blocked.Reason,
+		})
 	}
 
 	return &cproto.StateResponse{
@@ -409,13 +750,16 @@ func stateToProto(state *coordinator.State, agentInfo info.Agent) (*cproto.State
 			Unprivileged: agentInfo.Unprivileged(),
 			IsManaged:    !agentInfo.IsStandalone(),
 		},
-		State:          state.State,
-		Message:        state.Message,
-		FleetState:     state.FleetState,
-		FleetMessage:   state.FleetMessage,
-		Components:     components,
-		UpgradeDetails: upgradeDetails,
-		Collector:      collectorToProto(state.Collector),
+		State:             state.State,
+		Message:           state.Message,
+		FleetState:        state.FleetState,
+		FleetMessage:      state.FleetMessage,
+		Components:        components,
+		UpgradeDetails:    upgradeDetails,
+		Collector:         collectorToProto(state.Collector),
+		FleetServer:       fleetServer,
+		BlockedComponents: blockedComponents,
+		FleetHosts:        fleetHosts,
 	}, nil
 }
 
@@ -5,6 +5,12 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,12 +19,15 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/elastic/elastic-agent-client/v7/pkg/client"
 	"github.com/elastic/elastic-agent-libs/logp"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/coordinator"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/info"
 	"github.com/elastic/elastic-agent/internal/pkg/agent/application/upgrade/details"
+	"github.com/elastic/elastic-agent/internal/pkg/diagnostics"
 	"github.com/elastic/elastic-agent/pkg/component"
 	"github.com/elastic/elastic-agent/pkg/component/runtime"
 	"github.com/elastic/elastic-agent/pkg/control"
@@ -76,6 +85,22 @@ func TestStateMapping(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "with upgrade health gate",
+			agentState:   cproto.State_HEALTHY,
+			agentMessage: "Running",
+			fleetState:   cproto.State_HEALTHY,
+			fleetMessage: "Connected",
+			upgradeDetails: &details.Details{
+				TargetVersion: "8.13.0",
+				State:         details.StateWatching,
+				ActionID:      "some-action-id",
+				Metadata: details.Metadata{
+					HealthGate:      details.HealthGatePending,
+					HealthGateUntil: &now,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -200,6 +225,7 @@ func TestStateMapping(t *testing.T) {
 					FailedState:     string(tc.upgradeDetails.Metadata.FailedState),
 					ErrorMsg:        tc.upgradeDetails.Metadata.ErrorMsg,
 					RetryErrorMsg:   tc.upgradeDetails.Metadata.RetryErrorMsg,
+					HealthGate:      string(tc.upgradeDetails.Metadata.HealthGate),
 				}
 
 				if tc.upgradeDetails.Metadata.ScheduledAt != nil &&
@@ -212,6 +238,11 @@ func TestStateMapping(t *testing.T) {
 					expectedMetadata.RetryUntil = tc.upgradeDetails.Metadata.RetryUntil.Format(control.TimeFormat())
 				}
 
+				if tc.upgradeDetails.Metadata.HealthGateUntil != nil &&
+					!tc.upgradeDetails.Metadata.HealthGateUntil.IsZero() {
+					expectedMetadata.HealthGateUntil = tc.upgradeDetails.Metadata.HealthGateUntil.Format(control.TimeFormat())
+				}
+
 				assert.Equal(t, string(tc.upgradeDetails.State), stateResponse.UpgradeDetails.State)
 				assert.Equal(t, tc.upgradeDetails.TargetVersion, stateResponse.UpgradeDetails.TargetVersion)
 				assert.Equal(t, tc.upgradeDetails.ActionID, stateResponse.UpgradeDetails.ActionId)
@@ -220,3 +251,337 @@ func TestStateMapping(t *testing.T) {
 		})
 	}
 }
+
+func TestUpgradeProgressEvent(t *testing.T) {
+	now := time.Now()
+	eta := now.Add(time.Minute)
+	d := details.NewDetails("8.13.0", details.StateDownloading, "action-1")
+	d.Metadata.DownloadPercent = 0.25
+	d.Metadata.DownloadRate = 1024
+	d.Metadata.DownloadETA = &eta
+
+	event := upgradeProgressEvent(d, now)
+	assert.Equal(t, now.UTC(), event.Time.AsTime())
+	assert.Equal(t, "8.13.0", event.TargetVersion)
+	assert.Equal(t, "action-1", event.ActionId)
+	assert.Equal(t, string(details.StateDownloading), event.State)
+	assert.Equal(t, float32(0.25), event.DownloadPercent)
+	assert.Equal(t, float32(1024), event.DownloadRate)
+	require.NotNil(t, event.DownloadEta)
+	assert.Equal(t, eta.UTC(), event.DownloadEta.AsTime())
+
+	d.Metadata.DownloadETA = nil
+	assert.Nil(t, upgradeProgressEvent(d, now).DownloadEta)
+}
+
+// fakeLogsStream collects the log lines sent by StreamLogs.
+type fakeLogsStream struct {
+	grpc.ServerStream
+
+	ctx   context.Context
+	mx    sync.Mutex
+	lines []string
+}
+
+func (f *fakeLogsStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeLogsStream) Send(line *cproto.LogLine) error {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	f.lines = append(f.lines, string(line.Line))
+	return nil
+}
+
+func (f *fakeLogsStream) received() []string {
+	f.mx.Lock()
+	defer f.mx.Unlock()
+	return slices.Clone(f.lines)
+}
+
+func TestStreamLogs(t *testing.T) {
+	logsDir := t.TempDir()
+	logFile := filepath.Join(logsDir, "elastic-agent-20240103.ndjson")
+	content := `{"log.level":"info","message":"agent started"}
+{"log.level":"error","component":{"id":"filestream-default"},"message":"failed to open file"}
+{"log.level":"info","component":{"id":"filestream-default"},"message":"harvester started"}
+{"log.level":"warn","component":{"id":"system-default"},"message":"slow metricset"}
+`
+	require.NoError(t, os.WriteFile(logFile, []byte(content), 0o600))
+	s := &Server{logsDir: logsDir}
+
+	stream := func(t *testing.T, req *cproto.StreamLogsRequest) []string {
+		srv := &fakeLogsStream{ctx: context.Background()}
+		require.NoError(t, s.StreamLogs(req, srv))
+		return srv.received()
+	}
+
+	t.Run("last lines", func(t *testing.T) {
+		lines := stream(t, &cproto.StreamLogsRequest{Lines: 2})
+		assert.Equal(t, []string{
+			`{"log.level":"info","component":{"id":"filestream-default"},"message":"harvester started"}`,
+			`{"log.level":"warn","component":{"id":"system-default"},"message":"slow metricset"}`,
+		}, lines)
+	})
+
+	t.Run("component filter", func(t *testing.T) {
+		lines := stream(t, &cproto.StreamLogsRequest{Lines: 10, ComponentId: "filestream-default"})
+		assert.Equal(t, []string{
+			`{"log.level":"error","component":{"id":"filestream-default"},"message":"failed to open file"}`,
+			`{"log.level":"info","component":{"id":"filestream-default"},"message":"harvester started"}`,
+		}, lines)
+	})
+
+	t.Run("level filter", func(t *testing.T) {
+		lines := stream(t, &cproto.StreamLogsRequest{Lines: 10, Level: "warning"})
+		assert.Equal(t, []string{
+			`{"log.level":"error","component":{"id":"filestream-default"},"message":"failed to open file"}`,
+			`{"log.level":"warn","component":{"id":"system-default"},"message":"slow metricset"}`,
+		}, lines)
+	})
+
+	t.Run("invalid level", func(t *testing.T) {
+		srv := &fakeLogsStream{ctx: context.Background()}
+		assert.Error(t, s.StreamLogs(&cproto.StreamLogsRequest{Level: "verbose"}, srv))
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		srv := &fakeLogsStream{ctx: ctx}
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- s.StreamLogs(&cproto.StreamLogsRequest{Lines: 1, Follow: true, ComponentId: "system-default"}, srv)
+		}()
+		// the new line is only written once the last lines were sent
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			assert.Len(c, srv.received(), 1)
+		}, 5*time.Second, 10*time.Millisecond)
+
+		f, err := os.OpenFile(logFile, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		// the new line is written in two parts, only the complete line is sent
+		_, err = f.WriteString(`{"log.level":"info","component":{"id":"system-default"},`)
+		require.NoError(t, err)
+		_, err = f.WriteString(`"message":"metricset recovered"}` + "\n")
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		assert.EventuallyWithT(t, func(c *assert.CollectT) {
+			assert.Equal(c, []string{
+				`{"log.level":"warn","component":{"id":"system-default"},"message":"slow metricset"}`,
+				`{"log.level":"info","component":{"id":"system-default"},"message":"metricset recovered"}`,
+			}, srv.received())
+		}, 5*time.Second, 100*time.Millisecond)
+
+		cancel()
+		assert.NoError(t, <-errCh)
+	})
+}
+
+func TestWatchState(t *testing.T) {
+	compState := func(id string, compState client.UnitState, unitState client.UnitState) runtime.ComponentComponentState {
+		return runtime.ComponentComponentState{
+			Component: component.Component{ID: id},
+			State: runtime.ComponentState{
+				State:   compState,
+				Message: compState.String(),
+				Units: map[runtime.ComponentUnitKey]runtime.ComponentUnitState{
+					{UnitType: client.UnitTypeInput, UnitID: id + "-unit"}: {State: unitState, Message: unitState.String()},
+				},
+			},
+		}
+	}
+	states := []coordinator.State{
+		{
+			State:   cproto.State_HEALTHY,
+			Message: "Running",
+			Components: []runtime.ComponentComponentState{
+				compState("filestream-default", client.UnitStateHealthy, client.UnitStateHealthy),
+				compState("system-default", client.UnitStateHealthy, client.UnitStateHealthy),
+			},
+		},
+		{
+			State:   cproto.State_DEGRADED,
+			Message: "1 or more components/units in a degraded state",
+			Components: []runtime.ComponentComponentState{
+				compState("filestream-default", client.UnitStateHealthy, client.UnitStateDegraded),
+				compState("system-default", client.UnitStateDegraded, client.UnitStateHealthy),
+			},
+		},
+	}
+
+	type event struct {
+		source      cproto.StatusEventSource
+		reason      cproto.StatusEventReason
+		componentID string
+		state       cproto.State
+	}
+	testcases := map[string]struct {
+		req      *cproto.WatchStateRequest
+		expected []event
+	}{
+		"every transition": {
+			req: &cproto.WatchStateRequest{},
+			expected: []event{
+				{cproto.StatusEventSource_AGENT, cproto.StatusEventReason_ADDED, "", cproto.State_HEALTHY},
+				{cproto.StatusEventSource_FLEET, cproto.StatusEventReason_ADDED, "", cproto.State_STARTING},
+				{cproto.StatusEventSource_COMPONENT, cproto.StatusEventReason_ADDED, "filestream-default", cproto.State_HEALTHY},
+				{cproto.StatusEventSource_UNIT, cproto.StatusEventReason_ADDED, "filestream-default", cproto.State_HEALTHY},
+				{cproto.StatusEventSource_COMPONENT, cproto.StatusEventReason_ADDED, "system-default", cproto.State_HEALTHY},
+				{cproto.StatusEventSource_UNIT, cproto.StatusEventReason_ADDED, "system-default", cproto.State_HEALTHY},
+				{cproto.StatusEventSource_AGENT, cproto.StatusEventReason_STATE_CHANGED, "", cproto.State_DEGRADED},
+				{cproto.StatusEventSource_UNIT, cproto.StatusEventReason_STATE_CHANGED, "filestream-default", cproto.State_DEGRADED},
+				{cproto.StatusEventSource_COMPONENT, cproto.StatusEventReason_STATE_CHANGED, "system-default", cproto.State_DEGRADED},
+			},
+		},
+		"units of a component": {
+			req: &cproto.WatchStateRequest{
+				Sources:      []cproto.StatusEventSource{cproto.StatusEventSource_UNIT},
+				ComponentIds: []string{"filestream-default"},
+			},
+			expected: []event{
+				{cproto.StatusEventSource_UNIT, cproto.StatusEventReason_ADDED, "filestream-default", cproto.State_HEALTHY},
+				{cproto.StatusEventSource_UNIT, cproto.StatusEventReason_STATE_CHANGED, "filestream-default", cproto.State_DEGRADED},
+			},
+		},
+		"state changes of the agent and of a component": {
+			req: &cproto.WatchStateRequest{
+				ComponentIds: []string{"system-default"},
+				Reasons:      []cproto.StatusEventReason{cproto.StatusEventReason_STATE_CHANGED},
+			},
+			expected: []event{
+				{cproto.StatusEventSource_AGENT, cproto.StatusEventReason_STATE_CHANGED, "", cproto.State_DEGRADED},
+				{cproto.StatusEventSource_COMPONENT, cproto.StatusEventReason_STATE_CHANGED, "system-default", cproto.State_DEGRADED},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s := &Server{agentInfo: new(info.AgentInfo)}
+			subChan := make(chan coordinator.State)
+			sent := make(chan event, 100)
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- s.watchStatusEvents(ctx, subChan, newStatusEventFilter(tc.req), func(e *cproto.StatusEvent) error {
+					sent <- event{e.Source, e.Reason, e.ComponentId, e.State}
+					return nil
+				})
+			}()
+
+			for _, state := range states {
+				subChan <- state
+			}
+			// the events of the last state are sent once the next state is received
+			subChan <- states[len(states)-1]
+			cancel()
+			assert.ErrorIs(t, <-errCh, context.Canceled)
+
+			close(sent)
+			var received []event
+			for e := range sent {
+				received = append(received, e)
+			}
+			assert.Equal(t, tc.expected, received)
+		})
+	}
+}
+
+func TestResourcesResponse(t *testing.T) {
+	now := time.Now()
+	components := []runtime.ComponentComponentState{
+		{Component: component.Component{ID: "system-default"}, State: runtime.ComponentState{Pid: 20}},
+		{Component: component.Component{ID: "filestream-default"}, State: runtime.ComponentState{Pid: 10}},
+		{Component: component.Component{ID: "endpoint-default"}},
+	}
+	restarts := map[string]uint64{"filestream-default": 2, "endpoint-default": 1}
+	sample := func(pid int, usage *cproto.ProcessResources) error {
+		if pid == 20 {
+			return errors.New("process exited")
+		}
+		usage.CpuTimeMs = uint64(pid) * 1000
+		usage.Rss = uint64(pid) * 1024
+		usage.FdCount = uint64(pid)
+		return nil
+	}
+
+	resp := resourcesResponse(1, components, restarts, sample, now)
+	assert.Equal(t, now.UTC(), resp.Time.AsTime())
+	require.Len(t, resp.Processes, 4)
+	assert.Equal(t, &cproto.ProcessResources{Id: agentProcessID, Pid: 1, CpuTimeMs: 1000, Rss: 1024, FdCount: 1}, resp.Processes[0])
+	assert.Equal(t, &cproto.ProcessResources{Id: "endpoint-default", Restarts: 1}, resp.Processes[1])
+	assert.Equal(t, &cproto.ProcessResources{Id: "filestream-default", Pid: 10, CpuTimeMs: 10000, Rss: 10240, FdCount: 10, Restarts: 2}, resp.Processes[2])
+	assert.Equal(t, &cproto.ProcessResources{Id: "system-default", Pid: 20}, resp.Processes[3])
+}
+
+func TestSampleProcess(t *testing.T) {
+	var usage cproto.ProcessResources
+	require.NoError(t, newProcessSampler(rootFS{})(os.Getpid(), &usage))
+	assert.NotZero(t, usage.Rss)
+}
+
+func TestRootFS(t *testing.T) {
+	root := string(filepath.Separator)
+	assert.False(t, rootFS{}.IsSet())
+	assert.Equal(t, filepath.Join(root, "proc", "1", "stat"), rootFS{}.ResolveHostFS("/proc/1/stat"))
+	assert.Equal(t, filepath.Join(root, "proc", "1", "stat"), rootFS{}.Join("proc", "1", "stat"))
+}
+
+func TestSplitProfilesRequest(t *testing.T) {
+	// without profiles the components collect the CPU profile themselves
+	metrics, profiles, cpuDuration := splitProfilesRequest(&cproto.DiagnosticComponentsRequest{
+		AdditionalMetrics: []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CONN, cproto.AdditionalDiagnosticRequest_CPU},
+	})
+	assert.Equal(t, []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CONN, cproto.AdditionalDiagnosticRequest_CPU}, metrics)
+	assert.False(t, profiles)
+	assert.Zero(t, cpuDuration)
+
+	metrics, profiles, cpuDuration = splitProfilesRequest(&cproto.DiagnosticComponentsRequest{
+		AdditionalMetrics:  []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CONN, cproto.AdditionalDiagnosticRequest_CPU, cproto.AdditionalDiagnosticRequest_PROFILES},
+		CpuProfileDuration: durationpb.New(time.Minute),
+	})
+	assert.Equal(t, []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_CONN}, metrics)
+	assert.True(t, profiles)
+	assert.Equal(t, time.Minute, cpuDuration)
+
+	// the default duration is used when not set
+	_, _, cpuDuration = splitProfilesRequest(&cproto.DiagnosticComponentsRequest{
+		AdditionalMetrics: []cproto.AdditionalDiagnosticRequest{cproto.AdditionalDiagnosticRequest_PROFILES, cproto.AdditionalDiagnosticRequest_CPU},
+	})
+	assert.Equal(t, diagnostics.DiagCPUDuration, cpuDuration)
+}
+
+func TestCollectProfiles(t *testing.T) {
+	fetch := func(_ context.Context, componentID, name string, duration time.Duration) ([]byte, error) {
+		if componentID == "endpoint-default" {
+			return nil, errors.New("pprof not enabled")
+		}
+		return []byte(componentID + " " + name + " " + duration.String()), nil
+	}
+	contents := func(results []*cproto.DiagnosticFileResult) map[string]string {
+		m := make(map[string]string, len(results))
+		for _, r := range results {
+			m[r.Filename] = string(r.Content)
+		}
+		return m
+	}
+
+	results := collectProfiles(context.Background(), []string{"filestream-default", "endpoint-default"}, 0, fetch)
+	require.Len(t, results, 2)
+	assert.Equal(t, map[string]string{
+		"monitoring-goroutine.pprof.gz": "filestream-default goroutine 0s",
+		"monitoring-heap.pprof.gz":      "filestream-default heap 0s",
+	}, contents(results[0]))
+	assert.Equal(t, map[string]string{
+		"monitoring-goroutine.pprof.gz": "failed to read the goroutine profile from the monitoring endpoint: pprof not enabled",
+		"monitoring-heap.pprof.gz":      "failed to read the heap profile from the monitoring endpoint: pprof not enabled",
+	}, contents(results[1]))
+
+	results = collectProfiles(context.Background(), []string{"filestream-default"}, time.Minute, fetch)
+	require.Len(t, results, 1)
+	assert.Equal(t, "filestream-default profile 1m0s", contents(results[0])["monitoring-cpu.pprof"])
+	assert.Len(t, componentProfiles, 2, "the CPU profile must not be added to the shared profiles")
+}
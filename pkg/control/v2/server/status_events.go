@@ -0,0 +1,163 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"slices"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+type unitKey struct {
+	unitType cproto.UnitType
+	unitID   string
+}
+
+// statusEvents returns the events for the changes between the prev and curr states. Every source of curr is
+// reported as added when prev is nil.
+func statusEvents(prev, curr *cproto.StateResponse, now time.Time) []*cproto.StatusEvent {
+	ts := timestamppb.New(now)
+	var events []*cproto.StatusEvent
+	add := func(event *cproto.StatusEvent) {
+		event.Time = ts
+		events = append(events, event)
+	}
+
+	if prev == nil {
+		prev = &cproto.StateResponse{}
+		add(&cproto.StatusEvent{Source: cproto.StatusEventSource_AGENT, Reason: cproto.StatusEventReason_ADDED, State: curr.State, Message: curr.Message})
+		add(&cproto.StatusEvent{Source: cproto.StatusEventSource_FLEET, Reason: cproto.StatusEventReason_ADDED, State: curr.FleetState, Message: curr.FleetMessage})
+	} else {
+		if event := changeEvent(prev.State, prev.Message, curr.State, curr.Message); event != nil {
+			event.Source = cproto.StatusEventSource_AGENT
+			add(event)
+		}
+		if event := changeEvent(prev.FleetState, prev.FleetMessage, curr.FleetState, curr.FleetMessage); event != nil {
+			event.Source = cproto.StatusEventSource_FLEET
+			add(event)
+		}
+	}
+
+	prevComps := make(map[string]*cproto.ComponentState, len(prev.Components))
+	for _, comp := range prev.Components {
+		prevComps[comp.Id] = comp
+	}
+	currComps := make(map[string]struct{}, len(curr.Components))
+	for _, comp := range curr.Components {
+		currComps[comp.Id] = struct{}{}
+		prevComp, ok := prevComps[comp.Id]
+		if !ok {
+			add(&cproto.StatusEvent{Source: cproto.StatusEventSource_COMPONENT, Reason: cproto.StatusEventReason_ADDED, ComponentId: comp.Id, State: comp.State, Message: comp.Message})
+			for _, unit := range comp.Units {
+				add(unitEvent(comp.Id, unit, cproto.StatusEventReason_ADDED))
+			}
+			continue
+		}
+		if event := changeEvent(prevComp.State, prevComp.Message, comp.State, comp.Message); event != nil {
+			event.Source = cproto.StatusEventSource_COMPONENT
+			event.ComponentId = comp.Id
+			add(event)
+		}
+
+		prevUnits := make(map[unitKey]*cproto.ComponentUnitState, len(prevComp.Units))
+		for _, unit := range prevComp.Units {
+			prevUnits[unitKey{unit.UnitType, unit.UnitId}] = unit
+		}
+		currUnits := make(map[unitKey]struct{}, len(comp.Units))
+		for _, unit := range comp.Units {
+			key := unitKey{unit.UnitType, unit.UnitId}
+			currUnits[key] = struct{}{}
+			prevUnit, ok := prevUnits[key]
+			if !ok {
+				add(unitEvent(comp.Id, unit, cproto.StatusEventReason_ADDED))
+				continue
+			}
+			if event := changeEvent(prevUnit.State, prevUnit.Message, unit.State, unit.Message); event != nil {
+				event.Source = cproto.StatusEventSource_UNIT
+				event.ComponentId = comp.Id
+				event.UnitType = unit.UnitType
+				event.UnitId = unit.UnitId
+				add(event)
+			}
+		}
+		for _, unit := range prevComp.Units {
+			if _, ok := currUnits[unitKey{unit.UnitType, unit.UnitId}]; !ok {
+				add(unitEvent(comp.Id, unit, cproto.StatusEventReason_REMOVED))
+			}
+		}
+	}
+
+	for _, comp := range prev.Components {
+		if _, ok := currComps[comp.Id]; ok {
+			continue
+		}
+		for _, unit := range comp.Units {
+			add(unitEvent(comp.Id, unit, cproto.StatusEventReason_REMOVED))
+		}
+		add(&cproto.StatusEvent{Source: cproto.StatusEventSource_COMPONENT, Reason: cproto.StatusEventReason_REMOVED, ComponentId: comp.Id, State: comp.State, Message: comp.Message})
+	}
+	return events
+}
+
+// changeEvent returns the event for the change of state or message, nil when neither changed.
+func changeEvent(prevState cproto.State, prevMessage string, state cproto.State, message string) *cproto.StatusEvent {
+	switch {
+	case prevState != state:
+		return &cproto.StatusEvent{Reason: cproto.StatusEventReason_STATE_CHANGED, PreviousState: prevState, State: state, Message: message}
+	case prevMessage != message:
+		return &cproto.StatusEvent{Reason: cproto.StatusEventReason_MESSAGE_CHANGED, State: state, Message: message}
+	default:
+		return nil
+	}
+}
+
+func unitEvent(componentID string, unit *cproto.ComponentUnitState, reason cproto.StatusEventReason) *cproto.StatusEvent {
+	return &cproto.StatusEvent{
+		Source:      cproto.StatusEventSource_UNIT,
+		Reason:      reason,
+		ComponentId: componentID,
+		UnitType:    unit.UnitType,
+		UnitId:      unit.UnitId,
+		State:       unit.State,
+		Message:     unit.Message,
+	}
+}
+
+// statusEventFilter selects the events streamed by WatchState, an empty list selects every value.
+type statusEventFilter struct {
+	sources      []cproto.StatusEventSource
+	componentIDs []string
+	reasons      []cproto.StatusEventReason
+}
+
+func newStatusEventFilter(req *cproto.WatchStateRequest) *statusEventFilter {
+	return &statusEventFilter{
+		sources:      req.GetSources(),
+		componentIDs: req.GetComponentIds(),
+		reasons:      req.GetReasons(),
+	}
+}
+
+// match returns true when the event is selected by the filter, a nil filter selects every event.
+func (f *statusEventFilter) match(event *cproto.StatusEvent) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.sources) > 0 && !slices.Contains(f.sources, event.Source) {
+		return false
+	}
+	if len(f.reasons) > 0 && !slices.Contains(f.reasons, event.Reason) {
+		return false
+	}
+	switch event.Source {
+	case cproto.StatusEventSource_COMPONENT, cproto.StatusEventSource_UNIT:
+		return len(f.componentIDs) == 0 || slices.Contains(f.componentIDs, event.ComponentId)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/elastic/elastic-agent/pkg/control/v2/cproto"
+)
+
+func TestStatusEvents(t *testing.T) {
+	now := time.Now()
+	ts := timestamppb.New(now)
+
+	state := func(modify func(state *cproto.StateResponse)) *cproto.StateResponse {
+		state := &cproto.StateResponse{
+			State:        cproto.State_HEALTHY,
+			Message:      "Running",
+			FleetState:   cproto.State_HEALTHY,
+			FleetMessage: "Connected",
+			Components: []*cproto.ComponentState{
+				{
+					Id:      "filestream-default",
+					State:   cproto.State_HEALTHY,
+					Message: "Healthy: communicating with pid '42'",
+					Units: []*cproto.ComponentUnitState{
+						{UnitType: cproto.UnitType_INPUT, UnitId: "filestream-default-logs", State: cproto.State_HEALTHY, Message: "Healthy"},
+						{UnitType: cproto.UnitType_OUTPUT, UnitId: "filestream-default", State: cproto.State_HEALTHY, Message: "Healthy"},
+					},
+				},
+			},
+		}
+		if modify != nil {
+			modify(state)
+		}
+		return state
+	}
+
+	testcases := map[string]struct {
+		prev     *cproto.StateResponse
+		curr     *cproto.StateResponse
+		expected []*cproto.StatusEvent
+	}{
+		"first state": {
+			curr: state(nil),
+			expected: []*cproto.StatusEvent{
+				{Time: ts, Source: cproto.StatusEventSource_AGENT, Reason: cproto.StatusEventReason_ADDED, State: cproto.State_HEALTHY, Message: "Running"},
+				{Time: ts, Source: cproto.StatusEventSource_FLEET, Reason: cproto.StatusEventReason_ADDED, State: cproto.State_HEALTHY, Message: "Connected"},
+				{Time: ts, Source: cproto.StatusEventSource_COMPONENT, Reason: cproto.StatusEventReason_ADDED, ComponentId: "filestream-default", State: cproto.State_HEALTHY, Message: "Healthy: communicating with pid '42'"},
+				{Time: ts, Source: cproto.StatusEventSource_UNIT, Reason: cproto.StatusEventReason_ADDED, ComponentId: "filestream-default", UnitType: cproto.UnitType_INPUT, UnitId: "filestream-default-logs", State: cproto.State_HEALTHY, Message: "Healthy"},
+				{Time: ts, Source: cproto.StatusEventSource_UNIT, Reason: cproto.StatusEventReason_ADDED, ComponentId: "filestream-default", UnitType: cproto.UnitType_OUTPUT, UnitId: "filestream-default", State: cproto.State_HEALTHY, Message: "Healthy"},
+			},
+		},
+		"no change": {
+			prev: state(nil),
+			curr: state(nil),
+		},
+		"agent and unit degraded": {
+			prev: state(nil),
+			curr: state(func(state *cproto.StateResponse) {
+				state.State = cproto.State_DEGRADED
+				state.Message = "1 or more components/units in a degraded state"
+				state.Components[0].Units[0].State = cproto.State_DEGRADED
+				state.Components[0].Units[0].Message = "file not found"
+			}),
+			expected: []*cproto.StatusEvent{
+				{Time: ts, Source: cproto.StatusEventSource_AGENT, Reason: cproto.StatusEventReason_STATE_CHANGED, PreviousState: cproto.State_HEALTHY, State: cproto.State_DEGRADED, Message: "1 or more components/units in a degraded state"},
+				{Time: ts, Source: cproto.StatusEventSource_UNIT, Reason: cproto.StatusEventReason_STATE_CHANGED, ComponentId: "filestream-default", UnitType: cproto.UnitType_INPUT, UnitId: "filestream-default-logs", PreviousState: cproto.State_HEALTHY, State: cproto.State_DEGRADED, Message: "file not found"},
+			},
+		},
+		"fleet message changed": {
+			prev: state(func(state *cproto.StateResponse) {
+				state.FleetState = cproto.State_FAILED
+				state.FleetMessage = "connection refused"
+			}),
+			curr: state(func(state *cproto.StateResponse) {
+				state.FleetState = cproto.State_FAILED
+				state.FleetMessage = "timeout"
+			}),
+			expected: []*cproto.StatusEvent{
+				{Time: ts, Source: cproto.StatusEventSource_FLEET, Reason: cproto.StatusEventReason_MESSAGE_CHANGED, State: cproto.State_FAILED, Message: "timeout"},
+			},
+		},
+		"unit removed": {
+			prev: state(nil),
+			curr: state(func(state *cproto.StateResponse) {
+				state.Components[0].Units = state.Components[0].Units[1:]
+			}),
+			expected: []*cproto.StatusEvent{
+				{Time: ts, Source: cproto.StatusEventSource_UNIT, Reason: cproto.StatusEventReason_REMOVED, ComponentId: "filestream-default", UnitType: cproto.UnitType_INPUT, UnitId: "filestream-default-logs", State: cproto.State_HEALTHY, Message: "Healthy"},
+			},
+		},
+		"component removed": {
+			prev: state(nil),
+			curr: state(func(state *cproto.StateResponse) {
+				state.Components = nil
+			}),
+			expected: []*cproto.StatusEvent{
+				{Time: ts, Source: cproto.StatusEventSource_UNIT, Reason: cproto.StatusEventReason_REMOVED, ComponentId: "filestream-default", UnitType: cproto.UnitType_INPUT, UnitId: "filestream-default-logs", State: cproto.State_HEALTHY, Message: "Healthy"},
+				{Time: ts, Source: cproto.StatusEventSource_UNIT, Reason: cproto.StatusEventReason_REMOVED, ComponentId: "filestream-default", UnitType: cproto.UnitType_OUTPUT, UnitId: "filestream-default", State: cproto.State_HEALTHY, Message: "Healthy"},
+				{Time: ts, Source: cproto.StatusEventSource_COMPONENT, Reason: cproto.StatusEventReason_REMOVED, ComponentId: "filestream-default", State: cproto.State_HEALTHY, Message: "Healthy: communicating with pid '42'"},
+			},
+		},
+	}
+
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			events := statusEvents(tc.prev, tc.curr, now)
+			assert.Len(t, events, len(tc.expected))
+			for i := range tc.expected {
+				if i < len(events) {
+					assert.Equal(t, tc.expected[i].String(), events[i].String())
+				}
+			}
+		})
+	}
+}
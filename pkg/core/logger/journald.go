@@ -0,0 +1,260 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"go.elastic.co/ecszap"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/elastic/elastic-agent-libs/file"
+	"github.com/elastic/elastic-agent-libs/logp"
+)
+
+// componentKey is the field the logger of a component is created with, see createLogWriter of the runtime.
+const componentKey = "component"
+
+var journaldLevelEnabler *zap.AtomicLevel
+
+// JournaldConfig is the configuration of the journald output, enabled with agent.logging.to_journald.
+type JournaldConfig struct {
+	// Components also sends the logs of the components to journald, by default only the logs of the Elastic Agent
+	// are sent.
+	Components bool `config:"components" yaml:"components" json:"components"`
+}
+
+// DefaultJournaldConfig returns the default configuration of the journald output.
+func DefaultJournaldConfig() *JournaldConfig {
+	return &JournaldConfig{}
+}
+
+// Option is an option of the logger created with NewFromConfig.
+type Option func(*options)
+
+type options struct {
+	journald *JournaldConfig
+}
+
+// WithJournald sends the logs to journald with their fields as structured journal fields, in place of the log
+// files of the configuration. The internal log files read by the monitoring are always written.
+func WithJournald(cfg *JournaldConfig) Option {
+	return func(o *options) {
+		if cfg == nil {
+			cfg = DefaultJournaldConfig()
+		}
+		o.journald = cfg
+	}
+}
+
+// JournaldAvailable returns true when the journal of systemd is available on the host.
+func JournaldAvailable() bool {
+	return journal.Enabled()
+}
+
+// makeJournaldOutput creates a zapcore.Core sending the logs to journald.
+func makeJournaldOutput(cfg *Config, journaldCfg *JournaldConfig) zapcore.Core {
+	al := zap.NewAtomicLevelAt(cfg.Level.ZapLevel())
+	journaldLevelEnabler = &al
+	return &journaldCore{
+		LevelEnabler: journaldLevelEnabler,
+		send:         journal.Send,
+		identifier:   cfg.Beat,
+		components:   journaldCfg.Components,
+	}
+}
+
+// makeComponentFileOutput creates a zapcore.Core writing the logs of the components to the log files of the
+// configuration, used when journald replaces the log files but the logs of the components are not sent to it.
+func makeComponentFileOutput(cfg *Config) (zapcore.Core, error) {
+	rotator, err := file.NewFileRotator(filepath.Join(cfg.Files.Path, cfg.Files.Name),
+		file.MaxSizeBytes(cfg.Files.MaxSize),
+		file.MaxBackups(cfg.Files.MaxBackups),
+		file.Permissions(os.FileMode(cfg.Files.Permissions)),
+		file.Interval(cfg.Files.Interval),
+		file.RotateOnStartup(cfg.Files.RotateOnStartup),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the file rotator of the component logs: %w", err)
+	}
+
+	encoderConfig := ecszap.ECSCompatibleEncoderConfig(logp.JSONEncoderConfig())
+	encoderConfig.EncodeTime = UtcTimestampEncode
+	encoder := zapcore.NewJSONEncoder(encoderConfig)
+	// the level follows the one of the journald output, created first
+	return &componentCore{Core: ecszap.WrapCore(zapcore.NewCore(encoder, rotator, journaldLevelEnabler))}, nil
+}
+
+// componentCore is a zapcore.Core only writing the logs of the components.
+type componentCore struct {
+	zapcore.Core
+	// component is true when the core is the one of a component
+	component bool
+}
+
+func (c *componentCore) With(fields []zapcore.Field) zapcore.Core {
+	component := c.component
+	for _, f := range fields {
+		if f.Key == componentKey {
+			component = true
+		}
+	}
+	return &componentCore{Core: c.Core.With(fields), component: component}
+}
+
+func (c *componentCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.component {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// journaldCore is a zapcore.Core sending each entry to journald, the fields of the entry are flattened to journal
+// fields, {"component": {"id": "x"}} is sent as COMPONENT_ID=x.
+type journaldCore struct {
+	zapcore.LevelEnabler
+	send       func(message string, priority journal.Priority, vars map[string]string) error
+	identifier string
+
+	// components is false when the logs of the components are not sent
+	components bool
+	// component is true when the core is the one of a component
+	component bool
+	fields    []zapcore.Field
+}
+
+func (c *journaldCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	for _, f := range fields {
+		if f.Key == componentKey {
+			clone.component = true
+		}
+	}
+	return &clone
+}
+
+func (c *journaldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.skipped() || !c.Enabled(ent.Level) {
+		return ce
+	}
+	return ce.AddCore(ent, c)
+}
+
+func (c *journaldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if c.skipped() {
+		return nil
+	}
+
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	vars := make(map[string]string, len(enc.Fields)+4)
+	flattenJournalFields(vars, "", enc.Fields)
+	vars["SYSLOG_IDENTIFIER"] = c.identifier
+	if ent.LoggerName != "" {
+		vars["LOGGER_NAME"] = ent.LoggerName
+	}
+	if ent.Caller.Defined {
+		vars["CODE_FILE"] = ent.Caller.File
+		vars["CODE_LINE"] = strconv.Itoa(ent.Caller.Line)
+	}
+	if ent.Stack != "" {
+		vars["STACK_TRACE"] = ent.Stack
+	}
+	if err := c.send(ent.Message, journalPriority(ent.Level), vars); err != nil {
+		return fmt.Errorf("failed to send log to journald: %w", err)
+	}
+	return nil
+}
+
+func (c *journaldCore) Sync() error {
+	return nil
+}
+
+// skipped returns true when the core is the one of a component and the logs of the components are not sent.
+func (c *journaldCore) skipped() bool {
+	return c.component && !c.components
+}
+
+// journalPriority maps the level of the entry to the syslog priority of journald.
+func journalPriority(lvl zapcore.Level) journal.Priority {
+	switch lvl {
+	case zapcore.DebugLevel:
+		return journal.PriDebug
+	case zapcore.InfoLevel:
+		return journal.PriInfo
+	case zapcore.WarnLevel:
+		return journal.PriWarning
+	case zapcore.ErrorLevel:
+		return journal.PriErr
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return journal.PriCrit
+	case zapcore.FatalLevel:
+		return journal.PriEmerg
+	default:
+		return journal.PriInfo
+	}
+}
+
+// flattenJournalFields adds the fields to vars with their keys joined by _ and made valid journal field names.
+func flattenJournalFields(vars map[string]string, prefix string, fields map[string]interface{}) {
+	for k, v := range fields {
+		name := journalFieldName(prefix, k)
+		if name == "" {
+			continue
+		}
+		switch v := v.(type) {
+		case map[string]interface{}:
+			flattenJournalFields(vars, name, v)
+		case string:
+			vars[name] = v
+		case fmt.Stringer:
+			vars[name] = v.String()
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			vars[name] = fmt.Sprint(v)
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				vars[name] = fmt.Sprint(v)
+				continue
+			}
+			vars[name] = string(b)
+		}
+	}
+}
+
+// journalFieldName returns the key as a journal field name, only made of uppercase letters, digits and _ and not
+// starting with _ that is reserved to the fields set by journald.
+func journalFieldName(prefix string, key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if prefix != "" {
+		name = prefix + "_" + name
+	}
+	return strings.TrimLeft(name, "_")
+}
@@ -0,0 +1,92 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package logger
+
+import (
+	"testing"
+
+	"github.com/coreos/go-systemd/v22/journal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type journalEntry struct {
+	message  string
+	priority journal.Priority
+	vars     map[string]string
+}
+
+func newTestJournaldCore(components bool) (*journaldCore, *[]journalEntry) {
+	var entries []journalEntry
+	return &journaldCore{
+		LevelEnabler: zap.NewAtomicLevelAt(zapcore.InfoLevel),
+		send: func(message string, priority journal.Priority, vars map[string]string) error {
+			entries = append(entries, journalEntry{message, priority, vars})
+			return nil
+		},
+		identifier: agentName,
+		components: components,
+	}, &entries
+}
+
+func TestJournaldCore(t *testing.T) {
+	core, entries := newTestJournaldCore(false)
+	log := zap.New(core).Named("coordinator").With(zap.Any("log", map[string]interface{}{"source": "elastic-agent"}))
+
+	log.Debug("not sent")
+	log.Warn("Unit state changed", zap.String("unit.id", "filestream-default"), zap.Int("revision", 2), zap.Strings("tags", []string{"a", "b"}))
+	require.Len(t, *entries, 1)
+
+	entry := (*entries)[0]
+	assert.Equal(t, "Unit state changed", entry.message)
+	assert.Equal(t, journal.PriWarning, entry.priority)
+	assert.Equal(t, map[string]string{
+		"SYSLOG_IDENTIFIER": "elastic-agent",
+		"LOGGER_NAME":       "coordinator",
+		"LOG_SOURCE":        "elastic-agent",
+		"UNIT_ID":           "filestream-default",
+		"REVISION":          "2",
+		"TAGS":              `["a","b"]`,
+	}, entry.vars)
+}
+
+func TestJournaldCoreComponents(t *testing.T) {
+	component := zap.Any(componentKey, map[string]interface{}{"id": "filestream-default", "type": "filestream"})
+	entry := zapcore.Entry{Level: zapcore.ErrorLevel, Message: "failed to read"}
+
+	core, entries := newTestJournaldCore(false)
+	compCore := core.With([]zapcore.Field{component})
+	assert.Nil(t, compCore.Check(entry, nil), "the logs of the components are not sent by default")
+	require.NoError(t, compCore.Write(entry, nil))
+	assert.Empty(t, *entries)
+
+	core, entries = newTestJournaldCore(true)
+	compCore = core.With([]zapcore.Field{component})
+	require.NoError(t, compCore.Write(entry, nil))
+	require.Len(t, *entries, 1)
+	assert.Equal(t, journal.PriErr, (*entries)[0].priority)
+	assert.Equal(t, "filestream-default", (*entries)[0].vars["COMPONENT_ID"])
+	assert.Equal(t, "filestream", (*entries)[0].vars["COMPONENT_TYPE"])
+}
+
+func TestComponentCore(t *testing.T) {
+	observed, logs := observer.New(zapcore.InfoLevel)
+	log := zap.New(&componentCore{Core: observed})
+
+	log.Info("Elastic Agent log")
+	log.With(zap.Any(componentKey, map[string]interface{}{"id": "filestream-default"})).Info("component log")
+	require.Equal(t, 1, logs.Len(), "only the logs of the components are written")
+	assert.Equal(t, "component log", logs.All()[0].Message)
+}
+
+func TestJournalFieldName(t *testing.T) {
+	assert.Equal(t, "ERROR_MESSAGE", journalFieldName("", "error.message"))
+	assert.Equal(t, "COMPONENT_ID", journalFieldName("COMPONENT", "id"))
+	assert.Equal(t, "TIMESTAMP", journalFieldName("", "@timestamp"))
+	assert.Equal(t, "", journalFieldName("", "_"))
+}
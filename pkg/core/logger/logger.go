@@ -66,8 +66,8 @@ func NewWithLogpLevel(name string, level logp.Level, logInternal bool) (*Logger,
 
 // NewFromConfig takes the user configuration and generate the right logger.
 // We should finish implementation, need support on the library that we use.
-func NewFromConfig(name string, cfg, eventLogCfg *Config, logInternal bool) (*Logger, error) {
-	return new(name, cfg, eventLogCfg, logInternal)
+func NewFromConfig(name string, cfg, eventLogCfg *Config, logInternal bool, opts ...Option) (*Logger, error) {
+	return new(name, cfg, eventLogCfg, logInternal, opts...)
 }
 
 // NewWithoutConfig returns a new logger without having a configuration.
@@ -109,7 +109,26 @@ func AddCallerSkip(l *Logger, skip int) *Logger {
 	return l.WithOptions(zap.AddCallerSkip(skip))
 }
 
-func new(name string, cfg, eventLoggerCfg *Config, logInternal bool) (*Logger, error) {
+func new(name string, cfg, eventLoggerCfg *Config, logInternal bool, opts ...Option) (*Logger, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	journaldUnavailable := false
+	if o.journald != nil && !JournaldAvailable() {
+		// keep the configured outputs, the logs are not lost when journald is not running
+		journaldUnavailable = true
+		o.journald = nil
+	}
+	fileCfg := cfg
+	if o.journald != nil {
+		// journald replaces the log files of the configuration
+		journaldCfg := *cfg
+		journaldCfg.ToFiles = false
+		cfg = &journaldCfg
+	}
+
 	commonCfg, err := ToCommonConfig(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("could not convert log config: %w", err)
@@ -124,6 +143,17 @@ func new(name string, cfg, eventLoggerCfg *Config, logInternal bool) (*Logger, e
 
 		outputs = append(outputs, internal)
 	}
+	if o.journald != nil {
+		outputs = append(outputs, makeJournaldOutput(cfg, o.journald))
+		if !o.journald.Components && fileCfg.ToFiles {
+			// the logs of the components not sent to journald are kept in the log files of the configuration
+			componentFiles, err := makeComponentFileOutput(fileCfg)
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, componentFiles)
+		}
+	}
 
 	eventLoggercommonCfg, err := ToCommonConfig(eventLoggerCfg)
 	if err != nil {
@@ -134,7 +164,11 @@ func new(name string, cfg, eventLoggerCfg *Config, logInternal bool) (*Logger, e
 		return nil, fmt.Errorf("error initializing logging: %w", err)
 	}
 
-	return logp.NewLogger(name), nil
+	log := logp.NewLogger(name)
+	if journaldUnavailable {
+		log.Warn("Logging to journald is enabled but journald is not available, logging to the configured outputs")
+	}
+	return log, nil
 }
 
 func ToCommonConfig(cfg *Config) (*config.C, error) {
@@ -162,6 +196,9 @@ func SetLevel(lvl logp.Level) {
 	if internalLevelEnabler != nil {
 		internalLevelEnabler.SetLevel(zapLevel)
 	}
+	if journaldLevelEnabler != nil {
+		journaldLevelEnabler.SetLevel(zapLevel)
+	}
 }
 
 // DefaultLoggingConfig returns default configuration for agent logging.
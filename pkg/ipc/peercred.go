@@ -0,0 +1,12 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+package ipc
+
+import (
+	"errors"
+)
+
+// ErrPeerCredentialsUnsupported is returned when the credentials of the peers cannot be read on the platform.
+var ErrPeerCredentialsUnsupported = errors.New("peer credentials are not supported on this platform")
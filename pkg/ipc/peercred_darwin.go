@@ -0,0 +1,45 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build darwin
+
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// PeerCredentialsSupported is true when the identity of the peers can be read from the connections.
+const PeerCredentialsSupported = true
+
+// PeerCredentials returns the user and the primary group of the process at the other end of the unix
+// socket.
+func PeerCredentials(conn net.Conn) (uint32, uint32, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0, 0, fmt.Errorf("connection %T is not a unix socket", conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, 0, err
+	}
+	var cred *unix.Xucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	}); err != nil {
+		return 0, 0, err
+	}
+	if credErr != nil {
+		return 0, 0, fmt.Errorf("failed to read the peer credentials: %w", credErr)
+	}
+	if cred.Ngroups == 0 {
+		return cred.Uid, 0, fmt.Errorf("peer credentials of uid %d have no group", cred.Uid)
+	}
+	return cred.Uid, cred.Groups[0], nil
+}
@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !linux && !darwin
+
+package ipc
+
+import (
+	"net"
+)
+
+// PeerCredentialsSupported is false, on Windows the access to the named pipes is restricted by their
+// security descriptor. The other platforms only rely on the permissions of the socket.
+const PeerCredentialsSupported = false
+
+// PeerCredentials is not supported on this platform.
+func PeerCredentials(_ net.Conn) (uint32, uint32, error) {
+	return 0, 0, ErrPeerCredentialsUnsupported
+}
@@ -9,6 +9,8 @@ import (
 	"runtime"
 	"sync"
 
+	"github.com/docker/go-units"
+
 	"github.com/elastic/elastic-agent/internal/pkg/config"
 )
 
@@ -33,6 +35,50 @@ type LimitsConfig struct {
 	// Translates into the GOMAXPROCS runtime parameter for each Go process started by the agent and the agent itself.
 	// By default is set to `0` which means using all available CPUs.
 	GoMaxProcs int `yaml:"go_max_procs" config:"go_max_procs" json:"go_max_procs"`
+
+	// Components are the resource budgets of the components by name, the name is either the ID of a component,
+	// its input type or the name of its binary. The budgets are enforced by the agent and not sent to the components.
+	Components map[string]ComponentBudget `yaml:"components,omitempty" config:"components" json:"-"`
+}
+
+// ComponentBudget is the resource budget of a component, enforced by the runtime with cgroups on Linux, job
+// objects on Windows and rlimits where neither is available.
+type ComponentBudget struct {
+	// CPU is the number of CPUs the component can use, fractions are allowed. 0 means no limit.
+	CPU float64 `yaml:"cpu,omitempty" config:"cpu" json:"cpu,omitempty"`
+	// Memory is the memory the component can use, as a size like 512MB. Empty means no limit.
+	Memory string `yaml:"memory,omitempty" config:"memory" json:"memory,omitempty"`
+}
+
+// Validate validates the budget.
+func (b *ComponentBudget) Validate() error {
+	if b.CPU < 0 {
+		return fmt.Errorf("invalid CPU budget %v, must not be negative", b.CPU)
+	}
+	if _, err := b.MemoryBytes(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MemoryBytes returns the memory budget in bytes, 0 when there is no limit.
+func (b *ComponentBudget) MemoryBytes() (uint64, error) {
+	if b.Memory == "" {
+		return 0, nil
+	}
+	memory, err := units.RAMInBytes(b.Memory)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory budget %q: %w", b.Memory, err)
+	}
+	if memory < 0 {
+		return 0, fmt.Errorf("invalid memory budget %q, must not be negative", b.Memory)
+	}
+	return uint64(memory), nil
+}
+
+// IsZero returns true when the budget doesn't limit anything.
+func (b *ComponentBudget) IsZero() bool {
+	return b.CPU == 0 && b.Memory == ""
 }
 
 type LimitsOnChangeCallback func(new, old LimitsConfig)
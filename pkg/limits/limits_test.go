@@ -96,3 +96,29 @@ func TestAddLimitsOnChangeCallback(t *testing.T) {
 		require.False(t, called, "callback must not be called")
 	})
 }
+
+func TestParseComponentBudgets(t *testing.T) {
+	parsed, err := Parse(config.MustNewConfigFrom(`
+agent.limits.components:
+  filestream:
+    cpu: 0.5
+    memory: 512MB
+  metricbeat:
+    memory: 1GB
+`))
+	require.NoError(t, err)
+	require.Len(t, parsed.Components, 2)
+
+	filestream := parsed.Components["filestream"]
+	require.Equal(t, 0.5, filestream.CPU)
+	memory, err := filestream.MemoryBytes()
+	require.NoError(t, err)
+	require.Equal(t, uint64(512*1024*1024), memory)
+	require.False(t, filestream.IsZero())
+
+	_, err = Parse(config.MustNewConfigFrom(`agent.limits.components.filestream.memory: lots`))
+	require.Error(t, err, "an invalid memory budget should be rejected")
+
+	_, err = Parse(config.MustNewConfigFrom(`agent.limits.components.filestream.cpu: -1`))
+	require.Error(t, err, "a negative CPU budget should be rejected")
+}
@@ -773,7 +773,7 @@ func WithCmdOptions(cmdOptions ...process.CmdOption) func(opt *statusOpts) {
 
 // ExecStatus executes `elastic-agent status --output=json`.
 //
-// Returns the parsed output and the error from the execution. Keep in mind the agent exits with status 1 if it's
+// Returns the parsed output and the error from the execution. Keep in mind the agent exits with a non-zero status if it's
 // unhealthy, but it still outputs the status successfully. This call does require that the Elastic Agent is running
 // and communication over the control protocol is working.
 //
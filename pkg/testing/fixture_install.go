@@ -269,9 +269,9 @@ func (f *Fixture) installNoPkgManager(ctx context.Context, installOpts *InstallO
 	// we just installed agent, the control socket is at a well-known location
 	socketPath := fmt.Sprintf("unix://%s", socketRunSymlink) // use symlink as that works for all versions
 	if runtime.GOOS == "windows" {
-		// Windows uses a fixed named pipe, that is always the same.
+		// Windows uses a fixed named pipe for the namespace.
 		// It is the same even running in unprivileged mode.
-		socketPath = paths.WindowsControlSocketInstalledPath
+		socketPath = paths.WindowsControlSocketInstalledPathForNamespace(installOpts.Namespace)
 	} else if !installOpts.Privileged {
 		// Unprivileged versions move the socket to inside the installed directory
 		// of the Elastic Agent.
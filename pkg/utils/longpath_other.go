@@ -0,0 +1,20 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build !windows
+
+package utils
+
+// MaxPathLength is not limited on non-Windows systems.
+const MaxPathLength = 0
+
+// FixLongPath returns path unmodified, only Windows limits the length of a path.
+func FixLongPath(path string) string {
+	return path
+}
+
+// LongPathsEnabled always returns true, only Windows limits the length of a path.
+func LongPathsEnabled() (bool, error) {
+	return true, nil
+}
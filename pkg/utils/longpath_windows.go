@@ -0,0 +1,71 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+const (
+	// MaxPathLength is the maximum length of a path on Windows when long paths are not enabled (MAX_PATH).
+	MaxPathLength = 260
+
+	// maxDirLength is the maximum length of a directory path on Windows when long paths are not enabled,
+	// this leaves room for an 8.3 filename (MAX_PATH - 12).
+	maxDirLength = MaxPathLength - 12
+
+	longPathPrefix    = `\\?\`
+	longUNCPathPrefix = `\\?\UNC\`
+
+	fileSystemKey        = `SYSTEM\CurrentControlSet\Control\FileSystem`
+	longPathsEnabledName = "LongPathsEnabled"
+)
+
+// FixLongPath returns the extended-length form of path when it is too long to be used with the Win32 API
+// without long path support. The returned path is absolute and prefixed with `\\?\` (or `\\?\UNC\`).
+//
+// The os package already does this for the paths it is given, this is required for the Win32 API that is
+// called directly (ACLs, ownership, etc.) and for relative paths that only become long once made absolute.
+func FixLongPath(path string) string {
+	if strings.HasPrefix(path, longPathPrefix) || strings.HasPrefix(path, `\\.\`) {
+		// already extended-length or a device path
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if len(abs) < maxDirLength {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		// UNC path \\server\share\path
+		return longUNCPathPrefix + abs[2:]
+	}
+	return longPathPrefix + abs
+}
+
+// LongPathsEnabled returns true when long path support is enabled for the system (LongPathsEnabled).
+func LongPathsEnabled() (bool, error) {
+	key, err := registry.OpenKey(registry.LOCAL_MACHINE, fileSystemKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false, err
+	}
+	defer key.Close()
+	val, _, err := key.GetIntegerValue(longPathsEnabledName)
+	if errors.Is(err, registry.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return val == 1, nil
+}
@@ -0,0 +1,42 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFixLongPath(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	longPath := `C:\` + strings.Join([]string{long, long, long}, `\`)
+	longUNC := `\\server\share\` + strings.Join([]string{long, long, long}, `\`)
+
+	testcases := map[string]struct {
+		path     string
+		expected string
+	}{
+		"short":             {path: `C:\Program Files\Elastic\Agent`, expected: `C:\Program Files\Elastic\Agent`},
+		"long":              {path: longPath, expected: `\\?\` + longPath},
+		"long UNC":          {path: longUNC, expected: `\\?\UNC\` + longUNC[2:]},
+		"already extended":  {path: `\\?\` + longPath, expected: `\\?\` + longPath},
+		"device path":       {path: `\\.\pipe\elastic-agent`, expected: `\\.\pipe\elastic-agent`},
+		"long with cleanup": {path: longPath + `\.\b\..`, expected: `\\?\` + longPath},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, FixLongPath(tc.path))
+		})
+	}
+}
+
+func TestLongPathsEnabled(t *testing.T) {
+	_, err := LongPathsEnabled()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,79 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package utils
+
+import (
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// trustedWriteAccess are the access rights that allow replacing the content, the attributes or the security of a file.
+const trustedWriteAccess = windows.FILE_WRITE_DATA | windows.FILE_APPEND_DATA | windows.FILE_WRITE_EA |
+	windows.FILE_WRITE_ATTRIBUTES | windows.DELETE | windows.WRITE_DAC | windows.WRITE_OWNER |
+	windows.GENERIC_WRITE | windows.GENERIC_ALL
+
+// CheckTrustedFile ensures the file is a regular file owned by Administrators or SYSTEM and that its DACL grants no
+// write access to the other users, so it cannot be replaced by a less privileged user.
+func CheckTrustedFile(path string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("could not stat %s: %w", path, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	sd, err := windows.GetNamedSecurityInfo(path, windows.SE_FILE_OBJECT, windows.OWNER_SECURITY_INFORMATION|windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("could not get the security information of %s: %w", path, err)
+	}
+	owner, _, err := sd.Owner()
+	if err != nil {
+		return fmt.Errorf("could not get the owner of %s: %w", path, err)
+	}
+	if !isTrustedSID(owner) {
+		return fmt.Errorf("%s must be owned by Administrators or SYSTEM, it is owned by %s", path, owner.String())
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("could not get the DACL of %s: %w", path, err)
+	}
+	if dacl == nil {
+		// a NULL DACL grants full access to everyone
+		return fmt.Errorf("%s has no DACL, it is writable by everyone", path)
+	}
+	for i := uint32(0); i < uint32(dacl.AceCount); i++ {
+		var ace *windows.ACCESS_ALLOWED_ACE
+		if err := windows.GetAce(dacl, i, &ace); err != nil {
+			return fmt.Errorf("could not get the ACE %d of %s: %w", i, path, err)
+		}
+		if ace.Header.AceFlags&windows.INHERIT_ONLY_ACE != 0 {
+			// only applies to the children of a directory
+			continue
+		}
+		switch ace.Header.AceType {
+		case windows.ACCESS_DENIED_ACE_TYPE:
+			continue
+		case windows.ACCESS_ALLOWED_ACE_TYPE:
+		default:
+			return fmt.Errorf("%s has an ACE of unsupported type %d", path, ace.Header.AceType)
+		}
+		sid := (*windows.SID)(unsafe.Pointer(&ace.SidStart))
+		if ace.Mask&trustedWriteAccess != 0 && !isTrustedSID(sid) {
+			return fmt.Errorf("%s must not be writable by %s", path, sid.String())
+		}
+	}
+	return nil
+}
+
+// isTrustedSID returns true for the Administrators group and the SYSTEM user.
+func isTrustedSID(sid *windows.SID) bool {
+	return sid.IsWellKnown(windows.WinBuiltinAdministratorsSid) || sid.IsWellKnown(windows.WinLocalSystemSid)
+}
@@ -0,0 +1,57 @@
+// Copyright Elasticsearch B.V. and/or licensed to Elasticsearch B.V. under one
+// or more contributor license agreements. Licensed under the Elastic License 2.0;
+// you may not use this file except in compliance with the Elastic License 2.0.
+
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/windows"
+
+	"github.com/elastic/elastic-agent/internal/pkg/acl"
+)
+
+func TestCheckTrustedFile(t *testing.T) {
+	if root, _ := HasRoot(); !root {
+		t.Skip("giving the ownership of a file to Administrators requires Administrator rights")
+	}
+	admins, err := windows.CreateWellKnownSid(windows.WinBuiltinAdministratorsSid)
+	require.NoError(t, err)
+	system, err := windows.CreateWellKnownSid(windows.WinLocalSystemSid)
+	require.NoError(t, err)
+	everyone, err := windows.CreateWellKnownSid(windows.WinWorldSid)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "trusted.exe")
+	require.NoError(t, os.WriteFile(path, []byte("trusted"), 0o600))
+	require.NoError(t, acl.TakeOwnership(path, admins, nil))
+	require.NoError(t, acl.Apply(path, true, false,
+		acl.GrantSid(windows.GENERIC_ALL, admins),
+		acl.GrantSid(windows.GENERIC_ALL, system),
+		acl.GrantSid(windows.GENERIC_READ|windows.GENERIC_EXECUTE, everyone),
+	))
+	assert.NoError(t, CheckTrustedFile(path))
+
+	require.NoError(t, acl.Apply(path, false, false, acl.GrantSid(windows.GENERIC_WRITE, everyone)))
+	assert.ErrorContains(t, CheckTrustedFile(path), "must not be writable by S-1-1-0")
+
+	user, err := CurrentFileOwner()
+	require.NoError(t, err)
+	userSID, err := windows.StringToSid(user.UID)
+	require.NoError(t, err)
+	if !isTrustedSID(userSID) {
+		owned := filepath.Join(t.TempDir(), "owned.exe")
+		require.NoError(t, os.WriteFile(owned, []byte("owned"), 0o600))
+		require.NoError(t, acl.TakeOwnership(owned, userSID, nil))
+		assert.ErrorContains(t, CheckTrustedFile(owned), "must be owned by Administrators or SYSTEM")
+	}
+
+	assert.ErrorContains(t, CheckTrustedFile(t.TempDir()), "is not a regular file")
+}
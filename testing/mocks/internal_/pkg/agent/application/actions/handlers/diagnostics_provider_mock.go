@@ -18,6 +18,8 @@ import (
 	mock "github.com/stretchr/testify/mock"
 
 	runtime "github.com/elastic/elastic-agent/pkg/component/runtime"
+
+	time "time"
 )
 
 // DiagnosticsProvider is an autogenerated mock type for the diagnosticsProvider type
@@ -80,14 +82,14 @@ func (_c *DiagnosticsProvider_DiagnosticHooks_Call) RunAndReturn(run func() diag
 	return _c
 }
 
-// PerformComponentDiagnostics provides a mock function with given fields: ctx, additionalMetrics, req
-func (_m *DiagnosticsProvider) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
+// PerformComponentDiagnostics provides a mock function with given fields: ctx, additionalMetrics, cpuProfile, req
+func (_m *DiagnosticsProvider) PerformComponentDiagnostics(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component) ([]runtime.ComponentDiagnostic, error) {
 	_va := make([]interface{}, len(req))
 	for _i := range req {
 		_va[_i] = req[_i]
 	}
 	var _ca []interface{}
-	_ca = append(_ca, ctx, additionalMetrics)
+	_ca = append(_ca, ctx, additionalMetrics, cpuProfile)
 	_ca = append(_ca, _va...)
 	ret := _m.Called(_ca...)
 
@@ -97,19 +99,19 @@ func (_m *DiagnosticsProvider) PerformComponentDiagnostics(ctx context.Context,
 
 	var r0 []runtime.ComponentDiagnostic
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, ...component.Component) ([]runtime.ComponentDiagnostic, error)); ok {
-		return rf(ctx, additionalMetrics, req...)
+	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) ([]runtime.ComponentDiagnostic, error)); ok {
+		return rf(ctx, additionalMetrics, cpuProfile, req...)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, ...component.Component) []runtime.ComponentDiagnostic); ok {
-		r0 = rf(ctx, additionalMetrics, req...)
+	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) []runtime.ComponentDiagnostic); ok {
+		r0 = rf(ctx, additionalMetrics, cpuProfile, req...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]runtime.ComponentDiagnostic)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []cproto.AdditionalDiagnosticRequest, ...component.Component) error); ok {
-		r1 = rf(ctx, additionalMetrics, req...)
+	if rf, ok := ret.Get(1).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) error); ok {
+		r1 = rf(ctx, additionalMetrics, cpuProfile, req...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -125,21 +127,22 @@ type DiagnosticsProvider_PerformComponentDiagnostics_Call struct {
 // PerformComponentDiagnostics is a helper method to define mock.On call
 //   - ctx context.Context
 //   - additionalMetrics []cproto.AdditionalDiagnosticRequest
+//   - cpuProfile time.Duration
 //   - req ...component.Component
-func (_e *DiagnosticsProvider_Expecter) PerformComponentDiagnostics(ctx interface{}, additionalMetrics interface{}, req ...interface{}) *DiagnosticsProvider_PerformComponentDiagnostics_Call {
+func (_e *DiagnosticsProvider_Expecter) PerformComponentDiagnostics(ctx interface{}, additionalMetrics interface{}, cpuProfile interface{}, req ...interface{}) *DiagnosticsProvider_PerformComponentDiagnostics_Call {
 	return &DiagnosticsProvider_PerformComponentDiagnostics_Call{Call: _e.mock.On("PerformComponentDiagnostics",
-		append([]interface{}{ctx, additionalMetrics}, req...)...)}
+		append([]interface{}{ctx, additionalMetrics, cpuProfile}, req...)...)}
 }
 
-func (_c *DiagnosticsProvider_PerformComponentDiagnostics_Call) Run(run func(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, req ...component.Component)) *DiagnosticsProvider_PerformComponentDiagnostics_Call {
+func (_c *DiagnosticsProvider_PerformComponentDiagnostics_Call) Run(run func(ctx context.Context, additionalMetrics []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, req ...component.Component)) *DiagnosticsProvider_PerformComponentDiagnostics_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		variadicArgs := make([]component.Component, len(args)-2)
-		for i, a := range args[2:] {
+		variadicArgs := make([]component.Component, len(args)-3)
+		for i, a := range args[3:] {
 			if a != nil {
 				variadicArgs[i] = a.(component.Component)
 			}
 		}
-		run(args[0].(context.Context), args[1].([]cproto.AdditionalDiagnosticRequest), variadicArgs...)
+		run(args[0].(context.Context), args[1].([]cproto.AdditionalDiagnosticRequest), args[2].(time.Duration), variadicArgs...)
 	})
 	return _c
 }
@@ -149,7 +152,7 @@ func (_c *DiagnosticsProvider_PerformComponentDiagnostics_Call) Return(_a0 []run
 	return _c
 }
 
-func (_c *DiagnosticsProvider_PerformComponentDiagnostics_Call) RunAndReturn(run func(context.Context, []cproto.AdditionalDiagnosticRequest, ...component.Component) ([]runtime.ComponentDiagnostic, error)) *DiagnosticsProvider_PerformComponentDiagnostics_Call {
+func (_c *DiagnosticsProvider_PerformComponentDiagnostics_Call) RunAndReturn(run func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...component.Component) ([]runtime.ComponentDiagnostic, error)) *DiagnosticsProvider_PerformComponentDiagnostics_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -16,6 +16,8 @@ import (
 	grpc "google.golang.org/grpc"
 
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // Client is an autogenerated mock type for the Client type
@@ -31,6 +33,64 @@ func (_m *Client) EXPECT() *Client_Expecter {
 	return &Client_Expecter{mock: &_m.Mock}
 }
 
+// Components provides a mock function with given fields: ctx
+func (_m *Client) Components(ctx context.Context) (*client.AgentComponents, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Components")
+	}
+
+	var r0 *client.AgentComponents
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*client.AgentComponents, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *client.AgentComponents); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*client.AgentComponents)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_Components_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Components'
+type Client_Components_Call struct {
+	*mock.Call
+}
+
+// Components is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Client_Expecter) Components(ctx interface{}) *Client_Components_Call {
+	return &Client_Components_Call{Call: _e.mock.On("Components", ctx)}
+}
+
+func (_c *Client_Components_Call) Run(run func(ctx context.Context)) *Client_Components_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Client_Components_Call) Return(_a0 *client.AgentComponents, _a1 error) *Client_Components_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_Components_Call) RunAndReturn(run func(context.Context) (*client.AgentComponents, error)) *Client_Components_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Configure provides a mock function with given fields: ctx, config
 func (_m *Client) Configure(ctx context.Context, config string) error {
 	ret := _m.Called(ctx, config)
@@ -139,9 +199,9 @@ func (_c *Client_Connect_Call) RunAndReturn(run func(context.Context, ...grpc.Di
 	return _c
 }
 
-// DiagnosticAgent provides a mock function with given fields: ctx, additionalDiags
-func (_m *Client) DiagnosticAgent(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest) ([]client.DiagnosticFileResult, error) {
-	ret := _m.Called(ctx, additionalDiags)
+// DiagnosticAgent provides a mock function with given fields: ctx, additionalDiags, cpuProfile
+func (_m *Client) DiagnosticAgent(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration) ([]client.DiagnosticFileResult, error) {
+	ret := _m.Called(ctx, additionalDiags, cpuProfile)
 
 	if len(ret) == 0 {
 		panic("no return value specified for DiagnosticAgent")
@@ -149,19 +209,19 @@ func (_m *Client) DiagnosticAgent(ctx context.Context, additionalDiags []cproto.
 
 	var r0 []client.DiagnosticFileResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest) ([]client.DiagnosticFileResult, error)); ok {
-		return rf(ctx, additionalDiags)
+	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration) ([]client.DiagnosticFileResult, error)); ok {
+		return rf(ctx, additionalDiags, cpuProfile)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest) []client.DiagnosticFileResult); ok {
-		r0 = rf(ctx, additionalDiags)
+	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration) []client.DiagnosticFileResult); ok {
+		r0 = rf(ctx, additionalDiags, cpuProfile)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]client.DiagnosticFileResult)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []cproto.AdditionalDiagnosticRequest) error); ok {
-		r1 = rf(ctx, additionalDiags)
+	if rf, ok := ret.Get(1).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration) error); ok {
+		r1 = rf(ctx, additionalDiags, cpuProfile)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -176,14 +236,14 @@ type Client_DiagnosticAgent_Call struct {
 
 // DiagnosticAgent is a helper method to define mock.On call
 //   - ctx context.Context
-//   - additionalDiags []cproto.AdditionalDiagnosticRequest
-func (_e *Client_Expecter) DiagnosticAgent(ctx interface{}, additionalDiags interface{}) *Client_DiagnosticAgent_Call {
-	return &Client_DiagnosticAgent_Call{Call: _e.mock.On("DiagnosticAgent", ctx, additionalDiags)}
+//   - additionalDiags []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration
+func (_e *Client_Expecter) DiagnosticAgent(ctx interface{}, additionalDiags interface{}, cpuProfile interface{}) *Client_DiagnosticAgent_Call {
+	return &Client_DiagnosticAgent_Call{Call: _e.mock.On("DiagnosticAgent", ctx, additionalDiags, cpuProfile)}
 }
 
-func (_c *Client_DiagnosticAgent_Call) Run(run func(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest)) *Client_DiagnosticAgent_Call {
+func (_c *Client_DiagnosticAgent_Call) Run(run func(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration)) *Client_DiagnosticAgent_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		run(args[0].(context.Context), args[1].([]cproto.AdditionalDiagnosticRequest))
+		run(args[0].(context.Context), args[1].([]cproto.AdditionalDiagnosticRequest), args[2].(time.Duration))
 	})
 	return _c
 }
@@ -193,19 +253,19 @@ func (_c *Client_DiagnosticAgent_Call) Return(_a0 []client.DiagnosticFileResult,
 	return _c
 }
 
-func (_c *Client_DiagnosticAgent_Call) RunAndReturn(run func(context.Context, []cproto.AdditionalDiagnosticRequest) ([]client.DiagnosticFileResult, error)) *Client_DiagnosticAgent_Call {
+func (_c *Client_DiagnosticAgent_Call) RunAndReturn(run func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration) ([]client.DiagnosticFileResult, error)) *Client_DiagnosticAgent_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// DiagnosticComponents provides a mock function with given fields: ctx, additionalDiags, components
-func (_m *Client) DiagnosticComponents(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest, components ...client.DiagnosticComponentRequest) ([]client.DiagnosticComponentResult, error) {
+// DiagnosticComponents provides a mock function with given fields: ctx, additionalDiags, cpuProfile, components
+func (_m *Client) DiagnosticComponents(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, components ...client.DiagnosticComponentRequest) ([]client.DiagnosticComponentResult, error) {
 	_va := make([]interface{}, len(components))
 	for _i := range components {
 		_va[_i] = components[_i]
 	}
 	var _ca []interface{}
-	_ca = append(_ca, ctx, additionalDiags)
+	_ca = append(_ca, ctx, additionalDiags, cpuProfile)
 	_ca = append(_ca, _va...)
 	ret := _m.Called(_ca...)
 
@@ -215,19 +275,19 @@ func (_m *Client) DiagnosticComponents(ctx context.Context, additionalDiags []cp
 
 	var r0 []client.DiagnosticComponentResult
 	var r1 error
-	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, ...client.DiagnosticComponentRequest) ([]client.DiagnosticComponentResult, error)); ok {
-		return rf(ctx, additionalDiags, components...)
+	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...client.DiagnosticComponentRequest) ([]client.DiagnosticComponentResult, error)); ok {
+		return rf(ctx, additionalDiags, cpuProfile, components...)
 	}
-	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, ...client.DiagnosticComponentRequest) []client.DiagnosticComponentResult); ok {
-		r0 = rf(ctx, additionalDiags, components...)
+	if rf, ok := ret.Get(0).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...client.DiagnosticComponentRequest) []client.DiagnosticComponentResult); ok {
+		r0 = rf(ctx, additionalDiags, cpuProfile, components...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]client.DiagnosticComponentResult)
 		}
 	}
 
-	if rf, ok := ret.Get(1).(func(context.Context, []cproto.AdditionalDiagnosticRequest, ...client.DiagnosticComponentRequest) error); ok {
-		r1 = rf(ctx, additionalDiags, components...)
+	if rf, ok := ret.Get(1).(func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...client.DiagnosticComponentRequest) error); ok {
+		r1 = rf(ctx, additionalDiags, cpuProfile, components...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -242,22 +302,22 @@ type Client_DiagnosticComponents_Call struct {
 
 // DiagnosticComponents is a helper method to define mock.On call
 //   - ctx context.Context
-//   - additionalDiags []cproto.AdditionalDiagnosticRequest
+//   - additionalDiags []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration
 //   - components ...client.DiagnosticComponentRequest
-func (_e *Client_Expecter) DiagnosticComponents(ctx interface{}, additionalDiags interface{}, components ...interface{}) *Client_DiagnosticComponents_Call {
+func (_e *Client_Expecter) DiagnosticComponents(ctx interface{}, additionalDiags interface{}, cpuProfile interface{}, components ...interface{}) *Client_DiagnosticComponents_Call {
 	return &Client_DiagnosticComponents_Call{Call: _e.mock.On("DiagnosticComponents",
-		append([]interface{}{ctx, additionalDiags}, components...)...)}
+		append([]interface{}{ctx, additionalDiags, cpuProfile}, components...)...)}
 }
 
-func (_c *Client_DiagnosticComponents_Call) Run(run func(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest, components ...client.DiagnosticComponentRequest)) *Client_DiagnosticComponents_Call {
+func (_c *Client_DiagnosticComponents_Call) Run(run func(ctx context.Context, additionalDiags []cproto.AdditionalDiagnosticRequest, cpuProfile time.Duration, components ...client.DiagnosticComponentRequest)) *Client_DiagnosticComponents_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		variadicArgs := make([]client.DiagnosticComponentRequest, len(args)-2)
-		for i, a := range args[2:] {
+		variadicArgs := make([]client.DiagnosticComponentRequest, len(args)-3)
+		for i, a := range args[3:] {
 			if a != nil {
 				variadicArgs[i] = a.(client.DiagnosticComponentRequest)
 			}
 		}
-		run(args[0].(context.Context), args[1].([]cproto.AdditionalDiagnosticRequest), variadicArgs...)
+		run(args[0].(context.Context), args[1].([]cproto.AdditionalDiagnosticRequest), args[2].(time.Duration), variadicArgs...)
 	})
 	return _c
 }
@@ -267,7 +327,7 @@ func (_c *Client_DiagnosticComponents_Call) Return(_a0 []client.DiagnosticCompon
 	return _c
 }
 
-func (_c *Client_DiagnosticComponents_Call) RunAndReturn(run func(context.Context, []cproto.AdditionalDiagnosticRequest, ...client.DiagnosticComponentRequest) ([]client.DiagnosticComponentResult, error)) *Client_DiagnosticComponents_Call {
+func (_c *Client_DiagnosticComponents_Call) RunAndReturn(run func(context.Context, []cproto.AdditionalDiagnosticRequest, time.Duration, ...client.DiagnosticComponentRequest) ([]client.DiagnosticComponentResult, error)) *Client_DiagnosticComponents_Call {
 	_c.Call.Return(run)
 	return _c
 }
@@ -377,6 +437,170 @@ func (_c *Client_Disconnect_Call) RunAndReturn(run func()) *Client_Disconnect_Ca
 	return _c
 }
 
+// MaintenanceUnlock provides a mock function with given fields: ctx, duration
+func (_m *Client) MaintenanceUnlock(ctx context.Context, duration time.Duration) (time.Time, error) {
+	ret := _m.Called(ctx, duration)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MaintenanceUnlock")
+	}
+
+	var r0 time.Time
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) (time.Time, error)); ok {
+		return rf(ctx, duration)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration) time.Time); ok {
+		r0 = rf(ctx, duration)
+	} else {
+		r0 = ret.Get(0).(time.Time)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration) error); ok {
+		r1 = rf(ctx, duration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_MaintenanceUnlock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'MaintenanceUnlock'
+type Client_MaintenanceUnlock_Call struct {
+	*mock.Call
+}
+
+// MaintenanceUnlock is a helper method to define mock.On call
+//   - ctx context.Context
+//   - duration time.Duration
+func (_e *Client_Expecter) MaintenanceUnlock(ctx interface{}, duration interface{}) *Client_MaintenanceUnlock_Call {
+	return &Client_MaintenanceUnlock_Call{Call: _e.mock.On("MaintenanceUnlock", ctx, duration)}
+}
+
+func (_c *Client_MaintenanceUnlock_Call) Run(run func(ctx context.Context, duration time.Duration)) *Client_MaintenanceUnlock_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(time.Duration))
+	})
+	return _c
+}
+
+func (_c *Client_MaintenanceUnlock_Call) Return(_a0 time.Time, _a1 error) *Client_MaintenanceUnlock_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_MaintenanceUnlock_Call) RunAndReturn(run func(context.Context, time.Duration) (time.Time, error)) *Client_MaintenanceUnlock_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Migrate provides a mock function with given fields: ctx, targetURI, enrollmentToken, settings
+func (_m *Client) Migrate(ctx context.Context, targetURI string, enrollmentToken string, settings []byte) error {
+	ret := _m.Called(ctx, targetURI, enrollmentToken, settings)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Migrate")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, []byte) error); ok {
+		r0 = rf(ctx, targetURI, enrollmentToken, settings)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Client_Migrate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Migrate'
+type Client_Migrate_Call struct {
+	*mock.Call
+}
+
+// Migrate is a helper method to define mock.On call
+//   - ctx context.Context
+//   - targetURI string
+//   - enrollmentToken string
+//   - settings []byte
+func (_e *Client_Expecter) Migrate(ctx interface{}, targetURI interface{}, enrollmentToken interface{}, settings interface{}) *Client_Migrate_Call {
+	return &Client_Migrate_Call{Call: _e.mock.On("Migrate", ctx, targetURI, enrollmentToken, settings)}
+}
+
+func (_c *Client_Migrate_Call) Run(run func(ctx context.Context, targetURI string, enrollmentToken string, settings []byte)) *Client_Migrate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(string), args[2].(string), args[3].([]byte))
+	})
+	return _c
+}
+
+func (_c *Client_Migrate_Call) Return(_a0 error) *Client_Migrate_Call {
+	_c.Call.Return(_a0)
+	return _c
+}
+
+func (_c *Client_Migrate_Call) RunAndReturn(run func(context.Context, string, string, []byte) error) *Client_Migrate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Resources provides a mock function with given fields: ctx
+func (_m *Client) Resources(ctx context.Context) (*client.AgentResources, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Resources")
+	}
+
+	var r0 *client.AgentResources
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*client.AgentResources, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *client.AgentResources); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*client.AgentResources)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_Resources_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Resources'
+type Client_Resources_Call struct {
+	*mock.Call
+}
+
+// Resources is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Client_Expecter) Resources(ctx interface{}) *Client_Resources_Call {
+	return &Client_Resources_Call{Call: _e.mock.On("Resources", ctx)}
+}
+
+func (_c *Client_Resources_Call) Run(run func(ctx context.Context)) *Client_Resources_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Client_Resources_Call) Return(_a0 *client.AgentResources, _a1 error) *Client_Resources_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_Resources_Call) RunAndReturn(run func(context.Context) (*client.AgentResources, error)) *Client_Resources_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Restart provides a mock function with given fields: ctx
 func (_m *Client) Restart(ctx context.Context) error {
 	ret := _m.Called(ctx)
@@ -539,6 +763,65 @@ func (_c *Client_StateWatch_Call) RunAndReturn(run func(context.Context) (client
 	return _c
 }
 
+// StreamLogs provides a mock function with given fields: ctx, req
+func (_m *Client) StreamLogs(ctx context.Context, req client.LogsRequest) (client.ClientLogsStream, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for StreamLogs")
+	}
+
+	var r0 client.ClientLogsStream
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, client.LogsRequest) (client.ClientLogsStream, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, client.LogsRequest) client.ClientLogsStream); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(client.ClientLogsStream)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, client.LogsRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_StreamLogs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'StreamLogs'
+type Client_StreamLogs_Call struct {
+	*mock.Call
+}
+
+// StreamLogs is a helper method to define mock.On call
+//   - ctx context.Context
+//   - req client.LogsRequest
+func (_e *Client_Expecter) StreamLogs(ctx interface{}, req interface{}) *Client_StreamLogs_Call {
+	return &Client_StreamLogs_Call{Call: _e.mock.On("StreamLogs", ctx, req)}
+}
+
+func (_c *Client_StreamLogs_Call) Run(run func(ctx context.Context, req client.LogsRequest)) *Client_StreamLogs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(client.LogsRequest))
+	})
+	return _c
+}
+
+func (_c *Client_StreamLogs_Call) Return(_a0 client.ClientLogsStream, _a1 error) *Client_StreamLogs_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_StreamLogs_Call) RunAndReturn(run func(context.Context, client.LogsRequest) (client.ClientLogsStream, error)) *Client_StreamLogs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Upgrade provides a mock function with given fields: ctx, version, rollback, sourceURI, skipVerify, skipDefaultPgp, pgpBytes
 func (_m *Client) Upgrade(ctx context.Context, version string, rollback bool, sourceURI string, skipVerify bool, skipDefaultPgp bool, pgpBytes ...string) (string, error) {
 	_va := make([]interface{}, len(pgpBytes))
@@ -615,6 +898,64 @@ func (_c *Client_Upgrade_Call) RunAndReturn(run func(context.Context, string, bo
 	return _c
 }
 
+// Vars provides a mock function with given fields: ctx
+func (_m *Client) Vars(ctx context.Context) (*client.AgentVars, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Vars")
+	}
+
+	var r0 *client.AgentVars
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*client.AgentVars, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *client.AgentVars); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*client.AgentVars)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_Vars_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Vars'
+type Client_Vars_Call struct {
+	*mock.Call
+}
+
+// Vars is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Client_Expecter) Vars(ctx interface{}) *Client_Vars_Call {
+	return &Client_Vars_Call{Call: _e.mock.On("Vars", ctx)}
+}
+
+func (_c *Client_Vars_Call) Run(run func(ctx context.Context)) *Client_Vars_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Client_Vars_Call) Return(_a0 *client.AgentVars, _a1 error) *Client_Vars_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_Vars_Call) RunAndReturn(run func(context.Context) (*client.AgentVars, error)) *Client_Vars_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // Version provides a mock function with given fields: ctx
 func (_m *Client) Version(ctx context.Context) (client.Version, error) {
 	ret := _m.Called(ctx)
@@ -671,6 +1012,181 @@ func (_c *Client_Version_Call) RunAndReturn(run func(context.Context) (client.Ve
 	return _c
 }
 
+// WatchState provides a mock function with given fields: ctx, req
+func (_m *Client) WatchState(ctx context.Context, req client.WatchStateRequest) (client.ClientStatusWatch, error) {
+	ret := _m.Called(ctx, req)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchState")
+	}
+
+	var r0 client.ClientStatusWatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, client.WatchStateRequest) (client.ClientStatusWatch, error)); ok {
+		return rf(ctx, req)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, client.WatchStateRequest) client.ClientStatusWatch); ok {
+		r0 = rf(ctx, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(client.ClientStatusWatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, client.WatchStateRequest) error); ok {
+		r1 = rf(ctx, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_WatchState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WatchState'
+type Client_WatchState_Call struct {
+	*mock.Call
+}
+
+// WatchState is a helper method to define mock.On call
+//   - ctx context.Context
+//   - req client.WatchStateRequest
+func (_e *Client_Expecter) WatchState(ctx interface{}, req interface{}) *Client_WatchState_Call {
+	return &Client_WatchState_Call{Call: _e.mock.On("WatchState", ctx, req)}
+}
+
+func (_c *Client_WatchState_Call) Run(run func(ctx context.Context, req client.WatchStateRequest)) *Client_WatchState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context), args[1].(client.WatchStateRequest))
+	})
+	return _c
+}
+
+func (_c *Client_WatchState_Call) Return(_a0 client.ClientStatusWatch, _a1 error) *Client_WatchState_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_WatchState_Call) RunAndReturn(run func(context.Context, client.WatchStateRequest) (client.ClientStatusWatch, error)) *Client_WatchState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WatchStatus provides a mock function with given fields: ctx
+func (_m *Client) WatchStatus(ctx context.Context) (client.ClientStatusWatch, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchStatus")
+	}
+
+	var r0 client.ClientStatusWatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (client.ClientStatusWatch, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) client.ClientStatusWatch); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(client.ClientStatusWatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_WatchStatus_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WatchStatus'
+type Client_WatchStatus_Call struct {
+	*mock.Call
+}
+
+// WatchStatus is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Client_Expecter) WatchStatus(ctx interface{}) *Client_WatchStatus_Call {
+	return &Client_WatchStatus_Call{Call: _e.mock.On("WatchStatus", ctx)}
+}
+
+func (_c *Client_WatchStatus_Call) Run(run func(ctx context.Context)) *Client_WatchStatus_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Client_WatchStatus_Call) Return(_a0 client.ClientStatusWatch, _a1 error) *Client_WatchStatus_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_WatchStatus_Call) RunAndReturn(run func(context.Context) (client.ClientStatusWatch, error)) *Client_WatchStatus_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// WatchUpgradeProgress provides a mock function with given fields: ctx
+func (_m *Client) WatchUpgradeProgress(ctx context.Context) (client.ClientUpgradeProgressWatch, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for WatchUpgradeProgress")
+	}
+
+	var r0 client.ClientUpgradeProgressWatch
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (client.ClientUpgradeProgressWatch, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) client.ClientUpgradeProgressWatch); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(client.ClientUpgradeProgressWatch)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Client_WatchUpgradeProgress_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'WatchUpgradeProgress'
+type Client_WatchUpgradeProgress_Call struct {
+	*mock.Call
+}
+
+// WatchUpgradeProgress is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *Client_Expecter) WatchUpgradeProgress(ctx interface{}) *Client_WatchUpgradeProgress_Call {
+	return &Client_WatchUpgradeProgress_Call{Call: _e.mock.On("WatchUpgradeProgress", ctx)}
+}
+
+func (_c *Client_WatchUpgradeProgress_Call) Run(run func(ctx context.Context)) *Client_WatchUpgradeProgress_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run(args[0].(context.Context))
+	})
+	return _c
+}
+
+func (_c *Client_WatchUpgradeProgress_Call) Return(_a0 client.ClientUpgradeProgressWatch, _a1 error) *Client_WatchUpgradeProgress_Call {
+	_c.Call.Return(_a0, _a1)
+	return _c
+}
+
+func (_c *Client_WatchUpgradeProgress_Call) RunAndReturn(run func(context.Context) (client.ClientUpgradeProgressWatch, error)) *Client_WatchUpgradeProgress_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewClient creates a new instance of Client. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewClient(t interface {